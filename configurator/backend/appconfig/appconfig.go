@@ -27,6 +27,9 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("server.log.level", "info")
 	viper.SetDefault("server.allowed_domains", []string{"localhost", jcors.AppTopLevelDomainTemplate})
 	viper.SetDefault("ui.base_url", "/")
+	viper.SetDefault("server.log.format", logging.TextFormat)
+	viper.SetDefault("server.health.timeout_ms", 5000)
+	viper.SetDefault("server.health.cache_ttl_sec", 30)
 
 	if containerized {
 		viper.SetDefault("server.log.path", "/home/configurator/data/logs")
@@ -67,6 +70,9 @@ func Init(containerized bool) error {
 	} else {
 		globalLogsWriter = os.Stdout
 	}
+	if err := logging.SetFormat(viper.GetString("server.log.format")); err != nil {
+		return err
+	}
 	err := logging.InitGlobalLogger(globalLogsWriter, viper.GetString("server.log.level"))
 	if err != nil {
 		return err
@@ -18,6 +18,9 @@ var (
 	errIsCloud       = errors.New("This API call is supported only for Redis-based authorization")
 	errUserNotFound  = errors.New("User is not found")
 	errMultipleUsers = errors.New("Multiple users found. Please use your own personal access token for this API call")
+
+	errImpersonatedPasswordChange = errors.New("Password can't be changed in impersonated session")
+	errImpersonationExpired       = errors.New("Impersonation session is expired")
 )
 
 type MailSender interface {
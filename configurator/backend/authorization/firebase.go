@@ -4,6 +4,7 @@ import (
 	"context"
 	"path/filepath"
 	"strings"
+	"time"
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/auth"
@@ -12,11 +13,20 @@ import (
 	"github.com/jitsucom/jitsu/configurator/middleware"
 	"github.com/jitsucom/jitsu/configurator/openapi"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 	"google.golang.org/api/option"
 )
 
+const (
+	firebaseCustomTokenType = "firebase_custom_token"
+
+	//custom token claims are copied to ID tokens of the session
+	impersonatedByClaim         = "impersonated_by"
+	impersonationExpiresAtClaim = "impersonation_expires_at"
+)
+
 type FirebaseInit struct {
 	AdminDomain     string
 	AdminEmails     []string
@@ -39,11 +49,9 @@ func NewFirebase(ctx context.Context, init FirebaseInit) (*Firebase, error) {
 
 	logging.Infof("Initializing firebase authorization storage..")
 
-	app, err := firebase.NewApp(ctx,
-		&firebase.Config{ProjectID: init.ProjectID},
-		option.WithCredentialsFile(init.CredentialsFile))
+	app, err := newFirebaseApp(ctx, init.ProjectID, init.CredentialsFile)
 	if err != nil {
-		return nil, errors.Wrap(err, "init firebase app")
+		return nil, err
 	}
 
 	authClient, err := app.Auth(ctx)
@@ -80,6 +88,17 @@ func (fb *Firebase) Authorize(ctx context.Context, accessToken string) (*middlew
 		}
 	}
 
+	impersonatedBy, _ := token.Claims[impersonatedByClaim].(string)
+	if impersonatedBy != "" {
+		//ID tokens of the session are refreshed by Firebase SDK so the expiration is checked explicitly
+		if expiresAt, ok := token.Claims[impersonationExpiresAtClaim].(float64); !ok || timestamp.Now().Unix() > int64(expiresAt) {
+			return nil, middleware.ReadableError{
+				Description: "User access token is invalid",
+				Cause:       errImpersonationExpired,
+			}
+		}
+	}
+
 	user, err := fb.authClient.GetUser(ctx, token.UID)
 	if err != nil {
 		return nil, middleware.ReadableError{
@@ -106,7 +125,28 @@ func (fb *Firebase) Authorize(ctx context.Context, accessToken string) (*middlew
 			Id:    user.UID,
 			Email: user.Email,
 		},
-		IsAdmin: isAdmin,
+		IsAdmin:        isAdmin,
+		ImpersonatedBy: impersonatedBy,
+	}, nil
+}
+
+func (fb *Firebase) Impersonate(ctx context.Context, userID, impersonatedBy string, ttl time.Duration) (*handlers.ImpersonationToken, error) {
+	expiresAt := timestamp.Now().UTC().Add(ttl)
+	token, err := fb.authClient.CustomTokenWithClaims(ctx, userID, map[string]interface{}{
+		impersonatedByClaim:         impersonatedBy,
+		impersonationExpiresAtClaim: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, middleware.ReadableError{
+			Description: "Failed to create custom token via Firebase",
+			Cause:       err,
+		}
+	}
+
+	return &handlers.ImpersonationToken{
+		Token:     token,
+		TokenType: firebaseCustomTokenType,
+		ExpiresAt: expiresAt,
 	}, nil
 }
 
@@ -182,6 +222,17 @@ func (fb *Firebase) AutoSignUp(ctx context.Context, email string, _ *string) (st
 	return createdUser.UID, nil
 }
 
+func newFirebaseApp(ctx context.Context, projectID, credentialsFile string) (*firebase.App, error) {
+	app, err := firebase.NewApp(ctx,
+		&firebase.Config{ProjectID: projectID},
+		option.WithCredentialsFile(credentialsFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "init firebase app")
+	}
+
+	return app, nil
+}
+
 func isProvidedByGoogle(info []*auth.UserInfo) bool {
 	for _, info := range info {
 		if info.ProviderID == "google.com" {
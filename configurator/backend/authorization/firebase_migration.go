@@ -0,0 +1,339 @@
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"firebase.google.com/go/v4/auth"
+	"github.com/gomodule/redigo/redis"
+	"github.com/jitsucom/jitsu/configurator/common"
+	"github.com/jitsucom/jitsu/configurator/entities"
+	"github.com/jitsucom/jitsu/configurator/handlers"
+	"github.com/jitsucom/jitsu/configurator/openapi"
+	"github.com/jitsucom/jitsu/configurator/storages"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	MigratedUserCreated = "created"
+	MigratedUserExists  = "exists"
+	MigratedUserSkipped = "skipped"
+	MigratedUserFailed  = "failed"
+
+	migratedPasswordImported      = "imported"
+	migratedPasswordResetRequired = "reset_required"
+	migratedPasswordUnchanged     = "unchanged"
+
+	firestoreUsersInfoCollection = "users_info"
+)
+
+type FirebaseMigrationInit struct {
+	ProjectID       string
+	CredentialsFile string
+	// ImportUserInfo enables import of user names, settings and projects from Firestore users_info collection
+	ImportUserInfo bool
+	Target         *Redis
+	Configurations *storages.ConfigurationsService
+}
+
+// FirebaseMigration imports Firebase Auth users into Redis authorization.
+// Users keep Firebase IDs, so their project links and permissions in the configurations storage stay valid.
+// Users which already exist in Redis (by email) aren't changed, so the migration can be re-run
+type FirebaseMigration struct {
+	authClient     *auth.Client
+	firestore      *firestore.Client
+	target         *Redis
+	configurations *storages.ConfigurationsService
+
+	mutex   sync.Mutex
+	running bool
+}
+
+func NewFirebaseMigration(ctx context.Context, init FirebaseMigrationInit) (*FirebaseMigration, error) {
+	if !filepath.IsAbs(init.CredentialsFile) {
+		return nil, errors.New("auth.firebase_migration.credentials_file must be an absolute path")
+	}
+
+	app, err := newFirebaseApp(ctx, init.ProjectID, init.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	authClient, err := app.Auth(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "init firebase auth client")
+	}
+
+	var firestoreClient *firestore.Client
+	if init.ImportUserInfo {
+		if firestoreClient, err = app.Firestore(ctx); err != nil {
+			return nil, errors.Wrap(err, "init firestore client")
+		}
+	}
+
+	return &FirebaseMigration{
+		authClient:     authClient,
+		firestore:      firestoreClient,
+		target:         init.Target,
+		configurations: init.Configurations,
+	}, nil
+}
+
+// MigrateUsers imports all Firebase users. If dryRun is true, nothing is written and the report contains
+// what would be done
+func (fm *FirebaseMigration) MigrateUsers(ctx context.Context, dryRun bool) (*handlers.UserMigrationReport, error) {
+	fm.mutex.Lock()
+	if fm.running {
+		fm.mutex.Unlock()
+		return nil, errors.New("Firebase users migration is already running")
+	}
+	fm.running = true
+	fm.mutex.Unlock()
+
+	defer func() {
+		fm.mutex.Lock()
+		fm.running = false
+		fm.mutex.Unlock()
+	}()
+
+	conn, err := fm.target.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeQuietly(conn)
+
+	report := &handlers.UserMigrationReport{
+		DryRun:    dryRun,
+		StartedAt: timestamp.Now(),
+		Users:     []handlers.MigratedUser{},
+	}
+
+	users := fm.authClient.Users(ctx, "")
+	for {
+		record, err := users.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "list firebase users")
+		}
+
+		user := fm.migrateUser(ctx, conn, record, dryRun)
+		addMigratedUser(report, user)
+		if user.Status == MigratedUserFailed {
+			logging.Errorf("Failed to migrate Firebase user [%s]: %s", user.SourceID, user.Error)
+		}
+	}
+
+	report.FinishedAt = timestamp.Now()
+	logging.Infof("Firebase users migration (dry run: %t) has been finished: total: %d, created: %d, existing: %d, skipped: %d, failed: %d",
+		dryRun, report.Total, report.Created, report.Existing, report.Skipped, report.Failed)
+	return report, nil
+}
+
+func (fm *FirebaseMigration) Close() error {
+	if fm.firestore != nil {
+		return fm.firestore.Close()
+	}
+
+	return nil
+}
+
+func (fm *FirebaseMigration) migrateUser(ctx context.Context, conn redis.Conn, record *auth.ExportedUserRecord, dryRun bool) handlers.MigratedUser {
+	user := handlers.MigratedUser{
+		SourceID: record.UID,
+		Email:    record.Email,
+	}
+
+	switch {
+	case record.Email == "":
+		user.Status, user.Error = MigratedUserSkipped, "user doesn't have an email"
+	case record.Disabled:
+		user.Status, user.Error = MigratedUserSkipped, "user is disabled in Firebase"
+	default:
+		if err := fm.importUser(ctx, conn, record, &user, dryRun); err != nil {
+			user.Status, user.Error = MigratedUserFailed, err.Error()
+		}
+	}
+
+	return user
+}
+
+func (fm *FirebaseMigration) importUser(ctx context.Context, conn redis.Conn, record *auth.ExportedUserRecord, user *handlers.MigratedUser, dryRun bool) error {
+	userID, err := fm.target.getUserIDByEmail(conn, record.Email)
+	switch {
+	case err == nil:
+		user.UserID, user.Status, user.Password = userID, MigratedUserExists, migratedPasswordUnchanged
+	case errors.Is(err, errUserNotFound):
+		if exists, err := redis.Bool(conn.Do("EXISTS", userKey(record.UID))); err != nil {
+			return errors.Wrap(err, "check user existence")
+		} else if exists {
+			return errors.Errorf("user with ID [%s] already exists with another email", record.UID)
+		}
+
+		hashedPassword, password, err := fm.hashedPassword(record)
+		if err != nil {
+			return err
+		}
+
+		user.UserID, user.Status, user.Password = record.UID, MigratedUserCreated, password
+		if !dryRun {
+			if err := fm.target.importUser(conn, record.UID, record.Email, hashedPassword); err != nil {
+				return err
+			}
+		}
+	default:
+		return err
+	}
+
+	projects, err := fm.linkProjects(ctx, record.UID, user.UserID, dryRun)
+	user.Projects = projects
+	return err
+}
+
+// hashedPassword returns Firebase password hash if it has been exported and can be checked.
+// Otherwise a random password is generated and the user has to reset it
+func (fm *FirebaseMigration) hashedPassword(record *auth.ExportedUserRecord) (string, string, error) {
+	if record.PasswordHash != "" && fm.target.firebaseScrypt != nil {
+		return encodeFirebasePassword(record.PasswordSalt, record.PasswordHash), migratedPasswordImported, nil
+	}
+
+	hashedPassword, err := fm.target.passwordEncoder.Encode(uuid.NewV4().String())
+	if err != nil {
+		return "", "", errors.Wrap(err, "encode password")
+	}
+
+	return hashedPassword, migratedPasswordResetRequired, nil
+}
+
+// linkProjects links the user to the projects of the Firebase user (from the configurations storage and Firestore user info).
+// Project permissions are copied if the user has been matched by email with another ID
+func (fm *FirebaseMigration) linkProjects(ctx context.Context, sourceID, userID string, dryRun bool) ([]string, error) {
+	sourceProjects, err := fm.configurations.GetUserProjects(sourceID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get user projects")
+	}
+
+	projects := common.StringSetFrom(sourceProjects)
+	var userInfo *openapi.UpdateUserInfoRequest
+	if fm.firestore != nil {
+		if userInfo, err = fm.getFirestoreUserInfo(ctx, sourceID); err != nil {
+			return nil, err
+		} else if userInfo != nil && userInfo.Project != nil && userInfo.Project.Id != nil {
+			projects.Add(*userInfo.Project.Id)
+		}
+	}
+
+	projectIDs := projects.Values()
+	sort.Strings(projectIDs)
+	if dryRun {
+		return projectIDs, nil
+	}
+
+	if userInfo != nil {
+		if err := fm.importUserInfo(ctx, userID, userInfo); err != nil {
+			return projectIDs, err
+		}
+	}
+
+	linked := common.StringSet{}
+	if userID != sourceID {
+		userProjects, err := fm.configurations.GetUserProjects(userID)
+		if err != nil {
+			return projectIDs, errors.Wrap(err, "get user projects")
+		}
+
+		linked = common.StringSetFrom(userProjects)
+	}
+
+	for _, projectID := range projectIDs {
+		if _, ok := linked[projectID]; ok {
+			continue
+		}
+
+		if userID != sourceID {
+			if permissions, err := fm.configurations.GetProjectPermissions(sourceID, projectID); err != nil {
+				return projectIDs, err
+			} else if err := fm.configurations.UpdateProjectPermissions(projectID, userID, *permissions); err != nil {
+				return projectIDs, err
+			}
+		}
+
+		if err := fm.configurations.LinkUserToProject(userID, projectID); err != nil {
+			return projectIDs, errors.Wrapf(err, "link user to project %s", projectID)
+		}
+	}
+
+	return projectIDs, nil
+}
+
+func (fm *FirebaseMigration) getFirestoreUserInfo(ctx context.Context, userID string) (*openapi.UpdateUserInfoRequest, error) {
+	document := fm.firestore.Collection(firestoreUsersInfoCollection).Doc(userID)
+	snapshots, err := fm.firestore.GetAll(ctx, []*firestore.DocumentRef{document})
+	if err != nil {
+		return nil, errors.Wrap(err, "get firestore user info")
+	} else if len(snapshots) == 0 || !snapshots[0].Exists() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(snapshots[0].Data())
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal firestore user info")
+	}
+
+	var userInfo openapi.UpdateUserInfoRequest
+	if err := json.Unmarshal(data, &userInfo); err != nil {
+		return nil, errors.Wrap(err, "unmarshal firestore user info")
+	}
+
+	return &userInfo, nil
+}
+
+// importUserInfo saves Firestore user info only if the user doesn't have it in the configurations storage
+func (fm *FirebaseMigration) importUserInfo(ctx context.Context, userID string, userInfo *openapi.UpdateUserInfoRequest) error {
+	err := fm.configurations.Load(userID, new(entities.UserInfo))
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, storages.ErrConfigurationNotFound):
+		return errors.Wrap(err, "load user info")
+	}
+
+	if _, err := fm.configurations.UpdateUserInfo(ctx, userID, userInfo); err != nil {
+		return errors.Wrap(err, "import user info")
+	}
+
+	return nil
+}
+
+func addMigratedUser(report *handlers.UserMigrationReport, user handlers.MigratedUser) {
+	report.Total++
+	switch user.Status {
+	case MigratedUserCreated:
+		report.Created++
+	case MigratedUserExists:
+		report.Existing++
+	case MigratedUserSkipped:
+		report.Skipped++
+	case MigratedUserFailed:
+		report.Failed++
+	}
+
+	switch user.Password {
+	case migratedPasswordImported:
+		report.PasswordsImported++
+	case migratedPasswordResetRequired:
+		report.PasswordResetRequired++
+	}
+
+	report.ProjectLinks += len(user.Projects)
+	report.Users = append(report.Users, user)
+}
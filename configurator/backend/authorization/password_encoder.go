@@ -1,6 +1,19 @@
 package authorization
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// firebaseScryptPrefix marks password hashes imported from Firebase Auth: firebase-scrypt$<salt>$<hash>
+const firebaseScryptPrefix = "firebase-scrypt$"
 
 type PasswordEncoder interface {
 	Encode(value string) (string, error)
@@ -20,3 +33,109 @@ func (_bcrypt) Encode(value string) (string, error) {
 func (_bcrypt) Compare(encoded, raw string) error {
 	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(raw))
 }
+
+// FirebasePasswordHash is Firebase Auth project password hash parameters
+// (Firebase console: Authentication > Users > Password hash parameters). All keys are base64 encoded
+type FirebasePasswordHash struct {
+	SignerKey     string `json:"signer_key" mapstructure:"signer_key"`
+	SaltSeparator string `json:"salt_separator" mapstructure:"salt_separator"`
+	Rounds        int    `json:"rounds" mapstructure:"rounds"`
+	MemCost       int    `json:"mem_cost" mapstructure:"mem_cost"`
+}
+
+// firebaseScrypt checks passwords hashed with Firebase modified scrypt algorithm.
+// It can't encode new passwords: they are encoded with bcrypt
+type firebaseScrypt struct {
+	signerKey     []byte
+	saltSeparator []byte
+	rounds        int
+	memCost       int
+}
+
+func newFirebaseScrypt(config *FirebasePasswordHash) (*firebaseScrypt, error) {
+	signerKey, err := decodeBase64(config.SignerKey)
+	if err != nil || len(signerKey) == 0 {
+		return nil, errors.New("firebase_password_hash.signer_key must be a non-empty base64 string")
+	}
+
+	saltSeparator, err := decodeBase64(config.SaltSeparator)
+	if err != nil {
+		return nil, errors.New("firebase_password_hash.salt_separator must be a base64 string")
+	}
+
+	if config.Rounds < 1 || config.Rounds > 8 {
+		return nil, errors.New("firebase_password_hash.rounds must be between 1 and 8")
+	}
+
+	if config.MemCost < 1 || config.MemCost > 14 {
+		return nil, errors.New("firebase_password_hash.mem_cost must be between 1 and 14")
+	}
+
+	return &firebaseScrypt{
+		signerKey:     signerKey,
+		saltSeparator: saltSeparator,
+		rounds:        config.Rounds,
+		memCost:       config.MemCost,
+	}, nil
+}
+
+func (fs *firebaseScrypt) Compare(encoded, raw string) error {
+	parts := strings.Split(strings.TrimPrefix(encoded, firebaseScryptPrefix), "$")
+	if len(parts) != 2 {
+		return errors.New("malformed firebase password hash")
+	}
+
+	salt, err := decodeBase64(parts[0])
+	if err != nil {
+		return errors.Wrap(err, "decode firebase password salt")
+	}
+
+	hash, err := decodeBase64(parts[1])
+	if err != nil {
+		return errors.Wrap(err, "decode firebase password hash")
+	}
+
+	derivedKey, err := scrypt.Key([]byte(raw), append(salt, fs.saltSeparator...), 1<<uint(fs.memCost), fs.rounds, 1, 32)
+	if err != nil {
+		return errors.Wrap(err, "derive scrypt key")
+	}
+
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return errors.Wrap(err, "init aes cipher")
+	}
+
+	// signer key is encrypted with AES-256-CTR and zero IV
+	expected := make([]byte, len(fs.signerKey))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(expected, fs.signerKey)
+	if subtle.ConstantTimeCompare(expected, hash) != 1 {
+		return errors.New("password doesn't match")
+	}
+
+	return nil
+}
+
+func encodeFirebasePassword(salt, hash string) string {
+	return firebaseScryptPrefix + salt + "$" + hash
+}
+
+func isFirebasePassword(encoded string) bool {
+	return strings.HasPrefix(encoded, firebaseScryptPrefix)
+}
+
+// decodeBase64 decodes both standard (Firebase console) and URL-safe (Firebase Auth API) base64 strings
+func decodeBase64(value string) ([]byte, error) {
+	if strings.ContainsAny(value, "-_") {
+		return base64.URLEncoding.DecodeString(padBase64(value))
+	}
+
+	return base64.StdEncoding.DecodeString(padBase64(value))
+}
+
+func padBase64(value string) string {
+	if rem := len(value) % 4; rem != 0 {
+		return value + strings.Repeat("=", 4-rem)
+	}
+
+	return value
+}
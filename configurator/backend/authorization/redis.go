@@ -36,15 +36,26 @@ const (
 type RedisInit struct {
 	PoolFactory *meta.RedisPoolFactory
 	MailSender  MailSender
+	//FirebasePasswordHash is required for signing in with passwords imported from Firebase
+	FirebasePasswordHash *FirebasePasswordHash
 }
 
 type Redis struct {
 	passwordEncoder PasswordEncoder
+	firebaseScrypt  *firebaseScrypt
 	redisPool       *meta.RedisPool
 	mailSender      MailSender
 }
 
 func NewRedis(init RedisInit) (*Redis, error) {
+	var fs *firebaseScrypt
+	if init.FirebasePasswordHash != nil {
+		var err error
+		if fs, err = newFirebaseScrypt(init.FirebasePasswordHash); err != nil {
+			return nil, errors.Wrap(err, "init firebase password hash")
+		}
+	}
+
 	redisPool, err := init.PoolFactory.Create()
 	if err != nil {
 		return nil, errors.Wrap(err, "create redis pool")
@@ -52,6 +63,7 @@ func NewRedis(init RedisInit) (*Redis, error) {
 
 	return &Redis{
 		passwordEncoder: _bcrypt{},
+		firebaseScrypt:  fs,
 		redisPool:       redisPool,
 		mailSender:      init.MailSender,
 	}, nil
@@ -114,6 +126,36 @@ func (r *Redis) Authorize(ctx context.Context, accessToken string) (*middleware.
 			Id:    token.UserID,
 			Email: email,
 		},
+		ImpersonatedBy: token.ImpersonatedBy,
+	}, nil
+}
+
+func (r *Redis) Impersonate(ctx context.Context, userID, impersonatedBy string, ttl time.Duration) (*handlers.ImpersonationToken, error) {
+	conn, err := r.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeQuietly(conn)
+
+	if _, err := r.getUserEmail(conn, userID); err != nil {
+		return nil, middleware.ReadableError{
+			Description: "Failed to load user email from Redis",
+			Cause:       err,
+		}
+	}
+
+	now := timestamp.Now()
+	token := newRedisToken(now, userID, accessTokenType, ttl)
+	token.ImpersonatedBy = impersonatedBy
+	if err := r.saveToken(conn, accessTokenType, token); err != nil {
+		return nil, errors.Wrapf(err, "save %s", accessTokenType.name())
+	}
+
+	return &handlers.ImpersonationToken{
+		Token:     token.AccessToken,
+		TokenType: accessTokenType.name(),
+		ExpiresAt: now.UTC().Add(ttl),
 	}, nil
 }
 
@@ -355,8 +397,8 @@ func (r *Redis) SignIn(ctx context.Context, email, password string) (*openapi.To
 		}
 	}
 
-	if err := r.passwordEncoder.Compare(hashedPassword, password); err != nil {
-		return nil, errors.New("invalid password")
+	if err := r.comparePassword(conn, userID, hashedPassword, password); err != nil {
+		return nil, err
 	}
 
 	tokenPair, err := r.generateTokenPair(conn, userID, defaultTokenPairTTL)
@@ -522,6 +564,10 @@ func (r *Redis) ChangePassword(ctx context.Context, accessToken, newPassword str
 		}
 	}
 
+	if token.ImpersonatedBy != "" {
+		return nil, errImpersonatedPasswordChange
+	}
+
 	if err := r.changePassword(conn, token.UserID, newPassword); err != nil {
 		return nil, middleware.ReadableError{
 			Description: "Failed to change user password in Redis",
@@ -754,6 +800,50 @@ func (r *Redis) createUser(conn redis.Conn, email, password string, precondition
 	return id, nil
 }
 
+// comparePassword checks the password. Passwords imported from Firebase are re-encoded with bcrypt after the first sign in
+func (r *Redis) comparePassword(conn redis.Conn, userID, hashedPassword, password string) error {
+	if !isFirebasePassword(hashedPassword) {
+		if err := r.passwordEncoder.Compare(hashedPassword, password); err != nil {
+			return errors.New("invalid password")
+		}
+
+		return nil
+	}
+
+	if r.firebaseScrypt == nil {
+		return errors.New("Password has been imported from Firebase, but auth.redis.firebase_password_hash isn't configured. Please reset the password")
+	}
+
+	if err := r.firebaseScrypt.Compare(hashedPassword, password); err != nil {
+		return errors.New("invalid password")
+	}
+
+	if rehashed, err := r.passwordEncoder.Encode(password); err != nil {
+		logging.SystemErrorf("Failed to encode imported Firebase password of user [%s]: %v", userID, err)
+	} else if _, err := conn.Do("HSET", userKey(userID), userHashedPasswordField, rehashed); err != nil {
+		logging.SystemErrorf("Failed to update imported Firebase password of user [%s]: %v", userID, err)
+	}
+
+	return nil
+}
+
+// importUser creates the user with the ID and the already hashed password
+func (r *Redis) importUser(conn redis.Conn, id, email, hashedPassword string) error {
+	if _, err := conn.Do("HSET", userKey(id),
+		userIDField, id,
+		userEmailField, email,
+		userHashedPasswordField, hashedPassword,
+	); err != nil {
+		return errors.Wrap(err, "import user")
+	}
+
+	if _, err := conn.Do("HSET", usersIndexKey, email, id); err != nil {
+		return errors.Wrapf(err, "update %s", usersIndexKey)
+	}
+
+	return nil
+}
+
 func (r *Redis) changePassword(conn redis.Conn, userID, newPassword string) error {
 	hashedPassword, err := r.passwordEncoder.Encode(newPassword)
 	if err != nil {
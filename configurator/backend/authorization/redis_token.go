@@ -19,11 +19,12 @@ var defaultTokenPairTTL = tokenPairTTL{
 }
 
 type redisToken struct {
-	UserID       string `json:"user_id"`
-	ExpiredAt    string `json:"expired_at"`
-	TokenType    string `json:"token_type"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	UserID         string `json:"user_id"`
+	ExpiredAt      string `json:"expired_at"`
+	TokenType      string `json:"token_type"`
+	AccessToken    string `json:"access_token"`
+	RefreshToken   string `json:"refresh_token"`
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
 }
 
 func (t *redisToken) validate() error {
@@ -11,6 +11,7 @@ import (
 	"github.com/jitsucom/jitsu/configurator/entities"
 	enadapters "github.com/jitsucom/jitsu/server/adapters"
 	enconfig "github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/consent"
 	enstorages "github.com/jitsucom/jitsu/server/storages"
 	"github.com/jitsucom/jitsu/server/utils"
 	"github.com/mitchellh/mapstructure"
@@ -36,6 +37,8 @@ func MapConfig(destinationID string, destination *entities.Destination, defaultS
 		config, err = mapGoogleAnalytics(destination)
 	case enstorages.GCSType:
 		config, err = mapGoogleCloudStorage(destination)
+	case enstorages.GoogleSheetsType:
+		config, err = mapGoogleSheets(destination)
 	case enstorages.FacebookType:
 		config, err = mapFacebook(destination)
 	case enstorages.WebHookType:
@@ -104,6 +107,21 @@ func MapConfig(destinationID string, destination *entities.Destination, defaultS
 		config.CachingConfiguration = &enconfig.CachingConfiguration{Disabled: destination.CachingConfiguration.Disabled}
 	}
 
+	//consent categories mapping
+	if destination.Consent != nil && len(destination.Consent.Categories) > 0 {
+		config.Consent = &consent.Config{
+			Categories:  destination.Consent.Categories,
+			Mode:        destination.Consent.Mode,
+			StripFields: destination.Consent.StripFields,
+			Missing:     destination.Consent.Missing,
+		}
+	}
+
+	//native filter expression is evaluated before the transformation
+	config.Filter = destination.Filter
+	//batch staging file format (Redshift, Snowflake, BigQuery)
+	config.StagingFormat = destination.StagingFormat
+
 	//only keys
 	config.OnlyTokens = destination.OnlyKeys
 	config.Package = destination.Package
@@ -191,6 +209,34 @@ func mapGoogleCloudStorage(dest *entities.Destination) (*enconfig.DestinationCon
 	}, nil
 }
 
+func mapGoogleSheets(dest *entities.Destination) (*enconfig.DestinationConfig, error) {
+	var formData entities.GoogleSheetsFormData
+	if err := common.DecodeAsJSON(dest.Data, &formData); err != nil {
+		return nil, err
+	}
+
+	config := &enadapters.GoogleSheetsConfig{
+		SpreadsheetID: formData.SpreadsheetID,
+		KeyFile:       formData.Key,
+		Mode:          formData.Mode,
+		MaxRows:       formData.MaxRows,
+	}
+
+	var configValues map[string]interface{}
+	if err := mapstructure.Decode(config, &configValues); err != nil {
+		return nil, fmt.Errorf("Error marshalling config to map: %v", err)
+	}
+
+	return &enconfig.DestinationConfig{
+		Type: enstorages.GoogleSheetsType,
+		Mode: "batch",
+		DataLayout: &enconfig.DataLayout{
+			TableNameTemplate: formData.TableName,
+		},
+		Config: configValues,
+	}, nil
+}
+
 func mapBigQuery(bqDestination *entities.Destination) (*enconfig.DestinationConfig, error) {
 	b, err := json.Marshal(bqDestination.Data)
 	if err != nil {
@@ -0,0 +1,140 @@
+package emails
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	SMTPProvider     = "smtp"
+	SendGridProvider = "sendgrid"
+	SESProvider      = "ses"
+
+	defaultFrom         = "support@jitsu.com"
+	defaultSignature    = "Your Jitsu - an open-source data collection platform team"
+	defaultProjectName  = "Jitsu"
+	defaultPrimaryColor = "#4D66FF"
+	defaultSESRegion    = "us-east-1"
+)
+
+//Configuration is an email service configuration: provider, sender, branding and custom templates
+type Configuration struct {
+	//Provider is smtp (default), sendgrid or ses
+	Provider  string `json:"provider" mapstructure:"provider"`
+	From      string `json:"from" mapstructure:"from"`
+	FromName  string `json:"from_name" mapstructure:"from_name"`
+	ReplyTo   string `json:"reply_to" mapstructure:"reply_to"`
+	Signature string `json:"signature" mapstructure:"signature"`
+
+	SMTP     *SMTPConfiguration     `json:"smtp" mapstructure:"smtp"`
+	SendGrid *SendGridConfiguration `json:"sendgrid" mapstructure:"sendgrid"`
+	SES      *SESConfiguration      `json:"ses" mapstructure:"ses"`
+
+	Branding Branding `json:"branding" mapstructure:"branding"`
+	//Templates overrides default templates by name: reset_password, account_created
+	Templates map[string]*TemplateConfiguration `json:"templates" mapstructure:"templates"`
+}
+
+//SendGridConfiguration is a SendGrid API key with Mail Send permission
+type SendGridConfiguration struct {
+	APIKey string `json:"api_key" mapstructure:"api_key"`
+}
+
+//SESConfiguration is AWS credentials with ses:SendEmail permission. If credentials aren't set,
+//default AWS credentials chain is used (env variables, shared credentials file, IAM role)
+type SESConfiguration struct {
+	Region          string `json:"region" mapstructure:"region"`
+	AccessKeyID     string `json:"access_key_id" mapstructure:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" mapstructure:"secret_access_key"`
+}
+
+//Branding is a set of variables which are available in all templates
+type Branding struct {
+	ProjectName  string `json:"project_name" mapstructure:"project_name"`
+	LogoURL      string `json:"logo_url" mapstructure:"logo_url"`
+	PrimaryColor string `json:"primary_color" mapstructure:"primary_color"`
+	HomeURL      string `json:"home_url" mapstructure:"home_url"`
+	SupportEmail string `json:"support_email" mapstructure:"support_email"`
+}
+
+//TemplateConfiguration is a custom email subject (text template) and a path to HTML template file.
+//Empty values are replaced with the defaults
+type TemplateConfiguration struct {
+	Subject string `json:"subject" mapstructure:"subject"`
+	Path    string `json:"path" mapstructure:"path"`
+}
+
+//FromSMTP returns configuration of the smtp provider from the legacy smtp section
+func FromSMTP(smtp *SMTPConfiguration) *Configuration {
+	return &Configuration{
+		Provider:  SMTPProvider,
+		From:      smtp.From,
+		ReplyTo:   smtp.ReplyTo,
+		Signature: smtp.Signature,
+		SMTP:      smtp,
+	}
+}
+
+//Validate returns err if the provider configuration is invalid or custom templates can't be read. Sets default values
+func (c *Configuration) Validate() error {
+	if c.Provider == "" {
+		c.Provider = SMTPProvider
+	}
+
+	switch c.Provider {
+	case SMTPProvider:
+		if c.SMTP == nil {
+			return errors.New("smtp section is required with smtp provider")
+		}
+		if err := c.SMTP.Validate(); err != nil {
+			return err
+		}
+	case SendGridProvider:
+		if c.SendGrid == nil || c.SendGrid.APIKey == "" {
+			return errors.New("sendgrid.api_key is required with sendgrid provider")
+		}
+	case SESProvider:
+		if c.SES == nil {
+			c.SES = &SESConfiguration{}
+		}
+		if (c.SES.AccessKeyID == "") != (c.SES.SecretAccessKey == "") {
+			return errors.New("ses.access_key_id and ses.secret_access_key must be set together")
+		}
+		if c.SES.Region == "" {
+			c.SES.Region = defaultSESRegion
+		}
+	default:
+		return errors.Errorf("unknown email provider: '%s'. Supported: %s, %s, %s", c.Provider, SMTPProvider, SendGridProvider, SESProvider)
+	}
+
+	if c.From == "" {
+		c.From = defaultFrom
+	}
+
+	if c.Signature == "" {
+		c.Signature = defaultSignature
+	}
+
+	if c.Branding.ProjectName == "" {
+		c.Branding.ProjectName = defaultProjectName
+	}
+
+	if c.Branding.PrimaryColor == "" {
+		c.Branding.PrimaryColor = defaultPrimaryColor
+	}
+
+	for name, tc := range c.Templates {
+		if _, ok := defaultTemplates[templateName(name)]; !ok {
+			return errors.Errorf("unknown email template: '%s'. Supported: %s, %s", name, resetPassword, accountCreated)
+		}
+
+		if tc != nil && tc.Path != "" {
+			if _, err := os.Stat(tc.Path); err != nil {
+				return errors.Wrapf(err, "read '%s' template file", name)
+			}
+		}
+	}
+
+	return nil
+}
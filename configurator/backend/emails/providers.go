@@ -0,0 +1,198 @@
+package emails
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/pkg/errors"
+	gomail "gopkg.in/mail.v2"
+)
+
+const (
+	sendGridAPIURL = "https://api.sendgrid.com/v3/mail/send"
+	sendTimeout    = 30 * time.Second
+)
+
+type message struct {
+	From     string
+	FromName string
+	ReplyTo  string
+	To       string
+	Subject  string
+	HTML     string
+}
+
+//provider delivers emails. check is called on start and disables the service if it fails
+type provider interface {
+	check() error
+	send(msg *message) error
+}
+
+func newProvider(config *Configuration) (provider, error) {
+	switch config.Provider {
+	case SMTPProvider:
+		return &smtpProvider{config: config.SMTP}, nil
+	case SendGridProvider:
+		return &sendGridProvider{apiKey: config.SendGrid.APIKey, client: &http.Client{Timeout: sendTimeout}}, nil
+	case SESProvider:
+		return newSESProvider(config.SES)
+	default:
+		return nil, errors.Errorf("unknown email provider: '%s'", config.Provider)
+	}
+}
+
+type smtpProvider struct {
+	config *SMTPConfiguration
+}
+
+func (sp *smtpProvider) check() error {
+	sc, err := dialer(sp.config).Dial()
+	if err != nil {
+		return err
+	}
+
+	return sc.Close()
+}
+
+func (sp *smtpProvider) send(msg *message) error {
+	m := gomail.NewMessage()
+	if msg.FromName != "" {
+		m.SetAddressHeader("From", msg.From, msg.FromName)
+	} else {
+		m.SetHeader("From", msg.From)
+	}
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+	if msg.ReplyTo != "" {
+		m.SetHeader("Reply-To", msg.ReplyTo)
+	}
+	m.SetBody("text/html", msg.HTML)
+
+	return dialer(sp.config).DialAndSend(m)
+}
+
+func dialer(cfg *SMTPConfiguration) *gomail.Dialer {
+	return gomail.NewDialer(cfg.Host, cfg.Port, cfg.User, cfg.Password)
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+type sendGridProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+//check doesn't verify the API key: SendGrid keys with only Mail Send permission can't call other endpoints
+func (sgp *sendGridProvider) check() error {
+	return nil
+}
+
+func (sgp *sendGridProvider) send(msg *message) error {
+	body := &sendGridMessage{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: msg.To}}}},
+		From:             sendGridAddress{Email: msg.From, Name: msg.FromName},
+		Subject:          msg.Subject,
+		Content:          []sendGridContent{{Type: "text/html", Value: msg.HTML}},
+	}
+	if msg.ReplyTo != "" {
+		body.ReplyTo = &sendGridAddress{Email: msg.ReplyTo}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sendGridAPIURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+sgp.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := sgp.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "SendGrid API request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("SendGrid API error [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+type sesProvider struct {
+	client *ses.SES
+}
+
+func newSESProvider(config *SESConfiguration) (*sesProvider, error) {
+	awsConfig := aws.NewConfig().WithRegion(config.Region)
+	if config.AccessKeyID != "" {
+		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, ""))
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "create AWS session")
+	}
+
+	return &sesProvider{client: ses.New(sess)}, nil
+}
+
+func (sp *sesProvider) check() error {
+	_, err := sp.client.GetSendQuota(&ses.GetSendQuotaInput{})
+	return err
+}
+
+func (sp *sesProvider) send(msg *message) error {
+	source := msg.From
+	if msg.FromName != "" {
+		source = (&mail.Address{Name: msg.FromName, Address: msg.From}).String()
+	}
+
+	input := &ses.SendEmailInput{
+		Source:      aws.String(source),
+		Destination: &ses.Destination{ToAddresses: []*string{aws.String(msg.To)}},
+		Message: &ses.Message{
+			Subject: &ses.Content{Charset: aws.String("UTF-8"), Data: aws.String(msg.Subject)},
+			Body:    &ses.Body{Html: &ses.Content{Charset: aws.String("UTF-8"), Data: aws.String(msg.HTML)}},
+		},
+	}
+	if msg.ReplyTo != "" {
+		input.ReplyToAddresses = []*string{aws.String(msg.ReplyTo)}
+	}
+
+	_, err := sp.client.SendEmail(input)
+	return err
+}
@@ -1,24 +1,27 @@
 package emails
 
 import (
-	"bytes"
-	"text/template"
+	"sync"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/pkg/errors"
-	gomail "gopkg.in/mail.v2"
 )
 
+const maxFailures = 100
+
 var ErrSMTPNotConfigured = errors.New("SMTP isn't configured")
 
+//SMTPConfiguration is an smtp provider configuration. From, Signature and ReplyTo are used only in the legacy
+//smtp section (see FromSMTP)
 type SMTPConfiguration struct {
-	Host      string `json:"host"`
-	Port      int    `json:"port"`
-	User      string `json:"user"`
-	Password  string `json:"password"`
-	From      string `json:"from"`
-	Signature string `json:"signature"`
-	ReplyTo   string `json:"reply_to"`
+	Host      string `json:"host" mapstructure:"host"`
+	Port      int    `json:"port" mapstructure:"port"`
+	User      string `json:"user" mapstructure:"user"`
+	Password  string `json:"password" mapstructure:"password"`
+	From      string `json:"from" mapstructure:"from"`
+	Signature string `json:"signature" mapstructure:"signature"`
+	ReplyTo   string `json:"reply_to" mapstructure:"reply_to"`
 }
 
 func (sc *SMTPConfiguration) Validate() error {
@@ -34,42 +37,52 @@ func (sc *SMTPConfiguration) Validate() error {
 		return errors.New("smtp user is required")
 	}
 
-	if sc.From == "" {
-		sc.From = "support@jitsu.com"
-	}
-
-	if sc.Signature == "" {
-		sc.Signature = "Your Jitsu - an open-source data collection platform team"
-	}
-
 	return nil
 }
 
+//Failure is a failed email sending. The last maxFailures failures are kept in memory and are available to admins
+type Failure struct {
+	Time      time.Time `json:"time"`
+	Template  string    `json:"template"`
+	Recipient string    `json:"recipient"`
+	Provider  string    `json:"provider"`
+	Error     string    `json:"error"`
+}
+
 type Service struct {
-	smtp      *SMTPConfiguration
-	templates map[templateSubject]*template.Template
+	config    *Configuration
+	provider  provider
+	templates map[templateName]*emailTemplate
+
+	failuresMutex sync.RWMutex
+	failures      []Failure
 }
 
-func NewService(smtp *SMTPConfiguration) (*Service, error) {
-	if smtp == nil {
+//NewService returns nil if config is nil or the provider check fails (the service is disabled)
+func NewService(config *Configuration) (*Service, error) {
+	if config == nil {
 		return nil, nil
 	}
 
-	logging.Info("Initializing SMTP email service..")
-	if sc, err := dialer(smtp).Dial(); err != nil {
-		logging.Warnf("Invalid SMTP configuration – service is disabled: %v", err)
-		return nil, nil
-	} else {
-		_ = sc.Close()
+	templates, err := parseTemplates(config.Templates)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse templates")
 	}
 
-	templates, err := parseTemplates()
+	provider, err := newProvider(config)
 	if err != nil {
-		return nil, errors.Wrap(err, "parse templates")
+		return nil, errors.Wrapf(err, "create %s provider", config.Provider)
+	}
+
+	logging.Infof("Initializing %s email service..", config.Provider)
+	if err := provider.check(); err != nil {
+		logging.Warnf("Invalid %s email configuration – service is disabled: %v", config.Provider, err)
+		return nil, nil
 	}
 
 	return &Service{
-		smtp:      smtp,
+		config:    config,
+		provider:  provider,
 		templates: templates,
 	}, nil
 }
@@ -78,37 +91,73 @@ func (s *Service) IsConfigured() bool {
 	return s != nil
 }
 
-func (s *Service) send(subject templateSubject, email, link string) error {
+func (s *Service) send(name templateName, email, link string) error {
 	if !s.IsConfigured() {
 		return ErrSMTPNotConfigured
 	}
 
-	tmplt, ok := s.templates[subject]
+	tmplt, ok := s.templates[name]
 	if !ok {
-		return errors.Errorf("unknown email template: '%s'", subject)
-	}
-
-	msg := gomail.NewMessage()
-	msg.SetHeader("From", s.smtp.From)
-	msg.SetHeader("To", email)
-	msg.SetHeader("Subject", subject.String())
-	if s.smtp.ReplyTo != "" {
-		msg.SetHeader("Reply-To", s.smtp.ReplyTo)
+		return errors.Errorf("unknown email template: '%s'", name)
 	}
 
-	var body bytes.Buffer
-	if err := tmplt.Execute(&body, templateValues{
+	subject, html, err := tmplt.execute(templateValues{
 		Email:     email,
 		Link:      link,
-		Signature: s.smtp.Signature,
-	}); err != nil {
-		return errors.Wrap(err, "transform email template")
+		Signature: s.config.Signature,
+		Branding:  s.config.Branding,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "transform email template")
+	} else {
+		err = s.provider.send(&message{
+			From:     s.config.From,
+			FromName: s.config.FromName,
+			ReplyTo:  s.config.ReplyTo,
+			To:       email,
+			Subject:  subject,
+			HTML:     html,
+		})
 	}
 
-	msg.SetBody("text/html", body.String())
+	if err != nil {
+		s.addFailure(name, email, err)
+	}
 
-	dialer := gomail.NewDialer(s.smtp.Host, s.smtp.Port, s.smtp.User, s.smtp.Password)
-	return dialer.DialAndSend(msg)
+	return err
+}
+
+func (s *Service) addFailure(name templateName, email string, err error) {
+	logging.Errorf("Error sending '%s' email to %s via %s: %v", name, email, s.config.Provider, err)
+
+	s.failuresMutex.Lock()
+	defer s.failuresMutex.Unlock()
+	s.failures = append(s.failures, Failure{
+		Time:      time.Now().UTC(),
+		Template:  name.String(),
+		Recipient: email,
+		Provider:  s.config.Provider,
+		Error:     err.Error(),
+	})
+	if len(s.failures) > maxFailures {
+		s.failures = s.failures[len(s.failures)-maxFailures:]
+	}
+}
+
+//Failures returns the last failed email sendings. The newest first
+func (s *Service) Failures() []Failure {
+	if !s.IsConfigured() {
+		return nil
+	}
+
+	s.failuresMutex.RLock()
+	defer s.failuresMutex.RUnlock()
+	result := make([]Failure, 0, len(s.failures))
+	for i := len(s.failures) - 1; i >= 0; i-- {
+		result = append(result, s.failures[i])
+	}
+
+	return result
 }
 
 func (s *Service) SendResetPassword(email, link string) error {
@@ -118,7 +167,3 @@ func (s *Service) SendResetPassword(email, link string) error {
 func (s *Service) SendAccountCreated(email, link string) error {
 	return s.send(accountCreated, email, link)
 }
-
-func dialer(cfg *SMTPConfiguration) *gomail.Dialer {
-	return gomail.NewDialer(cfg.Host, cfg.Port, cfg.User, cfg.Password)
-}
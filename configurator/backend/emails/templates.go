@@ -1,61 +1,124 @@
 package emails
 
 import (
+	"bytes"
+	htmltemplate "html/template"
+	"io/ioutil"
 	"text/template"
 
 	"github.com/pkg/errors"
 )
 
-type templateSubject string
+type templateName string
 
-func (s templateSubject) String() string {
-	return string(s)
+func (n templateName) String() string {
+	return string(n)
 }
 
 type templateValues struct {
 	Email     string
 	Link      string
 	Signature string
+	Branding
 }
 
 const (
-	resetPassword  templateSubject = "Reset your password for Jitsu - an open-source data collection platform"
-	accountCreated templateSubject = "Your Jitsu account has been created"
+	resetPassword  templateName = "reset_password"
+	accountCreated templateName = "account_created"
 )
 
-func parseTemplates() (map[templateSubject]*template.Template, error) {
-	result := make(map[templateSubject]*template.Template, len(rawTemplates))
-	for subject, value := range rawTemplates {
-		if tmplt, err := template.New(subject.String()).Parse(value); err != nil {
-			return nil, errors.Wrapf(err, "parse template '%s'", subject)
-		} else {
-			result[subject] = tmplt
+type rawTemplate struct {
+	subject string
+	html    string
+}
+
+type emailTemplate struct {
+	subject *template.Template
+	html    *htmltemplate.Template
+}
+
+func (t *emailTemplate) execute(values templateValues) (subject, html string, err error) {
+	var subjectBuf, htmlBuf bytes.Buffer
+	if err := t.subject.Execute(&subjectBuf, values); err != nil {
+		return "", "", errors.Wrap(err, "execute subject template")
+	}
+
+	if err := t.html.Execute(&htmlBuf, values); err != nil {
+		return "", "", errors.Wrap(err, "execute html template")
+	}
+
+	return subjectBuf.String(), htmlBuf.String(), nil
+}
+
+//parseTemplates returns default templates overridden with custom subjects and HTML files
+func parseTemplates(custom map[string]*TemplateConfiguration) (map[templateName]*emailTemplate, error) {
+	result := make(map[templateName]*emailTemplate, len(defaultTemplates))
+	for name, raw := range defaultTemplates {
+		if tc := custom[name.String()]; tc != nil {
+			if tc.Subject != "" {
+				raw.subject = tc.Subject
+			}
+
+			if tc.Path != "" {
+				data, err := ioutil.ReadFile(tc.Path)
+				if err != nil {
+					return nil, errors.Wrapf(err, "read template '%s' file", name)
+				}
+				raw.html = string(data)
+			}
 		}
+
+		subject, err := template.New(name.String()).Parse(raw.subject)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse template '%s' subject", name)
+		}
+
+		html, err := htmltemplate.New(name.String()).Parse(raw.html)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse template '%s'", name)
+		}
+
+		result[name] = &emailTemplate{subject: subject, html: html}
 	}
 
 	return result, nil
 }
 
-var rawTemplates = map[templateSubject]string{
-	resetPassword: `<!DOCTYPE html>
+const header = `{{if .LogoURL}}<p><img src='{{.LogoURL}}' alt='{{.ProjectName}}' style='max-height: 48px'></p>{{end}}`
+
+const footer = `<p>Thanks,</p>
+	<p>{{.Signature}}</p>
+	{{if .SupportEmail}}<p style='color: #888888; font-size: 12px'>Questions? Contact us at <a href='mailto:{{.SupportEmail}}'>{{.SupportEmail}}</a></p>{{end}}
+	{{if .HomeURL}}<p style='color: #888888; font-size: 12px'><a href='{{.HomeURL}}'>{{.ProjectName}}</a></p>{{end}}`
+
+var defaultTemplates = map[templateName]rawTemplate{
+	resetPassword: {
+		subject: "Reset your password for {{.ProjectName}}",
+		html: `<!DOCTYPE html>
 <html>
 <body>
-    <p>Hello,</p>
-	<p>Follow this <a href='{{.Link}}'>link</a> to reset your Jitsu - an open-source data collection platform password for your {{.Email}} account.</p>
+	` + header + `
+	<p>Hello,</p>
+	<p>Follow this link to reset your {{.ProjectName}} password for your {{.Email}} account.</p>
+	<p><a href='{{.Link}}' style='display: inline-block; padding: 10px 20px; border-radius: 4px; color: #FFFFFF; text-decoration: none; background-color: {{.PrimaryColor}}'>Reset password</a></p>
 	<p>If you didn't ask to reset your password, you can ignore this email.</p>
-	<p>Thanks,</p>
-	<p>{{.Signature}}</p>
+	` + footer + `
 </body>
 </html>`,
+	},
 
-	accountCreated: `<DOCTYPE html>
+	accountCreated: {
+		subject: "Your {{.ProjectName}} account has been created",
+		html: `<!DOCTYPE html>
 <html>
 <body>
+	` + header + `
 	<p>Hello and welcome!</p>
-	<p>Your new Jitsu {{.Email}} account has been registered. Follow this <a href='{{.Link}}'>link</a> to set the password.</p>
+	<p>Your new {{.ProjectName}} {{.Email}} account has been registered. Follow this link to set the password.</p>
+	<p><a href='{{.Link}}' style='display: inline-block; padding: 10px 20px; border-radius: 4px; color: #FFFFFF; text-decoration: none; background-color: {{.PrimaryColor}}'>Set password</a></p>
 	<p>If you didn't expect this, you can safely ignore this email.</p>
-	<p>Thanks,</p>
-	<p>{{.Signature}}</p>
+	` + footer + `
 </body>
 </html>`,
+	},
 }
@@ -1,5 +1,11 @@
 package entities
 
+import (
+	"github.com/jitsucom/jitsu/server/iphandling"
+	"github.com/jitsucom/jitsu/server/routing"
+	"github.com/jitsucom/jitsu/server/validation"
+)
+
 // APIKey entity is stored in main storage (Firebase)
 type APIKey struct {
 	ID             string   `firestore:"uid" json:"uid" yaml:"id,omitempty"`
@@ -7,6 +13,10 @@ type APIKey struct {
 	ServerSecret   string   `firestore:"serverAuth" json:"serverAuth" yaml:"server_secret,omitempty"`
 	Origins        []string `firestore:"origins" json:"origins" yaml:"origins,omitempty"`
 	BatchPeriodMin int      `firestore:"batchPeriodMin" json:"batchPeriodMin" yaml:"batch_period_min,omitempty"`
+
+	Validation *validation.Config `firestore:"validation" json:"validation,omitempty" yaml:"validation,omitempty"`
+	Routing    *routing.Config    `firestore:"routing" json:"routing,omitempty" yaml:"routing,omitempty"`
+	IPHandling *iphandling.Config `firestore:"ipHandling" json:"ipHandling,omitempty" yaml:"ip_handling,omitempty"`
 }
 
 // APIKeys entity is stored in main storage (Firebase)
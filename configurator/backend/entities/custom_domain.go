@@ -3,9 +3,14 @@ package entities
 type CustomDomain struct {
 	Name   string `firestore:"name" json:"name"`
 	Status string `firestore:"status" json:"status"`
+	//LastError is the last CNAME check or certificate issuance error of the domain
+	LastError string `firestore:"lastError" json:"lastError,omitempty"`
 }
 
 type CustomDomains struct {
 	CertificateExpirationDate string          `firestore:"_certificateExpiration" json:"_certificateExpiration"`
+	CertificateIssuedAt       string          `firestore:"_certificateIssuedAt" json:"_certificateIssuedAt,omitempty"`
+	LastIssuanceAttemptAt     string          `firestore:"_lastIssuanceAttemptAt" json:"_lastIssuanceAttemptAt,omitempty"`
+	LastIssuanceError         string          `firestore:"_lastIssuanceError" json:"_lastIssuanceError,omitempty"`
 	Domains                   []*CustomDomain `firestore:"domains" json:"domains"`
 }
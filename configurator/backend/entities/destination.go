@@ -20,6 +20,9 @@ type Destination struct {
 	PrimaryKeyFields               []string                 `firestore:"_primary_key_fields" json:"_primary_key_fields"`
 	CachingConfiguration           *CachingConfiguration    `firestore:"_caching_configuration" json:"_caching_configuration"`
 	DisableDefaultPrimaryKeyFields bool                     `firestore:"_disable_default_primary_key_fields" json:"_disable_default_primary_key_fields"`
+	Consent                        *Consent                 `firestore:"_consent" json:"_consent,omitempty"`
+	Filter                         string                   `firestore:"_filter" json:"_filter,omitempty"`
+	StagingFormat                  string                   `firestore:"_staging_format" json:"_staging_format,omitempty"`
 }
 
 // Destinations entity is stored in main storage (Firebase or Redis)
@@ -45,6 +48,14 @@ type CachingConfiguration struct {
 	Disabled bool `firestore:"_disabled" json:"_disabled"`
 }
 
+// Consent entity is stored in main storage (Firebase or Redis)
+type Consent struct {
+	Categories  []string `firestore:"_categories" json:"_categories"`
+	Mode        string   `firestore:"_mode" json:"_mode"`
+	StripFields []string `firestore:"_strip_fields" json:"_strip_fields"`
+	Missing     string   `firestore:"_missing" json:"_missing"`
+}
+
 // IsEmpty returns true if mappings is empty
 func (m *Mappings) IsEmpty() bool {
 	return m == nil || len(m.Rules) == 0
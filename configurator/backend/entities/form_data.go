@@ -188,3 +188,12 @@ type GCSFormData struct {
 	Format             adapters.FileEncodingFormat `firestore:"gcsFormat" json:"gcsFormat"`
 	CompressionEnabled bool                        `firestore:"gcsCompressionEnabled" json:"gcsCompressionEnabled"`
 }
+
+// GoogleSheetsFormData entity is stored in main storage (Firebase/Redis)
+type GoogleSheetsFormData struct {
+	TableName     string `firestore:"tableName" json:"tableName"`
+	Key           string `firestore:"gsKey" json:"gsKey"`
+	SpreadsheetID string `firestore:"gsSpreadsheetId" json:"gsSpreadsheetId"`
+	Mode          string `firestore:"gsMode" json:"gsMode"`
+	MaxRows       int    `firestore:"gsMaxRows" json:"gsMaxRows"`
+}
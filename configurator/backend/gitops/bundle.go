@@ -0,0 +1,89 @@
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	destinationsType = "destinations"
+	sourcesType      = "sources"
+	apiKeysType      = "api_keys"
+
+	JSONFormat = "json"
+	YAMLFormat = "yaml"
+)
+
+// applyOrder is an order of objects creation and update: destinations refer to API keys and sources refer to destinations.
+// Objects are deleted in the reverse order
+var applyOrder = []string{apiKeysType, destinationsType, sourcesType}
+
+// Bundle is a declarative project configuration. A nil collection isn't managed by the bundle: its objects aren't
+// changed on apply. An empty collection means that all objects of the type must be deleted
+type Bundle struct {
+	Project      string                   `json:"project,omitempty" yaml:"project,omitempty"`
+	APIKeys      []map[string]interface{} `json:"api_keys" yaml:"api_keys"`
+	Destinations []map[string]interface{} `json:"destinations" yaml:"destinations"`
+	Sources      []map[string]interface{} `json:"sources" yaml:"sources"`
+}
+
+// ParseBundle parses the bundle from YAML or JSON (JSON is a subset of YAML). Values are normalized into JSON types
+// so they can be compared with stored objects
+func ParseBundle(payload []byte) (*Bundle, error) {
+	bundle := &Bundle{}
+	if err := yaml.Unmarshal(payload, bundle); err != nil {
+		return nil, fmt.Errorf("error parsing configuration bundle: %v", err)
+	}
+
+	//YAML integers are converted into JSON numbers. Nil collections stay nil and empty ones stay empty
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing configuration bundle: %v", err)
+	}
+
+	normalized := &Bundle{}
+	if err := json.Unmarshal(data, normalized); err != nil {
+		return nil, fmt.Errorf("error normalizing configuration bundle: %v", err)
+	}
+
+	return normalized, nil
+}
+
+// Marshal serializes the bundle into YAML or JSON format
+func (b *Bundle) Marshal(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "", JSONFormat:
+		return json.MarshalIndent(b, "", "  ")
+	case YAMLFormat:
+		return yaml.Marshal(b)
+	default:
+		return nil, fmt.Errorf("unknown format: %s. Supported: %s, %s", format, JSONFormat, YAMLFormat)
+	}
+}
+
+func (b *Bundle) objects(objectType string) []map[string]interface{} {
+	switch objectType {
+	case apiKeysType:
+		return b.APIKeys
+	case destinationsType:
+		return b.Destinations
+	case sourcesType:
+		return b.Sources
+	default:
+		return nil
+	}
+}
+
+func (b *Bundle) setObjects(objectType string, objects []map[string]interface{}) {
+	switch objectType {
+	case apiKeysType:
+		b.APIKeys = objects
+	case destinationsType:
+		b.Destinations = objects
+	case sourcesType:
+		b.Sources = objects
+	}
+}
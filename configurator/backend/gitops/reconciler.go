@@ -0,0 +1,133 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/jitsucom/jitsu/server/safego"
+)
+
+const defaultIntervalSec = 60
+
+// ProjectSource is a location of a project configuration bundle: http(s):// URL or a local file path
+type ProjectSource struct {
+	ProjectID string `mapstructure:"project_id" json:"project_id,omitempty" yaml:"project_id,omitempty"`
+	Source    string `mapstructure:"source" json:"source,omitempty" yaml:"source,omitempty"`
+	// DryRun reconciler only logs the plan
+	DryRun bool `mapstructure:"dry_run" json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+// ReconcilerConfig is a configuration of the background configuration sync
+type ReconcilerConfig struct {
+	IntervalSec int              `mapstructure:"interval_sec" json:"interval_sec,omitempty" yaml:"interval_sec,omitempty"`
+	Projects    []*ProjectSource `mapstructure:"projects" json:"projects,omitempty" yaml:"projects,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid. Also sets default values
+func (rc *ReconcilerConfig) Validate() error {
+	if rc.IntervalSec <= 0 {
+		rc.IntervalSec = defaultIntervalSec
+	}
+
+	for i, project := range rc.Projects {
+		if project.ProjectID == "" {
+			return fmt.Errorf("gitops.projects[%d]: project_id is required", i)
+		}
+		if project.Source == "" {
+			return fmt.Errorf("gitops.projects[%d]: source is required", i)
+		}
+	}
+
+	return nil
+}
+
+// Reconciler periodically loads configuration bundles and applies them to the projects. Changes made outside
+// of the bundles (e.g. in UI) are reverted on the next run
+type Reconciler struct {
+	service *Service
+	config  *ReconcilerConfig
+	closed  chan struct{}
+}
+
+// NewReconciler returns configured Reconciler and starts reconciliation goroutine
+func NewReconciler(service *Service, config *ReconcilerConfig) (*Reconciler, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &Reconciler{service: service, config: config, closed: make(chan struct{})}
+	r.start()
+	return r, nil
+}
+
+func (r *Reconciler) start() {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(time.Duration(r.config.IntervalSec) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			for _, project := range r.config.Projects {
+				r.reconcile(project)
+			}
+
+			select {
+			case <-r.closed:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func (r *Reconciler) reconcile(project *ProjectSource) {
+	payload, err := load(project.Source)
+	if err != nil {
+		logging.Errorf("[gitops] Error loading configuration bundle of project [%s] from %s: %v", project.ProjectID, project.Source, err)
+		return
+	}
+
+	bundle, err := ParseBundle(payload)
+	if err != nil {
+		logging.Errorf("[gitops] Error parsing configuration bundle of project [%s]: %v", project.ProjectID, err)
+		return
+	}
+
+	plan, err := r.service.Apply(context.Background(), project.ProjectID, bundle, project.DryRun)
+	if err != nil {
+		logging.Errorf("[gitops] Error applying configuration bundle to project [%s]: %v", project.ProjectID, err)
+		return
+	}
+
+	for _, change := range plan.Changes {
+		if project.DryRun {
+			logging.Infof("[gitops] Project [%s] isn't in sync: %s %s [%s] is required", project.ProjectID, change.Action, change.ObjectType, change.ObjectID)
+		} else {
+			logging.Infof("[gitops] Project [%s]: %s %s [%s]", project.ProjectID, change.Action, change.ObjectType, change.ObjectID)
+		}
+	}
+}
+
+// load returns the bundle payload from http(s) URL or a local file
+func load(source string) ([]byte, error) {
+	var response *resources.ResponsePayload
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		response, err = resources.LoadFromHTTP(source, "")
+	} else {
+		response, err = resources.LoadFromFile(source, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Content, nil
+}
+
+func (r *Reconciler) Close() error {
+	close(r.closed)
+	return nil
+}
@@ -0,0 +1,226 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/jitsucom/jitsu/configurator/openapi"
+	"github.com/jitsucom/jitsu/configurator/storages"
+)
+
+const (
+	CreateAction = "create"
+	UpdateAction = "update"
+	DeleteAction = "delete"
+)
+
+// Change is a planned change of a project object
+type Change struct {
+	ObjectType string                 `json:"objectType"`
+	ObjectID   string                 `json:"objectId"`
+	Action     string                 `json:"action"`
+	Diff       []*storages.DiffChange `json:"diff,omitempty"`
+}
+
+// Plan is a list of changes which are required to bring the project configuration to the bundle state.
+// Empty plan means the project configuration is in sync with the bundle
+type Plan struct {
+	ProjectID string    `json:"projectId"`
+	DryRun    bool      `json:"dryRun"`
+	Changes   []*Change `json:"changes"`
+}
+
+// Validator returns err if the object can't be saved
+type Validator func(objectType string, object interface{}) error
+
+// Service exports project configuration into bundles and applies bundles idempotently
+type Service struct {
+	configurations *storages.ConfigurationsService
+	validate       Validator
+}
+
+// NewService returns configured Service
+func NewService(configurations *storages.ConfigurationsService, validate Validator) *Service {
+	return &Service{configurations: configurations, validate: validate}
+}
+
+// Export returns the project destinations, sources and API keys as a bundle. Secret fields are masked, masked values
+// are kept unchanged on apply
+func (s *Service) Export(projectID string) (*Bundle, error) {
+	bundle := &Bundle{Project: projectID}
+	for _, objectType := range applyOrder {
+		objects, err := s.getObjects(objectType, projectID)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(objects)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(s.configurations.MaskSecrets(objectType, data), &objects); err != nil {
+			return nil, err
+		}
+		bundle.setObjects(objectType, objects)
+	}
+
+	return bundle, nil
+}
+
+// Apply creates, updates and deletes project objects so the project configuration matches the bundle.
+// Objects are matched by ID (see storages.ConfigurationsService.GetObjectIDField). Unchanged objects aren't saved,
+// so applying the same bundle twice doesn't change anything. In dry run mode only the plan is returned
+func (s *Service) Apply(ctx context.Context, projectID string, bundle *Bundle, dryRun bool) (*Plan, error) {
+	if bundle.Project != "" && bundle.Project != projectID {
+		return nil, fmt.Errorf("bundle is for project [%s], but applied to project [%s]", bundle.Project, projectID)
+	}
+
+	plan, desired, err := s.plan(projectID, bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.DryRun = dryRun
+	if dryRun {
+		return plan, nil
+	}
+
+	for _, change := range plan.Changes {
+		if err := s.applyChange(ctx, projectID, change, desired[change.ObjectType][change.ObjectID]); err != nil {
+			return nil, fmt.Errorf("error applying %s of %s [%s]: %v", change.Action, change.ObjectType, change.ObjectID, err)
+		}
+	}
+
+	return plan, nil
+}
+
+// plan returns changes in apply order (creations and updates first, deletions in reverse order) and desired objects
+// by object type and ID
+func (s *Service) plan(projectID string, bundle *Bundle) (*Plan, map[string]map[string]map[string]interface{}, error) {
+	plan := &Plan{ProjectID: projectID, Changes: []*Change{}}
+	desired := map[string]map[string]map[string]interface{}{}
+	var deletions []*Change
+	for _, objectType := range applyOrder {
+		bundleObjects := bundle.objects(objectType)
+		if bundleObjects == nil {
+			//collection isn't managed by the bundle
+			continue
+		}
+
+		idField := s.configurations.GetObjectIDField(objectType)
+		desiredObjects := make(map[string]map[string]interface{}, len(bundleObjects))
+		for i, object := range bundleObjects {
+			id, ok := object[idField].(string)
+			if !ok || id == "" {
+				return nil, nil, fmt.Errorf("%s[%d]: %s field is required", objectType, i, idField)
+			}
+			if _, ok := desiredObjects[id]; ok {
+				return nil, nil, fmt.Errorf("%s[%d]: %s [%s] isn't unique", objectType, i, idField, id)
+			}
+			if s.validate != nil {
+				if err := s.validate(objectType, object); err != nil {
+					return nil, nil, fmt.Errorf("%s[%d] [%s]: %v", objectType, i, id, err)
+				}
+			}
+			desiredObjects[id] = object
+		}
+		desired[objectType] = desiredObjects
+
+		currentObjects, err := s.getObjects(objectType, projectID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		current := make(map[string]map[string]interface{}, len(currentObjects))
+		for _, object := range currentObjects {
+			if id := fmt.Sprint(object[idField]); id != "" {
+				current[id] = object
+			}
+		}
+
+		for _, object := range bundleObjects {
+			id := object[idField].(string)
+			currentObject, ok := current[id]
+			s.configurations.UnmaskSecrets(objectType, object, currentObject)
+			if !ok {
+				plan.Changes = append(plan.Changes, &Change{ObjectType: objectType, ObjectID: id, Action: CreateAction,
+					Diff: storages.DiffObjects(nil, object)})
+			} else if !reflect.DeepEqual(currentObject, object) {
+				plan.Changes = append(plan.Changes, &Change{ObjectType: objectType, ObjectID: id, Action: UpdateAction,
+					Diff: storages.DiffObjects(currentObject, object)})
+			}
+		}
+
+		var typeDeletions []*Change
+		for id, object := range current {
+			if _, ok := desiredObjects[id]; !ok {
+				typeDeletions = append(typeDeletions, &Change{ObjectType: objectType, ObjectID: id, Action: DeleteAction,
+					Diff: storages.DiffObjects(object, nil)})
+			}
+		}
+		sort.Slice(typeDeletions, func(i, j int) bool { return typeDeletions[i].ObjectID < typeDeletions[j].ObjectID })
+		deletions = append(typeDeletions, deletions...)
+	}
+
+	plan.Changes = append(plan.Changes, deletions...)
+	for _, change := range plan.Changes {
+		change.Diff = s.configurations.MaskSecretChanges(change.ObjectType, change.Diff)
+	}
+
+	return plan, desired, nil
+}
+
+func (s *Service) applyChange(ctx context.Context, projectID string, change *Change, object map[string]interface{}) error {
+	payload := &storages.PatchPayload{
+		ObjectArrayPath: s.configurations.GetObjectArrayPathByObjectType(change.ObjectType),
+		ObjectMeta: &storages.ObjectMeta{
+			IDFieldPath: s.configurations.GetObjectIDField(change.ObjectType),
+			Value:       change.ObjectID,
+		},
+		Patch: object,
+	}
+
+	var err error
+	switch change.Action {
+	case CreateAction:
+		_, err = s.configurations.CreateObjectWithLock(ctx, change.ObjectType, projectID, &openapi.AnyObject{AdditionalProperties: object}, true)
+	case UpdateAction:
+		_, err = s.configurations.ReplaceObjectWithLock(ctx, change.ObjectType, projectID, payload)
+	case DeleteAction:
+		_, err = s.configurations.DeleteObjectWithLock(ctx, change.ObjectType, projectID, payload)
+	}
+
+	return err
+}
+
+// getObjects returns the project objects of the type or an empty slice if the project doesn't have them
+func (s *Service) getObjects(objectType, projectID string) ([]map[string]interface{}, error) {
+	data, err := s.configurations.GetConfigWithLock(objectType, projectID)
+	if errors.Is(err, storages.ErrConfigurationNotFound) {
+		return []map[string]interface{}{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting %s of project [%s]: %v", objectType, projectID, err)
+	}
+
+	collection := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("error parsing %s of project [%s]: %v", objectType, projectID, err)
+	}
+
+	objects := []map[string]interface{}{}
+	if array, ok := collection[s.configurations.GetObjectArrayPathByObjectType(objectType)]; ok {
+		if err := json.Unmarshal(array, &objects); err != nil {
+			return nil, fmt.Errorf("error parsing %s of project [%s]: %v", objectType, projectID, err)
+		}
+	}
+
+	if objects == nil {
+		objects = []map[string]interface{}{}
+	}
+
+	return objects, nil
+}
@@ -42,7 +42,7 @@ func (ch *ConfigurationHandler) GetConfig(ctx *gin.Context) {
 		if config, err := ch.getConfig(collection, projectID); err != nil {
 			mw.BadRequest(ctx, "get config error", err)
 		} else {
-			ctx.Data(http.StatusOK, jsonContentType, config)
+			ctx.Data(http.StatusOK, jsonContentType, ch.configurationsService.MaskSecrets(collection, config))
 		}
 	}
 }
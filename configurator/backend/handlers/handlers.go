@@ -10,8 +10,10 @@ import (
 )
 
 var (
-	ErrUserExists       = errors.New("User already exists")
-	errSSLNotConfigured = errors.New("SSL is not configured in Jitsu configuration")
+	ErrUserExists                     = errors.New("User already exists")
+	errSSLNotConfigured               = errors.New("SSL is not configured in Jitsu configuration")
+	errFirebaseMigrationNotConfigured = errors.New("Firebase users migration is not configured. Please configure auth.firebase_migration section")
+	errEmailsNotConfigured            = errors.New("Email service is not configured. Please configure email or smtp section")
 )
 
 type CreatedUser struct {
@@ -25,6 +27,13 @@ type SSOSession struct {
 	AccessToken string
 }
 
+//ImpersonationToken is a short-lived token for signing in as another user
+type ImpersonationToken struct {
+	Token     string
+	TokenType string
+	ExpiresAt time.Time
+}
+
 type Authorizator interface {
 	AuthorizationType() string
 	GetUserEmail(ctx context.Context, userID string) (string, error)
@@ -32,6 +41,8 @@ type Authorizator interface {
 	AutoSignUp(ctx context.Context, email string, callback *string) (userID string, err error)
 	Local() (LocalAuthorizator, error)
 	Cloud() (CloudAuthorizator, error)
+	//Impersonate issues a token which can't be refreshed. All requests with the token are marked with impersonatedBy
+	Impersonate(ctx context.Context, userID, impersonatedBy string, ttl time.Duration) (*ImpersonationToken, error)
 }
 
 type LocalAuthorizator interface {
@@ -53,6 +64,35 @@ type LocalAuthorizator interface {
 
 type CloudAuthorizator interface{}
 
+type UserMigrator interface {
+	MigrateUsers(ctx context.Context, dryRun bool) (*UserMigrationReport, error)
+}
+
+type UserMigrationReport struct {
+	DryRun                bool           `json:"dryRun"`
+	StartedAt             time.Time      `json:"startedAt"`
+	FinishedAt            time.Time      `json:"finishedAt"`
+	Total                 int            `json:"total"`
+	Created               int            `json:"created"`
+	Existing              int            `json:"existing"`
+	Skipped               int            `json:"skipped"`
+	Failed                int            `json:"failed"`
+	PasswordsImported     int            `json:"passwordsImported"`
+	PasswordResetRequired int            `json:"passwordResetRequired"`
+	ProjectLinks          int            `json:"projectLinks"`
+	Users                 []MigratedUser `json:"users"`
+}
+
+type MigratedUser struct {
+	SourceID string   `json:"sourceId"`
+	UserID   string   `json:"userId,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	Status   string   `json:"status"`
+	Password string   `json:"password,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
 type SSOProvider interface {
 	Name() string
 	AccessTokenTTL() time.Duration
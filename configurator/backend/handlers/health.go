@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/health"
+)
+
+//HealthHandler handles Kubernetes probes and load balancers health check requests
+type HealthHandler struct {
+	healthService *health.Service
+}
+
+//NewHealthHandler returns configured HealthHandler instance
+func NewHealthHandler(healthService *health.Service) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+//LiveHandler returns HTTP 200 while the process is able to serve requests. Dependencies aren't checked
+func (hh *HealthHandler) LiveHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, hh.healthService.Live())
+}
+
+//ReadyHandler returns per-dependency statuses. HTTP 503 is returned if the configurations storage is unavailable
+func (hh *HealthHandler) ReadyHandler(c *gin.Context) {
+	report := hh.healthService.Ready(c.Request.Context())
+	if !report.Ready() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
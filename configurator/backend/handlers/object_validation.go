@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/cors"
+	"github.com/jitsucom/jitsu/server/templates"
+)
+
+const (
+	destinationsObjectType = "destinations"
+	apiKeysObjectType      = "api_keys"
+)
+
+// ValidateObject returns err if the object contains invalid configuration which can be checked before saving.
+// Destinations table name expressions (see templates.IsExpressionTemplate) and API keys origins (see cors.ValidateRule)
+// are validated. Other fields are validated by Jitsu Server on configuration reload
+func ValidateObject(objectType string, object interface{}) error {
+	switch objectType {
+	case destinationsObjectType:
+		return validateDestination(object)
+	case apiKeysObjectType:
+		return validateAPIKey(object)
+	default:
+		return nil
+	}
+}
+
+func validateDestination(object interface{}) error {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	destination := struct {
+		Data struct {
+			TableName string `json:"tableName"`
+		} `json:"_formData"`
+	}{}
+	if err := json.Unmarshal(b, &destination); err != nil {
+		//malformed objects are handled by the storage
+		return nil
+	}
+
+	if tableName := destination.Data.TableName; templates.IsExpressionTemplate(tableName) {
+		if _, err := templates.NewExpressionTemplateExecutor(tableName); err != nil {
+			return fmt.Errorf("invalid table name expression: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func validateAPIKey(object interface{}) error {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	apiKey := struct {
+		Origins []string `json:"origins"`
+	}{}
+	if err := json.Unmarshal(b, &apiKey); err != nil {
+		//malformed objects are handled by the storage
+		return nil
+	}
+
+	for _, origin := range apiKey.Origins {
+		if err := cors.ValidateRule(origin); err != nil {
+			return fmt.Errorf("invalid origin: %v", err)
+		}
+	}
+
+	return nil
+}
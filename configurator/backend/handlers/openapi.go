@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jitsucom/jitsu/configurator/common"
 	"github.com/jitsucom/jitsu/configurator/destinations"
+	"github.com/jitsucom/jitsu/configurator/emails"
 	"github.com/jitsucom/jitsu/configurator/entities"
+	"github.com/jitsucom/jitsu/configurator/gitops"
 	"github.com/jitsucom/jitsu/configurator/jitsu"
 	mw "github.com/jitsucom/jitsu/configurator/middleware"
 	"github.com/jitsucom/jitsu/configurator/openapi"
@@ -46,6 +49,13 @@ log:
   path: <path to event logs directory>
 `
 	jsonContentType = "application/json"
+
+	etagHeader        = "ETag"
+	ifMatchHeader     = "If-Match"
+	ifNoneMatchHeader = "If-None-Match"
+
+	defaultImpersonationTTLMinutes = 30
+	maxImpersonationTTLMinutes     = 60
 )
 
 // stubS3Config is used in generate Jitsu Server yaml config
@@ -84,6 +94,9 @@ type OpenAPI struct {
 	JitsuService   *jitsu.Service
 	UpdateExecutor *ssl.UpdateExecutor
 	DefaultS3      *jadapters.S3Config
+	GitOps         *gitops.Service
+	UserMigrator   UserMigrator
+	Emails         *emails.Service
 }
 
 var t openapi.ServerInterface = &OpenAPI{}
@@ -105,6 +118,9 @@ func (oa *OpenAPI) GetApiKeysConfiguration(ctx *gin.Context) {
 				ServerSecret:   key.ServerSecret,
 				Origins:        key.Origins,
 				BatchPeriodMin: key.BatchPeriodMin,
+				Validation:     key.Validation,
+				Routing:        key.Routing,
+				IPHandling:     key.IPHandling,
 			}
 		}
 
@@ -231,6 +247,45 @@ func (oa *OpenAPI) EvaluateDestinationJSTransformationScript(ctx *gin.Context) {
 	}
 }
 
+func (oa *OpenAPI) DebugDestinationTransformation(ctx *gin.Context) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	var req openapi.AnyObject
+	projectID := ctx.Query("project_id")
+	if authority, err := mw.GetAuthority(ctx); err != nil {
+		mw.Unauthorized(ctx, err)
+	} else if err := ctx.BindJSON(&req); err != nil {
+		mw.InvalidInputJSON(ctx, err)
+	} else if projectID == "" {
+		mw.RequiredField(ctx, "project_id")
+	} else if authority.CheckPermission(ctx, projectID, entities.ViewConfigPermission) {
+		uid, ok := req.Get("uid")
+		if !ok {
+			mw.RequiredField(ctx, "uid")
+			return
+		}
+		uidParts := strings.Split(fmt.Sprint(uid), ".")
+		if len(uidParts) < 2 {
+			mw.BadRequest(ctx, "destination's uid must have project id part: '{project_id}.{destination_id}'", nil)
+			return
+		}
+		//additionally check project id of destination we work with
+		if !authority.CheckPermission(ctx, uidParts[0], entities.ViewConfigPermission) {
+			return
+		}
+
+		if reqData, err := req.MarshalJSON(); err != nil {
+			mw.BadRequest(ctx, "Failed to marshal request body to json", err)
+		} else if serverStatusCode, serverResponse, err := oa.JitsuService.DebugTransformation(reqData); err != nil {
+			mw.BadRequest(ctx, "Failed to get response from Jitsu server", err)
+		} else {
+			ctx.Data(serverStatusCode, jsonContentType, serverResponse)
+		}
+	}
+}
+
 func (oa *OpenAPI) TestDestinationConfiguration(ctx *gin.Context) {
 	if ctx.IsAborted() {
 		return
@@ -246,7 +301,13 @@ func (oa *OpenAPI) TestDestinationConfiguration(ctx *gin.Context) {
 	} else if serverStatusCode, serverResponse, err := oa.JitsuService.TestDestination(destinationData); err != nil {
 		mw.BadRequest(ctx, "Failed to get response from jitsu server", err)
 	} else if serverStatusCode == http.StatusOK {
-		ctx.JSON(http.StatusOK, &openapi.StatusResponse{Status: "Connection established"})
+		//Jitsu Server responds with diagnostics steps results
+		result := make(map[string]interface{})
+		if err := json.Unmarshal(serverResponse, &result); err != nil {
+			logging.Warnf("Failed to parse destination connection test result: %v", err)
+		}
+		result["status"] = "Connection established"
+		ctx.JSON(http.StatusOK, result)
 	} else {
 		ctx.Data(serverStatusCode, jsonContentType, serverResponse)
 	}
@@ -400,6 +461,24 @@ func (oa *OpenAPI) ReissueProjectSSLCertificates(ctx *gin.Context, params openap
 	}
 }
 
+func (oa *OpenAPI) GetSSLCertificatesStatus(ctx *gin.Context, params openapi.GetSSLCertificatesStatusParams) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	if updater := oa.UpdateExecutor; updater == nil {
+		mw.Unsupported(ctx, errSSLNotConfigured)
+	} else if authority, err := mw.GetAuthority(ctx); err != nil {
+		mw.Unauthorized(ctx, err)
+	} else if projectID := string(params.ProjectId); authority.CheckPermission(ctx, projectID, entities.ViewConfigPermission) {
+		if status, err := updater.GetStatus(projectID); err != nil {
+			mw.BadRequest(ctx, fmt.Sprintf("Error getting SSL status of project [%s]", projectID), err)
+		} else {
+			ctx.JSON(http.StatusOK, status)
+		}
+	}
+}
+
 func (oa *OpenAPI) ReissueAllConfiguredSSLCertificates(ctx *gin.Context, params openapi.ReissueAllConfiguredSSLCertificatesParams) {
 	if ctx.IsAborted() {
 		return
@@ -731,7 +810,7 @@ func (oa *OpenAPI) GetObjectsByProjectIdAndObjectType(ctx *gin.Context, projectI
 		} else if err != nil {
 			mw.BadRequest(ctx, fmt.Sprintf("failed to get objects for object type=[%s], projectID=[%s]", objectType, projectID), err)
 		} else if objectsPath := oa.Configurations.GetObjectArrayPathByObjectType(objectType); objectsPath == "" {
-			ctx.Data(http.StatusOK, jsonContentType, objectsData)
+			ctx.Data(http.StatusOK, jsonContentType, oa.Configurations.MaskSecrets(objectType, objectsData))
 		} else {
 			objectsValue := make(map[string]json.RawMessage)
 			if err := json.Unmarshal(objectsData, &objectsValue); err != nil {
@@ -739,7 +818,7 @@ func (oa *OpenAPI) GetObjectsByProjectIdAndObjectType(ctx *gin.Context, projectI
 			} else if objects, ok := objectsValue[objectsPath]; !ok {
 				mw.BadRequest(ctx, fmt.Sprintf("failed to read %s objects node for object type=[%s], projectID=[%s]", objectsPath, objectType, projectID), err)
 			} else {
-				ctx.Data(http.StatusOK, jsonContentType, objects)
+				ctx.Data(http.StatusOK, jsonContentType, oa.Configurations.MaskSecrets(objectType, objects))
 			}
 		}
 	}
@@ -756,10 +835,12 @@ func (oa *OpenAPI) CreateObjectInProject(ctx *gin.Context, projectID openapi.Pro
 	} else if projectID := string(projectID); authority.CheckPermission(ctx, projectID, entities.ModifyConfigPermission) {
 		if err := ctx.BindJSON(&req); err != nil {
 			mw.InvalidInputJSON(ctx, err)
-		} else if newObject, err := oa.Configurations.CreateObjectWithLock(ctx, string(objectType), projectID, &req); err != nil {
-			mw.BadRequest(ctx, fmt.Sprintf("failed to create object [%s], project id=[%s]", objectType, projectID), err)
+		} else if err := ValidateObject(string(objectType), &req); err != nil {
+			mw.BadRequest(ctx, fmt.Sprintf("invalid object [%s], project id=[%s]", objectType, projectID), err)
+		} else if newObject, err := oa.Configurations.CreateObjectWithLock(ctx, string(objectType), projectID, &req, ctx.GetHeader(ifNoneMatchHeader) == "*"); err != nil {
+			objectError(ctx, fmt.Sprintf("failed to create object [%s], project id=[%s]", objectType, projectID), err)
 		} else {
-			ctx.Data(http.StatusOK, jsonContentType, newObject)
+			objectResponse(ctx, oa.Configurations.MaskSecrets(string(objectType), newObject))
 		}
 	}
 }
@@ -779,13 +860,14 @@ func (oa *OpenAPI) DeleteObjectByUid(ctx *gin.Context, projectID openapi.Project
 				IDFieldPath: oa.Configurations.GetObjectIDField(objectType),
 				Value:       objectUID,
 			},
-			Patch: nil,
+			Patch:   nil,
+			IfMatch: ctx.GetHeader(ifMatchHeader),
 		}
 
 		if deletedObject, err := oa.Configurations.DeleteObjectWithLock(ctx, objectType, projectID, payload); err != nil {
-			mw.BadRequest(ctx, fmt.Sprintf("failed to delete object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
+			objectError(ctx, fmt.Sprintf("failed to delete object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
 		} else {
-			ctx.Data(http.StatusOK, jsonContentType, deletedObject)
+			ctx.Data(http.StatusOK, jsonContentType, oa.Configurations.MaskSecrets(objectType, deletedObject))
 		}
 	}
 }
@@ -806,9 +888,9 @@ func (oa *OpenAPI) GetObjectByUid(ctx *gin.Context, projectID openapi.ProjectId,
 		}
 
 		if object, err := oa.Configurations.GetObjectWithLock(objectType, projectID, objectArrayPath, objectMeta); err != nil {
-			mw.BadRequest(ctx, fmt.Sprintf("failed to get object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
+			objectError(ctx, fmt.Sprintf("failed to get object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
 		} else {
-			ctx.Data(http.StatusOK, jsonContentType, object)
+			objectResponse(ctx, oa.Configurations.MaskSecrets(objectType, object))
 		}
 	}
 }
@@ -832,13 +914,16 @@ func (oa *OpenAPI) PatchObjectByUid(ctx *gin.Context, projectID openapi.ProjectI
 					IDFieldPath: oa.Configurations.GetObjectIDField(objectType),
 					Value:       objectUID,
 				},
-				Patch: req,
+				Patch:   req,
+				IfMatch: ctx.GetHeader(ifMatchHeader),
 			}
 
-			if newObject, err := oa.Configurations.PatchObjectWithLock(ctx, objectType, projectID, patch); err != nil {
-				mw.BadRequest(ctx, fmt.Sprintf("failed to patch object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
+			if err := ValidateObject(objectType, req); err != nil {
+				mw.BadRequest(ctx, fmt.Sprintf("invalid object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
+			} else if newObject, err := oa.Configurations.PatchObjectWithLock(ctx, objectType, projectID, patch); err != nil {
+				objectError(ctx, fmt.Sprintf("failed to patch object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
 			} else {
-				ctx.Data(http.StatusOK, jsonContentType, newObject)
+				objectResponse(ctx, oa.Configurations.MaskSecrets(objectType, newObject))
 			}
 		}
 	}
@@ -863,18 +948,131 @@ func (oa *OpenAPI) ReplaceObjectByUid(ctx *gin.Context, projectID openapi.Projec
 					IDFieldPath: oa.Configurations.GetObjectIDField(objectType),
 					Value:       objectUID,
 				},
-				Patch: req,
+				Patch:   req,
+				IfMatch: ctx.GetHeader(ifMatchHeader),
 			}
 
-			if newObject, err := oa.Configurations.ReplaceObjectWithLock(ctx, objectType, projectID, patch); err != nil {
-				mw.BadRequest(ctx, fmt.Sprintf("failed to patch object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
+			if err := ValidateObject(objectType, req); err != nil {
+				mw.BadRequest(ctx, fmt.Sprintf("invalid object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
+			} else if newObject, err := oa.Configurations.ReplaceObjectWithLock(ctx, objectType, projectID, patch); err != nil {
+				objectError(ctx, fmt.Sprintf("failed to replace object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
 			} else {
-				ctx.Data(http.StatusOK, jsonContentType, newObject)
+				objectResponse(ctx, oa.Configurations.MaskSecrets(objectType, newObject))
 			}
 		}
 	}
 }
 
+func (oa *OpenAPI) GetObjectVersions(ctx *gin.Context, projectID openapi.ProjectId, objectType openapi.ObjectType, objectUID openapi.ObjectUid) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	if authority, err := mw.GetAuthority(ctx); err != nil {
+		mw.Unauthorized(ctx, err)
+	} else if projectID := string(projectID); authority.CheckPermission(ctx, projectID, entities.ViewConfigPermission) {
+		objectType, objectUID := string(objectType), string(objectUID)
+		if versions, err := oa.Configurations.GetObjectVersionsWithLock(objectType, projectID, objectUID); err != nil {
+			mw.BadRequest(ctx, fmt.Sprintf("failed to get versions of object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
+		} else {
+			for _, version := range versions {
+				version.Object = oa.Configurations.MaskSecrets(objectType, version.Object)
+			}
+			ctx.JSON(http.StatusOK, versions)
+		}
+	}
+}
+
+func (oa *OpenAPI) GetObjectVersionsDiff(ctx *gin.Context, projectID openapi.ProjectId, objectType openapi.ObjectType, objectUID openapi.ObjectUid, params openapi.GetObjectVersionsDiffParams) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	var to int64
+	if params.To != nil {
+		to = *params.To
+	}
+
+	if authority, err := mw.GetAuthority(ctx); err != nil {
+		mw.Unauthorized(ctx, err)
+	} else if projectID := string(projectID); authority.CheckPermission(ctx, projectID, entities.ViewConfigPermission) {
+		objectType, objectUID := string(objectType), string(objectUID)
+		if diff, err := oa.Configurations.GetObjectVersionsDiffWithLock(objectType, projectID, objectUID, params.From, to); err != nil {
+			mw.BadRequest(ctx, fmt.Sprintf("failed to get versions diff of object [%s] in project [%s], id=[%s]", objectType, projectID, objectUID), err)
+		} else {
+			ctx.JSON(http.StatusOK, diff)
+		}
+	}
+}
+
+func (oa *OpenAPI) RollbackObjectToVersion(ctx *gin.Context, projectID openapi.ProjectId, objectType openapi.ObjectType, objectUID openapi.ObjectUid, version openapi.Version) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	if authority, err := mw.GetAuthority(ctx); err != nil {
+		mw.Unauthorized(ctx, err)
+	} else if projectID := string(projectID); authority.CheckPermission(ctx, projectID, entities.ModifyConfigPermission) {
+		objectType, objectUID := string(objectType), string(objectUID)
+		objectMeta := &storages.ObjectMeta{
+			IDFieldPath: oa.Configurations.GetObjectIDField(objectType),
+			Value:       objectUID,
+		}
+
+		if object, err := oa.Configurations.RollbackObjectWithLock(ctx, objectType, projectID, objectMeta, int64(version)); err != nil {
+			mw.BadRequest(ctx, fmt.Sprintf("failed to roll back object [%s] in project [%s], id=[%s] to version %d", objectType, projectID, objectUID, version), err)
+		} else {
+			ctx.Data(http.StatusOK, jsonContentType, oa.Configurations.MaskSecrets(objectType, object))
+		}
+	}
+}
+
+func (oa *OpenAPI) ExportProjectConfiguration(ctx *gin.Context, projectID openapi.ProjectId, params openapi.ExportProjectConfigurationParams) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	format := gitops.JSONFormat
+	if params.Format != nil {
+		format = *params.Format
+	}
+
+	if authority, err := mw.GetAuthority(ctx); err != nil {
+		mw.Unauthorized(ctx, err)
+	} else if projectID := string(projectID); authority.CheckPermission(ctx, projectID, entities.ViewConfigPermission) {
+		if bundle, err := oa.GitOps.Export(projectID); err != nil {
+			mw.BadRequest(ctx, fmt.Sprintf("failed to export configuration of project [%s]", projectID), err)
+		} else if data, err := bundle.Marshal(format); err != nil {
+			mw.BadRequest(ctx, fmt.Sprintf("failed to serialize configuration of project [%s]", projectID), err)
+		} else if format == gitops.YAMLFormat {
+			ctx.Data(http.StatusOK, "application/x-yaml", data)
+		} else {
+			ctx.Data(http.StatusOK, jsonContentType, data)
+		}
+	}
+}
+
+func (oa *OpenAPI) ApplyProjectConfiguration(ctx *gin.Context, projectID openapi.ProjectId, params openapi.ApplyProjectConfigurationParams) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	dryRun := params.DryRun != nil && *params.DryRun
+	if authority, err := mw.GetAuthority(ctx); err != nil {
+		mw.Unauthorized(ctx, err)
+	} else if projectID := string(projectID); authority.CheckPermission(ctx, projectID, entities.ModifyConfigPermission) {
+		if payload, err := ctx.GetRawData(); err != nil {
+			mw.BadRequest(ctx, "failed to read request body", err)
+		} else if bundle, err := gitops.ParseBundle(payload); err != nil {
+			mw.InvalidInputJSON(ctx, err)
+		} else if plan, err := oa.GitOps.Apply(ctx, projectID, bundle, dryRun); err != nil {
+			mw.BadRequest(ctx, fmt.Sprintf("failed to apply configuration to project [%s]", projectID), err)
+		} else {
+			ctx.JSON(http.StatusOK, plan)
+		}
+	}
+}
+
 func (oa *OpenAPI) GetProjectSettings(ctx *gin.Context, projectID openapi.ProjectId) {
 	if ctx.IsAborted() {
 		return
@@ -1277,6 +1475,114 @@ func (oa *OpenAPI) UpdateUser(ctx *gin.Context, userID openapi.UserId) {
 	}
 }
 
+func (oa *OpenAPI) MigrateFirebaseUsers(ctx *gin.Context, params openapi.MigrateFirebaseUsersParams) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	if oa.UserMigrator == nil {
+		mw.Unsupported(ctx, errFirebaseMigrationNotConfigured)
+		return
+	}
+
+	dryRun := params.DryRun != nil && *params.DryRun
+	if report, err := oa.UserMigrator.MigrateUsers(ctx, dryRun); err != nil {
+		mw.BadRequest(ctx, "Failed to migrate Firebase users", err)
+	} else {
+		ctx.JSON(http.StatusOK, report)
+	}
+}
+
+func (oa *OpenAPI) GetEmailFailures(ctx *gin.Context) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	if !oa.Emails.IsConfigured() {
+		mw.Unsupported(ctx, errEmailsNotConfigured)
+	} else {
+		ctx.JSON(http.StatusOK, map[string]interface{}{"failures": oa.Emails.Failures()})
+	}
+}
+
+func (oa *OpenAPI) ImpersonateUser(ctx *gin.Context, userID openapi.UserId) {
+	if ctx.IsAborted() {
+		return
+	}
+
+	authority, err := mw.GetAuthority(ctx)
+	if err != nil {
+		mw.Unauthorized(ctx, err)
+		return
+	} else if authority.ImpersonatedBy != "" {
+		mw.Forbidden(ctx, "Impersonation isn't allowed in impersonated session")
+		return
+	} else if !authority.IsAdmin {
+		mw.Forbidden(ctx, "Only platform admins can impersonate users")
+		return
+	}
+
+	impersonatedBy := "server"
+	if user, err := authority.User(); err == nil {
+		impersonatedBy = user.Id
+	}
+
+	userId := string(userID)
+	if userId == impersonatedBy {
+		mw.BadRequest(ctx, "Admin can't impersonate themselves", nil)
+		return
+	}
+
+	var req openapi.ImpersonateUserRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		mw.InvalidInputJSON(ctx, err)
+		return
+	} else if strings.TrimSpace(req.Reason) == "" {
+		mw.RequiredField(ctx, "reason")
+		return
+	}
+
+	ttlMinutes := defaultImpersonationTTLMinutes
+	if req.TtlMinutes != nil {
+		ttlMinutes = *req.TtlMinutes
+	}
+
+	if ttlMinutes <= 0 || ttlMinutes > maxImpersonationTTLMinutes {
+		mw.BadRequest(ctx, fmt.Sprintf("ttlMinutes must be in range [1, %d]", maxImpersonationTTLMinutes), nil)
+		return
+	}
+
+	email, err := oa.Authorizator.GetUserEmail(ctx, userId)
+	if err != nil {
+		mw.BadRequest(ctx, "get user email failed", err)
+		return
+	}
+
+	token, err := oa.Authorizator.Impersonate(ctx, userId, impersonatedBy, time.Duration(ttlMinutes)*time.Minute)
+	if err != nil {
+		mw.BadRequest(ctx, "Failed to impersonate user", err)
+		return
+	}
+
+	expiresAt := timestamp.ToISOFormat(token.ExpiresAt)
+	oa.Configurations.AddImpersonationAudit(ctx, userId, map[string]interface{}{
+		"email":     email,
+		"reason":    req.Reason,
+		"tokenType": token.TokenType,
+		"expiresAt": expiresAt,
+	})
+	logging.Infof("[impersonation] User [%s] is impersonated by [%s] till %s. Reason: %s", userId, impersonatedBy, expiresAt, req.Reason)
+
+	ctx.JSON(http.StatusOK, openapi.ImpersonationResponse{
+		Token:          token.Token,
+		TokenType:      token.TokenType,
+		UserId:         userId,
+		Email:          email,
+		ImpersonatedBy: impersonatedBy,
+		ExpiresAt:      expiresAt,
+	})
+}
+
 func (oa *OpenAPI) PurgeAudit(ctx *gin.Context, params openapi.PurgeAuditParams) {
 	if ctx.IsAborted() {
 		return
@@ -1311,3 +1617,23 @@ func (oa *OpenAPI) makeUserPlatformAdmin(ctx *gin.Context, userEmail string, aut
 		return nil
 	}
 }
+
+// objectResponse writes the object with its ETag which can be used in If-Match header of the next modification
+func objectResponse(ctx *gin.Context, object []byte) {
+	ctx.Header(etagHeader, storages.ObjectETag(object))
+	ctx.Data(http.StatusOK, jsonContentType, object)
+}
+
+// objectError writes objects API error with the status code by the error kind
+func objectError(ctx *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, storages.ErrObjectNotFound), errors.Is(err, storages.ErrConfigurationNotFound):
+		mw.Error(ctx, http.StatusNotFound, msg, err)
+	case errors.Is(err, storages.ErrObjectAlreadyExists):
+		mw.Error(ctx, http.StatusConflict, msg, err)
+	case errors.Is(err, storages.ErrPreconditionFailed):
+		mw.Error(ctx, http.StatusPreconditionFailed, msg, err)
+	default:
+		mw.BadRequest(ctx, msg, err)
+	}
+}
@@ -13,12 +13,20 @@ import (
 type ProxyHandler struct {
 	jitsuService *jitsu.Service
 	decorators   map[string]jitsu.APIDecorator
+	streams      map[string]bool
 }
 
-func NewProxyHandler(jitsuService *jitsu.Service, decorators map[string]jitsu.APIDecorator) *ProxyHandler {
+// NewProxyHandler returns ProxyHandler. Responses of streams paths are proxied as they come (e.g. Server-Sent Events)
+func NewProxyHandler(jitsuService *jitsu.Service, decorators map[string]jitsu.APIDecorator, streams ...string) *ProxyHandler {
+	streamsSet := make(map[string]bool, len(streams))
+	for _, stream := range streams {
+		streamsSet[stream] = true
+	}
+
 	return &ProxyHandler{
 		jitsuService: jitsuService,
 		decorators:   decorators,
+		streams:      streamsSet,
 	}
 }
 
@@ -36,6 +44,10 @@ func (ph *ProxyHandler) Handler(ctx *gin.Context) {
 	} else if authority.CheckPermission(ctx, projectID, entities.ViewConfigPermission) {
 		if req, err := ph.getJitsuRequest(ctx); err != nil {
 			mw.BadRequest(ctx, "Failed to create proxy request to Jitsu server", err)
+		} else if ph.streams[ctx.Request.URL.Path] {
+			if err := ph.jitsuService.ProxyStream(ctx.Request.Context(), req, ctx.Writer); err != nil {
+				mw.BadRequest(ctx, "Failed to proxy stream from Jitsu server", err)
+			}
 		} else if serverStatusCode, serverResponse, err := ph.jitsuService.ProxySend(req); err != nil {
 			mw.BadRequest(ctx, "Failed to proxy request to Jitsu server", err)
 		} else {
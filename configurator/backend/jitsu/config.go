@@ -2,6 +2,17 @@ package jitsu
 
 import (
 	"errors"
+	"fmt"
+)
+
+const (
+	//HTTP01Challenge - ACME challenge files are copied to all hosts (default)
+	HTTP01Challenge = "http-01"
+	//DNS01Challenge - ACME challenge TXT records are created via DNS provider API. Required for wildcard certificates
+	DNS01Challenge = "dns-01"
+
+	CloudflareDNSProvider = "cloudflare"
+	Route53DNSProvider    = "route53"
 )
 
 type SSHConfig struct {
@@ -33,7 +44,65 @@ type SSLConfig struct {
 	NginxConfigPath      string     `mapstructure:"nginx_conf_path"`
 	AcmeChallengePath    string     `mapstructure:"acme_challenge_path"`
 	ServerConfigTemplate string     `mapstructure:"server_config_template"`
-	Period               int        `mapstructure:"period"`
+	//Period is an interval in hours between certificates renewal checks. Checks aren't scheduled if it is 0
+	Period int `mapstructure:"period"`
+	//Challenge is http-01 (default) or dns-01
+	Challenge string     `mapstructure:"challenge"`
+	DNS       *DNSConfig `mapstructure:"dns"`
+	//RenewBeforeDays is an amount of days before the certificate expiration when it is reissued. Default value is 30
+	RenewBeforeDays int `mapstructure:"renew_before_days"`
+}
+
+//DNSConfig is a DNS provider configuration for dns-01 challenge
+type DNSConfig struct {
+	Provider string `mapstructure:"provider"`
+	//PropagationTimeoutSec is a max time of waiting TXT records on authoritative nameservers. Default value is 120
+	PropagationTimeoutSec int               `mapstructure:"propagation_timeout_sec"`
+	Cloudflare            *CloudflareConfig `mapstructure:"cloudflare"`
+	Route53               *Route53Config    `mapstructure:"route53"`
+}
+
+//CloudflareConfig is a Cloudflare API token with Zone.DNS edit permission
+type CloudflareConfig struct {
+	APIToken string `mapstructure:"api_token"`
+}
+
+//Route53Config is AWS credentials with route53:ChangeResourceRecordSets permission. If credentials aren't set,
+//default AWS credentials chain is used (env variables, shared credentials file, IAM role)
+type Route53Config struct {
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	Region          string `mapstructure:"region"`
+	//HostedZoneID is optional. If it isn't set, the zone is found by the domain name
+	HostedZoneID string `mapstructure:"hosted_zone_id"`
+}
+
+func (dc *DNSConfig) Validate() error {
+	if dc == nil {
+		return fmt.Errorf("jitsu.ssl.dns is required config object with %s challenge", DNS01Challenge)
+	}
+
+	switch dc.Provider {
+	case CloudflareDNSProvider:
+		if dc.Cloudflare == nil || dc.Cloudflare.APIToken == "" {
+			return errors.New("jitsu.ssl.dns.cloudflare.api_token is required parameter")
+		}
+	case Route53DNSProvider:
+		if dc.Route53 == nil {
+			return errors.New("jitsu.ssl.dns.route53 is required config object")
+		}
+		if (dc.Route53.AccessKeyID == "") != (dc.Route53.SecretAccessKey == "") {
+			return errors.New("jitsu.ssl.dns.route53.access_key_id and jitsu.ssl.dns.route53.secret_access_key must be set together")
+		}
+	default:
+		return fmt.Errorf("jitsu.ssl.dns.provider must be one of: %s, %s", CloudflareDNSProvider, Route53DNSProvider)
+	}
+
+	if dc.PropagationTimeoutSec < 0 {
+		return errors.New("jitsu.ssl.dns.propagation_timeout_sec must be positive")
+	}
+
+	return nil
 }
 
 func (sc *SSLConfig) Validate() error {
@@ -62,8 +131,21 @@ func (sc *SSLConfig) Validate() error {
 		return errors.New("jitsu.ssl.nginx_conf_path is required parameter")
 	}
 
-	if sc.AcmeChallengePath == "" {
-		return errors.New("jitsu.ssl.acme_challenge_path is required parameter")
+	switch sc.Challenge {
+	case "", HTTP01Challenge:
+		if sc.AcmeChallengePath == "" {
+			return errors.New("jitsu.ssl.acme_challenge_path is required parameter")
+		}
+	case DNS01Challenge:
+		if err := sc.DNS.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("jitsu.ssl.challenge must be one of: %s, %s", HTTP01Challenge, DNS01Challenge)
+	}
+
+	if sc.RenewBeforeDays < 0 || sc.RenewBeforeDays >= 90 {
+		return errors.New("jitsu.ssl.renew_before_days must be between 1 and 89 (Let's Encrypt certificates are valid for 90 days)")
 	}
 
 	if sc.ServerConfigTemplate == "" {
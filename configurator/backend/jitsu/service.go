@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/logging"
 	smdlwr "github.com/jitsucom/jitsu/server/middleware"
 	"io"
 	"io/ioutil"
@@ -25,6 +26,8 @@ type Service struct {
 	adminToken     string
 
 	client *http.Client
+	//streamClient is used for long-living streaming responses. It doesn't have timeout
+	streamClient *http.Client
 }
 
 //NewService returns Service and runs goroutine for cluster monitoring
@@ -33,6 +36,7 @@ func NewService(balancerAPIURL, adminToken string) *Service {
 		balancerAPIURL: strings.TrimSuffix(balancerAPIURL, "/"),
 		adminToken:     adminToken,
 		client:         &http.Client{Timeout: 10 * time.Minute},
+		streamClient:   &http.Client{},
 	}
 }
 
@@ -60,11 +64,84 @@ func (s *Service) EvaluateExpression(reqB []byte) (int, []byte, error) {
 	})
 }
 
+//DebugTransformation runs transformation against sample events on Jitsu Server
+func (s *Service) DebugTransformation(reqB []byte) (int, []byte, error) {
+	return s.ProxySend(&Request{
+		Method: http.MethodPost,
+		URN:    "/api/v1/transformations/debug",
+		Body:   bytes.NewBuffer(reqB),
+	})
+}
+
+//Ping checks that Jitsu Server responds to /ping requests
+func (s *Service) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.balancerAPIURL+"/ping", nil)
+	if err != nil {
+		return fmt.Errorf("Error creating request: %v", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error getting response: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Jitsu Server responded with HTTP %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 //ProxySend sends HTTP request to balancerAPIURL with input parameters
 func (s *Service) ProxySend(req *Request) (int, []byte, error) {
 	return s.sendReq(req.Method, s.balancerAPIURL+"/"+strings.TrimPrefix(req.URN, "/"), req.Body)
 }
 
+//ProxyStream sends HTTP request to balancerAPIURL with input parameters and copies the response to the writer as it comes
+//(e.g. Server-Sent Events). Streaming stops when the ctx is done or the server closes the response.
+//returns error only if the response hasn't been started
+func (s *Service) ProxyStream(ctx context.Context, req *Request, w http.ResponseWriter) error {
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, s.balancerAPIURL+"/"+strings.TrimPrefix(req.URN, "/"), req.Body)
+	if err != nil {
+		return fmt.Errorf("Error creating request: %v", err)
+	}
+
+	httpReq.Header.Add(smdlwr.AdminTokenKey, s.adminToken)
+	resp, err := s.streamClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("Error getting response: %v", err)
+	}
+	defer resp.Body.Close()
+
+	for _, header := range []string{"Content-Type", "Cache-Control", "X-Accel-Buffering"} {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return nil
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				logging.Warnf("Error reading streaming response from %s: %v", req.URN, err)
+			}
+			return nil
+		}
+	}
+}
+
 //sendReq sends HTTP request
 func (s *Service) sendReq(method, requestURL string, body io.Reader) (int, []byte, error) {
 	req, err := http.NewRequest(method, requestURL, body)
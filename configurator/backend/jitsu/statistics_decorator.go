@@ -1,6 +1,8 @@
 package jitsu
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jitsucom/jitsu/configurator/middleware"
 )
@@ -20,3 +22,27 @@ func (sd *StatisticsDecorator) Decorate(c *gin.Context) (*Request, error) {
 
 	return BuildRequestWithQueryParams(c, map[string]string{"destination_id": projectID + "." + destinationID}), nil
 }
+
+//RollupsDecorator prefixes comma separated destination_id values with the project ID
+type RollupsDecorator struct{}
+
+func NewRollupsDecorator() *RollupsDecorator {
+	return &RollupsDecorator{}
+}
+
+func (rd *RollupsDecorator) Decorate(c *gin.Context) (*Request, error) {
+	projectID := c.Query(middleware.ProjectIDQuery)
+	destinationIDs := c.Query("destination_id")
+	if destinationIDs == "" {
+		return BuildRequest(c), nil
+	}
+
+	var projectDestinationIDs []string
+	for _, destinationID := range strings.Split(destinationIDs, ",") {
+		if destinationID = strings.TrimSpace(destinationID); destinationID != "" {
+			projectDestinationIDs = append(projectDestinationIDs, projectID+"."+destinationID)
+		}
+	}
+
+	return BuildRequestWithQueryParams(c, map[string]string{"destination_id": strings.Join(projectDestinationIDs, ",")}), nil
+}
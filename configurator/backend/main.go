@@ -19,21 +19,25 @@ import (
 	"github.com/gin-gonic/contrib/static"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/go-acme/lego/challenge"
 	"github.com/go-playground/validator/v10"
 	"github.com/jitsucom/jitsu/configurator/appconfig"
 	"github.com/jitsucom/jitsu/configurator/authorization"
 	"github.com/jitsucom/jitsu/configurator/cors"
 	"github.com/jitsucom/jitsu/configurator/destinations"
 	"github.com/jitsucom/jitsu/configurator/emails"
+	"github.com/jitsucom/jitsu/configurator/gitops"
 	"github.com/jitsucom/jitsu/configurator/handlers"
 	"github.com/jitsucom/jitsu/configurator/jitsu"
 	"github.com/jitsucom/jitsu/configurator/middleware"
 	"github.com/jitsucom/jitsu/configurator/openapi"
+	"github.com/jitsucom/jitsu/configurator/secrets"
 	"github.com/jitsucom/jitsu/configurator/ssh"
 	"github.com/jitsucom/jitsu/configurator/ssl"
 	"github.com/jitsucom/jitsu/configurator/storages"
 	enadapters "github.com/jitsucom/jitsu/server/adapters"
 	config "github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/health"
 	"github.com/jitsucom/jitsu/server/locks"
 	locksinmemory "github.com/jitsucom/jitsu/server/locks/inmemory"
 	locksredis "github.com/jitsucom/jitsu/server/locks/redis"
@@ -186,13 +190,26 @@ func main() {
 		appconfig.Instance.ScheduleLastClosing(redisPool)
 	}
 
-	configurationsService := storages.NewConfigurationsService(configurationsStorage, defaultPostgres, lockFactory)
+	var secretsService *secrets.Service
+	if viper.IsSet("secrets") {
+		secretsConfig := &secrets.Config{}
+		if err := viper.UnmarshalKey("secrets", secretsConfig); err != nil {
+			logging.Fatalf("Error parsing 'secrets' config: %v", err)
+		}
+
+		if secretsService, err = secrets.NewService(secretsConfig); err != nil {
+			logging.Fatalf("Error creating secrets service: %v", err)
+		}
+		logging.Infof("Secret configuration fields are encrypted with %s provider", secretsConfig.Provider)
+	}
+
+	configurationsService := storages.NewConfigurationsService(configurationsStorage, defaultPostgres, lockFactory, secretsService)
 	if err != nil {
 		logging.Fatalf("Error creating configurations service: %v", err)
 	}
 	appconfig.Instance.ScheduleClosing(configurationsService)
 
-	//** SMTP (email service) **
+	//** Email service (SMTP, SendGrid or SES) **
 	emailsService, err := newEmailService(viper.GetViper())
 	if err != nil {
 		logging.Fatalf("Error creating emails service: %v", err)
@@ -203,6 +220,18 @@ func main() {
 		logging.Fatalf("Error creating authorization service: %v", err)
 	}
 	appconfig.Instance.ScheduleClosing(authorizator)
+
+	//** Firebase users migration **
+	var userMigrator handlers.UserMigrator
+	if viper.IsSet("auth.firebase_migration") {
+		firebaseMigration, err := newFirebaseMigration(ctx, viper.GetViper(), authorizator, emailsService, configurationsService)
+		if err != nil {
+			logging.Fatalf("Error creating Firebase users migration: %v", err)
+		}
+		appconfig.Instance.ScheduleClosing(firebaseMigration)
+		userMigrator = firebaseMigration
+	}
+
 	ssoProvider := newSSOProvider(viper.GetViper())
 	appconfig.Instance.ScheduleClosing(ssoProvider)
 
@@ -236,18 +265,64 @@ func main() {
 			logging.Fatalf("Error creating SSH client: %v", err)
 		}
 
-		customDomainProcessor, err := ssl.NewCertificateService(sshClient, jitsuConfig.SSL.Hosts, configurationsService, jitsuConfig.SSL.ServerConfigTemplate, jitsuConfig.SSL.NginxConfigPath, jitsuConfig.SSL.AcmeChallengePath)
+		var dnsProvider challenge.Provider
+		if jitsuConfig.SSL.Challenge == jitsu.DNS01Challenge {
+			dnsProvider, err = ssl.NewDNSProvider(jitsuConfig.SSL.DNS)
+			if err != nil {
+				logging.Fatalf("Error creating SSL DNS-01 challenge provider: %v", err)
+			}
+		}
+
+		customDomainProcessor, err := ssl.NewCertificateService(sshClient, jitsuConfig.SSL.Hosts, configurationsService, jitsuConfig.SSL.ServerConfigTemplate, jitsuConfig.SSL.NginxConfigPath, jitsuConfig.SSL.AcmeChallengePath, dnsProvider)
+		if err != nil {
+			logging.Fatalf("Error creating SSL certificate service: %v", err)
+		}
 
-		sslUpdateExecutor = ssl.NewSSLUpdateExecutor(customDomainProcessor, jitsuConfig.SSL.Hosts, jitsuConfig.SSL.SSH.User, jitsuConfig.SSL.SSH.PrivateKeyPath, jitsuConfig.CName, jitsuConfig.SSL.CertificatePath, jitsuConfig.SSL.PKPath, jitsuConfig.SSL.AcmeChallengePath)
+		sslUpdateExecutor = ssl.NewSSLUpdateExecutor(customDomainProcessor, jitsuConfig.SSL.Hosts, jitsuConfig.SSL.SSH.User, jitsuConfig.SSL.SSH.PrivateKeyPath, jitsuConfig.CName, jitsuConfig.SSL.CertificatePath, jitsuConfig.SSL.PKPath, jitsuConfig.SSL.AcmeChallengePath, jitsuConfig.SSL.RenewBeforeDays)
+		if jitsuConfig.SSL.Period > 0 {
+			sslUpdateExecutor.Schedule(time.Duration(jitsuConfig.SSL.Period) * time.Hour)
+		}
 	} else {
-		customDomainProcessor, _ := ssl.NewCertificateService(nil, nil, configurationsService, "", "", "")
-		sslUpdateExecutor = ssl.NewSSLUpdateExecutor(customDomainProcessor, nil, "", "", "", "", "", "")
+		customDomainProcessor, _ := ssl.NewCertificateService(nil, nil, configurationsService, "", "", "", nil)
+		sslUpdateExecutor = ssl.NewSSLUpdateExecutor(customDomainProcessor, nil, "", "", "", "", "", "", 0)
 	}
 
 	cors.Init(viper.GetString("server.domain"), viper.GetStringSlice("server.allowed_domains"))
 
+	//health and readiness checks for Kubernetes probes and load balancers
+	healthService := health.NewService(time.Duration(viper.GetInt("server.health.timeout_ms")) * time.Millisecond)
+	healthService.Register(health.Check{
+		Name:     "configurations_storage",
+		Critical: true,
+		Func: func(ctx context.Context) (interface{}, error) {
+			return nil, configurationsStorage.Ping(ctx)
+		},
+	})
+	healthService.Register(health.Check{
+		Name:     "jitsu_server",
+		CacheTTL: time.Duration(viper.GetInt("server.health.cache_ttl_sec")) * time.Second,
+		Func: func(ctx context.Context) (interface{}, error) {
+			return nil, jitsuService.Ping(ctx)
+		},
+	})
+
+	//** GitOps (declarative configuration sync) **
+	gitopsService := gitops.NewService(configurationsService, handlers.ValidateObject)
+	if viper.IsSet("gitops") {
+		reconcilerConfig := &gitops.ReconcilerConfig{}
+		if err := viper.UnmarshalKey("gitops", reconcilerConfig); err != nil {
+			logging.Fatalf("Error parsing 'gitops' config: %v", err)
+		}
+
+		reconciler, err := gitops.NewReconciler(gitopsService, reconcilerConfig)
+		if err != nil {
+			logging.Fatalf("Error creating gitops reconciler: %v", err)
+		}
+		appconfig.Instance.ScheduleClosing(reconciler)
+	}
+
 	router := SetupRouter(jitsuService, configurationsService,
-		authorizator, ssoProvider, s3Config, sslUpdateExecutor, emailsService)
+		authorizator, ssoProvider, s3Config, sslUpdateExecutor, emailsService, healthService, gitopsService, userMigrator)
 
 	notifications.ServerStart(runtime.GetInfo())
 	logging.Info("⚙️  Started configurator: " + appconfig.Instance.Authority)
@@ -262,13 +337,25 @@ func main() {
 }
 
 func newEmailService(vp *viper.Viper) (*emails.Service, error) {
-	var config emails.SMTPConfiguration
-	if data := os.Getenv("JITSU_SMTP_CONFIG"); data != "" {
-		if err := json.Unmarshal([]byte(data), &config); err != nil {
+	var config *emails.Configuration
+	if data := os.Getenv("JITSU_EMAIL_CONFIG"); data != "" {
+		config = &emails.Configuration{}
+		if err := json.Unmarshal([]byte(data), config); err != nil {
+			return nil, errors.Wrap(err, "unmarshal email config")
+		}
+	} else if vp.IsSet("email") {
+		config = &emails.Configuration{}
+		if err := vp.UnmarshalKey("email", config); err != nil {
+			return nil, errors.Wrap(err, "unmarshal email config")
+		}
+	} else if data := os.Getenv("JITSU_SMTP_CONFIG"); data != "" {
+		smtp := &emails.SMTPConfiguration{}
+		if err := json.Unmarshal([]byte(data), smtp); err != nil {
 			return nil, errors.Wrap(err, "unmarshal smtp config")
 		}
+		config = emails.FromSMTP(smtp)
 	} else if vp.IsSet("smtp.host") {
-		config = emails.SMTPConfiguration{
+		config = emails.FromSMTP(&emails.SMTPConfiguration{
 			Host:      viper.GetString("smtp.host"),
 			Port:      viper.GetInt("smtp.port"),
 			User:      viper.GetString("smtp.user"),
@@ -276,16 +363,16 @@ func newEmailService(vp *viper.Viper) (*emails.Service, error) {
 			From:      viper.GetString("smtp.from"),
 			Signature: viper.GetString("smtp.signature"),
 			ReplyTo:   viper.GetString("smtp.reply_to"),
-		}
+		})
 	} else {
 		return nil, nil
 	}
 
 	if err := config.Validate(); err != nil {
-		return nil, errors.Wrap(err, "validate smtp config")
+		return nil, errors.Wrap(err, "validate email config")
 	}
 
-	return emails.NewService(&config)
+	return emails.NewService(config)
 }
 
 func newSSOProvider(vp *viper.Viper) handlers.SSOProvider {
@@ -342,34 +429,74 @@ func newAuthorizator(ctx context.Context, vp *viper.Viper, mailSender authorizat
 			MailSender:      mailSender,
 		})
 	} else if vp.IsSet("auth.redis.host") {
-		host := vp.GetString("auth.redis.host")
-		if host == "" {
-			return nil, errors.New("auth.redis.host is required")
+		return newRedisAuthorization(vp, mailSender)
+	} else {
+		return nil, errors.New("Unknown 'auth' section type. Supported: firebase, redis")
+	}
+}
+
+func newRedisAuthorization(vp *viper.Viper, mailSender authorization.MailSender) (*authorization.Redis, error) {
+	host := vp.GetString("auth.redis.host")
+	if host == "" {
+		return nil, errors.New("auth.redis.host is required")
+	}
+
+	port := vp.GetInt("auth.redis.port")
+	sentinelMaster := vp.GetString("auth.redis.sentinel_master_name")
+	redisPassword := vp.GetString("auth.redis.password")
+	redisDatabase := vp.GetInt("auth.redis.database")
+
+	tlsSkipVerify := vp.GetBool("auth.redis.tls_skip_verify")
+	redisPoolFactory := meta.NewRedisPoolFactory(host, port, redisPassword, redisDatabase, tlsSkipVerify, sentinelMaster).
+		WithConfiguration(vp.Sub("auth.redis"))
+	if defaultPort, ok := redisPoolFactory.CheckAndSetDefaultPort(); ok {
+		logging.Infof("auth.redis.port isn't configured. Will be used default: %d", defaultPort)
+	}
+
+	var firebasePasswordHash *authorization.FirebasePasswordHash
+	if vp.IsSet("auth.redis.firebase_password_hash") {
+		firebasePasswordHash = &authorization.FirebasePasswordHash{}
+		if err := vp.UnmarshalKey("auth.redis.firebase_password_hash", firebasePasswordHash); err != nil {
+			return nil, errors.Wrap(err, "parse auth.redis.firebase_password_hash")
 		}
+	}
 
-		port := vp.GetInt("auth.redis.port")
-		sentinelMaster := vp.GetString("auth.redis.sentinel_master_name")
-		redisPassword := vp.GetString("auth.redis.password")
-		redisDatabase := vp.GetInt("auth.redis.database")
+	return authorization.NewRedis(authorization.RedisInit{
+		PoolFactory:          redisPoolFactory,
+		MailSender:           mailSender,
+		FirebasePasswordHash: firebasePasswordHash,
+	})
+}
 
-		tlsSkipVerify := vp.GetBool("auth.redis.tls_skip_verify")
-		redisPoolFactory := meta.NewRedisPoolFactory(host, port, redisPassword, redisDatabase, tlsSkipVerify, sentinelMaster)
-		if defaultPort, ok := redisPoolFactory.CheckAndSetDefaultPort(); ok {
-			logging.Infof("auth.redis.port isn't configured. Will be used default: %d", defaultPort)
+//newFirebaseMigration returns Firebase users migration into Redis authorization (auth.redis section).
+//The migration is available while Firebase authorization is still in use as well
+func newFirebaseMigration(ctx context.Context, vp *viper.Viper, authorizator Authorizator, mailSender authorization.MailSender,
+	configurationsService *storages.ConfigurationsService) (*authorization.FirebaseMigration, error) {
+	target, ok := authorizator.(*authorization.Redis)
+	if !ok {
+		if !vp.IsSet("auth.redis.host") {
+			return nil, errors.New("auth.redis section is required as the migration target")
 		}
 
-		return authorization.NewRedis(authorization.RedisInit{
-			PoolFactory: redisPoolFactory,
-			MailSender:  mailSender,
-		})
-	} else {
-		return nil, errors.New("Unknown 'auth' section type. Supported: firebase, redis")
+		var err error
+		if target, err = newRedisAuthorization(vp, mailSender); err != nil {
+			return nil, errors.Wrap(err, "create redis authorization")
+		}
+		appconfig.Instance.ScheduleClosing(target)
 	}
+
+	return authorization.NewFirebaseMigration(ctx, authorization.FirebaseMigrationInit{
+		ProjectID:       vp.GetString("auth.firebase_migration.project_id"),
+		CredentialsFile: vp.GetString("auth.firebase_migration.credentials_file"),
+		ImportUserInfo:  vp.GetBool("auth.firebase_migration.import_user_info"),
+		Target:          target,
+		Configurations:  configurationsService,
+	})
 }
 
 func SetupRouter(jitsuService *jitsu.Service, configurationsService *storages.ConfigurationsService,
 	authorizator Authorizator, ssoProvider handlers.SSOProvider, defaultS3 *enadapters.S3Config, sslUpdateExecutor *ssl.UpdateExecutor,
-	emailService *emails.Service) *gin.Engine {
+	emailService *emails.Service, healthService *health.Service, gitopsService *gitops.Service, userMigrator handlers.UserMigrator) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 
@@ -411,6 +538,10 @@ func SetupRouter(jitsuService *jitsu.Service, configurationsService *storages.Co
 		c.String(http.StatusOK, "pong")
 	})
 
+	healthHandler := handlers.NewHealthHandler(healthService)
+	router.GET("/health/live", healthHandler.LiveHandler)
+	router.GET("/health/ready", healthHandler.ReadyHandler)
+
 	ssoAuthHandler := &handlers.SSOAuthHandler{
 		Authorizator:   authorizator,
 		Provider:       ssoProvider,
@@ -424,9 +555,11 @@ func SetupRouter(jitsuService *jitsu.Service, configurationsService *storages.Co
 	proxyHandler := handlers.NewProxyHandler(jitsuService, map[string]jitsu.APIDecorator{
 		//write here custom decorators for a certain HTTP URN paths
 		"/proxy/api/v1/events/cache":        jitsu.NewEventsCacheDecorator(configurationsService).Decorate,
+		"/proxy/api/v1/events/tail":         jitsu.NewEventsCacheDecorator(configurationsService).Decorate,
 		"/proxy/api/v1/statistics":          jitsu.NewStatisticsDecorator().Decorate,
 		"/proxy/api/v1/statistics/detailed": jitsu.NewStatisticsDecorator().Decorate,
-	})
+		"/proxy/api/v1/statistics/rollups":  jitsu.NewRollupsDecorator().Decorate,
+	}, "/proxy/api/v1/events/tail")
 	router.Any("/proxy/*path", authenticatorMiddleware.ManagementWrapper(proxyHandler.Handler))
 
 	router.Any("/check_domain", authenticatorMiddleware.ManagementWrapper(func(c *gin.Context) {
@@ -463,6 +596,9 @@ func SetupRouter(jitsuService *jitsu.Service, configurationsService *storages.Co
 		JitsuService:   jitsuService,
 		UpdateExecutor: sslUpdateExecutor,
 		DefaultS3:      defaultS3,
+		GitOps:         gitopsService,
+		UserMigrator:   userMigrator,
+		Emails:         emailService,
 	}
 
 	return openapi.RegisterHandlersWithOptions(router, openAPIHandler, openapi.GinServerOptions{
@@ -485,7 +621,8 @@ func initializeStorage(vp *viper.Viper) (storages.ConfigurationsStorage, *meta.R
 		tlsSkipVerify := vp.GetBool("storage.redis.tls_skip_verify")
 		sentinelMaster := vp.GetString("storage.redis.sentinel_master_name")
 
-		redisPoolFactory := meta.NewRedisPoolFactory(host, port, password, database, tlsSkipVerify, sentinelMaster)
+		redisPoolFactory := meta.NewRedisPoolFactory(host, port, password, database, tlsSkipVerify, sentinelMaster).
+			WithConfiguration(vp.Sub("storage.redis"))
 		if defaultPort, ok := redisPoolFactory.CheckAndSetDefaultPort(); ok {
 			logging.Infof("storage.redis.port isn't configured. Will be used default: %d", defaultPort)
 		}
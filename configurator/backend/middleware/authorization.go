@@ -19,21 +19,25 @@ var (
 
 const (
 	authorityKey = "__authority"
+
+	ImpersonatedByHeader = "X-Jitsu-Impersonated-By"
 )
 
 type Authorization struct {
-	User    openapi.UserBasicInfo
-	IsAdmin bool
+	User           openapi.UserBasicInfo
+	IsAdmin        bool
+	ImpersonatedBy string
 }
 type ProjectIDBody struct {
 	ProjectID string `json:"project_id"`
 }
 
 type Authority struct {
-	Token    string
-	IsAdmin  bool
-	Projects map[string]*entities.ProjectPermissions
-	user     *openapi.UserBasicInfo
+	Token          string
+	IsAdmin        bool
+	Projects       map[string]*entities.ProjectPermissions
+	ImpersonatedBy string
+	user           *openapi.UserBasicInfo
 }
 
 func (a *Authority) Allow(projectID string) bool {
@@ -123,9 +127,10 @@ func (i *AuthorizationInterceptor) Intercept(ctx *gin.Context) {
 		return
 	} else {
 		authority = Authority{
-			Token:   token,
-			IsAdmin: auth.IsAdmin,
-			user:    &auth.User,
+			Token:          token,
+			IsAdmin:        auth.IsAdmin,
+			ImpersonatedBy: auth.ImpersonatedBy,
+			user:           &auth.User,
 		}
 	}
 
@@ -137,6 +142,13 @@ func (i *AuthorizationInterceptor) Intercept(ctx *gin.Context) {
 		}
 	}
 
+	//impersonated session is never an admin one
+	if authority.ImpersonatedBy != "" {
+		authority.IsAdmin = false
+		ctx.Header(ImpersonatedByHeader, authority.ImpersonatedBy)
+		logging.Infof("[impersonation] %s %s request by [%s] as user [%s]", ctx.Request.Method, ctx.Request.URL.Path, authority.ImpersonatedBy, authority.user.Id)
+	}
+
 	authority.Projects = make(map[string]*entities.ProjectPermissions)
 
 	if managementScope {
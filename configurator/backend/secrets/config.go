@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	StaticProvider = "static"
+	AWSKMSProvider = "aws_kms"
+	GCPKMSProvider = "gcp_kms"
+	VaultProvider  = "vault"
+)
+
+// Config is a configuration of secret fields encryption
+type Config struct {
+	Provider string        `mapstructure:"provider" json:"provider,omitempty" yaml:"provider,omitempty"`
+	Static   *StaticConfig `mapstructure:"static" json:"static,omitempty" yaml:"static,omitempty"`
+	AWSKMS   *AWSKMSConfig `mapstructure:"aws_kms" json:"aws_kms,omitempty" yaml:"aws_kms,omitempty"`
+	GCPKMS   *GCPKMSConfig `mapstructure:"gcp_kms" json:"gcp_kms,omitempty" yaml:"gcp_kms,omitempty"`
+	Vault    *VaultConfig  `mapstructure:"vault" json:"vault,omitempty" yaml:"vault,omitempty"`
+	// Fields are case-insensitive substrings of secret field names. Default: DefaultFields
+	Fields []string `mapstructure:"fields" json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// StaticConfig is a configuration of the static master key: base64 encoded 32 bytes
+type StaticConfig struct {
+	Key string `mapstructure:"key" json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// AWSKMSConfig is a configuration of AWS KMS master key. If credentials are empty, default AWS credentials chain is used
+type AWSKMSConfig struct {
+	KeyID           string `mapstructure:"key_id" json:"key_id,omitempty" yaml:"key_id,omitempty"`
+	Region          string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`
+	AccessKeyID     string `mapstructure:"access_key_id" json:"access_key_id,omitempty" yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `mapstructure:"secret_access_key" json:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"`
+}
+
+// GCPKMSConfig is a configuration of Google Cloud KMS master key. KeyName format:
+// projects/<project>/locations/<location>/keyRings/<key ring>/cryptoKeys/<key>
+// If credentials JSON is empty, Google Application Default Credentials are used
+type GCPKMSConfig struct {
+	KeyName     string `mapstructure:"key_name" json:"key_name,omitempty" yaml:"key_name,omitempty"`
+	Credentials string `mapstructure:"credentials" json:"credentials,omitempty" yaml:"credentials,omitempty"`
+}
+
+// VaultConfig is a configuration of HashiCorp Vault transit secrets engine master key
+type VaultConfig struct {
+	Address   string `mapstructure:"address" json:"address,omitempty" yaml:"address,omitempty"`
+	Token     string `mapstructure:"token" json:"token,omitempty" yaml:"token,omitempty"`
+	Namespace string `mapstructure:"namespace" json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Mount is a transit engine mount path. Default: transit
+	Mount string `mapstructure:"mount" json:"mount,omitempty" yaml:"mount,omitempty"`
+	Key   string `mapstructure:"key" json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	switch c.Provider {
+	case StaticProvider:
+		if c.Static == nil || c.Static.Key == "" {
+			return errors.New("secrets.static.key is required")
+		}
+	case AWSKMSProvider:
+		if c.AWSKMS == nil || c.AWSKMS.KeyID == "" {
+			return errors.New("secrets.aws_kms.key_id is required")
+		}
+	case GCPKMSProvider:
+		if c.GCPKMS == nil || c.GCPKMS.KeyName == "" {
+			return errors.New("secrets.gcp_kms.key_name is required")
+		}
+	case VaultProvider:
+		if c.Vault == nil || c.Vault.Address == "" || c.Vault.Token == "" || c.Vault.Key == "" {
+			return errors.New("secrets.vault.address, secrets.vault.token and secrets.vault.key are required")
+		}
+	default:
+		return fmt.Errorf("unknown secrets provider: %q. Supported: %s, %s, %s, %s", c.Provider, StaticProvider, AWSKMSProvider, GCPKMSProvider, VaultProvider)
+	}
+
+	return nil
+}
@@ -0,0 +1,315 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcpKMSScope     = "https://www.googleapis.com/auth/cloudkms"
+	gcpKMSURL       = "https://cloudkms.googleapis.com/v1/"
+	defaultMount    = "transit"
+	providerTimeout = 10 * time.Second
+)
+
+// KeyProvider encrypts and decrypts data keys with a master key. With KMS and Vault providers the master key never leaves the provider
+type KeyProvider interface {
+	Name() string
+	EncryptKey(ctx context.Context, key []byte) ([]byte, error)
+	DecryptKey(ctx context.Context, encryptedKey []byte) ([]byte, error)
+}
+
+// NewKeyProvider returns configured KeyProvider
+func NewKeyProvider(config *Config) (KeyProvider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Provider {
+	case StaticProvider:
+		return newStaticProvider(config.Static)
+	case AWSKMSProvider:
+		return newAWSKMSProvider(config.AWSKMS)
+	case GCPKMSProvider:
+		return newGCPKMSProvider(config.GCPKMS)
+	default:
+		return newVaultProvider(config.Vault), nil
+	}
+}
+
+// staticProvider encrypts data keys with AES-256-GCM master key from the configuration
+type staticProvider struct {
+	aead cipher.AEAD
+}
+
+func newStaticProvider(config *StaticConfig) (*staticProvider, error) {
+	key, err := base64.StdEncoding.DecodeString(config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding secrets.static.key from base64: %v", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secrets.static.key must be 32 bytes long. Got: %d", len(key))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &staticProvider{aead: aead}, nil
+}
+
+func (sp *staticProvider) Name() string {
+	return StaticProvider
+}
+
+func (sp *staticProvider) EncryptKey(_ context.Context, key []byte) ([]byte, error) {
+	return seal(sp.aead, key)
+}
+
+func (sp *staticProvider) DecryptKey(_ context.Context, encryptedKey []byte) ([]byte, error) {
+	return open(sp.aead, encryptedKey)
+}
+
+// awsKMSProvider encrypts data keys with AWS KMS key
+type awsKMSProvider struct {
+	keyID  string
+	client *kms.KMS
+}
+
+func newAWSKMSProvider(config *AWSKMSConfig) (*awsKMSProvider, error) {
+	awsConfig := aws.NewConfig()
+	if config.Region != "" {
+		awsConfig.WithRegion(config.Region)
+	}
+	if config.AccessKeyID != "" {
+		awsConfig.WithCredentials(credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, ""))
+	}
+
+	kmsSession, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	return &awsKMSProvider{keyID: config.KeyID, client: kms.New(kmsSession)}, nil
+}
+
+func (ap *awsKMSProvider) Name() string {
+	return AWSKMSProvider
+}
+
+func (ap *awsKMSProvider) EncryptKey(ctx context.Context, key []byte) ([]byte, error) {
+	output, err := ap.client.EncryptWithContext(ctx, &kms.EncryptInput{KeyId: aws.String(ap.keyID), Plaintext: key})
+	if err != nil {
+		return nil, fmt.Errorf("AWS KMS encrypt: %v", err)
+	}
+
+	return output.CiphertextBlob, nil
+}
+
+func (ap *awsKMSProvider) DecryptKey(ctx context.Context, encryptedKey []byte) ([]byte, error) {
+	output, err := ap.client.DecryptWithContext(ctx, &kms.DecryptInput{KeyId: aws.String(ap.keyID), CiphertextBlob: encryptedKey})
+	if err != nil {
+		return nil, fmt.Errorf("AWS KMS decrypt: %v", err)
+	}
+
+	return output.Plaintext, nil
+}
+
+// gcpKMSProvider encrypts data keys with Google Cloud KMS key via REST API
+type gcpKMSProvider struct {
+	keyName string
+	client  *http.Client
+}
+
+func newGCPKMSProvider(config *GCPKMSConfig) (*gcpKMSProvider, error) {
+	ctx := context.Background()
+	var googleCredentials *google.Credentials
+	var err error
+	if config.Credentials != "" {
+		googleCredentials, err = google.CredentialsFromJSON(ctx, []byte(config.Credentials), gcpKMSScope)
+	} else {
+		googleCredentials, err = google.FindDefaultCredentials(ctx, gcpKMSScope)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting Google Cloud credentials: %v", err)
+	}
+
+	client := oauth2.NewClient(ctx, googleCredentials.TokenSource)
+	client.Timeout = providerTimeout
+	return &gcpKMSProvider{keyName: config.KeyName, client: client}, nil
+}
+
+func (gp *gcpKMSProvider) Name() string {
+	return GCPKMSProvider
+}
+
+func (gp *gcpKMSProvider) EncryptKey(ctx context.Context, key []byte) ([]byte, error) {
+	response := &struct {
+		Ciphertext string `json:"ciphertext"`
+	}{}
+	if err := postJSON(ctx, gp.client, gcpKMSURL+gp.keyName+":encrypt", nil,
+		map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)}, response); err != nil {
+		return nil, fmt.Errorf("Google Cloud KMS encrypt: %v", err)
+	}
+
+	return base64.StdEncoding.DecodeString(response.Ciphertext)
+}
+
+func (gp *gcpKMSProvider) DecryptKey(ctx context.Context, encryptedKey []byte) ([]byte, error) {
+	response := &struct {
+		Plaintext string `json:"plaintext"`
+	}{}
+	if err := postJSON(ctx, gp.client, gcpKMSURL+gp.keyName+":decrypt", nil,
+		map[string]string{"ciphertext": base64.StdEncoding.EncodeToString(encryptedKey)}, response); err != nil {
+		return nil, fmt.Errorf("Google Cloud KMS decrypt: %v", err)
+	}
+
+	return base64.StdEncoding.DecodeString(response.Plaintext)
+}
+
+// vaultProvider encrypts data keys with HashiCorp Vault transit secrets engine
+type vaultProvider struct {
+	baseURL string
+	key     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newVaultProvider(config *VaultConfig) *vaultProvider {
+	mount := config.Mount
+	if mount == "" {
+		mount = defaultMount
+	}
+
+	headers := map[string]string{"X-Vault-Token": config.Token}
+	if config.Namespace != "" {
+		headers["X-Vault-Namespace"] = config.Namespace
+	}
+
+	return &vaultProvider{
+		baseURL: fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(config.Address, "/"), strings.Trim(mount, "/")),
+		key:     config.Key,
+		headers: headers,
+		client:  &http.Client{Timeout: providerTimeout},
+	}
+}
+
+func (vp *vaultProvider) Name() string {
+	return VaultProvider
+}
+
+// keyURL returns transit operation URL: <address>/v1/<mount>/<operation>/<key>
+func (vp *vaultProvider) keyURL(operation string) string {
+	return vp.baseURL + "/" + operation + "/" + vp.key
+}
+
+func (vp *vaultProvider) EncryptKey(ctx context.Context, key []byte) ([]byte, error) {
+	response := &struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}{}
+	if err := postJSON(ctx, vp.client, vp.keyURL("encrypt"), vp.headers,
+		map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)}, response); err != nil {
+		return nil, fmt.Errorf("Vault encrypt: %v", err)
+	}
+
+	//vault ciphertext is a string like vault:v1:<base64>
+	return []byte(response.Data.Ciphertext), nil
+}
+
+func (vp *vaultProvider) DecryptKey(ctx context.Context, encryptedKey []byte) ([]byte, error) {
+	response := &struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}{}
+	if err := postJSON(ctx, vp.client, vp.keyURL("decrypt"), vp.headers,
+		map[string]string{"ciphertext": string(encryptedKey)}, response); err != nil {
+		return nil, fmt.Errorf("Vault decrypt: %v", err)
+	}
+
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+// postJSON sends JSON request and unmarshals JSON response into result
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(data))
+	}
+
+	return json.Unmarshal(data, result)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce + ciphertext
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts nonce + ciphertext
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
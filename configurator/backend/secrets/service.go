@@ -0,0 +1,377 @@
+package secrets
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Mask replaces secret values in API responses. Masked values in requests are replaced with the stored ones
+	Mask = "********"
+
+	// encryptedPrefix is a prefix of encrypted values: jitsu:enc:v1:<provider>:<base64 encrypted data key>:<base64 nonce and ciphertext>
+	encryptedPrefix = "jitsu:enc:v1:"
+	dataKeyTTL      = time.Hour
+)
+
+// identityFields are used for matching objects in arrays (e.g. destinations by _uid)
+var identityFields = []string{"_uid", "_id", "id", "uid"}
+
+// DefaultFields are case-insensitive substrings of secret field names
+var DefaultFields = []string{"password", "secret", "token", "apikey", "api_key", "jsonkey", "json_key", "private_key", "gcskey", "gskey", "credentials_json"}
+
+// Service encrypts secret fields of configuration objects with envelope encryption: every value is encrypted with AES-256-GCM
+// data key and the data key is encrypted with the KeyProvider master key and stored along with the value.
+// Data keys are rotated every hour, decrypted data keys are cached.
+// All methods of nil Service return values as is, so encryption and masking are disabled if secrets aren't configured
+type Service struct {
+	provider KeyProvider
+	fields   []string
+
+	mutex            sync.Mutex
+	dataKey          cipher.AEAD
+	encryptedDataKey string
+	dataKeyCreatedAt time.Time
+	decryptedKeys    map[string]cipher.AEAD
+}
+
+// NewService returns configured Service
+func NewService(config *Config) (*Service, error) {
+	provider, err := NewKeyProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := config.Fields
+	if len(fields) == 0 {
+		fields = DefaultFields
+	}
+	lowerFields := make([]string, len(fields))
+	for i, field := range fields {
+		lowerFields[i] = strings.ToLower(field)
+	}
+
+	return &Service{provider: provider, fields: lowerFields, decryptedKeys: map[string]cipher.AEAD{}}, nil
+}
+
+// IsSecretField returns true if the field name contains one of secret fields substrings
+func (s *Service) IsSecretField(name string) bool {
+	if s == nil {
+		return false
+	}
+
+	name = strings.ToLower(name)
+	for _, field := range s.fields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Encrypt encrypts secret fields values in the JSON value in place. Already encrypted values aren't changed
+func (s *Service) Encrypt(value interface{}) (interface{}, error) {
+	if s == nil {
+		return value, nil
+	}
+
+	return s.transform(value, s.encryptValue)
+}
+
+// Decrypt decrypts secret fields values in the JSON value in place. Plain values (e.g. stored before encryption
+// has been configured) aren't changed
+func (s *Service) Decrypt(value interface{}) (interface{}, error) {
+	if s == nil {
+		return value, nil
+	}
+
+	return s.transform(value, s.decryptValue)
+}
+
+// Mask replaces non-empty secret fields values in the JSON value with Mask in place
+func (s *Service) Mask(value interface{}) interface{} {
+	if s == nil {
+		return value
+	}
+
+	masked, _ := s.transform(value, func(value interface{}) (interface{}, error) {
+		if value == "" {
+			return value, nil
+		}
+		return Mask, nil
+	})
+	return masked
+}
+
+// Unmask replaces masked secret fields values in the JSON value with values from the same paths of the current value in place.
+// Masked fields which don't exist in the current value are removed
+func (s *Service) Unmask(value, current interface{}) interface{} {
+	if s == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		currentObject, _ := current.(map[string]interface{})
+		for key, node := range v {
+			if node == Mask && s.IsSecretField(key) {
+				if currentNode, ok := currentObject[key]; ok {
+					v[key] = currentNode
+				} else {
+					delete(v, key)
+				}
+				continue
+			}
+			v[key] = s.Unmask(node, currentObject[key])
+		}
+	case []interface{}:
+		currentArray, _ := current.([]interface{})
+		for i, node := range v {
+			v[i] = s.Unmask(node, currentArrayNode(node, currentArray, i))
+		}
+	}
+
+	return value
+}
+
+// currentArrayNode returns the element of the current array with the same identity field value as the node
+// (objects might be reordered or removed). Elements without identity fields are matched by position
+func currentArrayNode(node interface{}, currentArray []interface{}, position int) interface{} {
+	if object, ok := node.(map[string]interface{}); ok {
+		for _, idField := range identityFields {
+			id, ok := object[idField]
+			if !ok {
+				continue
+			}
+
+			for _, currentNode := range currentArray {
+				if currentObject, ok := currentNode.(map[string]interface{}); ok && currentObject[idField] == id {
+					return currentObject
+				}
+			}
+			return nil
+		}
+	}
+
+	if position < len(currentArray) {
+		return currentArray[position]
+	}
+
+	return nil
+}
+
+// EncryptJSON encrypts secret fields in serialized JSON
+func (s *Service) EncryptJSON(data []byte) ([]byte, error) {
+	if s == nil || len(data) == 0 {
+		return data, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("error parsing JSON for encryption: %v", err)
+	}
+
+	encrypted, err := s.Encrypt(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(encrypted, "", "    ")
+}
+
+// DecryptJSON decrypts secret fields in serialized JSON
+func (s *Service) DecryptJSON(data []byte) ([]byte, error) {
+	if s == nil || len(data) == 0 {
+		return data, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("error parsing JSON for decryption: %v", err)
+	}
+
+	decrypted, err := s.Decrypt(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(decrypted)
+}
+
+// MaskJSON masks secret fields in serialized JSON. Returns data as is if it isn't a JSON
+func (s *Service) MaskJSON(data []byte) []byte {
+	if s == nil || len(data) == 0 {
+		return data
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+
+	masked, err := json.Marshal(s.Mask(value))
+	if err != nil {
+		return data
+	}
+
+	return masked
+}
+
+// transform applies f to non-nil secret fields values. Objects and arrays are traversed recursively
+func (s *Service) transform(value interface{}, f func(value interface{}) (interface{}, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, node := range v {
+			var err error
+			if node != nil && s.IsSecretField(key) {
+				node, err = f(node)
+			} else {
+				node, err = s.transform(node, f)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			v[key] = node
+		}
+	case []interface{}:
+		for i, node := range v {
+			node, err := s.transform(node, f)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			v[i] = node
+		}
+	}
+
+	return value, nil
+}
+
+func (s *Service) encryptValue(value interface{}) (interface{}, error) {
+	if str, ok := value.(string); ok && (strings.HasPrefix(str, encryptedPrefix) || str == Mask) {
+		return value, nil
+	}
+
+	//secret value might be an object (e.g. Google service account key)
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, encryptedDataKey, err := s.currentDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(aead, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedPrefix + s.provider.Name() + ":" + encryptedDataKey + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (s *Service) decryptValue(value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok || !strings.HasPrefix(str, encryptedPrefix) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(str, encryptedPrefix), ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	if parts[0] != s.provider.Name() {
+		return nil, fmt.Errorf("value has been encrypted with %s provider, but %s provider is configured", parts[0], s.provider.Name())
+	}
+
+	aead, err := s.dataKeyFor(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("error decoding encrypted value: %v", err)
+	}
+
+	plaintext, err := open(aead, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting value: %v", err)
+	}
+
+	var decrypted interface{}
+	if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+		return nil, fmt.Errorf("error parsing decrypted value: %v", err)
+	}
+
+	return decrypted, nil
+}
+
+// currentDataKey returns the data key for encryption and its base64 encrypted form. The key is generated on the first call
+// and rotated every dataKeyTTL
+func (s *Service) currentDataKey() (cipher.AEAD, string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.dataKey != nil && time.Since(s.dataKeyCreatedAt) < dataKeyTTL {
+		return s.dataKey, s.encryptedDataKey, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, "", fmt.Errorf("error generating data key: %v", err)
+	}
+
+	encryptedKey, err := s.provider.EncryptKey(context.Background(), key)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encrypting data key: %v", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.dataKey = aead
+	s.encryptedDataKey = base64.StdEncoding.EncodeToString(encryptedKey)
+	s.dataKeyCreatedAt = time.Now()
+	s.decryptedKeys[s.encryptedDataKey] = aead
+	return s.dataKey, s.encryptedDataKey, nil
+}
+
+// dataKeyFor returns decrypted data key by its base64 encrypted form
+func (s *Service) dataKeyFor(encryptedDataKey string) (cipher.AEAD, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if aead, ok := s.decryptedKeys[encryptedDataKey]; ok {
+		return aead, nil
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(encryptedDataKey)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding data key: %v", err)
+	}
+
+	key, err := s.provider.DecryptKey(context.Background(), encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data key: %v", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.decryptedKeys[encryptedDataKey] = aead
+	return aead, nil
+}
@@ -0,0 +1,357 @@
+package ssl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/go-acme/lego/challenge"
+	"github.com/go-acme/lego/challenge/dns01"
+	"github.com/jitsucom/jitsu/configurator/jitsu"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	cloudflareAPIURL          = "https://api.cloudflare.com/client/v4"
+	defaultPropagationTimeout = 2 * time.Minute
+	propagationCheckInterval  = 4 * time.Second
+	challengeRecordTTL        = 120
+	defaultRoute53Region      = "us-east-1"
+)
+
+//NewDNSProvider returns DNS-01 challenge provider which creates TXT records via Cloudflare or Route53 API.
+//Challenge records might be delegated: if _acme-challenge.<domain> is a CNAME, the record is created in the CNAME target zone
+func NewDNSProvider(config *jitsu.DNSConfig) (challenge.Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	timeout := defaultPropagationTimeout
+	if config.PropagationTimeoutSec > 0 {
+		timeout = time.Duration(config.PropagationTimeoutSec) * time.Second
+	}
+
+	var api dnsRecordsAPI
+	switch config.Provider {
+	case jitsu.CloudflareDNSProvider:
+		api = &cloudflareAPI{token: config.Cloudflare.APIToken, client: &http.Client{Timeout: 30 * time.Second}, zoneIDs: map[string]string{}}
+	case jitsu.Route53DNSProvider:
+		route53API, err := newRoute53API(config.Route53)
+		if err != nil {
+			return nil, err
+		}
+		api = route53API
+	}
+
+	return &dnsProvider{api: api, timeout: timeout, values: map[string][]string{}}, nil
+}
+
+//dnsRecordsAPI sets all TXT record values of fqdn in the zone. Empty values removes the record
+type dnsRecordsAPI interface {
+	setTXTRecord(zone, fqdn string, values []string) error
+}
+
+//dnsProvider is a lego DNS-01 challenge provider. It keeps values of challenge records because the certificate for
+//a domain and its wildcard requires two values of the same record
+type dnsProvider struct {
+	api     dnsRecordsAPI
+	timeout time.Duration
+
+	mutex  sync.Mutex
+	values map[string][]string
+}
+
+func (dp *dnsProvider) Present(domain, token, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	fqdn = resolveChallengeCName(fqdn)
+	zone, err := dns01.FindZoneByFqdn(fqdn)
+	if err != nil {
+		return fmt.Errorf("error finding DNS zone of [%s]: %v", fqdn, err)
+	}
+
+	dp.mutex.Lock()
+	defer dp.mutex.Unlock()
+
+	values := append(dp.values[fqdn], value)
+	logging.Infof("Creating [%s] domain challenge TXT record [%s] in zone [%s]", domain, fqdn, zone)
+	if err := dp.api.setTXTRecord(zone, fqdn, values); err != nil {
+		return fmt.Errorf("error creating TXT record [%s]: %v", fqdn, err)
+	}
+	dp.values[fqdn] = values
+	return nil
+}
+
+func (dp *dnsProvider) CleanUp(domain, token, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	fqdn = resolveChallengeCName(fqdn)
+	zone, err := dns01.FindZoneByFqdn(fqdn)
+	if err != nil {
+		return fmt.Errorf("error finding DNS zone of [%s]: %v", fqdn, err)
+	}
+
+	dp.mutex.Lock()
+	defer dp.mutex.Unlock()
+
+	var values []string
+	for _, v := range dp.values[fqdn] {
+		if v != value {
+			values = append(values, v)
+		}
+	}
+	if err := dp.api.setTXTRecord(zone, fqdn, values); err != nil {
+		return fmt.Errorf("error removing TXT record [%s]: %v", fqdn, err)
+	}
+
+	if len(values) == 0 {
+		delete(dp.values, fqdn)
+	} else {
+		dp.values[fqdn] = values
+	}
+	return nil
+}
+
+//Timeout returns max time of waiting the record on authoritative nameservers
+func (dp *dnsProvider) Timeout() (time.Duration, time.Duration) {
+	return dp.timeout, propagationCheckInterval
+}
+
+//resolveChallengeCName returns CNAME target of the challenge record (if delegated) or fqdn
+func resolveChallengeCName(fqdn string) string {
+	cname, err := net.LookupCNAME(fqdn)
+	if err != nil || cname == "" {
+		return fqdn
+	}
+	return dns01.ToFqdn(cname)
+}
+
+//cloudflareAPI manages TXT records via Cloudflare API v4 with API token
+type cloudflareAPI struct {
+	token  string
+	client *http.Client
+
+	//zoneIDs are zone name -> Cloudflare zone ID
+	zoneIDs map[string]string
+}
+
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+func (ca *cloudflareAPI) setTXTRecord(zone, fqdn string, values []string) error {
+	zoneID, err := ca.zoneID(zone)
+	if err != nil {
+		return err
+	}
+
+	name := dns01.UnFqdn(fqdn)
+	existing := []*cloudflareRecord{}
+	if err := ca.do(http.MethodGet, fmt.Sprintf("/zones/%s/dns_records?type=TXT&name=%s", zoneID, url.QueryEscape(name)), nil, &existing); err != nil {
+		return err
+	}
+
+	required := map[string]bool{}
+	for _, value := range values {
+		required[value] = true
+	}
+	for _, record := range existing {
+		if required[record.Content] {
+			delete(required, record.Content)
+			continue
+		}
+		if err := ca.do(http.MethodDelete, fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, record.ID), nil, nil); err != nil {
+			return err
+		}
+	}
+	for value := range required {
+		record := &cloudflareRecord{Type: "TXT", Name: name, Content: value, TTL: challengeRecordTTL}
+		if err := ca.do(http.MethodPost, fmt.Sprintf("/zones/%s/dns_records", zoneID), record, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ca *cloudflareAPI) zoneID(zone string) (string, error) {
+	name := dns01.UnFqdn(zone)
+	if zoneID, ok := ca.zoneIDs[name]; ok {
+		return zoneID, nil
+	}
+
+	zones := []struct {
+		ID string `json:"id"`
+	}{}
+	if err := ca.do(http.MethodGet, "/zones?name="+url.QueryEscape(name), nil, &zones); err != nil {
+		return "", err
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("zone [%s] isn't found in Cloudflare account (or API token doesn't have access to it)", name)
+	}
+
+	ca.zoneIDs[name] = zones[0].ID
+	return zones[0].ID, nil
+}
+
+func (ca *cloudflareAPI) do(method, path string, body, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, cloudflareAPIURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+ca.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ca.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Cloudflare API request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading Cloudflare API response: %v", err)
+	}
+
+	response := &cloudflareResponse{}
+	if err := json.Unmarshal(respBody, response); err != nil {
+		return fmt.Errorf("error parsing Cloudflare API response [%d]: %s", resp.StatusCode, string(respBody))
+	}
+	if !response.Success {
+		var messages []string
+		for _, e := range response.Errors {
+			messages = append(messages, fmt.Sprintf("%d: %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("Cloudflare API error [%d]: %s", resp.StatusCode, strings.Join(messages, "; "))
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("error parsing Cloudflare API result: %v", err)
+		}
+	}
+	return nil
+}
+
+//route53API manages TXT records via AWS Route53 API
+type route53API struct {
+	client       *route53.Route53
+	hostedZoneID string
+
+	//zoneIDs are zone name -> hosted zone ID
+	zoneIDs map[string]string
+}
+
+func newRoute53API(config *jitsu.Route53Config) (*route53API, error) {
+	region := config.Region
+	if region == "" {
+		region = defaultRoute53Region
+	}
+
+	awsConfig := aws.NewConfig().WithRegion(region)
+	if config.AccessKeyID != "" {
+		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, ""))
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	return &route53API{client: route53.New(sess), hostedZoneID: config.HostedZoneID, zoneIDs: map[string]string{}}, nil
+}
+
+func (ra *route53API) setTXTRecord(zone, fqdn string, values []string) error {
+	zoneID, err := ra.zoneID(zone)
+	if err != nil {
+		return err
+	}
+
+	recordSet := &route53.ResourceRecordSet{Name: aws.String(fqdn), Type: aws.String(route53.RRTypeTxt), TTL: aws.Int64(challengeRecordTTL)}
+	action := route53.ChangeActionUpsert
+	if len(values) == 0 {
+		//DELETE requires the current record set
+		current, err := ra.client.ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+			HostedZoneId:    aws.String(zoneID),
+			StartRecordName: aws.String(fqdn),
+			StartRecordType: aws.String(route53.RRTypeTxt),
+			MaxItems:        aws.String("1"),
+		})
+		if err != nil {
+			return err
+		}
+		if len(current.ResourceRecordSets) == 0 || aws.StringValue(current.ResourceRecordSets[0].Name) != fqdn ||
+			aws.StringValue(current.ResourceRecordSets[0].Type) != route53.RRTypeTxt {
+			return nil
+		}
+		recordSet = current.ResourceRecordSets[0]
+		action = route53.ChangeActionDelete
+	} else {
+		for _, value := range values {
+			recordSet.ResourceRecords = append(recordSet.ResourceRecords, &route53.ResourceRecord{Value: aws.String(`"` + value + `"`)})
+		}
+	}
+
+	_, err = ra.client.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch: &route53.ChangeBatch{
+			Comment: aws.String("Managed by Jitsu: ACME DNS-01 challenge"),
+			Changes: []*route53.Change{{Action: aws.String(action), ResourceRecordSet: recordSet}},
+		},
+	})
+	return err
+}
+
+func (ra *route53API) zoneID(zone string) (string, error) {
+	if ra.hostedZoneID != "" {
+		return ra.hostedZoneID, nil
+	}
+	if zoneID, ok := ra.zoneIDs[zone]; ok {
+		return zoneID, nil
+	}
+
+	zones, err := ra.client.ListHostedZonesByName(&route53.ListHostedZonesByNameInput{DNSName: aws.String(dns01.UnFqdn(zone))})
+	if err != nil {
+		return "", err
+	}
+	for _, hostedZone := range zones.HostedZones {
+		if aws.StringValue(hostedZone.Name) == zone && (hostedZone.Config == nil || !aws.BoolValue(hostedZone.Config.PrivateZone)) {
+			zoneID := strings.TrimPrefix(aws.StringValue(hostedZone.Id), "/hostedzone/")
+			ra.zoneIDs[zone] = zoneID
+			return zoneID, nil
+		}
+	}
+
+	return "", fmt.Errorf("public hosted zone [%s] isn't found in Route53", zone)
+}
@@ -14,10 +14,12 @@ import (
 
 	"github.com/go-acme/lego/certcrypto"
 	"github.com/go-acme/lego/certificate"
+	"github.com/go-acme/lego/challenge"
 	"github.com/go-acme/lego/lego"
 	"github.com/go-acme/lego/registration"
 	"github.com/jitsucom/jitsu/configurator/entities"
 	"github.com/jitsucom/jitsu/configurator/files"
+	"github.com/jitsucom/jitsu/configurator/jitsu"
 	"github.com/jitsucom/jitsu/configurator/ssh"
 	"github.com/jitsucom/jitsu/configurator/storages"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -39,6 +41,8 @@ type CertificateService struct {
 	serverConfigTemplate  *template.Template
 	nginxConfigPath       string
 	acmeChallengePath     string
+	//dnsProvider is used for dns-01 challenge. If it is nil, http-01 challenge is used
+	dnsProvider challenge.Provider
 }
 
 type EnUser struct {
@@ -84,7 +88,27 @@ func (p *MultipleServersProvider) CleanUp(domain, token, keyAuth string) error {
 	return nil
 }
 
-func (s *CertificateService) ExecuteHTTP01Challenge(domains []string) ([]byte, []byte, error) {
+//ChallengeType returns the configured ACME challenge type
+func (s *CertificateService) ChallengeType() string {
+	if s.dnsProvider != nil {
+		return jitsu.DNS01Challenge
+	}
+	return jitsu.HTTP01Challenge
+}
+
+//SupportsWildcards returns true if wildcard certificates can be issued (only with dns-01 challenge)
+func (s *CertificateService) SupportsWildcards() bool {
+	return s.dnsProvider != nil
+}
+
+//ObtainCertificate executes the configured challenge and returns the certificate bundle and the private key
+func (s *CertificateService) ObtainCertificate(domains []string) ([]byte, []byte, error) {
+	for _, domain := range domains {
+		if isWildcard(domain) && !s.SupportsWildcards() {
+			return nil, nil, fmt.Errorf("wildcard domain [%s] requires %s challenge", domain, jitsu.DNS01Challenge)
+		}
+	}
+
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		return nil, nil, err
@@ -93,8 +117,6 @@ func (s *CertificateService) ExecuteHTTP01Challenge(domains []string) ([]byte, [
 		Email: email,
 		key:   privateKey,
 	}
-	http01Provider := &MultipleServersProvider{SshClient: s.sshClient, TargetHosts: s.enHosts, HostChallengeDirectory: defaultHTTP01Location, AcmeChallengePath: s.acmeChallengePath}
-
 	config := lego.NewConfig(&myUser)
 	config.CADirURL = certificationServer
 	config.Certificate.KeyType = certcrypto.RSA2048
@@ -107,7 +129,11 @@ func (s *CertificateService) ExecuteHTTP01Challenge(domains []string) ([]byte, [
 		return nil, nil, err
 	}
 	myUser.Registration = reg
-	err = client.Challenge.SetHTTP01Provider(http01Provider)
+	if s.dnsProvider != nil {
+		err = client.Challenge.SetDNS01Provider(s.dnsProvider)
+	} else {
+		err = client.Challenge.SetHTTP01Provider(&MultipleServersProvider{SshClient: s.sshClient, TargetHosts: s.enHosts, HostChallengeDirectory: defaultHTTP01Location, AcmeChallengePath: s.acmeChallengePath})
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -156,7 +182,7 @@ func (s *CertificateService) UploadCertificate(certificatePath string, privateKe
 	return nil
 }
 
-func NewCertificateService(sshClient *ssh.ClientWrapper, enHosts []string, configurationsService *storages.ConfigurationsService, serverConfigTemplatePath string, nginxSSLConfigPath string, acmeChallengePath string) (*CertificateService, error) {
+func NewCertificateService(sshClient *ssh.ClientWrapper, enHosts []string, configurationsService *storages.ConfigurationsService, serverConfigTemplatePath string, nginxSSLConfigPath string, acmeChallengePath string, dnsProvider challenge.Provider) (*CertificateService, error) {
 	if configurationsService == nil {
 		return nil, fmt.Errorf("failed to create custom domain processor: [firebase] must not be nil")
 	}
@@ -168,7 +194,7 @@ func NewCertificateService(sshClient *ssh.ClientWrapper, enHosts []string, confi
 			return nil, err
 		}
 	}
-	return &CertificateService{sshClient: sshClient, enHosts: enHosts, configurationsService: configurationsService, serverConfigTemplate: serverConfigTemplate, nginxConfigPath: files.FixPath(nginxSSLConfigPath), acmeChallengePath: files.FixPath(acmeChallengePath)}, nil
+	return &CertificateService{sshClient: sshClient, enHosts: enHosts, configurationsService: configurationsService, serverConfigTemplate: serverConfigTemplate, nginxConfigPath: files.FixPath(nginxSSLConfigPath), acmeChallengePath: files.FixPath(acmeChallengePath), dnsProvider: dnsProvider}, nil
 }
 
 func (s *CertificateService) UpdateCustomDomains(ctx context.Context, projectID string, domains *entities.CustomDomains) error {
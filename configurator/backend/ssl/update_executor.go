@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"github.com/jitsucom/jitsu/configurator/appconfig"
 	"io/ioutil"
+	"math"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/configurator/entities"
 	"github.com/jitsucom/jitsu/configurator/files"
 	entime "github.com/jitsucom/jitsu/configurator/time"
@@ -16,10 +19,15 @@ import (
 	"github.com/jitsucom/jitsu/server/safego"
 )
 
-const maxDaysBeforeExpiration = 30
+const defaultRenewBeforeDays = 30
+const certificateValidityDays = 90
 const cnameFailedStatus = "cname_failed"
 const cnameOkStatus = "cname_ok"
 const okStatus = "ok"
+const wildcardPrefix = "*."
+
+//wildcardCNameProbe is a subdomain which is resolved for checking wildcard domain CNAME (*.example.com CNAME balancer)
+const wildcardCNameProbe = "jitsu-cname-check."
 
 type UpdateExecutor struct {
 	sslService               *CertificateService
@@ -30,10 +38,35 @@ type UpdateExecutor struct {
 	sslCertificatesStorePath string
 	sslPkStorePath           string
 	acmeChallengePath        string
+	renewBeforeDays          int
+}
+
+//CertificatesStatus is a dto with the project certificate state and the last issuance result
+type CertificatesStatus struct {
+	Challenge             string          `json:"challenge"`
+	RenewBeforeDays       int             `json:"renewBeforeDays"`
+	CertificateExpiration string          `json:"certificateExpiration,omitempty"`
+	DaysBeforeExpiration  *int            `json:"daysBeforeExpiration,omitempty"`
+	RenewalRequired       bool            `json:"renewalRequired"`
+	CertificateIssuedAt   string          `json:"certificateIssuedAt,omitempty"`
+	LastIssuanceAttemptAt string          `json:"lastIssuanceAttemptAt,omitempty"`
+	LastIssuanceError     string          `json:"lastIssuanceError,omitempty"`
+	Domains               []*DomainStatus `json:"domains"`
 }
 
-func NewSSLUpdateExecutor(processor *CertificateService, targetHosts []string, user string, privateKeyPath string, balancerName string, certsPath string, pkPath string, acmeChallengePath string) *UpdateExecutor {
-	return &UpdateExecutor{sslService: processor, enHosts: targetHosts, user: user, privateKeyPath: privateKeyPath, enCName: balancerName, sslCertificatesStorePath: files.FixPath(certsPath), sslPkStorePath: files.FixPath(pkPath), acmeChallengePath: files.FixPath(acmeChallengePath)}
+//DomainStatus is a dto with the custom domain CNAME check status and the last error
+type DomainStatus struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Wildcard  bool   `json:"wildcard"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+func NewSSLUpdateExecutor(processor *CertificateService, targetHosts []string, user string, privateKeyPath string, balancerName string, certsPath string, pkPath string, acmeChallengePath string, renewBeforeDays int) *UpdateExecutor {
+	if renewBeforeDays <= 0 {
+		renewBeforeDays = defaultRenewBeforeDays
+	}
+	return &UpdateExecutor{sslService: processor, enHosts: targetHosts, user: user, privateKeyPath: privateKeyPath, enCName: balancerName, sslCertificatesStorePath: files.FixPath(certsPath), sslPkStorePath: files.FixPath(pkPath), acmeChallengePath: files.FixPath(acmeChallengePath), renewBeforeDays: renewBeforeDays}
 }
 
 func (e *UpdateExecutor) Schedule(interval time.Duration) {
@@ -66,7 +99,7 @@ func (e *UpdateExecutor) CheckDomain(domainName string) bool {
 	}
 	for _, domains := range domainsPerProject {
 		for _, domain := range domains.Domains {
-			if domain.Name == domainName {
+			if domain.Name == domainName || matchesWildcard(domain.Name, domainName) {
 				if domain.Status == okStatus || domain.Status == cnameOkStatus {
 					logging.Infof("[CheckDomain] [OK] Requested for valid custom domain: %s", domainName)
 					return true
@@ -81,18 +114,20 @@ func (e *UpdateExecutor) CheckDomain(domainName string) bool {
 	return false
 }
 
+//Run processes domains of all projects. An error of one project doesn't stop processing of others
 func (e *UpdateExecutor) Run(ctx context.Context) error {
 	domainsPerProject, err := e.sslService.LoadCustomDomains()
 	if err != nil {
 		return err
 	}
+	var multiErr error
 	for projectID, domains := range domainsPerProject {
 		if err := e.processProjectDomains(ctx, projectID, domains); err != nil {
 			logging.Error(err)
-			return err
+			multiErr = multierror.Append(multiErr, err)
 		}
 	}
-	return nil
+	return multiErr
 }
 
 func (e *UpdateExecutor) RunForProject(ctx context.Context, projectID string) error {
@@ -103,8 +138,43 @@ func (e *UpdateExecutor) RunForProject(ctx context.Context, projectID string) er
 	return e.processProjectDomains(ctx, projectID, domains)
 }
 
+//GetStatus returns the project certificate expiration, the last issuance error and domains statuses
+func (e *UpdateExecutor) GetStatus(projectID string) (*CertificatesStatus, error) {
+	domains, err := e.sslService.LoadCustomDomainsByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &CertificatesStatus{
+		Challenge:             e.sslService.ChallengeType(),
+		RenewBeforeDays:       e.renewBeforeDays,
+		CertificateExpiration: domains.CertificateExpirationDate,
+		CertificateIssuedAt:   domains.CertificateIssuedAt,
+		LastIssuanceAttemptAt: domains.LastIssuanceAttemptAt,
+		LastIssuanceError:     domains.LastIssuanceError,
+		Domains:               make([]*DomainStatus, 0, len(domains.Domains)),
+	}
+	if domains.CertificateExpirationDate != "" {
+		expirationDate, err := entime.ParseISOString(domains.CertificateExpirationDate)
+		if err != nil {
+			return nil, err
+		}
+		days := int(math.Floor(expirationDate.Sub(time.Now().UTC()).Hours() / 24))
+		status.DaysBeforeExpiration = &days
+		status.RenewalRequired = days < e.renewBeforeDays
+	}
+	for _, domain := range domains.Domains {
+		status.Domains = append(status.Domains, &DomainStatus{Name: domain.Name, Status: domain.Status, Wildcard: isWildcard(domain.Name), LastError: domain.LastError})
+		if domain.Status != okStatus && domain.Status != cnameFailedStatus {
+			status.RenewalRequired = true
+		}
+	}
+
+	return status, nil
+}
+
 func (e *UpdateExecutor) processProjectDomains(ctx context.Context, projectID string, domains *entities.CustomDomains) error {
-	validDomains := filterExistingCNames(domains, e.enCName)
+	validDomains := e.filterIssuableDomains(domains)
 	updateRequired, err := e.updateRequired(domains, validDomains)
 	if err != nil {
 		return err
@@ -113,36 +183,61 @@ func (e *UpdateExecutor) processProjectDomains(ctx context.Context, projectID st
 		return e.sslService.UpdateCustomDomains(ctx, projectID, domains)
 	}
 
-	certificate, privateKey, err := e.sslService.ExecuteHTTP01Challenge(validDomains)
+	now := time.Now().UTC()
+	domains.LastIssuanceAttemptAt = entime.AsISOString(now)
+	expirationDate, err := e.issueCertificate(projectID, validDomains)
 	if err != nil {
+		err = fmt.Errorf("Error issuing SSL certificate for project [%s] domains %v: %v", projectID, validDomains, err)
+		domains.LastIssuanceError = err.Error()
+		for _, domain := range domains.Domains {
+			if contains(validDomains, domain.Name) {
+				domain.LastError = err.Error()
+			}
+		}
+		if updateErr := e.sslService.UpdateCustomDomains(ctx, projectID, domains); updateErr != nil {
+			logging.Errorf("Error saving project [%s] SSL issuance error: %v", projectID, updateErr)
+		}
 		return err
 	}
+
+	for _, domain := range domains.Domains {
+		if contains(validDomains, domain.Name) {
+			domain.Status = okStatus
+			domain.LastError = ""
+		}
+	}
+	domains.CertificateExpirationDate = entime.AsISOString(expirationDate)
+	domains.CertificateIssuedAt = entime.AsISOString(now)
+	domains.LastIssuanceError = ""
+	return e.sslService.UpdateCustomDomains(ctx, projectID, domains)
+}
+
+//issueCertificate obtains the certificate, uploads it to all hosts and returns the certificate expiration date
+func (e *UpdateExecutor) issueCertificate(projectID string, validDomains []string) (time.Time, error) {
+	certificate, privateKey, err := e.sslService.ObtainCertificate(validDomains)
+	if err != nil {
+		return time.Time{}, err
+	}
 	certFileName := e.sslCertificatesStorePath + projectID + "_cert.pem"
 	err = ioutil.WriteFile(certFileName, certificate, rwPermission)
 	if err != nil {
-		return err
+		return time.Time{}, err
 	}
 	pkFileName := e.privateKeyPath + projectID + "_pk.pem"
 	err = ioutil.WriteFile(pkFileName, privateKey, rwPermission)
 	if err != nil {
-		return err
+		return time.Time{}, err
 	}
 	if err = e.sslService.UploadCertificate(certFileName, pkFileName, projectID, validDomains, e.enHosts); err != nil {
-		return err
+		return time.Time{}, fmt.Errorf("error uploading certificate: %v", err)
 	}
 
-	for _, domain := range domains.Domains {
-		if contains(validDomains, domain.Name) {
-			domain.Status = okStatus
-		}
-	}
-	expirationDate := time.Now().UTC().Add(time.Hour * time.Duration(24*90))
-	domains.CertificateExpirationDate = entime.AsISOString(expirationDate)
-	err = e.sslService.UpdateCustomDomains(ctx, projectID, domains)
+	parsed, err := certcrypto.ParsePEMCertificate(certificate)
 	if err != nil {
-		return err
+		logging.Warnf("Error parsing project [%s] certificate: %v. Expiration date is considered as %d days", projectID, err, certificateValidityDays)
+		return time.Now().UTC().Add(time.Hour * time.Duration(24*certificateValidityDays)), nil
 	}
-	return nil
+	return parsed.NotAfter.UTC(), nil
 }
 
 func (e *UpdateExecutor) updateRequired(domains *entities.CustomDomains, validDomains []string) (bool, error) {
@@ -161,7 +256,7 @@ func (e *UpdateExecutor) updateRequired(domains *entities.CustomDomains, validDo
 	}
 	days := expirationDate.Sub(time.Now().UTC()).Hours() / 24
 
-	if days < maxDaysBeforeExpiration {
+	if days < float64(e.renewBeforeDays) {
 		return true, nil
 	}
 	for _, domain := range domains.Domains {
@@ -172,6 +267,31 @@ func (e *UpdateExecutor) updateRequired(domains *entities.CustomDomains, validDo
 	return false, nil
 }
 
+//filterIssuableDomains checks domains CNAME and returns domains which can be included into the certificate.
+//Wildcard domains are skipped if the challenge doesn't support them
+func (e *UpdateExecutor) filterIssuableDomains(domains *entities.CustomDomains) []string {
+	resultDomains := make([]string, 0)
+	for _, domain := range domains.Domains {
+		if !checkDomain(domain.Name, e.enCName) {
+			domain.Status = cnameFailedStatus
+			domain.LastError = fmt.Sprintf("CNAME record of %s doesn't point to %s", domain.Name, e.enCName)
+			continue
+		}
+
+		if domain.Status != okStatus {
+			domain.Status = cnameOkStatus
+		}
+		if isWildcard(domain.Name) && !e.sslService.SupportsWildcards() {
+			domain.LastError = fmt.Sprintf("Wildcard domain %s requires dns-01 challenge which isn't configured", domain.Name)
+			continue
+		}
+
+		domain.LastError = ""
+		resultDomains = append(resultDomains, domain.Name)
+	}
+	return resultDomains
+}
+
 func contains(domains []string, name string) bool {
 	for _, domain := range domains {
 		if name == domain {
@@ -181,20 +301,17 @@ func contains(domains []string, name string) bool {
 	return false
 }
 
-func filterExistingCNames(domains *entities.CustomDomains, enCName string) []string {
-	resultDomains := make([]string, 0)
-	for _, domain := range domains.Domains {
+func isWildcard(domain string) bool {
+	return strings.HasPrefix(domain, wildcardPrefix)
+}
 
-		if checkDomain(domain.Name, enCName) {
-			resultDomains = append(resultDomains, domain.Name)
-			if domain.Status != okStatus {
-				domain.Status = cnameOkStatus
-			}
-		} else {
-			domain.Status = cnameFailedStatus
-		}
+//matchesWildcard returns true if the domain is a direct subdomain of the wildcard domain: *.example.com matches a.example.com
+func matchesWildcard(wildcard, domain string) bool {
+	if !isWildcard(wildcard) {
+		return false
 	}
-	return resultDomains
+	suffix := wildcard[len(wildcardPrefix)-1:]
+	return len(domain) > len(suffix) && strings.HasSuffix(domain, suffix) && !strings.Contains(strings.TrimSuffix(domain, suffix), ".")
 }
 
 func checkDomain(domain string, validCName string) bool {
@@ -203,6 +320,10 @@ func checkDomain(domain string, validCName string) bool {
 	if onlyNumbers {
 		return false
 	}
+	//wildcard CNAME is checked with a subdomain
+	if isWildcard(domain) {
+		domain = wildcardCNameProbe + domain[len(wildcardPrefix):]
+	}
 	out, err := exec.Command("nslookup", domain).Output()
 	if err != nil {
 		logging.Infof("Failed to check domain %s: %s", domain, err.Error())
@@ -31,10 +31,11 @@ func (k auditRecordKey) String() string {
 
 type auditRecord struct {
 	auditRecordKey
-	UserID     string      `json:"userId,omitempty"`
-	RecordedAt string      `json:"recordedAt"`
-	OldValue   interface{} `json:"oldValue,omitempty"`
-	NewValue   interface{} `json:"newValue,omitempty"`
+	UserID         string      `json:"userId,omitempty"`
+	ImpersonatedBy string      `json:"impersonatedBy,omitempty"`
+	RecordedAt     string      `json:"recordedAt"`
+	OldValue       interface{} `json:"oldValue,omitempty"`
+	NewValue       interface{} `json:"newValue,omitempty"`
 }
 
 func (r *auditRecord) isValid() (bool, error) {
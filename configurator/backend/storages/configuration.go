@@ -1,6 +1,7 @@
 package storages
 
 import (
+	"context"
 	"errors"
 	"time"
 )
@@ -26,6 +27,8 @@ type ConfigurationsStorage interface {
 	Delete(collection string, id string) error
 
 	AddScored(key string, score int64, entity []byte) error
+	//GetScored returns entities with score in [from, to] interval ordered by score
+	GetScored(key string, from, to int64) ([][]byte, error)
 
 	RemoveScored(prefix string, from, to int64) error
 
@@ -41,6 +44,9 @@ type ConfigurationsStorage interface {
 	// DeleteRelatedIDs deletes related IDs from the relation.
 	DeleteRelatedIDs(relation, id string, relatedIDs ...string) error
 
+	//Ping checks the storage connectivity
+	Ping(ctx context.Context) error
+
 	//Close frees all the resources used by the storage (close connections etc.)
 	Close() error
 }
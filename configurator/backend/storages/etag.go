@@ -0,0 +1,72 @@
+package storages
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrObjectNotFound      = errors.New("Object wasn't found")
+	ErrObjectAlreadyExists = errors.New("Object already exists")
+	ErrPreconditionFailed  = errors.New("Object has been changed: ETag doesn't match")
+)
+
+// ObjectETag returns a strong ETag of the object. The object is serialized into canonical JSON (sorted keys),
+// so the same object always has the same ETag regardless of fields order. Serialized objects are accepted as well
+func ObjectETag(object interface{}) string {
+	switch data := object.(type) {
+	case []byte:
+		object = nil
+		if err := json.Unmarshal(data, &object); err != nil {
+			return ""
+		}
+	case json.RawMessage:
+		object = nil
+		if err := json.Unmarshal(data, &object); err != nil {
+			return ""
+		}
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%q", fmt.Sprintf("%x", sha1.Sum(data)))
+}
+
+// checkIfMatch returns ErrPreconditionFailed if ifMatch isn't empty and doesn't match the current object ETag
+func checkIfMatch(ifMatch string, object interface{}) error {
+	ifMatch = strings.TrimPrefix(strings.TrimSpace(ifMatch), "W/")
+	if ifMatch == "" || ifMatch == "*" {
+		return nil
+	}
+
+	if etag := ObjectETag(object); etag != ifMatch {
+		return fmt.Errorf("%w. Current ETag: %s", ErrPreconditionFailed, etag)
+	}
+
+	return nil
+}
+
+// checkIfMatch checks ifMatch against the ETag of the object with masked secret fields as API responses contain masked objects
+func (cs *ConfigurationsService) checkIfMatch(objectType, ifMatch string, object interface{}) error {
+	if !secretObjectTypes[objectType] || cs.secrets == nil {
+		return checkIfMatch(ifMatch, object)
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	return checkIfMatch(ifMatch, cs.MaskSecrets(objectType, data))
+}
+
+func objectNotFoundError(arrayPath string, objectMeta *ObjectMeta) error {
+	return fmt.Errorf("%w: id [%s] in path [%s] in the collection", ErrObjectNotFound, objectMeta.Value, arrayPath)
+}
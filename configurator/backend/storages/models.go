@@ -9,6 +9,8 @@ type PatchPayload struct {
 	ObjectArrayPath string                 `json:"arrayPath,omitempty"`
 	ObjectMeta      *ObjectMeta            `json:"object,omitempty"`
 	Patch           map[string]interface{} `json:"patch,omitempty"`
+	//IfMatch is an expected ETag of the object (see ObjectETag). Empty value means no check
+	IfMatch string `json:"-"`
 }
 
 //ObjectMeta is a dto for object meta information such as identifier path
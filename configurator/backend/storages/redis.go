@@ -1,6 +1,7 @@
 package storages
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -193,28 +194,30 @@ func (r *Redis) AddScored(key string, score int64, entity []byte) error {
 	return err
 }
 
-func (r *Redis) RemoveScored(prefix string, from, to int64) error {
+func (r *Redis) GetScored(key string, from, to int64) ([][]byte, error) {
 	conn := r.pool.Get()
 	defer conn.Close()
 
-	var cursor int
-	for {
-		if reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", prefix+"*")); err != nil {
-			return errors.Wrap(err, "scan keys")
-		} else if cursor, err = redis.Int(reply[0], nil); err != nil {
-			return errors.Wrap(err, "parse cursor value")
-		} else if keys, err := redis.Strings(reply[1], nil); err != nil {
-			return errors.Wrap(err, "parse values")
-		} else {
-			for _, value := range keys {
-				if _, err := conn.Do("ZREMRANGEBYSCORE", value, from, to); err != nil {
-					return errors.Wrap(err, "remove range")
-				}
-			}
-		}
+	entities, err := redis.ByteSlices(conn.Do("ZRANGEBYSCORE", key, from, to))
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
 
-		if cursor == 0 {
-			break
+	return entities, nil
+}
+
+func (r *Redis) RemoveScored(prefix string, from, to int64) error {
+	keys, err := r.pool.ScanKeys(prefix+"*", "", 0)
+	if err != nil {
+		return errors.Wrap(err, "scan keys")
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	for _, value := range keys {
+		if _, err := conn.Do("ZREMRANGEBYSCORE", value, from, to); err != nil {
+			return errors.Wrap(err, "remove range")
 		}
 	}
 
@@ -285,6 +288,11 @@ func toRedisKey(collection string) string {
 	return meta.ConfigPrefix + collection
 }
 
+//Ping checks Redis connectivity
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
 func (r *Redis) Close() error {
 	return r.pool.Close()
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"path"
 	"reflect"
 	"strings"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/jitsucom/jitsu/configurator/entities"
 	mw "github.com/jitsucom/jitsu/configurator/middleware"
 	"github.com/jitsucom/jitsu/configurator/openapi"
+	"github.com/jitsucom/jitsu/configurator/secrets"
 	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/locks"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -39,6 +41,8 @@ const (
 
 	systemCollection = "system"
 
+	impersonationAuditType = "impersonation"
+
 	airbyteType      = "airbyte"
 	singerType       = "singer"
 	airbyteTypeField = "docker_image"
@@ -52,6 +56,12 @@ const (
 	unknownObjectPosition = -1
 )
 
+// secretObjectTypes are object types with encrypted secret fields (see secrets.Service)
+var secretObjectTypes = map[string]bool{
+	destinationsCollection: true,
+	sourcesCollection:      true,
+}
+
 // collectionsDependencies is used for updating last_updated field in db. It leads Jitsu Server to reload configuration with new changes
 var collectionsDependencies = map[string]string{
 	geoDataResolversCollection: destinationsCollection,
@@ -62,14 +72,18 @@ type ConfigurationsService struct {
 	lockFactory        locks.LockFactory
 	defaultDestination *destinations.Postgres
 	locksCloser        io.Closer
+	secrets            *secrets.Service
 }
 
+// NewConfigurationsService returns configured ConfigurationsService. If secretsService is nil, secret fields
+// are stored in plaintext and aren't masked
 func NewConfigurationsService(storage ConfigurationsStorage, defaultDestination *destinations.Postgres,
-	lockFactory locks.LockFactory) *ConfigurationsService {
+	lockFactory locks.LockFactory, secretsService *secrets.Service) *ConfigurationsService {
 	return &ConfigurationsService{
 		storage:            storage,
 		defaultDestination: defaultDestination,
 		lockFactory:        lockFactory,
+		secrets:            secretsService,
 	}
 }
 
@@ -88,6 +102,15 @@ func (cs *ConfigurationsService) saveWithLock(ctx context.Context, objectType, p
 		logging.Warnf("Failed to read [%s.%s] from DB: %v", objectType, projectID, err)
 	}
 
+	if secretObjectTypes[objectType] {
+		//masked secrets from API responses are saved back unchanged
+		var current interface{}
+		if len(oldVersion) > 0 {
+			_ = json.Unmarshal(oldVersion, &current)
+		}
+		projectConfig = cs.secrets.Unmask(projectConfig, current)
+	}
+
 	data, err := cs.save(objectType, projectID, projectConfig)
 	if err != nil {
 		return nil, err
@@ -112,13 +135,21 @@ func (cs *ConfigurationsService) getWithLock(objectType, projectID string) ([]by
 }
 
 // save proxies save request to the storage and updates dependency collection last_update (if a dependency is present)
+// secret fields are encrypted before saving, returns serialized data without encryption
 func (cs *ConfigurationsService) save(objectType, projectID string, projectConfig interface{}) ([]byte, error) {
 	serialized, err := json.MarshalIndent(projectConfig, "", "    ")
 	if err != nil {
 		return nil, err
 	}
 
-	if err := cs.storage.Store(objectType, projectID, serialized); err != nil {
+	stored := serialized
+	if secretObjectTypes[objectType] {
+		if stored, err = cs.secrets.EncryptJSON(serialized); err != nil {
+			return nil, fmt.Errorf("error encrypting secrets of [%s.%s]: %v", objectType, projectID, err)
+		}
+	}
+
+	if err := cs.storage.Store(objectType, projectID, stored); err != nil {
 		return nil, err
 	}
 
@@ -146,9 +177,88 @@ func (cs *ConfigurationsService) delete(objectType, projectID string) error {
 	return nil
 }
 
-// get proxies get request to the storage
+// get proxies get request to the storage and decrypts secret fields
 func (cs *ConfigurationsService) get(objectType, projectID string) ([]byte, error) {
-	return cs.storage.Get(objectType, projectID)
+	data, err := cs.storage.Get(objectType, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.decrypt(objectType, projectID, data)
+}
+
+// decrypt returns data with decrypted secret fields if the object type has secrets
+func (cs *ConfigurationsService) decrypt(objectType, projectID string, data []byte) ([]byte, error) {
+	if !secretObjectTypes[objectType] {
+		return data, nil
+	}
+
+	decrypted, err := cs.secrets.DecryptJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting secrets of [%s.%s]: %v", objectType, projectID, err)
+	}
+
+	return decrypted, nil
+}
+
+// MaskSecrets returns serialized object or objects collection with masked secret fields. Should be used in API responses
+func (cs *ConfigurationsService) MaskSecrets(objectType string, data []byte) []byte {
+	if !secretObjectTypes[objectType] {
+		return data
+	}
+
+	return cs.secrets.MaskJSON(data)
+}
+
+// UnmaskSecrets replaces masked secret fields of the object with values of the current object
+func (cs *ConfigurationsService) UnmaskSecrets(objectType string, object, current map[string]interface{}) {
+	if secretObjectTypes[objectType] {
+		cs.secrets.Unmask(object, current)
+	}
+}
+
+// MaskSecretChanges masks values of changed secret fields in the diff
+func (cs *ConfigurationsService) MaskSecretChanges(objectType string, changes []*DiffChange) []*DiffChange {
+	if !secretObjectTypes[objectType] {
+		return changes
+	}
+
+	for _, change := range changes {
+		if cs.secrets.IsSecretField(path.Base(change.Path)) {
+			if change.OldValue != nil {
+				change.OldValue = secrets.Mask
+			}
+			if change.NewValue != nil {
+				change.NewValue = secrets.Mask
+			}
+		} else {
+			change.OldValue = cs.secrets.Mask(change.OldValue)
+			change.NewValue = cs.secrets.Mask(change.NewValue)
+		}
+	}
+
+	return changes
+}
+
+// encryptedAuditValue returns the audit record value with encrypted secret fields. Values which can't be encrypted are masked
+func (cs *ConfigurationsService) encryptedAuditValue(objectType string, value interface{}) interface{} {
+	if !secretObjectTypes[objectType] || cs.secrets == nil || isEmptyValue(value) {
+		return value
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		if data, err = cs.secrets.EncryptJSON(data); err == nil {
+			return json.RawMessage(data)
+		}
+	}
+
+	logging.SystemErrorf("Failed to encrypt audit value of [%s]: %v", objectType, err)
+	if data, err := json.Marshal(value); err == nil {
+		return json.RawMessage(cs.secrets.MaskJSON(data))
+	}
+
+	return nil
 }
 
 // ** General functions **
@@ -293,6 +403,10 @@ func (cs *ConfigurationsService) GetAllDestinations() (map[string]*entities.Dest
 
 	result := map[string]*entities.Destinations{}
 	for projectID, destinationsBytes := range allDestinations {
+		if destinationsBytes, err = cs.decrypt(objectType, projectID, destinationsBytes); err != nil {
+			return nil, err
+		}
+
 		destEntity := &entities.Destinations{}
 		if err := json.Unmarshal(destinationsBytes, destEntity); err != nil {
 			logging.Errorf("Failed to parse destination %s, project id=[%s], %v", string(destinationsBytes), projectID, err)
@@ -308,12 +422,23 @@ func (cs *ConfigurationsService) PurgeAudit(from, to int64) error {
 	return cs.storage.RemoveScored("audit:*", from, to)
 }
 
+// AddImpersonationAudit records the impersonation of the user by the current admin
+func (cs *ConfigurationsService) AddImpersonationAudit(ctx context.Context, userID string, impersonation interface{}) {
+	cs.addAuditLog(ctx, auditRecordKey{ObjectType: impersonationAuditType, ObjectID: userID}, nil, impersonation)
+}
+
 func (cs *ConfigurationsService) addAuditLog(ctx context.Context, key auditRecordKey, old, new interface{}) {
+	cs.addAuditLogWithVersion(ctx, key, old, new, 0)
+}
+
+// addAuditLogWithVersion writes the audit record and stores a new object version (if the object type is versioned).
+// rollbackOf is a version number the object has been rolled back to (0 if it isn't a rollback)
+func (cs *ConfigurationsService) addAuditLogWithVersion(ctx context.Context, key auditRecordKey, old, new interface{}, rollbackOf int64) {
 	now := timestamp.Now()
 	record := &auditRecord{
 		auditRecordKey: key,
-		OldValue:       old,
-		NewValue:       new,
+		OldValue:       cs.encryptedAuditValue(key.ObjectType, old),
+		NewValue:       cs.encryptedAuditValue(key.ObjectType, new),
 		RecordedAt:     now.Format(entities.LastUpdatedLayout),
 	}
 
@@ -323,6 +448,8 @@ func (cs *ConfigurationsService) addAuditLog(ctx context.Context, key auditRecor
 		} else if authority.IsAdmin {
 			record.UserID = "server"
 		}
+
+		record.ImpersonatedBy = authority.ImpersonatedBy
 	}
 
 	if valid, err := record.isValid(); err != nil {
@@ -341,6 +468,8 @@ func (cs *ConfigurationsService) addAuditLog(ctx context.Context, key auditRecor
 	if err := cs.storage.AddScored(fmt.Sprintf("audit:%s", key), now.UnixMilli(), data); err != nil {
 		logging.SystemErrorf("Failed to add audit log for [%s]: %v", key, err)
 	}
+
+	cs.addVersion(record, rollbackOf)
 }
 
 // GetDestinationsByProjectID uses getWithLock func under the hood, returns all destinations per project
@@ -474,6 +603,10 @@ func (cs *ConfigurationsService) GetAllSources() (map[string]*entities.Sources,
 	result := map[string]*entities.Sources{}
 
 	for projectID, sourcesBytes := range allSources {
+		if sourcesBytes, err = cs.decrypt(objectType, projectID, sourcesBytes); err != nil {
+			return nil, err
+		}
+
 		sourceEntity := &entities.Sources{}
 		if err := json.Unmarshal(sourcesBytes, sourceEntity); err != nil {
 			logging.Errorf("Failed to parse source %s, project id=[%s], %v", string(sourcesBytes), projectID, err)
@@ -636,8 +769,9 @@ func (cs *ConfigurationsService) UpdateCustomDomain(ctx context.Context, project
 // ** Objects API **
 
 // CreateObjectWithLock locks project object Types and add new object
-// returns new object
-func (cs *ConfigurationsService) CreateObjectWithLock(ctx context.Context, objectType string, projectID string, object *openapi.AnyObject) ([]byte, error) {
+// returns new object. If keepID is true, the object ID from the payload is stored as is (stable ID) and
+// ErrObjectAlreadyExists is returned if it has already been used. Otherwise unique ID is generated from the payload ID
+func (cs *ConfigurationsService) CreateObjectWithLock(ctx context.Context, objectType string, projectID string, object *openapi.AnyObject, keepID bool) ([]byte, error) {
 	lock, err := cs.lockProjectObject(objectType, projectID)
 	if err != nil {
 		return nil, err
@@ -645,6 +779,8 @@ func (cs *ConfigurationsService) CreateObjectWithLock(ctx context.Context, objec
 	defer lock.Unlock()
 
 	arrayPath := cs.GetObjectArrayPathByObjectType(objectType)
+	//masked values can't be restored in a new object
+	cs.UnmaskSecrets(objectType, object.AdditionalProperties, nil)
 
 	//extract configuration fields
 	idField := cs.GetObjectIDField(objectType)
@@ -689,6 +825,12 @@ func (cs *ConfigurationsService) CreateObjectWithLock(ctx context.Context, objec
 		}
 	}
 
+	if keepID {
+		if id, ok := object.Get(idField); ok && usedIDs[fmt.Sprint(id)] {
+			return nil, fmt.Errorf("%w: id [%v] in path [%s] in the collection", ErrObjectAlreadyExists, id, arrayPath)
+		}
+	}
+
 	generatedID := cs.GenerateID(typeField, idField, objectType, projectID, object, usedIDs)
 	object.Set(idField, generatedID)
 
@@ -736,6 +878,9 @@ func (cs *ConfigurationsService) PatchObjectWithLock(ctx context.Context, object
 
 	if patchPayload.ObjectArrayPath == "" && objectsArray == nil {
 		//single object (geo data resolver or telemetry)
+		if err := cs.checkIfMatch(objectType, patchPayload.IfMatch, projectConfig); err != nil {
+			return nil, err
+		}
 		oldVersion, _ = json.Marshal(projectConfig)
 		newProjectConfigWithObject = jsonutils.Merge(projectConfig, patchPayload.Patch)
 		newVersion = newProjectConfigWithObject
@@ -752,10 +897,14 @@ func (cs *ConfigurationsService) PatchObjectWithLock(ctx context.Context, object
 			}
 		}
 		if objectPosition == unknownObjectPosition {
-			return nil, fmt.Errorf("object hasn't been found by id in path [%s] in the collection", patchPayload.ObjectArrayPath)
+			return nil, objectNotFoundError(patchPayload.ObjectArrayPath, patchPayload.ObjectMeta)
 		}
 
 		object := objectsArray[objectPosition]
+		if err := cs.checkIfMatch(objectType, patchPayload.IfMatch, object); err != nil {
+			return nil, err
+		}
+		cs.UnmaskSecrets(objectType, patchPayload.Patch, object)
 		oldVersion, _ = json.Marshal(object)
 		patchedObject = jsonutils.Merge(object, patchPayload.Patch)
 		newVersion = patchedObject
@@ -805,11 +954,13 @@ func (cs *ConfigurationsService) ReplaceObjectWithLock(ctx context.Context, obje
 	var (
 		newProjectConfigWithObject map[string]interface{}
 		oldVersion                 interface{}
-		newVersion, _              = json.Marshal(patchPayload.Patch)
 	)
 
 	if patchPayload.ObjectArrayPath == "" && objectsArray == nil {
 		//single object (geo data resolver or telemetry)
+		if err := cs.checkIfMatch(objectType, patchPayload.IfMatch, projectConfig); err != nil {
+			return nil, err
+		}
 		oldVersion = projectConfig
 		newProjectConfigWithObject = patchPayload.Patch
 	} else {
@@ -825,6 +976,14 @@ func (cs *ConfigurationsService) ReplaceObjectWithLock(ctx context.Context, obje
 			}
 		}
 
+		if objectPosition == unknownObjectPosition {
+			return nil, objectNotFoundError(patchPayload.ObjectArrayPath, patchPayload.ObjectMeta)
+		}
+		if err := cs.checkIfMatch(objectType, patchPayload.IfMatch, objectsArray[objectPosition]); err != nil {
+			return nil, err
+		}
+		cs.UnmaskSecrets(objectType, patchPayload.Patch, objectsArray[objectPosition])
+
 		oldVersion = objectsArray[objectPosition]
 		newProjectConfigWithObject = buildProjectDataObject(projectConfig, objectsArray, patchPayload.Patch, objectPosition, patchPayload.ObjectArrayPath)
 	}
@@ -833,6 +992,8 @@ func (cs *ConfigurationsService) ReplaceObjectWithLock(ctx context.Context, obje
 		return nil, err
 	}
 
+	newVersion, _ := json.Marshal(patchPayload.Patch)
+
 	cs.addAuditLog(ctx, auditRecordKey{
 		ObjectType: objectType,
 		ProjectID:  projectID,
@@ -868,6 +1029,9 @@ func (cs *ConfigurationsService) DeleteObjectWithLock(ctx context.Context, objec
 	if deletePayload.ObjectArrayPath == "" && objectsArray == nil {
 		//single object (geo data resolver or telemetry)
 		//just delete it
+		if err := cs.checkIfMatch(objectType, deletePayload.IfMatch, projectConfig); err != nil {
+			return nil, err
+		}
 		if err := cs.delete(objectType, projectID); err != nil {
 			return nil, err
 		}
@@ -892,11 +1056,14 @@ func (cs *ConfigurationsService) DeleteObjectWithLock(ctx context.Context, objec
 	}
 
 	if objectPosition == unknownObjectPosition {
-		return nil, fmt.Errorf("object hasn't been found by id in path [%s] in the collection", deletePayload.ObjectArrayPath)
+		return nil, objectNotFoundError(deletePayload.ObjectArrayPath, deletePayload.ObjectMeta)
 	}
 
 	//save without foundObject
 	objectToDelete := objectsArray[objectPosition]
+	if err := cs.checkIfMatch(objectType, deletePayload.IfMatch, objectToDelete); err != nil {
+		return nil, err
+	}
 	newObjectsArray := append(objectsArray[:objectPosition], objectsArray[objectPosition+1:]...)
 	projectConfig[deletePayload.ObjectArrayPath] = newObjectsArray
 
@@ -955,7 +1122,7 @@ func (cs *ConfigurationsService) GetObjectWithLock(objectType, projectID, object
 		}
 	}
 
-	return nil, fmt.Errorf("object hasn't been found by id in path [%s] in the collection", objectArrayPath)
+	return nil, objectNotFoundError(objectArrayPath, objectMeta)
 }
 
 func (cs *ConfigurationsService) LinkUserToProject(userID, projectID string) error {
@@ -0,0 +1,350 @@
+package storages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/pkg/errors"
+)
+
+const (
+	VersionCreated    = "created"
+	VersionUpdated    = "updated"
+	VersionDeleted    = "deleted"
+	VersionRolledBack = "rolled_back"
+
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+var ErrVersionNotFound = errors.New("Version wasn't found")
+
+// versionedObjectTypes are object types which are stored as immutable versions on every save
+var versionedObjectTypes = map[string]bool{
+	destinationsCollection: true,
+	sourcesCollection:      true,
+	apiKeysCollection:      true,
+}
+
+// ObjectVersion is an immutable snapshot of a saved object
+type ObjectVersion struct {
+	Version    int64  `json:"version"`
+	ObjectType string `json:"objectType"`
+	ProjectID  string `json:"projectId"`
+	ObjectID   string `json:"objectId"`
+	Action     string `json:"action"`
+	// RollbackOf is a version the object has been rolled back to
+	RollbackOf int64  `json:"rollbackOf,omitempty"`
+	Author     string `json:"author,omitempty"`
+	CreatedAt  string `json:"createdAt"`
+	// Object is empty in deletion versions
+	Object json.RawMessage `json:"object,omitempty"`
+}
+
+// VersionsDiff is a list of changes between two object versions
+type VersionsDiff struct {
+	From    int64         `json:"from"`
+	To      int64         `json:"to"`
+	Changes []*DiffChange `json:"changes"`
+}
+
+// DiffChange is a change of a JSON node. Path is a JSON path like /config/host. Arrays are compared as a whole value
+type DiffChange struct {
+	Path     string      `json:"path"`
+	Type     string      `json:"type"`
+	OldValue interface{} `json:"oldValue,omitempty"`
+	NewValue interface{} `json:"newValue,omitempty"`
+}
+
+func versionsKey(key auditRecordKey) string {
+	return fmt.Sprintf("versions:%s", key)
+}
+
+func isVersioned(key auditRecordKey) bool {
+	return versionedObjectTypes[key.ObjectType] && key.ProjectID != "" && key.ObjectID != ""
+}
+
+// addVersion stores a new version of the object from the audit record. Must be called under the project object lock
+func (cs *ConfigurationsService) addVersion(record *auditRecord, rollbackOf int64) {
+	if !isVersioned(record.auditRecordKey) {
+		return
+	}
+
+	versions, err := cs.getVersions(record.auditRecordKey)
+	if err != nil {
+		logging.SystemErrorf("Failed to read versions of [%s]: %v", record.auditRecordKey, err)
+		return
+	}
+
+	version := &ObjectVersion{
+		Version:    1,
+		ObjectType: record.ObjectType,
+		ProjectID:  record.ProjectID,
+		ObjectID:   record.ObjectID,
+		Action:     VersionUpdated,
+		RollbackOf: rollbackOf,
+		Author:     record.UserID,
+		CreatedAt:  record.RecordedAt,
+	}
+	if len(versions) > 0 {
+		version.Version = versions[len(versions)-1].Version + 1
+	}
+
+	switch {
+	case rollbackOf > 0:
+		version.Action = VersionRolledBack
+	case isEmptyValue(record.NewValue):
+		version.Action = VersionDeleted
+	case isEmptyValue(record.OldValue):
+		version.Action = VersionCreated
+	}
+
+	if !isEmptyValue(record.NewValue) {
+		if version.Object, err = json.Marshal(record.NewValue); err != nil {
+			logging.SystemErrorf("Failed to marshal version of [%s]: %v", record.auditRecordKey, err)
+			return
+		}
+	}
+
+	data, err := json.Marshal(version)
+	if err != nil {
+		logging.SystemErrorf("Failed to marshal version of [%s]: %v", record.auditRecordKey, err)
+		return
+	}
+
+	if err := cs.storage.AddScored(versionsKey(record.auditRecordKey), version.Version, data); err != nil {
+		logging.SystemErrorf("Failed to add version of [%s]: %v", record.auditRecordKey, err)
+	}
+}
+
+// isEmptyValue returns true if the audit record value is nil or an empty JSON
+func isEmptyValue(value interface{}) bool {
+	if data, ok := value.(json.RawMessage); ok {
+		return len(data) == 0
+	}
+
+	return value == nil
+}
+
+// getVersions returns all object versions ordered by version number
+func (cs *ConfigurationsService) getVersions(key auditRecordKey) ([]*ObjectVersion, error) {
+	entities, err := cs.storage.GetScored(versionsKey(key), 1, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*ObjectVersion, 0, len(entities))
+	for _, data := range entities {
+		version := &ObjectVersion{}
+		if err := json.Unmarshal(data, version); err != nil {
+			return nil, errors.Wrap(err, "unmarshal version")
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+func findVersion(versions []*ObjectVersion, number int64) (*ObjectVersion, error) {
+	for _, version := range versions {
+		if version.Version == number {
+			return version, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, number)
+}
+
+// GetObjectVersionsWithLock returns all versions of the object ordered by version number
+func (cs *ConfigurationsService) GetObjectVersionsWithLock(objectType, projectID, objectID string) ([]*ObjectVersion, error) {
+	lock, err := cs.lockProjectObject(objectType, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	return cs.getVersions(auditRecordKey{ObjectType: objectType, ProjectID: projectID, ObjectID: objectID})
+}
+
+// GetObjectVersionsDiffWithLock returns changes between two object versions. If to is 0, the last version is used
+func (cs *ConfigurationsService) GetObjectVersionsDiffWithLock(objectType, projectID, objectID string, from, to int64) (*VersionsDiff, error) {
+	versions, err := cs.GetObjectVersionsWithLock(objectType, projectID, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == 0 && len(versions) > 0 {
+		to = versions[len(versions)-1].Version
+	}
+
+	fromVersion, err := findVersion(versions, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toVersion, err := findVersion(versions, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldObject, newObject interface{}
+	if len(fromVersion.Object) > 0 {
+		if err := json.Unmarshal(fromVersion.Object, &oldObject); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal version %d", from)
+		}
+	}
+
+	if len(toVersion.Object) > 0 {
+		if err := json.Unmarshal(toVersion.Object, &newObject); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal version %d", to)
+		}
+	}
+
+	//encrypted values differ even if secrets are the same
+	if oldObject, err = cs.secrets.Decrypt(oldObject); err != nil {
+		return nil, errors.Wrapf(err, "decrypt version %d", from)
+	}
+	if newObject, err = cs.secrets.Decrypt(newObject); err != nil {
+		return nil, errors.Wrapf(err, "decrypt version %d", to)
+	}
+
+	return &VersionsDiff{From: from, To: to, Changes: cs.MaskSecretChanges(objectType, DiffObjects(oldObject, newObject))}, nil
+}
+
+// RollbackObjectWithLock restores the object from the version and stores it as a new version. Deleted objects are
+// restored as well. Returns the restored object
+func (cs *ConfigurationsService) RollbackObjectWithLock(ctx context.Context, objectType, projectID string, objectMeta *ObjectMeta, number int64) ([]byte, error) {
+	lock, err := cs.lockProjectObject(objectType, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	key := auditRecordKey{ObjectType: objectType, ProjectID: projectID, ObjectID: objectMeta.Value}
+	versions, err := cs.getVersions(key)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := findVersion(versions, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(version.Object) == 0 {
+		return nil, fmt.Errorf("version %d is a deletion of the object and can't be restored", number)
+	}
+
+	object := map[string]interface{}{}
+	if err := json.Unmarshal(version.Object, &object); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal version %d", number)
+	}
+	if _, err := cs.secrets.Decrypt(object); err != nil {
+		return nil, errors.Wrapf(err, "decrypt version %d", number)
+	}
+
+	arrayPath := cs.GetObjectArrayPathByObjectType(objectType)
+	var (
+		projectConfig map[string]interface{}
+		objectsArray  []map[string]interface{}
+		oldVersion    interface{}
+	)
+
+	data, err := cs.get(objectType, projectID)
+	if err != nil && !errors.Is(err, ErrConfigurationNotFound) {
+		return nil, err
+	} else if err == nil {
+		if projectConfig, objectsArray, err = deserializeProjectObjects(data, arrayPath, objectType, projectID); err != nil {
+			return nil, err
+		}
+	}
+
+	objectPosition := unknownObjectPosition
+	for i, objectI := range objectsArray {
+		_, ok, err := findObject(i, objectI, objectType, projectID, objectMeta)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			objectPosition = i
+			oldVersion = objectI
+			break
+		}
+	}
+
+	newProjectConfig := buildProjectDataObject(projectConfig, objectsArray, object, objectPosition, arrayPath)
+	if _, err := cs.save(objectType, projectID, newProjectConfig); err != nil {
+		return nil, err
+	}
+
+	restored, err := json.Marshal(object)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing restored object: %v", err)
+	}
+
+	cs.addAuditLogWithVersion(ctx, key, oldVersion, json.RawMessage(restored), number)
+	return restored, nil
+}
+
+// DiffObjects returns changes between old and new JSON values (nil values mean absent objects)
+func DiffObjects(oldValue, newValue interface{}) []*DiffChange {
+	changes := make([]*DiffChange, 0)
+	diffJSON("", oldValue, newValue, &changes)
+	return changes
+}
+
+// diffJSON appends changes between old and new JSON values. Objects are compared recursively
+func diffJSON(path string, oldValue, newValue interface{}, changes *[]*DiffChange) {
+	oldObject, oldIsObject := oldValue.(map[string]interface{})
+	newObject, newIsObject := newValue.(map[string]interface{})
+	if oldIsObject && newIsObject {
+		keys := make([]string, 0, len(oldObject)+len(newObject))
+		for key := range oldObject {
+			keys = append(keys, key)
+		}
+		for key := range newObject {
+			if _, ok := oldObject[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			oldNode, oldOk := oldObject[key]
+			newNode, newOk := newObject[key]
+			nodePath := path + "/" + key
+			switch {
+			case !oldOk:
+				*changes = append(*changes, &DiffChange{Path: nodePath, Type: DiffAdded, NewValue: newNode})
+			case !newOk:
+				*changes = append(*changes, &DiffChange{Path: nodePath, Type: DiffRemoved, OldValue: oldNode})
+			default:
+				diffJSON(nodePath, oldNode, newNode, changes)
+			}
+		}
+		return
+	}
+
+	if reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	switch {
+	case oldValue == nil:
+		*changes = append(*changes, &DiffChange{Path: path, Type: DiffAdded, NewValue: newValue})
+	case newValue == nil:
+		*changes = append(*changes, &DiffChange{Path: path, Type: DiffRemoved, OldValue: oldValue})
+	default:
+		*changes = append(*changes, &DiffChange{Path: path, Type: DiffChanged, OldValue: oldValue, NewValue: newValue})
+	}
+}
@@ -7,6 +7,7 @@ import {
   hiddenValue,
   jsonType,
   booleanType,
+  arrayOf,
 } from "../../sources/types"
 import { ReactNode } from "react"
 import * as React from "react"
@@ -198,3 +199,60 @@ export function fileParameters(folderField, formatField, compressionField): Para
     },
   ]
 }
+
+/**
+ * Consent categories mapping (CMP integration). Is common for all destinations
+ */
+export const consentParameters: Parameter[] = [
+  {
+    id: "_consent._categories",
+    displayName: "Required Consent Categories",
+    required: false,
+    type: arrayOf(stringType),
+    documentation: (
+      <>
+        Consent categories (e.g. <code>analytics</code>, <code>marketing</code>) which must be granted by the event
+        consent (TCF string or categories map) to send the event to this destination. Leave empty to send all events.
+        See{" "}
+        <a href="https://jitsu.com/docs/configuration/consent" target="_blank" rel="noreferrer">
+          Docs
+        </a>
+        .
+      </>
+    ),
+  },
+  {
+    id: "_consent._mode",
+    displayName: "Events Without Consent",
+    required: false,
+    defaultValue: "drop",
+    type: selectionType(["drop", "strip"], 1),
+    documentation: (
+      <>
+        <b>drop</b> - events without required consent aren't sent to this destination
+        <br />
+        <b>strip</b> - personal data fields are removed from events without required consent
+      </>
+    ),
+  },
+  {
+    id: "_consent._strip_fields",
+    displayName: "Personal Data Fields",
+    required: false,
+    type: arrayOf(stringType),
+    documentation: (
+      <>
+        JSON paths of fields which are removed in <b>strip</b> mode. Default: /user, /eventn_ctx/user, /ids,
+        /eventn_ctx/ids, /source_ip
+      </>
+    ),
+  },
+  {
+    id: "_consent._missing",
+    displayName: "Events Without Consent Information",
+    required: false,
+    defaultValue: "allow",
+    type: selectionType(["allow", "deny"], 1),
+    documentation: <>What to do with events which don't contain consent at all (e.g. sent before CMP integration)</>,
+  },
+]
@@ -0,0 +1,91 @@
+import { filteringExpressionDocumentation, tableName } from "./common"
+import { intType, jsonType, selectionType, stringType } from "../../sources/types"
+import * as React from "react"
+import { ReactNode } from "react"
+import { Destination } from "../types"
+
+let icon: ReactNode = (
+  <svg xmlns="http://www.w3.org/2000/svg" height="100%" width="100%" viewBox="0 0 48 64">
+    <path d="M30 0H5C2.2 0 0 2.2 0 5v54c0 2.8 2.2 5 5 5h38c2.8 0 5-2.2 5-5V18L30 0z" fill="#0f9d58" />
+    <path d="M30 0v13c0 2.8 2.2 5 5 5h13L30 0z" fill="#87ceac" />
+    <path
+      d="M11 29v22h26V29H11zm11.5 19H14v-5h8.5v5zm0-8H14v-5h8.5v5zm11.5 8h-8.5v-5H34v5zm0-8h-8.5v-5H34v5z"
+      fill="#f1f1f1"
+    />
+  </svg>
+)
+
+const destination: Destination = {
+  description: (
+    <>
+      Google Sheets destination writes small tables (such as daily aggregates) into a spreadsheet. Every table is a
+      sheet with the same name
+    </>
+  ),
+  syncFromSourcesStatus: "supported",
+  id: "google_sheets",
+  type: "other",
+  displayName: "Google Sheets",
+  ui: {
+    icon,
+    title: (cfg: object) => {
+      return cfg["_formData"]["gsSpreadsheetId"]
+    },
+    connectCmd: _ => null,
+  },
+  parameters: [
+    tableName(filteringExpressionDocumentation),
+    {
+      id: "_formData.gsKey",
+      displayName: "Access Key",
+      documentation: (
+        <>
+          Google Service Account JSON credentials. Share the spreadsheet with the service account email.{" "}
+          <a
+            target="_blank"
+            href="https://jitsu.com/docs/configuration/google-authorization#service-account-configuration"
+          >
+            Read more about Google Authorization
+          </a>
+        </>
+      ),
+      required: true,
+      type: jsonType,
+    },
+    {
+      id: "_formData.gsSpreadsheetId",
+      displayName: "Spreadsheet ID",
+      documentation: (
+        <>
+          Spreadsheet ID is a part of the spreadsheet URL: https://docs.google.com/spreadsheets/d/
+          <b>spreadsheet_id</b>/edit
+        </>
+      ),
+      required: true,
+      type: stringType,
+    },
+    {
+      id: "_formData.gsMode",
+      displayName: "Write Mode",
+      documentation: (
+        <>
+          <b>append</b> adds every batch of events to the end of the sheet. <b>replace</b> overwrites the sheet with
+          every batch. Source synchronizations always overwrite the sheet on full refresh
+        </>
+      ),
+      required: true,
+      defaultValue: "append",
+      type: selectionType(["append", "replace"], 1),
+    },
+    {
+      id: "_formData.gsMaxRows",
+      displayName: "Max Rows",
+      documentation: <>Maximum number of rows written at once. Bigger batches fail</>,
+      required: false,
+      defaultValue: 10000,
+      type: intType,
+    },
+  ],
+}
+
+export default destination
@@ -13,6 +13,7 @@ import hubspotDestination from "./hubspot"
 import dbtcloudDestination from "./dbtcloud"
 import s3Destination from "./s3"
 import gcsDestination from "./googleCloudStorage"
+import googleSheetsDestination from "./googleSheets"
 import mixpanelDestination from "./mixpanel"
 import mixpanel2Destination from "./mixpanel2"
 import bentoDestination from "./bento"
@@ -23,6 +24,7 @@ import npmDestination from "./npm"
 
 import { Destination } from "../types"
 import tagDestination from "./tag"
+import { consentParameters } from "./common"
 
 export {
   postgresDestination,
@@ -39,12 +41,14 @@ export {
   dbtcloudDestination,
   s3Destination,
   gcsDestination,
+  googleSheetsDestination,
   mixpanelDestination,
   mixpanel2Destination,
   tagDestination,
   bentoDestination,
   plausibleDestination,
-    elasticsearchDestination
+    elasticsearchDestination,
+  consentParameters,
 }
 
 export const destinationsReferenceMap: { [key: string]: Destination } = {
@@ -62,6 +66,7 @@ export const destinationsReferenceMap: { [key: string]: Destination } = {
   dbtcloud: dbtcloudDestination,
   s3: s3Destination,
   gcs: gcsDestination,
+  google_sheets: googleSheetsDestination,
   mixpanel: mixpanelDestination,
   mixpanel2: mixpanel2Destination,
   tag: tagDestination,
@@ -1,6 +1,9 @@
 import {
+  arrayOf,
+  booleanType,
   intType,
   isoUtcDateType,
+  jsonType,
   oauthSecretType,
   passwordType,
   selectionType,
@@ -8,6 +11,7 @@ import {
   stringType,
 } from "../types"
 import { googleServiceAuthDocumentation } from "./documentation"
+import * as logos from "./logos"
 
 import { googleAuthConfigParameters } from "./commonParams"
 
@@ -1259,4 +1263,521 @@ export const amplitude: SourceConnector = {
   collectionParameters: [],
 }
 
-export const allNativeConnectors = [facebook, redis, firebase, googleAds, googleAnalytics, googlePlay, amplitude]
+export const postgresCdc: SourceConnector = {
+  pic: (
+    <svg viewBox="0 0 32 32" xmlns="http://www.w3.org/2000/svg" height="100%" width="100%">
+      <path
+        d="M22.84 23.4c.2-1.62.14-1.86 1.35-1.6l.31.03c.93.04 2.14-.15 2.85-.48 1.53-.71 2.44-1.9.93-1.59-3.44.71-3.68-.46-3.68-.46 3.64-5.4 5.16-12.24 3.85-13.92-3.58-4.57-9.77-2.41-9.87-2.35l-.03.01a12.26 12.26 0 0 0-2.3-.24c-1.55-.03-2.73.41-3.63 1.09 0 0-11.05-4.55-10.54 5.73.11 2.19 3.13 16.55 6.74 12.22 1.32-1.58 2.59-2.92 2.59-2.92.63.42 1.39.64 2.19.56l.06-.05c-.02.2-.01.4.02.63-.93 1.04-.66 1.22-2.52 1.6-1.88.39-.78 1.08-.05 1.26.87.22 2.9.53 4.27-1.39l-.05.22c.36.29.62 1.9.57 3.36-.04 1.46-.07 2.46.21 3.23.29.77.57 2.51 3.01 1.99 2.04-.44 3.1-1.57 3.25-3.47.1-1.35.34-1.15.36-2.36z"
+        fill="#336791"
+      />
+    </svg>
+  ),
+  displayName: "PostgreSQL CDC",
+  id: "postgres_cdc",
+  collectionTypes: [],
+  collectionParameters: [
+    {
+      displayName: "Tables",
+      id: "tables",
+      type: arrayOf(stringType),
+      required: false,
+      documentation: (
+        <>
+          Captured tables in <b>[schema.]table</b> format. Jitsu creates a publication for them if it doesn't exist.
+          With wal2json plugin empty value means all tables.
+        </>
+      ),
+    },
+    {
+      displayName: "Replication Slot",
+      id: "slot_name",
+      type: stringType,
+      required: false,
+      documentation: (
+        <>
+          Logical replication slot name. Jitsu creates the slot if it doesn't exist. Default value:{" "}
+          <code>jitsu_[source id]_[collection name]</code>
+        </>
+      ),
+    },
+    {
+      displayName: "Publication",
+      id: "publication",
+      type: stringType,
+      required: false,
+      documentation: <>Publication name (pgoutput plugin only). Default value: replication slot name</>,
+    },
+  ],
+  configParameters: [
+    {
+      displayName: "Host",
+      id: "config.host",
+      type: stringType,
+      required: true,
+      documentation: <>PostgreSQL host</>,
+    },
+    {
+      displayName: "Port",
+      id: "config.port",
+      type: intType,
+      defaultValue: 5432,
+      required: true,
+      documentation: <>PostgreSQL port</>,
+    },
+    {
+      displayName: "Database",
+      id: "config.db",
+      type: stringType,
+      required: true,
+      documentation: <>PostgreSQL database name</>,
+    },
+    {
+      displayName: "Username",
+      id: "config.username",
+      type: stringType,
+      required: true,
+      documentation: (
+        <>
+          PostgreSQL user with <b>REPLICATION</b> attribute
+        </>
+      ),
+    },
+    {
+      displayName: "Password",
+      id: "config.password",
+      type: passwordType,
+      documentation: <>PostgreSQL password</>,
+    },
+    {
+      displayName: "Output Plugin",
+      id: "config.plugin",
+      type: selectionType(["pgoutput", "wal2json"], 1),
+      defaultValue: "pgoutput",
+      documentation: (
+        <>
+          Logical decoding output plugin. <b>pgoutput</b> is built into PostgreSQL 10+. <b>wal2json</b> must be
+          installed on the server.
+        </>
+      ),
+    },
+  ],
+  documentation: {
+    overview: (
+      <>
+        The PostgreSQL CDC connector reads inserts, updates and deletes from a{" "}
+        <a target="_blank" href="https://www.postgresql.org/docs/current/logicaldecoding-explanation.html">
+          logical replication slot
+        </a>{" "}
+        and stores them with operation metadata (<code>cdc_operation</code>, <code>cdc_lsn</code>,{" "}
+        <code>cdc_commit_time</code> etc.). Every sync loads only new changes.
+      </>
+    ),
+    connection: (
+      <>
+        Set <code>wal_level = logical</code> in postgresql.conf and use a user with <b>REPLICATION</b> attribute. See
+        details in the{" "}
+        <a target="_blank" href="https://jitsu.com/docs/sources/postgres-cdc">
+          documentation
+        </a>
+        .
+      </>
+    ),
+  },
+}
+
+export const mysqlCdc: SourceConnector = {
+  pic: logos.tap_mysql,
+  displayName: "MySQL CDC",
+  id: "mysql_cdc",
+  collectionTypes: [],
+  collectionParameters: [
+    {
+      displayName: "Tables",
+      id: "tables",
+      type: arrayOf(stringType),
+      required: false,
+      documentation: <>Captured tables of the configured database. Empty value means all tables</>,
+    },
+    {
+      displayName: "Initial Snapshot",
+      id: "snapshot",
+      type: booleanType,
+      defaultValue: true,
+      required: false,
+      documentation: (
+        <>
+          If enabled, the first sync loads all rows of captured tables and then streams changes from the binlog.
+          Otherwise only changes made after the first sync are loaded
+        </>
+      ),
+    },
+  ],
+  configParameters: [
+    {
+      displayName: "Host",
+      id: "config.host",
+      type: stringType,
+      required: true,
+      documentation: <>MySQL or MariaDB host</>,
+    },
+    {
+      displayName: "Port",
+      id: "config.port",
+      type: intType,
+      defaultValue: 3306,
+      required: true,
+      documentation: <>MySQL or MariaDB port</>,
+    },
+    {
+      displayName: "Database",
+      id: "config.db",
+      type: stringType,
+      required: true,
+      documentation: <>Database name</>,
+    },
+    {
+      displayName: "Username",
+      id: "config.username",
+      type: stringType,
+      required: true,
+      documentation: (
+        <>
+          User with <b>REPLICATION SLAVE</b>, <b>REPLICATION CLIENT</b> and <b>SELECT</b> privileges
+        </>
+      ),
+    },
+    {
+      displayName: "Password",
+      id: "config.password",
+      type: passwordType,
+      documentation: <>User password</>,
+    },
+    {
+      displayName: "Server ID",
+      id: "config.server_id",
+      type: intType,
+      required: false,
+      documentation: (
+        <>
+          Unique replica <code>server_id</code>. Default value is generated from the source and the collection ids
+        </>
+      ),
+    },
+  ],
+  documentation: {
+    overview: (
+      <>
+        The MySQL CDC connector reads inserts, updates and deletes of MySQL or MariaDB tables from the{" "}
+        <a target="_blank" href="https://dev.mysql.com/doc/refman/8.0/en/binary-log.html">
+          binary log
+        </a>{" "}
+        and stores them with operation metadata (<code>cdc_operation</code>, <code>cdc_gtid</code>,{" "}
+        <code>cdc_commit_time</code> etc.). The first sync loads a snapshot of captured tables, every next sync loads
+        only new changes.
+      </>
+    ),
+    connection: (
+      <>
+        Enable binary logging with <code>binlog_format = ROW</code> and use a user with <b>REPLICATION SLAVE</b>{" "}
+        and <b>REPLICATION CLIENT</b> privileges. See details in the{" "}
+        <a target="_blank" href="https://jitsu.com/docs/sources/mysql-cdc">
+          documentation
+        </a>
+        .
+      </>
+    ),
+  },
+}
+
+export const httpApi: SourceConnector = {
+  pic: (
+    <svg viewBox="0 0 24 24" xmlns="http://www.w3.org/2000/svg" height="100%" width="100%">
+      <path
+        d="M12 2a10 10 0 1 0 0 20 10 10 0 0 0 0-20zm6.93 6h-2.95a15.65 15.65 0 0 0-1.38-3.56A8.03 8.03 0 0 1 18.93 8zM12 4.04c.83 1.2 1.48 2.53 1.91 3.96h-3.82c.43-1.43 1.08-2.76 1.91-3.96zM4.26 14a8.2 8.2 0 0 1 0-4h3.38a16.5 16.5 0 0 0 0 4H4.26zm.81 2h2.95c.32 1.25.78 2.45 1.38 3.56A7.99 7.99 0 0 1 5.07 16zm2.95-8H5.07a7.99 7.99 0 0 1 4.33-3.56A15.65 15.65 0 0 0 8.02 8zM12 19.96A14.1 14.1 0 0 1 10.09 16h3.82A14.1 14.1 0 0 1 12 19.96zM14.34 14H9.66a14.7 14.7 0 0 1 0-4h4.68a14.7 14.7 0 0 1 0 4zm.25 5.56c.6-1.11 1.06-2.31 1.38-3.56h2.95a8.03 8.03 0 0 1-4.33 3.56zM16.36 14a16.5 16.5 0 0 0 0-4h3.38a8.2 8.2 0 0 1 0 4h-3.38z"
+        fill="#1890ff"
+      />
+    </svg>
+  ),
+  displayName: "HTTP API",
+  id: "http_api",
+  collectionTypes: [],
+  collectionParameters: [
+    {
+      displayName: "URL",
+      id: "url",
+      type: stringType,
+      required: true,
+      documentation: (
+        <>
+          Endpoint URL: absolute or relative to Base URL. Use <code>{"{{.Cursor}}"}</code> for the incremental cursor
+          value
+        </>
+      ),
+    },
+    {
+      displayName: "HTTP Method",
+      id: "method",
+      type: selectionType(["GET", "POST"], 1),
+      defaultValue: "GET",
+      required: false,
+      documentation: <>HTTP method of requests</>,
+    },
+    {
+      displayName: "Request Body",
+      id: "body",
+      type: jsonType,
+      required: false,
+      documentation: (
+        <>
+          JSON request body template (POST only). Use <code>{"{{.Cursor}}"}</code> for the incremental cursor value
+        </>
+      ),
+    },
+    {
+      displayName: "Query Parameters",
+      id: "query_parameters",
+      type: jsonType,
+      required: false,
+      documentation: (
+        <>
+          JSON object of query parameters templates, e.g. <code>{'{"updated_since": "{{.Cursor}}"}'}</code>
+        </>
+      ),
+    },
+    {
+      displayName: "Records Path",
+      id: "records_path",
+      type: stringType,
+      required: false,
+      documentation: (
+        <>
+          JSON path of records array in the response, e.g. <code>/data/items</code>. Empty value means the response
+          is an array
+        </>
+      ),
+    },
+    {
+      displayName: "Pagination",
+      id: "pagination.type",
+      type: selectionType(["none", "cursor", "offset", "link_header"], 1),
+      defaultValue: "none",
+      required: false,
+      documentation: (
+        <>
+          <b>cursor</b>: the next page cursor (or URL) is read from the response. <b>offset</b>: offset and limit
+          query parameters. <b>link_header</b>: the next page URL is read from <code>Link</code> header
+        </>
+      ),
+    },
+    {
+      displayName: "Next Page Cursor Path",
+      id: "pagination.next_cursor_path",
+      type: stringType,
+      required: false,
+      documentation: <>JSON path of the next page cursor in the response (cursor pagination), e.g. /meta/next</>,
+    },
+    {
+      displayName: "Next Page Cursor Parameter",
+      id: "pagination.cursor_parameter",
+      type: stringType,
+      defaultValue: "cursor",
+      required: false,
+      documentation: <>Query parameter name of the next page cursor (cursor pagination)</>,
+    },
+    {
+      displayName: "Page Size",
+      id: "pagination.page_size",
+      type: intType,
+      defaultValue: 100,
+      required: false,
+      documentation: <>Value of limit query parameter (offset pagination)</>,
+    },
+    {
+      displayName: "Incremental Cursor Field",
+      id: "cursor_field",
+      type: stringType,
+      required: false,
+      documentation: (
+        <>
+          JSON path of the record field (e.g. <code>/updated_at</code>) which max value is saved after every sync and is
+          used as <code>{"{{.Cursor}}"}</code> in the next sync. Empty value means all data is reloaded on every sync
+        </>
+      ),
+    },
+    {
+      displayName: "Initial Cursor",
+      id: "initial_cursor",
+      type: stringType,
+      required: false,
+      documentation: <>Cursor value of the first sync</>,
+    },
+  ],
+  configParameters: [
+    {
+      displayName: "Base URL",
+      id: "config.base_url",
+      type: stringType,
+      required: false,
+      documentation: <>Base URL of relative collection URLs, e.g. https://api.example.com/v1</>,
+    },
+    {
+      displayName: "Headers",
+      id: "config.headers",
+      type: jsonType,
+      required: false,
+      documentation: <>JSON object of HTTP headers which are sent with every request</>,
+    },
+    {
+      displayName: "Authorization",
+      id: "config.auth.type",
+      type: selectionType(["bearer", "basic", "oauth2"], 1),
+      required: false,
+      documentation: (
+        <>
+          <b>bearer</b>: token. <b>basic</b>: username and password. <b>oauth2</b>: OAuth2 client credentials flow
+        </>
+      ),
+    },
+    {
+      displayName: "Bearer Token",
+      id: "config.auth.token",
+      type: passwordType,
+      required: false,
+      documentation: <>Token of bearer authorization</>,
+    },
+    {
+      displayName: "Username",
+      id: "config.auth.username",
+      type: stringType,
+      required: false,
+      documentation: <>Username of basic authorization</>,
+    },
+    {
+      displayName: "Password",
+      id: "config.auth.password",
+      type: passwordType,
+      required: false,
+      documentation: <>Password of basic authorization</>,
+    },
+    {
+      displayName: "OAuth2 Client ID",
+      id: "config.auth.client_id",
+      type: stringType,
+      required: false,
+      documentation: <>Client ID of OAuth2 authorization</>,
+    },
+    {
+      displayName: "OAuth2 Client Secret",
+      id: "config.auth.client_secret",
+      type: passwordType,
+      required: false,
+      documentation: <>Client secret of OAuth2 authorization</>,
+    },
+    {
+      displayName: "OAuth2 Token URL",
+      id: "config.auth.token_url",
+      type: stringType,
+      required: false,
+      documentation: <>Token endpoint URL of OAuth2 authorization</>,
+    },
+  ],
+  documentation: {
+    overview: (
+      <>
+        The HTTP API connector pulls JSON data from any REST API: configure the endpoint URL, authorization, pagination
+        and an incremental cursor field. Every collection is a separate endpoint.
+      </>
+    ),
+    connection: (
+      <>
+        See configuration examples in the{" "}
+        <a target="_blank" href="https://jitsu.com/docs/sources/http-api">
+          documentation
+        </a>
+        .
+      </>
+    ),
+  },
+}
+
+export const googleSheets: SourceConnector = {
+  pic: logos.tap_google_sheets,
+  displayName: "Google Sheets",
+  id: "google_sheets",
+  collectionTypes: [],
+  collectionParameters: [
+    {
+      displayName: "Range",
+      id: "range",
+      type: stringType,
+      required: true,
+      documentation: (
+        <>
+          Sheet name (e.g. <code>Sheet1</code>) or range in A1 notation (e.g. <code>Sheet1!A1:F</code>)
+        </>
+      ),
+    },
+    {
+      displayName: "Header Row",
+      id: "header_row",
+      type: intType,
+      defaultValue: 1,
+      required: false,
+      documentation: <>Number of the range row with column names. Rows above it are skipped</>,
+    },
+    {
+      displayName: "Formatted Values",
+      id: "formatted",
+      type: booleanType,
+      defaultValue: false,
+      required: false,
+      documentation: <>If enabled, values are synced as they are displayed in the sheet (e.g. "$1.00" instead of 1)</>,
+    },
+  ],
+  configParameters: [
+    ...googleAuthConfigParameters({
+      oauthSecretsRequired: false,
+    }),
+    {
+      displayName: "Spreadsheet ID",
+      id: "config.spreadsheet_id",
+      type: stringType,
+      required: true,
+      documentation: (
+        <>
+          Spreadsheet ID is a part of the spreadsheet URL: https://docs.google.com/spreadsheets/d/
+          <b>spreadsheet_id</b>/edit
+        </>
+      ),
+    },
+  ],
+  documentation: {
+    overview: (
+      <>
+        The Google Sheets connector syncs sheet ranges into destination tables. The first row of the range contains
+        column names, every next row is a table row. Data is fully reloaded on every sync
+      </>
+    ),
+    connection: googleServiceAuthDocumentation({
+      oauthEnabled: true,
+      serviceAccountEnabled: true,
+      scopes: ["https://www.googleapis.com/auth/spreadsheets.readonly"],
+      serviceName: "Google Sheets",
+      apis: ["Google Sheets API"],
+    }),
+  },
+}
+
+export const allNativeConnectors = [
+  facebook,
+  redis,
+  firebase,
+  googleAds,
+  googleAnalytics,
+  googlePlay,
+  amplitude,
+  postgresCdc,
+  mysqlCdc,
+  httpApi,
+  googleSheets,
+]
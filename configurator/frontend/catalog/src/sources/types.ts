@@ -314,6 +314,10 @@ type SourceConnectorId =
   | "firebase"
   | "redis"
   | "amplitude"
+  | "postgres_cdc"
+  | "mysql_cdc"
+  | "http_api"
+  | "google_sheets"
   | `singer-${string}`
   | `airbyte-source-${string}`
   | `sdk-${string}`
@@ -1,4 +1,4 @@
-import { Button, Form, Input, InputNumber } from "antd"
+import { Button, Form, Input, InputNumber, Select } from "antd"
 import { observer } from "mobx-react-lite"
 import { apiKeysStore } from "../../../stores/apiKeys"
 import { Prompt, useHistory, useParams } from "react-router-dom"
@@ -55,7 +55,12 @@ const SecretKey: React.FC<{
   )
 }
 
-type EditorObject = Omit<ApiKey, "origins"> & { originsText?: string; connectedDestinations?: string[] }
+type EditorObject = Omit<ApiKey, "origins"> & {
+  originsText?: string
+  connectedDestinations?: string[]
+  validationSchemaText?: string
+  validationMode?: ApiKeyValidation["mode"]
+}
 
 function getEditorObject({ origins, ...rest }: ApiKey) {
   return {
@@ -63,14 +68,38 @@ function getEditorObject({ origins, ...rest }: ApiKey) {
     comment: rest.comment || rest.uid,
     originsText: origins ? origins.join("\n") : "",
     connectedDestinations: destinationsStore.list.filter(d => d._onlyKeys.includes(rest.uid)).map(d => d._uid),
+    validationSchemaText: rest.validation?.schema ? JSON.stringify(rest.validation.schema, null, 2) : "",
+    validationMode: rest.validation?.mode || "reject",
+  }
+}
+
+function getValidation(
+  validationSchemaText: string | undefined,
+  validationMode: ApiKeyValidation["mode"],
+  initialValue: ApiKeyValidation | undefined
+): ApiKeyValidation | undefined {
+  const validation: ApiKeyValidation = { ...initialValue, mode: validationMode }
+  if (validationSchemaText && validationSchemaText.trim() !== "") {
+    try {
+      validation.schema = JSON.parse(validationSchemaText)
+    } catch (e) {
+      throw new Error(`Events JSON Schema isn't a valid JSON: ${e.message}`)
+    }
+  } else {
+    delete validation.schema
   }
+  return validation.schema || validation.required_fields?.length || validation.event_types ? validation : undefined
 }
 
-function getKey({ originsText, connectedDestinations, ...rest }: EditorObject, initialValue: ApiKey) {
+function getKey(
+  { originsText, connectedDestinations, validationSchemaText, validationMode, ...rest }: EditorObject,
+  initialValue: ApiKey
+) {
   return {
     ...initialValue,
     ...rest,
     origins: originsText && originsText.trim() !== "" ? originsText.split("\n").map(line => line.trim()) : [],
+    validation: getValidation(validationSchemaText, validationMode, initialValue.validation),
   }
 }
 
@@ -179,7 +208,8 @@ const ApiKeyEditorComponent: React.FC = props => {
                     <a target="_blank" href="https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS">
                       CORS headers
                     </a>
-                    . Leave empty for accept traffic from any domain. Wildcard syntax (<code>*.domain.com</code>) is
+                    : browser requests from other origins are rejected. Leave empty for accept traffic from any domain.
+                    Wildcard syntax (<code>*.domain.com</code> for subdomains, <code>domain*</code> for prefixes) is
                     accepted. Put each domain on a new line
                   </>
                 }
@@ -208,6 +238,42 @@ const ApiKeyEditorComponent: React.FC = props => {
                   />
                 </Form.Item>
               </FormField>
+              <FormField
+                label="Events JSON Schema"
+                tooltip={
+                  <>
+                    JSON Schema which all events sent with this key must match. Leave empty to accept any events. See{" "}
+                    <a target="_blank" href="https://jitsu.com/docs/other-features/events-validation">
+                      Events Validation
+                    </a>
+                  </>
+                }
+                key="validationSchemaText"
+              >
+                <Form.Item name="validationSchemaText">
+                  <TextArea
+                    disabled={disableEdit}
+                    required={false}
+                    size="large"
+                    rows={10}
+                    name="validationSchemaText"
+                    className="font-mono"
+                    placeholder={'{"type": "object", "required": ["event_type"]}'}
+                  />
+                </Form.Item>
+              </FormField>
+              <FormField
+                label="Invalid Events"
+                tooltip="Reject: requests with invalid events are rejected with HTTP 422 error. Quarantine: invalid events are stored into 'events_quarantine' table of SQL destinations with validation errors"
+                key="validationMode"
+              >
+                <Form.Item name="validationMode">
+                  <Select disabled={disableEdit} size="large">
+                    <Select.Option value="reject">Reject</Select.Option>
+                    <Select.Option value="quarantine">Quarantine</Select.Option>
+                  </Select>
+                </Form.Item>
+              </FormField>
               <FormField
                 label="Connected Destinations"
                 tooltip={
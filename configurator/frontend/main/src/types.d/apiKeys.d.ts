@@ -4,4 +4,34 @@ declare interface ApiKey {
   origins: string[]
   serverAuth: string
   uid: string
+  validation?: ApiKeyValidation
+  routing?: ApiKeyRouting
+}
+
+declare interface ApiKeyValidationRules {
+  schema?: any
+  required_fields?: string[]
+}
+
+declare interface ApiKeyValidation extends ApiKeyValidationRules {
+  event_types?: { [eventType: string]: ApiKeyValidationRules }
+  mode?: "reject" | "quarantine"
+  quarantine_table?: string
+}
+
+declare interface ApiKeyRoutingRule {
+  name?: string
+  when?: {
+    event_types?: string[]
+    hosts?: string[]
+    fields?: { path: string; values?: string[] }[]
+  }
+  destinations: string[]
+  table?: string
+}
+
+declare interface ApiKeyRouting {
+  rules?: ApiKeyRoutingRule[]
+  unmatched_destinations?: string[]
+  drop_unmatched?: boolean
 }
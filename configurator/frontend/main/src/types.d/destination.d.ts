@@ -20,4 +20,12 @@ declare interface DestinationData {
   }
   _onlyKeys: string[]
   _sources?: string[]
+  _consent?: DestinationConsent
+}
+
+declare interface DestinationConsent {
+  _categories?: string[]
+  _mode?: "drop" | "strip"
+  _strip_fields?: string[]
+  _missing?: "allow" | "deny"
 }
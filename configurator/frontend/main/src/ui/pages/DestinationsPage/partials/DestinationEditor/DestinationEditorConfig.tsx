@@ -5,7 +5,7 @@ import debounce from "lodash/debounce"
 // @Components
 import { ConfigurableFieldsForm } from "ui/components/ConfigurableFieldsForm/ConfigurableFieldsForm"
 // @Types
-import { Destination } from "@jitsu/catalog"
+import { consentParameters, Destination } from "@jitsu/catalog"
 import { FormInstance } from "antd/lib/form/hooks/useForm"
 import useProject from "../../../../../hooks/useProject"
 import { allPermissions } from "../../../../../lib/services/permissions"
@@ -27,7 +27,7 @@ const DestinationEditorConfig = ({ destinationData, destinationReference, form,
       <Form disabled={disableEdit} name="destination-config" form={form} autoComplete="off" onChange={handleChange}>
         <ConfigurableFieldsForm
           handleTouchAnyField={handleTouchAnyField}
-          fieldsParamsList={destinationReference.parameters}
+          fieldsParamsList={[...destinationReference.parameters, ...consentParameters]}
           form={form}
           initialValues={destinationData}
         />
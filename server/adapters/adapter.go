@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
 	"io"
 	"regexp"
 )
@@ -27,6 +28,39 @@ type SQLAdapter interface {
 	ReplaceTable(originalTable, replacementTable string, dropOldTable bool) error
 }
 
+//ColumnTypeMigrator is a SQLAdapter capability to change type of the existing column (e.g. bigint -> text)
+//it is implemented only by adapters which can do it without data loss
+type ColumnTypeMigrator interface {
+	AlterColumnType(table *Table, columnName string, column typing.SQLColumn) error
+}
+
+const (
+	//MaterializedTable recreates a table with SELECT query results on every run
+	MaterializedTable = "table"
+	//MaterializedView creates or replaces a view with SELECT query
+	MaterializedView = "view"
+)
+
+//Materializer is a SQLAdapter capability to materialize SELECT query results in the warehouse
+//as a table or a view (materialized: MaterializedTable or MaterializedView) with the name
+type Materializer interface {
+	Materialize(name, query, materialized string) error
+}
+
+const (
+	//EraseDelete deletes rows with the identifier
+	EraseDelete = "delete"
+	//EraseAnonymize sets identifier columns to NULL in rows with the identifier (rows are kept for aggregated reports)
+	EraseAnonymize = "anonymize"
+)
+
+//Eraser is a SQLAdapter capability to erase personal data (right to be forgotten): deletes rows (mode: EraseDelete)
+//or sets columns to NULL (mode: EraseAnonymize) where any of the columns equals to the identifier value.
+//Columns which don't exist in the table are skipped. Returns amount of affected rows or -1 if it is unknown
+type Eraser interface {
+	Erase(tableName string, columns []string, value string, mode string) (int64, error)
+}
+
 //Adapter is an adapter for all destinations
 type Adapter interface {
 	io.Closer
@@ -2,6 +2,7 @@ package adapters
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/errorj"
@@ -10,21 +11,28 @@ import (
 	"github.com/jitsucom/jitsu/server/uuid"
 	_ "github.com/lib/pq"
 	"strings"
+	"time"
 )
 
 const (
-	copyTemplate = `copy "%s"."%s"
+	copyTemplate = `copy "%s"."%s"%s
 					from 's3://%s/%s'
-    				ACCESS_KEY_ID '%s'
-    				SECRET_ACCESS_KEY '%s'
-    				region '%s'
+    				%s
+    				region '%s'`
+	copyJSONFormat = `
     				json 'auto'
                     dateformat 'auto'
                     timeformat 'auto'`
+	copyCSVFormat = `
+    				csv ignoreheader 1 emptyasnull
+                    dateformat 'auto'
+                    timeformat 'auto'`
 
 	deleteBeforeBulkMergeUsing     = `DELETE FROM "%s"."%s" using "%s"."%s" where %s`
 	deleteBeforeBulkMergeCondition = `"%s"."%s".%s = "%s"."%s".%s`
 	redshiftBulkMergeInsert        = `INSERT INTO "%s"."%s" (%s) select %s from "%s"."%s"`
+	redshiftDistKeyTemplate        = ` DISTKEY("%s")`
+	redshiftSortKeyTemplate        = ` COMPOUND SORTKEY(%s)`
 
 	primaryKeyFieldsRedshiftQuery = `select tco.constraint_name as constraint_name, kcu.column_name as key_column
 									 from information_schema.table_constraints tco
@@ -35,6 +43,9 @@ const (
 				                     where tco.table_schema = $1 and tco.table_name = $2 and tco.constraint_type = 'PRIMARY KEY'
                                      order by kcu.ordinal_position`
 
+	copyCredentialsTemplate  = `ACCESS_KEY_ID '%s' SECRET_ACCESS_KEY '%s'`
+	copySessionTokenTemplate = ` SESSION_TOKEN '%s'`
+
 	RedshiftValuesLimit = 32767 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
 	credentialsMask     = "*****"
 )
@@ -88,25 +99,82 @@ func (ar *AwsRedshift) OpenTx() (*Transaction, error) {
 }
 
 //Copy transfer data from s3 to redshift by passing COPY request to redshift
-func (ar *AwsRedshift) Copy(fileKey, tableName string) error {
-	//add folder prefix if configured
-	if ar.s3Config.Folder != "" {
-		fileKey = ar.s3Config.Folder + "/" + fileKey
+//header is the columns order of the csv file. It is used only with FileFormatCSV
+func (ar *AwsRedshift) Copy(fileKey, tableName string, format FileEncodingFormat, header []string) error {
+	columns, formatOption, err := redshiftCopyFormat(format, header)
+	if err != nil {
+		return errorj.CopyError.Wrap(err, "failed to build copy statement").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema: ar.dataSourceProxy.config.Schema,
+				Table:  tableName,
+			})
 	}
 
-	statement := fmt.Sprintf(copyTemplate, ar.dataSourceProxy.config.Schema, tableName, ar.s3Config.Bucket, fileKey, ar.s3Config.AccessKeyID, ar.s3Config.SecretKey, ar.s3Config.Region)
+	//add folder prefix and compression extension if configured
+	fileKey = ar.s3Config.ObjectKey(fileKey)
+	options := formatOption + redshiftCompressionOption(ar.s3Config.Compression)
+
+	//static or temporary credentials of the default AWS credential chain (with session token)
+	awsCredentials, err := ar.s3Config.Credentials()
+	if err != nil {
+		return errorj.CopyError.Wrap(err, "failed to get aws credentials").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema: ar.dataSourceProxy.config.Schema,
+				Table:  tableName,
+			})
+	}
+	copyCredentials := fmt.Sprintf(copyCredentialsTemplate, awsCredentials.AccessKeyID, awsCredentials.SecretAccessKey)
+	maskedCopyCredentials := fmt.Sprintf(copyCredentialsTemplate, credentialsMask, credentialsMask)
+	if awsCredentials.SessionToken != "" {
+		copyCredentials += fmt.Sprintf(copySessionTokenTemplate, awsCredentials.SessionToken)
+		maskedCopyCredentials += fmt.Sprintf(copySessionTokenTemplate, credentialsMask)
+	}
+
+	statement := fmt.Sprintf(copyTemplate, ar.dataSourceProxy.config.Schema, tableName, columns, ar.s3Config.Bucket, fileKey, copyCredentials, ar.s3Config.Region) + options
 	if _, err := ar.dataSourceProxy.dataSource.ExecContext(ar.dataSourceProxy.ctx, statement); err != nil {
 		return errorj.CopyError.Wrap(err, "failed to copy data from s3").
 			WithProperty(errorj.DBInfo, &ErrorPayload{
 				Schema:    ar.dataSourceProxy.config.Schema,
 				Table:     tableName,
-				Statement: fmt.Sprintf(copyTemplate, ar.dataSourceProxy.config.Schema, tableName, ar.s3Config.Bucket, fileKey, credentialsMask, credentialsMask, ar.s3Config.Region),
+				Statement: fmt.Sprintf(copyTemplate, ar.dataSourceProxy.config.Schema, tableName, columns, ar.s3Config.Bucket, fileKey, maskedCopyCredentials, ar.s3Config.Region) + options,
 			})
 	}
 
 	return nil
 }
 
+//redshiftCopyFormat returns COPY command column list and format parameters of the staged file.
+//Columnar formats aren't supported: Redshift maps parquet columns by position and requires all table columns
+func redshiftCopyFormat(format FileEncodingFormat, header []string) (string, string, error) {
+	switch format {
+	case "", FileFormatJSON, FileFormatFlatJSON:
+		return "", copyJSONFormat, nil
+	case FileFormatCSV:
+		if len(header) == 0 {
+			return "", "", errors.New("csv header is required")
+		}
+		quotedColumnNames := make([]string, 0, len(header))
+		for _, columnName := range header {
+			quotedColumnNames = append(quotedColumnNames, fmt.Sprintf(`"%s"`, columnName))
+		}
+		return " (" + strings.Join(quotedColumnNames, ",") + ")", copyCSVFormat, nil
+	default:
+		return "", "", fmt.Errorf("unsupported staging format [%s]. Available: [%s, %s]", format, FileFormatJSON, FileFormatCSV)
+	}
+}
+
+//redshiftCompressionOption returns COPY command compression parameter
+func redshiftCompressionOption(compression FileCompression) string {
+	switch compression {
+	case FileCompressionGZIP:
+		return " gzip"
+	case FileCompressionZSTD:
+		return " zstd"
+	default:
+		return ""
+	}
+}
+
 //CreateDbSchema create database schema instance if doesn't exist
 func (ar *AwsRedshift) CreateDbSchema(dbSchemaName string) error {
 	query := fmt.Sprintf(createDbSchemaIfNotExistsTemplate, dbSchemaName)
@@ -181,7 +249,12 @@ func (ar *AwsRedshift) insertBatch(insertContext *InsertContext) (err error) {
 }
 
 //insertSingle inserts single provided object in Redshift with typecasts
+//uses staging table + delete/insert if primary keys are configured
 func (ar *AwsRedshift) insertSingle(eventContext *EventContext) error {
+	if len(eventContext.Table.PKFields) > 0 {
+		return ar.insertBatch(NewBatchInsertContext(eventContext.Table, []map[string]interface{}{eventContext.ProcessedEvent}, true, nil))
+	}
+
 	_, quotedColumnNames, placeholders, values := ar.dataSourceProxy.buildInsertPayload(eventContext.Table, eventContext.ProcessedEvent)
 
 	statement := fmt.Sprintf(insertTemplate, ar.dataSourceProxy.config.Schema, eventContext.Table.Name, strings.Join(quotedColumnNames, ", "), "("+strings.Join(placeholders, ", ")+")")
@@ -287,7 +360,43 @@ func (ar *AwsRedshift) CreateTable(tableSchema *Table) error {
 		}
 	}()
 
-	return ar.dataSourceProxy.createTableInTransaction(wrappedTx, tableSchema)
+	return ar.createTableInTransaction(wrappedTx, tableSchema)
+}
+
+//createTableInTransaction creates table with DISTKEY and COMPOUND SORTKEY from the table layout (if configured)
+//and primary key
+func (ar *AwsRedshift) createTableInTransaction(wrappedTx *Transaction, table *Table) error {
+	if table.Layout == nil || (table.Layout.DistKey == "" && len(table.Layout.SortKeys) == 0) {
+		return ar.dataSourceProxy.createTableInTransaction(wrappedTx, table)
+	}
+
+	var columnsDDL []string
+	pkFields := table.GetPKFieldsMap()
+	for _, columnName := range table.SortedColumnNames() {
+		columnsDDL = append(columnsDDL, ar.dataSourceProxy.columnDDL(columnName, table.Columns[columnName], pkFields))
+	}
+
+	query := fmt.Sprintf(createTableTemplate, ar.dataSourceProxy.config.Schema, table.Name, strings.Join(columnsDDL, ", "))
+	if table.Layout.DistKey != "" {
+		query += fmt.Sprintf(redshiftDistKeyTemplate, table.Layout.DistKey)
+	}
+	if len(table.Layout.SortKeys) > 0 {
+		query += fmt.Sprintf(redshiftSortKeyTemplate, `"`+strings.Join(table.Layout.SortKeys, `", "`)+`"`)
+	}
+	ar.dataSourceProxy.queryLogger.LogDDL(query)
+
+	if _, err := wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, query); err != nil {
+		err = checkErr(err)
+		return errorj.CreateTableError.Wrap(err, "failed to create table").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:      ar.dataSourceProxy.config.Schema,
+				Table:       table.Name,
+				PrimaryKeys: table.GetPKFields(),
+				Statement:   query,
+			})
+	}
+
+	return ar.dataSourceProxy.createPrimaryKeyInTransaction(wrappedTx, table)
 }
 
 //Update one record in Redshift
@@ -418,6 +527,43 @@ func (ar *AwsRedshift) recreateNotNullColumnInTransaction(wrappedTx *Transaction
 	return nil
 }
 
+//AlterColumnType changes column type in transaction: create tmp column with the new type -> copy all values with cast ->
+//delete old column -> rename tmp column (Redshift doesn't support ALTER COLUMN TYPE except varchar length)
+func (ar *AwsRedshift) AlterColumnType(table *Table, columnName string, column typing.SQLColumn) error {
+	wrappedTx, err := ar.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	schema := ar.dataSourceProxy.config.Schema
+	tmpColumnName := columnName + "_tmp"
+	queries := []struct {
+		statement string
+		msg       string
+	}{
+		{fmt.Sprintf(addColumnTemplate, schema, table.Name, ar.dataSourceProxy.columnDDL(tmpColumnName, column, map[string]bool{})), "failed to create tmp column"},
+		{fmt.Sprintf(copyColumnTemplate, schema, table.Name, tmpColumnName, fmt.Sprintf(`"%s"::%s`, columnName, column.DDLType())), "failed to copy column data"},
+		{fmt.Sprintf(dropColumnTemplate, schema, table.Name, columnName), "failed to drop old column"},
+		{fmt.Sprintf(renameColumnTemplate, schema, table.Name, tmpColumnName, columnName), "failed to rename tmp column"},
+	}
+
+	for _, query := range queries {
+		ar.dataSourceProxy.queryLogger.LogDDL(query.statement)
+		if _, err := wrappedTx.tx.ExecContext(ar.dataSourceProxy.ctx, query.statement); err != nil {
+			err = checkErr(err)
+			rbErr := wrappedTx.Rollback()
+			return errorj.Group(errorj.PatchTableError.Wrap(err, query.msg).
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema:    schema,
+					Table:     table.Name,
+					Statement: query.statement,
+				}), rbErr)
+		}
+	}
+
+	return wrappedTx.Commit()
+}
+
 //Truncate deletes all records in tableName table
 func (ar *AwsRedshift) Truncate(tableName string) error {
 	return ar.dataSourceProxy.Truncate(tableName)
@@ -432,6 +578,21 @@ func (ar *AwsRedshift) ReplaceTable(originalTable, replacementTable string, drop
 	return ar.dataSourceProxy.ReplaceTable(originalTable, replacementTable, true)
 }
 
+//Expire deletes (or counts if dryRun) expired rows uses underlying postgres datasource
+func (ar *AwsRedshift) Expire(tableName, column string, threshold time.Time, dryRun bool) (int64, error) {
+	return ar.dataSourceProxy.Expire(tableName, column, threshold, dryRun)
+}
+
+//Erase deletes rows or sets columns to NULL uses underlying postgres datasource
+func (ar *AwsRedshift) Erase(tableName string, columns []string, value string, mode string) (int64, error) {
+	return ar.dataSourceProxy.Erase(tableName, columns, value, mode)
+}
+
+//Materialize creates a view or recreates a table with the query results uses underlying postgres datasource
+func (ar *AwsRedshift) Materialize(name, query, materialized string) error {
+	return ar.dataSourceProxy.Materialize(name, query, materialized)
+}
+
 //bulkMergeInTransaction uses temporary table and insert from select statement
 func (ar *AwsRedshift) bulkMergeInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
 	tmpTable := &Table{
@@ -0,0 +1,25 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedshiftCopyFormat(t *testing.T) {
+	columns, options, err := redshiftCopyFormat(FileFormatJSON, nil)
+	require.NoError(t, err)
+	require.Equal(t, "", columns)
+	require.Contains(t, options, "json 'auto'")
+
+	columns, options, err = redshiftCopyFormat(FileFormatCSV, []string{"event_id", "user_email"})
+	require.NoError(t, err)
+	require.Equal(t, ` ("event_id","user_email")`, columns)
+	require.Contains(t, options, "csv ignoreheader 1 emptyasnull")
+
+	_, _, err = redshiftCopyFormat(FileFormatCSV, nil)
+	require.Error(t, err)
+
+	_, _, err = redshiftCopyFormat(FileFormatParquet, nil)
+	require.EqualError(t, err, "unsupported staging format [parquet]. Available: [json, csv]")
+}
@@ -0,0 +1,205 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/jitsucom/jitsu/server/errorj"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"go.uber.org/atomic"
+)
+
+const azureBlobEndpointTemplate = "https://%s.blob.core.windows.net"
+
+//AzureBlobConfig is a dto for Azure Blob Storage config deserialization
+type AzureBlobConfig struct {
+	AccountName string `mapstructure:"account_name,omitempty" json:"account_name,omitempty" yaml:"account_name,omitempty"`
+	//AccountKey is a shared key of the storage account. Either AccountKey or SASToken is required
+	AccountKey string `mapstructure:"account_key,omitempty" json:"account_key,omitempty" yaml:"account_key,omitempty"`
+	SASToken   string `mapstructure:"sas_token,omitempty" json:"sas_token,omitempty" yaml:"sas_token,omitempty"`
+	Container  string `mapstructure:"container,omitempty" json:"container,omitempty" yaml:"container,omitempty"`
+	//Endpoint is a custom blob service endpoint (sovereign clouds, Azurite, etc.). Default: https://$account_name.blob.core.windows.net
+	Endpoint string `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	//EncryptionScope is a name of the encryption scope (with a customer-managed key) which encrypts uploaded blobs
+	EncryptionScope string `mapstructure:"encryption_scope,omitempty" json:"encryption_scope,omitempty" yaml:"encryption_scope,omitempty"`
+	FileConfig      `mapstructure:",squash" yaml:"-,inline"`
+}
+
+//Validate returns err if invalid
+func (ac *AzureBlobConfig) Validate() error {
+	if ac == nil {
+		return errors.New("Azure Blob Storage config is required")
+	}
+	if ac.AccountName == "" {
+		return errors.New("Azure account_name is required parameter")
+	}
+	if ac.AccountKey == "" && ac.SASToken == "" {
+		return errors.New("Azure account_key or sas_token is required parameter")
+	}
+	if ac.Container == "" {
+		return errors.New("Azure container is required parameter")
+	}
+	return ac.ValidateCompression()
+}
+
+//AzureBlob is an Azure Blob Storage adapter for uploading/deleting files
+type AzureBlob struct {
+	config    *AzureBlobConfig
+	container azblob.ContainerURL
+	ctx       context.Context
+
+	closed *atomic.Bool
+}
+
+//NewAzureBlob returns configured AzureBlob adapter
+func NewAzureBlob(ctx context.Context, config *AzureBlobConfig) (*AzureBlob, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(azureBlobEndpointTemplate, config.AccountName)
+	}
+	containerURL, err := url.Parse(strings.TrimSuffix(endpoint, "/") + "/" + config.Container)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing Azure Blob Storage endpoint [%s]: %v", endpoint, err)
+	}
+
+	var credential azblob.Credential
+	if config.AccountKey != "" {
+		credential, err = azblob.NewSharedKeyCredential(config.AccountName, config.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating Azure Blob Storage credentials: %v", err)
+		}
+	} else {
+		credential = azblob.NewAnonymousCredential()
+		containerURL.RawQuery = strings.TrimPrefix(config.SASToken, "?")
+	}
+
+	if config.Format == "" {
+		config.Format = FileFormatFlatJSON
+	}
+
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	return &AzureBlob{config: config, container: azblob.NewContainerURL(*containerURL, pipeline), ctx: ctx, closed: atomic.NewBool(false)}, nil
+}
+
+func (ab *AzureBlob) Format() FileEncodingFormat {
+	return ab.config.Format
+}
+
+func (ab *AzureBlob) Compression() FileCompression {
+	return ab.config.Compression
+}
+
+//UploadBytes creates named blob in the container with payload
+func (ab *AzureBlob) UploadBytes(fileName string, fileBytes []byte) error {
+	if ab.closed.Load() {
+		return fmt.Errorf("attempt to use closed AzureBlob instance")
+	}
+
+	if err := ab.config.PrepareFile(&fileName, &fileBytes); err != nil {
+		return err
+	}
+
+	var fileType string
+	switch ab.config.Compression {
+	case FileCompressionGZIP:
+		fileType = "application/gzip"
+	case FileCompressionZSTD:
+		fileType = "application/zstd"
+	default:
+		fileType = http.DetectContentType(fileBytes)
+	}
+
+	options := azblob.UploadToBlockBlobOptions{BlobHTTPHeaders: azblob.BlobHTTPHeaders{ContentType: fileType}}
+	if ab.config.EncryptionScope != "" {
+		options.ClientProvidedKeyOptions = azblob.ClientProvidedKeyOptions{EncryptionScope: &ab.config.EncryptionScope}
+	}
+	if _, err := azblob.UploadBufferToBlockBlob(ab.ctx, fileBytes, ab.container.NewBlockBlobURL(fileName), options); err != nil {
+		return errorj.SaveOnStageError.Wrap(err, "failed to write file to azure blob storage").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    ab.config.Container,
+				Statement: fmt.Sprintf("file: %s", fileName),
+			})
+	}
+
+	return nil
+}
+
+//DeleteObject deletes blob from the container by key
+func (ab *AzureBlob) DeleteObject(key string) error {
+	if ab.closed.Load() {
+		return fmt.Errorf("attempt to use closed AzureBlob instance")
+	}
+
+	_ = ab.config.PrepareFile(&key, nil)
+	if _, err := ab.container.NewBlobURL(key).Delete(ab.ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{}); err != nil {
+		return errorj.SaveOnStageError.Wrap(err, "failed to delete from azure blob storage").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    ab.config.Container,
+				Statement: fmt.Sprintf("file: %s", key),
+			})
+	}
+
+	return nil
+}
+
+//ListObjects returns keys of all blobs with the prefix (relative to the configured folder).
+//Reads all pages of the container listing
+func (ab *AzureBlob) ListObjects(prefix string) ([]string, error) {
+	if ab.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed AzureBlob instance")
+	}
+
+	folderPrefix := ab.config.folderPrefix()
+	var keys []string
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		page, err := ab.container.ListBlobsFlatSegment(ab.ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: folderPrefix + prefix})
+		if err != nil {
+			return nil, errorj.SaveOnStageError.Wrap(err, "failed to list objects in azure blob storage").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Bucket:    ab.config.Container,
+					Statement: fmt.Sprintf("prefix: %s", prefix),
+				})
+		}
+
+		for _, blob := range page.Segment.BlobItems {
+			keys = append(keys, strings.TrimPrefix(blob.Name, folderPrefix))
+		}
+		marker = page.NextMarker
+	}
+
+	return keys, nil
+}
+
+//ValidateWritePermission tries to create temporary file and remove it.
+//returns nil if file creation was successful.
+func (ab *AzureBlob) ValidateWritePermission() error {
+	filename := fmt.Sprintf("test_%v", timestamp.NowUTC())
+
+	if err := ab.UploadBytes(filename, []byte{}); err != nil {
+		return err
+	}
+
+	if err := ab.DeleteObject(filename); err != nil {
+		logging.Warnf("Cannot remove object %q from Azure Blob Storage: %v", filename, err)
+		// Suppressing error because we need to check only write permission
+		// return err
+	}
+
+	return nil
+}
+
+//Close returns nil
+func (ab *AzureBlob) Close() error {
+	ab.closed.Store(true)
+	return nil
+}
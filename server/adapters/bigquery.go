@@ -21,8 +21,11 @@ import (
 )
 
 const (
-	deleteBigQueryTemplate   = "DELETE FROM `%s.%s.%s` WHERE %s"
-	truncateBigQueryTemplate = "TRUNCATE TABLE `%s.%s.%s`"
+	deleteBigQueryTemplate      = "DELETE FROM `%s.%s.%s` WHERE %s"
+	countBigQueryTemplate       = "SELECT count(*) FROM `%s.%s.%s` WHERE %s"
+	truncateBigQueryTemplate    = "TRUNCATE TABLE `%s.%s.%s`"
+	materializeBigQueryTemplate = "CREATE OR REPLACE %s `%s.%s.%s` AS %s"
+	eraseUpdateBigQueryTemplate = "UPDATE `%s.%s.%s` SET %s WHERE %s"
 
 	rowsLimitPerInsertOperation = 500
 )
@@ -66,12 +69,24 @@ func NewBigQuery(ctx context.Context, config *GoogleConfig, queryLogger *logging
 	return &BigQuery{ctx: ctx, client: client, config: config, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToBigQueryString)}, nil
 }
 
-// Copy transfers data from google cloud storage file to google BigQuery table as one batch
-func (bq *BigQuery) Copy(fileKey, tableName string) error {
+// Copy transfers data from google cloud storage file to google BigQuery table as one batch.
+// CSV files are loaded by columns positions: the header must be in the table columns order (see GetTableColumnNames)
+func (bq *BigQuery) Copy(fileKey, tableName string, format FileEncodingFormat) error {
 	table := bq.client.Dataset(bq.config.Dataset).Table(tableName)
 
-	gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, fileKey))
-	gcsRef.SourceFormat = bigquery.JSON
+	gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, bq.config.ObjectKey(fileKey)))
+	if err := bigQuerySourceFormat(gcsRef, format); err != nil {
+		return errorj.CopyError.Wrap(err, "failed to configure BQ loader").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset: bq.config.Dataset,
+				Bucket:  bq.config.Bucket,
+				Project: bq.config.Project,
+				Table:   tableName,
+			})
+	}
+	if bq.config.Compression == FileCompressionGZIP {
+		gcsRef.Compression = bigquery.Gzip
+	}
 	loader := table.LoaderFrom(gcsRef)
 	loader.CreateDisposition = bigquery.CreateNever
 
@@ -109,6 +124,23 @@ func (bq *BigQuery) Copy(fileKey, tableName string) error {
 	return nil
 }
 
+// bigQuerySourceFormat configures load job source format of the staged file
+func bigQuerySourceFormat(gcsRef *bigquery.GCSReference, format FileEncodingFormat) error {
+	switch format {
+	case "", FileFormatJSON, FileFormatFlatJSON:
+		gcsRef.SourceFormat = bigquery.JSON
+	case FileFormatCSV:
+		gcsRef.SourceFormat = bigquery.CSV
+		gcsRef.SkipLeadingRows = 1
+	case FileFormatParquet:
+		gcsRef.SourceFormat = bigquery.Parquet
+	default:
+		return fmt.Errorf("unsupported staging format [%s]. Available: [%s, %s, %s]", format, FileFormatJSON, FileFormatCSV, FileFormatParquet)
+	}
+
+	return nil
+}
+
 func (bq *BigQuery) Test() error {
 	_, err := bq.client.Query("SELECT 1;").Read(context.Background())
 	return err
@@ -151,6 +183,27 @@ func (bq *BigQuery) GetTableSchema(tableName string) (*Table, error) {
 	return table, nil
 }
 
+// GetTableColumnNames returns column names in the table schema order
+func (bq *BigQuery) GetTableColumnNames(tableName string) ([]string, error) {
+	meta, err := bq.client.Dataset(bq.config.Dataset).Table(tableName).Metadata(bq.ctx)
+	if err != nil {
+		return nil, errorj.GetTableError.Wrap(err, "failed to get table").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset: bq.config.Dataset,
+				Bucket:  bq.config.Bucket,
+				Project: bq.config.Project,
+				Table:   tableName,
+			})
+	}
+
+	columnNames := make([]string, 0, len(meta.Schema))
+	for _, field := range meta.Schema {
+		columnNames = append(columnNames, field.Name)
+	}
+
+	return columnNames, nil
+}
+
 // CreateTable creates google BigQuery table from Table
 func (bq *BigQuery) CreateTable(table *Table) error {
 	bqTable := bq.client.Dataset(bq.config.Dataset).Table(table.Name)
@@ -194,6 +247,11 @@ func (bq *BigQuery) CreateTable(table *Table) error {
 			partitioningType = bigquery.YearPartitioningType
 		}
 		tableMetaData.TimePartitioning = &bigquery.TimePartitioning{Field: table.Partition.Field, Type: partitioningType}
+	} else if table.Layout != nil && table.Layout.PartitionBy != "" {
+		tableMetaData.TimePartitioning = &bigquery.TimePartitioning{Field: table.Layout.PartitionBy, Type: bigquery.DayPartitioningType}
+	}
+	if table.Layout != nil && len(table.Layout.ClusterBy) > 0 {
+		tableMetaData.Clustering = &bigquery.Clustering{Fields: table.Layout.ClusterBy}
 	}
 	if err := bqTable.Create(bq.ctx, &tableMetaData); err != nil {
 		schemaJson, _ := bqSchema.ToJSONFields()
@@ -449,6 +507,155 @@ func (bq *BigQuery) ReplaceTable(originalTable, replacementTable string, dropOld
 }
 
 // Truncate deletes all records in tableName table
+// Materialize creates or replaces a view or a table with the query results
+func (bq *BigQuery) Materialize(name, query, materialized string) error {
+	objectType := "VIEW"
+	if materialized == MaterializedTable {
+		objectType = "TABLE"
+	}
+
+	statement := fmt.Sprintf(materializeBigQueryTemplate, objectType, bq.config.Project, bq.config.Dataset, name, query)
+	bq.queryLogger.LogQuery(statement)
+	if _, err := bq.client.Query(statement).Read(bq.ctx); err != nil {
+		return errorj.MaterializeError.Wrap(err, "failed to materialize query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset:   bq.config.Dataset,
+				Project:   bq.config.Project,
+				Table:     name,
+				Statement: statement,
+			})
+	}
+
+	return nil
+}
+
+// Expire deletes (or counts if dryRun) rows where the column is less than the threshold
+// Tables with rows in the streaming buffer (inserted during the last ~30 minutes) can't be modified by DML statements
+func (bq *BigQuery) Expire(tableName, column string, threshold time.Time, dryRun bool) (int64, error) {
+	table, err := bq.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	if ok, err := checkRetentionColumn(table, column); !ok {
+		return 0, err
+	}
+
+	condition := "`" + column + "` < @threshold"
+	var statement string
+	var affected int64
+	if dryRun {
+		statement = fmt.Sprintf(countBigQueryTemplate, bq.config.Project, bq.config.Dataset, tableName, condition)
+	} else {
+		statement = fmt.Sprintf(deleteBigQueryTemplate, bq.config.Project, bq.config.Dataset, tableName, condition)
+	}
+	bq.queryLogger.LogQueryWithValues(statement, []interface{}{threshold})
+
+	q := bq.client.Query(statement)
+	q.Parameters = []bigquery.QueryParameter{{Name: "threshold", Value: threshold.UTC()}}
+	if dryRun {
+		affected, err = bq.count(q)
+	} else {
+		affected, err = bq.runDML(q)
+	}
+	if err != nil {
+		return 0, errorj.ExpireError.Wrap(err, "failed to expire data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset:   bq.config.Dataset,
+				Project:   bq.config.Project,
+				Table:     tableName,
+				Statement: statement,
+			})
+	}
+
+	return affected, nil
+}
+
+// count runs SELECT count(*) query and returns the result
+func (bq *BigQuery) count(q *bigquery.Query) (int64, error) {
+	it, err := q.Read(bq.ctx)
+	if err != nil {
+		return 0, err
+	}
+	var row []bigquery.Value
+	if err := it.Next(&row); err != nil {
+		return 0, err
+	}
+	if len(row) == 0 {
+		return 0, errors.New("count query returned empty row")
+	}
+	count, ok := row[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("count query returned %T instead of int64", row[0])
+	}
+
+	return count, nil
+}
+
+// Erase deletes rows or sets columns to NULL where any of the columns equals to the value
+// Tables with rows in the streaming buffer (inserted during the last ~30 minutes) can't be modified by DML statements
+func (bq *BigQuery) Erase(tableName string, columns []string, value string, mode string) (int64, error) {
+	if err := validateEraseMode(mode); err != nil {
+		return 0, err
+	}
+
+	table, err := bq.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	columns = existingColumns(table, columns)
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	query := &eraseQuery{
+		quote:       func(column string) string { return "`" + column + "`" },
+		cast:        func(column string) string { return "CAST(" + column + " AS STRING)" },
+		placeholder: func(i int) string { return "@value" },
+	}
+	statement, _ := query.build(fmt.Sprintf(deleteBigQueryTemplate, bq.config.Project, bq.config.Dataset, tableName, "%s"),
+		fmt.Sprintf(eraseUpdateBigQueryTemplate, bq.config.Project, bq.config.Dataset, tableName, "%s", "%s"), columns, value, mode)
+	bq.queryLogger.LogQueryWithValues(statement, []interface{}{value})
+
+	q := bq.client.Query(statement)
+	q.Parameters = []bigquery.QueryParameter{{Name: "value", Value: value}}
+	affected, err := bq.runDML(q)
+	if err != nil {
+		return 0, errorj.EraseError.Wrap(err, "failed to erase data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset:   bq.config.Dataset,
+				Project:   bq.config.Project,
+				Table:     tableName,
+				Statement: statement,
+			})
+	}
+
+	return affected, nil
+}
+
+// runDML runs DML query and returns amount of affected rows
+func (bq *BigQuery) runDML(q *bigquery.Query) (int64, error) {
+	job, err := q.Run(bq.ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	status, err := job.Wait(bq.ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := status.Err(); err != nil {
+		return 0, err
+	}
+
+	if status.Statistics != nil {
+		if queryStatistics, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok {
+			return queryStatistics.NumDMLAffectedRows, nil
+		}
+	}
+
+	return -1, nil
+}
+
 func (bq *BigQuery) Truncate(tableName string) error {
 	query := fmt.Sprintf(truncateBigQueryTemplate, bq.config.Project, bq.config.Dataset, tableName)
 	bq.queryLogger.LogQuery(query)
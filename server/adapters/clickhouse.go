@@ -9,13 +9,16 @@ import (
 	"fmt"
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"io/ioutil"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/mailru/go-clickhouse"
 )
 
@@ -28,6 +31,7 @@ const (
 
 	insertCHTemplate          = `INSERT INTO "%s"."%s" (%s) VALUES %s`
 	deleteQueryChTemplate     = `ALTER TABLE %s.%s DELETE WHERE %s`
+	countRowsCHTemplate       = `SELECT count() FROM "%s"."%s" WHERE %s`
 	dropTableCHTemplate       = `DROP TABLE %s"%s"."%s" %s`
 	onClusterCHClauseTemplate = ` ON CLUSTER "%s" `
 	columnCHNullableTemplate  = ` Nullable(%s) `
@@ -40,6 +44,8 @@ const (
 	renameDistributedTableCHTemplate   = `RENAME TABLE "%s"."dist_%s" TO "dist_%s"`
 
 	truncateTableCHTemplate            = `TRUNCATE TABLE IF EXISTS "%s"."%s"`
+	createTableAsCHTemplate            = `CREATE TABLE "%s"."%s" ENGINE = MergeTree() ORDER BY tuple() AS %s`
+	createViewCHTemplate               = `CREATE OR REPLACE VIEW "%s"."%s" AS %s`
 	truncateDistributedTableCHTemplate = `TRUNCATE TABLE IF EXISTS "%s"."dist_%s" %s`
 
 	defaultPartition  = `PARTITION BY (toYYYYMM(_timestamp))`
@@ -103,6 +109,9 @@ type ClickHouseConfig struct {
 	TLS      map[string]string `mapstructure:"tls,omitempty" json:"tls,omitempty" yaml:"tls,omitempty"`
 	Cluster  string            `mapstructure:"cluster,omitempty" json:"cluster,omitempty" yaml:"cluster,omitempty"`
 	Engine   *EngineConfig     `mapstructure:"engine,omitempty" json:"engine,omitempty" yaml:"engine,omitempty"`
+	//SSLConfiguration is applied to all https:// DSNs (inline PEM content or file paths)
+	SSLConfiguration *SSLConfig            `mapstructure:"ssl,omitempty" json:"ssl,omitempty" yaml:"ssl,omitempty"`
+	Pool             *ConnectionPoolConfig `mapstructure:"pool,omitempty" json:"pool,omitempty" yaml:"pool,omitempty"`
 }
 
 // EngineConfig dto for deserialized clickhouse engine config
@@ -148,6 +157,47 @@ func (chc *ClickHouseConfig) Validate() error {
 		return errors.New("db is required parameter")
 	}
 
+	if chc.SSLConfiguration != nil {
+		if err := chc.SSLConfiguration.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return chc.Pool.Validate()
+}
+
+// ProcessSSL registers TLS config of every DSN and enriches DSNs with 'tls_config' parameter
+func (chc *ClickHouseConfig) ProcessSSL() error {
+	if chc.SSLConfiguration == nil || chc.SSLConfiguration.Mode == SSLModeDisable {
+		return nil
+	}
+
+	for i, dsn := range chc.Dsns {
+		dsnURL, err := url.Parse(strings.TrimSpace(dsn))
+		if err != nil {
+			return fmt.Errorf("error parsing DSN: %v", err)
+		}
+		if dsnURL.Scheme != "https" {
+			return fmt.Errorf("'ssl' configuration requires https:// DSN: %s", dsnURL.Host)
+		}
+
+		tlsConfig, err := chc.SSLConfiguration.TLSConfig(dsnURL.Hostname())
+		if err != nil {
+			return err
+		}
+
+		//one TLS config per ClickHouse node (the same key is overwritten on destination reloading)
+		tlsConfigName := "jitsu_" + uuid.GetHash(map[string]interface{}{"host": dsnURL.Host})
+		if err := clickhouse.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+			return fmt.Errorf("error registering ClickHouse TLS config: %v", err)
+		}
+
+		query := dsnURL.Query()
+		query.Set("tls_config", tlsConfigName)
+		dsnURL.RawQuery = query.Encode()
+		chc.Dsns[i] = dsnURL.String()
+	}
+
 	return nil
 }
 
@@ -219,6 +269,15 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 	}, nil
 }
 
+// WithPartitionBy returns copy of the factory with PARTITION BY (expression) clause
+// raw engine statement isn't changed (partition_by with engine.raw_statement is rejected on destination creation)
+func (tsf TableStatementFactory) WithPartitionBy(expression string) TableStatementFactory {
+	if tsf.partitionClause != "" {
+		tsf.partitionClause = "PARTITION BY (" + expression + ")"
+	}
+	return tsf
+}
+
 // CreateTableStatement return clickhouse DDL for creating table statement
 func (tsf TableStatementFactory) CreateTableStatement(tableName, columnsClause string) string {
 	engineStatement := tsf.engineStatement
@@ -242,7 +301,7 @@ type ClickHouse struct {
 }
 
 // NewClickHouse returns configured ClickHouse adapter instance
-func NewClickHouse(ctx context.Context, connectionString, database, cluster string, tlsConfig map[string]string,
+func NewClickHouse(ctx context.Context, connectionString, database, cluster string, tlsConfig map[string]string, poolConfig *ConnectionPoolConfig,
 	tableStatementFactory *TableStatementFactory, nullableFields map[string]bool,
 	queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*ClickHouse, error) {
 	connectionString = strings.TrimSpace(connectionString)
@@ -270,6 +329,10 @@ func NewClickHouse(ctx context.Context, connectionString, database, cluster stri
 	}
 
 	connectionString += "wait_end_of_query=1"
+	//explicit max_execution_time setting has priority
+	if !strings.Contains(connectionString, "max_execution_time=") && poolConfig.StatementTimeout() > 0 {
+		connectionString += fmt.Sprintf("&max_execution_time=%d", int(poolConfig.StatementTimeout().Seconds()))
+	}
 	//connect
 	dataSource, err := sql.Open("clickhouse", connectionString)
 	if err != nil {
@@ -282,6 +345,8 @@ func NewClickHouse(ctx context.Context, connectionString, database, cluster stri
 		return nil, err
 	}
 
+	poolConfig.apply(dataSource, 0, 0)
+
 	return &ClickHouse{
 		ctx:                   ctx,
 		database:              database,
@@ -326,7 +391,11 @@ func (ch *ClickHouse) CreateTable(table *Table) error {
 
 	//sorting columns asc
 	sort.Strings(columnsDDL)
-	statementStr := ch.tableStatementFactory.CreateTableStatement(table.Name, strings.Join(columnsDDL, ","))
+	tableStatementFactory := *ch.tableStatementFactory
+	if table.Layout != nil && table.Layout.PartitionBy != "" {
+		tableStatementFactory = tableStatementFactory.WithPartitionBy(table.Layout.PartitionBy)
+	}
+	statementStr := tableStatementFactory.CreateTableStatement(table.Name, strings.Join(columnsDDL, ","))
 	ch.queryLogger.LogDDL(statementStr)
 
 	if _, err := ch.dataSource.ExecContext(ch.ctx, statementStr); err != nil {
@@ -447,6 +516,33 @@ func (ch *ClickHouse) PatchTableSchema(patchSchema *Table) error {
 	return nil
 }
 
+// AlterColumnType changes column type with MODIFY COLUMN (ClickHouse converts existing values in background)
+func (ch *ClickHouse) AlterColumnType(table *Table, columnName string, column typing.SQLColumn) error {
+	modifyColumnDDL := "MODIFY COLUMN " + ch.columnDDL(columnName, column)
+	query := fmt.Sprintf(alterTableCHTemplate, ch.database, table.Name, ch.getOnClusterClause(), modifyColumnDDL)
+	ch.queryLogger.LogDDL(query)
+
+	if _, err := ch.dataSource.ExecContext(ch.ctx, query); err != nil {
+		return errorj.PatchTableError.Wrap(err, "failed to alter column type").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  ch.database,
+				Cluster:   ch.cluster,
+				Table:     table.Name,
+				Statement: query,
+			})
+	}
+
+	if ch.cluster != "" {
+		query := fmt.Sprintf(alterDistributedTableCHTemplate, ch.database, table.Name, ch.getOnClusterClause(), modifyColumnDDL)
+		ch.queryLogger.LogDDL(query)
+		if _, err := ch.dataSource.ExecContext(ch.ctx, query); err != nil {
+			logging.Errorf("[%s] Error altering distributed table for [%s] with statement [%s]: %v", ch.destinationId(), table.Name, query, err)
+		}
+	}
+
+	return nil
+}
+
 // Insert inserts provided object in ClickHouse as a single record or batch
 func (ch *ClickHouse) Insert(insertContext *InsertContext) error {
 	if !insertContext.deleteConditions.IsEmpty() {
@@ -478,6 +574,117 @@ func (ch *ClickHouse) delete(table *Table, deleteConditions *base.DeleteConditio
 	return nil
 }
 
+//Materialize creates or replaces a view or recreates a table (MergeTree) with the query results.
+//Table is created with a temporary name and exchanged with the original one. ClickHouse cluster isn't supported
+func (ch *ClickHouse) Materialize(name, query, materialized string) error {
+	if ch.cluster != "" {
+		return errors.New("SQL transformations aren't supported for ClickHouse cluster")
+	}
+
+	if materialized == MaterializedTable {
+		tmpTable := name + "_tmp" + timestamp.Now().Format("_20060102_150405")
+		if err := ch.executeMaterialization(name, fmt.Sprintf(createTableAsCHTemplate, ch.database, tmpTable, query)); err != nil {
+			return err
+		}
+
+		return ch.ReplaceTable(name, tmpTable, true)
+	}
+
+	return ch.executeMaterialization(name, fmt.Sprintf(createViewCHTemplate, ch.database, name, query))
+}
+
+func (ch *ClickHouse) executeMaterialization(name, statement string) error {
+	ch.queryLogger.LogDDL(statement)
+	if _, err := ch.dataSource.ExecContext(ch.ctx, statement); err != nil {
+		return errorj.MaterializeError.Wrap(err, "failed to materialize query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  ch.database,
+				Table:     name,
+				Statement: statement,
+			})
+	}
+
+	return nil
+}
+
+//Expire deletes (or counts if dryRun) rows where the column is less than the threshold.
+//ClickHouse executes ALTER TABLE DELETE as an asynchronous mutation, so amount of deleted rows is unknown
+func (ch *ClickHouse) Expire(tableName, column string, threshold time.Time, dryRun bool) (int64, error) {
+	table, err := ch.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	if ok, err := checkRetentionColumn(table, column); !ok {
+		return 0, err
+	}
+
+	condition := fmt.Sprintf(`"%s" < ?`, column)
+	values := []interface{}{threshold.UTC()}
+	var statement string
+	affected := int64(-1)
+	if dryRun {
+		statement = fmt.Sprintf(countRowsCHTemplate, ch.database, tableName, condition)
+		ch.queryLogger.LogQueryWithValues(statement, values)
+		affected, err = countRows(ch.ctx, ch.dataSource, statement, values...)
+	} else {
+		statement = fmt.Sprintf(alterTableCHTemplate, ch.database, tableName, ch.getOnClusterClause(), "DELETE WHERE "+condition)
+		ch.queryLogger.LogQueryWithValues(statement, values)
+		_, err = ch.dataSource.ExecContext(ch.ctx, statement, values...)
+	}
+	if err != nil {
+		return 0, errorj.ExpireError.Wrap(err, "failed to expire data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  ch.database,
+				Cluster:   ch.cluster,
+				Table:     tableName,
+				Statement: statement,
+				Values:    values,
+			})
+	}
+
+	return affected, nil
+}
+
+//Erase deletes rows or sets columns to NULL where any of the columns equals to the value.
+//ClickHouse executes ALTER TABLE DELETE/UPDATE as asynchronous mutations, so amount of affected rows is unknown.
+//Sorting key columns can't be updated, use delete mode for them
+func (ch *ClickHouse) Erase(tableName string, columns []string, value string, mode string) (int64, error) {
+	if err := validateEraseMode(mode); err != nil {
+		return 0, err
+	}
+
+	table, err := ch.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	columns = existingColumns(table, columns)
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	query := &eraseQuery{
+		quote:       func(column string) string { return fmt.Sprintf(`"%s"`, column) },
+		cast:        func(column string) string { return "toString(" + column + ")" },
+		placeholder: func(i int) string { return "?" },
+	}
+	statement, values := query.build(fmt.Sprintf(alterTableCHTemplate, ch.database, tableName, ch.getOnClusterClause(), "DELETE WHERE %s"),
+		fmt.Sprintf(alterTableCHTemplate, ch.database, tableName, ch.getOnClusterClause(), "UPDATE %s WHERE %s"), columns, value, mode)
+	ch.queryLogger.LogQueryWithValues(statement, values)
+
+	if _, err := ch.dataSource.ExecContext(ch.ctx, statement, values...); err != nil {
+		return 0, errorj.EraseError.Wrap(err, "failed to erase data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  ch.database,
+				Cluster:   ch.cluster,
+				Table:     tableName,
+				Statement: statement,
+				Values:    values,
+			})
+	}
+
+	return -1, nil
+}
+
 // Truncate deletes all records in tableName table
 func (ch *ClickHouse) Truncate(tableName string) error {
 	sqlParams := SqlParams{
@@ -171,6 +171,20 @@ func TestTableStatementFactory(t *testing.T) {
 	}
 }
 
+func TestTableStatementFactoryWithPartitionBy(t *testing.T) {
+	factory, err := NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1"})
+	require.NoError(t, err)
+
+	actual := factory.WithPartitionBy("toYYYYMMDD(_timestamp)").CreateTableStatement("test_table", "a String")
+	require.Equal(t, "CREATE TABLE \"db1\".\"test_table\"  (a String) ENGINE = ReplacingMergeTree() PARTITION BY (toYYYYMMDD(_timestamp)) ORDER BY (eventn_ctx_event_id)", strings.TrimSpace(actual))
+
+	rawFactory, err := NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1", Engine: &EngineConfig{RawStatement: "ENGINE = MergeTree() ORDER BY (a)"}})
+	require.NoError(t, err)
+
+	actual = rawFactory.WithPartitionBy("toYYYYMMDD(_timestamp)").CreateTableStatement("test_table", "a String")
+	require.Equal(t, "CREATE TABLE \"db1\".\"test_table\"  (a String) ENGINE = MergeTree() ORDER BY (a)", strings.TrimSpace(actual))
+}
+
 func TestClickhouseTruncateExistingTable(t *testing.T) {
 	recordsCount := len(timestamps)
 	table := &Table{
@@ -219,7 +233,7 @@ func setupClickHouseDatabase(t *testing.T, table *Table) (*test.ClickHouseContai
 	if err != nil {
 		t.Fatalf("failed to initialize table statement factory: %v", err)
 	}
-	adapter, err := NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, tsf, map[string]bool{},
+	adapter, err := NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, nil, tsf, map[string]bool{},
 		&logging.QueryLogger{}, typing.SQLTypes{})
 	if err != nil {
 		t.Fatalf("Failed to create ClickHouse adapter: %v", err)
@@ -0,0 +1,75 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+//eraseQuery is a builder of erasure statements for a particular SQL dialect
+type eraseQuery struct {
+	//quote returns quoted column name
+	quote func(column string) string
+	//cast returns column expression casted to string type
+	cast func(quotedColumn string) string
+	//placeholder returns parameter placeholder by index (starts from 1)
+	placeholder func(i int) string
+}
+
+//build returns statement and parameters for the mode. deleteTemplate has a placeholder for the WHERE condition,
+//updateTemplate has placeholders for SET assignments and the WHERE condition:
+//DELETE FROM table WHERE c1 = ? OR c2 = ?
+//UPDATE table SET c1 = NULL, c2 = NULL WHERE c1 = ? OR c2 = ?
+func (eq *eraseQuery) build(deleteTemplate, updateTemplate string, columns []string, value, mode string) (string, []interface{}) {
+	var conditions, assignments []string
+	var values []interface{}
+	for i, column := range columns {
+		quoted := eq.quote(column)
+		conditions = append(conditions, fmt.Sprintf("%s = %s", eq.cast(quoted), eq.placeholder(i+1)))
+		assignments = append(assignments, quoted+" = NULL")
+		values = append(values, value)
+	}
+
+	where := strings.Join(conditions, " OR ")
+	if mode == EraseAnonymize {
+		return fmt.Sprintf(updateTemplate, strings.Join(assignments, ", "), where), values
+	}
+
+	return fmt.Sprintf(deleteTemplate, where), values
+}
+
+//existingColumns returns columns which exist in the table (case insensitive)
+func existingColumns(table *Table, columns []string) []string {
+	tableColumns := map[string]bool{}
+	for name := range table.Columns {
+		tableColumns[strings.ToLower(name)] = true
+	}
+
+	var result []string
+	for _, column := range columns {
+		if tableColumns[strings.ToLower(column)] {
+			result = append(result, column)
+		}
+	}
+
+	return result
+}
+
+//validateEraseMode returns err if the mode is unknown
+func validateEraseMode(mode string) error {
+	if mode != EraseDelete && mode != EraseAnonymize {
+		return fmt.Errorf("unknown erase mode: %s. Supported: %s, %s", mode, EraseDelete, EraseAnonymize)
+	}
+
+	return nil
+}
+
+//rowsAffected returns amount of affected rows or -1 if the driver doesn't support it
+func rowsAffected(result sql.Result) int64 {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+
+	return affected
+}
@@ -0,0 +1,37 @@
+package adapters
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEraseQuery(t *testing.T) {
+	query := &eraseQuery{
+		quote:       func(column string) string { return `"` + column + `"` },
+		cast:        func(column string) string { return column + "::text" },
+		placeholder: func(i int) string { return "$" + strconv.Itoa(i) },
+	}
+
+	statement, values := query.build(`DELETE FROM "events" WHERE %s`, `UPDATE "events" SET %s WHERE %s`,
+		[]string{"user_id", "user_email"}, "john@example.com", EraseDelete)
+	require.Equal(t, `DELETE FROM "events" WHERE "user_id"::text = $1 OR "user_email"::text = $2`, statement)
+	require.Equal(t, []interface{}{"john@example.com", "john@example.com"}, values)
+
+	statement, values = query.build(`DELETE FROM "events" WHERE %s`, `UPDATE "events" SET %s WHERE %s`,
+		[]string{"user_id", "user_email"}, "john@example.com", EraseAnonymize)
+	require.Equal(t, `UPDATE "events" SET "user_id" = NULL, "user_email" = NULL WHERE "user_id"::text = $1 OR "user_email"::text = $2`, statement)
+	require.Len(t, values, 2)
+}
+
+func TestExistingColumns(t *testing.T) {
+	table := &Table{Name: "events", Columns: Columns{"USER_ID": typing.SQLColumn{Type: "text"}, "event_type": typing.SQLColumn{Type: "text"}}}
+	require.Equal(t, []string{"user_id"}, existingColumns(table, []string{"user_id", "user_email"}))
+	require.Empty(t, existingColumns(&Table{Name: "events", Columns: Columns{}}, []string{"user_id"}))
+
+	require.NoError(t, validateEraseMode(EraseDelete))
+	require.NoError(t, validateEraseMode(EraseAnonymize))
+	require.Error(t, validateEraseMode("truncate"))
+}
@@ -65,3 +65,12 @@ func (ec *EventContext) GetSerializedOriginalEvent() string {
 
 	return ""
 }
+
+//GetTableName returns destination table name or empty string if the event hasn't been processed
+func (ec *EventContext) GetTableName() string {
+	if ec.Table != nil {
+		return ec.Table.Name
+	}
+
+	return ""
+}
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pkg/errors"
 )
 
@@ -19,8 +20,21 @@ const (
 	FileFormatCSV       FileEncodingFormat = "csv"       //flattened csv objects with \n delimiter
 	FileFormatParquet   FileEncodingFormat = "parquet"   //flattened objects which are marshalled in apache parquet file
 	FileCompressionGZIP FileCompression    = "gzip"      //gzip compression
+	FileCompressionZSTD FileCompression    = "zstd"      //zstandard compression
 )
 
+// Extension returns file name suffix of the compressed file
+func (fc FileCompression) Extension() string {
+	switch fc {
+	case FileCompressionGZIP:
+		return ".gz"
+	case FileCompressionZSTD:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
 type FileConfig struct {
 	Folder      string             `mapstructure:"folder,omitempty" json:"folder,omitempty" yaml:"folder,omitempty"`
 	Format      FileEncodingFormat `mapstructure:"format,omitempty" json:"format,omitempty" yaml:"format,omitempty"`
@@ -32,36 +46,88 @@ func (c FileConfig) PrepareFile(fileName *string, fileBytes *[]byte) error {
 		*fileName = c.Folder + "/" + *fileName
 	}
 
-	if c.Compression == FileCompressionGZIP {
-		*fileName = fileNameGZIP(*fileName)
-		if fileBytes != nil {
-			var err error
-			buf, err := compressGZIP(*fileBytes)
-			if err != nil {
-				return errors.Errorf("Error compressing file %v", err)
-			}
+	*fileName += c.Compression.Extension()
+	if fileBytes == nil {
+		return nil
+	}
+
+	switch c.Compression {
+	case FileCompressionGZIP:
+		buf, err := compressGZIP(*fileBytes)
+		if err != nil {
+			return errors.Errorf("Error compressing file %v", err)
+		}
 
-			*fileBytes = buf.Bytes()
+		*fileBytes = buf.Bytes()
+	case FileCompressionZSTD:
+		b, err := compressZSTD(*fileBytes)
+		if err != nil {
+			return errors.Errorf("Error compressing file %v", err)
 		}
+
+		*fileBytes = b
 	}
 
 	return nil
 }
 
-func (c *FileConfig) RequireDefaultStage(storageType string) {
+// ObjectKey returns the key under which the file is stored on the stage (with folder and compression extension)
+func (c FileConfig) ObjectKey(fileName string) string {
+	_ = c.PrepareFile(&fileName, nil)
+	return fileName
+}
+
+// folderPrefix returns the prefix of all object keys on the stage
+func (c FileConfig) folderPrefix() string {
+	if c.Folder == "" {
+		return ""
+	}
+	return c.Folder + "/"
+}
+
+// ValidateCompression returns err if compression is unknown
+func (c FileConfig) ValidateCompression() error {
+	switch c.Compression {
+	case "", FileCompressionGZIP, FileCompressionZSTD:
+		return nil
+	default:
+		return errors.Errorf("unknown compression [%s]. Available: [%s, %s]", c.Compression, FileCompressionGZIP, FileCompressionZSTD)
+	}
+}
+
+// RequireDefaultStage resets folder and compression which aren't supported by the stage.
+// supportedCompressions are compressions which the destination COPY command is able to read
+func (c *FileConfig) RequireDefaultStage(storageType string, supportedCompressions ...FileCompression) {
 	if c.Folder != "" {
 		logging.Warnf("customizing folder [%s] is not supported for [%s] stage, using root directory", c.Folder, storageType)
 		c.Folder = ""
 	}
 
 	if c.Compression != "" {
+		for _, supported := range supportedCompressions {
+			if c.Compression == supported {
+				return
+			}
+		}
 		logging.Warnf("customizing compression [%s] is not supported for [%s] stage, using no compression", c.Compression, storageType)
 		c.Compression = ""
 	}
 }
 
-func fileNameGZIP(fileName string) string {
-	return fileName + ".gz"
+// RequireStagingFormat adjusts compression for the format of files which are staged for the warehouse COPY.
+// Parquet files are compressed internally so the stage compression is disabled. CSV files are compressed with gzip by default
+func (c *FileConfig) RequireStagingFormat(storageType string, format FileEncodingFormat) {
+	switch format {
+	case FileFormatParquet:
+		if c.Compression != "" {
+			logging.Warnf("compression [%s] is not supported for [%s] %s staging files, using parquet internal compression", c.Compression, storageType, format)
+			c.Compression = ""
+		}
+	case FileFormatCSV:
+		if c.Compression == "" {
+			c.Compression = FileCompressionGZIP
+		}
+	}
 }
 
 func compressGZIP(b []byte) (*bytes.Buffer, error) {
@@ -73,3 +139,13 @@ func compressGZIP(b []byte) (*bytes.Buffer, error) {
 	}
 	return buf, nil
 }
+
+func compressZSTD(b []byte) ([]byte, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Close()
+
+	return encoder.EncodeAll(b, make([]byte, 0, len(b)/2)), nil
+}
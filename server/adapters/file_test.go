@@ -0,0 +1,96 @@
+package adapters
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareFileCompression(t *testing.T) {
+	payload := []byte(`{"event_type":"pageview","user":{"id":"1"}}` + "\n" + `{"event_type":"click","user":{"id":"2"}}`)
+
+	tests := []struct {
+		name             string
+		config           FileConfig
+		expectedFileName string
+		decompress       func(b []byte) ([]byte, error)
+	}{
+		{
+			"no compression",
+			FileConfig{Folder: "events"},
+			"events/file.log",
+			func(b []byte) ([]byte, error) { return b, nil },
+		},
+		{
+			"gzip",
+			FileConfig{Compression: FileCompressionGZIP},
+			"file.log.gz",
+			func(b []byte) ([]byte, error) {
+				r, err := gzip.NewReader(bytes.NewReader(b))
+				if err != nil {
+					return nil, err
+				}
+				return ioutil.ReadAll(r)
+			},
+		},
+		{
+			"zstd",
+			FileConfig{Folder: "events", Compression: FileCompressionZSTD},
+			"events/file.log.zst",
+			func(b []byte) ([]byte, error) {
+				decoder, err := zstd.NewReader(nil)
+				if err != nil {
+					return nil, err
+				}
+				defer decoder.Close()
+				return decoder.DecodeAll(b, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := "file.log"
+			fileBytes := append([]byte{}, payload...)
+			require.NoError(t, tt.config.PrepareFile(&fileName, &fileBytes))
+			require.Equal(t, tt.expectedFileName, fileName)
+			require.Equal(t, tt.expectedFileName, tt.config.ObjectKey("file.log"))
+
+			actual, err := tt.decompress(fileBytes)
+			require.NoError(t, err)
+			require.Equal(t, payload, actual)
+		})
+	}
+}
+
+func TestRequireDefaultStage(t *testing.T) {
+	config := &FileConfig{Folder: "events", Compression: FileCompressionGZIP}
+	config.RequireDefaultStage("bigquery", FileCompressionGZIP)
+	require.Equal(t, "", config.Folder)
+	require.Equal(t, FileCompressionGZIP, config.Compression)
+
+	config = &FileConfig{Compression: FileCompressionZSTD}
+	config.RequireDefaultStage("bigquery", FileCompressionGZIP)
+	require.Equal(t, FileCompression(""), config.Compression)
+}
+
+func TestRequireStagingFormat(t *testing.T) {
+	config := &FileConfig{}
+	config.RequireStagingFormat("redshift", FileFormatCSV)
+	require.Equal(t, FileCompressionGZIP, config.Compression)
+
+	config = &FileConfig{Compression: FileCompressionZSTD}
+	config.RequireStagingFormat("redshift", FileFormatCSV)
+	require.Equal(t, FileCompressionZSTD, config.Compression)
+
+	config = &FileConfig{Compression: FileCompressionGZIP}
+	config.RequireStagingFormat("snowflake", FileFormatParquet)
+	require.Equal(t, FileCompression(""), config.Compression)
+
+	config = &FileConfig{}
+	config.RequireStagingFormat("bigquery", FileFormatJSON)
+	require.Equal(t, FileCompression(""), config.Compression)
+}
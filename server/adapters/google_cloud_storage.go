@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"strings"
 
 	"github.com/jitsucom/jitsu/server/errorj"
@@ -14,6 +15,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -24,6 +26,8 @@ type GoogleConfig struct {
 	Project    string      `mapstructure:"bq_project,omitempty" json:"bq_project,omitempty" yaml:"bq_project,omitempty"`
 	Dataset    string      `mapstructure:"bq_dataset,omitempty" json:"bq_dataset,omitempty" yaml:"bq_dataset,omitempty"`
 	KeyFile    interface{} `mapstructure:"key_file,omitempty" json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	//KMSKeyName is a Cloud KMS key which encrypts uploaded objects instead of the bucket default key
+	KMSKeyName string `mapstructure:"kms_key_name,omitempty" json:"kms_key_name,omitempty" yaml:"kms_key_name,omitempty"`
 	FileConfig `mapstructure:",squash" yaml:"-,inline"`
 
 	//will be set on validation
@@ -140,6 +144,7 @@ func (gcs *GoogleCloudStorage) UploadBytes(fileName string, fileBytes []byte) (e
 	bucket := gcs.client.Bucket(gcs.config.Bucket)
 	object := bucket.Object(fileName)
 	w := object.NewWriter(gcs.ctx)
+	w.KMSKeyName = gcs.config.KMSKeyName
 
 	if _, err := w.Write(fileBytes); err != nil {
 		return errorj.SaveOnStageError.Wrap(err, "failed to write file to google cloud storage").
@@ -187,6 +192,51 @@ func (gcs *GoogleCloudStorage) DeleteObject(key string) (err error) {
 	return nil
 }
 
+//ListObjects returns keys of all objects with the prefix (relative to the configured folder).
+//Reads all pages of the bucket listing
+func (gcs *GoogleCloudStorage) ListObjects(prefix string) ([]string, error) {
+	if gcs.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed GoogleCloudStorage instance")
+	}
+
+	folderPrefix := gcs.config.folderPrefix()
+	it := gcs.client.Bucket(gcs.config.Bucket).Objects(gcs.ctx, &storage.Query{Prefix: folderPrefix + prefix})
+	var keys []string
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, errorj.SaveOnStageError.Wrap(err, "failed to list objects in google cloud storage").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Bucket:    gcs.config.Bucket,
+					Statement: fmt.Sprintf("prefix: %s", prefix),
+				})
+		}
+		keys = append(keys, strings.TrimPrefix(attrs.Name, folderPrefix))
+	}
+}
+
+//DownloadBytes returns payload of the object by key (relative to the configured folder)
+func (gcs *GoogleCloudStorage) DownloadBytes(key string) ([]byte, error) {
+	if gcs.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed GoogleCloudStorage instance")
+	}
+
+	reader, err := gcs.client.Bucket(gcs.config.Bucket).Object(gcs.config.folderPrefix() + key).NewReader(gcs.ctx)
+	if err != nil {
+		return nil, errorj.SaveOnStageError.Wrap(err, "failed to download from google cloud storage").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    gcs.config.Bucket,
+				Statement: fmt.Sprintf("file: %s", key),
+			})
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
 //ValidateWritePermission tries to create temporary file and remove it.
 //returns nil if file creation was successful.
 func (gcs *GoogleCloudStorage) ValidateWritePermission() error {
@@ -0,0 +1,360 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	//GoogleSheetsAppendMode appends batches to sheets
+	GoogleSheetsAppendMode = "append"
+	//GoogleSheetsReplaceMode overwrites sheets with every batch
+	GoogleSheetsReplaceMode = "replace"
+
+	googleSheetsDefaultMaxRows = 10000
+	googleSheetsRawInput       = "RAW"
+	googleSheetsInsertRows     = "INSERT_ROWS"
+	googleSheetsRequestTimeout = time.Minute
+)
+
+//GoogleSheetsConfig is a Google Sheets destination configuration
+type GoogleSheetsConfig struct {
+	SpreadsheetID string      `mapstructure:"spreadsheet_id,omitempty" json:"spreadsheet_id,omitempty" yaml:"spreadsheet_id,omitempty"`
+	KeyFile       interface{} `mapstructure:"key_file,omitempty" json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	//Mode is append (default) or replace. It is applied to events batches, source syncs always replace data on full refresh
+	Mode string `mapstructure:"mode,omitempty" json:"mode,omitempty" yaml:"mode,omitempty"`
+	//MaxRows limits rows count of one write. Sheets are for small aggregate tables
+	MaxRows int `mapstructure:"max_rows,omitempty" json:"max_rows,omitempty" yaml:"max_rows,omitempty"`
+
+	//will be set on validation
+	credentials option.ClientOption
+}
+
+//Validate returns err if configuration is invalid and sets default values
+func (gsc *GoogleSheetsConfig) Validate() error {
+	if gsc == nil {
+		return errors.New("Google Sheets config is required")
+	}
+	if gsc.SpreadsheetID == "" {
+		return errors.New("spreadsheet_id is required parameter")
+	}
+	if gsc.Mode == "" {
+		gsc.Mode = GoogleSheetsAppendMode
+	}
+	if gsc.Mode != GoogleSheetsAppendMode && gsc.Mode != GoogleSheetsReplaceMode {
+		return fmt.Errorf("unknown mode [%s]. Supported: %s, %s", gsc.Mode, GoogleSheetsAppendMode, GoogleSheetsReplaceMode)
+	}
+	if gsc.MaxRows <= 0 {
+		gsc.MaxRows = googleSheetsDefaultMaxRows
+	}
+
+	keyConfig := &GoogleConfig{KeyFile: gsc.KeyFile}
+	if err := keyConfig.Validate(); err != nil {
+		return err
+	}
+	gsc.credentials = keyConfig.credentials
+	return nil
+}
+
+//GoogleSheets is a Google Sheets adapter. Every table is written into the sheet with the same name.
+//The first sheet row contains column names
+type GoogleSheets struct {
+	ctx     context.Context
+	config  *GoogleSheetsConfig
+	service *sheets.Service
+
+	mutex *sync.Mutex
+	//sheetIDs is a cache of sheet title -> sheet id
+	sheetIDs map[string]int64
+}
+
+//NewGoogleSheets returns configured GoogleSheets adapter instance
+func NewGoogleSheets(ctx context.Context, config *GoogleSheetsConfig, opts ...option.ClientOption) (*GoogleSheets, error) {
+	if config.credentials != nil {
+		opts = append(opts, config.credentials)
+	}
+	service, err := sheets.NewService(ctx, append(opts, option.WithScopes(sheets.SpreadsheetsScope))...)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating Google Sheets client: %v", err)
+	}
+
+	return &GoogleSheets{ctx: ctx, config: config, service: service, mutex: &sync.Mutex{}}, nil
+}
+
+//Test checks access to the spreadsheet
+func (gs *GoogleSheets) Test() error {
+	_, err := gs.sheetID("")
+	return err
+}
+
+//Append writes objects after the last sheet row. New columns are added to the header
+func (gs *GoogleSheets) Append(sheet string, objects []map[string]interface{}) error {
+	if err := gs.checkRowsCount(sheet, objects); err != nil {
+		return err
+	}
+	if _, err := gs.ensureSheet(sheet); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(gs.ctx, googleSheetsRequestTimeout)
+	defer cancel()
+
+	headerRange, err := gs.service.Spreadsheets.Values.Get(gs.config.SpreadsheetID, sheetRange(sheet, "1:1")).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("Error reading header of sheet [%s]: %v", sheet, err)
+	}
+	var header []string
+	if len(headerRange.Values) > 0 {
+		for _, cell := range headerRange.Values[0] {
+			header = append(header, fmt.Sprint(cell))
+		}
+	}
+
+	columns := mergeColumns(header, objects)
+	if len(columns) > len(header) {
+		headerRow := &sheets.ValueRange{Values: [][]interface{}{toCells(columns)}}
+		if _, err := gs.service.Spreadsheets.Values.Update(gs.config.SpreadsheetID, sheetRange(sheet, "A1"), headerRow).
+			ValueInputOption(googleSheetsRawInput).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("Error writing header of sheet [%s]: %v", sheet, err)
+		}
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+
+	rows := &sheets.ValueRange{Values: toRows(columns, objects)}
+	if _, err := gs.service.Spreadsheets.Values.Append(gs.config.SpreadsheetID, sheetRange(sheet, "A1"), rows).
+		ValueInputOption(googleSheetsRawInput).InsertDataOption(googleSheetsInsertRows).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("Error appending %d rows to sheet [%s]: %v", len(objects), sheet, err)
+	}
+
+	return nil
+}
+
+//Replace clears the sheet and writes the header and objects
+func (gs *GoogleSheets) Replace(sheet string, objects []map[string]interface{}) error {
+	if err := gs.checkRowsCount(sheet, objects); err != nil {
+		return err
+	}
+	if err := gs.Clear(sheet); err != nil {
+		return err
+	}
+
+	columns := mergeColumns(nil, objects)
+	if len(columns) == 0 {
+		return nil
+	}
+	values := append([][]interface{}{toCells(columns)}, toRows(columns, objects)...)
+
+	ctx, cancel := context.WithTimeout(gs.ctx, googleSheetsRequestTimeout)
+	defer cancel()
+	if _, err := gs.service.Spreadsheets.Values.Update(gs.config.SpreadsheetID, sheetRange(sheet, "A1"), &sheets.ValueRange{Values: values}).
+		ValueInputOption(googleSheetsRawInput).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("Error writing %d rows to sheet [%s]: %v", len(objects), sheet, err)
+	}
+
+	return nil
+}
+
+//Clear removes all values from the sheet. The sheet is created if it doesn't exist
+func (gs *GoogleSheets) Clear(sheet string) error {
+	if _, err := gs.ensureSheet(sheet); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(gs.ctx, googleSheetsRequestTimeout)
+	defer cancel()
+	if _, err := gs.service.Spreadsheets.Values.Clear(gs.config.SpreadsheetID, sheetRange(sheet, ""), &sheets.ClearValuesRequest{}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("Error clearing sheet [%s]: %v", sheet, err)
+	}
+
+	return nil
+}
+
+//ReplaceSheet deletes the original sheet and renames the replacement sheet
+func (gs *GoogleSheets) ReplaceSheet(originalSheet, replacementSheet string) error {
+	replacementID, err := gs.sheetID(replacementSheet)
+	if err != nil {
+		return err
+	}
+	if replacementID < 0 {
+		return fmt.Errorf("sheet [%s] doesn't exist", replacementSheet)
+	}
+	originalID, err := gs.sheetID(originalSheet)
+	if err != nil {
+		return err
+	}
+
+	var requests []*sheets.Request
+	if originalID >= 0 {
+		requests = append(requests, &sheets.Request{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: originalID, ForceSendFields: []string{"SheetId"}}})
+	}
+	requests = append(requests, &sheets.Request{UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+		Fields:     "title",
+		Properties: &sheets.SheetProperties{SheetId: replacementID, Title: originalSheet, ForceSendFields: []string{"SheetId"}},
+	}})
+
+	return gs.batchUpdate(requests...)
+}
+
+//DeleteSheet deletes the sheet if it exists
+func (gs *GoogleSheets) DeleteSheet(sheet string) error {
+	id, err := gs.sheetID(sheet)
+	if err != nil || id < 0 {
+		return err
+	}
+
+	return gs.batchUpdate(&sheets.Request{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: id, ForceSendFields: []string{"SheetId"}}})
+}
+
+//Close does nothing
+func (gs *GoogleSheets) Close() error {
+	return nil
+}
+
+func (gs *GoogleSheets) checkRowsCount(sheet string, objects []map[string]interface{}) error {
+	if len(objects) > gs.config.MaxRows {
+		return fmt.Errorf("%d rows exceed max_rows limit [%d] of sheet [%s]", len(objects), gs.config.MaxRows, sheet)
+	}
+
+	return nil
+}
+
+//ensureSheet creates the sheet if it doesn't exist and returns its id
+func (gs *GoogleSheets) ensureSheet(sheet string) (int64, error) {
+	id, err := gs.sheetID(sheet)
+	if err != nil || id >= 0 {
+		return id, err
+	}
+
+	if err := gs.batchUpdate(&sheets.Request{AddSheet: &sheets.AddSheetRequest{Properties: &sheets.SheetProperties{Title: sheet}}}); err != nil {
+		return -1, err
+	}
+
+	return gs.sheetID(sheet)
+}
+
+//sheetID returns the sheet id from the cache or reloads spreadsheet sheets. Returns -1 if the sheet doesn't exist
+func (gs *GoogleSheets) sheetID(sheet string) (int64, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	if id, ok := gs.sheetIDs[sheet]; ok {
+		return id, nil
+	}
+
+	ctx, cancel := context.WithTimeout(gs.ctx, googleSheetsRequestTimeout)
+	defer cancel()
+	spreadsheet, err := gs.service.Spreadsheets.Get(gs.config.SpreadsheetID).Fields("sheets.properties(sheetId,title)").Context(ctx).Do()
+	if err != nil {
+		return -1, fmt.Errorf("Error getting spreadsheet [%s]: %v", gs.config.SpreadsheetID, err)
+	}
+
+	gs.sheetIDs = map[string]int64{}
+	for _, s := range spreadsheet.Sheets {
+		if s.Properties != nil {
+			gs.sheetIDs[s.Properties.Title] = s.Properties.SheetId
+		}
+	}
+
+	if id, ok := gs.sheetIDs[sheet]; ok {
+		return id, nil
+	}
+	return -1, nil
+}
+
+//batchUpdate executes spreadsheet update requests and resets sheets cache
+func (gs *GoogleSheets) batchUpdate(requests ...*sheets.Request) error {
+	gs.mutex.Lock()
+	gs.sheetIDs = nil
+	gs.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(gs.ctx, googleSheetsRequestTimeout)
+	defer cancel()
+	if _, err := gs.service.Spreadsheets.BatchUpdate(gs.config.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("Error updating spreadsheet [%s]: %v", gs.config.SpreadsheetID, err)
+	}
+
+	return nil
+}
+
+//sheetRange returns A1 notation range with quoted sheet name
+func sheetRange(sheet, cells string) string {
+	quoted := "'" + strings.ReplaceAll(sheet, "'", "''") + "'"
+	if cells == "" {
+		return quoted
+	}
+
+	return quoted + "!" + cells
+}
+
+//mergeColumns returns header columns and sorted new columns from objects
+func mergeColumns(header []string, objects []map[string]interface{}) []string {
+	existing := make(map[string]bool, len(header))
+	for _, column := range header {
+		existing[column] = true
+	}
+
+	var newColumns []string
+	for _, object := range objects {
+		for column := range object {
+			if !existing[column] {
+				existing[column] = true
+				newColumns = append(newColumns, column)
+			}
+		}
+	}
+	sort.Strings(newColumns)
+
+	return append(append([]string{}, header...), newColumns...)
+}
+
+func toRows(columns []string, objects []map[string]interface{}) [][]interface{} {
+	rows := make([][]interface{}, 0, len(objects))
+	for _, object := range objects {
+		row := make([]interface{}, len(columns))
+		for i, column := range columns {
+			row[i] = toCellValue(object[column])
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func toCells(columns []string) []interface{} {
+	cells := make([]interface{}, len(columns))
+	for i, column := range columns {
+		cells[i] = column
+	}
+
+	return cells
+}
+
+//toCellValue converts a value into a sheet cell value: nil is an empty cell, time is formatted, objects and arrays are JSON strings
+func toCellValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string, bool, int, int32, int64, float32, float64:
+		return v
+	case time.Time:
+		return v.UTC().Format(timestamp.Layout)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
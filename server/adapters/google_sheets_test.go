@@ -0,0 +1,167 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+//fakeSpreadsheet is an in-memory Sheets API spreadsheet
+type fakeSpreadsheet struct {
+	mutex  sync.Mutex
+	nextID int64
+	ids    map[string]int64
+	values map[string][][]interface{}
+}
+
+func (fs *fakeSpreadsheet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v4/spreadsheets/id")
+	switch {
+	case path == "":
+		spreadsheet := &sheets.Spreadsheet{}
+		for title, id := range fs.ids {
+			spreadsheet.Sheets = append(spreadsheet.Sheets, &sheets.Sheet{Properties: &sheets.SheetProperties{SheetId: id, Title: title}})
+		}
+		json.NewEncoder(w).Encode(spreadsheet)
+	case path == ":batchUpdate":
+		request := &sheets.BatchUpdateSpreadsheetRequest{}
+		json.NewDecoder(r.Body).Decode(request)
+		for _, req := range request.Requests {
+			switch {
+			case req.AddSheet != nil:
+				fs.nextID++
+				fs.ids[req.AddSheet.Properties.Title] = fs.nextID
+			case req.DeleteSheet != nil:
+				title := fs.title(req.DeleteSheet.SheetId)
+				delete(fs.ids, title)
+				delete(fs.values, title)
+			case req.UpdateSheetProperties != nil:
+				title := fs.title(req.UpdateSheetProperties.Properties.SheetId)
+				newTitle := req.UpdateSheetProperties.Properties.Title
+				fs.ids[newTitle], fs.values[newTitle] = fs.ids[title], fs.values[title]
+				delete(fs.ids, title)
+				delete(fs.values, title)
+			}
+		}
+		w.Write([]byte("{}"))
+	case strings.HasPrefix(path, "/values/"):
+		a1Range, action := strings.TrimPrefix(path, "/values/"), ""
+		for _, a := range []string{"append", "clear"} {
+			if strings.HasSuffix(a1Range, ":"+a) {
+				a1Range, action = strings.TrimSuffix(a1Range, ":"+a), a
+			}
+		}
+		sheet := strings.ReplaceAll(strings.Trim(strings.SplitN(a1Range, "!", 2)[0], "'"), "''", "'")
+
+		valueRange := &sheets.ValueRange{}
+		json.NewDecoder(r.Body).Decode(valueRange)
+		switch {
+		case r.Method == http.MethodGet:
+			if len(fs.values[sheet]) > 0 {
+				valueRange.Values = fs.values[sheet][:1]
+			}
+			json.NewEncoder(w).Encode(valueRange)
+			return
+		case action == "clear":
+			delete(fs.values, sheet)
+		case action == "append":
+			fs.values[sheet] = append(fs.values[sheet], valueRange.Values...)
+		case r.Method == http.MethodPut:
+			for i, row := range valueRange.Values {
+				if i < len(fs.values[sheet]) {
+					fs.values[sheet][i] = row
+				} else {
+					fs.values[sheet] = append(fs.values[sheet], row)
+				}
+			}
+		}
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (fs *fakeSpreadsheet) title(id int64) string {
+	for title, sheetID := range fs.ids {
+		if sheetID == id {
+			return title
+		}
+	}
+	return ""
+}
+
+func TestGoogleSheets(t *testing.T) {
+	spreadsheet := &fakeSpreadsheet{ids: map[string]int64{"Sheet1": 0}, values: map[string][][]interface{}{}}
+	server := httptest.NewServer(spreadsheet)
+	defer server.Close()
+
+	adapter, err := NewGoogleSheets(context.Background(), &GoogleSheetsConfig{SpreadsheetID: "id", MaxRows: 2},
+		option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	require.NoError(t, adapter.Test())
+
+	created := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, adapter.Append("it's events", []map[string]interface{}{{"id": 1, "created": created}}))
+	require.NoError(t, adapter.Append("it's events", []map[string]interface{}{{"id": 2, "name": "a", "tags": []string{"x"}}}))
+	require.Equal(t, [][]interface{}{
+		{"created", "id", "name", "tags"},
+		{"2022-03-01T10:00:00.000000Z", float64(1)},
+		{"", float64(2), "a", `["x"]`},
+	}, trimRows(spreadsheet.values["it's events"]))
+
+	require.NoError(t, adapter.Replace("totals", []map[string]interface{}{{"day": "2022-03-01", "count": 5}}))
+	require.Equal(t, [][]interface{}{{"count", "day"}, {float64(5), "2022-03-01"}}, spreadsheet.values["totals"])
+
+	require.Error(t, adapter.Replace("totals", []map[string]interface{}{{"a": 1}, {"a": 2}, {"a": 3}}))
+
+	require.NoError(t, adapter.Replace("totals_tmp", []map[string]interface{}{{"count": 6}}))
+	require.NoError(t, adapter.ReplaceSheet("totals", "totals_tmp"))
+	require.Equal(t, [][]interface{}{{"count"}, {float64(6)}}, spreadsheet.values["totals"])
+	_, ok := spreadsheet.ids["totals_tmp"]
+	require.False(t, ok)
+
+	require.NoError(t, adapter.DeleteSheet("Sheet1"))
+	require.NotContains(t, spreadsheet.ids, "Sheet1")
+}
+
+//trimRows removes trailing empty cells
+func trimRows(rows [][]interface{}) [][]interface{} {
+	result := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		end := len(row)
+		for end > 0 && row[end-1] == "" {
+			end--
+		}
+		result[i] = row[:end]
+	}
+	return result
+}
+
+func TestToCellValue(t *testing.T) {
+	require.Equal(t, "", toCellValue(nil))
+	require.Equal(t, 1.5, toCellValue(1.5))
+	require.Equal(t, `{"a":1}`, toCellValue(map[string]interface{}{"a": 1}))
+	require.Equal(t, "2022-03-01T10:00:00.000000Z", toCellValue(time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)))
+}
+
+func TestGoogleSheetsConfigValidate(t *testing.T) {
+	config := &GoogleSheetsConfig{SpreadsheetID: "id", KeyFile: `{"type": "service_account"}`}
+	require.NoError(t, config.Validate())
+	require.Equal(t, GoogleSheetsAppendMode, config.Mode)
+	require.Equal(t, googleSheetsDefaultMaxRows, config.MaxRows)
+
+	require.Error(t, (&GoogleSheetsConfig{KeyFile: "{}"}).Validate())
+	require.Error(t, (&GoogleSheetsConfig{SpreadsheetID: "id", KeyFile: `{"type": "service_account"}`, Mode: "upsert"}).Validate())
+}
@@ -36,11 +36,16 @@ const (
 	mySQLMergeTemplate               = "INSERT INTO `%s`.`%s` (%s) VALUES %s ON DUPLICATE KEY UPDATE %s"
 	mySQLBulkMergeTemplate           = "INSERT INTO `%s`.`%s` (%s) SELECT * FROM (SELECT %s FROM `%s`.`%s`) AS tmp ON DUPLICATE KEY UPDATE %s"
 	mySQLDeleteQueryTemplate         = "DELETE FROM `%s`.`%s` WHERE %s"
+	mySQLCountRowsTemplate           = "SELECT count(*) FROM `%s`.`%s` WHERE %s"
 	mySQLAddColumnTemplate           = "ALTER TABLE `%s`.`%s` ADD COLUMN %s"
+	mySQLModifyColumnTemplate        = "ALTER TABLE `%s`.`%s` MODIFY COLUMN %s"
 	mySQLRenameTableTemplate         = "RENAME TABLE `%s`.`%s` TO `%s`.`%s`"
 
 	mySQLDropTableTemplate     = "DROP TABLE `%s`.`%s`"
 	mySQLTruncateTableTemplate = "TRUNCATE TABLE `%s`.`%s`"
+	mySQLCreateTableAsTemplate = "CREATE TABLE `%s`.`%s` AS %s"
+	mySQLCreateViewTemplate    = "CREATE OR REPLACE VIEW `%s`.`%s` AS %s"
+	mySQLEraseUpdateTemplate   = "UPDATE `%s`.`%s` SET %s WHERE %s"
 	MySQLValuesLimit           = 65535 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
 	batchRetryAttempts         = 3     //number of additional tries to proceed batch update or insert.
 	// Batch operation takes a long time. And some mysql servers or middlewares prone to closing connections in the middle.
@@ -68,16 +73,25 @@ type MySQL struct {
 	config      *DataSourceConfig
 	dataSource  *sql.DB
 	queryLogger *logging.QueryLogger
+	//preparedStatements is used in stream mode inserts
+	preparedStatements *preparedStatements
 
 	sqlTypes typing.SQLTypes
 }
 
 //NewMySQL returns configured MySQL adapter instance
 func NewMySQL(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*MySQL, error) {
+	if err := processMySQLSSL(config); err != nil {
+		return nil, err
+	}
 	if _, ok := config.Parameters["tls"]; !ok {
 		// similar to postgres default value of sslmode option
 		config.Parameters["tls"] = "preferred"
 	}
+	//MySQL doesn't have statement timeout for inserts, so waiting for the server response is limited instead
+	if _, ok := config.Parameters["readTimeout"]; !ok && config.Pool.StatementTimeout() > 0 {
+		config.Parameters["readTimeout"] = config.Pool.StatementTimeout().String()
+	}
 	connectionString := mySQLDriverConnectionString(config)
 	dataSource, err := sql.Open("mysql", connectionString)
 	if err != nil {
@@ -89,11 +103,11 @@ func NewMySQL(ctx context.Context, config *DataSourceConfig, queryLogger *loggin
 		return nil, err
 	}
 
-	//set default values
-	dataSource.SetConnMaxLifetime(3 * time.Minute)
-	dataSource.SetMaxIdleConns(10)
+	//default connection lifetime is 3 minutes and 10 idle connections
+	config.Pool.apply(dataSource, 3*time.Minute, 10)
 
-	return &MySQL{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToMySQL)}, nil
+	return &MySQL{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger,
+		preparedStatements: config.Pool.newPreparedStatements(dataSource), sqlTypes: reformatMappings(sqlTypes, SchemaToMySQL)}, nil
 }
 
 //Type returns MySQL type
@@ -282,7 +296,7 @@ func (m *MySQL) insertSingle(eventContext *EventContext) error {
 
 	m.queryLogger.LogQueryWithValues(statement, values)
 
-	if _, err := m.dataSource.ExecContext(m.ctx, statement, values...); err != nil {
+	if err := m.preparedStatements.exec(m.ctx, statement, values...); err != nil {
 		return errorj.ExecuteInsertError.Wrap(err, "failed to execute single insert").
 			WithProperty(errorj.DBInfo, &ErrorPayload{
 				Schema:      m.config.Db,
@@ -400,6 +414,110 @@ func (m *MySQL) toDeleteQuery(conditions *base.DeleteConditions) (string, []inte
 	return strings.Join(queryConditions, " "+conditions.JoinCondition+" "), values
 }
 
+//Materialize creates or replaces a view or recreates a table with the query results.
+//Table is created with a temporary name and replaces the original one
+func (m *MySQL) Materialize(name, query, materialized string) error {
+	if materialized == MaterializedTable {
+		tmpTable := name + "_tmp" + timestamp.Now().Format("_20060102_150405")
+		if err := m.executeMaterialization(name, fmt.Sprintf(mySQLCreateTableAsTemplate, m.config.Db, tmpTable, query)); err != nil {
+			return err
+		}
+
+		return m.ReplaceTable(name, tmpTable, true)
+	}
+
+	return m.executeMaterialization(name, fmt.Sprintf(mySQLCreateViewTemplate, m.config.Db, name, query))
+}
+
+func (m *MySQL) executeMaterialization(name, statement string) error {
+	m.queryLogger.LogDDL(statement)
+	if _, err := m.dataSource.ExecContext(m.ctx, statement); err != nil {
+		return errorj.MaterializeError.Wrap(err, "failed to materialize query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  m.config.Db,
+				Table:     name,
+				Statement: statement,
+			})
+	}
+
+	return nil
+}
+
+//Expire deletes (or counts if dryRun) rows where the column is less than the threshold
+func (m *MySQL) Expire(tableName, column string, threshold time.Time, dryRun bool) (int64, error) {
+	table, err := m.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	if ok, err := checkRetentionColumn(table, column); !ok {
+		return 0, err
+	}
+
+	condition := "`" + column + "` < ?"
+	values := []interface{}{threshold.UTC()}
+	var statement string
+	var affected int64
+	if dryRun {
+		statement = fmt.Sprintf(mySQLCountRowsTemplate, m.config.Db, tableName, condition)
+		m.queryLogger.LogQueryWithValues(statement, values)
+		affected, err = countRows(m.ctx, m.dataSource, statement, values...)
+	} else {
+		statement = fmt.Sprintf(mySQLDeleteQueryTemplate, m.config.Db, tableName, condition)
+		m.queryLogger.LogQueryWithValues(statement, values)
+		var result sql.Result
+		if result, err = m.dataSource.ExecContext(m.ctx, statement, values...); err == nil {
+			affected = rowsAffected(result)
+		}
+	}
+	if err != nil {
+		return 0, errorj.ExpireError.Wrap(err, "failed to expire data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  m.config.Db,
+				Table:     tableName,
+				Statement: statement,
+			})
+	}
+
+	return affected, nil
+}
+
+//Erase deletes rows or sets columns to NULL where any of the columns equals to the value
+func (m *MySQL) Erase(tableName string, columns []string, value string, mode string) (int64, error) {
+	if err := validateEraseMode(mode); err != nil {
+		return 0, err
+	}
+
+	table, err := m.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	columns = existingColumns(table, columns)
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	query := &eraseQuery{
+		quote:       func(column string) string { return "`" + column + "`" },
+		cast:        func(column string) string { return "CAST(" + column + " AS CHAR)" },
+		placeholder: func(i int) string { return "?" },
+	}
+	statement, values := query.build(fmt.Sprintf(mySQLDeleteQueryTemplate, m.config.Db, tableName, "%s"),
+		fmt.Sprintf(mySQLEraseUpdateTemplate, m.config.Db, tableName, "%s", "%s"), columns, value, mode)
+	m.queryLogger.LogQueryWithValues(statement, values)
+
+	result, err := m.dataSource.ExecContext(m.ctx, statement, values...)
+	if err != nil {
+		return 0, errorj.EraseError.Wrap(err, "failed to erase data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  m.config.Db,
+				Table:     tableName,
+				Statement: statement,
+			})
+	}
+
+	return rowsAffected(result), nil
+}
+
 //Truncate deletes all records in tableName table
 func (m *MySQL) Truncate(tableName string) error {
 	sqlParams := SqlParams{
@@ -422,6 +540,7 @@ func (m *MySQL) Truncate(tableName string) error {
 
 //Close underlying sql.DB
 func (m *MySQL) Close() error {
+	m.preparedStatements.close()
 	return m.dataSource.Close()
 }
 
@@ -520,6 +639,30 @@ func (m *MySQL) getPrimaryKeys(tableName string) (map[string]bool, error) {
 	return primaryKeys, nil
 }
 
+//processMySQLSSL registers TLS config of the MySQL driver and enriches input DataSourceConfig 'tls' parameter with it
+func processMySQLSSL(config *DataSourceConfig) error {
+	if config.SSLConfiguration == nil {
+		return nil
+	}
+
+	tlsConfig, err := config.SSLConfiguration.TLSConfig(config.Host)
+	if err != nil {
+		return err
+	}
+	if tlsConfig == nil {
+		config.Parameters["tls"] = "false"
+		return nil
+	}
+
+	//one TLS config per MySQL server and user (the same key is overwritten on destination reloading)
+	tlsConfigName := "jitsu_" + uuid.GetHash(map[string]interface{}{"host": config.Host, "port": config.Port, "db": config.Db, "username": config.Username})
+	if err := mysql.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+		return fmt.Errorf("error registering MySQL TLS config: %v", err)
+	}
+	config.Parameters["tls"] = tlsConfigName
+	return nil
+}
+
 func mySQLDriverConnectionString(config *DataSourceConfig) string {
 	// [user[:password]@][net[(addr)]]/dbname[?param1=value1&paramN=valueN]
 	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
@@ -744,6 +887,23 @@ func (m *MySQL) buildUpdateSection(header []string) string {
 	return strings.Join(updateColumns, ",")
 }
 
+//AlterColumnType changes column type in place (MySQL converts existing values)
+func (m *MySQL) AlterColumnType(table *Table, columnName string, column typing.SQLColumn) error {
+	query := fmt.Sprintf(mySQLModifyColumnTemplate, m.config.Db, table.Name, m.columnDDL(columnName, column, table.GetPKFieldsMap()))
+	m.queryLogger.LogDDL(query)
+
+	if _, err := m.dataSource.ExecContext(m.ctx, query); err != nil {
+		return errorj.PatchTableError.Wrap(err, "failed to alter column type").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    m.config.Db,
+				Table:     table.Name,
+				Statement: query,
+			})
+	}
+
+	return nil
+}
+
 //columnDDL returns column DDL (quoted column name, mapped sql type and 'not null' if pk field)
 func (m *MySQL) columnDDL(name string, column typing.SQLColumn, pkFields map[string]bool) string {
 	sqlType := column.DDLType()
@@ -55,6 +55,7 @@ WHERE tco.constraint_type = 'PRIMARY KEY' AND
 	bulkMergeTemplate                 = `INSERT INTO "%s"."%s"(%s) SELECT %s FROM "%s"."%s" ON CONFLICT ON CONSTRAINT %s DO UPDATE SET %s`
 	bulkMergePrefix                   = `excluded`
 	deleteQueryTemplate               = `DELETE FROM "%s"."%s" WHERE %s`
+	countRowsTemplate                 = `SELECT count(*) FROM "%s"."%s" WHERE %s`
 
 	updateStatement   = `UPDATE "%s"."%s" SET %s WHERE %s=$%d`
 	dropTableTemplate = `DROP TABLE %s"%s"."%s"`
@@ -62,8 +63,13 @@ WHERE tco.constraint_type = 'PRIMARY KEY' AND
 	copyColumnTemplate            = `UPDATE "%s"."%s" SET %s = %s`
 	dropColumnTemplate            = `ALTER TABLE "%s"."%s" DROP COLUMN %s`
 	renameColumnTemplate          = `ALTER TABLE "%s"."%s" RENAME COLUMN %s TO %s`
+	alterColumnTypeTemplate       = `ALTER TABLE "%s"."%s" ALTER COLUMN "%s" TYPE %s USING "%s"::%s`
 	renameTableTemplate           = `ALTER TABLE "%s"."%s" RENAME TO "%s"`
 	postgresTruncateTableTemplate = `TRUNCATE "%s"."%s"`
+	createTableAsTemplate         = `CREATE TABLE "%s"."%s" AS %s`
+	dropViewTemplate              = `DROP VIEW IF EXISTS "%s"."%s"`
+	createViewTemplate            = `CREATE VIEW "%s"."%s" AS %s`
+	eraseUpdateTemplate           = `UPDATE "%s"."%s" SET %s WHERE %s`
 	PostgresValuesLimit           = 65535 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
 )
 
@@ -157,15 +163,16 @@ func ObjectValuesToString(header []string, valueArgs []interface{}) string {
 
 // DataSourceConfig dto for deserialized datasource config (e.g. in Postgres or AwsRedshift destination)
 type DataSourceConfig struct {
-	Host             string            `mapstructure:"host,omitempty" json:"host,omitempty" yaml:"host,omitempty"`
-	Port             int               `mapstructure:"port,omitempty" json:"port,omitempty" yaml:"port,omitempty"`
-	Db               string            `mapstructure:"db,omitempty" json:"db,omitempty" yaml:"db,omitempty"`
-	Schema           string            `mapstructure:"schema,omitempty" json:"schema,omitempty" yaml:"schema,omitempty"`
-	Username         string            `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
-	Password         string            `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
-	Parameters       map[string]string `mapstructure:"parameters,omitempty" json:"parameters,omitempty" yaml:"parameters,omitempty"`
-	SSLConfiguration *SSLConfig        `mapstructure:"ssl,omitempty" json:"ssl,omitempty" yaml:"ssl,omitempty"`
-	S3               *S3Config         `mapstructure:"s3,omitempty" json:"s3,omitempty" yaml:"s3,omitempty"`
+	Host             string                `mapstructure:"host,omitempty" json:"host,omitempty" yaml:"host,omitempty"`
+	Port             int                   `mapstructure:"port,omitempty" json:"port,omitempty" yaml:"port,omitempty"`
+	Db               string                `mapstructure:"db,omitempty" json:"db,omitempty" yaml:"db,omitempty"`
+	Schema           string                `mapstructure:"schema,omitempty" json:"schema,omitempty" yaml:"schema,omitempty"`
+	Username         string                `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
+	Password         string                `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	Parameters       map[string]string     `mapstructure:"parameters,omitempty" json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	SSLConfiguration *SSLConfig            `mapstructure:"ssl,omitempty" json:"ssl,omitempty" yaml:"ssl,omitempty"`
+	S3               *S3Config             `mapstructure:"s3,omitempty" json:"s3,omitempty" yaml:"s3,omitempty"`
+	Pool             *ConnectionPoolConfig `mapstructure:"pool,omitempty" json:"pool,omitempty" yaml:"pool,omitempty"`
 }
 
 // Validate required fields in DataSourceConfig
@@ -192,7 +199,7 @@ func (dsc *DataSourceConfig) Validate() error {
 			return err
 		}
 	}
-	return nil
+	return dsc.Pool.Validate()
 }
 
 // Postgres is adapter for creating,patching (schema or table), inserting data to postgres
@@ -201,42 +208,46 @@ type Postgres struct {
 	config      *DataSourceConfig
 	dataSource  *sql.DB
 	queryLogger *logging.QueryLogger
+	//preparedStatements is used in stream mode inserts
+	preparedStatements *preparedStatements
 
 	sqlTypes typing.SQLTypes
 }
 
 // NewPostgresUnderRedshift returns configured Postgres adapter instance without mapping old types
 func NewPostgresUnderRedshift(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Postgres, error) {
-	connectionString := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s ",
-		config.Host, config.Port, config.Db, config.Username, config.Password)
-	//concat provided connection parameters
-	for k, v := range config.Parameters {
-		connectionString += k + "=" + v + " "
-	}
-	dataSource, err := sql.Open("postgres", connectionString)
+	dataSource, err := openPostgres(config)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := dataSource.Ping(); err != nil {
-		dataSource.Close()
+	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger,
+		preparedStatements: config.Pool.newPreparedStatements(dataSource), sqlTypes: sqlTypes}, nil
+}
+
+// NewPostgres return configured Postgres adapter instance
+func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Postgres, error) {
+	dataSource, err := openPostgres(config)
+	if err != nil {
 		return nil, err
 	}
 
-	//set default value
-	dataSource.SetConnMaxLifetime(10 * time.Minute)
-
-	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: sqlTypes}, nil
+	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger,
+		preparedStatements: config.Pool.newPreparedStatements(dataSource), sqlTypes: reformatMappings(sqlTypes, SchemaToPostgres)}, nil
 }
 
-// NewPostgres return configured Postgres adapter instance
-func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Postgres, error) {
+// openPostgres opens and pings connection pool configured with DataSourceConfig parameters and pool settings
+func openPostgres(config *DataSourceConfig) (*sql.DB, error) {
 	connectionString := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s ",
 		config.Host, config.Port, config.Db, config.Username, config.Password)
 	//concat provided connection parameters
 	for k, v := range config.Parameters {
 		connectionString += k + "=" + v + " "
 	}
+	//explicit statement_timeout parameter has priority
+	if _, ok := config.Parameters["statement_timeout"]; !ok && config.Pool.StatementTimeout() > 0 {
+		connectionString += fmt.Sprintf("statement_timeout=%d ", config.Pool.StatementTimeout().Milliseconds())
+	}
 	dataSource, err := sql.Open("postgres", connectionString)
 	if err != nil {
 		return nil, err
@@ -247,10 +258,9 @@ func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *log
 		return nil, err
 	}
 
-	//set default value
-	dataSource.SetConnMaxLifetime(10 * time.Minute)
-
-	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToPostgres)}, nil
+	//default connection lifetime is 10 minutes
+	config.Pool.apply(dataSource, 10*time.Minute, 0)
+	return dataSource, nil
 }
 
 // Type returns Postgres type
@@ -421,7 +431,7 @@ func (p *Postgres) insertSingle(eventContext *EventContext) error {
 
 	p.queryLogger.LogQueryWithValues(statement, values)
 
-	if _, err := p.dataSource.ExecContext(p.ctx, statement, values...); err != nil {
+	if err := p.preparedStatements.exec(p.ctx, statement, values...); err != nil {
 		err = checkErr(err)
 
 		return errorj.ExecuteInsertError.Wrap(err, "failed to execute single insert").
@@ -678,6 +688,133 @@ func (p *Postgres) ReplaceTable(originalTable, replacementTable string, dropOldT
 	return
 }
 
+//Materialize creates a view or recreates a table with the query results.
+//Table is created with a temporary name and replaces the original one, so readers always see complete results
+func (p *Postgres) Materialize(name, query, materialized string) (err error) {
+	if materialized == MaterializedTable {
+		tmpTable := name + "_tmp" + timestamp.Now().Format("_20060102_150405")
+		if err := p.executeMaterialization(name, fmt.Sprintf(createTableAsTemplate, p.config.Schema, tmpTable, query)); err != nil {
+			return err
+		}
+
+		return p.ReplaceTable(name, tmpTable, true)
+	}
+
+	return p.executeMaterialization(name, fmt.Sprintf(dropViewTemplate, p.config.Schema, name),
+		fmt.Sprintf(createViewTemplate, p.config.Schema, name, query))
+}
+
+//executeMaterialization executes statements in transaction
+func (p *Postgres) executeMaterialization(name string, statements ...string) (err error) {
+	wrappedTx, err := p.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			rbErr := wrappedTx.Rollback()
+			if rbErr != nil {
+				err = errorj.Group(err, rbErr)
+			}
+		} else {
+			err = wrappedTx.Commit()
+		}
+	}()
+
+	for _, statement := range statements {
+		p.queryLogger.LogDDL(statement)
+		if _, err := wrappedTx.tx.ExecContext(p.ctx, statement); err != nil {
+			err = checkErr(err)
+			return errorj.MaterializeError.Wrap(err, "failed to materialize query").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema:    p.config.Schema,
+					Table:     name,
+					Statement: statement,
+				})
+		}
+	}
+
+	return nil
+}
+
+//Expire deletes (or counts if dryRun) rows where the column is less than the threshold
+func (p *Postgres) Expire(tableName, column string, threshold time.Time, dryRun bool) (int64, error) {
+	table, err := p.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	if ok, err := checkRetentionColumn(table, column); !ok {
+		return 0, err
+	}
+
+	condition := fmt.Sprintf(`"%s" < $1`, column)
+	values := []interface{}{threshold.UTC()}
+	var statement string
+	var affected int64
+	if dryRun {
+		statement = fmt.Sprintf(countRowsTemplate, p.config.Schema, tableName, condition)
+		p.queryLogger.LogQueryWithValues(statement, values)
+		affected, err = countRows(p.ctx, p.dataSource, statement, values...)
+	} else {
+		statement = fmt.Sprintf(deleteQueryTemplate, p.config.Schema, tableName, condition)
+		p.queryLogger.LogQueryWithValues(statement, values)
+		var result sql.Result
+		if result, err = p.dataSource.ExecContext(p.ctx, statement, values...); err == nil {
+			affected = rowsAffected(result)
+		}
+	}
+	if err != nil {
+		err = checkErr(err)
+		return 0, errorj.ExpireError.Wrap(err, "failed to expire data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Table:     tableName,
+				Statement: statement,
+			})
+	}
+
+	return affected, nil
+}
+
+//Erase deletes rows or sets columns to NULL where any of the columns equals to the value
+func (p *Postgres) Erase(tableName string, columns []string, value string, mode string) (int64, error) {
+	if err := validateEraseMode(mode); err != nil {
+		return 0, err
+	}
+
+	table, err := p.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	columns = existingColumns(table, columns)
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	query := &eraseQuery{
+		quote:       func(column string) string { return fmt.Sprintf(`"%s"`, column) },
+		cast:        func(column string) string { return column + "::text" },
+		placeholder: func(i int) string { return "$" + strconv.Itoa(i) },
+	}
+	statement, values := query.build(fmt.Sprintf(deleteQueryTemplate, p.config.Schema, tableName, "%s"),
+		fmt.Sprintf(eraseUpdateTemplate, p.config.Schema, tableName, "%s", "%s"), columns, value, mode)
+	p.queryLogger.LogQueryWithValues(statement, values)
+
+	result, err := p.dataSource.ExecContext(p.ctx, statement, values...)
+	if err != nil {
+		err = checkErr(err)
+		return 0, errorj.EraseError.Wrap(err, "failed to erase data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Table:     tableName,
+				Statement: statement,
+			})
+	}
+
+	return rowsAffected(result), nil
+}
+
 // Update one record in Postgres
 func (p *Postgres) Update(table *Table, object map[string]interface{}, whereKey string, whereValue interface{}) error {
 	columns := make([]string, len(object), len(object))
@@ -933,6 +1070,25 @@ func (p *Postgres) executeInsertInTransaction(wrappedTx *Transaction, table *Tab
 	return nil
 }
 
+// AlterColumnType changes column type in place with USING cast of existing values
+func (p *Postgres) AlterColumnType(table *Table, columnName string, column typing.SQLColumn) error {
+	sqlType := column.DDLType()
+	query := fmt.Sprintf(alterColumnTypeTemplate, p.config.Schema, table.Name, columnName, sqlType, columnName, sqlType)
+	p.queryLogger.LogDDL(query)
+
+	if _, err := p.dataSource.ExecContext(p.ctx, query); err != nil {
+		err = checkErr(err)
+		return errorj.PatchTableError.Wrap(err, "failed to alter column type").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Table:     table.Name,
+				Statement: query,
+			})
+	}
+
+	return nil
+}
+
 // columnDDL returns column DDL (quoted column name, mapped sql type and 'not null' if pk field)
 func (p *Postgres) columnDDL(name string, column typing.SQLColumn, pkFields map[string]bool) string {
 	var notNullClause string
@@ -981,6 +1137,7 @@ func (p *Postgres) getDefaultValueStatement(sqlType string) string {
 
 // Close underlying sql.DB
 func (p *Postgres) Close() error {
+	p.preparedStatements.close()
 	return p.dataSource.Close()
 }
 
@@ -0,0 +1,42 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+//Expirer is a SQLAdapter capability to enforce data retention: deletes rows where the timestamp column is less than the threshold.
+//If dryRun is true, rows are only counted. Returns amount of (to be) deleted rows or -1 if it is unknown
+type Expirer interface {
+	Expire(tableName, column string, threshold time.Time, dryRun bool) (int64, error)
+}
+
+//FilesExpirer is a file storage adapter capability to enforce data retention: deletes files which were modified before the threshold.
+//If dryRun is true, files are only counted. Returns amount of (to be) deleted files
+type FilesExpirer interface {
+	ExpireFiles(threshold time.Time, dryRun bool) (int64, error)
+}
+
+//checkRetentionColumn returns false if the table doesn't exist (nothing to expire) and err if the table doesn't have the column
+func checkRetentionColumn(table *Table, column string) (bool, error) {
+	if !table.Exists() {
+		return false, nil
+	}
+	if len(existingColumns(table, []string{column})) == 0 {
+		return false, fmt.Errorf("retention column %s doesn't exist in the table", column)
+	}
+
+	return true, nil
+}
+
+//countRows returns the result of SELECT count(*) statement
+func countRows(ctx context.Context, dataSource *sql.DB, statement string, values ...interface{}) (int64, error) {
+	var count int64
+	if err := dataSource.QueryRowContext(ctx, statement, values...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
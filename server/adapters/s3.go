@@ -2,28 +2,62 @@ package adapters
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"go.uber.org/atomic"
 )
 
+const (
+	s3ServerSideEncryptionAES256 = "AES256"
+	s3ServerSideEncryptionKMS    = "aws:kms"
+
+	//defaultS3Region is used with custom endpoints (MinIO, etc.) which don't require region
+	//and for region auto-detection requests
+	defaultS3Region = "us-east-1"
+)
+
 //S3Config is a dto for config deserialization
 type S3Config struct {
+	//AccessKeyID and SecretKey are static credentials. If they are empty, the default AWS credential chain is used:
+	//environment variables, shared config profile (including SSO), web identity token (EKS IRSA), ECS task role and EC2 instance profile
 	AccessKeyID string `mapstructure:"access_key_id,omitempty" json:"access_key_id,omitempty" yaml:"access_key_id,omitempty"`
 	SecretKey   string `mapstructure:"secret_access_key,omitempty" json:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"`
-	Bucket      string `mapstructure:"bucket,omitempty" json:"bucket,omitempty" yaml:"bucket,omitempty"`
-	Region      string `mapstructure:"region,omitempty" json:"region,omitempty" yaml:"region,omitempty"`
-	Endpoint    string `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
-	FileConfig  `mapstructure:",squash" yaml:"-,inline"`
+	//Profile is a shared config profile of the default credential chain
+	Profile string `mapstructure:"profile,omitempty" json:"profile,omitempty" yaml:"profile,omitempty"`
+	//RoleARN is an IAM role which is assumed on behalf of the static or default credentials
+	RoleARN string `mapstructure:"role_arn,omitempty" json:"role_arn,omitempty" yaml:"role_arn,omitempty"`
+	//ExternalID is an external ID of the assumed role
+	ExternalID string `mapstructure:"external_id,omitempty" json:"external_id,omitempty" yaml:"external_id,omitempty"`
+	Bucket     string `mapstructure:"bucket,omitempty" json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	//Region is a bucket region. It is detected automatically if empty
+	Region string `mapstructure:"region,omitempty" json:"region,omitempty" yaml:"region,omitempty"`
+	//Endpoint is a custom endpoint of S3-compatible storage (MinIO, etc.)
+	Endpoint string `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	//ForcePathStyle enables path-style addressing (endpoint/bucket/key) which is required by most S3-compatible storages
+	ForcePathStyle bool `mapstructure:"force_path_style,omitempty" json:"force_path_style,omitempty" yaml:"force_path_style,omitempty"`
+	//ServerSideEncryption is an encryption of uploaded objects: AES256 (SSE-S3) or aws:kms (SSE-KMS)
+	ServerSideEncryption string `mapstructure:"server_side_encryption,omitempty" json:"server_side_encryption,omitempty" yaml:"server_side_encryption,omitempty"`
+	//KMSKeyID is a KMS key for aws:kms encryption. Default AWS managed key is used if empty
+	KMSKeyID   string `mapstructure:"kms_key_id,omitempty" json:"kms_key_id,omitempty" yaml:"kms_key_id,omitempty"`
+	FileConfig `mapstructure:",squash" yaml:"-,inline"`
+
+	//will be set on the first usage
+	session *session.Session
 }
 
 //Validate returns err if invalid
@@ -31,18 +65,98 @@ func (s3c *S3Config) Validate() error {
 	if s3c == nil {
 		return errors.New("S3 config is required")
 	}
-	if s3c.AccessKeyID == "" {
-		return errors.New("S3 access_key_id is required parameter")
+	if s3c.AccessKeyID == "" && s3c.SecretKey != "" {
+		return errors.New("S3 access_key_id is required parameter with secret_access_key")
+	}
+	if s3c.AccessKeyID != "" && s3c.SecretKey == "" {
+		return errors.New("S3 secret_access_key is required parameter with access_key_id")
 	}
-	if s3c.SecretKey == "" {
-		return errors.New("S3 secret_access_key is required parameter")
+	if s3c.ExternalID != "" && s3c.RoleARN == "" {
+		return errors.New("S3 role_arn is required parameter with external_id")
 	}
 	if s3c.Bucket == "" {
 		return errors.New("S3 bucket is required parameter")
 	}
-	if s3c.Region == "" {
-		return errors.New("S3 region is required parameter")
+	switch s3c.ServerSideEncryption {
+	case "", s3ServerSideEncryptionAES256:
+		if s3c.KMSKeyID != "" {
+			return fmt.Errorf("S3 kms_key_id requires server_side_encryption: %s", s3ServerSideEncryptionKMS)
+		}
+	case s3ServerSideEncryptionKMS:
+	default:
+		return fmt.Errorf("Unknown S3 server_side_encryption [%s]. Available: [%s, %s]", s3c.ServerSideEncryption, s3ServerSideEncryptionAES256, s3ServerSideEncryptionKMS)
+	}
+	return s3c.ValidateCompression()
+}
+
+//awsSession returns AWS session with static credentials or with the default credential chain.
+//If RoleARN is set, the role is assumed (with ExternalID)
+func (s3c *S3Config) awsSession() (*session.Session, error) {
+	if s3c.session != nil {
+		return s3c.session, nil
+	}
+
+	options := session.Options{SharedConfigState: session.SharedConfigEnable, Profile: s3c.Profile}
+	if s3c.AccessKeyID != "" {
+		options.Config.Credentials = credentials.NewStaticCredentials(s3c.AccessKeyID, s3c.SecretKey, "")
+	}
+	if s3c.Region != "" {
+		options.Config.Region = aws.String(s3c.Region)
+	}
+	awsSession, err := session.NewSessionWithOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating AWS session: %v", err)
+	}
+	//region from environment or shared config is used for STS requests
+	if aws.StringValue(awsSession.Config.Region) == "" {
+		awsSession.Config.Region = aws.String(defaultS3Region)
+	}
+
+	if s3c.RoleARN != "" {
+		awsSession = awsSession.Copy(&aws.Config{Credentials: stscreds.NewCredentials(awsSession, s3c.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			if s3c.ExternalID != "" {
+				p.ExternalID = aws.String(s3c.ExternalID)
+			}
+		})})
+	}
+
+	s3c.session = awsSession
+	return awsSession, nil
+}
+
+//Credentials returns current (static or temporary) AWS credentials for passing into COPY commands of Redshift and Snowflake
+func (s3c *S3Config) Credentials() (credentials.Value, error) {
+	awsSession, err := s3c.awsSession()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	value, err := awsSession.Config.Credentials.Get()
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("Error getting AWS credentials: %v", err)
+	}
+
+	return value, nil
+}
+
+//resolveRegion detects bucket region if it isn't configured
+func (s3c *S3Config) resolveRegion(awsSession *session.Session) error {
+	if s3c.Region != "" {
+		return nil
 	}
+
+	if s3c.Endpoint != "" {
+		s3c.Region = defaultS3Region
+		return nil
+	}
+
+	region, err := s3manager.GetBucketRegion(context.Background(), awsSession, s3c.Bucket, aws.StringValue(awsSession.Config.Region))
+	if err != nil {
+		return fmt.Errorf("Error detecting region of S3 bucket [%s]. Please configure region explicitly: %v", s3c.Bucket, err)
+	}
+
+	logging.Infof("Detected region of S3 bucket [%s]: %s", s3c.Bucket, region)
+	s3c.Region = region
 	return nil
 }
 
@@ -60,18 +174,25 @@ func NewS3(s3Config *S3Config) (*S3, error) {
 		return nil, err
 	}
 
+	awsSession, err := s3Config.awsSession()
+	if err != nil {
+		return nil, err
+	}
+	if err := s3Config.resolveRegion(awsSession); err != nil {
+		return nil, err
+	}
+
 	awsConfig := aws.NewConfig().
-		WithCredentials(credentials.NewStaticCredentials(s3Config.AccessKeyID, s3Config.SecretKey, "")).
-		WithRegion(s3Config.Region)
+		WithRegion(s3Config.Region).
+		WithS3ForcePathStyle(s3Config.ForcePathStyle)
 	if s3Config.Endpoint != "" {
 		awsConfig.WithEndpoint(s3Config.Endpoint)
 	}
 	if s3Config.Format == "" {
 		s3Config.Format = FileFormatFlatJSON
 	}
-	s3Session := session.Must(session.NewSession())
 
-	return &S3{client: s3.New(s3Session, awsConfig), config: s3Config, closed: atomic.NewBool(false)}, nil
+	return &S3{client: s3.New(awsSession, awsConfig), config: s3Config, closed: atomic.NewBool(false)}, nil
 }
 
 func (a *S3) Format() FileEncodingFormat {
@@ -97,15 +218,24 @@ func (a *S3) UploadBytes(fileName string, fileBytes []byte) error {
 	}
 
 	var fileType string
-	if a.config.Compression == FileCompressionGZIP {
+	switch a.config.Compression {
+	case FileCompressionGZIP:
 		fileType = "application/gzip"
-	} else {
+	case FileCompressionZSTD:
+		fileType = "application/zstd"
+	default:
 		fileType = http.DetectContentType(fileBytes)
 	}
 
 	params.ContentType = aws.String(fileType)
 	params.Key = aws.String(fileName)
 	params.Body = bytes.NewReader(fileBytes)
+	if a.config.ServerSideEncryption != "" {
+		params.ServerSideEncryption = aws.String(a.config.ServerSideEncryption)
+	}
+	if a.config.KMSKeyID != "" {
+		params.SSEKMSKeyId = aws.String(a.config.KMSKeyID)
+	}
 	if _, err := a.client.PutObject(params); err != nil {
 		return errorj.SaveOnStageError.Wrap(err, "failed to write file to s3").
 			WithProperty(errorj.DBInfo, &ErrorPayload{
@@ -143,6 +273,91 @@ func (a *S3) DeleteObject(key string) error {
 	return nil
 }
 
+//ListObjects returns keys of all objects with the prefix (relative to the configured folder).
+//Reads all pages of the bucket listing
+func (a *S3) ListObjects(prefix string) ([]string, error) {
+	if a.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed S3 instance")
+	}
+
+	folderPrefix := a.config.folderPrefix()
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(a.config.Bucket), Prefix: aws.String(folderPrefix + prefix)}
+	var keys []string
+	if err := a.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(object.Key), folderPrefix))
+		}
+		return true
+	}); err != nil {
+		return nil, errorj.SaveOnStageError.Wrap(err, "failed to list objects in s3").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    a.config.Bucket,
+				Statement: fmt.Sprintf("prefix: %s", prefix),
+			})
+	}
+
+	return keys, nil
+}
+
+//ExpireFiles deletes (or counts if dryRun) objects in the configured folder which were modified before the threshold
+func (a *S3) ExpireFiles(threshold time.Time, dryRun bool) (int64, error) {
+	if a.closed.Load() {
+		return 0, fmt.Errorf("attempt to use closed S3 instance")
+	}
+
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(a.config.Bucket), Prefix: aws.String(a.config.folderPrefix())}
+	var expired []string
+	if err := a.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if object.LastModified != nil && object.LastModified.Before(threshold) {
+				expired = append(expired, aws.StringValue(object.Key))
+			}
+		}
+		return true
+	}); err != nil {
+		return 0, errorj.ExpireError.Wrap(err, "failed to list objects in s3").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket: a.config.Bucket,
+			})
+	}
+	if dryRun {
+		return int64(len(expired)), nil
+	}
+
+	var deleted int64
+	for _, key := range expired {
+		if _, err := a.client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(a.config.Bucket), Key: aws.String(key)}); err != nil {
+			return deleted, errorj.ExpireError.Wrap(err, "failed to delete from s3").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Bucket:    a.config.Bucket,
+					Statement: fmt.Sprintf("file: %s", key),
+				})
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+//DownloadBytes returns payload of the object by key (relative to the configured folder)
+func (a *S3) DownloadBytes(key string) ([]byte, error) {
+	if a.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed S3 instance")
+	}
+
+	output, err := a.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(a.config.Bucket), Key: aws.String(a.config.folderPrefix() + key)})
+	if err != nil {
+		return nil, errorj.SaveOnStageError.Wrap(err, "failed to download from s3").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    a.config.Bucket,
+				Statement: fmt.Sprintf("file: %s", key),
+			})
+	}
+	defer output.Body.Close()
+
+	return ioutil.ReadAll(output.Body)
+}
+
 //ValidateWritePermission tries to create temporary file and remove it.
 //returns nil if file creation was successful.
 func (a *S3) ValidateWritePermission() error {
@@ -0,0 +1,191 @@
+package adapters
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+//s3CompatibleServer emulates path-style S3-compatible storage (MinIO) with 2 objects per ListObjectsV2 page
+type s3CompatibleServer struct {
+	mutex   sync.Mutex
+	objects  map[string]http.Header
+	modified map[string]time.Time
+	keys     []string
+}
+
+func (s *s3CompatibleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !strings.HasPrefix(r.URL.Path, "/bucket") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/bucket"), "/")
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = r.Header.Clone()
+		s.modified[key] = time.Now().UTC()
+		s.keys = append(s.keys, key)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		for i, k := range s.keys {
+			if k == key {
+				s.keys = append(s.keys[:i], s.keys[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		prefix := r.URL.Query().Get("prefix")
+		var matched []string
+		for _, k := range s.keys {
+			if strings.HasPrefix(k, prefix) {
+				matched = append(matched, k)
+			}
+		}
+
+		start := 0
+		if token := r.URL.Query().Get("continuation-token"); token != "" {
+			fmt.Sscanf(token, "%d", &start)
+		}
+		end := start + 2
+		truncated := end < len(matched)
+		if !truncated {
+			end = len(matched)
+		}
+
+		body := `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>bucket</Name>`
+		for _, k := range matched[start:end] {
+			body += "<Contents><Key>" + k + "</Key><LastModified>" + s.modified[k].Format(time.RFC3339) + "</LastModified></Contents>"
+		}
+		body += fmt.Sprintf("<IsTruncated>%t</IsTruncated>", truncated)
+		if truncated {
+			body += fmt.Sprintf("<NextContinuationToken>%d</NextContinuationToken>", end)
+		}
+		body += "</ListBucketResult>"
+		w.Write([]byte(body))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3CompatibleStage(t *testing.T) {
+	storage := &s3CompatibleServer{objects: map[string]http.Header{}, modified: map[string]time.Time{}}
+	server := httptest.NewServer(storage)
+	defer server.Close()
+
+	s3, err := NewS3(&S3Config{
+		AccessKeyID:          "key",
+		SecretKey:            "secret",
+		Bucket:               "bucket",
+		Endpoint:             server.URL,
+		ForcePathStyle:       true,
+		ServerSideEncryption: s3ServerSideEncryptionKMS,
+		KMSKeyID:             "kms_key",
+		FileConfig:           FileConfig{Folder: "staging"},
+	})
+	require.NoError(t, err)
+	defer s3.Close()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s3.UploadBytes(fmt.Sprintf("file_%d.log", i), []byte(`{"a":1}`)))
+	}
+	require.NoError(t, s3.UploadBytes("other.log", []byte(`{"a":1}`)))
+
+	header := storage.objects["staging/file_0.log"]
+	require.NotNil(t, header, "object must be uploaded via path-style request into the folder")
+	require.Equal(t, s3ServerSideEncryptionKMS, header.Get("X-Amz-Server-Side-Encryption"))
+	require.Equal(t, "kms_key", header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+
+	keys, err := s3.ListObjects("file_")
+	require.NoError(t, err)
+	require.Equal(t, []string{"file_0.log", "file_1.log", "file_2.log", "file_3.log", "file_4.log"}, keys)
+
+	require.Equal(t, defaultS3Region, s3.config.Region, "region of S3-compatible storage isn't detected")
+	awsCredentials, err := s3.config.Credentials()
+	require.NoError(t, err)
+	require.Equal(t, "key", awsCredentials.AccessKeyID)
+	require.Equal(t, "secret", awsCredentials.SecretAccessKey)
+	require.Empty(t, awsCredentials.SessionToken)
+
+	storage.mutex.Lock()
+	storage.modified["staging/file_0.log"] = time.Now().UTC().AddDate(0, 0, -40)
+	storage.modified["staging/file_1.log"] = time.Now().UTC().AddDate(0, 0, -31)
+	storage.mutex.Unlock()
+	threshold := time.Now().UTC().AddDate(0, 0, -30)
+
+	expired, err := s3.ExpireFiles(threshold, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), expired)
+	require.Len(t, storage.objects, 6, "dry run mustn't delete objects")
+
+	expired, err = s3.ExpireFiles(threshold, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), expired)
+	keys, err = s3.ListObjects("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"file_2.log", "file_3.log", "file_4.log", "other.log"}, keys)
+}
+
+func TestS3ConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      S3Config
+		expectedErr string
+	}{
+		{
+			"default credential chain with auto-detected region",
+			S3Config{Bucket: "bucket"},
+			"",
+		},
+		{
+			"assumed role with external id",
+			S3Config{Bucket: "bucket", RoleARN: "arn:aws:iam::123456789012:role/jitsu", ExternalID: "external"},
+			"",
+		},
+		{
+			"secret key without access key",
+			S3Config{SecretKey: "secret", Bucket: "bucket"},
+			"S3 access_key_id is required parameter with secret_access_key",
+		},
+		{
+			"external id without role",
+			S3Config{Bucket: "bucket", ExternalID: "external"},
+			"S3 role_arn is required parameter with external_id",
+		},
+		{
+			"s3-compatible without region",
+			S3Config{AccessKeyID: "key", SecretKey: "secret", Bucket: "bucket", Endpoint: "http://minio:9000"},
+			"",
+		},
+		{
+			"unknown encryption",
+			S3Config{AccessKeyID: "key", SecretKey: "secret", Bucket: "bucket", Region: "us-east-1", ServerSideEncryption: "aes"},
+			"Unknown S3 server_side_encryption [aes]. Available: [AES256, aws:kms]",
+		},
+		{
+			"kms key without kms encryption",
+			S3Config{AccessKeyID: "key", SecretKey: "secret", Bucket: "bucket", Region: "us-east-1", KMSKeyID: "kms_key"},
+			"S3 kms_key_id requires server_side_encryption: aws:kms",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -22,13 +24,18 @@ import (
 const (
 	tableExistenceSFQuery   = `SELECT count(*) from INFORMATION_SCHEMA.COLUMNS where TABLE_SCHEMA = ? and TABLE_NAME = ?`
 	descSchemaSFQuery       = `desc table %s.%s`
-	copyStatementFileFormat = ` FILE_FORMAT=(TYPE= 'CSV', FIELD_OPTIONALLY_ENCLOSED_BY = '"' ESCAPE_UNENCLOSED_FIELD = NONE SKIP_HEADER = 1 EMPTY_FIELD_AS_NULL = true) `
-	gcpFrom                 = `FROM @%s
+	copyStatementFileFormat = ` FILE_FORMAT=(TYPE= 'CSV', FIELD_OPTIONALLY_ENCLOSED_BY = '"' ESCAPE_UNENCLOSED_FIELD = NONE SKIP_HEADER = 1 EMPTY_FIELD_AS_NULL = true COMPRESSION = %s) `
+	namedStageFrom          = `FROM @%s
    							   %s
                                PATTERN = '%s'`
 	awsS3From = `FROM 's3://%s/%s'
-					           CREDENTIALS = (aws_key_id='%s' aws_secret_key='%s') 
+					           CREDENTIALS = (aws_key_id='%s' aws_secret_key='%s'%s) 
                                %s`
+	awsS3TokenCredential = ` aws_token='%s'`
+
+	copyStatementJSONFileFormat    = ` FILE_FORMAT=(TYPE= 'JSON' COMPRESSION = %s) `
+	copyStatementParquetFileFormat = ` FILE_FORMAT=(TYPE= 'PARQUET' USE_LOGICAL_TYPE = true) `
+	copyMatchByColumnNameOption    = ` MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE`
 
 	sfMergeStatement = `MERGE INTO %s.%s USING (SELECT %s FROM %s.%s) %s ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)`
 
@@ -36,11 +43,15 @@ const (
 	addSFColumnTemplate                 = `ALTER TABLE %s.%s ADD COLUMN %s`
 	sfRenameTableTemplate               = `ALTER TABLE %s%s.%s RENAME TO %s`
 	createSFTableTemplate               = `CREATE TABLE %s.%s (%s)`
+	clusterBySFTemplate                 = ` CLUSTER BY (%s)`
 	insertSFTemplate                    = `INSERT INTO %s.%s (%s) VALUES %s`
 	deleteSFTemplate                    = `DELETE FROM %s.%s WHERE %s`
+	countSFTemplate                     = `SELECT count(*) FROM %s.%s WHERE %s`
 	dropSFTableTemplate                 = `DROP TABLE %s%s.%s`
 	truncateSFTableTemplate             = `TRUNCATE TABLE IF EXISTS %s.%s`
+	materializeSFTemplate               = `CREATE OR REPLACE %s %s.%s AS %s`
 	updateSFTemplate                    = `UPDATE %s.%s SET %s WHERE %s = ?`
+	eraseUpdateSFTemplate               = `UPDATE %s.%s SET %s WHERE %s`
 )
 
 var (
@@ -56,17 +67,19 @@ var (
 
 //SnowflakeConfig dto for deserialized datasource config for Snowflake
 type SnowflakeConfig struct {
-	Account    string             `mapstructure:"account,omitempty" json:"account,omitempty" yaml:"account,omitempty"`
-	Port       int                `mapstructure:"port,omitempty" json:"port,omitempty" yaml:"port,omitempty"`
-	Db         string             `mapstructure:"db,omitempty" json:"db,omitempty" yaml:"db,omitempty"`
-	Schema     string             `mapstructure:"schema,omitempty" json:"schema,omitempty" yaml:"schema,omitempty"`
-	Username   string             `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
-	Password   string             `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
-	Warehouse  string             `mapstructure:"warehouse,omitempty" json:"warehouse,omitempty" yaml:"warehouse,omitempty"`
-	Stage      string             `mapstructure:"stage,omitempty" json:"stage,omitempty" yaml:"stage,omitempty"`
-	Parameters map[string]*string `mapstructure:"parameters,omitempty" json:"parameters,omitempty" yaml:"parameters,omitempty"`
-	S3         *S3Config          `mapstructure:"s3,omitempty" json:"s3,omitempty" yaml:"s3,omitempty"`
-	Google     *GoogleConfig      `mapstructure:"google,omitempty" json:"google,omitempty" yaml:"google,omitempty"`
+	Account    string                `mapstructure:"account,omitempty" json:"account,omitempty" yaml:"account,omitempty"`
+	Port       int                   `mapstructure:"port,omitempty" json:"port,omitempty" yaml:"port,omitempty"`
+	Db         string                `mapstructure:"db,omitempty" json:"db,omitempty" yaml:"db,omitempty"`
+	Schema     string                `mapstructure:"schema,omitempty" json:"schema,omitempty" yaml:"schema,omitempty"`
+	Username   string                `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
+	Password   string                `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	Warehouse  string                `mapstructure:"warehouse,omitempty" json:"warehouse,omitempty" yaml:"warehouse,omitempty"`
+	Stage      string                `mapstructure:"stage,omitempty" json:"stage,omitempty" yaml:"stage,omitempty"`
+	Parameters map[string]*string    `mapstructure:"parameters,omitempty" json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	S3         *S3Config             `mapstructure:"s3,omitempty" json:"s3,omitempty" yaml:"s3,omitempty"`
+	Google     *GoogleConfig         `mapstructure:"google,omitempty" json:"google,omitempty" yaml:"google,omitempty"`
+	Azure      *AzureBlobConfig      `mapstructure:"azure,omitempty" json:"azure,omitempty" yaml:"azure,omitempty"`
+	Pool       *ConnectionPoolConfig `mapstructure:"pool,omitempty" json:"pool,omitempty" yaml:"pool,omitempty"`
 }
 
 //Validate required fields in SnowflakeConfig
@@ -92,7 +105,7 @@ func (sc *SnowflakeConfig) Validate() error {
 	}
 
 	sc.Schema = reformatValue(sc.Schema)
-	return nil
+	return sc.Pool.Validate()
 }
 
 //Snowflake is adapter for creating,patching (schema or table), inserting data to snowflake
@@ -108,6 +121,14 @@ type Snowflake struct {
 //NewSnowflake returns configured Snowflake adapter instance
 func NewSnowflake(ctx context.Context, config *SnowflakeConfig, s3Config *S3Config,
 	queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Snowflake, error) {
+	//explicit STATEMENT_TIMEOUT_IN_SECONDS parameter has priority
+	if _, ok := config.Parameters["STATEMENT_TIMEOUT_IN_SECONDS"]; !ok && config.Pool.StatementTimeout() > 0 {
+		if config.Parameters == nil {
+			config.Parameters = map[string]*string{}
+		}
+		statementTimeout := strconv.Itoa(int(config.Pool.StatementTimeout().Seconds()))
+		config.Parameters["STATEMENT_TIMEOUT_IN_SECONDS"] = &statementTimeout
+	}
 	cfg := &sf.Config{
 		Account:   config.Account,
 		User:      config.Username,
@@ -133,6 +154,8 @@ func NewSnowflake(ctx context.Context, config *SnowflakeConfig, s3Config *S3Conf
 		return nil, err
 	}
 
+	config.Pool.apply(dataSource, 0, 0)
+
 	return &Snowflake{ctx: ctx, config: config, s3Config: s3Config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToSnowflake)}, nil
 }
 
@@ -320,26 +343,63 @@ func (s *Snowflake) GetTableSchema(tableName string) (*Table, error) {
 	return table, nil
 }
 
-//Copy transfer data from s3 to Snowflake by passing COPY request to Snowflake
-func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
+//Copy transfer data from s3 (or named stage) to Snowflake by passing COPY request to Snowflake
+//header is the columns order of the csv file. It is used only with FileFormatCSV: json and parquet files are loaded by column names
+func (s *Snowflake) Copy(fileName, tableName string, format FileEncodingFormat, header []string) error {
 	var reformattedHeader []string
 	for _, v := range header {
 		reformattedHeader = append(reformattedHeader, reformatValue(v))
 	}
 
-	statement := fmt.Sprintf(`COPY INTO %s.%s (%s) `, s.config.Schema, reformatValue(tableName), strings.Join(reformattedHeader, ","))
+	statement := fmt.Sprintf(`COPY INTO %s.%s `, s.config.Schema, reformatValue(tableName))
+	if format == FileFormatCSV {
+		statement = fmt.Sprintf(`COPY INTO %s.%s (%s) `, s.config.Schema, reformatValue(tableName), strings.Join(reformattedHeader, ","))
+	}
 	maskedCredentialsStatement := statement
-	if s.s3Config != nil {
+	if s.s3Config != nil && (s.s3Config.Endpoint == "" || s.config.Stage == "") {
 		//s3 integration stage
-		if s.s3Config.Folder != "" {
-			fileName = s.s3Config.Folder + "/" + fileName
+		fileName = s.s3Config.ObjectKey(fileName)
+		fileFormat, err := snowflakeFileFormat(format, s.s3Config.Compression)
+		if err != nil {
+			return errorj.CopyError.Wrap(err, "failed to build copy statement").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema: s.config.Schema,
+					Table:  tableName,
+				})
+		}
+		//static or temporary credentials of the default AWS credential chain (with session token)
+		awsCredentials, err := s.s3Config.Credentials()
+		if err != nil {
+			return errorj.CopyError.Wrap(err, "failed to get aws credentials").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema: s.config.Schema,
+					Table:  tableName,
+				})
 		}
-		statement += fmt.Sprintf(awsS3From, s.s3Config.Bucket, fileName, s.s3Config.AccessKeyID, s.s3Config.SecretKey, copyStatementFileFormat)
-		maskedCredentialsStatement += fmt.Sprintf(awsS3From, s.s3Config.Bucket, fileName, credentialsMask, credentialsMask, copyStatementFileFormat)
+		var token, maskedToken string
+		if awsCredentials.SessionToken != "" {
+			token = fmt.Sprintf(awsS3TokenCredential, awsCredentials.SessionToken)
+			maskedToken = fmt.Sprintf(awsS3TokenCredential, credentialsMask)
+		}
+		statement += fmt.Sprintf(awsS3From, s.s3Config.Bucket, fileName, awsCredentials.AccessKeyID, awsCredentials.SecretAccessKey, token, fileFormat)
+		maskedCredentialsStatement += fmt.Sprintf(awsS3From, s.s3Config.Bucket, fileName, credentialsMask, credentialsMask, maskedToken, fileFormat)
 	} else {
-		//gcp integration stage
-		statement += fmt.Sprintf(gcpFrom, s.config.Stage, copyStatementFileFormat, fileName)
-		maskedCredentialsStatement += fmt.Sprintf(gcpFrom, s.config.Stage, copyStatementFileFormat, fileName)
+		//named stage: gcp, azure or s3-compatible integration (default stage without compression)
+		fileFormat, err := snowflakeFileFormat(format, "")
+		if err != nil {
+			return errorj.CopyError.Wrap(err, "failed to build copy statement").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema: s.config.Schema,
+					Table:  tableName,
+				})
+		}
+		statement += fmt.Sprintf(namedStageFrom, s.config.Stage, fileFormat, fileName)
+		maskedCredentialsStatement += fmt.Sprintf(namedStageFrom, s.config.Stage, fileFormat, fileName)
+	}
+
+	if format != FileFormatCSV {
+		statement += copyMatchByColumnNameOption
+		maskedCredentialsStatement += copyMatchByColumnNameOption
 	}
 
 	if _, err := s.dataSource.ExecContext(s.ctx, statement); err != nil {
@@ -354,6 +414,32 @@ func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
 	return nil
 }
 
+//snowflakeFileFormat returns FILE_FORMAT parameter of the staged file. Parquet files are compressed internally
+func snowflakeFileFormat(format FileEncodingFormat, compression FileCompression) (string, error) {
+	switch format {
+	case FileFormatCSV:
+		return fmt.Sprintf(copyStatementFileFormat, snowflakeCompressionOption(compression)), nil
+	case FileFormatJSON, FileFormatFlatJSON:
+		return fmt.Sprintf(copyStatementJSONFileFormat, snowflakeCompressionOption(compression)), nil
+	case FileFormatParquet:
+		return copyStatementParquetFileFormat, nil
+	default:
+		return "", fmt.Errorf("unsupported staging format [%s]. Available: [%s, %s, %s]", format, FileFormatCSV, FileFormatJSON, FileFormatParquet)
+	}
+}
+
+//snowflakeCompressionOption returns FILE_FORMAT COMPRESSION value
+func snowflakeCompressionOption(compression FileCompression) string {
+	switch compression {
+	case FileCompressionGZIP:
+		return "GZIP"
+	case FileCompressionZSTD:
+		return "ZSTD"
+	default:
+		return "NONE"
+	}
+}
+
 //Insert inserts data with InsertContext as a single object or a batch into Snowflake
 func (s *Snowflake) Insert(insertContext *InsertContext) error {
 	if insertContext.eventContext != nil {
@@ -364,7 +450,12 @@ func (s *Snowflake) Insert(insertContext *InsertContext) error {
 }
 
 // insertSingle inserts provided object into Snowflake
+// uses MERGE if primary keys are configured
 func (s *Snowflake) insertSingle(eventContext *EventContext) error {
+	if len(eventContext.Table.PKFields) > 0 {
+		return s.insertBatch(eventContext.Table, []map[string]interface{}{eventContext.ProcessedEvent}, nil)
+	}
+
 	var columnNames, placeholders []string
 	var values []interface{}
 	columns := make([]string, 0, len(eventContext.ProcessedEvent))
@@ -488,6 +579,102 @@ func (s *Snowflake) ReplaceTable(originalTable, replacementTable string, dropOld
 	return
 }
 
+//Materialize creates or replaces a view or a table with the query results
+func (s *Snowflake) Materialize(name, query, materialized string) error {
+	objectType := "VIEW"
+	if materialized == MaterializedTable {
+		objectType = "TABLE"
+	}
+
+	statement := fmt.Sprintf(materializeSFTemplate, objectType, s.config.Schema, reformatValue(name), query)
+	s.queryLogger.LogDDL(statement)
+	if _, err := s.dataSource.ExecContext(s.ctx, statement); err != nil {
+		return errorj.MaterializeError.Wrap(err, "failed to materialize query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    s.config.Schema,
+				Table:     name,
+				Statement: statement,
+			})
+	}
+
+	return nil
+}
+
+//Expire deletes (or counts if dryRun) rows where the column is less than the threshold
+func (s *Snowflake) Expire(tableName, column string, threshold time.Time, dryRun bool) (int64, error) {
+	table, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	if ok, err := checkRetentionColumn(table, column); !ok {
+		return 0, err
+	}
+
+	condition := reformatValue(column) + " < ?"
+	values := []interface{}{threshold.UTC()}
+	var statement string
+	var affected int64
+	if dryRun {
+		statement = fmt.Sprintf(countSFTemplate, s.config.Schema, reformatValue(tableName), condition)
+		s.queryLogger.LogQueryWithValues(statement, values)
+		affected, err = countRows(s.ctx, s.dataSource, statement, values...)
+	} else {
+		statement = fmt.Sprintf(deleteSFTemplate, s.config.Schema, reformatValue(tableName), condition)
+		s.queryLogger.LogQueryWithValues(statement, values)
+		var result sql.Result
+		if result, err = s.dataSource.ExecContext(s.ctx, statement, values...); err == nil {
+			affected = rowsAffected(result)
+		}
+	}
+	if err != nil {
+		return 0, errorj.ExpireError.Wrap(err, "failed to expire data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    s.config.Schema,
+				Table:     tableName,
+				Statement: statement,
+			})
+	}
+
+	return affected, nil
+}
+
+//Erase deletes rows or sets columns to NULL where any of the columns equals to the value
+func (s *Snowflake) Erase(tableName string, columns []string, value string, mode string) (int64, error) {
+	if err := validateEraseMode(mode); err != nil {
+		return 0, err
+	}
+
+	table, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	columns = existingColumns(table, columns)
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	query := &eraseQuery{
+		quote:       reformatValue,
+		cast:        func(column string) string { return "TO_VARCHAR(" + column + ")" },
+		placeholder: func(i int) string { return "?" },
+	}
+	statement, values := query.build(fmt.Sprintf(deleteSFTemplate, s.config.Schema, reformatValue(tableName), "%s"),
+		fmt.Sprintf(eraseUpdateSFTemplate, s.config.Schema, reformatValue(tableName), "%s", "%s"), columns, value, mode)
+	s.queryLogger.LogQueryWithValues(statement, values)
+
+	result, err := s.dataSource.ExecContext(s.ctx, statement, values...)
+	if err != nil {
+		return 0, errorj.EraseError.Wrap(err, "failed to erase data").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    s.config.Schema,
+				Table:     tableName,
+				Statement: statement,
+			})
+	}
+
+	return rowsAffected(result), nil
+}
+
 //Truncate deletes all records in tableName table
 func (s *Snowflake) Truncate(tableName string) error {
 	sqlParams := SqlParams{
@@ -552,6 +739,13 @@ func (s *Snowflake) createTableInTransaction(wrappedTx *Transaction, table *Tabl
 	//sorting columns asc
 	sort.Strings(columnsDDL)
 	query := fmt.Sprintf(createSFTableTemplate, s.config.Schema, reformatValue(table.Name), strings.Join(columnsDDL, ","))
+	if table.Layout != nil && len(table.Layout.ClusterBy) > 0 {
+		var clusterBy []string
+		for _, field := range table.Layout.ClusterBy {
+			clusterBy = append(clusterBy, reformatValue(field))
+		}
+		query += fmt.Sprintf(clusterBySFTemplate, strings.Join(clusterBy, ", "))
+	}
 	s.queryLogger.LogDDL(query)
 
 	_, err := wrappedTx.tx.ExecContext(s.ctx, query)
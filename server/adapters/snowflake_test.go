@@ -87,3 +87,21 @@ func TestReformatToParam(t *testing.T) {
 		})
 	}
 }
+
+func TestSnowflakeFileFormat(t *testing.T) {
+	fileFormat, err := snowflakeFileFormat(FileFormatCSV, FileCompressionGZIP)
+	require.NoError(t, err)
+	require.Contains(t, fileFormat, "TYPE= 'CSV'")
+	require.Contains(t, fileFormat, "COMPRESSION = GZIP")
+
+	fileFormat, err = snowflakeFileFormat(FileFormatJSON, "")
+	require.NoError(t, err)
+	require.Contains(t, fileFormat, "TYPE= 'JSON' COMPRESSION = NONE")
+
+	fileFormat, err = snowflakeFileFormat(FileFormatParquet, FileCompressionGZIP)
+	require.NoError(t, err)
+	require.Equal(t, copyStatementParquetFileFormat, fileFormat)
+
+	_, err = snowflakeFileFormat("avro", "")
+	require.Error(t, err)
+}
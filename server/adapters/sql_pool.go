@@ -0,0 +1,157 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const defaultPreparedStatementsCacheSize = 100
+
+//ConnectionPoolConfig is a dto for deserialized connection pool configuration of SQL destinations.
+//Zero values mean adapter defaults
+type ConnectionPoolConfig struct {
+	MaxOpenConns       int `mapstructure:"max_open_conns,omitempty" json:"max_open_conns,omitempty" yaml:"max_open_conns,omitempty"`
+	MaxIdleConns       int `mapstructure:"max_idle_conns,omitempty" json:"max_idle_conns,omitempty" yaml:"max_idle_conns,omitempty"`
+	ConnMaxLifetimeSec int `mapstructure:"conn_max_lifetime_sec,omitempty" json:"conn_max_lifetime_sec,omitempty" yaml:"conn_max_lifetime_sec,omitempty"`
+	//StatementTimeoutSec is passed to the database as a session setting (statement_timeout, max_execution_time, etc.)
+	StatementTimeoutSec int `mapstructure:"statement_timeout_sec,omitempty" json:"statement_timeout_sec,omitempty" yaml:"statement_timeout_sec,omitempty"`
+	//PreparedStatementsCacheSize is a max number of prepared stream mode insert statements per connection pool.
+	//Default: 100. -1 disables caching (e.g. for PgBouncer in transaction pooling mode)
+	PreparedStatementsCacheSize int `mapstructure:"prepared_statements_cache_size,omitempty" json:"prepared_statements_cache_size,omitempty" yaml:"prepared_statements_cache_size,omitempty"`
+}
+
+//Validate returns err if invalid
+func (pc *ConnectionPoolConfig) Validate() error {
+	if pc == nil {
+		return nil
+	}
+	if pc.MaxOpenConns < 0 || pc.MaxIdleConns < 0 || pc.ConnMaxLifetimeSec < 0 || pc.StatementTimeoutSec < 0 {
+		return errors.New("'pool' values must be positive")
+	}
+	if pc.MaxOpenConns > 0 && pc.MaxIdleConns > pc.MaxOpenConns {
+		return errors.New("'pool.max_idle_conns' must be less or equal to 'pool.max_open_conns'")
+	}
+	if pc.PreparedStatementsCacheSize < -1 {
+		return errors.New("'pool.prepared_statements_cache_size' must be positive or -1 (disabled)")
+	}
+	return nil
+}
+
+//StatementTimeout returns configured statement timeout or 0 if isn't set
+func (pc *ConnectionPoolConfig) StatementTimeout() time.Duration {
+	if pc == nil {
+		return 0
+	}
+	return time.Duration(pc.StatementTimeoutSec) * time.Second
+}
+
+//apply sets configured values (or defaults if they aren't configured) to the connection pool
+func (pc *ConnectionPoolConfig) apply(dataSource *sql.DB, defaultConnMaxLifetime time.Duration, defaultMaxIdleConns int) {
+	if pc == nil {
+		pc = &ConnectionPoolConfig{}
+	}
+
+	connMaxLifetime := defaultConnMaxLifetime
+	if pc.ConnMaxLifetimeSec > 0 {
+		connMaxLifetime = time.Duration(pc.ConnMaxLifetimeSec) * time.Second
+	}
+	dataSource.SetConnMaxLifetime(connMaxLifetime)
+
+	maxIdleConns := defaultMaxIdleConns
+	if pc.MaxIdleConns > 0 {
+		maxIdleConns = pc.MaxIdleConns
+	}
+	if maxIdleConns > 0 {
+		dataSource.SetMaxIdleConns(maxIdleConns)
+	}
+
+	if pc.MaxOpenConns > 0 {
+		dataSource.SetMaxOpenConns(pc.MaxOpenConns)
+	}
+}
+
+//newPreparedStatements returns preparedStatements cache (without cache if caching is disabled)
+func (pc *ConnectionPoolConfig) newPreparedStatements(dataSource *sql.DB) *preparedStatements {
+	size := defaultPreparedStatementsCacheSize
+	if pc != nil && pc.PreparedStatementsCacheSize != 0 {
+		size = pc.PreparedStatementsCacheSize
+	}
+	if size < 0 {
+		return &preparedStatements{dataSource: dataSource}
+	}
+
+	cache, err := lru.NewWithEvict(size, func(_ interface{}, value interface{}) {
+		if err := value.(*sql.Stmt).Close(); err != nil {
+			logging.Warnf("Error closing evicted prepared statement: %v", err)
+		}
+	})
+	if err != nil {
+		logging.SystemErrorf("Error creating prepared statements cache with size %d: %v", size, err)
+		return &preparedStatements{dataSource: dataSource}
+	}
+
+	return &preparedStatements{dataSource: dataSource, cache: cache}
+}
+
+//preparedStatements is an LRU cache of prepared statements by query text.
+//Stream mode inserts into the same table with the same set of columns have the same text,
+//so reusing prepared statements saves query parsing and planning on every event.
+//Evicted statements are closed only when there are no in-flight executions
+type preparedStatements struct {
+	dataSource *sql.DB
+	//cache is nil if caching is disabled
+	cache *lru.Cache
+	//lifecycle guards statements against closing (on eviction or Close) during execution
+	lifecycle sync.RWMutex
+}
+
+//exec executes cached prepared statement or prepares a new one and puts it in the cache.
+//Works as sql.DB.ExecContext if caching is disabled
+func (ps *preparedStatements) exec(ctx context.Context, statement string, values ...interface{}) error {
+	if ps.cache == nil {
+		_, err := ps.dataSource.ExecContext(ctx, statement, values...)
+		return err
+	}
+
+	ps.lifecycle.RLock()
+	if cached, ok := ps.cache.Get(statement); ok {
+		_, err := cached.(*sql.Stmt).ExecContext(ctx, values...)
+		ps.lifecycle.RUnlock()
+		return err
+	}
+	ps.lifecycle.RUnlock()
+
+	//cache miss: prepare under exclusive lock because adding might evict (and close) a statement
+	ps.lifecycle.Lock()
+	defer ps.lifecycle.Unlock()
+
+	cached, ok := ps.cache.Get(statement)
+	if !ok {
+		stmt, err := ps.dataSource.PrepareContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+		ps.cache.Add(statement, stmt)
+		cached = stmt
+	}
+
+	_, err := cached.(*sql.Stmt).ExecContext(ctx, values...)
+	return err
+}
+
+//close closes all cached statements
+func (ps *preparedStatements) close() {
+	if ps.cache == nil {
+		return
+	}
+
+	ps.lifecycle.Lock()
+	defer ps.lifecycle.Unlock()
+	ps.cache.Purge()
+}
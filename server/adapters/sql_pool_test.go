@@ -0,0 +1,149 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//countingDriver is a fake SQL driver which counts prepared, closed and executed statements
+type countingDriver struct {
+	mutex    sync.Mutex
+	prepared map[string]int
+	closed   int
+	executed int
+}
+
+func (d *countingDriver) Open(string) (driver.Conn, error) {
+	return &countingConn{driver: d}, nil
+}
+
+type countingConn struct {
+	driver *countingDriver
+}
+
+func (c *countingConn) Prepare(query string) (driver.Stmt, error) {
+	c.driver.mutex.Lock()
+	defer c.driver.mutex.Unlock()
+	c.driver.prepared[query]++
+	return &countingStmt{driver: c.driver}, nil
+}
+
+func (c *countingConn) Close() error { return nil }
+func (c *countingConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions aren't supported")
+}
+
+type countingStmt struct {
+	driver *countingDriver
+}
+
+func (s *countingStmt) Close() error {
+	s.driver.mutex.Lock()
+	defer s.driver.mutex.Unlock()
+	s.driver.closed++
+	return nil
+}
+
+func (s *countingStmt) NumInput() int { return -1 }
+
+func (s *countingStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.driver.mutex.Lock()
+	defer s.driver.mutex.Unlock()
+	s.driver.executed++
+	return driver.RowsAffected(1), nil
+}
+
+func (s *countingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("queries aren't supported")
+}
+
+var countingDriverInstance = &countingDriver{prepared: map[string]int{}}
+
+func init() {
+	sql.Register("jitsu_counting", countingDriverInstance)
+}
+
+func TestPreparedStatements(t *testing.T) {
+	dataSource, err := sql.Open("jitsu_counting", "")
+	require.NoError(t, err)
+	dataSource.SetMaxOpenConns(1)
+	defer dataSource.Close()
+
+	ps := (&ConnectionPoolConfig{PreparedStatementsCacheSize: 2}).newPreparedStatements(dataSource)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, ps.exec(ctx, "insert_1", 1))
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, ps.exec(ctx, "insert_2", 1))
+	//evicts insert_1
+	require.NoError(t, ps.exec(ctx, "insert_3", 1))
+	require.NoError(t, ps.exec(ctx, "insert_1", 1))
+
+	countingDriverInstance.mutex.Lock()
+	require.Equal(t, map[string]int{"insert_1": 2, "insert_2": 1, "insert_3": 1}, countingDriverInstance.prepared)
+	require.Equal(t, 13, countingDriverInstance.executed)
+	require.Equal(t, 2, countingDriverInstance.closed, "evicted statements must be closed")
+	countingDriverInstance.mutex.Unlock()
+
+	ps.close()
+	countingDriverInstance.mutex.Lock()
+	require.Equal(t, 4, countingDriverInstance.closed, "all statements must be closed")
+	countingDriverInstance.mutex.Unlock()
+}
+
+func TestConnectionPoolConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *ConnectionPoolConfig
+		expectedErr string
+	}{
+		{
+			"not configured",
+			nil,
+			"",
+		},
+		{
+			"all values",
+			&ConnectionPoolConfig{MaxOpenConns: 20, MaxIdleConns: 5, ConnMaxLifetimeSec: 300, StatementTimeoutSec: 60, PreparedStatementsCacheSize: 50},
+			"",
+		},
+		{
+			"disabled prepared statements",
+			&ConnectionPoolConfig{PreparedStatementsCacheSize: -1},
+			"",
+		},
+		{
+			"negative timeout",
+			&ConnectionPoolConfig{StatementTimeoutSec: -1},
+			"'pool' values must be positive",
+		},
+		{
+			"idle connections more than max",
+			&ConnectionPoolConfig{MaxOpenConns: 5, MaxIdleConns: 10},
+			"'pool.max_idle_conns' must be less or equal to 'pool.max_open_conns'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
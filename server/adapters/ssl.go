@@ -1,6 +1,8 @@
 package adapters
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -53,7 +55,8 @@ func FromString(sslMode string) SSLMode {
 	}
 }
 
-//SSLConfig is a dto for deserialized SSL configuration for Postgres
+//SSLConfig is a dto for deserialized SSL configuration for Postgres, Redshift, MySQL and ClickHouse.
+//ServerCA, ClientCert and ClientKey might be PEM content as well as absolute file paths
 type SSLConfig struct {
 	Mode       SSLMode `mapstructure:"mode,omitempty" json:"mode,omitempty" yaml:"mode,omitempty"`
 	ServerCA   string  `mapstructure:"server_ca,omitempty" json:"server_ca,omitempty" yaml:"server_ca,omitempty"`
@@ -71,21 +74,95 @@ func (sc *SSLConfig) Validate() error {
 		return errors.New("'ssl.mode' is required parameter")
 	}
 
-	if sc.Mode == SSLModeVerifyCA || sc.Mode == SSLModeVerifyFull {
-		if sc.ServerCA == "" {
-			return errors.New("'ssl.server_ca' is required parameter")
-		}
+	if sc.Mode == SSLModeVerifyCA && sc.ServerCA == "" {
+		return errors.New("'ssl.server_ca' is required parameter")
+	}
+
+	if sc.ClientCert != "" && sc.ClientKey == "" {
+		return errors.New("'ssl.client_key' is required parameter with 'ssl.client_cert'")
+	}
+
+	if sc.ClientCert == "" && sc.ClientKey != "" {
+		return errors.New("'ssl.client_cert' is required parameter with 'ssl.client_key'")
+	}
+
+	return nil
+}
+
+//TLSConfig returns tls.Config for drivers which are configured in code (MySQL, ClickHouse) or nil in disable mode.
+//serverName is used for hostname verification in verify-full mode. System root CAs are used if server_ca isn't set
+func (sc *SSLConfig) TLSConfig(serverName string) (*tls.Config, error) {
+	if sc.Mode == SSLModeDisable {
+		return nil, nil
+	}
 
-		if sc.ClientCert == "" {
-			return errors.New("'ssl.client_cert' is required parameter")
+	tlsConfig := &tls.Config{}
+	if sc.ServerCA != "" {
+		serverCA, err := readSSLPayload(sc.ServerCA)
+		if err != nil {
+			return nil, fmt.Errorf("error reading server_ca: %v", err)
 		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(serverCA) {
+			return nil, errors.New("server_ca doesn't contain PEM encoded certificates")
+		}
+	}
 
-		if sc.ClientKey == "" {
-			return errors.New("'ssl.client_key' is required parameter")
+	if sc.ClientCert != "" {
+		clientCert, err := readSSLPayload(sc.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client_cert: %v", err)
+		}
+		clientKey, err := readSSLPayload(sc.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client_key: %v", err)
 		}
+		certificate, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing client_cert and client_key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
 	}
 
-	return nil
+	switch sc.Mode {
+	case SSLModeRequire:
+		//encryption only
+		tlsConfig.InsecureSkipVerify = true
+	case SSLModeVerifyCA:
+		//certificate chain is verified without hostname
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifyCertificateChain(tlsConfig.RootCAs)
+	case SSLModeVerifyFull:
+		tlsConfig.ServerName = serverName
+	}
+
+	return tlsConfig, nil
+}
+
+//verifyCertificateChain returns tls.Config.VerifyPeerCertificate func which verifies server certificate chain by roots
+func verifyCertificateChain(roots *x509.CertPool) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server hasn't provided certificates")
+		}
+
+		var certificates []*x509.Certificate
+		for _, raw := range rawCerts {
+			certificate, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("error parsing server certificate: %v", err)
+			}
+			certificates = append(certificates, certificate)
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, certificate := range certificates[1:] {
+			intermediates.AddCert(certificate)
+		}
+
+		_, err := certificates[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+		return err
+	}
 }
 
 //SSLDir returns SSL dir
@@ -102,11 +179,6 @@ func ProcessSSL(dir string, dsc *DataSourceConfig) error {
 		return nil
 	}
 
-	if dsc.SSLConfiguration.Mode == SSLModeRequire {
-		//default driver ssl mode
-		return nil
-	}
-
 	dsc.Parameters["sslmode"] = dsc.SSLConfiguration.Mode.String()
 
 	if dsc.SSLConfiguration.Mode == SSLModeDisable {
@@ -118,23 +190,27 @@ func ProcessSSL(dir string, dsc *DataSourceConfig) error {
 		return fmt.Errorf("Error creating dir for SSL files: %v", err)
 	}
 
-	serverCAPath, err := getSSLFilePath("server_ca", dir, dsc.SSLConfiguration.ServerCA)
-	if err != nil {
-		return fmt.Errorf("error saving server_ca: %v", err)
+	if dsc.SSLConfiguration.ServerCA != "" {
+		serverCAPath, err := getSSLFilePath("server_ca", dir, dsc.SSLConfiguration.ServerCA)
+		if err != nil {
+			return fmt.Errorf("error saving server_ca: %v", err)
+		}
+		dsc.Parameters["sslrootcert"] = serverCAPath
 	}
-	dsc.Parameters["sslrootcert"] = serverCAPath
 
-	clientCertPath, err := getSSLFilePath("client_cert", dir, dsc.SSLConfiguration.ClientCert)
-	if err != nil {
-		return fmt.Errorf("error saving client_cert: %v", err)
-	}
-	dsc.Parameters["sslcert"] = clientCertPath
+	if dsc.SSLConfiguration.ClientCert != "" {
+		clientCertPath, err := getSSLFilePath("client_cert", dir, dsc.SSLConfiguration.ClientCert)
+		if err != nil {
+			return fmt.Errorf("error saving client_cert: %v", err)
+		}
+		dsc.Parameters["sslcert"] = clientCertPath
 
-	clientKeyPath, err := getSSLFilePath("client_key", dir, dsc.SSLConfiguration.ClientKey)
-	if err != nil {
-		return fmt.Errorf("error saving client_key: %v", err)
+		clientKeyPath, err := getSSLFilePath("client_key", dir, dsc.SSLConfiguration.ClientKey)
+		if err != nil {
+			return fmt.Errorf("error saving client_key: %v", err)
+		}
+		dsc.Parameters["sslkey"] = clientKeyPath
 	}
-	dsc.Parameters["sslkey"] = clientKeyPath
 
 	return nil
 }
@@ -150,3 +226,13 @@ func getSSLFilePath(name, dir, payload string) (string, error) {
 	err := ioutil.WriteFile(filepath, []byte(payload), 0600)
 	return filepath, err
 }
+
+//readSSLPayload returns file content if input payload is filepath
+//otherwise returns payload
+func readSSLPayload(payload string) ([]byte, error) {
+	if path.IsAbs(payload) {
+		return ioutil.ReadFile(payload)
+	}
+
+	return []byte(payload), nil
+}
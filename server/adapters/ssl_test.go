@@ -0,0 +1,137 @@
+package adapters
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+//testCertificate is a PEM encoded certificate with its key and DER bytes
+type testCertificate struct {
+	certPEM string
+	keyPEM  string
+	der     []byte
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+}
+
+//newTestCertificate returns self-signed CA certificate if parent is nil or certificate signed by parent
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	issuer, issuerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCertificate{
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		der:     der,
+		cert:    cert,
+		key:     key,
+	}
+}
+
+func TestSSLConfigTLSConfig(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	server := newTestCertificate(t, "db.internal", ca)
+	client := newTestCertificate(t, "jitsu", ca)
+	otherCA := newTestCertificate(t, "other", nil)
+
+	tlsConfig, err := (&SSLConfig{Mode: SSLModeDisable}).TLSConfig("db.internal")
+	require.NoError(t, err)
+	require.Nil(t, tlsConfig)
+
+	tlsConfig, err = (&SSLConfig{Mode: SSLModeRequire, ClientCert: client.certPEM, ClientKey: client.keyPEM}).TLSConfig("db.internal")
+	require.NoError(t, err)
+	require.True(t, tlsConfig.InsecureSkipVerify)
+	require.Len(t, tlsConfig.Certificates, 1)
+
+	tlsConfig, err = (&SSLConfig{Mode: SSLModeVerifyFull, ServerCA: ca.certPEM}).TLSConfig("db.internal")
+	require.NoError(t, err)
+	require.False(t, tlsConfig.InsecureSkipVerify)
+	require.Equal(t, "db.internal", tlsConfig.ServerName)
+	require.NotNil(t, tlsConfig.RootCAs)
+
+	tlsConfig, err = (&SSLConfig{Mode: SSLModeVerifyCA, ServerCA: ca.certPEM}).TLSConfig("10.0.0.1")
+	require.NoError(t, err)
+	require.True(t, tlsConfig.InsecureSkipVerify, "hostname mustn't be verified in verify-ca mode")
+	require.NoError(t, tlsConfig.VerifyPeerCertificate([][]byte{server.der}, nil))
+	require.Error(t, tlsConfig.VerifyPeerCertificate([][]byte{otherCA.der}, nil))
+
+	_, err = (&SSLConfig{Mode: SSLModeVerifyCA, ServerCA: "not a certificate"}).TLSConfig("db.internal")
+	require.EqualError(t, err, "server_ca doesn't contain PEM encoded certificates")
+}
+
+func TestSSLConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *SSLConfig
+		expectedErr string
+	}{
+		{
+			"require without certificates",
+			&SSLConfig{Mode: SSLModeRequire},
+			"",
+		},
+		{
+			"verify-full with system root CAs",
+			&SSLConfig{Mode: SSLModeVerifyFull},
+			"",
+		},
+		{
+			"verify-ca without server CA",
+			&SSLConfig{Mode: SSLModeVerifyCA},
+			"'ssl.server_ca' is required parameter",
+		},
+		{
+			"client cert without key",
+			&SSLConfig{Mode: SSLModeRequire, ClientCert: "cert"},
+			"'ssl.client_key' is required parameter with 'ssl.client_cert'",
+		},
+		{
+			"without mode",
+			&SSLConfig{},
+			"'ssl.mode' is required parameter",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
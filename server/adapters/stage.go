@@ -2,9 +2,12 @@ package adapters
 
 import "io"
 
-//Stage is an intermediate layer (for BQ, Snowflake, Redshift, etc)
+//Stage is an intermediate layer (for BQ, Snowflake, Redshift, etc).
+//Implementations: S3 (and S3-compatible storages), Google Cloud Storage and Azure Blob Storage
 type Stage interface {
 	io.Closer
 	UploadBytes(fileName string, fileBytes []byte) error
 	DeleteObject(key string) error
+	//ListObjects returns keys of all objects with the prefix (all pages of the bucket listing)
+	ListObjects(prefix string) ([]string, error)
 }
@@ -17,6 +17,18 @@ type TableField struct {
 	Value interface{} `json:"value,omitempty"`
 }
 
+//TableLayout is a partitioning and clustering configuration which is applied on table creation
+type TableLayout struct {
+	//PartitionBy is BigQuery time partitioning field (by day) or ClickHouse PARTITION BY expression
+	PartitionBy string
+	//ClusterBy is BigQuery clustering fields or Snowflake CLUSTER BY fields
+	ClusterBy []string
+	//SortKeys is Redshift COMPOUND SORTKEY fields
+	SortKeys []string
+	//DistKey is Redshift DISTKEY field
+	DistKey string
+}
+
 //Table is a dto for DWH Table representation
 type Table struct {
 	Schema string
@@ -26,6 +38,7 @@ type Table struct {
 	PKFields       map[string]bool
 	PrimaryKeyName string
 	Partition      schema.DatePartition
+	Layout         *TableLayout
 
 	DeletePkFields bool
 }
@@ -67,6 +80,7 @@ func (t *Table) Clone() *Table {
 		Columns:        clonedColumns,
 		PKFields:       clonedPkFields,
 		PrimaryKeyName: t.PrimaryKeyName,
+		Layout:         t.Layout,
 		DeletePkFields: t.DeletePkFields,
 	}
 }
@@ -2,10 +2,12 @@ package airbyte
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/runner"
 	"github.com/jitsucom/jitsu/server/safego"
 	"io"
@@ -45,10 +47,12 @@ type Bridge struct {
 	imageMutex    *sync.RWMutex
 	pullingImages *sync.Map
 	pulledImages  map[string]bool
+	//Versions manages pinned connector versions
+	Versions *VersionsManager
 }
 
 // Init initializes airbyte Bridge
-func Init(ctx context.Context, containerizedRun bool, configDir, workspaceVolume string, batchSize int, logWriter io.Writer) error {
+func Init(ctx context.Context, containerizedRun bool, configDir, workspaceVolume string, batchSize int, metaStorage meta.Storage, logWriter io.Writer) error {
 	logging.Infof("Initializing Airbyte bridge. Batch size: %d", batchSize)
 
 	if logWriter == nil {
@@ -90,6 +94,7 @@ func Init(ctx context.Context, containerizedRun bool, configDir, workspaceVolume
 
 	if host := os.Getenv("KUBERNETES_SERVICE_HOST"); host != "" {
 		logging.Infof("[airbyte] ⚠️  Jitsu runs in Kubernetes. Additional setup may be required: https://jitsu.com/docs/sources-configuration/airbyte/k8s")
+		instance.Versions = newVersionsManager(ctx, instance, metaStorage)
 		Instance = instance
 		return nil
 	}
@@ -108,6 +113,7 @@ func Init(ctx context.Context, containerizedRun bool, configDir, workspaceVolume
 	}
 
 	logging.Infof("[airbyte] ✅ Mounted volume %s: OK", workspaceVolume)
+	instance.Versions = newVersionsManager(ctx, instance, metaStorage)
 	Instance = instance
 	return nil
 }
@@ -164,31 +170,43 @@ func (b *Bridge) IsImagePulled(dockerRepoImage, version string) (bool, error) {
 	//or do pull
 	if _, exists := b.pullingImages.LoadOrStore(dockerVersionedImage, true); !exists {
 		safego.Run(func() {
-			b.pullImage(dockerVersionedImage)
+			defer b.pullingImages.Delete(dockerVersionedImage)
+			if err := b.pullImage(dockerVersionedImage); err != nil {
+				logging.SystemError(err)
+			}
 		})
 	}
 
 	return false, nil
 }
 
-// pullImage executes docker pull
-func (b *Bridge) pullImage(dockerVersionedImage string) {
-	defer b.pullingImages.Delete(dockerVersionedImage)
+// ResolveVersion returns docker image version which should be used: the pinned one if the configured version is empty
+// or 'latest'
+func (b *Bridge) ResolveVersion(dockerImage, configuredVersion string) string {
+	if b == nil || b.Versions == nil {
+		if configuredVersion == "" {
+			return LatestVersion
+		}
+		return configuredVersion
+	}
+
+	return b.Versions.ResolveVersion(dockerImage, configuredVersion)
+}
 
+// pullImage executes docker pull
+func (b *Bridge) pullImage(dockerVersionedImage string) error {
 	pullImgOutWriter := logging.NewStringWriter()
 	pullImgErrWriter := logging.NewStringWriter()
 
 	//pull last image
 	if err := runner.ExecCmd(BridgeType, "", DockerCommand, pullImgOutWriter, pullImgErrWriter, time.Minute*30, "pull", dockerVersionedImage); err != nil {
-		errMsg := b.BuildMsg("Error pulling airbyte image:", pullImgOutWriter, pullImgErrWriter, err)
-		logging.SystemError(errMsg)
-
-		return
+		return errors.New(b.BuildMsg("Error pulling airbyte image:", pullImgOutWriter, pullImgErrWriter, err))
 	}
 
 	b.imageMutex.Lock()
 	b.pulledImages[dockerVersionedImage] = true
 	b.imageMutex.Unlock()
+	return nil
 }
 
 // BuildMsg returns formatted error
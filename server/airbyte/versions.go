@@ -0,0 +1,314 @@
+package airbyte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	//pinned versions are stored in meta storage as signatures of the bridge pseudo source
+	pinnedVersionsCollection = "pinned_versions"
+	versionsReloadInterval   = time.Minute
+
+	StagingPulling   = "pulling"
+	StagingChecking  = "checking"
+	StagingSucceeded = "succeeded"
+	StagingFailed    = "failed"
+)
+
+// ErrNotPinned is returned when connector image doesn't have a pinned version
+var ErrNotPinned = errors.New("connector image version isn't pinned")
+
+// PinnedVersion is a connector docker image version which is used instead of 'latest'
+type PinnedVersion struct {
+	DockerImage     string    `json:"docker_image"`
+	Version         string    `json:"version"`
+	PreviousVersion string    `json:"previous_version,omitempty"`
+	PinnedAt        time.Time `json:"pinned_at"`
+}
+
+// StagingResult is a result of connector version verification: docker pull, spec and check (if config is provided)
+type StagingResult struct {
+	DockerImage string      `json:"docker_image"`
+	Version     string      `json:"version"`
+	Status      string      `json:"status"`
+	Spec        interface{} `json:"spec,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	StartedAt   time.Time   `json:"started_at"`
+	FinishedAt  *time.Time  `json:"finished_at,omitempty"`
+}
+
+// VersionsManager keeps pinned connector versions in meta storage, periodically reloads them and pre-pulls
+// pinned images. Staging results are kept in memory of the node which has run them
+type VersionsManager struct {
+	bridge      *Bridge
+	metaStorage meta.Storage
+
+	mutex   sync.RWMutex
+	pinned  map[string]*PinnedVersion
+	staging map[string]*StagingResult
+	//stage verifies the connector version (pull, spec and check) and returns the spec
+	stage func(key, dockerImage, version string, airbyteSourceConfig interface{}) (interface{}, error)
+}
+
+func newVersionsManager(ctx context.Context, bridge *Bridge, metaStorage meta.Storage) *VersionsManager {
+	vm := &VersionsManager{
+		bridge:      bridge,
+		metaStorage: metaStorage,
+		pinned:      map[string]*PinnedVersion{},
+		staging:     map[string]*StagingResult{},
+	}
+	vm.stage = vm.verify
+
+	vm.reload()
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(versionsReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				vm.reload()
+			}
+		}
+	})
+
+	return vm
+}
+
+// reload loads pinned versions from meta storage and starts pulling of not pulled pinned images
+func (vm *VersionsManager) reload() {
+	signatures, err := vm.metaStorage.GetSignatures(BridgeType, pinnedVersionsCollection)
+	if err != nil {
+		logging.SystemErrorf("[airbyte] Error loading pinned connector versions: %v", err)
+		return
+	}
+
+	pinned := make(map[string]*PinnedVersion, len(signatures))
+	for dockerImage, value := range signatures {
+		pinnedVersion := &PinnedVersion{}
+		if err := json.Unmarshal([]byte(value), pinnedVersion); err != nil {
+			logging.SystemErrorf("[airbyte] Error parsing pinned version of [%s]: %v", dockerImage, err)
+			continue
+		}
+		pinned[dockerImage] = pinnedVersion
+	}
+
+	vm.mutex.Lock()
+	vm.pinned = pinned
+	vm.mutex.Unlock()
+
+	for dockerImage, pinnedVersion := range pinned {
+		if _, err := vm.bridge.IsImagePulled(vm.bridge.AddAirbytePrefix(dockerImage), pinnedVersion.Version); err != nil {
+			logging.Errorf("[airbyte] Error pre-pulling pinned image [%s:%s]: %v", dockerImage, pinnedVersion.Version, err)
+		}
+	}
+}
+
+// ResolveVersion returns pinned version if the configured one is empty or 'latest'
+func (vm *VersionsManager) ResolveVersion(dockerImage, configuredVersion string) string {
+	if configuredVersion != "" && configuredVersion != LatestVersion {
+		return configuredVersion
+	}
+
+	if pinnedVersion, ok := vm.GetPinned(dockerImage); ok {
+		return pinnedVersion.Version
+	}
+
+	return LatestVersion
+}
+
+// GetPinned returns pinned version of the connector image
+func (vm *VersionsManager) GetPinned(dockerImage string) (*PinnedVersion, bool) {
+	vm.mutex.RLock()
+	defer vm.mutex.RUnlock()
+
+	pinnedVersion, ok := vm.pinned[dockerImage]
+	return pinnedVersion, ok
+}
+
+// GetStagingResults returns all staging results of the connector image sorted by start time
+func (vm *VersionsManager) GetStagingResults(dockerImage string) []*StagingResult {
+	vm.mutex.RLock()
+	defer vm.mutex.RUnlock()
+
+	results := []*StagingResult{}
+	for _, result := range vm.staging {
+		if result.DockerImage == dockerImage {
+			resultCopy := *result
+			results = append(results, &resultCopy)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].StartedAt.Before(results[j].StartedAt)
+	})
+
+	return results
+}
+
+// Pin pins the connector image version. Without force the version must pass the staging successfully
+func (vm *VersionsManager) Pin(dockerImage, version string, force bool) (*PinnedVersion, error) {
+	if version == "" || version == LatestVersion {
+		return nil, errors.New("version is required and can't be 'latest'")
+	}
+
+	if !force {
+		vm.mutex.RLock()
+		result, ok := vm.staging[stagingKey(dockerImage, version)]
+		succeeded := ok && result.Status == StagingSucceeded
+		vm.mutex.RUnlock()
+		if !succeeded {
+			return nil, fmt.Errorf("version [%s] hasn't passed staging successfully. Stage it first or use force", version)
+		}
+	}
+
+	previousVersion := LatestVersion
+	if current, ok := vm.GetPinned(dockerImage); ok {
+		if current.Version == version {
+			return current, nil
+		}
+		previousVersion = current.Version
+	}
+
+	pinnedVersion := &PinnedVersion{DockerImage: dockerImage, Version: version, PreviousVersion: previousVersion, PinnedAt: timestamp.Now().UTC()}
+	if err := vm.save(pinnedVersion); err != nil {
+		return nil, err
+	}
+
+	if _, err := vm.bridge.IsImagePulled(vm.bridge.AddAirbytePrefix(dockerImage), version); err != nil {
+		logging.Errorf("[airbyte] Error pre-pulling pinned image [%s:%s]: %v", dockerImage, version, err)
+	}
+
+	return pinnedVersion, nil
+}
+
+// Rollback pins the previous version of the connector image. If the previous version is 'latest' the image is unpinned
+func (vm *VersionsManager) Rollback(dockerImage string) (*PinnedVersion, error) {
+	current, ok := vm.GetPinned(dockerImage)
+	if !ok {
+		return nil, ErrNotPinned
+	}
+
+	if current.PreviousVersion == "" || current.PreviousVersion == LatestVersion {
+		return nil, vm.Unpin(dockerImage)
+	}
+
+	pinnedVersion := &PinnedVersion{DockerImage: dockerImage, Version: current.PreviousVersion, PreviousVersion: current.Version, PinnedAt: timestamp.Now().UTC()}
+	if err := vm.save(pinnedVersion); err != nil {
+		return nil, err
+	}
+
+	return pinnedVersion, nil
+}
+
+// Unpin deletes pinned version of the connector image: 'latest' version will be used
+func (vm *VersionsManager) Unpin(dockerImage string) error {
+	if _, ok := vm.GetPinned(dockerImage); !ok {
+		return ErrNotPinned
+	}
+
+	if err := vm.metaStorage.DeleteSignatureIntervals(BridgeType, pinnedVersionsCollection, dockerImage); err != nil {
+		return fmt.Errorf("error deleting pinned version: %v", err)
+	}
+
+	vm.mutex.Lock()
+	delete(vm.pinned, dockerImage)
+	vm.mutex.Unlock()
+
+	return nil
+}
+
+// Stage starts the connector version verification in background: pulls the image, runs spec and check
+// (if airbyteSourceConfig isn't nil). Returns the current result if the version is being staged
+func (vm *VersionsManager) Stage(dockerImage, version string, airbyteSourceConfig interface{}) *StagingResult {
+	key := stagingKey(dockerImage, version)
+
+	vm.mutex.Lock()
+	if result, ok := vm.staging[key]; ok && result.FinishedAt == nil {
+		resultCopy := *result
+		vm.mutex.Unlock()
+		return &resultCopy
+	}
+	result := &StagingResult{DockerImage: dockerImage, Version: version, Status: StagingPulling, StartedAt: timestamp.Now().UTC()}
+	vm.staging[key] = result
+	resultCopy := *result
+	vm.mutex.Unlock()
+
+	safego.Run(func() {
+		spec, err := vm.stage(key, dockerImage, version, airbyteSourceConfig)
+
+		vm.mutex.Lock()
+		defer vm.mutex.Unlock()
+		finishedAt := timestamp.Now().UTC()
+		result.FinishedAt = &finishedAt
+		result.Spec = spec
+		if err != nil {
+			result.Status = StagingFailed
+			result.Error = err.Error()
+			logging.Warnf("[airbyte] Staging of [%s:%s] has failed: %v", dockerImage, version, err)
+		} else {
+			result.Status = StagingSucceeded
+			logging.Infof("[airbyte] Staging of [%s:%s] has succeeded", dockerImage, version)
+		}
+	})
+
+	return &resultCopy
+}
+
+// verify pulls the connector image, runs spec and check (if airbyteSourceConfig isn't nil)
+func (vm *VersionsManager) verify(key, dockerImage, version string, airbyteSourceConfig interface{}) (interface{}, error) {
+	if err := vm.bridge.pullImage(fmt.Sprintf("%s:%s", vm.bridge.AddAirbytePrefix(dockerImage), version)); err != nil {
+		return nil, err
+	}
+
+	vm.setStagingStatus(key, StagingChecking)
+	spec, err := NewRunner("Staging", dockerImage, version, "").Spec()
+	if err != nil {
+		return nil, fmt.Errorf("spec: %v", err)
+	}
+
+	if airbyteSourceConfig != nil {
+		if err := NewRunner("Staging", dockerImage, version, "").Check(airbyteSourceConfig); err != nil {
+			return spec, fmt.Errorf("check: %v", err)
+		}
+	}
+
+	return spec, nil
+}
+
+func (vm *VersionsManager) setStagingStatus(key, status string) {
+	vm.mutex.Lock()
+	defer vm.mutex.Unlock()
+	if result, ok := vm.staging[key]; ok {
+		result.Status = status
+	}
+}
+
+func (vm *VersionsManager) save(pinnedVersion *PinnedVersion) error {
+	b, _ := json.Marshal(pinnedVersion)
+	if err := vm.metaStorage.SaveSignature(BridgeType, pinnedVersionsCollection, pinnedVersion.DockerImage, string(b)); err != nil {
+		return fmt.Errorf("error saving pinned version: %v", err)
+	}
+
+	vm.mutex.Lock()
+	vm.pinned[pinnedVersion.DockerImage] = pinnedVersion
+	vm.mutex.Unlock()
+
+	logging.Infof("[airbyte] Connector [%s] version [%s] has been pinned (previous: [%s])", pinnedVersion.DockerImage, pinnedVersion.Version, pinnedVersion.PreviousVersion)
+	return nil
+}
+
+func stagingKey(dockerImage, version string) string {
+	return dockerImage + ":" + version
+}
@@ -0,0 +1,234 @@
+package airbyte
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/stretchr/testify/require"
+)
+
+// signaturesStorage keeps signatures in memory. saveErr is returned from SaveSignature if it is set
+type signaturesStorage struct {
+	meta.Dummy
+
+	mutex      sync.Mutex
+	signatures map[string]string
+	saveErr    error
+}
+
+func (ss *signaturesStorage) GetSignatures(sourceID, collection string) (map[string]string, error) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	result := make(map[string]string, len(ss.signatures))
+	for key, value := range ss.signatures {
+		result[key] = value
+	}
+	return result, nil
+}
+
+func (ss *signaturesStorage) SaveSignature(sourceID, collection, interval, signature string) error {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	if ss.saveErr != nil {
+		return ss.saveErr
+	}
+	ss.signatures[interval] = signature
+	return nil
+}
+
+func (ss *signaturesStorage) DeleteSignatureIntervals(sourceID, collection string, intervals ...string) error {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	for _, interval := range intervals {
+		delete(ss.signatures, interval)
+	}
+	return nil
+}
+
+// newTestVersionsManager returns VersionsManager with the bridge which has all test images pulled (docker isn't used)
+func newTestVersionsManager(t *testing.T, storage *signaturesStorage) *VersionsManager {
+	bridge := &Bridge{
+		imageMutex:    &sync.RWMutex{},
+		pullingImages: &sync.Map{},
+		pulledImages: map[string]bool{
+			"airbyte/source-test:1.0": true,
+			"airbyte/source-test:2.0": true,
+			"airbyte/source-test:3.0": true,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return newVersionsManager(ctx, bridge, storage)
+}
+
+// waitStaging waits until the staging of the version is finished and returns the result
+func waitStaging(t *testing.T, vm *VersionsManager, version string) *StagingResult {
+	var finished *StagingResult
+	require.Eventually(t, func() bool {
+		for _, result := range vm.GetStagingResults("source-test") {
+			if result.Version == version && result.FinishedAt != nil {
+				finished = result
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	return finished
+}
+
+func TestVersionsPin(t *testing.T) {
+	storage := &signaturesStorage{signatures: map[string]string{}}
+	vm := newTestVersionsManager(t, storage)
+
+	_, err := vm.Pin("source-test", "", true)
+	require.Error(t, err)
+	_, err = vm.Pin("source-test", LatestVersion, true)
+	require.Error(t, err)
+
+	//not staged version can be pinned only with force
+	_, err = vm.Pin("source-test", "1.0", false)
+	require.EqualError(t, err, "version [1.0] hasn't passed staging successfully. Stage it first or use force")
+	_, ok := vm.GetPinned("source-test")
+	require.False(t, ok)
+	require.Equal(t, LatestVersion, vm.ResolveVersion("source-test", ""))
+
+	pinned, err := vm.Pin("source-test", "1.0", true)
+	require.NoError(t, err)
+	require.Equal(t, "1.0", pinned.Version)
+	require.Equal(t, LatestVersion, pinned.PreviousVersion)
+	require.Contains(t, storage.signatures, "source-test")
+
+	require.Equal(t, "1.0", vm.ResolveVersion("source-test", ""))
+	require.Equal(t, "1.0", vm.ResolveVersion("source-test", LatestVersion))
+	require.Equal(t, "0.9", vm.ResolveVersion("source-test", "0.9"), "explicitly configured version must be used")
+
+	//pinning the same version doesn't change the previous one
+	pinned, err = vm.Pin("source-test", "1.0", true)
+	require.NoError(t, err)
+	require.Equal(t, LatestVersion, pinned.PreviousVersion)
+
+	//pinned versions are loaded from meta storage
+	reloaded := newTestVersionsManager(t, storage)
+	pinned, ok = reloaded.GetPinned("source-test")
+	require.True(t, ok)
+	require.Equal(t, "1.0", pinned.Version)
+}
+
+func TestVersionsStageAndPromote(t *testing.T) {
+	vm := newTestVersionsManager(t, &signaturesStorage{signatures: map[string]string{}})
+
+	release := make(chan struct{})
+	vm.stage = func(key, dockerImage, version string, airbyteSourceConfig interface{}) (interface{}, error) {
+		<-release
+		vm.setStagingStatus(key, StagingChecking)
+		if version == "3.0" {
+			return nil, errors.New("check: connection refused")
+		}
+		return map[string]interface{}{"connectionSpecification": map[string]interface{}{}}, nil
+	}
+
+	result := vm.Stage("source-test", "2.0", nil)
+	require.Equal(t, StagingPulling, result.Status)
+	require.Nil(t, result.FinishedAt)
+
+	//staging in progress: the current result is returned and the version can't be pinned yet
+	result = vm.Stage("source-test", "2.0", nil)
+	require.Equal(t, StagingPulling, result.Status)
+	require.Len(t, vm.GetStagingResults("source-test"), 1)
+	_, err := vm.Pin("source-test", "2.0", false)
+	require.Error(t, err)
+
+	close(release)
+	result = waitStaging(t, vm, "2.0")
+	require.Equal(t, StagingSucceeded, result.Status)
+	require.Empty(t, result.Error)
+	require.NotNil(t, result.Spec)
+
+	//promote the staged version
+	pinned, err := vm.Pin("source-test", "2.0", false)
+	require.NoError(t, err)
+	require.Equal(t, "2.0", pinned.Version)
+	require.Equal(t, "2.0", vm.ResolveVersion("source-test", ""))
+
+	//failed staging
+	vm.Stage("source-test", "3.0", nil)
+	result = waitStaging(t, vm, "3.0")
+	require.Equal(t, StagingFailed, result.Status)
+	require.Equal(t, "check: connection refused", result.Error)
+
+	_, err = vm.Pin("source-test", "3.0", false)
+	require.EqualError(t, err, "version [3.0] hasn't passed staging successfully. Stage it first or use force")
+	require.Equal(t, "2.0", vm.ResolveVersion("source-test", ""))
+
+	results := vm.GetStagingResults("source-test")
+	require.Len(t, results, 2)
+	require.Equal(t, "2.0", results[0].Version)
+	require.Equal(t, "3.0", results[1].Version)
+}
+
+func TestVersionsRollback(t *testing.T) {
+	storage := &signaturesStorage{signatures: map[string]string{}}
+	vm := newTestVersionsManager(t, storage)
+
+	//nothing to roll back
+	_, err := vm.Rollback("source-test")
+	require.Equal(t, ErrNotPinned, err)
+	require.Equal(t, ErrNotPinned, vm.Unpin("source-test"))
+
+	_, err = vm.Pin("source-test", "1.0", true)
+	require.NoError(t, err)
+	_, err = vm.Pin("source-test", "2.0", true)
+	require.NoError(t, err)
+
+	pinned, err := vm.Rollback("source-test")
+	require.NoError(t, err)
+	require.Equal(t, "1.0", pinned.Version)
+	require.Equal(t, "2.0", pinned.PreviousVersion)
+	require.Equal(t, "1.0", vm.ResolveVersion("source-test", ""))
+
+	//rollback of the rollback returns the newer version
+	pinned, err = vm.Rollback("source-test")
+	require.NoError(t, err)
+	require.Equal(t, "2.0", pinned.Version)
+
+	//rollback to 'latest' unpins the image
+	require.NoError(t, vm.Unpin("source-test"))
+	_, err = vm.Pin("source-test", "1.0", true)
+	require.NoError(t, err)
+	pinned, err = vm.Rollback("source-test")
+	require.NoError(t, err)
+	require.Nil(t, pinned)
+	_, ok := vm.GetPinned("source-test")
+	require.False(t, ok)
+	require.NotContains(t, storage.signatures, "source-test")
+	require.Equal(t, LatestVersion, vm.ResolveVersion("source-test", ""))
+}
+
+func TestVersionsSaveError(t *testing.T) {
+	storage := &signaturesStorage{signatures: map[string]string{}}
+	vm := newTestVersionsManager(t, storage)
+
+	_, err := vm.Pin("source-test", "1.0", true)
+	require.NoError(t, err)
+	_, err = vm.Pin("source-test", "2.0", true)
+	require.NoError(t, err)
+
+	storage.saveErr = errors.New("connection refused")
+	_, err = vm.Pin("source-test", "3.0", true)
+	require.EqualError(t, err, "error saving pinned version: connection refused")
+	_, err = vm.Rollback("source-test")
+	require.EqualError(t, err, "error saving pinned version: connection refused")
+
+	pinned, ok := vm.GetPinned("source-test")
+	require.True(t, ok)
+	require.Equal(t, "2.0", pinned.Version, "pinned version mustn't be changed if it isn't saved")
+}
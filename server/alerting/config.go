@@ -0,0 +1,183 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	DestinationErrorRateRule = "destination_error_rate"
+	QueueSizeRule            = "queue_size"
+	SyncTaskFailedRule       = "sync_task_failed"
+	DiskUsageRule            = "disk_usage"
+	QuotaUsageRule           = "quota_usage"
+
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+
+	defaultEvaluationIntervalSec = 60
+	defaultErrorRateWindowMin    = 5
+	defaultDiskUsagePath         = "/"
+)
+
+// Config is a configuration of alerting: rules evaluated periodically and notifiers
+type Config struct {
+	Enabled               bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	EvaluationIntervalSec int  `mapstructure:"evaluation_interval_sec" json:"evaluation_interval_sec,omitempty" yaml:"evaluation_interval_sec,omitempty"`
+	// RepeatIntervalMin is an interval of repeat notifications about still firing alerts. 0 - notify only once
+	RepeatIntervalMin int              `mapstructure:"repeat_interval_min" json:"repeat_interval_min,omitempty" yaml:"repeat_interval_min,omitempty"`
+	Notifiers         *NotifiersConfig `mapstructure:"notifiers" json:"notifiers,omitempty" yaml:"notifiers,omitempty"`
+	Rules             []*RuleConfig    `mapstructure:"rules" json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// RuleConfig is a configuration of a single alerting rule
+type RuleConfig struct {
+	// Name is a unique rule name. Default value is the rule type
+	Name string `mapstructure:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	// Type is one of: destination_error_rate, queue_size, sync_task_failed, disk_usage, quota_usage
+	Type string `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	// Severity is critical (default) or warning
+	Severity string `mapstructure:"severity" json:"severity,omitempty" yaml:"severity,omitempty"`
+	// Threshold: errors fraction (0..1) for destination_error_rate, events amount for queue_size,
+	// used space percent (0..100) for disk_usage
+	Threshold float64 `mapstructure:"threshold" json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// WindowMin is a window of destination_error_rate calculation
+	WindowMin int `mapstructure:"window_min" json:"window_min,omitempty" yaml:"window_min,omitempty"`
+	// MinEvents is a min amount of events in the window for destination_error_rate firing
+	MinEvents int64 `mapstructure:"min_events" json:"min_events,omitempty" yaml:"min_events,omitempty"`
+	// Destinations limits destination_error_rate and queue_size rules to certain destinations IDs. Empty - all destinations
+	Destinations []string `mapstructure:"destinations" json:"destinations,omitempty" yaml:"destinations,omitempty"`
+	// Path is a disk_usage filesystem path
+	Path string `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// NotifiersConfig is a configuration of alerts destinations. At least one notifier is required
+type NotifiersConfig struct {
+	Slack     *SlackConfig     `mapstructure:"slack" json:"slack,omitempty" yaml:"slack,omitempty"`
+	PagerDuty *PagerDutyConfig `mapstructure:"pagerduty" json:"pagerduty,omitempty" yaml:"pagerduty,omitempty"`
+	Email     *EmailConfig     `mapstructure:"email" json:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// SlackConfig is a Slack incoming webhook configuration
+type SlackConfig struct {
+	URL string `mapstructure:"url" json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// PagerDutyConfig is a PagerDuty Events API v2 configuration
+type PagerDutyConfig struct {
+	RoutingKey string `mapstructure:"routing_key" json:"routing_key,omitempty" yaml:"routing_key,omitempty"`
+	// URL is PagerDuty Events API URL. Default value is https://events.pagerduty.com/v2/enqueue
+	URL string `mapstructure:"url" json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// EmailConfig is an SMTP configuration
+type EmailConfig struct {
+	Host     string   `mapstructure:"host" json:"host,omitempty" yaml:"host,omitempty"`
+	Port     int      `mapstructure:"port" json:"port,omitempty" yaml:"port,omitempty"`
+	User     string   `mapstructure:"user" json:"user,omitempty" yaml:"user,omitempty"`
+	Password string   `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty"`
+	From     string   `mapstructure:"from" json:"from,omitempty" yaml:"from,omitempty"`
+	To       []string `mapstructure:"to" json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid. Also sets default values
+func (c *Config) Validate() error {
+	if c.EvaluationIntervalSec <= 0 {
+		c.EvaluationIntervalSec = defaultEvaluationIntervalSec
+	}
+
+	if c.RepeatIntervalMin < 0 {
+		return fmt.Errorf("alerting.repeat_interval_min must be positive or 0. Got: %d", c.RepeatIntervalMin)
+	}
+
+	if c.Notifiers == nil || (c.Notifiers.Slack == nil && c.Notifiers.PagerDuty == nil && c.Notifiers.Email == nil) {
+		return errors.New("alerting.notifiers: at least one notifier (slack, pagerduty or email) is required")
+	}
+	if err := c.Notifiers.validate(); err != nil {
+		return err
+	}
+
+	if len(c.Rules) == 0 {
+		return errors.New("alerting.rules is required")
+	}
+
+	names := map[string]bool{}
+	for i, rule := range c.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("alerting.rules[%d]: %v", i, err)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("alerting.rules[%d]: rule name [%s] isn't unique", i, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+
+	return nil
+}
+
+func (nc *NotifiersConfig) validate() error {
+	if nc.Slack != nil && nc.Slack.URL == "" {
+		return errors.New("alerting.notifiers.slack.url is required")
+	}
+
+	if nc.PagerDuty != nil && nc.PagerDuty.RoutingKey == "" {
+		return errors.New("alerting.notifiers.pagerduty.routing_key is required")
+	}
+
+	if nc.Email != nil {
+		if nc.Email.Host == "" {
+			return errors.New("alerting.notifiers.email.host is required")
+		}
+		if nc.Email.Port <= 0 {
+			return errors.New("alerting.notifiers.email.port is required")
+		}
+		if nc.Email.From == "" {
+			return errors.New("alerting.notifiers.email.from is required")
+		}
+		if len(nc.Email.To) == 0 {
+			return errors.New("alerting.notifiers.email.to is required")
+		}
+	}
+
+	return nil
+}
+
+func (rc *RuleConfig) validate() error {
+	if rc.Name == "" {
+		rc.Name = rc.Type
+	}
+
+	switch rc.Severity {
+	case "":
+		rc.Severity = SeverityCritical
+	case SeverityCritical, SeverityWarning:
+	default:
+		return fmt.Errorf("unknown severity [%s]. Supported: [%s, %s]", rc.Severity, SeverityCritical, SeverityWarning)
+	}
+
+	switch rc.Type {
+	case DestinationErrorRateRule:
+		if rc.Threshold <= 0 || rc.Threshold > 1 {
+			return fmt.Errorf("%s threshold must be in (0, 1] range. Got: %v", rc.Type, rc.Threshold)
+		}
+		if rc.WindowMin <= 0 {
+			rc.WindowMin = defaultErrorRateWindowMin
+		}
+	case QueueSizeRule:
+		if rc.Threshold <= 0 {
+			return fmt.Errorf("%s threshold must be positive. Got: %v", rc.Type, rc.Threshold)
+		}
+	case DiskUsageRule:
+		if rc.Threshold <= 0 || rc.Threshold > 100 {
+			return fmt.Errorf("%s threshold must be in (0, 100] range. Got: %v", rc.Type, rc.Threshold)
+		}
+		if rc.Path == "" {
+			rc.Path = defaultDiskUsagePath
+		}
+	case SyncTaskFailedRule, QuotaUsageRule:
+	default:
+		return fmt.Errorf("unknown rule type [%s]. Supported: [%s, %s, %s, %s, %s]", rc.Type, DestinationErrorRateRule, QueueSizeRule, SyncTaskFailedRule, DiskUsageRule, QuotaUsageRule)
+	}
+
+	return nil
+}
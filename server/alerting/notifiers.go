@@ -0,0 +1,185 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+	gomail "gopkg.in/mail.v2"
+)
+
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+
+	defaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+	green = "#5cb85c"
+	red   = "#d9534f"
+	amber = "#f0ad4e"
+)
+
+// Notification is an alert state change (or a repeat notification) which is sent to notifiers
+type Notification struct {
+	*Alert
+	Status      string
+	FiredAt     time.Time
+	ServiceName string
+	Version     string
+	ServerName  string
+}
+
+// Title returns a short notification description
+func (n *Notification) Title() string {
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(n.Status), n.Rule, n.Subject)
+}
+
+// Notifier sends alerts notifications to an external system
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, notification *Notification) error
+}
+
+type Map map[string]interface{}
+
+// SlackNotifier sends notifications to Slack incoming webhook
+type SlackNotifier struct {
+	url string
+}
+
+func (sn *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (sn *SlackNotifier) Notify(ctx context.Context, notification *Notification) error {
+	color := red
+	switch {
+	case notification.Status == StatusResolved:
+		color = green
+	case notification.Severity == SeverityWarning:
+		color = amber
+	}
+
+	text := fmt.Sprintf("*Severity:* %s\n*Server:* %s\n*Fired at:* %s\n%s", notification.Severity, notification.ServerName,
+		notification.FiredAt.Format(time.RFC3339), notification.Summary)
+	return requests.URL(sn.url).
+		Method(http.MethodPost).
+		BodyJSON(Map{
+			"text": fmt.Sprintf("*%s %s* %s", notification.ServiceName, notification.Version, notification.Title()),
+			"attachments": []Map{{
+				"color": color,
+				"blocks": []Map{
+					{
+						"type": "divider",
+					},
+					{
+						"type": "section",
+						"text": Map{
+							"type": "mrkdwn",
+							"text": text,
+						},
+					},
+				},
+			}},
+		}).
+		CheckStatus(http.StatusOK).
+		Fetch(ctx)
+}
+
+// PagerDutyNotifier sends trigger and resolve events to PagerDuty Events API v2. Alert key is used as dedup_key
+type PagerDutyNotifier struct {
+	url        string
+	routingKey string
+}
+
+func (pdn *PagerDutyNotifier) Name() string {
+	return "pagerduty"
+}
+
+func (pdn *PagerDutyNotifier) Notify(ctx context.Context, notification *Notification) error {
+	event := Map{
+		"routing_key": pdn.routingKey,
+		"dedup_key":   notification.Key,
+	}
+	if notification.Status == StatusResolved {
+		event["event_action"] = "resolve"
+	} else {
+		severity := "critical"
+		if notification.Severity == SeverityWarning {
+			severity = "warning"
+		}
+		event["event_action"] = "trigger"
+		event["payload"] = Map{
+			"summary":   notification.Summary,
+			"source":    notification.ServerName,
+			"severity":  severity,
+			"component": notification.Subject,
+			"group":     notification.Rule,
+			"class":     notification.Type,
+			"timestamp": notification.FiredAt.Format(time.RFC3339),
+			"custom_details": Map{
+				"value":     notification.Value,
+				"threshold": notification.Threshold,
+				"service":   notification.ServiceName + " " + notification.Version,
+			},
+		}
+	}
+
+	return requests.URL(pdn.url).
+		Method(http.MethodPost).
+		BodyJSON(event).
+		CheckStatus(http.StatusOK, http.StatusAccepted).
+		Fetch(ctx)
+}
+
+// EmailNotifier sends notifications via SMTP
+type EmailNotifier struct {
+	config *EmailConfig
+}
+
+func (en *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (en *EmailNotifier) Notify(ctx context.Context, notification *Notification) error {
+	body := bytes.Buffer{}
+	fmt.Fprintf(&body, "%s\n\nRule: %s\nSeverity: %s\nServer: %s %s [%s]\nFired at: %s\n", notification.Summary, notification.Rule,
+		notification.Severity, notification.ServiceName, notification.Version, notification.ServerName, notification.FiredAt.Format(time.RFC3339))
+
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", en.config.From)
+	msg.SetHeader("To", en.config.To...)
+	msg.SetHeader("Subject", fmt.Sprintf("%s %s", notification.ServiceName, notification.Title()))
+	msg.SetBody("text/plain", body.String())
+
+	dialer := gomail.NewDialer(en.config.Host, en.config.Port, en.config.User, en.config.Password)
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Timeout = time.Until(deadline)
+	}
+
+	return dialer.DialAndSend(msg)
+}
+
+// newNotifiers returns configured notifiers
+func newNotifiers(config *NotifiersConfig) []Notifier {
+	var notifiers []Notifier
+	if config.Slack != nil {
+		notifiers = append(notifiers, &SlackNotifier{url: config.Slack.URL})
+	}
+	if config.PagerDuty != nil {
+		url := config.PagerDuty.URL
+		if url == "" {
+			url = defaultPagerDutyURL
+		}
+		notifiers = append(notifiers, &PagerDutyNotifier{url: url, routingKey: config.PagerDuty.RoutingKey})
+	}
+	if config.Email != nil {
+		notifiers = append(notifiers, &EmailNotifier{config: config.Email})
+	}
+
+	return notifiers
+}
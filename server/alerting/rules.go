@@ -0,0 +1,202 @@
+package alerting
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/quota"
+)
+
+// Alert is a firing alert. Alerts with the same key are considered the same alert across evaluations
+type Alert struct {
+	Key      string  `json:"key"`
+	Rule     string  `json:"rule"`
+	Type     string  `json:"type"`
+	Severity string  `json:"severity"`
+	Subject  string  `json:"subject"`
+	Summary  string  `json:"summary"`
+	Value    float64 `json:"value"`
+	// Threshold is 0 for rules without threshold (e.g. sync_task_failed)
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// Rule is evaluated periodically and returns currently firing alerts
+type Rule interface {
+	Name() string
+	Evaluate(now time.Time) ([]*Alert, error)
+}
+
+// baseRule keeps common rule configuration
+type baseRule struct {
+	config       *RuleConfig
+	destinations map[string]bool
+}
+
+func newBaseRule(config *RuleConfig) baseRule {
+	destinations := map[string]bool{}
+	for _, destinationID := range config.Destinations {
+		destinations[destinationID] = true
+	}
+
+	return baseRule{config: config, destinations: destinations}
+}
+
+func (br *baseRule) Name() string {
+	return br.config.Name
+}
+
+// matches returns true if the destination isn't filtered out by the rule configuration
+func (br *baseRule) matches(destinationID string) bool {
+	return len(br.destinations) == 0 || br.destinations[destinationID]
+}
+
+func (br *baseRule) alert(subject, summary string, value float64) *Alert {
+	return &Alert{
+		Key:       br.config.Name + ":" + subject,
+		Rule:      br.config.Name,
+		Type:      br.config.Type,
+		Severity:  br.config.Severity,
+		Subject:   subject,
+		Summary:   summary,
+		Value:     value,
+		Threshold: br.config.Threshold,
+	}
+}
+
+// totalsSnapshot is cumulative destinations events amounts at a certain moment
+type totalsSnapshot struct {
+	at     time.Time
+	totals map[string]counters.DestinationTotals
+}
+
+// errorRateRule fires when destination errors fraction in the window exceeds the threshold.
+// The rate is calculated from in-process counters: only events processed by the current server are taken into account
+type errorRateRule struct {
+	baseRule
+	totals    func() map[string]counters.DestinationTotals
+	snapshots []*totalsSnapshot
+}
+
+func (ers *errorRateRule) Evaluate(now time.Time) ([]*Alert, error) {
+	current := &totalsSnapshot{at: now, totals: ers.totals()}
+	ers.snapshots = append(ers.snapshots, current)
+
+	//keep the latest snapshot which is older than the window as a baseline
+	windowStart := now.Add(-time.Duration(ers.config.WindowMin) * time.Minute)
+	for len(ers.snapshots) > 1 && !ers.snapshots[1].at.After(windowStart) {
+		ers.snapshots = ers.snapshots[1:]
+	}
+	baseline := ers.snapshots[0]
+
+	var alerts []*Alert
+	for destinationID, totals := range current.totals {
+		if !ers.matches(destinationID) {
+			continue
+		}
+
+		previous := baseline.totals[destinationID]
+		errorsCount := totals.Errors - previous.Errors
+		total := totals.Success - previous.Success + errorsCount
+		if total <= 0 || total < ers.config.MinEvents {
+			continue
+		}
+
+		rate := float64(errorsCount) / float64(total)
+		if rate > ers.config.Threshold {
+			alerts = append(alerts, ers.alert(destinationID, fmt.Sprintf("Destination [%s] error rate is %.2f%% (%d of %d events in the last %d min). Threshold: %.2f%%",
+				destinationID, rate*100, errorsCount, total, ers.config.WindowMin, ers.config.Threshold*100), rate))
+		}
+	}
+
+	return alerts, nil
+}
+
+// queueSizeRule fires when destination events queue size exceeds the threshold
+type queueSizeRule struct {
+	baseRule
+	sizes func() map[string]int64
+}
+
+func (qsr *queueSizeRule) Evaluate(now time.Time) ([]*Alert, error) {
+	var alerts []*Alert
+	for destinationID, size := range qsr.sizes() {
+		if !qsr.matches(destinationID) {
+			continue
+		}
+
+		if float64(size) > qsr.config.Threshold {
+			alerts = append(alerts, qsr.alert(destinationID, fmt.Sprintf("Destination [%s] events queue size is %d. Threshold: %.0f",
+				destinationID, size, qsr.config.Threshold), float64(size)))
+		}
+	}
+
+	return alerts, nil
+}
+
+// syncTaskFailedRule fires while the last synchronization task of a source collection has failed (all retries are exhausted)
+type syncTaskFailedRule struct {
+	baseRule
+	failures func() []*TaskFailure
+}
+
+func (stfr *syncTaskFailedRule) Evaluate(now time.Time) ([]*Alert, error) {
+	var alerts []*Alert
+	for _, failure := range stfr.failures() {
+		alerts = append(alerts, stfr.alert(failure.Source+"."+failure.Collection, fmt.Sprintf("Synchronization task [%s] of source [%s] collection [%s] has failed: %s",
+			failure.TaskID, failure.Source, failure.Collection, failure.Error), 1))
+	}
+
+	return alerts, nil
+}
+
+// diskUsageRule fires when used space of the filesystem exceeds the threshold percent
+type diskUsageRule struct {
+	baseRule
+	usage func(path string) (float64, error)
+}
+
+func (dur *diskUsageRule) Evaluate(now time.Time) ([]*Alert, error) {
+	usedPercent, err := dur.usage(dur.config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error getting [%s] disk usage: %v", dur.config.Path, err)
+	}
+
+	if usedPercent > dur.config.Threshold {
+		return []*Alert{dur.alert(dur.config.Path, fmt.Sprintf("Disk usage of [%s] is %.1f%%. Threshold: %.1f%%",
+			dur.config.Path, usedPercent, dur.config.Threshold), usedPercent)}, nil
+	}
+
+	return nil, nil
+}
+
+// quotaUsageRule fires while a project or an API key events usage exceeds a soft or a hard quota limit
+type quotaUsageRule struct {
+	baseRule
+	usages func() []*quota.Usage
+}
+
+func (qur *quotaUsageRule) Evaluate(now time.Time) ([]*Alert, error) {
+	var alerts []*Alert
+	for _, usage := range qur.usages() {
+		limitKind := "soft"
+		if usage.HardExceeded {
+			limitKind = "hard"
+		} else if !usage.SoftExceeded {
+			continue
+		}
+
+		alerts = append(alerts, qur.alert(usage.Subject+":"+usage.ID, fmt.Sprintf("Events quota %s limit of %s [%s] is exceeded. Usage: %d events today, %d events this month",
+			limitKind, usage.Subject, usage.ID, usage.Daily, usage.Monthly), float64(usage.Monthly)))
+	}
+
+	return alerts, nil
+}
+
+// sortAlerts sorts alerts by key for deterministic notifications order
+func sortAlerts(alerts []*Alert) {
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Key < alerts[j].Key
+	})
+}
@@ -0,0 +1,92 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/quota"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorRateRule(t *testing.T) {
+	totals := map[string]counters.DestinationTotals{}
+	rule := &errorRateRule{
+		baseRule: newBaseRule(&RuleConfig{Name: "errors", Type: DestinationErrorRateRule, Threshold: 0.1, WindowMin: 5, MinEvents: 10}),
+		totals: func() map[string]counters.DestinationTotals {
+			return totals
+		},
+	}
+	start := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	alerts, err := rule.Evaluate(start)
+	require.NoError(t, err)
+	require.Empty(t, alerts)
+
+	//dest1: 20% errors, dest2: 5% errors, dest3: not enough events
+	totals = map[string]counters.DestinationTotals{
+		"dest1": {Success: 80, Errors: 20},
+		"dest2": {Success: 95, Errors: 5},
+		"dest3": {Success: 1, Errors: 5},
+	}
+	alerts, err = rule.Evaluate(start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, "errors:dest1", alerts[0].Key)
+	require.InDelta(t, 0.2, alerts[0].Value, 0.0001)
+
+	//only events of the last 5 minutes are taken into account: dest1 has no errors in the window
+	totals = map[string]counters.DestinationTotals{
+		"dest1": {Success: 180, Errors: 20},
+	}
+	_, err = rule.Evaluate(start.Add(4 * time.Minute))
+	require.NoError(t, err)
+	alerts, err = rule.Evaluate(start.Add(7 * time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, alerts)
+}
+
+func TestQueueSizeAndDiskUsageRules(t *testing.T) {
+	queueRule := &queueSizeRule{
+		baseRule: newBaseRule(&RuleConfig{Name: "queue", Type: QueueSizeRule, Threshold: 100, Destinations: []string{"dest1", "dest2"}}),
+		sizes: func() map[string]int64 {
+			return map[string]int64{"dest1": 101, "dest2": 100, "dest3": 1000}
+		},
+	}
+	alerts, err := queueRule.Evaluate(time.Now())
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, "queue:dest1", alerts[0].Key)
+
+	diskRule := &diskUsageRule{
+		baseRule: newBaseRule(&RuleConfig{Name: "disk", Type: DiskUsageRule, Threshold: 90, Path: "/data"}),
+		usage: func(path string) (float64, error) {
+			return 95.5, nil
+		},
+	}
+	alerts, err = diskRule.Evaluate(time.Now())
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, "disk:/data", alerts[0].Key)
+	require.Equal(t, 95.5, alerts[0].Value)
+}
+
+func TestQuotaUsageRule(t *testing.T) {
+	rule := &quotaUsageRule{
+		baseRule: newBaseRule(&RuleConfig{Name: "quota", Type: QuotaUsageRule}),
+		usages: func() []*quota.Usage {
+			return []*quota.Usage{
+				{Subject: quota.APIKeySubject, ID: "key1", Daily: 10, Monthly: 100},
+				{Subject: quota.APIKeySubject, ID: "key2", Daily: 20, Monthly: 200, SoftExceeded: true},
+				{Subject: quota.ProjectSubject, ID: "project1", Daily: 30, Monthly: 300, SoftExceeded: true, HardExceeded: true},
+			}
+		},
+	}
+	alerts, err := rule.Evaluate(time.Now())
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	require.Equal(t, "quota:api_key:key2", alerts[0].Key)
+	require.Contains(t, alerts[0].Summary, "soft limit")
+	require.Equal(t, "quota:project:project1", alerts[1].Key)
+	require.Contains(t, alerts[1].Summary, "hard limit")
+}
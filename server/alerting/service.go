@@ -0,0 +1,299 @@
+package alerting
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/quota"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+const notifyTimeout = 30 * time.Second
+
+var instance *Service
+
+// TaskFailure is the last failed synchronization task of a source collection
+type TaskFailure struct {
+	Source     string    `json:"source"`
+	Collection string    `json:"collection"`
+	TaskID     string    `json:"task_id"`
+	Error      string    `json:"error"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// alertState is a state of a firing alert. It is used for avoiding repeat notifications
+type alertState struct {
+	alert      *Alert
+	firedAt    time.Time
+	notifiedAt time.Time
+}
+
+// Service evaluates alerting rules periodically and sends notifications when alerts start firing, are resolved
+// or are still firing after the repeat interval
+type Service struct {
+	serviceName    string
+	version        string
+	serverName     string
+	interval       time.Duration
+	repeatInterval time.Duration
+
+	rules     []Rule
+	notifiers []Notifier
+
+	mutex  sync.RWMutex
+	states map[string]*alertState
+
+	tasksMutex   sync.RWMutex
+	taskFailures map[string]*TaskFailure
+
+	closed chan struct{}
+}
+
+// NewService returns configured Service. Rules evaluation should be started with Start()
+func NewService(config *Config, serviceName, version, serverName string) (*Service, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		serviceName:    serviceName,
+		version:        version,
+		serverName:     serverName,
+		interval:       time.Duration(config.EvaluationIntervalSec) * time.Second,
+		repeatInterval: time.Duration(config.RepeatIntervalMin) * time.Minute,
+		notifiers:      newNotifiers(config.Notifiers),
+		states:         map[string]*alertState{},
+		taskFailures:   map[string]*TaskFailure{},
+		closed:         make(chan struct{}),
+	}
+
+	for _, ruleConfig := range config.Rules {
+		s.rules = append(s.rules, s.newRule(ruleConfig))
+	}
+
+	return s, nil
+}
+
+// Init creates the global Service and starts rules evaluation
+func Init(config *Config, serviceName, version, serverName string) (*Service, error) {
+	s, err := NewService(config, serviceName, version, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	instance = s
+	s.Start()
+	return s, nil
+}
+
+func (s *Service) newRule(config *RuleConfig) Rule {
+	base := newBaseRule(config)
+	switch config.Type {
+	case DestinationErrorRateRule:
+		return &errorRateRule{baseRule: base, totals: counters.GetDestinationTotals}
+	case QueueSizeRule:
+		return &queueSizeRule{baseRule: base, sizes: events.DestinationQueueSizes}
+	case DiskUsageRule:
+		return &diskUsageRule{baseRule: base, usage: diskUsedPercent}
+	case QuotaUsageRule:
+		return &quotaUsageRule{baseRule: base, usages: quotaUsages}
+	default:
+		return &syncTaskFailedRule{baseRule: base, failures: s.TaskFailures}
+	}
+}
+
+// Start runs a goroutine which evaluates rules every interval
+func (s *Service) Start() {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.closed:
+				return
+			case <-ticker.C:
+				s.Evaluate(context.Background())
+			}
+		}
+	})
+}
+
+// Evaluate evaluates all rules, updates alerts states and sends notifications
+func (s *Service) Evaluate(ctx context.Context) {
+	now := timestamp.Now().UTC()
+
+	var notifications []*Notification
+	s.mutex.Lock()
+	for _, rule := range s.rules {
+		alerts, err := rule.Evaluate(now)
+		if err != nil {
+			//keep the rule alerts states as is
+			logging.Errorf("[alerting] Error evaluating rule [%s]: %v", rule.Name(), err)
+			continue
+		}
+
+		notifications = append(notifications, s.updateStates(rule.Name(), alerts, now)...)
+	}
+	s.mutex.Unlock()
+
+	for _, notification := range notifications {
+		s.notify(ctx, notification)
+	}
+}
+
+// updateStates returns notifications about new, repeated and resolved alerts of the rule. Must be called under the lock
+func (s *Service) updateStates(ruleName string, alerts []*Alert, now time.Time) []*Notification {
+	sortAlerts(alerts)
+
+	var notifications []*Notification
+	firing := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		firing[alert.Key] = true
+
+		state, ok := s.states[alert.Key]
+		if !ok {
+			state = &alertState{alert: alert, firedAt: now, notifiedAt: now}
+			s.states[alert.Key] = state
+			notifications = append(notifications, s.notification(state, StatusFiring))
+			continue
+		}
+
+		state.alert = alert
+		if s.repeatInterval > 0 && now.Sub(state.notifiedAt) >= s.repeatInterval {
+			state.notifiedAt = now
+			notifications = append(notifications, s.notification(state, StatusFiring))
+		}
+	}
+
+	var resolved []string
+	for key, state := range s.states {
+		if state.alert.Rule == ruleName && !firing[key] {
+			resolved = append(resolved, key)
+		}
+	}
+	sort.Strings(resolved)
+	for _, key := range resolved {
+		notifications = append(notifications, s.notification(s.states[key], StatusResolved))
+		delete(s.states, key)
+	}
+
+	return notifications
+}
+
+func (s *Service) notification(state *alertState, status string) *Notification {
+	alertCopy := *state.alert
+	return &Notification{
+		Alert:       &alertCopy,
+		Status:      status,
+		FiredAt:     state.firedAt,
+		ServiceName: s.serviceName,
+		Version:     s.version,
+		ServerName:  s.serverName,
+	}
+}
+
+func (s *Service) notify(ctx context.Context, notification *Notification) {
+	logging.Infof("[alerting] %s: %s", notification.Title(), notification.Summary)
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	for _, notifier := range s.notifiers {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			logging.Errorf("[alerting] Error sending %s notification [%s]: %v", notifier.Name(), notification.Key, err)
+		}
+	}
+}
+
+// FiringAlerts returns currently firing alerts sorted by key
+func (s *Service) FiringAlerts() []*Alert {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	alerts := make([]*Alert, 0, len(s.states))
+	for _, state := range s.states {
+		alertCopy := *state.alert
+		alerts = append(alerts, &alertCopy)
+	}
+	sortAlerts(alerts)
+	return alerts
+}
+
+// TaskFailures returns the last failed synchronization tasks of source collections
+func (s *Service) TaskFailures() []*TaskFailure {
+	s.tasksMutex.RLock()
+	defer s.tasksMutex.RUnlock()
+
+	failures := make([]*TaskFailure, 0, len(s.taskFailures))
+	for _, failure := range s.taskFailures {
+		failureCopy := *failure
+		failures = append(failures, &failureCopy)
+	}
+	return failures
+}
+
+func (s *Service) taskFinished(sourceID, collection, taskID, errMsg string) {
+	key := sourceID + "." + collection
+
+	s.tasksMutex.Lock()
+	defer s.tasksMutex.Unlock()
+	if errMsg == "" {
+		delete(s.taskFailures, key)
+		return
+	}
+
+	s.taskFailures[key] = &TaskFailure{Source: sourceID, Collection: collection, TaskID: taskID, Error: errMsg, FailedAt: timestamp.Now().UTC()}
+}
+
+// Close stops rules evaluation
+func (s *Service) Close() error {
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+
+	return nil
+}
+
+// SyncTaskFailed records the synchronization task failure (all retries have been exhausted)
+func SyncTaskFailed(sourceID, collection, taskID, errMsg string) {
+	if instance != nil {
+		if errMsg == "" {
+			errMsg = "unknown error"
+		}
+		instance.taskFinished(sourceID, collection, taskID, errMsg)
+	}
+}
+
+// SyncTaskSucceeded resolves the source collection synchronization failure
+func SyncTaskSucceeded(sourceID, collection, taskID string) {
+	if instance != nil {
+		instance.taskFinished(sourceID, collection, taskID, "")
+	}
+}
+
+// quotaUsages returns projects and API keys quotas usages or nil if quotas are disabled
+func quotaUsages() []*quota.Usage {
+	if quotaService := quota.Instance(); quotaService != nil {
+		return quotaService.Usages()
+	}
+
+	return nil
+}
+
+func diskUsedPercent(path string) (float64, error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return usage.UsedPercent, nil
+}
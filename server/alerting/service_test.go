@@ -0,0 +1,161 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRule struct {
+	name   string
+	alerts []*Alert
+	err    error
+}
+
+func (tr *testRule) Name() string {
+	return tr.name
+}
+
+func (tr *testRule) Evaluate(now time.Time) ([]*Alert, error) {
+	return tr.alerts, tr.err
+}
+
+type testNotifier struct {
+	mutex         sync.Mutex
+	notifications []*Notification
+}
+
+func (tn *testNotifier) Name() string {
+	return "test"
+}
+
+func (tn *testNotifier) Notify(ctx context.Context, notification *Notification) error {
+	tn.mutex.Lock()
+	defer tn.mutex.Unlock()
+	tn.notifications = append(tn.notifications, notification)
+	return nil
+}
+
+func (tn *testNotifier) statuses() []string {
+	tn.mutex.Lock()
+	defer tn.mutex.Unlock()
+	var statuses []string
+	for _, notification := range tn.notifications {
+		statuses = append(statuses, notification.Status+":"+notification.Key)
+	}
+	tn.notifications = nil
+	return statuses
+}
+
+func testAlert(rule, subject string) *Alert {
+	return &Alert{Key: rule + ":" + subject, Rule: rule, Subject: subject, Severity: SeverityCritical}
+}
+
+func TestAlertsStateTracking(t *testing.T) {
+	rule := &testRule{name: "queue"}
+	notifier := &testNotifier{}
+	service := &Service{
+		rules:        []Rule{rule},
+		notifiers:    []Notifier{notifier},
+		states:       map[string]*alertState{},
+		taskFailures: map[string]*TaskFailure{},
+	}
+
+	rule.alerts = []*Alert{testAlert("queue", "dest1")}
+	service.Evaluate(context.Background())
+	require.Equal(t, []string{"firing:queue:dest1"}, notifier.statuses())
+
+	//still firing: notified only once
+	service.Evaluate(context.Background())
+	require.Empty(t, notifier.statuses())
+
+	rule.alerts = []*Alert{testAlert("queue", "dest1"), testAlert("queue", "dest2")}
+	service.Evaluate(context.Background())
+	require.Equal(t, []string{"firing:queue:dest2"}, notifier.statuses())
+	require.Len(t, service.FiringAlerts(), 2)
+
+	//evaluation error doesn't resolve alerts
+	rule.alerts, rule.err = nil, errors.New("evaluation error")
+	service.Evaluate(context.Background())
+	require.Empty(t, notifier.statuses())
+
+	rule.alerts, rule.err = []*Alert{testAlert("queue", "dest2")}, nil
+	service.Evaluate(context.Background())
+	require.Equal(t, []string{"resolved:queue:dest1"}, notifier.statuses())
+	require.Len(t, service.FiringAlerts(), 1)
+
+	//repeat notification after repeat interval
+	service.repeatInterval = time.Nanosecond
+	service.Evaluate(context.Background())
+	require.Equal(t, []string{"firing:queue:dest2"}, notifier.statuses())
+}
+
+func TestSyncTaskFailures(t *testing.T) {
+	notifier := &testNotifier{}
+	service := &Service{
+		notifiers:    []Notifier{notifier},
+		states:       map[string]*alertState{},
+		taskFailures: map[string]*TaskFailure{},
+	}
+	service.rules = []Rule{service.newRule(&RuleConfig{Name: "sync", Type: SyncTaskFailedRule})}
+
+	service.taskFinished("src", "users", "task1", "connection refused")
+	service.Evaluate(context.Background())
+	require.Equal(t, []string{"firing:sync:src.users"}, notifier.statuses())
+
+	service.taskFinished("src", "users", "task2", "")
+	service.Evaluate(context.Background())
+	require.Equal(t, []string{"resolved:sync:src.users"}, notifier.statuses())
+	require.Empty(t, service.TaskFailures())
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received = append(received, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	notifier := &PagerDutyNotifier{url: server.URL, routingKey: "key"}
+	notification := &Notification{Alert: testAlert("queue", "dest1"), Status: StatusFiring, ServerName: "server1"}
+	require.NoError(t, notifier.Notify(context.Background(), notification))
+	notification.Status = StatusResolved
+	require.NoError(t, notifier.Notify(context.Background(), notification))
+
+	require.Len(t, received, 2)
+	require.Equal(t, "trigger", received[0]["event_action"])
+	require.Equal(t, "queue:dest1", received[0]["dedup_key"])
+	require.Equal(t, "server1", received[0]["payload"].(map[string]interface{})["source"])
+	require.Equal(t, "resolve", received[1]["event_action"])
+	require.Nil(t, received[1]["payload"])
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := &Config{Rules: []*RuleConfig{{Type: QueueSizeRule, Threshold: 1000}}}
+	require.Error(t, config.Validate(), "notifiers are required")
+
+	config.Notifiers = &NotifiersConfig{Slack: &SlackConfig{URL: "https://hooks.slack.com/services/x"}}
+	require.NoError(t, config.Validate())
+	require.Equal(t, QueueSizeRule, config.Rules[0].Name)
+	require.Equal(t, SeverityCritical, config.Rules[0].Severity)
+	require.Equal(t, defaultEvaluationIntervalSec, config.EvaluationIntervalSec)
+
+	config.Rules = append(config.Rules, &RuleConfig{Type: QueueSizeRule, Threshold: 10})
+	require.Error(t, config.Validate(), "rule names must be unique")
+
+	config.Rules = []*RuleConfig{{Type: DestinationErrorRateRule, Threshold: 5}}
+	require.Error(t, config.Validate(), "error rate threshold is a fraction")
+
+	config.Rules = []*RuleConfig{{Type: "cpu_usage"}}
+	require.Error(t, config.Validate())
+}
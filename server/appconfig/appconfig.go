@@ -1,6 +1,7 @@
 package appconfig
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -8,12 +9,14 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/identifiers"
 
 	"github.com/jitsucom/jitsu/server/authorization"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/useragent"
+	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/spf13/viper"
 )
 
@@ -47,6 +50,21 @@ type AppConfig struct {
 	GlobalUniqueIDField   *identifiers.UniqueID
 	EnrichWithHTTPContext bool
 
+	//Cookieless is a privacy mode: JS and pixel endpoints don't use cookies, anonymous ID is derived on the server
+	//from client IP, user-agent and CookielessSalt and rotates daily
+	Cookieless     bool
+	CookielessSalt string
+
+	//CircuitBreakerFailureThreshold is a count of consecutive destination connection errors which pauses streaming (0 - disabled)
+	CircuitBreakerFailureThreshold int
+	CircuitBreakerProbeIntervalSec int
+	//CatchUp is a configuration of streaming destinations catch-up mode
+	CatchUp CatchUpConfiguration
+
+	//ShutdownTimeout is a deadline of the graceful shutdown. The server exits forcibly after it
+	ShutdownTimeout time.Duration
+
+	drainMe     []Drainer
 	closeMe     []io.Closer
 	lastCloseMe []io.Closer
 
@@ -54,6 +72,31 @@ type AppConfig struct {
 	writeAheadLog   io.Closer
 }
 
+//CatchUpConfiguration is a configuration of streaming catch-up mode. When a destination queue has at least LagThreshold
+//events or the oldest queued event is older than AgeThreshold, ThreadsCount extra streaming workers are started and
+//stream mode micro-batches become BatchSizeMultiplier times larger until the lag is below half of the thresholds.
+//0 value disables the threshold
+type CatchUpConfiguration struct {
+	Enabled             bool
+	LagThreshold        int64
+	AgeThreshold        time.Duration
+	ThreadsCount        int
+	BatchSizeMultiplier int
+}
+
+//Drainer stops accepting new work and waits for in-flight work until ctx is done
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
+
+//DrainerFunc is an adapter to allow the use of ordinary functions (e.g. http.Server.Shutdown) as Drainer
+type DrainerFunc func(ctx context.Context) error
+
+//Drain calls f(ctx)
+func (f DrainerFunc) Drain(ctx context.Context) error {
+	return f(ctx)
+}
+
 var (
 	Instance     *AppConfig
 	RawVersion   string
@@ -71,6 +114,7 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("server.name", defaultServerName)
 	viper.SetDefault("server.port", "8001")
 	viper.SetDefault("server.log.level", "info")
+	viper.SetDefault("server.log.format", logging.TextFormat)
 	viper.SetDefault("server.auth_reload_sec", 1)
 	viper.SetDefault("server.api_keys_reload_sec", 1)
 	viper.SetDefault("server.destinations_reload_sec", 1)
@@ -92,10 +136,41 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("server.strict_auth_tokens", false)
 	viper.SetDefault("server.max_columns", 100)
 	viper.SetDefault("server.max_event_size", 51200)
+	viper.SetDefault("server.metrics.prometheus.max_label_values", 1000)
+	viper.SetDefault("server.health.timeout_ms", 5000)
+	viper.SetDefault("server.health.cache_ttl_sec", 30)
+	viper.SetDefault("server.shutdown.timeout_sec", 30)
+	viper.SetDefault("server.cookieless.enabled", false)
+	viper.SetDefault("server.http.max_body_size", 10485760)
+	viper.SetDefault("server.http.read_timeout_sec", 60)
+	viper.SetDefault("server.http.read_header_timeout_sec", 60)
+	viper.SetDefault("server.http.write_timeout_sec", 0)
+	viper.SetDefault("server.http.idle_timeout_sec", 65)
+	viper.SetDefault("server.http.h2c.enabled", false)
+	viper.SetDefault("server.http.h2c.max_concurrent_streams", 250)
+	viper.SetDefault("alerting.enabled", false)
+	viper.SetDefault("delivery_tracking.enabled", false)
+	viper.SetDefault("delivery_tracking.capacity", 100000)
+	viper.SetDefault("delivery_tracking.ttl_hours", 72)
+	viper.SetDefault("delivery_tracking.pool_size", 1)
+	viper.SetDefault("delivery_tracking.trim_interval_sec", 60)
+	viper.SetDefault("schema_registry.enabled", true)
+	viper.SetDefault("schema_registry.history_capacity", 1000)
+	viper.SetDefault("quotas.enabled", false)
+	viper.SetDefault("quotas.refresh_interval_sec", 30)
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.service_name", "jitsu-server")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.otlp.timeout_ms", 10000)
 	viper.SetDefault("server.configurator_urn", "/configurator")
+	viper.SetDefault("server.grpc.enabled", false)
+	viper.SetDefault("server.grpc.port", "8002")
+	viper.SetDefault("server.grpc.max_message_size", 4194304)
+	viper.SetDefault("mqtt.enabled", false)
 	//unique IDs
 	viper.SetDefault("server.fields_configuration.unique_id_field", "/eventn_ctx/event_id||/eventn_ctx_event_id||/event_id")
 	viper.SetDefault("server.fields_configuration.user_agent_path", "/eventn_ctx/user_agent||/user_agent")
+	viper.SetDefault("server.fields_configuration.client_hints_path", "/eventn_ctx/client_hints||/client_hints")
 	//default enrichment rules
 	viper.SetDefault("server.fields_configuration.src_source_ip", "/source_ip")
 	viper.SetDefault("server.fields_configuration.dst_source_ip", "/eventn_ctx/location||/location")
@@ -108,7 +183,23 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("log.rotation_min", 5)
 
 	viper.SetDefault("batch_uploader.threads_count", 1)
+	viper.SetDefault("events.queue.type", "redis")
+	//0 - queue size isn't limited
+	viper.SetDefault("events.queue.max_size", 0)
+	viper.SetDefault("events.queue.overflow_policy", "drop_new")
+	viper.SetDefault("events.queue.priority_lanes", false)
 	viper.SetDefault("streaming.threads_count", 1)
+	viper.SetDefault("bot_filter.enabled", false)
+	viper.SetDefault("bot_filter.mode", "drop")
+	viper.SetDefault("bot_filter.mark_field", "/bot_reason")
+	viper.SetDefault("bot_filter.ip_lists_reload_min", 60)
+	viper.SetDefault("streaming.circuit_breaker.failure_threshold", 10)
+	viper.SetDefault("streaming.circuit_breaker.probe_interval_sec", 30)
+	viper.SetDefault("streaming.catch_up.enabled", false)
+	viper.SetDefault("streaming.catch_up.lag_threshold", 10000)
+	viper.SetDefault("streaming.catch_up.age_threshold_sec", 300)
+	viper.SetDefault("streaming.catch_up.threads_count", 4)
+	viper.SetDefault("streaming.catch_up.batch_size_multiplier", 5)
 
 	viper.SetDefault("sql_debug_log.ddl.enabled", true)
 	viper.SetDefault("sql_debug_log.ddl.rotation_min", "1440")
@@ -123,6 +214,16 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("users_recognition.pool.size", 10)
 	viper.SetDefault("users_recognition.cache_ttl_min", 180)
 
+	//personal data erasure (right to be forgotten): flattened identifier columns of the default events table
+	viper.SetDefault("erasure.mode", "delete")
+	viper.SetDefault("erasure.columns", []string{"user_id", "user_email", "user_internal_id", "user_anonymous_id", "user_hashed_anonymous_id",
+		"eventn_ctx_user_id", "eventn_ctx_user_email", "eventn_ctx_user_internal_id", "eventn_ctx_user_anonymous_id", "eventn_ctx_user_hashed_anonymous_id"})
+
+	//data retention: disabled by default. Days are configured globally or per destination
+	viper.SetDefault("retention.enabled", false)
+	viper.SetDefault("retention.interval_min", 1440)
+	viper.SetDefault("retention.dry_run", false)
+
 	viper.SetDefault("singer-bridge.python", "python3")
 	viper.SetDefault("singer-bridge.install_taps", true)
 	viper.SetDefault("singer-bridge.update_taps", false)
@@ -139,12 +240,26 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("sync-tasks.log.enabled", true)
 	viper.SetDefault("sync-tasks.log.path", logging.GlobalType)
 	viper.SetDefault("sync-tasks.store_attempts", 3)
+	viper.SetDefault("sync-tasks.concurrency", 1)
+	viper.SetDefault("sync-tasks.retry.max_attempts", 0)
+	viper.SetDefault("sync-tasks.retry.initial_delay_sec", 60)
+	viper.SetDefault("sync-tasks.retry.max_delay_sec", 3600)
+	viper.SetDefault("sync-tasks.retry.multiplier", 2)
+	viper.SetDefault("sync-tasks.retry.jitter", 0.2)
 
 	//User Recognition anonymous events default TTL 10080 min - 7 days
 	viper.SetDefault("meta.storage.redis.ttl_minutes.anonymous_events", 10080)
 
 	//MaxMind URL
 	viper.SetDefault("maxmind.official_url", "https://download.maxmind.com/app/geoip_download?license_key=%s&edition_id=%s&suffix=tar.gz")
+	//IP2Location download URL: token and file code (e.g. DB11LITEBIN)
+	viper.SetDefault("ip2location.official_url", "https://www.ip2location.com/download/?token=%s&file=%s")
+	viper.SetDefault("ipinfo.url", "https://ipinfo.io")
+	viper.SetDefault("geo.ipinfo.cache_size", 100000)
+	viper.SetDefault("geo.ipinfo.cache_ttl_min", 1440)
+	viper.SetDefault("geo.ipinfo.rate_limit_per_sec", 10)
+	//geo databases are re-downloaded once a day
+	viper.SetDefault("geo.refresh_interval_hours", 24)
 
 	//Segment API mappings
 	//uses remove type mappings (e.g. "/page->") because we have root path mapping "/context -> /"
@@ -236,6 +351,19 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("node.pool_size", 1)
 	viper.SetDefault("node.max_space", 100)
 	viper.SetDefault("node.sources_max_space", 500)
+	viper.SetDefault("node.pool.max_sessions", 0)
+	viper.SetDefault("node.pool.session_idle_timeout_sec", 3600)
+	viper.SetDefault("node.pool.warm", false)
+	viper.SetDefault("python.enabled", false)
+	viper.SetDefault("python.pool_size", 1)
+	viper.SetDefault("wasm.enabled", false)
+	viper.SetDefault("wasm.max_memory_mb", 64)
+	viper.SetDefault("wasm.timeout_ms", 5000)
+	viper.SetDefault("script_cache.enabled", true)
+	viper.SetDefault("script_cache.type", "file")
+	viper.SetDefault("script_cache.ttl_sec", 30*24*60*60)
+	viper.SetDefault("transform.logs_rate_limit_per_sec", 10)
+	viper.SetDefault("transform.metrics_rate_limit_per_sec", 100)
 
 	if containerized {
 		viper.SetDefault("server.static_files_dir", "/home/eventnative/app/web")
@@ -294,6 +422,14 @@ func Init(containerized bool, dockerHubID string) error {
 	} else {
 		logging.GlobalLogsWriter = os.Stdout
 	}
+	if err := logging.SetFormat(viper.GetString("server.log.format")); err != nil {
+		return fmt.Errorf("Error parsing server.log.format: %v", err)
+	}
+	for component, level := range viper.GetStringMapString("server.log.levels") {
+		if err := logging.SetComponentLevel(component, level); err != nil {
+			return fmt.Errorf("Error parsing server.log.levels.%s: %v", component, err)
+		}
+	}
 	err := logging.InitGlobalLogger(logging.GlobalLogsWriter, viper.GetString("server.log.level"))
 	if err != nil {
 		return err
@@ -413,11 +549,53 @@ func Init(containerized bool, dockerHubID string) error {
 
 	enrichWithHTTPContext := viper.GetBool("server.event_enrichment.http_context")
 	appConfig.EnrichWithHTTPContext = enrichWithHTTPContext
+	appConfig.CircuitBreakerFailureThreshold = viper.GetInt("streaming.circuit_breaker.failure_threshold")
+	appConfig.CircuitBreakerProbeIntervalSec = viper.GetInt("streaming.circuit_breaker.probe_interval_sec")
+	appConfig.CatchUp = CatchUpConfiguration{
+		Enabled:             viper.GetBool("streaming.catch_up.enabled"),
+		LagThreshold:        viper.GetInt64("streaming.catch_up.lag_threshold"),
+		AgeThreshold:        time.Duration(viper.GetInt("streaming.catch_up.age_threshold_sec")) * time.Second,
+		ThreadsCount:        viper.GetInt("streaming.catch_up.threads_count"),
+		BatchSizeMultiplier: viper.GetInt("streaming.catch_up.batch_size_multiplier"),
+	}
+	if appConfig.CatchUp.Enabled && appConfig.CatchUp.ThreadsCount < 0 {
+		return fmt.Errorf("streaming.catch_up.threads_count can't be negative")
+	}
+	shutdownTimeoutSec := viper.GetInt("server.shutdown.timeout_sec")
+	if shutdownTimeoutSec <= 0 {
+		return fmt.Errorf("server.shutdown.timeout_sec must be positive: %d", shutdownTimeoutSec)
+	}
+	appConfig.ShutdownTimeout = time.Duration(shutdownTimeoutSec) * time.Second
+
+	appConfig.Cookieless = viper.GetBool("server.cookieless.enabled")
+	appConfig.CookielessSalt = viper.GetString("server.cookieless.salt")
+	if appConfig.Cookieless {
+		if appConfig.CookielessSalt == "" {
+			appConfig.CookielessSalt = uuid.New()
+			logging.Warnf("server.cookieless.salt isn't configured. Random salt is used: anonymous IDs will differ between Jitsu instances and after restart")
+		}
+		logging.Info("🍪 Cookie-less mode is enabled: anonymous IDs are derived on the server and rotate daily")
+	}
 
 	Instance = &appConfig
 	return nil
 }
 
+// ScheduleDraining adds drainer (HTTP server, batch uploader) which is drained before closing
+func (a *AppConfig) ScheduleDraining(d Drainer) {
+	a.drainMe = append(a.drainMe, d)
+}
+
+// Drain drains all drainers one by one in reverse order of scheduling (HTTP server is scheduled last and stops accepting events first).
+// Every drainer waits for in-flight work until ctx is done
+func (a *AppConfig) Drain(ctx context.Context) {
+	for i := len(a.drainMe) - 1; i >= 0; i-- {
+		if err := a.drainMe[i].Drain(ctx); err != nil {
+			logging.Errorf("[Shutdown] Error draining: %v", err)
+		}
+	}
+}
+
 func (a *AppConfig) ScheduleClosing(c io.Closer) {
 	a.closeMe = append(a.closeMe, c)
 }
@@ -3,16 +3,49 @@ package authorization
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/iphandling"
 	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/jitsucom/jitsu/server/routing"
+	"github.com/jitsucom/jitsu/server/sampling"
+	"github.com/jitsucom/jitsu/server/validation"
 	"strings"
 )
 
+const (
+	//TokenPriorityHigh - events are drained first by streaming workers, batch files are uploaded every minute
+	TokenPriorityHigh = "high"
+	//TokenPriorityLow - events are drained last by streaming workers, batch files are uploaded twice less frequently
+	TokenPriorityLow = "low"
+)
+
 type Token struct {
 	ID             string   `mapstructure:"id" json:"id,omitempty"`
 	ClientSecret   string   `mapstructure:"client_secret" json:"client_secret,omitempty"`
 	ServerSecret   string   `mapstructure:"server_secret" json:"server_secret,omitempty"`
 	Origins        []string `mapstructure:"origins" json:"origins,omitempty"`
 	BatchPeriodMin int      `mapstructure:"batch_period_min" json:"batch_period_min,omitempty"`
+	Priority       string   `mapstructure:"priority" json:"priority,omitempty"`
+
+	Validation *validation.Config `mapstructure:"validation" json:"validation,omitempty"`
+	Routing    *routing.Config    `mapstructure:"routing" json:"routing,omitempty"`
+	Sampling   *sampling.Config   `mapstructure:"sampling" json:"sampling,omitempty"`
+	IPHandling *iphandling.Config `mapstructure:"ip_handling" json:"ip_handling,omitempty"`
+}
+
+//GetBatchPeriodMin returns batch_period_min if it is set or batch period according to the token priority
+func (t *Token) GetBatchPeriodMin(defaultBatchPeriodMin int) int {
+	if t.BatchPeriodMin > 0 {
+		return t.BatchPeriodMin
+	}
+
+	switch t.Priority {
+	case TokenPriorityHigh:
+		return 1
+	case TokenPriorityLow:
+		return defaultBatchPeriodMin * 2
+	default:
+		return defaultBatchPeriodMin
+	}
 }
 
 type TokensPayload struct {
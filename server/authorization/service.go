@@ -3,6 +3,7 @@ package authorization
 import (
 	"errors"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/iphandling"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/spf13/viper"
@@ -190,6 +191,14 @@ func (s *Service) GetToken(tokenFilter string) *Token {
 	return nil
 }
 
+// GetIPHandling returns IP handling configuration of the token by client_secret/server_secret/token id or nil
+func (s *Service) GetIPHandling(tokenFilter string) *iphandling.Config {
+	if token := s.GetToken(tokenFilter); token != nil {
+		return token.IPHandling
+	}
+	return nil
+}
+
 // parse and set tokensHolder with lock
 func (s *Service) updateTokens(payload []byte) {
 	tokens, err := parseFromBytes(payload)
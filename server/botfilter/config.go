@@ -0,0 +1,78 @@
+package botfilter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Filter modes
+const (
+	// DropMode skips bot events: they aren't sent to destinations
+	DropMode = "drop"
+	// MarkMode sets the filter reason into the mark field and sends events to destinations
+	MarkMode = "mark"
+
+	defaultMarkField        = "/bot_reason"
+	defaultIPListsReloadMin = 60
+)
+
+// Filter reasons
+const (
+	HoneypotReason     = "honeypot"
+	UserAgentReason    = "user_agent"
+	IPReputationReason = "ip_reputation"
+	RateAnomalyReason  = "rate_anomaly"
+)
+
+// Config is a configuration of bot and spam traffic filtering
+type Config struct {
+	Enabled bool   `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Mode    string `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+	// MarkField is a path where the filter reason is set in mark mode
+	MarkField string `mapstructure:"mark_field" json:"mark_field,omitempty" yaml:"mark_field,omitempty"`
+
+	// UserAgents are additional case insensitive substrings of bot user agents
+	UserAgents               []string `mapstructure:"user_agents" json:"user_agents,omitempty" yaml:"user_agents,omitempty"`
+	DisableDefaultUserAgents bool     `mapstructure:"disable_default_user_agents" json:"disable_default_user_agents,omitempty" yaml:"disable_default_user_agents,omitempty"`
+
+	// IPs are blocked IP addresses and CIDR ranges
+	IPs []string `mapstructure:"ips" json:"ips,omitempty" yaml:"ips,omitempty"`
+	// IPLists are files or URLs with blocked IP addresses and CIDR ranges (one per line, # - comments)
+	IPLists          []string `mapstructure:"ip_lists" json:"ip_lists,omitempty" yaml:"ip_lists,omitempty"`
+	IPListsReloadMin int      `mapstructure:"ip_lists_reload_min" json:"ip_lists_reload_min,omitempty" yaml:"ip_lists_reload_min,omitempty"`
+
+	// HoneypotFields are paths of hidden form fields which are filled only by bots
+	HoneypotFields []string `mapstructure:"honeypot_fields" json:"honeypot_fields,omitempty" yaml:"honeypot_fields,omitempty"`
+
+	RateAnomaly *RateAnomalyConfig `mapstructure:"rate_anomaly" json:"rate_anomaly,omitempty" yaml:"rate_anomaly,omitempty"`
+}
+
+// RateAnomalyConfig is a configuration of too frequent events from the same IP address detection
+type RateAnomalyConfig struct {
+	MaxEventsPerIP int `mapstructure:"max_events_per_ip" json:"max_events_per_ip,omitempty" yaml:"max_events_per_ip,omitempty"`
+	WindowSec      int `mapstructure:"window_sec" json:"window_sec,omitempty" yaml:"window_sec,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "", DropMode, MarkMode:
+	default:
+		return fmt.Errorf("unsupported bot filter mode: %s. Supported: %s, %s", c.Mode, DropMode, MarkMode)
+	}
+
+	if c.IPListsReloadMin < 0 {
+		return errors.New("bot_filter.ip_lists_reload_min can't be negative")
+	}
+
+	if c.RateAnomaly != nil {
+		if c.RateAnomaly.MaxEventsPerIP < 0 || c.RateAnomaly.WindowSec < 0 {
+			return errors.New("bot_filter.rate_anomaly parameters can't be negative")
+		}
+		if c.RateAnomaly.MaxEventsPerIP > 0 && c.RateAnomaly.WindowSec == 0 {
+			return errors.New("bot_filter.rate_anomaly.window_sec is required")
+		}
+	}
+
+	return nil
+}
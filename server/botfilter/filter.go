@@ -0,0 +1,198 @@
+package botfilter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/resources"
+)
+
+const sourceIPPath = "/source_ip"
+
+// Filter detects bot and spam events by honeypot fields, user agent, IP reputation and events rate.
+// Detected events are dropped or marked with the reason
+type Filter struct {
+	drop           bool
+	markField      jsonutils.JSONPath
+	userAgentPath  jsonutils.JSONPath
+	sourceIPPath   jsonutils.JSONPath
+	userAgents     []string
+	honeypotFields []jsonutils.JSONPath
+	ipReputation   *ipReputation
+	rateCounter    *rateCounter
+}
+
+// NewFilter returns configured Filter or nil if it is disabled. IP lists are reloaded in background
+func NewFilter(config *Config, userAgentPath string) (*Filter, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	markField := config.MarkField
+	if markField == "" {
+		markField = defaultMarkField
+	}
+
+	filter := &Filter{
+		drop:          config.Mode != MarkMode,
+		markField:     jsonutils.NewJSONPath(markField),
+		userAgentPath: jsonutils.NewJSONPath(userAgentPath),
+		sourceIPPath:  jsonutils.NewJSONPath(sourceIPPath),
+		ipReputation:  &ipReputation{sets: map[string]*ipSet{}},
+		rateCounter:   newRateCounter(config.RateAnomaly),
+	}
+	if filter.markField.IsEmpty() {
+		return nil, fmt.Errorf("bot_filter.mark_field must be a valid path like: /node1/node2. Got: %q", markField)
+	}
+
+	if !config.DisableDefaultUserAgents {
+		filter.userAgents = append(filter.userAgents, defaultBotUserAgents...)
+	}
+	for _, userAgent := range config.UserAgents {
+		if userAgent = strings.ToLower(strings.TrimSpace(userAgent)); userAgent != "" {
+			filter.userAgents = append(filter.userAgents, userAgent)
+		}
+	}
+
+	for _, field := range config.HoneypotFields {
+		path := jsonutils.NewJSONPath(field)
+		if path.IsEmpty() {
+			return nil, fmt.Errorf("bot_filter.honeypot_fields must be valid paths like: /node1/node2. Got: %q", field)
+		}
+		filter.honeypotFields = append(filter.honeypotFields, path)
+	}
+
+	if len(config.IPs) > 0 {
+		set, err := parseIPSet(config.IPs)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing bot_filter.ips: %v", err)
+		}
+		filter.ipReputation.set("config", set)
+	}
+
+	reloadMin := config.IPListsReloadMin
+	if reloadMin == 0 {
+		reloadMin = defaultIPListsReloadMin
+	}
+	for _, source := range config.IPLists {
+		source := source
+		loadFunc := resources.LoadFromFile
+		if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			loadFunc = resources.LoadFromHTTP
+		}
+		resources.Watch("bot_filter_ip_list", source, loadFunc, func(content []byte) {
+			set, err := parseIPList(content)
+			if err != nil {
+				logging.Errorf("Error parsing bot filter IP list [%s]: %v", source, err)
+				return
+			}
+			filter.ipReputation.set(source, set)
+			logging.Infof("Bot filter IP list [%s] has been loaded: %d IPs, %d ranges", source, len(set.ips), len(set.nets))
+		}, time.Duration(reloadMin)*time.Minute)
+	}
+
+	return filter, nil
+}
+
+// Apply checks the event and returns false if the event must be dropped. In mark mode the reason is set into
+// the mark field and true is returned. Filtered events are counted per API key
+func (f *Filter) Apply(tokenID string, event events.Event) bool {
+	if f == nil {
+		return true
+	}
+
+	reason := f.Check(event)
+	if reason == "" {
+		return true
+	}
+
+	if f.drop {
+		metrics.BotFilteredEvent(tokenID, reason, DropMode)
+		return false
+	}
+
+	metrics.BotFilteredEvent(tokenID, reason, MarkMode)
+	if err := f.markField.Set(event, reason); err != nil {
+		logging.SystemErrorf("Error setting bot filter reason: %v", err)
+	}
+	return true
+}
+
+// Check returns the reason if the event is detected as a bot or spam event or empty string otherwise
+func (f *Filter) Check(event events.Event) string {
+	for _, field := range f.honeypotFields {
+		if value, ok := field.Get(event); ok && isFilled(value) {
+			return HoneypotReason
+		}
+	}
+
+	if len(f.userAgents) > 0 {
+		if value, ok := f.userAgentPath.Get(event); ok {
+			if userAgent, ok := value.(string); ok && f.isBotUserAgent(userAgent) {
+				return UserAgentReason
+			}
+		}
+	}
+
+	ip := f.sourceIP(event)
+	if ip == "" {
+		return ""
+	}
+
+	if !f.ipReputation.isEmpty() && f.ipReputation.blocked(ip) {
+		return IPReputationReason
+	}
+
+	if f.rateCounter != nil && f.rateCounter.exceeded(ip) {
+		return RateAnomalyReason
+	}
+
+	return ""
+}
+
+func (f *Filter) isBotUserAgent(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, botUserAgent := range f.userAgents {
+		if strings.Contains(userAgent, botUserAgent) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sourceIP returns the first IP of the source IP value (it may contain proxies chain)
+func (f *Filter) sourceIP(event events.Event) string {
+	value, ok := f.sourceIPPath.Get(event)
+	if !ok {
+		return ""
+	}
+	ip, ok := value.(string)
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.Split(ip, ",")[0])
+}
+
+func isFilled(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case bool:
+		return v
+	default:
+		return true
+	}
+}
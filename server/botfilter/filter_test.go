@@ -0,0 +1,130 @@
+package botfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserAgentPath = "/eventn_ctx/user_agent"
+
+func TestNewFilterDisabled(t *testing.T) {
+	filter, err := NewFilter(nil, testUserAgentPath)
+	require.NoError(t, err)
+	require.Nil(t, filter)
+
+	filter, err = NewFilter(&Config{Mode: MarkMode}, testUserAgentPath)
+	require.NoError(t, err)
+	require.Nil(t, filter)
+
+	require.True(t, filter.Apply("token", events.Event{}), "nil filter must accept all events")
+
+	_, err = NewFilter(&Config{Enabled: true, Mode: "unknown"}, testUserAgentPath)
+	require.Error(t, err)
+	_, err = NewFilter(&Config{Enabled: true, IPs: []string{"not an ip"}}, testUserAgentPath)
+	require.Error(t, err)
+	_, err = NewFilter(&Config{Enabled: true, RateAnomaly: &RateAnomalyConfig{MaxEventsPerIP: 10}}, testUserAgentPath)
+	require.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	filter, err := NewFilter(&Config{
+		Enabled:        true,
+		UserAgents:     []string{"  MyCrawler "},
+		IPs:            []string{"10.0.0.1", "192.168.0.0/16", "# comment"},
+		HoneypotFields: []string{"/form/website"},
+	}, testUserAgentPath)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		event    events.Event
+		expected string
+	}{
+		{
+			"human",
+			events.Event{"eventn_ctx": map[string]interface{}{"user_agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) Chrome/92.0"}, "source_ip": "1.1.1.1"},
+			"",
+		},
+		{
+			"default bot user agent",
+			events.Event{"eventn_ctx": map[string]interface{}{"user_agent": "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}},
+			UserAgentReason,
+		},
+		{
+			"custom bot user agent",
+			events.Event{"eventn_ctx": map[string]interface{}{"user_agent": "mycrawler/1.0"}},
+			UserAgentReason,
+		},
+		{
+			"blocked ip",
+			events.Event{"source_ip": "10.0.0.1"},
+			IPReputationReason,
+		},
+		{
+			"blocked range with proxies chain",
+			events.Event{"source_ip": "192.168.10.20, 10.10.10.10"},
+			IPReputationReason,
+		},
+		{
+			"empty honeypot",
+			events.Event{"form": map[string]interface{}{"website": " "}, "source_ip": "1.1.1.1"},
+			"",
+		},
+		{
+			"filled honeypot",
+			events.Event{"form": map[string]interface{}{"website": "http://spam.com"}, "source_ip": "1.1.1.1"},
+			HoneypotReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, filter.Check(tt.event))
+		})
+	}
+}
+
+func TestRateAnomaly(t *testing.T) {
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+
+	filter, err := NewFilter(&Config{
+		Enabled:                  true,
+		DisableDefaultUserAgents: true,
+		RateAnomaly:              &RateAnomalyConfig{MaxEventsPerIP: 2, WindowSec: 60},
+	}, testUserAgentPath)
+	require.NoError(t, err)
+
+	event := events.Event{"source_ip": "1.1.1.1"}
+	require.Equal(t, "", filter.Check(event))
+	require.Equal(t, "", filter.Check(event))
+	require.Equal(t, RateAnomalyReason, filter.Check(event))
+	require.Equal(t, "", filter.Check(events.Event{"source_ip": "2.2.2.2"}))
+
+	timestamp.SetFreezeTime(timestamp.Now().Add(time.Minute))
+	require.Equal(t, "", filter.Check(event), "counters must be reset in the new window")
+}
+
+func TestApply(t *testing.T) {
+	dropFilter, err := NewFilter(&Config{Enabled: true, Mode: DropMode}, testUserAgentPath)
+	require.NoError(t, err)
+	markFilter, err := NewFilter(&Config{Enabled: true, Mode: MarkMode, MarkField: "/bot/reason"}, testUserAgentPath)
+	require.NoError(t, err)
+
+	bot := func() events.Event {
+		return events.Event{"eventn_ctx": map[string]interface{}{"user_agent": "HeadlessChrome/92.0"}}
+	}
+
+	require.False(t, dropFilter.Apply("token", bot()))
+
+	event := bot()
+	require.True(t, markFilter.Apply("token", event))
+	require.Equal(t, map[string]interface{}{"reason": UserAgentReason}, event["bot"])
+
+	human := events.Event{"eventn_ctx": map[string]interface{}{"user_agent": "Mozilla/5.0 Firefox/91.0"}}
+	require.True(t, markFilter.Apply("token", human))
+	require.NotContains(t, human, "bot")
+}
@@ -0,0 +1,108 @@
+package botfilter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ipSet is a set of IP addresses and CIDR ranges
+type ipSet struct {
+	ips  map[string]bool
+	nets []*net.IPNet
+}
+
+// parseIPSet parses IP addresses and CIDR ranges separated by new lines. Empty lines and # comments are skipped
+func parseIPSet(lines []string) (*ipSet, error) {
+	set := &ipSet{ips: map[string]bool{}}
+	for _, line := range lines {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.Contains(line, "/") {
+			_, ipNet, err := net.ParseCIDR(line)
+			if err != nil {
+				return nil, fmt.Errorf("malformed CIDR range %q: %v", line, err)
+			}
+			set.nets = append(set.nets, ipNet)
+			continue
+		}
+
+		ip := net.ParseIP(line)
+		if ip == nil {
+			return nil, fmt.Errorf("malformed IP address %q", line)
+		}
+		set.ips[ip.String()] = true
+	}
+
+	return set, nil
+}
+
+func parseIPList(content []byte) (*ipSet, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return parseIPSet(lines)
+}
+
+func (s *ipSet) contains(ip net.IP) bool {
+	if s.ips[ip.String()] {
+		return true
+	}
+	for _, ipNet := range s.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ipReputation keeps blocked IP sets: static ones from the configuration and reloadable lists by source
+type ipReputation struct {
+	mutex sync.RWMutex
+	sets  map[string]*ipSet
+}
+
+func (ir *ipReputation) set(source string, set *ipSet) {
+	ir.mutex.Lock()
+	ir.sets[source] = set
+	ir.mutex.Unlock()
+}
+
+func (ir *ipReputation) isEmpty() bool {
+	ir.mutex.RLock()
+	defer ir.mutex.RUnlock()
+	return len(ir.sets) == 0
+}
+
+func (ir *ipReputation) blocked(ipValue string) bool {
+	ip := net.ParseIP(ipValue)
+	if ip == nil {
+		return false
+	}
+
+	ir.mutex.RLock()
+	defer ir.mutex.RUnlock()
+	for _, set := range ir.sets {
+		if set.contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package botfilter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+// rateCounter counts events per IP address in fixed time windows
+type rateCounter struct {
+	mutex       sync.Mutex
+	maxEvents   int
+	window      time.Duration
+	windowStart time.Time
+	counts      map[string]int
+}
+
+func newRateCounter(config *RateAnomalyConfig) *rateCounter {
+	if config == nil || config.MaxEventsPerIP == 0 {
+		return nil
+	}
+
+	return &rateCounter{
+		maxEvents: config.MaxEventsPerIP,
+		window:    time.Duration(config.WindowSec) * time.Second,
+		counts:    map[string]int{},
+	}
+}
+
+// exceeded increments the IP counter and returns true if the IP has sent more than max events in the current window
+func (rc *rateCounter) exceeded(ip string) bool {
+	now := timestamp.Now()
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if now.Sub(rc.windowStart) >= rc.window {
+		rc.windowStart = now
+		rc.counts = map[string]int{}
+	}
+
+	rc.counts[ip]++
+	return rc.counts[ip] > rc.maxEvents
+}
@@ -0,0 +1,15 @@
+package botfilter
+
+// defaultBotUserAgents are lower case substrings of well-known crawlers, monitoring tools and headless browsers.
+// Generic "bot" isn't used as is because it matches real devices (e.g. Cubot phones). HTTP client libraries (curl, okhttp, etc.)
+// aren't included because server-to-server integrations use them
+
+var defaultBotUserAgents = []string{
+	"bot/", "bot;", "bot)", "bot-", "+http", "crawler", "spider", "slurp", "crawling",
+	"googlebot", "bingbot", "yandexbot", "baiduspider", "duckduckbot", "applebot", "petalbot", "sogou", "exabot",
+	"facebookexternalhit", "facebot", "twitterbot", "linkedinbot", "slackbot", "telegrambot", "discordbot", "whatsapp",
+	"ahrefs", "semrush", "mj12bot", "dotbot", "bytespider", "gptbot", "ccbot", "claudebot", "perplexitybot",
+	"headlesschrome", "phantomjs", "selenium", "puppeteer", "playwright",
+	"lighthouse", "pagespeed", "gtmetrix", "pingdom", "uptimerobot", "statuscake", "site24x7", "newrelicpinger", "datadog",
+	"scrapy", "libwww-perl",
+}
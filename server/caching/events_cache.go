@@ -29,6 +29,7 @@ type EventsCache struct {
 	lastDestinationsErrors        sync.Map
 	lastTokensErrors              sync.Map
 	rateLimiters                  sync.Map
+	liveTail                      *LiveTail
 
 	doneOnce *sync.Once
 	done     chan struct{}
@@ -46,6 +47,7 @@ func NewEventsCache(enabled bool, storage meta.Storage, capacityPerTokenOrDestin
 		})
 		//return closed
 		return &EventsCache{
+			liveTail: NewLiveTail(),
 			doneOnce: doneOnce,
 			done:     done,
 		}
@@ -59,6 +61,7 @@ func NewEventsCache(enabled bool, storage meta.Storage, capacityPerTokenOrDestin
 		})
 		//return closed
 		return &EventsCache{
+			liveTail: NewLiveTail(),
 			doneOnce: doneOnce,
 			done:     done,
 		}
@@ -77,6 +80,7 @@ func NewEventsCache(enabled bool, storage meta.Storage, capacityPerTokenOrDestin
 		poolSize:                      poolSize,
 		timeWindow:                    time.Second * time.Duration(timeWindowSeconds),
 		trimInterval:                  time.Millisecond * time.Duration(trimIntervalMs),
+		liveTail:                      NewLiveTail(),
 
 		done:     done,
 		doneOnce: doneOnce,
@@ -166,6 +170,16 @@ func (ec *EventsCache) startTrimmer() {
 
 //RawEvent puts value into channel which will be read and written to storage
 func (ec *EventsCache) RawEvent(disabled bool, tokenID string, serializedPayload []byte, skipMsg string) {
+	if !disabled && ec.liveTail.HasSubscribers(meta.EventsTokenNamespace, tokenID) {
+		ec.liveTail.Publish(meta.EventsTokenNamespace, tokenID, meta.Event{
+			Original:  string(serializedPayload),
+			TokenID:   tokenID,
+			Skip:      skipMsg,
+			Timestamp: timestamp.NowUTC(),
+			UID:       uuid.New(),
+		})
+	}
+
 	if !disabled && ec.isActive() {
 		if !ec.isRateLimiterAllowed(tokenID, meta.EventsPureStatus) {
 			return
@@ -183,6 +197,16 @@ func (ec *EventsCache) RawEvent(disabled bool, tokenID string, serializedPayload
 
 //RawErrorEvent puts value into channel which will be read and written to storage
 func (ec *EventsCache) RawErrorEvent(disabled bool, tokenID string, serializedMalformedPayload []byte, err error) {
+	if !disabled && ec.liveTail.HasSubscribers(meta.EventsTokenNamespace, tokenID) {
+		ec.liveTail.Publish(meta.EventsTokenNamespace, tokenID, meta.Event{
+			Malformed: string(serializedMalformedPayload),
+			TokenID:   tokenID,
+			Error:     err.Error(),
+			Timestamp: timestamp.NowUTC(),
+			UID:       uuid.New(),
+		})
+	}
+
 	if !disabled && ec.isActive() {
 		//error goes both in general collection and dedicated errors' collection.
 		if ec.isRateLimiterAllowed(tokenID, meta.EventsPureStatus) {
@@ -208,6 +232,10 @@ func (ec *EventsCache) RawErrorEvent(disabled bool, tokenID string, serializedMa
 
 //Succeed puts value into channel which will be read and updated in storage
 func (ec *EventsCache) Succeed(eventContext *adapters.EventContext) {
+	if !eventContext.CacheDisabled {
+		ec.publishStatusEvent(eventContext.DestinationID, &statusEvent{successEventContext: eventContext})
+	}
+
 	if !eventContext.CacheDisabled && ec.isActive() {
 		if !ec.isRateLimiterAllowed(eventContext.DestinationID, meta.EventsPureStatus) {
 			return
@@ -225,6 +253,10 @@ func (ec *EventsCache) Succeed(eventContext *adapters.EventContext) {
 
 //Error puts value into channel which will be read and updated in storage
 func (ec *EventsCache) Error(cacheDisabled bool, destinationID, originEvent string, errMsg string) {
+	if !cacheDisabled {
+		ec.publishStatusEvent(destinationID, &statusEvent{originEvent: originEvent, destinationID: destinationID, error: errMsg})
+	}
+
 	if !cacheDisabled && ec.isActive() {
 		//error goes both in general collection and dedicated errors' collection.
 		if ec.isRateLimiterAllowed(destinationID, meta.EventsPureStatus) {
@@ -251,6 +283,10 @@ func (ec *EventsCache) Error(cacheDisabled bool, destinationID, originEvent stri
 
 //Skip puts value into channel which will be read and updated in storage
 func (ec *EventsCache) Skip(cacheDisabled bool, destinationID, originEvent string, errMsg string) {
+	if !cacheDisabled {
+		ec.publishStatusEvent(destinationID, &statusEvent{skip: true, originEvent: originEvent, destinationID: destinationID, error: errMsg})
+	}
+
 	if !cacheDisabled && ec.isActive() {
 		if !ec.isRateLimiterAllowed(destinationID, meta.EventsPureStatus) {
 			return
@@ -266,6 +302,23 @@ func (ec *EventsCache) Skip(cacheDisabled bool, destinationID, originEvent strin
 	}
 }
 
+//publishStatusEvent sends processed event to live tail subscribers of the destination
+//event entity is created only if there are subscribers
+func (ec *EventsCache) publishStatusEvent(destinationID string, statusEvent *statusEvent) {
+	if !ec.liveTail.HasSubscribers(meta.EventsDestinationNamespace, destinationID) {
+		return
+	}
+
+	eventEntity, err := ec.createEventEntity(statusEvent)
+	if err != nil {
+		logging.Errorf("[%s] failed to create meta event entity [%v] for live tail: %v", destinationID, statusEvent, err)
+		return
+	}
+
+	eventEntity.UID = uuid.New()
+	ec.liveTail.Publish(meta.EventsDestinationNamespace, destinationID, *eventEntity)
+}
+
 //saveTokenEvent saves raw JSON event into the storage by token
 //and saves to token error collection if error
 func (ec *EventsCache) saveTokenEvent(tokenID string, serializedPayload, serializedMalformedPayload []byte, errMsg, skipMsg, eventMetaStatus string) {
@@ -424,6 +477,11 @@ func (ec *EventsCache) createEventEntity(statusEvent *statusEvent) (*meta.Event,
 // 1. last [token, destination] namespace raw JSON events with limit
 // 2. amount of rate limited events
 func (ec *EventsCache) Get(namespace, id, status string, limit int) ([]meta.Event, uint64) {
+	if ec.storage == nil {
+		//events cache is disabled
+		return []meta.Event{}, 0
+	}
+
 	metaEvents, err := ec.storage.GetEvents(namespace, id, status, limit)
 	lastMinuteLimited := ec.getLastMinuteLimited(id, status)
 	if err != nil {
@@ -445,6 +503,11 @@ func (ec *EventsCache) GetTotal(namespace, id, status string) int {
 	return total
 }
 
+//GetLiveTail returns LiveTail for subscribing to incoming and processed events in real time
+func (ec *EventsCache) GetLiveTail() *LiveTail {
+	return ec.liveTail
+}
+
 //GetCacheCapacityAndIntervalWindow returns cache capacity and window interval seconds
 func (ec *EventsCache) GetCacheCapacityAndIntervalWindow() (int, int) {
 	return ec.capacityPerTokenOrDestination, int(ec.timeWindow.Seconds())
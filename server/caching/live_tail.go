@@ -0,0 +1,147 @@
+package caching
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"go.uber.org/atomic"
+)
+
+const tailSubscriptionBufferSize = 1000
+
+//TailFilter is a filter of live tail events
+type TailFilter struct {
+	//Status: empty value means all events, meta.EventsErrorStatus means only events with errors
+	Status string
+	//Contains is a substring which must be present in the event (original, success or error payload)
+	Contains string
+}
+
+//TailSubscription is a live tail subscriber to events of the certain token or destination ids
+type TailSubscription struct {
+	Events  chan meta.Event
+	dropped *atomic.Uint64
+
+	namespace string
+	ids       []string
+	filter    *TailFilter
+}
+
+//Dropped returns the number of events which weren't delivered because the subscriber was too slow
+func (ts *TailSubscription) Dropped() uint64 {
+	return ts.dropped.Load()
+}
+
+//Matches returns true if event passes the filter. nil filter matches all events
+func (tf *TailFilter) Matches(event *meta.Event) bool {
+	if tf == nil {
+		return true
+	}
+
+	if tf.Status == meta.EventsErrorStatus && event.Error == "" {
+		return false
+	}
+
+	if tf.Contains != "" {
+		return strings.Contains(event.Original, tf.Contains) ||
+			strings.Contains(event.Malformed, tf.Contains) ||
+			strings.Contains(event.Success, tf.Contains) ||
+			strings.Contains(event.Error, tf.Contains) ||
+			strings.Contains(event.Skip, tf.Contains)
+	}
+
+	return true
+}
+
+//LiveTail broadcasts incoming and processed events to subscribers in real time
+//works per server node: subscribers receive only events which are processed by the current node
+type LiveTail struct {
+	mutex       sync.RWMutex
+	subscribers map[string]map[*TailSubscription]bool
+}
+
+//NewLiveTail returns empty LiveTail
+func NewLiveTail() *LiveTail {
+	return &LiveTail{subscribers: map[string]map[*TailSubscription]bool{}}
+}
+
+//Subscribe returns TailSubscription to events of namespace (token or destination) ids
+//subscription must be closed with Unsubscribe
+func (lt *LiveTail) Subscribe(namespace string, ids []string, filter *TailFilter) *TailSubscription {
+	subscription := &TailSubscription{
+		Events:    make(chan meta.Event, tailSubscriptionBufferSize),
+		dropped:   atomic.NewUint64(0),
+		namespace: namespace,
+		ids:       ids,
+		filter:    filter,
+	}
+
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	for _, id := range ids {
+		key := tailKey(namespace, id)
+		subscriptions, ok := lt.subscribers[key]
+		if !ok {
+			subscriptions = map[*TailSubscription]bool{}
+			lt.subscribers[key] = subscriptions
+		}
+		subscriptions[subscription] = true
+	}
+
+	return subscription
+}
+
+//Unsubscribe removes subscription. Events channel isn't closed because it might be in use by publishers
+func (lt *LiveTail) Unsubscribe(subscription *TailSubscription) {
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	for _, id := range subscription.ids {
+		key := tailKey(subscription.namespace, id)
+		delete(lt.subscribers[key], subscription)
+		if len(lt.subscribers[key]) == 0 {
+			delete(lt.subscribers, key)
+		}
+	}
+}
+
+//HasSubscribers returns true if there is at least one subscriber to namespace id
+//it is used for skipping serialization of events which nobody listens to
+func (lt *LiveTail) HasSubscribers(namespace, id string) bool {
+	if lt == nil {
+		return false
+	}
+
+	lt.mutex.RLock()
+	defer lt.mutex.RUnlock()
+
+	return len(lt.subscribers[tailKey(namespace, id)]) > 0
+}
+
+//Publish sends event to all matching subscribers without blocking
+func (lt *LiveTail) Publish(namespace, id string, event meta.Event) {
+	if lt == nil {
+		return
+	}
+
+	lt.mutex.RLock()
+	defer lt.mutex.RUnlock()
+
+	for subscription := range lt.subscribers[tailKey(namespace, id)] {
+		if !subscription.filter.Matches(&event) {
+			continue
+		}
+
+		select {
+		case subscription.Events <- event:
+		default:
+			subscription.dropped.Inc()
+		}
+	}
+}
+
+func tailKey(namespace, id string) string {
+	return namespace + ":" + id
+}
@@ -0,0 +1,51 @@
+package caching
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveTail(t *testing.T) {
+	liveTail := NewLiveTail()
+	require.False(t, liveTail.HasSubscribers(meta.EventsTokenNamespace, "token1"))
+
+	all := liveTail.Subscribe(meta.EventsTokenNamespace, []string{"token1", "token2"}, nil)
+	errors := liveTail.Subscribe(meta.EventsTokenNamespace, []string{"token1"}, &TailFilter{Status: meta.EventsErrorStatus, Contains: "purchase"})
+	require.True(t, liveTail.HasSubscribers(meta.EventsTokenNamespace, "token1"))
+	require.False(t, liveTail.HasSubscribers(meta.EventsDestinationNamespace, "token1"))
+
+	liveTail.Publish(meta.EventsTokenNamespace, "token1", meta.Event{Original: `{"event_type":"purchase"}`})
+	liveTail.Publish(meta.EventsTokenNamespace, "token1", meta.Event{Malformed: `{"event_type":"pageview"`, Error: "malformed"})
+	liveTail.Publish(meta.EventsTokenNamespace, "token1", meta.Event{Malformed: `{"event_type":"purchase"`, Error: "malformed"})
+	liveTail.Publish(meta.EventsTokenNamespace, "token2", meta.Event{Original: `{"event_type":"purchase"}`, TokenID: "token2"})
+	liveTail.Publish(meta.EventsTokenNamespace, "token3", meta.Event{Original: `{"event_type":"purchase"}`})
+
+	require.Len(t, all.Events, 4)
+	require.Len(t, errors.Events, 1)
+	require.Equal(t, meta.Event{Malformed: `{"event_type":"purchase"`, Error: "malformed"}, <-errors.Events)
+
+	liveTail.Unsubscribe(errors)
+	require.True(t, liveTail.HasSubscribers(meta.EventsTokenNamespace, "token1"))
+	liveTail.Unsubscribe(all)
+	require.False(t, liveTail.HasSubscribers(meta.EventsTokenNamespace, "token1"))
+	require.False(t, liveTail.HasSubscribers(meta.EventsTokenNamespace, "token2"))
+
+	var nilLiveTail *LiveTail
+	require.False(t, nilLiveTail.HasSubscribers(meta.EventsTokenNamespace, "token1"))
+	nilLiveTail.Publish(meta.EventsTokenNamespace, "token1", meta.Event{})
+}
+
+func TestLiveTailSlowSubscriber(t *testing.T) {
+	liveTail := NewLiveTail()
+	subscription := liveTail.Subscribe(meta.EventsDestinationNamespace, []string{"destination1"}, nil)
+	defer liveTail.Unsubscribe(subscription)
+
+	for i := 0; i < tailSubscriptionBufferSize+10; i++ {
+		liveTail.Publish(meta.EventsDestinationNamespace, "destination1", meta.Event{DestinationID: "destination1"})
+	}
+
+	require.Len(t, subscription.Events, tailSubscriptionBufferSize)
+	require.Equal(t, uint64(10), subscription.Dropped())
+}
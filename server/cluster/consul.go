@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"github.com/hashicorp/consul/api"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"io"
+	"strings"
+)
+
+//consul key [variables] - description
+//** Heart beat **
+//${prefix}cluster/heartbeat/${serverName} - server instance added timestamp utc
+
+type ConsulManager struct {
+	serverName   string
+	heartbeatKey string
+	kv           *api.KV
+	closer       io.Closer
+}
+
+//NewConsulManager returned configured cluster.Manger based on Consul implementation
+func NewConsulManager(serverName string, client *api.Client, prefix string) *ConsulManager {
+	cm := &ConsulManager{
+		serverName:   serverName,
+		heartbeatKey: prefix + "cluster/heartbeat/",
+		kv:           client.KV(),
+	}
+
+	hb := newHeartbeat(cm)
+	hb.start()
+	cm.closer = hb
+	return cm
+}
+
+//GetInstances returns instance names list from Consul KV
+func (cm *ConsulManager) GetInstances() ([]string, error) {
+	pairs, _, err := cm.kv.List(cm.heartbeatKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []string{}
+	for _, pair := range pairs {
+		instance := strings.TrimPrefix(pair.Key, cm.heartbeatKey)
+		if isAlive(instance, string(pair.Value)) {
+			instances = append(instances, instance)
+		}
+	}
+
+	return instances, nil
+}
+
+//heartbeat writes timestamp under the server name key
+//Instances are considered alive if timestamp < now minus 120 seconds
+func (cm *ConsulManager) heartbeat() error {
+	_, err := cm.kv.Put(&api.KVPair{Key: cm.heartbeatKey + cm.serverName, Value: []byte(timestamp.NowUTC())}, nil)
+	return err
+}
+
+func (cm *ConsulManager) Close() error {
+	return cm.closer.Close()
+}
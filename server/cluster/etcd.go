@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"context"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"io"
+	"strings"
+	"time"
+)
+
+//etcd key [variables] - description
+//** Heart beat **
+//${prefix}cluster/heartbeat/${serverName} - server instance added timestamp utc
+
+const requestTimeout = 10 * time.Second
+
+type EtcdManager struct {
+	serverName   string
+	heartbeatKey string
+	client       *clientv3.Client
+	closer       io.Closer
+}
+
+//NewEtcdManager returned configured cluster.Manger based on etcd implementation
+func NewEtcdManager(serverName string, client *clientv3.Client, prefix string) *EtcdManager {
+	em := &EtcdManager{
+		serverName:   serverName,
+		heartbeatKey: prefix + "cluster/heartbeat/",
+		client:       client,
+	}
+
+	hb := newHeartbeat(em)
+	hb.start()
+	em.closer = hb
+	return em
+}
+
+//GetInstances returns instance names list from etcd
+func (em *EtcdManager) GetInstances() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	response, err := em.client.Get(ctx, em.heartbeatKey, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []string{}
+	for _, kv := range response.Kvs {
+		instance := strings.TrimPrefix(string(kv.Key), em.heartbeatKey)
+		if isAlive(instance, string(kv.Value)) {
+			instances = append(instances, instance)
+		}
+	}
+
+	return instances, nil
+}
+
+//heartbeat writes timestamp under the server name key
+//Instances are considered alive if timestamp < now minus 120 seconds
+func (em *EtcdManager) heartbeat() error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	_, err := em.client.Put(ctx, em.heartbeatKey+em.serverName, timestamp.NowUTC())
+	return err
+}
+
+func (em *EtcdManager) Close() error {
+	return em.closer.Close()
+}
@@ -3,6 +3,7 @@ package cluster
 import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
 	"time"
 )
 
@@ -46,3 +47,14 @@ func (h *heartbeat) Close() error {
 	close(h.closed)
 	return nil
 }
+
+//isAlive returns true if the instance last heartbeat time is less than 2 minutes ago
+func isAlive(instance, lastHeartbeatTime string) bool {
+	t, err := time.Parse(time.RFC3339Nano, lastHeartbeatTime)
+	if err != nil {
+		logging.SystemErrorf("error parsing instance [%s] heartbeat time [%s] string into time: %v", instance, lastHeartbeatTime, err)
+		return false
+	}
+
+	return timestamp.Now().UTC().Sub(t).Seconds() <= 120
+}
@@ -2,12 +2,10 @@ package cluster
 
 import (
 	"github.com/gomodule/redigo/redis"
-	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"io"
-	"time"
 )
 
 //redis key [variables] - description
@@ -50,14 +48,7 @@ func (rm *RedisManager) GetInstances() ([]string, error) {
 
 	instances := []string{}
 	for instance, lastHeartbeatTime := range instancesMap {
-		t, err := time.Parse(time.RFC3339Nano, lastHeartbeatTime)
-		if err != nil {
-			logging.SystemErrorf("error parsing instance [%s] heartbeat time [%s] string into time: %v", instance, lastHeartbeatTime, err)
-			continue
-		}
-
-		//account only instances with last heartbeat less than 2 minutes ago
-		if timestamp.Now().UTC().Sub(t).Seconds() <= 120 {
+		if isAlive(instance, lastHeartbeatTime) {
 			instances = append(instances, instance)
 		}
 	}
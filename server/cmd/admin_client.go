@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/spf13/cobra"
+)
+
+const adminTokenEnv = "CLUSTER_ADMIN_TOKEN"
+
+//adminClient is an HTTP client of Jitsu admin API
+type adminClient struct {
+	httpClient *http.Client
+	host       string
+	token      string
+}
+
+//adminAPIError is returned by adminClient if Jitsu responds with non 200 HTTP code
+type adminAPIError struct {
+	statusCode int
+	body       []byte
+}
+
+func (aae *adminAPIError) Error() string {
+	return fmt.Sprintf("Jitsu HTTP code: %d response: %s", aae.statusCode, string(aae.body))
+}
+
+//newAdminClient returns configured adminClient. If token is empty, it is taken from CLUSTER_ADMIN_TOKEN env variable
+func newAdminClient(host, token string) (*adminClient, error) {
+	if token == "" {
+		token = os.Getenv(adminTokenEnv)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("--admin-token flag or %s env variable is required", adminTokenEnv)
+	}
+
+	return &adminClient{
+		httpClient: &http.Client{Timeout: 3 * time.Minute},
+		host:       strings.TrimRight(host, "/"),
+		token:      token,
+	}, nil
+}
+
+//do sends the request with JSON body (if it isn't nil) and decodes JSON response into result (if it isn't nil)
+//returns *adminAPIError if response code isn't 200
+func (ac *adminClient) do(method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, ac.host+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.AdminTokenKey, ac.token)
+
+	resp, err := ac.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &adminAPIError{statusCode: resp.StatusCode, body: responseBody}
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(responseBody, result); err != nil {
+			return fmt.Errorf("error parsing Jitsu response [%s]: %v", string(responseBody), err)
+		}
+	}
+
+	return nil
+}
+
+//addAdminFlags adds --host and --admin-token flags into the command
+func addAdminFlags(command *cobra.Command, host, token *string) {
+	command.Flags().StringVar(host, "host", "http://localhost:8000", "(optional) Jitsu host")
+	command.Flags().StringVar(token, "admin-token", "", "(optional) Jitsu admin token (server.admin_token). CLUSTER_ADMIN_TOKEN env variable is used by default")
+}
+
+//printJSON writes indented JSON into stdout
+func printJSON(value interface{}) error {
+	b, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(os.Stdout, string(b))
+	return err
+}
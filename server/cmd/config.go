@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jitsucom/jitsu/server/configvalidator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	//command flags
+	configStrict bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Operations with Jitsu server configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate <config file>",
+	Short: "Validate Jitsu server YAML or JSON configuration file without starting the server",
+	Long: `Reads Jitsu server configuration file and runs the same checks as the server does on startup: unknown keys,
+required fields of destinations according to their types, mutually exclusive options, sources and api_keys.
+Prints found problems with configuration paths and exits with non-zero code if there are errors
+(or warnings with --strict flag)`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := readConfigFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		problems := configvalidator.Validate(settings)
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stdout, problem)
+		}
+
+		errs := problems.Errors()
+		if len(errs) > 0 || configStrict && len(problems) > 0 {
+			return fmt.Errorf("%s has %d error(s) and %d warning(s)", args[0], len(errs), len(problems)-len(errs))
+		}
+
+		_, err = fmt.Fprintf(os.Stdout, "%s is valid\n", args[0])
+		return err
+	},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+
+	configValidateCmd.Flags().BoolVar(&configStrict, "strict", false, "(optional) treat warnings (e.g. unknown configuration sections) as errors")
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const destinationsTestPath = "/api/v1/destinations/test"
+
+var placeholderRegexp = regexp.MustCompile(`\$\{env\.[\w_]+(?:\|[^\}]*)?\}`)
+
+var (
+	//command flags
+	destinationsHost, destinationsAdminToken, destinationID string
+	destinationsJSON                                        bool
+)
+
+//connectionTestResult is a response of destinations test admin endpoint
+type connectionTestResult struct {
+	Status string `json:"status"`
+	Steps  []struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Error      string `json:"error,omitempty"`
+		DurationMs int64  `json:"duration_ms"`
+	} `json:"steps"`
+}
+
+//connectionTestErrorResponse is a response of destinations test admin endpoint if the test has failed
+type connectionTestErrorResponse struct {
+	Message string                `json:"message"`
+	Payload *connectionTestResult `json:"payload,omitempty"`
+}
+
+var destinationsCmd = &cobra.Command{
+	Use:   "destinations",
+	Short: "Operations with Jitsu destinations via admin API",
+}
+
+var destinationsTestCmd = &cobra.Command{
+	Use:   "test [flags] <config file>",
+	Short: "Test connection to the destination via the running Jitsu server",
+	Long: `Reads destination configuration from YAML or JSON file and tests the connection via Jitsu admin API
+(connect, authenticate, create a test table, insert a test event and cleanup). The file is either a single destination
+configuration or Jitsu server configuration with 'destinations' section (use --id for choosing a destination)`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		destination, err := readDestinationConfig(args[0], destinationID)
+		if err != nil {
+			return err
+		}
+
+		client, err := newAdminClient(destinationsHost, destinationsAdminToken)
+		if err != nil {
+			return err
+		}
+
+		result := &connectionTestResult{}
+		testErr := client.do(http.MethodPost, destinationsTestPath, destination, result)
+		var message string
+		if testErr != nil {
+			apiErr, ok := testErr.(*adminAPIError)
+			if !ok || apiErr.statusCode != http.StatusBadRequest {
+				return testErr
+			}
+			errResponse := &connectionTestErrorResponse{}
+			if err := json.Unmarshal(apiErr.body, errResponse); err != nil || errResponse.Payload == nil {
+				return testErr
+			}
+			result = errResponse.Payload
+			message = errResponse.Message
+		}
+
+		if destinationsJSON {
+			if err := printJSON(result); err != nil {
+				return err
+			}
+		} else {
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(writer, "STEP\tSTATUS\tDURATION\tERROR")
+			for _, step := range result.Steps {
+				fmt.Fprintf(writer, "%s\t%s\t%dms\t%s\n", step.Name, step.Status, step.DurationMs, step.Error)
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+		}
+
+		if testErr != nil {
+			return fmt.Errorf("destination test has failed: %s", message)
+		}
+
+		return nil
+	},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(destinationsCmd)
+	destinationsCmd.AddCommand(destinationsTestCmd)
+
+	addAdminFlags(destinationsTestCmd, &destinationsHost, &destinationsAdminToken)
+	destinationsTestCmd.Flags().StringVar(&destinationID, "id", "", "(optional) destination ID if the file is Jitsu server configuration with several destinations")
+	destinationsTestCmd.Flags().BoolVar(&destinationsJSON, "json", false, "(optional) print result as JSON")
+}
+
+//readDestinationConfig returns destination configuration from the YAML or JSON file. If the file has 'destinations' section,
+//the destination is chosen by id (it can be omitted if the section contains only one destination)
+func readDestinationConfig(filePath, id string) (map[string]interface{}, error) {
+	settings, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	destinations, ok := settings["destinations"].(map[string]interface{})
+	if !ok {
+		if id != "" {
+			return nil, fmt.Errorf("%s doesn't have 'destinations' section", filePath)
+		}
+		return settings, nil
+	}
+
+	if id == "" {
+		if len(destinations) != 1 {
+			ids := make([]string, 0, len(destinations))
+			for destinationID := range destinations {
+				ids = append(ids, destinationID)
+			}
+			sort.Strings(ids)
+			return nil, fmt.Errorf("--id is required. Destinations in %s: %s", filePath, strings.Join(ids, ", "))
+		}
+		for destinationID := range destinations {
+			id = destinationID
+		}
+	}
+
+	destination, ok := destinations[id].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("destination [%s] isn't found in %s", id, filePath)
+	}
+
+	return destination, nil
+}
+
+//readConfigFile returns all settings of YAML or JSON file with resolved ${env.VAR|default} placeholders
+func readConfigFile(filePath string) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigFile(filePath)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", filePath, err)
+	}
+
+	settings := v.AllSettings()
+	if len(settings) == 0 {
+		return nil, errors.New(filePath + " is empty")
+	}
+
+	return resolvePlaceholders(settings).(map[string]interface{}), nil
+}
+
+//resolvePlaceholders replaces ${env.VAR1|env.VAR2|default} placeholders in string values with the first set env variable
+//or the default value as the server does on startup. Placeholders which can't be resolved are kept
+func resolvePlaceholders(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		if !placeholderRegexp.MatchString(typed) {
+			return typed
+		}
+		return placeholderRegexp.ReplaceAllStringFunc(typed, func(placeholder string) string {
+			expression := strings.TrimSuffix(strings.TrimPrefix(placeholder, "${"), "}")
+			for _, alternative := range strings.Split(expression, "|") {
+				if !strings.HasPrefix(alternative, "env.") {
+					return alternative
+				}
+				if envValue := os.Getenv(strings.TrimPrefix(alternative, "env.")); envValue != "" {
+					return envValue
+				}
+			}
+			return placeholder
+		})
+	case map[string]interface{}:
+		for k, v := range typed {
+			typed[k] = resolvePlaceholders(v)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range typed {
+			typed[k] = resolvePlaceholders(v)
+		}
+	case []interface{}:
+		for i, v := range typed {
+			typed[i] = resolvePlaceholders(v)
+		}
+	}
+
+	return value
+}
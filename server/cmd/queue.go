@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logevents"
+	"github.com/spf13/cobra"
+)
+
+const (
+	//queue kinds of local events files
+	incomingQueueKind      = "incoming"
+	failedQueueKind        = "failed"
+	writeAheadLogQueueKind = "write-ahead-log"
+
+	queueFileTimeLayout = "2006-01-02T15-04-05"
+)
+
+var (
+	queueFileRegexp = regexp.MustCompile(`^(incoming\.tok|failed\.dst|write-ahead-log)=?(.*?)(?:-(\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2})(?:\.\d{3})?)?\.log(?:\.gz)?$`)
+
+	//command flags
+	queueDir  string
+	queueJSON bool
+)
+
+//QueueSummary is a summary of local events files of one kind and owner (API key or destination)
+type QueueSummary struct {
+	Kind   string `json:"kind"`
+	Owner  string `json:"owner,omitempty"`
+	Files  int    `json:"files"`
+	Events int    `json:"events"`
+	Bytes  int64  `json:"bytes"`
+	//Oldest is the time of the oldest rotated file. Nil if there are only files which are being written
+	Oldest *time.Time `json:"oldest,omitempty"`
+}
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Operations with local events queue files",
+}
+
+var queueInspectCmd = &cobra.Command{
+	Use:   "inspect [flags]",
+	Short: "Show pending batch files, failed events and write-ahead log of the server",
+	Long: `Reads local events files of Jitsu server (log.path directory): incoming events which haven't been uploaded into
+batch destinations yet, failed events and write-ahead log. Prints amount of files and events per API key or destination`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		summaries, err := inspectQueue(queueDir)
+		if err != nil {
+			return err
+		}
+
+		if queueJSON {
+			return printJSON(summaries)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "KIND\tOWNER\tFILES\tEVENTS\tBYTES\tOLDEST")
+		for _, summary := range summaries {
+			oldest := "-"
+			if summary.Oldest != nil {
+				oldest = summary.Oldest.Format(time.RFC3339)
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%d\t%s\n", summary.Kind, summary.Owner, summary.Files, summary.Events, summary.Bytes, oldest)
+		}
+		return writer.Flush()
+	},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueInspectCmd)
+
+	queueInspectCmd.Flags().StringVar(&queueDir, "dir", "/home/eventnative/data/logs/events", "(optional) Jitsu events log directory (log.path)")
+	queueInspectCmd.Flags().BoolVar(&queueJSON, "json", false, "(optional) print result as JSON")
+}
+
+//inspectQueue returns summaries of incoming, failed and write-ahead log files in the directory sorted by kind and owner
+func inspectQueue(dir string) ([]*QueueSummary, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("error reading events directory: %v", err)
+	}
+
+	summaries := map[string]*QueueSummary{}
+	for _, subDir := range []string{logevents.IncomingDir, logevents.FailedDir} {
+		files, err := filepath.Glob(filepath.Join(dir, subDir, "*.log*"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, filePath := range files {
+			kind, owner, fileTime, ok := parseQueueFileName(filepath.Base(filePath))
+			if !ok {
+				continue
+			}
+
+			info, err := os.Stat(filePath)
+			if err != nil {
+				return nil, err
+			}
+			lines, err := countLines(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s: %v", filePath, err)
+			}
+
+			key := kind + "/" + owner
+			summary, ok := summaries[key]
+			if !ok {
+				summary = &QueueSummary{Kind: kind, Owner: owner}
+				summaries[key] = summary
+			}
+			summary.Files++
+			summary.Events += lines
+			summary.Bytes += info.Size()
+			if fileTime != nil && (summary.Oldest == nil || fileTime.Before(*summary.Oldest)) {
+				summary.Oldest = fileTime
+			}
+		}
+	}
+
+	result := make([]*QueueSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Owner < result[j].Owner
+	})
+
+	return result, nil
+}
+
+//parseQueueFileName returns kind, owner (API key or destination ID) and time of rotated file
+//(nil if the file is being written now). Returns false if the file isn't an events file
+func parseQueueFileName(name string) (string, string, *time.Time, bool) {
+	parts := queueFileRegexp.FindStringSubmatch(name)
+	if parts == nil {
+		return "", "", nil, false
+	}
+
+	var kind string
+	switch parts[1] {
+	case "incoming.tok":
+		kind = incomingQueueKind
+	case "failed.dst":
+		kind = failedQueueKind
+	default:
+		kind = writeAheadLogQueueKind
+	}
+
+	var fileTime *time.Time
+	if parts[3] != "" {
+		if t, err := time.Parse(queueFileTimeLayout, parts[3]); err == nil {
+			fileTime = &t
+		}
+	}
+
+	return kind, parts[2], fileTime, true
+}
+
+//countLines returns amount of lines (events) in the file. Gzipped files are decompressed
+func countLines(filePath string) (int, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(filePath, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	lines := 0
+	bufReader := bufio.NewReader(reader)
+	for {
+		line, err := bufReader.ReadSlice('\n')
+		if len(line) > 0 && err != bufio.ErrBufferFull {
+			lines++
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQueueFileName(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedKind  string
+		expectedOwner string
+		expectedTime  string
+		expectedOk    bool
+	}{
+		{"incoming.tok=token1-2022-03-01T10-00-00.000.log", incomingQueueKind, "token1", "2022-03-01T10:00:00Z", true},
+		{"incoming.tok=token-with-dashes.log", incomingQueueKind, "token-with-dashes", "", true},
+		{"failed.dst=dest1-2022-03-01T10-00-00.000.log.gz", failedQueueKind, "dest1", "2022-03-01T10:00:00Z", true},
+		{"write-ahead-log-2022-03-01T10-00-00.000.log", writeAheadLogQueueKind, "", "2022-03-01T10:00:00Z", true},
+		{"cli_state.state", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, owner, fileTime, ok := parseQueueFileName(tt.name)
+			require.Equal(t, tt.expectedOk, ok)
+			require.Equal(t, tt.expectedKind, kind)
+			require.Equal(t, tt.expectedOwner, owner)
+			if tt.expectedTime == "" {
+				require.Nil(t, fileTime)
+			} else {
+				require.Equal(t, tt.expectedTime, fileTime.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestInspectQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "incoming"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "incoming", "incoming.tok=token1-2022-03-01T10-00-00.000.log"), []byte("{}\n{}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "incoming", "incoming.tok=token1.log"), []byte("{}"), 0644))
+
+	summaries, err := inspectQueue(dir)
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	require.Equal(t, incomingQueueKind, summaries[0].Kind)
+	require.Equal(t, "token1", summaries[0].Owner)
+	require.Equal(t, 2, summaries[0].Files)
+	require.Equal(t, 3, summaries[0].Events)
+	require.Equal(t, "2022-03-01T10:00:00Z", summaries[0].Oldest.Format(time.RFC3339))
+
+	_, err = inspectQueue(filepath.Join(dir, "unknown"))
+	require.Error(t, err)
+}
@@ -45,6 +45,7 @@ var replayCmd = &cobra.Command{
 	Short: "CLI for uploading data from local files into Jitsu destinations via API",
 	Long:  `Jitsu CLI tool for bulk uploading files with events into Jitsu. Common use case: upload archive logs (aka replay)`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		logWelcomeBanner(version)
 		if os.Getenv("SERVER_TELEMETRY_DISABLED_USAGE") != "true" {
 			var cs int64
 			if chunkSize != maxChunkSize {
@@ -15,8 +15,8 @@ var version = ""
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:    "",
-	Short:  "Jitsu CLI tool for bulk uploading files with events into Jitsu",
-	Long:   `Jitsu CLI tool for bulk uploading files with events into Jitsu. Common use case: upload archive logs (aka replay)`,
+	Short:  "Jitsu CLI tool for bulk uploading files with events into Jitsu and operational tasks",
+	Long:   `Jitsu CLI tool for bulk uploading files with events into Jitsu (aka replay) and operational tasks: local queue inspection, destinations testing, API keys issuing and configuration validation`,
 	Hidden: true,
 }
 
@@ -24,7 +24,6 @@ var rootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute(tag string) {
 	version = tag
-	logWelcomeBanner(version)
 	if os.Getenv("SERVER_TELEMETRY_DISABLED_USAGE") != "true" {
 		telemetry.Init(serviceName, "", version, "", "")
 	}
@@ -36,4 +35,15 @@ func Execute(tag string) {
 	}
 }
 
+//IsCommand returns true if the name is a CLI command and the binary should be run as CLI instead of the server
+func IsCommand(name string) bool {
+	for _, command := range rootCmd.Commands() {
+		if command.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func init() {}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const secretCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+var (
+	//command flags
+	tokenProject, tokenID string
+	tokenOrigins          []string
+	tokenJSON             bool
+)
+
+//issuedToken is an API key in the format of Jitsu server api_keys configuration
+type issuedToken struct {
+	ID           string   `json:"id"`
+	ClientSecret string   `json:"client_secret"`
+	ServerSecret string   `json:"server_secret"`
+	Origins      []string `json:"origins,omitempty"`
+}
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Operations with Jitsu API keys",
+}
+
+var tokensIssueCmd = &cobra.Command{
+	Use:   "issue [flags]",
+	Short: "Generate a new API key (client and server secrets) for Jitsu server configuration",
+	Long: `Generates a new API key with the same format as Jitsu Configurator does (js.<project>.<random> client secret and
+s2s.<project>.<random> server secret) and prints it as api_keys YAML section (or JSON with --json flag)`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, err := issueToken(tokenProject, tokenID, tokenOrigins)
+		if err != nil {
+			return err
+		}
+
+		if tokenJSON {
+			return printJSON(token)
+		}
+
+		_, err = fmt.Fprint(os.Stdout, token.yaml())
+		return err
+	},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(tokensCmd)
+	tokensCmd.AddCommand(tokensIssueCmd)
+
+	tokensIssueCmd.Flags().StringVar(&tokenProject, "project", "", "(optional) project prefix of the API key ID and secrets. Random if missing")
+	tokensIssueCmd.Flags().StringVar(&tokenID, "id", "", "(optional) API key ID. <project>.<random> by default")
+	tokensIssueCmd.Flags().StringSliceVar(&tokenOrigins, "origins", nil, "(optional) comma separated list of origins which are allowed to send events with the client secret")
+	tokensIssueCmd.Flags().BoolVar(&tokenJSON, "json", false, "(optional) print result as JSON")
+}
+
+//issueToken returns a new token with random secrets
+func issueToken(project, id string, origins []string) (*issuedToken, error) {
+	if project == "" {
+		random, err := randomLowerString(8)
+		if err != nil {
+			return nil, err
+		}
+		project = random
+	}
+	if strings.ContainsAny(project, ". ") {
+		return nil, fmt.Errorf("project [%s] mustn't contain dots and spaces", project)
+	}
+
+	if id == "" {
+		random, err := randomLowerString(6)
+		if err != nil {
+			return nil, err
+		}
+		id = project + "." + random
+	}
+
+	clientSecret, err := randomLowerString(21)
+	if err != nil {
+		return nil, err
+	}
+	serverSecret, err := randomLowerString(21)
+	if err != nil {
+		return nil, err
+	}
+
+	return &issuedToken{
+		ID:           id,
+		ClientSecret: "js." + project + "." + clientSecret,
+		ServerSecret: "s2s." + project + "." + serverSecret,
+		Origins:      origins,
+	}, nil
+}
+
+//yaml returns api_keys configuration section with the token
+func (it *issuedToken) yaml() string {
+	var sb strings.Builder
+	sb.WriteString("api_keys:\n")
+	sb.WriteString("  - id: " + strconv.Quote(it.ID) + "\n")
+	sb.WriteString("    client_secret: " + strconv.Quote(it.ClientSecret) + "\n")
+	sb.WriteString("    server_secret: " + strconv.Quote(it.ServerSecret) + "\n")
+	if len(it.Origins) > 0 {
+		sb.WriteString("    origins:\n")
+		for _, origin := range it.Origins {
+			sb.WriteString("      - " + strconv.Quote(origin) + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+//randomLowerString returns cryptographically secure random string of lower case letters and digits
+func randomLowerString(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(secretCharset)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("error generating random string: %v", err)
+		}
+		b[i] = secretCharset[n.Int64()]
+	}
+
+	return string(b), nil
+}
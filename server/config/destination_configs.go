@@ -2,10 +2,15 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
+	"github.com/jitsucom/jitsu/server/consent"
+	"github.com/jitsucom/jitsu/server/dataprotection"
 	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/iphandling"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/utils"
 	"github.com/mitchellh/mapstructure"
@@ -13,20 +18,33 @@ import (
 
 // DestinationConfig is a destination configuration for serialization
 type DestinationConfig struct {
-	OnlyTokens             []string                 `mapstructure:"only_tokens" json:"only_tokens,omitempty" yaml:"only_tokens,omitempty"`
-	Type                   string                   `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
-	Package                string                   `mapstructure:"package" json:"package,omitempty" yaml:"package,omitempty"`
-	Mode                   string                   `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
-	StreamingThreadsCount  int                      `mapstructure:"streaming_threads_count" json:"streaming_threads_count,omitempty" yaml:"streaming_threads_count,omitempty"`
-	DataLayout             *DataLayout              `mapstructure:"data_layout,omitempty" json:"data_layout,omitempty" yaml:"data_layout,omitempty"`
-	UsersRecognition       *UsersRecognition        `mapstructure:"users_recognition" json:"users_recognition,omitempty" yaml:"users_recognition,omitempty"`
-	Enrichment             []*enrichment.RuleConfig `mapstructure:"enrichment" json:"enrichment,omitempty" yaml:"enrichment,omitempty"`
-	Log                    *logging.SQLDebugConfig  `mapstructure:"log" json:"log,omitempty" yaml:"log,omitempty"`
-	BreakOnError           bool                     `mapstructure:"break_on_error" json:"break_on_error,omitempty" yaml:"break_on_error,omitempty"`
-	Staged                 bool                     `mapstructure:"staged" json:"staged,omitempty" yaml:"staged,omitempty"`
-	CachingConfiguration   *CachingConfiguration    `mapstructure:"caching" json:"caching,omitempty" yaml:"caching,omitempty"`
-	PostHandleDestinations []string                 `mapstructure:"post_handle_destinations,omitempty" json:"post_handle_destinations,omitempty" yaml:"post_handle_destinations,omitempty"`
-	GeoDataResolverID      string                   `mapstructure:"geo_data_resolver_id" json:"geo_data_resolver_id,omitempty" yaml:"geo_data_resolver_id,omitempty"`
+	OnlyTokens             []string                     `mapstructure:"only_tokens" json:"only_tokens,omitempty" yaml:"only_tokens,omitempty"`
+	Type                   string                       `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Package                string                       `mapstructure:"package" json:"package,omitempty" yaml:"package,omitempty"`
+	Mode                   string                       `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+	StreamingThreadsCount  int                          `mapstructure:"streaming_threads_count" json:"streaming_threads_count,omitempty" yaml:"streaming_threads_count,omitempty"`
+	DataLayout             *DataLayout                  `mapstructure:"data_layout,omitempty" json:"data_layout,omitempty" yaml:"data_layout,omitempty"`
+	UsersRecognition       *UsersRecognition            `mapstructure:"users_recognition" json:"users_recognition,omitempty" yaml:"users_recognition,omitempty"`
+	Enrichment             []*enrichment.RuleConfig     `mapstructure:"enrichment" json:"enrichment,omitempty" yaml:"enrichment,omitempty"`
+	EnrichmentPipeline     []*enrichment.EnricherConfig `mapstructure:"enrichment_pipeline" json:"enrichment_pipeline,omitempty" yaml:"enrichment_pipeline,omitempty"`
+	Log                    *logging.SQLDebugConfig      `mapstructure:"log" json:"log,omitempty" yaml:"log,omitempty"`
+	BreakOnError           bool                         `mapstructure:"break_on_error" json:"break_on_error,omitempty" yaml:"break_on_error,omitempty"`
+	Staged                 bool                         `mapstructure:"staged" json:"staged,omitempty" yaml:"staged,omitempty"`
+	CachingConfiguration   *CachingConfiguration        `mapstructure:"caching" json:"caching,omitempty" yaml:"caching,omitempty"`
+	PostHandleDestinations []string                     `mapstructure:"post_handle_destinations,omitempty" json:"post_handle_destinations,omitempty" yaml:"post_handle_destinations,omitempty"`
+	GeoDataResolverID      string                       `mapstructure:"geo_data_resolver_id" json:"geo_data_resolver_id,omitempty" yaml:"geo_data_resolver_id,omitempty"`
+	Queue                  *QueueConfiguration          `mapstructure:"queue" json:"queue,omitempty" yaml:"queue,omitempty"`
+	StreamBatch            *StreamBatch                 `mapstructure:"stream_batch" json:"stream_batch,omitempty" yaml:"stream_batch,omitempty"`
+	ScriptLimits           *ScriptLimits                `mapstructure:"script_limits" json:"script_limits,omitempty" yaml:"script_limits,omitempty"`
+	SQLTransformations     *SQLTransformations          `mapstructure:"sql_transformations" json:"sql_transformations,omitempty" yaml:"sql_transformations,omitempty"`
+	DataProtection         *dataprotection.Config       `mapstructure:"data_protection" json:"data_protection,omitempty" yaml:"data_protection,omitempty"`
+	Erasure                *Erasure                     `mapstructure:"erasure" json:"erasure,omitempty" yaml:"erasure,omitempty"`
+	Retention              *Retention                   `mapstructure:"retention" json:"retention,omitempty" yaml:"retention,omitempty"`
+	Consent                *consent.Config              `mapstructure:"consent" json:"consent,omitempty" yaml:"consent,omitempty"`
+	IPHandling             *iphandling.Config           `mapstructure:"ip_handling" json:"ip_handling,omitempty" yaml:"ip_handling,omitempty"`
+	Filter                 string                       `mapstructure:"filter" json:"filter,omitempty" yaml:"filter,omitempty"`
+	StagingFormat          string                       `mapstructure:"staging_format" json:"staging_format,omitempty" yaml:"staging_format,omitempty"`
+	Mirror                 *Mirror                      `mapstructure:"mirror" json:"mirror,omitempty" yaml:"mirror,omitempty"`
 
 	//Deprecated
 	DataSource map[string]interface{} `mapstructure:"datasource,omitempty" json:"datasource,omitempty" yaml:"datasource,omitempty"`
@@ -96,12 +114,32 @@ type DataLayout struct {
 
 	TransformEnabled *bool  `mapstructure:"transform_enabled" json:"transform_enabled,omitempty" yaml:"transform_enabled,omitempty"`
 	Transform        string `mapstructure:"transform" json:"transform,omitempty" yaml:"transform,omitempty"`
+	//TransformLanguage is a language of Transform code: "javascript" (default), "python" or "wasm" (Transform is a path to .wasm module)
+	TransformLanguage string `mapstructure:"transform_language" json:"transform_language,omitempty" yaml:"transform_language,omitempty"`
+	//TransformDependencies are npm dependencies (package name -> version) of JavaScript transform available via require()
+	TransformDependencies map[string]string `mapstructure:"transform_dependencies" json:"transform_dependencies,omitempty" yaml:"transform_dependencies,omitempty"`
 	//Deprecated
 	Mappings          *Mapping `mapstructure:"mappings" json:"mappings,omitempty" yaml:"mappings,omitempty"`
 	MaxColumns        int      `mapstructure:"max_columns" json:"max_columns,omitempty" yaml:"max_columns,omitempty"`
 	TableNameTemplate string   `mapstructure:"table_name_template" json:"table_name_template,omitempty" yaml:"table_name_template,omitempty"`
 	PrimaryKeyFields  []string `mapstructure:"primary_key_fields" json:"primary_key_fields,omitempty" yaml:"primary_key_fields,omitempty"`
 	UniqueIDField     string   `mapstructure:"unique_id_field" json:"unique_id_field,omitempty" yaml:"unique_id_field,omitempty"`
+	//TablePrimaryKeyFields is a map of table name -> primary key fields which are used instead of PrimaryKeyFields for the table
+	TablePrimaryKeyFields map[string][]string `mapstructure:"table_primary_key_fields" json:"table_primary_key_fields,omitempty" yaml:"table_primary_key_fields,omitempty"`
+	//ColumnTypes is a map of column name (or JSON path) -> SQL type which is used instead of inferred type
+	ColumnTypes map[string]string `mapstructure:"column_types" json:"column_types,omitempty" yaml:"column_types,omitempty"`
+	//ColumnTypesMigration is a mode of widening existing columns types: "" (disabled), "dry_run" or "enabled"
+	ColumnTypesMigration string `mapstructure:"column_types_migration" json:"column_types_migration,omitempty" yaml:"column_types_migration,omitempty"`
+	//TablePartitioning is a map of table name ("*" for all tables) -> partitioning and clustering applied on table creation
+	TablePartitioning map[string]*TablePartitioning `mapstructure:"table_partitioning" json:"table_partitioning,omitempty" yaml:"table_partitioning,omitempty"`
+}
+
+// TablePartitioning is a model for table partitioning and clustering configuration
+type TablePartitioning struct {
+	PartitionBy string   `mapstructure:"partition_by" json:"partition_by,omitempty" yaml:"partition_by,omitempty"`
+	ClusterBy   []string `mapstructure:"cluster_by" json:"cluster_by,omitempty" yaml:"cluster_by,omitempty"`
+	SortKeys    []string `mapstructure:"sort_keys" json:"sort_keys,omitempty" yaml:"sort_keys,omitempty"`
+	DistKey     string   `mapstructure:"dist_key" json:"dist_key,omitempty" yaml:"dist_key,omitempty"`
 }
 
 // UsersRecognition is a model for Users recognition module configuration
@@ -120,6 +158,99 @@ type CachingConfiguration struct {
 	Disabled bool `mapstructure:"disabled" json:"disabled" yaml:"disabled"`
 }
 
+// QueueConfiguration is a configuration for destination events queue size limit (streaming mode)
+type QueueConfiguration struct {
+	MaxSize        int64  `mapstructure:"max_size" json:"max_size,omitempty" yaml:"max_size,omitempty"`
+	OverflowPolicy string `mapstructure:"overflow_policy" json:"overflow_policy,omitempty" yaml:"overflow_policy,omitempty"`
+}
+
+// StreamBatch is a configuration of stream mode micro-batching in SQL destinations: events are accumulated per table
+// and written with one multi-row insert when Size events are collected or every FlushIntervalMs
+type StreamBatch struct {
+	Size            int `mapstructure:"size" json:"size,omitempty" yaml:"size,omitempty"`
+	FlushIntervalMs int `mapstructure:"flush_interval_ms" json:"flush_interval_ms,omitempty" yaml:"flush_interval_ms,omitempty"`
+}
+
+// IsEnabled returns true if more than 1 event is configured to be written at once
+func (sb *StreamBatch) IsEnabled() bool {
+	return sb != nil && sb.Size > 1
+}
+
+// FlushInterval returns configured interval or 1 second by default
+func (sb *StreamBatch) FlushInterval() time.Duration {
+	if sb == nil || sb.FlushIntervalMs <= 0 {
+		return time.Second
+	}
+	return time.Duration(sb.FlushIntervalMs) * time.Millisecond
+}
+
+// SQLTransformations is a configuration of SQL models which are materialized in the destination warehouse on schedule
+type SQLTransformations struct {
+	Schedule string     `mapstructure:"schedule" json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	Models   []SQLModel `mapstructure:"models" json:"models,omitempty" yaml:"models,omitempty"`
+}
+
+// SQLModel is a SELECT statement materialized as a table or a view. Models are run after models from DependsOn
+type SQLModel struct {
+	Name         string   `mapstructure:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	SQL          string   `mapstructure:"sql" json:"sql,omitempty" yaml:"sql,omitempty"`
+	Materialized string   `mapstructure:"materialized" json:"materialized,omitempty" yaml:"materialized,omitempty"`
+	DependsOn    []string `mapstructure:"depends_on" json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+}
+
+// Erasure is a configuration of personal data erasure (right to be forgotten) in SQL destination tables.
+// Empty fields are taken from the global erasure configuration
+type Erasure struct {
+	Disabled bool     `mapstructure:"disabled" json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Tables   []string `mapstructure:"tables" json:"tables,omitempty" yaml:"tables,omitempty"`
+	Columns  []string `mapstructure:"columns" json:"columns,omitempty" yaml:"columns,omitempty"`
+	Mode     string   `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+// Retention is a configuration of data retention in destination tables (SQL destinations) or files (S3):
+// data older than Days is deleted periodically. Empty fields are taken from the global retention configuration
+type Retention struct {
+	Disabled bool     `mapstructure:"disabled" json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Days     int      `mapstructure:"days" json:"days,omitempty" yaml:"days,omitempty"`
+	Tables   []string `mapstructure:"tables" json:"tables,omitempty" yaml:"tables,omitempty"`
+	Column   string   `mapstructure:"column" json:"column,omitempty" yaml:"column,omitempty"`
+}
+
+// Mirror is a configuration of a mirror destination (type: mirror): a logical destination which writes every event
+// into all Destinations (e.g. EU and US warehouses). Each destination has its own queue, retries and statistics
+type Mirror struct {
+	Destinations []string `mapstructure:"destinations" json:"destinations,omitempty" yaml:"destinations,omitempty"`
+}
+
+// Validate returns err if the mirror of mirrorID has less than 2 destinations, duplicates or refers to itself
+func (m *Mirror) Validate(mirrorID string) error {
+	if len(m.Destinations) < 2 {
+		return errors.New("mirror must contain at least 2 destinations")
+	}
+
+	unique := map[string]bool{}
+	for _, destinationID := range m.Destinations {
+		if destinationID == mirrorID {
+			return errors.New("mirror can't contain itself")
+		}
+		if unique[destinationID] {
+			return fmt.Errorf("destination [%s] is duplicated", destinationID)
+		}
+		unique[destinationID] = true
+	}
+
+	return nil
+}
+
+// ScriptLimits is a configuration of resource limits of destination transformation and plugin scripts
+// limits are applied per destination: events of all API keys are transformed by the same script instance
+type ScriptLimits struct {
+	MaxMemoryMB       int    `mapstructure:"max_memory_mb" json:"max_memory_mb,omitempty" yaml:"max_memory_mb,omitempty"`
+	TimeoutMs         int    `mapstructure:"timeout_ms" json:"timeout_ms,omitempty" yaml:"timeout_ms,omitempty"`
+	OutOfMemoryPolicy string `mapstructure:"oom_policy" json:"oom_policy,omitempty" yaml:"oom_policy,omitempty"`
+	MaxRetries        int    `mapstructure:"max_retries" json:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+}
+
 // IsEnabled returns true if enabled
 func (ur *UsersRecognition) IsEnabled() bool {
 	return ur != nil && ur.Enabled
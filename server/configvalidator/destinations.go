@@ -0,0 +1,240 @@
+package configvalidator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/filtering"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/templates"
+)
+
+var (
+	destinationModes     = []string{storages.BatchMode, storages.StreamMode, storages.SynchronousMode}
+	transformLanguages   = []string{"", "javascript", templates.PythonRuntime, templates.WasmRuntime}
+	columnTypesMigration = []string{storages.ColumnTypesMigrationDisabled, storages.ColumnTypesMigrationDryRun, storages.ColumnTypesMigrationEnabled}
+	overflowPolicies     = []string{"", events.QueueOverflowDropNew, events.QueueOverflowDropOldest, events.QueueOverflowBlock, events.QueueOverflowSpill}
+)
+
+//validateDestination checks the destination configuration and its type specific configuration
+func (v *validator) validateDestination(id string, value interface{}) {
+	path := destinationsKey + "." + id
+	raw, ok := value.(map[string]interface{})
+	if !ok {
+		v.errorf(path, "must be an object")
+		return
+	}
+
+	destination := &config.DestinationConfig{}
+	if !v.decode(path, raw, destination, true) {
+		return
+	}
+
+	if destination.Type == storages.MirrorType {
+		v.validateMirror(path, id, destination)
+		return
+	}
+
+	//destination ID is used as the type if the type isn't set
+	destinationType := destination.Type
+	if destinationType == "" {
+		destinationType = id
+	}
+	typeConfig, ok := storages.GetTypeConfig(destinationType)
+	if !ok {
+		if destination.Type == "" {
+			v.errorf(path+".type", "is required")
+		} else {
+			v.errorf(path+".type", "unknown destination type [%s]. Supported: [%s]", destination.Type, strings.Join(supportedTypes(), ", "))
+		}
+		return
+	}
+
+	if destination.Mode != "" && !contains(destinationModes, destination.Mode) {
+		v.errorf(path+".mode", "unknown mode [%s]. Supported: [%s]", destination.Mode, strings.Join(destinationModes, ", "))
+	} else if len(typeConfig.Modes) > 0 {
+		mode := destination.Mode
+		if mode == "" {
+			mode = storages.BatchMode
+		}
+		if !contains(typeConfig.Modes, mode) {
+			v.errorf(path+".mode", "%s destination doesn't support %s mode. Supported: [%s]", destinationType, mode, strings.Join(typeConfig.Modes, ", "))
+		}
+	}
+
+	if destinationType == storages.NpmType && destination.Package == "" {
+		v.errorf(path+".package", "is required for %s destination", storages.NpmType)
+	}
+
+	v.validateTypeConfig(path, raw, destination, typeConfig)
+	for _, options := range typeConfig.Exclusive {
+		v.checkExclusive(path, raw, options...)
+	}
+
+	if dataLayout := destination.DataLayout; dataLayout != nil {
+		if !contains(transformLanguages, dataLayout.TransformLanguage) {
+			v.errorf(path+".data_layout.transform_language", "unknown language [%s]. Supported: [%s]", dataLayout.TransformLanguage, strings.Join(transformLanguages[1:], ", "))
+		}
+		if !contains(columnTypesMigration, dataLayout.ColumnTypesMigration) {
+			v.errorf(path+".data_layout.column_types_migration", "unknown mode [%s]. Supported: [%s]", dataLayout.ColumnTypesMigration, strings.Join(columnTypesMigration[1:], ", "))
+		}
+		v.checkExclusive(path, raw, "data_layout.mapping", "data_layout.mappings")
+		mappingEnabled := len(dataLayout.Mapping) > 0 || dataLayout.Mappings != nil && len(dataLayout.Mappings.Fields) > 0
+		transformEnabled := dataLayout.Transform != "" && dataLayout.Transform != templates.TransformDefaultTemplate &&
+			(dataLayout.TransformEnabled == nil || *dataLayout.TransformEnabled)
+		if mappingEnabled && transformEnabled {
+			v.errorf(path+".data_layout", "mappings (deprecated) and transform are mutually exclusive. Set transform_enabled: false or remove mappings")
+		}
+	}
+
+	if destination.Queue != nil && !contains(overflowPolicies, destination.Queue.OverflowPolicy) {
+		v.errorf(path+".queue.overflow_policy", "unknown policy [%s]. Supported: [%s]", destination.Queue.OverflowPolicy, strings.Join(overflowPolicies[1:], ", "))
+	}
+	if destination.UsersRecognition != nil {
+		if err := destination.UsersRecognition.Validate(); err != nil {
+			v.errorf(path, "%v", err)
+		}
+	}
+	if destination.Retention != nil && destination.Retention.Days < 0 {
+		v.errorf(path+".retention.days", "can't be negative")
+	}
+	if err := filtering.Validate(destination.Filter); err != nil {
+		v.errorf(path+".filter", "%v", err)
+	}
+	if _, err := typeConfig.StagingFormat(destination.StagingFormat); err != nil {
+		v.errorf(path+".staging_format", "%v", err)
+	}
+}
+
+//validateMirror checks mirror section. Members are checked in validateMirrorMembers
+func (v *validator) validateMirror(path, id string, destination *config.DestinationConfig) {
+	if destination.Mirror == nil {
+		v.errorf(path+".mirror", "is required for %s destination", storages.MirrorType)
+		return
+	}
+
+	if err := destination.Mirror.Validate(id); err != nil {
+		v.errorf(path+".mirror.destinations", "%v", err)
+		return
+	}
+
+	v.mirrors[id] = destination.Mirror.Destinations
+}
+
+//validateMirrorMembers checks that mirrors members are configured and aren't mirrors
+func (v *validator) validateMirrorMembers(destinations map[string]interface{}) {
+	mirrorIDs := make([]string, 0, len(v.mirrors))
+	for id := range v.mirrors {
+		mirrorIDs = append(mirrorIDs, id)
+	}
+	sort.Strings(mirrorIDs)
+
+	for _, id := range mirrorIDs {
+		for i, memberID := range v.mirrors[id] {
+			path := fmt.Sprintf("%s.%s.mirror.destinations[%d]", destinationsKey, id, i)
+			member, ok := destinations[memberID]
+			if !ok {
+				v.errorf(path, "destination [%s] isn't configured", memberID)
+			} else if raw, ok := member.(map[string]interface{}); ok && raw["type"] == storages.MirrorType {
+				v.errorf(path, "destination [%s] is a mirror. Mirrors can't be nested", memberID)
+			}
+		}
+	}
+}
+
+//validateTypeConfig checks 'config' section (or the deprecated type section) with the type specific configuration
+func (v *validator) validateTypeConfig(path string, raw map[string]interface{}, destination *config.DestinationConfig, typeConfig *storages.TypeConfig) {
+	sectionPath := path + ".config"
+	section := destination.Config
+	if typeConfig.Section != "" {
+		deprecated, ok := raw[typeConfig.Section].(map[string]interface{})
+		if ok && len(section) > 0 {
+			v.errorf(path, "config and %s (deprecated) sections are mutually exclusive", typeConfig.Section)
+			return
+		}
+		if ok {
+			sectionPath = path + "." + typeConfig.Section
+			section = deprecated
+		}
+	}
+
+	if typeConfig.NewConfig == nil {
+		return
+	}
+
+	//backward compatibility with port number as string
+	if port, ok := section["port"].(string); ok {
+		if _, err := strconv.Atoi(port); err == nil || port == "" {
+			copied := make(map[string]interface{}, len(section))
+			for key, value := range section {
+				copied[key] = value
+			}
+			copied["port"], _ = strconv.Atoi(port)
+			section = copied
+		}
+	}
+
+	typed := typeConfig.NewConfig()
+	//type specific configuration is decoded without weak typing as storages do
+	if !v.decode(sectionPath, section, typed, false) {
+		return
+	}
+	if err := typed.Validate(); err != nil {
+		v.errorf(sectionPath, "%v", err)
+	}
+}
+
+//checkExclusive reports an error if more than one option is set. Options are paths relative to the destination
+func (v *validator) checkExclusive(path string, raw map[string]interface{}, options ...string) {
+	var set []string
+	for _, option := range options {
+		if lookup(raw, option) != nil {
+			set = append(set, option)
+		}
+	}
+
+	if len(set) > 1 {
+		v.errorf(path, "%s are mutually exclusive", strings.Join(set, " and "))
+	}
+}
+
+//lookup returns value by dot separated path or nil if it doesn't exist
+func lookup(m map[string]interface{}, path string) interface{} {
+	parts := strings.Split(path, ".")
+	var value interface{} = m
+	for _, part := range parts {
+		switch object := value.(type) {
+		case map[string]interface{}:
+			value = object[part]
+		case map[interface{}]interface{}:
+			value = object[part]
+		default:
+			return nil
+		}
+	}
+
+	return value
+}
+
+func supportedTypes() []string {
+	types := make([]string, 0, len(storages.StorageTypes))
+	for destinationType := range storages.StorageTypes {
+		types = append(types, destinationType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
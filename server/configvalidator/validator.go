@@ -0,0 +1,276 @@
+package configvalidator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/authorization"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/oauth"
+	"github.com/jitsucom/jitsu/server/sampling"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	destinationsKey = "destinations"
+	sourcesKey      = "sources"
+	apiKeysKey      = "api_keys"
+	loadSheddingKey = "load_shedding"
+)
+
+//knownSections are top level sections of Jitsu server configuration. Keys of source OAuth credentials
+//(e.g. google_ads.developer_token) are added in init()
+var knownSections = map[string]bool{
+	"server": true, "log": true, "destinations": true, "sources": true, "api_keys": true, "users_recognition": true,
+	"sql_debug_log": true, "events": true, "coordination": true, "meta": true, "geo": true, "geo_resolvers": true,
+	"maxmind": true, "ip2location": true, "ipinfo": true, "node": true, "sync-tasks": true, "singer-bridge": true,
+	"airbyte-bridge": true, "google-ads": true, "clickhouse": true,
+	"delivery_tracking": true, "schema_registry": true, "tracing": true, "retention": true, "erasure": true, "script_cache": true, "wasm": true,
+	"python": true, "transform": true, "streaming": true, "compatibility": true, "bot_filter": true, "quotas": true,
+	"mqtt": true, "batch_uploader": true, "load_shedding": true, "alerting": true, "notifications": true, "data_protection": true,
+	"consent": true, "configurator": true, "ui": true, "system": true, "synchronization_service": true,
+	"experimental": true, "deployment_id": true, "config_location": true,
+}
+
+func init() {
+	for _, fields := range oauth.Fields {
+		for _, viperKey := range fields {
+			knownSections[strings.Split(viperKey, ".")[0]] = true
+		}
+	}
+}
+
+//Problem is a configuration problem with the path of the configuration value (e.g. destinations.pg.config.host)
+type Problem struct {
+	Path    string
+	Message string
+	//Warning is a problem which doesn't prevent the server from starting (e.g. unknown top level section)
+	Warning bool
+}
+
+func (p *Problem) String() string {
+	if p.Warning {
+		return "warning: " + p.Path + ": " + p.Message
+	}
+
+	return p.Path + ": " + p.Message
+}
+
+//Problems is a list of configuration problems
+type Problems []*Problem
+
+//Errors returns problems which aren't warnings
+func (p Problems) Errors() Problems {
+	var errs Problems
+	for _, problem := range p {
+		if !problem.Warning {
+			errs = append(errs, problem)
+		}
+	}
+
+	return errs
+}
+
+//String returns problems one per line
+func (p Problems) String() string {
+	lines := make([]string, 0, len(p))
+	for _, problem := range p {
+		lines = append(lines, problem.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+//validator collects problems of the configuration
+type validator struct {
+	problems Problems
+	//mirrors are members IDs by mirror destinations IDs. Members are checked after all destinations
+	mirrors map[string][]string
+}
+
+//Validate checks Jitsu server configuration (all settings from viper): unknown keys, required fields of destinations
+//according to their types, mutually exclusive options, sources and api_keys. Destinations and sources which are
+//loaded by URL aren't checked
+func Validate(settings map[string]interface{}) Problems {
+	v := &validator{mirrors: map[string][]string{}}
+	for _, key := range sortedKeys(settings) {
+		if !knownSections[key] {
+			v.warnf(key, "unknown configuration section")
+		}
+	}
+
+	var destinationIDs map[string]bool
+	switch destinations := settings[destinationsKey].(type) {
+	case nil, string:
+	case map[string]interface{}:
+		destinationIDs = map[string]bool{}
+		for _, id := range sortedKeys(destinations) {
+			destinationIDs[id] = true
+			v.validateDestination(id, destinations[id])
+		}
+		v.validateMirrorMembers(destinations)
+	default:
+		v.errorf(destinationsKey, "must be an object or an URL")
+	}
+
+	switch sources := settings[sourcesKey].(type) {
+	case nil, string:
+	case map[string]interface{}:
+		for _, id := range sortedKeys(sources) {
+			v.validateSource(id, sources[id], destinationIDs)
+		}
+	default:
+		v.errorf(sourcesKey, "must be an object or an URL")
+	}
+
+	if apiKeys, ok := settings[apiKeysKey]; ok {
+		v.validateAPIKeys(apiKeys)
+		if server, ok := settings["server"].(map[string]interface{}); ok {
+			for _, deprecatedKey := range []string{"api_keys", "auth"} {
+				if _, ok := server[deprecatedKey]; ok {
+					v.errorf(apiKeysKey, "api_keys and server.%s (deprecated) are mutually exclusive", deprecatedKey)
+				}
+			}
+		}
+	}
+
+	if loadShedding, ok := settings[loadSheddingKey]; ok {
+		config := &sampling.LoadSheddingConfig{}
+		if v.decode(loadSheddingKey, loadShedding, config, true) && config.Enabled {
+			if err := config.Validate(); err != nil {
+				v.errorf(loadSheddingKey, "%v", err)
+			}
+		}
+	}
+
+	return v.problems
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, &Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, &Problem{Path: path, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+//decode decodes value into result and reports decoding errors and keys which don't exist in result as unknown keys.
+//weak decoding is the same as viper.Unmarshal does (e.g. "10" is decoded into int). Returns false if value can't be decoded
+func (v *validator) decode(path string, value interface{}, result interface{}, weak bool) bool {
+	metadata := &mapstructure.Metadata{}
+	decoderConfig := &mapstructure.DecoderConfig{Metadata: metadata, Result: result}
+	if weak {
+		decoderConfig.WeaklyTypedInput = true
+		decoderConfig.DecodeHook = mapstructure.ComposeDecodeHookFunc(mapstructure.StringToTimeDurationHookFunc(), mapstructure.StringToSliceHookFunc(","))
+	}
+	decoder, err := mapstructure.NewDecoder(decoderConfig)
+	if err != nil {
+		v.errorf(path, "%v", err)
+		return false
+	}
+
+	if err := decoder.Decode(value); err != nil {
+		if decodeErr, ok := err.(*mapstructure.Error); ok {
+			for _, msg := range decodeErr.Errors {
+				v.errorf(path, "%s", msg)
+			}
+		} else {
+			v.errorf(path, "%v", err)
+		}
+		return false
+	}
+
+	sort.Strings(metadata.Unused)
+	for _, key := range metadata.Unused {
+		v.errorf(path+"."+key, "unknown key")
+	}
+
+	return true
+}
+
+//validateSource checks source type and links to destinations (if destinations are configured in the same file)
+func (v *validator) validateSource(id string, value interface{}, destinationIDs map[string]bool) {
+	path := sourcesKey + "." + id
+	source := &base.SourceConfig{}
+	if !v.decode(path, value, source, true) {
+		return
+	}
+
+	if source.Type == "" {
+		v.errorf(path+".type", "is required")
+	}
+	if len(source.Destinations) == 0 {
+		v.warnf(path+".destinations", "no destinations are linked: the source will be skipped")
+	}
+	if destinationIDs == nil {
+		return
+	}
+	for i, destinationID := range source.Destinations {
+		if !destinationIDs[destinationID] {
+			v.errorf(fmt.Sprintf("%s.destinations[%d]", path, i), "destination [%s] isn't configured", destinationID)
+		}
+	}
+	for i, destinationID := range source.PostHandleDestinations {
+		if !destinationIDs[destinationID] {
+			v.errorf(fmt.Sprintf("%s.post_handle_destinations[%d]", path, i), "destination [%s] isn't configured", destinationID)
+		}
+	}
+}
+
+//validateAPIKeys checks api_keys section. It can be a list of secrets, a list of API keys objects
+//or a string with URL or file:// path (isn't checked)
+func (v *validator) validateAPIKeys(apiKeys interface{}) {
+	switch value := apiKeys.(type) {
+	case string:
+	case []interface{}:
+		ids := map[string]string{}
+		for i, apiKey := range value {
+			path := fmt.Sprintf("%s[%d]", apiKeysKey, i)
+			switch key := apiKey.(type) {
+			case string:
+				if key == "" {
+					v.errorf(path, "mustn't be empty")
+				}
+			case map[interface{}]interface{}, map[string]interface{}:
+				token := &authorization.Token{}
+				if !v.decode(path, key, token, true) {
+					continue
+				}
+				if token.ID == "" {
+					v.errorf(path+".id", "is required")
+				} else if previous, ok := ids[token.ID]; ok {
+					v.errorf(path+".id", "[%s] is already used in %s", token.ID, previous)
+				} else {
+					ids[token.ID] = path
+				}
+				if token.ClientSecret == "" && token.ServerSecret == "" {
+					v.errorf(path, "client_secret or server_secret is required")
+				}
+				if token.Sampling != nil {
+					if err := token.Sampling.Validate(); err != nil {
+						v.errorf(path+".sampling", "%v", err)
+					}
+				}
+				if token.IPHandling != nil {
+					if err := token.IPHandling.Validate(); err != nil {
+						v.errorf(path+".ip_handling", "%v", err)
+					}
+				}
+			default:
+				v.errorf(path, "must be a string or an object")
+			}
+		}
+	default:
+		v.errorf(apiKeysKey, "must be a list or a string")
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
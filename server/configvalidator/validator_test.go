@@ -0,0 +1,311 @@
+package configvalidator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func readSettings(t *testing.T, payload string) map[string]interface{} {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(bytes.NewBufferString(payload)))
+	return v.AllSettings()
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			"valid config",
+			`
+server:
+  port: 8001
+destinations:
+  pg:
+    type: postgres
+    mode: stream
+    config:
+      host: localhost
+      port: '5432'
+      db: events
+      username: user
+  webhook:
+    mode: stream
+    config:
+      url: https://example.com
+sources:
+  src:
+    type: postgres
+    destinations: [pg]
+api_keys:
+  - id: key1
+    client_secret: js.secret
+  - s2s.secret`,
+			nil,
+		},
+		{
+			"destinations loaded by URL aren't checked",
+			`destinations: https://configurator/api/v1/destinations`,
+			nil,
+		},
+		{
+			"unknown keys",
+			`
+servr:
+  port: 8001
+destinations:
+  pg:
+    type: postgres
+    modee: stream
+    data_layout:
+      primary_key_field: [id]
+    config:
+      host: localhost
+      db: events
+      username: user
+      pasword: secret`,
+			[]string{
+				"warning: servr: unknown configuration section",
+				"destinations.pg.data_layout.primary_key_field: unknown key",
+				"destinations.pg.modee: unknown key",
+				"destinations.pg.config.pasword: unknown key",
+			},
+		},
+		{
+			"required fields and types",
+			`
+destinations:
+  pg:
+    type: postgres
+    config:
+      host: localhost
+  custom:
+    config:
+      url: https://example.com
+  typo:
+    type: postgress
+  npm:
+    mode: stream
+  ch:
+    type: clickhouse
+    mode: batched
+    config:
+      dsns: 5`,
+			[]string{
+				"destinations.ch.mode: unknown mode [batched]. Supported: [batch, stream, synchronous]",
+				"destinations.ch.config: 'dsns': source data must be an array or slice, got int",
+				"destinations.custom.type: is required",
+				"destinations.npm.package: is required for npm destination",
+				"destinations.pg.config: Datasource db is required parameter",
+				"destinations.typo.type: unknown destination type [postgress]. Supported: [amplitude, bigquery, clickhouse, dbtcloud, facebook, gcs, google_analytics, google_sheets, hubspot, mysql, npm, postgres, redshift, s3, snowflake, tag, webhook]",
+			},
+		},
+		{
+			"modes and mutually exclusive options",
+			`
+destinations:
+  s3:
+    mode: stream
+    config:
+      bucket: events
+    s3:
+      bucket: events
+  webhook:
+    config:
+      url: https://example.com
+  sf:
+    type: snowflake
+    config:
+      account: acc
+      db: db
+      username: user
+      warehouse: wh
+      s3:
+        bucket: events
+      google:
+        gcs_bucket: events
+  pg:
+    type: postgres
+    data_layout:
+      mappings:
+        fields:
+          - src: /a
+            action: remove
+      transform: 'return {...$, a: 1}'
+    config:
+      host: localhost
+      db: events
+      username: user
+api_keys:
+  - id: key1
+    client_secret: js.secret
+  - id: key1
+  - 5
+server:
+  api_keys: [secret]`,
+			[]string{
+				"destinations.pg.data_layout: mappings (deprecated) and transform are mutually exclusive. Set transform_enabled: false or remove mappings",
+				"destinations.s3.mode: s3 destination doesn't support stream mode. Supported: [batch]",
+				"destinations.s3: config and s3 (deprecated) sections are mutually exclusive",
+				"destinations.sf: config.s3 and config.google are mutually exclusive",
+				"destinations.webhook.mode: webhook destination doesn't support batch mode. Supported: [stream]",
+				"api_keys[1].id: [key1] is already used in api_keys[0]",
+				"api_keys[1]: client_secret or server_secret is required",
+				"api_keys[2]: must be a string or an object",
+				"api_keys: api_keys and server.api_keys (deprecated) are mutually exclusive",
+			},
+		},
+		{
+			"sources",
+			`
+destinations:
+  pg:
+    type: postgres
+    config:
+      host: localhost
+      db: events
+      username: user
+sources:
+  src:
+    destinations: [pg, unknown]
+  empty:
+    type: postgres`,
+			[]string{
+				"warning: sources.empty.destinations: no destinations are linked: the source will be skipped",
+				"sources.src.type: is required",
+				"sources.src.destinations[1]: destination [unknown] isn't configured",
+			},
+		},
+		{
+			"sampling and load shedding",
+			`
+api_keys:
+  - id: key1
+    client_secret: js.secret
+    sampling:
+      rules:
+        - event_types: [scroll]
+          rate: 1.5
+load_shedding:
+  enabled: true
+  priorities: [lowest]`,
+			[]string{
+				"api_keys[0].sampling: rules[0]: rate must be between 0 and 1. Got: 1.5",
+				"load_shedding: load_shedding.queue_depth_threshold must be positive",
+			},
+		},
+		{
+			"mirrors",
+			`
+destinations:
+  eu:
+    type: webhook
+    mode: stream
+    config:
+      url: https://eu.example.com
+  us:
+    type: webhook
+    mode: stream
+    config:
+      url: https://us.example.com
+  warehouse:
+    type: mirror
+    only_tokens: [key1]
+    mirror:
+      destinations: [eu, us]
+  nested:
+    type: mirror
+    mirror:
+      destinations: [warehouse, unknown]
+  single:
+    type: mirror
+    mirror:
+      destinations: [eu]
+  empty:
+    type: mirror`,
+			[]string{
+				"destinations.empty.mirror: is required for mirror destination",
+				"destinations.single.mirror.destinations: mirror must contain at least 2 destinations",
+				"destinations.nested.mirror.destinations[0]: destination [warehouse] is a mirror. Mirrors can't be nested",
+				"destinations.nested.mirror.destinations[1]: destination [unknown] isn't configured",
+			},
+		},
+		{
+			"filters",
+			`
+destinations:
+  eu:
+    type: webhook
+    mode: stream
+    filter: event_type in ("pageview", "conversion") && host != "staging.example.com"
+    config:
+      url: https://eu.example.com
+  us:
+    type: webhook
+    mode: stream
+    filter: event_type in "pageview"
+    config:
+      url: https://us.example.com`,
+			[]string{
+				`destinations.us.filter: error parsing filter expression: expected ( or [ but got "pageview" at position 14`,
+			},
+		},
+		{
+			"staging formats",
+			`
+destinations:
+  sf:
+    type: snowflake
+    staging_format: parquet
+    config:
+      account: acc
+      db: db
+      username: user
+      warehouse: wh
+      s3:
+        bucket: events
+  rs:
+    type: redshift
+    staging_format: parquet
+    config:
+      host: redshift.example.com
+      db: db
+      username: user
+      s3:
+        bucket: events
+  pg:
+    type: postgres
+    staging_format: csv
+    config:
+      host: pg.example.com
+      db: db
+      username: user`,
+			[]string{
+				`destinations.pg.staging_format: staging files aren't used by the destination type`,
+				`destinations.rs.staging_format: unsupported staging format [parquet]. Supported: [json, csv]`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := Validate(readSettings(t, tt.config))
+			var actual []string
+			for _, problem := range problems {
+				actual = append(actual, problem.String())
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestProblemsErrors(t *testing.T) {
+	problems := Problems{{Path: "a", Message: "unknown configuration section", Warning: true}, {Path: "b.type", Message: "is required"}}
+	require.Equal(t, Problems{problems[1]}, problems.Errors())
+	require.Equal(t, "warning: a: unknown configuration section\nb.type: is required", problems.String())
+}
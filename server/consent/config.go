@@ -0,0 +1,111 @@
+package consent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Consent modes: what happens with events without required consent
+const (
+	// DropMode skips events without required consent
+	DropMode = "drop"
+	// StripMode removes personal data fields from events without required consent
+	StripMode = "strip"
+
+	// AllowMissing treats events without consent information as consented (backward compatible default)
+	AllowMissing = "allow"
+	// DenyMissing treats events without consent information as not consented
+	DenyMissing = "deny"
+
+	// DefaultField is a default path of the consent in events
+	DefaultField = "/eventn_ctx/consent||/consent"
+)
+
+var (
+	// DefaultStripFields are personal data fields which are removed in strip mode if fields aren't configured
+	DefaultStripFields = []string{"/user", "/eventn_ctx/user", "/ids", "/eventn_ctx/ids", "/source_ip"}
+
+	// DefaultTCFPurposes is a default mapping of consent categories to IAB TCF v2 purposes. A category is granted
+	// by TCF consent string only if all its purposes are granted. Categories without purposes are always granted
+	DefaultTCFPurposes = map[string][]int{
+		"necessary":       {},
+		"functional":      {1},
+		"analytics":       {1, 8, 9, 10},
+		"marketing":       {1, 2, 3, 4, 7},
+		"personalization": {1, 5, 6},
+	}
+
+	settings      = &Settings{Field: DefaultField, TCFPurposes: DefaultTCFPurposes}
+	settingsMutex sync.RWMutex
+)
+
+// Settings is a global consent configuration: path of the consent in events and categories to TCF purposes mapping
+type Settings struct {
+	Field       string           `mapstructure:"field" json:"field,omitempty" yaml:"field,omitempty"`
+	TCFPurposes map[string][]int `mapstructure:"tcf_purposes" json:"tcf_purposes,omitempty" yaml:"tcf_purposes,omitempty"`
+}
+
+// Config is a destination consent configuration: categories which must be granted for sending events into the destination
+type Config struct {
+	Categories  []string `mapstructure:"categories" json:"categories,omitempty" yaml:"categories,omitempty"`
+	Mode        string   `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+	StripFields []string `mapstructure:"strip_fields" json:"strip_fields,omitempty" yaml:"strip_fields,omitempty"`
+	Missing     string   `mapstructure:"missing" json:"missing,omitempty" yaml:"missing,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "", DropMode, StripMode:
+	default:
+		return fmt.Errorf("unsupported consent mode: %s. Supported: %s, %s", c.Mode, DropMode, StripMode)
+	}
+
+	switch c.Missing {
+	case "", AllowMissing, DenyMissing:
+	default:
+		return fmt.Errorf("unsupported consent missing policy: %s. Supported: %s, %s", c.Missing, AllowMissing, DenyMissing)
+	}
+
+	for _, category := range c.Categories {
+		if strings.TrimSpace(category) == "" {
+			return errors.New("consent categories can't be empty")
+		}
+	}
+
+	return nil
+}
+
+// SetSettings sets global consent settings. Empty fields are replaced with defaults
+func SetSettings(s *Settings) error {
+	result := &Settings{Field: DefaultField, TCFPurposes: DefaultTCFPurposes}
+	if s != nil {
+		if s.Field != "" {
+			result.Field = s.Field
+		}
+		if len(s.TCFPurposes) > 0 {
+			result.TCFPurposes = map[string][]int{}
+			for category, purposes := range s.TCFPurposes {
+				for _, purpose := range purposes {
+					if purpose < 1 || purpose > tcfPurposesCount {
+						return fmt.Errorf("TCF purpose of %s category must be in range [1, %d]. Got: %d", category, tcfPurposesCount, purpose)
+					}
+				}
+				result.TCFPurposes[strings.ToLower(category)] = purposes
+			}
+		}
+	}
+
+	settingsMutex.Lock()
+	settings = result
+	settingsMutex.Unlock()
+	return nil
+}
+
+func getSettings() *Settings {
+	settingsMutex.RLock()
+	defer settingsMutex.RUnlock()
+	return settings
+}
@@ -0,0 +1,153 @@
+package consent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+// Step checks that the event consent grants all required categories. Events without required consent
+// are dropped (DropMode) or personal data fields are removed from them (StripMode)
+type Step struct {
+	field       jsonutils.JSONPath
+	categories  []string
+	strip       bool
+	stripFields []jsonutils.JSONPath
+	denyMissing bool
+	tcfPurposes map[string][]int
+}
+
+// NewStep returns configured Step or nil if there are no required categories
+func NewStep(config *Config) (*Step, error) {
+	if config == nil || len(config.Categories) == 0 {
+		return nil, nil
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := getSettings()
+	field := jsonutils.NewJSONPath(s.Field)
+	if field.IsEmpty() {
+		return nil, fmt.Errorf("consent field must be a valid path like: /node1/node2. Got: %q", s.Field)
+	}
+
+	step := &Step{
+		field:       field,
+		strip:       config.Mode == StripMode,
+		denyMissing: config.Missing == DenyMissing,
+		tcfPurposes: s.TCFPurposes,
+	}
+	for _, category := range config.Categories {
+		step.categories = append(step.categories, strings.ToLower(strings.TrimSpace(category)))
+	}
+
+	stripFields := config.StripFields
+	if len(stripFields) == 0 {
+		stripFields = DefaultStripFields
+	}
+	for _, stripField := range stripFields {
+		path := jsonutils.NewJSONPath(stripField)
+		if path.IsEmpty() {
+			return nil, fmt.Errorf("consent strip field must be a valid path like: /node1/node2. Got: %q", stripField)
+		}
+		step.stripFields = append(step.stripFields, path)
+	}
+
+	return step, nil
+}
+
+// Execute returns false if the event must be dropped. In strip mode personal data fields are removed
+// from the event without required consent and true is returned
+func (s *Step) Execute(object map[string]interface{}) bool {
+	if s == nil || s.Granted(object) {
+		return true
+	}
+
+	if !s.strip {
+		return false
+	}
+
+	for _, field := range s.stripFields {
+		field.GetAndRemove(object)
+	}
+	return true
+}
+
+// Granted returns true if the event consent grants all required categories
+func (s *Step) Granted(object map[string]interface{}) bool {
+	value, ok := s.field.Get(object)
+	if !ok || value == nil {
+		return !s.denyMissing
+	}
+
+	granted := s.grantedCategories(value)
+	for _, category := range s.categories {
+		if !granted(category) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//grantedCategories returns func which checks if the category is granted by the consent value:
+//TCF v2 consent string, categories map {"analytics": true} or array of granted categories ["analytics"]
+func (s *Step) grantedCategories(value interface{}) func(category string) bool {
+	switch v := value.(type) {
+	case string:
+		purposes, err := ParseTCFPurposes(v)
+		if err != nil {
+			return func(string) bool { return false }
+		}
+		return func(category string) bool {
+			categoryPurposes, ok := s.tcfPurposes[category]
+			if !ok {
+				return false
+			}
+			for _, purpose := range categoryPurposes {
+				if !purposes[purpose] {
+					return false
+				}
+			}
+			return true
+		}
+	case map[string]interface{}:
+		categories := map[string]bool{}
+		for category, granted := range v {
+			categories[strings.ToLower(category)] = isGranted(granted)
+		}
+		return func(category string) bool { return categories[category] }
+	case []interface{}:
+		categories := map[string]bool{}
+		for _, category := range v {
+			if str, ok := category.(string); ok {
+				categories[strings.ToLower(str)] = true
+			}
+		}
+		return func(category string) bool { return categories[category] }
+	default:
+		return func(string) bool { return false }
+	}
+}
+
+func isGranted(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "granted", "yes", "1":
+			return true
+		}
+		return false
+	case float64:
+		return v == 1
+	case int:
+		return v == 1
+	default:
+		return false
+	}
+}
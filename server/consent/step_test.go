@@ -0,0 +1,138 @@
+package consent
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//buildTCString returns TCF v2 core segment with consented purposes
+func buildTCString(version int, purposes ...int) string {
+	b := make([]byte, 29)
+	writeBits := func(offset, length, value int) {
+		for i := 0; i < length; i++ {
+			if value>>(length-1-i)&1 == 1 {
+				b[(offset+i)/8] |= 1 << (7 - uint((offset+i)%8))
+			}
+		}
+	}
+	writeBits(0, 6, version)
+	for _, purpose := range purposes {
+		writeBits(tcfPurposesOffset+purpose-1, 1, 1)
+	}
+	return base64.RawURLEncoding.EncodeToString(b) + ".YAAAAAAAAAAA"
+}
+
+func TestParseTCFPurposes(t *testing.T) {
+	purposes, err := ParseTCFPurposes(buildTCString(2, 1, 8, 24))
+	require.NoError(t, err)
+	require.Equal(t, map[int]bool{1: true, 8: true, 24: true}, purposes)
+
+	_, err = ParseTCFPurposes(buildTCString(1, 1))
+	require.Error(t, err)
+	_, err = ParseTCFPurposes("CPX")
+	require.Error(t, err)
+	_, err = ParseTCFPurposes("not a tc string!")
+	require.Error(t, err)
+}
+
+func TestStep(t *testing.T) {
+	require.NoError(t, SetSettings(nil))
+
+	tests := []struct {
+		name     string
+		config   *Config
+		event    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			"Categories map granted",
+			&Config{Categories: []string{"analytics"}},
+			map[string]interface{}{"eventn_ctx": map[string]interface{}{"consent": map[string]interface{}{"Analytics": true}}, "source_ip": "1.1.1.1"},
+			map[string]interface{}{"eventn_ctx": map[string]interface{}{"consent": map[string]interface{}{"Analytics": true}}, "source_ip": "1.1.1.1"},
+		},
+		{
+			"Categories map not granted",
+			&Config{Categories: []string{"analytics", "marketing"}},
+			map[string]interface{}{"consent": map[string]interface{}{"analytics": "granted", "marketing": "denied"}},
+			nil,
+		},
+		{
+			"Categories array granted",
+			&Config{Categories: []string{"analytics"}},
+			map[string]interface{}{"consent": []interface{}{"necessary", "analytics"}},
+			map[string]interface{}{"consent": []interface{}{"necessary", "analytics"}},
+		},
+		{
+			"TCF granted",
+			&Config{Categories: []string{"analytics"}},
+			map[string]interface{}{"consent": buildTCString(2, 1, 8, 9, 10)},
+			map[string]interface{}{"consent": buildTCString(2, 1, 8, 9, 10)},
+		},
+		{
+			"TCF not granted",
+			&Config{Categories: []string{"analytics"}},
+			map[string]interface{}{"consent": buildTCString(2, 1, 8)},
+			nil,
+		},
+		{
+			"Malformed TCF",
+			&Config{Categories: []string{"necessary"}},
+			map[string]interface{}{"consent": "malformed"},
+			nil,
+		},
+		{
+			"Missing consent is allowed by default",
+			&Config{Categories: []string{"analytics"}},
+			map[string]interface{}{"source_ip": "1.1.1.1"},
+			map[string]interface{}{"source_ip": "1.1.1.1"},
+		},
+		{
+			"Missing consent is denied",
+			&Config{Categories: []string{"analytics"}, Missing: DenyMissing},
+			map[string]interface{}{"source_ip": "1.1.1.1"},
+			nil,
+		},
+		{
+			"Strip default fields",
+			&Config{Categories: []string{"analytics"}, Mode: StripMode},
+			map[string]interface{}{"consent": map[string]interface{}{"analytics": false}, "source_ip": "1.1.1.1", "user": map[string]interface{}{"id": "u1"}, "event_type": "pageview"},
+			map[string]interface{}{"consent": map[string]interface{}{"analytics": false}, "event_type": "pageview"},
+		},
+		{
+			"Strip configured fields",
+			&Config{Categories: []string{"analytics"}, Mode: StripMode, StripFields: []string{"/user/email"}},
+			map[string]interface{}{"consent": map[string]interface{}{}, "user": map[string]interface{}{"id": "u1", "email": "a@b.com"}},
+			map[string]interface{}{"consent": map[string]interface{}{}, "user": map[string]interface{}{"id": "u1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewStep(tt.config)
+			require.NoError(t, err)
+
+			if tt.expected == nil {
+				require.False(t, step.Execute(tt.event))
+				return
+			}
+			require.True(t, step.Execute(tt.event))
+			require.Equal(t, tt.expected, tt.event)
+		})
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	_, err := NewStep(&Config{Categories: []string{"analytics"}, Mode: "block"})
+	require.Error(t, err)
+	_, err = NewStep(&Config{Categories: []string{"analytics"}, Missing: "ignore"})
+	require.Error(t, err)
+	_, err = NewStep(&Config{Categories: []string{"analytics"}, StripFields: []string{"/"}})
+	require.Error(t, err)
+	require.Error(t, SetSettings(&Settings{TCFPurposes: map[string][]int{"analytics": {25}}}))
+
+	step, err := NewStep(&Config{})
+	require.NoError(t, err)
+	require.Nil(t, step)
+	require.True(t, step.Execute(map[string]interface{}{}))
+}
@@ -0,0 +1,52 @@
+package consent
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	tcfVersion       = 2
+	tcfPurposesCount = 24
+	//bit offset of PurposesConsent field in TCF v2 core string:
+	//Version(6) Created(36) LastUpdated(36) CmpId(12) CmpVersion(12) ConsentScreen(6) ConsentLanguage(12)
+	//VendorListVersion(12) TcfPolicyVersion(6) IsServiceSpecific(1) UseNonStandardTexts(1) SpecialFeatureOptIns(12)
+	tcfPurposesOffset = 152
+)
+
+// ParseTCFPurposes returns purposes (1..24) which are consented in IAB TCF v2 consent string
+func ParseTCFPurposes(tcString string) (map[int]bool, error) {
+	core := strings.SplitN(strings.TrimSpace(tcString), ".", 2)[0]
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(core, "="))
+	if err != nil {
+		return nil, fmt.Errorf("malformed TCF consent string: %v", err)
+	}
+	if len(b)*8 < tcfPurposesOffset+tcfPurposesCount {
+		return nil, errors.New("malformed TCF consent string: core segment is too short")
+	}
+
+	if version := readBits(b, 0, 6); version != tcfVersion {
+		return nil, fmt.Errorf("unsupported TCF consent string version: %d", version)
+	}
+
+	purposes := map[int]bool{}
+	for i := 0; i < tcfPurposesCount; i++ {
+		if readBits(b, tcfPurposesOffset+i, 1) == 1 {
+			purposes[i+1] = true
+		}
+	}
+
+	return purposes, nil
+}
+
+//readBits returns unsigned integer of length bits starting from offset bit (big-endian)
+func readBits(b []byte, offset, length int) int {
+	result := 0
+	for i := offset; i < offset+length; i++ {
+		bit := (b[i/8] >> (7 - uint(i%8))) & 1
+		result = result<<1 | int(bit)
+	}
+	return result
+}
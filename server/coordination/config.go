@@ -0,0 +1,82 @@
+package coordination
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	defaultDialTimeoutSec = 5
+	defaultLockTTLSec     = 15
+	defaultPrefix         = "jitsu/"
+)
+
+//EtcdConfig is a configuration of etcd coordination service
+type EtcdConfig struct {
+	Endpoints []string `mapstructure:"endpoints" json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	//Endpoint is a shortcut for a single endpoint
+	Endpoint       string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Username       string `mapstructure:"username" json:"username,omitempty" yaml:"username,omitempty"`
+	Password       string `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty"`
+	DialTimeoutSec int    `mapstructure:"dial_timeout_sec" json:"dial_timeout_sec,omitempty" yaml:"dial_timeout_sec,omitempty"`
+	LockTTLSec     int    `mapstructure:"lock_ttl_sec" json:"lock_ttl_sec,omitempty" yaml:"lock_ttl_sec,omitempty"`
+	Prefix         string `mapstructure:"prefix" json:"prefix,omitempty" yaml:"prefix,omitempty"`
+}
+
+//Validate returns err if configuration is invalid. Also sets default values
+func (ec *EtcdConfig) Validate() error {
+	if ec.Endpoint != "" {
+		ec.Endpoints = append(ec.Endpoints, ec.Endpoint)
+	}
+	if len(ec.Endpoints) == 0 {
+		return errors.New("coordination.etcd.endpoints is required config parameter")
+	}
+
+	if ec.DialTimeoutSec <= 0 {
+		ec.DialTimeoutSec = defaultDialTimeoutSec
+	}
+	if ec.LockTTLSec <= 0 {
+		ec.LockTTLSec = defaultLockTTLSec
+	}
+	ec.Prefix = normalizePrefix(ec.Prefix)
+
+	return nil
+}
+
+//ConsulConfig is a configuration of Consul coordination service
+type ConsulConfig struct {
+	Address    string `mapstructure:"address" json:"address,omitempty" yaml:"address,omitempty"`
+	Scheme     string `mapstructure:"scheme" json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	Datacenter string `mapstructure:"datacenter" json:"datacenter,omitempty" yaml:"datacenter,omitempty"`
+	Token      string `mapstructure:"token" json:"token,omitempty" yaml:"token,omitempty"`
+	LockTTLSec int    `mapstructure:"lock_ttl_sec" json:"lock_ttl_sec,omitempty" yaml:"lock_ttl_sec,omitempty"`
+	Prefix     string `mapstructure:"prefix" json:"prefix,omitempty" yaml:"prefix,omitempty"`
+}
+
+//Validate returns err if configuration is invalid. Also sets default values
+func (cc *ConsulConfig) Validate() error {
+	if cc.Address == "" {
+		return errors.New("coordination.consul.address is required config parameter")
+	}
+
+	if cc.LockTTLSec <= 0 {
+		cc.LockTTLSec = defaultLockTTLSec
+	} else if cc.LockTTLSec < 10 {
+		return errors.New("coordination.consul.lock_ttl_sec must be at least 10 seconds")
+	}
+	cc.Prefix = normalizePrefix(cc.Prefix)
+
+	return nil
+}
+
+//normalizePrefix returns the keys prefix with trailing slash
+func normalizePrefix(prefix string) string {
+	if prefix == "" {
+		return defaultPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return prefix
+}
@@ -2,13 +2,20 @@ package coordination
 
 import (
 	"context"
+	"fmt"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/cluster"
 	"github.com/jitsucom/jitsu/server/locks"
+	consullocks "github.com/jitsucom/jitsu/server/locks/consul"
+	etcdlocks "github.com/jitsucom/jitsu/server/locks/etcd"
 	inmemorylocks "github.com/jitsucom/jitsu/server/locks/inmemory"
 	redislocks "github.com/jitsucom/jitsu/server/locks/redis"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -44,6 +51,74 @@ func NewRedisService(ctx context.Context, serverName string, factory *meta.Redis
 	}, nil
 }
 
+//NewEtcdService returns configured etcd Service
+func NewEtcdService(ctx context.Context, serverName string, config *EtcdConfig) (*Service, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	logging.Infof("🛫 Initializing etcd coordination service [%s]...", strings.Join(config.Endpoints, ","))
+
+	dialTimeout := time.Duration(config.DialTimeoutSec) * time.Second
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   config.Endpoints,
+		Username:    config.Username,
+		Password:    config.Password,
+		DialTimeout: dialTimeout,
+		Context:     ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating etcd client: %v", err)
+	}
+
+	statusCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	if _, err := client.Status(statusCtx, config.Endpoints[0]); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error connecting to etcd: %v", err)
+	}
+
+	lockFactory, locksCloser := etcdlocks.NewLockFactory(ctx, client, config.Prefix, config.LockTTLSec)
+
+	return &Service{
+		clusterManager:   cluster.NewEtcdManager(serverName, client, config.Prefix),
+		locksFactory:     lockFactory,
+		locksCloser:      locksCloser,
+		connectionCloser: client,
+	}, nil
+}
+
+//NewConsulService returns configured Consul Service
+func NewConsulService(serverName string, config *ConsulConfig) (*Service, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	logging.Infof("🛫 Initializing consul coordination service [%s]...", config.Address)
+
+	client, err := api.NewClient(&api.Config{
+		Address:    config.Address,
+		Scheme:     config.Scheme,
+		Datacenter: config.Datacenter,
+		Token:      config.Token,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating consul client: %v", err)
+	}
+
+	if _, err := client.Status().Leader(); err != nil {
+		return nil, fmt.Errorf("error connecting to consul: %v", err)
+	}
+
+	lockFactory, locksCloser := consullocks.NewLockFactory(client, config.Prefix, fmt.Sprintf("%ds", config.LockTTLSec))
+
+	return &Service{
+		clusterManager: cluster.NewConsulManager(serverName, client, config.Prefix),
+		locksFactory:   lockFactory,
+		locksCloser:    locksCloser,
+	}, nil
+}
+
 //NewInMemoryService returns configured inmemory Service
 func NewInMemoryService(serverName string) *Service {
 	lockFactory, _ := inmemorylocks.NewLockFactory()
@@ -65,14 +140,24 @@ func (s *Service) CreateLock(name string) locks.Lock {
 	return s.locksFactory.CreateLock(name)
 }
 
+//Close unlocks all locks, stops heartbeat and closes the connection
 func (s *Service) Close() error {
+	var multiErr error
 	if s.locksCloser != nil {
-		return s.locksCloser.Close()
+		if err := s.locksCloser.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+	}
+
+	if err := s.clusterManager.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
 	}
 
 	if s.connectionCloser != nil {
-		return s.connectionCloser.Close()
+		if err := s.connectionCloser.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
 	}
 
-	return nil
+	return multiErr
 }
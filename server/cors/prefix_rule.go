@@ -1,6 +1,7 @@
 package cors
 
 import (
+	"fmt"
 	"net/url"
 	"strings"
 )
@@ -10,8 +11,13 @@ type Rule interface {
 	IsAllowed(host, origin string) bool
 }
 
-//NewRule returns Rule based on expression
+//NewRule returns Rule based on expression. Scheme, port and trailing slash of the expression are ignored
+//(origins are compared without them): https://abc.com:8080/ is the same as abc.com
 func NewRule(expression string) Rule {
+	expression = strings.TrimSuffix(removeSchema(strings.TrimSpace(expression)), "/")
+	if i := strings.LastIndex(expression, ":"); i >= 0 {
+		expression = expression[:i]
+	}
 	if strings.Contains(expression, AppTopLevelDomainTemplate) {
 		return &AppDomainRule{expression: expression}
 	}
@@ -19,6 +25,29 @@ func NewRule(expression string) Rule {
 	return NewPrefixSuffixRule(expression)
 }
 
+//ValidateRule returns err if the expression is empty, contains a path or a wildcard in the middle.
+//Supported forms: *, abc.com, *abc.com, *.abc.com (subdomains only), abc* and {{APP_TLD}} templates
+func ValidateRule(expression string) error {
+	value := strings.TrimSuffix(removeSchema(strings.TrimSpace(expression)), "/")
+	if value == "*" {
+		return nil
+	}
+
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "*"), "*")
+	switch {
+	case value == "" || value == ".":
+		return fmt.Errorf("origin [%s] is empty", expression)
+	case strings.ContainsAny(value, " \t\r\n"):
+		return fmt.Errorf("origin [%s] contains whitespaces", expression)
+	case strings.Contains(value, "*"):
+		return fmt.Errorf("origin [%s] contains wildcard in the middle. Wildcard is supported only at the beginning or at the end: *.abc.com or abc*", expression)
+	case strings.Contains(value, "/"):
+		return fmt.Errorf("origin [%s] must be a domain (with optional scheme and port) without path", expression)
+	default:
+		return nil
+	}
+}
+
 //PrefixSuffixRule checks domain by prefix abc* and suffix: *.abc.com
 type PrefixSuffixRule struct {
 	prefix   bool
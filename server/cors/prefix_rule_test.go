@@ -0,0 +1,41 @@
+package cors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		origin     string
+		expected   bool
+	}{
+		{"Exact domain", "abc.com", "https://abc.com", true},
+		{"Exact domain with scheme", "https://abc.com/", "https://abc.com:8080", true},
+		{"Exact domain with port", "localhost:3000", "http://localhost:3000", true},
+		{"Exact domain doesn't match subdomain", "abc.com", "https://app.abc.com", false},
+		{"Wildcard subdomain", "*.abc.com", "https://app.abc.com", true},
+		{"Wildcard subdomain of any level", "https://*.abc.com", "https://a.b.abc.com", true},
+		{"Wildcard subdomain doesn't match the domain", "*.abc.com", "https://abc.com", false},
+		{"Wildcard subdomain doesn't match other domain", "*.abc.com", "https://notabc.com", false},
+		{"Prefix", "abc*", "http://abcd.com", true},
+		{"Any origin", "*", "http://abcd.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, NewRule(tt.expression).IsAllowed("", tt.origin))
+		})
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	for _, expression := range []string{"*", "abc.com", "https://abc.com/", "*.abc.com", "*abc.com", "abc*", "localhost:3000", "*.{{APP_TLD}}"} {
+		require.NoError(t, ValidateRule(expression), expression)
+	}
+	for _, expression := range []string{"", " ", "**", "*.", "app.*.abc.com", "abc .com", "https://abc.com/path"} {
+		require.Error(t, ValidateRule(expression), expression)
+	}
+}
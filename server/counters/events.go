@@ -17,11 +17,20 @@ type Key struct {
 	namespace, id, eventType, status string
 }
 
+//DestinationTotals is a cumulative amount of destination events since the server start
+type DestinationTotals struct {
+	Success int64
+	Errors  int64
+}
+
 type Events struct {
 	storage meta.Storage
 
-	mutex  *sync.RWMutex
-	buffer map[Key]int64
+	mutex   *sync.RWMutex
+	buffer  map[Key]int64
+	rollups map[meta.RollupKey]int64
+	//destinationTotals aren't persisted. They are used for in-process error rate calculation (e.g. alerting)
+	destinationTotals map[string]*DestinationTotals
 
 	closed chan struct{}
 }
@@ -31,7 +40,10 @@ func InitEvents(storage meta.Storage) {
 		storage: storage,
 		mutex:   &sync.RWMutex{},
 		buffer:  map[Key]int64{},
+		rollups: map[meta.RollupKey]int64{},
 		closed:  make(chan struct{}),
+
+		destinationTotals: map[string]*DestinationTotals{},
 	}
 	safego.Run(eventsInstance.startPersisting)
 }
@@ -51,14 +63,12 @@ func (e *Events) startPersisting() {
 
 //persist extract values from the buffer and persist them. Leave buffer empty
 func (e *Events) persist() {
-	bufCopy := map[Key]int64{}
-
 	//extract
 	e.mutex.Lock()
-	for k, v := range e.buffer {
-		bufCopy[k] = v
-	}
+	bufCopy := e.buffer
+	rollupsCopy := e.rollups
 	e.buffer = map[Key]int64{}
+	e.rollups = map[meta.RollupKey]int64{}
 	e.mutex.Unlock()
 
 	//persist
@@ -67,6 +77,13 @@ func (e *Events) persist() {
 			logging.SystemErrorf("Error updating %s [%s] events [%s] counter id=[%s] value [%d]: %v", key.status, key.eventType, key.namespace, key.id, value, err)
 		}
 	}
+
+	for key, value := range rollupsCopy {
+		key := key
+		if err := e.storage.IncrementRollupsCount(&key, timestamp.Now().UTC(), value); err != nil {
+			logging.SystemErrorf("Error updating usage rollup %+v value [%d]: %v", key, value, err)
+		}
+	}
 }
 
 func (e *Events) event(id, namespace, eventType, status string, value int64) {
@@ -86,6 +103,51 @@ func (e *Events) event(id, namespace, eventType, status string, value int64) {
 	e.mutex.Unlock()
 }
 
+func (e *Events) rollup(key meta.RollupKey, value int64) {
+	if e == nil {
+		return
+	}
+
+	e.mutex.Lock()
+	e.rollups[key] += value
+	e.mutex.Unlock()
+}
+
+func (e *Events) destinationTotal(destinationID, status string, value int64) {
+	if e == nil {
+		return
+	}
+
+	e.mutex.Lock()
+	totals, ok := e.destinationTotals[destinationID]
+	if !ok {
+		totals = &DestinationTotals{}
+		e.destinationTotals[destinationID] = totals
+	}
+	if status == meta.SuccessStatus {
+		totals.Success += value
+	} else {
+		totals.Errors += value
+	}
+	e.mutex.Unlock()
+}
+
+//GetDestinationTotals returns a copy of cumulative success and errors destinations events amounts since the server start
+func GetDestinationTotals() map[string]DestinationTotals {
+	if eventsInstance == nil {
+		return map[string]DestinationTotals{}
+	}
+
+	eventsInstance.mutex.RLock()
+	defer eventsInstance.mutex.RUnlock()
+
+	result := make(map[string]DestinationTotals, len(eventsInstance.destinationTotals))
+	for destinationID, totals := range eventsInstance.destinationTotals {
+		result[destinationID] = *totals
+	}
+	return result
+}
+
 //SuccessPushSourceEvents increments:
 // deprecated source events deprecated push source events
 // new push source counters
@@ -111,21 +173,25 @@ func SuccessPullSourceEvents(sourceID string, value int64) {
 //SuccessPushDestinationEvents increments:
 // deprecated destination events
 // new push destination counters
+// in-process destination totals
 func SuccessPushDestinationEvents(destinationID string, value int64) {
 	//536-issue DEPRECATED
 	successEvents(destinationID, meta.DestinationNamespace, "", value)
 
 	successEvents(destinationID, meta.DestinationNamespace, meta.PushEventType, value)
+	eventsInstance.destinationTotal(destinationID, meta.SuccessStatus, value)
 }
 
 //SuccessPullDestinationEvents increments:
 // deprecated destination events
 // new pull destination counters
+// in-process destination totals
 func SuccessPullDestinationEvents(destinationID string, value int64) {
 	//536-issue DEPRECATED
 	successEvents(destinationID, meta.DestinationNamespace, "", value)
 
 	successEvents(destinationID, meta.DestinationNamespace, meta.PullEventType, value)
+	eventsInstance.destinationTotal(destinationID, meta.SuccessStatus, value)
 }
 
 func successEvents(id, namespace, eventType string, value int64) {
@@ -141,21 +207,25 @@ func ErrorPullSourceEvents(sourceID string, value int64) {
 //ErrorPullDestinationEvents increments:
 // deprecated destination events
 // new pull destination counters
+// in-process destination totals
 func ErrorPullDestinationEvents(destinationID string, value int64) {
 	//536-issue DEPRECATED
 	errorEvents(destinationID, meta.DestinationNamespace, "", value)
 
 	errorEvents(destinationID, meta.DestinationNamespace, meta.PullEventType, value)
+	eventsInstance.destinationTotal(destinationID, meta.ErrorStatus, value)
 }
 
 //ErrorPushDestinationEvents increments:
 // deprecated destination events
 // new pull destination counters
+// in-process destination totals
 func ErrorPushDestinationEvents(destinationID string, value int64) {
 	//536-issue DEPRECATED
 	errorEvents(destinationID, meta.DestinationNamespace, "", value)
 
 	errorEvents(destinationID, meta.DestinationNamespace, meta.PushEventType, value)
+	eventsInstance.destinationTotal(destinationID, meta.ErrorStatus, value)
 }
 
 func errorEvents(id, namespace, eventType string, value int64) {
@@ -185,6 +255,18 @@ func skipEvents(id, namespace, eventType string, value int64) {
 	eventsInstance.event(id, namespace, eventType, meta.SkipStatus, value)
 }
 
+//PushRollup increments usage rollup of push events. API key (token) ID is used as the source ID
+//status: [success, errors, skip]
+func PushRollup(destinationID, tokenID, table, status string, value int64) {
+	eventsInstance.rollup(meta.RollupKey{DestinationID: destinationID, SourceID: tokenID, APIKey: tokenID, Table: table, Status: status}, value)
+}
+
+//PullRollup increments usage rollup of pull (synchronization) events
+//status: [success, errors]
+func PullRollup(destinationID, sourceID, table, status string, value int64) {
+	eventsInstance.rollup(meta.RollupKey{DestinationID: destinationID, SourceID: sourceID, Table: table, Status: status}, value)
+}
+
 func Close() {
 	if eventsInstance != nil {
 		close(eventsInstance.closed)
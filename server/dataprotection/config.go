@@ -0,0 +1,79 @@
+package dataprotection
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Data protection methods
+const (
+	// HashMethod replaces value with hex encoded SHA-256 of salt + value. It is irreversible
+	HashMethod = "hash"
+	// TokenizeMethod replaces value with deterministic token (HMAC-SHA256 with the secret key). The same values
+	// have the same tokens, so tokenized fields can be used in joins
+	TokenizeMethod = "tokenize"
+	// EncryptMethod replaces value with AES-GCM encrypted value. It can be decrypted with the key
+	EncryptMethod = "encrypt"
+)
+
+// Config is a data protection configuration. Salt and key are secret references:
+// env://VARIABLE_NAME, awskms://base64_encrypted_data_key or a plain value
+type Config struct {
+	Salt  string        `mapstructure:"salt" json:"salt,omitempty" yaml:"salt,omitempty"`
+	Key   string        `mapstructure:"key" json:"key,omitempty" yaml:"key,omitempty"`
+	Rules []*RuleConfig `mapstructure:"rules" json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// RuleConfig is a configuration of protected fields
+type RuleConfig struct {
+	Fields    []string `mapstructure:"fields" json:"fields,omitempty" yaml:"fields,omitempty"`
+	Method    string   `mapstructure:"method" json:"method,omitempty" yaml:"method,omitempty"`
+	Normalize bool     `mapstructure:"normalize" json:"normalize,omitempty" yaml:"normalize,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	for _, rule := range c.Rules {
+		if rule == nil {
+			continue
+		}
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+
+		switch rule.Method {
+		case HashMethod:
+			if c.Salt == "" {
+				return errors.New("'salt' is required for hash method")
+			}
+		case TokenizeMethod, EncryptMethod:
+			if c.Key == "" {
+				return fmt.Errorf("'key' is required for %s method", rule.Method)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate returns err if the rule configuration is invalid
+func (rc *RuleConfig) Validate() error {
+	rc.Method = strings.ToLower(strings.TrimSpace(rc.Method))
+	if len(rc.Fields) == 0 {
+		return errors.New("'fields' is required data protection rule parameter")
+	}
+
+	switch rc.Method {
+	case HashMethod, TokenizeMethod, EncryptMethod:
+		return nil
+	case "":
+		return errors.New("'method' is required data protection rule parameter")
+	default:
+		return fmt.Errorf("unsupported data protection method: %s. Supported: %s, %s, %s", rc.Method, HashMethod, TokenizeMethod, EncryptMethod)
+	}
+}
+
+func (rc *RuleConfig) String() string {
+	return fmt.Sprintf("[%s] %s", rc.Method, strings.Join(rc.Fields, ", "))
+}
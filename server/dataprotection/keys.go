@@ -0,0 +1,108 @@
+package dataprotection
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+const (
+	envSecretPrefix    = "env://"
+	awsKMSSecretPrefix = "awskms://"
+)
+
+var (
+	// kmsDecrypt decrypts data key with AWS KMS. Credentials and region are taken from the standard AWS environment
+	// variables or shared config
+	kmsDecrypt = func(ciphertext []byte) ([]byte, error) {
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := kms.New(sess).Decrypt(&kms.DecryptInput{CiphertextBlob: ciphertext})
+		if err != nil {
+			return nil, err
+		}
+
+		return output.Plaintext, nil
+	}
+
+	// resolvedSecrets caches resolved secrets for not requesting KMS on every destination reload
+	resolvedSecrets      = map[string][]byte{}
+	resolvedSecretsMutex sync.Mutex
+)
+
+// resolveSecret returns secret value by reference:
+// env://VARIABLE_NAME - value of the environment variable
+// awskms://base64_ciphertext - data key decrypted with AWS KMS
+// otherwise the reference is a plain secret value
+func resolveSecret(reference string) ([]byte, error) {
+	resolvedSecretsMutex.Lock()
+	defer resolvedSecretsMutex.Unlock()
+
+	if secret, ok := resolvedSecrets[reference]; ok {
+		return secret, nil
+	}
+
+	var secret []byte
+	switch {
+	case strings.HasPrefix(reference, envSecretPrefix):
+		name := strings.TrimPrefix(reference, envSecretPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("environment variable %s is empty", name)
+		}
+		secret = []byte(value)
+	case strings.HasPrefix(reference, awsKMSSecretPrefix):
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(reference, awsKMSSecretPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("AWS KMS ciphertext must be base64 encoded: %v", err)
+		}
+		secret, err = kmsDecrypt(ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting data key with AWS KMS: %v", err)
+		}
+	default:
+		secret = []byte(reference)
+	}
+
+	resolvedSecrets[reference] = secret
+	return secret, nil
+}
+
+// resolveKey returns AES key (16, 24 or 32 bytes) by reference. Key value can be hex, base64 or raw bytes
+func resolveKey(reference string) ([]byte, error) {
+	secret, err := resolveSecret(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	// KMS returns raw data key bytes
+	if strings.HasPrefix(reference, awsKMSSecretPrefix) && isValidKeyLength(len(secret)) {
+		return secret, nil
+	}
+
+	value := strings.TrimSpace(string(secret))
+	if key, err := hex.DecodeString(value); err == nil && isValidKeyLength(len(key)) {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && isValidKeyLength(len(key)) {
+		return key, nil
+	}
+	if isValidKeyLength(len(secret)) {
+		return secret, nil
+	}
+
+	return nil, fmt.Errorf("key must be 16, 24 or 32 bytes long (hex or base64 encoded). Got: %d bytes", len(secret))
+}
+
+func isValidKeyLength(length int) bool {
+	return length == 16 || length == 24 || length == 32
+}
@@ -0,0 +1,249 @@
+package dataprotection
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+const (
+	tokenPrefix     = "tok_"
+	encryptedPrefix = "enc_"
+)
+
+var (
+	// ErrMalformedValue is returned if encrypted value can't be decrypted
+	ErrMalformedValue = errors.New("malformed encrypted value")
+
+	defaultConfig *Config
+	defaultMutex  sync.RWMutex
+)
+
+// SetDefault sets global data protection configuration which is used by destinations without own configuration
+func SetDefault(config *Config) error {
+	if config != nil {
+		if _, err := NewStep(config); err != nil {
+			return err
+		}
+	}
+
+	defaultMutex.Lock()
+	defaultConfig = config
+	defaultMutex.Unlock()
+	return nil
+}
+
+// Default returns global data protection configuration or nil if it isn't configured
+func Default() *Config {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultConfig
+}
+
+type rule struct {
+	fields    []jsonutils.JSONPath
+	method    string
+	normalize bool
+}
+
+// Step hashes, tokenizes or encrypts configured event fields. It is executed before mapping and transformation
+// so personal data doesn't reach destinations and user transformations in plain form
+type Step struct {
+	rules []*rule
+	salt  []byte
+	key   []byte
+	aead  cipher.AEAD
+}
+
+// NewStep returns configured Step or nil if there are no rules
+func NewStep(config *Config) (*Step, error) {
+	if config == nil || len(config.Rules) == 0 {
+		return nil, nil
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	step := &Step{}
+	for _, ruleConfig := range config.Rules {
+		if ruleConfig == nil {
+			continue
+		}
+
+		r := &rule{method: ruleConfig.Method, normalize: ruleConfig.Normalize}
+		for _, field := range ruleConfig.Fields {
+			path := jsonutils.NewJSONPath(field)
+			if path.IsEmpty() {
+				return nil, fmt.Errorf("data protection field must be a valid path like: /node1/node2. Got: %q", field)
+			}
+			r.fields = append(r.fields, path)
+		}
+		step.rules = append(step.rules, r)
+	}
+
+	if config.Salt != "" {
+		salt, err := resolveSecret(config.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving data protection salt: %v", err)
+		}
+		step.salt = salt
+	}
+
+	if config.Key != "" {
+		key, err := resolveKey(config.Key)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving data protection key: %v", err)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		step.key = key
+		step.aead = aead
+	}
+
+	return step, nil
+}
+
+// Execute replaces configured fields values with protected ones. Arrays are protected element-wise,
+// nested objects are skipped
+func (s *Step) Execute(object map[string]interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	for _, r := range s.rules {
+		for _, field := range r.fields {
+			value, ok := field.Get(object)
+			if !ok || value == nil {
+				continue
+			}
+
+			protected, err := s.protect(r, value)
+			if err != nil {
+				return fmt.Errorf("error protecting field %s: %v", field.String(), err)
+			}
+
+			if err := field.Set(object, protected); err != nil {
+				return fmt.Errorf("error setting protected field %s: %v", field.String(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Step) protect(r *rule, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return v, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, element := range v {
+			protected, err := s.protect(r, element)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = protected
+		}
+		return result, nil
+	}
+
+	str := fmt.Sprint(value)
+	if r.normalize {
+		str = strings.ToLower(strings.TrimSpace(str))
+	}
+
+	switch r.method {
+	case HashMethod:
+		return Hash(s.salt, str), nil
+	case TokenizeMethod:
+		return Tokenize(s.key, str), nil
+	case EncryptMethod:
+		return encrypt(s.aead, str)
+	default:
+		return nil, fmt.Errorf("unsupported data protection method: %s", r.method)
+	}
+}
+
+// Hash returns hex encoded SHA-256 of salt + value
+func Hash(salt []byte, value string) string {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(value))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Tokenize returns deterministic token of the value: tok_ + base64 of the first 16 bytes of HMAC-SHA256
+func Tokenize(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Encrypt returns enc_ + base64 of random nonce and AES-GCM encrypted value
+func Encrypt(key []byte, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	return encrypt(aead, value)
+}
+
+// Decrypt returns plain value encrypted with Encrypt
+func Decrypt(key []byte, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return "", ErrMalformedValue
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(payload) < aead.NonceSize() {
+		return "", ErrMalformedValue
+	}
+
+	plain, err := aead.Open(nil, payload[:aead.NonceSize()], payload[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+func encrypt(aead cipher.AEAD, value string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	payload := aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating AES cipher: %v", err)
+	}
+
+	return cipher.NewGCM(block)
+}
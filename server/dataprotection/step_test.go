@@ -0,0 +1,122 @@
+package dataprotection
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestStep(t *testing.T) {
+	step, err := NewStep(&Config{
+		Salt: "salt",
+		Key:  testKey,
+		Rules: []*RuleConfig{
+			{Fields: []string{"/user/email", "/emails"}, Method: "HASH", Normalize: true},
+			{Fields: []string{"/source_ip"}, Method: TokenizeMethod},
+			{Fields: []string{"/user/name", "/user/address"}, Method: EncryptMethod},
+		},
+	})
+	require.NoError(t, err)
+
+	object := map[string]interface{}{
+		"source_ip": "10.10.10.10",
+		"emails":    []interface{}{"a@b.com", nil},
+		"user": map[string]interface{}{
+			"email":   " John@Example.com",
+			"name":    "John",
+			"address": map[string]interface{}{"city": "New York"},
+		},
+	}
+	require.NoError(t, step.Execute(object))
+
+	user := object["user"].(map[string]interface{})
+	require.Equal(t, Hash([]byte("salt"), "john@example.com"), user["email"])
+	require.Len(t, user["email"], 64)
+	require.Equal(t, []interface{}{Hash([]byte("salt"), "a@b.com"), nil}, object["emails"])
+	require.Equal(t, map[string]interface{}{"city": "New York"}, user["address"])
+
+	token := object["source_ip"].(string)
+	require.True(t, strings.HasPrefix(token, tokenPrefix))
+	key, _ := resolveKey(testKey)
+	require.Equal(t, Tokenize(key, "10.10.10.10"), token)
+
+	encrypted := user["name"].(string)
+	require.True(t, strings.HasPrefix(encrypted, encryptedPrefix))
+	decrypted, err := Decrypt(key, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "John", decrypted)
+
+	//nil step does nothing
+	var nilStep *Step
+	require.NoError(t, nilStep.Execute(object))
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	first, err := Encrypt(key, "value")
+	require.NoError(t, err)
+	second, err := Encrypt(key, "value")
+	require.NoError(t, err)
+	require.NotEqual(t, first, second, "encryption must use random nonce")
+
+	value, err := Decrypt(key, second)
+	require.NoError(t, err)
+	require.Equal(t, "value", value)
+
+	_, err = Decrypt(key, "abc")
+	require.Equal(t, ErrMalformedValue, err)
+	_, err = Decrypt([]byte("fedcba9876543210"), first)
+	require.Error(t, err)
+}
+
+func TestNewStepErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{"Without fields", &Config{Salt: "salt", Rules: []*RuleConfig{{Method: HashMethod}}}},
+		{"Unknown method", &Config{Salt: "salt", Rules: []*RuleConfig{{Fields: []string{"/email"}, Method: "mask"}}}},
+		{"Hash without salt", &Config{Rules: []*RuleConfig{{Fields: []string{"/email"}, Method: HashMethod}}}},
+		{"Encrypt without key", &Config{Rules: []*RuleConfig{{Fields: []string{"/email"}, Method: EncryptMethod}}}},
+		{"Invalid key length", &Config{Key: "abc", Rules: []*RuleConfig{{Fields: []string{"/email"}, Method: EncryptMethod}}}},
+		{"Empty env", &Config{Salt: "env://JITSU_TEST_EMPTY_SALT", Rules: []*RuleConfig{{Fields: []string{"/email"}, Method: HashMethod}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStep(tt.config)
+			require.Error(t, err)
+		})
+	}
+
+	step, err := NewStep(&Config{})
+	require.NoError(t, err)
+	require.Nil(t, step)
+}
+
+func TestResolveKey(t *testing.T) {
+	os.Setenv("JITSU_TEST_DATA_PROTECTION_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")))
+	defer os.Unsetenv("JITSU_TEST_DATA_PROTECTION_KEY")
+
+	key, err := resolveKey("env://JITSU_TEST_DATA_PROTECTION_KEY")
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abcdef"), key)
+
+	kmsDecrypt = func(ciphertext []byte) ([]byte, error) {
+		if string(ciphertext) != "encrypted" {
+			return nil, errors.New("invalid ciphertext")
+		}
+		return []byte("fedcba9876543210"), nil
+	}
+	key, err = resolveKey("awskms://" + base64.StdEncoding.EncodeToString([]byte("encrypted")))
+	require.NoError(t, err)
+	require.Equal(t, []byte("fedcba9876543210"), key)
+
+	_, err = resolveKey("awskms://" + base64.StdEncoding.EncodeToString([]byte("wrong")))
+	require.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package delivery
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	//StatusPending is a status of an accepted event which hasn't been processed by the destination yet
+	StatusPending = "pending"
+	//StatusDelivered is a status of an event which has been written into the destination
+	StatusDelivered = "delivered"
+	//StatusSkipped is a status of an event which has been skipped by the destination transformation
+	StatusSkipped = "skipped"
+	//StatusFailed is a status of an event which hasn't been written into the destination because of an error
+	StatusFailed = "failed"
+
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+//Record is an event delivery record: ingestion info and the last outcome per destination
+type Record struct {
+	EventID      string                        `json:"event_id"`
+	APIKey       string                        `json:"api_key,omitempty"`
+	ReceivedAt   *time.Time                    `json:"received_at,omitempty"`
+	Destinations map[string]*DestinationStatus `json:"destinations"`
+}
+
+//DestinationStatus is an event delivery outcome in a certain destination
+type DestinationStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	//Generation is a destinations configuration version which has processed the event
+	Generation int64     `json:"generation,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+//Filter is a search filter. Empty fields match all records
+type Filter struct {
+	APIKey        string
+	DestinationID string
+	Status        string
+	Limit         int
+}
+
+//limit returns the filter limit with default and max values applied
+func (f *Filter) limit() int {
+	if f.Limit <= 0 {
+		return defaultSearchLimit
+	}
+	if f.Limit > maxSearchLimit {
+		return maxSearchLimit
+	}
+	return f.Limit
+}
+
+//Match returns true if the record satisfies the filter
+func (f *Filter) Match(record *Record) bool {
+	if f.APIKey != "" && f.APIKey != record.APIKey {
+		return false
+	}
+
+	if f.DestinationID == "" && f.Status == "" {
+		return true
+	}
+
+	for destinationID, status := range record.Destinations {
+		if f.DestinationID != "" && f.DestinationID != destinationID {
+			continue
+		}
+		if f.Status != "" && f.Status != status.Status {
+			continue
+		}
+		return true
+	}
+
+	return false
+}
+
+//lastActivity returns the latest record update time
+func (r *Record) lastActivity() time.Time {
+	var last time.Time
+	if r.ReceivedAt != nil {
+		last = *r.ReceivedAt
+	}
+	for _, status := range r.Destinations {
+		if status.UpdatedAt.After(last) {
+			last = status.UpdatedAt
+		}
+	}
+	return last
+}
+
+//sortRecords sorts records by the last activity in descending order
+func sortRecords(records []*Record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].lastActivity().After(records[j].lastActivity())
+	})
+}
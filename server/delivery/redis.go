@@ -0,0 +1,256 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/metrics"
+)
+
+//** Events delivery tracking **
+//delivery:event#${eventID} [api_key, received_at, destination#${destinationID}] - hashtable with the event delivery record.
+//Destination fields contain DestinationStatus JSON
+//delivery:index [eventID] score: creation unix ms - sorted set of all records for search and trimming
+const (
+	eventKeyPrefix       = "delivery:event#"
+	indexKey             = "delivery:index"
+	apiKeyField          = "api_key"
+	receivedAtField      = "received_at"
+	destinationKeyPrefix = "destination#"
+
+	searchPageSize = 100
+	trimBatchSize  = 1000
+)
+
+//Redis is a Storage based on Redis hashtables with TTL and a sorted set index bounded by capacity
+type Redis struct {
+	pool         *meta.RedisPool
+	capacity     int
+	ttlSeconds   int
+	errorMetrics *meta.ErrorMetrics
+}
+
+//NewRedis returns configured Redis storage
+func NewRedis(pool *meta.RedisPool, capacity int, ttl time.Duration) *Redis {
+	return &Redis{
+		pool:         pool,
+		capacity:     capacity,
+		ttlSeconds:   int(ttl.Seconds()),
+		errorMetrics: meta.NewErrorMetrics(metrics.MetaRedisErrors),
+	}
+}
+
+//Received creates the record with pending statuses. HSETNX keeps outcomes which have been written before
+func (r *Redis) Received(eventID, apiKey string, destinationIDs []string, receivedAt time.Time) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	eventKey := eventKeyPrefix + eventID
+	if _, err := conn.Do("HSET", eventKey, apiKeyField, apiKey, receivedAtField, receivedAt.UnixNano()/int64(time.Millisecond)); err != nil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	pending, _ := json.Marshal(&DestinationStatus{Status: StatusPending, UpdatedAt: receivedAt})
+	for _, destinationID := range destinationIDs {
+		if _, err := conn.Do("HSETNX", eventKey, destinationKeyPrefix+destinationID, pending); err != nil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+	}
+
+	return r.index(conn, eventKey, eventID, receivedAt)
+}
+
+//Update writes the destination outcome
+func (r *Redis) Update(eventID, destinationID string, status *DestinationStatus) error {
+	serialized, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to serialize delivery status [%v]: %v", status, err)
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	eventKey := eventKeyPrefix + eventID
+	if _, err := conn.Do("HSET", eventKey, destinationKeyPrefix+destinationID, serialized); err != nil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	return r.index(conn, eventKey, eventID, status.UpdatedAt)
+}
+
+//index sets the record TTL and adds the record into the index if it isn't there
+func (r *Redis) index(conn redis.Conn, eventKey, eventID string, createdAt time.Time) error {
+	if r.ttlSeconds > 0 {
+		if _, err := conn.Do("EXPIRE", eventKey, r.ttlSeconds); err != nil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+	}
+
+	if _, err := conn.Do("ZADD", indexKey, "NX", createdAt.UnixNano()/int64(time.Millisecond), eventID); err != nil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	return nil
+}
+
+//Get returns the record or nil if it doesn't exist (or has been expired)
+func (r *Redis) Get(eventID string) (*Record, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return r.get(conn, eventID)
+}
+
+func (r *Redis) get(conn redis.Conn, eventID string) (*Record, error) {
+	fields, err := redis.StringMap(conn.Do("HGETALL", eventKeyPrefix+eventID))
+	if err != nil && err != redis.ErrNil {
+		r.errorMetrics.NoticeError(err)
+		return nil, err
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return parseRecord(eventID, fields)
+}
+
+//Search scans the index from the newest records and returns records which match the filter.
+//Not more than capacity records are scanned
+func (r *Redis) Search(filter *Filter) ([]*Record, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	limit := filter.limit()
+	records := []*Record{}
+	for start := 0; len(records) < limit && (r.capacity <= 0 || start < r.capacity); start += searchPageSize {
+		eventIDs, err := redis.Strings(conn.Do("ZREVRANGE", indexKey, start, start+searchPageSize-1))
+		if err != nil && err != redis.ErrNil {
+			r.errorMetrics.NoticeError(err)
+			return nil, err
+		}
+
+		for _, eventID := range eventIDs {
+			record, err := r.get(conn, eventID)
+			if err != nil {
+				return nil, err
+			}
+			if record != nil && filter.Match(record) {
+				records = append(records, record)
+				if len(records) == limit {
+					break
+				}
+			}
+		}
+
+		if len(eventIDs) < searchPageSize {
+			break
+		}
+	}
+
+	return records, nil
+}
+
+//Trim removes the oldest records out of capacity and expired records from the index
+func (r *Redis) Trim(now time.Time) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if r.ttlSeconds > 0 {
+		threshold := now.Add(-time.Duration(r.ttlSeconds)*time.Second).UnixNano() / int64(time.Millisecond)
+		if _, err := conn.Do("ZREMRANGEBYSCORE", indexKey, "-inf", threshold); err != nil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+	}
+
+	if r.capacity <= 0 {
+		return nil
+	}
+
+	for {
+		total, err := redis.Int(conn.Do("ZCARD", indexKey))
+		if err != nil && err != redis.ErrNil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+
+		excess := total - r.capacity
+		if excess <= 0 {
+			return nil
+		}
+		if excess > trimBatchSize {
+			excess = trimBatchSize
+		}
+
+		eventIDs, err := redis.Strings(conn.Do("ZRANGE", indexKey, 0, excess-1))
+		if err != nil && err != redis.ErrNil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+		if len(eventIDs) == 0 {
+			return nil
+		}
+
+		keys := make([]string, len(eventIDs))
+		members := make([]interface{}, len(eventIDs)+1)
+		members[0] = indexKey
+		for i, eventID := range eventIDs {
+			keys[i] = eventKeyPrefix + eventID
+			members[i+1] = eventID
+		}
+		if _, err := r.pool.DeleteKeys(conn, keys...); err != nil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+		if _, err := conn.Do("ZREM", members...); err != nil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+	}
+}
+
+func (r *Redis) Type() string {
+	return RedisStorageType
+}
+
+func (r *Redis) Close() error {
+	return r.pool.Close()
+}
+
+//parseRecord returns Record from Redis hashtable fields
+func parseRecord(eventID string, fields map[string]string) (*Record, error) {
+	record := &Record{EventID: eventID, APIKey: fields[apiKeyField], Destinations: map[string]*DestinationStatus{}}
+	if receivedAt, ok := fields[receivedAtField]; ok {
+		ms, err := strconv.ParseInt(receivedAt, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of event [%s]: %v", receivedAtField, eventID, err)
+		}
+		t := time.Unix(0, ms*int64(time.Millisecond)).UTC()
+		record.ReceivedAt = &t
+	}
+
+	for field, value := range fields {
+		if !strings.HasPrefix(field, destinationKeyPrefix) {
+			continue
+		}
+
+		status := &DestinationStatus{}
+		if err := json.Unmarshal([]byte(value), status); err != nil {
+			return nil, fmt.Errorf("failed to deserialize delivery status of event [%s] %s: %v", eventID, field, err)
+		}
+		record.Destinations[strings.TrimPrefix(field, destinationKeyPrefix)] = status
+	}
+
+	return record, nil
+}
@@ -0,0 +1,193 @@
+package delivery
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+var instance *Service
+
+//update is a channel dto: received event or destination outcome
+type update struct {
+	eventID        string
+	apiKey         string
+	destinationIDs []string
+	receivedAt     time.Time
+	destinationID  string
+	status         *DestinationStatus
+}
+
+//Service writes delivery records asynchronously (events processing isn't blocked by the storage)
+//and trims the storage periodically
+type Service struct {
+	storage Storage
+	updates chan *update
+
+	//closeMutex guards updates channel from writing after closing
+	closeMutex sync.RWMutex
+	closed     bool
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+//NewService returns Service and starts poolSize writing goroutines and the trimmer
+func NewService(storage Storage, poolSize int, trimInterval time.Duration) *Service {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+
+	s := &Service{
+		storage: storage,
+		updates: make(chan *update, poolSize*10000),
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < poolSize; i++ {
+		s.wg.Add(1)
+		safego.Run(func() {
+			defer s.wg.Done()
+			for u := range s.updates {
+				s.write(u)
+			}
+		})
+	}
+
+	s.startTrimmer(trimInterval)
+	return s
+}
+
+//Init creates the global Service
+func Init(storage Storage, poolSize int, trimInterval time.Duration) *Service {
+	instance = NewService(storage, poolSize, trimInterval)
+	return instance
+}
+
+//Instance returns the global Service or nil if delivery tracking is disabled
+func Instance() *Service {
+	return instance
+}
+
+func (s *Service) write(u *update) {
+	var err error
+	if u.status == nil {
+		err = s.storage.Received(u.eventID, u.apiKey, u.destinationIDs, u.receivedAt)
+	} else {
+		err = s.storage.Update(u.eventID, u.destinationID, u.status)
+	}
+
+	if err != nil {
+		logging.Errorf("[delivery] Error saving event [%s] delivery record: %v", u.eventID, err)
+	}
+}
+
+func (s *Service) startTrimmer(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if err := s.storage.Trim(timestamp.Now().UTC()); err != nil {
+					logging.Warnf("[delivery] Error trimming delivery records: %v", err)
+				}
+			}
+		}
+	})
+}
+
+//enqueue puts the update into the channel. Updates are dropped if the channel is full
+func (s *Service) enqueue(u *update) {
+	s.closeMutex.RLock()
+	defer s.closeMutex.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.updates <- u:
+	default:
+		if rand.Int31n(1000) == 0 {
+			logging.Warnf("[delivery] Delivery records queue overflow. Some events won't be tracked. Consider increasing delivery_tracking.pool_size")
+		}
+	}
+}
+
+//Get returns the event delivery record or nil if it doesn't exist
+func (s *Service) Get(eventID string) (*Record, error) {
+	return s.storage.Get(eventID)
+}
+
+//Search returns the last delivery records which match the filter
+func (s *Service) Search(filter *Filter) ([]*Record, error) {
+	records, err := s.storage.Search(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sortRecords(records)
+	return records, nil
+}
+
+//Close stops the trimmer, writes queued updates and closes the storage
+func (s *Service) Close() error {
+	s.closeMutex.Lock()
+	if s.closed {
+		s.closeMutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	close(s.updates)
+	s.closeMutex.Unlock()
+
+	s.wg.Wait()
+
+	return s.storage.Close()
+}
+
+//Received records the accepted event with pending statuses of the destinations
+func Received(eventID, apiKey string, destinationIDs []string) {
+	if instance == nil || eventID == "" {
+		return
+	}
+
+	instance.enqueue(&update{eventID: eventID, apiKey: apiKey, destinationIDs: destinationIDs, receivedAt: timestamp.Now().UTC()})
+}
+
+//Delivered records the event has been written into the destination
+func Delivered(eventID, destinationID string, generation int64) {
+	outcome(eventID, destinationID, generation, StatusDelivered, "")
+}
+
+//Skipped records the event has been skipped by the destination transformation
+func Skipped(eventID, destinationID string, generation int64, reason string) {
+	outcome(eventID, destinationID, generation, StatusSkipped, reason)
+}
+
+//Failed records the event hasn't been written into the destination because of the error
+func Failed(eventID, destinationID string, generation int64, errMsg string) {
+	outcome(eventID, destinationID, generation, StatusFailed, errMsg)
+}
+
+func outcome(eventID, destinationID string, generation int64, status, errMsg string) {
+	if instance == nil || eventID == "" {
+		return
+	}
+
+	instance.enqueue(&update{
+		eventID:       eventID,
+		destinationID: destinationID,
+		status:        &DestinationStatus{Status: status, Error: errMsg, Generation: generation, UpdatedAt: timestamp.Now().UTC()},
+	})
+}
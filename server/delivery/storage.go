@@ -0,0 +1,204 @@
+package delivery
+
+import (
+	"container/list"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/spf13/viper"
+)
+
+const (
+	MemoryStorageType = "memory"
+	RedisStorageType  = "redis"
+)
+
+//Storage is a bounded storage of delivery records. Records are kept not longer than TTL and
+//only last capacity records are kept
+type Storage interface {
+	io.Closer
+	//Received creates the record with pending statuses of all destinations. Doesn't overwrite already written outcomes
+	Received(eventID, apiKey string, destinationIDs []string, receivedAt time.Time) error
+	//Update writes the event outcome in the destination
+	Update(eventID, destinationID string, status *DestinationStatus) error
+	//Get returns the record or nil if it doesn't exist
+	Get(eventID string) (*Record, error)
+	//Search returns the last records which match the filter
+	Search(filter *Filter) ([]*Record, error)
+	//Trim removes records out of capacity and TTL
+	Trim(now time.Time) error
+	Type() string
+}
+
+//InitializeStorage returns configured Storage: redis if delivery_tracking.redis or meta.storage.redis is configured
+//or memory otherwise
+func InitializeStorage(metaStorageConfiguration *viper.Viper, capacity int, ttl time.Duration) (Storage, error) {
+	var redisConfigurationSource *viper.Viper
+
+	if metaStorageConfiguration != nil {
+		//redis config from meta.storage section
+		redisConfigurationSource = metaStorageConfiguration.Sub("redis")
+	}
+
+	//get redis configuration from separated config section if configured
+	if viper.GetString("delivery_tracking.redis.host") != "" {
+		redisConfigurationSource = viper.Sub("delivery_tracking.redis")
+	}
+
+	if redisConfigurationSource == nil || redisConfigurationSource.GetString("host") == "" {
+		logging.Infof("📬 Events delivery records are stored in memory (last %d events)", capacity)
+		return NewMemory(capacity, ttl), nil
+	}
+
+	factory := meta.NewRedisPoolFactory(redisConfigurationSource.GetString("host"), redisConfigurationSource.GetInt("port"),
+		redisConfigurationSource.GetString("password"), redisConfigurationSource.GetInt("database"),
+		redisConfigurationSource.GetBool("tls_skip_verify"), redisConfigurationSource.GetString("sentinel_master_name")).
+		WithConfiguration(redisConfigurationSource)
+	options := factory.GetOptions()
+	options.MaxActive = 100
+	factory.WithOptions(options)
+	factory.CheckAndSetDefaultPort()
+
+	logging.Infof("📬 Initializing events delivery records redis [%s] (last %d events)...", factory.Details(), capacity)
+	pool, err := factory.Create()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRedis(pool, capacity, ttl), nil
+}
+
+//Memory is an in-memory Storage for single node deployments without Redis
+type Memory struct {
+	capacity int
+	ttl      time.Duration
+
+	mutex   sync.RWMutex
+	records map[string]*list.Element
+	//order keeps *Record in creation order: the oldest is in front
+	order *list.List
+}
+
+//NewMemory returns configured Memory storage
+func NewMemory(capacity int, ttl time.Duration) *Memory {
+	return &Memory{
+		capacity: capacity,
+		ttl:      ttl,
+		records:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+//Received creates the record with pending statuses
+func (m *Memory) Received(eventID, apiKey string, destinationIDs []string, receivedAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	record := m.getOrCreate(eventID)
+	record.APIKey = apiKey
+	record.ReceivedAt = &receivedAt
+	for _, destinationID := range destinationIDs {
+		if _, ok := record.Destinations[destinationID]; !ok {
+			record.Destinations[destinationID] = &DestinationStatus{Status: StatusPending, UpdatedAt: receivedAt}
+		}
+	}
+	return nil
+}
+
+//Update writes the destination outcome
+func (m *Memory) Update(eventID, destinationID string, status *DestinationStatus) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	statusCopy := *status
+	m.getOrCreate(eventID).Destinations[destinationID] = &statusCopy
+	return nil
+}
+
+//getOrCreate returns the record and removes the oldest records out of capacity. Must be called under the lock
+func (m *Memory) getOrCreate(eventID string) *Record {
+	if element, ok := m.records[eventID]; ok {
+		return element.Value.(*Record)
+	}
+
+	record := &Record{EventID: eventID, Destinations: map[string]*DestinationStatus{}}
+	m.records[eventID] = m.order.PushBack(record)
+	for m.capacity > 0 && m.order.Len() > m.capacity {
+		m.remove(m.order.Front())
+	}
+	return record
+}
+
+func (m *Memory) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.records, element.Value.(*Record).EventID)
+}
+
+//Get returns a copy of the record or nil
+func (m *Memory) Get(eventID string) (*Record, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	element, ok := m.records[eventID]
+	if !ok {
+		return nil, nil
+	}
+	return copyRecord(element.Value.(*Record)), nil
+}
+
+//Search returns the last created records which match the filter
+func (m *Memory) Search(filter *Filter) ([]*Record, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	limit := filter.limit()
+	records := []*Record{}
+	for element := m.order.Back(); element != nil && len(records) < limit; element = element.Prev() {
+		record := element.Value.(*Record)
+		if filter.Match(record) {
+			records = append(records, copyRecord(record))
+		}
+	}
+	return records, nil
+}
+
+//Trim removes records which haven't been updated longer than TTL
+func (m *Memory) Trim(now time.Time) error {
+	if m.ttl <= 0 {
+		return nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	threshold := now.Add(-m.ttl)
+	for element := m.order.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*Record).lastActivity().Before(threshold) {
+			m.remove(element)
+		}
+		element = next
+	}
+	return nil
+}
+
+func (m *Memory) Type() string {
+	return MemoryStorageType
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+func copyRecord(record *Record) *Record {
+	recordCopy := *record
+	recordCopy.Destinations = make(map[string]*DestinationStatus, len(record.Destinations))
+	for destinationID, status := range record.Destinations {
+		statusCopy := *status
+		recordCopy.Destinations[destinationID] = &statusCopy
+	}
+	return &recordCopy
+}
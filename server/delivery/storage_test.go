@@ -0,0 +1,105 @@
+package delivery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storage := NewMemory(3, time.Hour)
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	//outcome is written before the received event: pending status mustn't overwrite it
+	require.NoError(t, storage.Update("event1", "dest1", &DestinationStatus{Status: StatusDelivered, UpdatedAt: now.Add(time.Second)}))
+	require.NoError(t, storage.Received("event1", "key1", []string{"dest1", "dest2"}, now))
+	require.NoError(t, storage.Received("event2", "key2", []string{"dest1"}, now))
+	require.NoError(t, storage.Update("event2", "dest1", &DestinationStatus{Status: StatusFailed, Error: "connection refused", UpdatedAt: now.Add(time.Second)}))
+
+	record, err := storage.Get("event1")
+	require.NoError(t, err)
+	require.Equal(t, "key1", record.APIKey)
+	require.Equal(t, now, *record.ReceivedAt)
+	require.Equal(t, StatusDelivered, record.Destinations["dest1"].Status)
+	require.Equal(t, StatusPending, record.Destinations["dest2"].Status)
+
+	records, err := storage.Search(&Filter{Status: StatusFailed})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "event2", records[0].EventID)
+	require.Equal(t, "connection refused", records[0].Destinations["dest1"].Error)
+
+	records, err = storage.Search(&Filter{APIKey: "key1", DestinationID: "dest2", Status: StatusPending})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	records, err = storage.Search(&Filter{DestinationID: "dest2", Status: StatusDelivered})
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	//only last 3 records are kept
+	require.NoError(t, storage.Received("event3", "key1", []string{"dest1"}, now))
+	require.NoError(t, storage.Received("event4", "key1", []string{"dest1"}, now.Add(2*time.Hour)))
+	record, err = storage.Get("event1")
+	require.NoError(t, err)
+	require.Nil(t, record)
+
+	records, err = storage.Search(&Filter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "event4", records[0].EventID)
+
+	//expired records are removed
+	require.NoError(t, storage.Trim(now.Add(90*time.Minute)))
+	records, err = storage.Search(&Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "event4", records[0].EventID)
+}
+
+func TestService(t *testing.T) {
+	storage := NewMemory(100, 0)
+	service := Init(storage, 2, 0)
+	defer func() {
+		instance = nil
+	}()
+
+	Received("event1", "key1", []string{"dest1", "dest2", "dest3"})
+	Delivered("event1", "dest1", 2)
+	Skipped("event1", "dest2", 1, "transformation returned null")
+	Failed("event1", "dest3", 1, "table doesn't exist")
+	//events without ID aren't tracked
+	Delivered("", "dest1", 1)
+	require.NoError(t, service.Close())
+
+	record, err := storage.Get("event1")
+	require.NoError(t, err)
+	require.Len(t, record.Destinations, 3)
+	require.Equal(t, StatusDelivered, record.Destinations["dest1"].Status)
+	require.Equal(t, int64(2), record.Destinations["dest1"].Generation)
+	require.Equal(t, StatusSkipped, record.Destinations["dest2"].Status)
+	require.Equal(t, "transformation returned null", record.Destinations["dest2"].Error)
+	require.Equal(t, StatusFailed, record.Destinations["dest3"].Status)
+
+	records, err := storage.Search(&Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	//updates after closing are ignored
+	Delivered("event2", "dest1", 1)
+}
+
+func TestParseRecord(t *testing.T) {
+	record, err := parseRecord("event1", map[string]string{
+		apiKeyField:                    "key1",
+		receivedAtField:                "1654077600000",
+		destinationKeyPrefix + "dest1": `{"status":"failed","error":"timeout","updated_at":"2022-06-01T10:00:01Z"}`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "key1", record.APIKey)
+	require.Equal(t, time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC), *record.ReceivedAt)
+	require.Equal(t, &DestinationStatus{Status: StatusFailed, Error: "timeout", UpdatedAt: time.Date(2022, 6, 1, 10, 0, 1, 0, time.UTC)}, record.Destinations["dest1"])
+
+	_, err = parseRecord("event1", map[string]string{destinationKeyPrefix + "dest1": "not json"})
+	require.Error(t, err)
+}
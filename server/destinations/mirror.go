@@ -0,0 +1,80 @@
+package destinations
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+//expandMirrors returns destinations configurations without mirrors and members of the valid mirrors by mirror IDs.
+//Mirror only_tokens are added to members only_tokens: every member consumes mirror API keys events with its own
+//queue (stream mode) or upload status (batch mode), retries and statistics
+func expandMirrors(dc map[string]config.DestinationConfig) (map[string]config.DestinationConfig, map[string][]string) {
+	result := make(map[string]config.DestinationConfig, len(dc))
+	var mirrorIDs []string
+	for id, destination := range dc {
+		if destination.Type == storages.MirrorType {
+			mirrorIDs = append(mirrorIDs, id)
+			continue
+		}
+		result[id] = destination
+	}
+	sort.Strings(mirrorIDs)
+
+	mirrors := map[string][]string{}
+	for _, mirrorID := range mirrorIDs {
+		mirror := dc[mirrorID]
+		if err := validateMirror(mirrorID, mirror.Mirror, result); err != nil {
+			logging.Errorf("[%s] Error initializing mirror destination: %v. Mirror will be skipped", mirrorID, err)
+			continue
+		}
+
+		for _, memberID := range mirror.Mirror.Destinations {
+			member := result[memberID]
+			member.OnlyTokens = mergeTokens(member.OnlyTokens, mirror.OnlyTokens)
+			result[memberID] = member
+		}
+
+		members := append([]string{}, mirror.Mirror.Destinations...)
+		sort.Strings(members)
+		mirrors[mirrorID] = members
+	}
+
+	return result, mirrors
+}
+
+//validateMirror returns err if the mirror configuration is invalid or refers to destinations which aren't configured
+func validateMirror(mirrorID string, mirror *config.Mirror, destinations map[string]config.DestinationConfig) error {
+	if mirror == nil {
+		return fmt.Errorf("mirror section is required in %s destination", storages.MirrorType)
+	}
+
+	if err := mirror.Validate(mirrorID); err != nil {
+		return err
+	}
+
+	for _, memberID := range mirror.Destinations {
+		if _, ok := destinations[memberID]; !ok {
+			return fmt.Errorf("destination [%s] isn't configured or is a mirror", memberID)
+		}
+	}
+
+	return nil
+}
+
+//mergeTokens returns a new slice with unique tokens of both slices
+func mergeTokens(tokens, other []string) []string {
+	unique := map[string]bool{}
+	merged := make([]string, 0, len(tokens)+len(other))
+	for _, token := range append(append([]string{}, tokens...), other...) {
+		if !unique[token] {
+			unique[token] = true
+			merged = append(merged, token)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,34 @@
+package destinations
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandMirrors(t *testing.T) {
+	dc := map[string]config.DestinationConfig{
+		"eu":   {Type: storages.PostgresType, OnlyTokens: []string{"token1"}},
+		"us":   {Type: storages.PostgresType},
+		"apac": {Type: storages.PostgresType, OnlyTokens: []string{"token3"}},
+		"warehouse": {Type: storages.MirrorType, OnlyTokens: []string{"token1", "token2"},
+			Mirror: &config.Mirror{Destinations: []string{"us", "eu"}}},
+		"nested":  {Type: storages.MirrorType, Mirror: &config.Mirror{Destinations: []string{"warehouse", "apac"}}},
+		"unknown": {Type: storages.MirrorType, Mirror: &config.Mirror{Destinations: []string{"eu", "unknown_destination"}}},
+		"empty":   {Type: storages.MirrorType},
+	}
+
+	expanded, mirrors := expandMirrors(dc)
+
+	require.Equal(t, map[string][]string{"warehouse": {"eu", "us"}}, mirrors)
+	require.Len(t, expanded, 3)
+	require.Equal(t, []string{"token1", "token2"}, expanded["eu"].OnlyTokens)
+	require.Equal(t, []string{"token1", "token2"}, expanded["us"].OnlyTokens)
+	require.Equal(t, []string{"token3"}, expanded["apac"].OnlyTokens)
+
+	//the original configuration isn't changed: it is expanded again on force reload
+	require.Equal(t, []string{"token1"}, dc["eu"].OnlyTokens)
+	require.Empty(t, dc["us"].OnlyTokens)
+}
@@ -13,11 +13,14 @@ import (
 	"github.com/jitsucom/jitsu/server/logevents"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/resources"
+	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/storages"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/mailru/go-clickhouse"
 	"github.com/spf13/viper"
 	"io/ioutil"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -58,8 +61,12 @@ type Service struct {
 
 	//events queues by destination ID
 	queueConsumerByDestinationID map[string]events.Consumer
+	//members destinations IDs by mirror IDs
+	mirrors map[string][]string
 
 	strictAuth bool
+	//generation is incremented on every configuration reload. Storages are created with the current generation
+	generation int64
 }
 
 // NewTestService returns test instance. It is used only for tests
@@ -162,6 +169,29 @@ func (s *Service) GetConsumers(tokenID string) (consumers []events.Consumer) {
 	return
 }
 
+//GetRoutedConsumers returns consumers of the routed destinations (see routing package).
+//Batch destinations share one consumer per token: it is returned if at least one of them is routed
+//and not routed events are skipped by batch storages processors
+func (s *Service) GetRoutedConsumers(tokenID string, destinationIDs map[string]bool) (consumers []events.Consumer) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	batchRouted := false
+	for id := range s.batchStoragesByTokenID[tokenID] {
+		if destinationIDs[id] {
+			batchRouted = true
+			break
+		}
+	}
+
+	for id, c := range s.consumersByTokenID[tokenID] {
+		if destinationIDs[id] || (id == tokenID && batchRouted) {
+			consumers = append(consumers, c)
+		}
+	}
+	return
+}
+
 func (s *Service) GetDestinationByID(id string) (storages.StorageProxy, bool) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -216,6 +246,47 @@ func (s *Service) GetDestinationIDs(tokenID string) map[string]bool {
 	return ids
 }
 
+// GetAllDestinationIDs returns sorted IDs of all configured destinations
+func (s *Service) GetAllDestinationIDs() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ids := make([]string, 0, len(s.unitsByID))
+	for id := range s.unitsByID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+//GetMirrors returns sorted members IDs by mirror destinations IDs
+func (s *Service) GetMirrors() map[string][]string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	mirrors := make(map[string][]string, len(s.mirrors))
+	for mirrorID, members := range s.mirrors {
+		mirrors[mirrorID] = append([]string{}, members...)
+	}
+	return mirrors
+}
+
+//ExpandMirrors returns destinations IDs where mirror IDs are replaced with their members IDs
+func (s *Service) ExpandMirrors(destinationIDs []string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	expanded := make([]string, 0, len(destinationIDs))
+	for _, id := range destinationIDs {
+		if members, ok := s.mirrors[id]; ok {
+			expanded = append(expanded, members...)
+		} else {
+			expanded = append(expanded, id)
+		}
+	}
+	return expanded
+}
+
 func (s *Service) GetEventsConsumerByDestinationID(destinationID string) (events.Consumer, bool) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -245,8 +316,14 @@ func (s *Service) updateDestinations(payload []byte) {
 
 // 1. close and remove all destinations which don't exist in new config
 // 2. recreate/create changed/new destinations
+// 3. atomically switch changed destinations to the new versions and close the old versions in background.
+// Stream destinations events queue is handed off to the new version so in-flight events aren't dropped
 func (s *Service) init(dc map[string]config.DestinationConfig) {
 	StatusInstance.Reloading = true
+	s.generation++
+
+	//mirrors aren't storages: their API keys are linked to the members
+	dc, mirrors := expandMirrors(dc)
 
 	//close and remove non-existent (in new config)
 	toDelete := map[string]*Unit{}
@@ -270,6 +347,9 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 	newSynchronousStorages := TokenizedStorages{}
 	newIDs := TokenizedIDs{}
 	queueConsumerByDestinationID := map[string]events.Consumer{}
+	newUnits := map[string]*Unit{}
+	//old versions of changed destinations which are replaced with the new ones
+	replacedUnits := map[string]*Unit{}
 
 	for destinationID, d := range dc {
 		//common case
@@ -290,29 +370,52 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 			continue
 		}
 
-		unit, ok := s.unitsByID[id]
+		oldUnit, ok := s.unitsByID[id]
 		if ok {
-			if unit.hash == hash {
+			if oldUnit.hash == hash {
 				//destination wasn't changed
 				continue
 			}
-			//remove old (for recreation)
-			s.mutex.Lock()
-			s.removeAndClose(id, unit)
-			s.mutex.Unlock()
 		}
 
 		if !s.strictAuth && len(destinationConfig.OnlyTokens) == 0 {
+			if oldUnit != nil {
+				s.mutex.Lock()
+				s.removeAndClose(id, oldUnit)
+				s.mutex.Unlock()
+			}
 			logging.Warnf("[%s] destination's authorization isn't ready. Will be created in next reloading cycle.", id)
 			//authorization tokens weren't loaded => create this destination when authorization service will be reloaded
 			//and call force reload on this service
 			continue
 		}
 
+		//stream destination events queue is handed off to the new version: old streaming workers finish
+		//the current events and the new ones continue reading the same queue
+		var handedOffQueue events.Queue
+		if oldUnit != nil {
+			if oldUnit.eventQueue != nil && oldUnit.storage.Mode() == storages.StreamMode && destinationConfig.Mode == storages.StreamMode {
+				handedOffQueue = oldUnit.eventQueue
+				if !reflect.DeepEqual(oldUnit.queueConfig, destinationConfig.Queue) {
+					logging.Warnf("[%s] events queue configuration has been changed. It will be applied after the server restart", id)
+				}
+			} else {
+				//the queue can't be shared: remove old (for recreation)
+				s.mutex.Lock()
+				s.removeAndClose(id, oldUnit)
+				s.mutex.Unlock()
+				oldUnit = nil
+			}
+		}
+
 		//create new
-		newStorageProxy, eventQueue, err := s.storageFactory.Create(id, destinationConfig)
+		newStorageProxy, eventQueue, err := s.storageFactory.Create(id, destinationConfig, s.generation, handedOffQueue)
 		if err != nil {
-			logging.Errorf("[%s] Error initializing destination of type %s: %v", id, destinationConfig.Type, err)
+			if oldUnit != nil {
+				logging.Errorf("[%s] Error initializing new version of destination of type %s: %v. The previous version will be used", id, destinationConfig.Type, err)
+			} else {
+				logging.Errorf("[%s] Error initializing destination of type %s: %v", id, destinationConfig.Type, err)
+			}
 			continue
 		}
 		storageType, ok := storages.StorageTypes[destinationConfig.Type]
@@ -320,15 +423,22 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 			destinationConfig.Mode = storages.SynchronousMode
 		}
 		if eventQueue != nil {
-			appconfig.Instance.ScheduleEventsConsumerClosing(eventQueue)
+			if eventQueue != handedOffQueue {
+				appconfig.Instance.ScheduleEventsConsumerClosing(eventQueue)
+			}
 			queueConsumerByDestinationID[id] = eventQueue
 		}
+		if oldUnit != nil {
+			replacedUnits[id] = oldUnit
+		}
 
-		s.unitsByID[id] = &Unit{
-			eventQueue: eventQueue,
-			storage:    newStorageProxy,
-			tokenIDs:   destinationConfig.OnlyTokens,
-			hash:       hash,
+		newUnits[id] = &Unit{
+			eventQueue:  eventQueue,
+			storage:     newStorageProxy,
+			tokenIDs:    destinationConfig.OnlyTokens,
+			hash:        hash,
+			queueConfig: destinationConfig.Queue,
+			generation:  s.generation,
 		}
 
 		//create:
@@ -356,8 +466,8 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 				if !ok {
 					token := appconfig.Instance.AuthorizationService.GetToken(tokenID)
 					batchPeriodMin := 0
-					if token != nil && token.BatchPeriodMin > 0 {
-						batchPeriodMin = token.BatchPeriodMin
+					if token != nil {
+						batchPeriodMin = token.GetBatchPeriodMin(0)
 					}
 					incomeLogger := s.loggerFactory.CreateIncomingLogger(tokenID, batchPeriodMin)
 					appconfig.Instance.ScheduleEventsConsumerClosing(incomeLogger)
@@ -378,6 +488,12 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 	}
 
 	s.mutex.Lock()
+	for id, oldUnit := range replacedUnits {
+		s.remove(id, oldUnit)
+	}
+	for id, unit := range newUnits {
+		s.unitsByID[id] = unit
+	}
 	s.consumersByTokenID.AddAll(newConsumers)
 	s.batchStoragesByTokenID.AddAll(newStorages)
 	s.synchronousStoragesByTokenID.AddAll(newSynchronousStorages)
@@ -386,14 +502,41 @@ func (s *Service) init(dc map[string]config.DestinationConfig) {
 	for destinationID, eventsQueueConsumer := range queueConsumerByDestinationID {
 		s.queueConsumerByDestinationID[destinationID] = eventsQueueConsumer
 	}
+	s.mirrors = mirrors
 	s.mutex.Unlock()
 
+	//old versions aren't used by new events anymore. Close them when in-flight events are written
+	for id, oldUnit := range replacedUnits {
+		destinationID := id
+		unit := oldUnit
+		safego.Run(func() {
+			//the events queue is used by the new version
+			if err := unit.CloseStorage(); err != nil {
+				logging.Errorf("[%s] Error closing previous version [%d] of destination: %v", destinationID, unit.generation, err)
+				return
+			}
+			logging.Infof("[%s] previous version [%d] of destination has been closed", destinationID, unit.generation)
+		})
+	}
+
 	StatusInstance.Reloading = false
 }
 
 // removeAndClose removes and closes destination from all collections and close it
 // method must be called with locks
 func (s *Service) removeAndClose(destinationID string, unit *Unit) {
+	s.remove(destinationID, unit)
+
+	if err := unit.Close(); err != nil {
+		logging.Errorf("[%s] Error closing unit: %v", destinationID, err)
+	}
+
+	logging.Infof("[%s] destination has been removed!", destinationID)
+}
+
+// remove removes destination from all collections. The unit isn't closed
+// method must be called with locks
+func (s *Service) remove(destinationID string, unit *Unit) {
 	//remove from other collections: queue or logger(if needed) + storage
 	for _, tokenID := range unit.tokenIDs {
 		//id
@@ -450,12 +593,7 @@ func (s *Service) removeAndClose(destinationID string, unit *Unit) {
 		delete(s.queueConsumerByDestinationID, destinationID)
 	}
 
-	if err := unit.Close(); err != nil {
-		logging.Errorf("[%s] Error closing unit: %v", destinationID, err)
-	}
-
 	delete(s.unitsByID, destinationID)
-	logging.Infof("[%s] destination has been removed!", destinationID)
 }
 
 func (s *Service) GetFactory() storages.Factory {
@@ -286,3 +286,45 @@ func startTestServer(ph *payloadHolder) *httptest.Server {
 			w.Write(ph.payload)
 		}))
 }
+
+func TestServiceReloadHandsOffQueue(t *testing.T) {
+	viper.Set("server.destinations_reload_sec", 1)
+	viper.Set("server.api_keys_reload_sec", 1)
+	viper.Set("server.log.path", "")
+	viper.Set("sql_debug_log.ddl.enabled", false)
+
+	mockAuthServer := startTestServer(&payloadHolder{payload: []byte(`{"tokens": [{"client_secret": "token1"}]}`)})
+	defer mockAuthServer.Close()
+	viper.Set("server.auth", mockAuthServer.URL)
+	appconfig.Init(false, "")
+
+	loggerFactory := logevents.NewFactory("/tmp", 5, false, nil, nil, false, 1, false, false)
+	service, err := NewService(nil, `{"destinations": {"pg_stream": {"type": "postgres", "mode": "stream", "only_tokens": ["token1"], "datasource": {"host": "host1"}}}}`,
+		storages.NewMockFactory(), loggerFactory, true)
+	require.NoError(t, err)
+
+	tokenID := appconfig.Instance.AuthorizationService.GetTokenID("token1")
+	initialQueue, ok := service.GetEventsConsumerByDestinationID("pg_stream")
+	require.True(t, ok)
+	initialStorage, ok := service.GetDestinationByID("pg_stream")
+	require.True(t, ok)
+	require.Equal(t, int64(1), service.unitsByID["pg_stream"].generation)
+
+	//changed stream destination reuses the events queue
+	service.updateDestinations([]byte(`{"destinations": {"pg_stream": {"type": "postgres", "mode": "stream", "only_tokens": ["token1"], "datasource": {"host": "host2"}}}}`))
+
+	queue, ok := service.GetEventsConsumerByDestinationID("pg_stream")
+	require.True(t, ok)
+	require.True(t, initialQueue == queue, "events queue must be handed off to the new destination version")
+	storage, ok := service.GetDestinationByID("pg_stream")
+	require.True(t, ok)
+	require.False(t, initialStorage == storage, "storage must be recreated")
+	require.Equal(t, int64(2), service.unitsByID["pg_stream"].generation)
+	require.Equal(t, 1, len(service.GetConsumers(tokenID)))
+
+	//changed mode: the queue is recreated
+	service.updateDestinations([]byte(`{"destinations": {"pg_stream": {"type": "postgres", "mode": "batch", "only_tokens": ["token1"], "datasource": {"host": "host2"}}}}`))
+	require.Equal(t, 1, len(service.GetBatchStorages(tokenID)))
+	_, ok = service.GetEventsConsumerByDestinationID("pg_stream")
+	require.False(t, ok)
+}
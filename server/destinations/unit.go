@@ -3,6 +3,7 @@ package destinations
 import (
 	"fmt"
 	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/storages"
 )
@@ -14,6 +15,10 @@ type Unit struct {
 
 	tokenIDs []string
 	hash     uint64
+	//queueConfig is applied only on the queue creation. Handed off queues keep the initial configuration
+	queueConfig *config.QueueConfiguration
+	//generation is a configuration version the unit has been created from
+	generation int64
 }
 
 //CloseStorage runs storages.StorageProxy Close()
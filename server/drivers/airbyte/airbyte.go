@@ -152,7 +152,7 @@ func TestAirbyte(sourceConfig *base.SourceConfig) error {
 		config.ImageVersion = airbyte.LatestVersion
 	}
 	base.FillPreconfiguredOauth(config.DockerImage, config.Config)
-	airbyteRunner := airbyte.NewRunner(sourceConfig.SourceID, config.DockerImage, config.ImageVersion, "")
+	airbyteRunner := airbyte.NewRunner(sourceConfig.SourceID, config.DockerImage, airbyte.Instance.ResolveVersion(config.DockerImage, config.ImageVersion), "")
 
 	if err := airbyteRunner.Check(config.Config); err != nil {
 		return err
@@ -217,7 +217,7 @@ func (a *Airbyte) EnsureCatalog() error {
 // Ready returns true if catalog is discovered
 func (a *Airbyte) Ready() (bool, error) {
 	//check if docker image isn't pulled
-	ready, err := airbyte.Instance.IsImagePulled(airbyte.Instance.AddAirbytePrefix(a.GetTap()), a.config.ImageVersion)
+	ready, err := airbyte.Instance.IsImagePulled(airbyte.Instance.AddAirbytePrefix(a.GetTap()), a.imageVersion())
 	if err != nil {
 		return false, err
 	}
@@ -273,7 +273,7 @@ func (a *Airbyte) Load(config string, state string, taskLogger logging.TaskLogge
 		return err
 	}
 
-	airbyteRunner := airbyte.NewRunner(a.ID(), a.GetTap(), a.config.ImageVersion, taskCloser.TaskID())
+	airbyteRunner := airbyte.NewRunner(a.ID(), a.GetTap(), a.imageVersion(), taskCloser.TaskID())
 
 	syncCommand := &base.SyncCommand{
 		Cmd:        airbyteRunner,
@@ -317,7 +317,7 @@ func (a *Airbyte) GetDriversInfo() *base.DriversInfo {
 	return &base.DriversInfo{
 		SourceType:       a.config.DockerImage,
 		ConnectorOrigin:  a.Type(),
-		ConnectorVersion: a.config.ImageVersion,
+		ConnectorVersion: a.imageVersion(),
 		Streams:          len(a.streamsRepresentation),
 	}
 }
@@ -353,7 +353,7 @@ func (a *Airbyte) Close() (multiErr error) {
 // 3. reformat catalog to airbyte format and writes it to the file system
 // returns catalog
 func (a *Airbyte) loadCatalog() (string, map[string]*base.StreamRepresentation, error) {
-	airbyteRunner := airbyte.NewRunner(a.ID(), a.GetTap(), a.config.ImageVersion, "")
+	airbyteRunner := airbyte.NewRunner(a.ID(), a.GetTap(), a.imageVersion(), "")
 	rawCatalog, err := airbyteRunner.Discover(a.config.Config, time.Minute*30)
 	if err != nil {
 		return "", nil, err
@@ -402,6 +402,11 @@ func selectedStreamsWithNamespace(config *Config) map[string]base.StreamConfigur
 	return selectedStreamsWithNamespace
 }
 
+// imageVersion returns the configured docker image version or the pinned one if 'latest' is configured
+func (a *Airbyte) imageVersion() string {
+	return airbyte.Instance.ResolveVersion(a.GetTap(), a.config.ImageVersion)
+}
+
 func (a *Airbyte) IsClosed() bool {
 	select {
 	case <-a.closed:
@@ -11,7 +11,10 @@ import (
 	"github.com/spf13/viper"
 )
 
-const ConfigSignatureSuffix = "_JITSU_config"
+const (
+	ConfigSignatureSuffix = "_JITSU_config"
+	StateSignatureSuffix  = "_JITSU_state"
+)
 
 //StreamConfiguration is a dto for serialization selected streams configuration
 type StreamConfiguration struct {
@@ -32,6 +35,11 @@ type SourceConfig struct {
 	Collections []interface{} `mapstructure:"collections" json:"collections,omitempty" yaml:"collections,omitempty"`
 	Schedule    string        `mapstructure:"schedule" json:"schedule,omitempty" yaml:"schedule,omitempty"`
 
+	//SyncConcurrency is a max number of collection chunks (intervals) synchronized in parallel
+	SyncConcurrency int `mapstructure:"sync_concurrency" json:"sync_concurrency,omitempty" yaml:"sync_concurrency,omitempty"`
+	//SyncRateLimit is a max number of chunks started per second (shared by all source collections)
+	SyncRateLimit float64 `mapstructure:"sync_rate_limit" json:"sync_rate_limit,omitempty" yaml:"sync_rate_limit,omitempty"`
+
 	Config        map[string]interface{} `mapstructure:"config" json:"config,omitempty" yaml:"config,omitempty"`
 	Notifications map[string]interface{} `mapstructure:"notifications" json:"notifications,omitempty" yaml:"notifications,omitempty"`
 	ProjectName   string                 `mapstructure:"project_name" json:"project_name,omitempty" yaml:"project_name,omitempty"`
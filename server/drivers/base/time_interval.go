@@ -1,8 +1,11 @@
 package base
 
 import (
-	"github.com/jitsucom/jitsu/server/schema"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/jitsucom/jitsu/server/schema"
 )
 
 const SignatureLayout = "2006-01-02T15:04:05.000Z"
@@ -22,6 +25,27 @@ func NewTimeInterval(granularity schema.Granularity, t time.Time) *TimeInterval
 	}
 }
 
+//ParseTimeInterval returns TimeInterval from its string representation (see TimeInterval.String())
+func ParseTimeInterval(value string) (*TimeInterval, error) {
+	parts := strings.SplitN(value, "_", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed time interval: %s", value)
+	}
+
+	location, err := time.LoadLocation(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed time interval [%s] time zone: %v", value, err)
+	}
+
+	granularity := schema.Granularity(parts[1])
+	t, err := granularity.Parse(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed time interval [%s]: %v", value, err)
+	}
+
+	return &TimeInterval{TimeZoneID: location.String(), granularity: granularity, time: t}, nil
+}
+
 func (ti *TimeInterval) LowerEndpoint() time.Time {
 	return ti.granularity.Lower(ti.time)
 }
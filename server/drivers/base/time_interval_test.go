@@ -0,0 +1,29 @@
+package base
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeInterval(t *testing.T) {
+	moment := time.Date(2022, 5, 17, 13, 0, 0, 0, time.UTC)
+	for _, granularity := range []schema.Granularity{schema.HOUR, schema.DAY, schema.MONTH, schema.QUARTER, schema.YEAR, schema.ALL} {
+		interval := NewTimeInterval(granularity, granularity.Lower(moment))
+
+		parsed, err := ParseTimeInterval(interval.String())
+		require.NoError(t, err, granularity)
+		require.Equal(t, interval.String(), parsed.String(), granularity)
+		require.Equal(t, interval.LowerEndpoint(), parsed.LowerEndpoint(), granularity)
+		require.Equal(t, interval.UpperEndpoint(), parsed.UpperEndpoint(), granularity)
+	}
+
+	_, err := ParseTimeInterval("UTC_DAY")
+	require.Error(t, err)
+	_, err = ParseTimeInterval("UTC_MINUTE_2022-05-17")
+	require.Error(t, err)
+	_, err = ParseTimeInterval("UTC_DAY_2022-05")
+	require.Error(t, err)
+}
@@ -23,6 +23,10 @@ const (
 	GooglePlayType      = "google_play"
 	GoogleAdsType       = "google_ads"
 	RedisType           = "redis"
+	PostgresCDCType     = "postgres_cdc"
+	MySQLCDCType        = "mysql_cdc"
+	HTTPAPIType         = "http_api"
+	GoogleSheetsType    = "google_sheets"
 
 	SingerType          = "singer"
 	AirbyteType         = "airbyte"
@@ -165,6 +169,25 @@ type Driver interface {
 	Delete() error
 }
 
+//IncrementalDriver interface must be implemented by drivers which return only new objects on every sync (e.g. CDC)
+type IncrementalDriver interface {
+	Driver
+
+	//IsIncremental returns true if objects loaded by previous syncs must be kept in destinations
+	IsIncremental() bool
+}
+
+//StatefulDriver interface must be implemented by incremental drivers which keep their read position
+//(e.g. binlog GTID set) in meta storage
+type StatefulDriver interface {
+	IncrementalDriver
+
+	//SetState sets the last saved position. It is called before every sync
+	SetState(state string)
+	//GetState returns the position of objects which have been passed to ObjectsLoader. Empty value isn't saved
+	GetState() string
+}
+
 //CLIDriver interface must be implemented by every CLI source type (Singer or Airbyte)
 type CLIDriver interface {
 	Driver
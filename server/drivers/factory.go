@@ -12,7 +12,11 @@ import (
 	_ "github.com/jitsucom/jitsu/server/drivers/google_ads"
 	_ "github.com/jitsucom/jitsu/server/drivers/google_analytics"
 	_ "github.com/jitsucom/jitsu/server/drivers/google_play"
+	_ "github.com/jitsucom/jitsu/server/drivers/google_sheets"
+	_ "github.com/jitsucom/jitsu/server/drivers/http_api"
 	_ "github.com/jitsucom/jitsu/server/drivers/jitsu_sdk"
+	_ "github.com/jitsucom/jitsu/server/drivers/mysql_cdc"
+	_ "github.com/jitsucom/jitsu/server/drivers/postgres_cdc"
 	_ "github.com/jitsucom/jitsu/server/drivers/redis"
 	_ "github.com/jitsucom/jitsu/server/drivers/singer"
 	"github.com/jitsucom/jitsu/server/jsonutils"
@@ -0,0 +1,65 @@
+package google_sheets
+
+import (
+	"errors"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+const (
+	unformattedValue = "UNFORMATTED_VALUE"
+	formattedValue   = "FORMATTED_VALUE"
+)
+
+//GoogleSheetsConfig is a Google Sheets source configuration dto for serialization
+type GoogleSheetsConfig struct {
+	AuthConfig    *base.GoogleAuthConfig `mapstructure:"auth" json:"auth,omitempty" yaml:"auth,omitempty"`
+	SpreadsheetID string                 `mapstructure:"spreadsheet_id" json:"spreadsheet_id,omitempty" yaml:"spreadsheet_id,omitempty"`
+}
+
+//Validate returns err if configuration is invalid
+func (gsc *GoogleSheetsConfig) Validate() error {
+	if gsc.SpreadsheetID == "" {
+		return errors.New("spreadsheet_id field must not be empty")
+	}
+
+	if gsc.AuthConfig == nil {
+		return errors.New("'auth' is required")
+	}
+
+	return gsc.AuthConfig.Validate()
+}
+
+//GoogleSheetsParameters is a synchronized sheet range configuration dto for serialization
+type GoogleSheetsParameters struct {
+	//Range is a sheet name or a range in A1 notation (e.g. Sheet1!A1:F)
+	Range string `mapstructure:"range" json:"range,omitempty" yaml:"range,omitempty"`
+	//HeaderRow is the number of the row with column names in the range. Rows above it are skipped
+	HeaderRow int `mapstructure:"header_row" json:"header_row,omitempty" yaml:"header_row,omitempty"`
+	//Formatted means values are loaded as they are displayed in the sheet (e.g. "$1.00" instead of 1)
+	Formatted bool `mapstructure:"formatted" json:"formatted,omitempty" yaml:"formatted,omitempty"`
+}
+
+//Validate returns err if configuration is invalid and sets default values
+func (gsp *GoogleSheetsParameters) Validate() error {
+	if gsp.Range == "" {
+		return errors.New("range is required")
+	}
+	if gsp.HeaderRow < 0 {
+		return errors.New("header_row must be positive")
+	}
+	if gsp.HeaderRow == 0 {
+		gsp.HeaderRow = 1
+	}
+
+	return nil
+}
+
+//valueRenderOption returns Sheets API value render option
+func (gsp *GoogleSheetsParameters) valueRenderOption() string {
+	if gsp.Formatted {
+		return formattedValue
+	}
+
+	return unformattedValue
+}
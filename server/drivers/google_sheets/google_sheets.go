@@ -0,0 +1,233 @@
+package google_sheets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	formattedDateTime = "FORMATTED_STRING"
+	chunkSize         = 10000
+)
+
+//GoogleSheets is a Google Sheets driver. It loads a sheet range on every sync (full refresh):
+//the header row cells are column names and every next not empty row is an object
+type GoogleSheets struct {
+	base.IntervalDriver
+
+	ctx        context.Context
+	config     *GoogleSheetsConfig
+	parameters *GoogleSheetsParameters
+	collection *base.Collection
+	service    *sheets.Service
+}
+
+func init() {
+	base.RegisterDriver(base.GoogleSheetsType, NewGoogleSheets)
+	base.RegisterTestConnectionFunc(base.GoogleSheetsType, TestGoogleSheets)
+}
+
+//NewGoogleSheets returns configured Google Sheets driver instance
+func NewGoogleSheets(ctx context.Context, sourceConfig *base.SourceConfig, collection *base.Collection) (base.Driver, error) {
+	config, err := parseConfig(sourceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := &GoogleSheetsParameters{}
+	if err := jsonutils.UnmarshalConfig(collection.Parameters, parameters); err != nil {
+		return nil, err
+	}
+	if err := parameters.Validate(); err != nil {
+		return nil, err
+	}
+
+	service, err := newService(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GoogleSheets{
+		IntervalDriver: base.IntervalDriver{SourceType: sourceConfig.Type},
+		ctx:            ctx,
+		config:         config,
+		parameters:     parameters,
+		collection:     collection,
+		service:        service,
+	}, nil
+}
+
+//TestGoogleSheets tests access to the spreadsheet without creating Driver instance
+func TestGoogleSheets(sourceConfig *base.SourceConfig) error {
+	config, err := parseConfig(sourceConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	service, err := newService(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	if _, err := service.Spreadsheets.Get(config.SpreadsheetID).Fields("spreadsheetId").Context(ctx).Do(); err != nil {
+		return fmt.Errorf("error getting spreadsheet [%s]: %v", config.SpreadsheetID, err)
+	}
+
+	return nil
+}
+
+func parseConfig(sourceConfig *base.SourceConfig) (*GoogleSheetsConfig, error) {
+	config := &GoogleSheetsConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return nil, err
+	}
+	config.AuthConfig.FillPreconfiguredOauth(base.GoogleSheetsType)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func newService(ctx context.Context, config *GoogleSheetsConfig) (*sheets.Service, error) {
+	credentialsJSON, err := config.AuthConfig.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	service, err := sheets.NewService(ctx, option.WithCredentialsJSON(credentialsJSON), option.WithScopes(sheets.SpreadsheetsReadonlyScope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Google Sheets service: %v", err)
+	}
+
+	return service, nil
+}
+
+//GetRefreshWindow returns 0 because the range is reloaded on every sync
+func (gs *GoogleSheets) GetRefreshWindow() (time.Duration, error) {
+	return 0, nil
+}
+
+//GetAllAvailableIntervals returns ALL constant
+func (gs *GoogleSheets) GetAllAvailableIntervals() ([]*base.TimeInterval, error) {
+	return []*base.TimeInterval{base.NewTimeInterval(schema.ALL, time.Time{})}, nil
+}
+
+//GetObjectsFor loads the whole range and passes objects to objectsLoader by chunks.
+//The first chunk is passed even if the range is empty: it deletes previously loaded data
+func (gs *GoogleSheets) GetObjectsFor(interval *base.TimeInterval, objectsLoader base.ObjectsLoader) error {
+	valueRange, err := gs.service.Spreadsheets.Values.Get(gs.config.SpreadsheetID, gs.parameters.Range).
+		ValueRenderOption(gs.parameters.valueRenderOption()).
+		DateTimeRenderOption(formattedDateTime).
+		Context(gs.ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("error getting range [%s] of spreadsheet [%s]: %v", gs.parameters.Range, gs.config.SpreadsheetID, err)
+	}
+
+	objects := rowsToObjects(valueRange.Values, gs.parameters.HeaderRow)
+	logging.Debugf("[%s] Rows to sync: %d", gs.collection.SourceID, len(objects))
+
+	total := len(objects)
+	for pos := 0; pos == 0 || pos < total; pos += chunkSize {
+		end := pos + chunkSize
+		if end > total {
+			end = total
+		}
+		percent := 100
+		if total > 0 {
+			percent = end * 100 / total
+		}
+		if err := objectsLoader(objects[pos:end], pos, total, percent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//rowsToObjects converts sheet rows into objects. headerRow is 1-based number of the row with column names.
+//Not named columns are named column_<column number>. Empty cells are skipped
+func rowsToObjects(rows [][]interface{}, headerRow int) []map[string]interface{} {
+	if len(rows) < headerRow {
+		return []map[string]interface{}{}
+	}
+
+	columns := columnNames(rows[headerRow-1])
+	objects := make([]map[string]interface{}, 0, len(rows)-headerRow)
+	for _, row := range rows[headerRow:] {
+		object := map[string]interface{}{}
+		for i, cell := range row {
+			if cell == nil || cell == "" {
+				continue
+			}
+			if i < len(columns) {
+				object[columns[i]] = cell
+			} else {
+				object[defaultColumnName(i)] = cell
+			}
+		}
+		if len(object) > 0 {
+			objects = append(objects, object)
+		}
+	}
+
+	return objects
+}
+
+//columnNames returns unique column names from the header row cells
+func columnNames(header []interface{}) []string {
+	names := make([]string, len(header))
+	used := map[string]int{}
+	for i, cell := range header {
+		name := ""
+		if cell != nil {
+			name = strings.TrimSpace(fmt.Sprint(cell))
+		}
+		if name == "" {
+			name = defaultColumnName(i)
+		}
+
+		used[name]++
+		if count := used[name]; count > 1 {
+			name = fmt.Sprintf("%s_%d", name, count)
+		}
+		names[i] = name
+	}
+
+	return names
+}
+
+func defaultColumnName(i int) string {
+	return fmt.Sprintf("column_%d", i+1)
+}
+
+//Type returns Google Sheets type
+func (gs *GoogleSheets) Type() string {
+	return base.GoogleSheetsType
+}
+
+//GetCollectionTable returns collection table
+func (gs *GoogleSheets) GetCollectionTable() string {
+	return gs.collection.GetTableName()
+}
+
+//GetCollectionMetaKey returns collection meta key (key is used in meta storage)
+func (gs *GoogleSheets) GetCollectionMetaKey() string {
+	return gs.collection.Name + "_" + gs.GetCollectionTable()
+}
+
+//Close does nothing
+func (gs *GoogleSheets) Close() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package google_sheets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowsToObjects(t *testing.T) {
+	rows := [][]interface{}{
+		{"Report for March"},
+		{"campaign", "", "spend", "campaign", " clicks "},
+		{"spring", "x", 10.5, "dup", float64(3)},
+		{},
+		{"", ""},
+		{"autumn", "", nil, "", 7, "extra"},
+	}
+
+	objects := rowsToObjects(rows, 2)
+	require.Equal(t, []map[string]interface{}{
+		{"campaign": "spring", "column_2": "x", "spend": 10.5, "campaign_2": "dup", "clicks": float64(3)},
+		{"campaign": "autumn", "clicks": 7, "column_6": "extra"},
+	}, objects)
+
+	require.Empty(t, rowsToObjects(rows[:1], 2))
+	require.Empty(t, rowsToObjects(nil, 1))
+}
+
+func TestParametersValidate(t *testing.T) {
+	parameters := &GoogleSheetsParameters{Range: "Sheet1!A1:F"}
+	require.NoError(t, parameters.Validate())
+	require.Equal(t, 1, parameters.HeaderRow)
+	require.Equal(t, unformattedValue, parameters.valueRenderOption())
+
+	require.Error(t, (&GoogleSheetsParameters{}).Validate())
+	require.Error(t, (&GoogleSheetsParameters{Range: "Sheet1", HeaderRow: -1}).Validate())
+}
@@ -0,0 +1,217 @@
+package http_api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+const (
+	BearerAuth = "bearer"
+	BasicAuth  = "basic"
+	OAuth2Auth = "oauth2"
+
+	NoPagination         = "none"
+	CursorPagination     = "cursor"
+	OffsetPagination     = "offset"
+	LinkHeaderPagination = "link_header"
+
+	defaultCursorParameter = "cursor"
+	defaultOffsetParameter = "offset"
+	defaultLimitParameter  = "limit"
+	defaultPageSize        = 100
+	defaultMaxPages        = 10000
+)
+
+//HTTPAPIConfig is a generic HTTP API source configuration dto for serialization
+type HTTPAPIConfig struct {
+	//BaseURL is prepended to relative collection URLs
+	BaseURL string      `mapstructure:"base_url" json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	Headers StringMap   `mapstructure:"headers" json:"headers,omitempty" yaml:"headers,omitempty"`
+	Auth    *AuthConfig `mapstructure:"auth" json:"auth,omitempty" yaml:"auth,omitempty"`
+}
+
+//Validate returns err if configuration is invalid
+func (hac *HTTPAPIConfig) Validate() error {
+	if hac == nil {
+		return errors.New("http_api config is required")
+	}
+	if hac.BaseURL != "" {
+		if _, err := url.Parse(hac.BaseURL); err != nil {
+			return fmt.Errorf("base_url is invalid: %v", err)
+		}
+	}
+
+	return hac.Auth.Validate()
+}
+
+//AuthConfig is an HTTP API authorization configuration
+type AuthConfig struct {
+	//Type is bearer, basic or oauth2 (client credentials flow)
+	Type     string `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Token    string `mapstructure:"token" json:"token,omitempty" yaml:"token,omitempty"`
+	Username string `mapstructure:"username" json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty"`
+
+	ClientID     string   `mapstructure:"client_id" json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret string   `mapstructure:"client_secret" json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	TokenURL     string   `mapstructure:"token_url" json:"token_url,omitempty" yaml:"token_url,omitempty"`
+	Scopes       []string `mapstructure:"scopes" json:"scopes,omitempty" yaml:"scopes,omitempty"`
+}
+
+//Validate returns err if configuration is invalid. Nil value means no authorization
+func (ac *AuthConfig) Validate() error {
+	if ac == nil {
+		return nil
+	}
+
+	switch ac.Type {
+	case BearerAuth:
+		if ac.Token == "" {
+			return errors.New("auth.token is required for bearer authorization")
+		}
+	case BasicAuth:
+		if ac.Username == "" {
+			return errors.New("auth.username is required for basic authorization")
+		}
+	case OAuth2Auth:
+		if ac.ClientID == "" || ac.ClientSecret == "" || ac.TokenURL == "" {
+			return errors.New("auth.client_id, auth.client_secret and auth.token_url are required for oauth2 authorization")
+		}
+	default:
+		return fmt.Errorf("unknown auth.type [%s]. Supported: %s, %s, %s", ac.Type, BearerAuth, BasicAuth, OAuth2Auth)
+	}
+
+	return nil
+}
+
+//HTTPAPIParameters is an HTTP API endpoint configuration dto for serialization
+type HTTPAPIParameters struct {
+	//URL is absolute or relative to base_url endpoint URL. It is a Go template with {{.Cursor}} variable
+	URL    string `mapstructure:"url" json:"url,omitempty" yaml:"url,omitempty"`
+	Method string `mapstructure:"method" json:"method,omitempty" yaml:"method,omitempty"`
+	//Body is a Go template of the request body with {{.Cursor}} variable
+	Body string `mapstructure:"body" json:"body,omitempty" yaml:"body,omitempty"`
+	//QueryParameters are Go templates of query parameters with {{.Cursor}} variable
+	QueryParameters StringMap `mapstructure:"query_parameters" json:"query_parameters,omitempty" yaml:"query_parameters,omitempty"`
+	//RecordsPath is a JSON path of records array in the response. Empty value means the response is an array
+	RecordsPath string            `mapstructure:"records_path" json:"records_path,omitempty" yaml:"records_path,omitempty"`
+	Pagination  *PaginationConfig `mapstructure:"pagination" json:"pagination,omitempty" yaml:"pagination,omitempty"`
+	//CursorField is a JSON path of incremental cursor in records. Empty value means full refresh on every sync
+	CursorField string `mapstructure:"cursor_field" json:"cursor_field,omitempty" yaml:"cursor_field,omitempty"`
+	//InitialCursor is the cursor value of the first sync
+	InitialCursor string `mapstructure:"initial_cursor" json:"initial_cursor,omitempty" yaml:"initial_cursor,omitempty"`
+}
+
+//Validate returns err if configuration is invalid and sets default values
+func (hap *HTTPAPIParameters) Validate(baseURL string) error {
+	if hap == nil {
+		return errors.New("'parameters' configuration section is required")
+	}
+	if hap.URL == "" {
+		return errors.New("url is required")
+	}
+	if baseURL == "" && !strings.HasPrefix(hap.URL, "http://") && !strings.HasPrefix(hap.URL, "https://") {
+		return fmt.Errorf("url [%s] must be absolute if base_url isn't configured", hap.URL)
+	}
+	if hap.Method == "" {
+		hap.Method = http.MethodGet
+	}
+	hap.Method = strings.ToUpper(hap.Method)
+
+	templates := map[string]string{"url": hap.URL, "body": hap.Body}
+	for name, value := range hap.QueryParameters {
+		templates["query_parameters."+name] = value
+	}
+	for name, value := range templates {
+		if _, err := template.New(name).Parse(value); err != nil {
+			return fmt.Errorf("%s template is invalid: %v", name, err)
+		}
+	}
+
+	if hap.Pagination == nil {
+		hap.Pagination = &PaginationConfig{Type: NoPagination}
+	}
+	return hap.Pagination.Validate()
+}
+
+//PaginationConfig is a pagination strategy configuration
+type PaginationConfig struct {
+	//Type is none, cursor, offset or link_header
+	Type string `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	//NextCursorPath is a JSON path of the next page cursor (or the next page URL) in the response
+	NextCursorPath  string `mapstructure:"next_cursor_path" json:"next_cursor_path,omitempty" yaml:"next_cursor_path,omitempty"`
+	CursorParameter string `mapstructure:"cursor_parameter" json:"cursor_parameter,omitempty" yaml:"cursor_parameter,omitempty"`
+	OffsetParameter string `mapstructure:"offset_parameter" json:"offset_parameter,omitempty" yaml:"offset_parameter,omitempty"`
+	LimitParameter  string `mapstructure:"limit_parameter" json:"limit_parameter,omitempty" yaml:"limit_parameter,omitempty"`
+	PageSize        int    `mapstructure:"page_size" json:"page_size,omitempty" yaml:"page_size,omitempty"`
+	//MaxPages limits the number of requests per sync
+	MaxPages int `mapstructure:"max_pages" json:"max_pages,omitempty" yaml:"max_pages,omitempty"`
+}
+
+//Validate returns err if configuration is invalid and sets default values
+func (pc *PaginationConfig) Validate() error {
+	if pc.Type == "" {
+		pc.Type = NoPagination
+	}
+	if pc.MaxPages <= 0 {
+		pc.MaxPages = defaultMaxPages
+	}
+
+	switch pc.Type {
+	case NoPagination, LinkHeaderPagination:
+	case CursorPagination:
+		if pc.NextCursorPath == "" {
+			return errors.New("pagination.next_cursor_path is required for cursor pagination")
+		}
+		if pc.CursorParameter == "" {
+			pc.CursorParameter = defaultCursorParameter
+		}
+	case OffsetPagination:
+		if pc.OffsetParameter == "" {
+			pc.OffsetParameter = defaultOffsetParameter
+		}
+		if pc.LimitParameter == "" {
+			pc.LimitParameter = defaultLimitParameter
+		}
+		if pc.PageSize <= 0 {
+			pc.PageSize = defaultPageSize
+		}
+	default:
+		return fmt.Errorf("unknown pagination.type [%s]. Supported: %s, %s, %s, %s", pc.Type, NoPagination, CursorPagination, OffsetPagination, LinkHeaderPagination)
+	}
+
+	return nil
+}
+
+//StringMap is a map of strings which is configured as a JSON object or as a string with JSON object (UI JSON fields)
+type StringMap map[string]string
+
+//UnmarshalJSON accepts JSON object or string with JSON object. Not string values are converted into strings
+func (sm *StringMap) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		if strings.TrimSpace(str) == "" {
+			return nil
+		}
+		b = []byte(str)
+	}
+
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+
+	result := make(StringMap, len(values))
+	for name, value := range values {
+		if value != nil {
+			result[name] = fmt.Sprint(value)
+		}
+	}
+	*sm = result
+	return nil
+}
@@ -0,0 +1,452 @@
+package http_api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/schema"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	requestTimeout  = time.Minute
+	retryCount      = 3
+	retryDelay      = time.Second
+	maxResponseSize = 100 * 1024 * 1024
+)
+
+//templateData is a URL, body and query parameters templates data
+type templateData struct {
+	//Cursor is the incremental cursor value of the previous sync (or initial_cursor)
+	Cursor string
+}
+
+//HTTPAPI is a generic HTTP API driver. It requests the configured endpoint page by page and returns records
+//from JSON responses. If cursor_field is configured, the max cursor value is saved after every sync and is available
+//in the next sync request templates as {{.Cursor}}
+type HTTPAPI struct {
+	base.IntervalDriver
+
+	ctx        context.Context
+	collection *base.Collection
+	config     *HTTPAPIConfig
+	parameters *HTTPAPIParameters
+	httpClient *http.Client
+
+	urlTemplate     *template.Template
+	bodyTemplate    *template.Template
+	queryTemplates  map[string]*template.Template
+	recordsPath     jsonutils.JSONPath
+	cursorFieldPath jsonutils.JSONPath
+
+	//state is the incremental cursor value
+	state string
+}
+
+func init() {
+	base.RegisterDriver(base.HTTPAPIType, NewHTTPAPI)
+	base.RegisterTestConnectionFunc(base.HTTPAPIType, TestHTTPAPI)
+}
+
+//NewHTTPAPI returns configured HTTPAPI driver instance
+func NewHTTPAPI(ctx context.Context, sourceConfig *base.SourceConfig, collection *base.Collection) (base.Driver, error) {
+	config := &HTTPAPIConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	parameters := &HTTPAPIParameters{}
+	if err := jsonutils.UnmarshalConfig(collection.Parameters, parameters); err != nil {
+		return nil, err
+	}
+	if err := parameters.Validate(config.BaseURL); err != nil {
+		return nil, err
+	}
+
+	driver := &HTTPAPI{
+		IntervalDriver:  base.IntervalDriver{SourceType: sourceConfig.Type},
+		ctx:             ctx,
+		collection:      collection,
+		config:          config,
+		parameters:      parameters,
+		httpClient:      newHTTPClient(ctx, config.Auth),
+		urlTemplate:     template.Must(template.New("url").Parse(parameters.URL)),
+		bodyTemplate:    template.Must(template.New("body").Parse(parameters.Body)),
+		queryTemplates:  map[string]*template.Template{},
+		recordsPath:     jsonutils.NewJSONPath(parameters.RecordsPath),
+		cursorFieldPath: jsonutils.NewJSONPath(parameters.CursorField),
+	}
+	for name, value := range parameters.QueryParameters {
+		driver.queryTemplates[name] = template.Must(template.New(name).Parse(value))
+	}
+
+	return driver, nil
+}
+
+//TestHTTPAPI tests configuration and authorization (oauth2 token request) without creating Driver instance
+func TestHTTPAPI(sourceConfig *base.SourceConfig) error {
+	config := &HTTPAPIConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	if config.Auth != nil && config.Auth.Type == OAuth2Auth {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		if _, err := clientCredentials(config.Auth).Token(ctx); err != nil {
+			return fmt.Errorf("error getting oauth2 token: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func newHTTPClient(ctx context.Context, auth *AuthConfig) *http.Client {
+	httpClient := &http.Client{Timeout: requestTimeout}
+	if auth != nil && auth.Type == OAuth2Auth {
+		//token requests use the same timeout
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+		client := clientCredentials(auth).Client(ctx)
+		client.Timeout = requestTimeout
+		return client
+	}
+
+	return httpClient
+}
+
+func clientCredentials(auth *AuthConfig) *clientcredentials.Config {
+	return &clientcredentials.Config{
+		ClientID:     auth.ClientID,
+		ClientSecret: auth.ClientSecret,
+		TokenURL:     auth.TokenURL,
+		Scopes:       auth.Scopes,
+	}
+}
+
+//GetRefreshWindow returns zero: every sync requests the endpoint from the beginning or from the cursor
+func (h *HTTPAPI) GetRefreshWindow() (time.Duration, error) {
+	return 0, nil
+}
+
+//GetAllAvailableIntervals returns ALL constant
+func (h *HTTPAPI) GetAllAvailableIntervals() ([]*base.TimeInterval, error) {
+	return []*base.TimeInterval{base.NewTimeInterval(schema.ALL, time.Time{})}, nil
+}
+
+//GetObjectsFor requests all pages and passes records of every page to objectsLoader.
+//The cursor is moved only after all pages have been loaded because APIs may return records in any order
+func (h *HTTPAPI) GetObjectsFor(interval *base.TimeInterval, objectsLoader base.ObjectsLoader) error {
+	data := &templateData{Cursor: h.state}
+	if data.Cursor == "" {
+		data.Cursor = h.parameters.InitialCursor
+	}
+
+	requestURL, err := h.buildURL(data)
+	if err != nil {
+		return err
+	}
+	body, err := executeTemplate(h.bodyTemplate, data)
+	if err != nil {
+		return fmt.Errorf("error building request body: %v", err)
+	}
+
+	paginator := newPaginator(h.parameters.Pagination)
+	pageURL := paginator.first(requestURL)
+	maxCursor := data.Cursor
+	pos := 0
+	for page := 1; ; page++ {
+		if err := h.ctx.Err(); err != nil {
+			return err
+		}
+
+		response, header, err := h.request(pageURL, body)
+		if err != nil {
+			return err
+		}
+		records, err := h.extractRecords(response)
+		if err != nil {
+			return fmt.Errorf("error extracting records from %s response: %v", pageURL.Redacted(), err)
+		}
+
+		for _, record := range records {
+			if value, ok := h.cursorFieldPath.Get(record); ok && value != nil {
+				if cursor := fmt.Sprint(value); compareCursors(cursor, maxCursor) > 0 {
+					maxCursor = cursor
+				}
+			}
+		}
+
+		//full refresh passes the first page even if it is empty for deleting previously loaded data
+		if len(records) > 0 || (pos == 0 && !h.IsIncremental()) {
+			if err := objectsLoader(records, pos, -1, -1); err != nil {
+				return err
+			}
+			pos += len(records)
+		}
+
+		next, ok := paginator.next(pageURL, response, header, len(records))
+		if !ok {
+			break
+		}
+		if page >= h.parameters.Pagination.MaxPages {
+			logging.Warnf("[%s_%s] Max pages limit [%d] has been reached. The rest pages will be requested in the next sync", h.collection.SourceID, h.collection.Name, h.parameters.Pagination.MaxPages)
+			break
+		}
+		pageURL = next
+	}
+
+	if h.IsIncremental() && maxCursor != "" && maxCursor != h.state {
+		h.state = maxCursor
+		return objectsLoader(nil, pos, -1, -1)
+	}
+
+	return nil
+}
+
+//buildURL returns the endpoint URL with query parameters
+func (h *HTTPAPI) buildURL(data *templateData) (*url.URL, error) {
+	rawURL, err := executeTemplate(h.urlTemplate, data)
+	if err != nil {
+		return nil, fmt.Errorf("error building url: %v", err)
+	}
+	if h.config.BaseURL != "" && !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		rawURL = strings.TrimSuffix(h.config.BaseURL, "/") + "/" + strings.TrimPrefix(rawURL, "/")
+	}
+
+	requestURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url [%s]: %v", rawURL, err)
+	}
+
+	query := requestURL.Query()
+	for name, queryTemplate := range h.queryTemplates {
+		value, err := executeTemplate(queryTemplate, data)
+		if err != nil {
+			return nil, fmt.Errorf("error building query parameter [%s]: %v", name, err)
+		}
+		query.Set(name, value)
+	}
+	requestURL.RawQuery = query.Encode()
+
+	return requestURL, nil
+}
+
+//request sends the request with retries on 429 and 5xx responses and returns parsed JSON response
+func (h *HTTPAPI) request(requestURL *url.URL, body string) (interface{}, http.Header, error) {
+	var lastErr error
+	for attempt := 0; attempt < retryCount; attempt++ {
+		response, header, retryAfter, err := h.doRequest(requestURL, body)
+		if err == nil {
+			return response, header, nil
+		}
+		lastErr = err
+		if retryAfter < 0 {
+			break
+		}
+
+		if retryAfter == 0 {
+			retryAfter = retryDelay * time.Duration(1<<uint(attempt))
+		}
+		select {
+		case <-h.ctx.Done():
+			return nil, nil, h.ctx.Err()
+		case <-time.After(retryAfter):
+		}
+	}
+
+	return nil, nil, lastErr
+}
+
+//doRequest returns parsed response or error with retry delay: negative value means the error isn't retryable
+func (h *HTTPAPI) doRequest(requestURL *url.URL, body string) (interface{}, http.Header, time.Duration, error) {
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = bytes.NewBufferString(body)
+	}
+	request, err := http.NewRequestWithContext(h.ctx, h.parameters.Method, requestURL.String(), bodyReader)
+	if err != nil {
+		return nil, nil, -1, err
+	}
+
+	request.Header.Set("Accept", "application/json")
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range h.config.Headers {
+		request.Header.Set(name, value)
+	}
+	if auth := h.config.Auth; auth != nil {
+		switch auth.Type {
+		case BearerAuth:
+			request.Header.Set("Authorization", "Bearer "+auth.Token)
+		case BasicAuth:
+			request.SetBasicAuth(auth.Username, auth.Password)
+		}
+	}
+
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("error requesting %s: %v", requestURL.Redacted(), err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("error reading %s response: %v", requestURL.Redacted(), err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%s returned HTTP %d: %s", requestURL.Redacted(), response.StatusCode, string(responseBody))
+		if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError {
+			return nil, nil, parseRetryAfter(response.Header.Get("Retry-After")), err
+		}
+		return nil, nil, -1, err
+	}
+
+	var parsed interface{}
+	if len(bytes.TrimSpace(responseBody)) > 0 {
+		if err := parsers.ParseJSONAsObject(responseBody, &parsed); err != nil {
+			return nil, nil, -1, fmt.Errorf("error parsing %s response as JSON: %v", requestURL.Redacted(), err)
+		}
+	}
+
+	return parsed, response.Header, 0, nil
+}
+
+//extractRecords returns objects from records_path (or the whole response)
+func (h *HTTPAPI) extractRecords(response interface{}) ([]map[string]interface{}, error) {
+	if !h.recordsPath.IsEmpty() {
+		object, ok := response.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("response must be a JSON object with records_path [%s]", h.parameters.RecordsPath)
+		}
+		value, ok := h.recordsPath.Get(object)
+		if !ok {
+			return nil, nil
+		}
+		response = value
+	}
+
+	switch records := response.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return []map[string]interface{}{records}, nil
+	case []interface{}:
+		objects := make([]map[string]interface{}, 0, len(records))
+		for i, record := range records {
+			object, ok := record.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("record %d must be a JSON object: %v", i, record)
+			}
+			objects = append(objects, object)
+		}
+		return objects, nil
+	default:
+		return nil, fmt.Errorf("records must be a JSON array or object: %v", response)
+	}
+}
+
+func executeTemplate(t *template.Template, data *templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+//parseRetryAfter returns Retry-After header value in seconds or zero
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+//compareCursors compares numbers numerically and other values (e.g. ISO timestamps) lexicographically.
+//Empty value is less than any other value
+func compareCursors(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case b == "":
+		return 1
+	case a == "":
+		return -1
+	}
+
+	aNumber, aErr := strconv.ParseFloat(a, 64)
+	bNumber, bErr := strconv.ParseFloat(b, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case aNumber > bNumber:
+			return 1
+		case aNumber < bNumber:
+			return -1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(a, b)
+}
+
+//IsIncremental returns true if cursor_field is configured: objects loaded by previous syncs must be kept
+func (h *HTTPAPI) IsIncremental() bool {
+	return h.parameters.CursorField != ""
+}
+
+//SetState sets the cursor value which has been saved by the previous sync
+func (h *HTTPAPI) SetState(state string) {
+	h.state = state
+}
+
+//GetState returns the cursor value
+func (h *HTTPAPI) GetState() string {
+	if !h.IsIncremental() {
+		return ""
+	}
+	return h.state
+}
+
+//Type returns HTTPAPI type
+func (h *HTTPAPI) Type() string {
+	return base.HTTPAPIType
+}
+
+//GetCollectionTable returns collection table
+func (h *HTTPAPI) GetCollectionTable() string {
+	return h.collection.GetTableName()
+}
+
+//GetCollectionMetaKey returns collection meta key (key is used in meta storage)
+func (h *HTTPAPI) GetCollectionMetaKey() string {
+	return h.collection.Name + "_" + h.GetCollectionTable()
+}
+
+//Close closes idle connections
+func (h *HTTPAPI) Close() error {
+	h.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,186 @@
+package http_api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDriver(t *testing.T, config, parameters map[string]interface{}) *HTTPAPI {
+	driver, err := NewHTTPAPI(context.Background(),
+		&base.SourceConfig{SourceID: "api", Type: base.HTTPAPIType, Config: config},
+		&base.Collection{SourceID: "api", Name: "users", Parameters: parameters})
+	require.NoError(t, err)
+	return driver.(*HTTPAPI)
+}
+
+//load runs a sync and returns loaded objects ids and the number of objectsLoader calls
+func load(t *testing.T, driver *HTTPAPI) ([]string, int) {
+	var ids []string
+	calls := 0
+	err := driver.GetObjectsFor(nil, func(objects []map[string]interface{}, pos, total, percent int) error {
+		require.Equal(t, len(ids), pos)
+		for _, object := range objects {
+			ids = append(ids, fmt.Sprint(object["id"]))
+		}
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	return ids, calls
+}
+
+func TestCursorPaginationAndIncrementalCursor(t *testing.T) {
+	var requestedSince []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		requestedSince = append(requestedSince, r.URL.Query().Get("updated_since"))
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprint(w, `{"data": {"users": [{"id": 1, "updated": "2022-01-02"}, {"id": 2, "updated": "2022-01-05"}]}, "next": "p2"}`)
+		case "p2":
+			fmt.Fprint(w, `{"data": {"users": [{"id": 3, "updated": "2022-01-03"}]}, "next": null}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	driver := newTestDriver(t,
+		map[string]interface{}{"base_url": server.URL, "auth": map[string]interface{}{"type": BearerAuth, "token": "secret"}},
+		map[string]interface{}{
+			"url":              "/users",
+			"query_parameters": map[string]interface{}{"updated_since": "{{.Cursor}}"},
+			"records_path":     "/data/users",
+			"pagination":       map[string]interface{}{"type": CursorPagination, "next_cursor_path": "/next", "cursor_parameter": "page"},
+			"cursor_field":     "/updated",
+			"initial_cursor":   "2022-01-01",
+		})
+	require.True(t, driver.IsIncremental())
+
+	ids, calls := load(t, driver)
+	require.Equal(t, []string{"1", "2", "3"}, ids)
+	//2 pages and the state
+	require.Equal(t, 3, calls)
+	require.Equal(t, "2022-01-05", driver.GetState())
+	require.Equal(t, []string{"2022-01-01", "2022-01-01"}, requestedSince)
+
+	driver.SetState("2022-01-05")
+	requestedSince = nil
+	load(t, driver)
+	require.Equal(t, "2022-01-05", requestedSince[0])
+}
+
+func TestOffsetPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		require.Equal(t, "2", r.URL.Query().Get("limit"))
+
+		var users []map[string]interface{}
+		for i := offset; i < offset+2 && i < 5; i++ {
+			users = append(users, map[string]interface{}{"id": i})
+		}
+		json.NewEncoder(w).Encode(users)
+	}))
+	defer server.Close()
+
+	driver := newTestDriver(t, map[string]interface{}{},
+		map[string]interface{}{
+			"url":        server.URL + "/users",
+			"pagination": map[string]interface{}{"type": OffsetPagination, "offset_parameter": "skip", "page_size": 2},
+		})
+	require.False(t, driver.IsIncremental())
+
+	ids, _ := load(t, driver)
+	require.Equal(t, []string{"0", "1", "2", "3", "4"}, ids)
+	require.Equal(t, "", driver.GetState())
+}
+
+func TestLinkHeaderPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "user:pass", user+":"+password)
+
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `</users?page=2>; rel="next", </users?page=2>; rel="last"`)
+			fmt.Fprint(w, `[{"id": "a"}]`)
+		} else {
+			w.Header().Set("Link", `</users>; rel="first"`)
+			fmt.Fprint(w, `[{"id": "b"}]`)
+		}
+	}))
+	defer server.Close()
+
+	driver := newTestDriver(t,
+		map[string]interface{}{"auth": map[string]interface{}{"type": BasicAuth, "username": "user", "password": "pass"}},
+		map[string]interface{}{"url": server.URL + "/users", "pagination": map[string]interface{}{"type": LinkHeaderPagination}})
+
+	ids, _ := load(t, driver)
+	require.Equal(t, []string{"a", "b"}, ids)
+}
+
+func TestFullRefreshEmptyResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items": []}`)
+	}))
+	defer server.Close()
+
+	driver := newTestDriver(t, map[string]interface{}{}, map[string]interface{}{"url": server.URL, "records_path": "/items"})
+
+	//the first empty page deletes previously loaded data
+	ids, calls := load(t, driver)
+	require.Empty(t, ids)
+	require.Equal(t, 1, calls)
+}
+
+func TestParametersValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseURL    string
+		parameters *HTTPAPIParameters
+		expectErr  bool
+	}{
+		{"relative url without base url", "", &HTTPAPIParameters{URL: "/users"}, true},
+		{"relative url", "https://api.example.com", &HTTPAPIParameters{URL: "/users"}, false},
+		{"invalid template", "", &HTTPAPIParameters{URL: "https://api.example.com/{{.Cursor"}, true},
+		{"cursor pagination without path", "", &HTTPAPIParameters{URL: "https://api.example.com", Pagination: &PaginationConfig{Type: CursorPagination}}, true},
+		{"unknown pagination", "", &HTTPAPIParameters{URL: "https://api.example.com", Pagination: &PaginationConfig{Type: "pages"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parameters.Validate(tt.baseURL)
+			if tt.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, http.MethodGet, tt.parameters.Method)
+			}
+		})
+	}
+}
+
+func TestCompareCursors(t *testing.T) {
+	require.Equal(t, 1, compareCursors("10", "9"))
+	require.Equal(t, -1, compareCursors("2022-01-01T00:00:00Z", "2022-01-02T00:00:00Z"))
+	require.Equal(t, 1, compareCursors("a", ""))
+	require.Equal(t, 0, compareCursors("1.0", "1"))
+}
+
+func TestStringMapUnmarshal(t *testing.T) {
+	parameters := &HTTPAPIParameters{}
+	require.NoError(t, json.Unmarshal([]byte(`{"query_parameters": "{\"limit\": 10, \"q\": \"a\"}"}`), parameters))
+	require.Equal(t, StringMap{"limit": "10", "q": "a"}, parameters.QueryParameters)
+
+	config := &HTTPAPIConfig{}
+	require.NoError(t, json.Unmarshal([]byte(`{"headers": {"X-Api-Version": 2}}`), config))
+	require.Equal(t, StringMap{"X-Api-Version": "2"}, config.Headers)
+}
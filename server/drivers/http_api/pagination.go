@@ -0,0 +1,151 @@
+package http_api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+var linkRegexp = regexp.MustCompile(`<([^>]*)>\s*;([^,]*)`)
+
+//paginator builds next page URLs
+type paginator interface {
+	//first returns the first page URL
+	first(u *url.URL) *url.URL
+	//next returns the next page URL or false if the current page is the last one
+	next(current *url.URL, response interface{}, header http.Header, recordsCount int) (*url.URL, bool)
+}
+
+func newPaginator(config *PaginationConfig) paginator {
+	switch config.Type {
+	case CursorPagination:
+		return &cursorPaginator{config: config, nextCursorPath: jsonutils.NewJSONPath(config.NextCursorPath)}
+	case OffsetPagination:
+		return &offsetPaginator{config: config}
+	case LinkHeaderPagination:
+		return &linkHeaderPaginator{}
+	default:
+		return &noPaginator{}
+	}
+}
+
+type noPaginator struct{}
+
+func (np *noPaginator) first(u *url.URL) *url.URL {
+	return u
+}
+
+func (np *noPaginator) next(*url.URL, interface{}, http.Header, int) (*url.URL, bool) {
+	return nil, false
+}
+
+//cursorPaginator reads the next page cursor from the response. If the cursor is a URL, it is requested as is.
+//Otherwise it is sent in the cursor query parameter
+type cursorPaginator struct {
+	config         *PaginationConfig
+	nextCursorPath jsonutils.JSONPath
+}
+
+func (cp *cursorPaginator) first(u *url.URL) *url.URL {
+	return u
+}
+
+func (cp *cursorPaginator) next(current *url.URL, response interface{}, _ http.Header, _ int) (*url.URL, bool) {
+	object, ok := response.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := cp.nextCursorPath.Get(object)
+	if !ok || value == nil {
+		return nil, false
+	}
+	cursor := fmt.Sprint(value)
+	if cursor == "" {
+		return nil, false
+	}
+
+	if strings.HasPrefix(cursor, "http://") || strings.HasPrefix(cursor, "https://") || strings.HasPrefix(cursor, "/") {
+		next, err := current.Parse(cursor)
+		if err != nil {
+			return nil, false
+		}
+		return next, true
+	}
+
+	return withQueryParameter(current, cp.config.CursorParameter, cursor), true
+}
+
+//offsetPaginator requests pages with offset and limit query parameters until a page is smaller than the limit
+type offsetPaginator struct {
+	config *PaginationConfig
+	offset int
+}
+
+func (op *offsetPaginator) first(u *url.URL) *url.URL {
+	op.offset = 0
+	u = withQueryParameter(u, op.config.LimitParameter, strconv.Itoa(op.config.PageSize))
+	return withQueryParameter(u, op.config.OffsetParameter, "0")
+}
+
+func (op *offsetPaginator) next(current *url.URL, _ interface{}, _ http.Header, recordsCount int) (*url.URL, bool) {
+	if recordsCount < op.config.PageSize {
+		return nil, false
+	}
+
+	op.offset += recordsCount
+	return withQueryParameter(current, op.config.OffsetParameter, strconv.Itoa(op.offset)), true
+}
+
+//linkHeaderPaginator follows rel="next" link of Link header (RFC 8288)
+type linkHeaderPaginator struct{}
+
+func (lhp *linkHeaderPaginator) first(u *url.URL) *url.URL {
+	return u
+}
+
+func (lhp *linkHeaderPaginator) next(current *url.URL, _ interface{}, header http.Header, _ int) (*url.URL, bool) {
+	for _, link := range header.Values("Link") {
+		for _, match := range linkRegexp.FindAllStringSubmatch(link, -1) {
+			if !isNextRelation(match[2]) {
+				continue
+			}
+			next, err := current.Parse(strings.TrimSpace(match[1]))
+			if err != nil {
+				return nil, false
+			}
+			return next, true
+		}
+	}
+
+	return nil, false
+}
+
+//isNextRelation returns true if link parameters contain rel="next"
+func isNextRelation(parameters string) bool {
+	for _, parameter := range strings.Split(parameters, ";") {
+		kv := strings.SplitN(strings.TrimSpace(parameter), "=", 2)
+		if len(kv) != 2 || !strings.EqualFold(kv[0], "rel") {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.Trim(kv[1], `"`)) {
+			if strings.EqualFold(rel, "next") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func withQueryParameter(u *url.URL, name, value string) *url.URL {
+	result := *u
+	query := result.Query()
+	query.Set(name, value)
+	result.RawQuery = query.Encode()
+	return &result
+}
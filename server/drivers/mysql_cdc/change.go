@@ -0,0 +1,87 @@
+package mysql_cdc
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	SnapshotOperation = "snapshot"
+	InsertOperation   = "insert"
+	UpdateOperation   = "update"
+	DeleteOperation   = "delete"
+
+	MySQLFlavor   = "mysql"
+	MariaDBFlavor = "mariadb"
+
+	cdcKey        = "cdc"
+	operationKey  = "operation"
+	schemaKey     = "schema"
+	tableKey      = "table"
+	commitTimeKey = "commit_time"
+	gtidKey       = "gtid"
+	binlogFileKey = "binlog_file"
+	binlogPosKey  = "binlog_pos"
+)
+
+//change is a decoded row level change or a snapshot row
+type change struct {
+	operation  string
+	schema     string
+	table      string
+	commitTime time.Time
+	gtid       string
+	binlogFile string
+	binlogPos  uint32
+	//columns are new row values (snapshot, insert, update) or old row values (delete)
+	columns map[string]interface{}
+}
+
+//toObject returns row values with operation metadata under cdc key
+func (c *change) toObject() map[string]interface{} {
+	object := make(map[string]interface{}, len(c.columns)+1)
+	for name, value := range c.columns {
+		object[name] = value
+	}
+
+	metadata := map[string]interface{}{
+		operationKey: c.operation,
+		schemaKey:    c.schema,
+		tableKey:     c.table,
+	}
+	if !c.commitTime.IsZero() {
+		metadata[commitTimeKey] = c.commitTime
+	}
+	if c.gtid != "" {
+		metadata[gtidKey] = c.gtid
+	}
+	if c.binlogFile != "" {
+		metadata[binlogFileKey] = c.binlogFile
+		metadata[binlogPosKey] = c.binlogPos
+	}
+	object[cdcKey] = metadata
+
+	return object
+}
+
+//position is a binlog read position which is saved as the driver state
+type position struct {
+	Flavor string `json:"flavor"`
+	//GTIDSet is MySQL executed GTID set or MariaDB GTID position. Binlog is read from it if it isn't empty
+	GTIDSet string `json:"gtid_set,omitempty"`
+	File    string `json:"file,omitempty"`
+	Pos     uint32 `json:"pos,omitempty"`
+}
+
+func parsePosition(state string) (*position, error) {
+	pos := &position{}
+	if err := json.Unmarshal([]byte(state), pos); err != nil {
+		return nil, err
+	}
+	return pos, nil
+}
+
+func (p *position) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
@@ -0,0 +1,140 @@
+package mysql_cdc
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	defaultPort      = 3306
+	defaultBatchSize = 10000
+	//server IDs of replicas are generated in [minServerID, minServerID+serverIDRange) range
+	minServerID   = 1000
+	serverIDRange = 1 << 30
+
+	tlsParameter   = "tls"
+	tlsSkipVerify  = "skip-verify"
+	tlsRequired    = "true"
+	connectTimeout = 30 * time.Second
+)
+
+//MySQLCDCConfig is a MySQL/MariaDB binlog source configuration dto for serialization
+type MySQLCDCConfig struct {
+	Host     string      `mapstructure:"host" json:"host,omitempty" yaml:"host,omitempty"`
+	Port     json.Number `mapstructure:"port" json:"port,omitempty" yaml:"port,omitempty"`
+	Db       string      `mapstructure:"db" json:"db,omitempty" yaml:"db,omitempty"`
+	Username string      `mapstructure:"username" json:"username,omitempty" yaml:"username,omitempty"`
+	Password string      `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty"`
+	//Parameters are go-sql-driver DSN parameters. tls=true|skip-verify is used in the binlog connection as well
+	Parameters map[string]string `mapstructure:"parameters" json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	//ServerID is a unique replica server_id. Default value is generated from source and collection ids
+	ServerID uint32 `mapstructure:"server_id" json:"server_id,omitempty" yaml:"server_id,omitempty"`
+	//BatchSize is a max number of rows which are stored at once
+	BatchSize int `mapstructure:"batch_size" json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+}
+
+//Validate returns err if configuration is invalid and sets default values
+func (mcc *MySQLCDCConfig) Validate() error {
+	if mcc == nil {
+		return errors.New("mysql_cdc config is required")
+	}
+	if mcc.Host == "" {
+		return errors.New("host is not set")
+	}
+	if mcc.Db == "" {
+		return errors.New("db is not set")
+	}
+	if mcc.Username == "" {
+		return errors.New("username is not set")
+	}
+	if mcc.Port == "" {
+		mcc.Port = json.Number(fmt.Sprint(defaultPort))
+	}
+	if _, err := mcc.Port.Int64(); err != nil {
+		return fmt.Errorf("port must be int: %v", err)
+	}
+	if mcc.BatchSize <= 0 {
+		mcc.BatchSize = defaultBatchSize
+	}
+
+	return nil
+}
+
+//Address returns host:port
+func (mcc *MySQLCDCConfig) Address() string {
+	return mcc.Host + ":" + mcc.Port.String()
+}
+
+//binlogTLSConfig returns TLS configuration of the binlog connection (tls=true|skip-verify parameter) or nil
+func (mcc *MySQLCDCConfig) binlogTLSConfig() *tls.Config {
+	tlsMode := mcc.Parameters[tlsParameter]
+	if tlsMode != tlsRequired && tlsMode != tlsSkipVerify {
+		return nil
+	}
+
+	return &tls.Config{ServerName: mcc.Host, InsecureSkipVerify: tlsMode == tlsSkipVerify}
+}
+
+//DSN returns go-sql-driver connection string
+func (mcc *MySQLCDCConfig) DSN() string {
+	config := mysql.NewConfig()
+	config.User = mcc.Username
+	config.Passwd = mcc.Password
+	config.Net = "tcp"
+	config.Addr = mcc.Address()
+	config.DBName = mcc.Db
+	config.ParseTime = true
+	config.Loc = time.UTC
+	config.Timeout = connectTimeout
+	config.Params = map[string]string{}
+	for k, v := range mcc.Parameters {
+		if k == tlsParameter {
+			config.TLSConfig = v
+		} else {
+			config.Params[k] = v
+		}
+	}
+
+	return config.FormatDSN()
+}
+
+//MySQLCDCParameters is a captured tables configuration dto for serialization
+type MySQLCDCParameters struct {
+	//Tables is a list of captured tables of the configured db. Empty value means all tables
+	Tables []string `mapstructure:"tables" json:"tables,omitempty" yaml:"tables,omitempty"`
+	//Snapshot: if true (default), all rows of captured tables are loaded before streaming changes
+	Snapshot *bool `mapstructure:"snapshot" json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
+}
+
+//Validate returns err if configuration is invalid
+func (mcp *MySQLCDCParameters) Validate() error {
+	if mcp == nil {
+		return errors.New("'parameters' configuration section is required")
+	}
+	for _, table := range mcp.Tables {
+		if table == "" || strings.Contains(table, ".") {
+			return fmt.Errorf("table [%s] must be a table name of the configured db", table)
+		}
+	}
+
+	return nil
+}
+
+//IsSnapshotEnabled returns true if the initial snapshot is enabled
+func (mcp *MySQLCDCParameters) IsSnapshotEnabled() bool {
+	return mcp.Snapshot == nil || *mcp.Snapshot
+}
+
+//defaultServerID returns server_id derived from the source and the collection
+func defaultServerID(sourceID, collection string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(sourceID + "_" + collection))
+	return minServerID + h.Sum32()%serverIDRange
+}
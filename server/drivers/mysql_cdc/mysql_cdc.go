@@ -0,0 +1,351 @@
+package mysql_cdc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+const (
+	versionQuery         = `SELECT VERSION()`
+	binlogSettingsQuery  = `SELECT @@GLOBAL.log_bin, @@GLOBAL.binlog_format`
+	gtidModeQuery        = `SELECT @@GLOBAL.gtid_mode`
+	gtidExecutedQuery    = `SELECT @@GLOBAL.gtid_executed`
+	mariadbGTIDPosQuery  = `SELECT @@GLOBAL.gtid_binlog_pos`
+	masterStatusQuery    = `SHOW MASTER STATUS`
+	binaryLogStatusQuery = `SHOW BINARY LOG STATUS`
+
+	rowBinlogFormat = "ROW"
+	gtidModeOn      = "ON"
+)
+
+//MySQLCDC is a MySQL/MariaDB binlog driver. It connects as a replica, reads row level changes of captured tables and
+//returns them as objects with operation metadata. Initial sync loads snapshot of captured tables before streaming.
+//The binlog position (GTID set or file and position) is saved as the driver state only after objects have been stored
+//so changes are delivered at least once
+type MySQLCDC struct {
+	base.IntervalDriver
+
+	ctx        context.Context
+	collection *base.Collection
+	config     *MySQLCDCConfig
+	parameters *MySQLCDCParameters
+	dataSource *sql.DB
+	schemas    *schemaCache
+
+	//state is the position of objects which have been passed to objectsLoader
+	state string
+}
+
+func init() {
+	base.RegisterDriver(base.MySQLCDCType, NewMySQLCDC)
+	base.RegisterTestConnectionFunc(base.MySQLCDCType, TestMySQLCDC)
+}
+
+//NewMySQLCDC returns configured MySQLCDC driver instance
+func NewMySQLCDC(ctx context.Context, sourceConfig *base.SourceConfig, collection *base.Collection) (base.Driver, error) {
+	config := &MySQLCDCConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.ServerID == 0 {
+		config.ServerID = defaultServerID(sourceConfig.SourceID, collection.Name)
+	}
+
+	parameters := &MySQLCDCParameters{}
+	if err := jsonutils.UnmarshalConfig(collection.Parameters, parameters); err != nil {
+		return nil, err
+	}
+	if err := parameters.Validate(); err != nil {
+		return nil, err
+	}
+
+	dataSource, err := connect(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLCDC{
+		IntervalDriver: base.IntervalDriver{SourceType: sourceConfig.Type},
+		ctx:            ctx,
+		collection:     collection,
+		config:         config,
+		parameters:     parameters,
+		dataSource:     dataSource,
+		schemas:        newSchemaCache(ctx, dataSource),
+	}, nil
+}
+
+//TestMySQLCDC tests connection and binlog settings without creating Driver instance
+func TestMySQLCDC(sourceConfig *base.SourceConfig) error {
+	config := &MySQLCDCConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	dataSource, err := connect(config)
+	if err != nil {
+		return err
+	}
+	defer dataSource.Close()
+
+	var logBin, binlogFormat string
+	if err := dataSource.QueryRow(binlogSettingsQuery).Scan(&logBin, &binlogFormat); err != nil {
+		return err
+	}
+	if logBin != "1" && !strings.EqualFold(logBin, "ON") {
+		return fmt.Errorf("binary logging is disabled. Please enable log_bin")
+	}
+	if !strings.EqualFold(binlogFormat, rowBinlogFormat) {
+		return fmt.Errorf("binlog_format must be '%s'. Current value: '%s'", rowBinlogFormat, binlogFormat)
+	}
+
+	//checks REPLICATION CLIENT privilege
+	if _, _, err := binlogFilePosition(context.Background(), dataSource); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func connect(config *MySQLCDCConfig) (*sql.DB, error) {
+	dataSource, err := sql.Open("mysql", config.DSN())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dataSource.Ping(); err != nil {
+		dataSource.Close()
+		return nil, err
+	}
+
+	dataSource.SetConnMaxLifetime(10 * time.Minute)
+	return dataSource, nil
+}
+
+//GetRefreshWindow returns zero: every sync reads only new changes
+func (m *MySQLCDC) GetRefreshWindow() (time.Duration, error) {
+	return 0, nil
+}
+
+//GetAllAvailableIntervals returns ALL constant
+func (m *MySQLCDC) GetAllAvailableIntervals() ([]*base.TimeInterval, error) {
+	return []*base.TimeInterval{base.NewTimeInterval(schema.ALL, time.Time{})}, nil
+}
+
+//GetObjectsFor loads snapshot of captured tables on the first sync (if enabled) and then reads binlog changes
+//till the end of the binlog
+func (m *MySQLCDC) GetObjectsFor(interval *base.TimeInterval, objectsLoader base.ObjectsLoader) error {
+	loader := &batchLoader{objectsLoader: objectsLoader, batchSize: m.config.BatchSize}
+
+	if m.state == "" {
+		start, err := m.currentPosition()
+		if err != nil {
+			return fmt.Errorf("error getting current binlog position: %v", err)
+		}
+
+		if m.parameters.IsSnapshotEnabled() {
+			logging.Infof("[%s_%s] Loading snapshot. Binlog position: %s", m.collection.SourceID, m.collection.Name, start)
+			if err := m.snapshot(start, loader); err != nil {
+				return err
+			}
+		} else {
+			m.state = start.String()
+			if err := loader.flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	start, err := parsePosition(m.state)
+	if err != nil {
+		return fmt.Errorf("error parsing binlog position [%s]: %v", m.state, err)
+	}
+
+	return m.stream(start, loader)
+}
+
+//currentPosition returns the current binlog position of the server
+func (m *MySQLCDC) currentPosition() (*position, error) {
+	pos := &position{Flavor: MySQLFlavor}
+
+	var version string
+	if err := m.dataSource.QueryRowContext(m.ctx, versionQuery).Scan(&version); err != nil {
+		return nil, err
+	}
+	if strings.Contains(strings.ToLower(version), MariaDBFlavor) {
+		pos.Flavor = MariaDBFlavor
+	}
+
+	//GTIDs are read before file position: transactions between two queries will be read twice but not lost
+	if pos.Flavor == MariaDBFlavor {
+		if err := m.dataSource.QueryRowContext(m.ctx, mariadbGTIDPosQuery).Scan(&pos.GTIDSet); err != nil {
+			return nil, err
+		}
+	} else {
+		var gtidMode string
+		if err := m.dataSource.QueryRowContext(m.ctx, gtidModeQuery).Scan(&gtidMode); err == nil && strings.EqualFold(gtidMode, gtidModeOn) {
+			if err := m.dataSource.QueryRowContext(m.ctx, gtidExecutedQuery).Scan(&pos.GTIDSet); err != nil {
+				return nil, err
+			}
+			pos.GTIDSet = strings.Replace(pos.GTIDSet, "\n", "", -1)
+		}
+	}
+
+	file, filePos, err := binlogFilePosition(m.ctx, m.dataSource)
+	if err != nil {
+		return nil, err
+	}
+	pos.File = file
+	pos.Pos = filePos
+
+	return pos, nil
+}
+
+//binlogFilePosition returns the current binlog file and position. SHOW MASTER STATUS is replaced with
+//SHOW BINARY LOG STATUS in MySQL 8.4
+func binlogFilePosition(ctx context.Context, dataSource *sql.DB) (string, uint32, error) {
+	file, pos, err := queryFilePosition(ctx, dataSource, masterStatusQuery)
+	if err != nil {
+		var fallbackErr error
+		if file, pos, fallbackErr = queryFilePosition(ctx, dataSource, binaryLogStatusQuery); fallbackErr != nil {
+			return "", 0, fmt.Errorf("error getting binlog status: %v", err)
+		}
+	}
+	if file == "" {
+		return "", 0, fmt.Errorf("binlog status is empty. Please check that binary logging is enabled")
+	}
+
+	return file, pos, nil
+}
+
+func queryFilePosition(ctx context.Context, dataSource *sql.DB, query string) (string, uint32, error) {
+	rows, err := dataSource.QueryContext(ctx, query)
+	if err != nil {
+		return "", 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", 0, err
+	}
+	if !rows.Next() {
+		return "", 0, rows.Err()
+	}
+
+	//result columns differ between versions: File and Position are always the first
+	values := make([]sql.RawBytes, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return "", 0, err
+	}
+	if len(values) < 2 {
+		return "", 0, fmt.Errorf("unexpected result of %s", query)
+	}
+
+	var pos uint32
+	if _, err := fmt.Sscan(string(values[1]), &pos); err != nil {
+		return "", 0, fmt.Errorf("malformed binlog position [%s]: %v", string(values[1]), err)
+	}
+
+	return string(values[0]), pos, nil
+}
+
+//isCaptured returns true if changes of the table must be read
+func (m *MySQLCDC) isCaptured(schemaName, table string) bool {
+	if schemaName != m.config.Db {
+		return false
+	}
+	if len(m.parameters.Tables) == 0 {
+		return true
+	}
+	for _, captured := range m.parameters.Tables {
+		if captured == table {
+			return true
+		}
+	}
+
+	return false
+}
+
+//IsIncremental returns true: objects loaded by previous syncs must be kept
+func (m *MySQLCDC) IsIncremental() bool {
+	return true
+}
+
+//SetState sets the binlog position which has been saved by the previous sync
+func (m *MySQLCDC) SetState(state string) {
+	m.state = state
+}
+
+//GetState returns the binlog position of objects which have been passed to objectsLoader
+func (m *MySQLCDC) GetState() string {
+	return m.state
+}
+
+//Type returns MySQLCDC type
+func (m *MySQLCDC) Type() string {
+	return base.MySQLCDCType
+}
+
+//GetCollectionTable returns collection table
+func (m *MySQLCDC) GetCollectionTable() string {
+	return m.collection.GetTableName()
+}
+
+//GetCollectionMetaKey returns collection meta key (key is used in meta storage)
+func (m *MySQLCDC) GetCollectionMetaKey() string {
+	return m.collection.Name + "_" + m.GetCollectionTable()
+}
+
+//Close closes connection pool
+func (m *MySQLCDC) Close() error {
+	return m.dataSource.Close()
+}
+
+//batchLoader passes objects to objectsLoader by batches
+type batchLoader struct {
+	objectsLoader base.ObjectsLoader
+	batchSize     int
+	objects       []map[string]interface{}
+	pos           int
+}
+
+func (bl *batchLoader) add(objects ...map[string]interface{}) {
+	bl.objects = append(bl.objects, objects...)
+}
+
+//flushIfFull flushes objects if there are at least batchSize objects
+func (bl *batchLoader) flushIfFull() error {
+	if len(bl.objects) < bl.batchSize {
+		return nil
+	}
+	return bl.flush()
+}
+
+//flush passes objects to objectsLoader. Empty batch is passed as well for saving the driver state
+func (bl *batchLoader) flush() error {
+	if err := bl.objectsLoader(bl.objects, bl.pos, -1, -1); err != nil {
+		return err
+	}
+	bl.pos += len(bl.objects)
+	bl.objects = nil
+	return nil
+}
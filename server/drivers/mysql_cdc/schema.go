@@ -0,0 +1,159 @@
+package mysql_cdc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const columnsQuery = `SELECT COLUMN_NAME, COLUMN_TYPE FROM information_schema.COLUMNS
+	WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`
+
+type columnSchema struct {
+	name       string
+	unsigned   bool
+	enumValues []string
+}
+
+//schemaCache keeps tables columns from information_schema. It is used when the binlog doesn't contain
+//column names (binlog_row_metadata=MINIMAL) and is invalidated on every DDL statement
+type schemaCache struct {
+	ctx        context.Context
+	dataSource *sql.DB
+	tables     map[string][]*columnSchema
+}
+
+func newSchemaCache(ctx context.Context, dataSource *sql.DB) *schemaCache {
+	return &schemaCache{ctx: ctx, dataSource: dataSource, tables: map[string][]*columnSchema{}}
+}
+
+func (sc *schemaCache) invalidate() {
+	sc.tables = map[string][]*columnSchema{}
+}
+
+func (sc *schemaCache) get(schemaName, table string) ([]*columnSchema, error) {
+	key := schemaName + "." + table
+	if columns, ok := sc.tables[key]; ok {
+		return columns, nil
+	}
+
+	rows, err := sc.dataSource.QueryContext(sc.ctx, columnsQuery, schemaName, table)
+	if err != nil {
+		return nil, fmt.Errorf("error querying columns of table [%s]: %v", key, err)
+	}
+	defer rows.Close()
+
+	var columns []*columnSchema
+	for rows.Next() {
+		var name, columnType string
+		if err := rows.Scan(&name, &columnType); err != nil {
+			return nil, fmt.Errorf("error scanning columns of table [%s]: %v", key, err)
+		}
+		columns = append(columns, &columnSchema{
+			name:       name,
+			unsigned:   strings.Contains(strings.ToLower(columnType), "unsigned"),
+			enumValues: parseEnumValues(columnType),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading columns of table [%s]: %v", key, err)
+	}
+
+	sc.tables[key] = columns
+	return columns, nil
+}
+
+//resolve returns columns of TABLE_MAP event. Column names, signedness and ENUM/SET labels are read from the binlog
+//optional metadata (binlog_row_metadata=FULL) or from information_schema. If the current table structure doesn't match
+//the binlog (table has been changed after the event was written), positional column names are kept
+func (sc *schemaCache) resolve(tm *replication.TableMapEvent) ([]*columnSchema, error) {
+	names := tm.ColumnNameString()
+	unsigned := tm.UnsignedMap()
+	enumValues := tm.EnumStrValueMap()
+	setValues := tm.SetStrValueMap()
+
+	columns := make([]*columnSchema, tm.ColumnCount)
+	hasColumnNames, hasSignedness, hasEnumValues := len(names) == len(columns), true, true
+	for i := range columns {
+		col := &columnSchema{name: fmt.Sprintf("col_%d", i+1), unsigned: unsigned[i]}
+		if hasColumnNames {
+			col.name = names[i]
+		}
+		if tm.IsNumericColumn(i) && unsigned == nil {
+			hasSignedness = false
+		}
+		if tm.IsEnumColumn(i) {
+			col.enumValues = enumValues[i]
+		} else if tm.IsSetColumn(i) {
+			col.enumValues = setValues[i]
+		}
+		if tm.IsEnumOrSetColumn(i) && col.enumValues == nil {
+			hasEnumValues = false
+		}
+		columns[i] = col
+	}
+	if hasColumnNames && hasSignedness && hasEnumValues {
+		return columns, nil
+	}
+
+	schemaName, table := string(tm.Schema), string(tm.Table)
+	tableColumns, err := sc.get(schemaName, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(tableColumns) != len(columns) {
+		logging.Warnf("Table [%s.%s] has %d columns but binlog event has %d columns. Positional column names are used. Please set binlog_row_metadata=FULL for reading column names from the binlog",
+			schemaName, table, len(tableColumns), len(columns))
+		return columns, nil
+	}
+
+	for i, col := range columns {
+		if !hasColumnNames {
+			col.name = tableColumns[i].name
+		}
+		if !hasSignedness {
+			col.unsigned = tableColumns[i].unsigned
+		}
+		if !hasEnumValues {
+			col.enumValues = tableColumns[i].enumValues
+		}
+	}
+
+	return columns, nil
+}
+
+//parseEnumValues returns labels of enum('a','b') or set('a','b') column type
+func parseEnumValues(columnType string) []string {
+	lower := strings.ToLower(columnType)
+	if !strings.HasPrefix(lower, "enum(") && !strings.HasPrefix(lower, "set(") {
+		return nil
+	}
+
+	body := columnType[strings.Index(columnType, "(")+1 : strings.LastIndex(columnType, ")")]
+	var labels []string
+	var label strings.Builder
+	inQuotes := false
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\'' && inQuotes && i+1 < len(body) && body[i+1] == '\'':
+			//escaped quote
+			label.WriteByte(c)
+			i++
+		case c == '\'':
+			inQuotes = !inQuotes
+			if !inQuotes {
+				labels = append(labels, label.String())
+				label.Reset()
+			}
+		case inQuotes:
+			label.WriteByte(c)
+		}
+	}
+
+	return labels
+}
@@ -0,0 +1,138 @@
+package mysql_cdc
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const tablesQuery = `SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME`
+
+//snapshot loads all rows of captured tables in one repeatable read transaction. Objects are passed to objectsLoader
+//by batches. The driver state isn't set until the last batch, so a failed snapshot is restarted from the beginning
+func (m *MySQLCDC) snapshot(start *position, loader *batchLoader) error {
+	tx, err := m.dataSource.BeginTx(m.ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("error starting snapshot transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	tables, err := m.capturedTables(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if err := m.snapshotTable(tx, table, loader); err != nil {
+			return fmt.Errorf("error loading snapshot of table [%s]: %v", table, err)
+		}
+	}
+
+	m.state = start.String()
+	return loader.flush()
+}
+
+//capturedTables returns configured tables or all tables of the db
+func (m *MySQLCDC) capturedTables(tx *sql.Tx) ([]string, error) {
+	if len(m.parameters.Tables) > 0 {
+		return m.parameters.Tables, nil
+	}
+
+	rows, err := tx.QueryContext(m.ctx, tablesQuery, m.config.Db)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables of db [%s]: %v", m.config.Db, err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+func (m *MySQLCDC) snapshotTable(tx *sql.Tx, table string, loader *batchLoader) error {
+	rows, err := tx.QueryContext(m.ctx, fmt.Sprintf("SELECT * FROM %s.%s", quoteIdentifier(m.config.Db), quoteIdentifier(table)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columnTypes))
+	pointers := make([]interface{}, len(columnTypes))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+
+		columns := make(map[string]interface{}, len(columnTypes))
+		for i, columnType := range columnTypes {
+			value, err := convertSnapshotValue(columnType.DatabaseTypeName(), values[i])
+			if err != nil {
+				return fmt.Errorf("error converting column [%s] value: %v", columnType.Name(), err)
+			}
+			columns[columnType.Name()] = value
+		}
+
+		c := &change{operation: SnapshotOperation, schema: m.config.Db, table: table, columns: columns}
+		loader.add(c.toObject())
+		if err := loader.flushIfFull(); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+//convertSnapshotValue converts go-sql-driver text protocol value into the same type as binlog value has
+func convertSnapshotValue(databaseType string, value interface{}) (interface{}, error) {
+	b, ok := value.([]byte)
+	if !ok {
+		//nil or time.Time
+		return value, nil
+	}
+
+	s := string(b)
+	switch strings.TrimPrefix(databaseType, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		return strconv.ParseFloat(s, 64)
+	case "DECIMAL", "FLOAT", "DOUBLE":
+		return strconv.ParseFloat(s, 64)
+	case "BIT":
+		return int64(readBigEndian(b)), nil
+	case "JSON":
+		var object interface{}
+		if err := json.Unmarshal(b, &object); err != nil {
+			return nil, err
+		}
+		return object, nil
+	case "GEOMETRY":
+		return hex.EncodeToString(b), nil
+	default:
+		return s, nil
+	}
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
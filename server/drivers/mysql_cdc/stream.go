@@ -0,0 +1,363 @@
+package mysql_cdc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/google/uuid"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/siddontang/go-log/log"
+)
+
+const (
+	//binlog events which change rows but can't be decoded
+	partialUpdateRowsEventType  = 39
+	transactionPayloadEventType = 40
+	mariadbQueryCompressedType  = 165
+	mariadbRowsCompressedLast   = 171
+
+	beginQuery = "BEGIN"
+
+	//streamIdleTimeout stops reading if there are no events although the end position hasn't been reached
+	//(e.g. the rest of the binlog consists of transactions which are skipped by the server)
+	streamIdleTimeout = time.Minute
+)
+
+//binlogStream keeps the read position and the current transaction while reading the binlog
+type binlogStream struct {
+	flavor  string
+	file    string
+	filePos uint32
+	//gtids is MySQL executed GTID set or MariaDB GTID position. nil if MySQL binlog is read from the file position
+	gtids gomysql.GTIDSet
+	//readFile and readPos are the position of the last read event (not committed)
+	readFile string
+	readPos  uint32
+
+	//current transaction
+	gtid        string
+	transaction []*change
+	tables      map[uint64]*tableColumns
+}
+
+//tableColumns is a captured table of TABLE_MAP event with resolved columns
+type tableColumns struct {
+	schema  string
+	table   string
+	columns []*columnSchema
+}
+
+//stream reads binlog changes from the position till the binlog end position at the moment of the call.
+//Objects are passed to loader by whole transactions
+func (m *MySQLCDC) stream(start *position, loader *batchLoader) error {
+	bs, err := newBinlogStream(start)
+	if err != nil {
+		return fmt.Errorf("error parsing binlog position %s: %v", start, err)
+	}
+
+	endFile, endPos, err := binlogFilePosition(m.ctx, m.dataSource)
+	if err != nil {
+		return err
+	}
+	if !bs.before(endFile, endPos) {
+		return nil
+	}
+
+	syncer := replication.NewBinlogSyncer(m.syncerConfig(start.Flavor))
+	defer syncer.Close()
+
+	var streamer *replication.BinlogStreamer
+	if bs.gtids != nil && start.GTIDSet != "" {
+		streamer, err = syncer.StartSyncGTID(bs.gtids.Clone())
+	} else {
+		streamer, err = syncer.StartSync(gomysql.Position{Name: bs.file, Pos: bs.filePos})
+	}
+	if err != nil {
+		return fmt.Errorf("error requesting binlog from position %s: %v", start, err)
+	}
+
+	initialState := m.state
+	for bs.before(endFile, endPos) {
+		ctx, cancel := context.WithTimeout(m.ctx, streamIdleTimeout)
+		event, err := streamer.GetEvent(ctx)
+		cancel()
+		if err != nil {
+			if m.ctx.Err() != nil {
+				return m.ctx.Err()
+			}
+			if err == context.DeadlineExceeded {
+				logging.Warnf("[%s_%s] No binlog events for %s at %s. Binlog end position: %s:%d", m.collection.SourceID,
+					m.collection.Name, streamIdleTimeout, bs.currentPosition(), endFile, endPos)
+				break
+			}
+			return fmt.Errorf("error reading binlog %s: %v", bs.currentPosition(), err)
+		}
+
+		committed, err := m.handleEvent(bs, event)
+		if err != nil {
+			return fmt.Errorf("error handling binlog event %s: %v", bs.currentPosition(), err)
+		}
+
+		if committed {
+			for _, c := range bs.transaction {
+				c.commitTime = time.Unix(int64(event.Header.Timestamp), 0).UTC()
+				loader.add(c.toObject())
+			}
+			bs.transaction = nil
+			m.state = bs.currentPosition().String()
+
+			if err := loader.flushIfFull(); err != nil {
+				return err
+			}
+		}
+	}
+
+	//position is saved even without objects: skipped events of other tables mustn't be read again
+	if len(loader.objects) > 0 || m.state != initialState {
+		return loader.flush()
+	}
+
+	return nil
+}
+
+//syncerConfig returns go-mysql replica configuration. Reconnects are disabled: the next sync continues from the saved position
+func (m *MySQLCDC) syncerConfig(flavor string) replication.BinlogSyncerConfig {
+	port, _ := m.config.Port.Int64()
+	return replication.BinlogSyncerConfig{
+		ServerID:         m.config.ServerID,
+		Flavor:           flavor,
+		Host:             m.config.Host,
+		Port:             uint16(port),
+		User:             m.config.Username,
+		Password:         m.config.Password,
+		TLSConfig:        m.config.binlogTLSConfig(),
+		ParseTime:        true,
+		DisableRetrySync: true,
+		Logger:           newSyncerLogger(fmt.Sprintf("[%s_%s] ", m.collection.SourceID, m.collection.Name)),
+	}
+}
+
+//newSyncerLogger returns go-mysql logger which writes warnings and errors into the server log
+func newSyncerLogger(prefix string) *log.Logger {
+	logger := log.New(&syncerLogHandler{prefix: prefix}, log.Llevel)
+	logger.SetLevel(log.LevelWarn)
+	return logger
+}
+
+//syncerLogHandler is a go-mysql log handler which writes into the server log
+type syncerLogHandler struct {
+	prefix string
+}
+
+func (slh *syncerLogHandler) Write(p []byte) (int, error) {
+	logging.Warn(slh.prefix + strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+func (slh *syncerLogHandler) Close() error {
+	return nil
+}
+
+func newBinlogStream(start *position) (*binlogStream, error) {
+	bs := &binlogStream{flavor: start.Flavor, file: start.File, filePos: start.Pos, readFile: start.File, readPos: start.Pos,
+		tables: map[uint64]*tableColumns{}}
+
+	var err error
+	if start.Flavor == MariaDBFlavor {
+		bs.gtids, err = gomysql.ParseMariadbGTIDSet(start.GTIDSet)
+	} else if start.GTIDSet != "" {
+		//transactions are tracked only if the position is a GTID set. Otherwise file position is used
+		bs.gtids, err = gomysql.ParseMysqlGTIDSet(start.GTIDSet)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return bs, nil
+}
+
+//handleEvent updates the stream position and collects changes of the current transaction.
+//Returns true if the transaction has been committed
+func (m *MySQLCDC) handleEvent(bs *binlogStream, event *replication.BinlogEvent) (bool, error) {
+	header := event.Header
+	if err := checkEventSupported(byte(header.EventType)); err != nil {
+		return false, err
+	}
+	//artificial events have zero position
+	if header.LogPos > 0 {
+		bs.readPos = header.LogPos
+	}
+
+	switch e := event.Event.(type) {
+	case *replication.RotateEvent:
+		bs.file = string(e.NextLogName)
+		bs.filePos = uint32(e.Position)
+		bs.readFile = bs.file
+		bs.readPos = bs.filePos
+	case *replication.GTIDEvent:
+		sid, err := uuid.FromBytes(e.SID)
+		if err != nil {
+			return false, err
+		}
+		bs.gtid = fmt.Sprintf("%s:%d", sid, e.GNO)
+	case *replication.MariadbGTIDEvent:
+		bs.gtid = e.GTID.String()
+	case *replication.TableMapEvent:
+		schemaName, table := string(e.Schema), string(e.Table)
+		if !m.isCaptured(schemaName, table) {
+			delete(bs.tables, e.TableID)
+			return false, nil
+		}
+		columns, err := m.schemas.resolve(e)
+		if err != nil {
+			return false, err
+		}
+		bs.tables[e.TableID] = &tableColumns{schema: schemaName, table: table, columns: columns}
+	case *replication.RowsEvent:
+		tc, ok := bs.tables[e.TableID]
+		if !ok {
+			return false, nil
+		}
+
+		rows, err := tc.rows(header.EventType, e)
+		if err != nil {
+			return false, err
+		}
+		for _, row := range rows {
+			bs.transaction = append(bs.transaction, &change{
+				operation:  rowsOperation(header.EventType),
+				schema:     tc.schema,
+				table:      tc.table,
+				gtid:       bs.gtid,
+				binlogFile: bs.file,
+				binlogPos:  header.LogPos,
+				columns:    row,
+			})
+		}
+	case *replication.QueryEvent:
+		query := string(e.Query)
+		if query == beginQuery {
+			return false, nil
+		}
+		//table structure might be changed: table maps of next transactions are resolved with the new schema
+		if isDDL(query) {
+			m.schemas.invalidate()
+		}
+		return true, bs.commit(header)
+	case *replication.XIDEvent:
+		return true, bs.commit(header)
+	}
+
+	return false, nil
+}
+
+//commit moves the position to the end of the transaction
+func (bs *binlogStream) commit(header *replication.EventHeader) error {
+	if header.LogPos > 0 {
+		bs.filePos = header.LogPos
+	}
+	gtid := bs.gtid
+	bs.gtid = ""
+	if bs.gtids != nil && gtid != "" {
+		return bs.gtids.Update(gtid)
+	}
+
+	return nil
+}
+
+//before returns true if the last read event is before the binlog file position
+func (bs *binlogStream) before(file string, pos uint32) bool {
+	return bs.readFile < file || bs.readFile == file && bs.readPos < pos
+}
+
+func (bs *binlogStream) currentPosition() *position {
+	pos := &position{Flavor: bs.flavor, File: bs.file, Pos: bs.filePos}
+	if bs.gtids != nil {
+		pos.GTIDSet = bs.gtids.String()
+	}
+
+	return pos
+}
+
+//rows returns rows of WRITE, UPDATE or DELETE rows event. Inserted and updated rows are returned with new values,
+//deleted rows are returned with old values. Columns which aren't written (binlog_row_image=MINIMAL) are skipped
+func (tc *tableColumns) rows(eventType replication.EventType, e *replication.RowsEvent) ([]map[string]interface{}, error) {
+	if int(e.ColumnCount) != len(tc.columns) {
+		return nil, fmt.Errorf("rows event has %d columns but table map of [%s.%s] has %d columns", e.ColumnCount, tc.schema, tc.table, len(tc.columns))
+	}
+
+	//update events contain before and after images of each row
+	first, step := 0, 1
+	if rowsOperation(eventType) == UpdateOperation {
+		first, step = 1, 2
+	}
+
+	var rows []map[string]interface{}
+	for i := first; i < len(e.Rows); i += step {
+		skipped := map[int]bool{}
+		if i < len(e.SkippedColumns) {
+			for _, index := range e.SkippedColumns[i] {
+				skipped[index] = true
+			}
+		}
+
+		row := make(map[string]interface{}, len(tc.columns)-len(skipped))
+		for index, col := range tc.columns {
+			if skipped[index] {
+				continue
+			}
+			value, err := convertBinlogValue(e.Table, index, col, e.Rows[i][index])
+			if err != nil {
+				return nil, fmt.Errorf("error reading column [%s] of table [%s.%s]: %v", col.name, tc.schema, tc.table, err)
+			}
+			row[col.name] = value
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+//checkEventSupported returns err for events which change rows but can't be decoded
+func checkEventSupported(eventType byte) error {
+	switch {
+	case eventType == partialUpdateRowsEventType:
+		return fmt.Errorf("partial JSON updates aren't supported. Please set binlog_row_value_options=''")
+	case eventType == transactionPayloadEventType:
+		return fmt.Errorf("compressed transactions aren't supported. Please set binlog_transaction_compression=OFF")
+	case eventType >= mariadbQueryCompressedType && eventType <= mariadbRowsCompressedLast:
+		return fmt.Errorf("compressed binlog events aren't supported. Please set log_bin_compress=OFF")
+	default:
+		return nil
+	}
+}
+
+//rowsOperation returns operation name of the rows event type or empty string for other events
+func rowsOperation(eventType replication.EventType) string {
+	switch eventType {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		return InsertOperation
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		return UpdateOperation
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		return DeleteOperation
+	default:
+		return ""
+	}
+}
+
+//isDDL returns true if the query changes tables structure
+func isDDL(query string) bool {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	for _, prefix := range []string{"ALTER ", "CREATE ", "DROP ", "RENAME ", "TRUNCATE "} {
+		if strings.HasPrefix(query, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package mysql_cdc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+//ordersTableMap returns TABLE_MAP event of shop.orders(id int unsigned, name varchar(255), created datetime,
+//status enum('new','paid')) with binlog_row_metadata=FULL
+func ordersTableMap() *replication.TableMapEvent {
+	return &replication.TableMapEvent{
+		TableID:     7,
+		Schema:      []byte("shop"),
+		Table:       []byte("orders"),
+		ColumnCount: 4,
+		ColumnType:  []byte{gomysql.MYSQL_TYPE_LONG, gomysql.MYSQL_TYPE_VARCHAR, gomysql.MYSQL_TYPE_DATETIME2, gomysql.MYSQL_TYPE_STRING},
+		ColumnMeta:  []uint16{0, 255, 0, uint16(gomysql.MYSQL_TYPE_ENUM)<<8 | 1},
+		//the first numeric column is unsigned
+		SignednessBitmap: []byte{0x80},
+		ColumnName:       [][]byte{[]byte("id"), []byte("name"), []byte("created"), []byte("status")},
+		EnumStrValue:     [][][]byte{{[]byte("new"), []byte("paid")}},
+	}
+}
+
+func binlogEvent(eventType replication.EventType, logPos uint32, event replication.Event) *replication.BinlogEvent {
+	return &replication.BinlogEvent{Header: &replication.EventHeader{EventType: eventType, LogPos: logPos}, Event: event}
+}
+
+func TestHandleEvents(t *testing.T) {
+	m := &MySQLCDC{
+		config:     &MySQLCDCConfig{Db: "shop"},
+		parameters: &MySQLCDCParameters{},
+		schemas:    newSchemaCache(context.Background(), nil),
+	}
+	bs, err := newBinlogStream(&position{Flavor: MySQLFlavor, GTIDSet: "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-7", File: "binlog.000001", Pos: 1000})
+	require.NoError(t, err)
+	require.True(t, bs.before("binlog.000002", 500))
+
+	sid := uuid.MustParse("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	tm := ordersTableMap()
+	otherTable := &replication.TableMapEvent{TableID: 8, Schema: []byte("other"), Table: []byte("orders"), ColumnCount: 1,
+		ColumnType: []byte{gomysql.MYSQL_TYPE_LONG}, ColumnMeta: []uint16{0}}
+	created := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	events := []*replication.BinlogEvent{
+		binlogEvent(replication.ROTATE_EVENT, 0, &replication.RotateEvent{Position: 4, NextLogName: []byte("binlog.000002")}),
+		binlogEvent(replication.GTID_EVENT, 200, &replication.GTIDEvent{SID: sid[:], GNO: 8}),
+		binlogEvent(replication.QUERY_EVENT, 250, &replication.QueryEvent{Query: []byte(beginQuery)}),
+		binlogEvent(replication.TABLE_MAP_EVENT, 300, tm),
+		binlogEvent(replication.TABLE_MAP_EVENT, 350, otherTable),
+		binlogEvent(replication.WRITE_ROWS_EVENTv2, 400, &replication.RowsEvent{TableID: 7, Table: tm, ColumnCount: 4,
+			Rows:           [][]interface{}{{int32(-1), "abc", created, int64(2)}, {int32(1), nil, nil, int64(1)}},
+			SkippedColumns: [][]int{{}, {}}}),
+		//minimal row image: only id in before image and without created in after image
+		binlogEvent(replication.UPDATE_ROWS_EVENTv2, 420, &replication.RowsEvent{TableID: 7, Table: tm, ColumnCount: 4,
+			Rows:           [][]interface{}{{int32(42), nil, nil, nil}, {int32(42), "new", nil, int64(1)}},
+			SkippedColumns: [][]int{{1, 2, 3}, {2}}}),
+		binlogEvent(replication.DELETE_ROWS_EVENTv2, 450, &replication.RowsEvent{TableID: 8, Table: otherTable, ColumnCount: 1,
+			Rows: [][]interface{}{{int32(1)}}, SkippedColumns: [][]int{{}}}),
+	}
+	for _, event := range events {
+		committed, err := m.handleEvent(bs, event)
+		require.NoError(t, err)
+		require.False(t, committed)
+	}
+
+	require.Len(t, bs.transaction, 3)
+	require.Equal(t, &change{operation: InsertOperation, schema: "shop", table: "orders", gtid: "3e11fa47-71ca-11e1-9e33-c80aa9429562:8",
+		binlogFile: "binlog.000002", binlogPos: 400,
+		columns: map[string]interface{}{"id": int64(4294967295), "name": "abc", "created": created, "status": "paid"}}, bs.transaction[0])
+	require.Equal(t, map[string]interface{}{"id": int64(1), "name": nil, "created": nil, "status": "new"}, bs.transaction[1].columns)
+	require.Equal(t, UpdateOperation, bs.transaction[2].operation)
+	require.Equal(t, map[string]interface{}{"id": int64(42), "name": "new", "status": "new"}, bs.transaction[2].columns)
+
+	committed, err := m.handleEvent(bs, binlogEvent(replication.XID_EVENT, 500, &replication.XIDEvent{}))
+	require.NoError(t, err)
+	require.True(t, committed)
+	require.Equal(t, &position{Flavor: MySQLFlavor, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-8", File: "binlog.000002", Pos: 500},
+		bs.currentPosition())
+	require.False(t, bs.before("binlog.000002", 500))
+
+	//DDL invalidates the schema cache
+	m.schemas.tables["shop.orders"] = []*columnSchema{{name: "id"}}
+	committed, err = m.handleEvent(bs, binlogEvent(replication.QUERY_EVENT, 600, &replication.QueryEvent{Query: []byte("ALTER TABLE orders ADD COLUMN note text")}))
+	require.NoError(t, err)
+	require.True(t, committed)
+	require.Empty(t, m.schemas.tables)
+
+	_, err = m.handleEvent(bs, binlogEvent(transactionPayloadEventType, 700, &replication.GenericEvent{}))
+	require.Error(t, err)
+}
+
+func TestMariaDBPosition(t *testing.T) {
+	bs, err := newBinlogStream(&position{Flavor: MariaDBFlavor, File: "mysql-bin.000001", Pos: 4})
+	require.NoError(t, err)
+
+	m := &MySQLCDC{config: &MySQLCDCConfig{Db: "shop"}, parameters: &MySQLCDCParameters{}}
+	for _, event := range []*replication.BinlogEvent{
+		binlogEvent(replication.MARIADB_GTID_EVENT, 100, &replication.MariadbGTIDEvent{GTID: gomysql.MariadbGTID{DomainID: 0, ServerID: 1, SequenceNumber: 100}}),
+		binlogEvent(replication.XID_EVENT, 200, &replication.XIDEvent{}),
+		binlogEvent(replication.MARIADB_GTID_EVENT, 300, &replication.MariadbGTIDEvent{GTID: gomysql.MariadbGTID{DomainID: 1, ServerID: 2, SequenceNumber: 5}}),
+		binlogEvent(replication.QUERY_EVENT, 400, &replication.QueryEvent{Query: []byte("COMMIT")}),
+	} {
+		_, err := m.handleEvent(bs, event)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, &position{Flavor: MariaDBFlavor, GTIDSet: "0-1-100,1-2-5", File: "mysql-bin.000001", Pos: 400}, bs.currentPosition())
+
+	_, err = newBinlogStream(&position{Flavor: MariaDBFlavor, GTIDSet: "0-1-1'"})
+	require.Error(t, err)
+}
+
+func TestConvertBinlogValue(t *testing.T) {
+	tm := &replication.TableMapEvent{
+		ColumnCount: 8,
+		ColumnType: []byte{gomysql.MYSQL_TYPE_LONGLONG, gomysql.MYSQL_TYPE_INT24, gomysql.MYSQL_TYPE_STRING, gomysql.MYSQL_TYPE_DATE,
+			gomysql.MYSQL_TYPE_JSON, gomysql.MYSQL_TYPE_GEOMETRY, gomysql.MYSQL_TYPE_FLOAT, gomysql.MYSQL_TYPE_YEAR},
+		ColumnMeta: []uint16{0, 0, uint16(gomysql.MYSQL_TYPE_SET)<<8 | 1, 0, 4, 4, 4, 0},
+	}
+	tests := []struct {
+		name     string
+		index    int
+		col      *columnSchema
+		value    interface{}
+		expected interface{}
+	}{
+		{"null", 0, &columnSchema{}, nil, nil},
+		{"signed bigint", 0, &columnSchema{}, int64(-1), int64(-1)},
+		{"unsigned bigint", 0, &columnSchema{unsigned: true}, int64(-1), float64(18446744073709551615)},
+		{"unsigned mediumint", 1, &columnSchema{unsigned: true}, int32(-1), int64(16777215)},
+		{"set", 2, &columnSchema{enumValues: []string{"a", "b", "c"}}, int64(5), "a,c"},
+		{"set without labels", 2, &columnSchema{}, int64(5), int64(5)},
+		{"date", 3, &columnSchema{}, "2022-03-04", time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"zero date", 3, &columnSchema{}, "0000-00-00", "0000-00-00"},
+		{"json", 4, &columnSchema{}, `{"a":1,"b":"x"}`, map[string]interface{}{"a": float64(1), "b": "x"}},
+		{"geometry", 5, &columnSchema{}, []byte{0x01, 0xff}, "01ff"},
+		{"float", 6, &columnSchema{}, float32(0.5), float64(0.5)},
+		{"year", 7, &columnSchema{}, 2022, int64(2022)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := convertBinlogValue(tm, tt.index, tt.col, tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, value)
+		})
+	}
+
+	_, err := convertBinlogValue(tm, 4, &columnSchema{}, "{")
+	require.Error(t, err)
+}
+
+func TestParseEnumValues(t *testing.T) {
+	require.Equal(t, []string{"a", "b'c", "d,e"}, parseEnumValues("enum('a','b''c','d,e')"))
+	require.Equal(t, []string{"x"}, parseEnumValues("SET('x')"))
+	require.Nil(t, parseEnumValues("int(10) unsigned"))
+}
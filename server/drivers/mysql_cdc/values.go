@@ -0,0 +1,107 @@
+package mysql_cdc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+const dateLayout = "2006-01-02"
+
+//convertBinlogValue converts go-mysql row value into the same type as snapshot value has: int64 for integers,
+//BIT, YEAR and ENUM/SET without labels, float64 for FLOAT, DOUBLE, DECIMAL and unsigned BIGINT greater than MaxInt64,
+//time.Time (UTC) for dates except zero dates, string for text, blobs and TIME, hex string for GEOMETRY
+//and decoded object for JSON
+func convertBinlogValue(tm *replication.TableMapEvent, i int, col *columnSchema, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int8:
+		if col.unsigned {
+			return int64(uint8(v)), nil
+		}
+		return int64(v), nil
+	case int16:
+		if col.unsigned {
+			return int64(uint16(v)), nil
+		}
+		return int64(v), nil
+	case int32:
+		if col.unsigned && tm.ColumnType[i] == gomysql.MYSQL_TYPE_INT24 {
+			return int64(uint32(v) & 0xffffff), nil
+		}
+		if col.unsigned {
+			return int64(uint32(v)), nil
+		}
+		return int64(v), nil
+	case int64:
+		switch {
+		case tm.IsEnumColumn(i):
+			if v > 0 && int(v) <= len(col.enumValues) {
+				return col.enumValues[v-1], nil
+			}
+			return v, nil
+		case tm.IsSetColumn(i):
+			if len(col.enumValues) == 0 {
+				return v, nil
+			}
+			var values []string
+			for bit, value := range col.enumValues {
+				if v&(1<<uint(bit)) != 0 {
+					values = append(values, value)
+				}
+			}
+			return strings.Join(values, ","), nil
+		case col.unsigned && v < 0:
+			return float64(uint64(v)), nil
+		default:
+			return v, nil
+		}
+	case int:
+		//YEAR
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case time.Time:
+		return v.UTC(), nil
+	case []byte:
+		if tm.IsGeometryColumn(i) {
+			return hex.EncodeToString(v), nil
+		}
+		return string(v), nil
+	case string:
+		switch tm.ColumnType[i] {
+		case gomysql.MYSQL_TYPE_JSON:
+			if v == "" {
+				return nil, nil
+			}
+			var object interface{}
+			if err := json.Unmarshal([]byte(v), &object); err != nil {
+				return nil, fmt.Errorf("error decoding JSON: %v", err)
+			}
+			return object, nil
+		case gomysql.MYSQL_TYPE_DATE, gomysql.MYSQL_TYPE_NEWDATE:
+			//zero dates are kept as strings
+			if t, err := time.Parse(dateLayout, v); err == nil {
+				return t, nil
+			}
+			return v, nil
+		default:
+			return v, nil
+		}
+	default:
+		//nil, float64
+		return value, nil
+	}
+}
+
+func readBigEndian(b []byte) uint64 {
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+package postgres_cdc
+
+import (
+	"time"
+)
+
+const (
+	InsertOperation   = "insert"
+	UpdateOperation   = "update"
+	DeleteOperation   = "delete"
+	TruncateOperation = "truncate"
+
+	cdcKey        = "cdc"
+	operationKey  = "operation"
+	lsnKey        = "lsn"
+	xidKey        = "xid"
+	commitTimeKey = "commit_time"
+	schemaKey     = "schema"
+	tableKey      = "table"
+)
+
+//change is a decoded row level change
+type change struct {
+	operation  string
+	lsn        string
+	xid        uint32
+	commitTime time.Time
+	schema     string
+	table      string
+	//columns are new row values (insert, update) or old row key values (delete)
+	columns map[string]interface{}
+}
+
+//toObject returns row values with operation metadata under cdc key
+func (c *change) toObject() map[string]interface{} {
+	object := make(map[string]interface{}, len(c.columns)+1)
+	for name, value := range c.columns {
+		object[name] = value
+	}
+
+	metadata := map[string]interface{}{
+		operationKey: c.operation,
+		lsnKey:       c.lsn,
+		xidKey:       c.xid,
+		schemaKey:    c.schema,
+		tableKey:     c.table,
+	}
+	if !c.commitTime.IsZero() {
+		metadata[commitTimeKey] = c.commitTime
+	}
+	object[cdcKey] = metadata
+
+	return object
+}
+
+//decoder parses logical decoding output plugin messages
+type decoder interface {
+	//decode parses one logical decoding output row and returns row level changes (if any)
+	decode(lsn string, data []byte) ([]*change, error)
+}
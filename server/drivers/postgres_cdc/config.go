@@ -0,0 +1,138 @@
+package postgres_cdc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	PgoutputPlugin = "pgoutput"
+	Wal2JSONPlugin = "wal2json"
+
+	defaultPort      = 5432
+	defaultSchema    = "public"
+	defaultBatchSize = 10000
+	slotNamePrefix   = "jitsu_"
+	maxSlotNameLen   = 63
+)
+
+var slotNameIllegalChars = regexp.MustCompile(`[^a-z0-9_]`)
+
+//PostgresCDCConfig is a Postgres logical replication source configuration dto for serialization
+type PostgresCDCConfig struct {
+	Host       string            `mapstructure:"host" json:"host,omitempty" yaml:"host,omitempty"`
+	Port       json.Number       `mapstructure:"port" json:"port,omitempty" yaml:"port,omitempty"`
+	Db         string            `mapstructure:"db" json:"db,omitempty" yaml:"db,omitempty"`
+	Username   string            `mapstructure:"username" json:"username,omitempty" yaml:"username,omitempty"`
+	Password   string            `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty"`
+	Parameters map[string]string `mapstructure:"parameters" json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	//Plugin is a logical decoding output plugin: pgoutput (default) or wal2json
+	Plugin string `mapstructure:"plugin" json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	//BatchSize is a max number of changes which are read from the replication slot and stored at once
+	BatchSize int `mapstructure:"batch_size" json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+}
+
+//Validate returns err if configuration is invalid and sets default values
+func (pcc *PostgresCDCConfig) Validate() error {
+	if pcc == nil {
+		return errors.New("postgres_cdc config is required")
+	}
+	if pcc.Host == "" {
+		return errors.New("host is not set")
+	}
+	if pcc.Db == "" {
+		return errors.New("db is not set")
+	}
+	if pcc.Username == "" {
+		return errors.New("username is not set")
+	}
+	if pcc.Port == "" {
+		pcc.Port = json.Number(fmt.Sprint(defaultPort))
+	}
+	if _, err := pcc.Port.Int64(); err != nil {
+		return fmt.Errorf("port must be int: %v", err)
+	}
+	if pcc.Plugin == "" {
+		pcc.Plugin = PgoutputPlugin
+	}
+	if pcc.Plugin != PgoutputPlugin && pcc.Plugin != Wal2JSONPlugin {
+		return fmt.Errorf("unknown plugin: %s. Supported: [%s, %s]", pcc.Plugin, PgoutputPlugin, Wal2JSONPlugin)
+	}
+	if pcc.BatchSize <= 0 {
+		pcc.BatchSize = defaultBatchSize
+	}
+
+	return nil
+}
+
+//ConnectionString returns lib/pq connection string
+func (pcc *PostgresCDCConfig) ConnectionString() string {
+	connectionString := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s ",
+		pcc.Host, pcc.Port.String(), pcc.Db, pcc.Username, pcc.Password)
+	//concat provided connection parameters
+	for k, v := range pcc.Parameters {
+		connectionString += k + "=" + v + " "
+	}
+
+	return connectionString
+}
+
+//PostgresCDCParameters is a replication slot configuration dto for serialization
+type PostgresCDCParameters struct {
+	//Tables is a list of captured tables in [schema.]table format
+	Tables []string `mapstructure:"tables" json:"tables,omitempty" yaml:"tables,omitempty"`
+	//SlotName is a logical replication slot name. Default: jitsu_{source_id}_{collection}
+	SlotName string `mapstructure:"slot_name" json:"slot_name,omitempty" yaml:"slot_name,omitempty"`
+	//Publication is a publication name (pgoutput only). Default: slot name
+	Publication string `mapstructure:"publication" json:"publication,omitempty" yaml:"publication,omitempty"`
+}
+
+//Validate returns err if configuration is invalid and sets default slot and publication names
+func (pcp *PostgresCDCParameters) Validate(plugin, sourceID, collection string) error {
+	if pcp == nil {
+		return errors.New("'parameters' configuration section is required")
+	}
+	if pcp.SlotName == "" {
+		pcp.SlotName = defaultSlotName(sourceID, collection)
+	}
+	if plugin == PgoutputPlugin {
+		if pcp.Publication == "" {
+			pcp.Publication = pcp.SlotName
+		}
+	} else if pcp.Publication != "" {
+		return fmt.Errorf("'publication' is supported only with %s plugin", PgoutputPlugin)
+	}
+	for _, table := range pcp.Tables {
+		if _, _, err := splitTableName(table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//defaultSlotName returns jitsu_{source_id}_{collection} with only allowed in slot names characters
+func defaultSlotName(sourceID, collection string) string {
+	name := slotNameIllegalChars.ReplaceAllString(strings.ToLower(slotNamePrefix+sourceID+"_"+collection), "_")
+	if len(name) > maxSlotNameLen {
+		name = name[:maxSlotNameLen]
+	}
+
+	return name
+}
+
+//splitTableName returns schema and table from [schema.]table string
+func splitTableName(table string) (string, string, error) {
+	parts := strings.Split(table, ".")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return defaultSchema, parts[0], nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return parts[0], parts[1], nil
+	default:
+		return "", "", fmt.Errorf("table [%s] must be in [schema.]table format", table)
+	}
+}
@@ -0,0 +1,183 @@
+package postgres_cdc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+//pgoutputMessage builds pgoutput binary messages
+type pgoutputMessage struct {
+	bytes.Buffer
+}
+
+func newPgoutputMessage(messageType byte) *pgoutputMessage {
+	m := &pgoutputMessage{}
+	m.WriteByte(messageType)
+	return m
+}
+
+func (m *pgoutputMessage) uint16(v uint16) *pgoutputMessage {
+	binary.Write(m, binary.BigEndian, v)
+	return m
+}
+
+func (m *pgoutputMessage) uint32(v uint32) *pgoutputMessage {
+	binary.Write(m, binary.BigEndian, v)
+	return m
+}
+
+func (m *pgoutputMessage) uint64(v uint64) *pgoutputMessage {
+	binary.Write(m, binary.BigEndian, v)
+	return m
+}
+
+func (m *pgoutputMessage) string(s string) *pgoutputMessage {
+	m.WriteString(s)
+	m.WriteByte(0)
+	return m
+}
+
+func (m *pgoutputMessage) byte(b byte) *pgoutputMessage {
+	m.WriteByte(b)
+	return m
+}
+
+func (m *pgoutputMessage) text(s string) *pgoutputMessage {
+	m.WriteByte(textValue)
+	m.uint32(uint32(len(s)))
+	m.WriteString(s)
+	return m
+}
+
+func TestPgoutputDecoder(t *testing.T) {
+	commitTime := time.Date(2022, 3, 1, 10, 0, 0, 123000000, time.UTC)
+	decoder := newPgoutputDecoder()
+
+	changes, err := decoder.decode("0/1", newPgoutputMessage(beginMessage).uint64(100).uint64(uint64(commitTime.Sub(postgresEpoch).Microseconds())).uint32(567).Bytes())
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	relationMessage := newPgoutputMessage(relationMessage).uint32(16384).string("public").string("users").byte('d').uint16(5).
+		byte(1).string("id").uint32(int4OID).uint32(0).
+		byte(0).string("name").uint32(25).uint32(0).
+		byte(0).string("active").uint32(boolOID).uint32(0).
+		byte(0).string("payload").uint32(jsonbOID).uint32(0).
+		byte(0).string("updated_at").uint32(timestamptzOID).uint32(0)
+	changes, err = decoder.decode("0/2", relationMessage.Bytes())
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	insert := newPgoutputMessage(insertMessage).uint32(16384).byte(newTupleKind).uint16(5).
+		text("1").text("John").text("t").text(`{"plan":"pro"}`).text("2022-03-01 12:00:00.5+02")
+	changes, err = decoder.decode("0/3", insert.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{
+		"id":         int64(1),
+		"name":       "John",
+		"active":     true,
+		"payload":    map[string]interface{}{"plan": "pro"},
+		"updated_at": time.Date(2022, 3, 1, 10, 0, 0, 500000000, time.UTC),
+		"cdc": map[string]interface{}{
+			"operation":   InsertOperation,
+			"lsn":         "0/3",
+			"xid":         uint32(567),
+			"schema":      "public",
+			"table":       "users",
+			"commit_time": commitTime,
+		},
+	}}, toObjects(changes))
+
+	//old key tuple is skipped, toasted payload isn't sent
+	update := newPgoutputMessage(updateMessage).uint32(16384).
+		byte(keyTupleKind).uint16(5).text("1").byte(nullValue).byte(nullValue).byte(nullValue).byte(nullValue).
+		byte(newTupleKind).uint16(5).text("2").text("Jane").text("f").byte(toastedValue).byte(nullValue)
+	changes, err = decoder.decode("0/4", update.Bytes())
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, UpdateOperation, changes[0].operation)
+	require.Equal(t, map[string]interface{}{"id": int64(2), "name": "Jane", "active": false, "updated_at": nil}, changes[0].columns)
+
+	deleteMsg := newPgoutputMessage(deleteMessage).uint32(16384).
+		byte(keyTupleKind).uint16(5).text("2").byte(nullValue).byte(nullValue).byte(nullValue).byte(nullValue)
+	changes, err = decoder.decode("0/5", deleteMsg.Bytes())
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, DeleteOperation, changes[0].operation)
+	require.Equal(t, int64(2), changes[0].columns["id"])
+
+	truncate := newPgoutputMessage(truncateMessage).uint32(1).byte(0).uint32(16384)
+	changes, err = decoder.decode("0/6", truncate.Bytes())
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, TruncateOperation, changes[0].operation)
+	require.Equal(t, "users", changes[0].table)
+
+	_, err = decoder.decode("0/7", newPgoutputMessage(insertMessage).uint32(1).byte(newTupleKind).uint16(0).Bytes())
+	require.EqualError(t, err, "relation message with id 1 wasn't received")
+
+	_, err = decoder.decode("0/8", newPgoutputMessage(insertMessage).uint32(16384).byte(newTupleKind).uint16(5).text("1").Bytes())
+	require.EqualError(t, err, "error parsing insert message of public.users: message is too short")
+}
+
+func TestWal2JSONDecoder(t *testing.T) {
+	decoder := newWal2JSONDecoder()
+
+	changes, err := decoder.decode("0/1", []byte(`{"action":"B","xid":567,"timestamp":"2022-03-01 12:00:00.123+02"}`))
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	changes, err = decoder.decode("0/2", []byte(`{"action":"I","schema":"public","table":"users","columns":[{"name":"id","type":"integer","value":1},{"name":"created_at","type":"timestamp without time zone","value":"2022-03-01 10:00:00"},{"name":"payload","type":"jsonb","value":"{\"plan\":\"pro\"}"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{
+		"id":         json.Number("1"),
+		"created_at": time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC),
+		"payload":    map[string]interface{}{"plan": "pro"},
+		"cdc": map[string]interface{}{
+			"operation":   InsertOperation,
+			"lsn":         "0/2",
+			"xid":         uint32(567),
+			"schema":      "public",
+			"table":       "users",
+			"commit_time": time.Date(2022, 3, 1, 10, 0, 0, 123000000, time.UTC),
+		},
+	}}, toObjects(changes))
+
+	changes, err = decoder.decode("0/3", []byte(`{"action":"D","schema":"public","table":"users","identity":[{"name":"id","type":"integer","value":1}]}`))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, DeleteOperation, changes[0].operation)
+	require.Equal(t, map[string]interface{}{"id": json.Number("1")}, changes[0].columns)
+
+	changes, err = decoder.decode("0/4", []byte(`{"action":"C","xid":567}`))
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, err = decoder.decode("0/5", []byte(`{"action":`))
+	require.Error(t, err)
+}
+
+func TestPostgresCDCParameters(t *testing.T) {
+	parameters := &PostgresCDCParameters{Tables: []string{"users", "billing.invoices"}}
+	require.NoError(t, parameters.Validate(PgoutputPlugin, "Prod-DB", "changes"))
+	require.Equal(t, "jitsu_prod_db_changes", parameters.SlotName)
+	require.Equal(t, "jitsu_prod_db_changes", parameters.Publication)
+
+	parameters = &PostgresCDCParameters{Publication: "all_tables"}
+	require.EqualError(t, parameters.Validate(Wal2JSONPlugin, "source", "changes"), "'publication' is supported only with pgoutput plugin")
+
+	parameters = &PostgresCDCParameters{Tables: []string{"a.b.c"}}
+	require.EqualError(t, parameters.Validate(PgoutputPlugin, "source", "changes"), "table [a.b.c] must be in [schema.]table format")
+}
+
+func toObjects(changes []*change) []map[string]interface{} {
+	var objects []map[string]interface{}
+	for _, c := range changes {
+		objects = append(objects, c.toObject())
+	}
+	return objects
+}
@@ -0,0 +1,270 @@
+package postgres_cdc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//pgoutput message types (see https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html)
+const (
+	beginMessage    = 'B'
+	commitMessage   = 'C'
+	relationMessage = 'R'
+	insertMessage   = 'I'
+	updateMessage   = 'U'
+	deleteMessage   = 'D'
+	truncateMessage = 'T'
+
+	newTupleKind   = 'N'
+	keyTupleKind   = 'K'
+	oldTupleKind   = 'O'
+	nullValue      = 'n'
+	toastedValue   = 'u'
+	textValue      = 't'
+	pgCatalogName  = "pg_catalog"
+	microsInSecond = 1000000
+)
+
+var (
+	//postgresEpoch is a start of Postgres timestamps
+	postgresEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	errMessageTooShort = errors.New("message is too short")
+)
+
+type relationColumn struct {
+	name    string
+	typeOID uint32
+}
+
+type relation struct {
+	schema  string
+	table   string
+	columns []relationColumn
+}
+
+//pgoutputDecoder parses pgoutput binary messages (protocol version 1). Relation messages are sent before the first
+//change of the relation in every decoding session, so a new decoder must be used per peek call
+type pgoutputDecoder struct {
+	relations  map[uint32]*relation
+	xid        uint32
+	commitTime time.Time
+}
+
+func newPgoutputDecoder() *pgoutputDecoder {
+	return &pgoutputDecoder{relations: map[uint32]*relation{}}
+}
+
+func (pd *pgoutputDecoder) decode(lsn string, data []byte) ([]*change, error) {
+	if len(data) == 0 {
+		return nil, errMessageTooShort
+	}
+
+	reader := &messageReader{data: data[1:]}
+	switch data[0] {
+	case beginMessage:
+		reader.uint64() //final LSN
+		pd.commitTime = reader.time()
+		pd.xid = reader.uint32()
+		return nil, reader.err
+	case commitMessage:
+		pd.xid = 0
+		pd.commitTime = time.Time{}
+		return nil, nil
+	case relationMessage:
+		return nil, pd.decodeRelation(reader)
+	case insertMessage:
+		rel, err := pd.relation(reader)
+		if err != nil {
+			return nil, err
+		}
+		reader.byte() //N
+		return pd.newChange(InsertOperation, lsn, rel, reader)
+	case updateMessage:
+		rel, err := pd.relation(reader)
+		if err != nil {
+			return nil, err
+		}
+		kind := reader.byte()
+		if kind == keyTupleKind || kind == oldTupleKind {
+			//skip old tuple
+			reader.tuple(rel)
+			reader.byte() //N
+		}
+		return pd.newChange(UpdateOperation, lsn, rel, reader)
+	case deleteMessage:
+		rel, err := pd.relation(reader)
+		if err != nil {
+			return nil, err
+		}
+		reader.byte() //K or O
+		return pd.newChange(DeleteOperation, lsn, rel, reader)
+	case truncateMessage:
+		relationsCount := reader.uint32()
+		reader.byte() //options
+		var changes []*change
+		for i := uint32(0); i < relationsCount && reader.err == nil; i++ {
+			rel, err := pd.relation(reader)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, &change{operation: TruncateOperation, lsn: lsn, xid: pd.xid, commitTime: pd.commitTime,
+				schema: rel.schema, table: rel.table})
+		}
+		return changes, reader.err
+	default:
+		//type, origin and logical messages aren't used
+		return nil, nil
+	}
+}
+
+func (pd *pgoutputDecoder) decodeRelation(reader *messageReader) error {
+	relationID := reader.uint32()
+	rel := &relation{schema: reader.string(), table: reader.string()}
+	if rel.schema == "" {
+		rel.schema = pgCatalogName
+	}
+	reader.byte() //replica identity
+	columnsCount := reader.uint16()
+	for i := uint16(0); i < columnsCount && reader.err == nil; i++ {
+		reader.byte() //flags
+		column := relationColumn{name: reader.string(), typeOID: reader.uint32()}
+		reader.uint32() //type modifier
+		rel.columns = append(rel.columns, column)
+	}
+	if reader.err != nil {
+		return fmt.Errorf("error parsing relation message: %v", reader.err)
+	}
+
+	pd.relations[relationID] = rel
+	return nil
+}
+
+func (pd *pgoutputDecoder) relation(reader *messageReader) (*relation, error) {
+	relationID := reader.uint32()
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	rel, ok := pd.relations[relationID]
+	if !ok {
+		return nil, fmt.Errorf("relation message with id %d wasn't received", relationID)
+	}
+
+	return rel, nil
+}
+
+func (pd *pgoutputDecoder) newChange(operation, lsn string, rel *relation, reader *messageReader) ([]*change, error) {
+	columns := reader.tuple(rel)
+	if reader.err != nil {
+		return nil, fmt.Errorf("error parsing %s message of %s.%s: %v", operation, rel.schema, rel.table, reader.err)
+	}
+
+	return []*change{{operation: operation, lsn: lsn, xid: pd.xid, commitTime: pd.commitTime,
+		schema: rel.schema, table: rel.table, columns: columns}}, nil
+}
+
+//messageReader reads big-endian pgoutput fields. The first error is kept and all further reads return zero values
+type messageReader struct {
+	data []byte
+	err  error
+}
+
+func (mr *messageReader) next(n int) []byte {
+	if mr.err != nil {
+		return nil
+	}
+	if len(mr.data) < n {
+		mr.err = errMessageTooShort
+		return nil
+	}
+	b := mr.data[:n]
+	mr.data = mr.data[n:]
+	return b
+}
+
+func (mr *messageReader) byte() byte {
+	if b := mr.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (mr *messageReader) uint16() uint16 {
+	if b := mr.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (mr *messageReader) uint32() uint32 {
+	if b := mr.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (mr *messageReader) uint64() uint64 {
+	if b := mr.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+//time reads microseconds since Postgres epoch
+func (mr *messageReader) time() time.Time {
+	micros := int64(mr.uint64())
+	return postgresEpoch.Add(time.Duration(micros/microsInSecond)*time.Second + time.Duration(micros%microsInSecond)*time.Microsecond)
+}
+
+//string reads null-terminated string
+func (mr *messageReader) string() string {
+	if mr.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(mr.data, 0)
+	if i < 0 {
+		mr.err = errMessageTooShort
+		return ""
+	}
+	s := string(mr.data[:i])
+	mr.data = mr.data[i+1:]
+	return s
+}
+
+//tuple reads TupleData. Unchanged TOAST values are skipped
+func (mr *messageReader) tuple(rel *relation) map[string]interface{} {
+	columnsCount := int(mr.uint16())
+	if mr.err != nil {
+		return nil
+	}
+	if columnsCount > len(rel.columns) {
+		mr.err = fmt.Errorf("tuple has %d columns but relation has %d", columnsCount, len(rel.columns))
+		return nil
+	}
+
+	columns := make(map[string]interface{}, columnsCount)
+	for i := 0; i < columnsCount && mr.err == nil; i++ {
+		column := rel.columns[i]
+		kind := mr.byte()
+		if mr.err != nil {
+			break
+		}
+		switch kind {
+		case nullValue:
+			columns[column.name] = nil
+		case toastedValue:
+		case textValue:
+			length := int(mr.uint32())
+			if value := mr.next(length); mr.err == nil {
+				columns[column.name] = convertTextValue(column.typeOID, string(value))
+			}
+		default:
+			mr.err = fmt.Errorf("unknown tuple value kind: %q", kind)
+		}
+	}
+
+	return columns
+}
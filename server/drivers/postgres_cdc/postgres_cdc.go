@@ -0,0 +1,345 @@
+package postgres_cdc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/lib/pq"
+)
+
+const (
+	walLevelLogical = "logical"
+
+	slotPluginQuery        = `SELECT plugin FROM pg_replication_slots WHERE slot_name = $1 AND database = current_database()`
+	createSlotQuery        = `SELECT pg_create_logical_replication_slot($1, $2)`
+	publicationExistsQuery = `SELECT count(*) FROM pg_publication WHERE pubname = $1`
+	createPublicationQuery = `CREATE PUBLICATION %s FOR TABLE %s`
+	walLevelQuery          = `SHOW wal_level`
+
+	//peek functions don't move the slot. Changes are consumed with get functions after they have been stored
+	pgoutputPeekQuery    = `SELECT lsn::text, data FROM pg_logical_slot_peek_binary_changes($1, NULL, $2, %s)`
+	pgoutputConsumeQuery = `SELECT count(*) FROM pg_logical_slot_get_binary_changes($1, $2::pg_lsn, NULL, %s)`
+	wal2jsonPeekQuery    = `SELECT lsn::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2, %s)`
+	wal2jsonConsumeQuery = `SELECT count(*) FROM pg_logical_slot_get_changes($1, $2::pg_lsn, NULL, %s)`
+)
+
+//PostgresCDC is a Postgres logical replication driver. It reads row level changes from a logical replication slot
+//and returns them as objects with operation metadata. The slot position is moved only after changes have been stored
+//so changes are delivered at least once
+type PostgresCDC struct {
+	base.IntervalDriver
+
+	ctx        context.Context
+	collection *base.Collection
+	config     *PostgresCDCConfig
+	parameters *PostgresCDCParameters
+	dataSource *sql.DB
+
+	peekQuery    string
+	consumeQuery string
+	pluginArgs   []interface{}
+	slotReady    bool
+}
+
+func init() {
+	base.RegisterDriver(base.PostgresCDCType, NewPostgresCDC)
+	base.RegisterTestConnectionFunc(base.PostgresCDCType, TestPostgresCDC)
+}
+
+//NewPostgresCDC returns configured PostgresCDC driver instance
+func NewPostgresCDC(ctx context.Context, sourceConfig *base.SourceConfig, collection *base.Collection) (base.Driver, error) {
+	config := &PostgresCDCConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	parameters := &PostgresCDCParameters{}
+	if err := jsonutils.UnmarshalConfig(collection.Parameters, parameters); err != nil {
+		return nil, err
+	}
+	if err := parameters.Validate(config.Plugin, sourceConfig.SourceID, collection.Name); err != nil {
+		return nil, err
+	}
+
+	dataSource, err := connect(config)
+	if err != nil {
+		return nil, err
+	}
+
+	driver := &PostgresCDC{
+		IntervalDriver: base.IntervalDriver{SourceType: sourceConfig.Type},
+		ctx:            ctx,
+		collection:     collection,
+		config:         config,
+		parameters:     parameters,
+		dataSource:     dataSource,
+	}
+	driver.initPluginQueries()
+
+	return driver, nil
+}
+
+//TestPostgresCDC tests connection and logical replication availability without creating Driver instance
+func TestPostgresCDC(sourceConfig *base.SourceConfig) error {
+	config := &PostgresCDCConfig{}
+	if err := jsonutils.UnmarshalConfig(sourceConfig.Config, config); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	dataSource, err := connect(config)
+	if err != nil {
+		return err
+	}
+	defer dataSource.Close()
+
+	var walLevel string
+	if err := dataSource.QueryRow(walLevelQuery).Scan(&walLevel); err != nil {
+		return err
+	}
+	if walLevel != walLevelLogical {
+		return fmt.Errorf("wal_level must be '%s' for logical replication. Current value: '%s'", walLevelLogical, walLevel)
+	}
+
+	return nil
+}
+
+func connect(config *PostgresCDCConfig) (*sql.DB, error) {
+	dataSource, err := sql.Open("postgres", config.ConnectionString())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dataSource.Ping(); err != nil {
+		dataSource.Close()
+		return nil, err
+	}
+
+	dataSource.SetConnMaxLifetime(10 * time.Minute)
+	return dataSource, nil
+}
+
+//initPluginQueries builds peek and consume queries with output plugin options
+func (p *PostgresCDC) initPluginQueries() {
+	var options []string
+	if p.config.Plugin == PgoutputPlugin {
+		options = []string{"proto_version", "1", "publication_names", p.parameters.Publication}
+	} else {
+		options = []string{"format-version", "2", "include-timestamp", "1", "include-xids", "1"}
+		if len(p.parameters.Tables) > 0 {
+			options = append(options, "add-tables", strings.Join(p.parameters.Tables, ","))
+		}
+	}
+
+	//$1 and $2 are slot name and limit (or LSN)
+	placeholders := make([]string, len(options))
+	for i, option := range options {
+		placeholders[i] = fmt.Sprintf("$%d", i+3)
+		p.pluginArgs = append(p.pluginArgs, option)
+	}
+	optionsPlaceholders := strings.Join(placeholders, ", ")
+
+	if p.config.Plugin == PgoutputPlugin {
+		p.peekQuery = fmt.Sprintf(pgoutputPeekQuery, optionsPlaceholders)
+		p.consumeQuery = fmt.Sprintf(pgoutputConsumeQuery, optionsPlaceholders)
+	} else {
+		p.peekQuery = fmt.Sprintf(wal2jsonPeekQuery, optionsPlaceholders)
+		p.consumeQuery = fmt.Sprintf(wal2jsonConsumeQuery, optionsPlaceholders)
+	}
+}
+
+func (p *PostgresCDC) newDecoder() decoder {
+	if p.config.Plugin == PgoutputPlugin {
+		return newPgoutputDecoder()
+	}
+
+	return newWal2JSONDecoder()
+}
+
+//GetRefreshWindow returns zero: every sync reads only new changes
+func (p *PostgresCDC) GetRefreshWindow() (time.Duration, error) {
+	return 0, nil
+}
+
+//GetAllAvailableIntervals returns ALL constant
+func (p *PostgresCDC) GetAllAvailableIntervals() ([]*base.TimeInterval, error) {
+	return []*base.TimeInterval{base.NewTimeInterval(schema.ALL, time.Time{})}, nil
+}
+
+//GetObjectsFor reads changes from the replication slot by batches until there are no more changes.
+//Every batch is passed to objectsLoader and only after that is consumed from the slot
+func (p *PostgresCDC) GetObjectsFor(interval *base.TimeInterval, objectsLoader base.ObjectsLoader) error {
+	if err := p.ensureReplicationSlot(); err != nil {
+		return err
+	}
+
+	pos := 0
+	for {
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+
+		objects, lastLSN, rowsCount, err := p.peekChanges()
+		if err != nil {
+			return fmt.Errorf("error reading changes from replication slot [%s]: %v", p.parameters.SlotName, err)
+		}
+		if rowsCount == 0 {
+			return nil
+		}
+
+		if len(objects) > 0 {
+			if err := objectsLoader(objects, pos, -1, -1); err != nil {
+				return err
+			}
+			pos += len(objects)
+		}
+
+		if err := p.consumeChanges(lastLSN); err != nil {
+			return fmt.Errorf("error moving replication slot [%s] to LSN [%s]: %v", p.parameters.SlotName, lastLSN, err)
+		}
+
+		//peek returns whole transactions so a batch can be bigger than the limit
+		if rowsCount < p.config.BatchSize {
+			return nil
+		}
+	}
+}
+
+//peekChanges returns decoded objects, LSN of the last read record and the number of read records
+func (p *PostgresCDC) peekChanges() ([]map[string]interface{}, string, int, error) {
+	args := append([]interface{}{p.parameters.SlotName, p.config.BatchSize}, p.pluginArgs...)
+	rows, err := p.dataSource.QueryContext(p.ctx, p.peekQuery, args...)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer rows.Close()
+
+	decoder := p.newDecoder()
+	var objects []map[string]interface{}
+	var lastLSN string
+	rowsCount := 0
+	for rows.Next() {
+		var lsn string
+		var data []byte
+		if err := rows.Scan(&lsn, &data); err != nil {
+			return nil, "", 0, err
+		}
+
+		changes, err := decoder.decode(lsn, data)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("error decoding %s record at LSN [%s]: %v", p.config.Plugin, lsn, err)
+		}
+		for _, c := range changes {
+			objects = append(objects, c.toObject())
+		}
+
+		lastLSN = lsn
+		rowsCount++
+	}
+
+	return objects, lastLSN, rowsCount, rows.Err()
+}
+
+//consumeChanges moves the slot to the end of the record at LSN
+func (p *PostgresCDC) consumeChanges(lsn string) error {
+	args := append([]interface{}{p.parameters.SlotName, lsn}, p.pluginArgs...)
+	var count int
+	return p.dataSource.QueryRowContext(p.ctx, p.consumeQuery, args...).Scan(&count)
+}
+
+//ensureReplicationSlot creates the publication (pgoutput) and the replication slot if they don't exist
+func (p *PostgresCDC) ensureReplicationSlot() error {
+	if p.slotReady {
+		return nil
+	}
+
+	var plugin string
+	err := p.dataSource.QueryRowContext(p.ctx, slotPluginQuery, p.parameters.SlotName).Scan(&plugin)
+	switch {
+	case err == nil:
+		if plugin != p.config.Plugin {
+			return fmt.Errorf("replication slot [%s] uses [%s] plugin but [%s] is configured", p.parameters.SlotName, plugin, p.config.Plugin)
+		}
+	case err == sql.ErrNoRows:
+		if p.config.Plugin == PgoutputPlugin {
+			if err := p.ensurePublication(); err != nil {
+				return err
+			}
+		}
+
+		if _, err := p.dataSource.ExecContext(p.ctx, createSlotQuery, p.parameters.SlotName, p.config.Plugin); err != nil {
+			return fmt.Errorf("error creating replication slot [%s]: %v", p.parameters.SlotName, err)
+		}
+		logging.Infof("[%s_%s] Replication slot [%s] has been created", p.collection.SourceID, p.collection.Name, p.parameters.SlotName)
+	default:
+		return fmt.Errorf("error getting replication slot [%s]: %v", p.parameters.SlotName, err)
+	}
+
+	p.slotReady = true
+	return nil
+}
+
+func (p *PostgresCDC) ensurePublication() error {
+	var count int
+	if err := p.dataSource.QueryRowContext(p.ctx, publicationExistsQuery, p.parameters.Publication).Scan(&count); err != nil {
+		return fmt.Errorf("error getting publication [%s]: %v", p.parameters.Publication, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if len(p.parameters.Tables) == 0 {
+		return fmt.Errorf("publication [%s] doesn't exist. Please create it or configure 'tables' parameter", p.parameters.Publication)
+	}
+
+	var tables []string
+	for _, table := range p.parameters.Tables {
+		schemaName, tableName, _ := splitTableName(table)
+		tables = append(tables, pq.QuoteIdentifier(schemaName)+"."+pq.QuoteIdentifier(tableName))
+	}
+
+	query := fmt.Sprintf(createPublicationQuery, pq.QuoteIdentifier(p.parameters.Publication), strings.Join(tables, ", "))
+	if _, err := p.dataSource.ExecContext(p.ctx, query); err != nil {
+		return fmt.Errorf("error creating publication [%s]: %v", p.parameters.Publication, err)
+	}
+	logging.Infof("[%s_%s] Publication [%s] has been created", p.collection.SourceID, p.collection.Name, p.parameters.Publication)
+
+	return nil
+}
+
+//IsIncremental returns true: objects loaded by previous syncs must be kept
+func (p *PostgresCDC) IsIncremental() bool {
+	return true
+}
+
+//Type returns PostgresCDC type
+func (p *PostgresCDC) Type() string {
+	return base.PostgresCDCType
+}
+
+//GetCollectionTable returns collection table
+func (p *PostgresCDC) GetCollectionTable() string {
+	return p.collection.GetTableName()
+}
+
+//GetCollectionMetaKey returns collection meta key (key is used in meta storage)
+func (p *PostgresCDC) GetCollectionMetaKey() string {
+	return p.collection.Name + "_" + p.GetCollectionTable()
+}
+
+//Close closes connection pool. The replication slot isn't dropped for not losing changes between restarts
+func (p *PostgresCDC) Close() error {
+	return p.dataSource.Close()
+}
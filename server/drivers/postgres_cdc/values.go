@@ -0,0 +1,96 @@
+package postgres_cdc
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Postgres built-in type OIDs (see pg_type.dat)
+const (
+	boolOID        = 16
+	int8OID        = 20
+	int2OID        = 21
+	int4OID        = 23
+	oidOID         = 26
+	jsonOID        = 114
+	float4OID      = 700
+	float8OID      = 701
+	dateOID        = 1082
+	timestampOID   = 1114
+	timestamptzOID = 1184
+	numericOID     = 1700
+	jsonbOID       = 3802
+)
+
+//Postgres text output layouts of date and time types
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+//convertTextValue converts value in Postgres text format into Go type according to the column type OID.
+//Values of unknown types are kept as strings
+func convertTextValue(typeOID uint32, value string) interface{} {
+	switch typeOID {
+	case boolOID:
+		return value == "t"
+	case int2OID, int4OID, int8OID, oidOID:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case float4OID, float8OID, numericOID:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case jsonOID, jsonbOID:
+		return convertJSONValue(value)
+	case dateOID, timestampOID, timestamptzOID:
+		if t, ok := parseTime(value); ok {
+			return t
+		}
+	}
+
+	return value
+}
+
+//convertTypedValue converts wal2json value according to the column type name
+func convertTypedValue(typeName string, value interface{}) interface{} {
+	str, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	switch {
+	case typeName == "json" || typeName == "jsonb":
+		return convertJSONValue(str)
+	case typeName == "date" || strings.HasPrefix(typeName, "timestamp"):
+		if t, ok := parseTime(str); ok {
+			return t
+		}
+	}
+
+	return value
+}
+
+func convertJSONValue(value string) interface{} {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return value
+	}
+
+	return parsed
+}
+
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+
+	return time.Time{}, false
+}
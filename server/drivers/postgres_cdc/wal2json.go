@@ -0,0 +1,94 @@
+package postgres_cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+//wal2json format-version 2 actions
+const (
+	wal2jsonBegin    = "B"
+	wal2jsonCommit   = "C"
+	wal2jsonInsert   = "I"
+	wal2jsonUpdate   = "U"
+	wal2jsonDelete   = "D"
+	wal2jsonTruncate = "T"
+)
+
+var wal2jsonOperations = map[string]string{
+	wal2jsonInsert:   InsertOperation,
+	wal2jsonUpdate:   UpdateOperation,
+	wal2jsonDelete:   DeleteOperation,
+	wal2jsonTruncate: TruncateOperation,
+}
+
+type wal2jsonColumn struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type wal2jsonRecord struct {
+	Action    string           `json:"action"`
+	Xid       uint32           `json:"xid"`
+	Timestamp string           `json:"timestamp"`
+	Schema    string           `json:"schema"`
+	Table     string           `json:"table"`
+	Columns   []wal2jsonColumn `json:"columns"`
+	Identity  []wal2jsonColumn `json:"identity"`
+}
+
+//wal2jsonDecoder parses wal2json format-version 2 records (one record per change)
+type wal2jsonDecoder struct {
+	xid        uint32
+	commitTime time.Time
+}
+
+func newWal2JSONDecoder() *wal2jsonDecoder {
+	return &wal2jsonDecoder{}
+}
+
+func (wd *wal2jsonDecoder) decode(lsn string, data []byte) ([]*change, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	record := &wal2jsonRecord{}
+	if err := decoder.Decode(record); err != nil {
+		return nil, fmt.Errorf("error parsing wal2json record: %v", err)
+	}
+
+	switch record.Action {
+	case wal2jsonBegin:
+		wd.xid = record.Xid
+		wd.commitTime, _ = parseTime(record.Timestamp)
+		return nil, nil
+	case wal2jsonCommit:
+		wd.xid = 0
+		wd.commitTime = time.Time{}
+		return nil, nil
+	}
+
+	operation, ok := wal2jsonOperations[record.Action]
+	if !ok {
+		//logical messages aren't used
+		return nil, nil
+	}
+
+	//deletes contain only identity (old key) columns
+	columns := record.Columns
+	if record.Action == wal2jsonDelete {
+		columns = record.Identity
+	}
+
+	var values map[string]interface{}
+	if record.Action != wal2jsonTruncate {
+		values = make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			values[column.Name] = convertTypedValue(column.Type, column.Value)
+		}
+	}
+
+	return []*change{{operation: operation, lsn: lsn, xid: wd.xid, commitTime: wd.commitTime,
+		schema: record.Schema, table: record.Table, columns: values}}, nil
+}
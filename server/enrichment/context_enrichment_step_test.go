@@ -70,7 +70,7 @@ func TestWithJsPreprocess(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			jsPreprocessor := events.NewJsProcessor(&events.DummyRecognition{}, viper.GetString("server.fields_configuration.user_agent_path"))
+			jsPreprocessor := events.NewJsProcessor(&events.DummyRecognition{}, viper.GetString("server.fields_configuration.user_agent_path"), viper.GetString("server.fields_configuration.client_hints_path"))
 
 			ContextEnrichmentStep(tt.input, "token", tt.request, jsPreprocessor, appconfig.Instance.GlobalUniqueIDField)
 
@@ -0,0 +1,127 @@
+package enrichment
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const defaultCurrencyField = "/currency"
+
+//CurrencyConversionRule converts amount from the event currency into the target currency with configured exchange rates
+//Rates are amounts of the target currency per one unit of the currency: {EUR: 1.08} means 1 EUR = 1.08 USD if target is USD
+type CurrencyConversionRule struct {
+	source         jsonutils.JSONPath
+	destination    jsonutils.JSONPath
+	currencyField  jsonutils.JSONPath
+	targetCurrency string
+	rates          map[string]float64
+}
+
+func NewCurrencyConversionRule(ec *EnricherConfig) (*CurrencyConversionRule, error) {
+	if ec.From == "" {
+		return nil, errors.New("'from' is required currency_conversion parameter")
+	}
+	if ec.To == "" {
+		return nil, errors.New("'to' is required currency_conversion parameter")
+	}
+	if ec.TargetCurrency == "" {
+		return nil, errors.New("'target_currency' is required currency_conversion parameter")
+	}
+	if len(ec.Rates) == 0 {
+		return nil, errors.New("'rates' is required currency_conversion parameter")
+	}
+
+	currencyField := ec.CurrencyField
+	if currencyField == "" {
+		currencyField = defaultCurrencyField
+	}
+
+	source, destination, err := parsePaths(ec.From, ec.To, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	currencyPath := jsonutils.NewJSONPath(strings.ToLower(currencyField))
+	if currencyPath.IsEmpty() {
+		return nil, errors.New("'currency_field' must be a valid path like: /node1/node2")
+	}
+
+	rates := make(map[string]float64, len(ec.Rates))
+	for currency, rate := range ec.Rates {
+		if rate <= 0 {
+			return nil, errors.New("currency rates must be positive numbers")
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	targetCurrency := strings.ToUpper(ec.TargetCurrency)
+	rates[targetCurrency] = 1
+
+	return &CurrencyConversionRule{
+		source:         source,
+		destination:    destination,
+		currencyField:  currencyPath,
+		targetCurrency: targetCurrency,
+		rates:          rates,
+	}, nil
+}
+
+//Execute sets converted amount if the event contains amount and currency with known rate
+func (ccr *CurrencyConversionRule) Execute(event map[string]interface{}) {
+	amountIface, ok := ccr.source.Get(event)
+	if !ok {
+		return
+	}
+	amount, ok := toFloat(amountIface)
+	if !ok {
+		return
+	}
+
+	currencyIface, ok := ccr.currencyField.Get(event)
+	if !ok {
+		return
+	}
+	currency, ok := currencyIface.(string)
+	if !ok {
+		return
+	}
+
+	rate, ok := ccr.rates[strings.ToUpper(strings.TrimSpace(currency))]
+	if !ok {
+		return
+	}
+
+	converted := math.Round(amount*rate*10000) / 10000
+	if err := ccr.destination.Set(event, converted); err != nil {
+		logging.SystemErrorf("Converted amount wasn't set: %v", err)
+	}
+}
+
+func (ccr *CurrencyConversionRule) Name() string {
+	return CurrencyConversionEnricher
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
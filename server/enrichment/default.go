@@ -9,19 +9,23 @@ import (
 var (
 	DefaultSrcIP jsonutils.JSONPath
 	DefaultDstIP jsonutils.JSONPath
+	//DefaultSrcClientHints is a path of User-Agent Client Hints which are merged into parsed user-agent
+	DefaultSrcClientHints jsonutils.JSONPath
 
 	DefaultUaRule = &UserAgentParseRule{}
 )
 
 //InitDefault initializes default lookup enrichment rules
-func InitDefault(srcIP, dstIP, srcUA, dstUA string) {
+func InitDefault(srcIP, dstIP, srcUA, dstUA, srcClientHints string) {
 	DefaultSrcIP = jsonutils.NewJSONPath(srcIP)
 	DefaultDstIP = jsonutils.NewJSONPath(dstIP)
+	DefaultSrcClientHints = jsonutils.NewJSONPath(srcClientHints)
 
 	var err error
 	DefaultUaRule, err = newUserAgentParseRule(
 		jsonutils.NewJSONPath(srcUA),
 		jsonutils.NewJSONPath(dstUA),
+		DefaultSrcClientHints,
 		func(m map[string]interface{}) bool {
 			return true
 		})
@@ -37,6 +37,7 @@ func TestDefault(t *testing.T) {
 		viper.GetString("server.fields_configuration.dst_source_ip"),
 		viper.GetString("server.fields_configuration.src_ua"),
 		viper.GetString("server.fields_configuration.dst_ua"),
+		viper.GetString("server.fields_configuration.client_hints_path"),
 	)
 
 	tests := []struct {
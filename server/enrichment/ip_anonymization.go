@@ -0,0 +1,45 @@
+package enrichment
+
+import (
+	"github.com/jitsucom/jitsu/server/iphandling"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+var truncateIPHandling = &iphandling.Config{Mode: iphandling.TruncateMode}
+
+//IPAnonymizationRule replaces the last octet of IPv4 address with 1 (the same as ip_policy=strict does)
+//and keeps only /48 prefix of IPv6 address. Comma separated lists of IPs are supported
+type IPAnonymizationRule struct {
+	source      jsonutils.JSONPath
+	destination jsonutils.JSONPath
+}
+
+func NewIPAnonymizationRule(source, destination jsonutils.JSONPath) *IPAnonymizationRule {
+	return &IPAnonymizationRule{source: source, destination: destination}
+}
+
+func (iar *IPAnonymizationRule) Execute(event map[string]interface{}) {
+	ipIface, ok := iar.source.Get(event)
+	if !ok {
+		return
+	}
+
+	ip, ok := ipIface.(string)
+	if !ok || ip == "" {
+		return
+	}
+
+	if err := iar.destination.Set(event, AnonymizeIP(ip)); err != nil {
+		logging.SystemErrorf("Anonymized IP wasn't set: %v", err)
+	}
+}
+
+func (iar *IPAnonymizationRule) Name() string {
+	return IPAnonymizationEnricher
+}
+
+//AnonymizeIP returns anonymized IP address or comma separated list of anonymized IP addresses
+func AnonymizeIP(ipStr string) string {
+	return truncateIPHandling.Apply(ipStr)
+}
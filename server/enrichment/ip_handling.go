@@ -0,0 +1,142 @@
+package enrichment
+
+import (
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/iphandling"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+)
+
+//IPHandling is a name of the rule which applies IP handling configuration of the destination
+const IPHandling = "ip_handling"
+
+//APIKeyIPHandling returns IP handling configuration of the API key or nil
+type APIKeyIPHandling func(apiKey string) *iphandling.Config
+
+//APIKeyIPHandlingStep applies IP handling configuration of the API key at ingestion (before events are cached, archived
+//or written to fallback files): to the client IP and to source_ip of the payloads.
+//In geo_then_drop mode geo data is resolved with geoResolver before the IP is removed. Returns handled client IP
+func APIKeyIPHandlingStep(config *iphandling.Config, clientIP string, geoResolver geo.Resolver, payloads ...events.Event) string {
+	if config.IsEmpty() {
+		return clientIP
+	}
+
+	for _, payload := range payloads {
+		ip := clientIP
+		payloadIP, hasIP := DefaultSrcIP.Get(payload)
+		if hasIP {
+			ip, _ = payloadIP.(string)
+		}
+
+		if config.Mode == iphandling.GeoThenDropMode {
+			if _, resolved := DefaultDstIP.Get(payload); !resolved && ip != "" {
+				resolveGeo(payload, ip, geoResolver)
+			}
+			if hasIP {
+				DefaultSrcIP.GetAndRemove(payload)
+			}
+			continue
+		}
+
+		if hasIP && ip != "" {
+			if err := DefaultSrcIP.Set(payload, config.Apply(ip)); err != nil {
+				logging.SystemErrorf("Handled IP wasn't set: %v", err)
+			}
+		}
+	}
+
+	return config.Apply(clientIP)
+}
+
+//resolveGeo puts geo data of the ip into DefaultDstIP path of the payload
+func resolveGeo(payload events.Event, ip string, geoResolver geo.Resolver) {
+	if geoResolver == nil {
+		return
+	}
+
+	geoData, err := resolveIPs(geoResolver, ip)
+	if err != nil {
+		if err != geo.EmptyIP {
+			logging.Errorf("Error resolving geo ip [%s]: %v", ip, err)
+		}
+		return
+	}
+
+	//convert all structs to map[string]interface{} for inner typecasting
+	result, err := parsers.ParseInterface(geoData)
+	if err != nil {
+		logging.SystemErrorf("Error converting geo ip node: %v", err)
+		return
+	}
+
+	if err = DefaultDstIP.SetOrMergeIfExist(payload, result); err != nil {
+		logging.SystemErrorf("Resolved geo data wasn't set: %v", err)
+	}
+}
+
+//IPHandlingRule applies the destination IP handling configuration to source_ip when it is stricter than the event API
+//key one (API key configuration is applied at ingestion, see APIKeyIPHandlingStep).
+//The rule is executed after the enrichment pipeline. In geo_then_drop mode geo data is resolved before the IP is removed
+//(if it hasn't been resolved by the pipeline)
+type IPHandlingRule struct {
+	destinationConfig *iphandling.Config
+	apiKeyConfig      APIKeyIPHandling
+	geoRule           *IPLookupRule
+}
+
+//NewIPHandlingRule returns configured IPHandlingRule. destinationConfig and apiKeyConfig are optional
+func NewIPHandlingRule(destinationConfig *iphandling.Config, apiKeyConfig APIKeyIPHandling, geoService *geo.Service,
+	geoResolverID string) (*IPHandlingRule, error) {
+	if destinationConfig != nil {
+		if err := destinationConfig.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &IPHandlingRule{
+		destinationConfig: destinationConfig,
+		apiKeyConfig:      apiKeyConfig,
+		geoRule:           CreateDefaultJsIPRule(geoService, geoResolverID),
+	}, nil
+}
+
+func (ihr *IPHandlingRule) Execute(event map[string]interface{}) {
+	config := ihr.destinationConfig
+	if config.IsEmpty() {
+		return
+	}
+
+	//API key configuration has already been applied at ingestion: destination one overrides it only if it is stricter
+	if apiKey, ok := event[ApiTokenKey].(string); ok && ihr.apiKeyConfig != nil {
+		if iphandling.Stricter(ihr.apiKeyConfig(apiKey), config) != config {
+			return
+		}
+	}
+
+	ipIface, ok := DefaultSrcIP.Get(event)
+	if !ok {
+		return
+	}
+
+	ip, ok := ipIface.(string)
+	if !ok || ip == "" {
+		return
+	}
+
+	if config.Mode == iphandling.GeoThenDropMode {
+		if _, resolved := DefaultDstIP.Get(event); !resolved {
+			ihr.geoRule.Execute(event)
+		}
+		DefaultSrcIP.GetAndRemove(event)
+		return
+	}
+
+	if err := DefaultSrcIP.Set(event, config.Apply(ip)); err != nil {
+		logging.SystemErrorf("Handled IP wasn't set: %v", err)
+	}
+}
+
+func (ihr *IPHandlingRule) Name() string {
+	return IPHandling
+}
@@ -0,0 +1,179 @@
+package enrichment
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/iphandling"
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPHandlingRule(t *testing.T) {
+	SetTestDefaultParams()
+	InitDefault("/source_ip", "/location", "/user_agent", "/parsed_ua", "/client_hints")
+
+	apiKeyConfigs := map[string]*iphandling.Config{
+		"truncate_key": {Mode: iphandling.TruncateMode},
+		"drop_key":     {Mode: iphandling.GeoThenDropMode},
+	}
+	apiKeyIPHandling := func(apiKey string) *iphandling.Config {
+		return apiKeyConfigs[apiKey]
+	}
+
+	tests := []struct {
+		name              string
+		destinationConfig *iphandling.Config
+		input             map[string]interface{}
+		expected          map[string]interface{}
+	}{
+		{
+			"Not configured",
+			nil,
+			map[string]interface{}{"source_ip": "10.10.10.10"},
+			map[string]interface{}{"source_ip": "10.10.10.10"},
+		},
+		{
+			"Keep",
+			&iphandling.Config{Mode: iphandling.KeepMode},
+			map[string]interface{}{"source_ip": "10.10.10.10"},
+			map[string]interface{}{"source_ip": "10.10.10.10"},
+		},
+		{
+			"Destination truncate",
+			&iphandling.Config{Mode: iphandling.TruncateMode},
+			map[string]interface{}{"source_ip": "10.10.10.10"},
+			map[string]interface{}{"source_ip": "10.10.10.1"},
+		},
+		{
+			"Destination hash",
+			&iphandling.Config{Mode: iphandling.HashMode, Salt: "salt"},
+			map[string]interface{}{"source_ip": "10.10.10.10"},
+			map[string]interface{}{"source_ip": "37fe8d367cdb04625456b1be4b7ec2e200bee3368a05ef8bdb244bfaaf6fbde2"},
+		},
+		{
+			"API key config is applied at ingestion",
+			nil,
+			map[string]interface{}{"source_ip": "10.10.10.1", "api_key": "truncate_key"},
+			map[string]interface{}{"source_ip": "10.10.10.1", "api_key": "truncate_key"},
+		},
+		{
+			"Geo then drop keeps resolved geo",
+			&iphandling.Config{Mode: iphandling.GeoThenDropMode},
+			map[string]interface{}{"source_ip": "10.10.10.10", "location": map[string]interface{}{"city": "test"}},
+			map[string]interface{}{"location": map[string]interface{}{"city": "test"}},
+		},
+		{
+			"Stricter API key skips destination config",
+			&iphandling.Config{Mode: iphandling.TruncateMode},
+			map[string]interface{}{"api_key": "drop_key", "location": map[string]interface{}{"city": "test"}},
+			map[string]interface{}{"api_key": "drop_key", "location": map[string]interface{}{"city": "test"}},
+		},
+		{
+			"Same API key config skips destination config",
+			&iphandling.Config{Mode: iphandling.TruncateMode},
+			map[string]interface{}{"source_ip": "10.10.10.1", "api_key": "truncate_key"},
+			map[string]interface{}{"source_ip": "10.10.10.1", "api_key": "truncate_key"},
+		},
+		{
+			"Stricter destination wins",
+			&iphandling.Config{Mode: iphandling.HashMode, Salt: "salt"},
+			map[string]interface{}{"source_ip": "10.10.10.10", "api_key": "truncate_key"},
+			map[string]interface{}{"source_ip": "37fe8d367cdb04625456b1be4b7ec2e200bee3368a05ef8bdb244bfaaf6fbde2", "api_key": "truncate_key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appconfig.Init(false, "")
+
+			geoService := geo.NewTestService(geo.Mock{"10.10.10.10": &geo.Data{Country: "US", City: "New York", Lat: 79.00, Lon: 22.00, Zip: "14101"}})
+			rule, err := NewIPHandlingRule(tt.destinationConfig, apiKeyIPHandling, geoService, "")
+			require.NoError(t, err)
+
+			rule.Execute(tt.input)
+			test.ObjectsEqual(t, tt.expected, tt.input, "Events aren't equal")
+		})
+	}
+}
+
+func TestIPHandlingRuleInvalidConfig(t *testing.T) {
+	_, err := NewIPHandlingRule(&iphandling.Config{Mode: iphandling.HashMode}, nil, geo.NewTestService(nil), "")
+	require.Error(t, err)
+}
+
+func TestAPIKeyIPHandlingStep(t *testing.T) {
+	SetTestDefaultParams()
+	InitDefault("/source_ip", "/location", "/user_agent", "/parsed_ua", "/client_hints")
+
+	location := map[string]interface{}{"city": "New York", "country": "US", "latitude": float64(79), "longitude": float64(22), "zip": "14101"}
+	geoResolver := geo.Mock{
+		"10.10.10.10": &geo.Data{Country: "US", City: "New York", Lat: 79.00, Lon: 22.00, Zip: "14101"},
+		"20.20.20.20": &geo.Data{Country: "US", City: "New York", Lat: 79.00, Lon: 22.00, Zip: "14101"},
+	}
+
+	tests := []struct {
+		name             string
+		config           *iphandling.Config
+		clientIP         string
+		input            []map[string]interface{}
+		expectedClientIP string
+		expected         []map[string]interface{}
+	}{
+		{
+			"Not configured",
+			nil,
+			"10.10.10.10",
+			[]map[string]interface{}{{"source_ip": "20.20.20.20"}},
+			"10.10.10.10",
+			[]map[string]interface{}{{"source_ip": "20.20.20.20"}},
+		},
+		{
+			"Truncate",
+			&iphandling.Config{Mode: iphandling.TruncateMode},
+			"10.10.10.10",
+			[]map[string]interface{}{{"source_ip": "20.20.20.20"}, {"event_type": "pageview"}},
+			"10.10.10.1",
+			[]map[string]interface{}{{"source_ip": "20.20.20.1"}, {"event_type": "pageview"}},
+		},
+		{
+			"Hash",
+			&iphandling.Config{Mode: iphandling.HashMode, Salt: "salt"},
+			"10.10.10.10",
+			[]map[string]interface{}{{"event_type": "pageview"}},
+			"37fe8d367cdb04625456b1be4b7ec2e200bee3368a05ef8bdb244bfaaf6fbde2",
+			[]map[string]interface{}{{"event_type": "pageview"}},
+		},
+		{
+			"Geo then drop",
+			&iphandling.Config{Mode: iphandling.GeoThenDropMode},
+			"10.10.10.10",
+			[]map[string]interface{}{
+				{"source_ip": "20.20.20.20"},
+				{"event_type": "pageview"},
+				{"source_ip": "20.20.20.20", "location": map[string]interface{}{"city": "test"}},
+			},
+			"",
+			[]map[string]interface{}{
+				{"location": location},
+				{"event_type": "pageview", "location": location},
+				{"location": map[string]interface{}{"city": "test"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payloads []events.Event
+			for _, input := range tt.input {
+				payloads = append(payloads, input)
+			}
+
+			clientIP := APIKeyIPHandlingStep(tt.config, tt.clientIP, geoResolver, payloads...)
+			require.Equal(t, tt.expectedClientIP, clientIP)
+			for i, expected := range tt.expected {
+				test.ObjectsEqual(t, expected, tt.input[i], "Events aren't equal")
+			}
+		})
+	}
+}
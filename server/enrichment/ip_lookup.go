@@ -74,7 +74,13 @@ func (ir *IPLookupRule) Name() string {
 
 //resolve tries to resolve comma separated ips or plain ip
 //returns first result without error
-func (ir *IPLookupRule) resolve(ipStr string) (data *geo.Data, err error) {
+func (ir *IPLookupRule) resolve(ipStr string) (*geo.Data, error) {
+	return resolveIPs(ir.geoService.GetGeoResolver(ir.geoResolverID), ipStr)
+}
+
+//resolveIPs resolves comma separated ips or plain ip with the resolver
+//returns first result without error
+func resolveIPs(resolver geo.Resolver, ipStr string) (data *geo.Data, err error) {
 	ips := []string{ipStr}
 	if strings.Contains(ipStr, ",") {
 		ips = []string{}
@@ -84,8 +90,6 @@ func (ir *IPLookupRule) resolve(ipStr string) (data *geo.Data, err error) {
 		}
 	}
 
-	resolver := ir.geoService.GetGeoResolver(ir.geoResolverID)
-
 	for _, ip := range ips {
 		data, err = resolver.Resolve(ip)
 		//return first without error
@@ -0,0 +1,74 @@
+package enrichment
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//JavaScriptRule executes custom JavaScript enricher. The code gets the event as $ and returns an object which is merged
+//into 'to' path (or into the event root if 'to' isn't set). null or undefined result means no enrichment
+type JavaScriptRule struct {
+	executor    ScriptExecutor
+	destination jsonutils.JSONPath
+}
+
+func NewJavaScriptRule(code, to string, scriptFactory ScriptExecutorFactory) (*JavaScriptRule, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.New("'code' is required javascript enricher parameter")
+	}
+
+	var destination jsonutils.JSONPath
+	if to != "" {
+		destination = jsonutils.NewJSONPath(strings.ToLower(to))
+		if destination.IsEmpty() {
+			return nil, errors.New("'to' must be a valid path like: /node1/node2")
+		}
+	}
+
+	executor, err := scriptFactory(code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JavaScriptRule{executor: executor, destination: destination}, nil
+}
+
+func (jsr *JavaScriptRule) Execute(event map[string]interface{}) {
+	result, err := jsr.executor.Execute(event)
+	if err != nil {
+		logging.Errorf("Error executing javascript enricher: %v", err)
+		return
+	}
+
+	if result == nil {
+		return
+	}
+
+	values, ok := result.(map[string]interface{})
+	if !ok {
+		logging.Errorf("javascript enricher must return an object. Got: %T", result)
+		return
+	}
+
+	if jsr.destination == nil {
+		for key, value := range values {
+			event[key] = value
+		}
+		return
+	}
+
+	if err := jsr.destination.SetOrMergeIfExist(event, values); err != nil {
+		logging.SystemErrorf("javascript enricher result wasn't set: %v", err)
+	}
+}
+
+func (jsr *JavaScriptRule) Name() string {
+	return JavaScriptEnricher
+}
+
+func (jsr *JavaScriptRule) Close() {
+	jsr.executor.Close()
+}
@@ -13,3 +13,8 @@ func (les *LookupEnrichmentStep) Execute(object map[string]interface{}) {
 		rule.Execute(object)
 	}
 }
+
+//Close releases resources of the rules (e.g. JavaScript enrichers)
+func (les *LookupEnrichmentStep) Close() {
+	closeRules(les.enrichmentRules)
+}
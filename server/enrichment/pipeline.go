@@ -0,0 +1,211 @@
+package enrichment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+//Built-in enrichers which can be used in the enrichment pipeline. Geo and user-agent enrichers are also available under
+//the old rule names (ip_lookup and user_agent_parse)
+const (
+	GeoEnricher                    = "geo"
+	UserAgentEnricher              = "user_agent"
+	IPAnonymizationEnricher        = "ip_anonymization"
+	CurrencyConversionEnricher     = "currency_conversion"
+	ReferrerClassificationEnricher = "referrer_classification"
+	JavaScriptEnricher             = "javascript"
+)
+
+//DefaultPipeline is used if a destination doesn't have configured enrichment pipeline
+var DefaultPipeline = []*EnricherConfig{{Type: GeoEnricher}, {Type: UserAgentEnricher}}
+
+//ScriptExecutor executes custom JavaScript enricher code with the event as a parameter
+type ScriptExecutor interface {
+	Execute(event map[string]interface{}) (interface{}, error)
+	Close()
+}
+
+//ScriptExecutorFactory creates ScriptExecutor from JavaScript enricher code
+type ScriptExecutorFactory func(code string) (ScriptExecutor, error)
+
+//EnricherConfig is a configuration of an enrichment pipeline step
+type EnricherConfig struct {
+	Type    string   `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Enabled *bool    `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	From    string   `mapstructure:"from" json:"from,omitempty" yaml:"from,omitempty"`
+	To      string   `mapstructure:"to" json:"to,omitempty" yaml:"to,omitempty"`
+	APIKeys []string `mapstructure:"api_keys" json:"api_keys,omitempty" yaml:"api_keys,omitempty"`
+
+	//currency_conversion
+	CurrencyField  string             `mapstructure:"currency_field" json:"currency_field,omitempty" yaml:"currency_field,omitempty"`
+	TargetCurrency string             `mapstructure:"target_currency" json:"target_currency,omitempty" yaml:"target_currency,omitempty"`
+	Rates          map[string]float64 `mapstructure:"rates" json:"rates,omitempty" yaml:"rates,omitempty"`
+
+	//referrer_classification
+	InternalDomains []string `mapstructure:"internal_domains" json:"internal_domains,omitempty" yaml:"internal_domains,omitempty"`
+
+	//javascript
+	Code string `mapstructure:"code" json:"code,omitempty" yaml:"code,omitempty"`
+}
+
+//IsEnabled returns false only if the enricher is explicitly disabled
+func (ec *EnricherConfig) IsEnabled() bool {
+	return ec.Enabled == nil || *ec.Enabled
+}
+
+func (ec *EnricherConfig) String() string {
+	var params []string
+	if ec.From != "" || ec.To != "" {
+		params = append(params, fmt.Sprintf("%s -> %s", ec.From, ec.To))
+	}
+	if len(ec.APIKeys) > 0 {
+		params = append(params, fmt.Sprintf("api keys: %s", strings.Join(ec.APIKeys, ", ")))
+	}
+	if !ec.IsEnabled() {
+		params = append(params, "disabled")
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("[%s] %s", ec.Type, strings.Join(params, " ")))
+}
+
+//NewPipeline returns ordered enrichment rules from the pipeline configuration. Disabled enrichers are skipped.
+//Enrichers with configured api_keys are applied only to events which were sent with one of these API keys
+func NewPipeline(enrichers []*EnricherConfig, geoService *geo.Service, geoResolverID string,
+	scriptFactory ScriptExecutorFactory) ([]Rule, error) {
+	var rules []Rule
+	for _, enricherConfig := range enrichers {
+		if enricherConfig == nil || !enricherConfig.IsEnabled() {
+			continue
+		}
+
+		rule, err := newEnricher(enricherConfig, geoService, geoResolverID, scriptFactory)
+		if err != nil {
+			closeRules(rules)
+			return nil, fmt.Errorf("error creating enricher %s: %v", enricherConfig.String(), err)
+		}
+
+		if len(enricherConfig.APIKeys) > 0 {
+			rule = newAPIKeysFilteredRule(rule, enricherConfig.APIKeys)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func newEnricher(ec *EnricherConfig, geoService *geo.Service, geoResolverID string, scriptFactory ScriptExecutorFactory) (Rule, error) {
+	switch strings.ToLower(ec.Type) {
+	case GeoEnricher, IPLookup:
+		if ec.From == "" && ec.To == "" {
+			return CreateDefaultJsIPRule(geoService, geoResolverID), nil
+		}
+		source, destination, err := parsePaths(ec.From, ec.To, DefaultSrcIP, DefaultDstIP)
+		if err != nil {
+			return nil, err
+		}
+		return NewIPLookupRule(source, destination, geoService, geoResolverID)
+	case UserAgentEnricher, UserAgentParse:
+		if ec.From == "" && ec.To == "" {
+			//shared rule with the shared cache
+			return DefaultUaRule, nil
+		}
+		source, destination, err := parsePaths(ec.From, ec.To, DefaultUaRule.source, DefaultUaRule.destination)
+		if err != nil {
+			return nil, err
+		}
+		return NewUserAgentParseRule(source, destination)
+	case IPAnonymizationEnricher:
+		source, destination, err := parsePaths(ec.From, ec.To, DefaultSrcIP, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewIPAnonymizationRule(source, destination), nil
+	case CurrencyConversionEnricher:
+		return NewCurrencyConversionRule(ec)
+	case ReferrerClassificationEnricher:
+		source, destination, err := parsePaths(ec.From, ec.To, defaultSrcReferrer, defaultDstReferrer)
+		if err != nil {
+			return nil, err
+		}
+		return NewReferrerClassificationRule(source, destination, ec.InternalDomains), nil
+	case JavaScriptEnricher:
+		if scriptFactory == nil {
+			return nil, errors.New("JavaScript enrichers aren't supported")
+		}
+		return NewJavaScriptRule(ec.Code, ec.To, scriptFactory)
+	case "":
+		return nil, errors.New("'type' is required enricher parameter")
+	default:
+		return nil, fmt.Errorf("unsupported enricher type: %s", ec.Type)
+	}
+}
+
+//parsePaths returns JSON paths from the configuration or default values if they aren't set
+//destination path equals to the source path if there is no default destination
+func parsePaths(from, to string, defaultSource, defaultDestination jsonutils.JSONPath) (jsonutils.JSONPath, jsonutils.JSONPath, error) {
+	source := defaultSource
+	if from != "" {
+		source = jsonutils.NewJSONPath(strings.ToLower(from))
+		if source.IsEmpty() {
+			return nil, nil, errors.New("'from' must be a valid path like: /node1/node2")
+		}
+	}
+
+	destination := defaultDestination
+	if to != "" {
+		destination = jsonutils.NewJSONPath(strings.ToLower(to))
+		if destination.IsEmpty() {
+			return nil, nil, errors.New("'to' must be a valid path like: /node1/node2")
+		}
+	}
+	if destination == nil {
+		destination = source
+	}
+
+	if source == nil || source.IsEmpty() {
+		return nil, nil, errors.New("'from' is required enricher parameter")
+	}
+
+	return source, destination, nil
+}
+
+func closeRules(rules []Rule) {
+	for _, rule := range rules {
+		if closer, ok := rule.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
+
+//APIKeysFilteredRule executes the underlying rule only for events with configured API keys
+type APIKeysFilteredRule struct {
+	Rule
+	apiKeys map[string]bool
+}
+
+func newAPIKeysFilteredRule(rule Rule, apiKeys []string) *APIKeysFilteredRule {
+	keys := make(map[string]bool, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		keys[apiKey] = true
+	}
+
+	return &APIKeysFilteredRule{Rule: rule, apiKeys: keys}
+}
+
+func (akr *APIKeysFilteredRule) Execute(event map[string]interface{}) {
+	apiKey, ok := event[ApiTokenKey].(string)
+	if !ok || !akr.apiKeys[apiKey] {
+		return
+	}
+
+	akr.Rule.Execute(event)
+}
+
+func (akr *APIKeysFilteredRule) Close() {
+	closeRules([]Rule{akr.Rule})
+}
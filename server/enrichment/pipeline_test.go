@@ -0,0 +1,140 @@
+package enrichment
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+type testScriptExecutor struct {
+	execute func(event map[string]interface{}) (interface{}, error)
+	closed  bool
+}
+
+func (tse *testScriptExecutor) Execute(event map[string]interface{}) (interface{}, error) {
+	return tse.execute(event)
+}
+
+func (tse *testScriptExecutor) Close() {
+	tse.closed = true
+}
+
+func TestPipeline(t *testing.T) {
+	SetTestDefaultParams()
+	appconfig.Init(false, "")
+	InitDefault("/source_ip", "/eventn_ctx/location", "/eventn_ctx/user_agent", "/eventn_ctx/parsed_ua", "")
+
+	disabled := false
+	script := &testScriptExecutor{execute: func(event map[string]interface{}) (interface{}, error) {
+		//anonymized IP must be visible for the next enrichers
+		return map[string]interface{}{"js_ip": event["source_ip"]}, nil
+	}}
+	scriptFactory := func(code string) (ScriptExecutor, error) {
+		return script, nil
+	}
+	geoService := geo.NewTestService(geo.Mock{"10.10.10.1": &geo.Data{Country: "US", City: "New York"}})
+
+	rules, err := NewPipeline([]*EnricherConfig{
+		{Type: IPAnonymizationEnricher},
+		{Type: GeoEnricher},
+		{Type: UserAgentEnricher, Enabled: &disabled},
+		{Type: ReferrerClassificationEnricher},
+		{Type: CurrencyConversionEnricher, From: "/revenue", To: "/revenue_usd", TargetCurrency: "usd", Rates: map[string]float64{"EUR": 1.1}},
+		{Type: JavaScriptEnricher, Code: "return {js_ip: $.source_ip}", APIKeys: []string{"key1"}},
+	}, geoService, "", scriptFactory)
+	require.NoError(t, err)
+	require.Len(t, rules, 5)
+
+	event := map[string]interface{}{
+		"api_key":    "key1",
+		"source_ip":  "10.10.10.10",
+		"revenue":    10,
+		"currency":   "eur",
+		"eventn_ctx": map[string]interface{}{"referer": "https://www.google.com/search?q=jitsu", "user_agent": "Mozilla/5.0"},
+	}
+	NewLookupEnrichmentStep(rules).Execute(event)
+	test.ObjectsEqual(t, map[string]interface{}{
+		"api_key":     "key1",
+		"source_ip":   "10.10.10.1",
+		"revenue":     10,
+		"currency":    "eur",
+		"revenue_usd": float64(11),
+		"js_ip":       "10.10.10.1",
+		"eventn_ctx": map[string]interface{}{
+			"referer":         "https://www.google.com/search?q=jitsu",
+			"user_agent":      "Mozilla/5.0",
+			"location":        map[string]interface{}{"country": "US", "city": "New York"},
+			"parsed_referrer": map[string]interface{}{"medium": "search", "source": "Google"},
+		},
+	}, event, "Enriched events aren't equal")
+
+	//javascript enricher isn't applied to events with other API keys
+	event = map[string]interface{}{"api_key": "key2", "source_ip": "10.10.10.10"}
+	NewLookupEnrichmentStep(rules).Execute(event)
+	require.NotContains(t, event, "js_ip")
+
+	NewLookupEnrichmentStep(rules).Close()
+	require.True(t, script.closed)
+}
+
+func TestPipelineErrors(t *testing.T) {
+	scriptFactory := func(code string) (ScriptExecutor, error) {
+		return nil, errors.New("script error")
+	}
+
+	tests := []struct {
+		name     string
+		enricher *EnricherConfig
+	}{
+		{"Empty type", &EnricherConfig{}},
+		{"Unknown type", &EnricherConfig{Type: "unknown"}},
+		{"Invalid path", &EnricherConfig{Type: IPAnonymizationEnricher, From: "/"}},
+		{"Currency conversion without rates", &EnricherConfig{Type: CurrencyConversionEnricher, From: "/revenue", To: "/revenue_usd", TargetCurrency: "USD"}},
+		{"Currency conversion with negative rate", &EnricherConfig{Type: CurrencyConversionEnricher, From: "/revenue", To: "/revenue_usd", TargetCurrency: "USD", Rates: map[string]float64{"EUR": -1}}},
+		{"JavaScript without code", &EnricherConfig{Type: JavaScriptEnricher}},
+		{"JavaScript error", &EnricherConfig{Type: JavaScriptEnricher, Code: "return {}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPipeline([]*EnricherConfig{tt.enricher}, nil, "", scriptFactory)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	require.Equal(t, "10.10.10.1", AnonymizeIP("10.10.10.10"))
+	require.Equal(t, "10.10.10.1,192.168.0.1", AnonymizeIP("10.10.10.10, 192.168.0.15"))
+	require.Equal(t, "2001:db8:85a3::", AnonymizeIP("2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	require.Equal(t, "abc", AnonymizeIP("abc"))
+}
+
+func TestReferrerClassification(t *testing.T) {
+	rule := NewReferrerClassificationRule(defaultSrcReferrer, defaultDstReferrer, []string{"jitsu.com"})
+	tests := []struct {
+		referrer       string
+		documentHost   string
+		expectedMedium string
+		expectedSource string
+	}{
+		{"", "example.com", ReferrerDirect, ""},
+		{"https://example.com/page", "www.example.com", ReferrerInternal, ""},
+		{"https://docs.jitsu.com/", "example.com", ReferrerInternal, ""},
+		{"https://www.google.co.uk/", "example.com", ReferrerSearch, "Google"},
+		{"https://mail.google.com/mail/u/0/", "example.com", ReferrerEmail, "Gmail"},
+		{"https://t.co/abc", "example.com", ReferrerSocial, "Twitter"},
+		{"https://m.facebook.com/", "example.com", ReferrerSocial, "Facebook"},
+		{"https://blog.t.example.com/", "example.org", ReferrerOther, "blog.t.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.referrer, func(t *testing.T) {
+			medium, source := rule.classify(tt.referrer, tt.documentHost)
+			require.Equal(t, tt.expectedMedium, medium)
+			require.Equal(t, tt.expectedSource, source)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package enrichment
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//referrer mediums
+const (
+	ReferrerDirect   = "direct"
+	ReferrerInternal = "internal"
+	ReferrerSearch   = "search"
+	ReferrerSocial   = "social"
+	ReferrerEmail    = "email"
+	ReferrerOther    = "referral"
+)
+
+var (
+	defaultSrcReferrer = jsonutils.NewJSONPath("/eventn_ctx/referer||/referer")
+	defaultDstReferrer = jsonutils.NewJSONPath("/eventn_ctx/parsed_referrer||/parsed_referrer")
+	documentHost       = jsonutils.NewJSONPath("/eventn_ctx/doc_host||/doc_host")
+
+	//knownReferrers is a list of known referrer domains (without public suffix) with medium and source name
+	knownReferrers = map[string]referrer{
+		"google":           {ReferrerSearch, "Google"},
+		"bing":             {ReferrerSearch, "Bing"},
+		"yahoo":            {ReferrerSearch, "Yahoo"},
+		"yandex":           {ReferrerSearch, "Yandex"},
+		"duckduckgo":       {ReferrerSearch, "DuckDuckGo"},
+		"baidu":            {ReferrerSearch, "Baidu"},
+		"ecosia":           {ReferrerSearch, "Ecosia"},
+		"naver":            {ReferrerSearch, "Naver"},
+		"facebook":         {ReferrerSocial, "Facebook"},
+		"fb":               {ReferrerSocial, "Facebook"},
+		"instagram":        {ReferrerSocial, "Instagram"},
+		"twitter":          {ReferrerSocial, "Twitter"},
+		"t":                {ReferrerSocial, "Twitter"},
+		"x":                {ReferrerSocial, "Twitter"},
+		"linkedin":         {ReferrerSocial, "LinkedIn"},
+		"lnkd":             {ReferrerSocial, "LinkedIn"},
+		"reddit":           {ReferrerSocial, "Reddit"},
+		"youtube":          {ReferrerSocial, "YouTube"},
+		"pinterest":        {ReferrerSocial, "Pinterest"},
+		"tiktok":           {ReferrerSocial, "TikTok"},
+		"vk":               {ReferrerSocial, "VKontakte"},
+		"news.ycombinator": {ReferrerSocial, "Hacker News"},
+		"mail.google":      {ReferrerEmail, "Gmail"},
+		"outlook.live":     {ReferrerEmail, "Outlook"},
+		"mail.yahoo":       {ReferrerEmail, "Yahoo Mail"},
+		"mail.yandex":      {ReferrerEmail, "Yandex Mail"},
+	}
+)
+
+type referrer struct {
+	medium string
+	source string
+}
+
+//ReferrerClassificationRule classifies referrer URL into medium (direct, internal, search, social, email or referral)
+//and source (e.g. Google or referrer host). Referrers from the document host or configured internal domains are internal
+type ReferrerClassificationRule struct {
+	source          jsonutils.JSONPath
+	destination     jsonutils.JSONPath
+	internalDomains []string
+}
+
+func NewReferrerClassificationRule(source, destination jsonutils.JSONPath, internalDomains []string) *ReferrerClassificationRule {
+	domains := make([]string, 0, len(internalDomains))
+	for _, domain := range internalDomains {
+		domains = append(domains, strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www."))
+	}
+
+	return &ReferrerClassificationRule{source: source, destination: destination, internalDomains: domains}
+}
+
+func (rcr *ReferrerClassificationRule) Execute(event map[string]interface{}) {
+	var referrerURL string
+	if value, ok := rcr.source.Get(event); ok {
+		referrerURL, _ = value.(string)
+	}
+
+	var host string
+	if value, ok := documentHost.Get(event); ok {
+		host, _ = value.(string)
+	}
+
+	medium, source := rcr.classify(referrerURL, host)
+	result := map[string]interface{}{"medium": medium}
+	if source != "" {
+		result["source"] = source
+	}
+
+	if err := rcr.destination.SetOrMergeIfExist(event, result); err != nil {
+		logging.SystemErrorf("Classified referrer wasn't set: %v", err)
+	}
+}
+
+func (rcr *ReferrerClassificationRule) Name() string {
+	return ReferrerClassificationEnricher
+}
+
+//classify returns referrer medium and source
+func (rcr *ReferrerClassificationRule) classify(referrerURL, documentHost string) (string, string) {
+	referrerURL = strings.TrimSpace(referrerURL)
+	if referrerURL == "" {
+		return ReferrerDirect, ""
+	}
+
+	u, err := url.Parse(referrerURL)
+	if err != nil || u.Hostname() == "" {
+		return ReferrerOther, ""
+	}
+	referrerHost := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	if documentHost != "" && sameDomain(referrerHost, strings.TrimPrefix(strings.ToLower(documentHost), "www.")) {
+		return ReferrerInternal, ""
+	}
+	for _, domain := range rcr.internalDomains {
+		if sameDomain(referrerHost, domain) {
+			return ReferrerInternal, ""
+		}
+	}
+
+	//strip public suffix (the last short labels like com, co.uk) and try the longest known domain first:
+	//mail.google.com -> mail.google -> google
+	labels := strings.Split(referrerHost, ".")
+	suffixStart := len(labels) - 1
+	for suffixStart > 1 && len(labels[suffixStart-1]) <= 3 {
+		suffixStart--
+	}
+	for i := 0; i < suffixStart; i++ {
+		if known, ok := knownReferrers[strings.Join(labels[i:suffixStart], ".")]; ok {
+			return known.medium, known.source
+		}
+	}
+
+	return ReferrerOther, referrerHost
+}
+
+//sameDomain returns true if host equals to the domain or is its subdomain
+func sameDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -1,6 +1,8 @@
 package enrichment
 
 import (
+	"encoding/json"
+
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/jsonutils"
@@ -19,6 +21,7 @@ type ConditionFunc func(map[string]interface{}) bool
 type UserAgentParseRule struct {
 	source                  jsonutils.JSONPath
 	destination             jsonutils.JSONPath
+	clientHints             jsonutils.JSONPath
 	uaResolver              useragent.Resolver
 	enrichmentConditionFunc ConditionFunc
 	cache                   *lru.TwoQueueCache
@@ -27,10 +30,10 @@ type UserAgentParseRule struct {
 func NewUserAgentParseRule(source, destination jsonutils.JSONPath) (*UserAgentParseRule, error) {
 	//always do enrichment
 	conditionFunc := func(m map[string]interface{}) bool { return true }
-	return newUserAgentParseRule(source, destination, conditionFunc)
+	return newUserAgentParseRule(source, destination, DefaultSrcClientHints, conditionFunc)
 }
 
-func newUserAgentParseRule(source, destination jsonutils.JSONPath, conditionFunc ConditionFunc) (*UserAgentParseRule, error) {
+func newUserAgentParseRule(source, destination, clientHints jsonutils.JSONPath, conditionFunc ConditionFunc) (*UserAgentParseRule, error) {
 	cache, err := lru.New2Q(100_000)
 	if err != nil {
 		return nil, errors.Wrap(err, "create user-agent cache error")
@@ -39,6 +42,7 @@ func newUserAgentParseRule(source, destination jsonutils.JSONPath, conditionFunc
 	return &UserAgentParseRule{
 		source:                  source,
 		destination:             destination,
+		clientHints:             clientHints,
 		uaResolver:              appconfig.Instance.UaResolver,
 		enrichmentConditionFunc: conditionFunc,
 		cache:                   cache,
@@ -46,6 +50,7 @@ func newUserAgentParseRule(source, destination jsonutils.JSONPath, conditionFunc
 }
 
 //Execute sets parsed ua from cache or resolves with useragent.Resolver. Also returns set value to destination path
+//User-Agent Client Hints (if present in the event) are merged into parsed ua
 func (uap *UserAgentParseRule) Execute(event map[string]interface{}) {
 	if !uap.enrichmentConditionFunc(event) {
 		return
@@ -61,9 +66,15 @@ func (uap *UserAgentParseRule) Execute(event map[string]interface{}) {
 		return
 	}
 
-	parsedUAMap, ok := uap.cache.Get(ua)
+	hints, hintsKey := uap.getClientHints(event)
+	cacheKey := ua
+	if hintsKey != "" {
+		cacheKey = ua + "\n" + hintsKey
+	}
+
+	parsedUAMap, ok := uap.cache.Get(cacheKey)
 	if !ok {
-		parsedUa := uap.uaResolver.Resolve(ua)
+		parsedUa := useragent.MergeClientHints(uap.uaResolver.Resolve(ua), hints)
 
 		var err error
 		//convert all structs to map[string]interface{} for inner typecasting
@@ -72,7 +83,7 @@ func (uap *UserAgentParseRule) Execute(event map[string]interface{}) {
 			logging.SystemErrorf("Error converting ua parse node: %v", err)
 			return
 		}
-		uap.cache.Add(ua, parsedUAMap)
+		uap.cache.Add(cacheKey, parsedUAMap)
 	}
 
 	//don't overwrite existent
@@ -82,6 +93,30 @@ func (uap *UserAgentParseRule) Execute(event map[string]interface{}) {
 	}
 }
 
+//getClientHints returns client hints from the event and their serialized form which is used as a part of the cache key
+func (uap *UserAgentParseRule) getClientHints(event map[string]interface{}) (*useragent.ClientHints, string) {
+	if uap.clientHints == nil || uap.clientHints.IsEmpty() {
+		return nil, ""
+	}
+
+	hintsIface, ok := uap.clientHints.Get(event)
+	if !ok || hintsIface == nil {
+		return nil, ""
+	}
+
+	b, err := json.Marshal(hintsIface)
+	if err != nil {
+		return nil, ""
+	}
+
+	hints := &useragent.ClientHints{}
+	if err := json.Unmarshal(b, hints); err != nil || hints.IsEmpty() {
+		return nil, ""
+	}
+
+	return hints, string(b)
+}
+
 func (uap *UserAgentParseRule) Name() string {
 	return UserAgentParse
 }
@@ -56,6 +56,14 @@ func TestUserAgentParse(t *testing.T) {
 			map[string]interface{}{"ua": "mock"},
 			map[string]interface{}{"ua": "mock", "parsed_ua": map[string]interface{}{"device_family": "PK", "os_family": "Windows", "os_version": "95", "ua_family": "Chrome", "ua_version": "1.0.0"}},
 		},
+		{
+			"Object with ua and client hints",
+			"/ua",
+			"/parsed_ua",
+			map[string]interface{}{"ua": "mock", "client_hints": map[string]interface{}{"platform": "Windows", "platform_version": "14.0.0", "mobile": false}},
+			map[string]interface{}{"ua": "mock", "client_hints": map[string]interface{}{"platform": "Windows", "platform_version": "14.0.0", "mobile": false},
+				"parsed_ua": map[string]interface{}{"device_family": "PK", "device_type": "desktop", "os_family": "Windows", "os_version": "11", "ua_family": "Chrome", "ua_version": "1.0.0"}},
+		},
 		{
 			"Object with parsed ua doesn't overwrite",
 			"/ua",
@@ -69,6 +77,7 @@ func TestUserAgentParse(t *testing.T) {
 			appconfig.Init(false, "")
 			appconfig.Instance.UaResolver = useragent.Mock{}
 
+			DefaultSrcClientHints = jsonutils.NewJSONPath("/client_hints")
 			uaRule, err := NewUserAgentParseRule(jsonutils.NewJSONPath(tt.source), jsonutils.NewJSONPath(tt.destination))
 			require.NoError(t, err)
 
@@ -0,0 +1,203 @@
+package erasure
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+// scrubArchive removes events which contain the identifier in the identifier columns from all archived files
+// in the directory (recursively)
+func scrubArchive(archiveDir, identifier string, columns []string) *ArchiveReport {
+	report := &ArchiveReport{}
+	if len(columns) == 0 {
+		report.Errors = append(report.Errors, "erasure.columns must be configured")
+		return report
+	}
+
+	columnsSet := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		columnsSet[schema.Reformat(column)] = true
+	}
+
+	err := filepath.Walk(archiveDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !isArchiveFile(path) {
+			return nil
+		}
+
+		report.FilesScanned++
+		removed, err := scrubFile(path, identifier, columnsSet)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", path, err))
+			return nil
+		}
+		if removed > 0 {
+			report.FilesModified++
+			report.EventsRemoved += removed
+		}
+		return nil
+	})
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	return report
+}
+
+func isArchiveFile(path string) bool {
+	return strings.HasSuffix(path, ".log") || strings.HasSuffix(path, ".log.gz")
+}
+
+// scrubFile rewrites the file without lines (events) which contain the identifier in the identifier columns
+// returns amount of removed lines
+func scrubFile(path, identifier string, columns map[string]bool) (int, error) {
+	compressed := strings.HasSuffix(path, ".gz")
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if compressed {
+		reader, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return 0, err
+		}
+		if b, err = ioutil.ReadAll(reader); err != nil {
+			return 0, err
+		}
+	}
+
+	//fast path: the file doesn't contain the identifier at all
+	if !bytes.Contains(b, []byte(identifier)) && !bytes.Contains(b, escapedJSON(identifier)) {
+		return 0, nil
+	}
+
+	var output bytes.Buffer
+	removed := 0
+	reader := bufio.NewReader(bytes.NewReader(b))
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if lineContains(line, identifier, columns) {
+				removed++
+			} else {
+				output.Write(line)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return 0, readErr
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	content := output.Bytes()
+	if compressed {
+		var gzipped bytes.Buffer
+		gzw := gzip.NewWriter(&gzipped)
+		if _, err := gzw.Write(content); err != nil {
+			return 0, err
+		}
+		if err := gzw.Close(); err != nil {
+			return 0, err
+		}
+		content = gzipped.Bytes()
+	}
+
+	tmpPath := path + ".erasure.tmp"
+	if err := ioutil.WriteFile(tmpPath, content, 0644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// lineContains returns true if any identifier column of the JSON line (event) equals to the identifier.
+// Events are flattened the same way as in SQL destinations (user.email is user_email column).
+// Lines which aren't valid JSON objects are kept
+func lineContains(line []byte, identifier string, columns map[string]bool) bool {
+	object, ok := decodeObject(line)
+	if !ok {
+		return false
+	}
+
+	if eventContains(object, identifier, columns) {
+		return true
+	}
+
+	//fallback files contain failed events as objects or serialized into strings
+	for _, key := range []string{"event", "malformed_event"} {
+		switch v := object[key].(type) {
+		case map[string]interface{}:
+			if eventContains(v, identifier, columns) {
+				return true
+			}
+		case string:
+			if event, ok := decodeObject([]byte(v)); ok && eventContains(event, identifier, columns) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func decodeObject(b []byte) (map[string]interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	object := map[string]interface{}{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, false
+	}
+
+	return object, true
+}
+
+func eventContains(event map[string]interface{}, identifier string, columns map[string]bool) bool {
+	flattened, err := schema.NewFlattener().FlattenObject(event)
+	if err != nil {
+		return false
+	}
+
+	for column := range columns {
+		switch v := flattened[column].(type) {
+		case string:
+			if v == identifier {
+				return true
+			}
+		case json.Number:
+			if v.String() == identifier {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func escapedJSON(value string) []byte {
+	b, _ := json.Marshal(value)
+	return bytes.Trim(b, `"`)
+}
@@ -0,0 +1,96 @@
+package erasure
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const archivedEvents = `{"event_type":"pageview","user":{"id":"user1","email":"john@example.com"}}
+{"event_type":"pageview","user":{"id":"user2"}}
+{"event":"{\"user\":{\"email\":\"john@example.com\"}}","error":"destination error"}
+{"event_type":"identify","eventn_ctx":{"user":{"email":"john@example.com"}}}
+{"event_type":"pageview","page_title":"john@example.com.au"}
+{"event_type":"pageview","page_title":"john@example.com"}
+`
+
+var identifierColumns = []string{"user_id", "user_email", "eventn_ctx_user_id", "eventn_ctx_user_email"}
+
+func TestScrubArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "erasure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "2021-10-01"), 0755))
+	plainFile := filepath.Join(dir, "2021-10-01", "incoming.tok=token1-2021-10-01T10-00-00.000.log")
+	require.NoError(t, ioutil.WriteFile(plainFile, []byte(archivedEvents), 0644))
+
+	var gzipped bytes.Buffer
+	gzw := gzip.NewWriter(&gzipped)
+	_, err = gzw.Write([]byte(archivedEvents))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	gzipFile := filepath.Join(dir, "2021-10-01", "streaming-archive.dst=dest1-2021-10-01T10-00-00.000.log.gz")
+	require.NoError(t, ioutil.WriteFile(gzipFile, gzipped.Bytes(), 0644))
+
+	untouchedFile := filepath.Join(dir, "2021-10-01", "incoming.tok=token2-2021-10-01T10-00-00.000.log")
+	require.NoError(t, ioutil.WriteFile(untouchedFile, []byte(`{"user":{"id":"user3"}}`+"\n"), 0644))
+
+	report := scrubArchive(dir, "john@example.com", identifierColumns)
+	require.Empty(t, report.Errors)
+	require.Equal(t, 3, report.FilesScanned)
+	require.Equal(t, 2, report.FilesModified)
+	require.Equal(t, 6, report.EventsRemoved)
+
+	expected := `{"event_type":"pageview","user":{"id":"user2"}}
+{"event_type":"pageview","page_title":"john@example.com.au"}
+{"event_type":"pageview","page_title":"john@example.com"}
+`
+	b, err := ioutil.ReadFile(plainFile)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(b))
+
+	b, err = ioutil.ReadFile(gzipFile)
+	require.NoError(t, err)
+	reader, err := gzip.NewReader(bytes.NewReader(b))
+	require.NoError(t, err)
+	b, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(b))
+
+	//second run doesn't find anything
+	report = scrubArchive(dir, "john@example.com", identifierColumns)
+	require.Equal(t, 0, report.EventsRemoved)
+	require.Equal(t, 0, report.FilesModified)
+}
+
+func TestScrubArchiveUnrelatedFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "erasure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "incoming.tok=token1-2021-10-01T10-00-00.000.log")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{"event_type":"pageview","user":{"id":42}}
+{"event_type":"pageview","user":{"id":"42"}}
+{"event_type":"conversion","order_id":42,"user":{"id":"user1"}}
+{"event_type":"pageview","page":"42","items":[42]}
+`), 0644))
+
+	report := scrubArchive(dir, "42", identifierColumns)
+	require.Empty(t, report.Errors)
+	require.Equal(t, 2, report.EventsRemoved)
+
+	b, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, `{"event_type":"conversion","order_id":42,"user":{"id":"user1"}}
+{"event_type":"pageview","page":"42","items":[42]}
+`, string(b))
+
+	report = scrubArchive(dir, "42", nil)
+	require.Len(t, report.Errors, 1)
+}
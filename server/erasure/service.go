@@ -0,0 +1,330 @@
+package erasure
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/dataprotection"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	// Running - erasure is in progress
+	Running = "running"
+	// Succeeded - personal data has been erased from all destinations and archived files
+	Succeeded = "succeeded"
+	// Skipped - destination doesn't support erasure or erasure is disabled in the destination
+	Skipped = "skipped"
+	// Failed - personal data hasn't been erased from at least one destination or archived file
+	Failed = "failed"
+)
+
+// Request is a request for erasing all personal data of the user (right to be forgotten)
+type Request struct {
+	// Identifier is a user identifier value (e.g. user ID or email) which is looked up in identifier columns
+	Identifier string `json:"identifier"`
+	// DestinationIDs is an optional filter of destinations. All destinations are processed by default
+	DestinationIDs []string `json:"destination_ids,omitempty"`
+	// SkipArchive disables archived files scrubbing
+	SkipArchive bool `json:"skip_archive,omitempty"`
+	// RequestedBy and Reason are saved into the audit report as is
+	RequestedBy string `json:"requested_by,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Validate returns err if the request is invalid
+func (r *Request) Validate() error {
+	if strings.TrimSpace(r.Identifier) == "" {
+		return errors.New("identifier is required parameter")
+	}
+
+	return nil
+}
+
+// Report is an auditable report of the erasure. It contains only SHA-256 hash of the identifier
+type Report struct {
+	ID             string               `json:"id"`
+	IdentifierHash string               `json:"identifier_hash"`
+	RequestedBy    string               `json:"requested_by,omitempty"`
+	Reason         string               `json:"reason,omitempty"`
+	Status         string               `json:"status"`
+	Destinations   []*DestinationReport `json:"destinations"`
+	Archive        *ArchiveReport       `json:"archive,omitempty"`
+	StartedAt      time.Time            `json:"started_at"`
+	FinishedAt     *time.Time           `json:"finished_at,omitempty"`
+}
+
+// DestinationReport is a result of the erasure in a destination: affected rows per table (-1 if it is unknown)
+type DestinationReport struct {
+	ID     string           `json:"id"`
+	Status string           `json:"status"`
+	Tables map[string]int64 `json:"tables,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// ArchiveReport is a result of archived files scrubbing
+type ArchiveReport struct {
+	FilesScanned  int      `json:"files_scanned"`
+	FilesModified int      `json:"files_modified"`
+	EventsRemoved int      `json:"events_removed"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// Service erases personal data from SQL destinations and archived files and keeps audit reports
+// in memory and in the append-only audit log file
+type Service struct {
+	destinationService *destinations.Service
+	archiveDir         string
+	auditLogPath       string
+	defaults           *config.Erasure
+
+	mutex      sync.RWMutex
+	auditMutex sync.Mutex
+	reports    map[string]*Report
+}
+
+// NewTestService returns test instance - only for tests
+func NewTestService() *Service {
+	return &Service{reports: map[string]*Report{}}
+}
+
+// NewService returns configured Service with reports loaded from the audit log file
+func NewService(destinationService *destinations.Service, archiveDir, auditLogPath string, defaults *config.Erasure) (*Service, error) {
+	if err := logging.EnsureDir(filepath.Dir(auditLogPath)); err != nil {
+		return nil, fmt.Errorf("error creating erasure audit log directory: %v", err)
+	}
+
+	s := &Service{
+		destinationService: destinationService,
+		archiveDir:         archiveDir,
+		auditLogPath:       auditLogPath,
+		defaults:           defaults,
+		reports:            map[string]*Report{},
+	}
+	if err := s.loadReports(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Erase validates the request and starts erasing in background
+// returns created report
+func (s *Service) Erase(req *Request) (*Report, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	destinationIDs := req.DestinationIDs
+	if len(destinationIDs) == 0 {
+		destinationIDs = s.destinationService.GetAllDestinationIDs()
+	}
+	for _, destinationID := range destinationIDs {
+		if _, ok := s.destinationService.GetDestinationByID(destinationID); !ok {
+			return nil, fmt.Errorf("destination [%s] doesn't exist", destinationID)
+		}
+	}
+
+	report := &Report{
+		ID:             uuid.New().String(),
+		IdentifierHash: dataprotection.Hash(nil, req.Identifier),
+		RequestedBy:    req.RequestedBy,
+		Reason:         req.Reason,
+		Status:         Running,
+		StartedAt:      timestamp.Now().UTC(),
+	}
+	s.mutex.Lock()
+	s.reports[report.ID] = report
+	s.mutex.Unlock()
+
+	logging.Infof("[%s] Personal data erasure from %d destinations has been started", report.ID, len(destinationIDs))
+	safego.Run(func() {
+		s.run(report, req, destinationIDs)
+	})
+
+	return s.GetReport(report.ID), nil
+}
+
+// GetReport returns a copy of the report or nil if it doesn't exist
+func (s *Service) GetReport(id string) *Report {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	report, ok := s.reports[id]
+	if !ok {
+		return nil
+	}
+
+	return copyReport(report)
+}
+
+// GetReports returns copies of all reports sorted by start time
+func (s *Service) GetReports() []*Report {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	reports := make([]*Report, 0, len(s.reports))
+	for _, report := range s.reports {
+		reports = append(reports, copyReport(report))
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].StartedAt.Before(reports[j].StartedAt)
+	})
+
+	return reports
+}
+
+func (s *Service) run(report *Report, req *Request, destinationIDs []string) {
+	status := Succeeded
+	for _, destinationID := range destinationIDs {
+		destinationReport := s.eraseFromDestination(destinationID, req.Identifier)
+		if destinationReport.Status == Failed {
+			status = Failed
+			logging.Errorf("[%s] Error erasing personal data from destination [%s]: %s", report.ID, destinationID, destinationReport.Error)
+		}
+
+		s.mutex.Lock()
+		report.Destinations = append(report.Destinations, destinationReport)
+		s.mutex.Unlock()
+	}
+
+	var archiveReport *ArchiveReport
+	if !req.SkipArchive && s.archiveDir != "" {
+		archiveReport = scrubArchive(s.archiveDir, req.Identifier, s.archiveColumns(destinationIDs))
+		if len(archiveReport.Errors) > 0 {
+			status = Failed
+			logging.Errorf("[%s] Error scrubbing archived files: %s", report.ID, strings.Join(archiveReport.Errors, "; "))
+		}
+	}
+
+	finishedAt := timestamp.Now().UTC()
+	s.mutex.Lock()
+	report.Archive = archiveReport
+	report.Status = status
+	report.FinishedAt = &finishedAt
+	reportCopy := copyReport(report)
+	s.mutex.Unlock()
+
+	if err := s.writeAuditLog(reportCopy); err != nil {
+		logging.SystemErrorf("[%s] Error writing personal data erasure report into audit log: %v", report.ID, err)
+	}
+	logging.Infof("[%s] Personal data erasure has been finished with status: %s", report.ID, status)
+}
+
+// archiveColumns returns default identifier columns and identifier columns of the destinations:
+// archived events are matched by the same columns as destination tables
+func (s *Service) archiveColumns(destinationIDs []string) []string {
+	var columns []string
+	if s.defaults != nil {
+		columns = append(columns, s.defaults.Columns...)
+	}
+
+	for _, destinationID := range destinationIDs {
+		if storageProxy, ok := s.destinationService.GetDestinationByID(destinationID); ok {
+			if storage, ok := storageProxy.Get(); ok {
+				columns = append(columns, storage.ErasureColumns(s.defaults)...)
+			}
+		}
+	}
+
+	return columns
+}
+
+func (s *Service) eraseFromDestination(destinationID, identifier string) *DestinationReport {
+	destinationReport := &DestinationReport{ID: destinationID}
+	storageProxy, ok := s.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		destinationReport.Status = Failed
+		destinationReport.Error = "destination doesn't exist"
+		return destinationReport
+	}
+	storage, ok := storageProxy.Get()
+	if !ok {
+		destinationReport.Status = Failed
+		destinationReport.Error = "destination isn't initialized"
+		return destinationReport
+	}
+
+	tables, err := storage.Erase(identifier, s.defaults)
+	destinationReport.Tables = tables
+	switch {
+	case err == storages.ErrErasureNotSupported || err == storages.ErrErasureDisabled:
+		destinationReport.Status = Skipped
+		destinationReport.Error = err.Error()
+	case err != nil:
+		destinationReport.Status = Failed
+		destinationReport.Error = err.Error()
+	default:
+		destinationReport.Status = Succeeded
+	}
+
+	return destinationReport
+}
+
+// writeAuditLog appends the report as a JSON line into the audit log file
+func (s *Service) writeAuditLog(report *Report) error {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	s.auditMutex.Lock()
+	defer s.auditMutex.Unlock()
+
+	file, err := os.OpenFile(s.auditLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+// loadReports reads finished reports from the audit log file. Malformed lines are skipped
+func (s *Service) loadReports() error {
+	file, err := os.Open(s.auditLogPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error opening erasure audit log: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		report := &Report{}
+		if err := json.Unmarshal(scanner.Bytes(), report); err != nil || report.ID == "" {
+			logging.Warnf("Malformed erasure audit log line has been skipped: %s", scanner.Text())
+			continue
+		}
+		s.reports[report.ID] = report
+	}
+
+	return scanner.Err()
+}
+
+func copyReport(report *Report) *Report {
+	reportCopy := *report
+	reportCopy.Destinations = append([]*DestinationReport{}, report.Destinations...)
+	return &reportCopy
+}
@@ -28,6 +28,9 @@ var (
 	TruncateError             = sqlError.NewSubtype("truncate")
 	BulkMergeError            = sqlError.NewSubtype("bulk_merge")
 	CopyError                 = sqlError.NewSubtype("copy")
+	MaterializeError          = sqlError.NewSubtype("materialize")
+	EraseError                = sqlError.NewSubtype("erase")
+	ExpireError               = sqlError.NewSubtype("expire")
 
 	stageErr             = reportedErrors.NewType("stage")
 	SaveOnStageError     = stageErr.NewSubtype("save_on_stage")
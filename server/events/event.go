@@ -23,6 +23,7 @@ type Event map[string]interface{}
 type SkippedEvent struct {
 	Event           json.RawMessage `json:"event,omitempty"`
 	Error           string          `json:"error,omitempty"`
+	EventID         string          `json:"event_id,omitempty"`
 	RecognizedEvent bool
 }
 
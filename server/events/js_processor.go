@@ -11,16 +11,22 @@ var HashedAnonymIDPath = jsonutils.NewJSONPath("/eventn_ctx/user/hashed_anonymou
 
 //JsProcessor preprocess client integration events
 type JsProcessor struct {
-	usersRecognition  Recognition
-	userAgentJSONPath jsonutils.JSONPath
+	usersRecognition    Recognition
+	userAgentJSONPath   jsonutils.JSONPath
+	clientHintsJSONPath jsonutils.JSONPath
 }
 
 //NewJsProcessor returns configured JsProcessor
-func NewJsProcessor(usersRecognition Recognition, userAgentPath string) *JsProcessor {
-	return &JsProcessor{usersRecognition: usersRecognition, userAgentJSONPath: jsonutils.NewJSONPath(userAgentPath)}
+func NewJsProcessor(usersRecognition Recognition, userAgentPath, clientHintsPath string) *JsProcessor {
+	return &JsProcessor{
+		usersRecognition:    usersRecognition,
+		userAgentJSONPath:   jsonutils.NewJSONPath(userAgentPath),
+		clientHintsJSONPath: jsonutils.NewJSONPath(clientHintsPath),
+	}
 }
 
-//Preprocess sets user-agent from request header to configured nodes
+//Preprocess sets user-agent and client hints from request headers to configured nodes
+//(client hints sent in the event payload aren't overwritten)
 //sets user anonymous ID if GDPR
 func (jp *JsProcessor) Preprocess(event Event, reqContext *RequestContext) {
 	if reqContext.UserAgent != "" {
@@ -29,6 +35,12 @@ func (jp *JsProcessor) Preprocess(event Event, reqContext *RequestContext) {
 		}
 	}
 
+	if reqContext.ClientHints != nil && !jp.clientHintsJSONPath.IsEmpty() {
+		if err := jp.clientHintsJSONPath.SetIfNotExist(event, reqContext.ClientHints); err != nil {
+			logging.Warnf("Unable to set client hints from headers to event object: %v", err)
+		}
+	}
+
 	if !reqContext.CookiesLawCompliant {
 		if err := UserAnonymIDPath.Set(event, reqContext.JitsuAnonymousID); err != nil {
 			logging.SystemErrorf("Error setting generated Jitsu anonymous ID: %v", err)
@@ -3,13 +3,16 @@ package events
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/jitsucom/jitsu/server/events/internal"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/queue"
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"go.uber.org/atomic"
 )
 
 var ErrQueueClosed = errors.New("queue is closed")
@@ -28,12 +31,19 @@ type NativeQueue struct {
 	queue      queue.Queue
 
 	metricsReporter internal.MetricReporter
-	closed          chan struct{}
+	//limiter is nil if queue size isn't limited
+	limiter *queueLimiter
+	//overflowMutex serializes pushes of QueueOverflowDropOldest policy: the oldest events are evicted before pushing
+	overflowMutex sync.Mutex
+	//headReceivedAt is a receiving time (unix nanoseconds) of the last dequeued event. It is used as the oldest event time
+	headReceivedAt *atomic.Int64
+	closed         chan struct{}
 }
 
-func NewNativeQueue(namespace, subsystem, identifier string, underlyingQueue queue.Queue) (Queue, error) {
+//NewNativeQueue returns configured NativeQueue. limits might be nil (queue size isn't limited)
+func NewNativeQueue(namespace, subsystem, identifier string, underlyingQueue queue.Queue, limits *QueueLimits, spillStorage SpillStorage) (Queue, error) {
 	var metricsReporter internal.MetricReporter
-	if underlyingQueue.Type() == queue.RedisType {
+	if underlyingQueue.Type() == queue.RedisType || underlyingQueue.Type() == queue.RedisStreamsType {
 		metricsReporter = &internal.SharedQueueMetricReporter{}
 	} else {
 		metricsReporter = &internal.ServerMetricReporter{}
@@ -47,8 +57,16 @@ func NewNativeQueue(namespace, subsystem, identifier string, underlyingQueue que
 		subsystem:       subsystem,
 		identifier:      identifier,
 		metricsReporter: metricsReporter,
+		headReceivedAt:  atomic.NewInt64(0),
 		closed:          make(chan struct{}, 1),
 	}
+	if limits != nil && limits.MaxSize > 0 {
+		nq.limiter = newQueueLimiter(subsystem, identifier, limits, spillStorage, underlyingQueue.Size()+underlyingQueue.BufferSize())
+	}
+
+	if namespace == queue.DestinationNamespace {
+		destinationQueues.register(identifier, nq)
+	}
 
 	safego.Run(nq.startMonitor)
 	return nq, nil
@@ -57,12 +75,23 @@ func NewNativeQueue(namespace, subsystem, identifier string, underlyingQueue que
 func (q *NativeQueue) startMonitor() {
 	debugTicker := time.NewTicker(time.Minute * 10)
 	metricsTicker := time.NewTicker(time.Second * 60)
+	sizeTicker := time.NewTicker(time.Second * 5)
 	for {
 		select {
 		case <-q.closed:
 			return
+		case <-sizeTicker.C:
+			//sync approximate size with the real one (shared queues are changed by other servers)
+			if q.limiter != nil {
+				if size := q.queue.Size(); size >= 0 {
+					q.limiter.size.Store(size + q.queue.BufferSize())
+				}
+			}
 		case <-metricsTicker.C:
 			q.metricsReporter.SetMetrics(q.subsystem, q.identifier, int(q.queue.Size()), int(q.queue.BufferSize()))
+			if q.namespace == queue.DestinationNamespace {
+				metrics.SetStreamEventsOldestAge(q.subsystem, q.identifier, q.Lag().OldestEventAge.Seconds())
+			}
 		case <-debugTicker.C:
 			size := q.queue.Size()
 			logging.Infof("[queue: %s_%s_%s] current size: %d", q.namespace, q.subsystem, q.identifier, size)
@@ -70,43 +99,189 @@ func (q *NativeQueue) startMonitor() {
 	}
 }
 
+//Consume enqueues event. If queue size limit is reached applies overflow policy
 func (q *NativeQueue) Consume(f map[string]interface{}, tokenID string) {
+	if q.limiter != nil && q.limiter.limits.Policy == QueueOverflowDropOldest {
+		q.overflowMutex.Lock()
+		defer q.overflowMutex.Unlock()
+	}
+
+	if q.limiter != nil && q.limiter.full() {
+		q.limiter.overflow()
+		switch q.limiter.limits.Policy {
+		case QueueOverflowSpill:
+			q.limiter.spill.add(f)
+			return
+		case QueueOverflowBlock:
+			if !q.waitForSpace() {
+				logSkippedEvent(f, fmt.Errorf("queue size limit %d is reached and there is no free space after %s", q.limiter.limits.MaxSize, queueBlockTimeout.String()))
+				return
+			}
+		case QueueOverflowDropOldest:
+			for q.limiter.full() {
+				if !q.dropOldest() {
+					break
+				}
+			}
+		default:
+			logSkippedEvent(f, fmt.Errorf("queue size limit %d is reached", q.limiter.limits.MaxSize))
+			return
+		}
+	}
+
 	q.ConsumeTimed(f, timestamp.Now().UTC(), tokenID)
 }
 
+//waitForSpace blocks until there is free space in the queue. Returns false on timeout or if queue is closed
+func (q *NativeQueue) waitForSpace() bool {
+	deadline := time.Now().Add(queueBlockTimeout)
+	for q.limiter.full() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-q.closed:
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	return true
+}
+
+//dropOldest synchronously removes the oldest event from the queue. Returns false if there is nothing to remove
+func (q *NativeQueue) dropOldest() bool {
+	var ite interface{}
+	var err error
+	if pollingQueue, ok := q.queue.(queue.PollingQueue); ok {
+		ite, err = pollingQueue.Poll()
+	} else {
+		ite, err = q.queue.Pop()
+	}
+	if err != nil {
+		switch err {
+		case queue.ErrQueueEmpty:
+			//approximate size is greater than the real one
+			q.limiter.size.Store(q.queue.BufferSize())
+		case queue.ErrQueueClosed:
+		default:
+			logging.Errorf("[%s] Error dropping the oldest event from the queue: %v", q.identifier, err)
+		}
+		return false
+	}
+
+	q.limiter.size.Dec()
+	q.metricsReporter.DequeuedEvent(q.subsystem, q.identifier)
+
+	var event Event
+	if te, ok := ite.(*TimedEvent); ok {
+		event = te.Payload
+		q.observeHead(event)
+	}
+	logSkippedEvent(event, fmt.Errorf("queue size limit %d is reached. The oldest event is dropped", q.limiter.limits.MaxSize))
+	return true
+}
+
+//ConsumeTimed enqueues event which will be processed not earlier than t. PriorityField is moved from the payload into TimedEvent
 func (q *NativeQueue) ConsumeTimed(payload map[string]interface{}, t time.Time, tokenID string) {
 	te := &TimedEvent{
 		Payload:      payload,
 		DequeuedTime: t,
 		TokenID:      tokenID,
 	}
+	if priority, ok := payload[PriorityField]; ok {
+		//the payload is shared between destinations queues: the field is removed from a copy
+		te.Payload = make(map[string]interface{}, len(payload))
+		for k, v := range payload {
+			if k != PriorityField {
+				te.Payload[k] = v
+			}
+		}
+		te.Priority, _ = priority.(string)
+	}
 
 	if err := q.queue.Push(te); err != nil {
 		logSkippedEvent(payload, fmt.Errorf("Error pushing event to the queue: %v", err))
 		return
 	}
 
+	if q.limiter != nil {
+		q.limiter.size.Inc()
+	}
 	q.metricsReporter.EnqueuedEvent(q.subsystem, q.identifier)
 }
 
+//DequeueBlock returns the next event and acknowledges it right away
 func (q *NativeQueue) DequeueBlock() (Event, time.Time, string, error) {
-	ite, err := q.queue.Pop()
+	event, dequeuedTime, tokenID, ack, err := q.DequeueBlockWithAck()
+	if err != nil {
+		return nil, time.Time{}, "", err
+	}
+
+	ack()
+	return event, dequeuedTime, tokenID, nil
+}
+
+//DequeueBlockWithAck returns the next event and a func which must be called after the event is processed (written,
+//put back into the queue or skipped). Events of the underlying queue which doesn't support acknowledgement
+//(see queue.AckQueue) are removed from the queue right away
+func (q *NativeQueue) DequeueBlockWithAck() (Event, time.Time, string, func(), error) {
+	var ite interface{}
+	var err error
+	ack := func() {}
+	if ackQueue, ok := q.queue.(queue.AckQueue); ok {
+		ite, ack, err = ackQueue.PopWithAck()
+	} else {
+		ite, err = q.queue.Pop()
+	}
 	if err != nil {
 		if err == queue.ErrQueueClosed {
-			return nil, time.Time{}, "", ErrQueueClosed
+			return nil, time.Time{}, "", nil, ErrQueueClosed
 		}
 
-		return nil, time.Time{}, "", err
+		return nil, time.Time{}, "", nil, err
 	}
 
+	if q.limiter != nil {
+		q.limiter.size.Dec()
+	}
 	q.metricsReporter.DequeuedEvent(q.subsystem, q.identifier)
 
 	te, ok := ite.(*TimedEvent)
 	if !ok {
-		return nil, time.Time{}, "", fmt.Errorf("wrong type of event dto in queue. Expected: *TimedEvent, actual: %T (%s)", ite, ite)
+		ack()
+		return nil, time.Time{}, "", nil, fmt.Errorf("wrong type of event dto in queue. Expected: *TimedEvent, actual: %T (%s)", ite, ite)
 	}
+	q.observeHead(te.Payload)
 
-	return te.Payload, te.DequeuedTime, te.TokenID, nil
+	return te.Payload, te.DequeuedTime, te.TokenID, ack, nil
+}
+
+//Lag returns the queue size (including buffers) and the approximate age of the oldest event: time since receiving
+//of the last dequeued event. The age is 0 if the queue is empty
+func (q *NativeQueue) Lag() QueueLag {
+	size := q.queue.Size()
+	if size < 0 {
+		return QueueLag{Size: -1}
+	}
+
+	lag := QueueLag{Size: size + q.queue.BufferSize()}
+	if headReceivedAt := q.headReceivedAt.Load(); lag.Size > 0 && headReceivedAt > 0 {
+		if age := timestamp.Now().Sub(time.Unix(0, headReceivedAt)); age > 0 {
+			lag.OldestEventAge = age
+		}
+	}
+
+	return lag
+}
+
+//observeHead remembers receiving time of the dequeued event
+func (q *NativeQueue) observeHead(event Event) {
+	if receivedAt, ok := event[timestamp.Key].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, receivedAt); err == nil {
+			q.headReceivedAt.Store(t.UnixNano())
+		}
+	}
 }
 
 //Close closes underlying queue
@@ -116,6 +291,10 @@ func (q *NativeQueue) Close() error {
 		return nil
 	default:
 		close(q.closed)
+		destinationQueues.unregister(q.identifier, q)
+		if q.limiter != nil {
+			q.limiter.close()
+		}
 		return q.queue.Close()
 	}
 }
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/queue"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+//ackQueueMock is an in-memory queue which counts acknowledgements
+type ackQueueMock struct {
+	queue.PollingQueue
+
+	acked int
+}
+
+func (aqm *ackQueueMock) PopWithAck() (interface{}, func(), error) {
+	v, err := aqm.PollingQueue.Pop()
+	return v, func() { aqm.acked++ }, err
+}
+
+func (aqm *ackQueueMock) PollWithAck() (interface{}, func(), error) {
+	v, err := aqm.PollingQueue.Poll()
+	return v, func() { aqm.acked++ }, err
+}
+
+func TestNativeQueueAck(t *testing.T) {
+	underlyingQueue := &ackQueueMock{PollingQueue: queue.NewInMemory(10).(queue.PollingQueue)}
+	nq, err := NewNativeQueue(queue.DestinationNamespace, "test", "destination1", underlyingQueue, nil, nil)
+	require.NoError(t, err)
+	defer nq.Close()
+
+	nq.Consume(map[string]interface{}{"id": "1"}, "token1")
+	nq.Consume(map[string]interface{}{"id": "2"}, "token1")
+
+	event, _, tokenID, ack, err := nq.(AckQueue).DequeueBlockWithAck()
+	require.NoError(t, err)
+	require.Equal(t, Event{"id": "1"}, event)
+	require.Equal(t, "token1", tokenID)
+	require.Equal(t, 0, underlyingQueue.acked)
+	ack()
+	require.Equal(t, 1, underlyingQueue.acked)
+
+	//DequeueBlock acknowledges right away
+	event, _, _, err = nq.DequeueBlock()
+	require.NoError(t, err)
+	require.Equal(t, Event{"id": "2"}, event)
+	require.Equal(t, 2, underlyingQueue.acked)
+}
+
+func TestNativeQueueLag(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	timestamp.SetFreezeTime(now)
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+
+	nq, err := NewNativeQueue(queue.DestinationNamespace, "test", "destination1", queue.NewInMemory(10), nil, nil)
+	require.NoError(t, err)
+	defer nq.Close()
+
+	require.Equal(t, QueueLag{}, nq.(LagQueue).Lag())
+
+	nq.Consume(map[string]interface{}{"id": "1", timestamp.Key: timestamp.ToISOFormat(now.Add(-time.Minute))}, "token1")
+	nq.Consume(map[string]interface{}{"id": "2", timestamp.Key: timestamp.ToISOFormat(now.Add(-time.Second))}, "token1")
+	nq.Consume(map[string]interface{}{"id": "3", timestamp.Key: timestamp.ToISOFormat(now)}, "token1")
+
+	//the age is measured from the last dequeued event
+	_, _, _, err = nq.DequeueBlock()
+	require.NoError(t, err)
+	require.Equal(t, QueueLag{Size: 2, OldestEventAge: time.Minute}, nq.(LagQueue).Lag())
+
+	_, _, _, err = nq.DequeueBlock()
+	require.NoError(t, err)
+	require.Equal(t, QueueLag{Size: 1, OldestEventAge: time.Second}, nq.(LagQueue).Lag())
+
+	//empty queue doesn't lag
+	_, _, _, err = nq.DequeueBlock()
+	require.NoError(t, err)
+	require.Equal(t, QueueLag{}, nq.(LagQueue).Lag())
+}
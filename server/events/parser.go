@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,8 +23,22 @@ const (
 	screenKey = "screen"
 
 	messageIDKey = "messageId"
+
+	typeKey     = "type"
+	writeKeyKey = "writeKey"
 )
 
+//segmentCallTypes are Segment HTTP Tracking API call types by endpoint path: /v1/track (/v1/t), /v1/identify (/v1/i), etc.
+//events of single call endpoints may not contain 'type' field
+var segmentCallTypes = map[string]string{
+	"track": "track", "t": "track",
+	"identify": "identify", "i": "identify",
+	"page": "page", "p": "page",
+	"screen": "screen", "s": "screen",
+	"group": "group", "g": "group",
+	"alias": "alias", "a": "alias",
+}
+
 var malformedSegmentBatch = errors.New("malformed Segment body 'batch' type. Expected array of objects")
 
 type ParsingError struct {
@@ -40,15 +55,22 @@ type Parser interface {
 	ParseEventsBody(c *gin.Context) ([]Event, *ParsingError)
 }
 
+//BinaryDecoder decodes binary HTTP bodies (e.g. Avro or Protobuf) into objects according to the Content-Type header
+//returns false if the content type isn't supported and the body should be parsed as JSON
+type BinaryDecoder interface {
+	Decode(contentType string, body []byte) ([]map[string]interface{}, bool, error)
+}
+
 //jitsuParser parses Jitsu events
 type jitsuParser struct {
 	maxEventSize           int
 	maxCachedEventsErrSize int
+	binaryDecoder          BinaryDecoder
 }
 
-//NewJitsuParser returns jitsuParser
-func NewJitsuParser(maxEventSize, maxCachedEventsErrSize int) Parser {
-	return &jitsuParser{maxEventSize: maxEventSize, maxCachedEventsErrSize: maxCachedEventsErrSize}
+//NewJitsuParser returns jitsuParser. binaryDecoder is optional
+func NewJitsuParser(maxEventSize, maxCachedEventsErrSize int, binaryDecoder BinaryDecoder) Parser {
+	return &jitsuParser{maxEventSize: maxEventSize, maxCachedEventsErrSize: maxCachedEventsErrSize, binaryDecoder: binaryDecoder}
 }
 
 //ParseEventsBody parses HTTP body and returns Event objects or err if occurred
@@ -59,6 +81,13 @@ func (jp *jitsuParser) ParseEventsBody(c *gin.Context) ([]Event, *ParsingError)
 		return nil, parsingError(nil, err)
 	}
 
+	if jp.binaryDecoder != nil {
+		objects, ok, err := jp.binaryDecoder.Decode(c.GetHeader("Content-Type"), body)
+		if ok {
+			return jp.binaryEvents(body, objects, err)
+		}
+	}
+
 	maxCachedEventsErrSize := jp.maxCachedEventsErrSize
 	if len(body) < jp.maxCachedEventsErrSize {
 		maxCachedEventsErrSize = len(body)
@@ -94,6 +123,27 @@ func (jp *jitsuParser) ParseEventsBody(c *gin.Context) ([]Event, *ParsingError)
 	}
 }
 
+//binaryEvents returns decoded binary body objects as events or err if occurred or size limit is exceeded
+//binary payloads aren't put into the events cache
+func (jp *jitsuParser) binaryEvents(body []byte, objects []map[string]interface{}, err error) ([]Event, *ParsingError) {
+	if err != nil {
+		return nil, parsingError(nil, fmt.Errorf("error decoding HTTP body: %v", err))
+	}
+	if len(objects) == 0 {
+		return nil, parsingError(nil, errors.New("HTTP body doesn't contain events"))
+	}
+	if len(body) > jp.maxEventSize*len(objects) {
+		return nil, parsingError(nil, fmt.Errorf("Size of one of events exceeds limit: %d", jp.maxEventSize))
+	}
+
+	eventsArray := make([]Event, 0, len(objects))
+	for _, object := range objects {
+		eventsArray = append(eventsArray, object)
+	}
+
+	return eventsArray, nil
+}
+
 //parseTemplateEvents parses
 // {template : {}, events: [{},{}]} structure
 //return false if event doesn't have this structure
@@ -203,8 +253,16 @@ func (sp *segmentParser) ParseEventsBody(c *gin.Context) ([]Event, *ParsingError
 		return nil, parsingErr
 	}
 
+	callType := segmentCallTypes[path.Base(c.Request.URL.Path)]
+
 	var resultEvents []Event
 	for _, input := range inputEvents {
+		if _, ok := input[typeKey]; !ok && callType != "" {
+			input[typeKey] = callType
+		}
+		//write key is used only for authorization
+		delete(input, writeKeyKey)
+
 		mapped, err := sp.mapper.Map(input)
 		if err != nil {
 			return nil, parsingError(nil, err)
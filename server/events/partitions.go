@@ -0,0 +1,428 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/locks"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/queue"
+	"github.com/jitsucom/jitsu/server/safego"
+	"go.uber.org/atomic"
+)
+
+const (
+	//DefaultPartitionKey is a default events queue partition key: user anonymous ID (keeps order of user events)
+	DefaultPartitionKey              = "/eventn_ctx/user/anonymous_id||/user/anonymous_id"
+	DefaultPartitionsRebalancePeriod = 10 * time.Second
+
+	partitionPostfix     = "_partition_"
+	partitionPollTimeout = time.Second
+	partitionLockPrefix  = "events_queue_partition:"
+)
+
+//PartitionsCoordinator provides cluster instances and distributed locks for claiming events queue partitions
+//(coordination.Service)
+type PartitionsCoordinator interface {
+	GetJitsuInstancesInCluster() ([]string, error)
+	CreateLock(name string) locks.Lock
+}
+
+//PartitionsConfig is a configuration of partitioned destinations events queues
+type PartitionsConfig struct {
+	//Count is a number of partitions per destination queue
+	Count int
+	//Key is a JSON path of the event field which is used for choosing partition
+	Key string
+	//RebalancePeriod is a period of checking cluster instances and claiming/releasing partitions
+	RebalancePeriod time.Duration
+	//ServerName is the current server instance name
+	ServerName  string
+	Coordinator PartitionsCoordinator
+}
+
+//Validate returns err if the configuration is invalid and sets default values
+func (pc *PartitionsConfig) Validate() error {
+	if pc.Count <= 0 {
+		return errors.New("partitions count must be positive")
+	}
+	if pc.ServerName == "" {
+		return errors.New("server name is required")
+	}
+	if pc.Coordinator == nil {
+		return errors.New("coordination service is required")
+	}
+	if pc.Key == "" {
+		pc.Key = DefaultPartitionKey
+	}
+	if pc.RebalancePeriod <= 0 {
+		pc.RebalancePeriod = DefaultPartitionsRebalancePeriod
+	}
+
+	return nil
+}
+
+//partitionOwnership is a claimed partition with the worker which reads events from it
+type partitionOwnership struct {
+	lock locks.Lock
+	stop chan struct{}
+	done chan struct{}
+}
+
+//partitionedQueue is a queue.Queue implementation with N underlying shared queues (partitions).
+//Events are pushed into partitions by hash of the partition key (events with the same key keep order).
+//Every server instance reads only partitions which it owns: partitions are assigned to cluster instances
+//with rendezvous hashing (minimal reassignments on membership changes) and claimed with coordination service locks.
+//Ownership is rebalanced every RebalancePeriod. A released partition is unlocked only after its worker has handed over
+//the last read event, so the partition is never read by two instances at the same time.
+//Events of partitions with acknowledgement support (queue.AckPollingQueue) are acknowledged by the consumer
+//(see PopWithAck). Events which haven't been handed over on closing are left unacknowledged (or pushed back)
+type partitionedQueue struct {
+	identifier  string
+	partitions  []queue.PollingQueue
+	keyPath     jsonutils.JSONPath
+	serverName  string
+	coordinator PartitionsCoordinator
+
+	//roundRobin is used for events without partition key
+	roundRobin *atomic.Uint32
+
+	mutex sync.Mutex
+	owned map[int]*partitionOwnership
+
+	events chan *prefetchedEvent
+	wg     sync.WaitGroup
+	closed chan struct{}
+}
+
+//newPartitionedQueue returns partitionedQueue and starts partitions rebalancing
+func newPartitionedQueue(identifier string, partitions []queue.PollingQueue, config *PartitionsConfig) queue.Queue {
+	pq := &partitionedQueue{
+		identifier:  identifier,
+		partitions:  partitions,
+		keyPath:     jsonutils.NewJSONPath(config.Key),
+		serverName:  config.ServerName,
+		coordinator: config.Coordinator,
+		roundRobin:  atomic.NewUint32(0),
+		owned:       map[int]*partitionOwnership{},
+		events:      make(chan *prefetchedEvent),
+		closed:      make(chan struct{}),
+	}
+
+	pq.wg.Add(1)
+	safego.Run(func() {
+		defer pq.wg.Done()
+		pq.startRebalancing(config.RebalancePeriod)
+	})
+
+	return pq
+}
+
+//startRebalancing rebalances partitions right away and then every period until the queue is closed
+func (pq *partitionedQueue) startRebalancing(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		pq.rebalance()
+
+		select {
+		case <-pq.closed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//rebalance releases partitions which are assigned to other instances and claims assigned partitions
+func (pq *partitionedQueue) rebalance() {
+	instances, err := pq.coordinator.GetJitsuInstancesInCluster()
+	if err != nil {
+		logging.Errorf("[%s] Error getting cluster instances for events queue partitions rebalancing: %v", pq.identifier, err)
+		return
+	}
+
+	assigned := assignPartitions(len(pq.partitions), instances, pq.serverName)
+
+	pq.mutex.Lock()
+	defer pq.mutex.Unlock()
+
+	select {
+	case <-pq.closed:
+		return
+	default:
+	}
+
+	for partition := range pq.owned {
+		if !assigned[partition] {
+			pq.release(partition)
+		}
+	}
+
+	for partition := range assigned {
+		if _, ok := pq.owned[partition]; !ok {
+			pq.claim(partition)
+		}
+	}
+}
+
+//claim locks the partition and starts reading it. If the partition is still locked by the previous owner, it will be
+//claimed on the next rebalancing. Must be called under the mutex
+func (pq *partitionedQueue) claim(partition int) {
+	lock := pq.coordinator.CreateLock(partitionLockPrefix + partitionIdentifier(pq.identifier, partition))
+	locked, err := lock.TryLock(0)
+	if err != nil {
+		logging.Errorf("[%s] Error locking events queue partition %d: %v", pq.identifier, partition, err)
+		return
+	}
+	if !locked {
+		logging.Debugf("[%s] Events queue partition %d is still owned by another instance", pq.identifier, partition)
+		return
+	}
+
+	ownership := &partitionOwnership{lock: lock, stop: make(chan struct{}), done: make(chan struct{})}
+	pq.owned[partition] = ownership
+	safego.Run(func() {
+		pq.read(partition, ownership)
+	})
+
+	logging.Infof("[%s] Events queue partition %d has been claimed", pq.identifier, partition)
+}
+
+//release stops the partition worker and unlocks the partition. Must be called under the mutex
+func (pq *partitionedQueue) release(partition int) {
+	ownership := pq.owned[partition]
+	close(ownership.stop)
+	<-ownership.done
+	ownership.lock.Unlock()
+	delete(pq.owned, partition)
+
+	logging.Infof("[%s] Events queue partition %d has been released", pq.identifier, partition)
+}
+
+//read polls events from the partition and passes them into the events channel until the ownership is stopped
+func (pq *partitionedQueue) read(partition int, ownership *partitionOwnership) {
+	defer close(ownership.done)
+
+	for {
+		select {
+		case <-ownership.stop:
+			return
+		case <-pq.closed:
+			return
+		default:
+		}
+
+		event, err := pollLane(pq.partitions[partition])
+		if err != nil {
+			if err == queue.ErrQueueEmpty {
+				continue
+			}
+			if err == queue.ErrQueueClosed {
+				return
+			}
+
+			logging.Errorf("[%s] Error reading event from events queue partition %d: %v", pq.identifier, partition, err)
+			select {
+			case <-ownership.stop:
+				return
+			case <-pq.closed:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		//the event is handed over even if the ownership is stopped because it has been already read from the partition
+		select {
+		case pq.events <- event:
+		case <-pq.closed:
+			pq.returnEvent(partition, event)
+			return
+		}
+	}
+}
+
+//returnEvent leaves the event which hasn't been handed over in the partition: events of partitions with acknowledgement
+//support aren't acknowledged (they are delivered again), other events are pushed back into the partition
+func (pq *partitionedQueue) returnEvent(partition int, event *prefetchedEvent) {
+	if _, ok := pq.partitions[partition].(queue.AckPollingQueue); ok {
+		return
+	}
+
+	if err := pq.partitions[partition].Push(event.value); err != nil {
+		if te, ok := event.value.(*TimedEvent); ok {
+			logSkippedEvent(te.Payload, fmt.Errorf("error pushing event back to the queue partition %d on closing: %v", partition, err))
+		}
+	}
+}
+
+//Push puts value into the partition according to the partition key hash
+func (pq *partitionedQueue) Push(v interface{}) error {
+	return pq.partitions[pq.partitionOf(v)].Push(v)
+}
+
+//partitionOf returns partition by the event partition key hash. Events without the key are distributed with round robin
+func (pq *partitionedQueue) partitionOf(v interface{}) int {
+	if te, ok := v.(*TimedEvent); ok {
+		if key, ok := pq.keyPath.Get(te.Payload); ok && key != nil {
+			if keyStr := fmt.Sprint(key); keyStr != "" {
+				hash := fnv.New32a()
+				_, _ = hash.Write([]byte(keyStr))
+				return int(hash.Sum32() % uint32(len(pq.partitions)))
+			}
+		}
+	}
+
+	return int(pq.roundRobin.Inc() % uint32(len(pq.partitions)))
+}
+
+//Pop returns the next event from owned partitions and acknowledges it right away
+func (pq *partitionedQueue) Pop() (interface{}, error) {
+	v, ack, err := pq.PopWithAck()
+	if err != nil {
+		return nil, err
+	}
+
+	ack()
+	return v, nil
+}
+
+//PopWithAck returns the next event from owned partitions or waits for the next event.
+//ack func acknowledges the event in the partition
+func (pq *partitionedQueue) PopWithAck() (interface{}, func(), error) {
+	select {
+	case <-pq.closed:
+		return nil, nil, queue.ErrQueueClosed
+	case event := <-pq.events:
+		return event.value, event.ack, nil
+	}
+}
+
+//Poll returns the next event from owned partitions and acknowledges it right away.
+//Returns ErrQueueEmpty if there are no events during partitionPollTimeout
+func (pq *partitionedQueue) Poll() (interface{}, error) {
+	v, ack, err := pq.PollWithAck()
+	if err != nil {
+		return nil, err
+	}
+
+	ack()
+	return v, nil
+}
+
+//PollWithAck works as Poll and returns an acknowledgement func as PopWithAck does
+func (pq *partitionedQueue) PollWithAck() (interface{}, func(), error) {
+	select {
+	case <-pq.closed:
+		return nil, nil, queue.ErrQueueClosed
+	case event := <-pq.events:
+		return event.value, event.ack, nil
+	case <-time.After(partitionPollTimeout):
+		return nil, nil, queue.ErrQueueEmpty
+	}
+}
+
+//Size returns sum of partitions sizes
+func (pq *partitionedQueue) Size() int64 {
+	var size int64
+	for _, partition := range pq.partitions {
+		partitionSize := partition.Size()
+		if partitionSize < 0 {
+			return -1
+		}
+		size += partitionSize
+	}
+
+	return size
+}
+
+//BufferSize returns sum of partitions buffer sizes
+func (pq *partitionedQueue) BufferSize() int64 {
+	var size int64
+	for _, partition := range pq.partitions {
+		size += partition.BufferSize()
+	}
+
+	return size
+}
+
+//Type returns underlying partitions type
+func (pq *partitionedQueue) Type() string {
+	return pq.partitions[0].Type()
+}
+
+//ownedPartitions returns sorted partitions which are read by the current instance
+func (pq *partitionedQueue) ownedPartitions() []int {
+	pq.mutex.Lock()
+	defer pq.mutex.Unlock()
+
+	partitions := make([]int, 0, len(pq.owned))
+	for partition := range pq.owned {
+		partitions = append(partitions, partition)
+	}
+	sort.Ints(partitions)
+	return partitions
+}
+
+//Close stops rebalancing, releases owned partitions and closes them
+func (pq *partitionedQueue) Close() error {
+	close(pq.closed)
+	pq.wg.Wait()
+
+	pq.mutex.Lock()
+	for partition := range pq.owned {
+		pq.release(partition)
+	}
+	pq.mutex.Unlock()
+
+	var err error
+	for _, partition := range pq.partitions {
+		if closeErr := partition.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+//partitionIdentifier returns underlying partition queue identifier
+func partitionIdentifier(identifier string, partition int) string {
+	return identifier + partitionPostfix + strconv.Itoa(partition)
+}
+
+//assignPartitions returns partitions which are assigned to the server with rendezvous hashing:
+//every partition is assigned to the instance with the highest hash of (instance, partition).
+//The server is considered as an instance even if it isn't in instances yet (e.g. before the first heartbeat)
+func assignPartitions(count int, instances []string, serverName string) map[int]bool {
+	candidates := append([]string{serverName}, instances...)
+
+	assigned := map[int]bool{}
+	for partition := 0; partition < count; partition++ {
+		var owner string
+		var maxWeight uint64
+		for _, instance := range candidates {
+			weight := rendezvousWeight(instance, partition)
+			if owner == "" || weight > maxWeight || (weight == maxWeight && instance < owner) {
+				owner, maxWeight = instance, weight
+			}
+		}
+
+		if owner == serverName {
+			assigned[partition] = true
+		}
+	}
+
+	return assigned
+}
+
+func rendezvousWeight(instance string, partition int) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(instance + "#" + strconv.Itoa(partition)))
+	return hash.Sum64()
+}
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/locks"
+	"github.com/jitsucom/jitsu/server/locks/inmemory"
+	"github.com/jitsucom/jitsu/server/queue"
+	"github.com/stretchr/testify/require"
+)
+
+//testCoordinator is a PartitionsCoordinator with shared in-memory locks and configurable cluster instances
+type testCoordinator struct {
+	sync.Mutex
+	instances   []string
+	lockFactory locks.LockFactory
+}
+
+func (tc *testCoordinator) GetJitsuInstancesInCluster() ([]string, error) {
+	tc.Lock()
+	defer tc.Unlock()
+	return append([]string{}, tc.instances...), nil
+}
+
+func (tc *testCoordinator) CreateLock(name string) locks.Lock {
+	return tc.lockFactory.CreateLock(name)
+}
+
+func (tc *testCoordinator) setInstances(instances ...string) {
+	tc.Lock()
+	tc.instances = instances
+	tc.Unlock()
+}
+
+func newTestCoordinator(instances ...string) *testCoordinator {
+	lockFactory, _ := inmemory.NewLockFactory()
+	return &testCoordinator{instances: instances, lockFactory: lockFactory}
+}
+
+//sharedPartition is a partition shared between several partitioned queues in tests. It isn't closed by partitioned queues
+type sharedPartition struct {
+	queue.PollingQueue
+}
+
+func (sp *sharedPartition) Close() error {
+	return nil
+}
+
+func newTestPartitions(count int) []queue.PollingQueue {
+	partitions := make([]queue.PollingQueue, count)
+	for i := range partitions {
+		partitions[i] = queue.NewInMemory(100).(queue.PollingQueue)
+	}
+	return partitions
+}
+
+func TestAssignPartitions(t *testing.T) {
+	instances := []string{"node1", "node2", "node3"}
+	owners := map[int]string{}
+	for _, instance := range instances {
+		for partition := range assignPartitions(32, instances, instance) {
+			require.NotContains(t, owners, partition, "partition %d is assigned to several instances", partition)
+			owners[partition] = instance
+		}
+	}
+	require.Len(t, owners, 32)
+
+	//only partitions of the removed instance are reassigned
+	for partition := range assignPartitions(32, []string{"node1", "node2"}, "node1") {
+		require.Contains(t, []string{"node1", "node3"}, owners[partition])
+	}
+	for partition := range assignPartitions(32, []string{"node1", "node2"}, "node2") {
+		require.Contains(t, []string{"node2", "node3"}, owners[partition])
+	}
+
+	//the server owns all partitions before the first heartbeat
+	require.Len(t, assignPartitions(4, nil, "node1"), 4)
+}
+
+func TestPartitionedQueueKeyOrder(t *testing.T) {
+	config := &PartitionsConfig{Count: 4, ServerName: "node1", Coordinator: newTestCoordinator("node1")}
+	require.NoError(t, config.Validate())
+
+	pq := newPartitionedQueue("test", newTestPartitions(4), config).(*partitionedQueue)
+	defer pq.Close()
+
+	require.Eventually(t, func() bool { return len(pq.ownedPartitions()) == 4 }, time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, pq.Push(&TimedEvent{Payload: map[string]interface{}{"user": map[string]interface{}{"anonymous_id": "user1"}, "n": i}}))
+	}
+
+	for i := 0; i < 10; i++ {
+		v, err := pq.Pop()
+		require.NoError(t, err)
+		require.Equal(t, i, v.(*TimedEvent).Payload["n"])
+	}
+}
+
+func TestPartitionedQueueRebalancing(t *testing.T) {
+	coordinator := newTestCoordinator("node1", "node2")
+	partitions := newTestPartitions(8)
+	config1 := &PartitionsConfig{Count: 8, ServerName: "node1", RebalancePeriod: 50 * time.Millisecond, Coordinator: coordinator}
+	config2 := &PartitionsConfig{Count: 8, ServerName: "node2", RebalancePeriod: 50 * time.Millisecond, Coordinator: coordinator}
+	require.NoError(t, config1.Validate())
+	require.NoError(t, config2.Validate())
+
+	shared := make([]queue.PollingQueue, len(partitions))
+	for i, partition := range partitions {
+		shared[i] = &sharedPartition{partition}
+	}
+
+	pq1 := newPartitionedQueue("test", partitions, config1).(*partitionedQueue)
+	pq2 := newPartitionedQueue("test", shared, config2).(*partitionedQueue)
+	defer pq1.Close()
+
+	require.Eventually(t, func() bool {
+		return len(pq1.ownedPartitions())+len(pq2.ownedPartitions()) == 8
+	}, time.Second, 10*time.Millisecond)
+	for _, partition := range pq1.ownedPartitions() {
+		require.NotContains(t, pq2.ownedPartitions(), partition)
+	}
+
+	//events are read by the partition owner
+	for _, partition := range pq2.ownedPartitions() {
+		require.NoError(t, partitions[partition].Push(&TimedEvent{Payload: map[string]interface{}{"partition": fmt.Sprint(partition)}}))
+		v, err := pq2.Pop()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprint(partition), v.(*TimedEvent).Payload["partition"])
+	}
+
+	//node2 leaves the cluster: node1 claims all partitions after they are released
+	coordinator.setInstances("node1")
+	require.NoError(t, pq2.Close())
+
+	require.Eventually(t, func() bool { return len(pq1.ownedPartitions()) == 8 }, time.Second, 10*time.Millisecond)
+}
+
+func TestPartitionedQueueAck(t *testing.T) {
+	config := &PartitionsConfig{Count: 1, ServerName: "node1", Coordinator: newTestCoordinator("node1")}
+	require.NoError(t, config.Validate())
+
+	partition := &ackQueueMock{PollingQueue: queue.NewInMemory(10).(queue.PollingQueue)}
+	pq := newPartitionedQueue("test", []queue.PollingQueue{partition}, config).(*partitionedQueue)
+	defer pq.Close()
+
+	require.NoError(t, pq.Push(&TimedEvent{Payload: map[string]interface{}{"id": "1"}}))
+	v, ack, err := pq.PopWithAck()
+	require.NoError(t, err)
+	require.Equal(t, "1", v.(*TimedEvent).Payload["id"])
+	require.Equal(t, 0, partition.acked)
+	ack()
+	require.Equal(t, 1, partition.acked)
+}
+
+func TestPartitionedQueueCloseKeepsReadEvents(t *testing.T) {
+	config := &PartitionsConfig{Count: 2, ServerName: "node1", Coordinator: newTestCoordinator("node1")}
+	require.NoError(t, config.Validate())
+
+	ackPartition := &ackQueueMock{PollingQueue: queue.NewInMemory(10).(queue.PollingQueue)}
+	plainPartition := &sizeOnCloseLane{PollingQueue: queue.NewInMemory(10).(queue.PollingQueue)}
+	pq := newPartitionedQueue("test", []queue.PollingQueue{ackPartition, plainPartition}, config).(*partitionedQueue)
+	require.Eventually(t, func() bool { return len(pq.ownedPartitions()) == 2 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, ackPartition.Push(&TimedEvent{Payload: map[string]interface{}{"id": "1"}}))
+	require.NoError(t, plainPartition.Push(&TimedEvent{Payload: map[string]interface{}{"id": "2"}}))
+	//events are read from partitions and wait for handing over
+	require.Eventually(t, func() bool { return pq.Size() == 0 }, time.Second, 10*time.Millisecond)
+
+	_ = pq.Close()
+	require.Equal(t, 0, ackPartition.acked, "not handed over event must be left unacknowledged")
+	require.Equal(t, int64(1), plainPartition.sizeOnClose, "not handed over event must be pushed back")
+}
@@ -0,0 +1,287 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/queue"
+	"github.com/jitsucom/jitsu/server/safego"
+)
+
+const (
+	//PriorityField is an event field for tagging event priority from JS SDK/API calls (overrides API key priority).
+	//It is removed from the event before enqueueing (see TimedEvent.Priority)
+	PriorityField = "_priority"
+
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+
+	highPriorityLanePostfix = "_high_priority"
+	lowPriorityLanePostfix  = "_low_priority"
+
+	lanesPollTimeout = time.Second
+)
+
+//priorities is a drain order of priority lanes
+var priorities = []string{PriorityHigh, PriorityNormal, PriorityLow}
+
+//IsValidPriority returns true if priority is one of high, normal, low
+func IsValidPriority(priority string) bool {
+	return priority == PriorityHigh || priority == PriorityNormal || priority == PriorityLow
+}
+
+//priorityLanes is a queue.Queue implementation with an underlying queue per priority
+//events are pushed into lanes according to PriorityField or API key priority.
+//Pop returns high priority events first, then normal, then low.
+//Every lane has a prefetch goroutine which holds at most one polled event until it is taken by Pop.
+//On closing held events are pushed back into their lanes. Events of lanes with acknowledgement support
+//(queue.AckPollingQueue) are acknowledged by the consumer (see PopWithAck)
+type priorityLanes struct {
+	identifier    string
+	lanes         map[string]queue.PollingQueue
+	prefetched    map[string]chan *prefetchedEvent
+	tokenPriority func(tokenID string) string
+
+	wg     sync.WaitGroup
+	closed chan struct{}
+}
+
+//prefetchedEvent is a polled lane event with its acknowledgement func
+type prefetchedEvent struct {
+	value interface{}
+	ack   func()
+}
+
+//newPriorityLanes returns priorityLanes and starts prefetch goroutines
+//tokenPriority returns API key priority (might return empty string)
+func newPriorityLanes(identifier string, lanes map[string]queue.PollingQueue, tokenPriority func(tokenID string) string) queue.Queue {
+	pl := &priorityLanes{
+		identifier:    identifier,
+		lanes:         lanes,
+		prefetched:    map[string]chan *prefetchedEvent{},
+		tokenPriority: tokenPriority,
+		closed:        make(chan struct{}),
+	}
+
+	for _, priority := range priorities {
+		prefetched := make(chan *prefetchedEvent)
+		pl.prefetched[priority] = prefetched
+		lane := lanes[priority]
+		pl.wg.Add(1)
+		safego.Run(func() {
+			defer pl.wg.Done()
+			pl.prefetch(lane, prefetched)
+		})
+	}
+
+	return pl
+}
+
+//prefetch polls events from the lane and passes them into the channel until priorityLanes is closed.
+//If priorityLanes is closed while the event is held, the event is pushed back into the lane
+func (pl *priorityLanes) prefetch(lane queue.PollingQueue, prefetched chan *prefetchedEvent) {
+	for {
+		select {
+		case <-pl.closed:
+			return
+		default:
+		}
+
+		event, err := pollLane(lane)
+		if err != nil {
+			if err == queue.ErrQueueEmpty {
+				continue
+			}
+			if err == queue.ErrQueueClosed {
+				return
+			}
+
+			logging.Errorf("[%s] Error reading event from priority lane: %v", pl.identifier, err)
+			select {
+			case <-pl.closed:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		select {
+		case prefetched <- event:
+		case <-pl.closed:
+			if err := lane.Push(event.value); err != nil {
+				if te, ok := event.value.(*TimedEvent); ok {
+					logSkippedEvent(te.Payload, fmt.Errorf("error pushing event back to the priority lane on closing: %v", err))
+				}
+			}
+			event.ack()
+			return
+		}
+	}
+}
+
+//pollLane polls the lane with acknowledgement if the lane supports it
+func pollLane(lane queue.PollingQueue) (*prefetchedEvent, error) {
+	if ackLane, ok := lane.(queue.AckPollingQueue); ok {
+		v, ack, err := ackLane.PollWithAck()
+		if err != nil {
+			return nil, err
+		}
+		return &prefetchedEvent{value: v, ack: ack}, nil
+	}
+
+	v, err := lane.Poll()
+	if err != nil {
+		return nil, err
+	}
+	return &prefetchedEvent{value: v, ack: func() {}}, nil
+}
+
+//Push puts value into the lane according to the event priority
+func (pl *priorityLanes) Push(v interface{}) error {
+	priority := PriorityNormal
+	if te, ok := v.(*TimedEvent); ok {
+		priority = pl.resolvePriority(te)
+	}
+
+	return pl.lanes[priority].Push(v)
+}
+
+//resolvePriority returns event priority (see TimedEvent.Priority) or API key priority or PriorityNormal
+func (pl *priorityLanes) resolvePriority(te *TimedEvent) string {
+	if IsValidPriority(te.Priority) {
+		return te.Priority
+	}
+
+	if pl.tokenPriority != nil {
+		if priority := pl.tokenPriority(te.TokenID); IsValidPriority(priority) {
+			return priority
+		}
+	}
+
+	return PriorityNormal
+}
+
+//Pop returns the next event from the highest priority non-empty lane and acknowledges it right away
+func (pl *priorityLanes) Pop() (interface{}, error) {
+	v, ack, err := pl.PopWithAck()
+	if err != nil {
+		return nil, err
+	}
+
+	ack()
+	return v, nil
+}
+
+//PopWithAck returns the next event from the highest priority non-empty lane or waits for the next event.
+//ack func acknowledges the event in the lane
+func (pl *priorityLanes) PopWithAck() (interface{}, func(), error) {
+	high, normal, low := pl.prefetched[PriorityHigh], pl.prefetched[PriorityNormal], pl.prefetched[PriorityLow]
+
+	var event *prefetchedEvent
+	select {
+	case event = <-high:
+		return event.value, event.ack, nil
+	default:
+	}
+
+	select {
+	case event = <-high:
+	case event = <-normal:
+	default:
+	}
+	if event != nil {
+		return event.value, event.ack, nil
+	}
+
+	select {
+	case <-pl.closed:
+		return nil, nil, queue.ErrQueueClosed
+	case event = <-high:
+	case event = <-normal:
+	case event = <-low:
+	}
+
+	return event.value, event.ack, nil
+}
+
+//Poll returns the next event from the lowest priority non-empty lane and acknowledges it right away.
+//It is used for evicting events (see QueueOverflowDropOldest): low priority events are dropped first.
+//Returns ErrQueueEmpty if there are no events during lanesPollTimeout
+func (pl *priorityLanes) Poll() (interface{}, error) {
+	high, normal, low := pl.prefetched[PriorityHigh], pl.prefetched[PriorityNormal], pl.prefetched[PriorityLow]
+
+	var event *prefetchedEvent
+	select {
+	case event = <-low:
+	default:
+	}
+
+	if event == nil {
+		select {
+		case event = <-low:
+		case event = <-normal:
+		default:
+		}
+	}
+
+	if event == nil {
+		select {
+		case <-pl.closed:
+			return nil, queue.ErrQueueClosed
+		case event = <-low:
+		case event = <-normal:
+		case event = <-high:
+		case <-time.After(lanesPollTimeout):
+			return nil, queue.ErrQueueEmpty
+		}
+	}
+
+	event.ack()
+	return event.value, nil
+}
+
+//Size returns sum of lanes sizes
+func (pl *priorityLanes) Size() int64 {
+	var size int64
+	for _, lane := range pl.lanes {
+		laneSize := lane.Size()
+		if laneSize < 0 {
+			return -1
+		}
+		size += laneSize
+	}
+
+	return size
+}
+
+//BufferSize returns sum of lanes buffer sizes
+func (pl *priorityLanes) BufferSize() int64 {
+	var size int64
+	for _, lane := range pl.lanes {
+		size += lane.BufferSize()
+	}
+
+	return size
+}
+
+//Type returns underlying lanes type
+func (pl *priorityLanes) Type() string {
+	return pl.lanes[PriorityNormal].Type()
+}
+
+//Close stops prefetch goroutines (held events are pushed back into lanes) and closes all lanes
+func (pl *priorityLanes) Close() error {
+	close(pl.closed)
+	pl.wg.Wait()
+
+	var err error
+	for _, priority := range priorities {
+		if closeErr := pl.lanes[priority].Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/queue"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLanes() map[string]queue.PollingQueue {
+	return map[string]queue.PollingQueue{
+		PriorityHigh:   queue.NewInMemory(100).(queue.PollingQueue),
+		PriorityNormal: queue.NewInMemory(100).(queue.PollingQueue),
+		PriorityLow:    queue.NewInMemory(100).(queue.PollingQueue),
+	}
+}
+
+func TestPriorityLanesOrder(t *testing.T) {
+	lanes := newTestLanes()
+	tokenPriority := func(tokenID string) string {
+		if tokenID == "bulk" {
+			return PriorityLow
+		}
+		return ""
+	}
+	pl := newPriorityLanes("test", lanes, tokenPriority)
+	defer pl.Close()
+
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "low"}, TokenID: "bulk"}))
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "normal"}, TokenID: "token"}))
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "high"}, TokenID: "bulk", Priority: PriorityHigh}))
+
+	//wait for prefetching
+	require.Eventually(t, func() bool { return pl.Size() == 0 }, time.Second, 10*time.Millisecond)
+
+	for _, expected := range []string{"high", "normal", "low"} {
+		v, err := pl.Pop()
+		require.NoError(t, err)
+		require.Equal(t, expected, v.(*TimedEvent).Payload["id"])
+	}
+}
+
+//sizeOnCloseLane records the lane size on closing
+type sizeOnCloseLane struct {
+	queue.PollingQueue
+
+	sizeOnClose int64
+}
+
+func (l *sizeOnCloseLane) Close() error {
+	l.sizeOnClose = l.Size()
+	return l.PollingQueue.Close()
+}
+
+func TestPriorityLanesCloseKeepsPrefetchedEvents(t *testing.T) {
+	lanes := newTestLanes()
+	for priority, lane := range lanes {
+		lanes[priority] = &sizeOnCloseLane{PollingQueue: lane}
+	}
+	pl := newPriorityLanes("test", lanes, nil)
+
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "high"}, Priority: PriorityHigh}))
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "low"}, Priority: PriorityLow}))
+	require.Eventually(t, func() bool { return pl.Size() == 0 }, time.Second, 10*time.Millisecond)
+
+	//prefetched events are pushed back into lanes before closing (in-memory lanes report them as unprocessed)
+	require.Error(t, pl.Close())
+	require.Equal(t, int64(1), lanes[PriorityHigh].(*sizeOnCloseLane).sizeOnClose)
+	require.Equal(t, int64(0), lanes[PriorityNormal].(*sizeOnCloseLane).sizeOnClose)
+	require.Equal(t, int64(1), lanes[PriorityLow].(*sizeOnCloseLane).sizeOnClose)
+}
+
+func TestPriorityLanesAck(t *testing.T) {
+	lanes := newTestLanes()
+	ackLane := &ackQueueMock{PollingQueue: lanes[PriorityNormal]}
+	lanes[PriorityNormal] = ackLane
+	pl := newPriorityLanes("test", lanes, nil).(*priorityLanes)
+	defer pl.Close()
+
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "1"}}))
+	v, ack, err := pl.PopWithAck()
+	require.NoError(t, err)
+	require.Equal(t, "1", v.(*TimedEvent).Payload["id"])
+	require.Equal(t, 0, ackLane.acked)
+	ack()
+	require.Equal(t, 1, ackLane.acked)
+}
+
+func TestNativeQueueMovesPriorityField(t *testing.T) {
+	underlyingQueue := queue.NewInMemory(10)
+	nq, err := NewNativeQueue(queue.DestinationNamespace, "test", "destination1", underlyingQueue, nil, nil)
+	require.NoError(t, err)
+	defer nq.Close()
+
+	payload := map[string]interface{}{"id": "1", PriorityField: PriorityHigh}
+	nq.Consume(payload, "token1")
+	require.Equal(t, PriorityHigh, payload[PriorityField], "shared payload mustn't be changed")
+
+	v, err := underlyingQueue.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &TimedEvent{Payload: map[string]interface{}{"id": "1"}, DequeuedTime: v.(*TimedEvent).DequeuedTime, TokenID: "token1", Priority: PriorityHigh}, v)
+}
+
+func TestPriorityLanesPollDrainsLowPriorityFirst(t *testing.T) {
+	pl := newPriorityLanes("test", newTestLanes(), nil).(*priorityLanes)
+	defer pl.Close()
+
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "high"}, Priority: PriorityHigh}))
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "normal"}}))
+	require.NoError(t, pl.Push(&TimedEvent{Payload: map[string]interface{}{"id": "low"}, Priority: PriorityLow}))
+	require.Eventually(t, func() bool { return pl.Size() == 0 }, time.Second, 10*time.Millisecond)
+
+	for _, expected := range []string{"low", "normal", "high"} {
+		v, err := pl.Poll()
+		require.NoError(t, err)
+		require.Equal(t, expected, v.(*TimedEvent).Payload["id"])
+	}
+
+	//empty lanes don't block
+	_, err := pl.Poll()
+	require.Equal(t, queue.ErrQueueEmpty, err)
+}
+
+func TestNativeQueueDropOldestWithPriorityLanes(t *testing.T) {
+	pl := newPriorityLanes("test", newTestLanes(), nil)
+	nq, err := NewNativeQueue(queue.DestinationNamespace, "test", "destination1", pl, &QueueLimits{MaxSize: 2, Policy: QueueOverflowDropOldest}, nil)
+	require.NoError(t, err)
+	defer nq.Close()
+
+	nq.Consume(map[string]interface{}{"id": "high", PriorityField: PriorityHigh}, "token1")
+	nq.Consume(map[string]interface{}{"id": "low", PriorityField: PriorityLow}, "token1")
+	require.Eventually(t, func() bool { return pl.Size() == 0 }, time.Second, 10*time.Millisecond)
+	nq.Consume(map[string]interface{}{"id": "normal"}, "token1")
+
+	for _, expected := range []string{"high", "normal"} {
+		event, _, _, err := nq.DequeueBlock()
+		require.NoError(t, err)
+		require.Equal(t, expected, event["id"])
+	}
+}
@@ -1,5 +1,7 @@
 package events
 
+import "github.com/jitsucom/jitsu/server/useragent"
+
 const (
 	apiPreprocessorType     = "api"
 	jsPreprocessorType      = "js"
@@ -16,6 +18,8 @@ type RequestContext struct {
 	JitsuAnonymousID    string `json:"jitsu_anonymous_id,omitempty"`
 	HashedAnonymousID   string `json:"hashed_anonymous_id,omitempty"`
 	CookiesLawCompliant bool   `json:"cookie_laws_compliant,omitempty"`
+
+	ClientHints *useragent.ClientHints `json:"client_hints,omitempty"`
 }
 
 // Processor is used in preprocessing and postprocessing events before and after consuming(storing)
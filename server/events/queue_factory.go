@@ -15,6 +15,8 @@ type TimedEvent struct {
 	Payload      map[string]interface{}
 	DequeuedTime time.Time
 	TokenID      string
+	//Priority is a PriorityField value of the event (the field is removed from the payload)
+	Priority string `json:",omitempty"`
 }
 
 type DummyQueue struct {
@@ -42,25 +44,166 @@ type Queue interface {
 	DequeueBlock() (Event, time.Time, string, error)
 }
 
+//LagQueue is a Queue which reports its lag (e.g. NativeQueue)
+type LagQueue interface {
+	Queue
+	Lag() QueueLag
+}
+
+//QueueLag is an amount of queued events and the age of the oldest one. Size is -1 if it is unknown
+type QueueLag struct {
+	Size           int64
+	OldestEventAge time.Duration
+}
+
+//AckQueue is a Queue which removes dequeued events only after acknowledgement (e.g. Redis Streams queue)
+type AckQueue interface {
+	Queue
+	//DequeueBlockWithAck returns the next event and a func which must be called after the event is processed
+	DequeueBlockWithAck() (Event, time.Time, string, func(), error)
+}
+
 type QueueFactory struct {
 	redisPool        *meta.RedisPool
 	redisReadTimeout time.Duration
+
+	//streamsConsumerName isn't empty if destinations events queues are Redis Streams
+	streamsConsumerName string
+
+	//defaultLimits is used for destinations without own queue limits (nil - queue size isn't limited)
+	defaultLimits *QueueLimits
+	spillStorage  SpillStorage
+
+	//priorityLanes enables separated underlying queues for high/normal/low priority events
+	priorityLanes bool
+	tokenPriority func(tokenID string) string
+
+	//partitions isn't nil if destinations events queues are partitioned between server instances
+	partitions *PartitionsConfig
 }
 
 func NewQueueFactory(redisPool *meta.RedisPool, redisReadTimeout time.Duration) *QueueFactory {
 	return &QueueFactory{redisPool: redisPool, redisReadTimeout: redisReadTimeout}
 }
 
-func (qf *QueueFactory) CreateEventsQueue(subsystem, identifier string) (Queue, error) {
+//WithRedisStreams configures factory to create destinations events queues based on Redis Streams
+//with the consumer name (must be unique per server instance)
+func (qf *QueueFactory) WithRedisStreams(consumerName string) *QueueFactory {
+	qf.streamsConsumerName = consumerName
+	return qf
+}
+
+//WithLimits configures default destinations queues size limit and overflow policy
+//spillStorage is required for QueueOverflowSpill policy
+func (qf *QueueFactory) WithLimits(defaultLimits *QueueLimits, spillStorage SpillStorage) *QueueFactory {
+	qf.defaultLimits = defaultLimits
+	qf.spillStorage = spillStorage
+	return qf
+}
+
+//WithPriorityLanes configures factory to create destinations events queues with high/normal/low priority lanes
+//tokenPriority returns API key priority
+func (qf *QueueFactory) WithPriorityLanes(tokenPriority func(tokenID string) string) *QueueFactory {
+	qf.priorityLanes = true
+	qf.tokenPriority = tokenPriority
+	return qf
+}
+
+//WithPartitions configures factory to create destinations events queues partitioned by the event key.
+//Partitions are shared between server instances with the coordination service
+func (qf *QueueFactory) WithPartitions(config *PartitionsConfig) *QueueFactory {
+	qf.partitions = config
+	return qf
+}
+
+//CreateEventsQueue returns destination events queue. limits might be nil (factory default limits are used)
+func (qf *QueueFactory) CreateEventsQueue(subsystem, identifier string, limits *QueueLimits) (Queue, error) {
+	if limits == nil {
+		limits = qf.defaultLimits
+	}
+	if limits != nil {
+		if err := limits.Validate(); err != nil {
+			return nil, err
+		}
+		if limits.Policy == QueueOverflowSpill && qf.spillStorage == nil {
+			return nil, fmt.Errorf("queue overflow policy %s requires events.queue.spill.s3 configuration", QueueOverflowSpill)
+		}
+	}
+
 	var underlyingQueue queue.Queue
-	if qf.redisPool != nil {
-		logging.Infof("[%s] initializing redis events queue", identifier)
-		underlyingQueue = queue.NewRedis(queue.DestinationNamespace, identifier, qf.redisPool, TimedEventBuilder, qf.redisReadTimeout)
+	if qf.priorityLanes {
+		lanes := map[string]queue.PollingQueue{}
+		for priority, postfix := range map[string]string{PriorityHigh: highPriorityLanePostfix, PriorityNormal: "", PriorityLow: lowPriorityLanePostfix} {
+			lane, err := qf.createDestinationQueue(identifier + postfix)
+			if err == nil {
+				pollingLane, ok := lane.(queue.PollingQueue)
+				if !ok {
+					_ = lane.Close()
+					err = fmt.Errorf("%s queue doesn't support priority lanes", lane.Type())
+				} else {
+					lanes[priority] = pollingLane
+				}
+			}
+			if err != nil {
+				for _, created := range lanes {
+					_ = created.Close()
+				}
+				return nil, err
+			}
+		}
+		underlyingQueue = newPriorityLanes(identifier, lanes, qf.tokenPriority)
 	} else {
-		logging.Infof("[%s] initializing inmemory events queue", identifier)
-		underlyingQueue = queue.NewInMemory(1_000_000)
+		var err error
+		underlyingQueue, err = qf.createDestinationQueue(identifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewNativeQueue(queue.DestinationNamespace, subsystem, identifier, underlyingQueue, limits, qf.spillStorage)
+}
+
+//createDestinationQueue returns partitioned queue if partitions are configured or underlying queue otherwise
+func (qf *QueueFactory) createDestinationQueue(identifier string) (queue.Queue, error) {
+	if qf.partitions == nil {
+		return qf.createUnderlyingQueue(identifier)
 	}
-	return NewNativeQueue(queue.DestinationNamespace, subsystem, identifier, underlyingQueue)
+
+	partitions := make([]queue.PollingQueue, 0, qf.partitions.Count)
+	for i := 0; i < qf.partitions.Count; i++ {
+		partition, err := qf.createUnderlyingQueue(partitionIdentifier(identifier, i))
+		if err == nil {
+			pollingPartition, ok := partition.(queue.PollingQueue)
+			if !ok {
+				_ = partition.Close()
+				err = fmt.Errorf("%s queue doesn't support partitioning", partition.Type())
+			} else {
+				partitions = append(partitions, pollingPartition)
+			}
+		}
+		if err != nil {
+			for _, created := range partitions {
+				_ = created.Close()
+			}
+			return nil, err
+		}
+	}
+
+	logging.Infof("[%s] initializing partitioned events queue with %d partitions", identifier, qf.partitions.Count)
+	return newPartitionedQueue(identifier, partitions, qf.partitions), nil
+}
+
+//createUnderlyingQueue returns redis streams, redis or inmemory queue
+func (qf *QueueFactory) createUnderlyingQueue(identifier string) (queue.Queue, error) {
+	if qf.redisPool != nil && qf.streamsConsumerName != "" {
+		logging.Infof("[%s] initializing redis streams events queue", identifier)
+		return queue.NewRedisStreams(queue.DestinationNamespace, identifier, qf.streamsConsumerName, qf.redisPool, TimedEventBuilder, qf.redisReadTimeout)
+	} else if qf.redisPool != nil {
+		logging.Infof("[%s] initializing redis events queue", identifier)
+		return queue.NewRedis(queue.DestinationNamespace, identifier, qf.redisPool, TimedEventBuilder, qf.redisReadTimeout), nil
+	}
+
+	logging.Infof("[%s] initializing inmemory events queue", identifier)
+	return queue.NewInMemory(1_000_000), nil
 }
 
 func (qf *QueueFactory) CreateHTTPQueue(identifier string, serializationModelBuilder func() interface{}) queue.Queue {
@@ -0,0 +1,192 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"go.uber.org/atomic"
+)
+
+const (
+	//QueueOverflowDropNew - new events which don't fit into the queue are skipped (with warning in logs)
+	QueueOverflowDropNew = "drop_new"
+	//QueueOverflowDropOldest - the oldest events are removed from the queue to free space for new ones
+	QueueOverflowDropOldest = "drop_oldest"
+	//QueueOverflowBlock - producers wait until there is free space in the queue
+	QueueOverflowBlock = "block"
+	//QueueOverflowSpill - new events which don't fit into the queue are uploaded to the spill storage (e.g. S3)
+	QueueOverflowSpill = "spill"
+
+	//queueWarningThreshold is a queue usage ratio when warnings are written
+	queueWarningThreshold = 0.8
+
+	//queueBlockTimeout is a max time of waiting for free space in the queue with QueueOverflowBlock policy
+	queueBlockTimeout = 10 * time.Second
+
+	spillBatchSize     = 10_000
+	spillFlushInterval = 30 * time.Second
+)
+
+//SpillStorage stores events which don't fit into the queue. adapters.S3 implements it
+type SpillStorage interface {
+	UploadBytes(fileName string, fileBytes []byte) error
+}
+
+//QueueLimits is a destination queue size limit and overflow policy
+type QueueLimits struct {
+	MaxSize int64
+	Policy  string
+}
+
+//Validate returns err if policy is unknown
+func (ql *QueueLimits) Validate() error {
+	switch ql.Policy {
+	case QueueOverflowDropNew, QueueOverflowDropOldest, QueueOverflowBlock, QueueOverflowSpill:
+		return nil
+	default:
+		return fmt.Errorf("unknown queue overflow policy: %s. Supported: [%s, %s, %s, %s]", ql.Policy,
+			QueueOverflowDropNew, QueueOverflowDropOldest, QueueOverflowBlock, QueueOverflowSpill)
+	}
+}
+
+//queueLimiter keeps approximate queue size and applies QueueLimits
+type queueLimiter struct {
+	identifier string
+	subsystem  string
+	limits     *QueueLimits
+
+	size   *atomic.Int64
+	warned *atomic.Bool
+	spill  *spillBuffer
+}
+
+func newQueueLimiter(subsystem, identifier string, limits *QueueLimits, spillStorage SpillStorage, initialSize int64) *queueLimiter {
+	ql := &queueLimiter{
+		identifier: identifier,
+		subsystem:  subsystem,
+		limits:     limits,
+		size:       atomic.NewInt64(initialSize),
+		warned:     atomic.NewBool(false),
+	}
+	if limits.Policy == QueueOverflowSpill {
+		ql.spill = newSpillBuffer(identifier, spillStorage)
+	}
+
+	return ql
+}
+
+//full returns true if queue size reached the limit. Writes warning when the size crosses queueWarningThreshold
+func (ql *queueLimiter) full() bool {
+	size := ql.size.Load()
+	if float64(size) >= float64(ql.limits.MaxSize)*queueWarningThreshold {
+		if ql.warned.CAS(false, true) {
+			logging.Warnf("[%s] events queue size %d is close to the limit %d. Overflow policy: %s", ql.identifier, size, ql.limits.MaxSize, ql.limits.Policy)
+		}
+	} else {
+		ql.warned.Store(false)
+	}
+
+	return size >= ql.limits.MaxSize
+}
+
+//overflow counts overflowed event
+func (ql *queueLimiter) overflow() {
+	metrics.QueueOverflowEvent(ql.subsystem, ql.identifier, ql.limits.Policy)
+}
+
+func (ql *queueLimiter) close() {
+	if ql.spill != nil {
+		ql.spill.close()
+	}
+}
+
+//spillBuffer collects events as JSON lines and uploads them to SpillStorage by batches
+type spillBuffer struct {
+	sync.Mutex
+	identifier string
+	storage    SpillStorage
+
+	buffer *bytes.Buffer
+	count  int
+
+	closed chan struct{}
+}
+
+func newSpillBuffer(identifier string, storage SpillStorage) *spillBuffer {
+	sb := &spillBuffer{
+		identifier: identifier,
+		storage:    storage,
+		buffer:     &bytes.Buffer{},
+		closed:     make(chan struct{}),
+	}
+	safego.Run(sb.startFlusher)
+	return sb
+}
+
+func (sb *spillBuffer) add(payload map[string]interface{}) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		logSkippedEvent(payload, fmt.Errorf("Error serializing spilled event: %v", err))
+		return
+	}
+
+	sb.Lock()
+	sb.buffer.Write(b)
+	sb.buffer.WriteByte('\n')
+	sb.count++
+	full := sb.count >= spillBatchSize
+	sb.Unlock()
+
+	if full {
+		sb.flush()
+	}
+}
+
+func (sb *spillBuffer) startFlusher() {
+	ticker := time.NewTicker(spillFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sb.closed:
+			sb.flush()
+			return
+		case <-ticker.C:
+			sb.flush()
+		}
+	}
+}
+
+//flush uploads buffered events into spill storage as spill-$identifier-$time.log file
+//spilled events aren't replayed automatically: the file should be downloaded onto a server and replayed with the
+//fallback replay endpoint /api/v1/replay (absolute file_name, destination_id and file_format: raw_json)
+func (sb *spillBuffer) flush() {
+	sb.Lock()
+	if sb.count == 0 {
+		sb.Unlock()
+		return
+	}
+	payload := sb.buffer.Bytes()
+	count := sb.count
+	sb.buffer = &bytes.Buffer{}
+	sb.count = 0
+	sb.Unlock()
+
+	fileName := fmt.Sprintf("spill-%s-%s.log", sb.identifier, timestamp.Now().UTC().Format("2006-01-02T15-04-05.000"))
+	if err := sb.storage.UploadBytes(fileName, payload); err != nil {
+		logging.SystemErrorf("[%s] Error uploading %d spilled events into %s: %v", sb.identifier, count, fileName, err)
+		return
+	}
+
+	logging.Infof("[%s] %d events which didn't fit into the queue were spilled into %s", sb.identifier, count, fileName)
+}
+
+func (sb *spillBuffer) close() {
+	close(sb.closed)
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type spillStorageMock struct {
+	sync.Mutex
+	files map[string][]byte
+}
+
+func (ssm *spillStorageMock) UploadBytes(fileName string, fileBytes []byte) error {
+	ssm.Lock()
+	defer ssm.Unlock()
+	ssm.files[fileName] = fileBytes
+	return nil
+}
+
+func TestQueueLimitsDropNew(t *testing.T) {
+	q, err := NewQueueFactory(nil, 0).CreateEventsQueue("test", "dest1", &QueueLimits{MaxSize: 2, Policy: QueueOverflowDropNew})
+	require.NoError(t, err)
+	defer q.Close()
+
+	for i := 0; i < 5; i++ {
+		q.Consume(map[string]interface{}{"id": i}, "token")
+	}
+
+	nq := q.(*NativeQueue)
+	require.Equal(t, int64(2), nq.queue.Size())
+
+	event, _, _, err := q.DequeueBlock()
+	require.NoError(t, err)
+	require.EqualValues(t, 0, event["id"])
+}
+
+func TestQueueLimitsDropOldest(t *testing.T) {
+	q, err := NewQueueFactory(nil, 0).CreateEventsQueue("test", "dest1", &QueueLimits{MaxSize: 10, Policy: QueueOverflowDropOldest})
+	require.NoError(t, err)
+	defer q.Close()
+
+	wg := sync.WaitGroup{}
+	for producer := 0; producer < 10; producer++ {
+		wg.Add(1)
+		go func(producer int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				q.Consume(map[string]interface{}{"producer": producer, "id": i}, "token")
+			}
+		}(producer)
+	}
+	wg.Wait()
+
+	nq := q.(*NativeQueue)
+	require.Equal(t, int64(10), nq.queue.Size())
+	require.Equal(t, int64(10), nq.limiter.size.Load())
+
+	//the oldest events are dropped synchronously: the latest event is kept
+	q.Consume(map[string]interface{}{"id": "last"}, "token")
+	require.Equal(t, int64(10), nq.queue.Size())
+	for i := 0; i < 9; i++ {
+		_, _, _, err := q.DequeueBlock()
+		require.NoError(t, err)
+	}
+	event, _, _, err := q.DequeueBlock()
+	require.NoError(t, err)
+	require.Equal(t, "last", event["id"])
+}
+
+func TestQueueLimitsSpill(t *testing.T) {
+	storage := &spillStorageMock{files: map[string][]byte{}}
+	q, err := NewQueueFactory(nil, 0).
+		WithLimits(&QueueLimits{MaxSize: 1, Policy: QueueOverflowSpill}, storage).
+		CreateEventsQueue("test", "dest1", nil)
+	require.NoError(t, err)
+	defer q.Close()
+
+	for i := 0; i < 3; i++ {
+		q.Consume(map[string]interface{}{"id": i}, "token")
+	}
+
+	nq := q.(*NativeQueue)
+	require.Equal(t, int64(1), nq.queue.Size())
+
+	nq.limiter.spill.flush()
+	storage.Lock()
+	defer storage.Unlock()
+	require.Len(t, storage.files, 1)
+	for fileName, payload := range storage.files {
+		require.True(t, strings.HasPrefix(fileName, "spill-dest1-"), fileName)
+		require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", string(payload))
+	}
+}
+
+func TestQueueLimitsValidation(t *testing.T) {
+	_, err := NewQueueFactory(nil, 0).CreateEventsQueue("test", "dest1", &QueueLimits{MaxSize: 1, Policy: "unknown"})
+	require.Error(t, err)
+
+	_, err = NewQueueFactory(nil, 0).CreateEventsQueue("test", "dest1", &QueueLimits{MaxSize: 1, Policy: QueueOverflowSpill})
+	require.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package events
+
+import "sync"
+
+var destinationQueues = &queuesRegistry{queues: map[string]*NativeQueue{}}
+
+//queuesRegistry keeps all destinations events queues of the server
+type queuesRegistry struct {
+	sync.RWMutex
+	queues map[string]*NativeQueue
+}
+
+func (qr *queuesRegistry) register(identifier string, q *NativeQueue) {
+	qr.Lock()
+	qr.queues[identifier] = q
+	qr.Unlock()
+}
+
+func (qr *queuesRegistry) unregister(identifier string, q *NativeQueue) {
+	qr.Lock()
+	//destination might be already re-created with a new queue
+	if qr.queues[identifier] == q {
+		delete(qr.queues, identifier)
+	}
+	qr.Unlock()
+}
+
+//DestinationQueueSizes returns current sizes (including buffers) of destinations events queues per destination ID.
+//Shared queues (e.g. Redis) sizes are cluster-wide
+func DestinationQueueSizes() map[string]int64 {
+	destinationQueues.RLock()
+	queues := make(map[string]*NativeQueue, len(destinationQueues.queues))
+	for identifier, q := range destinationQueues.queues {
+		queues[identifier] = q
+	}
+	destinationQueues.RUnlock()
+
+	sizes := make(map[string]int64, len(queues))
+	for identifier, q := range queues {
+		if size := q.queue.Size(); size >= 0 {
+			sizes[identifier] = size + q.queue.BufferSize()
+		}
+	}
+	return sizes
+}
+
+//DestinationQueueLags returns lags of destinations events queues per destination ID. Shared queues (e.g. Redis) sizes are cluster-wide
+func DestinationQueueLags() map[string]QueueLag {
+	destinationQueues.RLock()
+	queues := make(map[string]*NativeQueue, len(destinationQueues.queues))
+	for identifier, q := range destinationQueues.queues {
+		queues[identifier] = q
+	}
+	destinationQueues.RUnlock()
+
+	lags := make(map[string]QueueLag, len(queues))
+	for identifier, q := range queues {
+		lags[identifier] = q.Lag()
+	}
+	return lags
+}
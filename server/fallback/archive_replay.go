@@ -0,0 +1,401 @@
+package fallback
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	//archive/$date/incoming.tok=$tokenID-$time.log.gz
+	incomingArchiveFileMask = "*/incoming.tok=%s-20*.log*"
+	//archive/$date/streaming-archive.dst=$destinationID-$time.log.gz
+	streamingArchiveFileMask = "*/streaming-archive.dst=%s-20*.log*"
+
+	//ArchiveReplayRunning - task is in progress
+	ArchiveReplayRunning = "running"
+	//ArchiveReplaySucceeded - all archived events from the time range have been replayed
+	ArchiveReplaySucceeded = "succeeded"
+	//ArchiveReplayFailed - task has been stopped because of an error
+	ArchiveReplayFailed = "failed"
+	//ArchiveReplayCanceled - task has been canceled by request
+	ArchiveReplayCanceled = "canceled"
+)
+
+var errArchiveReplayCanceled = errors.New("archive replay has been canceled")
+
+var archiveFileDateExtractRegexp = regexp.MustCompile("-(\\d{4}-\\d{2}-\\d{2}T\\d{2}-\\d{2}-\\d{2}(\\.\\d{3})?)\\.log")
+
+// ArchiveReplayRequest is a request for replaying archived events from the time range into destinations
+type ArchiveReplayRequest struct {
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	DestinationIDs []string  `json:"destination_ids"`
+	//TokenIDs is an optional filter of incoming archive files
+	TokenIDs []string `json:"token_ids,omitempty"`
+	//SourceDestinationID is an optional streaming destination which archive is used instead of incoming events archive
+	SourceDestinationID string `json:"source_destination_id,omitempty"`
+	//S3 is an optional bucket with a copy of the archive directory which is used instead of the local archive
+	S3 *adapters.S3Config `json:"s3,omitempty"`
+	//GoogleCloudStorage is an optional bucket with a copy of the archive directory which is used instead of the local archive
+	GoogleCloudStorage *adapters.GoogleConfig `json:"google_cloud_storage,omitempty"`
+}
+
+// Validate returns err if the request is invalid
+func (arr *ArchiveReplayRequest) Validate() error {
+	if arr.Start.IsZero() || arr.End.IsZero() {
+		return errors.New("start and end are required parameters")
+	}
+	if !arr.Start.Before(arr.End) {
+		return errors.New("start must be before end")
+	}
+	if len(arr.DestinationIDs) == 0 {
+		return errors.New("destination_ids can't be empty")
+	}
+	if arr.SourceDestinationID != "" && len(arr.TokenIDs) > 0 {
+		return errors.New("token_ids filter can't be used together with source_destination_id")
+	}
+	if arr.S3 != nil && arr.GoogleCloudStorage != nil {
+		return errors.New("s3 and google_cloud_storage can't be used together")
+	}
+
+	return nil
+}
+
+// ArchiveReplayTask is a status of archived events replay
+type ArchiveReplayTask struct {
+	ID             string                `json:"id"`
+	Request        *ArchiveReplayRequest `json:"request"`
+	Archive        string                `json:"archive"`
+	Status         string                `json:"status"`
+	FilesTotal     int                   `json:"files_total"`
+	FilesProcessed int                   `json:"files_processed"`
+	EventsReplayed int64                 `json:"events_replayed"`
+	Error          string                `json:"error,omitempty"`
+	StartedAt      time.Time             `json:"started_at"`
+	FinishedAt     *time.Time            `json:"finished_at,omitempty"`
+
+	canceled chan struct{}
+}
+
+// archiveReplayTasks keeps all archive replay tasks of the current server instance
+type archiveReplayTasks struct {
+	sync.RWMutex
+	tasks map[string]*ArchiveReplayTask
+}
+
+// ReplayArchive validates request and starts replaying archived events in background
+// returns created task
+func (s *Service) ReplayArchive(req *ArchiveReplayRequest) (*ArchiveReplayTask, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, destinationID := range req.DestinationIDs {
+		if _, err := s.getEventsConsumer(destinationID); err != nil {
+			return nil, err
+		}
+	}
+
+	storage, err := s.openArchiveStorage(req)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := findArchiveFiles(storage, req)
+	if err != nil {
+		_ = storage.Close()
+		return nil, err
+	}
+
+	//storage credentials aren't exposed in tasks statuses
+	taskRequest := *req
+	taskRequest.S3 = nil
+	taskRequest.GoogleCloudStorage = nil
+	task := &ArchiveReplayTask{
+		ID:         uuid.New().String(),
+		Request:    &taskRequest,
+		Archive:    storage.Location(),
+		Status:     ArchiveReplayRunning,
+		FilesTotal: len(files),
+		StartedAt:  timestamp.Now().UTC(),
+		canceled:   make(chan struct{}),
+	}
+	s.archiveReplayTasks.Lock()
+	s.archiveReplayTasks.tasks[task.ID] = task
+	s.archiveReplayTasks.Unlock()
+
+	logging.Infof("[%s] Archive replay of %d files from %s into %v from %s to %s has been started", task.ID, len(files), task.Archive,
+		req.DestinationIDs, timestamp.ToISOFormat(req.Start), timestamp.ToISOFormat(req.End))
+	safego.Run(func() {
+		s.runArchiveReplay(task, storage, files)
+	})
+
+	return s.GetArchiveReplayTask(task.ID), nil
+}
+
+// GetArchiveReplayTask returns a copy of the task or nil if it doesn't exist
+func (s *Service) GetArchiveReplayTask(taskID string) *ArchiveReplayTask {
+	s.archiveReplayTasks.RLock()
+	defer s.archiveReplayTasks.RUnlock()
+
+	task, ok := s.archiveReplayTasks.tasks[taskID]
+	if !ok {
+		return nil
+	}
+
+	taskCopy := *task
+	return &taskCopy
+}
+
+// GetArchiveReplayTasks returns copies of all tasks sorted by start time
+func (s *Service) GetArchiveReplayTasks() []*ArchiveReplayTask {
+	s.archiveReplayTasks.RLock()
+	defer s.archiveReplayTasks.RUnlock()
+
+	tasks := make([]*ArchiveReplayTask, 0, len(s.archiveReplayTasks.tasks))
+	for _, task := range s.archiveReplayTasks.tasks {
+		taskCopy := *task
+		tasks = append(tasks, &taskCopy)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].StartedAt.Before(tasks[j].StartedAt)
+	})
+
+	return tasks
+}
+
+// CancelArchiveReplayTask stops the running task and returns a copy of it
+// returns nil if the task doesn't exist
+func (s *Service) CancelArchiveReplayTask(taskID string) (*ArchiveReplayTask, error) {
+	s.archiveReplayTasks.Lock()
+	defer s.archiveReplayTasks.Unlock()
+
+	task, ok := s.archiveReplayTasks.tasks[taskID]
+	if !ok {
+		return nil, nil
+	}
+	if task.Status != ArchiveReplayRunning {
+		return nil, fmt.Errorf("Archive replay task %s has already been finished with status: %s", taskID, task.Status)
+	}
+
+	close(task.canceled)
+	s.finishArchiveReplay(task, ArchiveReplayCanceled, nil)
+	logging.Infof("[%s] Archive replay has been canceled", task.ID)
+
+	taskCopy := *task
+	return &taskCopy, nil
+}
+
+// runArchiveReplay reads archive files one by one and sends events from the time range into all destinations
+func (s *Service) runArchiveReplay(task *ArchiveReplayTask, storage archiveStorage, files []string) {
+	defer storage.Close()
+
+	var eventsReplayed int64
+	for _, key := range files {
+		select {
+		case <-task.canceled:
+			return
+		default:
+		}
+
+		replayed, err := s.replayArchiveFile(task, storage, key)
+		eventsReplayed += replayed
+
+		s.archiveReplayTasks.Lock()
+		task.EventsReplayed = eventsReplayed
+		if err == errArchiveReplayCanceled {
+			s.archiveReplayTasks.Unlock()
+			return
+		}
+		if err != nil {
+			s.finishArchiveReplay(task, ArchiveReplayFailed, err)
+			s.archiveReplayTasks.Unlock()
+			logging.Errorf("[%s] Archive replay has been failed: %v", task.ID, err)
+			return
+		}
+		task.FilesProcessed++
+		s.archiveReplayTasks.Unlock()
+	}
+
+	s.archiveReplayTasks.Lock()
+	s.finishArchiveReplay(task, ArchiveReplaySucceeded, nil)
+	s.archiveReplayTasks.Unlock()
+	logging.Infof("[%s] Archive replay has been finished: %d events have been replayed", task.ID, eventsReplayed)
+}
+
+// finishArchiveReplay sets the final status of the running task. Must be called under archiveReplayTasks lock
+func (s *Service) finishArchiveReplay(task *ArchiveReplayTask, status string, err error) {
+	if task.Status != ArchiveReplayRunning {
+		return
+	}
+
+	finishedAt := timestamp.Now().UTC()
+	task.Status = status
+	task.FinishedAt = &finishedAt
+	if err != nil {
+		task.Error = err.Error()
+	}
+}
+
+// replayArchiveFile sends events from the file which are in the request time range into destinations
+// returns amount of replayed events. Returns errArchiveReplayCanceled if the task has been canceled
+func (s *Service) replayArchiveFile(task *ArchiveReplayTask, storage archiveStorage, key string) (int64, error) {
+	b, err := storage.Read(key)
+	if err != nil {
+		return 0, err
+	}
+
+	objects, err := ExtractEvents(b, true, true)
+	if err != nil {
+		return 0, fmt.Errorf("Error parsing archive file %s: %v", key, err)
+	}
+
+	req := task.Request
+	var replayed int64
+	for _, object := range objects {
+		select {
+		case <-task.canceled:
+			return replayed, errArchiveReplayCanceled
+		default:
+		}
+
+		eventTime, ok := extractEventTime(object)
+		if !ok || eventTime.Before(req.Start) || eventTime.After(req.End) {
+			continue
+		}
+
+		var tokenID string
+		if apiTokenKey, ok := object[enrichment.ApiTokenKey]; ok {
+			tokenID = appconfig.Instance.AuthorizationService.GetTokenID(fmt.Sprint(apiTokenKey))
+		}
+
+		needCopyEvent := len(req.DestinationIDs) > 1
+		for _, destinationID := range req.DestinationIDs {
+			eventsConsumer, err := s.getEventsConsumer(destinationID)
+			if err != nil {
+				return replayed, err
+			}
+
+			event := object
+			if needCopyEvent {
+				event = events.Event(object).Clone()
+			}
+			eventsConsumer.Consume(event, tokenID)
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// getEventsConsumer returns events consumer of initialized not staged destination
+func (s *Service) getEventsConsumer(destinationID string) (events.Consumer, error) {
+	storageProxy, ok := s.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] wasn't found", destinationID)
+	}
+
+	storage, ok := storageProxy.Get()
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] hasn't been initialized yet", destinationID)
+	}
+	if storage.IsStaging() {
+		return nil, fmt.Errorf("Error running replay for destination [%s] in staged mode, "+
+			"cannot be used to store data (only available for dry-run)", destinationID)
+	}
+
+	eventsConsumer, ok := s.destinationService.GetEventsConsumerByDestinationID(destinationID)
+	if !ok {
+		return nil, fmt.Errorf("Unable to find events consumer by destinationID: %s", destinationID)
+	}
+
+	return eventsConsumer, nil
+}
+
+// findArchiveFiles returns sorted archive files which might contain events from the request time range
+// archive file date is a file rotation time: the file contains events which were written before it
+func findArchiveFiles(storage archiveStorage, req *ArchiveReplayRequest) ([]string, error) {
+	var masks []string
+	if req.SourceDestinationID != "" {
+		masks = append(masks, fmt.Sprintf(streamingArchiveFileMask, req.SourceDestinationID))
+	} else if len(req.TokenIDs) > 0 {
+		for _, tokenID := range req.TokenIDs {
+			masks = append(masks, fmt.Sprintf(incomingArchiveFileMask, tokenID))
+		}
+	} else {
+		masks = append(masks, fmt.Sprintf(incomingArchiveFileMask, "*"))
+	}
+
+	var files []string
+	for _, mask := range masks {
+		matched, err := storage.Find(mask)
+		if err != nil {
+			return nil, fmt.Errorf("Error finding archive files by mask [%s] in %s: %v", mask, storage.Location(), err)
+		}
+
+		for _, key := range matched {
+			fileDate, ok := extractArchiveFileDate(path.Base(key))
+			if !ok {
+				logging.Warnf("Skipping archive file %s in replay: malformed name", key)
+				continue
+			}
+			//files are rotated at least once a day
+			if fileDate.Before(req.Start) || fileDate.After(req.End.Add(24*time.Hour)) {
+				continue
+			}
+			files = append(files, key)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func extractArchiveFileDate(fileName string) (time.Time, bool) {
+	regexResult := archiveFileDateExtractRegexp.FindStringSubmatch(fileName)
+	if len(regexResult) < 2 {
+		return time.Time{}, false
+	}
+
+	layout := "2006-01-02T15-04-05"
+	if regexResult[2] != "" {
+		layout += ".000"
+	}
+	fileDate, err := time.Parse(layout, regexResult[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return fileDate, true
+}
+
+func extractEventTime(object events.Event) (time.Time, bool) {
+	switch value := object[timestamp.Key].(type) {
+	case time.Time:
+		return value, true
+	case string:
+		if t, err := timestamp.ParseISOFormat(value); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, true
+		}
+		return time.Time{}, false
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,251 @@
+package fallback
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+type storageStub struct {
+	storages.Storage
+}
+
+func (ss *storageStub) IsStaging() bool { return false }
+
+type storageProxyStub struct {
+	storages.StorageProxy
+}
+
+func (sps *storageProxyStub) Get() (storages.Storage, bool) { return &storageStub{}, true }
+
+// consumerMock collects events. If release isn't nil, Consume waits for it
+type consumerMock struct {
+	sync.Mutex
+	events  []events.Event
+	release chan struct{}
+}
+
+func (cm *consumerMock) Consume(event map[string]interface{}, tokenID string) {
+	if cm.release != nil {
+		<-cm.release
+	}
+	cm.Lock()
+	defer cm.Unlock()
+	cm.events = append(cm.events, event)
+}
+
+func (cm *consumerMock) Close() error { return nil }
+
+func (cm *consumerMock) count() int {
+	cm.Lock()
+	defer cm.Unlock()
+	return len(cm.events)
+}
+
+type bucketStorageMock struct {
+	files map[string][]byte
+}
+
+func (bsm *bucketStorageMock) ListObjects(prefix string) ([]string, error) {
+	var keys []string
+	for key := range bsm.files {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (bsm *bucketStorageMock) DownloadBytes(key string) ([]byte, error) {
+	b, ok := bsm.files[key]
+	if !ok {
+		return nil, fmt.Errorf("%s doesn't exist", key)
+	}
+	return b, nil
+}
+
+func (bsm *bucketStorageMock) Close() error { return nil }
+
+func newArchiveReplayTestService(t *testing.T, consumer events.Consumer) *Service {
+	archiveDir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(archiveDir) })
+
+	destinationService := destinations.NewTestService(map[string]*destinations.Unit{"dest1": destinations.NewTestUnit(&storageProxyStub{})},
+		destinations.TokenizedConsumers{}, destinations.TokenizedStorages{}, destinations.TokenizedIDs{},
+		map[string]events.Consumer{"dest1": consumer})
+
+	s := NewTestService()
+	s.archiveDir = archiveDir
+	s.destinationService = destinationService
+	return s
+}
+
+func archiveFilePayload(timestamps ...string) []byte {
+	buf := &bytes.Buffer{}
+	for i, ts := range timestamps {
+		buf.WriteString(fmt.Sprintf(`{"id":%d,"_timestamp":"%s"}`+"\n", i, ts))
+	}
+	return buf.Bytes()
+}
+
+func writeArchiveFile(t *testing.T, archiveDir, key string, payload []byte) {
+	require.NoError(t, os.MkdirAll(path.Join(archiveDir, path.Dir(key)), 0755))
+	if path.Ext(key) == ".gz" {
+		buf := &bytes.Buffer{}
+		writer := gzip.NewWriter(buf)
+		_, err := writer.Write(payload)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		payload = buf.Bytes()
+	}
+	require.NoError(t, ioutil.WriteFile(path.Join(archiveDir, key), payload, 0644))
+}
+
+func waitForArchiveReplay(t *testing.T, s *Service, taskID string) *ArchiveReplayTask {
+	var task *ArchiveReplayTask
+	require.Eventually(t, func() bool {
+		task = s.GetArchiveReplayTask(taskID)
+		return task.Status != ArchiveReplayRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return task
+}
+
+func TestArchiveReplayLifecycle(t *testing.T) {
+	consumer := &consumerMock{}
+	s := newArchiveReplayTestService(t, consumer)
+	writeArchiveFile(t, s.archiveDir, "2022-03-01/incoming.tok=token1-2022-03-01T10-00-00.000.log.gz",
+		archiveFilePayload("2022-03-01T09:00:00.000000Z", "2022-03-01T09:30:00.000000Z"))
+	//out of the time range event
+	writeArchiveFile(t, s.archiveDir, "2022-03-02/incoming.tok=token2-2022-03-02T10-00-00.000.log",
+		archiveFilePayload("2022-03-01T12:00:00.000000Z", "2022-03-02T09:00:00.000000Z"))
+	//out of the time range file
+	writeArchiveFile(t, s.archiveDir, "2022-02-01/incoming.tok=token1-2022-02-01T10-00-00.000.log",
+		archiveFilePayload("2022-03-01T09:00:00.000000Z"))
+
+	req := &ArchiveReplayRequest{
+		Start:          time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:            time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC),
+		DestinationIDs: []string{"dest1"},
+	}
+	task, err := s.ReplayArchive(req)
+	require.NoError(t, err)
+	require.Equal(t, 2, task.FilesTotal)
+	require.Equal(t, s.archiveDir, task.Archive)
+
+	task = waitForArchiveReplay(t, s, task.ID)
+	require.Equal(t, ArchiveReplaySucceeded, task.Status)
+	require.Equal(t, 2, task.FilesProcessed)
+	require.Equal(t, int64(3), task.EventsReplayed)
+	require.NotNil(t, task.FinishedAt)
+	require.Equal(t, 3, consumer.count())
+
+	require.Len(t, s.GetArchiveReplayTasks(), 1)
+	require.Nil(t, s.GetArchiveReplayTask("unknown"))
+
+	_, err = s.CancelArchiveReplayTask(task.ID)
+	require.Error(t, err, "finished task can't be canceled")
+}
+
+func TestArchiveReplayValidation(t *testing.T) {
+	s := newArchiveReplayTestService(t, &consumerMock{})
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.ReplayArchive(&ArchiveReplayRequest{Start: start, End: start, DestinationIDs: []string{"dest1"}})
+	require.Error(t, err)
+
+	_, err = s.ReplayArchive(&ArchiveReplayRequest{Start: start, End: start.Add(time.Hour), DestinationIDs: []string{"unknown"}})
+	require.Error(t, err)
+
+	_, err = s.ReplayArchive(&ArchiveReplayRequest{Start: start, End: start.Add(time.Hour), DestinationIDs: []string{"dest1"},
+		GoogleCloudStorage: &adapters.GoogleConfig{KeyFile: "workload_identity"}})
+	require.Error(t, err)
+
+	require.Empty(t, s.GetArchiveReplayTasks())
+}
+
+func TestArchiveReplayCancel(t *testing.T) {
+	consumer := &consumerMock{release: make(chan struct{})}
+	s := newArchiveReplayTestService(t, consumer)
+	writeArchiveFile(t, s.archiveDir, "2022-03-01/incoming.tok=token1-2022-03-01T10-00-00.000.log",
+		archiveFilePayload("2022-03-01T09:00:00.000000Z", "2022-03-01T09:10:00.000000Z", "2022-03-01T09:20:00.000000Z"))
+	writeArchiveFile(t, s.archiveDir, "2022-03-01/incoming.tok=token1-2022-03-01T11-00-00.000.log",
+		archiveFilePayload("2022-03-01T10:00:00.000000Z"))
+
+	task, err := s.ReplayArchive(&ArchiveReplayRequest{
+		Start:          time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:            time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC),
+		DestinationIDs: []string{"dest1"},
+	})
+	require.NoError(t, err)
+
+	//the first event is being consumed
+	consumer.release <- struct{}{}
+	canceled, err := s.CancelArchiveReplayTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, ArchiveReplayCanceled, canceled.Status)
+	require.NotNil(t, canceled.FinishedAt)
+	close(consumer.release)
+
+	//the replay stops after the event which is being consumed
+	require.Eventually(t, func() bool {
+		replayed := s.GetArchiveReplayTask(task.ID).EventsReplayed
+		return replayed > 0 && replayed == int64(consumer.count())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	task = s.GetArchiveReplayTask(task.ID)
+	require.Equal(t, ArchiveReplayCanceled, task.Status)
+	require.Empty(t, task.Error)
+	require.Equal(t, 0, task.FilesProcessed)
+	require.Less(t, consumer.count(), 4)
+
+	_, err = s.CancelArchiveReplayTask(task.ID)
+	require.Error(t, err)
+	canceled, err = s.CancelArchiveReplayTask("unknown")
+	require.NoError(t, err)
+	require.Nil(t, canceled)
+}
+
+func TestFindArchiveFilesInBucket(t *testing.T) {
+	storage := &bucketArchive{location: "s3://bucket/archive", storage: &bucketStorageMock{files: map[string][]byte{
+		"2022-03-01/incoming.tok=token1-2022-03-01T10-00-00.000.log.gz":         nil,
+		"2022-03-01/incoming.tok=token2-2022-03-01T11-00-00.000.log.gz":         nil,
+		"2022-03-01/streaming-archive.dst=dest1-2022-03-01T10-00-00.000.log.gz": nil,
+		"2022-03-01/incoming.tok=token1-malformed.log.gz":                       nil,
+		"2022-01-01/incoming.tok=token1-2022-01-01T10-00-00.000.log.gz":         nil,
+		"other/2022-03-01/incoming.tok=token1-2022-03-01T10-00-00.000.log.gz":   nil,
+	}}}
+	req := &ArchiveReplayRequest{
+		Start:          time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:            time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC),
+		DestinationIDs: []string{"dest1"},
+	}
+
+	files, err := findArchiveFiles(storage, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"2022-03-01/incoming.tok=token1-2022-03-01T10-00-00.000.log.gz",
+		"2022-03-01/incoming.tok=token2-2022-03-01T11-00-00.000.log.gz",
+	}, files)
+
+	req.TokenIDs = []string{"token2"}
+	files, err = findArchiveFiles(storage, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2022-03-01/incoming.tok=token2-2022-03-01T11-00-00.000.log.gz"}, files)
+
+	req.TokenIDs = nil
+	req.SourceDestinationID = "dest1"
+	files, err = findArchiveFiles(storage, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2022-03-01/streaming-archive.dst=dest1-2022-03-01T10-00-00.000.log.gz"}, files)
+}
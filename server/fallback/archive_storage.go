@@ -0,0 +1,150 @@
+package fallback
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+// archiveStorage is a storage of archived events files with the layout of the local archive directory:
+// $date/incoming.tok=$tokenID-$time.log.gz and $date/streaming-archive.dst=$destinationID-$time.log.gz
+type archiveStorage interface {
+	io.Closer
+	// Find returns sorted keys of files which match the mask (path.Match syntax)
+	Find(mask string) ([]string, error)
+	// Read returns file payload by key (decompressed if the file is gzipped)
+	Read(key string) ([]byte, error)
+	// Location returns human readable archive location
+	Location() string
+}
+
+// bucketStorage is a remote storage with files (S3, Google Cloud Storage)
+type bucketStorage interface {
+	io.Closer
+	ListObjects(prefix string) ([]string, error)
+	DownloadBytes(key string) ([]byte, error)
+}
+
+// localArchive is the events archive directory of the server (log.path/archive)
+type localArchive struct {
+	dir string
+}
+
+func (la *localArchive) Find(mask string) ([]string, error) {
+	matched, err := filepath.Glob(path.Join(la.dir, mask))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(matched))
+	for _, filePath := range matched {
+		key, err := filepath.Rel(la.dir, filePath)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (la *localArchive) Read(key string) ([]byte, error) {
+	return readFileBytes(path.Join(la.dir, key))
+}
+
+func (la *localArchive) Location() string {
+	return la.dir
+}
+
+func (la *localArchive) Close() error {
+	return nil
+}
+
+// bucketArchive is a copy of events archive directory in S3 or Google Cloud Storage bucket (under the configured folder)
+type bucketArchive struct {
+	location string
+	storage  bucketStorage
+
+	// keys are listed on the first Find call
+	keys []string
+}
+
+func (ba *bucketArchive) Find(mask string) ([]string, error) {
+	if ba.keys == nil {
+		keys, err := ba.storage.ListObjects("")
+		if err != nil {
+			return nil, err
+		}
+		ba.keys = keys
+	}
+
+	var matched []string
+	for _, key := range ba.keys {
+		ok, err := path.Match(mask, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, key)
+		}
+	}
+
+	sort.Strings(matched)
+	return matched, nil
+}
+
+func (ba *bucketArchive) Read(key string) ([]byte, error) {
+	b, err := ba.storage.DownloadBytes(key)
+	if err != nil {
+		return nil, fmt.Errorf("Error downloading archive file [%s] for replay: %v", key, err)
+	}
+
+	return decompressFileBytes(key, b)
+}
+
+func (ba *bucketArchive) Location() string {
+	return ba.location
+}
+
+func (ba *bucketArchive) Close() error {
+	return ba.storage.Close()
+}
+
+// openArchiveStorage returns the archive storage of the request: S3, Google Cloud Storage or the local archive directory
+func (s *Service) openArchiveStorage(req *ArchiveReplayRequest) (archiveStorage, error) {
+	switch {
+	case req.S3 != nil:
+		s3, err := adapters.NewS3(req.S3)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating S3 archive storage: %v", err)
+		}
+
+		return &bucketArchive{location: bucketLocation("s3", req.S3.Bucket, req.S3.Folder), storage: s3}, nil
+	case req.GoogleCloudStorage != nil:
+		if err := req.GoogleCloudStorage.Validate(); err != nil {
+			return nil, err
+		}
+		if err := req.GoogleCloudStorage.ValidateBatchMode(); err != nil {
+			return nil, err
+		}
+		gcs, err := adapters.NewGoogleCloudStorage(context.Background(), req.GoogleCloudStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return &bucketArchive{location: bucketLocation("gs", req.GoogleCloudStorage.Bucket, req.GoogleCloudStorage.Folder), storage: gcs}, nil
+	default:
+		return &localArchive{dir: s.archiveDir}, nil
+	}
+}
+
+func bucketLocation(scheme, bucket, folder string) string {
+	return scheme + "://" + strings.TrimSuffix(path.Join(bucket, folder), "/")
+}
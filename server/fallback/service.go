@@ -38,13 +38,15 @@ type Service struct {
 	destinationService *destinations.Service
 	usersRecognition   events.Recognition
 	archiver           *logfiles.Archiver
+	archiveDir         string
 
-	locks sync.Map
+	locks              sync.Map
+	archiveReplayTasks *archiveReplayTasks
 }
 
 // NewTestService returns test instance - only for tests
 func NewTestService() *Service {
-	return &Service{}
+	return &Service{archiveReplayTasks: &archiveReplayTasks{tasks: map[string]*ArchiveReplayTask{}}}
 }
 
 // NewService returns configured Service
@@ -62,6 +64,8 @@ func NewService(logEventsPath string, destinationService *destinations.Service,
 		destinationService: destinationService,
 		usersRecognition:   usersRecognition,
 		archiver:           logfiles.NewArchiver(fallbackPath, logArchiveEventPath),
+		archiveDir:         logArchiveEventPath,
+		archiveReplayTasks: &archiveReplayTasks{tasks: map[string]*ArchiveReplayTask{}},
 	}, nil
 }
 
@@ -86,7 +90,7 @@ func (s *Service) Replay(fileName, destinationID string, rawFile, skipMalformed
 	}
 	defer s.locks.Delete(fileName)
 
-	b, err := s.readFileBytes(filePath)
+	b, err := readFileBytes(filePath)
 	if err != nil {
 		return err
 	}
@@ -199,13 +203,18 @@ func (s *Service) GetFileStatuses(destinationsFilter map[string]bool) []*FileSta
 
 // readFileBytes reads file from the file system and returns byte payload or err if occurred
 // does unzip if file has been compressed
-func (s *Service) readFileBytes(filePath string) ([]byte, error) {
+func readFileBytes(filePath string) ([]byte, error) {
 	b, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("Error reading file [%s] for replay: %v", filePath, err)
 	}
 
-	if !strings.HasSuffix(filePath, ".gz") {
+	return decompressFileBytes(filePath, b)
+}
+
+// decompressFileBytes returns decompressed payload if the file is gzipped (.gz) or payload as is
+func decompressFileBytes(fileName string, b []byte) ([]byte, error) {
+	if !strings.HasSuffix(fileName, ".gz") {
 		return b, nil
 	}
 
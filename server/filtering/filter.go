@@ -0,0 +1,290 @@
+package filtering
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+// Filter is a compiled destination filter expression e.g. event_type in ("pageview", "conversion") && host != "staging.example.com".
+// It is evaluated natively before the destination transformation: events which don't match are skipped
+// without JavaScript runtime invocation
+type Filter struct {
+	expression string
+	root       node
+}
+
+// NewFilter returns compiled Filter or nil if the expression is empty
+func NewFilter(expression string) (*Filter, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, nil
+	}
+
+	root, err := parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing filter expression: %v", err)
+	}
+
+	return &Filter{expression: expression, root: root}, nil
+}
+
+// Validate returns err if the expression can't be parsed
+func Validate(expression string) error {
+	_, err := NewFilter(expression)
+	return err
+}
+
+// Match returns true if the event matches the expression or the filter is nil
+func (f *Filter) Match(object map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+
+	return f.root.eval(object)
+}
+
+// String returns the source expression
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+
+	return f.expression
+}
+
+type node interface {
+	eval(object map[string]interface{}) bool
+}
+
+type operand interface {
+	value(object map[string]interface{}) interface{}
+}
+
+type field struct {
+	path jsonutils.JSONPath
+}
+
+// value returns nil if the field doesn't exist
+func (f *field) value(object map[string]interface{}) interface{} {
+	value, _ := f.path.Get(object)
+	return value
+}
+
+type literal struct {
+	constant interface{}
+}
+
+func (l *literal) value(map[string]interface{}) interface{} {
+	return l.constant
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(object map[string]interface{}) bool {
+	return n.left.eval(object) || n.right.eval(object)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(object map[string]interface{}) bool {
+	return n.left.eval(object) && n.right.eval(object)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(object map[string]interface{}) bool {
+	return !n.operand.eval(object)
+}
+
+type truthyNode struct {
+	operand operand
+}
+
+func (n *truthyNode) eval(object map[string]interface{}) bool {
+	return truthy(n.operand.value(object))
+}
+
+type comparisonNode struct {
+	operator    string
+	left, right operand
+}
+
+func (n *comparisonNode) eval(object map[string]interface{}) bool {
+	left, right := n.left.value(object), n.right.value(object)
+	switch n.operator {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	case ">", ">=", "<", "<=":
+		result, ok := compare(left, right)
+		if !ok {
+			return false
+		}
+		switch n.operator {
+		case ">":
+			return result > 0
+		case ">=":
+			return result >= 0
+		case "<":
+			return result < 0
+		default:
+			return result <= 0
+		}
+	case "contains":
+		if array, ok := left.([]interface{}); ok {
+			for _, element := range array {
+				if equal(element, right) {
+					return true
+				}
+			}
+			return false
+		}
+		str, ok := stringValue(left)
+		return ok && right != nil && strings.Contains(str, toString(right))
+	case "starts_with":
+		str, ok := stringValue(left)
+		return ok && right != nil && strings.HasPrefix(str, toString(right))
+	case "ends_with":
+		str, ok := stringValue(left)
+		return ok && right != nil && strings.HasSuffix(str, toString(right))
+	}
+
+	return false
+}
+
+type matchesNode struct {
+	operand operand
+	re      *regexp.Regexp
+}
+
+func (n *matchesNode) eval(object map[string]interface{}) bool {
+	str, ok := stringValue(n.operand.value(object))
+	return ok && n.re.MatchString(str)
+}
+
+type inNode struct {
+	operand operand
+	values  []interface{}
+	negate  bool
+}
+
+func (n *inNode) eval(object map[string]interface{}) bool {
+	value := n.operand.value(object)
+	for _, v := range n.values {
+		if equal(value, v) {
+			return !n.negate
+		}
+	}
+
+	return n.negate
+}
+
+// equal compares values as numbers if both are numbers, otherwise as strings. null equals only null (missing field)
+func equal(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+
+	if l, ok := toFloat(left); ok {
+		if r, ok := toFloat(right); ok {
+			return l == r
+		}
+	}
+
+	return toString(left) == toString(right)
+}
+
+// compare compares numbers or strings. Returns false if values aren't comparable
+func compare(left, right interface{}) (int, bool) {
+	if l, ok := toFloat(left); ok {
+		if r, ok := toFloat(right); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+		return 0, false
+	}
+
+	l, ok := stringValue(left)
+	if !ok {
+		return 0, false
+	}
+	r, ok := stringValue(right)
+	if !ok {
+		return 0, false
+	}
+
+	return strings.Compare(l, r), true
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+
+	if f, ok := toFloat(value); ok {
+		return f != 0
+	}
+
+	return true
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+func stringValue(value interface{}) (string, bool) {
+	str, ok := value.(string)
+	return str, ok
+}
+
+func toString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+
+	return fmt.Sprint(value)
+}
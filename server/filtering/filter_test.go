@@ -0,0 +1,80 @@
+package filtering
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	event := map[string]interface{}{
+		"event_type": "pageview",
+		"eventn_ctx": map[string]interface{}{"doc_host": "www.example.com", "url": "https://www.example.com/pricing?utm=1"},
+		"user":       map[string]interface{}{"email": "john@example.com", "plan": "pro", "anonymous_id": ""},
+		"revenue":    json.Number("99.5"),
+		"items":      float64(3),
+		"tags":       []interface{}{"beta", "eu"},
+		"internal":   false,
+	}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`event_type in ("pageview", "conversion") && host != "staging.example.com"`, true},
+		{`event_type in ["conversion"]`, false},
+		{`event_type not in ("conversion", "identify")`, true},
+		{`host == 'www.example.com'`, true},
+		{`user.plan == "pro" and not internal`, true},
+		{`/user/email ends_with "@example.com"`, true},
+		{`eventn_ctx.url starts_with "https://www.example.com/pricing"`, true},
+		{`eventn_ctx.url contains "utm="`, true},
+		{`tags contains "eu"`, true},
+		{`tags contains "us"`, false},
+		{`user.email matches "^[a-z]+@example\\.com$"`, true},
+		{`revenue > 99 && revenue <= 99.5 && items == 3`, true},
+		{`items < -1`, false},
+		{`user.email`, true},
+		{`!user.anonymous_id`, true},
+		{`missing == null && missing != "x"`, true},
+		{`missing > 1 || missing`, false},
+		{`(event_type == "identify" || user.plan == "pro") && !(host == "localhost")`, true},
+		{`event_type == "identify" || user.plan == "pro" && internal`, false},
+		{`NOT (event_type == "pageview") OR items >= 3`, true},
+		{`items == "3"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			filter, err := NewFilter(tt.expression)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, filter.Match(event))
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	for _, expression := range []string{
+		`event_type ==`,
+		`event_type in "pageview"`,
+		`event_type in (user.plan)`,
+		`(event_type == "pageview"`,
+		`event_type == "pageview" )`,
+		`event_type = "pageview"`,
+		`event_type == "pageview`,
+		`user.email matches "["`,
+		`user.email matches user.plan`,
+		`&& event_type`,
+	} {
+		_, err := NewFilter(expression)
+		require.Error(t, err, expression)
+	}
+}
+
+func TestEmptyFilter(t *testing.T) {
+	filter, err := NewFilter("  ")
+	require.NoError(t, err)
+	require.Nil(t, filter)
+	require.True(t, filter.Match(map[string]interface{}{}))
+}
@@ -0,0 +1,150 @@
+package filtering
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenField
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenKeyword
+	tokenPunctuation
+)
+
+// keywords are case-insensitive
+var keywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "true": true, "false": true, "null": true,
+	"contains": true, "starts_with": true, "ends_with": true, "matches": true,
+}
+
+type token struct {
+	kind     tokenKind
+	text     string
+	value    interface{}
+	position int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return t.text
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// tokenize splits the expression into tokens. Fields are identifiers (event_type, user.email) or JSON paths (/user/email)
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			value, next, err := readString(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[i:next]), value: value, position: i})
+			i = next
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, position: start})
+		case isFieldStart(r):
+			start := i
+			for i < len(runes) && isFieldPart(runes[i]) {
+				i++
+			}
+			text := string(runes[start:i])
+			if lower := strings.ToLower(text); keywords[lower] {
+				tokens = append(tokens, token{kind: tokenKeyword, text: lower, position: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenField, text: text, position: start})
+			}
+		case r == '(' || r == ')' || r == '[' || r == ']' || r == ',':
+			tokens = append(tokens, token{kind: tokenPunctuation, text: string(r), position: i})
+			i++
+		default:
+			operator := readOperator(runes, i)
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, position: i})
+			i += len(operator)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, position: len(runes)}), nil
+}
+
+func isFieldStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '/' || r == '$'
+}
+
+func isFieldPart(r rune) bool {
+	return isFieldStart(r) || unicode.IsDigit(r) || r == '.' || r == '-'
+}
+
+// readOperator returns the operator at the position or an empty string
+func readOperator(runes []rune, i int) string {
+	for _, operator := range []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<", "!"} {
+		if strings.HasPrefix(string(runes[i:]), operator) {
+			return operator
+		}
+	}
+	return ""
+}
+
+// readString returns unquoted string value and the position after the closing quote
+func readString(runes []rune, start int) (string, int, error) {
+	quote := runes[start]
+	var sb strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 == len(runes) {
+				return "", 0, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			switch runes[i] {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			default:
+				sb.WriteRune(runes[i])
+			}
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteRune(runes[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string at position %d", start)
+}
@@ -0,0 +1,243 @@
+package filtering
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+// hostPath is the same as the routing host: document host of JS SDK events
+var hostPath = jsonutils.NewJSONPath("/eventn_ctx/doc_host||/doc_host")
+
+// parser is a recursive descent parser of the filter expression:
+//
+//	or         = and { ("||" | "or") and }
+//	and        = unary { ("&&" | "and") unary }
+//	unary      = ("!" | "not") unary | "(" or ")" | comparison
+//	comparison = operand [ ("==" | "!=" | ">" | ">=" | "<" | "<=" | "contains" | "starts_with" | "ends_with" | "matches") operand
+//	             | ["not"] "in" list ]
+//	list       = ("(" | "[") literal { "," literal } (")" | "]")
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(expression string) (node, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	result, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if current := p.current(); current.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", current, current.position)
+	}
+
+	return result, nil
+}
+
+func (p *parser) current() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, texts ...string) (token, error) {
+	t := p.next()
+	for _, text := range texts {
+		if t.is(kind, text) {
+			return t, nil
+		}
+	}
+
+	return t, fmt.Errorf("expected %s but got %s at position %d", strings.Join(texts, " or "), t, t.position)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for current := p.current(); current.is(tokenOperator, "||") || current.is(tokenKeyword, "or"); current = p.current() {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for current := p.current(); current.is(tokenOperator, "&&") || current.is(tokenKeyword, "and"); current = p.current() {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	current := p.current()
+	if current.is(tokenOperator, "!") || current.is(tokenKeyword, "not") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+
+	if current.is(tokenPunctuation, "(") {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenPunctuation, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	current := p.current()
+	switch {
+	case current.kind == tokenOperator && current.text != "!" && current.text != "&&" && current.text != "||",
+		current.is(tokenKeyword, "contains"), current.is(tokenKeyword, "starts_with"), current.is(tokenKeyword, "ends_with"):
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &comparisonNode{operator: current.text, left: left, right: right}, nil
+	case current.is(tokenKeyword, "matches"):
+		p.next()
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, fmt.Errorf("matches requires a string regular expression but got %s at position %d", t, t.position)
+		}
+		re, err := regexp.Compile(t.value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %v", t.position, err)
+		}
+		return &matchesNode{operand: left, re: re}, nil
+	case current.is(tokenKeyword, "in"), current.is(tokenKeyword, "not") && p.tokens[p.pos+1].is(tokenKeyword, "in"):
+		negate := current.text == "not"
+		if negate {
+			p.next()
+		}
+		p.next()
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{operand: left, values: values, negate: negate}, nil
+	}
+
+	//a single operand is checked for truthiness e.g. user.email or !user.email
+	return &truthyNode{operand: left}, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenField:
+		return newField(t)
+	case t.kind == tokenString, t.kind == tokenNumber:
+		return &literal{constant: t.value}, nil
+	case t.is(tokenKeyword, "true"):
+		return &literal{constant: true}, nil
+	case t.is(tokenKeyword, "false"):
+		return &literal{constant: false}, nil
+	case t.is(tokenKeyword, "null"):
+		return &literal{constant: nil}, nil
+	}
+
+	return nil, fmt.Errorf("expected field or value but got %s at position %d", t, t.position)
+}
+
+func (p *parser) parseList() ([]interface{}, error) {
+	open, err := p.expect(tokenPunctuation, "(", "[")
+	if err != nil {
+		return nil, err
+	}
+	closing := ")"
+	if open.text == "[" {
+		closing = "]"
+	}
+
+	var values []interface{}
+	for {
+		value, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		l, ok := value.(*literal)
+		if !ok {
+			return nil, fmt.Errorf("list must contain only values at position %d", p.tokens[p.pos-1].position)
+		}
+		values = append(values, l.constant)
+
+		t, err := p.expect(tokenPunctuation, ",", closing)
+		if err != nil {
+			return nil, err
+		}
+		if t.text == closing {
+			return values, nil
+		}
+	}
+}
+
+// newField returns field operand. "host" is the document host, identifiers with dots are nested fields
+// (user.email is /user/email), identifiers starting with / are JSON paths
+func newField(t token) (operand, error) {
+	if t.text == "host" {
+		return &field{path: hostPath}, nil
+	}
+
+	path := t.text
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + strings.ReplaceAll(path, ".", "/")
+	}
+
+	jsonPath := jsonutils.NewJSONPath(path)
+	if jsonPath.IsEmpty() {
+		return nil, fmt.Errorf("invalid field %s at position %d", t, t.position)
+	}
+
+	return &field{path: jsonPath}, nil
+}
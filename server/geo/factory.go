@@ -34,6 +34,10 @@ func NewMaxmindFactory(officialDownloadURLTemplate string) *MaxMindFactory {
 	return &MaxMindFactory{officialDownloadURLTemplate: officialDownloadURLTemplate}
 }
 
+func (f *MaxMindFactory) Type() string {
+	return MaxmindType
+}
+
 //Test tries to download all MaxMind databases and returns all available Edition
 //or error if no editions are available
 func (f *MaxMindFactory) Test(maxmindURL string) ([]*EditionRule, error) {
@@ -296,7 +300,7 @@ func checkPermissions(url string) error {
 }
 
 func loadFromURL(url string) ([]byte, error) {
-	logging.Infof("Start downloading maxmind from: %s", url)
+	logging.Infof("Start downloading geo database from: %s", url)
 
 	r, err := http.Get(url)
 	if err != nil {
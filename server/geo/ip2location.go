@@ -0,0 +1,537 @@
+package geo
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	IP2LocationPrefix = "ip2location://"
+
+	binSuffix = ".bin"
+	zipSuffix = ".zip"
+	//defaultIP2LocationFile is the most detailed free IP2Location LITE database (country, region, city, coordinates, zip)
+	defaultIP2LocationFile = "DB11LITEBIN"
+
+	ip2locationHeaderSize = 29
+	//ip2locationNotAvailable is a value of fields which aren't available in IP2Location DB
+	ip2locationNotAvailable = "-"
+)
+
+var ErrIP2LocationFileNotFound = fmt.Errorf("IP2Location DB (file with %s suffix) wasn't found", strings.ToUpper(binSuffix))
+
+//column positions of IP2Location DB fields by database type (DB1..DB26). 0 means that the field isn't available
+var (
+	ip2locationCountryPosition   = [27]uint32{0, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+	ip2locationRegionPosition    = [27]uint32{0, 0, 0, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3}
+	ip2locationCityPosition      = [27]uint32{0, 0, 0, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4}
+	ip2locationLatitudePosition  = [27]uint32{0, 0, 0, 0, 0, 5, 5, 0, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5}
+	ip2locationLongitudePosition = [27]uint32{0, 0, 0, 0, 0, 6, 6, 0, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6}
+	ip2locationZipPosition       = [27]uint32{0, 0, 0, 0, 0, 0, 0, 0, 0, 7, 7, 7, 7, 0, 7, 7, 7, 0, 7, 0, 7, 7, 7, 0, 7, 7, 7}
+)
+
+//IP2LocationFactory is responsible for creation geo resolvers from IP2Location (LITE) BIN databases
+type IP2LocationFactory struct {
+	officialDownloadURLTemplate string
+}
+
+func NewIP2LocationFactory(officialDownloadURLTemplate string) *IP2LocationFactory {
+	return &IP2LocationFactory{officialDownloadURLTemplate: officialDownloadURLTemplate}
+}
+
+func (f *IP2LocationFactory) Type() string {
+	return IP2LocationType
+}
+
+//Create creates Resolver from:
+// 1. URL in format: ip2location://<download_token>?file=DB11LITEBIN
+// 2. direct URL for download DB (BIN file or zip archive with BIN file)
+// 3. file path to DB
+// 4. dir path where there is a file (DB) with binSuffix or zip archive with it
+func (f *IP2LocationFactory) Create(location string) (Resolver, error) {
+	//1
+	if strings.HasPrefix(location, IP2LocationPrefix) {
+		token, file, err := f.parseIP2LocationAddress(location)
+		if err != nil {
+			return nil, err
+		}
+
+		return f.createFromURL(fmt.Sprintf(f.officialDownloadURLTemplate, url.QueryEscape(token), url.QueryEscape(file)))
+	}
+
+	//2
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return f.createFromURL(location)
+	}
+
+	//3
+	if strings.HasSuffix(strings.ToLower(location), binSuffix) {
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+
+		resolver, err := newIP2LocationResolver(file, file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		return resolver, nil
+	}
+
+	//4
+	logging.Infof("start observing files in %s dir for .BIN or .zip files with IP2Location db...", location)
+	b, err := findIP2LocationFile(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return newIP2LocationResolver(bytes.NewReader(b), nil)
+}
+
+//parseIP2LocationAddress parses ip2location://<download_token>?file=DB11LITEBIN format link
+func (f *IP2LocationFactory) parseIP2LocationAddress(location string) (string, string, error) {
+	value := strings.TrimPrefix(location, IP2LocationPrefix)
+	parts := strings.SplitN(value, "?", 2)
+	token := parts[0]
+	if token == "" {
+		return "", "", fmt.Errorf("malformed ip2location config [%s]. Should be in format - ip2location://<your download token>?file=DB11LITEBIN", location)
+	}
+
+	file := defaultIP2LocationFile
+	if len(parts) == 2 {
+		query, err := url.ParseQuery(parts[1])
+		if err != nil {
+			return "", "", fmt.Errorf("malformed ip2location config [%s]: %v", location, err)
+		}
+
+		if value := query.Get("file"); value != "" {
+			file = value
+		}
+	}
+
+	return token, file, nil
+}
+
+//createFromURL downloads IP2Location db from the url
+func (f *IP2LocationFactory) createFromURL(url string) (Resolver, error) {
+	b, err := loadFromURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if isZip(b) {
+		b, err = extractIP2LocationDBFromZip(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resolver, err := newIP2LocationResolver(bytes.NewReader(b), nil)
+	if err != nil {
+		//IP2Location download API responds with 200 and a plain text error message (e.g. NO PERMISSION)
+		if len(b) < 256 {
+			return nil, fmt.Errorf("error loading IP2Location db: %s", strings.TrimSpace(string(b)))
+		}
+
+		return nil, err
+	}
+
+	return resolver, nil
+}
+
+func findIP2LocationFile(dir string) ([]byte, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if strings.HasSuffix(strings.ToLower(f.Name()), binSuffix) {
+			return ioutil.ReadFile(path.Join(dir, f.Name()))
+		}
+	}
+
+	for _, f := range files {
+		if strings.HasSuffix(strings.ToLower(f.Name()), zipSuffix) {
+			absolutePath := path.Join(dir, f.Name())
+			zipBytes, err := ioutil.ReadFile(absolutePath)
+			if err != nil {
+				return nil, fmt.Errorf("error reading archive %s: %v", absolutePath, err)
+			}
+
+			content, err := extractIP2LocationDBFromZip(zipBytes)
+			if err != nil {
+				if err == ErrIP2LocationFileNotFound {
+					continue
+				}
+
+				return nil, err
+			}
+
+			return content, nil
+		}
+	}
+
+	return nil, ErrIP2LocationFileNotFound
+}
+
+func isZip(b []byte) bool {
+	return len(b) > 4 && bytes.Equal(b[:4], []byte("PK\x03\x04"))
+}
+
+//extractIP2LocationDBFromZip extracts BIN file from zip archive payload
+func extractIP2LocationDBFromZip(b []byte) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading zip archive: %v", err)
+	}
+
+	for _, file := range reader.File {
+		if !strings.HasSuffix(strings.ToLower(file.Name), binSuffix) {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error extracting %s from zip: %v", file.Name, err)
+		}
+
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s from zip: %v", file.Name, err)
+		}
+
+		return content, nil
+	}
+
+	return nil, ErrIP2LocationFileNotFound
+}
+
+//ip2locationMeta is a header of IP2Location BIN database
+type ip2locationMeta struct {
+	dbType        uint8
+	dbColumn      uint8
+	ipv4Count     uint32
+	ipv4Addr      uint32
+	ipv6Count     uint32
+	ipv6Addr      uint32
+	ipv4IndexAddr uint32
+	ipv6IndexAddr uint32
+}
+
+//IP2LocationResolver is a geo location data Resolver that is based on IP2Location BIN database.
+//All addresses in the database are 1-based, string pointers are 0-based
+type IP2LocationResolver struct {
+	reader io.ReaderAt
+	closer io.Closer
+	meta   ip2locationMeta
+}
+
+func newIP2LocationResolver(reader io.ReaderAt, closer io.Closer) (*IP2LocationResolver, error) {
+	header := make([]byte, ip2locationHeaderSize)
+	if _, err := reader.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("error reading IP2Location db header: %v", err)
+	}
+
+	meta := ip2locationMeta{
+		dbType:        header[0],
+		dbColumn:      header[1],
+		ipv4Count:     binary.LittleEndian.Uint32(header[5:]),
+		ipv4Addr:      binary.LittleEndian.Uint32(header[9:]),
+		ipv6Count:     binary.LittleEndian.Uint32(header[13:]),
+		ipv6Addr:      binary.LittleEndian.Uint32(header[17:]),
+		ipv4IndexAddr: binary.LittleEndian.Uint32(header[21:]),
+		ipv6IndexAddr: binary.LittleEndian.Uint32(header[25:]),
+	}
+
+	if meta.dbType == 0 || int(meta.dbType) >= len(ip2locationCountryPosition) || meta.dbColumn < 2 ||
+		(meta.ipv4Count == 0 && meta.ipv6Count == 0) {
+		return nil, errors.New("invalid IP2Location db: unknown BIN file format")
+	}
+
+	return &IP2LocationResolver{reader: reader, closer: closer, meta: meta}, nil
+}
+
+//Resolve returns location geo data (country, region, city, coordinates, zip) parsed from client ip address
+func (r *IP2LocationResolver) Resolve(ip string) (*Data, error) {
+	if ip == "" {
+		return nil, EmptyIP
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return nil, fmt.Errorf("Error parsing IP from string: %s", ip)
+	}
+
+	var columnsAddr uint32
+	var found bool
+	var err error
+	if ipv4 := parsedIP.To4(); ipv4 != nil {
+		columnsAddr, found, err = r.findIPv4(binary.BigEndian.Uint32(ipv4))
+	} else {
+		columnsAddr, found, err = r.findIPv6(binary.BigEndian.Uint64(parsedIP[:8]), binary.BigEndian.Uint64(parsedIP[8:]))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error looking up ip %s in IP2Location db: %v", ip, err)
+	}
+
+	data := &Data{}
+	if !found {
+		return data, nil
+	}
+
+	if err := r.readData(columnsAddr, data); err != nil {
+		return nil, fmt.Errorf("Error reading geo data of ip %s from IP2Location db: %v", ip, err)
+	}
+
+	return data, nil
+}
+
+//findIPv4 returns address of the first column after ip_from of the row with the ip range
+func (r *IP2LocationResolver) findIPv4(ipNum uint32) (uint32, bool, error) {
+	if r.meta.ipv4Count == 0 {
+		return 0, false, nil
+	}
+
+	low, high := uint32(0), r.meta.ipv4Count
+	if r.meta.ipv4IndexAddr > 0 {
+		indexAddr := r.meta.ipv4IndexAddr + (ipNum>>16)<<3
+		var err error
+		if low, err = r.readUint32(indexAddr); err != nil {
+			return 0, false, err
+		}
+		if high, err = r.readUint32(indexAddr + 4); err != nil {
+			return 0, false, err
+		}
+	}
+
+	//the last ip_to in the database is 2^32-1
+	if ipNum == math.MaxUint32 {
+		ipNum--
+	}
+
+	columnSize := uint32(r.meta.dbColumn) << 2
+	for low <= high {
+		mid := (low + high) >> 1
+		rowAddr := r.meta.ipv4Addr + mid*columnSize
+		ipFrom, err := r.readUint32(rowAddr)
+		if err != nil {
+			return 0, false, err
+		}
+		ipTo, err := r.readUint32(rowAddr + columnSize)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if ipNum >= ipFrom && ipNum < ipTo {
+			return rowAddr + 4, true, nil
+		}
+
+		if ipNum < ipFrom {
+			if mid == 0 {
+				break
+			}
+			high = mid - 1
+		} else {
+			low = mid + 1
+		}
+	}
+
+	return 0, false, nil
+}
+
+//findIPv6 returns address of the first column after ip_from of the row with the ip range
+func (r *IP2LocationResolver) findIPv6(ipHigh, ipLow uint64) (uint32, bool, error) {
+	if r.meta.ipv6Count == 0 {
+		return 0, false, nil
+	}
+
+	low, high := uint32(0), r.meta.ipv6Count
+	if r.meta.ipv6IndexAddr > 0 {
+		indexAddr := r.meta.ipv6IndexAddr + uint32(ipHigh>>48)<<3
+		var err error
+		if low, err = r.readUint32(indexAddr); err != nil {
+			return 0, false, err
+		}
+		if high, err = r.readUint32(indexAddr + 4); err != nil {
+			return 0, false, err
+		}
+	}
+
+	columnSize := 16 + uint32(r.meta.dbColumn-1)<<2
+	for low <= high {
+		mid := (low + high) >> 1
+		rowAddr := r.meta.ipv6Addr + mid*columnSize
+		fromHigh, fromLow, err := r.readUint128(rowAddr)
+		if err != nil {
+			return 0, false, err
+		}
+		toHigh, toLow, err := r.readUint128(rowAddr + columnSize)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if !less128(ipHigh, ipLow, fromHigh, fromLow) && less128(ipHigh, ipLow, toHigh, toLow) {
+			return rowAddr + 16, true, nil
+		}
+
+		if less128(ipHigh, ipLow, fromHigh, fromLow) {
+			if mid == 0 {
+				break
+			}
+			high = mid - 1
+		} else {
+			low = mid + 1
+		}
+	}
+
+	return 0, false, nil
+}
+
+//readData reads all available fields of the row. Column at position 2 is located at columnsAddr
+func (r *IP2LocationResolver) readData(columnsAddr uint32, data *Data) error {
+	columnAddr := func(positions [27]uint32) (uint32, bool) {
+		position := positions[r.meta.dbType]
+		if position == 0 {
+			return 0, false
+		}
+		return columnsAddr + (position-2)<<2, true
+	}
+
+	if addr, ok := columnAddr(ip2locationCountryPosition); ok {
+		pointer, err := r.readUint32(addr)
+		if err != nil {
+			return err
+		}
+		if data.Country, err = r.readString(pointer); err != nil {
+			return err
+		}
+		if data.CountryName, err = r.readString(pointer + 3); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if addr, ok := columnAddr(ip2locationRegionPosition); ok {
+		if data.Region, err = r.readStringColumn(addr); err != nil {
+			return err
+		}
+	}
+
+	if addr, ok := columnAddr(ip2locationCityPosition); ok {
+		if data.City, err = r.readStringColumn(addr); err != nil {
+			return err
+		}
+	}
+
+	if addr, ok := columnAddr(ip2locationLatitudePosition); ok {
+		if data.Lat, err = r.readFloat(addr); err != nil {
+			return err
+		}
+	}
+
+	if addr, ok := columnAddr(ip2locationLongitudePosition); ok {
+		if data.Lon, err = r.readFloat(addr); err != nil {
+			return err
+		}
+	}
+
+	if addr, ok := columnAddr(ip2locationZipPosition); ok {
+		if data.Zip, err = r.readStringColumn(addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *IP2LocationResolver) readUint32(addr uint32) (uint32, error) {
+	b := make([]byte, 4)
+	if _, err := r.reader.ReadAt(b, int64(addr)-1); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *IP2LocationResolver) readUint128(addr uint32) (uint64, uint64, error) {
+	b := make([]byte, 16)
+	if _, err := r.reader.ReadAt(b, int64(addr)-1); err != nil {
+		return 0, 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b[8:]), binary.LittleEndian.Uint64(b[:8]), nil
+}
+
+//readFloat reads float32 coordinate and converts it into float64 without float32 precision artifacts
+func (r *IP2LocationResolver) readFloat(addr uint32) (float64, error) {
+	bits, err := r.readUint32(addr)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(strconv.FormatFloat(float64(math.Float32frombits(bits)), 'f', -1, 32), 64)
+}
+
+//readString reads length-prefixed string by 0-based pointer
+func (r *IP2LocationResolver) readString(pointer uint32) (string, error) {
+	length := make([]byte, 1)
+	if _, err := r.reader.ReadAt(length, int64(pointer)); err != nil {
+		return "", err
+	}
+
+	value := make([]byte, length[0])
+	if _, err := r.reader.ReadAt(value, int64(pointer)+1); err != nil {
+		return "", err
+	}
+
+	if string(value) == ip2locationNotAvailable {
+		return "", nil
+	}
+
+	return string(value), nil
+}
+
+func (r *IP2LocationResolver) readStringColumn(addr uint32) (string, error) {
+	pointer, err := r.readUint32(addr)
+	if err != nil {
+		return "", err
+	}
+
+	return r.readString(pointer)
+}
+
+func (r *IP2LocationResolver) Type() string {
+	return IP2LocationType
+}
+
+//Close closes underlying DB file
+func (r *IP2LocationResolver) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+
+	return nil
+}
+
+//less128 returns true if (aHigh, aLow) < (bHigh, bLow)
+func less128(aHigh, aLow, bHigh, bLow uint64) bool {
+	return aHigh < bHigh || (aHigh == bHigh && aLow < bLow)
+}
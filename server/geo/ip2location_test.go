@@ -0,0 +1,166 @@
+package geo
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testIP2LocationRow struct {
+	ipFrom      uint32
+	country     string
+	countryName string
+	region      string
+	city        string
+	lat         float32
+	lon         float32
+}
+
+//buildIP2LocationDB5 returns IPv4 only DB5 BIN database (country, region, city, latitude, longitude)
+func buildIP2LocationDB5(rows []testIP2LocationRow) []byte {
+	const dbColumn = 6
+	const headerSize = 64
+	rowsSize := (len(rows) + 1) * dbColumn * 4
+	stringsAddr := uint32(headerSize + rowsSize)
+
+	var stringsBlock []byte
+	writeString := func(value string) uint32 {
+		pointer := stringsAddr + uint32(len(stringsBlock))
+		stringsBlock = append(stringsBlock, byte(len(value)))
+		stringsBlock = append(stringsBlock, value...)
+		return pointer
+	}
+
+	db := make([]byte, headerSize, headerSize+rowsSize)
+	db[0] = 5
+	db[1] = dbColumn
+	binary.LittleEndian.PutUint32(db[5:], uint32(len(rows)-1))
+	binary.LittleEndian.PutUint32(db[9:], headerSize+1)
+
+	appendUint32 := func(value uint32) {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, value)
+		db = append(db, b...)
+	}
+
+	for _, row := range rows {
+		appendUint32(row.ipFrom)
+		//country code takes 3 bytes and is followed by country name
+		countryPointer := writeString(row.country)
+		stringsBlock = append(stringsBlock, make([]byte, 2-len(row.country))...)
+		writeString(row.countryName)
+		appendUint32(countryPointer)
+		appendUint32(writeString(row.region))
+		appendUint32(writeString(row.city))
+		appendUint32(math.Float32bits(row.lat))
+		appendUint32(math.Float32bits(row.lon))
+	}
+	//padding row
+	for i := 0; i < dbColumn; i++ {
+		appendUint32(math.MaxUint32)
+	}
+
+	return append(db, stringsBlock...)
+}
+
+func testIP2LocationDB() []byte {
+	return buildIP2LocationDB5([]testIP2LocationRow{
+		{ipFrom: 0, country: "-", countryName: "-", region: "-", city: "-"},
+		{ipFrom: 16843008, country: "US", countryName: "United States of America", region: "California", city: "Los Angeles", lat: 34.05223, lon: -118.24368},
+		{ipFrom: 16843264, country: "-", countryName: "-", region: "-", city: "-"},
+		{ipFrom: 33554432, country: "FR", countryName: "France", region: "Ile-de-France", city: "Paris", lat: 48.85341, lon: 2.3488},
+		{ipFrom: math.MaxUint32, country: "-", countryName: "-", region: "-", city: "-"},
+	})
+}
+
+func TestIP2LocationResolver(t *testing.T) {
+	resolver, err := newIP2LocationResolver(bytes.NewReader(testIP2LocationDB()), nil)
+	require.NoError(t, err)
+
+	data, err := resolver.Resolve("1.1.1.5")
+	require.NoError(t, err)
+	require.Equal(t, &Data{Country: "US", CountryName: "United States of America", Region: "California", City: "Los Angeles", Lat: 34.05223, Lon: -118.24368}, data)
+
+	data, err = resolver.Resolve("2.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, "Paris", data.City)
+	require.Equal(t, 48.85341, data.Lat)
+
+	data, err = resolver.Resolve("255.255.255.255")
+	require.NoError(t, err)
+	require.Equal(t, "Paris", data.City)
+
+	//not available values
+	data, err = resolver.Resolve("1.1.2.1")
+	require.NoError(t, err)
+	require.Equal(t, &Data{}, data)
+
+	//IPv4 only database
+	data, err = resolver.Resolve("2001:db8::1")
+	require.NoError(t, err)
+	require.Equal(t, &Data{}, data)
+
+	_, err = resolver.Resolve("")
+	require.Equal(t, EmptyIP, err)
+
+	_, err = resolver.Resolve("abc")
+	require.Error(t, err)
+}
+
+func TestIP2LocationFactoryCreate(t *testing.T) {
+	dir := t.TempDir()
+	db := testIP2LocationDB()
+
+	//zip archive in dir
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+	w, err := zipWriter.Create("IP2LOCATION-LITE-DB5.BIN")
+	require.NoError(t, err)
+	_, err = w.Write(db)
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+	require.NoError(t, os.WriteFile(path.Join(dir, "IP2LOCATION-LITE-DB5.BIN.zip"), buf.Bytes(), 0644))
+
+	factory := NewIP2LocationFactory("")
+	resolver, err := factory.Create(dir)
+	require.NoError(t, err)
+	data, err := resolver.Resolve("1.1.1.1")
+	require.NoError(t, err)
+	require.Equal(t, "US", data.Country)
+	require.NoError(t, resolver.Close())
+
+	//BIN file path
+	binPath := path.Join(dir, "IP2LOCATION-LITE-DB5.BIN")
+	require.NoError(t, os.WriteFile(binPath, db, 0644))
+	resolver, err = factory.Create(binPath)
+	require.NoError(t, err)
+	data, err = resolver.Resolve("2.1.1.1")
+	require.NoError(t, err)
+	require.Equal(t, "FR", data.Country)
+	require.NoError(t, resolver.Close())
+
+	_, err = factory.Create(t.TempDir())
+	require.Equal(t, ErrIP2LocationFileNotFound, err)
+}
+
+func TestParseIP2LocationAddress(t *testing.T) {
+	factory := NewIP2LocationFactory("")
+	token, file, err := factory.parseIP2LocationAddress("ip2location://abc")
+	require.NoError(t, err)
+	require.Equal(t, "abc", token)
+	require.Equal(t, defaultIP2LocationFile, file)
+
+	token, file, err = factory.parseIP2LocationAddress("ip2location://abc?file=DB5LITEBINIPV6")
+	require.NoError(t, err)
+	require.Equal(t, "abc", token)
+	require.Equal(t, "DB5LITEBINIPV6", file)
+
+	_, _, err = factory.parseIP2LocationAddress("ip2location://")
+	require.Error(t, err)
+}
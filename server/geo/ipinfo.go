@@ -0,0 +1,195 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"golang.org/x/time/rate"
+)
+
+const (
+	IPInfoPrefix = "ipinfo://"
+
+	ipInfoRequestTimeout = 5 * time.Second
+)
+
+var ErrRateLimited = errors.New("geo resolver rate limit exceeded")
+
+//IPInfoFactory is responsible for creation geo resolvers which use ipinfo.io API
+type IPInfoFactory struct {
+	apiURL            string
+	cacheSize         int
+	cacheTTL          time.Duration
+	requestsRateLimit int
+}
+
+//NewIPInfoFactory returns IPInfoFactory. Resolvers cache up to cacheSize lookups for cacheTTL
+//and send at most requestsRateLimit API requests per second
+func NewIPInfoFactory(apiURL string, cacheSize int, cacheTTL time.Duration, requestsRateLimit int) *IPInfoFactory {
+	return &IPInfoFactory{
+		apiURL:            strings.TrimSuffix(apiURL, "/"),
+		cacheSize:         cacheSize,
+		cacheTTL:          cacheTTL,
+		requestsRateLimit: requestsRateLimit,
+	}
+}
+
+func (f *IPInfoFactory) Type() string {
+	return IPInfoType
+}
+
+//Create creates Resolver from ipinfo://<token> link or plain token
+func (f *IPInfoFactory) Create(location string) (Resolver, error) {
+	token := strings.TrimPrefix(location, IPInfoPrefix)
+	if token == "" {
+		return nil, fmt.Errorf("malformed ipinfo config [%s]. Should be in format - ipinfo://<your access token>", location)
+	}
+
+	cache, err := lru.New(f.cacheSize)
+	if err != nil {
+		return nil, fmt.Errorf("error creating ipinfo cache: %v", err)
+	}
+
+	return &IPInfoResolver{
+		apiURL:   f.apiURL,
+		token:    token,
+		client:   &http.Client{Timeout: ipInfoRequestTimeout},
+		cache:    cache,
+		cacheTTL: f.cacheTTL,
+		limiter:  rate.NewLimiter(rate.Limit(f.requestsRateLimit), f.requestsRateLimit),
+	}, nil
+}
+
+//IPInfoResolver is a geo location data Resolver that requests ipinfo.io API.
+//Lookups are cached in memory and API requests are rate limited: ErrRateLimited is returned if the limit is exceeded
+type IPInfoResolver struct {
+	apiURL   string
+	token    string
+	client   *http.Client
+	cache    *lru.Cache
+	cacheTTL time.Duration
+	limiter  *rate.Limiter
+}
+
+type ipInfoCacheEntry struct {
+	data      Data
+	expiresAt time.Time
+}
+
+type ipInfoResponse struct {
+	City    string `json:"city"`
+	Region  string `json:"region"`
+	Country string `json:"country"`
+	Loc     string `json:"loc"`
+	Org     string `json:"org"`
+	Postal  string `json:"postal"`
+	Bogon   bool   `json:"bogon"`
+}
+
+//Resolve returns location geo data from cache or from ipinfo.io API
+func (r *IPInfoResolver) Resolve(ip string) (*Data, error) {
+	if ip == "" {
+		return nil, EmptyIP
+	}
+
+	if net.ParseIP(ip) == nil {
+		return nil, fmt.Errorf("Error parsing IP from string: %s", ip)
+	}
+
+	if value, ok := r.cache.Get(ip); ok {
+		entry := value.(*ipInfoCacheEntry)
+		if timestamp.Now().Before(entry.expiresAt) {
+			data := entry.data
+			return &data, nil
+		}
+	}
+
+	if !r.limiter.Allow() {
+		return nil, ErrRateLimited
+	}
+
+	data, err := r.request(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.Add(ip, &ipInfoCacheEntry{data: *data, expiresAt: timestamp.Now().Add(r.cacheTTL)})
+	return data, nil
+}
+
+func (r *IPInfoResolver) request(ip string) (*Data, error) {
+	resp, err := r.client.Get(fmt.Sprintf("%s/%s?token=%s", r.apiURL, url.PathEscape(ip), url.QueryEscape(r.token)))
+	if err != nil {
+		return nil, fmt.Errorf("error requesting ipinfo: %v", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ipinfo response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests:
+			return nil, ErrRateLimited
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return nil, ErrInvalidLicenseKey
+		default:
+			return nil, fmt.Errorf("error requesting ipinfo: http code=%d [%s]", resp.StatusCode, string(b))
+		}
+	}
+
+	response := &ipInfoResponse{}
+	if err := json.Unmarshal(b, response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling ipinfo response [%s]: %v", string(b), err)
+	}
+
+	data := &Data{}
+	if response.Bogon {
+		return data, nil
+	}
+
+	data.Country = response.Country
+	data.Region = response.Region
+	data.City = response.City
+	data.Zip = response.Postal
+	if parts := strings.Split(response.Loc, ","); len(parts) == 2 {
+		data.Lat, _ = strconv.ParseFloat(parts[0], 64)
+		data.Lon, _ = strconv.ParseFloat(parts[1], 64)
+	}
+
+	//org is in format: AS15169 Google LLC
+	if strings.HasPrefix(response.Org, "AS") {
+		parts := strings.SplitN(response.Org, " ", 2)
+		if asn, err := strconv.ParseUint(strings.TrimPrefix(parts[0], "AS"), 10, 32); err == nil {
+			data.ASN = uint(asn)
+			if len(parts) == 2 {
+				data.ASO = parts[1]
+			}
+		}
+	} else {
+		data.Organization = response.Org
+	}
+
+	return data, nil
+}
+
+func (r *IPInfoResolver) Type() string {
+	return IPInfoType
+}
+
+func (r *IPInfoResolver) Close() error {
+	r.cache.Purge()
+	return nil
+}
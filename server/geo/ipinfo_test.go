@@ -0,0 +1,61 @@
+package geo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestIPInfoResolver(t *testing.T) {
+	requests := atomic.NewInt32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Inc()
+		if r.URL.Query().Get("token") != "token1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/8.8.8.8":
+			w.Write([]byte(`{"ip":"8.8.8.8","city":"Mountain View","region":"California","country":"US","loc":"37.4056,-122.0775","org":"AS15169 Google LLC","postal":"94043","timezone":"America/Los_Angeles"}`))
+		case "/10.0.0.1":
+			w.Write([]byte(`{"ip":"10.0.0.1","bogon":true}`))
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	resolver, err := NewIPInfoFactory(server.URL, 10, time.Hour, 2).Create("ipinfo://token1")
+	require.NoError(t, err)
+	defer resolver.Close()
+
+	expected := &Data{Country: "US", Region: "California", City: "Mountain View", Lat: 37.4056, Lon: -122.0775, Zip: "94043", ASN: 15169, ASO: "Google LLC"}
+	data, err := resolver.Resolve("8.8.8.8")
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+
+	//cached
+	data, err = resolver.Resolve("8.8.8.8")
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+	require.Equal(t, int32(1), requests.Load())
+
+	data, err = resolver.Resolve("10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, &Data{}, data)
+
+	//local rate limit is exceeded
+	_, err = resolver.Resolve("1.1.1.1")
+	require.Equal(t, ErrRateLimited, err)
+	require.Equal(t, int32(2), requests.Load())
+
+	invalidResolver, err := NewIPInfoFactory(server.URL, 10, time.Hour, 2).Create("ipinfo://token2")
+	require.NoError(t, err)
+	_, err = invalidResolver.Resolve("8.8.8.8")
+	require.Equal(t, ErrInvalidLicenseKey, err)
+}
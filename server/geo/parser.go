@@ -22,12 +22,44 @@ func parseConfigFromBytes(b []byte) (map[string]*ResolverConfig, error) {
 	return payload.GeoResolvers, nil
 }
 
-//ParseConfigAsLink works only with MaxMindType
-//returns MaxMind URL or error
-func ParseConfigAsLink(config *ResolverConfig) (string, error) {
-	if config.Type != MaxmindType {
+//ParseConfigAsLocation returns resolver location (link, URL or file path) from the config or error
+func ParseConfigAsLocation(config *ResolverConfig) (string, error) {
+	switch config.Type {
+	case MaxmindType:
+		return parseMaxmindConfig(config)
+	case IP2LocationType:
+		ic := &IP2LocationConfig{}
+		if err := jsonutils.UnmarshalConfig(config.Config, ic); err != nil {
+			return "", err
+		}
+
+		if ic.IP2LocationURL == "" {
+			return "", errors.New("ip2location_url is required field")
+		}
+
+		if !strings.Contains(ic.IP2LocationURL, "://") && !strings.HasPrefix(ic.IP2LocationURL, "/") {
+			return IP2LocationPrefix + ic.IP2LocationURL, nil
+		}
+
+		return ic.IP2LocationURL, nil
+	case IPInfoType:
+		ic := &IPInfoConfig{}
+		if err := jsonutils.UnmarshalConfig(config.Config, ic); err != nil {
+			return "", err
+		}
+
+		if ic.Token == "" {
+			return "", errors.New("token is required field")
+		}
+
+		return IPInfoPrefix + ic.Token, nil
+	default:
 		return "", fmt.Errorf("unsupported geo resolver type: %s", config.Type)
 	}
+}
+
+//parseMaxmindConfig returns MaxMind URL or error
+func parseMaxmindConfig(config *ResolverConfig) (string, error) {
 	mc := &MaxMindConfig{}
 
 	if err := jsonutils.UnmarshalConfig(config.Config, mc); err != nil {
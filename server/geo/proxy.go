@@ -7,11 +7,13 @@ import (
 	"time"
 )
 
-//UpdatableProxy creates Resolver and re-create it every 24 hours with retry (if create fails e.g. because of connection issue)
+//UpdatableProxy creates Resolver and re-create it every refreshInterval (if create fails e.g. because of connection issue
+//the current Resolver is kept until the next attempt). It is used for keeping geo databases up-to-date
 type UpdatableProxy struct {
-	factoryMethod func(path string) (Resolver, error)
+	factoryMethod   func(path string) (Resolver, error)
+	refreshInterval time.Duration
 
-	maxmindLink string
+	location string
 
 	mutex    *sync.RWMutex
 	resolver Resolver
@@ -20,18 +22,19 @@ type UpdatableProxy struct {
 }
 
 //newResolverProxy creates Resolver immediately and starts goroutine for re-create Resolver
-func newResolverProxy(maxmindLink string, factoryMethod func(path string) (Resolver, error)) (Resolver, error) {
-	underlyingResolver, err := factoryMethod(maxmindLink)
+func newResolverProxy(location string, factoryMethod func(path string) (Resolver, error), refreshInterval time.Duration) (Resolver, error) {
+	underlyingResolver, err := factoryMethod(location)
 	if err != nil {
 		return nil, err
 	}
 
 	up := &UpdatableProxy{
-		factoryMethod: factoryMethod,
-		maxmindLink:   maxmindLink,
-		mutex:         &sync.RWMutex{},
-		resolver:      underlyingResolver,
-		closed:        make(chan struct{}),
+		factoryMethod:   factoryMethod,
+		refreshInterval: refreshInterval,
+		location:        location,
+		mutex:           &sync.RWMutex{},
+		resolver:        underlyingResolver,
+		closed:          make(chan struct{}),
 	}
 
 	up.start()
@@ -40,22 +43,28 @@ func newResolverProxy(maxmindLink string, factoryMethod func(path string) (Resol
 
 func (up *UpdatableProxy) start() {
 	safego.RunWithRestart(func() {
-		ticker := time.NewTicker(24 * time.Hour)
+		ticker := time.NewTicker(up.refreshInterval)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-up.closed:
 				return
 			case <-ticker.C:
 				logging.Info("running geo resolver databases update..")
-				resolver, err := up.factoryMethod(up.maxmindLink)
+				resolver, err := up.factoryMethod(up.location)
 				if err != nil {
-					logging.SystemErrorf("Error reloading geo resolver [%s]: %v", up.maxmindLink, err)
+					logging.SystemErrorf("Error reloading geo resolver [%s]: %v", up.location, err)
 					continue
 				}
 
 				up.mutex.Lock()
+				oldResolver := up.resolver
 				up.resolver = resolver
 				up.mutex.Unlock()
+
+				if err := oldResolver.Close(); err != nil {
+					logging.Warnf("Error closing previous geo resolver [%s]: %v", up.location, err)
+				}
 			}
 		}
 	}).WithRestartTimeout(1 * time.Minute)
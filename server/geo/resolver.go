@@ -3,8 +3,10 @@ package geo
 import "errors"
 
 const (
-	MaxmindType = "maxmind"
-	DummyType   = "dummy"
+	MaxmindType     = "maxmind"
+	IP2LocationType = "ip2location"
+	IPInfoType      = "ipinfo"
+	DummyType       = "dummy"
 
 	UKCountry = "UK"
 )
@@ -50,6 +52,13 @@ type Resolver interface {
 	Close() error
 }
 
+//Provider creates geo data resolvers of a certain type from the location
+//(file path, URL or provider specific link with a license key)
+type Provider interface {
+	Type() string
+	Create(location string) (Resolver, error)
+}
+
 //Data is a geo location data dto
 type Data struct {
 	Continent   string  `mapstructure:"continent,omitempty" json:"continent,omitempty"`
@@ -78,3 +87,13 @@ type ResolverConfig struct {
 type MaxMindConfig struct {
 	MaxMindURL string `mapstructure:"maxmind_url" json:"maxmind_url,omitempty" yaml:"maxmind_url,omitempty"`
 }
+
+//IP2LocationConfig is a dto for IP2Location configuration serialization
+type IP2LocationConfig struct {
+	IP2LocationURL string `mapstructure:"ip2location_url" json:"ip2location_url,omitempty" yaml:"ip2location_url,omitempty"`
+}
+
+//IPInfoConfig is a dto for ipinfo.io configuration serialization
+type IPInfoConfig struct {
+	Token string `mapstructure:"token" json:"token,omitempty" yaml:"token,omitempty"`
+}
@@ -2,6 +2,8 @@ package geo
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/resources"
@@ -24,8 +26,9 @@ type Service struct {
 	resolversMutex *sync.RWMutex
 	globalMutex    *sync.RWMutex
 
-	ctx     context.Context
-	factory *MaxMindFactory
+	ctx             context.Context
+	providers       map[string]Provider
+	refreshInterval time.Duration
 
 	geoResolversByID map[string]*Unit
 
@@ -41,25 +44,38 @@ func NewTestService(globalResolver Resolver) *Service {
 		resolversMutex:    &sync.RWMutex{},
 		globalMutex:       &sync.RWMutex{},
 		ctx:               context.Background(),
-		factory:           nil,
+		providers:         map[string]Provider{},
 		geoResolversByID:  map[string]*Unit{},
 		globalGeoResolver: globalResolver,
 	}
 }
 
 //NewService returns initialized Service instance
-func NewService(ctx context.Context, geoURL, globalGeoMaxmindPath, officialDownloadURLTemplate string) *Service {
+//globalGeoProvider is a type of the global geo resolver provider. If it is empty, the type is detected by globalGeoPath prefix
+//(maxmind://, ip2location://, ipinfo://) and MaxMind is used by default.
+//Geo databases are re-downloaded every refreshInterval
+func NewService(ctx context.Context, geoURL, globalGeoPath, globalGeoProvider string, refreshInterval time.Duration, providers ...Provider) *Service {
 	service := &Service{
 		resolversMutex:    &sync.RWMutex{},
 		globalMutex:       &sync.RWMutex{},
 		ctx:               ctx,
-		factory:           NewMaxmindFactory(officialDownloadURLTemplate),
+		providers:         map[string]Provider{},
+		refreshInterval:   refreshInterval,
 		geoResolversByID:  map[string]*Unit{},
 		globalGeoResolver: &DummyResolver{},
 	}
 
-	if geoURL == "" && globalGeoMaxmindPath == "" {
-		logging.Info("❌ Geo resolution won't be available as 'geo.maxmind_path' (or 'geo_resolvers' section) are not set")
+	for _, provider := range providers {
+		service.providers[provider.Type()] = provider
+	}
+
+	if service.refreshInterval <= 0 {
+		logging.Error("geo.refresh_interval_hours must be greater than 0. 24 hours will be used as default value")
+		service.refreshInterval = 24 * time.Hour
+	}
+
+	if geoURL == "" && globalGeoPath == "" {
+		logging.Info("❌ Geo resolution won't be available as 'geo.path' (or 'geo.maxmind_path' or 'geo_resolvers' section) are not set")
 		return service
 	}
 
@@ -70,14 +86,17 @@ func NewService(ctx context.Context, geoURL, globalGeoMaxmindPath, officialDownl
 	}
 
 	//global geo resolver
-	if globalGeoMaxmindPath != "" {
+	if globalGeoPath != "" {
+		if globalGeoProvider == "" {
+			globalGeoProvider = detectProviderType(globalGeoPath)
+		}
 		//global geo resolver takes a long time for download all databases
 		safego.Run(func() {
-			defaultResolverProxy, err := newResolverProxy(globalGeoMaxmindPath, service.factory.Create)
+			defaultResolverProxy, err := service.create(globalGeoProvider, globalGeoPath)
 			if err != nil {
-				logging.Warnf("❌ Failed to load global MaxMind DB from %s: %v. Global geo resolution won't be available. You can configure custom one in Configurator UI", globalGeoMaxmindPath, err)
+				logging.Warnf("❌ Failed to load global %s geo resolver from %s: %v. Global geo resolution won't be available. You can configure custom one in Configurator UI", globalGeoProvider, globalGeoPath, err)
 			} else {
-				logging.Info("✅ Loaded MaxMind db:", globalGeoMaxmindPath)
+				logging.Infof("✅ Loaded %s geo resolver: %s", globalGeoProvider, globalGeoPath)
 				service.globalMutex.Lock()
 				service.globalGeoResolver = defaultResolverProxy
 				service.globalMutex.Unlock()
@@ -160,13 +179,13 @@ func (s *Service) init(rc map[string]*ResolverConfig) {
 			s.resolversMutex.Unlock()
 		}
 
-		maxmindlink, err := ParseConfigAsLink(config)
+		location, err := ParseConfigAsLocation(config)
 		if err != nil {
 			logging.Errorf("[%s] Error initializing geo resolver of type %s: %v", id, config.Type, err)
 			continue
 		}
 
-		resolverProxy, err := newResolverProxy(maxmindlink, s.factory.Create)
+		resolverProxy, err := s.create(config.Type, location)
 		if err != nil {
 			logging.Errorf("[%s] Error initializing geo resolver of type %s: %v", id, config.Type, err)
 			continue
@@ -203,9 +222,42 @@ func (s *Service) GetGlobalGeoResolver() Resolver {
 	return s.globalGeoResolver
 }
 
-//TestGeoResolver proxies request to the factory
+//TestGeoResolver proxies request to the MaxMind factory
 func (s *Service) TestGeoResolver(url string) ([]*EditionRule, error) {
-	return s.factory.Test(url)
+	factory, ok := s.providers[MaxmindType].(*MaxMindFactory)
+	if !ok {
+		return nil, errors.New("MaxMind geo resolver provider isn't configured")
+	}
+
+	return factory.Test(url)
+}
+
+//create creates Resolver with the provider. Resolvers based on databases are wrapped into UpdatableProxy
+//for refreshing databases on schedule
+func (s *Service) create(providerType, location string) (Resolver, error) {
+	provider, ok := s.providers[providerType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported geo resolver type: %s", providerType)
+	}
+
+	//ipinfo resolver requests API and doesn't have a database for refreshing
+	if providerType == IPInfoType {
+		return provider.Create(location)
+	}
+
+	return newResolverProxy(location, provider.Create, s.refreshInterval)
+}
+
+//detectProviderType returns provider type by location prefix. MaxMind is used by default
+func detectProviderType(location string) string {
+	switch {
+	case strings.HasPrefix(location, IP2LocationPrefix):
+		return IP2LocationType
+	case strings.HasPrefix(location, IPInfoPrefix):
+		return IPInfoType
+	default:
+		return MaxmindType
+	}
 }
 
 //GetPaidEditions returns paidEditions
@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationMetadataKey = "authorization"
+	notServerTokenErrMsg     = "The token isn't a server secret token. Please use an s2s integration token"
+)
+
+type tokenContextKey struct{}
+
+//authorizer checks that the token from request metadata is a server secret
+//and puts it into the request context
+type authorizer struct {
+	isServerTokenFunc func(string) ([]string, bool)
+	isClientTokenFunc func(string) ([]string, bool)
+}
+
+func (a *authorizer) authorize(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := extractToken(md)
+
+	if _, allowed := a.isServerTokenFunc(token); !allowed {
+		if _, exist := a.isClientTokenFunc(token); exist {
+			return nil, status.Error(codes.PermissionDenied, notServerTokenErrMsg)
+		}
+		return nil, status.Errorf(codes.Unauthenticated, middleware.ErrTokenNotFound, token)
+	}
+
+	return context.WithValue(ctx, tokenContextKey{}, token), nil
+}
+
+func (a *authorizer) unaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	authorizedCtx, err := a.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(authorizedCtx, req)
+}
+
+func (a *authorizer) streamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	authorizedCtx, err := a.authorize(ss.Context())
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authorizedStream{ServerStream: ss, ctx: authorizedCtx})
+}
+
+//authorizedStream is a grpc.ServerStream with the authorized context
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (as *authorizedStream) Context() context.Context {
+	return as.ctx
+}
+
+//extractToken returns token from
+//1. x-auth-token metadata
+//2. api_key metadata
+//3. authorization metadata (Bearer token or basic auth username)
+func extractToken(md metadata.MD) string {
+	for _, key := range []string{middleware.TokenHeaderName, middleware.APIKeyName} {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+
+	parts := strings.SplitN(values[0], " ", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	credentials := strings.TrimSpace(parts[1])
+	switch strings.ToLower(parts[0]) {
+	case "bearer":
+		return credentials
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return ""
+		}
+		return strings.SplitN(string(decoded), ":", 2)[0]
+	default:
+		return ""
+	}
+}
+
+//tokenFromContext returns the token which has been put by authorizer
+func tokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenContextKey{}).(string)
+	return token
+}
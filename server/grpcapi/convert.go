@@ -0,0 +1,62 @@
+package grpcapi
+
+import (
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/grpcapi/ingestionpb"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	eventTypeKey   = "event_type"
+	eventIDKey     = "event_id"
+	utcTimeKey     = "utc_time"
+	userKey        = "user"
+	sourceIPKey    = "source_ip"
+	userAgentKey   = "user_agent"
+	urlKey         = "url"
+	userIDKey      = "id"
+	anonymousIDKey = "anonymous_id"
+	emailKey       = "email"
+)
+
+//toEvent converts protobuf event into Jitsu JSON event (2.0 format)
+//properties are put into the root of the event, typed fields override properties with the same names
+func toEvent(pbEvent *ingestionpb.Event) events.Event {
+	event := events.Event{}
+	for key, value := range pbEvent.GetProperties().AsMap() {
+		event[key] = value
+	}
+
+	setNotEmpty(event, eventTypeKey, pbEvent.GetEventType())
+	setNotEmpty(event, eventIDKey, pbEvent.GetEventId())
+	setNotEmpty(event, sourceIPKey, pbEvent.GetSourceIp())
+	setNotEmpty(event, userAgentKey, pbEvent.GetUserAgent())
+	setNotEmpty(event, urlKey, pbEvent.GetUrl())
+	if pbEvent.GetUtcTime() != nil {
+		event[utcTimeKey] = pbEvent.GetUtcTime().AsTime().UTC().Format(timestamp.Layout)
+	}
+
+	if pbUser := pbEvent.GetUser(); pbUser != nil {
+		user, ok := event[userKey].(map[string]interface{})
+		if !ok {
+			user = map[string]interface{}{}
+		}
+		for key, value := range pbUser.GetTraits().AsMap() {
+			user[key] = value
+		}
+		setNotEmpty(user, userIDKey, pbUser.GetId())
+		setNotEmpty(user, anonymousIDKey, pbUser.GetAnonymousId())
+		setNotEmpty(user, emailKey, pbUser.GetEmail())
+		if len(user) > 0 {
+			event[userKey] = user
+		}
+	}
+
+	return event
+}
+
+func setNotEmpty(object map[string]interface{}, key, value string) {
+	if value != "" {
+		object[key] = value
+	}
+}
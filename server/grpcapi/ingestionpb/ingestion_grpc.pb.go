@@ -0,0 +1,180 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: grpcapi/ingestionpb/ingestion.proto
+
+package ingestionpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// IngestionClient is the client API for Ingestion service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IngestionClient interface {
+	//Send accepts a batch of events in a single request
+	Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendResponse, error)
+	//SendStream accepts a stream of batches and returns a summary when the client closes the stream
+	SendStream(ctx context.Context, opts ...grpc.CallOption) (Ingestion_SendStreamClient, error)
+}
+
+type ingestionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIngestionClient(cc grpc.ClientConnInterface) IngestionClient {
+	return &ingestionClient{cc}
+}
+
+func (c *ingestionClient) Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendResponse, error) {
+	out := new(SendResponse)
+	err := c.cc.Invoke(ctx, "/jitsu.ingestion.v1.Ingestion/Send", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestionClient) SendStream(ctx context.Context, opts ...grpc.CallOption) (Ingestion_SendStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ingestion_ServiceDesc.Streams[0], "/jitsu.ingestion.v1.Ingestion/SendStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ingestionSendStreamClient{stream}
+	return x, nil
+}
+
+type Ingestion_SendStreamClient interface {
+	Send(*SendRequest) error
+	CloseAndRecv() (*SendResponse, error)
+	grpc.ClientStream
+}
+
+type ingestionSendStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *ingestionSendStreamClient) Send(m *SendRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *ingestionSendStreamClient) CloseAndRecv() (*SendResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SendResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IngestionServer is the server API for Ingestion service.
+// All implementations must embed UnimplementedIngestionServer
+// for forward compatibility
+type IngestionServer interface {
+	//Send accepts a batch of events in a single request
+	Send(context.Context, *SendRequest) (*SendResponse, error)
+	//SendStream accepts a stream of batches and returns a summary when the client closes the stream
+	SendStream(Ingestion_SendStreamServer) error
+	mustEmbedUnimplementedIngestionServer()
+}
+
+// UnimplementedIngestionServer must be embedded to have forward compatible implementations.
+type UnimplementedIngestionServer struct {
+}
+
+func (UnimplementedIngestionServer) Send(context.Context, *SendRequest) (*SendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Send not implemented")
+}
+func (UnimplementedIngestionServer) SendStream(Ingestion_SendStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method SendStream not implemented")
+}
+func (UnimplementedIngestionServer) mustEmbedUnimplementedIngestionServer() {}
+
+// UnsafeIngestionServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IngestionServer will
+// result in compilation errors.
+type UnsafeIngestionServer interface {
+	mustEmbedUnimplementedIngestionServer()
+}
+
+func RegisterIngestionServer(s grpc.ServiceRegistrar, srv IngestionServer) {
+	s.RegisterService(&Ingestion_ServiceDesc, srv)
+}
+
+func _Ingestion_Send_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServer).Send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/jitsu.ingestion.v1.Ingestion/Send",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServer).Send(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ingestion_SendStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IngestionServer).SendStream(&ingestionSendStreamServer{stream})
+}
+
+type Ingestion_SendStreamServer interface {
+	SendAndClose(*SendResponse) error
+	Recv() (*SendRequest, error)
+	grpc.ServerStream
+}
+
+type ingestionSendStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *ingestionSendStreamServer) SendAndClose(m *SendResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *ingestionSendStreamServer) Recv() (*SendRequest, error) {
+	m := new(SendRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Ingestion_ServiceDesc is the grpc.ServiceDesc for Ingestion service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ingestion_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "jitsu.ingestion.v1.Ingestion",
+	HandlerType: (*IngestionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Send",
+			Handler:    _Ingestion_Send_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SendStream",
+			Handler:       _Ingestion_SendStream_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "grpcapi/ingestionpb/ingestion.proto",
+}
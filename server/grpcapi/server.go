@@ -0,0 +1,73 @@
+package grpcapi
+
+//go:generate protoc -I.. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative grpcapi/ingestionpb/ingestion.proto
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/grpcapi/ingestionpb"
+	"github.com/jitsucom/jitsu/server/logging"
+	"google.golang.org/grpc"
+)
+
+const gracefulStopTimeout = 10 * time.Second
+
+//Server is a gRPC server with Ingestion service and token authorization
+type Server struct {
+	server   *grpc.Server
+	listener net.Listener
+}
+
+//NewServer returns configured Server which listens on the port
+//isServerTokenFunc and isClientTokenFunc are used for requests authorization: only server secrets are allowed
+func NewServer(port string, maxMessageSize int, service ingestionpb.IngestionServer,
+	isServerTokenFunc, isClientTokenFunc func(string) ([]string, bool)) (*Server, error) {
+	listener, err := net.Listen("tcp", "0.0.0.0:"+port)
+	if err != nil {
+		return nil, fmt.Errorf("error listening gRPC port %s: %v", port, err)
+	}
+
+	return newServer(listener, maxMessageSize, service, isServerTokenFunc, isClientTokenFunc), nil
+}
+
+func newServer(listener net.Listener, maxMessageSize int, service ingestionpb.IngestionServer,
+	isServerTokenFunc, isClientTokenFunc func(string) ([]string, bool)) *Server {
+	auth := &authorizer{isServerTokenFunc: isServerTokenFunc, isClientTokenFunc: isClientTokenFunc}
+	server := grpc.NewServer(
+		grpc.MaxRecvMsgSize(maxMessageSize),
+		grpc.UnaryInterceptor(auth.unaryInterceptor),
+		grpc.StreamInterceptor(auth.streamInterceptor),
+	)
+	ingestionpb.RegisterIngestionServer(server, service)
+
+	return &Server{server: server, listener: listener}
+}
+
+//Start runs serving in a separate goroutine
+func (s *Server) Start() {
+	go func() {
+		if err := s.server.Serve(s.listener); err != nil {
+			logging.Errorf("gRPC server has been stopped with error: %v", err)
+		}
+	}()
+}
+
+//Close waits for in-flight requests and stops the server
+//streams which aren't closed by clients in gracefulStopTimeout are interrupted
+func (s *Server) Close() error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(gracefulStopTimeout):
+		s.server.Stop()
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/grpcapi/ingestionpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//testService counts events and remembers tokens from the context
+type testService struct {
+	ingestionpb.UnimplementedIngestionServer
+	tokens []string
+}
+
+func (ts *testService) Send(ctx context.Context, request *ingestionpb.SendRequest) (*ingestionpb.SendResponse, error) {
+	ts.tokens = append(ts.tokens, tokenFromContext(ctx))
+	return &ingestionpb.SendResponse{Accepted: uint64(len(request.Events)), Batches: 1}, nil
+}
+
+func (ts *testService) SendStream(stream ingestionpb.Ingestion_SendStreamServer) error {
+	ts.tokens = append(ts.tokens, tokenFromContext(stream.Context()))
+	response := &ingestionpb.SendResponse{}
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(response)
+		}
+		if err != nil {
+			return err
+		}
+		response.Accepted += uint64(len(request.Events))
+		response.Batches++
+	}
+}
+
+func tokensFunc(tokens ...string) func(string) ([]string, bool) {
+	return func(token string) ([]string, bool) {
+		for _, t := range tokens {
+			if t == token {
+				return nil, true
+			}
+		}
+		return nil, false
+	}
+}
+
+func TestServerAuthorization(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	service := &testService{}
+	server := newServer(listener, 1024*1024, service, tokensFunc("s2s"), tokensFunc("js"))
+	server.Start()
+	defer server.Close()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := ingestionpb.NewIngestionClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &ingestionpb.SendRequest{Events: []*ingestionpb.Event{{EventType: "a"}, {EventType: "b"}}}
+
+	_, err = client.Send(ctx, request)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Send(metadata.AppendToOutgoingContext(ctx, "x-auth-token", "js"), request)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	response, err := client.Send(metadata.AppendToOutgoingContext(ctx, "x-auth-token", "s2s"), request)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), response.Accepted)
+
+	stream, err := client.SendStream(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer s2s"))
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(request))
+	require.NoError(t, stream.Send(request))
+	response, err = stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), response.Accepted)
+	require.Equal(t, uint64(2), response.Batches)
+
+	stream, err = client.SendStream(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer unknown"))
+	require.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	require.Equal(t, []string{"s2s", "s2s"}, service.tokens)
+}
+
+func TestExtractToken(t *testing.T) {
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("s2s:"))
+	require.Equal(t, "s2s", extractToken(metadata.Pairs("x-auth-token", "s2s", "authorization", "Bearer other")))
+	require.Equal(t, "s2s", extractToken(metadata.Pairs("api_key", "s2s")))
+	require.Equal(t, "s2s", extractToken(metadata.Pairs("authorization", "bearer s2s")))
+	require.Equal(t, "s2s", extractToken(metadata.Pairs("authorization", basic)))
+	require.Equal(t, "", extractToken(metadata.Pairs("authorization", "Digest s2s")))
+	require.Equal(t, "", extractToken(nil))
+}
+
+func TestToEvent(t *testing.T) {
+	properties, err := structpb.NewStruct(map[string]interface{}{
+		"event_type": "overridden",
+		"amount":     10.5,
+		"user":       map[string]interface{}{"plan": "free"},
+	})
+	require.NoError(t, err)
+	traits, err := structpb.NewStruct(map[string]interface{}{"plan": "pro", "name": "John"})
+	require.NoError(t, err)
+
+	event := toEvent(&ingestionpb.Event{
+		EventType:  "purchase",
+		EventId:    "id1",
+		UtcTime:    timestamppb.New(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)),
+		User:       &ingestionpb.User{Id: "u1", Email: "john@example.com", Traits: traits},
+		SourceIp:   "1.1.1.1",
+		Properties: properties,
+	})
+	require.Equal(t, map[string]interface{}{
+		"event_type": "purchase",
+		"event_id":   "id1",
+		"utc_time":   "2022-01-02T03:04:05.000000Z",
+		"source_ip":  "1.1.1.1",
+		"amount":     10.5,
+		"user":       map[string]interface{}{"id": "u1", "email": "john@example.com", "plan": "pro", "name": "John"},
+	}, map[string]interface{}(event))
+
+	require.Equal(t, map[string]interface{}{}, map[string]interface{}(toEvent(&ingestionpb.Event{User: &ingestionpb.User{}})))
+}
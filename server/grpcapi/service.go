@@ -0,0 +1,197 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/appstatus"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/grpcapi/ingestionpb"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/multiplexing"
+	"github.com/jitsucom/jitsu/server/quota"
+	"github.com/jitsucom/jitsu/server/validation"
+	"github.com/jitsucom/jitsu/server/wal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const noDestinationsErrTemplate = "No destination is configured for token [%q] (or only staged ones)"
+
+//Service is an Ingestion gRPC service implementation
+//converts protobuf events into Jitsu events and accepts them in the same way as the server-to-server HTTP API
+type Service struct {
+	ingestionpb.UnimplementedIngestionServer
+
+	writeAheadLogService *wal.Service
+	multiplexingService  *multiplexing.Service
+	eventsCache          *caching.EventsCache
+	processor            events.Processor
+	destinationService   *destinations.Service
+	geoService           *geo.Service
+}
+
+//NewService returns configured Service
+func NewService(writeAheadLogService *wal.Service, multiplexingService *multiplexing.Service, eventsCache *caching.EventsCache,
+	processor events.Processor, destinationService *destinations.Service, geoService *geo.Service) *Service {
+	return &Service{
+		writeAheadLogService: writeAheadLogService,
+		multiplexingService:  multiplexingService,
+		eventsCache:          eventsCache,
+		processor:            processor,
+		destinationService:   destinationService,
+		geoService:           geoService,
+	}
+}
+
+//Send accepts a batch of events
+func (s *Service) Send(ctx context.Context, request *ingestionpb.SendRequest) (*ingestionpb.SendResponse, error) {
+	accepted, err := s.accept(ctx, request.GetEvents())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ingestionpb.SendResponse{Accepted: accepted, Batches: 1}, nil
+}
+
+//SendStream accepts batches until the client closes the stream
+//if a batch can't be accepted, the stream is interrupted with an error. All previous batches are accepted.
+func (s *Service) SendStream(stream ingestionpb.Ingestion_SendStreamServer) error {
+	response := &ingestionpb.SendResponse{}
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(response)
+		}
+		if err != nil {
+			return err
+		}
+
+		accepted, err := s.accept(stream.Context(), request.GetEvents())
+		if err != nil {
+			return err
+		}
+
+		response.Accepted += accepted
+		response.Batches++
+	}
+}
+
+//accept converts events and passes them to the multiplexing service
+//returns gRPC status errors
+func (s *Service) accept(ctx context.Context, pbEvents []*ingestionpb.Event) (uint64, error) {
+	if len(pbEvents) == 0 {
+		return 0, nil
+	}
+
+	token := tokenFromContext(ctx)
+	tokenID := appconfig.Instance.AuthorizationService.GetTokenID(token)
+
+	destinationStorages := s.destinationService.GetDestinations(tokenID)
+	cachingDisabled := false
+	for _, destinationStorage := range destinationStorages {
+		if destinationStorage.IsCachingDisabled() {
+			cachingDisabled = true
+			break
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	eventsArray := make([]events.Event, 0, len(pbEvents))
+	for _, pbEvent := range pbEvents {
+		event := toEvent(pbEvent)
+		if appconfig.Instance.EnrichWithHTTPContext {
+			event[events.HTTPContextField] = &events.HTTPContext{Headers: http.Header(md)}
+		}
+		eventsArray = append(eventsArray, event)
+	}
+
+	reqContext := getRequestContext(ctx, md)
+
+	//API key ip handling: is applied before events are cached and archived
+	geoResolver := s.geoService.GetGlobalGeoResolver()
+	if len(destinationStorages) > 0 {
+		geoResolver = s.geoService.GetGeoResolver(destinationStorages[0].GetGeoResolverID())
+	}
+	reqContext.ClientIP = enrichment.APIKeyIPHandlingStep(appconfig.Instance.AuthorizationService.GetIPHandling(token), reqContext.ClientIP, geoResolver, eventsArray...)
+	accepted := uint64(len(eventsArray))
+
+	//put all events to write-ahead-log if idle
+	if appstatus.Instance.Idle.Load() {
+		s.cacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, nil)
+		s.writeAheadLogService.Consume(eventsArray, reqContext, token, s.processor.Type())
+		return accepted, nil
+	}
+
+	if _, err := s.multiplexingService.AcceptRequest(ctx, s.processor, reqContext, token, eventsArray); err != nil {
+		if err == multiplexing.ErrNoDestinations {
+			s.cacheRawEvents(eventsArray, cachingDisabled, tokenID, fmt.Errorf(noDestinationsErrTemplate, token), nil)
+			return accepted, nil
+		}
+
+		s.cacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, err)
+		if validationErr, ok := err.(*validation.Error); ok {
+			return 0, status.Error(codes.InvalidArgument, validationErr.Error())
+		}
+		if quotaErr, ok := err.(*quota.ExceededError); ok {
+			return 0, status.Error(codes.ResourceExhausted, quotaErr.Error())
+		}
+
+		reqBody, _ := json.Marshal(eventsArray)
+		logging.Warnf("[gRPC] %v. Event: %s", err, string(reqBody))
+		return 0, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.cacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, nil)
+	return accepted, nil
+}
+
+func (s *Service) cacheRawEvents(eventsArray []events.Event, cachingDisabled bool, tokenID string, skip error, err error) {
+	for _, e := range eventsArray {
+		serializedPayload, _ := json.Marshal(e)
+		if err != nil {
+			s.eventsCache.RawErrorEvent(cachingDisabled, tokenID, serializedPayload, err)
+			return
+		}
+
+		skipMsg := ""
+		if skip != nil {
+			skipMsg = skip.Error()
+		}
+		s.eventsCache.RawEvent(cachingDisabled, tokenID, serializedPayload, skipMsg)
+	}
+}
+
+//getRequestContext returns request context with client IP from the connection and user-agent from metadata
+//events can override them with source_ip and user_agent fields
+func getRequestContext(ctx context.Context, md metadata.MD) *events.RequestContext {
+	var clientIP string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		clientIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(clientIP); err == nil {
+			clientIP = host
+		}
+	}
+
+	userAgent := strings.Join(md.Get("user-agent"), " ")
+
+	return &events.RequestContext{
+		UserAgent:           userAgent,
+		ClientIP:            clientIP,
+		HashedAnonymousID:   fmt.Sprintf("%x", md5.Sum([]byte(clientIP+userAgent))),
+		CookiesLawCompliant: true,
+	}
+}
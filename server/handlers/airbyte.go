@@ -57,6 +57,26 @@ type CatalogResponse struct {
 	Catalog interface{} `json:"catalog"`
 }
 
+// PinVersionRequest is a dto for connector version pinning
+type PinVersionRequest struct {
+	Version string `json:"version"`
+	//Force pins the version even if it hasn't passed staging
+	Force bool `json:"force"`
+}
+
+// PinnedVersionResponse is a dto with pinned connector version and staging results
+type PinnedVersionResponse struct {
+	Pinned  *airbyte.PinnedVersion   `json:"pinned"`
+	Staging []*airbyte.StagingResult `json:"staging"`
+}
+
+// StagingResponse is a dto with connector version staging result
+type StagingResponse struct {
+	middleware.StatusResponse
+
+	Staging *airbyte.StagingResult `json:"staging"`
+}
+
 type AirbyteHandler struct {
 	httpClient    *http.Client
 	discoverTasks sync.Map
@@ -104,10 +124,7 @@ func (ah *AirbyteHandler) SpecHandler(c *gin.Context) {
 		return
 	}
 
-	imageVersion := c.Query("image_version")
-	if imageVersion == "" {
-		imageVersion = airbyte.LatestVersion
-	}
+	imageVersion := airbyte.Instance.ResolveVersion(dockerImage, c.Query("image_version"))
 
 	airbyteRunner := airbyte.NewRunner("SpecHandler", dockerImage, imageVersion, "")
 	spec, err := airbyteRunner.Spec()
@@ -195,10 +212,7 @@ func (ah *AirbyteHandler) CatalogHandler(c *gin.Context) {
 	}
 	base.FillPreconfiguredOauth(dockerImage, airbyteSourceConnectorConfig)
 
-	imageVersion := c.Query("image_version")
-	if imageVersion == "" {
-		imageVersion = airbyte.LatestVersion
-	}
+	imageVersion := airbyte.Instance.ResolveVersion(dockerImage, c.Query("image_version"))
 	configHash, err := hashstructure.Hash(airbyteSourceConnectorConfig, hashstructure.FormatV2, nil)
 	if err != nil {
 		logging.Errorf("Failed to hash config: %v", err)
@@ -254,6 +268,129 @@ func (ah *AirbyteHandler) CatalogHandler(c *gin.Context) {
 	}
 }
 
+// PinnedVersionHandler returns pinned connector version and staging results of the docker image
+func (ah *AirbyteHandler) PinnedVersionHandler(c *gin.Context) {
+	versions, dockerImage, ok := getVersionsManager(c)
+	if !ok {
+		return
+	}
+
+	response := PinnedVersionResponse{Staging: versions.GetStagingResults(dockerImage)}
+	if pinnedVersion, ok := versions.GetPinned(dockerImage); ok {
+		response.Pinned = pinnedVersion
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PinVersionHandler pins connector version: sources with empty or 'latest' image_version will use it
+func (ah *AirbyteHandler) PinVersionHandler(c *gin.Context) {
+	versions, dockerImage, ok := getVersionsManager(c)
+	if !ok {
+		return
+	}
+
+	req := &PinVersionRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing pin version request: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	pinnedVersion, err := versions.Pin(dockerImage, req.Version, req.Force)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Error pinning [%s] version", dockerImage), err))
+		return
+	}
+
+	c.JSON(http.StatusOK, PinnedVersionResponse{Pinned: pinnedVersion, Staging: versions.GetStagingResults(dockerImage)})
+}
+
+// UnpinVersionHandler deletes pinned connector version
+func (ah *AirbyteHandler) UnpinVersionHandler(c *gin.Context) {
+	versions, dockerImage, ok := getVersionsManager(c)
+	if !ok {
+		return
+	}
+
+	if err := versions.Unpin(dockerImage); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Error unpinning [%s] version", dockerImage), err))
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
+
+// RollbackVersionHandler pins the previous connector version
+func (ah *AirbyteHandler) RollbackVersionHandler(c *gin.Context) {
+	versions, dockerImage, ok := getVersionsManager(c)
+	if !ok {
+		return
+	}
+
+	pinnedVersion, err := versions.Rollback(dockerImage)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Error rolling back [%s] version", dockerImage), err))
+		return
+	}
+
+	c.JSON(http.StatusOK, PinnedVersionResponse{Pinned: pinnedVersion, Staging: versions.GetStagingResults(dockerImage)})
+}
+
+// StageVersionHandler starts connector version staging: pulls the image, runs spec and check with the connector config
+// from the body (optional). Returns pending status until staging is finished
+func (ah *AirbyteHandler) StageVersionHandler(c *gin.Context) {
+	versions, dockerImage, ok := getVersionsManager(c)
+	if !ok {
+		return
+	}
+
+	imageVersion := c.Query("image_version")
+	if imageVersion == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("image_version is required query parameter", nil))
+		return
+	}
+
+	var airbyteSourceConnectorConfig interface{}
+	if c.Request.ContentLength > 0 {
+		config := map[string]interface{}{}
+		if err := c.BindJSON(&config); err != nil {
+			logging.Errorf("Error parsing airbyte source connector body: %v", err)
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+			return
+		}
+		base.FillPreconfiguredOauth(dockerImage, config)
+		airbyteSourceConnectorConfig = config
+	}
+
+	result := versions.Stage(dockerImage, imageVersion, airbyteSourceConnectorConfig)
+
+	status := middleware.PendingResponse()
+	if result.Status == airbyte.StagingSucceeded {
+		status = middleware.OKResponse()
+	} else if result.Status == airbyte.StagingFailed {
+		status = middleware.StatusResponse{Status: airbyte.StagingFailed, Message: result.Error}
+	}
+	c.JSON(http.StatusOK, StagingResponse{StatusResponse: status, Staging: result})
+}
+
+// getVersionsManager returns initialized versions manager and docker image name without 'airbyte/' prefix
+// or writes HTTP 400 and returns false
+func getVersionsManager(c *gin.Context) (*airbyte.VersionsManager, string, bool) {
+	dockerImage := strings.TrimPrefix(c.Param("dockerImageName"), airbyte.DockerImageRepositoryPrefix)
+	if dockerImage == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("docker image name is required path parameter", nil))
+		return nil, "", false
+	}
+
+	if airbyte.Instance == nil || airbyte.Instance.Versions == nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Airbyte was not initialized: %v", airbyte.InstanceError), nil))
+		return nil, "", false
+	}
+
+	return airbyte.Instance.Versions, dockerImage, true
+}
+
 func (ah *AirbyteHandler) getAvailableDockerVersions(dockerImageName string) ([]string, error) {
 	var tags []*DockerHubTag
 	nextURL := fmt.Sprintf(dockerHubURLTemplate, "airbyte", dockerImageName)
@@ -16,8 +16,11 @@ import (
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/fallback"
+	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/quota"
 	"github.com/jitsucom/jitsu/server/storages"
 	"github.com/jitsucom/jitsu/server/telemetry"
 )
@@ -26,13 +29,15 @@ import (
 type BulkHandler struct {
 	destinationService *destinations.Service
 	processor          events.Processor
+	geoService         *geo.Service
 }
 
 //NewBulkHandler returns configured BulkHandler
-func NewBulkHandler(destinationService *destinations.Service, processor events.Processor) *BulkHandler {
+func NewBulkHandler(destinationService *destinations.Service, processor events.Processor, geoService *geo.Service) *BulkHandler {
 	return &BulkHandler{
 		destinationService: destinationService,
 		processor:          processor,
+		geoService:         geoService,
 	}
 }
 
@@ -54,11 +59,27 @@ func (bh *BulkHandler) BulkLoadingHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
 		return
 	}
+	metrics.ReceivedTokenEvents(tokenID, len(eventObjects))
+
+	if err := quota.Check(tokenID, len(eventObjects)); err != nil {
+		counters.SkipPushSourceEvents(tokenID, int64(len(eventObjects)))
+		if quotaErr, ok := err.(*quota.ExceededError); ok {
+			quotaExceededResponse(c, quotaErr)
+		} else {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+		}
+		return
+	}
 
 	//use empty context (only IP) because server 2 server integration
-	emptyContext := &events.RequestContext{ClientIP: extractIP(c)}
+	//API key ip handling is applied to the client IP and events source_ip before destinations processing
+	ipHandling := appconfig.Instance.AuthorizationService.GetIPHandling(apiKey)
+	geoResolver := bh.geoService.GetGeoResolver(storageProxies[0].GetGeoResolverID())
+	clientIP := extractIP(c)
+	emptyContext := &events.RequestContext{ClientIP: enrichment.APIKeyIPHandlingStep(ipHandling, clientIP, geoResolver)}
 	uniqueIDField := storageProxies[0].GetUniqueIDField()
 	for _, object := range eventObjects {
+		enrichment.APIKeyIPHandlingStep(ipHandling, clientIP, geoResolver, object)
 		enrichment.ContextEnrichmentStep(object, apiKey, emptyContext, bh.processor, uniqueIDField)
 		enrichment.HTTPContextEnrichmentStep(c, object)
 	}
@@ -72,6 +93,7 @@ func (bh *BulkHandler) BulkLoadingHandler(c *gin.Context) {
 			metrics.ErrorTokenObjects(tokenID, rowsCount)
 			telemetry.Error(tokenID, storageProxy.ID(), events.SrcBulk, "", rowsCount)
 			counters.ErrorPushDestinationEvents(storageProxy.ID(), int64(rowsCount))
+			counters.PushRollup(storageProxy.ID(), tokenID, "", meta.ErrorStatus, int64(rowsCount))
 
 			c.JSON(http.StatusBadRequest, middleware.ErrResponse("failed to process file payload", err))
 			return
@@ -81,10 +103,14 @@ func (bh *BulkHandler) BulkLoadingHandler(c *gin.Context) {
 		metrics.SuccessTokenObjects(tokenID, rowsCount)
 		telemetry.Event(tokenID, storageProxy.ID(), events.SrcBulk, "", rowsCount)
 		counters.SuccessPushDestinationEvents(storageProxy.ID(), int64(rowsCount))
+		counters.PushRollup(storageProxy.ID(), tokenID, "", meta.SuccessStatus, int64(rowsCount))
 	}
 
 	counters.SuccessPushSourceEvents(tokenID, int64(rowsCount))
 
+	if warning := quota.Warning(tokenID); warning != "" {
+		c.Header(quota.WarningHeader, warning)
+	}
 	c.JSON(http.StatusOK, middleware.OKResponse())
 }
 
@@ -116,10 +142,12 @@ func extractBulkEvents(c *gin.Context) ([]map[string]interface{}, error) {
 }
 
 func (bh *BulkHandler) upload(storageProxy storages.StorageProxy, objects []map[string]interface{}, needCopyEvent bool) error {
-	storage, ok := storageProxy.Get()
+	storage, release, ok := storageProxy.Acquire()
 	if !ok {
-		return fmt.Errorf("Destination [%s] hasn't been initialized yet", storage.ID())
+		return fmt.Errorf("Destination [%s] hasn't been initialized yet", storageProxy.ID())
 	}
+	defer release()
+
 	if storage.IsStaging() {
 		return fmt.Errorf("Error running bulk loading for destination [%s] in staged mode, "+
 			"cannot be used to store data (only available for dry-run)", storage.ID())
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+//CircuitBreakersResponse is a dto for destinations circuit breakers response
+type CircuitBreakersResponse struct {
+	CircuitBreakers []storages.CircuitBreakerStatus `json:"circuit_breakers"`
+}
+
+//CircuitBreakersHandler returns circuit breakers states of all streaming destinations
+func CircuitBreakersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, CircuitBreakersResponse{CircuitBreakers: storages.CircuitBreakerStatuses()})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/delivery"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+)
+
+const deliveryTrackingDisabledErr = "Events delivery tracking is disabled. Please configure delivery_tracking.enabled: true"
+
+//DeliveryRecordsResponse is a dto for events delivery search response
+type DeliveryRecordsResponse struct {
+	Records []*delivery.Record `json:"records"`
+}
+
+//DeliveryHandler returns the event delivery record: where the event has been sent and with which result
+func DeliveryHandler(c *gin.Context) {
+	service := delivery.Instance()
+	if service == nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(deliveryTrackingDisabledErr, nil))
+		return
+	}
+
+	eventID := c.Param("eventID")
+	record, err := service.Get(eventID)
+	if err != nil {
+		logging.Errorf("Error getting event [%s] delivery record: %v", eventID, err)
+		c.JSON(http.StatusInternalServerError, middleware.ErrResponse("Error getting event delivery record", err))
+		return
+	}
+
+	if record == nil {
+		c.JSON(http.StatusNotFound, middleware.ErrResponse("Event ["+eventID+"] delivery record isn't found. It might be expired or out of delivery_tracking.capacity", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+//DeliverySearchHandler returns the last events delivery records
+//query parameters (all are optional):
+//api_key - API key id or client/server secret
+//destination_id - destination id
+//status - pending, delivered, skipped or failed (in the destination_id destination if it is set)
+//limit - max records count (default 100, max 1000)
+func DeliverySearchHandler(c *gin.Context) {
+	service := delivery.Instance()
+	if service == nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(deliveryTrackingDisabledErr, nil))
+		return
+	}
+
+	filter := &delivery.Filter{
+		APIKey:        c.Query("api_key"),
+		DestinationID: c.Query("destination_id"),
+		Status:        c.Query("status"),
+	}
+
+	switch filter.Status {
+	case "", delivery.StatusPending, delivery.StatusDelivered, delivery.StatusSkipped, delivery.StatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("status must be one of: pending, delivered, skipped, failed", nil))
+		return
+	}
+
+	if filter.APIKey != "" {
+		if tokenID := appconfig.Instance.AuthorizationService.GetTokenID(filter.APIKey); tokenID != "" {
+			filter.APIKey = tokenID
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("limit must be an integer", err))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	records, err := service.Search(filter)
+	if err != nil {
+		logging.Errorf("Error searching events delivery records: %v", err)
+		c.JSON(http.StatusInternalServerError, middleware.ErrResponse("Error searching events delivery records", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, DeliveryRecordsResponse{Records: records})
+}
@@ -52,7 +52,9 @@ func (dh *DestinationsHandler) Handler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
 		return
 	}
-	err := testDestinationConnection(destinationConfig, dh.userRecognition)
+	diagnostics := &connectionDiagnostics{}
+	err := testDestinationConnection(destinationConfig, dh.userRecognition, diagnostics)
+	result := diagnostics.result(err)
 	if err != nil {
 		msg := err.Error()
 		if strings.Contains(err.Error(), "i/o timeout") || storages.IsConnectionError(err) {
@@ -60,16 +62,20 @@ func (dh *DestinationsHandler) Handler(c *gin.Context) {
 		}
 		errorId := uuid.NewLettersNumbers()
 		logging.Errorf("Testing Destination %s Error ID:%s Config:%s : %v", destinationConfig.Type, errorId, destinationConfig.Config, err)
-		c.JSON(http.StatusBadRequest, middleware.ErrResponse("", fmt.Errorf("Error ID:%s: %s", errorId, msg)))
+		response := middleware.ErrResponse("", fmt.Errorf("Error ID:%s: %s", errorId, msg))
+		response.Payload = result
+		c.JSON(http.StatusBadRequest, response)
 		return
 	}
-	c.Status(http.StatusOK)
+	c.JSON(http.StatusOK, result)
 }
 
 // testDestinationConnection creates default table with 2 fields (eventn_ctx key and timestamp)
 // depends on the destination type calls destination test connection func
+// every check (host reachability, authentication, schema and table creation, staging write, test insert, cleanup)
+// is recorded in diagnostics as a separate step
 // returns err if has occurred
-func testDestinationConnection(config *config.DestinationConfig, globalConfiguration *config.UsersRecognition) error {
+func testDestinationConnection(config *config.DestinationConfig, globalConfiguration *config.UsersRecognition, diagnostics *connectionDiagnostics) error {
 	uniqueIDField := appconfig.Instance.GlobalUniqueIDField.GetFlatFieldName()
 	if config.DataLayout != nil && config.DataLayout.UniqueIDField != "" {
 		uniqueIDField = identifiers.NewUniqueID(config.DataLayout.UniqueIDField).GetFlatFieldName()
@@ -103,31 +109,31 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 			uniqueIDField: typing.SQLColumn{Type: "text"},
 			timestamp.Key: typing.SQLColumn{Type: "timestamp"},
 		}
-		return testPostgres(config, eventContext)
+		return testPostgres(config, eventContext, diagnostics)
 	case storages.ClickHouseType:
 		eventContext.Table.Columns = adapters.Columns{
 			uniqueIDField: typing.SQLColumn{Type: "String"},
 			timestamp.Key: typing.SQLColumn{Type: "DateTime"},
 		}
-		return testClickHouse(config, eventContext)
+		return testClickHouse(config, eventContext, diagnostics)
 	case storages.RedshiftType:
 		eventContext.Table.Columns = adapters.Columns{
 			uniqueIDField: typing.SQLColumn{Type: "text"},
 			timestamp.Key: typing.SQLColumn{Type: "timestamp"},
 		}
-		return testRedshift(config, eventContext)
+		return testRedshift(config, eventContext, diagnostics)
 	case storages.BigQueryType:
 		eventContext.Table.Columns = adapters.Columns{
 			uniqueIDField: typing.SQLColumn{Type: string(bigquery.StringFieldType)},
 			timestamp.Key: typing.SQLColumn{Type: string(bigquery.TimestampFieldType)},
 		}
-		return testBigQuery(config, eventContext)
+		return testBigQuery(config, eventContext, diagnostics)
 	case storages.SnowflakeType:
 		eventContext.Table.Columns = adapters.Columns{
 			uniqueIDField: typing.SQLColumn{Type: "text"},
 			timestamp.Key: typing.SQLColumn{Type: "timestamp(6)"},
 		}
-		return testSnowflake(config, eventContext)
+		return testSnowflake(config, eventContext, diagnostics)
 	case storages.GoogleAnalyticsType:
 		cfg := &adapters.GoogleAnalyticsConfig{}
 		if err := config.GetDestConfig(config.GoogleAnalytics, cfg); err != nil {
@@ -140,8 +146,7 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 			return err
 		}
 		fbAdapter := adapters.NewTestFacebookConversion(cfg)
-
-		return fbAdapter.TestAccess()
+		return diagnostics.run(accessStep, fbAdapter.TestAccess)
 	case storages.WebHookType:
 		cfg := &adapters.WebHookConfig{}
 		if err := config.GetDestConfig(config.WebHook, cfg); err != nil {
@@ -161,49 +166,68 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 			return err
 		}
 		amplitudeAdapter := adapters.NewTestAmplitude(cfg)
-		return amplitudeAdapter.TestAccess()
+		return diagnostics.run(accessStep, amplitudeAdapter.TestAccess)
 	case storages.HubSpotType:
 		cfg := &adapters.HubSpotConfig{}
 		if err := config.GetDestConfig(config.HubSpot, cfg); err != nil {
 			return err
 		}
 		hubspotAdapter := adapters.NewTestHubSpot(cfg)
-		return hubspotAdapter.TestAccess()
+		return diagnostics.run(accessStep, hubspotAdapter.TestAccess)
 	case storages.DbtCloudType:
 		cfg := &adapters.DbtCloudConfig{}
 		if err := config.GetDestConfig(config.DbtCloud, cfg); err != nil {
 			return err
 		}
 		dbtCloudAdapter := adapters.NewTestDbtCloud(cfg)
-		return dbtCloudAdapter.TestAccess()
+		return diagnostics.run(accessStep, dbtCloudAdapter.TestAccess)
 	case storages.MySQLType:
 		eventContext.Table.Columns = adapters.Columns{
 			uniqueIDField: typing.SQLColumn{Type: "text"},
 			timestamp.Key: typing.SQLColumn{Type: "DATETIME"},
 		}
-		return testMySQL(config, eventContext)
+		return testMySQL(config, eventContext, diagnostics)
 	case storages.S3Type:
 		s3config := &adapters.S3Config{}
 		if err := config.GetDestConfig(config.S3, s3config); err != nil {
 			return err
 		}
-		s3Adapter, err := adapters.NewS3(s3config)
-		if err != nil {
+		diagnostics.plan(authStep, writeStep)
+		var s3Adapter *adapters.S3
+		if err := diagnostics.run(authStep, func() (err error) {
+			s3Adapter, err = adapters.NewS3(s3config)
+			return err
+		}); err != nil {
 			return err
 		}
 		defer s3Adapter.Close()
-		return s3Adapter.ValidateWritePermission()
+		return diagnostics.run(writeStep, s3Adapter.ValidateWritePermission)
 	case storages.GCSType:
 		googleConfig := &adapters.GoogleConfig{}
 		if err := config.GetDestConfig(config.Google, googleConfig); err != nil {
 			return err
 		}
-		gcsAdapter, err := adapters.NewGoogleCloudStorage(context.Background(), googleConfig)
-		if err != nil {
+		diagnostics.plan(authStep, writeStep)
+		var gcsAdapter *adapters.GoogleCloudStorage
+		if err := diagnostics.run(authStep, func() (err error) {
+			gcsAdapter, err = adapters.NewGoogleCloudStorage(context.Background(), googleConfig)
+			return err
+		}); err != nil {
 			return err
 		}
 		defer gcsAdapter.Close()
-		return gcsAdapter.ValidateWritePermission()
+		return diagnostics.run(writeStep, gcsAdapter.ValidateWritePermission)
+	case storages.GoogleSheetsType:
+		sheetsConfig := &adapters.GoogleSheetsConfig{}
+		if err := config.GetDestConfig(map[string]interface{}{}, sheetsConfig); err != nil {
+			return err
+		}
+		sheetsAdapter, err := adapters.NewGoogleSheets(context.Background(), sheetsConfig)
+		if err != nil {
+			return err
+		}
+		defer sheetsAdapter.Close()
+		return diagnostics.run(accessStep, sheetsAdapter.Test)
 	case storages.NpmType:
 		plugin := &templates.DestinationPlugin{
 			Package: config.Package,
@@ -218,7 +242,7 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 		}
 
 		defer executor.Close()
-		return executor.Validate()
+		return diagnostics.run(accessStep, executor.Validate)
 	default:
 		return errors.New("unsupported destination type " + config.Type)
 	}
@@ -226,7 +250,7 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 
 // testPostgres connects to Postgres, creates table, write 1 test record, deletes table
 // returns err if has occurred
-func testPostgres(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
+func testPostgres(config *config.DestinationConfig, eventContext *adapters.EventContext, diagnostics *connectionDiagnostics) error {
 	dataSourceConfig := &adapters.DataSourceConfig{}
 	if err := config.GetDestConfig(config.DataSource, dataSourceConfig); err != nil {
 		return err
@@ -254,45 +278,52 @@ func testPostgres(config *config.DestinationConfig, eventContext *adapters.Event
 		}
 	}()
 
-	postgres, err := adapters.NewPostgres(context.Background(), dataSourceConfig, &logging.QueryLogger{}, typing.SQLTypes{})
-	if err != nil {
+	diagnostics.plan(resolveHostStep, tcpConnectStep, authStep, createSchemaStep, createTableStep, insertStep, cleanupStep)
+	if err := diagnostics.reach(dataSourceConfig.Host, dataSourceConfig.Port, ""); err != nil {
 		return err
 	}
 
-	//create db schema if doesn't exist
-	err = postgres.CreateDbSchema(dataSourceConfig.Schema)
-	if err != nil {
-		postgres.Close()
+	var postgres *adapters.Postgres
+	if err := diagnostics.run(authStep, func() (err error) {
+		postgres, err = adapters.NewPostgres(context.Background(), dataSourceConfig, &logging.QueryLogger{}, typing.SQLTypes{})
+		return err
+	}); err != nil {
 		return err
 	}
+	defer postgres.Close()
 
-	if err = postgres.CreateTable(eventContext.Table); err != nil {
-		postgres.Close()
+	//create db schema if doesn't exist
+	if err := diagnostics.run(createSchemaStep, func() error {
+		return postgres.CreateDbSchema(dataSourceConfig.Schema)
+	}); err != nil {
 		return err
 	}
 
-	defer func() {
-		if err := postgres.DropTable(eventContext.Table); err != nil {
-			logging.Errorf("Error dropping table in test connection: %v", err)
-		}
-
-		postgres.Close()
-	}()
-
-	if err = postgres.Insert(adapters.NewSingleInsertContext(eventContext)); err != nil {
+	if err := diagnostics.run(createTableStep, func() error {
+		return postgres.CreateTable(eventContext.Table)
+	}); err != nil {
 		return err
 	}
 
-	return nil
+	defer diagnostics.cleanup(cleanupStep, func() error {
+		return cleanupTestObjects(postgres, eventContext.Table, nil, "")
+	})
+
+	return diagnostics.run(insertStep, func() error {
+		return postgres.Insert(adapters.NewSingleInsertContext(eventContext))
+	})
 }
 
 // testClickHouse connects to all provided ClickHouse dsns, creates table, write 1 test record, deletes table
 // returns err if has occurred
-func testClickHouse(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
+func testClickHouse(config *config.DestinationConfig, eventContext *adapters.EventContext, diagnostics *connectionDiagnostics) error {
 	clickHouseConfig := &adapters.ClickHouseConfig{}
 	if err := config.GetDestConfig(config.ClickHouse, clickHouseConfig); err != nil {
 		return err
 	}
+	if err := clickHouseConfig.ProcessSSL(); err != nil {
+		return err
+	}
 	if config.UsersRecognition != nil && config.UsersRecognition.Enabled {
 		engine := clickHouseConfig.Engine
 		if engine != nil {
@@ -331,58 +362,105 @@ func testClickHouse(config *config.DestinationConfig, eventContext *adapters.Eve
 		return multiErr
 	}
 
+	for i := range clickHouseConfig.Dsns {
+		stepSuffix := clickHouseStepSuffix(i, len(clickHouseConfig.Dsns))
+		diagnostics.plan(resolveHostStep+stepSuffix, tcpConnectStep+stepSuffix, authStep+stepSuffix,
+			createSchemaStep+stepSuffix, createTableStep+stepSuffix, insertStep+stepSuffix, cleanupStep+stepSuffix)
+	}
+
 	for i, dsn := range clickHouseConfig.Dsns {
 		//create N tables where N=len(dsns). For testing each dsn
 		eventContext.Table.Name += strconv.Itoa(i)
 
-		dsnURL, err := url.Parse(strings.TrimSpace(dsn))
-		if err != nil {
+		stepSuffix := clickHouseStepSuffix(i, len(clickHouseConfig.Dsns))
+		if err := testClickHouseDsn(dsn, clickHouseConfig, tableStatementFactory, eventContext, diagnostics, stepSuffix); err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
+
+// testClickHouseDsn connects to ClickHouse dsn, creates table, write 1 test record, deletes table
+// returns err if has occurred
+func testClickHouseDsn(dsn string, clickHouseConfig *adapters.ClickHouseConfig, tableStatementFactory *adapters.TableStatementFactory,
+	eventContext *adapters.EventContext, diagnostics *connectionDiagnostics, stepSuffix string) error {
+	dsnURL, err := url.Parse(strings.TrimSpace(dsn))
+	if err != nil {
+		return err
+	}
+
+	if err := diagnostics.reach(dsnURL.Hostname(), clickHousePort(dsnURL), stepSuffix); err != nil {
+		return err
+	}
 
-		dsnQuery := dsnURL.Query()
-		//add custom timeout
-		dsnQuery.Set("timeout", "6s")
-		dsnURL.RawQuery = dsnQuery.Encode()
+	dsnQuery := dsnURL.Query()
+	//add custom timeout
+	dsnQuery.Set("timeout", "6s")
+	dsnURL.RawQuery = dsnQuery.Encode()
 
-		ch, err := adapters.NewClickHouse(context.Background(), dsnURL.String(),
-			clickHouseConfig.Database, clickHouseConfig.Cluster, clickHouseConfig.TLS, tableStatementFactory,
+	var ch *adapters.ClickHouse
+	if err := diagnostics.run(authStep+stepSuffix, func() (err error) {
+		ch, err = adapters.NewClickHouse(context.Background(), dsnURL.String(),
+			clickHouseConfig.Database, clickHouseConfig.Cluster, clickHouseConfig.TLS, clickHouseConfig.Pool, tableStatementFactory,
 			map[string]bool{}, &logging.QueryLogger{}, typing.SQLTypes{})
-		if err != nil {
-			return err
-		}
+		return err
+	}); err != nil {
+		return err
+	}
+	defer ch.Close()
 
-		if err := ch.CreateDB(clickHouseConfig.Database); err != nil {
-			ch.Close()
-			return err
-		}
+	if err := diagnostics.run(createSchemaStep+stepSuffix, func() error {
+		return ch.CreateDB(clickHouseConfig.Database)
+	}); err != nil {
+		return err
+	}
 
-		if err = ch.CreateTable(eventContext.Table); err != nil {
-			ch.Close()
-			return err
-		}
+	if err := diagnostics.run(createTableStep+stepSuffix, func() error {
+		return ch.CreateTable(eventContext.Table)
+	}); err != nil {
+		return err
+	}
 
-		insertErr := ch.Insert(adapters.NewSingleInsertContext(eventContext))
+	defer diagnostics.cleanup(cleanupStep+stepSuffix, func() error {
+		return cleanupTestObjects(ch, eventContext.Table, nil, "")
+	})
 
-		if err := ch.DropTable(eventContext.Table); err != nil {
-			logging.Errorf("Error dropping table in test connection: %v", err)
-		}
+	return diagnostics.run(insertStep+stepSuffix, func() error {
+		return ch.Insert(adapters.NewSingleInsertContext(eventContext))
+	})
+}
 
-		ch.Close()
+// clickHouseStepSuffix returns dsn index suffix of diagnostics steps if there are several dsns
+func clickHouseStepSuffix(i, dsnsCount int) string {
+	if dsnsCount > 1 {
+		return fmt.Sprintf("[%d]", i)
+	}
 
-		if insertErr != nil {
-			return insertErr
-		}
+	return ""
+}
+
+// clickHousePort returns port from dsn or default port of the dsn protocol
+func clickHousePort(dsnURL *url.URL) int {
+	if port, err := strconv.Atoi(dsnURL.Port()); err == nil {
+		return port
 	}
 
-	return nil
+	switch dsnURL.Scheme {
+	case "http":
+		return 8123
+	case "https":
+		return 8443
+	default:
+		return 9000
+	}
 }
 
-// testClickHouse depends on the destination mode:
+// testRedshift depends on the destination mode:
 // stream: connects to Redshift, creates table, writes 1 test record, deletes table
-// batch: connects to Redshift, S3, creates table, writes 1 test file with 1 test record, copies it to Redshift, deletes table
+// batch: connects to Redshift, S3, creates table, writes 1 test file with 1 test record, copies it to Redshift, deletes table and file
 // returns err if has occurred
-func testRedshift(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
+func testRedshift(config *config.DestinationConfig, eventContext *adapters.EventContext, diagnostics *connectionDiagnostics) error {
 	dataSourceConfig := &adapters.DataSourceConfig{}
 	if err := config.GetDestConfig(config.DataSource, dataSourceConfig); err != nil {
 		return err
@@ -418,69 +496,93 @@ func testRedshift(config *config.DestinationConfig, eventContext *adapters.Event
 	if !ok {
 		s3config = &adapters.S3Config{}
 	}
-	redshift, err := adapters.NewAwsRedshift(context.Background(), dataSourceConfig, s3config, &logging.QueryLogger{}, typing.SQLTypes{})
-	if err != nil {
+
+	batchMode := config.Mode == storages.BatchMode
+	if batchMode {
+		if err := s3config.Validate(); err != nil {
+			return err
+		}
+		diagnostics.plan(resolveHostStep, tcpConnectStep, authStep, createSchemaStep, createTableStep, stagingWriteStep, insertStep, cleanupStep)
+	} else {
+		diagnostics.plan(resolveHostStep, tcpConnectStep, authStep, createSchemaStep, createTableStep, insertStep, cleanupStep)
+	}
+
+	if err := diagnostics.reach(dataSourceConfig.Host, dataSourceConfig.Port, ""); err != nil {
 		return err
 	}
 
+	var redshift *adapters.AwsRedshift
+	if err := diagnostics.run(authStep, func() (err error) {
+		redshift, err = adapters.NewAwsRedshift(context.Background(), dataSourceConfig, s3config, &logging.QueryLogger{}, typing.SQLTypes{})
+		return err
+	}); err != nil {
+		return err
+	}
+	defer redshift.Close()
+
 	//create db schema if doesn't exist
-	err = redshift.CreateDbSchema(dataSourceConfig.Schema)
-	if err != nil {
-		redshift.Close()
+	if err := diagnostics.run(createSchemaStep, func() error {
+		return redshift.CreateDbSchema(dataSourceConfig.Schema)
+	}); err != nil {
 		return err
 	}
 
-	if err = redshift.CreateTable(eventContext.Table); err != nil {
-		redshift.Close()
+	if err := diagnostics.run(createTableStep, func() error {
+		return redshift.CreateTable(eventContext.Table)
+	}); err != nil {
 		return err
 	}
 
+	var stage adapters.Stage
+	var stagedFile string
 	defer func() {
-		if err := redshift.DropTable(eventContext.Table); err != nil {
-			logging.Errorf("Error dropping table in test connection: %v", err)
+		if stage != nil {
+			stage.Close()
 		}
-
-		redshift.Close()
 	}()
+	defer diagnostics.cleanup(cleanupStep, func() error {
+		return cleanupTestObjects(redshift, eventContext.Table, stage, stagedFile)
+	})
 
-	if config.Mode == storages.BatchMode {
-		if err := s3config.Validate(); err != nil {
-			return err
-		}
+	if !batchMode {
+		return diagnostics.run(insertStep, func() error {
+			return redshift.Insert(adapters.NewSingleInsertContext(eventContext))
+		})
+	}
 
+	if err := diagnostics.run(stagingWriteStep, func() error {
 		s3, err := adapters.NewS3(s3config)
 		if err != nil {
 			return err
 		}
-		defer s3.Close()
+		stage = s3
 
 		b, _ := json.Marshal(eventContext.ProcessedEvent)
 		if err := s3.UploadBytes(eventContext.Table.Name, b); err != nil {
 			return err
 		}
-
-		if err = redshift.Copy(eventContext.Table.Name, eventContext.Table.Name); err != nil {
-			return err
-		}
-	} else {
-		if err = redshift.Insert(adapters.NewSingleInsertContext(eventContext)); err != nil {
-			return err
-		}
+		stagedFile = eventContext.Table.Name
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	return nil
+	return diagnostics.run(insertStep, func() error {
+		return redshift.Copy(eventContext.Table.Name, eventContext.Table.Name, adapters.FileFormatJSON, nil)
+	})
 }
 
-// testClickHouse depends on the destination mode:
+// testBigQuery depends on the destination mode:
 // stream: connects to BigQuery, creates table, writes 1 test record, deletes table
-// batch: connects to BigQuery, Google Cloud Storage, creates table, writes 1 test file with 1 test record, copies it to BigQuery, deletes table
+// batch: connects to BigQuery, Google Cloud Storage, creates table, writes 1 test file with 1 test record, copies it to BigQuery, deletes table and file
 // returns err if has occurred
-func testBigQuery(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
+func testBigQuery(config *config.DestinationConfig, eventContext *adapters.EventContext, diagnostics *connectionDiagnostics) error {
 	google := &adapters.GoogleConfig{}
 	if err := config.GetDestConfig(config.Google, google); err != nil {
 		return err
 	}
-	if config.Mode == storages.BatchMode {
+	batchMode := config.Mode == storages.BatchMode
+	if batchMode {
 		if err := google.ValidateBatchMode(); err != nil {
 			return err
 		}
@@ -494,60 +596,78 @@ func testBigQuery(config *config.DestinationConfig, eventContext *adapters.Event
 		google.Dataset = "default"
 	}
 
-	bq, err := adapters.NewBigQuery(context.Background(), google, &logging.QueryLogger{}, typing.SQLTypes{})
-	if err != nil {
+	if batchMode {
+		diagnostics.plan(authStep, createSchemaStep, createTableStep, stagingWriteStep, insertStep, cleanupStep)
+	} else {
+		diagnostics.plan(authStep, createSchemaStep, createTableStep, insertStep, cleanupStep)
+	}
+
+	var bq *adapters.BigQuery
+	if err := diagnostics.run(authStep, func() (err error) {
+		bq, err = adapters.NewBigQuery(context.Background(), google, &logging.QueryLogger{}, typing.SQLTypes{})
+		return err
+	}); err != nil {
 		return err
 	}
+	defer bq.Close()
 
 	//create dataset if doesn't exist
-	err = bq.CreateDataset(google.Dataset)
-	if err != nil {
-		bq.Close()
+	if err := diagnostics.run(createSchemaStep, func() error {
+		return bq.CreateDataset(google.Dataset)
+	}); err != nil {
 		return err
 	}
 
-	if err = bq.CreateTable(eventContext.Table); err != nil {
-		bq.Close()
+	if err := diagnostics.run(createTableStep, func() error {
+		return bq.CreateTable(eventContext.Table)
+	}); err != nil {
 		return err
 	}
 
+	var stage adapters.Stage
+	var stagedFile string
 	defer func() {
-		if err := bq.DropTable(eventContext.Table); err != nil {
-			logging.Errorf("Error dropping table in test connection: %v", err)
+		if stage != nil {
+			stage.Close()
 		}
-
-		bq.Close()
 	}()
+	defer diagnostics.cleanup(cleanupStep, func() error {
+		return cleanupTestObjects(bq, eventContext.Table, stage, stagedFile)
+	})
+
+	if !batchMode {
+		return diagnostics.run(insertStep, func() error {
+			return bq.Insert(adapters.NewSingleInsertContext(eventContext))
+		})
+	}
 
-	if config.Mode == storages.BatchMode {
+	if err := diagnostics.run(stagingWriteStep, func() error {
 		googleStorage, err := adapters.NewGoogleCloudStorage(context.Background(), google)
 		if err != nil {
 			return err
 		}
-		defer googleStorage.Close()
+		stage = googleStorage
 
 		b, _ := json.Marshal(eventContext.ProcessedEvent)
 		if err := googleStorage.UploadBytes(eventContext.Table.Name, b); err != nil {
 			return err
 		}
-
-		if err = bq.Copy(eventContext.Table.Name, eventContext.Table.Name); err != nil {
-			return err
-		}
-	} else {
-		if err = bq.Insert(adapters.NewSingleInsertContext(eventContext)); err != nil {
-			return err
-		}
+		stagedFile = eventContext.Table.Name
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	return nil
+	return diagnostics.run(insertStep, func() error {
+		return bq.Copy(eventContext.Table.Name, eventContext.Table.Name, adapters.FileFormatJSON)
+	})
 }
 
-// testClickHouse depends on the destination mode:
+// testSnowflake depends on the destination mode:
 // stream: connects to Snowflake, creates table, writes 1 test record, deletes table
-// batch: connects to Snowflake, S3 or Google Cloud Storage, creates table, writes 1 test file with 1 test record, copies it to Snowflake, deletes table
+// batch: connects to Snowflake, S3, Azure Blob Storage or Google Cloud Storage, creates table, writes 1 test file with 1 test record, copies it to Snowflake, deletes table and file
 // returns err if has occurred
-func testSnowflake(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
+func testSnowflake(config *config.DestinationConfig, eventContext *adapters.EventContext, diagnostics *connectionDiagnostics) error {
 	snowflakeConfig := &adapters.SnowflakeConfig{}
 	if err := config.GetDestConfig(config.Snowflake, snowflakeConfig); err != nil {
 		return err
@@ -573,77 +693,109 @@ func testSnowflake(config *config.DestinationConfig, eventContext *adapters.Even
 	}
 	googleConfig, googleOk := gc.(*adapters.GoogleConfig)
 
-	snowflake, err := storages.CreateSnowflakeAdapter(context.Background(), s3config, *snowflakeConfig, &logging.QueryLogger{}, typing.SQLTypes{})
-	if err != nil {
+	batchMode := config.Mode == storages.BatchMode
+	if batchMode {
+		if s3config != nil {
+			if err := s3config.Validate(); err != nil {
+				return err
+			}
+		} else if snowflakeConfig.Azure != nil {
+			//with azure stage
+			if err := snowflakeConfig.Azure.Validate(); err != nil {
+				return err
+			}
+			//stage is required when azure integration
+			if snowflakeConfig.Stage == "" {
+				return errors.New("Snowflake stage is required parameter in Azure integration")
+			}
+		} else if googleOk {
+			//with google stage
+			if err := googleConfig.Validate(); err != nil {
+				return err
+			}
+			//stage is required when gcp integration
+			if snowflakeConfig.Stage == "" {
+				return errors.New("Snowflake stage is required parameter in GCP integration")
+			}
+		} else if err := s3config.Validate(); err != nil {
+			return err
+		}
+		diagnostics.plan(resolveHostStep, tcpConnectStep, authStep, createTableStep, stagingWriteStep, insertStep, cleanupStep)
+	} else {
+		diagnostics.plan(resolveHostStep, tcpConnectStep, authStep, createTableStep, insertStep, cleanupStep)
+	}
+
+	port := snowflakeConfig.Port
+	if port == 0 {
+		port = 443
+	}
+	if err := diagnostics.reach(snowflakeConfig.Account+".snowflakecomputing.com", port, ""); err != nil {
 		return err
 	}
 
-	if err = snowflake.CreateTable(eventContext.Table); err != nil {
-		snowflake.Close()
+	//also creates db schema if doesn't exist
+	var snowflake *adapters.Snowflake
+	if err := diagnostics.run(authStep, func() (err error) {
+		snowflake, err = storages.CreateSnowflakeAdapter(context.Background(), s3config, *snowflakeConfig, &logging.QueryLogger{}, typing.SQLTypes{})
+		return err
+	}); err != nil {
 		return err
 	}
+	defer snowflake.Close()
 
+	if err := diagnostics.run(createTableStep, func() error {
+		return snowflake.CreateTable(eventContext.Table)
+	}); err != nil {
+		return err
+	}
+
+	var stage adapters.Stage
+	var stagedFile string
 	defer func() {
-		if err := snowflake.DropTable(eventContext.Table); err != nil {
-			logging.Errorf("Error dropping table in test connection: %v", err)
+		if stage != nil {
+			stage.Close()
 		}
-
-		snowflake.Close()
 	}()
+	defer diagnostics.cleanup(cleanupStep, func() error {
+		return cleanupTestObjects(snowflake, eventContext.Table, stage, stagedFile)
+	})
+
+	if !batchMode {
+		return diagnostics.run(insertStep, func() error {
+			return snowflake.Insert(adapters.NewSingleInsertContext(eventContext))
+		})
+	}
 
 	var header []string
 	for column := range eventContext.Table.Columns {
 		header = append(header, column)
 	}
 
-	if config.Mode == storages.BatchMode {
-		var stageAdapter adapters.Stage
-		if googleOk {
-			//with google stage
-			if err := googleConfig.Validate(); err != nil {
-				return err
-			}
-			//stage is required when gcp integration
-			if snowflakeConfig.Stage == "" {
-				return errors.New("Snowflake stage is required parameter in GCP integration")
-			}
-			stageAdapter, err = adapters.NewGoogleCloudStorage(context.Background(), googleConfig)
-			if err != nil {
-				return err
-			}
-		} else {
-			if err := s3config.Validate(); err != nil {
-				return err
-			}
-
-			stageAdapter, err = adapters.NewS3(s3config)
-			if err != nil {
-				return err
-			}
-		}
-
-		defer stageAdapter.Close()
-
-		b, _ := json.Marshal(eventContext.ProcessedEvent)
-		if err := stageAdapter.UploadBytes(eventContext.Table.Name, b); err != nil {
+	if err := diagnostics.run(stagingWriteStep, func() (err error) {
+		stage, err = storages.NewSnowflakeStage(context.Background(), snowflakeConfig, s3config, googleConfig, adapters.FileFormatCSV)
+		if err != nil {
+			stage = nil
 			return err
 		}
 
-		if err = snowflake.Copy(eventContext.Table.Name, eventContext.Table.Name, header); err != nil {
-			return err
-		}
-	} else {
-		if err = snowflake.Insert(adapters.NewSingleInsertContext(eventContext)); err != nil {
+		b, _ := json.Marshal(eventContext.ProcessedEvent)
+		if err := stage.UploadBytes(eventContext.Table.Name, b); err != nil {
 			return err
 		}
+		stagedFile = eventContext.Table.Name
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	return nil
+	return diagnostics.run(insertStep, func() error {
+		return snowflake.Copy(eventContext.Table.Name, eventContext.Table.Name, adapters.FileFormatCSV, header)
+	})
 }
 
 // testMySQL connects to MySQL, creates table, write 1 test record, deletes table
 // returns err if has occurred
-func testMySQL(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
+func testMySQL(config *config.DestinationConfig, eventContext *adapters.EventContext, diagnostics *connectionDiagnostics) error {
 	dataSourceConfig := &adapters.DataSourceConfig{}
 	if err := config.GetDestConfig(config.DataSource, dataSourceConfig); err != nil {
 		return err
@@ -655,27 +807,31 @@ func testMySQL(config *config.DestinationConfig, eventContext *adapters.EventCon
 
 	dataSourceConfig.Parameters["timeout"] = "6s"
 
-	mysql, err := storages.CreateMySQLAdapter(context.Background(), *dataSourceConfig, &logging.QueryLogger{}, typing.SQLTypes{})
-	if err != nil {
+	diagnostics.plan(resolveHostStep, tcpConnectStep, authStep, createTableStep, insertStep, cleanupStep)
+	if err := diagnostics.reach(dataSourceConfig.Host, dataSourceConfig.Port, ""); err != nil {
 		return err
 	}
 
-	if err = mysql.CreateTable(eventContext.Table); err != nil {
-		mysql.Close()
+	var mysql *adapters.MySQL
+	if err := diagnostics.run(authStep, func() (err error) {
+		mysql, err = storages.CreateMySQLAdapter(context.Background(), *dataSourceConfig, &logging.QueryLogger{}, typing.SQLTypes{})
+		return err
+	}); err != nil {
 		return err
 	}
+	defer mysql.Close()
 
-	defer func() {
-		if err := mysql.DropTable(eventContext.Table); err != nil {
-			logging.Errorf("Error dropping table in test connection: %v", err)
-		}
-
-		mysql.Close()
-	}()
-
-	if err = mysql.Insert(adapters.NewSingleInsertContext(eventContext)); err != nil {
+	if err := diagnostics.run(createTableStep, func() error {
+		return mysql.CreateTable(eventContext.Table)
+	}); err != nil {
 		return err
 	}
 
-	return nil
+	defer diagnostics.cleanup(cleanupStep, func() error {
+		return cleanupTestObjects(mysql, eventContext.Table, nil, "")
+	})
+
+	return diagnostics.run(insertStep, func() error {
+		return mysql.Insert(adapters.NewSingleInsertContext(eventContext))
+	})
 }
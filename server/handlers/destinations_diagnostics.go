@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	StepStatusOK      = "ok"
+	StepStatusFailed  = "failed"
+	StepStatusSkipped = "skipped"
+
+	//configStep is reported only if the destination configuration is invalid
+	configStep       = "config"
+	resolveHostStep  = "resolve_host"
+	tcpConnectStep   = "tcp_connect"
+	authStep         = "auth"
+	accessStep       = "access"
+	createSchemaStep = "create_schema"
+	createTableStep  = "create_table"
+	stagingWriteStep = "staging_write"
+	writeStep        = "write"
+	insertStep       = "insert"
+	cleanupStep      = "cleanup"
+
+	reachTimeout = 6 * time.Second
+)
+
+//ConnectionTestStep is a result of a single destination connection check
+type ConnectionTestStep struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+//ConnectionTestResult is a structured result of destination connection test
+type ConnectionTestResult struct {
+	Status string                `json:"status"`
+	Steps  []*ConnectionTestStep `json:"steps"`
+}
+
+//tableDropper is a destination adapter which can drop the test table
+type tableDropper interface {
+	DropTable(table *adapters.Table) error
+}
+
+//connectionDiagnostics runs connection test steps one by one and collects their results.
+//Planned steps which haven't been run because of a previous failure are reported as skipped
+type connectionDiagnostics struct {
+	planned []string
+	steps   []*ConnectionTestStep
+	failed  bool
+}
+
+//plan declares steps of the destination test in order of execution
+func (cd *connectionDiagnostics) plan(steps ...string) {
+	cd.planned = append(cd.planned, steps...)
+}
+
+//run runs the check and records its result as a step. Returns the check error
+func (cd *connectionDiagnostics) run(name string, check func() error) error {
+	start := time.Now()
+	err := check()
+	step := &ConnectionTestStep{Name: name, Status: StepStatusOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		step.Status = StepStatusFailed
+		step.Error = err.Error()
+		cd.failed = true
+	}
+
+	cd.steps = append(cd.steps, step)
+	return err
+}
+
+//cleanup runs the check as a step which doesn't fail the connection test (e.g. dropping the test table). Failure is only reported
+func (cd *connectionDiagnostics) cleanup(name string, check func() error) {
+	failed := cd.failed
+	if err := cd.run(name, check); err != nil {
+		logging.Errorf("Error cleaning up in test connection: %v", err)
+	}
+	cd.failed = failed
+}
+
+//reach runs DNS resolution and TCP connection steps
+func (cd *connectionDiagnostics) reach(host string, port int, stepSuffix string) error {
+	if err := cd.run(resolveHostStep+stepSuffix, func() error {
+		_, err := net.LookupHost(host)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return cd.run(tcpConnectStep+stepSuffix, func() error {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), reachTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+//result returns steps results. If err isn't a result of any step, it is reported as invalid configuration
+func (cd *connectionDiagnostics) result(err error) *ConnectionTestResult {
+	var steps []*ConnectionTestStep
+	if err != nil && !cd.failed {
+		steps = append(steps, &ConnectionTestStep{Name: configStep, Status: StepStatusFailed, Error: err.Error()})
+	}
+	steps = append(steps, cd.steps...)
+
+	executed := make(map[string]bool, len(cd.steps))
+	for _, step := range cd.steps {
+		executed[step.Name] = true
+	}
+	for _, name := range cd.planned {
+		if !executed[name] {
+			steps = append(steps, &ConnectionTestStep{Name: name, Status: StepStatusSkipped})
+		}
+	}
+
+	result := &ConnectionTestResult{Status: StepStatusOK, Steps: steps}
+	if err != nil {
+		result.Status = StepStatusFailed
+	}
+
+	return result
+}
+
+//cleanupTestObjects drops the test table and deletes the test file from the stage if it has been uploaded
+func cleanupTestObjects(dropper tableDropper, table *adapters.Table, stage adapters.Stage, fileName string) error {
+	var multiErr error
+	if err := dropper.DropTable(table); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("error dropping test table: %v", err))
+	}
+
+	if stage != nil && fileName != "" {
+		if err := stage.DeleteObject(fileName); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("error deleting test file from stage: %v", err))
+		}
+	}
+
+	return multiErr
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+//stepsStatuses returns (name, status) pairs of the steps
+func stepsStatuses(steps []*ConnectionTestStep) [][2]string {
+	var result [][2]string
+	for _, step := range steps {
+		result = append(result, [2]string{step.Name, step.Status})
+	}
+	return result
+}
+
+func TestConnectionDiagnostics(t *testing.T) {
+	diagnostics := &connectionDiagnostics{}
+	diagnostics.plan(authStep, createTableStep, insertStep, cleanupStep)
+
+	require.NoError(t, diagnostics.run(authStep, func() error { return nil }))
+	err := diagnostics.run(createTableStep, func() error { return errors.New("permission denied") })
+	require.EqualError(t, err, "permission denied")
+
+	result := diagnostics.result(err)
+	require.Equal(t, StepStatusFailed, result.Status)
+	require.Equal(t, [][2]string{
+		{authStep, StepStatusOK},
+		{createTableStep, StepStatusFailed},
+		{insertStep, StepStatusSkipped},
+		{cleanupStep, StepStatusSkipped},
+	}, stepsStatuses(result.Steps))
+	require.Equal(t, "permission denied", result.Steps[1].Error)
+	require.Empty(t, result.Steps[2].Error)
+}
+
+func TestConnectionDiagnosticsCleanupDoesntFail(t *testing.T) {
+	diagnostics := &connectionDiagnostics{}
+	diagnostics.plan(insertStep, cleanupStep)
+
+	err := diagnostics.run(insertStep, func() error { return nil })
+	diagnostics.cleanup(cleanupStep, func() error { return errors.New("error dropping test table") })
+
+	result := diagnostics.result(err)
+	require.Equal(t, StepStatusOK, result.Status)
+	require.Equal(t, [][2]string{{insertStep, StepStatusOK}, {cleanupStep, StepStatusFailed}}, stepsStatuses(result.Steps))
+}
+
+func TestConnectionDiagnosticsConfigError(t *testing.T) {
+	diagnostics := &connectionDiagnostics{}
+	diagnostics.plan(authStep)
+
+	result := diagnostics.result(errors.New("host is required"))
+	require.Equal(t, StepStatusFailed, result.Status)
+	require.Equal(t, [][2]string{{configStep, StepStatusFailed}, {authStep, StepStatusSkipped}}, stepsStatuses(result.Steps))
+	require.Equal(t, "host is required", result.Steps[0].Error)
+}
+
+func TestDestinationsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("server.log.path", "")
+	viper.Set("sql_debug_log.ddl.enabled", false)
+	require.NoError(t, appconfig.Init(false, ""))
+
+	//port without listener: connection is refused
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedPort := closedListener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, closedListener.Close())
+
+	//listener which isn't Postgres: connection is closed right after accepting
+	notPostgres, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer notPostgres.Close()
+	go func() {
+		for {
+			conn, err := notPostgres.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	notPostgresPort := notPostgres.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name           string
+		config         map[string]interface{}
+		expectedStatus string
+		expectedSteps  [][2]string
+	}{
+		{
+			"Invalid configuration",
+			map[string]interface{}{"type": "postgres", "datasource": map[string]interface{}{"db": "test", "username": "test"}},
+			StepStatusFailed,
+			[][2]string{{configStep, StepStatusFailed}},
+		},
+		{
+			"Unsupported type",
+			map[string]interface{}{"type": "unknown"},
+			StepStatusFailed,
+			[][2]string{{configStep, StepStatusFailed}},
+		},
+		{
+			"TCP connection failure",
+			map[string]interface{}{"type": "postgres", "datasource": map[string]interface{}{"host": "127.0.0.1", "port": closedPort, "db": "test", "username": "test"}},
+			StepStatusFailed,
+			[][2]string{
+				{resolveHostStep, StepStatusOK},
+				{tcpConnectStep, StepStatusFailed},
+				{authStep, StepStatusSkipped},
+				{createSchemaStep, StepStatusSkipped},
+				{createTableStep, StepStatusSkipped},
+				{insertStep, StepStatusSkipped},
+				{cleanupStep, StepStatusSkipped},
+			},
+		},
+		{
+			"Authentication failure",
+			map[string]interface{}{"type": "postgres", "datasource": map[string]interface{}{"host": "127.0.0.1", "port": notPostgresPort, "db": "test", "username": "test"}},
+			StepStatusFailed,
+			[][2]string{
+				{resolveHostStep, StepStatusOK},
+				{tcpConnectStep, StepStatusOK},
+				{authStep, StepStatusFailed},
+				{createSchemaStep, StepStatusSkipped},
+				{createTableStep, StepStatusSkipped},
+				{insertStep, StepStatusSkipped},
+				{cleanupStep, StepStatusSkipped},
+			},
+		},
+		{
+			"No checks",
+			map[string]interface{}{"type": "webhook", "webhook": map[string]interface{}{"url": "https://example.com", "method": "POST"}},
+			StepStatusOK,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/api/v1/destinations/test", NewDestinationsHandler(nil).Handler)
+
+			body, err := json.Marshal(tt.config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/destinations/test", bytes.NewReader(body)))
+
+			result := &ConnectionTestResult{}
+			if tt.expectedStatus == StepStatusOK {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), result))
+			} else {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				response := &struct {
+					Message string                `json:"message"`
+					Payload *ConnectionTestResult `json:"payload"`
+				}{}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), response))
+				require.Contains(t, response.Message, "Error ID:")
+				require.NotNil(t, response.Payload)
+				result = response.Payload
+			}
+
+			require.Equal(t, tt.expectedStatus, result.Status)
+			require.Equal(t, tt.expectedSteps, stepsStatuses(result.Steps))
+			for _, step := range result.Steps {
+				if step.Status == StepStatusFailed {
+					require.NotEmpty(t, step.Error, "failed step must contain the error")
+				} else {
+					require.Empty(t, step.Error)
+				}
+			}
+		})
+	}
+}
@@ -60,10 +60,11 @@ func (drh *DryRunHandler) Handle(c *gin.Context) {
 	//get geo resolver
 	geoResolver := drh.geoService.GetGeoResolver(storageProxy.GetGeoResolverID())
 
-	reqContext := getRequestContext(c, geoResolver, payload)
+	token := c.GetString(middleware.TokenName)
+	reqContext := getRequestContext(c, geoResolver, token, payload)
 
 	//** Context enrichment **
-	enrichment.ContextEnrichmentStep(payload, c.GetString(middleware.TokenName), reqContext, drh.preprocessor, storage.GetUniqueIDField())
+	enrichment.ContextEnrichmentStep(payload, token, reqContext, drh.preprocessor, storage.GetUniqueIDField())
 	enrichment.HTTPContextEnrichmentStep(c, payload)
 
 	dataSchema, err := storage.DryRun(payload)
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/erasure"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+)
+
+//ErasureReportsResponse is a response of all personal data erasure reports
+type ErasureReportsResponse struct {
+	Reports []*erasure.Report `json:"reports"`
+}
+
+//ErasureHandler handles personal data erasure (right to be forgotten) requests
+type ErasureHandler struct {
+	erasureService *erasure.Service
+}
+
+func NewErasureHandler(erasureService *erasure.Service) *ErasureHandler {
+	return &ErasureHandler{erasureService: erasureService}
+}
+
+//EraseHandler starts erasing personal data of the user from all destinations and archived files in background
+func (eh *ErasureHandler) EraseHandler(c *gin.Context) {
+	req := &erasure.Request{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing erasure body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	report, err := eh.erasureService.Erase(req)
+	if err != nil {
+		logging.Errorf("Error starting personal data erasure: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to start personal data erasure", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+//ReportsHandler returns all personal data erasure reports
+func (eh *ErasureHandler) ReportsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, ErasureReportsResponse{Reports: eh.erasureService.GetReports()})
+}
+
+//ReportHandler returns personal data erasure report by id
+func (eh *ErasureHandler) ReportHandler(c *gin.Context) {
+	reportID := c.Param("reportID")
+	report := eh.erasureService.GetReport(reportID)
+	if report == nil {
+		c.JSON(http.StatusNotFound, middleware.ErrResponse("Erasure report "+reportID+" wasn't found", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -16,10 +16,17 @@ import (
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/multiplexing"
+	"github.com/jitsucom/jitsu/server/quota"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/useragent"
+	"github.com/jitsucom/jitsu/server/utils"
+	"github.com/jitsucom/jitsu/server/validation"
 	"github.com/jitsucom/jitsu/server/wal"
 )
 
@@ -87,6 +94,9 @@ func NewEventHandler(writeAheadLogService *wal.Service, multiplexingService *mul
 
 //PostHandler accepts all events according to token
 func (eh *EventHandler) PostHandler(c *gin.Context) {
+	//ask browsers to send high entropy client hints with the next requests
+	c.Header("Accept-CH", useragent.AcceptClientHints)
+
 	iface, ok := c.Get(middleware.TokenName)
 	if !ok {
 		logging.SystemError("Token wasn't found in the context")
@@ -112,6 +122,7 @@ func (eh *EventHandler) PostHandler(c *gin.Context) {
 		return
 	}
 
+	metrics.ReceivedTokenEvents(tokenID, len(eventsArray))
 	for _, event := range eventsArray {
 		enrichment.HTTPContextEnrichmentStep(c, event)
 	}
@@ -122,7 +133,7 @@ func (eh *EventHandler) PostHandler(c *gin.Context) {
 		geoResolver = eh.geoService.GetGeoResolver(destinationStorages[0].GetGeoResolverID())
 	}
 
-	reqContext := getRequestContext(c, geoResolver, eventsArray...)
+	reqContext := getRequestContext(c, geoResolver, token, eventsArray...)
 
 	//put all events to write-ahead-log if idle
 	if appstatus.Instance.Idle.Load() {
@@ -132,7 +143,7 @@ func (eh *EventHandler) PostHandler(c *gin.Context) {
 		return
 	}
 
-	extras, err := eh.multiplexingService.AcceptRequest(eh.processor, reqContext, token, eventsArray)
+	extras, err := eh.multiplexingService.AcceptRequest(c.Request.Context(), eh.processor, reqContext, token, eventsArray)
 	if err != nil {
 		if err == multiplexing.ErrNoDestinations {
 			eh.CacheRawEvents(eventsArray, cachingDisabled, tokenID, fmt.Errorf(noDestinationsErrTemplate, token), nil)
@@ -140,15 +151,32 @@ func (eh *EventHandler) PostHandler(c *gin.Context) {
 			return
 		}
 		eh.CacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, err)
+		if validationErr, ok := err.(*validation.Error); ok {
+			c.JSON(http.StatusUnprocessableEntity, middleware.ErrResponse("", utils.NewRichError(validationErr.Error(), validationErr.Events)))
+			return
+		}
+		if quotaErr, ok := err.(*quota.ExceededError); ok {
+			quotaExceededResponse(c, quotaErr)
+			return
+		}
 		reqBody, _ := json.Marshal(eventsArray)
 		logging.Warnf("%v. Event: %s", err, string(reqBody))
 		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
 		return
 	}
 	eh.CacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, nil)
+	if warning := quota.Warning(tokenID); warning != "" {
+		c.Header(quota.WarningHeader, warning)
+	}
 	c.JSON(http.StatusOK, EventResponse{Status: "ok", DeleteCookie: !reqContext.CookiesLawCompliant, SdkExtras: extras})
 }
 
+//quotaExceededResponse writes 429 response with Retry-After header (seconds until the quota period end)
+func quotaExceededResponse(c *gin.Context, quotaErr *quota.ExceededError) {
+	c.Header("Retry-After", strconv.Itoa(int(quotaErr.RetryAfter.Seconds())+1))
+	c.JSON(http.StatusTooManyRequests, middleware.ErrResponse(quotaErr.Error(), nil))
+}
+
 //GetHandler returns cached events by destination_ids
 func (eh *EventHandler) GetHandler(c *gin.Context) {
 	var err error
@@ -201,17 +229,7 @@ func (eh *EventHandler) GetHandler(c *gin.Context) {
 	for _, id := range strings.Split(ids, ",") {
 		eventsArray, lastMinuteLimited := eh.eventsCache.Get(namespace, id, status, limit)
 		for _, event := range eventsArray {
-			response.Events = append(response.Events, CachedEvent{
-				Original:      []byte(event.Original),
-				Success:       []byte(event.Success),
-				Malformed:     event.Malformed,
-				Error:         event.Error,
-				Skip:          event.Skip,
-				Timestamp:     event.Timestamp,
-				UID:           event.UID,
-				DestinationID: event.DestinationID,
-				TokenID:       event.TokenID,
-			})
+			response.Events = append(response.Events, newCachedEvent(event))
 		}
 		response.ResponseEvents += len(eventsArray)
 		response.LastMinuteLimited += lastMinuteLimited
@@ -221,6 +239,21 @@ func (eh *EventHandler) GetHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+//newCachedEvent returns CachedEvent dto from meta.Event
+func newCachedEvent(event meta.Event) CachedEvent {
+	return CachedEvent{
+		Original:      []byte(event.Original),
+		Success:       []byte(event.Success),
+		Malformed:     event.Malformed,
+		Error:         event.Error,
+		Skip:          event.Skip,
+		Timestamp:     event.Timestamp,
+		UID:           event.UID,
+		DestinationID: event.DestinationID,
+		TokenID:       event.TokenID,
+	}
+}
+
 func (eh *EventHandler) CacheRawEvents(eventsArray []events.Event, cachingDisabled bool, tokenID string, skip error, err error) {
 	for _, e := range eventsArray {
 		serializedPayload, _ := json.Marshal(e)
@@ -250,14 +283,17 @@ func extractIP(c *gin.Context, eventPayloads ...events.Event) string {
 	return middleware.ExtractIP(c)
 }
 
-func getRequestContext(c *gin.Context, geoResolver geo.Resolver, eventPayloads ...events.Event) *events.RequestContext {
+func getRequestContext(c *gin.Context, geoResolver geo.Resolver, token string, eventPayloads ...events.Event) *events.RequestContext {
 	clientIP := extractIP(c, eventPayloads...)
 	var compliant *bool
 	cookiesLawCompliant := true
 
 	//cookies
 	cookiePolicy := c.Query(middleware.CookiePolicyParameter)
-	if cookiePolicy != "" {
+	if appconfig.Instance.Cookieless {
+		//cookie-less mode overrides cookie_policy
+		cookiesLawCompliant = false
+	} else if cookiePolicy != "" {
 		switch cookiePolicy {
 		case middleware.ComplyValue:
 			value := complyWithCookieLaws(geoResolver, clientIP)
@@ -271,7 +307,13 @@ func getRequestContext(c *gin.Context, geoResolver geo.Resolver, eventPayloads .
 			logging.SystemErrorf("Unknown value %q for %q query parameter", middleware.CookiePolicyParameter, cookiePolicy)
 		}
 	}
-	hashedAnonymousID := fmt.Sprintf("%x", md5.Sum([]byte(clientIP+c.Request.UserAgent())))
+	var hashedAnonymousID string
+	if appconfig.Instance.Cookieless {
+		//rotating ID instead of a stable fingerprint
+		hashedAnonymousID = identifiers.CookielessAnonymousID(appconfig.Instance.CookielessSalt, clientIP, c.Request.UserAgent(), timestamp.Now())
+	} else {
+		hashedAnonymousID = fmt.Sprintf("%x", md5.Sum([]byte(clientIP+c.Request.UserAgent())))
+	}
 
 	var jitsuAnonymousID string
 	if !cookiesLawCompliant {
@@ -300,6 +342,9 @@ func getRequestContext(c *gin.Context, geoResolver geo.Resolver, eventPayloads .
 		}
 	}
 
+	//API key ip handling: is applied before events are cached and archived
+	clientIP = enrichment.APIKeyIPHandlingStep(appconfig.Instance.AuthorizationService.GetIPHandling(token), clientIP, geoResolver, eventPayloads...)
+
 	return &events.RequestContext{
 		UserAgent:           c.Request.UserAgent(),
 		ClientIP:            clientIP,
@@ -307,6 +352,7 @@ func getRequestContext(c *gin.Context, geoResolver geo.Resolver, eventPayloads .
 		JitsuAnonymousID:    jitsuAnonymousID,
 		HashedAnonymousID:   hashedAnonymousID,
 		CookiesLawCompliant: cookiesLawCompliant,
+		ClientHints:         useragent.ParseClientHints(c.Request.Header),
 	}
 }
 
@@ -320,13 +366,19 @@ func getThreeOctets(ip string) string {
 func complyWithCookieLaws(geoResolver geo.Resolver, ip string) bool {
 	ipThreeOctets := getThreeOctets(ip)
 
-	if geoResolver.Type() != geo.MaxmindType {
+	if geoResolver.Type() == geo.DummyType {
 		return false
 	}
 
 	data, err := geoResolver.Resolve(ipThreeOctets)
 	if err != nil {
-		logging.SystemErrorf("complying failed to resolve IP %q into geo data: %v", ipThreeOctets, err)
+		if err != geo.ErrRateLimited {
+			logging.SystemErrorf("complying failed to resolve IP %q into geo data: %v", ipThreeOctets, err)
+		}
+		return false
+	}
+
+	if data == nil || data.Country == "" {
 		return false
 	}
 
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/middleware"
+)
+
+const (
+	tailEventName         = "event"
+	tailHeartbeatName     = "heartbeat"
+	tailHeartbeatInterval = 15 * time.Second
+)
+
+//TailHeartbeat is a dto for periodical live tail heartbeat message
+type TailHeartbeat struct {
+	Dropped uint64 `json:"dropped"`
+}
+
+//EventsTailHandler streams incoming (token namespace) and processed (destination namespace) events in real time
+type EventsTailHandler struct {
+	eventsCache *caching.EventsCache
+}
+
+//NewEventsTailHandler returns configured EventsTailHandler
+func NewEventsTailHandler(eventsCache *caching.EventsCache) *EventsTailHandler {
+	return &EventsTailHandler{eventsCache: eventsCache}
+}
+
+//Handler streams events by token or destination ids as Server-Sent Events until the client disconnects
+//query parameters:
+//ids - required comma separated token or destination ids
+//namespace - token or destination (default)
+//status - error or empty value
+//filter - substring which must be present in the event
+//backlog - number of the last cached events per id to send before live events
+func (eth *EventsTailHandler) Handler(c *gin.Context) {
+	ids := c.Query("ids")
+	if ids == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("ids is required parameter", nil))
+		return
+	}
+
+	namespace := c.Query("namespace")
+	if namespace == "" {
+		namespace = meta.EventsDestinationNamespace
+	}
+	if namespace != meta.EventsDestinationNamespace && namespace != meta.EventsTokenNamespace {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("namespace query parameter can be only 'token' or 'destination'. Current value: %s", namespace), nil))
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && status != meta.EventsErrorStatus {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("status query parameter can be only %q or not specified. Current value: %s", meta.EventsErrorStatus, status), nil))
+		return
+	}
+
+	backlog := 0
+	if backlogStr := c.Query("backlog"); backlogStr != "" {
+		var err error
+		backlog, err = strconv.Atoi(backlogStr)
+		if err != nil || backlog < 0 {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("backlog must be positive int", nil))
+			return
+		}
+	}
+
+	idsArray := strings.Split(ids, ",")
+	filter := &caching.TailFilter{Status: status, Contains: c.Query("filter")}
+	liveTail := eth.eventsCache.GetLiveTail()
+	//subscribe before reading the backlog for not losing events in between (they might be sent twice)
+	subscription := liveTail.Subscribe(namespace, idsArray, filter)
+	defer liveTail.Unsubscribe(subscription)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	//disable proxy buffering (e.g. nginx)
+	c.Header("X-Accel-Buffering", "no")
+
+	recent := eth.getRecentEvents(namespace, idsArray, status, backlog, filter)
+	heartbeat := time.NewTicker(tailHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	//send headers right away for clients which wait for the response
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		if len(recent) > 0 {
+			c.SSEvent(tailEventName, newCachedEvent(recent[0]))
+			recent = recent[1:]
+			return true
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-subscription.Events:
+			c.SSEvent(tailEventName, newCachedEvent(event))
+		case <-heartbeat.C:
+			c.SSEvent(tailHeartbeatName, TailHeartbeat{Dropped: subscription.Dropped()})
+		}
+		return true
+	})
+}
+
+//getRecentEvents returns the last cached events sorted from the oldest to the newest
+func (eth *EventsTailHandler) getRecentEvents(namespace string, ids []string, status string, limit int, filter *caching.TailFilter) []meta.Event {
+	if limit == 0 {
+		return nil
+	}
+
+	var recent []meta.Event
+	for _, id := range ids {
+		eventsArray, _ := eth.eventsCache.Get(namespace, id, status, limit)
+		for i := range eventsArray {
+			if filter.Matches(&eventsArray[i]) {
+				recent = append(recent, eventsArray[i])
+			}
+		}
+	}
+
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].Timestamp < recent[j].Timestamp
+	})
+
+	return recent
+}
@@ -22,6 +22,10 @@ type ReplayRequest struct {
 	SkipMalformed bool   `json:"skip_malformed"`
 }
 
+type ArchiveReplayTasksResponse struct {
+	Tasks []*fallback.ArchiveReplayTask `json:"tasks"`
+}
+
 type FallbackHandler struct {
 	fallbackService *fallback.Service
 }
@@ -61,3 +65,55 @@ func (fh *FallbackHandler) ReplayHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, middleware.OKResponse())
 }
+
+//ArchiveReplayHandler starts replaying archived events from the time range into destinations in background
+func (fh *FallbackHandler) ArchiveReplayHandler(c *gin.Context) {
+	req := &fallback.ArchiveReplayRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing archive replay body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	task, err := fh.fallbackService.ReplayArchive(req)
+	if err != nil {
+		logging.Errorf("Error starting archive replay: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to start archive replay", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+//ArchiveReplayTasksHandler returns all archive replay tasks of the current server instance
+func (fh *FallbackHandler) ArchiveReplayTasksHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, ArchiveReplayTasksResponse{Tasks: fh.fallbackService.GetArchiveReplayTasks()})
+}
+
+//ArchiveReplayTaskHandler returns archive replay task by id
+func (fh *FallbackHandler) ArchiveReplayTaskHandler(c *gin.Context) {
+	taskID := c.Param("taskID")
+	task := fh.fallbackService.GetArchiveReplayTask(taskID)
+	if task == nil {
+		c.JSON(http.StatusNotFound, middleware.ErrResponse("Archive replay task "+taskID+" wasn't found", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+//CancelArchiveReplayTaskHandler stops running archive replay task
+func (fh *FallbackHandler) CancelArchiveReplayTaskHandler(c *gin.Context) {
+	taskID := c.Param("taskID")
+	task, err := fh.fallbackService.CancelArchiveReplayTask(taskID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to cancel archive replay task", err))
+		return
+	}
+	if task == nil {
+		c.JSON(http.StatusNotFound, middleware.ErrResponse("Archive replay task "+taskID+" wasn't found", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+const (
+	//GrafanaEventsInMetric is an amount of accepted incoming events (push events from API keys and pulled events from sources)
+	GrafanaEventsInMetric = "events_in"
+	//GrafanaEventsSkippedMetric is an amount of incoming events which weren't sent to any destination
+	GrafanaEventsSkippedMetric = "events_skipped"
+	//GrafanaEventsOutMetric is an amount of events stored in destinations
+	GrafanaEventsOutMetric = "events_out"
+	//GrafanaErrorsMetric is an amount of events which haven't been stored in destinations because of errors
+	GrafanaErrorsMetric = "errors"
+	//GrafanaDestinationHealthTable is a table with in-process totals and circuit breaker state of every destination
+	GrafanaDestinationHealthTable = "destination_health"
+
+	grafanaTimeseriesType = "timeserie"
+	grafanaTableType      = "table"
+
+	//grafanaTargetDelimiter separates metric name and source/API key/destination ID in the target: events_out:dest1
+	grafanaTargetDelimiter = ":"
+
+	rollupKeyLayout = "2006-01-02T15:04:05+0000"
+)
+
+var grafanaTimeseriesMetrics = []string{GrafanaEventsInMetric, GrafanaEventsSkippedMetric, GrafanaEventsOutMetric, GrafanaErrorsMetric}
+
+//GrafanaQueryRequest is a dto for Grafana JSON datasource query request
+type GrafanaQueryRequest struct {
+	Range      GrafanaRange    `json:"range"`
+	IntervalMs int64           `json:"intervalMs"`
+	Targets    []GrafanaTarget `json:"targets"`
+}
+
+//GrafanaRange is a dashboard time range
+type GrafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+//GrafanaTarget is a dashboard panel query: metric name with optional ID suffix (e.g. events_out:dest1) and
+//response type (timeserie or table)
+type GrafanaTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Type   string `json:"type"`
+}
+
+//GrafanaTimeseries is a dto for Grafana JSON datasource timeseries response: datapoints are [value, unix time in ms] pairs
+type GrafanaTimeseries struct {
+	Target     string     `json:"target"`
+	Datapoints [][2]int64 `json:"datapoints"`
+}
+
+//GrafanaTable is a dto for Grafana JSON datasource table response
+type GrafanaTable struct {
+	Type    string               `json:"type"`
+	Columns []GrafanaTableColumn `json:"columns"`
+	Rows    [][]interface{}      `json:"rows"`
+}
+
+//GrafanaTableColumn is a table column name and type (string, number, time)
+type GrafanaTableColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+//GrafanaAnnotationRequest is a dto for Grafana JSON datasource annotations request. Annotation is the dashboard
+//annotation configuration: query is an optional destination ID
+type GrafanaAnnotationRequest struct {
+	Range      GrafanaRange           `json:"range"`
+	Annotation map[string]interface{} `json:"annotation"`
+}
+
+//GrafanaAnnotation is a dto for Grafana JSON datasource annotations response: time is unix time in ms
+type GrafanaAnnotation struct {
+	Annotation map[string]interface{} `json:"annotation"`
+	Time       int64                  `json:"time"`
+	Title      string                 `json:"title"`
+	Text       string                 `json:"text"`
+	Tags       []string               `json:"tags"`
+}
+
+//GrafanaMetric is a dto for Grafana JSON datasource metrics response
+type GrafanaMetric struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+//GrafanaHandler serves pipeline statistics in Grafana JSON datasource plugin format
+//(test connection, search/metrics, query and annotations endpoints)
+type GrafanaHandler struct {
+	metaStorage            meta.Storage
+	tokenIDs               func() []string
+	sourceIDs              func() []string
+	destinationIDs         func() []string
+	circuitBreakerStatuses func() []storages.CircuitBreakerStatus
+}
+
+//NewGrafanaHandler returns configured GrafanaHandler. ID functions return current configured API keys, sources and destinations
+func NewGrafanaHandler(metaStorage meta.Storage, tokenIDs, sourceIDs, destinationIDs func() []string) *GrafanaHandler {
+	return &GrafanaHandler{metaStorage: metaStorage, tokenIDs: tokenIDs, sourceIDs: sourceIDs, destinationIDs: destinationIDs,
+		circuitBreakerStatuses: storages.CircuitBreakerStatuses}
+}
+
+//TestHandler is used by Grafana for checking datasource connection
+func (gh *GrafanaHandler) TestHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
+
+//SearchHandler returns all available targets (SimpleJSON plugin)
+func (gh *GrafanaHandler) SearchHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gh.targets())
+}
+
+//MetricsHandler returns all available targets with labels (JSON plugin)
+func (gh *GrafanaHandler) MetricsHandler(c *gin.Context) {
+	targets := gh.targets()
+	metrics := make([]GrafanaMetric, 0, len(targets))
+	for _, target := range targets {
+		metrics = append(metrics, GrafanaMetric{Label: target, Value: target})
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+//QueryHandler returns timeseries or tables of the requested targets for the dashboard time range.
+//Data is hourly or daily (if the requested interval is at least one day)
+func (gh *GrafanaHandler) QueryHandler(c *gin.Context) {
+	req := &GrafanaQueryRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	if err := req.Range.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+		return
+	}
+
+	granularity := meta.HOUR
+	if time.Duration(req.IntervalMs)*time.Millisecond >= 24*time.Hour {
+		granularity = meta.DAY
+	}
+
+	response := make([]interface{}, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.Target == "" {
+			continue
+		}
+
+		if target.Target == GrafanaDestinationHealthTable {
+			response = append(response, gh.destinationHealthTable())
+			continue
+		}
+
+		if target.Type == grafanaTableType {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Target [%s] can't be requested as a table. Only [%s] is supported", target.Target, GrafanaDestinationHealthTable), nil))
+			return
+		}
+
+		timeseries, err := gh.timeseries(target.Target, req.Range.From, req.Range.To, granularity)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Failed to provide [%s] statistics", target.Target), err))
+			return
+		}
+		response = append(response, timeseries)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+//AnnotationsHandler returns circuit breaker openings of destinations (or of the destination from the annotation query)
+//within the dashboard time range
+func (gh *GrafanaHandler) AnnotationsHandler(c *gin.Context) {
+	req := &GrafanaAnnotationRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	if err := req.Range.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+		return
+	}
+
+	destinationID, _ := req.Annotation["query"].(string)
+	destinationID = strings.TrimSpace(destinationID)
+	annotations := []GrafanaAnnotation{}
+	for _, status := range gh.circuitBreakerStatuses() {
+		if status.OpenedAt == nil || status.OpenedAt.Before(req.Range.From) || status.OpenedAt.After(req.Range.To) {
+			continue
+		}
+		if destinationID != "" && status.DestinationID != destinationID {
+			continue
+		}
+
+		annotations = append(annotations, GrafanaAnnotation{
+			Annotation: req.Annotation,
+			Time:       status.OpenedAt.UnixNano() / int64(time.Millisecond),
+			Title:      fmt.Sprintf("[%s] circuit breaker is open", status.DestinationID),
+			Text:       status.LastError,
+			Tags:       []string{"circuit_breaker", status.DestinationID},
+		})
+	}
+
+	c.JSON(http.StatusOK, annotations)
+}
+
+//Validate returns err if the range isn't set or from isn't before to
+func (gr GrafanaRange) Validate() error {
+	if gr.From.IsZero() || gr.To.IsZero() {
+		return errors.New("[range.from] and [range.to] are required")
+	}
+	if !gr.From.Before(gr.To) {
+		return errors.New("[range.from] must be before [range.to]")
+	}
+
+	return nil
+}
+
+//targets returns total metrics, per destination metrics and the destination health table
+func (gh *GrafanaHandler) targets() []string {
+	targets := append([]string{}, grafanaTimeseriesMetrics...)
+	for _, destinationID := range gh.destinationIDs() {
+		targets = append(targets,
+			GrafanaEventsOutMetric+grafanaTargetDelimiter+destinationID,
+			GrafanaErrorsMetric+grafanaTargetDelimiter+destinationID)
+	}
+
+	return append(targets, GrafanaDestinationHealthTable)
+}
+
+//timeseries returns datapoints of the target: metric name or metric name with source, API key or destination ID.
+//All time chunks of the range are returned (chunks without events have zero values)
+func (gh *GrafanaHandler) timeseries(target string, start, end time.Time, granularity meta.Granularity) (*GrafanaTimeseries, error) {
+	metric, id := target, ""
+	if i := strings.Index(target, grafanaTargetDelimiter); i >= 0 {
+		metric, id = target[:i], target[i+1:]
+	}
+
+	var namespace, status string
+	switch metric {
+	case GrafanaEventsInMetric:
+		namespace, status = meta.SourceNamespace, meta.SuccessStatus
+	case GrafanaEventsSkippedMetric:
+		namespace, status = meta.SourceNamespace, meta.SkipStatus
+	case GrafanaEventsOutMetric:
+		namespace, status = meta.DestinationNamespace, meta.SuccessStatus
+	case GrafanaErrorsMetric:
+		namespace, status = meta.DestinationNamespace, meta.ErrorStatus
+	default:
+		return nil, fmt.Errorf("Unknown metric: %s. Supported: [%s]", metric, strings.Join(grafanaTimeseriesMetrics, ", "))
+	}
+
+	//push events are counted by API key IDs in sources namespace
+	pushIDs, pullIDs := gh.tokenIDs(), gh.sourceIDs()
+	if namespace == meta.DestinationNamespace {
+		pushIDs, pullIDs = gh.destinationIDs(), gh.destinationIDs()
+	}
+	if id != "" {
+		pushIDs, pullIDs = []string{id}, []string{id}
+	}
+
+	perChunk := map[time.Time]int64{}
+	for eventType, ids := range map[string][]string{meta.PushEventType: pushIDs, meta.PullEventType: pullIDs} {
+		if len(ids) == 0 {
+			continue
+		}
+
+		eventsPerTime, err := gh.metaStorage.GetEventsWithGranularity(namespace, status, eventType, ids, start, end, granularity)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ept := range eventsPerTime {
+			chunk, err := time.Parse(rollupKeyLayout, ept.Key)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing statistics key [%s]: %v", ept.Key, err)
+			}
+			perChunk[chunk.UTC()] += int64(ept.Events)
+		}
+	}
+
+	return &GrafanaTimeseries{Target: target, Datapoints: datapoints(perChunk, start, end, granularity)}, nil
+}
+
+//datapoints returns [value, unix time in ms] pairs of every hour or day between start and end
+func datapoints(perChunk map[time.Time]int64, start, end time.Time, granularity meta.Granularity) [][2]int64 {
+	start = start.UTC()
+	chunk := time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, time.UTC)
+	step := time.Hour
+	if granularity == meta.DAY {
+		chunk = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		step = 24 * time.Hour
+	}
+
+	points := [][2]int64{}
+	for ; chunk.Before(end); chunk = chunk.Add(step) {
+		points = append(points, [2]int64{perChunk[chunk], chunk.UnixNano() / int64(time.Millisecond)})
+	}
+
+	return points
+}
+
+//destinationHealthTable returns in-process totals since the server start and circuit breakers states of destinations
+func (gh *GrafanaHandler) destinationHealthTable() *GrafanaTable {
+	totals := counters.GetDestinationTotals()
+	circuitBreakers := map[string]storages.CircuitBreakerStatus{}
+	for _, status := range gh.circuitBreakerStatuses() {
+		circuitBreakers[status.DestinationID] = status
+	}
+
+	destinationIDs := make([]string, 0, len(totals))
+	for destinationID := range totals {
+		destinationIDs = append(destinationIDs, destinationID)
+	}
+	for destinationID := range circuitBreakers {
+		if _, ok := totals[destinationID]; !ok {
+			destinationIDs = append(destinationIDs, destinationID)
+		}
+	}
+	sort.Strings(destinationIDs)
+
+	table := &GrafanaTable{
+		Type: grafanaTableType,
+		Columns: []GrafanaTableColumn{
+			{Text: "destination_id", Type: "string"},
+			{Text: "events_out", Type: "number"},
+			{Text: "errors", Type: "number"},
+			{Text: "error_rate", Type: "number"},
+			{Text: "circuit_breaker", Type: "string"},
+			{Text: "last_error", Type: "string"},
+		},
+		Rows: [][]interface{}{},
+	}
+	for _, destinationID := range destinationIDs {
+		destinationTotals := totals[destinationID]
+		var errorRate float64
+		if all := destinationTotals.Success + destinationTotals.Errors; all > 0 {
+			errorRate = float64(destinationTotals.Errors) / float64(all)
+		}
+
+		//batch destinations don't have circuit breakers
+		circuitBreaker := circuitBreakers[destinationID]
+		state := string(circuitBreaker.State)
+		if state == "" {
+			state = string(storages.CircuitBreakerClosed)
+		}
+
+		table.Rows = append(table.Rows, []interface{}{destinationID, destinationTotals.Success, destinationTotals.Errors,
+			errorRate, state, circuitBreaker.LastError})
+	}
+
+	return table
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+//statisticsStorage returns events per push/pull event type and keeps all statistics requests
+type statisticsStorage struct {
+	meta.Dummy
+
+	events   map[string][]meta.EventsPerTime
+	requests []string
+}
+
+func (ss *statisticsStorage) GetEventsWithGranularity(namespace, status, eventType string, ids []string, start, end time.Time, granularity meta.Granularity) ([]meta.EventsPerTime, error) {
+	ss.requests = append(ss.requests, strings.Join([]string{namespace, status, eventType, strings.Join(ids, ","), string(granularity)}, " "))
+	return ss.events[eventType], nil
+}
+
+func newTestGrafanaHandler(storage meta.Storage, statuses ...storages.CircuitBreakerStatus) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := NewGrafanaHandler(storage,
+		func() []string { return []string{"token1"} },
+		func() []string { return []string{"source1"} },
+		func() []string { return []string{"dest1", "dest2"} })
+	handler.circuitBreakerStatuses = func() []storages.CircuitBreakerStatus { return statuses }
+
+	router := gin.New()
+	router.GET("/api/v1/grafana/", handler.TestHandler)
+	router.POST("/api/v1/grafana/search", handler.SearchHandler)
+	router.POST("/api/v1/grafana/metrics", handler.MetricsHandler)
+	router.POST("/api/v1/grafana/query", handler.QueryHandler)
+	router.POST("/api/v1/grafana/annotations", handler.AnnotationsHandler)
+	return router
+}
+
+func grafanaRequest(t *testing.T, router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	return recorder
+}
+
+func TestGrafanaSearch(t *testing.T) {
+	router := newTestGrafanaHandler(&meta.Dummy{})
+
+	recorder := grafanaRequest(t, router, http.MethodGet, "/api/v1/grafana/", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+
+	recorder = grafanaRequest(t, router, http.MethodPost, "/api/v1/grafana/search", `{"target":""}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `["events_in","events_skipped","events_out","errors","events_out:dest1","errors:dest1","events_out:dest2","errors:dest2","destination_health"]`,
+		recorder.Body.String())
+
+	recorder = grafanaRequest(t, router, http.MethodPost, "/api/v1/grafana/metrics", `{}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	metrics := []GrafanaMetric{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &metrics))
+	require.Len(t, metrics, 9)
+	require.Equal(t, GrafanaMetric{Label: "events_out:dest1", Value: "events_out:dest1"}, metrics[4])
+}
+
+func TestGrafanaQuery(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          string
+		expectedCode     int
+		expectedResponse string
+		expectedRequests []string
+	}{
+		{
+			"Total hourly metric",
+			`{"range":{"from":"2022-06-01T08:30:00.000Z","to":"2022-06-01T10:00:00.000Z"},"intervalMs":60000,"targets":[{"target":"events_out","refId":"A","type":"timeserie"}]}`,
+			http.StatusOK,
+			`[{"target":"events_out","datapoints":[[5,1654070400000],[10,1654074000000]]}]`,
+			[]string{"destination success pull dest1,dest2 hour", "destination success push dest1,dest2 hour"},
+		},
+		{
+			"API key metric",
+			`{"range":{"from":"2022-06-01T08:00:00.000Z","to":"2022-06-01T10:00:00.000Z"},"intervalMs":60000,"targets":[{"target":"events_skipped:token1","refId":"A"}]}`,
+			http.StatusOK,
+			`[{"target":"events_skipped:token1","datapoints":[[5,1654070400000],[10,1654074000000]]}]`,
+			[]string{"source skip pull token1 hour", "source skip push token1 hour"},
+		},
+		{
+			"Daily metric",
+			`{"range":{"from":"2022-05-31T12:00:00.000Z","to":"2022-06-01T10:00:00.000Z"},"intervalMs":86400000,"targets":[{"target":"events_in","refId":"A"}]}`,
+			http.StatusOK,
+			`[{"target":"events_in","datapoints":[[0,1653955200000],[0,1654041600000]]}]`,
+			[]string{"source success pull source1 day", "source success push token1 day"},
+		},
+		{
+			"Empty targets are skipped",
+			`{"range":{"from":"2022-06-01T08:00:00.000Z","to":"2022-06-01T10:00:00.000Z"},"targets":[{"target":"","refId":"A"}]}`,
+			http.StatusOK,
+			`[]`,
+			nil,
+		},
+		{
+			"Unknown metric",
+			`{"range":{"from":"2022-06-01T08:00:00.000Z","to":"2022-06-01T10:00:00.000Z"},"targets":[{"target":"events_lost:dest1","refId":"A"}]}`,
+			http.StatusBadRequest,
+			`{"message":"Failed to provide [events_lost:dest1] statistics: Unknown metric: events_lost. Supported: [events_in, events_skipped, events_out, errors]","error":""}`,
+			nil,
+		},
+		{
+			"Timeseries as a table",
+			`{"range":{"from":"2022-06-01T08:00:00.000Z","to":"2022-06-01T10:00:00.000Z"},"targets":[{"target":"errors","refId":"A","type":"table"}]}`,
+			http.StatusBadRequest,
+			`{"message":"Target [errors] can't be requested as a table. Only [destination_health] is supported","error":""}`,
+			nil,
+		},
+		{
+			"Without range",
+			`{"targets":[{"target":"errors","refId":"A"}]}`,
+			http.StatusBadRequest,
+			`{"message":"[range.from] and [range.to] are required","error":""}`,
+			nil,
+		},
+		{
+			"Reversed range",
+			`{"range":{"from":"2022-06-01T10:00:00.000Z","to":"2022-06-01T08:00:00.000Z"},"targets":[{"target":"errors","refId":"A"}]}`,
+			http.StatusBadRequest,
+			`{"message":"[range.from] must be before [range.to]","error":""}`,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &statisticsStorage{events: map[string][]meta.EventsPerTime{
+				meta.PushEventType: {{Key: "2022-06-01T08:00:00+0000", Events: 5}, {Key: "2022-06-01T09:00:00+0000", Events: 7}},
+				meta.PullEventType: {{Key: "2022-06-01T09:00:00+0000", Events: 3}},
+			}}
+			//daily statistics doesn't have hourly keys
+			if strings.Contains(tt.request, `"intervalMs":86400000`) {
+				storage.events = nil
+			}
+			router := newTestGrafanaHandler(storage)
+
+			recorder := grafanaRequest(t, router, http.MethodPost, "/api/v1/grafana/query", tt.request)
+			require.Equal(t, tt.expectedCode, recorder.Code, recorder.Body.String())
+			require.JSONEq(t, tt.expectedResponse, recorder.Body.String())
+
+			//requests order depends on map iteration
+			requests := storage.requests
+			if len(requests) == 2 && requests[0] > requests[1] {
+				requests[0], requests[1] = requests[1], requests[0]
+			}
+			require.Equal(t, tt.expectedRequests, requests)
+		})
+	}
+}
+
+func TestGrafanaDestinationHealth(t *testing.T) {
+	counters.InitEvents(&meta.Dummy{})
+	defer counters.Close()
+	counters.SuccessPushDestinationEvents("dest1", 3)
+	counters.ErrorPushDestinationEvents("dest1", 1)
+
+	openedAt := time.Date(2022, 6, 1, 9, 0, 0, 0, time.UTC)
+	router := newTestGrafanaHandler(&meta.Dummy{}, storages.CircuitBreakerStatus{DestinationID: "dest2", State: storages.CircuitBreakerOpen,
+		LastError: "connection refused", OpenedAt: &openedAt})
+
+	recorder := grafanaRequest(t, router, http.MethodPost, "/api/v1/grafana/query",
+		`{"range":{"from":"2022-06-01T08:00:00.000Z","to":"2022-06-01T10:00:00.000Z"},"targets":[{"target":"destination_health","refId":"A","type":"table"}]}`)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.JSONEq(t, `[{
+		"type":"table",
+		"columns":[
+			{"text":"destination_id","type":"string"},
+			{"text":"events_out","type":"number"},
+			{"text":"errors","type":"number"},
+			{"text":"error_rate","type":"number"},
+			{"text":"circuit_breaker","type":"string"},
+			{"text":"last_error","type":"string"}
+		],
+		"rows":[
+			["dest1",3,1,0.25,"closed",""],
+			["dest2",0,0,0,"open","connection refused"]
+		]
+	}]`, recorder.Body.String())
+}
+
+func TestGrafanaAnnotations(t *testing.T) {
+	beforeRange := time.Date(2022, 6, 1, 7, 0, 0, 0, time.UTC)
+	inRange := time.Date(2022, 6, 1, 9, 0, 0, 0, time.UTC)
+	router := newTestGrafanaHandler(&meta.Dummy{},
+		storages.CircuitBreakerStatus{DestinationID: "dest1", State: storages.CircuitBreakerClosed},
+		storages.CircuitBreakerStatus{DestinationID: "dest2", State: storages.CircuitBreakerOpen, LastError: "connection refused", OpenedAt: &inRange},
+		storages.CircuitBreakerStatus{DestinationID: "dest3", State: storages.CircuitBreakerHalfOpen, LastError: "timeout", OpenedAt: &inRange},
+		storages.CircuitBreakerStatus{DestinationID: "dest4", State: storages.CircuitBreakerOpen, LastError: "timeout", OpenedAt: &beforeRange})
+
+	tests := []struct {
+		name             string
+		request          string
+		expectedCode     int
+		expectedResponse string
+	}{
+		{
+			"All destinations",
+			`{"range":{"from":"2022-06-01T08:00:00.000Z","to":"2022-06-01T10:00:00.000Z"},"annotation":{"name":"breakers","enable":true}}`,
+			http.StatusOK,
+			`[
+				{"annotation":{"name":"breakers","enable":true},"time":1654074000000,"title":"[dest2] circuit breaker is open","text":"connection refused","tags":["circuit_breaker","dest2"]},
+				{"annotation":{"name":"breakers","enable":true},"time":1654074000000,"title":"[dest3] circuit breaker is open","text":"timeout","tags":["circuit_breaker","dest3"]}
+			]`,
+		},
+		{
+			"Destination from the query",
+			`{"range":{"from":"2022-06-01T08:00:00.000Z","to":"2022-06-01T10:00:00.000Z"},"annotation":{"name":"dest3 breaker","query":" dest3 "}}`,
+			http.StatusOK,
+			`[{"annotation":{"name":"dest3 breaker","query":" dest3 "},"time":1654074000000,"title":"[dest3] circuit breaker is open","text":"timeout","tags":["circuit_breaker","dest3"]}]`,
+		},
+		{
+			"Nothing in range",
+			`{"range":{"from":"2022-06-01T10:00:00.000Z","to":"2022-06-01T11:00:00.000Z"},"annotation":{"name":"breakers"}}`,
+			http.StatusOK,
+			`[]`,
+		},
+		{
+			"Without range",
+			`{"annotation":{"name":"breakers"}}`,
+			http.StatusBadRequest,
+			`{"message":"[range.from] and [range.to] are required","error":""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := grafanaRequest(t, router, http.MethodPost, "/api/v1/grafana/annotations", tt.request)
+			require.Equal(t, tt.expectedCode, recorder.Code, recorder.Body.String())
+			require.JSONEq(t, tt.expectedResponse, recorder.Body.String())
+		})
+	}
+}
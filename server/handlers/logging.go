@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+)
+
+//LogLevelsRequest is a dto for per-component log levels update request.
+//Empty level resets the component level to the global one
+type LogLevelsRequest struct {
+	Components map[string]string `json:"components"`
+}
+
+//LogLevelsResponse is a dto for log levels response
+type LogLevelsResponse struct {
+	Level      string            `json:"level"`
+	Format     string            `json:"format"`
+	Components map[string]string `json:"components"`
+}
+
+//LogLevelsHandler returns global and per-component log levels
+func LogLevelsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, logLevelsResponse())
+}
+
+//UpdateLogLevelsHandler sets or resets per-component log levels in runtime
+func UpdateLogLevelsHandler(c *gin.Context) {
+	req := &LogLevelsRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	if len(req.Components) == 0 {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("components is required", nil))
+		return
+	}
+
+	for component, level := range req.Components {
+		if component == "" {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("component name can't be empty", nil))
+			return
+		}
+		if level != "" && logging.ToLevel(level) == logging.UNKNOWN {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("unknown log level ["+level+"] of component ["+component+"]", nil))
+			return
+		}
+	}
+
+	for component, level := range req.Components {
+		if level == "" {
+			logging.ResetComponentLevel(component)
+			logging.Infof("Log level of [%s] component has been reset to the global one", component)
+		} else {
+			//level is validated above
+			_ = logging.SetComponentLevel(component, level)
+			logging.Infof("Log level of [%s] component has been set to [%s]", component, level)
+		}
+	}
+
+	c.JSON(http.StatusOK, logLevelsResponse())
+}
+
+func logLevelsResponse() LogLevelsResponse {
+	format := logging.TextFormat
+	if logging.IsJSONFormat() {
+		format = logging.JSONFormat
+	}
+
+	return LogLevelsResponse{
+		Level:      logging.LogLevel.String(),
+		Format:     format,
+		Components: logging.ComponentLevels(),
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const defaultMirrorsReportPeriod = 24 * time.Hour
+
+//MirrorsResponse is a dto for mirror destinations consistency report
+type MirrorsResponse struct {
+	Start   time.Time       `json:"start"`
+	End     time.Time       `json:"end"`
+	Mirrors []*MirrorReport `json:"mirrors"`
+}
+
+//MirrorReport is a consistency report of a mirror destination. The mirror is consistent if all members
+//have stored the same amount of events in the period
+type MirrorReport struct {
+	ID         string                `json:"id"`
+	Consistent bool                  `json:"consistent"`
+	Members    []*MirrorMemberReport `json:"members"`
+}
+
+//MirrorMemberReport is statistics and state of a mirror member destination
+type MirrorMemberReport struct {
+	DestinationID string `json:"destination_id"`
+	Ready         bool   `json:"ready"`
+	EventsOut     int64  `json:"events_out"`
+	Errors        int64  `json:"errors"`
+	//Lag is an amount of events which have been stored in the most up-to-date member but haven't been stored in this one
+	Lag                 int64  `json:"lag"`
+	CircuitBreaker      string `json:"circuit_breaker"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty"`
+}
+
+//MirrorsHandler reports mirror destinations consistency: statistics of members are compared per period
+type MirrorsHandler struct {
+	destinationService *destinations.Service
+	metaStorage        meta.Storage
+}
+
+//NewMirrorsHandler returns configured MirrorsHandler
+func NewMirrorsHandler(destinationService *destinations.Service, metaStorage meta.Storage) *MirrorsHandler {
+	return &MirrorsHandler{destinationService: destinationService, metaStorage: metaStorage}
+}
+
+//Handler returns consistency reports of all mirrors for the period [start, end] (RFC3339). The last 24 hours by default
+func (mh *MirrorsHandler) Handler(c *gin.Context) {
+	end := timestamp.Now().UTC()
+	if endStr := c.Query("end"); endStr != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing [end] query parameter", err))
+			return
+		}
+		end = parsed
+	}
+
+	start := end.Add(-defaultMirrorsReportPeriod)
+	if startStr := c.Query("start"); startStr != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing [start] query parameter", err))
+			return
+		}
+		start = parsed
+	}
+
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[start] must be before [end]", nil))
+		return
+	}
+
+	circuitBreakers := map[string]storages.CircuitBreakerStatus{}
+	for _, status := range storages.CircuitBreakerStatuses() {
+		circuitBreakers[status.DestinationID] = status
+	}
+
+	mirrors := mh.destinationService.GetMirrors()
+	mirrorIDs := make([]string, 0, len(mirrors))
+	for mirrorID := range mirrors {
+		mirrorIDs = append(mirrorIDs, mirrorID)
+	}
+	sort.Strings(mirrorIDs)
+
+	response := &MirrorsResponse{Start: start, End: end, Mirrors: make([]*MirrorReport, 0, len(mirrorIDs))}
+	for _, mirrorID := range mirrorIDs {
+		report, err := mh.report(mirrorID, mirrors[mirrorID], circuitBreakers, start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, middleware.ErrResponse("Error getting mirror ["+mirrorID+"] statistics", err))
+			return
+		}
+		response.Mirrors = append(response.Mirrors, report)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+//report returns the mirror members statistics and lags relative to the member with the most stored events
+func (mh *MirrorsHandler) report(mirrorID string, members []string, circuitBreakers map[string]storages.CircuitBreakerStatus,
+	start, end time.Time) (*MirrorReport, error) {
+	report := &MirrorReport{ID: mirrorID, Consistent: true, Members: make([]*MirrorMemberReport, 0, len(members))}
+	var maxEventsOut int64
+	for _, memberID := range members {
+		member := &MirrorMemberReport{DestinationID: memberID, CircuitBreaker: string(storages.CircuitBreakerClosed)}
+		if storageProxy, ok := mh.destinationService.GetDestinationByID(memberID); ok {
+			_, member.Ready = storageProxy.Get()
+		}
+
+		var err error
+		if member.EventsOut, err = mh.events(memberID, meta.SuccessStatus, start, end); err != nil {
+			return nil, err
+		}
+		if member.Errors, err = mh.events(memberID, meta.ErrorStatus, start, end); err != nil {
+			return nil, err
+		}
+
+		//batch destinations don't have circuit breakers
+		if status, ok := circuitBreakers[memberID]; ok {
+			member.CircuitBreaker = string(status.State)
+			member.ConsecutiveFailures = status.ConsecutiveFailures
+			member.LastError = status.LastError
+		}
+
+		if member.EventsOut > maxEventsOut {
+			maxEventsOut = member.EventsOut
+		}
+		report.Members = append(report.Members, member)
+	}
+
+	for _, member := range report.Members {
+		member.Lag = maxEventsOut - member.EventsOut
+		if member.Lag > 0 || !member.Ready {
+			report.Consistent = false
+		}
+	}
+
+	return report, nil
+}
+
+//events returns an amount of push and pull events of the destination with the status in the period
+func (mh *MirrorsHandler) events(destinationID, status string, start, end time.Time) (int64, error) {
+	var total int64
+	for _, eventType := range []string{meta.PushEventType, meta.PullEventType} {
+		eventsPerTime, err := mh.metaStorage.GetEventsWithGranularity(meta.DestinationNamespace, status, eventType, []string{destinationID}, start, end, meta.HOUR)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, ept := range eventsPerTime {
+			total += int64(ept.Events)
+		}
+	}
+
+	return total, nil
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/multiplexing"
+	"github.com/jitsucom/jitsu/server/quota"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/uuid"
 )
@@ -83,19 +84,23 @@ func (ph *PixelHandler) Handle(c *gin.Context) {
 		geoResolver = ph.geoService.GetGeoResolver(destinationStorages[0].GetGeoResolverID())
 	}
 
-	reqContext := getRequestContext(c, geoResolver)
+	reqContext := getRequestContext(c, geoResolver, strToken, event)
 	if reqContext.CookiesLawCompliant {
 		reqContext.JitsuAnonymousID = ph.extractOrSetAnonymIDCookie(c, event, reqContext)
 	}
 
 	enrichment.HTTPContextEnrichmentStep(c, event)
 
-	_, err = ph.multiplexingService.AcceptRequest(ph.processor, reqContext, strToken, []events.Event{event})
+	_, err = ph.multiplexingService.AcceptRequest(c.Request.Context(), ph.processor, reqContext, strToken, []events.Event{event})
 	if err != nil {
 		if err == multiplexing.ErrNoDestinations {
 			c.Data(http.StatusOK, "image/gif", ph.emptyGIF)
 			return
 		}
+		if quotaErr, ok := err.(*quota.ExceededError); ok {
+			quotaExceededResponse(c, quotaErr)
+			return
+		}
 		reqBody, _ := json.Marshal(event)
 		logging.Errorf("%v. Tracking pixel event: %s", err, string(reqBody))
 		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/quota"
+)
+
+//QuotasResponse is a dto for events quotas usage response
+type QuotasResponse struct {
+	Quotas []*quota.Usage `json:"quotas"`
+}
+
+//QuotasHandler returns the current day and month events usage of projects and API keys with quotas
+func QuotasHandler(c *gin.Context) {
+	quotaService := quota.Instance()
+	if quotaService == nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Events quotas are disabled. Please configure quotas.enabled: true", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, QuotasResponse{Quotas: quotaService.Usages()})
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/retention"
+)
+
+//RetentionReportsResponse is a response of all data retention reports
+type RetentionReportsResponse struct {
+	Reports []*retention.Report `json:"reports"`
+}
+
+//RetentionHandler handles data retention requests
+type RetentionHandler struct {
+	retentionService *retention.Service
+}
+
+func NewRetentionHandler(retentionService *retention.Service) *RetentionHandler {
+	return &RetentionHandler{retentionService: retentionService}
+}
+
+//RunHandler starts deleting (or counting if dry_run) expired data in destinations in background
+func (rh *RetentionHandler) RunHandler(c *gin.Context) {
+	req := &retention.Request{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing retention body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	report, err := rh.retentionService.Run(req)
+	if err != nil {
+		logging.Errorf("Error starting data retention: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to start data retention", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+//ReportsHandler returns all data retention reports
+func (rh *RetentionHandler) ReportsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, RetentionReportsResponse{Reports: rh.retentionService.GetReports()})
+}
+
+//ReportHandler returns data retention report by id
+func (rh *RetentionHandler) ReportHandler(c *gin.Context) {
+	reportID := c.Param("reportID")
+	report := rh.retentionService.GetReport(reportID)
+	if report == nil {
+		c.JSON(http.StatusNotFound, middleware.ErrResponse("Retention report "+reportID+" wasn't found", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/schemaregistry"
+)
+
+const schemaRegistryDisabledErr = "Schema registry is disabled. Please configure schema_registry.enabled: true"
+
+//SchemaRegistryResponse is a dto for schema registry tables response
+type SchemaRegistryResponse struct {
+	Destinations []*schemaregistry.DestinationTables `json:"destinations"`
+}
+
+//SchemaHistoryResponse is a dto for schema registry history response
+type SchemaHistoryResponse struct {
+	Changes []*schemaregistry.Change `json:"changes"`
+}
+
+//SchemaRegistryHandler returns known tables with columns, SQL types and when they have been seen for the first time
+//query parameters:
+//destination_id - destination id (optional). All destinations are returned if it isn't set
+func SchemaRegistryHandler(c *gin.Context) {
+	service := schemaregistry.Instance()
+	if service == nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(schemaRegistryDisabledErr, nil))
+		return
+	}
+
+	destinations, err := service.GetTables(c.Query("destination_id"))
+	if err != nil {
+		logging.Errorf("Error getting schema registry tables: %v", err)
+		c.JSON(http.StatusInternalServerError, middleware.ErrResponse("Error getting schema registry tables", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, SchemaRegistryResponse{Destinations: destinations})
+}
+
+//SchemaHistoryHandler returns the last destination tables schema changes (the newest first)
+//query parameters:
+//destination_id - destination id (required)
+//table - table name (optional)
+//limit - max changes count (default 100, max 1000)
+func SchemaHistoryHandler(c *gin.Context) {
+	service := schemaregistry.Instance()
+	if service == nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(schemaRegistryDisabledErr, nil))
+		return
+	}
+
+	filter := &schemaregistry.HistoryFilter{
+		DestinationID: c.Query("destination_id"),
+		Table:         c.Query("table"),
+	}
+	if filter.DestinationID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("destination_id is required query parameter", nil))
+		return
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("limit must be an integer", err))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	changes, err := service.GetChanges(filter)
+	if err != nil {
+		logging.Errorf("Error getting destination [%s] schema changes: %v", filter.DestinationID, err)
+		c.JSON(http.StatusInternalServerError, middleware.ErrResponse("Error getting schema changes", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, SchemaHistoryResponse{Changes: changes})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/middleware"
+)
+
+//SegmentSettingsHandler returns analytics.js (analytics-next) CDN settings with Segment.io integration pointed to Jitsu
+//It allows to use analytics.js with 'cdnURL' option set to Jitsu URL
+type SegmentSettingsHandler struct {
+	serverPublicURL string
+}
+
+//NewSegmentSettingsHandler returns configured SegmentSettingsHandler
+func NewSegmentSettingsHandler(serverPublicURL string) *SegmentSettingsHandler {
+	return &SegmentSettingsHandler{serverPublicURL: serverPublicURL}
+}
+
+//Handler returns settings JSON if the write key is a Jitsu client or server secret
+func (ssh *SegmentSettingsHandler) Handler(c *gin.Context) {
+	writeKey := c.Param("writeKey")
+	_, clientSecret := appconfig.Instance.AuthorizationService.GetClientOrigins(writeKey)
+	_, serverSecret := appconfig.Instance.AuthorizationService.GetServerOrigins(writeKey)
+	if !clientSecret && !serverSecret {
+		c.JSON(http.StatusNotFound, middleware.ErrResponse("Write key is not found", nil))
+		return
+	}
+
+	protocol, host := "https", c.Request.Host
+	if c.Request.TLS == nil && c.GetHeader("X-Forwarded-Proto") != "https" {
+		protocol = "http"
+	}
+	if ssh.serverPublicURL != "" {
+		host = ssh.serverPublicURL
+		if i := strings.Index(host, "://"); i >= 0 {
+			protocol, host = host[:i], host[i+3:]
+		}
+	}
+
+	enabled := map[string]interface{}{"__default": map[string]interface{}{"enabled": true, "integrations": map[string]interface{}{}}}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"integrations": map[string]interface{}{
+			"Segment.io": map[string]interface{}{
+				"apiKey":   writeKey,
+				"apiHost":  strings.TrimSuffix(host, "/") + "/v1",
+				"protocol": protocol,
+			},
+		},
+		"plan": map[string]interface{}{
+			"track":    enabled,
+			"identify": enabled,
+			"group":    enabled,
+		},
+		"edgeFunction":         map[string]interface{}{},
+		"analyticsNextEnabled": true,
+		"metrics":              map[string]interface{}{"sampleRate": 0},
+	})
+}
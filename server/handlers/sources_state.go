@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+// SourceStateRequest is a dto for source collection state edit and reset endpoints
+type SourceStateRequest struct {
+	Source     string `json:"source"`
+	Collection string `json:"collection"`
+	//State is a new collection state (edit endpoint)
+	State string `json:"state"`
+	//From is a timestamp (RFC3339 or YYYY-MM-DD) from which the collection will be re-synchronized (reset endpoint)
+	From string `json:"from"`
+}
+
+// SourceStateResponse is a dto for source state endpoint
+type SourceStateResponse struct {
+	Collections []*CollectionState `json:"collections"`
+}
+
+// CollectionState is a dto with collection saved state (cursor) and synchronized chunks
+type CollectionState struct {
+	Collection string `json:"collection"`
+	//State is nil if the collection driver doesn't keep the state
+	State  *string       `json:"state,omitempty"`
+	Chunks []*ChunkState `json:"chunks,omitempty"`
+}
+
+// ChunkState is a dto with collection chunk (time interval) synchronization result
+type ChunkState struct {
+	Interval          string `json:"interval"`
+	From              string `json:"from,omitempty"`
+	To                string `json:"to,omitempty"`
+	SynchronizedUntil string `json:"synchronized_until"`
+}
+
+// ResetStateResponse is a dto for source state reset endpoint
+type ResetStateResponse struct {
+	middleware.StatusResponse
+	ResetChunks int `json:"reset_chunks"`
+}
+
+// SourceStateHandler returns saved state and chunks of all source collections or of a certain collection
+func (sh *SourcesHandler) SourceStateHandler(c *gin.Context) {
+	sourceID := c.Query("source")
+	if sourceID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("'source' is required query parameter", nil))
+		return
+	}
+	collectionFilter := c.Query("collection")
+
+	source, err := sh.sourcesService.GetSource(sourceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error getting source by id", err))
+		return
+	}
+
+	response := &SourceStateResponse{Collections: []*CollectionState{}}
+	for collection, driver := range source.DriverPerCollection {
+		if collectionFilter != "" && collectionFilter != collection {
+			continue
+		}
+
+		collectionState, err := sh.getCollectionState(sourceID, collection, driver)
+		if err != nil {
+			logging.Errorf("Error getting source [%s] collection [%s] state: %v", sourceID, collection, err)
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Error getting collection [%s] state", collection), err))
+			return
+		}
+		response.Collections = append(response.Collections, collectionState)
+	}
+
+	if collectionFilter != "" && len(response.Collections) == 0 {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Collection [%s] doesn't exist in source [%s]", collectionFilter, sourceID), nil))
+		return
+	}
+
+	sort.Slice(response.Collections, func(i, j int) bool {
+		return response.Collections[i].Collection < response.Collections[j].Collection
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateSourceStateHandler overwrites collection saved state (cursor). The state is used on the next synchronization
+func (sh *SourcesHandler) UpdateSourceStateHandler(c *gin.Context) {
+	req := &SourceStateRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing source state request: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	driver, ok := sh.getCollectionDriver(c, req)
+	if !ok {
+		return
+	}
+
+	metaKey, ok := stateMetaKey(driver)
+	if !ok {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Collection [%s] doesn't keep synchronization state", req.Collection), nil))
+		return
+	}
+
+	if err := sh.metaStorage.SaveSignature(req.Source, metaKey, schema.ALL.String(), req.State); err != nil {
+		logging.Errorf("Error saving source [%s] collection [%s] state: %v", req.Source, req.Collection, err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error saving collection state", err))
+		return
+	}
+
+	logging.Infof("Source [%s] collection [%s] state has been updated: %s", req.Source, req.Collection, req.State)
+	c.JSON(http.StatusOK, middleware.OKResponse())
+}
+
+// ResetSourceStateHandler resets collection state and synchronized chunks. If 'from' is set only chunks that end after
+// it are reset. Reset chunks will be re-synchronized on the next synchronization
+func (sh *SourcesHandler) ResetSourceStateHandler(c *gin.Context) {
+	req := &SourceStateRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing source state reset request: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	driver, ok := sh.getCollectionDriver(c, req)
+	if !ok {
+		return
+	}
+
+	var from *time.Time
+	if req.From != "" {
+		t, err := parseResetTime(req.From)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse 'from'", err))
+			return
+		}
+		from = &t
+	}
+
+	resetChunks, err := sh.resetCollectionState(req.Source, driver, from)
+	if err != nil {
+		logging.Errorf("Error resetting source [%s] collection [%s] state: %v", req.Source, req.Collection, err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error resetting collection state", err))
+		return
+	}
+
+	logging.Infof("Source [%s] collection [%s] state has been reset (from: [%s]). Reset chunks: [%d]", req.Source, req.Collection, req.From, resetChunks)
+	c.JSON(http.StatusOK, ResetStateResponse{StatusResponse: middleware.OKResponse(), ResetChunks: resetChunks})
+}
+
+// getCollectionDriver returns request collection driver or writes HTTP 400 and returns false
+func (sh *SourcesHandler) getCollectionDriver(c *gin.Context, req *SourceStateRequest) (driversbase.Driver, bool) {
+	if req.Source == "" || req.Collection == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("'source' and 'collection' are required fields", nil))
+		return nil, false
+	}
+
+	source, err := sh.sourcesService.GetSource(req.Source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error getting source by id", err))
+		return nil, false
+	}
+
+	driver, ok := source.DriverPerCollection[req.Collection]
+	if !ok {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Collection [%s] doesn't exist in source [%s]", req.Collection, req.Source), nil))
+		return nil, false
+	}
+
+	return driver, true
+}
+
+// getCollectionState returns collection state and chunks sorted by interval
+func (sh *SourcesHandler) getCollectionState(sourceID, collection string, driver driversbase.Driver) (*CollectionState, error) {
+	collectionState := &CollectionState{Collection: collection}
+	if metaKey, ok := stateMetaKey(driver); ok {
+		state, err := sh.metaStorage.GetSignature(sourceID, metaKey, schema.ALL.String())
+		if err != nil {
+			return nil, err
+		}
+		collectionState.State = &state
+	}
+
+	//CLI drivers keep only the state
+	if _, ok := driver.(driversbase.CLIDriver); ok {
+		return collectionState, nil
+	}
+
+	signatures, err := sh.metaStorage.GetSignatures(sourceID, driver.GetCollectionMetaKey())
+	if err != nil {
+		return nil, err
+	}
+
+	for interval, signature := range signatures {
+		chunk := &ChunkState{Interval: interval, SynchronizedUntil: signature}
+		if timeInterval, err := driversbase.ParseTimeInterval(interval); err == nil && !timeInterval.IsAll() {
+			chunk.From = timeInterval.LowerEndpoint().Format(driversbase.SignatureLayout)
+			chunk.To = timeInterval.UpperEndpoint().Format(driversbase.SignatureLayout)
+		}
+		collectionState.Chunks = append(collectionState.Chunks, chunk)
+	}
+	sort.Slice(collectionState.Chunks, func(i, j int) bool {
+		return collectionState.Chunks[i].Interval < collectionState.Chunks[j].Interval
+	})
+
+	return collectionState, nil
+}
+
+// resetCollectionState deletes the collection state and chunks signatures. If from isn't nil only chunks which end
+// after it are deleted (the state can't be reset partially). Returns the number of reset chunks
+func (sh *SourcesHandler) resetCollectionState(sourceID string, driver driversbase.Driver, from *time.Time) (int, error) {
+	collectionMetaKey := driver.GetCollectionMetaKey()
+	metaKey, stateful := stateMetaKey(driver)
+	if from != nil && stateful {
+		return 0, errors.New("the collection synchronization state can't be reset from a certain time: update the state or reset it completely")
+	}
+
+	signatures, err := sh.metaStorage.GetSignatures(sourceID, collectionMetaKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if from == nil {
+		if err := sh.metaStorage.DeleteSignature(sourceID, collectionMetaKey); err != nil {
+			return 0, err
+		}
+		if stateful && metaKey != collectionMetaKey {
+			if err := sh.metaStorage.DeleteSignature(sourceID, metaKey); err != nil {
+				return 0, err
+			}
+		}
+		return len(signatures), nil
+	}
+
+	var intervals []string
+	for interval := range signatures {
+		timeInterval, err := driversbase.ParseTimeInterval(interval)
+		if err != nil {
+			return 0, err
+		}
+		if timeInterval.IsAll() || !timeInterval.UpperEndpoint().Before(*from) {
+			intervals = append(intervals, interval)
+		}
+	}
+
+	if err := sh.metaStorage.DeleteSignatureIntervals(sourceID, collectionMetaKey, intervals...); err != nil {
+		return 0, err
+	}
+
+	return len(intervals), nil
+}
+
+// stateMetaKey returns meta storage key of the driver state and true if the driver keeps synchronization state
+func stateMetaKey(driver driversbase.Driver) (string, bool) {
+	if _, ok := driver.(driversbase.CLIDriver); ok {
+		return driver.GetCollectionMetaKey(), true
+	}
+	if _, ok := driver.(driversbase.StatefulDriver); ok {
+		return driver.GetCollectionMetaKey() + driversbase.StateSignatureSuffix, true
+	}
+
+	return "", false
+}
+
+// parseResetTime parses RFC3339 timestamp or YYYY-MM-DD date
+func parseResetTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	return time.Parse(timestamp.DashDayLayout, value)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -14,6 +15,11 @@ type StatisticsResponse struct {
 	Data   []meta.EventsPerTime `json:"data"`
 }
 
+type RollupsResponse struct {
+	Status string                `json:"status"`
+	Data   []meta.RollupsPerTime `json:"data"`
+}
+
 type StatisticsHandler struct {
 	metaStorage meta.Storage
 }
@@ -178,6 +184,103 @@ func (sh *StatisticsHandler) GetHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// RollupsHandler returns usage rollups grouped by dimensions (destination_id, source_id, api_key, table, status)
+// per hour or day. Dimensions query parameters are comma separated values filters
+func (sh *StatisticsHandler) RollupsHandler(c *gin.Context) {
+	startStr := c.Query("start")
+	if startStr == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[start] is a required query parameter", nil))
+		return
+	}
+	start, err := time.Parse(time.RFC3339Nano, startStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing [start] query parameter", err))
+		return
+	}
+
+	endStr := c.Query("end")
+	if endStr == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[end] is a required query parameter", nil))
+		return
+	}
+	end, err := time.Parse(time.RFC3339Nano, endStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing [end] query parameter", err))
+		return
+	}
+
+	granularityStr := c.Query("granularity")
+	if granularityStr == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("[granularity] is a required query parameter", nil))
+		return
+	}
+
+	granularity, err := meta.GranularityFromString(granularityStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing [granularity] query parameter", err))
+		return
+	}
+
+	groupBy, err := meta.ParseRollupDimensions(c.DefaultQuery("group_by", meta.RollupDestinationDimension))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing [group_by] query parameter", err))
+		return
+	}
+
+	filter := &meta.RollupFilter{
+		DestinationIDs: queryValues(c, meta.RollupDestinationDimension),
+		SourceIDs:      queryValues(c, meta.RollupSourceDimension),
+		APIKeys:        queryValues(c, meta.RollupAPIKeyDimension),
+		Tables:         queryValues(c, meta.RollupTableDimension),
+		Statuses:       queryValues(c, meta.RollupStatusDimension),
+		GroupBy:        groupBy,
+	}
+
+	for _, status := range filter.Statuses {
+		if status != meta.SuccessStatus &&
+			status != meta.ErrorStatus &&
+			status != meta.SkipStatus {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("Unknown [status] value: %s. Only ['%s', '%s', '%s'] are supported", status, meta.SuccessStatus, meta.SkipStatus, meta.ErrorStatus), nil))
+			return
+		}
+	}
+
+	//restrict rollups with project destinations
+	projectID := c.Query("project_id")
+	if projectID != "" && len(filter.DestinationIDs) == 0 {
+		filter.DestinationIDs, err = sh.metaStorage.GetProjectDestinationIDs(projectID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to get project destinations identifiers", err))
+			return
+		}
+
+		if len(filter.DestinationIDs) == 0 {
+			c.JSON(http.StatusOK, RollupsResponse{Data: []meta.RollupsPerTime{}, Status: "ok"})
+			return
+		}
+	}
+
+	rollups, err := sh.metaStorage.GetRollups(filter, start, end, granularity)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to provide usage rollups", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, RollupsResponse{Data: rollups, Status: "ok"})
+}
+
+// queryValues returns non-empty comma separated values of the query parameter
+func queryValues(c *gin.Context, name string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
 func (sh *StatisticsHandler) extractIDs(projectID, namespace string, c *gin.Context) ([]string, error) {
 	switch namespace {
 	case meta.DestinationNamespace:
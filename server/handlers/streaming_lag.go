@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+//StreamingLagResponse is a dto for streaming destinations queues lag response
+type StreamingLagResponse struct {
+	Destinations []*DestinationLag `json:"destinations"`
+}
+
+//DestinationLag is a streaming destination queue lag and catch-up mode state
+type DestinationLag struct {
+	DestinationID string `json:"destination_id"`
+	//QueueSize is -1 if the queue doesn't support size
+	QueueSize         int64                   `json:"queue_size"`
+	OldestEventAgeSec float64                 `json:"oldest_event_age_sec"`
+	CatchUp           *storages.CatchUpStatus `json:"catch_up,omitempty"`
+}
+
+//StreamingLagHandler returns queue size, the approximate age of the oldest queued event and catch-up mode state
+//of all streaming destinations
+func StreamingLagHandler(c *gin.Context) {
+	lags := events.DestinationQueueLags()
+	catchUps := storages.CatchUpStatuses()
+
+	destinationIDs := make([]string, 0, len(lags))
+	for destinationID := range lags {
+		destinationIDs = append(destinationIDs, destinationID)
+	}
+	sort.Strings(destinationIDs)
+
+	response := StreamingLagResponse{Destinations: make([]*DestinationLag, 0, len(destinationIDs))}
+	for _, destinationID := range destinationIDs {
+		lag := lags[destinationID]
+		destinationLag := &DestinationLag{
+			DestinationID:     destinationID,
+			QueueSize:         lag.Size,
+			OldestEventAgeSec: lag.OldestEventAge.Seconds(),
+		}
+		if catchUp, ok := catchUps[destinationID]; ok {
+			destinationLag.CatchUp = &catchUp
+		}
+		response.Destinations = append(response.Destinations, destinationLag)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/templates"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+// DebugTransformationRequest is a request dto for running a transformation against sample events
+type DebugTransformationRequest struct {
+	Uid               string                   `json:"uid,omitempty"`
+	Type              string                   `json:"type,omitempty"`
+	Transform         string                   `json:"transform,omitempty"`
+	Language          string                   `json:"language,omitempty"`
+	Dependencies      map[string]string        `json:"dependencies,omitempty"`
+	ScriptLimits      *config.ScriptLimits     `json:"script_limits,omitempty"`
+	Events            []map[string]interface{} `json:"events,omitempty"`
+	TemplateVariables map[string]interface{}   `json:"template_variables,omitempty"`
+}
+
+// DebugTransformationResult is a transformation result of a single sample event
+type DebugTransformationResult struct {
+	Result          interface{}  `json:"result"`
+	Logs            EvaluateLogs `json:"logs"`
+	Error           string       `json:"error,omitempty"`
+	ExecutionTimeMs float64      `json:"execution_time_ms"`
+}
+
+// DebugTransformationResponse is a response dto for running a transformation against sample events
+type DebugTransformationResponse struct {
+	Format  string                      `json:"format"`
+	Results []DebugTransformationResult `json:"results"`
+	Error   string                      `json:"error,omitempty"`
+}
+
+// Validate returns err if invalid
+func (dtr *DebugTransformationRequest) Validate() error {
+	if strings.TrimSpace(dtr.Transform) == "" {
+		return errors.New("'transform' is required field")
+	}
+
+	if len(dtr.Events) == 0 {
+		return errors.New("'events' is required field")
+	}
+
+	return nil
+}
+
+// Variables returns global variables of transformation script with destination data from request
+func (dtr *DebugTransformationRequest) Variables() map[string]interface{} {
+	vars := map[string]interface{}{"destinationId": dtr.Uid, "destinationType": dtr.Type}
+	utils.MapPutAll(vars, dtr.TemplateVariables)
+	return templates.EnrichedFuncMap(vars)
+}
+
+// TransformationDebugHandler runs user transformations against sample events in isolated script sessions.
+// Live pipelines aren't affected: every request creates its own script instance which is closed after the request
+type TransformationDebugHandler struct{}
+
+func NewTransformationDebugHandler() *TransformationDebugHandler {
+	return &TransformationDebugHandler{}
+}
+
+func (h *TransformationDebugHandler) Handler(c *gin.Context) {
+	req := &DebugTransformationRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing debug transformation body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse("Failed to parse body", err))
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+		return
+	}
+
+	response := h.debug(req)
+	if response.Error != "" {
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TransformationDebugHandler) debug(req *DebugTransformationRequest) (response DebugTransformationResponse) {
+	response = DebugTransformationResponse{}
+	//panic handler
+	defer func() {
+		if r := recover(); r != nil {
+			response.Error = fmt.Errorf("Error: %v", r).Error()
+		}
+	}()
+
+	limits, err := templates.NewScriptLimits(req.ScriptLimits)
+	if err != nil {
+		response.Error = fmt.Errorf("invalid script_limits: %v", err).Error()
+		return
+	}
+
+	var executor *templates.NodeExecutor
+	switch req.Language {
+	case templates.PythonRuntime:
+		executor, err = templates.NewStandaloneScriptExecutor(templates.PythonExpression(req.Transform), req.Variables(), limits)
+	case templates.WasmRuntime:
+		executor, err = templates.NewStandaloneScriptExecutor(templates.WasmModule(strings.TrimSpace(req.Transform)), req.Variables(), limits)
+	case "", "javascript":
+		var includes []string
+		if len(req.Dependencies) > 0 {
+			dependencies, err := templates.BundleDependencies(req.Dependencies)
+			if err != nil {
+				response.Error = fmt.Errorf("failed to bundle dependencies: %v", err).Error()
+				return
+			}
+
+			includes = append(includes, dependencies)
+		}
+
+		executor, err = templates.NewStandaloneScriptExecutor(templates.Expression(req.Transform), req.Variables(), limits, includes...)
+	default:
+		response.Error = fmt.Sprintf("unknown transformation language: %s", req.Language)
+		return
+	}
+
+	if err != nil {
+		response.Error = fmt.Errorf("failed to init transformation: %v", err).Error()
+		return
+	}
+
+	defer executor.Close()
+	response.Format = executor.Format()
+	response.Results = make([]DebugTransformationResult, 0, len(req.Events))
+	for _, event := range req.Events {
+		result := DebugTransformationResult{Logs: make(EvaluateLogs, 0)}
+		start := timestamp.Now()
+		transformed, err := executor.ProcessEvent(events.Event(event), &result.Logs)
+		result.ExecutionTimeMs = float64(timestamp.Now().Sub(start).Microseconds()) / 1000
+		if err != nil {
+			//JavaScript errors contain stack traces with lines of the user transformation code
+			result.Error = err.Error()
+		} else {
+			result.Result = transformed
+		}
+
+		response.Results = append(response.Results, result)
+	}
+
+	return
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/jitsucom/jitsu/server/templates"
+	"github.com/stretchr/testify/require"
+)
+
+//consoleScriptFactory creates scripts which write console records to the listener like JavaScript runtime does
+type consoleScriptFactory struct {
+	variables map[string]interface{}
+	closed    int
+}
+
+func (f *consoleScriptFactory) CreateScript(executable script.Executable, variables map[string]interface{}, standalone bool, includes ...string) (script.Interface, error) {
+	if executable.(script.Expression) == "syntax error" {
+		return nil, errors.New("SyntaxError: Unexpected identifier")
+	}
+
+	f.variables = variables
+	return &consoleScript{factory: f}, nil
+}
+
+type consoleScript struct {
+	factory *consoleScriptFactory
+}
+
+func (s *consoleScript) Describe() (script.Symbols, error) {
+	return script.Symbols{}, nil
+}
+
+//Execute logs the event type with console.log and console.warn, fails on events with 'fail' field
+func (s *consoleScript) Execute(name string, args script.Args, result interface{}, listener script.Listener) error {
+	event := args[0].(events.Event)
+	listener.Log("info", fmt.Sprintf("event_type: %v", event["event_type"]))
+	if _, ok := event["fail"]; ok {
+		listener.Log("error", "about to fail")
+		return errors.New("Error: fail\n    at process (transformation.js:3:11)")
+	}
+	listener.Log("warn", fmt.Sprintf("destination: %v", s.factory.variables["destinationId"]))
+
+	*(result.(*interface{})) = map[string]interface{}{"event_type": event["event_type"], "transformed": true}
+	return nil
+}
+
+func (s *consoleScript) Close() {
+	s.factory.closed++
+}
+
+func TestTransformationDebugHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	factory := &consoleScriptFactory{}
+	templates.SetScriptFactory(factory)
+	defer templates.SetScriptFactory(script.DummyFactory)
+
+	tests := []struct {
+		name             string
+		request          map[string]interface{}
+		expectedCode     int
+		expectedResponse string
+	}{
+		{
+			"Console output is captured per event",
+			map[string]interface{}{
+				"uid":       "dst1",
+				"transform": "return event",
+				"events":    []map[string]interface{}{{"event_type": "pageview"}, {"event_type": "click", "fail": true}},
+			},
+			http.StatusOK,
+			`{"format":"javascript","results":[
+				{"result":{"event_type":"pageview","transformed":true},"logs":[{"level":"info","message":"event_type: pageview"},{"level":"warn","message":"destination: dst1"}]},
+				{"result":null,"logs":[{"level":"info","message":"event_type: click"},{"level":"error","message":"about to fail"}],"error":"Error: fail\n    at process (transformation.js:3:11)"}
+			]}`,
+		},
+		{
+			"Init error",
+			map[string]interface{}{"transform": "syntax error", "events": []map[string]interface{}{{"event_type": "pageview"}}},
+			http.StatusBadRequest,
+			`{"format":"","results":null,"error":"failed to init transformation: spawn node process: SyntaxError: Unexpected identifier"}`,
+		},
+		{
+			"Unknown language",
+			map[string]interface{}{"transform": "return event", "language": "ruby", "events": []map[string]interface{}{{"event_type": "pageview"}}},
+			http.StatusBadRequest,
+			`{"format":"","results":null,"error":"unknown transformation language: ruby"}`,
+		},
+		{
+			"Empty events",
+			map[string]interface{}{"transform": "return event"},
+			http.StatusBadRequest,
+			`{"message":"'events' is required field","error":""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/api/v1/transformations/debug", NewTransformationDebugHandler().Handler)
+
+			body, err := json.Marshal(tt.request)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/transformations/debug", bytes.NewReader(body)))
+			require.Equal(t, tt.expectedCode, recorder.Code, recorder.Body.String())
+
+			//execution time isn't deterministic
+			response := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			results, _ := response["results"].([]interface{})
+			for _, result := range results {
+				executionTime, ok := result.(map[string]interface{})["execution_time_ms"]
+				require.True(t, ok, "execution_time_ms must be in every result")
+				require.GreaterOrEqual(t, executionTime, float64(0))
+				delete(result.(map[string]interface{}), "execution_time_ms")
+			}
+			actual, err := json.Marshal(response)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedResponse, string(actual))
+		})
+	}
+
+	require.Equal(t, 1, factory.closed, "script must be closed after the request")
+}
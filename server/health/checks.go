@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/coordination"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+const nodePingValue = "ping"
+
+// MetaStorageCheck returns critical check of meta storage (Redis) connectivity
+func MetaStorageCheck(metaStorage meta.Storage) Check {
+	return Check{
+		Name:     "meta_storage",
+		Critical: true,
+		Func: func(ctx context.Context) (interface{}, error) {
+			details := map[string]string{"type": metaStorage.Type()}
+			return details, metaStorage.Ping(ctx)
+		},
+	}
+}
+
+// CoordinationCheck returns critical check of coordination service: cluster instances must be obtainable
+func CoordinationCheck(coordinationService *coordination.Service) Check {
+	return Check{
+		Name:     "coordination",
+		Critical: true,
+		Func: func(ctx context.Context) (interface{}, error) {
+			instances, err := coordinationService.GetJitsuInstancesInCluster()
+			if err != nil {
+				return nil, err
+			}
+
+			return map[string]int{"instances": len(instances)}, nil
+		},
+	}
+}
+
+// DestinationsCheck returns non-critical check of destinations connectivity based on streaming destinations circuit
+// breakers: destinations with open circuit breakers have consecutive connection errors
+func DestinationsCheck(destinationsService *destinations.Service, cacheTTL time.Duration) Check {
+	return Check{
+		Name:     "destinations",
+		CacheTTL: cacheTTL,
+		Func: func(ctx context.Context) (interface{}, error) {
+			var unavailable []string
+			for _, status := range storages.CircuitBreakerStatuses() {
+				if status.State != storages.CircuitBreakerClosed {
+					unavailable = append(unavailable, status.DestinationID)
+				}
+			}
+
+			details := map[string]interface{}{"total": len(destinationsService.GetAllDestinationIDs())}
+			if len(unavailable) > 0 {
+				details["unavailable"] = unavailable
+				return details, Degraded(fmt.Errorf("destinations are unavailable: [%s]", strings.Join(unavailable, ", ")))
+			}
+
+			return details, nil
+		},
+	}
+}
+
+// NodeCheck returns non-critical check of Node.js runtime: a trivial transformation is executed.
+// scriptFactory is nil if Node.js isn't available
+func NodeCheck(scriptFactory script.Factory, cacheTTL time.Duration) Check {
+	return Check{
+		Name:     "node",
+		CacheTTL: cacheTTL,
+		Func: func(ctx context.Context) (interface{}, error) {
+			if scriptFactory == nil {
+				return nil, errors.New("Node.js runtime isn't available: JavaScript transformations are disabled")
+			}
+
+			instance, err := scriptFactory.CreateScript(script.Expression("return event"), nil, false)
+			if err != nil {
+				return nil, fmt.Errorf("error creating script: %v", err)
+			}
+			defer instance.Close()
+
+			var result string
+			if err := instance.Execute("", script.Args{nodePingValue}, &result, nil); err != nil {
+				return nil, fmt.Errorf("error executing script: %v", err)
+			}
+			if result != nodePingValue {
+				return nil, fmt.Errorf("unexpected script result: %s", result)
+			}
+
+			return nil, nil
+		},
+	}
+}
@@ -0,0 +1,224 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+
+	defaultCheckTimeout = 5 * time.Second
+)
+
+// CheckFunc checks a dependency and returns optional details. A returned error marks the dependency as failed,
+// an error wrapped with Degraded marks the dependency as working but not healthy
+type CheckFunc func(ctx context.Context) (interface{}, error)
+
+// Check is a named dependency check
+type Check struct {
+	Name string
+	//Critical checks failures make the instance not ready. Non-critical checks failures only degrade the status
+	Critical bool
+	//CacheTTL is a duration during which the last result is returned without running the check (0 - no caching)
+	CacheTTL time.Duration
+	Func     CheckFunc
+}
+
+// CheckResult is a dto with a single dependency check result
+type CheckResult struct {
+	Status    string      `json:"status"`
+	Critical  bool        `json:"critical"`
+	Error     string      `json:"error,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+	LatencyMs int64       `json:"latency_ms"`
+	Cached    bool        `json:"cached,omitempty"`
+	CheckedAt time.Time   `json:"checked_at"`
+}
+
+// Report is a dto for health endpoints response
+type Report struct {
+	Status string                  `json:"status"`
+	Uptime string                  `json:"uptime"`
+	Checks map[string]*CheckResult `json:"checks,omitempty"`
+}
+
+// Ready returns false if at least one critical dependency has failed
+func (r *Report) Ready() bool {
+	return r.Status != StatusFail
+}
+
+type degradedError struct {
+	err error
+}
+
+func (de *degradedError) Error() string {
+	return de.err.Error()
+}
+
+func (de *degradedError) Unwrap() error {
+	return de.err
+}
+
+// Degraded wraps the error so the check is reported as degraded instead of failed
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &degradedError{err: err}
+}
+
+// registeredCheck keeps the last check result for caching
+type registeredCheck struct {
+	Check
+
+	mutex      sync.Mutex
+	lastResult *CheckResult
+}
+
+// Service runs registered dependency checks concurrently and builds liveness and readiness reports
+type Service struct {
+	startedAt time.Time
+	timeout   time.Duration
+
+	mutex  sync.RWMutex
+	checks []*registeredCheck
+}
+
+// NewService returns configured Service. timeout is a max duration of a single check
+func NewService(timeout time.Duration) *Service {
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+
+	return &Service{startedAt: timestamp.Now(), timeout: timeout}
+}
+
+// Register adds the check. Checks with the same name are replaced
+func (s *Service) Register(check Check) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, existing := range s.checks {
+		if existing.Name == check.Name {
+			s.checks[i] = &registeredCheck{Check: check}
+			return
+		}
+	}
+	s.checks = append(s.checks, &registeredCheck{Check: check})
+}
+
+// Live returns liveness report: the process is running and able to serve HTTP requests. Dependencies aren't checked
+func (s *Service) Live() *Report {
+	return &Report{Status: StatusOK, Uptime: s.uptime()}
+}
+
+// Ready runs all registered checks (or returns cached results) and returns readiness report
+func (s *Service) Ready(ctx context.Context) *Report {
+	s.mutex.RLock()
+	checks := make([]*registeredCheck, len(s.checks))
+	copy(checks, s.checks)
+	s.mutex.RUnlock()
+
+	results := make([]*CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *registeredCheck) {
+			defer wg.Done()
+			results[i] = s.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusOK, Uptime: s.uptime(), Checks: make(map[string]*CheckResult, len(checks))}
+	for i, check := range checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+		switch {
+		case result.Status == StatusFail && check.Critical:
+			report.Status = StatusFail
+		case result.Status != StatusOK && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// run returns the cached check result if it isn't expired or runs the check with timeout
+func (s *Service) run(ctx context.Context, check *registeredCheck) *CheckResult {
+	check.mutex.Lock()
+	defer check.mutex.Unlock()
+
+	if check.CacheTTL > 0 && check.lastResult != nil && timestamp.Now().Sub(check.lastResult.CheckedAt) < check.CacheTTL {
+		cached := *check.lastResult
+		cached.Cached = true
+		return &cached
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	started := timestamp.Now()
+	details, err := s.execute(checkCtx, check.Func)
+	result := &CheckResult{
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		Details:   details,
+		LatencyMs: timestamp.Now().Sub(started).Milliseconds(),
+		CheckedAt: started,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		var de *degradedError
+		if errors.As(err, &de) {
+			result.Status = StatusDegraded
+		} else {
+			result.Status = StatusFail
+			logging.Warnf("[health] Dependency [%s] check has failed: %v", check.Name, err)
+		}
+	}
+
+	check.lastResult = result
+	resultCopy := *result
+	return &resultCopy
+}
+
+// execute runs the check in a separate goroutine so a hanging dependency can't block the report longer than timeout
+func (s *Service) execute(ctx context.Context, checkFunc CheckFunc) (interface{}, error) {
+	type outcome struct {
+		details interface{}
+		err     error
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		details, err := checkFunc(ctx)
+		done <- outcome{details: details, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.details, o.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("check timeout: %v", ctx.Err())
+	}
+}
+
+func (s *Service) uptime() string {
+	return timestamp.Now().Sub(s.startedAt).Round(time.Second).String()
+}
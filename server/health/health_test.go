@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func okCheck(name string, critical bool) Check {
+	return Check{Name: name, Critical: critical, Func: func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}}
+}
+
+func failedCheck(name string, critical bool, err error) Check {
+	return Check{Name: name, Critical: critical, Func: func(ctx context.Context) (interface{}, error) {
+		return nil, err
+	}}
+}
+
+func TestReadyStatus(t *testing.T) {
+	service := NewService(time.Second)
+	service.Register(okCheck("redis", true))
+	service.Register(okCheck("node", false))
+
+	report := service.Ready(context.Background())
+	require.Equal(t, StatusOK, report.Status)
+	require.True(t, report.Ready())
+	require.Len(t, report.Checks, 2)
+
+	//non-critical failure degrades the status
+	service.Register(failedCheck("node", false, errors.New("node isn't available")))
+	report = service.Ready(context.Background())
+	require.Equal(t, StatusDegraded, report.Status)
+	require.True(t, report.Ready())
+	require.Equal(t, StatusFail, report.Checks["node"].Status)
+	require.Equal(t, "node isn't available", report.Checks["node"].Error)
+
+	//degraded critical check doesn't make the instance not ready
+	service.Register(failedCheck("redis", true, Degraded(errors.New("slow"))))
+	report = service.Ready(context.Background())
+	require.Equal(t, StatusDegraded, report.Status)
+	require.Equal(t, StatusDegraded, report.Checks["redis"].Status)
+
+	service.Register(failedCheck("redis", true, errors.New("connection refused")))
+	report = service.Ready(context.Background())
+	require.Equal(t, StatusFail, report.Status)
+	require.False(t, report.Ready())
+
+	live := service.Live()
+	require.Equal(t, StatusOK, live.Status)
+	require.Empty(t, live.Checks)
+}
+
+func TestReadyCaching(t *testing.T) {
+	var calls atomic.Int32
+	service := NewService(time.Second)
+	service.Register(Check{Name: "destinations", CacheTTL: time.Hour, Func: func(ctx context.Context) (interface{}, error) {
+		calls.Inc()
+		return nil, nil
+	}})
+
+	report := service.Ready(context.Background())
+	require.False(t, report.Checks["destinations"].Cached)
+
+	report = service.Ready(context.Background())
+	require.True(t, report.Checks["destinations"].Cached)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestReadyTimeoutAndPanic(t *testing.T) {
+	service := NewService(50 * time.Millisecond)
+	service.Register(Check{Name: "hanging", Critical: true, Func: func(ctx context.Context) (interface{}, error) {
+		time.Sleep(time.Second)
+		return nil, nil
+	}})
+	service.Register(Check{Name: "panicking", Func: func(ctx context.Context) (interface{}, error) {
+		panic("unexpected")
+	}})
+
+	started := time.Now()
+	report := service.Ready(context.Background())
+	require.Less(t, time.Since(started), time.Second)
+	require.Equal(t, StatusFail, report.Status)
+	require.Equal(t, StatusFail, report.Checks["hanging"].Status)
+	require.Contains(t, report.Checks["hanging"].Error, "timeout")
+	require.Equal(t, StatusFail, report.Checks["panicking"].Status)
+	require.Contains(t, report.Checks["panicking"].Error, "panic")
+}
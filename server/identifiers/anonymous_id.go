@@ -0,0 +1,17 @@
+package identifiers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const cookielessIDRotationLayout = "2006-01-02"
+
+//CookielessAnonymousID returns anonymous ID for cookie-less mode: salted hash of client IP and user-agent.
+//The current UTC day is a part of the hash so the ID rotates daily and can't be used as a long-living fingerprint
+func CookielessAnonymousID(salt, clientIP, userAgent string, now time.Time) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{salt, now.UTC().Format(cookielessIDRotationLayout), clientIP, userAgent}, "|")))
+	return hex.EncodeToString(sum[:16])
+}
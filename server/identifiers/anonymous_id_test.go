@@ -0,0 +1,20 @@
+package identifiers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookielessAnonymousID(t *testing.T) {
+	morning := time.Date(2022, 3, 1, 8, 0, 0, 0, time.UTC)
+	id := CookielessAnonymousID("salt", "10.0.0.1", "Mozilla/5.0", morning)
+	require.Len(t, id, 32)
+
+	require.Equal(t, id, CookielessAnonymousID("salt", "10.0.0.1", "Mozilla/5.0", morning.Add(15*time.Hour)), "the same day")
+	require.NotEqual(t, id, CookielessAnonymousID("salt", "10.0.0.1", "Mozilla/5.0", morning.AddDate(0, 0, 1)), "rotated next day")
+	require.NotEqual(t, id, CookielessAnonymousID("other", "10.0.0.1", "Mozilla/5.0", morning))
+	require.NotEqual(t, id, CookielessAnonymousID("salt", "10.0.0.2", "Mozilla/5.0", morning))
+	require.NotEqual(t, id, CookielessAnonymousID("salt", "10.0.0.1", "curl/7.0", morning))
+}
@@ -94,7 +94,7 @@ func TestDestinationAdapterInsert(t *testing.T) {
 	err := appconfig.Init(false, "")
 	require.NoError(t, err)
 
-	enrichment.InitDefault("", "", "", "")
+	enrichment.InitDefault("", "", "", "", "")
 
 	tests := []struct {
 		name                      string
@@ -399,7 +399,7 @@ func initializeTestSuite(t *testing.T, destinationType string) (*InsertTestSuite
 			return nil, fmt.Errorf("failed to initialize table statement factory: %v", err)
 		}
 
-		adapter, err := adapters.NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, tsf, map[string]bool{},
+		adapter, err := adapters.NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, nil, tsf, map[string]bool{},
 			&logging.QueryLogger{}, typing.SQLTypes{})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create adapter: %v", err)
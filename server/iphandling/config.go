@@ -0,0 +1,120 @@
+package iphandling
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IP handling modes ordered from the least to the most strict
+const (
+	// KeepMode keeps IP address as is
+	KeepMode = "keep"
+	// TruncateMode replaces the last octet of IPv4 address with 1 and keeps only /48 prefix of IPv6 address
+	TruncateMode = "truncate"
+	// HashMode replaces IP address with HMAC-SHA256 hash of the address with the configured salt
+	HashMode = "hash"
+	// GeoThenDropMode resolves geo data from IP address and then removes the address
+	GeoThenDropMode = "geo_then_drop"
+)
+
+var (
+	// strictness is used for choosing the mode if both API key and destination are configured
+	strictness = map[string]int{KeepMode: 0, TruncateMode: 1, HashMode: 2, GeoThenDropMode: 3}
+
+	// ipv6TruncateMask keeps the first 48 bits of IPv6 address
+	ipv6TruncateMask = net.CIDRMask(48, 128)
+)
+
+// Config is an IP handling configuration of an API key or a destination
+type Config struct {
+	Mode string `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+	// Salt is required in hash mode
+	Salt string `mapstructure:"salt" json:"salt,omitempty" yaml:"salt,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	if _, ok := strictness[c.Mode]; !ok {
+		return fmt.Errorf("unsupported IP handling mode: %q. Supported: %s, %s, %s, %s", c.Mode, KeepMode, TruncateMode, HashMode, GeoThenDropMode)
+	}
+
+	if c.Mode == HashMode && c.Salt == "" {
+		return errors.New("salt is required in hash IP handling mode")
+	}
+
+	return nil
+}
+
+// IsEmpty returns true if IP address is kept as is
+func (c *Config) IsEmpty() bool {
+	return c == nil || c.Mode == "" || c.Mode == KeepMode
+}
+
+// Stricter returns the configuration with the stricter mode. nil configurations are ignored
+func Stricter(first, second *Config) *Config {
+	if first == nil {
+		return second
+	}
+	if second == nil {
+		return first
+	}
+
+	if strictness[second.Mode] > strictness[first.Mode] {
+		return second
+	}
+
+	return first
+}
+
+// Apply returns handled IP address or comma separated list of addresses. Empty string is returned
+// if the address must be removed (geo_then_drop)
+func (c *Config) Apply(ipStr string) string {
+	switch c.Mode {
+	case TruncateMode:
+		return mapIPs(ipStr, Truncate)
+	case HashMode:
+		return mapIPs(ipStr, func(ip string) string {
+			return hash(c.Salt, ip)
+		})
+	case GeoThenDropMode:
+		return ""
+	default:
+		return ipStr
+	}
+}
+
+// Truncate returns IPv4 address with the last octet replaced with 1 or /48 prefix of IPv6 address.
+// Values which aren't IP addresses are returned as is
+func Truncate(ipStr string) string {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return ipStr
+	}
+
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ipv4[3] = 1
+		return ipv4.String()
+	}
+
+	return ip.Mask(ipv6TruncateMask).String()
+}
+
+func hash(salt, ip string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func mapIPs(ipStr string, f func(string) string) string {
+	ips := strings.Split(ipStr, ",")
+	for i, ip := range ips {
+		ips[i] = f(strings.TrimSpace(ip))
+	}
+
+	return strings.Join(ips, ",")
+}
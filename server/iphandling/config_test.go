@@ -0,0 +1,50 @@
+package iphandling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, (&Config{Mode: KeepMode}).Validate())
+	require.NoError(t, (&Config{Mode: TruncateMode}).Validate())
+	require.NoError(t, (&Config{Mode: HashMode, Salt: "salt"}).Validate())
+	require.NoError(t, (&Config{Mode: GeoThenDropMode}).Validate())
+
+	require.Error(t, (&Config{Mode: "unknown"}).Validate())
+	require.Error(t, (&Config{Mode: HashMode}).Validate())
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *Config
+		input    string
+		expected string
+	}{
+		{"Keep", &Config{Mode: KeepMode}, "10.10.10.10", "10.10.10.10"},
+		{"Truncate IPv4", &Config{Mode: TruncateMode}, "10.10.10.10", "10.10.10.1"},
+		{"Truncate IPv6", &Config{Mode: TruncateMode}, "2001:db8:85a3:8d3:1319:8a2e:370:7348", "2001:db8:85a3::"},
+		{"Truncate list", &Config{Mode: TruncateMode}, "10.10.10.10, 20.20.20.20", "10.10.10.1,20.20.20.1"},
+		{"Truncate not IP", &Config{Mode: TruncateMode}, "abc", "abc"},
+		{"Hash", &Config{Mode: HashMode, Salt: "salt"}, "10.10.10.10", "37fe8d367cdb04625456b1be4b7ec2e200bee3368a05ef8bdb244bfaaf6fbde2"},
+		{"Geo then drop", &Config{Mode: GeoThenDropMode}, "10.10.10.10", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.config.Apply(tt.input))
+		})
+	}
+}
+
+func TestStricter(t *testing.T) {
+	keep, truncate, hash := &Config{Mode: KeepMode}, &Config{Mode: TruncateMode}, &Config{Mode: HashMode, Salt: "salt"}
+
+	require.Nil(t, Stricter(nil, nil))
+	require.Equal(t, truncate, Stricter(nil, truncate))
+	require.Equal(t, truncate, Stricter(truncate, nil))
+	require.Equal(t, truncate, Stricter(keep, truncate))
+	require.Equal(t, hash, Stricter(hash, truncate))
+	require.Equal(t, hash, Stricter(truncate, hash))
+}
@@ -0,0 +1,32 @@
+package consul
+
+import (
+	"github.com/hashicorp/consul/api"
+	"github.com/jitsucom/jitsu/server/locks"
+	"github.com/jitsucom/jitsu/server/locks/redis"
+	"io"
+)
+
+//LockFactory is a Consul based LockFactory
+type LockFactory struct {
+	client      *api.Client
+	prefix      string
+	sessionTTL  string
+	locksCloser *redis.LocksCloser
+}
+
+//NewLockFactory returns configured Consul based LockFactory. Locks are bound to sessions with sessionTTL (e.g. 15s)
+func NewLockFactory(client *api.Client, prefix, sessionTTL string) (*LockFactory, io.Closer) {
+	locksCloser := redis.NewLocksCloser()
+	return &LockFactory{
+		client:      client,
+		prefix:      prefix,
+		sessionTTL:  sessionTTL,
+		locksCloser: locksCloser,
+	}, locksCloser
+}
+
+//CreateLock returns lock instance (not yet locked)
+func (lf *LockFactory) CreateLock(name string) locks.Lock {
+	return newLock(name, lf.client, lf.prefix, lf.sessionTTL, lf.locksCloser)
+}
@@ -0,0 +1,124 @@
+package consul
+
+import (
+	"errors"
+	"github.com/hashicorp/consul/api"
+	"github.com/jitsucom/jitsu/server/locks/redis"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"time"
+)
+
+//consul key [variables] - description
+//** Locking **
+//${prefix}coordination/mutex/${identifier} - KV entry acquired by the lock owner session
+
+//minLockWaitTime is used for TryLock without waiting: Consul uses the default wait time (15s) if it is zero
+const minLockWaitTime = 10 * time.Millisecond
+
+//Lock is a Consul lock. Every acquisition creates a session which is renewed by the Consul client until Unlock.
+//If the server dies, the lock is released after the session TTL
+type Lock struct {
+	key        string
+	client     *api.Client
+	sessionTTL string
+	lockCloser *redis.LocksCloser
+
+	//exist only after locking
+	lock         *api.Lock
+	fencingToken int64
+	released     chan struct{}
+}
+
+func newLock(name string, client *api.Client, prefix, sessionTTL string, lockCloser *redis.LocksCloser) *Lock {
+	return &Lock{
+		key:        prefix + "coordination/mutex/" + name,
+		client:     client,
+		sessionTTL: sessionTTL,
+		lockCloser: lockCloser,
+	}
+}
+
+// TryLock Attempts to acquire lock within given amount of time. If lock is not free by
+// that time, returns false. Otherwise, returns true
+func (l *Lock) TryLock(timeout time.Duration) (bool, error) {
+	if timeout < minLockWaitTime {
+		timeout = minLockWaitTime
+	}
+
+	lock, err := l.client.LockOpts(&api.LockOptions{
+		Key:          l.key,
+		SessionName:  "jitsu lock " + l.key,
+		SessionTTL:   l.sessionTTL,
+		LockWaitTime: timeout,
+		LockTryOnce:  true,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	lostCh, err := lock.Lock(nil)
+	if err != nil {
+		return false, err
+	}
+	if lostCh == nil {
+		return false, nil
+	}
+
+	//ModifyIndex is changed on every acquisition and is greater than indexes of all previous acquisitions
+	pair, _, err := l.client.KV().Get(l.key, &api.QueryOptions{RequireConsistent: true})
+	if err != nil || pair == nil {
+		if unlockErr := lock.Unlock(); unlockErr != nil {
+			logging.Warnf("[lock: %s] error unlocking: %v", l.key, unlockErr)
+		}
+		if err == nil {
+			err = errors.New("lock key doesn't exist after acquisition")
+		}
+		return false, err
+	}
+
+	l.lock = lock
+	l.fencingToken = int64(pair.ModifyIndex)
+	l.released = make(chan struct{})
+	l.watchSession(lostCh)
+
+	l.lockCloser.Add(l.key, l)
+
+	return true, nil
+}
+
+//watchSession logs an error if the session has been invalidated before Unlock (e.g. Consul has been unavailable longer than session TTL)
+func (l *Lock) watchSession(lostCh <-chan struct{}) {
+	released := l.released
+	safego.Run(func() {
+		select {
+		case <-released:
+		case <-lostCh:
+			select {
+			case <-released:
+			default:
+				logging.SystemErrorf("[lock: %s] Consul session has been invalidated before unlocking. The lock might be acquired by another instance", l.key)
+			}
+		}
+	})
+}
+
+//FencingToken returns Consul modify index of the lock acquisition
+func (l *Lock) FencingToken() int64 {
+	return l.fencingToken
+}
+
+//Unlock releases the lock and destroys the session
+func (l *Lock) Unlock() {
+	if l.lock == nil {
+		return
+	}
+	close(l.released)
+
+	if err := l.lock.Unlock(); err != nil {
+		logging.SystemErrorf("error unlocking %s: %v", l.key, err)
+	}
+
+	l.lock = nil
+	l.lockCloser.Remove(l.key)
+}
@@ -0,0 +1,288 @@
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+//consulServer is a fake Consul HTTP API with sessions and KV endpoints which are used by api.Lock
+type consulServer struct {
+	mutex    sync.Mutex
+	index    uint64
+	sessions map[string]string
+	kv       map[string]*api.KVPair
+	//changed is closed and recreated on every change
+	changed chan struct{}
+	closed  chan struct{}
+}
+
+func newConsulServer(t *testing.T) (*consulServer, *api.Client) {
+	cs := &consulServer{
+		index:    1,
+		sessions: map[string]string{},
+		kv:       map[string]*api.KVPair{},
+		changed:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+
+	server := httptest.NewServer(cs)
+	t.Cleanup(server.Close)
+	//cleanups are called in reverse order: blocking queries are finished before closing the server
+	t.Cleanup(func() { close(cs.closed) })
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	require.NoError(t, err)
+
+	return cs, client
+}
+
+func (cs *consulServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		cs.getKey(w, r, strings.TrimPrefix(r.URL.Path, "/v1/kv/"))
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		cs.putKey(w, r, strings.TrimPrefix(r.URL.Path, "/v1/kv/"))
+	case r.Method == http.MethodPut && r.URL.Path == "/v1/session/create":
+		cs.createSession(w, r)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		cs.renewSession(w, strings.TrimPrefix(r.URL.Path, "/v1/session/renew/"))
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		cs.invalidate(strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/"))
+		writeJSON(w, true)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+//getKey supports blocking queries: waits until the index is changed or wait time is over
+func (cs *consulServer) getKey(w http.ResponseWriter, r *http.Request, key string) {
+	waitIndex, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	waitTime := time.Minute
+	if wait := r.URL.Query().Get("wait"); wait != "" {
+		waitTime, _ = time.ParseDuration(wait)
+	}
+	timeout := time.After(waitTime)
+
+	for {
+		cs.mutex.Lock()
+		index, changed, pair := cs.index, cs.changed, cs.kv[key]
+		var copied api.KVPair
+		if pair != nil {
+			copied = *pair
+		}
+		cs.mutex.Unlock()
+
+		if waitIndex < index {
+			w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+			if pair == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeJSON(w, []*api.KVPair{&copied})
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout:
+			waitIndex = 0
+		case <-cs.closed:
+			waitIndex = 0
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+//putKey supports only acquire and release operations
+func (cs *consulServer) putKey(w http.ResponseWriter, r *http.Request, key string) {
+	value, _ := ioutil.ReadAll(r.Body)
+	flags, _ := strconv.ParseUint(r.URL.Query().Get("flags"), 10, 64)
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	pair := cs.kv[key]
+	if session := r.URL.Query().Get("acquire"); session != "" {
+		_, ok := cs.sessions[session]
+		if !ok || (pair != nil && pair.Session != "" && pair.Session != session) {
+			writeJSON(w, false)
+			return
+		}
+		if pair == nil {
+			pair = &api.KVPair{Key: key, CreateIndex: cs.index + 1}
+			cs.kv[key] = pair
+		}
+		if pair.Session != session {
+			pair.LockIndex++
+		}
+		pair.Value, pair.Flags, pair.Session = value, flags, session
+		cs.change(pair)
+		writeJSON(w, true)
+	} else if session := r.URL.Query().Get("release"); session != "" {
+		if pair == nil || pair.Session != session {
+			writeJSON(w, false)
+			return
+		}
+		pair.Session = ""
+		cs.change(pair)
+		writeJSON(w, true)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (cs *consulServer) createSession(w http.ResponseWriter, r *http.Request) {
+	entry := &api.SessionEntry{}
+	if err := json.NewDecoder(r.Body).Decode(entry); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	id := fmt.Sprintf("session-%d", cs.index)
+	cs.sessions[id] = entry.TTL
+	cs.change(nil)
+	writeJSON(w, map[string]string{"ID": id})
+}
+
+func (cs *consulServer) renewSession(w http.ResponseWriter, id string) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	ttl, ok := cs.sessions[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeJSON(w, []*api.SessionEntry{{ID: id, TTL: ttl}})
+}
+
+//invalidate destroys the session and releases all keys held by it (as Consul does when the session TTL expires)
+func (cs *consulServer) invalidate(id string) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	delete(cs.sessions, id)
+	for _, pair := range cs.kv {
+		if pair.Session == id {
+			pair.Session = ""
+			cs.change(pair)
+		}
+	}
+}
+
+//change increments the index and notifies blocking queries. Must be called under the mutex
+func (cs *consulServer) change(pair *api.KVPair) {
+	cs.index++
+	if pair != nil {
+		pair.ModifyIndex = cs.index
+	}
+	close(cs.changed)
+	cs.changed = make(chan struct{})
+}
+
+func (cs *consulServer) sessionOf(key string) string {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if pair, ok := cs.kv[key]; ok {
+		return pair.Session
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func TestLock(t *testing.T) {
+	_, client := newConsulServer(t)
+	factory, closer := NewLockFactory(client, "test/", "15s")
+	defer closer.Close()
+
+	first := factory.CreateLock("system_lock").(*Lock)
+	locked, err := first.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+	firstToken := first.FencingToken()
+	require.Greater(t, firstToken, int64(0))
+
+	//contention: lock is held by the first instance
+	second := factory.CreateLock("system_lock").(*Lock)
+	locked, err = second.TryLock(0)
+	require.NoError(t, err)
+	require.False(t, locked)
+
+	started := time.Now()
+	locked, err = second.TryLock(300 * time.Millisecond)
+	require.NoError(t, err)
+	require.False(t, locked)
+	require.GreaterOrEqual(t, time.Since(started), 300*time.Millisecond)
+
+	//other locks aren't affected
+	other := factory.CreateLock("other_lock")
+	locked, err = other.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+	other.Unlock()
+
+	//waiting acquisition succeeds after unlocking
+	time.AfterFunc(200*time.Millisecond, first.Unlock)
+	locked, err = second.TryLock(5 * time.Second)
+	require.NoError(t, err)
+	require.True(t, locked)
+	secondToken := second.FencingToken()
+	require.Greater(t, secondToken, firstToken)
+	second.Unlock()
+
+	//fencing tokens only increase
+	locked, err = first.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+	require.Greater(t, first.FencingToken(), secondToken)
+	first.Unlock()
+}
+
+func TestLockSessionExpiry(t *testing.T) {
+	server, client := newConsulServer(t)
+	factory, closer := NewLockFactory(client, "test/", "15s")
+	defer closer.Close()
+
+	first := factory.CreateLock("system_lock").(*Lock)
+	locked, err := first.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	//the session is invalidated e.g. when the instance has been disconnected from Consul longer than the session TTL
+	server.invalidate(server.sessionOf(first.key))
+
+	second := factory.CreateLock("system_lock").(*Lock)
+	locked, err = second.TryLock(time.Second)
+	require.NoError(t, err)
+	require.True(t, locked)
+	require.Greater(t, second.FencingToken(), first.FencingToken())
+
+	//unlocking of the expired lock doesn't release the lock of another instance
+	first.Unlock()
+	third := factory.CreateLock("system_lock")
+	locked, err = third.TryLock(0)
+	require.NoError(t, err)
+	require.False(t, locked)
+
+	second.Unlock()
+}
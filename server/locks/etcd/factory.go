@@ -0,0 +1,35 @@
+package etcd
+
+import (
+	"context"
+	"github.com/jitsucom/jitsu/server/locks"
+	"github.com/jitsucom/jitsu/server/locks/redis"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"io"
+)
+
+//LockFactory is an etcd based LockFactory
+type LockFactory struct {
+	ctx         context.Context
+	client      *clientv3.Client
+	prefix      string
+	leaseTTL    int
+	locksCloser *redis.LocksCloser
+}
+
+//NewLockFactory returns configured etcd based LockFactory. Locks are bound to leases with leaseTTL seconds
+func NewLockFactory(ctx context.Context, client *clientv3.Client, prefix string, leaseTTL int) (*LockFactory, io.Closer) {
+	locksCloser := redis.NewLocksCloser()
+	return &LockFactory{
+		ctx:         ctx,
+		client:      client,
+		prefix:      prefix,
+		leaseTTL:    leaseTTL,
+		locksCloser: locksCloser,
+	}, locksCloser
+}
+
+//CreateLock returns lock instance (not yet locked)
+func (lf *LockFactory) CreateLock(name string) locks.Lock {
+	return newLock(name, lf.ctx, lf.client, lf.prefix, lf.leaseTTL, lf.locksCloser)
+}
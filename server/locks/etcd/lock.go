@@ -0,0 +1,128 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"github.com/jitsucom/jitsu/server/locks/redis"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"time"
+)
+
+//etcd key [variables] - description
+//** Locking **
+//${prefix}coordination/mutex/${identifier}/${leaseID} - lock waiters. The waiter with the lowest create revision holds the lock
+
+const unlockTimeout = 5 * time.Second
+
+//Lock is an etcd lock. Every acquisition creates a lease which is kept alive by the etcd client until Unlock.
+//If the server dies, the lock is released after the lease TTL
+type Lock struct {
+	mutexName  string
+	ctx        context.Context
+	client     *clientv3.Client
+	leaseTTL   int
+	lockCloser *redis.LocksCloser
+
+	//exist only after locking
+	session      *concurrency.Session
+	mutex        *concurrency.Mutex
+	fencingToken int64
+	released     chan struct{}
+}
+
+func newLock(name string, ctx context.Context, client *clientv3.Client, prefix string, leaseTTL int, lockCloser *redis.LocksCloser) *Lock {
+	return &Lock{
+		mutexName:  prefix + "coordination/mutex/" + name,
+		ctx:        ctx,
+		client:     client,
+		leaseTTL:   leaseTTL,
+		lockCloser: lockCloser,
+	}
+}
+
+// TryLock Attempts to acquire lock within given amount of time. If lock is not free by
+// that time, returns false. Otherwise, returns true
+func (l *Lock) TryLock(timeout time.Duration) (bool, error) {
+	session, err := concurrency.NewSession(l.client, concurrency.WithTTL(l.leaseTTL), concurrency.WithContext(l.ctx))
+	if err != nil {
+		return false, err
+	}
+
+	mutex := concurrency.NewMutex(session, l.mutexName)
+	if timeout == 0 {
+		err = mutex.TryLock(l.ctx)
+	} else {
+		ctx, cancel := context.WithTimeout(l.ctx, timeout)
+		err = mutex.Lock(ctx)
+		cancel()
+	}
+
+	if err != nil {
+		//revokes the lease and removes the waiter key
+		if closeErr := session.Close(); closeErr != nil {
+			logging.Warnf("[lock: %s] error closing etcd session: %v", l.mutexName, closeErr)
+		}
+
+		if err == concurrency.ErrLocked || errors.Is(err, context.DeadlineExceeded) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	l.session = session
+	l.mutex = mutex
+	//revision of the acquisition is greater than revisions of all previous acquisitions
+	l.fencingToken = mutex.Header().Revision
+	l.released = make(chan struct{})
+	l.watchLease()
+
+	l.lockCloser.Add(l.mutexName, l)
+
+	return true, nil
+}
+
+//watchLease logs an error if the lease has expired before Unlock (e.g. etcd has been unavailable longer than lease TTL)
+func (l *Lock) watchLease() {
+	session, released := l.session, l.released
+	safego.Run(func() {
+		select {
+		case <-released:
+		case <-session.Done():
+			select {
+			case <-released:
+			default:
+				logging.SystemErrorf("[lock: %s] etcd lease has expired before unlocking. The lock might be acquired by another instance", l.mutexName)
+			}
+		}
+	})
+}
+
+//FencingToken returns etcd revision of the lock acquisition
+func (l *Lock) FencingToken() int64 {
+	return l.fencingToken
+}
+
+//Unlock deletes the lock key and revokes the lease
+func (l *Lock) Unlock() {
+	if l.session == nil {
+		return
+	}
+	close(l.released)
+
+	ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+	defer cancel()
+	if err := l.mutex.Unlock(ctx); err != nil {
+		logging.SystemErrorf("error unlocking %s: %v", l.mutexName, err)
+	}
+	if err := l.session.Close(); err != nil {
+		logging.Warnf("[lock: %s] error closing etcd session: %v", l.mutexName, err)
+	}
+
+	l.session = nil
+	l.mutex = nil
+	l.lockCloser.Remove(l.mutexName)
+}
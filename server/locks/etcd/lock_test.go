@@ -0,0 +1,133 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+//freeURL returns http://localhost URL with a free port
+func freeURL(t *testing.T) url.URL {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	return url.URL{Scheme: "http", Host: listener.Addr().String()}
+}
+
+//runEtcd starts embedded single node etcd and returns the client connected to it
+func runEtcd(t *testing.T) *clientv3.Client {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	cfg.LCUrls, cfg.ACUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	cfg.LPUrls, cfg.APUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	cfg.InitialCluster = fmt.Sprintf("%s=%s", cfg.Name, peerURL.String())
+
+	server, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "etcd hasn't started in 10 seconds")
+	}
+
+	client, err := clientv3.New(clientv3.Config{Endpoints: []string{clientURL.String()}, DialTimeout: 5 * time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestLock(t *testing.T) {
+	client := runEtcd(t)
+	factory, closer := NewLockFactory(context.Background(), client, "test/", 5)
+	defer closer.Close()
+
+	first := factory.CreateLock("system_lock").(*Lock)
+	locked, err := first.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+	firstToken := first.FencingToken()
+	require.Greater(t, firstToken, int64(0))
+
+	//contention: lock is held by the first instance
+	second := factory.CreateLock("system_lock").(*Lock)
+	locked, err = second.TryLock(0)
+	require.NoError(t, err)
+	require.False(t, locked)
+
+	started := time.Now()
+	locked, err = second.TryLock(300 * time.Millisecond)
+	require.NoError(t, err)
+	require.False(t, locked)
+	require.GreaterOrEqual(t, time.Since(started), 300*time.Millisecond)
+
+	//other locks aren't affected
+	other := factory.CreateLock("other_lock")
+	locked, err = other.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+	other.Unlock()
+
+	//waiting acquisition succeeds after unlocking
+	time.AfterFunc(200*time.Millisecond, first.Unlock)
+	locked, err = second.TryLock(5 * time.Second)
+	require.NoError(t, err)
+	require.True(t, locked)
+	secondToken := second.FencingToken()
+	require.Greater(t, secondToken, firstToken)
+	second.Unlock()
+
+	//fencing tokens only increase
+	locked, err = first.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+	require.Greater(t, first.FencingToken(), secondToken)
+	first.Unlock()
+}
+
+func TestLockLeaseExpiry(t *testing.T) {
+	client := runEtcd(t)
+	factory, closer := NewLockFactory(context.Background(), client, "test/", 5)
+	defer closer.Close()
+
+	first := factory.CreateLock("system_lock").(*Lock)
+	locked, err := first.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	//the lease expires e.g. when the instance has been disconnected from etcd longer than the lease TTL
+	_, err = client.Revoke(context.Background(), first.session.Lease())
+	require.NoError(t, err)
+	select {
+	case <-first.session.Done():
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "session isn't closed after the lease expiry")
+	}
+
+	second := factory.CreateLock("system_lock").(*Lock)
+	locked, err = second.TryLock(0)
+	require.NoError(t, err)
+	require.True(t, locked)
+	require.Greater(t, second.FencingToken(), first.FencingToken())
+
+	//unlocking of the expired lock doesn't release the lock of another instance
+	first.Unlock()
+	third := factory.CreateLock("system_lock")
+	locked, err = third.TryLock(0)
+	require.NoError(t, err)
+	require.False(t, locked)
+
+	second.Unlock()
+}
@@ -16,3 +16,11 @@ type Lock interface {
 	TryLock(timeout time.Duration) (bool, error)
 	Unlock()
 }
+
+//FencedLock is a lock with a fencing token. The token increases with every acquisition of the lock, so a storage
+//can reject writes of a previous lock owner whose lock has expired (e.g. after a long GC pause)
+type FencedLock interface {
+	Lock
+	//FencingToken returns the token of the current acquisition. Must be called only after successful TryLock
+	FencingToken() int64
+}
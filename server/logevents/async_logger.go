@@ -9,26 +9,29 @@ import (
 	"github.com/jitsucom/jitsu/server/safego"
 	"go.uber.org/atomic"
 	"io"
+	"sync"
 )
 
 //AsyncLogger write json logs to file system in different goroutine
 type AsyncLogger struct {
 	writer             io.WriteCloser
-	queue              queue.Queue
+	queue              queue.PollingQueue
 	showInGlobalLogger bool
 
-	closed *atomic.Bool
+	closed    *atomic.Bool
+	observers sync.WaitGroup
 }
 
 //NewAsyncLogger creates AsyncLogger and run goroutine that's read from channel and write to file
 func NewAsyncLogger(writer io.WriteCloser, showInGlobalLogger bool, poolSize int) *AsyncLogger {
 	logger := &AsyncLogger{
 		writer:             writer,
-		queue:              queue.NewInMemory(100_000),
+		queue:              queue.NewInMemory(100_000).(queue.PollingQueue),
 		showInGlobalLogger: showInGlobalLogger,
 		closed:             atomic.NewBool(false),
 	}
 
+	logger.observers.Add(poolSize)
 	for i := 0; i < poolSize; i++ {
 		safego.RunWithRestart(logger.startObserver)
 	}
@@ -36,13 +39,17 @@ func NewAsyncLogger(writer io.WriteCloser, showInGlobalLogger bool, poolSize int
 	return logger
 }
 
+//startObserver writes events from the queue until the logger is closed and the queue is drained
 func (al *AsyncLogger) startObserver() {
 	for {
-		if al.closed.Load() {
-			break
+		event, err := al.queue.Poll()
+		if err == queue.ErrQueueEmpty || err == queue.ErrQueueClosed {
+			if al.closed.Load() {
+				al.observers.Done()
+				return
+			}
+			continue
 		}
-
-		event, err := al.queue.Pop()
 		if err != nil {
 			logging.Errorf("Error reading event from queue in async logger: %v", err)
 			continue
@@ -88,9 +95,14 @@ func (al *AsyncLogger) ConsumeAny(object interface{}) {
 	}
 }
 
-//Close underlying log file writer
+//Close writes all queued events and closes underlying log file writer
 func (al *AsyncLogger) Close() (resultErr error) {
 	al.closed.Store(true)
+	al.observers.Wait()
+
+	if err := al.queue.Close(); err != nil {
+		logging.Errorf("Error closing async logger queue: %v", err)
+	}
 
 	if err := al.writer.Close(); err != nil {
 		return fmt.Errorf("Error closing writer: %v", err)
@@ -1,26 +1,34 @@
 package logfiles
 
 import (
+	"context"
+	"fmt"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/atomic"
+
 	"github.com/jitsucom/jitsu/server/appstatus"
 	"github.com/jitsucom/jitsu/server/counters"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logevents"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/parsers"
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/storages"
 	"github.com/jitsucom/jitsu/server/telemetry"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/tracing"
 )
 
 const parsingErrSrc = "parsing"
@@ -40,6 +48,11 @@ type PeriodicUploader struct {
 	statusManager      *StatusManager
 	destinationService *destinations.Service
 	tokenLastUpload    map[string]time.Time
+
+	//mutex guards closed and the running upload cycle registration
+	mutex   sync.Mutex
+	closed  chan struct{}
+	running sync.WaitGroup
 }
 
 // NewUploader returns new configured PeriodicUploader instance
@@ -59,6 +72,7 @@ func NewUploader(logEventPath, fileMask string, defaultBatchPeriodMin int, concu
 		destinationService:    destinationService,
 		concurrentUploads:     concurrentUploads,
 		tokenLastUpload:       map[string]time.Time{},
+		closed:                make(chan struct{}),
 	}, nil
 }
 
@@ -76,221 +90,353 @@ func (u *PeriodicUploader) Start() {
 				time.Sleep(2 * time.Second)
 				continue
 			}
+
+			if !u.startCycle() {
+				break
+			}
 			startTime := timestamp.Now()
 			newTokenLastUpload := sync.Map{}
 			postHandlesMap := sync.Map{} //multimap postHandleDestinationId:destinationIds
 			files, err := filepath.Glob(u.fileMask)
 			if err != nil {
+				u.running.Done()
 				logging.SystemErrorf("Error finding files by %s mask: %v", u.fileMask, err)
 				return
 			}
-			var semaphore = make(chan int, u.concurrentUploads)
-			var wg sync.WaitGroup
-			for _, filePath := range files {
-				wg.Add(1)
-				semaphore <- 1
-				go func(filePath string) {
-					defer wg.Done()
-					defer func() { <-semaphore }()
-
-					fileStartTime := timestamp.Now()
-					fileName := filepath.Base(filePath)
-
-					regexResult := DateExtractRegexp.FindStringSubmatch(fileName)
-					if len(regexResult) != 2 {
-						logging.SystemErrorf("Error processing file %s. Malformed name", filePath)
-						return
-					}
-					fileDate, err := time.Parse("2006-01-02T15-04-05", regexResult[1])
-					if err != nil {
-						logging.SystemErrorf("Error processing file %s. Cant parse file date: %s", filePath, fileDate)
-						return
-					}
+			groups := groupFilesByToken(files)
+			pending := atomic.NewInt64(int64(len(files)))
+			metrics.SetBatchUploaderQueueSize(len(files))
 
-					if timestamp.Now().Sub(fileDate) > time.Hour*24*30 {
-						logging.Infof("Skipping file %s. File is more than 30 days old: %s", filePath, fileDate)
-						return
-					}
+			processFileGroups(groups, u.concurrentUploads, u.isClosed, func(filePath string) {
+				u.processFile(filePath, startTime, &newTokenLastUpload, &postHandlesMap)
+				metrics.SetBatchUploaderQueueSize(int(pending.Dec()))
+			})
 
-					//get token from filename
-					regexResult = logging.TokenIDExtractRegexp.FindStringSubmatch(fileName)
-					if len(regexResult) != 2 {
-						logging.SystemErrorf("Error processing file %s. Malformed name", filePath)
-						return
-					}
+			u.postHandle(startTime, timestamp.Now(), &postHandlesMap)
+			logging.Infof("Processing of %d files finished in %s", len(files), time.Since(startTime))
+			newTokenLastUpload.Range(func(key, value interface{}) bool {
+				u.tokenLastUpload[key.(string)] = value.(time.Time)
+				return true
+			})
+			u.running.Done()
 
-					tokenID := regexResult[1]
-					token := appconfig.Instance.AuthorizationService.GetToken(tokenID)
-					batchPeriodMin := time.Duration(u.defaultBatchPeriodMin) * time.Minute
-					if token != nil && token.BatchPeriodMin > 0 {
-						batchPeriodMin = time.Duration(token.BatchPeriodMin) * time.Minute
-					}
-					lastUpload, ok := u.tokenLastUpload[tokenID]
-					if ok {
-						if startTime.Sub(lastUpload) < batchPeriodMin {
-							logging.Infof("Period not passed yet: %s. Started: %s Last upload was %s period: %s", filePath, startTime, lastUpload, batchPeriodMin)
-							return
-						}
-					}
-					storageProxies := u.destinationService.GetBatchStorages(tokenID)
-					if len(storageProxies) == 0 {
-						logging.Warnf("Destination storages weren't found for file [%s] and token [%s]", filePath, tokenID)
-						return
-					}
+			select {
+			case <-u.closed:
+				return
+			case <-time.After(time.Minute - time.Since(startTime)):
+			}
+		}
+	})
+}
 
-					file, err := os.Open(filePath)
-					if err != nil {
-						logging.SystemErrorf("Error opening file [%s] with events: %v", filePath, err)
-						return
-					}
-					stat, err := file.Stat()
-					if err != nil {
-						_ = file.Close()
-						logging.SystemErrorf("Error checking size of file [%s] with events: %v", filePath, err)
-						return
-					}
-					if stat.Size() == 0 {
-						_ = file.Close()
-						os.Remove(filePath)
-						return
-					}
-					newTokenLastUpload.LoadOrStore(tokenID, startTime)
-					needCopyEvent := len(storageProxies) > 1
+// startCycle registers the upload cycle. Returns false if the uploader is closed
+func (u *PeriodicUploader) startCycle() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
 
-					objects, parsingErrors, err := parsers.ParseJSONFileWithFuncFallback(file, parsers.ParseJSON)
-					_ = file.Close()
-					if err != nil {
-						logging.SystemErrorf("Error parsing JSON file [%s] with events: %v", filePath, err)
-						return
-					}
-					defer func() {
-						logging.Infof("File %s processed with %d events in %s", fileName, len(objects), time.Since(fileStartTime))
-					}()
+	if u.isClosed() {
+		return false
+	}
+
+	u.running.Add(1)
+	return true
+}
 
-					if len(parsingErrors) > 0 {
-						if len(objects) == 0 {
-							logging.SystemErrorf("JSON file [%s] contains only records with errors: [%d]. (for instance event [%s]: %vs)", filePath, len(parsingErrors), string(parsingErrors[0].Original), parsingErrors[0].Error)
-							return
-						}
+func (u *PeriodicUploader) isClosed() bool {
+	select {
+	case <-u.closed:
+		return true
+	default:
+		return false
+	}
+}
 
-						logging.Warnf("JSON file %s contains %d malformed events. They are sent to failed log", filePath, len(parsingErrors))
-					}
+// Drain stops starting uploads of new files and waits until in-flight files are stored or ctx is done.
+// Per-table statuses of partially stored files are kept, so the rest of tables are stored after restart without duplicates
+func (u *PeriodicUploader) Drain(ctx context.Context) error {
+	u.mutex.Lock()
+	if !u.isClosed() {
+		close(u.closed)
+	}
+	u.mutex.Unlock()
 
-					//flag for archiving file if all storages don't have errors while storing this file
-					archiveFile := true
-					for _, storageProxy := range storageProxies {
-						storage, ok := storageProxy.Get()
-						if !ok {
-							archiveFile = false
-							continue
-						}
-
-						alreadyUploadedTables := map[string]bool{}
-						tableStatuses := u.statusManager.GetTablesStatuses(fileName, storage.ID())
-						for tableName, status := range tableStatuses {
-							if status.Uploaded {
-								alreadyUploadedTables[tableName] = true
-							}
-						}
-
-						resultPerTable, failedEvents, skippedEvents, err := storage.Store(fileName, objects, alreadyUploadedTables, needCopyEvent)
-
-						if !skippedEvents.IsEmpty() {
-							metrics.SkipTokenEvents(tokenID, storage.Type(), storage.ID(), len(skippedEvents.Events))
-							counters.SkipPushDestinationEvents(storage.ID(), int64(len(skippedEvents.Events)))
-						}
-
-						if err != nil {
-							archiveFile = false
-							logging.Errorf("[%s] Error storing file %s in destination: %v", storage.ID(), filePath, err)
-
-							//extract src
-							eventsSrc := map[string]int{}
-							for _, obj := range objects {
-								eventsSrc[events.ExtractSrc(obj)]++
-							}
-
-							errRowsCount := len(objects)
-							metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), errRowsCount)
-							counters.ErrorPushDestinationEvents(storage.ID(), int64(errRowsCount))
-
-							telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), eventsSrc)
-
-							continue
-						}
-
-						//** Fallback **
-						//events that are failed to be parsed
-						if len(parsingErrors) > 0 {
-							var parsingFailedEvents []*events.FailedEvent
-							for _, pe := range parsingErrors {
-								parsingFailedEvents = append(parsingFailedEvents, &events.FailedEvent{
-									MalformedEvent: string(pe.Original),
-									Error:          pe.Error,
-								})
-							}
-							storage.Fallback(parsingFailedEvents...)
-							telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), map[string]int{parsingErrSrc: len(parsingErrors)})
-						}
-						//events that are failed to be processed
-						if !failedEvents.IsEmpty() {
-							storage.Fallback(failedEvents.Events...)
-							metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), len(failedEvents.Events))
-							counters.ErrorPushDestinationEvents(storage.ID(), int64(len(failedEvents.Events)))
-							telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), failedEvents.Src)
-						}
-
-						for tableName, result := range resultPerTable {
-							if result.Err != nil {
-								archiveFile = false
-								logging.Errorf("[%s] Error storing table %s from file %s: %v", storage.ID(), tableName, filePath, result.Err)
-								metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), result.RowsCount)
-								counters.ErrorPushDestinationEvents(storage.ID(), int64(result.RowsCount))
-
-								telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), result.EventsSrc)
-							} else {
-								pHandles := storageProxy.GetPostHandleDestinations()
-								if pHandles != nil && result.RowsCount > 0 {
-									for _, pHandle := range pHandles {
-										mp, _ := postHandlesMap.LoadOrStore(pHandle, &sync.Map{})
-										dests := mp.(*sync.Map)
-										//if destination is already in map, then we don't need to add it again
-										dests.LoadOrStore(storage.ID(), true)
-									}
-								}
-								metrics.SuccessTokenEvents(tokenID, storage.Type(), storage.ID(), result.RowsCount)
-								counters.SuccessPushDestinationEvents(storage.ID(), int64(result.RowsCount))
-
-								telemetry.PushedEventsPerSrc(tokenID, storage.ID(), result.EventsSrc)
-							}
-
-							u.statusManager.UpdateStatus(fileName, storage.ID(), tableName, result.Err)
-						}
-					}
+	done := make(chan struct{})
+	safego.Run(func() {
+		u.running.Wait()
+		close(done)
+	})
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("batch uploader hasn't finished in-flight files: %v", ctx.Err())
+	}
+}
+
+// processFile parses log file and stores its events into all batch storages of the file token
+// archives file if all storages have stored data without errors
+func (u *PeriodicUploader) processFile(filePath string, startTime time.Time, newTokenLastUpload, postHandlesMap *sync.Map) {
+	fileStartTime := timestamp.Now()
+	fileName := filepath.Base(filePath)
+
+	regexResult := DateExtractRegexp.FindStringSubmatch(fileName)
+	if len(regexResult) != 2 {
+		logging.SystemErrorf("Error processing file %s. Malformed name", filePath)
+		return
+	}
+	fileDate, err := time.Parse("2006-01-02T15-04-05", regexResult[1])
+	if err != nil {
+		logging.SystemErrorf("Error processing file %s. Cant parse file date: %s", filePath, fileDate)
+		return
+	}
+
+	if timestamp.Now().Sub(fileDate) > time.Hour*24*30 {
+		logging.Infof("Skipping file %s. File is more than 30 days old: %s", filePath, fileDate)
+		return
+	}
+
+	//get token from filename
+	regexResult = logging.TokenIDExtractRegexp.FindStringSubmatch(fileName)
+	if len(regexResult) != 2 {
+		logging.SystemErrorf("Error processing file %s. Malformed name", filePath)
+		return
+	}
+
+	tokenID := regexResult[1]
+	token := appconfig.Instance.AuthorizationService.GetToken(tokenID)
+	batchPeriodMin := time.Duration(u.defaultBatchPeriodMin) * time.Minute
+	if token != nil {
+		batchPeriodMin = time.Duration(token.GetBatchPeriodMin(u.defaultBatchPeriodMin)) * time.Minute
+	}
+	lastUpload, ok := u.tokenLastUpload[tokenID]
+	if ok {
+		if startTime.Sub(lastUpload) < batchPeriodMin {
+			logging.Infof("Period not passed yet: %s. Started: %s Last upload was %s period: %s", filePath, startTime, lastUpload, batchPeriodMin)
+			return
+		}
+	}
+	storageProxies := u.destinationService.GetBatchStorages(tokenID)
+	if len(storageProxies) == 0 {
+		logging.Warnf("Destination storages weren't found for file [%s] and token [%s]", filePath, tokenID)
+		return
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		logging.SystemErrorf("Error opening file [%s] with events: %v", filePath, err)
+		return
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		logging.SystemErrorf("Error checking size of file [%s] with events: %v", filePath, err)
+		return
+	}
+	if stat.Size() == 0 {
+		_ = file.Close()
+		os.Remove(filePath)
+		return
+	}
+	newTokenLastUpload.LoadOrStore(tokenID, startTime)
+	needCopyEvent := len(storageProxies) > 1
+
+	objects, parsingErrors, err := parsers.ParseJSONFileWithFuncFallback(file, parsers.ParseJSON)
+	_ = file.Close()
+	if err != nil {
+		logging.SystemErrorf("Error parsing JSON file [%s] with events: %v", filePath, err)
+		return
+	}
+	defer func() {
+		logging.Infof("File %s processed with %d events in %s", fileName, len(objects), time.Since(fileStartTime))
+	}()
+
+	if len(parsingErrors) > 0 {
+		if len(objects) == 0 {
+			logging.SystemErrorf("JSON file [%s] contains only records with errors: [%d]. (for instance event [%s]: %vs)", filePath, len(parsingErrors), string(parsingErrors[0].Original), parsingErrors[0].Error)
+			return
+		}
+
+		logging.Warnf("JSON file %s contains %d malformed events. They are sent to failed log", filePath, len(parsingErrors))
+	}
+
+	//flag for archiving file if all storages don't have errors while storing this file
+	archiveFile := true
+	for _, storageProxy := range storageProxies {
+		//the storage isn't closed by configuration reload until the file is stored
+		storage, release, ok := storageProxy.Acquire()
+		if !ok {
+			archiveFile = false
+			continue
+		}
+
+		alreadyUploadedTables := map[string]bool{}
+		tableStatuses := u.statusManager.GetTablesStatuses(fileName, storage.ID())
+		for tableName, status := range tableStatuses {
+			if status.Uploaded {
+				alreadyUploadedTables[tableName] = true
+			}
+		}
+
+		loadStartTime := timestamp.Now()
+		_, storeSpan := tracing.Start(context.Background(), "jitsu.destination.store",
+			attribute.String("jitsu.destination_id", storage.ID()),
+			attribute.String("jitsu.destination_type", storage.Type()),
+			attribute.String("jitsu.file", fileName),
+			attribute.Int("jitsu.rows", len(objects)))
+		resultPerTable, failedEvents, skippedEvents, err := storage.Store(fileName, objects, alreadyUploadedTables, needCopyEvent)
+		tracing.End(storeSpan, err)
+		metrics.BatchLoadDuration(storage.Type(), storage.ID(), time.Since(loadStartTime).Seconds())
+
+		if !skippedEvents.IsEmpty() {
+			metrics.SkipTokenEvents(tokenID, storage.Type(), storage.ID(), len(skippedEvents.Events))
+			counters.SkipPushDestinationEvents(storage.ID(), int64(len(skippedEvents.Events)))
+			counters.PushRollup(storage.ID(), tokenID, "", meta.SkipStatus, int64(len(skippedEvents.Events)))
+		}
+
+		if err != nil {
+			archiveFile = false
+			logging.Errorf("[%s] Error storing file %s in destination: %v", storage.ID(), filePath, err)
+
+			//extract src
+			eventsSrc := map[string]int{}
+			for _, obj := range objects {
+				eventsSrc[events.ExtractSrc(obj)]++
+			}
+
+			errRowsCount := len(objects)
+			metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), errRowsCount)
+			metrics.DestinationErrors(storage.Type(), storage.ID(), storages.ErrorClass(err), errRowsCount)
+			counters.ErrorPushDestinationEvents(storage.ID(), int64(errRowsCount))
+			counters.PushRollup(storage.ID(), tokenID, "", meta.ErrorStatus, int64(errRowsCount))
+
+			telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), eventsSrc)
+
+			release()
+			continue
+		}
+
+		//** Fallback **
+		//events that are failed to be parsed
+		if len(parsingErrors) > 0 {
+			var parsingFailedEvents []*events.FailedEvent
+			for _, pe := range parsingErrors {
+				parsingFailedEvents = append(parsingFailedEvents, &events.FailedEvent{
+					MalformedEvent: string(pe.Original),
+					Error:          pe.Error,
+				})
+			}
+			storage.Fallback(parsingFailedEvents...)
+			telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), map[string]int{parsingErrSrc: len(parsingErrors)})
+		}
+		//events that are failed to be processed
+		if !failedEvents.IsEmpty() {
+			storage.Fallback(failedEvents.Events...)
+			metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), len(failedEvents.Events))
+			metrics.DestinationErrors(storage.Type(), storage.ID(), metrics.ErrorClassProcessing, len(failedEvents.Events))
+			counters.ErrorPushDestinationEvents(storage.ID(), int64(len(failedEvents.Events)))
+			counters.PushRollup(storage.ID(), tokenID, "", meta.ErrorStatus, int64(len(failedEvents.Events)))
+			telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), failedEvents.Src)
+		}
 
-					if archiveFile {
-						err := u.archiver.Archive(fileName)
-						if err != nil {
-							logging.SystemErrorf("Error archiving [%s] file: %v", filePath, err)
-						} else {
-							u.statusManager.CleanUp(fileName)
-						}
+		for tableName, result := range resultPerTable {
+			if result.Err != nil {
+				archiveFile = false
+				logging.Errorf("[%s] Error storing table %s from file %s: %v", storage.ID(), tableName, filePath, result.Err)
+				metrics.ErrorTokenEvents(tokenID, storage.Type(), storage.ID(), result.RowsCount)
+				metrics.DestinationErrors(storage.Type(), storage.ID(), storages.ErrorClass(result.Err), result.RowsCount)
+				counters.ErrorPushDestinationEvents(storage.ID(), int64(result.RowsCount))
+				counters.PushRollup(storage.ID(), tokenID, tableName, meta.ErrorStatus, int64(result.RowsCount))
+
+				telemetry.PushedErrorsPerSrc(tokenID, storage.ID(), result.EventsSrc)
+			} else {
+				pHandles := storageProxy.GetPostHandleDestinations()
+				if pHandles != nil && result.RowsCount > 0 {
+					for _, pHandle := range pHandles {
+						mp, _ := postHandlesMap.LoadOrStore(pHandle, &sync.Map{})
+						dests := mp.(*sync.Map)
+						//if destination is already in map, then we don't need to add it again
+						dests.LoadOrStore(storage.ID(), true)
 					}
-				}(filePath)
+				}
+				metrics.SuccessTokenEvents(tokenID, storage.Type(), storage.ID(), result.RowsCount)
+				counters.SuccessPushDestinationEvents(storage.ID(), int64(result.RowsCount))
+				counters.PushRollup(storage.ID(), tokenID, tableName, meta.SuccessStatus, int64(result.RowsCount))
+
+				telemetry.PushedEventsPerSrc(tokenID, storage.ID(), result.EventsSrc)
 			}
-			wg.Wait()
-			close(semaphore)
 
-			u.postHandle(startTime, timestamp.Now(), &postHandlesMap)
-			logging.Infof("Processing of %d files finished in %s", len(files), time.Since(startTime))
-			newTokenLastUpload.Range(func(key, value interface{}) bool {
-				u.tokenLastUpload[key.(string)] = value.(time.Time)
-				return true
-			})
-			time.Sleep(time.Minute - time.Since(startTime))
+			u.statusManager.UpdateStatus(fileName, storage.ID(), tableName, result.Err)
+		}
+		release()
+	}
 
+	if archiveFile {
+		err := u.archiver.Archive(fileName)
+		if err != nil {
+			logging.SystemErrorf("Error archiving [%s] file: %v", filePath, err)
+		} else {
+			u.statusManager.CleanUp(fileName)
 		}
-	})
+	}
+}
+
+// groupFilesByToken returns log files grouped by token ID
+// files in every group are sorted by name (rotation timestamp is a part of the name)
+func groupFilesByToken(files []string) [][]string {
+	var groups [][]string
+	tokenGroup := map[string]int{}
+	sort.Strings(files)
+	for _, filePath := range files {
+		tokenID := filePath
+		if regexResult := logging.TokenIDExtractRegexp.FindStringSubmatch(filepath.Base(filePath)); len(regexResult) == 2 {
+			tokenID = regexResult[1]
+		}
+
+		idx, ok := tokenGroup[tokenID]
+		if !ok {
+			idx = len(groups)
+			tokenGroup[tokenID] = idx
+			groups = append(groups, nil)
+		}
+		groups[idx] = append(groups[idx], filePath)
+	}
+
+	return groups
+}
+
+// processFileGroups processes groups of files with a bounded pool of workers: files of a group are processed one by one in order
+// while different groups are processed in parallel. Files which haven't been started are skipped once isClosed returns true
+func processFileGroups(groups [][]string, workers int, isClosed func() bool, process func(filePath string)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+	queue := make(chan []string, len(groups))
+	for _, group := range groups {
+		queue <- group
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		safego.Run(func() {
+			defer wg.Done()
+			for group := range queue {
+				for _, filePath := range group {
+					//checkpoint: files which haven't been started are uploaded after restart
+					if isClosed() {
+						return
+					}
+					process(filePath)
+				}
+			}
+		})
+	}
+	wg.Wait()
 }
 
 func (u *PeriodicUploader) postHandle(start, end time.Time, postHandlesMap *sync.Map) {
@@ -0,0 +1,78 @@
+package logfiles
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestGroupFilesByToken(t *testing.T) {
+	groups := groupFilesByToken([]string{
+		"/incoming/incoming.tok=token2-2022-03-01T10-05-00.log",
+		"/incoming/incoming.tok=token1-2022-03-01T10-10-00.log",
+		"/incoming/incoming.tok=token1-2022-03-01T10-00-00.log",
+		"/incoming/incoming.tok=token2-2022-03-01T10-00-00.log",
+		"/incoming/malformed.log",
+	})
+
+	require.Equal(t, [][]string{
+		{"/incoming/incoming.tok=token1-2022-03-01T10-00-00.log", "/incoming/incoming.tok=token1-2022-03-01T10-10-00.log"},
+		{"/incoming/incoming.tok=token2-2022-03-01T10-00-00.log", "/incoming/incoming.tok=token2-2022-03-01T10-05-00.log"},
+		{"/incoming/malformed.log"},
+	}, groups)
+}
+
+func TestProcessFileGroups(t *testing.T) {
+	groups := [][]string{
+		{"token1-1", "token1-2", "token1-3"},
+		{"token2-1", "token2-2", "token2-3"},
+		{"token3-1", "token3-2", "token3-3"},
+		{"token4-1", "token4-2", "token4-3"},
+	}
+
+	var mutex sync.Mutex
+	processed := map[string][]string{}
+	runningByToken := map[string]int{}
+	var running, maxRunning, maxRunningByToken int
+	processFileGroups(groups, 2, func() bool { return false }, func(filePath string) {
+		token := filePath[:6]
+		mutex.Lock()
+		runningByToken[token]++
+		if runningByToken[token] > maxRunningByToken {
+			maxRunningByToken = runningByToken[token]
+		}
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mutex.Lock()
+		runningByToken[token]--
+		running--
+		processed[token] = append(processed[token], filePath)
+		mutex.Unlock()
+	})
+
+	require.Equal(t, 1, maxRunningByToken, "files of the same token mustn't be processed in parallel")
+	require.Equal(t, 2, maxRunning, "files of different tokens must be processed in parallel by all workers")
+	for _, group := range groups {
+		require.Equal(t, group, processed[group[0][:6]], "files of the same token must be processed in order")
+	}
+}
+
+func TestProcessFileGroupsStopsOnClose(t *testing.T) {
+	closed := atomic.NewBool(false)
+	var processed []string
+	processFileGroups([][]string{{"token1-1", "token1-2", "token1-3"}}, 4, closed.Load, func(filePath string) {
+		processed = append(processed, filePath)
+		closed.Store(true)
+	})
+
+	require.Equal(t, []string{"token1-1"}, processed)
+}
@@ -17,6 +17,8 @@ const (
 	infoPrefix  = "[INFO]:"
 	debugPrefix = "[DEBUG]:"
 
+	systemErrorPrefix = "System error:"
+
 	GlobalType = "global"
 )
 
@@ -48,12 +50,13 @@ func (c Config) Validate() error {
 	return nil
 }
 
-// InitGlobalLogger initializes main logger
+// InitGlobalLogger initializes main logger. Output format is set with SetFormat
 func InitGlobalLogger(writer io.Writer, levelStr string) error {
-	dateTimeWriter := DateTimeWriterProxy{
-		writer: writer,
+	if jsonFormat {
+		log.SetOutput(JSONWriterProxy{writer: writer})
+	} else {
+		log.SetOutput(DateTimeWriterProxy{writer: writer})
 	}
-	log.SetOutput(dateTimeWriter)
 	log.SetFlags(0)
 
 	LogLevel = ToLevel(levelStr)
@@ -73,10 +76,10 @@ func SystemErrorf(format string, v ...interface{}) {
 }
 
 func SystemError(v ...interface{}) {
-	msg := []interface{}{"System error:"}
+	msg := []interface{}{systemErrorPrefix}
 	msg = append(msg, v...)
 	Error(msg...)
-	notifications.SystemError(msg...)
+	notifySystemError(msg...)
 }
 
 func Errorf(format string, v ...interface{}) {
@@ -84,9 +87,7 @@ func Errorf(format string, v ...interface{}) {
 }
 
 func Error(v ...interface{}) {
-	if LogLevel <= ERROR {
-		log.Println(errMsg(v...))
-	}
+	output(ERROR, "", nil, sprint(v...))
 }
 
 func Infof(format string, v ...interface{}) {
@@ -94,9 +95,7 @@ func Infof(format string, v ...interface{}) {
 }
 
 func Info(v ...interface{}) {
-	if LogLevel <= INFO {
-		log.Println(append([]interface{}{infoPrefix}, v...)...)
-	}
+	output(INFO, "", nil, sprint(v...))
 }
 
 func Debugf(format string, v ...interface{}) {
@@ -104,9 +103,7 @@ func Debugf(format string, v ...interface{}) {
 }
 
 func Debug(v ...interface{}) {
-	if LogLevel <= DEBUG {
-		log.Println(append([]interface{}{debugPrefix}, v...)...)
-	}
+	output(DEBUG, "", nil, sprint(v...))
 }
 
 func Warnf(format string, v ...interface{}) {
@@ -114,27 +111,59 @@ func Warnf(format string, v ...interface{}) {
 }
 
 func Warn(v ...interface{}) {
-	if LogLevel <= WARN {
-		log.Println(append([]interface{}{warnPrefix}, v...)...)
-	}
+	output(WARN, "", nil, sprint(v...))
 }
 
 func Fatal(v ...interface{}) {
-	if LogLevel <= FATAL {
-		log.Fatal(errMsg(v...))
-	}
+	output(FATAL, "", nil, sprint(v...))
 }
 
 func Fatalf(format string, v ...interface{}) {
-	if LogLevel <= FATAL {
-		log.Fatalf(errMsg(fmt.Sprintf(format, v...)))
+	output(FATAL, "", nil, fmt.Sprintf(format, v...))
+}
+
+//output writes the record into the global logger if the level is enabled for the component.
+//Fatal records stop the application
+func output(level Level, component string, fields Fields, msg string) {
+	if !enabled(component, level) {
+		return
 	}
+
+	var record string
+	if jsonFormat {
+		record = jsonRecord(level, component, fields, msg)
+	} else {
+		record = textRecord(level, msg)
+	}
+
+	if level == FATAL {
+		log.Fatal(record)
+	}
+	log.Println(record)
+}
+
+func textRecord(level Level, msg string) string {
+	switch level {
+	case DEBUG:
+		return debugPrefix + " " + msg
+	case INFO:
+		return infoPrefix + " " + msg
+	case WARN:
+		return warnPrefix + " " + msg
+	default:
+		return color.Red.Sprint(errPrefix + " " + msg)
+	}
+}
+
+func notifySystemError(v ...interface{}) {
+	notifications.SystemError(v...)
 }
 
-func errMsg(values ...interface{}) string {
-	valuesStr := []string{errPrefix}
+//sprint joins values with spaces like log.Println does
+func sprint(values ...interface{}) string {
+	valuesStr := make([]string, 0, len(values))
 	for _, v := range values {
 		valuesStr = append(valuesStr, fmt.Sprint(v))
 	}
-	return color.Red.Sprint(strings.Join(valuesStr, " "))
+	return strings.Join(valuesStr, " ")
 }
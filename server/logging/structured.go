@@ -0,0 +1,235 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	//TextFormat is a default plain-text logs format: 2006-01-02 15:04:05 [INFO]: message
+	TextFormat = "text"
+	//JSONFormat is a structured logs format: one JSON object per line
+	JSONFormat = "json"
+
+	ComponentField   = "component"
+	DestinationField = "destination"
+	APIKeyField      = "api_key"
+	EventIDField     = "event_id"
+
+	jsonTimeLayout = "2006-01-02T15:04:05.000Z"
+)
+
+var (
+	jsonFormat bool
+
+	//componentLevels is a copy-on-write map[string]Level with per-component levels. Written under componentLevelsMutex
+	componentLevels      atomic.Value
+	componentLevelsMutex sync.Mutex
+)
+
+func init() {
+	componentLevels.Store(map[string]Level{})
+}
+
+//Fields are structured log record fields
+type Fields map[string]interface{}
+
+//SetFormat sets global logger output format (text or json). Must be called before InitGlobalLogger
+func SetFormat(format string) error {
+	switch strings.TrimSpace(strings.ToLower(format)) {
+	case "", TextFormat:
+		jsonFormat = false
+	case JSONFormat:
+		jsonFormat = true
+	default:
+		return fmt.Errorf("Unknown log format [%s]. Supported: [%s, %s]", format, TextFormat, JSONFormat)
+	}
+
+	return nil
+}
+
+//IsJSONFormat returns true if global logger writes JSON records
+func IsJSONFormat() bool {
+	return jsonFormat
+}
+
+//SetComponentLevel overrides global log level for the component
+func SetComponentLevel(component, levelStr string) error {
+	level := ToLevel(levelStr)
+	if level == UNKNOWN {
+		return fmt.Errorf("Unknown log level [%s]. Supported: [debug, info, warn, error, fatal]", levelStr)
+	}
+
+	updateComponentLevels(func(levels map[string]Level) {
+		levels[component] = level
+	})
+	return nil
+}
+
+//ResetComponentLevel removes the component level override. Global log level will be used
+func ResetComponentLevel(component string) {
+	updateComponentLevels(func(levels map[string]Level) {
+		delete(levels, component)
+	})
+}
+
+//ComponentLevels returns per-component log levels overrides
+func ComponentLevels() map[string]string {
+	levels := componentLevels.Load().(map[string]Level)
+	result := make(map[string]string, len(levels))
+	for component, level := range levels {
+		result[component] = level.String()
+	}
+	return result
+}
+
+func updateComponentLevels(update func(levels map[string]Level)) {
+	componentLevelsMutex.Lock()
+	defer componentLevelsMutex.Unlock()
+
+	current := componentLevels.Load().(map[string]Level)
+	levels := make(map[string]Level, len(current)+1)
+	for component, level := range current {
+		levels[component] = level
+	}
+	update(levels)
+	componentLevels.Store(levels)
+}
+
+//enabled returns true if the record with the level should be written for the component
+func enabled(component string, level Level) bool {
+	if component != "" {
+		if componentLevel, ok := componentLevels.Load().(map[string]Level)[component]; ok {
+			return componentLevel <= level
+		}
+	}
+
+	return LogLevel <= level
+}
+
+//Logger writes records with the component name and structured fields into the global logger.
+//Fields are written only in JSON format, plain-text records are the same as global logger ones
+type Logger struct {
+	component string
+	fields    Fields
+}
+
+//Component returns Logger with the component name. Component log level can be adjusted with SetComponentLevel
+func Component(name string) *Logger {
+	return &Logger{component: name}
+}
+
+//With returns a copy of the Logger with the additional field
+func (l *Logger) With(key string, value interface{}) *Logger {
+	fields := make(Fields, len(l.fields)+1)
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+	return &Logger{component: l.component, fields: fields}
+}
+
+//WithDestination returns a copy of the Logger with destination field
+func (l *Logger) WithDestination(destinationID string) *Logger {
+	return l.With(DestinationField, destinationID)
+}
+
+//WithAPIKey returns a copy of the Logger with api_key field
+func (l *Logger) WithAPIKey(apiKey string) *Logger {
+	return l.With(APIKeyField, apiKey)
+}
+
+//WithEventID returns a copy of the Logger with event_id field
+func (l *Logger) WithEventID(eventID string) *Logger {
+	return l.With(EventIDField, eventID)
+}
+
+//IsDebugEnabled returns true if debug records of the component are written
+func (l *Logger) IsDebugEnabled() bool {
+	return enabled(l.component, DEBUG)
+}
+
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	output(DEBUG, l.component, l.fields, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Infof(format string, v ...interface{}) {
+	output(INFO, l.component, l.fields, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	output(WARN, l.component, l.fields, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	output(ERROR, l.component, l.fields, fmt.Sprintf(format, v...))
+}
+
+//SystemErrorf writes the error record and sends system error notification
+func (l *Logger) SystemErrorf(format string, v ...interface{}) {
+	msg := systemErrorPrefix + " " + fmt.Sprintf(format, v...)
+	output(ERROR, l.component, l.fields, msg)
+	notifySystemError(msg)
+}
+
+//jsonRecord returns a JSON log record: time, level, component, msg and sorted fields
+func jsonRecord(level Level, component string, fields Fields, msg string) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`{"time":`)
+	writeJSONValue(buf, timestamp.Now().UTC().Format(jsonTimeLayout))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, level.String())
+	if component != "" {
+		buf.WriteString(`,"component":`)
+		writeJSONValue(buf, component)
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, strings.TrimRight(msg, "\n"))
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		buf.WriteByte(',')
+		writeJSONValue(buf, key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, fields[key])
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(b)
+}
+
+//JSONWriterProxy is used as the global logger output in JSON format. It wraps plain-text records written with
+//the standard log package (e.g. by third-party libraries) into JSON records
+type JSONWriterProxy struct {
+	writer io.Writer
+}
+
+func (jwp JSONWriterProxy) Write(p []byte) (int, error) {
+	if bytes.HasPrefix(p, []byte("{")) {
+		return jwp.writer.Write(p)
+	}
+
+	if _, err := jwp.writer.Write([]byte(jsonRecord(INFO, "", nil, string(p)) + "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func initTestLogger(t *testing.T, format, level string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	require.NoError(t, SetFormat(format))
+	require.NoError(t, InitGlobalLogger(buf, level))
+	t.Cleanup(func() {
+		_ = SetFormat(TextFormat)
+		_ = InitGlobalLogger(os.Stdout, "")
+		for component := range ComponentLevels() {
+			ResetComponentLevel(component)
+		}
+	})
+	return buf
+}
+
+func TestJSONFormat(t *testing.T) {
+	buf := initTestLogger(t, JSONFormat, "info")
+
+	Component("streaming").WithDestination("dest1").WithAPIKey("key1").WithEventID("event1").Warnf("failed: %s", "timeout")
+	Debug("skipped record")
+	Info("global", "record")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	record := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, "warn", record["level"])
+	require.Equal(t, "streaming", record[ComponentField])
+	require.Equal(t, "failed: timeout", record["msg"])
+	require.Equal(t, "dest1", record[DestinationField])
+	require.Equal(t, "key1", record[APIKeyField])
+	require.Equal(t, "event1", record[EventIDField])
+	require.NotEmpty(t, record["time"])
+	require.True(t, strings.HasPrefix(lines[0], `{"time":`), "time must be the first key")
+
+	record = map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	require.Equal(t, "info", record["level"])
+	require.Equal(t, "global record", record["msg"])
+	require.NotContains(t, record, ComponentField)
+}
+
+func TestJSONWriterProxy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	proxy := JSONWriterProxy{writer: buf}
+
+	_, err := proxy.Write([]byte("plain \"text\" from library\n"))
+	require.NoError(t, err)
+
+	record := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, `plain "text" from library`, record["msg"])
+}
+
+func TestComponentLevels(t *testing.T) {
+	buf := initTestLogger(t, TextFormat, "warn")
+
+	logger := Component("storages")
+	logger.Infof("skipped")
+	require.False(t, logger.IsDebugEnabled())
+	require.Empty(t, buf.String())
+
+	require.NoError(t, SetComponentLevel("storages", "debug"))
+	require.Error(t, SetComponentLevel("storages", "verbose"))
+	require.Equal(t, map[string]string{"storages": "debug"}, ComponentLevels())
+	require.True(t, logger.IsDebugEnabled())
+	logger.With(DestinationField, "dest1").Debugf("written")
+	//other components and global records use the global level
+	Component("other").Infof("skipped")
+	Info("skipped")
+	require.Contains(t, buf.String(), "[DEBUG]: written\n")
+	require.NotContains(t, buf.String(), "dest1", "fields are written only in JSON format")
+	require.NotContains(t, buf.String(), "skipped")
+
+	ResetComponentLevel("storages")
+	require.Empty(t, ComponentLevels())
+	require.False(t, logger.IsDebugEnabled())
+}
+
+func TestSetFormat(t *testing.T) {
+	require.NoError(t, SetFormat("JSON"))
+	require.True(t, IsJSONFormat())
+	require.NoError(t, SetFormat(""))
+	require.False(t, IsJSONFormat())
+	require.Error(t, SetFormat("xml"))
+}
@@ -20,33 +20,52 @@ import (
 	"time"
 
 	"github.com/jitsucom/jitsu/server/script/node"
+	"github.com/jitsucom/jitsu/server/script/python"
+	"github.com/jitsucom/jitsu/server/script/wasm"
 	"github.com/jitsucom/jitsu/server/templates"
 
 	"github.com/gin-gonic/gin/binding"
+	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/airbyte"
+	"github.com/jitsucom/jitsu/server/alerting"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/appstatus"
+	"github.com/jitsucom/jitsu/server/botfilter"
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/cmd"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/configvalidator"
+	"github.com/jitsucom/jitsu/server/consent"
 	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/dataprotection"
+	"github.com/jitsucom/jitsu/server/delivery"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/erasure"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/fallback"
 	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/grpcapi"
+	"github.com/jitsucom/jitsu/server/health"
 	"github.com/jitsucom/jitsu/server/logevents"
 	"github.com/jitsucom/jitsu/server/logfiles"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/mqtt"
 	"github.com/jitsucom/jitsu/server/multiplexing"
 	"github.com/jitsucom/jitsu/server/notifications"
+	"github.com/jitsucom/jitsu/server/protocols"
+	"github.com/jitsucom/jitsu/server/queue"
+	"github.com/jitsucom/jitsu/server/quota"
+	"github.com/jitsucom/jitsu/server/retention"
 	"github.com/jitsucom/jitsu/server/routers"
 	"github.com/jitsucom/jitsu/server/runtime"
 	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/schemaregistry"
+	"github.com/jitsucom/jitsu/server/sampling"
 	"github.com/jitsucom/jitsu/server/scheduling"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/singer"
@@ -56,9 +75,12 @@ import (
 	"github.com/jitsucom/jitsu/server/system"
 	"github.com/jitsucom/jitsu/server/telemetry"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/tracing"
 	"github.com/jitsucom/jitsu/server/users"
 	"github.com/jitsucom/jitsu/server/wal"
 	"github.com/spf13/viper"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // some inner parameters
@@ -108,7 +130,7 @@ func setAppWorkDir() {
 }
 
 func main() {
-	if len(os.Args) >= 2 && os.Args[1] == "replay" {
+	if len(os.Args) >= 2 && cmd.IsCommand(os.Args[1]) {
 		cmd.Execute(tag)
 		return
 	}
@@ -134,6 +156,15 @@ func main() {
 		logging.Fatal("Error while reading application config:", err)
 	}
 
+	//strict config validation: the server doesn't start with invalid configuration unless server.strict_config is false
+	configProblems := configvalidator.Validate(viper.AllSettings())
+	for _, problem := range configProblems {
+		logging.Warnf("Configuration %s", problem)
+	}
+	if errs := configProblems.Errors(); len(errs) > 0 && (!viper.IsSet("server.strict_config") || viper.GetBool("server.strict_config")) {
+		logging.Fatalf("Invalid configuration. Fix the errors or set server.strict_config: false for starting anyway:\n%s", errs)
+	}
+
 	//parse EN version
 	appconfig.RawVersion = tag
 	appconfig.BuiltAt = builtAt
@@ -196,7 +227,7 @@ func main() {
 		logging.Fatal(err)
 	}
 
-	if err := airbyte.Init(ctx, *containerizedRun, viper.GetString("airbyte-bridge.config_dir"), viper.GetString("server.volumes.workspace"), viper.GetInt("airbyte-bridge.batch_size"), appconfig.Instance.AirbyteLogsWriter); err != nil {
+	if err := airbyte.Init(ctx, *containerizedRun, viper.GetString("airbyte-bridge.config_dir"), viper.GetString("server.volumes.workspace"), viper.GetInt("airbyte-bridge.batch_size"), metaStorage, appconfig.Instance.AirbyteLogsWriter); err != nil {
 		logging.Errorf("❌ Airbyte integration is disabled: %v", err)
 	}
 
@@ -206,15 +237,48 @@ func main() {
 		geoResolversURL = fmt.Sprintf("%s/api/v1/geo_data_resolvers?token=%s", appconfig.Instance.ConfiguratorURL, appconfig.Instance.ConfiguratorToken)
 	}
 
-	geoService := geo.NewService(ctx, geoResolversURL, viper.GetString("geo.maxmind_path"), viper.GetString("maxmind.official_url"))
+	globalGeoPath := viper.GetString("geo.path")
+	if globalGeoPath == "" {
+		globalGeoPath = viper.GetString("geo.maxmind_path")
+	}
+
+	geoService := geo.NewService(ctx, geoResolversURL, globalGeoPath, viper.GetString("geo.provider"),
+		time.Duration(viper.GetInt("geo.refresh_interval_hours"))*time.Hour,
+		geo.NewMaxmindFactory(viper.GetString("maxmind.official_url")),
+		geo.NewIP2LocationFactory(viper.GetString("ip2location.official_url")),
+		geo.NewIPInfoFactory(viper.GetString("ipinfo.url"), viper.GetInt("geo.ipinfo.cache_size"),
+			time.Duration(viper.GetInt("geo.ipinfo.cache_ttl_min"))*time.Minute, viper.GetInt("geo.ipinfo.rate_limit_per_sec")))
 
 	enrichment.InitDefault(
 		viper.GetString("server.fields_configuration.src_source_ip"),
 		viper.GetString("server.fields_configuration.dst_source_ip"),
 		viper.GetString("server.fields_configuration.src_ua"),
 		viper.GetString("server.fields_configuration.dst_ua"),
+		viper.GetString("server.fields_configuration.client_hints_path"),
 	)
 
+	//global data protection (PII hashing/encryption) for all destinations
+	if viper.IsSet("data_protection") {
+		dataProtectionConfig := &dataprotection.Config{}
+		if err := viper.UnmarshalKey("data_protection", dataProtectionConfig); err != nil {
+			logging.Fatalf("Error parsing 'data_protection' config: %v", err)
+		}
+		if err := dataprotection.SetDefault(dataProtectionConfig); err != nil {
+			logging.Fatalf("Error configuring data protection: %v", err)
+		}
+	}
+
+	//global consent settings: consent field in events and consent categories to TCF purposes mapping
+	if viper.IsSet("consent") {
+		consentSettings := &consent.Settings{}
+		if err := viper.UnmarshalKey("consent", consentSettings); err != nil {
+			logging.Fatalf("Error parsing 'consent' config: %v", err)
+		}
+		if err := consent.SetSettings(consentSettings); err != nil {
+			logging.Fatalf("Error configuring consent: %v", err)
+		}
+	}
+
 	safego.GlobalRecoverHandler = func(value interface{}) {
 		logging.Error("panic")
 		logging.Error(value)
@@ -229,7 +293,7 @@ func main() {
 	metricsExported := viper.GetBool("server.metrics.prometheus.enabled")
 	metricsRelay := metrics.InitRelay(clusterID, viper.Sub("server.metrics.relay"))
 	if metricsExported || metricsRelay != nil {
-		metrics.Init(metricsExported)
+		metrics.Init(metricsExported, viper.GetInt("server.metrics.prometheus.max_label_values"))
 		if metricsRelay != nil {
 			interval := 5 * time.Minute
 			if viper.IsSet("server.metrics.relay.interval") {
@@ -241,6 +305,10 @@ func main() {
 		}
 	}
 
+	if viper.GetBool("tracing.enabled") {
+		initTracing()
+	}
+
 	slackNotificationsWebHook := viper.GetString("notifications.slack.url")
 	if slackNotificationsWebHook != "" {
 		notifications.Init(notifications.ServiceName, tag, slackNotificationsWebHook, appconfig.Instance.ServerName, logging.Errorf)
@@ -253,6 +321,17 @@ func main() {
 		<-c
 		logging.Info("🤖 * Server is shutting down.. *")
 
+		//exit forcibly if resources haven't been freed up within the deadline
+		time.AfterFunc(appconfig.Instance.ShutdownTimeout, func() {
+			logging.Errorf("🤖 * Server hasn't been shut down within %s deadline. Exiting forcibly *", appconfig.Instance.ShutdownTimeout)
+			os.Exit(1)
+		})
+
+		//stop accepting events and wait for in-flight requests and batch loads. The rest of the deadline is for flushing and closing
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), appconfig.Instance.ShutdownTimeout/2)
+		appconfig.Instance.Drain(drainCtx)
+		drainCancel()
+
 		if metricsRelay != nil {
 			metricsRelay.Stop()
 		}
@@ -263,6 +342,9 @@ func main() {
 		appconfig.Instance.Close()
 		telemetry.Flush()
 		notifications.Flush()
+		if err := tracing.Close(); err != nil {
+			logging.Errorf("Error flushing traces: %v", err)
+		}
 		time.Sleep(4 * time.Second)
 		telemetry.Close()
 		//we should close it in the end
@@ -299,9 +381,9 @@ func main() {
 	//to force inmemory set events.queue.inmemory: true
 	var eventsQueueFactory *events.QueueFactory
 	if viper.GetBool("events.queue.inmemory") {
-		eventsQueueFactory, err = initializeEventsQueueFactory(nil)
+		eventsQueueFactory, err = initializeEventsQueueFactory(nil, coordinationService)
 	} else {
-		eventsQueueFactory, err = initializeEventsQueueFactory(metaStorageConfiguration)
+		eventsQueueFactory, err = initializeEventsQueueFactory(metaStorageConfiguration, coordinationService)
 	}
 	if err != nil {
 		logging.Fatal(err)
@@ -334,6 +416,44 @@ func main() {
 	eventsCache := caching.NewEventsCache(eventsCacheEnabled, metaStorage, eventsCacheSize, eventsCachePoolSize, eventsCacheTrimIntervalMs, timeWindowSeconds)
 	appconfig.Instance.ScheduleClosing(eventsCache)
 
+	// ** Events delivery tracking
+	if viper.GetBool("delivery_tracking.enabled") {
+		deliveryStorage, err := delivery.InitializeStorage(metaStorageConfiguration, viper.GetInt("delivery_tracking.capacity"),
+			time.Duration(viper.GetInt("delivery_tracking.ttl_hours"))*time.Hour)
+		if err != nil {
+			logging.Fatalf("Error initializing events delivery tracking storage: %v", err)
+		}
+		deliveryService := delivery.Init(deliveryStorage, viper.GetInt("delivery_tracking.pool_size"),
+			time.Duration(viper.GetInt("delivery_tracking.trim_interval_sec"))*time.Second)
+		appconfig.Instance.ScheduleClosing(deliveryService)
+	}
+
+	// ** Schema registry
+	if viper.GetBool("schema_registry.enabled") {
+		schemaRegistryStorage, err := schemaregistry.InitializeStorage(metaStorageConfiguration, viper.GetInt("schema_registry.history_capacity"))
+		if err != nil {
+			logging.Fatalf("Error initializing schema registry storage: %v", err)
+		}
+		appconfig.Instance.ScheduleClosing(schemaregistry.Init(schemaRegistryStorage))
+	}
+
+	// ** Events quotas
+	if viper.GetBool("quotas.enabled") {
+		quotaConfig := &quota.Config{}
+		if err := viper.UnmarshalKey("quotas", quotaConfig); err != nil {
+			logging.Fatalf("Error parsing 'quotas' config: %v", err)
+		}
+		if metaStorage.Type() == meta.DummyType {
+			logging.Warnf("Events quotas usage is counted only by the current server since meta storage isn't configured")
+		}
+		quotaService, err := quota.Init(quotaConfig, metaStorage)
+		if err != nil {
+			logging.Fatalf("Error initializing events quotas: %v", err)
+		}
+		appconfig.Instance.ScheduleClosing(quotaService)
+		logging.Infof("Events quotas: %d projects, %d API keys", len(quotaConfig.Projects), len(quotaConfig.APIKeys))
+	}
+
 	// ** Retroactive users recognition
 	globalRecognitionConfiguration := &config.UsersRecognition{
 		Enabled:             viper.GetBool("users_recognition.enabled"),
@@ -353,19 +473,54 @@ func main() {
 		globalRecognitionConfiguration.PoolSize = 1
 		logging.Infof("users_recognition.pool.size can't be 0. Using default value=1 instead")
 	}
+
+	if appconfig.Instance.Cookieless && !globalRecognitionConfiguration.IsEnabled() {
+		logging.Warnf("Cookie-less mode is enabled without users recognition: anonymous events won't be stitched with identified users. Read more: https://jitsu.com/docs/other-features/retroactive-user-recognition")
+	}
 	transformStorage, err := script.InitializeStorage(true, metaStorageConfiguration)
 	if err != nil {
 		logging.Fatalf("Error initializing transform key value storage: %v", err)
 	}
 
+	scriptCache, wasmCompilationCacheDir := initializeScriptCache(transformStorage)
 	scriptFactory, err := node.NewFactory(viper.GetInt("node.pool_size"), viper.GetInt("node.max_space"), viper.GetInt("node.sources_max_space"), transformStorage)
 	if err != nil {
 		logging.Warn(err)
 	} else {
 		appconfig.Instance.ScheduleLastClosing(scriptFactory)
+		poolConfig := node.PoolConfig{
+			MaxSessions: viper.GetInt("node.pool.max_sessions"),
+			IdleTimeout: time.Duration(viper.GetInt("node.pool.session_idle_timeout_sec")) * time.Second,
+			Warm:        viper.GetBool("node.pool.warm"),
+		}
+		if err := scriptFactory.ConfigurePool(poolConfig); err != nil {
+			logging.Errorf("Error configuring Node.js processes pool: %v", err)
+		}
+		scriptFactory.SetCache(scriptCache)
+		scriptFactory.SetReporter(script.NewReporter(viper.GetInt("transform.logs_rate_limit_per_sec"), viper.GetInt("transform.metrics_rate_limit_per_sec")))
 		templates.SetScriptFactory(scriptFactory)
 	}
 
+	if viper.GetBool("python.enabled") {
+		pythonScriptFactory, err := python.NewFactory(viper.GetInt("python.pool_size"), transformStorage)
+		if err != nil {
+			logging.Warn(err)
+		} else {
+			appconfig.Instance.ScheduleLastClosing(pythonScriptFactory)
+			templates.SetPythonScriptFactory(pythonScriptFactory)
+		}
+	}
+
+	if viper.GetBool("wasm.enabled") {
+		wasmScriptFactory, err := wasm.NewFactory(viper.GetInt("wasm.max_memory_mb"), time.Duration(viper.GetInt("wasm.timeout_ms"))*time.Millisecond, wasmCompilationCacheDir)
+		if err != nil {
+			logging.Warn(err)
+		} else {
+			appconfig.Instance.ScheduleLastClosing(wasmScriptFactory)
+			templates.SetWasmScriptFactory(wasmScriptFactory)
+		}
+	}
+
 	maxColumns := viper.GetInt("server.max_columns")
 	defaultStreamingThreadsCount := viper.GetInt("streaming.threads_count")
 	if defaultStreamingThreadsCount <= 0 {
@@ -447,6 +602,8 @@ func main() {
 			LastActivityThreshold: time.Duration(viper.GetInt("server.sync_tasks.stalled.last_activity_threshold_minutes")) * time.Minute,
 			ObserverStalledEvery:  time.Duration(viper.GetInt("server.sync_tasks.stalled.observe_stalled_every_seconds")) * time.Second,
 			NotificationService:   synchronization.NewNotificationService(notificationCtx, viper.GetStringMap("notifications")),
+			TaskService:           taskService,
+			RetryPolicy:           newSyncRetryPolicy(),
 		}
 
 		//Create task executor
@@ -474,6 +631,7 @@ func main() {
 		logging.Fatal("Error while creating file uploader", err)
 	}
 	uploader.Start()
+	appconfig.Instance.ScheduleDraining(uploader)
 
 	//Streaming events archiver
 	periodicArchiver := logfiles.NewPeriodicArchiver(streamArchiveFileMask, path.Join(logEventPath, logevents.ArchiveDir), time.Duration(streamArchiveEveryS)*time.Second)
@@ -489,6 +647,34 @@ func main() {
 		logging.Fatal("Error creating fallback service:", err)
 	}
 
+	erasureAuditLogPath := viper.GetString("erasure.audit_log")
+	if erasureAuditLogPath == "" {
+		erasureAuditLogPath = path.Join(logEventPath, "erasure", "audit.log")
+	}
+	erasureDefaults := &config.Erasure{
+		Tables:  viper.GetStringSlice("erasure.tables"),
+		Columns: viper.GetStringSlice("erasure.columns"),
+		Mode:    viper.GetString("erasure.mode"),
+	}
+	erasureService, err := erasure.NewService(destinationsService, path.Join(logEventPath, logevents.ArchiveDir), erasureAuditLogPath, erasureDefaults)
+	if err != nil {
+		logging.Fatal("Error creating erasure service:", err)
+	}
+
+	//** Data retention
+	retentionDefaults := &config.Retention{
+		Days:   viper.GetInt("retention.days"),
+		Tables: viper.GetStringSlice("retention.tables"),
+		Column: viper.GetString("retention.column"),
+	}
+	var retentionInterval time.Duration
+	if viper.GetBool("retention.enabled") {
+		retentionInterval = time.Duration(viper.GetInt("retention.interval_min")) * time.Minute
+		logging.Infof("Data retention is enforced every %s (dry run: %t)", retentionInterval, viper.GetBool("retention.dry_run"))
+	}
+	retentionService := retention.NewService(destinationsService, coordinationService, retentionDefaults, retentionInterval, viper.GetBool("retention.dry_run"))
+	appconfig.Instance.ScheduleClosing(retentionService)
+
 	//** Segment API
 	//field mapper
 	mappings, err := schema.ConvertOldMappings(config.Default, viper.GetStringSlice("compatibility.segment.endpoint"))
@@ -527,37 +713,162 @@ func main() {
 	//event processors
 	apiProcessor := events.NewAPIProcessor(usersRecognitionService)
 	bulkProcessor := events.NewBulkProcessor()
-	jsProcessor := events.NewJsProcessor(usersRecognitionService, viper.GetString("server.fields_configuration.user_agent_path"))
+	jsProcessor := events.NewJsProcessor(usersRecognitionService, viper.GetString("server.fields_configuration.user_agent_path"), viper.GetString("server.fields_configuration.client_hints_path"))
 	pixelProcessor := events.NewPixelProcessor()
 	segmentProcessor := events.NewSegmentProcessor(usersRecognitionService)
 	processorHolder := events.NewProcessorHolder(apiProcessor, jsProcessor, pixelProcessor, segmentProcessor, bulkProcessor)
 
-	multiplexingService := multiplexing.NewService(destinationsService)
+	//Avro and Protobuf events API bodies
+	protocolsConfig := &protocols.Config{}
+	if err := viper.UnmarshalKey("server.protocols", protocolsConfig); err != nil {
+		logging.Fatalf("Error parsing 'server.protocols' config: %v", err)
+	}
+	binaryDecoder, err := protocols.NewDecoder(protocolsConfig)
+	if err != nil {
+		logging.Fatalf("Error creating Avro and Protobuf decoder: %v", err)
+	}
+
+	//bot and spam traffic filtering
+	botFilterConfig := &botfilter.Config{}
+	if err := viper.UnmarshalKey("bot_filter", botFilterConfig); err != nil {
+		logging.Fatalf("Error parsing 'bot_filter' config: %v", err)
+	}
+	botFilter, err := botfilter.NewFilter(botFilterConfig, viper.GetString("server.fields_configuration.user_agent_path"))
+	if err != nil {
+		logging.Fatalf("Error creating bot filter: %v", err)
+	}
+
+	//per API key sampling is configured in api_keys. Load shedding drops low-value events when destinations queues are overloaded
+	loadSheddingConfig := &sampling.LoadSheddingConfig{}
+	if err := viper.UnmarshalKey("load_shedding", loadSheddingConfig); err != nil {
+		logging.Fatalf("Error parsing 'load_shedding' config: %v", err)
+	}
+	loadShedder, err := sampling.NewLoadShedder(loadSheddingConfig)
+	if err != nil {
+		logging.Fatalf("Error creating load shedder: %v", err)
+	}
+	if loadShedder != nil {
+		appconfig.Instance.ScheduleClosing(loadShedder)
+	}
+
+	multiplexingService := multiplexing.NewService(destinationsService, botFilter, loadShedder)
 	walService := wal.NewService(logEventPath, loggerFactory.CreateWriteAheadLogger(), multiplexingService, processorHolder)
 	appconfig.Instance.ScheduleWriteAheadLogClosing(walService)
 
-	router := routers.SetupRouter(adminToken, metaStorage, destinationsService, sourceService, taskService, fallbackService,
-		coordinationService, eventsCache, systemService, segmentRequestFieldsMapper, segmentCompatRequestFieldsMapper, processorHolder,
-		multiplexingService, walService, geoService, globalRecognitionConfiguration)
+	//health and readiness checks for Kubernetes probes and load balancers
+	healthService := health.NewService(time.Duration(viper.GetInt("server.health.timeout_ms")) * time.Millisecond)
+	healthCacheTTL := time.Duration(viper.GetInt("server.health.cache_ttl_sec")) * time.Second
+	healthService.Register(health.MetaStorageCheck(metaStorage))
+	healthService.Register(health.CoordinationCheck(coordinationService))
+	healthService.Register(health.DestinationsCheck(destinationsService, healthCacheTTL))
+	var nodeFactory script.Factory
+	if scriptFactory != nil {
+		nodeFactory = scriptFactory
+	}
+	healthService.Register(health.NodeCheck(nodeFactory, healthCacheTTL))
+
+	router := routers.SetupRouter(adminToken, metaStorage, destinationsService, sourceService, taskService, fallbackService, erasureService,
+		retentionService, coordinationService, eventsCache, systemService, segmentRequestFieldsMapper, segmentCompatRequestFieldsMapper, processorHolder,
+		multiplexingService, walService, geoService, binaryDecoder, globalRecognitionConfiguration, healthService)
+
+	//gRPC events ingestion API
+	if viper.GetBool("server.grpc.enabled") {
+		grpcService := grpcapi.NewService(walService, multiplexingService, eventsCache, processorHolder.GetAPIPreprocessor(), destinationsService, geoService)
+		grpcServer, err := grpcapi.NewServer(viper.GetString("server.grpc.port"), viper.GetInt("server.grpc.max_message_size"), grpcService,
+			appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins)
+		if err != nil {
+			logging.Fatal("Error creating gRPC server:", err)
+		}
+		grpcServer.Start()
+		appconfig.Instance.ScheduleClosing(grpcServer)
+		logging.Info("🚀 Started gRPC server on port: " + viper.GetString("server.grpc.port"))
+	}
+
+	//MQTT ingestion bridge
+	if viper.GetBool("mqtt.enabled") {
+		mqttConfig := &mqtt.Config{}
+		if err := viper.UnmarshalKey("mqtt", mqttConfig); err != nil {
+			logging.Fatalf("Error parsing 'mqtt' config: %v", err)
+		}
+		mqttBridge, err := mqtt.NewBridge(mqttConfig, appconfig.Instance.ServerName, binaryDecoder, walService, multiplexingService,
+			eventsCache, processorHolder.GetAPIPreprocessor(), destinationsService, geoService)
+		if err != nil {
+			logging.Fatalf("Error creating MQTT bridge: %v", err)
+		}
+		mqttBridge.Start()
+		appconfig.Instance.ScheduleClosing(mqttBridge)
+		logging.Infof("🚀 Started MQTT bridge: %d topics", len(mqttConfig.Topics))
+	}
+
+	//alerting rules evaluation and notifications
+	if viper.GetBool("alerting.enabled") {
+		alertingConfig := &alerting.Config{}
+		if err := viper.UnmarshalKey("alerting", alertingConfig); err != nil {
+			logging.Fatalf("Error parsing 'alerting' config: %v", err)
+		}
+		alertingService, err := alerting.Init(alertingConfig, notifications.ServiceName, tag, appconfig.Instance.ServerName)
+		if err != nil {
+			logging.Fatalf("Error initializing alerting: %v", err)
+		}
+		appconfig.Instance.ScheduleClosing(alertingService)
+		logging.Infof("🚨 Started alerting: %d rules", len(alertingConfig.Rules))
+	}
 
 	telemetry.ServerStart()
 	notifications.ServerStart(systemInfo)
 	logging.Info("🚀 Started server: " + appconfig.Instance.Authority)
+	var handler http.Handler = middleware.Cors(router, appconfig.Instance.AuthorizationService.GetClientOrigins)
+	if viper.GetBool("server.http.h2c.enabled") {
+		//HTTP/2 without TLS (e.g. behind a load balancer which terminates TLS) for multiplexing SDK requests over a single connection
+		handler = h2c.NewHandler(handler, &http2.Server{
+			MaxConcurrentStreams: uint32(viper.GetInt("server.http.h2c.max_concurrent_streams")),
+			IdleTimeout:          time.Duration(viper.GetInt("server.http.idle_timeout_sec")) * time.Second,
+		})
+	}
 	server := &http.Server{
 		Addr:              appconfig.Instance.Authority,
-		Handler:           middleware.Cors(router, appconfig.Instance.AuthorizationService.GetClientOrigins),
-		ReadTimeout:       time.Second * 60,
-		ReadHeaderTimeout: time.Second * 60,
-		IdleTimeout:       time.Second * 65,
+		Handler:           handler,
+		ReadTimeout:       time.Duration(viper.GetInt("server.http.read_timeout_sec")) * time.Second,
+		ReadHeaderTimeout: time.Duration(viper.GetInt("server.http.read_header_timeout_sec")) * time.Second,
+		WriteTimeout:      time.Duration(viper.GetInt("server.http.write_timeout_sec")) * time.Second,
+		IdleTimeout:       time.Duration(viper.GetInt("server.http.idle_timeout_sec")) * time.Second,
+	}
+	appconfig.Instance.ScheduleDraining(appconfig.DrainerFunc(server.Shutdown))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		logging.Fatal(err)
 	}
-	logging.Fatal(server.ListenAndServe())
+
+	//wait for the shutdown goroutine which exits the process
+	select {}
 }
 
-// initializeCoordinationService returns configured coordination.Service (redis or inmemory)
+// initializeCoordinationService returns configured coordination.Service (redis, etcd or consul)
 func initializeCoordinationService(ctx context.Context, metaStorageConfiguration *viper.Viper) (*coordination.Service, error) {
-	//check deprecated etcd
-	if viper.GetString("coordination.etcd.endpoint") != "" || viper.IsSet("synchronization_service") {
-		return nil, fmt.Errorf("coordination.etcd is no longer supported. Please use Redis instead. Read more about coordination service https://jitsu.com/docs/deployment/scale#redis")
+	//check deprecated synchronization service
+	if viper.IsSet("synchronization_service") {
+		return nil, fmt.Errorf("synchronization_service is no longer supported. Please use coordination section instead. Read more about coordination service https://jitsu.com/docs/deployment/scale#coordination")
+	}
+
+	//etcd
+	if viper.IsSet("coordination.etcd") {
+		etcdConfig := &coordination.EtcdConfig{}
+		if err := viper.UnmarshalKey("coordination.etcd", etcdConfig); err != nil {
+			return nil, fmt.Errorf("error parsing coordination.etcd: %v", err)
+		}
+
+		telemetry.Coordination("etcd")
+		return coordination.NewEtcdService(ctx, appconfig.Instance.ServerName, etcdConfig)
+	}
+
+	//consul
+	if viper.IsSet("coordination.consul") {
+		consulConfig := &coordination.ConsulConfig{}
+		if err := viper.UnmarshalKey("coordination.consul", consulConfig); err != nil {
+			return nil, fmt.Errorf("error parsing coordination.consul: %v", err)
+		}
+
+		telemetry.Coordination("consul")
+		return coordination.NewConsulService(appconfig.Instance.ServerName, consulConfig)
 	}
 
 	//redis
@@ -586,17 +897,19 @@ func initializeCoordinationService(ctx context.Context, metaStorageConfiguration
 		}
 
 		telemetry.Coordination("redis")
-		factory := meta.NewRedisPoolFactory(host, coordinationRedisConfiguration.GetInt("port"), coordinationRedisConfiguration.GetString("password"), coordinationRedisConfiguration.GetInt("database"), coordinationRedisConfiguration.GetBool("tls_skip_verify"), coordinationRedisConfiguration.GetString("sentinel_master_name"))
+		factory := meta.NewRedisPoolFactory(host, coordinationRedisConfiguration.GetInt("port"), coordinationRedisConfiguration.GetString("password"), coordinationRedisConfiguration.GetInt("database"), coordinationRedisConfiguration.GetBool("tls_skip_verify"), coordinationRedisConfiguration.GetString("sentinel_master_name")).
+			WithConfiguration(coordinationRedisConfiguration)
 		factory.CheckAndSetDefaultPort()
 		return coordination.NewRedisService(ctx, appconfig.Instance.ServerName, factory)
 	}
 
-	return nil, errors.New("Unknown coordination configuration. Currently only [redis] is supported. " +
+	return nil, errors.New("Unknown coordination configuration. Currently [redis], [etcd] and [consul] are supported. " +
 		"\n\tRead more about coordination service configuration: https://jitsu.com/docs/deployment/scale#coordination")
 }
 
 // initializeEventsQueueFactory returns configured events.QueueFactory (redis or inmemory)
-func initializeEventsQueueFactory(metaStorageConfiguration *viper.Viper) (*events.QueueFactory, error) {
+// coordinationService is used for sharing destinations events queues partitions between server instances
+func initializeEventsQueueFactory(metaStorageConfiguration *viper.Viper, coordinationService *coordination.Service) (*events.QueueFactory, error) {
 	var redisConfigurationSource *viper.Viper
 
 	if metaStorageConfiguration != nil {
@@ -613,7 +926,8 @@ func initializeEventsQueueFactory(metaStorageConfiguration *viper.Viper) (*event
 	var eventsQueueRedisPool *meta.RedisPool
 	var err error
 	if redisConfigurationSource != nil && redisConfigurationSource.GetString("host") != "" {
-		factory := meta.NewRedisPoolFactory(redisConfigurationSource.GetString("host"), redisConfigurationSource.GetInt("port"), redisConfigurationSource.GetString("password"), redisConfigurationSource.GetInt("database"), redisConfigurationSource.GetBool("tls_skip_verify"), redisConfigurationSource.GetString("sentinel_master_name"))
+		factory := meta.NewRedisPoolFactory(redisConfigurationSource.GetString("host"), redisConfigurationSource.GetInt("port"), redisConfigurationSource.GetString("password"), redisConfigurationSource.GetInt("database"), redisConfigurationSource.GetBool("tls_skip_verify"), redisConfigurationSource.GetString("sentinel_master_name")).
+			WithConfiguration(redisConfigurationSource)
 		opts := meta.DefaultOptions
 		opts.MaxActive = 5000
 		factory.WithOptions(opts)
@@ -625,5 +939,141 @@ func initializeEventsQueueFactory(metaStorageConfiguration *viper.Viper) (*event
 		}
 	}
 
-	return events.NewQueueFactory(eventsQueueRedisPool, pollTimeout), nil
+	queueFactory := events.NewQueueFactory(eventsQueueRedisPool, pollTimeout)
+	switch queueType := viper.GetString("events.queue.type"); queueType {
+	case queue.RedisType:
+	case queue.RedisStreamsType:
+		if eventsQueueRedisPool == nil {
+			return nil, fmt.Errorf("events.queue.type: %s requires events.queue.redis or meta.storage.redis configuration", queueType)
+		}
+		queueFactory.WithRedisStreams(appconfig.Instance.ServerName)
+	default:
+		return nil, fmt.Errorf("unknown events.queue.type: %s. Supported: [%s, %s]", queueType, queue.RedisType, queue.RedisStreamsType)
+	}
+
+	if partitionsCount := viper.GetInt("events.queue.partitions.count"); partitionsCount > 0 {
+		if eventsQueueRedisPool == nil {
+			return nil, errors.New("events.queue.partitions requires events.queue.redis or meta.storage.redis configuration")
+		}
+
+		partitionsConfig := &events.PartitionsConfig{
+			Count:           partitionsCount,
+			Key:             viper.GetString("events.queue.partitions.key"),
+			RebalancePeriod: time.Duration(viper.GetInt("events.queue.partitions.rebalance_period_sec")) * time.Second,
+			ServerName:      appconfig.Instance.ServerName,
+			Coordinator:     coordinationService,
+		}
+		if err := partitionsConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("error validating events.queue.partitions configuration: %v", err)
+		}
+		queueFactory.WithPartitions(partitionsConfig)
+		logging.Infof("Destinations events queues are partitioned: %d partitions by %s", partitionsConfig.Count, partitionsConfig.Key)
+	}
+
+	if viper.GetBool("events.queue.priority_lanes") {
+		queueFactory.WithPriorityLanes(func(tokenID string) string {
+			if token := appconfig.Instance.AuthorizationService.GetToken(tokenID); token != nil {
+				return token.Priority
+			}
+			return ""
+		})
+	}
+
+	var spillStorage events.SpillStorage
+	if viper.IsSet("events.queue.spill.s3") {
+		s3Config := &adapters.S3Config{}
+		if err := viper.UnmarshalKey("events.queue.spill.s3", s3Config); err != nil {
+			return nil, fmt.Errorf("error parsing events.queue.spill.s3 configuration: %v", err)
+		}
+		s3Adapter, err := adapters.NewS3(s3Config)
+		if err != nil {
+			return nil, fmt.Errorf("error creating events.queue.spill.s3 adapter: %v", err)
+		}
+		spillStorage = s3Adapter
+	}
+
+	var defaultLimits *events.QueueLimits
+	if maxSize := viper.GetInt64("events.queue.max_size"); maxSize > 0 {
+		defaultLimits = &events.QueueLimits{MaxSize: maxSize, Policy: viper.GetString("events.queue.overflow_policy")}
+		if err := defaultLimits.Validate(); err != nil {
+			return nil, fmt.Errorf("error validating events.queue.overflow_policy: %v", err)
+		}
+		if defaultLimits.Policy == events.QueueOverflowSpill && spillStorage == nil {
+			return nil, fmt.Errorf("events.queue.overflow_policy: %s requires events.queue.spill.s3 configuration", events.QueueOverflowSpill)
+		}
+	}
+	queueFactory.WithLimits(defaultLimits, spillStorage)
+
+	return queueFactory, nil
+}
+
+// initializeScriptCache returns configured script.Cache (file, redis or dummy if disabled) and directory
+// of compiled WebAssembly modules cache (empty if it isn't file cache)
+func initializeScriptCache(transformStorage script.Storage) (script.Cache, string) {
+	if !viper.GetBool("script_cache.enabled") {
+		return script.DummyCache{}, ""
+	}
+
+	switch cacheType := viper.GetString("script_cache.type"); cacheType {
+	case script.FileCacheType:
+		dir := viper.GetString("script_cache.dir")
+		if dir == "" {
+			dir = filepath.Join(viper.GetString("server.plugins_cache"), "scripts")
+		}
+
+		fileCache, err := script.NewFileCache(dir)
+		if err != nil {
+			logging.Errorf("Error initializing script cache: %v. Scripts won't be cached", err)
+			return script.DummyCache{}, ""
+		}
+
+		logging.Infof("📦 Scripts are cached in %s", dir)
+		return fileCache, fileCache.Dir("wasm")
+	case script.RedisCacheType:
+		if transformStorage.Type() != script.RedisStorageType {
+			logging.Errorf("script_cache.type: %s requires transform.redis or meta.storage.redis configuration. Scripts won't be cached", cacheType)
+			return script.DummyCache{}, ""
+		}
+
+		return script.NewStorageCache(transformStorage, viper.GetInt64("script_cache.ttl_sec")*1000), ""
+	default:
+		logging.Errorf("Unknown script_cache.type: %s. Supported: [%s, %s]. Scripts won't be cached", cacheType, script.FileCacheType, script.RedisCacheType)
+		return script.DummyCache{}, ""
+	}
+}
+
+// newSyncRetryPolicy returns configured sync tasks retry policy or nil if retries are disabled
+func newSyncRetryPolicy() *synchronization.RetryPolicy {
+	permanentErrors := synchronization.DefaultPermanentErrors
+	if viper.IsSet("sync-tasks.retry.permanent_errors") {
+		permanentErrors = viper.GetStringSlice("sync-tasks.retry.permanent_errors")
+	}
+
+	retryPolicy := synchronization.NewRetryPolicy(viper.GetInt("sync-tasks.retry.max_attempts"),
+		time.Duration(viper.GetInt("sync-tasks.retry.initial_delay_sec"))*time.Second,
+		time.Duration(viper.GetInt("sync-tasks.retry.max_delay_sec"))*time.Second,
+		viper.GetFloat64("sync-tasks.retry.multiplier"), viper.GetFloat64("sync-tasks.retry.jitter"), permanentErrors)
+	if retryPolicy != nil {
+		logging.Infof("Failed sync tasks will be retried up to %d times", retryPolicy.MaxAttempts)
+	}
+
+	return retryPolicy
+}
+
+//initTracing configures OpenTelemetry tracing with OTLP/HTTP exporter from tracing.* configuration
+func initTracing() {
+	endpoint := viper.GetString("tracing.otlp.endpoint")
+	if endpoint == "" {
+		logging.Fatal("tracing.otlp.endpoint is required when tracing is enabled")
+	}
+
+	exporter := tracing.NewOTLPExporter(endpoint, viper.GetStringMapString("tracing.otlp.headers"),
+		time.Duration(viper.GetInt("tracing.otlp.timeout_ms"))*time.Millisecond)
+	tracing.Init(&tracing.Config{
+		ServiceName:    viper.GetString("tracing.service_name"),
+		ServiceVersion: appconfig.RawVersion,
+		SampleRatio:    viper.GetFloat64("tracing.sample_ratio"),
+		Exporter:       exporter,
+	})
+	logging.Infof("✅ Initialized OpenTelemetry tracing to %s (sample ratio: %v)", endpoint, viper.GetFloat64("tracing.sample_ratio"))
 }
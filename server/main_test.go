@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -30,7 +31,7 @@ import (
 
 func SetTestDefaultParams() {
 	viper.Set("log.path", "")
-	viper.Set("api_keys", `{"tokens":[{"id":"id1","client_secret":"c2stoken","server_secret":"s2stoken","origins":["whiteorigin*"]}]}`)
+	viper.Set("api_keys", `{"tokens":[{"id":"id1","client_secret":"c2stoken","server_secret":"s2stoken","origins":["whiteorigin*","https://*.subdomain.com"]}]}`)
 	viper.Set("server.log.path", "")
 	viper.Set("sql_debug_log.ddl.enabled", false)
 }
@@ -87,7 +88,7 @@ func TestCors(t *testing.T) {
 			"origin.com",
 			"",
 			"",
-			200,
+			403,
 		},
 		{
 			"Wrong origin with token in random url",
@@ -95,7 +96,7 @@ func TestCors(t *testing.T) {
 			"origin.com",
 			"",
 			"",
-			200,
+			403,
 		},
 		{
 			"Wrong origin with token in header event url",
@@ -103,7 +104,7 @@ func TestCors(t *testing.T) {
 			"origin.com",
 			"c2stoken",
 			"",
-			200,
+			403,
 		},
 		{
 			"Wrong origin with token in header random url",
@@ -111,7 +112,7 @@ func TestCors(t *testing.T) {
 			"origin.com",
 			"c2stoken",
 			"",
-			200,
+			403,
 		},
 		{
 			"Ok origin with token in event url",
@@ -145,6 +146,22 @@ func TestCors(t *testing.T) {
 			"http://whiteoriginmy.com",
 			200,
 		},
+		{
+			"Ok subdomain origin with token in event url",
+			"/api/v1/event?token=c2stoken",
+			"https://app.subdomain.com",
+			"",
+			"https://app.subdomain.com",
+			200,
+		},
+		{
+			"Wrong subdomain origin with token in event url",
+			"/api/v1/event?token=c2stoken",
+			"https://notsubdomain.com",
+			"",
+			"",
+			403,
+		},
 		{
 			"S2S endpoint without cors",
 			"/api/v1/s2s/event?token=wrongtoken",
@@ -517,6 +534,11 @@ func TestSegmentAPIEndpoint(t *testing.T) {
 			"/api/v1/segment/compat",
 			"test_data/segment_api_events_output_compat.json",
 		},
+		{
+			"Segment native API ok",
+			"",
+			"test_data/segment_api_events_output_2.0.json",
+		},
 	}
 
 	for _, tt := range tests {
@@ -690,6 +712,73 @@ func TestPixelEndpoint(t *testing.T) {
 	}
 }
 
+func TestIngestionBodyLimit(t *testing.T) {
+	uuid.InitMock()
+	binding.EnableDecoderUseNumber = true
+
+	SetTestDefaultParams()
+	viper.Set("server.http.max_body_size", 100)
+	defer viper.Set("server.http.max_body_size", 10485760)
+
+	gzipped := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(gzipped)
+	_, err := gzipWriter.Write([]byte(strings.Repeat("a", 1000)))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	tests := []struct {
+		Name     string
+		ReqUrn   string
+		Body     []byte
+		Gzip     bool
+		Response string
+	}{
+		{
+			"Events API chunked body",
+			"/api/v1/s2s/event?token=s2stoken",
+			[]byte(strings.Repeat("a", 1000)),
+			false,
+			`{"message":"Request body size exceeds the limit: 100 bytes","error":""}`,
+		},
+		{
+			"Bulk API chunked body",
+			"/api/v1/events/bulk?token=s2stoken",
+			[]byte(strings.Repeat("a", 1000)),
+			false,
+			`{"message":"Request body size exceeds the limit: 100 bytes","error":""}`,
+		},
+		{
+			"Bulk API decompressed body",
+			"/api/v1/events/bulk?token=s2stoken",
+			gzipped.Bytes(),
+			true,
+			`{"message":"Decompressed request body size exceeds the limit: 100 bytes","error":""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			testSuite := testsuit.NewSuiteBuilder(t).Build(t)
+			defer testSuite.Close()
+
+			//body without Content-Length
+			apiReq, err := http.NewRequest("POST", "http://"+testSuite.HTTPAuthority()+tt.ReqUrn, ioutil.NopCloser(bytes.NewReader(tt.Body)))
+			require.NoError(t, err)
+			if tt.Gzip {
+				apiReq.Header.Add("Content-Encoding", "gzip")
+			}
+			resp, err := http.DefaultClient.Do(apiReq)
+			require.NoError(t, err)
+
+			b, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, "HTTP codes aren't equal")
+			require.Equal(t, tt.Response, string(b))
+		})
+	}
+}
+
 func TestIPCookiePolicyComply(t *testing.T) {
 	uuid.InitMock()
 	binding.EnableDecoderUseNumber = true
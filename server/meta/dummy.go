@@ -1,6 +1,9 @@
 package meta
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Dummy struct {
 }
@@ -8,6 +11,12 @@ type Dummy struct {
 func (d *Dummy) GetSignature(sourceID, collection, interval string) (string, error)   { return "", nil }
 func (d *Dummy) SaveSignature(sourceID, collection, interval, signature string) error { return nil }
 func (d *Dummy) DeleteSignature(sourceID, collection string) error                    { return nil }
+func (d *Dummy) GetSignatures(sourceID, collection string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+func (d *Dummy) DeleteSignatureIntervals(sourceID, collection string, intervals ...string) error {
+	return nil
+}
 
 func (d *Dummy) IncrementEventsCount(id, namespace, eventType, status string, now time.Time, value int64) error {
 	return nil
@@ -18,6 +27,10 @@ func (d *Dummy) GetProjectDestinationIDs(projectID string) ([]string, error) { r
 func (d *Dummy) GetEventsWithGranularity(namespace, status, eventType string, ids []string, start, end time.Time, granularity Granularity) ([]EventsPerTime, error) {
 	return nil, nil
 }
+func (d *Dummy) IncrementRollupsCount(key *RollupKey, now time.Time, value int64) error { return nil }
+func (d *Dummy) GetRollups(filter *RollupFilter, start, end time.Time, granularity Granularity) ([]RollupsPerTime, error) {
+	return []RollupsPerTime{}, nil
+}
 
 func (d *Dummy) AddEvent(namespace, id, status string, entity *Event) error  { return nil }
 func (d *Dummy) TrimEvents(namespace, id, status string, capacity int) error { return nil }
@@ -60,7 +73,8 @@ func (d *Dummy) GetTaskLogs(taskID string, from, to time.Time) ([]TaskLogRecord,
 func (d *Dummy) PushTask(task *Task) error { return nil }
 func (d *Dummy) PollTask() (*Task, error)  { return nil, nil }
 
-func (d *Dummy) GetOrCreateClusterID() string { return "" }
+func (d *Dummy) GetOrCreateClusterID() string   { return "" }
+func (d *Dummy) Ping(ctx context.Context) error { return nil }
 
 func (d *Dummy) Type() string {
 	return DummyType
@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -38,6 +39,9 @@ const (
 
 	responseTimestampLayout = "2006-01-02T15:04:05+0000"
 
+	hourlyRollupsPrefix = "rollups_hourly:day#"
+	dailyRollupsPrefix  = "rollups_daily:month#"
+
 	PushEventType = "push"
 	PullEventType = "pull"
 
@@ -87,6 +91,10 @@ type Redis struct {
 //daily_events:push_source#sourceID:month#yyyymm:success            [day] - hashtable with success events counter by day
 //hourly_events:push_source#sourceID:day#yyyymmdd:success           [hour] - hashtable with success events counter by hour
 //
+//** Usage rollups **
+//rollups_hourly:day#yyyymmdd  ["hour","destinationID","sourceID","apiKey","table","status"] - hashtable with events counters by hour and dimensions values
+//rollups_daily:month#yyyymm   ["day","destinationID","sourceID","apiKey","table","status"]  - hashtable with events counters by day and dimensions values
+//
 //** Events cache**
 //events_cache:destination#destinationID - sorted (desc by inserted time) list of destination Events that have statuses (success, failed, skip).
 //events_cache:token#tokenID - sorted (desc by inserted time) list of token raw JSON Events that don't have statuses
@@ -157,6 +165,48 @@ func (r *Redis) DeleteSignature(sourceID, collection string) error {
 	return nil
 }
 
+// GetSignatures returns all source collection interval signatures from Redis
+func (r *Redis) GetSignatures(sourceID, collection string) (map[string]string, error) {
+	key := "source#" + sourceID + ":collection#" + collection + ":chunks"
+	connection := r.pool.Get()
+	defer connection.Close()
+	signatures, err := redis.StringMap(connection.Do("HGETALL", key))
+	if err != nil && err != redis.ErrNil {
+		r.errorMetrics.NoticeError(err)
+		return nil, err
+	}
+
+	if signatures == nil {
+		signatures = map[string]string{}
+	}
+
+	return signatures, nil
+}
+
+// DeleteSignatureIntervals deletes source collection signatures of certain intervals from Redis
+func (r *Redis) DeleteSignatureIntervals(sourceID, collection string, intervals ...string) error {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	key := "source#" + sourceID + ":collection#" + collection + ":chunks"
+	connection := r.pool.Get()
+	defer connection.Close()
+
+	args := make([]interface{}, 0, len(intervals)+1)
+	args = append(args, key)
+	for _, interval := range intervals {
+		args = append(args, interval)
+	}
+	_, err := connection.Do("HDEL", args...)
+	if err != nil && err != redis.ErrNil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	return nil
+}
+
 // IncrementEventsCount increment events counter
 // namespaces: [destination, source]
 // eventType: [push, pull]
@@ -348,26 +398,26 @@ func (r *Redis) RemoveTasks(sourceID, collection string, taskIDs ...string) (int
 	}
 	logging.Debugf("Removed %d of %d from index %s", removed, len(taskIDs), taskIndexKey)
 
-	taskKeys := make([]interface{}, 0, len(taskIDs))
+	taskKeys := make([]string, 0, len(taskIDs))
 	for _, id := range taskIDs {
 		taskKeys = append(taskKeys, syncTasksPrefix+id)
 	}
 
-	removed, err = redis.Int(conn.Do("DEL", taskKeys...))
-	if err != nil && err != redis.ErrNil {
+	removed, err = r.pool.DeleteKeys(conn, taskKeys...)
+	if err != nil {
 		r.errorMetrics.NoticeError(err)
 		//no point to return error. we have already cleared index
 		logging.Errorf("failed to remove tasks. source:%s collection:%s tasks:%v err:%v", sourceID, collection, taskIDs, err)
 	} else {
 		logging.Debugf("Removed %d of %d from tasks. source:%s collection:%s", removed, len(taskIDs), sourceID, collection)
 	}
-	taskLogKeys := make([]interface{}, 0, len(taskIDs))
+	taskLogKeys := make([]string, 0, len(taskIDs))
 	for _, id := range taskIDs {
 		taskLogKeys = append(taskLogKeys, syncTasksPrefix+id+":logs")
 	}
 
-	removed, err = redis.Int(conn.Do("DEL", taskLogKeys...))
-	if err != nil && err != redis.ErrNil {
+	removed, err = r.pool.DeleteKeys(conn, taskLogKeys...)
+	if err != nil {
 		r.errorMetrics.NoticeError(err)
 		//no point to return error. we have already cleared index
 		logging.Errorf("failed to remove task logs. source:%s collection:%s tasks:%v err:%v", sourceID, collection, taskIDs, err)
@@ -462,63 +512,46 @@ func (r *Redis) GetAllTasksForInitialHeartbeat(runningStatus, scheduledStatus st
 	//the task is stalled if last activity was before current time - lastActivityThreshold
 	stalledTime := timestamp.Now().UTC().Truncate(lastActivityThreshold)
 
+	//in Redis Cluster mode keys are scanned on every master node
+	taskKeys, err := r.pool.ScanKeys(syncTasksPrefix+"*", "hash", 50000)
+	if err != nil {
+		r.errorMetrics.NoticeError(err)
+		return nil, err
+	}
+
 	var taskIDs []string
-	cursor := 0
+	for _, taskKey := range taskKeys {
+		taskID := strings.TrimPrefix(taskKey, syncTasksPrefix)
 
-	for {
-		scannedResult, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", syncTasksPrefix+"*", "TYPE", "hash", "COUNT", 50000))
+		//filter by status
+		task, err := r.getTask(conn, taskID)
 		if err != nil {
-			if err != nil && err != redis.ErrNil {
-				r.errorMetrics.NoticeError(err)
-				return nil, err
+			if err == ErrTaskNotFound {
+				logging.SystemErrorf("task [%s] wasn't found in initial heartbeat", taskID)
+				continue
 			}
-		}
 
-		if len(scannedResult) != 2 {
-			return nil, fmt.Errorf("error len of SCAN result: %v", scannedResult)
+			return nil, err
 		}
 
-		cursor, _ = redis.Int(scannedResult[0], nil)
-		taskKeysOutput, _ := redis.Strings(scannedResult[1], nil)
-
-		for _, taskKey := range taskKeysOutput {
-			taskID := strings.TrimPrefix(taskKey, syncTasksPrefix)
-
-			//filter by status
-			task, err := r.getTask(conn, taskID)
+		if task.Status == runningStatus {
+			ok, err := r.filterStalledTaskInRunningStatus(conn, task, stalledTime)
 			if err != nil {
-				if err == ErrTaskNotFound {
-					logging.SystemErrorf("task [%s] wasn't found in initial heartbeat", taskID)
-					continue
-				}
-
 				return nil, err
 			}
 
-			if task.Status == runningStatus {
-				ok, err := r.filterStalledTaskInRunningStatus(conn, task, stalledTime)
-				if err != nil {
-					return nil, err
-				}
-
-				if ok {
-					taskIDs = append(taskIDs, taskID)
-				}
-			} else if task.Status == scheduledStatus {
-				ok, err := r.filterStalledTaskInScheduledStatus(task, stalledTime)
-				if err != nil {
-					return nil, err
-				}
-
-				if ok {
-					taskIDs = append(taskIDs, taskID)
-				}
+			if ok {
+				taskIDs = append(taskIDs, taskID)
+			}
+		} else if task.Status == scheduledStatus {
+			ok, err := r.filterStalledTaskInScheduledStatus(task, stalledTime)
+			if err != nil {
+				return nil, err
 			}
-		}
 
-		//end of cycle
-		if cursor == 0 {
-			break
+			if ok {
+				taskIDs = append(taskIDs, taskID)
+			}
 		}
 	}
 
@@ -907,6 +940,81 @@ func (r *Redis) getEventsPerDay(conn redis.Conn, namespace, eventType, status st
 	return eventsPerTime, nil
 }
 
+// IncrementRollupsCount increments hourly and daily usage rollups counters of the dimensions values
+func (r *Redis) IncrementRollupsCount(key *RollupKey, now time.Time, value int64) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HINCRBY", getHourlyRollupsKey(now.Format(timestamp.DayLayout)), rollupField(now.Hour(), key), value)
+	if err != nil && err != redis.ErrNil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	_, err = conn.Do("HINCRBY", getDailyRollupsKey(now.Format(timestamp.MonthLayout)), rollupField(now.Day(), key), value)
+	if err != nil && err != redis.ErrNil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetRollups returns usage rollups which match the filter per hour or day (between start and end)
+func (r *Redis) GetRollups(filter *RollupFilter, start, end time.Time, granularity Granularity) ([]RollupsPerTime, error) {
+	var keys []string
+	var chunkTime func(key string, timeUnit int) time.Time
+	switch granularity {
+	case HOUR:
+		for _, day := range getCoveredDays(start, end) {
+			keys = append(keys, getHourlyRollupsKey(day))
+		}
+		chunkTime = func(key string, hour int) time.Time {
+			dayTime, _ := time.Parse(timestamp.DayLayout, strings.TrimPrefix(key, hourlyRollupsPrefix))
+			return dayTime.Add(time.Duration(hour) * time.Hour)
+		}
+	case DAY:
+		for _, month := range getCoveredMonths(start, end) {
+			keys = append(keys, getDailyRollupsKey(month))
+		}
+		chunkTime = func(key string, day int) time.Time {
+			monthTime, _ := time.Parse(timestamp.MonthLayout, strings.TrimPrefix(key, dailyRollupsPrefix))
+			//add (day - 1) cause month date starts from first month's day
+			return monthTime.AddDate(0, 0, day-1)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown granularity: %s", granularity.String())
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	aggregator := newRollupsAggregator(filter, start, end, granularity)
+	for _, key := range keys {
+		counters, err := redis.Int64Map(conn.Do("HGETALL", key))
+		if err != nil {
+			if err == redis.ErrNil {
+				continue
+			}
+
+			r.errorMetrics.NoticeError(err)
+			return nil, err
+		}
+
+		for field, value := range counters {
+			timeUnit, rollupKey, err := parseRollupField(field)
+			if err != nil {
+				logging.Warnf("Skipping rollup counter in [%s]: %v", key, err)
+				continue
+			}
+
+			aggregator.add(chunkTime(key, timeUnit), rollupKey, value)
+		}
+	}
+
+	return aggregator.result(), nil
+}
+
 // GetOrCreateClusterID returns clusterID from Redis or save input one
 func (r *Redis) GetOrCreateClusterID() string {
 	key := ConfigPrefix + SystemKey
@@ -941,6 +1049,16 @@ func (r *Redis) GetOrCreateClusterID() string {
 	return clusterID
 }
 
+//Ping checks Redis connectivity
+func (r *Redis) Ping(ctx context.Context) error {
+	if err := r.pool.Ping(ctx); err != nil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	return nil
+}
+
 func (r *Redis) Type() string {
 	return RedisType
 }
@@ -1045,6 +1163,14 @@ func getCachedEventsKey(namespace, id, status string) string {
 	return fmt.Sprintf("events_cache:%s#%s%s", namespace, id, statusPart)
 }
 
+func getHourlyRollupsKey(day string) string {
+	return hourlyRollupsPrefix + day
+}
+
+func getDailyRollupsKey(month string) string {
+	return dailyRollupsPrefix + month
+}
+
 func getHourlyEventsKey(id, namespace, eventType, day, status string) string {
 	return getEventsCounterKey("hourly_events", id, namespace, eventType, "day#"+day, status)
 }
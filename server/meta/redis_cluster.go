@@ -0,0 +1,104 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+const (
+	clusterMaxAttempts   = 3
+	clusterTryAgainDelay = 100 * time.Millisecond
+)
+
+var errClusterPipelining = errors.New("pipelining isn't supported in Redis Cluster mode")
+
+//clusterConn is a redis.Conn which executes every command on the Redis Cluster node that serves the command key.
+//It allows using one connection for commands with keys from different hash slots (like with standalone Redis).
+//MOVED and ASK redirections are followed. Pipelining (Send, Flush, Receive) isn't supported
+type clusterConn struct {
+	cluster *redisc.Cluster
+}
+
+//newClusterDialFunc returns dial func for redis.Pool which creates clusterConn
+func newClusterDialFunc(cluster *redisc.Cluster) func() (redis.Conn, error) {
+	return func() (redis.Conn, error) {
+		return &clusterConn{cluster: cluster}, nil
+	}
+}
+
+func (cc *clusterConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	//redis.Pool calls Do("") on connection close for flushing
+	if commandName == "" {
+		return nil, nil
+	}
+
+	conn := cc.cluster.Get()
+	defer conn.Close()
+
+	if key, ok := commandKey(commandName, args); ok {
+		if err := redisc.BindConn(conn, key); err != nil {
+			return nil, err
+		}
+	}
+
+	retryConn, err := redisc.RetryConn(conn, clusterMaxAttempts, clusterTryAgainDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	return retryConn.Do(commandName, args...)
+}
+
+//commandKey returns the key of commands where the key isn't the first argument
+//(otherwise redisc routes the command by the first argument)
+func commandKey(commandName string, args []interface{}) (string, bool) {
+	switch strings.ToUpper(commandName) {
+	case "EVAL", "EVALSHA":
+		//EVAL script numkeys key [key ...] arg [arg ...]
+		if len(args) > 2 {
+			if numKeys, err := strconv.Atoi(fmt.Sprint(args[1])); err == nil && numKeys > 0 {
+				return fmt.Sprint(args[2]), true
+			}
+		}
+	case "XGROUP", "XINFO":
+		//XGROUP CREATE key group id
+		if len(args) > 1 {
+			return fmt.Sprint(args[1]), true
+		}
+	case "XREAD", "XREADGROUP":
+		//XREADGROUP GROUP group consumer [COUNT count] [BLOCK ms] STREAMS key [key ...] id [id ...]
+		for i, arg := range args {
+			if strings.EqualFold(fmt.Sprint(arg), "STREAMS") && i+1 < len(args) {
+				return fmt.Sprint(args[i+1]), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func (cc *clusterConn) Send(string, ...interface{}) error {
+	return errClusterPipelining
+}
+
+func (cc *clusterConn) Flush() error {
+	return errClusterPipelining
+}
+
+func (cc *clusterConn) Receive() (interface{}, error) {
+	return nil, errClusterPipelining
+}
+
+func (cc *clusterConn) Err() error {
+	return nil
+}
+
+func (cc *clusterConn) Close() error {
+	return nil
+}
@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -127,3 +128,81 @@ func TestGetSentinelAndDialFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestWithConfiguration(t *testing.T) {
+	config := viper.New()
+	config.Set("username", "jitsu")
+	config.Set("tls", true)
+	config.Set("cluster", true)
+	config.Set("sentinel_password", "sentinel_pass")
+
+	factory := NewRedisPoolFactory("node1:7000,node2:7001", 0, "pass", 0, false, "").WithConfiguration(config)
+	require.Equal(t, "jitsu", factory.username)
+	require.True(t, factory.useTLS)
+	require.True(t, factory.cluster)
+	require.Equal(t, "sentinel_pass", factory.sentinelAuthPassword("pass"))
+	require.Equal(t, []string{"node1:7000", "node2:7001"}, factory.nodes())
+	require.Equal(t, "cluster node1:7000,node2:7001", factory.Details())
+
+	//nil configuration doesn't change the factory
+	factory = NewRedisPoolFactory("host", 6379, "pass", 0, false, "").WithConfiguration(nil)
+	require.False(t, factory.cluster)
+	require.Equal(t, "pass", factory.sentinelAuthPassword("pass"))
+	require.Equal(t, []string{"host:6379"}, factory.nodes())
+}
+
+func TestCreateClusterValidation(t *testing.T) {
+	clusterConfig := viper.New()
+	clusterConfig.Set("cluster", true)
+
+	tests := []struct {
+		name        string
+		factory     *RedisPoolFactory
+		expectedErr string
+	}{
+		{
+			"redis URL",
+			NewRedisPoolFactory("redis://localhost:6379", 0, "", 0, false, "").WithConfiguration(clusterConfig),
+			"Redis Cluster can't be configured with URL or sentinel. Please use host with comma separated startup nodes",
+		},
+		{
+			"sentinel",
+			NewRedisPoolFactory("host", 26379, "", 0, false, "master").WithConfiguration(clusterConfig),
+			"Redis Cluster can't be configured with URL or sentinel. Please use host with comma separated startup nodes",
+		},
+		{
+			"database",
+			NewRedisPoolFactory("host", 6379, "", 1, false, "").WithConfiguration(clusterConfig),
+			"Redis Cluster supports only database 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.factory.createCluster()
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestClusterCommandKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		args        []interface{}
+		expectedKey string
+		expectedOk  bool
+	}{
+		{"first argument key", "GET", []interface{}{"key"}, "", false},
+		{"eval", "EVALSHA", []interface{}{"sha", 1, "lock_key", "value"}, "lock_key", true},
+		{"eval without keys", "EVAL", []interface{}{"return 1", 0}, "", false},
+		{"xgroup", "XGROUP", []interface{}{"CREATE", "stream", "group", "$"}, "stream", true},
+		{"xreadgroup", "XREADGROUP", []interface{}{"GROUP", "group", "consumer", "COUNT", 1, "STREAMS", "stream", ">"}, "stream", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := commandKey(tt.command, tt.args)
+			require.Equal(t, tt.expectedOk, ok)
+			require.Equal(t, tt.expectedKey, key)
+		})
+	}
+}
@@ -7,13 +7,15 @@ import (
 	"github.com/FZambia/sentinel"
 	"github.com/gomodule/redigo/redis"
 	"github.com/hashicorp/go-multierror"
+	"github.com/mna/redisc"
 	"github.com/pkg/errors"
 )
 
-//RedisPool is a wrapper for keeping redis.Pool and sentinel.Sentinel and close them
+//RedisPool is a wrapper for keeping redis.Pool and sentinel.Sentinel (or redisc.Cluster in Redis Cluster mode) and close them
 type RedisPool struct {
 	pool     *redis.Pool
 	sentinel *sentinel.Sentinel
+	cluster  *redisc.Cluster
 }
 
 //GetPool returns the underlying redigo pool
@@ -21,7 +23,13 @@ func (rp *RedisPool) GetPool() *redis.Pool {
 	return rp.pool
 }
 
+//IsCluster returns true if the pool is connected to Redis Cluster
+func (rp *RedisPool) IsCluster() bool {
+	return rp.cluster != nil
+}
+
 //Get returns a connection from the pool
+//In Redis Cluster mode every command is executed on the node which serves the command key
 func (rp *RedisPool) Get() redis.Conn {
 	return rp.pool.Get()
 }
@@ -34,12 +42,128 @@ func (rp *RedisPool) GetContext(ctx context.Context) (redis.Conn, error) {
 	}
 }
 
-//Close closes redis pool and sentinel if configured
+//Ping checks that a connection can be obtained and Redis responds to PING command
+//In Redis Cluster mode every node is checked
+func (rp *RedisPool) Ping(ctx context.Context) error {
+	if rp.cluster != nil {
+		return rp.cluster.EachNode(false, func(address string, conn redis.Conn) error {
+			if _, err := redis.DoContext(conn, ctx, "PING"); err != nil {
+				return fmt.Errorf("%s: %v", address, err)
+			}
+			return nil
+		})
+	}
+
+	conn, err := rp.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "PING")
+	return err
+}
+
+//DeleteKeys deletes keys with DEL command and returns number of deleted keys
+//In Redis Cluster mode keys are grouped by hash slot because multi-key commands require all keys in the same slot
+func (rp *RedisPool) DeleteKeys(conn redis.Conn, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	groups := [][]string{keys}
+	if rp.cluster != nil {
+		groups = redisc.SplitBySlot(keys...)
+	}
+
+	deleted := 0
+	for _, group := range groups {
+		args := make([]interface{}, len(group))
+		for i, key := range group {
+			args[i] = key
+		}
+
+		count, err := redis.Int(conn.Do("DEL", args...))
+		if err != nil && err != redis.ErrNil {
+			return deleted, err
+		}
+		deleted += count
+	}
+
+	return deleted, nil
+}
+
+//ScanKeys returns all keys which match the pattern (and the type if keyType isn't empty) with SCAN command
+//In Redis Cluster mode every master node is scanned
+func (rp *RedisPool) ScanKeys(match, keyType string, count int) ([]string, error) {
+	if rp.cluster != nil {
+		var keys []string
+		err := rp.cluster.EachNode(false, func(address string, conn redis.Conn) error {
+			nodeKeys, err := scanKeys(conn, match, keyType, count)
+			if err != nil {
+				return fmt.Errorf("%s: %v", address, err)
+			}
+			keys = append(keys, nodeKeys...)
+			return nil
+		})
+		return keys, err
+	}
+
+	conn := rp.Get()
+	defer conn.Close()
+
+	return scanKeys(conn, match, keyType, count)
+}
+
+func scanKeys(conn redis.Conn, match, keyType string, count int) ([]string, error) {
+	var keys []string
+	cursor := 0
+	for {
+		args := []interface{}{cursor, "MATCH", match}
+		if keyType != "" {
+			args = append(args, "TYPE", keyType)
+		}
+		if count > 0 {
+			args = append(args, "COUNT", count)
+		}
+
+		values, err := redis.Values(conn.Do("SCAN", args...))
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN response length: %d", len(values))
+		}
+
+		cursor, err = redis.Int(values[0], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		batch, err := redis.Strings(values[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, batch...)
+
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+//Close closes redis pool, sentinel and cluster if configured
 func (rp *RedisPool) Close() (multiErr error) {
 	if err := rp.pool.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("error closing redis pool: %v", err))
 	}
 
+	if rp.cluster != nil {
+		if err := rp.cluster.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("error closing redis cluster: %v", err))
+		}
+	}
+
 	if rp.sentinel != nil {
 		if err := rp.sentinel.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("error closing redis sentinel: %v", err))
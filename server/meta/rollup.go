@@ -0,0 +1,226 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Rollup dimensions
+const (
+	RollupDestinationDimension = "destination_id"
+	RollupSourceDimension      = "source_id"
+	RollupAPIKeyDimension      = "api_key"
+	RollupTableDimension       = "table"
+	RollupStatusDimension      = "status"
+)
+
+var rollupDimensions = []string{RollupDestinationDimension, RollupSourceDimension, RollupAPIKeyDimension, RollupTableDimension, RollupStatusDimension}
+
+//RollupKey is a set of usage statistics dimensions values
+//push events: SourceID and APIKey are the API key (token) ID
+//pull events: SourceID is the source ID, APIKey is empty
+type RollupKey struct {
+	DestinationID string `json:"destination_id,omitempty"`
+	SourceID      string `json:"source_id,omitempty"`
+	APIKey        string `json:"api_key,omitempty"`
+	Table         string `json:"table,omitempty"`
+	Status        string `json:"status,omitempty"`
+}
+
+//RollupsPerTime is an amount of events per hour or day with the same grouped dimensions values
+type RollupsPerTime struct {
+	Key string `json:"key"`
+	RollupKey
+	Events int64 `json:"events"`
+}
+
+//RollupFilter selects rollups by dimensions values (empty slice means any value)
+//and groups them by GroupBy dimensions (other dimensions are summed up)
+type RollupFilter struct {
+	DestinationIDs []string
+	SourceIDs      []string
+	APIKeys        []string
+	Tables         []string
+	Statuses       []string
+
+	GroupBy []string
+}
+
+//ParseRollupDimensions returns dimensions from comma separated string or error if a dimension is unknown
+func ParseRollupDimensions(value string) ([]string, error) {
+	var dimensions []string
+	for _, dimension := range strings.Split(value, ",") {
+		dimension = strings.TrimSpace(dimension)
+		if dimension == "" {
+			continue
+		}
+
+		if !isRollupDimension(dimension) {
+			return nil, fmt.Errorf("Unknown dimension: %s. Supported: [%s]", dimension, strings.Join(rollupDimensions, ", "))
+		}
+		dimensions = append(dimensions, dimension)
+	}
+
+	return dimensions, nil
+}
+
+func isRollupDimension(dimension string) bool {
+	for _, d := range rollupDimensions {
+		if d == dimension {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Match returns true if all key dimensions values satisfy the filter
+func (rf *RollupFilter) Match(key *RollupKey) bool {
+	return matchValue(rf.DestinationIDs, key.DestinationID) &&
+		matchValue(rf.SourceIDs, key.SourceID) &&
+		matchValue(rf.APIKeys, key.APIKey) &&
+		matchValue(rf.Tables, key.Table) &&
+		matchValue(rf.Statuses, key.Status)
+}
+
+//group returns a copy of the key with only GroupBy dimensions values
+func (rf *RollupFilter) group(key *RollupKey) RollupKey {
+	grouped := RollupKey{}
+	for _, dimension := range rf.GroupBy {
+		switch dimension {
+		case RollupDestinationDimension:
+			grouped.DestinationID = key.DestinationID
+		case RollupSourceDimension:
+			grouped.SourceID = key.SourceID
+		case RollupAPIKeyDimension:
+			grouped.APIKey = key.APIKey
+		case RollupTableDimension:
+			grouped.Table = key.Table
+		case RollupStatusDimension:
+			grouped.Status = key.Status
+		}
+	}
+
+	return grouped
+}
+
+func matchValue(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+//rollupField returns a hashtable field: JSON array [timeUnit, destinationID, sourceID, apiKey, table, status]
+//JSON is used because dimensions values (e.g. table names) may contain any delimiter
+func rollupField(timeUnit int, key *RollupKey) string {
+	b, _ := json.Marshal([]string{strconv.Itoa(timeUnit), key.DestinationID, key.SourceID, key.APIKey, key.Table, key.Status})
+	return string(b)
+}
+
+//parseRollupField returns time unit (hour or day) and rollup key from the hashtable field
+func parseRollupField(field string) (int, *RollupKey, error) {
+	var parts []string
+	if err := json.Unmarshal([]byte(field), &parts); err != nil {
+		return 0, nil, fmt.Errorf("Error parsing rollup field [%s]: %v", field, err)
+	}
+
+	if len(parts) != 6 {
+		return 0, nil, fmt.Errorf("Malformed rollup field [%s]: expected 6 parts", field)
+	}
+
+	timeUnit, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, nil, fmt.Errorf("Malformed rollup field [%s] time unit: %v", field, err)
+	}
+
+	return timeUnit, &RollupKey{DestinationID: parts[1], SourceID: parts[2], APIKey: parts[3], Table: parts[4], Status: parts[5]}, nil
+}
+
+//rollupsAggregator sums up events amounts per time chunk and grouped key
+type rollupsAggregator struct {
+	filter *RollupFilter
+	start  time.Time
+	end    time.Time
+
+	chunks map[time.Time]map[RollupKey]int64
+}
+
+func newRollupsAggregator(filter *RollupFilter, start, end time.Time, granularity Granularity) *rollupsAggregator {
+	start = start.UTC()
+	if granularity == DAY {
+		start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	} else {
+		start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, time.UTC)
+	}
+
+	return &rollupsAggregator{filter: filter, start: start, end: end, chunks: map[time.Time]map[RollupKey]int64{}}
+}
+
+//add sums up the value if the chunk time is in the interval and the key satisfies the filter
+func (ra *rollupsAggregator) add(chunk time.Time, key *RollupKey, value int64) {
+	if chunk.Before(ra.start) || !chunk.Before(ra.end) || !ra.filter.Match(key) {
+		return
+	}
+
+	perKey, ok := ra.chunks[chunk]
+	if !ok {
+		perKey = map[RollupKey]int64{}
+		ra.chunks[chunk] = perKey
+	}
+
+	perKey[ra.filter.group(key)] += value
+}
+
+//result returns rollups sorted by time and dimensions values
+func (ra *rollupsAggregator) result() []RollupsPerTime {
+	rollups := []RollupsPerTime{}
+	chunks := make([]time.Time, 0, len(ra.chunks))
+	for chunk := range ra.chunks {
+		chunks = append(chunks, chunk)
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Before(chunks[j]) })
+
+	for _, chunk := range chunks {
+		key := chunk.Format(responseTimestampLayout)
+		perKey := ra.chunks[chunk]
+		chunkRollups := make([]RollupsPerTime, 0, len(perKey))
+		for rollupKey, value := range perKey {
+			chunkRollups = append(chunkRollups, RollupsPerTime{Key: key, RollupKey: rollupKey, Events: value})
+		}
+		sort.Slice(chunkRollups, func(i, j int) bool {
+			return chunkRollups[i].RollupKey.less(&chunkRollups[j].RollupKey)
+		})
+
+		rollups = append(rollups, chunkRollups...)
+	}
+
+	return rollups
+}
+
+func (rk *RollupKey) less(other *RollupKey) bool {
+	if rk.DestinationID != other.DestinationID {
+		return rk.DestinationID < other.DestinationID
+	}
+	if rk.SourceID != other.SourceID {
+		return rk.SourceID < other.SourceID
+	}
+	if rk.APIKey != other.APIKey {
+		return rk.APIKey < other.APIKey
+	}
+	if rk.Table != other.Table {
+		return rk.Table < other.Table
+	}
+	return rk.Status < other.Status
+}
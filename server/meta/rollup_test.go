@@ -0,0 +1,67 @@
+package meta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollupField(t *testing.T) {
+	key := &RollupKey{DestinationID: "p1.dest", SourceID: "token1", APIKey: "token1", Table: "events:2021#01", Status: SuccessStatus}
+
+	timeUnit, actual, err := parseRollupField(rollupField(13, key))
+	require.NoError(t, err)
+	require.Equal(t, 13, timeUnit)
+	require.Equal(t, key, actual)
+
+	_, _, err = parseRollupField("13")
+	require.Error(t, err)
+	_, _, err = parseRollupField(`["13","d"]`)
+	require.Error(t, err)
+}
+
+func TestParseRollupDimensions(t *testing.T) {
+	dimensions, err := ParseRollupDimensions(" destination_id, table,,status ")
+	require.NoError(t, err)
+	require.Equal(t, []string{RollupDestinationDimension, RollupTableDimension, RollupStatusDimension}, dimensions)
+
+	dimensions, err = ParseRollupDimensions("")
+	require.NoError(t, err)
+	require.Empty(t, dimensions)
+
+	_, err = ParseRollupDimensions("destination_id,project")
+	require.Error(t, err)
+}
+
+func TestRollupsAggregator(t *testing.T) {
+	start := time.Date(2021, 3, 17, 10, 30, 0, 0, time.UTC)
+	end := time.Date(2021, 3, 17, 13, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time {
+		return time.Date(2021, 3, 17, h, 0, 0, 0, time.UTC)
+	}
+
+	filter := &RollupFilter{
+		DestinationIDs: []string{"d1", "d2"},
+		GroupBy:        []string{RollupDestinationDimension, RollupStatusDimension},
+	}
+	aggregator := newRollupsAggregator(filter, start, end, HOUR)
+	aggregator.add(hour(9), &RollupKey{DestinationID: "d1", Table: "events", Status: SuccessStatus}, 100)
+	aggregator.add(hour(10), &RollupKey{DestinationID: "d2", Table: "events", Status: SuccessStatus}, 1)
+	aggregator.add(hour(10), &RollupKey{DestinationID: "d1", Table: "events", Status: SuccessStatus}, 2)
+	aggregator.add(hour(10), &RollupKey{DestinationID: "d1", Table: "users", Status: SuccessStatus}, 3)
+	aggregator.add(hour(10), &RollupKey{DestinationID: "d1", Table: "users", Status: ErrorStatus}, 4)
+	aggregator.add(hour(10), &RollupKey{DestinationID: "d3", Table: "users", Status: SuccessStatus}, 5)
+	aggregator.add(hour(12), &RollupKey{DestinationID: "d2", Table: "users", Status: SkipStatus}, 6)
+	aggregator.add(hour(13), &RollupKey{DestinationID: "d2", Table: "users", Status: SkipStatus}, 7)
+
+	require.Equal(t, []RollupsPerTime{
+		{Key: "2021-03-17T10:00:00+0000", RollupKey: RollupKey{DestinationID: "d1", Status: ErrorStatus}, Events: 4},
+		{Key: "2021-03-17T10:00:00+0000", RollupKey: RollupKey{DestinationID: "d1", Status: SuccessStatus}, Events: 5},
+		{Key: "2021-03-17T10:00:00+0000", RollupKey: RollupKey{DestinationID: "d2", Status: SuccessStatus}, Events: 1},
+		{Key: "2021-03-17T12:00:00+0000", RollupKey: RollupKey{DestinationID: "d2", Status: SkipStatus}, Events: 6},
+	}, aggregator.result())
+
+	empty := newRollupsAggregator(&RollupFilter{}, start, end, DAY)
+	require.Equal(t, []RollupsPerTime{}, empty.result())
+}
@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"context"
 	"io"
 	"time"
 
@@ -21,6 +22,8 @@ type Storage interface {
 	GetSignature(sourceID, collection, interval string) (string, error)
 	SaveSignature(sourceID, collection, interval, signature string) error
 	DeleteSignature(sourceID, collection string) error
+	GetSignatures(sourceID, collection string) (map[string]string, error)
+	DeleteSignatureIntervals(sourceID, collection string, intervals ...string) error
 
 	//** Counters **
 	//events counters
@@ -30,6 +33,9 @@ type Storage interface {
 	//536-issue DEPRECATED instead of it all project sources will be selected
 	GetProjectPushSourceIDs(projectID string) ([]string, error)
 	GetEventsWithGranularity(namespace, status, eventType string, ids []string, start, end time.Time, granularity Granularity) ([]EventsPerTime, error)
+	//usage rollups by destination, source, api key, table and status
+	IncrementRollupsCount(key *RollupKey, now time.Time, value int64) error
+	GetRollups(filter *RollupFilter, start, end time.Time, granularity Granularity) ([]RollupsPerTime, error)
 
 	//** Events Cache **
 	//events caching
@@ -64,6 +70,7 @@ type Storage interface {
 
 	//system
 	GetOrCreateClusterID() string
+	Ping(ctx context.Context) error
 
 	Type() string
 }
@@ -80,7 +87,8 @@ func InitializeStorage(metaStorageConfiguration *viper.Viper) (Storage, error) {
 
 	sentinelMaster := metaStorageConfiguration.GetString("redis.sentinel_master_name")
 	tlsSkipVerify := metaStorageConfiguration.GetBool("redis.tls_skip_verify")
-	factory := NewRedisPoolFactory(host, port, password, database, tlsSkipVerify, sentinelMaster)
+	factory := NewRedisPoolFactory(host, port, password, database, tlsSkipVerify, sentinelMaster).
+		WithConfiguration(metaStorageConfiguration.Sub("redis"))
 	factory.CheckAndSetDefaultPort()
 
 	logging.Infof("🏪 Initializing meta storage redis [%s]...", factory.Details())
@@ -14,6 +14,8 @@ type Task struct {
 	StartedAt  string `json:"started_at,omitempty" redis:"started_at"`
 	FinishedAt string `json:"finished_at,omitempty" redis:"finished_at"`
 	Status     string `json:"status,omitempty" redis:"status"`
+	//Attempt is a retry number (0 for the first run)
+	Attempt int `json:"attempt,omitempty" redis:"attempt"`
 }
 
 //TaskLogRecord is a Redis entity
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var apiKeysLabels = []string{"project_id", "source_id"}
+
+var (
+	apiKeysReceivedEvents *prometheus.CounterVec
+)
+
+func initAPIKeys() {
+	apiKeysReceivedEvents = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "api_keys",
+		Name:      "received_events",
+	}, apiKeysLabels)
+}
+
+// ReceivedTokenEvents counts events accepted by API key before multiplexing into destinations
+func ReceivedTokenEvents(tokenID string, value int) {
+	if Enabled() {
+		apiKeysReceivedEvents.WithLabelValues(extractTokenLabels(tokenID)).Add(float64(value))
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var batchLoadLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	batchUploaderQueueSize prometheus.Gauge
+	batchLoadDuration      *prometheus.HistogramVec
+)
+
+func initBatchUploader() {
+	batchUploaderQueueSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "batch_uploader",
+		Name:      "queue_size",
+	})
+	Registry.MustRegister(batchUploaderQueueSize)
+
+	batchLoadDuration = NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "eventnative",
+		Subsystem: "batch_uploader",
+		Name:      "load_duration_seconds",
+		Buckets:   []float64{0.5, 1, 5, 15, 30, 60, 120, 300, 600},
+	}, batchLoadLabels)
+}
+
+// SetBatchUploaderQueueSize sets the amount of log files waiting to be loaded
+func SetBatchUploaderQueueSize(value int) {
+	if Enabled() {
+		batchUploaderQueueSize.Set(float64(value))
+	}
+}
+
+// BatchLoadDuration observes the duration of loading one log file into the destination
+func BatchLoadDuration(destinationType, destinationName string, seconds float64) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		batchLoadDuration.WithLabelValues(projectID, destinationType, destinationID).Observe(seconds)
+	}
+}
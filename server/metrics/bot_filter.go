@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var botFilterLabels = []string{"project_id", "source_id", "reason", "mode"}
+
+var (
+	botFilteredEvents *prometheus.CounterVec
+)
+
+func initBotFilter() {
+	botFilteredEvents = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "events",
+		Name:      "bot_filtered",
+	}, botFilterLabels)
+}
+
+//BotFilteredEvent counts events detected as bot or spam traffic per API key (mode: drop or mark)
+func BotFilteredEvent(tokenID, reason, mode string) {
+	if Enabled() {
+		projectID, sourceID := extractLabels(tokenID)
+		botFilteredEvents.WithLabelValues(projectID, sourceID, reason, mode).Inc()
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"sync"
+)
+
+// OverflowLabelValue replaces label values which exceed the configured cardinality limit
+const OverflowLabelValue = "_other"
+
+var (
+	apiKeysLabelLimiter      *labelLimiter
+	destinationsLabelLimiter *labelLimiter
+)
+
+// labelLimiter limits the amount of distinct values of a label. Values which are seen after the limit has been
+// reached are replaced with OverflowLabelValue. Zero or negative limit means no limit
+type labelLimiter struct {
+	mutex  sync.RWMutex
+	limit  int
+	values map[string]bool
+}
+
+func newLabelLimiter(limit int) *labelLimiter {
+	return &labelLimiter{limit: limit, values: map[string]bool{}}
+}
+
+// value returns the label value as is if it has been already seen or the limit hasn't been reached yet
+func (ll *labelLimiter) value(value string) string {
+	if ll == nil || ll.limit <= 0 {
+		return value
+	}
+
+	ll.mutex.RLock()
+	_, ok := ll.values[value]
+	ll.mutex.RUnlock()
+	if ok {
+		return value
+	}
+
+	ll.mutex.Lock()
+	defer ll.mutex.Unlock()
+	if _, ok := ll.values[value]; ok {
+		return value
+	}
+	if len(ll.values) >= ll.limit {
+		return OverflowLabelValue
+	}
+	ll.values[value] = true
+	return value
+}
+
+// extractTokenLabels returns project and API key labels with applied cardinality limit
+func extractTokenLabels(tokenID string) (projectID, sourceID string) {
+	projectID, sourceID = extractLabels(tokenID)
+	return projectID, apiKeysLabelLimiter.value(sourceID)
+}
+
+// extractDestinationLabels returns project and destination labels with applied cardinality limit
+func extractDestinationLabels(destinationName string) (projectID, destinationID string) {
+	projectID, destinationID = extractLabels(destinationName)
+	return projectID, destinationsLabelLimiter.value(destinationID)
+}
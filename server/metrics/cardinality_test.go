@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelLimiter(t *testing.T) {
+	limiter := newLabelLimiter(2)
+	require.Equal(t, "a", limiter.value("a"))
+	require.Equal(t, "b", limiter.value("b"))
+	require.Equal(t, OverflowLabelValue, limiter.value("c"))
+	require.Equal(t, "a", limiter.value("a"), "already seen values must be kept")
+	require.Equal(t, OverflowLabelValue, limiter.value("d"))
+
+	unlimited := newLabelLimiter(0)
+	for _, value := range []string{"a", "b", "c", "d"} {
+		require.Equal(t, value, unlimited.value(value))
+	}
+
+	var notInitialized *labelLimiter
+	require.Equal(t, "a", notInitialized.value("a"))
+}
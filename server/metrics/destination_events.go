@@ -36,8 +36,9 @@ func SuccessTokenEvent(tokenID, destinationType, destinationName string) {
 
 func SuccessTokenEvents(tokenID, destinationType, destinationName string, value int) {
 	if Enabled() {
-		projectID, destinationID := extractLabels(destinationName)
-		successEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID).Add(float64(value))
+		projectID, destinationID := extractDestinationLabels(destinationName)
+		successEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, apiKeysLabelLimiter.value(tokenID), destinationType, destinationID).Add(float64(value))
+		destinationRowsLoaded.WithLabelValues(projectID, destinationType, destinationID).Add(float64(value))
 	}
 }
 
@@ -51,29 +52,30 @@ func ErrorTokenEvent(tokenID, destinationType, destinationName string) {
 
 func ErrorTokenEvents(tokenID, destinationType, destinationName string, value int) {
 	if Enabled() {
-		projectID, destinationID := extractLabels(destinationName)
-		errorsEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID).Add(float64(value))
+		projectID, destinationID := extractDestinationLabels(destinationName)
+		errorsEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, apiKeysLabelLimiter.value(tokenID), destinationType, destinationID).Add(float64(value))
 	}
 }
 
 func SuccessSourceEvents(sourceType, sourceTap, sourceName, destinationType, destinationName string, value int) {
 	if Enabled() {
-		projectID, destinationID := extractLabels(destinationName)
+		projectID, destinationID := extractDestinationLabels(destinationName)
 		_, sourceID := extractLabels(sourceName)
 		successEvents.WithLabelValues(projectID, sourceType, sourceTap, sourceID, destinationType, destinationID).Add(float64(value))
+		destinationRowsLoaded.WithLabelValues(projectID, destinationType, destinationID).Add(float64(value))
 	}
 }
 
 func SkipTokenEvents(tokenID, destinationType, destinationName string, value int) {
 	if Enabled() {
-		projectID, destinationID := extractLabels(destinationName)
-		skippedEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, tokenID, destinationType, destinationID).Add(float64(value))
+		projectID, destinationID := extractDestinationLabels(destinationName)
+		skippedEvents.WithLabelValues(projectID, TokenSourceType, EmptySourceTap, apiKeysLabelLimiter.value(tokenID), destinationType, destinationID).Add(float64(value))
 	}
 }
 
 func ErrorSourceEvents(sourceType, sourceTap, sourceName, destinationType, destinationName string, value int) {
 	if Enabled() {
-		projectID, destinationID := extractLabels(destinationName)
+		projectID, destinationID := extractDestinationLabels(destinationName)
 		_, sourceID := extractLabels(sourceName)
 		errorsEvents.WithLabelValues(projectID, sourceType, sourceTap, sourceID, destinationType, destinationID).Add(float64(value))
 	}
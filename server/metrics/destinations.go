@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	//ErrorClassProcessing is used for events which failed on mapping, enrichment or transformation
+	ErrorClassProcessing = "processing"
+	//ErrorClassConnection is used for network and connection errors (the events are retried)
+	ErrorClassConnection = "connection"
+	//ErrorClassSystem is used for internal errors
+	ErrorClassSystem = "system"
+	//ErrorClassDestination is used for all other errors returned by destinations (e.g. schema or data errors)
+	ErrorClassDestination = "destination"
+)
+
+var destinationLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	destinationRowsLoaded    *prometheus.CounterVec
+	destinationErrorsByClass *prometheus.CounterVec
+	streamingLag             *prometheus.HistogramVec
+)
+
+func initDestinations() {
+	destinationRowsLoaded = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "rows_loaded",
+	}, destinationLabels)
+	destinationErrorsByClass = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "errors_by_class",
+	}, append(destinationLabels, "class"))
+	streamingLag = NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "streaming_lag_seconds",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600},
+	}, destinationLabels)
+}
+
+// RowsLoaded counts rows which have been written into the destination from all sources
+func RowsLoaded(destinationType, destinationName string, value int) {
+	if Enabled() {
+		projectID, destinationID := extractDestinationLabels(destinationName)
+		destinationRowsLoaded.WithLabelValues(projectID, destinationType, destinationID).Add(float64(value))
+	}
+}
+
+// DestinationErrors counts rows which haven't been written into the destination by error class (see ErrorClass* constants)
+func DestinationErrors(destinationType, destinationName, class string, value int) {
+	if Enabled() {
+		projectID, destinationID := extractDestinationLabels(destinationName)
+		destinationErrorsByClass.WithLabelValues(projectID, destinationType, destinationID, class).Add(float64(value))
+	}
+}
+
+// StreamingLag observes time between event receiving and its dequeuing by the destination streaming worker
+func StreamingLag(destinationType, destinationName string, seconds float64) {
+	if Enabled() {
+		projectID, destinationID := extractDestinationLabels(destinationName)
+		streamingLag.WithLabelValues(projectID, destinationType, destinationID).Observe(seconds)
+	}
+}
@@ -45,12 +45,17 @@ func NewHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus
 
 const Unknown = "unknown"
 
-func Init(exported bool) {
+// Init registers all application metrics. maxLabelValues limits the amount of distinct API keys and destinations
+// label values (0 - unlimited)
+func Init(exported bool, maxLabelValues int) {
 	Exported = exported
 	if Exported {
 		logging.Info("✅ Initializing Prometheus metrics..")
 	}
 
+	apiKeysLabelLimiter = newLabelLimiter(maxLabelValues)
+	destinationsLabelLimiter = newLabelLimiter(maxLabelValues)
+
 	Registry = prometheus.DefaultRegisterer.(*prometheus.Registry)
 
 	initEvents()
@@ -63,6 +68,11 @@ func Init(exported bool) {
 	initUsersRecognitionRedis()
 	initTransform()
 	initStreamEventsQueue()
+	initBatchUploader()
+	initBotFilter()
+	initSampling()
+	initDestinations()
+	initAPIKeys()
 }
 
 func InitRelay(clusterID string, viper *viper.Viper) *Relay {
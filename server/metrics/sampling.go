@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var samplingLabels = []string{"project_id", "source_id"}
+
+var (
+	sampledOutEvents   *prometheus.CounterVec
+	loadShedEvents     *prometheus.CounterVec
+	loadSheddingActive prometheus.Gauge
+)
+
+func initSampling() {
+	sampledOutEvents = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "events",
+		Name:      "sampled_out",
+	}, samplingLabels)
+	loadShedEvents = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "events",
+		Name:      "load_shed",
+	}, samplingLabels)
+	loadSheddingActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "events",
+		Name:      "load_shedding_active",
+	})
+	Registry.MustRegister(loadSheddingActive)
+}
+
+// SampledOutEvents counts events dropped by API key sampling rules
+func SampledOutEvents(tokenID string, value int) {
+	if Enabled() {
+		projectID, sourceID := extractLabels(tokenID)
+		sampledOutEvents.WithLabelValues(projectID, sourceID).Add(float64(value))
+	}
+}
+
+// LoadShedEvents counts low-value events dropped while load shedding is on
+func LoadShedEvents(tokenID string, value int) {
+	if Enabled() {
+		projectID, sourceID := extractLabels(tokenID)
+		loadShedEvents.WithLabelValues(projectID, sourceID).Add(float64(value))
+	}
+}
+
+// LoadShedding sets 1 if load shedding is on and 0 otherwise
+func LoadShedding(active bool) {
+	if Enabled() {
+		if active {
+			loadSheddingActive.Set(1)
+		} else {
+			loadSheddingActive.Set(0)
+		}
+	}
+}
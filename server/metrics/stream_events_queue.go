@@ -9,6 +9,9 @@ var streamEventsQueueLabels = []string{"project_id", "destination_type", "destin
 var (
 	streamEventsQueueSize  *prometheus.GaugeVec
 	streamEventsBufferSize *prometheus.GaugeVec
+	streamEventsOverflow   *prometheus.CounterVec
+	streamEventsOldestAge  *prometheus.GaugeVec
+	streamingCatchUp       *prometheus.GaugeVec
 )
 
 func initStreamEventsQueue() {
@@ -22,6 +25,21 @@ func initStreamEventsQueue() {
 		Subsystem: "destinations",
 		Name:      "events_buffer_size",
 	}, streamEventsQueueLabels)
+	streamEventsOverflow = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "events_queue_overflow",
+	}, []string{"project_id", "destination_type", "destination_id", "policy"})
+	streamEventsOldestAge = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "events_queue_oldest_event_age_seconds",
+	}, streamEventsQueueLabels)
+	streamingCatchUp = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "streaming_catch_up",
+	}, streamEventsQueueLabels)
 }
 
 func SetStreamEventsQueueSize(destinationType, destinationName string, value int) {
@@ -51,3 +69,31 @@ func EnqueuedEvent(destinationType, destinationName string) {
 		streamEventsQueueSize.WithLabelValues(projectID, destinationType, destinationID).Add(1)
 	}
 }
+
+// QueueOverflowEvent counts events which didn't fit into the destination queue and were handled with the overflow policy
+func QueueOverflowEvent(destinationType, destinationName, policy string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		streamEventsOverflow.WithLabelValues(projectID, destinationType, destinationID, policy).Inc()
+	}
+}
+
+// SetStreamEventsOldestAge sets the approximate age of the oldest event in the destination queue
+func SetStreamEventsOldestAge(destinationType, destinationName string, seconds float64) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		streamEventsOldestAge.WithLabelValues(projectID, destinationType, destinationID).Set(seconds)
+	}
+}
+
+// SetStreamingCatchUp sets 1 if the destination streaming workers are in catch-up mode and 0 otherwise
+func SetStreamingCatchUp(destinationType, destinationName string, active bool) {
+	if Enabled() {
+		var value float64
+		if active {
+			value = 1
+		}
+		projectID, destinationID := extractLabels(destinationName)
+		streamingCatchUp.WithLabelValues(projectID, destinationType, destinationID).Set(value)
+	}
+}
@@ -11,9 +11,16 @@ var (
 	transformKeyValueDels *prometheus.CounterVec
 	transformKeyValueSets *prometheus.HistogramVec
 
-	transformErrors *prometheus.CounterVec
+	transformErrors    *prometheus.CounterVec
+	transformExecution *prometheus.HistogramVec
 
 	transformKeyValueErrors *prometheus.CounterVec
+
+	transformKilled *prometheus.CounterVec
+
+	transformUserLogs    *prometheus.CounterVec
+	transformUserMetrics *prometheus.CounterVec
+	transformUserDropped *prometheus.CounterVec
 )
 
 func initTransform() {
@@ -41,12 +48,42 @@ func initTransform() {
 		Name:      "errors",
 	}, transformLabels)
 
+	transformExecution = NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "eventnative",
+		Subsystem: "javascript",
+		Name:      "execution_seconds",
+		Buckets:   []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+	}, transformLabels)
+
 	transformKeyValueErrors = NewCounterVec(prometheus.CounterOpts{
 		Namespace: "eventnative",
 		Subsystem: "javascript",
 		Name:      "redis",
 	}, []string{"error_type"})
 
+	transformKilled = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "javascript",
+		Name:      "killed",
+	}, append(transformLabels, "reason"))
+
+	transformUserLogs = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "javascript",
+		Name:      "user_logs",
+	}, append(transformLabels, "level"))
+
+	transformUserMetrics = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "javascript",
+		Name:      "user_metric",
+	}, append(transformLabels, "name"))
+
+	transformUserDropped = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "javascript",
+		Name:      "user_dropped",
+	}, append(transformLabels, "type"))
 }
 
 func TransformErrors(destinationId string) {
@@ -54,6 +91,15 @@ func TransformErrors(destinationId string) {
 		transformErrors.WithLabelValues(extractLabels(destinationId)).Inc()
 	}
 }
+
+//TransformExecutionTime observes duration of the transformation script execution for one event
+func TransformExecutionTime(destinationId string, seconds float64) {
+	if Enabled() {
+		projectID, destinationID := extractDestinationLabels(destinationId)
+		transformExecution.WithLabelValues(projectID, destinationID).Observe(seconds)
+	}
+}
+
 func TransformKeyValueGet(destinationId string) {
 	if Enabled() {
 		transformKeyValueGets.WithLabelValues(extractLabels(destinationId)).Inc()
@@ -77,3 +123,35 @@ func TransformKeyValueRedisErrors(errorType string) {
 		transformKeyValueErrors.WithLabelValues(errorType).Inc()
 	}
 }
+
+//TransformKilled counts script processes killed because of exceeded limits (reason: out_of_memory or timeout)
+func TransformKilled(destinationId, reason string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationId)
+		transformKilled.WithLabelValues(projectID, destinationID, reason).Inc()
+	}
+}
+
+//TransformUserLog counts log records written by user scripts via $log API
+func TransformUserLog(destinationId, level string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationId)
+		transformUserLogs.WithLabelValues(projectID, destinationID, level).Inc()
+	}
+}
+
+//TransformUserMetric adds value to the user script metric reported via $metric API
+func TransformUserMetric(destinationId, name string, value float64) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationId)
+		transformUserMetrics.WithLabelValues(projectID, destinationID, name).Add(value)
+	}
+}
+
+//TransformUserDropped counts user scripts log records and metrics dropped because of rate limiting (type: log or metric)
+func TransformUserDropped(destinationId, outputType string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationId)
+		transformUserDropped.WithLabelValues(projectID, destinationID, outputType).Inc()
+	}
+}
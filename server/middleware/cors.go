@@ -10,7 +10,9 @@ import (
 )
 
 //Cors handles OPTIONS requests and check if request /event or dynamic event endpoint or static endpoint (/t /s /p)
-//if token ok => check origins - if matched write origin to acao header otherwise don't write it
+//or Segment compatible endpoint (/v1/ - write key might be in the body so origins aren't checked)
+//if token ok => check origins of the API key - if matched (or the API key doesn't have origins) write origin to acao header
+//otherwise returns 403. Requests without Origin header (not from browsers) aren't checked
 //if not returns 401
 func Cors(h http.Handler, isAllowedOriginsFunc func(string) ([]string, bool)) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -21,15 +23,11 @@ func Cors(h http.Handler, isAllowedOriginsFunc func(string) ([]string, bool)) ht
 			origins, ok := isAllowedOriginsFunc(token)
 			reqOrigin := r.Header.Get("Origin")
 			if ok {
-				if len(origins) > 0 {
-					for _, allowedOrigin := range origins {
-						if cors.NewPrefixSuffixRule(allowedOrigin).IsAllowed("", reqOrigin) {
-							w.Header().Add("Access-Control-Allow-Origin", reqOrigin)
-							break
-						}
-					}
-				} else {
+				if isOriginAllowed(origins, r.Host, reqOrigin) {
 					w.Header().Add("Access-Control-Allow-Origin", reqOrigin)
+				} else if reqOrigin != "" {
+					writeCorsError(w, http.StatusForbidden, fmt.Sprintf(ErrOriginNotAllowed, reqOrigin))
+					return
 				}
 			} else {
 				//Unauthorized
@@ -38,14 +36,11 @@ func Cors(h http.Handler, isAllowedOriginsFunc func(string) ([]string, bool)) ht
 					w.WriteHeader(http.StatusOK)
 					return
 				}
-				response := ErrResponse(fmt.Sprintf(ErrTokenNotFound, token), nil)
-				b, _ := json.Marshal(response)
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Write(b)
+				writeCorsError(w, http.StatusUnauthorized, fmt.Sprintf(ErrTokenNotFound, token))
 				return
 			}
 
-		} else if strings.Contains(r.URL.Path, "/p/") || strings.Contains(r.URL.Path, "/s/") || strings.Contains(r.URL.Path, "/t/") {
+		} else if strings.HasPrefix(r.URL.Path, "/v1/") || strings.Contains(r.URL.Path, "/p/") || strings.Contains(r.URL.Path, "/s/") || strings.Contains(r.URL.Path, "/t/") {
 			writeDefaultCorsHeaders(w)
 			w.Header().Add("Access-Control-Allow-Origin", "*")
 		}
@@ -59,6 +54,26 @@ func Cors(h http.Handler, isAllowedOriginsFunc func(string) ([]string, bool)) ht
 	})
 }
 
+//isOriginAllowed returns true if the API key doesn't have origins or reqOrigin matches one of them
+func isOriginAllowed(origins []string, host, reqOrigin string) bool {
+	if len(origins) == 0 {
+		return true
+	}
+	for _, allowedOrigin := range origins {
+		if cors.NewRule(allowedOrigin).IsAllowed(host, reqOrigin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeCorsError(w http.ResponseWriter, code int, msg string) {
+	b, _ := json.Marshal(ErrResponse(msg, nil))
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
 func writeDefaultCorsHeaders(w http.ResponseWriter) {
 	w.Header().Add("Access-Control-Max-Age", "86400")
 	w.Header().Add("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE, PATCH")
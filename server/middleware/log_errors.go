@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"bytes"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jitsucom/jitsu/server/logging"
 )
@@ -12,7 +14,10 @@ type GinErrorBodyWriter struct {
 }
 
 func (gebw *GinErrorBodyWriter) Write(b []byte) (int, error) {
-	gebw.body.Write(b)
+	//only error responses are logged. Don't buffer successful ones (they might be long-living streams)
+	if gebw.Status() >= http.StatusBadRequest {
+		gebw.body.Write(b)
+	}
 	return gebw.ResponseWriter.Write(b)
 }
 
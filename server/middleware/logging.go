@@ -16,7 +16,10 @@ type bufferingLogWriter struct {
 }
 
 func (w bufferingLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	//only error responses are logged. Don't buffer successful ones (they might be long-living streams)
+	if w.Status() >= http.StatusBadRequest {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
 
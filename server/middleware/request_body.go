@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+//RequestBody limits the request body size and decompresses gzip bodies (Content-Encoding: gzip) before passing to main handler.
+//maxBodySize is applied to the compressed and to the decompressed body (protection from gzip bombs). Requests with
+//a greater Content-Length are rejected with 413 without reading, limited bodies are read before passing to main handler
+//and are rejected with 413 as soon as the limit is exceeded. 0 - the body size isn't limited
+func RequestBody(main gin.HandlerFunc, maxBodySize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBodySize > 0 && c.Request.ContentLength > maxBodySize {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrResponse(fmt.Sprintf("Request body size %d bytes exceeds the limit: %d bytes", c.Request.ContentLength, maxBodySize), nil))
+			return
+		}
+
+		var body io.Reader
+		if maxBodySize > 0 {
+			payload, err := readLimited(c.Request.Body, maxBodySize)
+			if err == errBodyTooLarge {
+				c.JSON(http.StatusRequestEntityTooLarge, ErrResponse(fmt.Sprintf("Request body size exceeds the limit: %d bytes", maxBodySize), nil))
+				return
+			} else if err != nil {
+				c.JSON(http.StatusBadRequest, ErrResponse("Error reading HTTP body", err))
+				return
+			}
+			body = bytes.NewReader(payload)
+		}
+
+		if strings.EqualFold(c.Request.Header.Get("Content-Encoding"), "gzip") {
+			if body == nil {
+				body = c.Request.Body
+			}
+			reader, err := gzip.NewReader(body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrResponse("Error reading gzip HTTP body", err))
+				return
+			}
+			defer reader.Close()
+
+			body = reader
+			if maxBodySize > 0 {
+				payload, err := readLimited(reader, maxBodySize)
+				if err == errBodyTooLarge {
+					c.JSON(http.StatusRequestEntityTooLarge, ErrResponse(fmt.Sprintf("Decompressed request body size exceeds the limit: %d bytes", maxBodySize), nil))
+					return
+				} else if err != nil {
+					c.JSON(http.StatusBadRequest, ErrResponse("Error reading gzip HTTP body", err))
+					return
+				}
+				body = bytes.NewReader(payload)
+			}
+			c.Request.Header.Del("Content-Encoding")
+			c.Request.Header.Del("Content-Length")
+			c.Request.ContentLength = -1
+		}
+
+		if body != nil {
+			c.Request.Body = ioutil.NopCloser(body)
+		}
+
+		main(c)
+	}
+}
+
+//readLimited reads the whole reader or returns errBodyTooLarge if it contains more than limit bytes
+func readLimited(reader io.Reader, limit int64) ([]byte, error) {
+	payload, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(payload)) > limit {
+		return nil, errBodyTooLarge
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBody(t *testing.T, body string) []byte {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	_, err := writer.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestRequestBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	echo := func(c *gin.Context) {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	}
+
+	tests := []struct {
+		name             string
+		body             []byte
+		gzip             bool
+		chunked          bool
+		expectedCode     int
+		expectedResponse string
+	}{
+		{
+			"plain body",
+			[]byte(`{"event_type":"pageview"}`),
+			false,
+			false,
+			http.StatusOK,
+			`{"event_type":"pageview"}`,
+		},
+		{
+			"gzip body",
+			gzipBody(t, `{"event_type":"pageview"}`),
+			true,
+			false,
+			http.StatusOK,
+			`{"event_type":"pageview"}`,
+		},
+		{
+			"too large content length",
+			[]byte(strings.Repeat("a", 101)),
+			false,
+			false,
+			http.StatusRequestEntityTooLarge,
+			`{"message":"Request body size 101 bytes exceeds the limit: 100 bytes","error":""}`,
+		},
+		{
+			"too large chunked body",
+			[]byte(strings.Repeat("a", 101)),
+			false,
+			true,
+			http.StatusRequestEntityTooLarge,
+			`{"message":"Request body size exceeds the limit: 100 bytes","error":""}`,
+		},
+		{
+			"too large decompressed body",
+			gzipBody(t, strings.Repeat("a", 1000)),
+			true,
+			false,
+			http.StatusRequestEntityTooLarge,
+			`{"message":"Decompressed request body size exceeds the limit: 100 bytes","error":""}`,
+		},
+		{
+			"body of the limit size",
+			[]byte(strings.Repeat("a", 100)),
+			false,
+			true,
+			http.StatusOK,
+			strings.Repeat("a", 100),
+		},
+		{
+			"malformed gzip body",
+			[]byte("not gzip"),
+			true,
+			false,
+			http.StatusBadRequest,
+			`{"message":"Error reading gzip HTTP body: unexpected EOF","error":""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/api/v1/event", RequestBody(echo, 100))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/event", bytes.NewReader(tt.body))
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			require.Equal(t, tt.expectedCode, recorder.Code)
+			require.Equal(t, tt.expectedResponse, recorder.Body.String())
+		})
+	}
+}
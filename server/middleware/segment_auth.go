@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//SegmentWriteKeyName is a field of Segment HTTP Tracking API payload with the write key (analytics.js)
+const SegmentWriteKeyName = "writeKey"
+
+//SegmentWriteKeyAuth checks Segment write key which is sent:
+//1. as basic auth username (analytics-node and other server libraries)
+//2. in 'writeKey' body field (analytics.js)
+//3. the same ways as Jitsu tokens (see extractToken)
+//write key must be a Jitsu client or server secret. gzip bodies are decompressed
+func SegmentWriteKeyAuth(main gin.HandlerFunc, isAllowedOriginsFuncs ...func(string) ([]string, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := readSegmentBody(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrResponse(err.Error(), nil))
+			return
+		}
+
+		token := extractToken(c.Request)
+		if token == "" {
+			payload := map[string]interface{}{}
+			if err := json.Unmarshal(body, &payload); err == nil {
+				token, _ = payload[SegmentWriteKeyName].(string)
+			}
+		}
+
+		for _, isAllowedOriginsFunc := range isAllowedOriginsFuncs {
+			if _, allowed := isAllowedOriginsFunc(token); allowed {
+				c.Set(TokenName, token)
+				main(c)
+				return
+			}
+		}
+
+		c.JSON(http.StatusUnauthorized, ErrResponse(fmt.Sprintf(ErrTokenNotFound, token), nil))
+	}
+}
+
+//readSegmentBody reads (and decompresses if gzip) the request body and replaces it with the read one
+func readSegmentBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading HTTP body: %v", err)
+	}
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("error reading gzip HTTP body: %v", err)
+		}
+		body, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing gzip HTTP body: %v", err)
+		}
+		r.Header.Del("Content-Encoding")
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
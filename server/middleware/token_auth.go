@@ -13,6 +13,8 @@ const (
 	TokenHeaderName  = "x-auth-token"
 	ErrTokenNotFound = "The token is not found: %s"
 
+	ErrOriginNotAllowed = "Origin %s isn't allowed for the API key. Please add it to the API key origins"
+
 	JitsuAnonymIDCookie   = "__eventn_id"
 	CookiePolicyParameter = "cookie_policy"
 	IPPolicyParameter     = "ip_policy"
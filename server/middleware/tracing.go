@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Tracing starts a server span for each HTTP request. Incoming W3C trace context headers are used as the parent
+// span context. The span context is put into the request context for the handlers
+func Tracing(c *gin.Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unknown"
+	}
+
+	ctx := tracing.ExtractHTTP(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := tracing.StartServer(ctx, fmt.Sprintf("HTTP %s %s", c.Request.Method, route),
+		attribute.String("http.method", c.Request.Method),
+		attribute.String("http.route", route),
+	)
+	defer span.End()
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.status_code", status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
@@ -0,0 +1,203 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/appstatus"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/multiplexing"
+	"github.com/jitsucom/jitsu/server/wal"
+)
+
+const (
+	disconnectQuiesceMs = 1000
+	connectTimeout      = 30 * time.Second
+)
+
+// Bridge subscribes to MQTT topics and passes messages as events of the configured API keys into the events pipeline
+type Bridge struct {
+	config *Config
+	client paho.Client
+
+	binaryDecoder        events.BinaryDecoder
+	writeAheadLogService *wal.Service
+	multiplexingService  *multiplexing.Service
+	eventsCache          *caching.EventsCache
+	processor            events.Processor
+	destinationService   *destinations.Service
+	geoService           *geo.Service
+}
+
+// NewBridge returns configured Bridge. Connection is established in Start
+// binaryDecoder is optional
+func NewBridge(config *Config, defaultClientID string, binaryDecoder events.BinaryDecoder, writeAheadLogService *wal.Service,
+	multiplexingService *multiplexing.Service, eventsCache *caching.EventsCache, processor events.Processor,
+	destinationService *destinations.Service, geoService *geo.Service) (*Bridge, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	bridge := &Bridge{
+		config:               config,
+		binaryDecoder:        binaryDecoder,
+		writeAheadLogService: writeAheadLogService,
+		multiplexingService:  multiplexingService,
+		eventsCache:          eventsCache,
+		processor:            processor,
+		destinationService:   destinationService,
+		geoService:           geoService,
+	}
+
+	clientID := config.ClientID
+	if clientID == "" {
+		clientID = defaultClientIDPrefix + defaultClientID
+	}
+
+	options := paho.NewClientOptions().
+		SetClientID(clientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetCleanSession(config.CleanSession).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOrderMatters(true).
+		SetOnConnectHandler(bridge.subscribe).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logging.Warnf("[mqtt] Connection to the broker has been lost: %v. Reconnecting..", err)
+		})
+	if config.TLSSkipVerify {
+		options.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	}
+	for _, broker := range config.Brokers {
+		options.AddBroker(broker)
+	}
+
+	bridge.client = paho.NewClient(options)
+	return bridge, nil
+}
+
+// Start connects to the broker in background. If the broker isn't available, connection is retried
+func (b *Bridge) Start() {
+	token := b.client.Connect()
+	go func() {
+		if !token.WaitTimeout(connectTimeout) {
+			logging.Warnf("[mqtt] Broker isn't available after %s. Retrying in background..", connectTimeout)
+			return
+		}
+		if token.Error() != nil {
+			logging.Errorf("[mqtt] Error connecting to the broker: %v", token.Error())
+		}
+	}()
+}
+
+// subscribe subscribes to all configured topics. It is called on every (re)connection
+func (b *Bridge) subscribe(client paho.Client) {
+	logging.Infof("[mqtt] Connected to the broker. Subscribing to %d topics..", len(b.config.Topics))
+	for _, topicConfig := range b.config.Topics {
+		topicConfig := topicConfig
+		subscriptionTopic := b.config.subscriptionTopic(topicConfig.Topic)
+		token := client.Subscribe(subscriptionTopic, b.config.qos(), func(_ paho.Client, message paho.Message) {
+			b.handle(topicConfig, message.Topic(), message.Payload())
+		})
+		go func() {
+			if token.WaitTimeout(connectTimeout) && token.Error() != nil {
+				logging.Errorf("[mqtt] Error subscribing to topic [%s]: %v", subscriptionTopic, token.Error())
+			}
+		}()
+	}
+}
+
+// handle parses message and accepts events. Malformed messages are put into the events cache
+func (b *Bridge) handle(topicConfig *TopicConfig, topic string, payload []byte) {
+	token := topicConfig.APIKey
+	tokenID := appconfig.Instance.AuthorizationService.GetTokenID(token)
+	if tokenID == "" {
+		logging.Warnf("[mqtt] Message from topic [%s] is skipped: API key configured for topic filter [%s] isn't found", topic, topicConfig.Topic)
+		return
+	}
+
+	destinationStorages := b.destinationService.GetDestinations(tokenID)
+	cachingDisabled := false
+	for _, destinationStorage := range destinationStorages {
+		if destinationStorage.IsCachingDisabled() {
+			cachingDisabled = true
+			break
+		}
+	}
+
+	eventsArray, err := parseMessage(topicConfig, b.binaryDecoder, topic, payload)
+	if err != nil {
+		logging.Warnf("[mqtt] Error parsing message from topic [%s]: %v", topic, err)
+		b.eventsCache.RawErrorEvent(cachingDisabled, tokenID, payload, err)
+		return
+	}
+	if len(eventsArray) == 0 {
+		return
+	}
+
+	// API key ip handling: is applied to events source_ip before events are cached and archived
+	geoResolver := b.geoService.GetGlobalGeoResolver()
+	if len(destinationStorages) > 0 {
+		geoResolver = b.geoService.GetGeoResolver(destinationStorages[0].GetGeoResolverID())
+	}
+	enrichment.APIKeyIPHandlingStep(appconfig.Instance.AuthorizationService.GetIPHandling(token), "", geoResolver, eventsArray...)
+
+	reqContext := &events.RequestContext{
+		HashedAnonymousID:   fmt.Sprintf("%x", md5.Sum([]byte(topic))),
+		CookiesLawCompliant: true,
+	}
+
+	//put all events to write-ahead-log if idle
+	if appstatus.Instance.Idle.Load() {
+		b.cacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, nil)
+		b.writeAheadLogService.Consume(eventsArray, reqContext, token, b.processor.Type())
+		return
+	}
+
+	if _, err := b.multiplexingService.AcceptRequest(context.Background(), b.processor, reqContext, token, eventsArray); err != nil {
+		if err == multiplexing.ErrNoDestinations {
+			b.cacheRawEvents(eventsArray, cachingDisabled, tokenID, fmt.Errorf("No destination is configured for token [%q] (or only staged ones)", token), nil)
+			return
+		}
+
+		b.cacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, err)
+		logging.Warnf("[mqtt] Error accepting message from topic [%s]: %v", topic, err)
+		return
+	}
+
+	b.cacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, nil)
+}
+
+func (b *Bridge) cacheRawEvents(eventsArray []events.Event, cachingDisabled bool, tokenID string, skip error, err error) {
+	for _, e := range eventsArray {
+		serializedPayload, _ := json.Marshal(e)
+		if err != nil {
+			b.eventsCache.RawErrorEvent(cachingDisabled, tokenID, serializedPayload, err)
+			continue
+		}
+
+		skipMsg := ""
+		if skip != nil {
+			skipMsg = skip.Error()
+		}
+		b.eventsCache.RawEvent(cachingDisabled, tokenID, serializedPayload, skipMsg)
+	}
+}
+
+// Close disconnects from the broker
+func (b *Bridge) Close() error {
+	b.client.Disconnect(disconnectQuiesceMs)
+	return nil
+}
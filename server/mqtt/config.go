@@ -0,0 +1,90 @@
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultQoS            = 1
+	defaultClientIDPrefix = "jitsu-"
+)
+
+// Config is a configuration of MQTT bridge: broker connection and topics mapping
+type Config struct {
+	Enabled  bool     `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Brokers  []string `mapstructure:"brokers" json:"brokers,omitempty" yaml:"brokers,omitempty"`
+	ClientID string   `mapstructure:"client_id" json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	Username string   `mapstructure:"username" json:"username,omitempty" yaml:"username,omitempty"`
+	Password string   `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty"`
+	// CleanSession: if false, the broker keeps subscriptions and queues QoS 1/2 messages while Jitsu is disconnected
+	CleanSession  bool `mapstructure:"clean_session" json:"clean_session,omitempty" yaml:"clean_session,omitempty"`
+	TLSSkipVerify bool `mapstructure:"tls_skip_verify" json:"tls_skip_verify,omitempty" yaml:"tls_skip_verify,omitempty"`
+	// SharedGroup is a shared subscription group ($share/<group>/<topic>). It is used in cluster deployments
+	// for delivering every message only to one Jitsu node
+	SharedGroup string         `mapstructure:"shared_group" json:"shared_group,omitempty" yaml:"shared_group,omitempty"`
+	QoS         *byte          `mapstructure:"qos" json:"qos,omitempty" yaml:"qos,omitempty"`
+	Topics      []*TopicConfig `mapstructure:"topics" json:"topics,omitempty" yaml:"topics,omitempty"`
+}
+
+// TopicConfig maps MQTT topic filter to the API key and event type
+type TopicConfig struct {
+	// Topic is a topic filter. Wildcards (+ and #) are supported
+	Topic string `mapstructure:"topic" json:"topic,omitempty" yaml:"topic,omitempty"`
+	// APIKey is a server or client secret of the API key
+	APIKey string `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	// EventType is set to events without event_type. Default value is the message topic
+	EventType string `mapstructure:"event_type" json:"event_type,omitempty" yaml:"event_type,omitempty"`
+	// TopicLevels are field names for message topic levels (e.g. [null, device_id] for devices/+/telemetry).
+	// Empty values are skipped
+	TopicLevels []string `mapstructure:"topic_levels" json:"topic_levels,omitempty" yaml:"topic_levels,omitempty"`
+	// ContentType is used for decoding Avro or Protobuf payloads (see server.protocols). JSON by default
+	ContentType string `mapstructure:"content_type" json:"content_type,omitempty" yaml:"content_type,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	if len(c.Brokers) == 0 {
+		return errors.New("mqtt.brokers is required")
+	}
+
+	if c.QoS != nil && *c.QoS > 2 {
+		return fmt.Errorf("mqtt.qos must be 0, 1 or 2. Got: %d", *c.QoS)
+	}
+
+	if len(c.Topics) == 0 {
+		return errors.New("mqtt.topics is required")
+	}
+
+	for i, topic := range c.Topics {
+		if topic.Topic == "" {
+			return fmt.Errorf("mqtt.topics[%d].topic is required", i)
+		}
+		if topic.APIKey == "" {
+			return fmt.Errorf("mqtt.topics[%d].api_key is required", i)
+		}
+		if strings.HasPrefix(topic.Topic, "$share/") {
+			return fmt.Errorf("mqtt.topics[%d].topic mustn't be a shared subscription. Use mqtt.shared_group instead", i)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) qos() byte {
+	if c.QoS == nil {
+		return defaultQoS
+	}
+
+	return *c.QoS
+}
+
+// subscriptionTopic returns topic filter with shared subscription prefix if configured
+func (c *Config) subscriptionTopic(topic string) string {
+	if c.SharedGroup == "" {
+		return topic
+	}
+
+	return "$share/" + c.SharedGroup + "/" + topic
+}
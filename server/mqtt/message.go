@@ -0,0 +1,96 @@
+package mqtt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/events"
+)
+
+const (
+	srcValue       = "mqtt"
+	topicKey       = "mqtt_topic"
+	eventTypeKey   = "event_type"
+	scalarValueKey = "value"
+)
+
+// parseMessage converts MQTT message payload into events:
+// JSON object is an event, JSON array is an array of events, JSON scalar (e.g. sensor value) is put into the value field
+// of an event. Avro and Protobuf payloads are decoded with binaryDecoder if topic content_type is configured
+func parseMessage(topicConfig *TopicConfig, binaryDecoder events.BinaryDecoder, topic string, payload []byte) ([]events.Event, error) {
+	objects, err := decodePayload(topicConfig, binaryDecoder, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	topicLevels := strings.Split(topic, "/")
+	eventsArray := make([]events.Event, 0, len(objects))
+	for _, object := range objects {
+		event := events.Event(object)
+		if _, ok := event[events.SrcKey]; !ok {
+			event[events.SrcKey] = srcValue
+		}
+		event[topicKey] = topic
+		if _, ok := event[eventTypeKey]; !ok {
+			if topicConfig.EventType != "" {
+				event[eventTypeKey] = topicConfig.EventType
+			} else {
+				event[eventTypeKey] = topic
+			}
+		}
+
+		for i, field := range topicConfig.TopicLevels {
+			if field != "" && i < len(topicLevels) {
+				event[field] = topicLevels[i]
+			}
+		}
+
+		eventsArray = append(eventsArray, event)
+	}
+
+	return eventsArray, nil
+}
+
+func decodePayload(topicConfig *TopicConfig, binaryDecoder events.BinaryDecoder, payload []byte) ([]map[string]interface{}, error) {
+	if topicConfig.ContentType != "" && binaryDecoder != nil {
+		objects, ok, err := binaryDecoder.Decode(topicConfig.ContentType, payload)
+		if ok {
+			if err != nil {
+				return nil, fmt.Errorf("error decoding %s payload: %v", topicConfig.ContentType, err)
+			}
+			return objects, nil
+		}
+	}
+
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 {
+		return nil, errors.New("empty payload")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	switch payload[0] {
+	case '{':
+		object := map[string]interface{}{}
+		if err := decoder.Decode(&object); err != nil {
+			return nil, fmt.Errorf("error parsing JSON payload: %v", err)
+		}
+		return []map[string]interface{}{object}, nil
+	case '[':
+		var objects []map[string]interface{}
+		if err := decoder.Decode(&objects); err != nil {
+			return nil, fmt.Errorf("error parsing JSON payload: %v", err)
+		}
+		return objects, nil
+	default:
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("error parsing JSON payload: %v", err)
+		}
+		return []map[string]interface{}{{scalarValueKey: value}}, nil
+	}
+}
@@ -0,0 +1,125 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		topicConfig *TopicConfig
+		topic       string
+		payload     string
+		expected    []events.Event
+		expectedErr string
+	}{
+		{
+			"JSON object",
+			&TopicConfig{Topic: "devices/+/telemetry", EventType: "telemetry", TopicLevels: []string{"", "device_id"}},
+			"devices/sensor-1/telemetry",
+			`{"temperature": 21.5}`,
+			[]events.Event{{"temperature": json.Number("21.5"), "src": "mqtt", "mqtt_topic": "devices/sensor-1/telemetry", "event_type": "telemetry", "device_id": "sensor-1"}},
+			"",
+		},
+		{
+			"JSON array with own event types",
+			&TopicConfig{Topic: "devices/#"},
+			"devices/sensor-1",
+			`[{"event_type": "alarm", "src": "gateway"}, {"battery": 30}]`,
+			[]events.Event{
+				{"event_type": "alarm", "src": "gateway", "mqtt_topic": "devices/sensor-1"},
+				{"battery": json.Number("30"), "src": "mqtt", "mqtt_topic": "devices/sensor-1", "event_type": "devices/sensor-1"},
+			},
+			"",
+		},
+		{
+			"JSON scalar",
+			&TopicConfig{Topic: "devices/+/temperature", TopicLevels: []string{"", "device_id", ""}},
+			"devices/sensor-2/temperature",
+			` 22 `,
+			[]events.Event{{"value": json.Number("22"), "src": "mqtt", "mqtt_topic": "devices/sensor-2/temperature", "event_type": "devices/sensor-2/temperature", "device_id": "sensor-2"}},
+			"",
+		},
+		{
+			"empty payload",
+			&TopicConfig{Topic: "devices/#"},
+			"devices/sensor-1",
+			"  ",
+			nil,
+			"empty payload",
+		},
+		{
+			"malformed JSON",
+			&TopicConfig{Topic: "devices/#"},
+			"devices/sensor-1",
+			`{"temperature":`,
+			nil,
+			"error parsing JSON payload: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parseMessage(tt.topicConfig, nil, tt.topic, []byte(tt.payload))
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	qos := byte(3)
+	tests := []struct {
+		name        string
+		config      *Config
+		expectedErr string
+	}{
+		{
+			"valid",
+			&Config{Brokers: []string{"tcp://localhost:1883"}, Topics: []*TopicConfig{{Topic: "devices/#", APIKey: "s2s.key"}}},
+			"",
+		},
+		{
+			"no brokers",
+			&Config{Topics: []*TopicConfig{{Topic: "devices/#", APIKey: "s2s.key"}}},
+			"mqtt.brokers is required",
+		},
+		{
+			"wrong qos",
+			&Config{Brokers: []string{"tcp://localhost:1883"}, QoS: &qos, Topics: []*TopicConfig{{Topic: "devices/#", APIKey: "s2s.key"}}},
+			"mqtt.qos must be 0, 1 or 2. Got: 3",
+		},
+		{
+			"no api key",
+			&Config{Brokers: []string{"tcp://localhost:1883"}, Topics: []*TopicConfig{{Topic: "devices/#"}}},
+			"mqtt.topics[0].api_key is required",
+		},
+		{
+			"shared topic",
+			&Config{Brokers: []string{"tcp://localhost:1883"}, Topics: []*TopicConfig{{Topic: "$share/jitsu/devices/#", APIKey: "s2s.key"}}},
+			"mqtt.topics[0].topic mustn't be a shared subscription. Use mqtt.shared_group instead",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+
+	config := &Config{SharedGroup: "jitsu"}
+	require.Equal(t, "$share/jitsu/devices/#", config.subscriptionTopic("devices/#"))
+	require.Equal(t, byte(defaultQoS), config.qos())
+}
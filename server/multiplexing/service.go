@@ -1,63 +1,185 @@
 package multiplexing
 
 import (
+	"context"
 	"errors"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/botfilter"
 	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/delivery"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/quota"
+	"github.com/jitsucom/jitsu/server/routing"
+	"github.com/jitsucom/jitsu/server/sampling"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/tracing"
+	"github.com/jitsucom/jitsu/server/validation"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+//MultiplexingComponent is a logging component of events multiplexing
+const MultiplexingComponent = "multiplexing"
+
 var (
 	ErrNoDestinations = errors.New("No destination is configured for token")
+
+	logger = logging.Component(MultiplexingComponent)
 )
 
 //Service is a service for accepting, multiplexing events and sending to consumers
 type Service struct {
 	destinationService *destinations.Service
+	botFilter          *botfilter.Filter
+	validators         *validation.Cache
+	routers            *routing.Cache
+	samplers           *sampling.Cache
+	loadShedder        *sampling.LoadShedder
 }
 
-//NewService returns configured Service instance
-func NewService(destinationService *destinations.Service) *Service {
+//NewService returns configured Service instance. botFilter and loadShedder are optional
+func NewService(destinationService *destinations.Service, botFilter *botfilter.Filter, loadShedder *sampling.LoadShedder) *Service {
 	return &Service{
 		destinationService: destinationService,
+		botFilter:          botFilter,
+		validators:         validation.NewCache(),
+		routers:            routing.NewCache(),
+		samplers:           sampling.NewCache(),
+		loadShedder:        loadShedder,
 	}
 }
 
 //AcceptRequest multiplexes input events, enriches with context and sends to consumers
-func (s *Service) AcceptRequest(processor events.Processor, reqContext *events.RequestContext, token string, eventsArray []events.Event) ([]map[string]interface{}, error) {
+//returns *validation.Error if the request contains invalid events and the API key validation mode is reject
+//returns *quota.ExceededError if the request exceeds a hard events quota of the API key or its project
+//Trace context of each event span is written into the event (see tracing.EventContextKey) if tracing is enabled
+func (s *Service) AcceptRequest(ctx context.Context, processor events.Processor, reqContext *events.RequestContext, token string, eventsArray []events.Event) ([]map[string]interface{}, error) {
 	tokenID := appconfig.Instance.AuthorizationService.GetTokenID(token)
 	destinationStorages := s.destinationService.GetDestinations(tokenID)
 	if len(destinationStorages) == 0 {
 		counters.SkipPushSourceEvents(tokenID, 1)
 		return nil, ErrNoDestinations
 	}
-	extras := make([]map[string]interface{}, 0)
+
+	//** Validation **
+	//events are validated before enrichment: JSON Schema describes the payload which is sent by the client
+	var router *routing.Router
+	var sampler *sampling.Sampler
+	var tokenPriority string
+	if tokenObj := appconfig.Instance.AuthorizationService.GetToken(token); tokenObj != nil {
+		if err := s.validators.Get(tokenID, tokenObj.Validation).Apply(eventsArray); err != nil {
+			counters.SkipPushSourceEvents(tokenID, int64(len(eventsArray)))
+			return nil, err
+		}
+		router = s.routers.Get(tokenID, tokenObj.Routing)
+		sampler = s.samplers.Get(tokenID, tokenObj.Sampling)
+		tokenPriority = tokenObj.Priority
+	}
+
+	//** Sampling and load shedding **
+	//events are dropped before quotas checking and enrichment for saving resources during traffic spikes
+	keptEvents := make([]events.Event, 0, len(eventsArray))
 	for _, payload := range eventsArray {
+		if s.loadShedder.Drop(tokenPriority, payload) {
+			counters.SkipPushSourceEvents(tokenID, 1)
+			metrics.LoadShedEvents(tokenID, 1)
+			_, eventSpan := tracing.Start(ctx, "jitsu.event", attribute.String("jitsu.token_id", tokenID), attribute.Bool("jitsu.load_shed", true))
+			eventSpan.End()
+			continue
+		}
+		if !sampler.Keep(payload) {
+			counters.SkipPushSourceEvents(tokenID, 1)
+			metrics.SampledOutEvents(tokenID, 1)
+			_, eventSpan := tracing.Start(ctx, "jitsu.event", attribute.String("jitsu.token_id", tokenID), attribute.Bool("jitsu.sampled_out", true))
+			eventSpan.End()
+			continue
+		}
+		keptEvents = append(keptEvents, payload)
+	}
+
+	//** Quotas **
+	//only events left after sampling and load shedding are charged
+	if err := quota.Check(tokenID, len(keptEvents)); err != nil {
+		counters.SkipPushSourceEvents(tokenID, int64(len(keptEvents)))
+		return nil, err
+	}
+
+	extras := make([]map[string]interface{}, 0)
+	for _, payload := range keptEvents {
+		eventCtx, eventSpan := tracing.Start(ctx, "jitsu.event", attribute.String("jitsu.token_id", tokenID))
+
 		//** Context enrichment **
 		//Note: we assume that destinations under 1 token can't have different unique ID configuration (JS SDK 2.0 or an old one)
+		_, enrichmentSpan := tracing.Start(eventCtx, "jitsu.enrichment")
 		enrichment.ContextEnrichmentStep(payload, token, reqContext, processor, destinationStorages[0].GetUniqueIDField())
 
+		//** Bot and spam filtering **
+		accepted := s.botFilter.Apply(tokenID, payload)
+		enrichmentSpan.End()
+		if !accepted {
+			counters.SkipPushSourceEvents(tokenID, 1)
+			eventSpan.SetAttributes(attribute.Bool("jitsu.bot_filtered", true))
+			eventSpan.End()
+			continue
+		}
+
 		//Persisted cache
 		//extract unique identifier
 		eventID := destinationStorages[0].GetUniqueIDField().Extract(payload)
 		if eventID == "" {
-			logging.SystemErrorf("[%s] Empty extracted unique identifier in: %s", destinationStorages[0].ID(), payload.DebugString())
+			logger.WithAPIKey(tokenID).WithDestination(destinationStorages[0].ID()).
+				SystemErrorf("[%s] Empty extracted unique identifier in: %s", destinationStorages[0].ID(), payload.DebugString())
+		}
+
+		//** Routing **
+		//rules are evaluated before transformation. Not routed events are skipped by destinations
+		route, routed := router.Route(payload)
+		if !routed {
+			counters.SkipPushSourceEvents(tokenID, 1)
+			eventSpan.SetAttributes(attribute.Bool("jitsu.routing_dropped", true))
+			eventSpan.End()
+			continue
 		}
 
 		//** Multiplexing **
-		consumers := s.destinationService.GetConsumers(tokenID)
-		synchronousStorages := s.destinationService.GetSynchronousStorages(tokenID)
+		var consumers []events.Consumer
+		var synchronousStorages []storages.StorageProxy
+		var destinationIDs []string
+		if route == nil {
+			consumers = s.destinationService.GetConsumers(tokenID)
+			synchronousStorages = s.destinationService.GetSynchronousStorages(tokenID)
+			for _, destinationProxy := range destinationStorages {
+				destinationIDs = append(destinationIDs, destinationProxy.ID())
+			}
+		} else {
+			consumers, synchronousStorages, destinationIDs = s.routedDestinations(tokenID, destinationStorages, route)
+			if len(destinationIDs) == 0 {
+				logger.WithAPIKey(tokenID).WithEventID(eventID).Warnf("Event [%s] is routed by rule [%s] to destinations %v which aren't linked to the API key. The event will be skipped", eventID, route.Rule, route.Destinations)
+				counters.SkipPushSourceEvents(tokenID, 1)
+				eventSpan.End()
+				continue
+			}
+			eventSpan.SetAttributes(attribute.String("jitsu.routing_rule", route.Rule))
+			routing.Apply(payload, &routing.Route{Rule: route.Rule, Destinations: destinationIDs, Table: route.Table})
+		}
 		if len(consumers) == 0 && len(synchronousStorages) == 0 {
 			counters.SkipPushSourceEvents(tokenID, 1)
+			tracing.End(eventSpan, ErrNoDestinations)
 			return nil, ErrNoDestinations
 		}
 
+		//** Delivery tracking **
+		delivery.Received(eventID, tokenID, destinationIDs)
+
+		tracing.InjectEvent(eventCtx, payload)
+		_, enqueueSpan := tracing.Start(eventCtx, "jitsu.enqueue", attribute.Int("jitsu.consumers", len(consumers)))
 		for _, consumer := range consumers {
 			consumer.Consume(payload, tokenID)
 		}
+		enqueueSpan.End()
 
 		for _, sc := range synchronousStorages {
 			synchronousStorage, ok := sc.Get()
@@ -69,15 +191,38 @@ func (s *Service) AcceptRequest(processor events.Processor, reqContext *events.R
 			}
 		}
 
-		var destinationIDs []string
-		for _, destinationProxy := range destinationStorages {
-			destinationIDs = append(destinationIDs, destinationProxy.ID())
-		}
 		//Retroactive users recognition
 		processor.Postprocess(payload, eventID, destinationIDs, tokenID)
 
 		counters.SuccessPushSourceEvents(tokenID, 1)
+		eventSpan.End()
 	}
 
 	return extras, nil
 }
+
+//routedDestinations returns consumers, synchronous storages and IDs of the route destinations linked to the API key
+func (s *Service) routedDestinations(tokenID string, destinationStorages []storages.StorageProxy, route *routing.Route) ([]events.Consumer, []storages.StorageProxy, []string) {
+	routed := map[string]bool{}
+	for _, id := range s.destinationService.ExpandMirrors(route.Destinations) {
+		routed[id] = true
+	}
+
+	var destinationIDs []string
+	linked := map[string]bool{}
+	for _, destinationProxy := range destinationStorages {
+		if routed[destinationProxy.ID()] {
+			destinationIDs = append(destinationIDs, destinationProxy.ID())
+			linked[destinationProxy.ID()] = true
+		}
+	}
+
+	var synchronousStorages []storages.StorageProxy
+	for _, sc := range s.destinationService.GetSynchronousStorages(tokenID) {
+		if linked[sc.ID()] {
+			synchronousStorages = append(synchronousStorages, sc)
+		}
+	}
+
+	return s.destinationService.GetRoutedConsumers(tokenID, linked), synchronousStorages, destinationIDs
+}
@@ -32,6 +32,10 @@ var (
 			"client_id":     "google_play.client_id",
 			"client_secret": "google_play.client_secret",
 		},
+		"google_sheets": {
+			"client_id":     "google_sheets.client_id",
+			"client_secret": "google_sheets.client_secret",
+		},
 		"tap-google-sheets": {
 			"client_id":     "google_sheets.client_id",
 			"client_secret": "google_sheets.client_secret",
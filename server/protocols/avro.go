@@ -0,0 +1,189 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	// confluentMagicByte is the first byte of messages in Confluent Schema Registry wire format
+	confluentMagicByte    = 0
+	confluentHeaderSize   = 5
+	schemaRegistryTimeout = 10 * time.Second
+)
+
+// ocfMagic is the header of Avro Object Container Files
+var ocfMagic = []byte{'O', 'b', 'j', 1}
+
+// avroDecoder decodes Avro Object Container Files (with an embedded schema) and
+// messages in Confluent Schema Registry wire format (magic byte + schema ID + binary datum)
+type avroDecoder struct {
+	schemaRegistryURL string
+	client            *http.Client
+
+	mutex  sync.RWMutex
+	codecs map[uint32]*goavro.Codec
+}
+
+func newAvroDecoder(config *AvroConfig) *avroDecoder {
+	decoder := &avroDecoder{
+		client: &http.Client{Timeout: schemaRegistryTimeout},
+		codecs: map[uint32]*goavro.Codec{},
+	}
+	if config != nil {
+		decoder.schemaRegistryURL = strings.TrimSuffix(config.SchemaRegistryURL, "/")
+	}
+
+	return decoder
+}
+
+// decode returns Avro records as JSON objects
+func (ad *avroDecoder) decode(body []byte) ([]map[string]interface{}, error) {
+	if bytes.HasPrefix(body, ocfMagic) {
+		return ad.decodeOCF(body)
+	}
+
+	if body[0] == confluentMagicByte {
+		return ad.decodeConfluent(body)
+	}
+
+	return nil, errors.New("Avro body must be an Object Container File or a message in Schema Registry wire format")
+}
+
+func (ad *avroDecoder) decodeOCF(body []byte) ([]map[string]interface{}, error) {
+	reader, err := goavro.NewOCFReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error reading Avro Object Container File: %v", err)
+	}
+
+	// Object Container File codec decodes unions as {"type": value}. Standard JSON codec is used for converting into plain JSON
+	codec, err := goavro.NewCodecForStandardJSONFull(reader.Codec().Schema())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Avro schema: %v", err)
+	}
+
+	var objects []map[string]interface{}
+	for reader.Scan() {
+		native, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("error reading Avro record: %v", err)
+		}
+
+		object, err := toObject(codec, native)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	if err := reader.Err(); err != nil {
+		return nil, fmt.Errorf("error reading Avro Object Container File: %v", err)
+	}
+
+	return objects, nil
+}
+
+// decodeConfluent decodes one or several concatenated messages in Schema Registry wire format
+func (ad *avroDecoder) decodeConfluent(body []byte) ([]map[string]interface{}, error) {
+	if ad.schemaRegistryURL == "" {
+		return nil, errors.New("server.protocols.avro.schema_registry_url must be configured for decoding Avro messages in Schema Registry wire format")
+	}
+
+	var objects []map[string]interface{}
+	for len(body) > 0 {
+		if len(body) < confluentHeaderSize || body[0] != confluentMagicByte {
+			return nil, errors.New("malformed Avro message: Schema Registry wire format header is expected")
+		}
+
+		schemaID := binary.BigEndian.Uint32(body[1:confluentHeaderSize])
+		codec, err := ad.getCodec(schemaID)
+		if err != nil {
+			return nil, err
+		}
+
+		native, remaining, err := codec.NativeFromBinary(body[confluentHeaderSize:])
+		if err != nil {
+			return nil, fmt.Errorf("error decoding Avro message with schema ID %d: %v", schemaID, err)
+		}
+
+		object, err := toObject(codec, native)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+		body = remaining
+	}
+
+	return objects, nil
+}
+
+// getCodec returns cached codec or loads the schema from Schema Registry. Schemas are immutable by ID
+func (ad *avroDecoder) getCodec(schemaID uint32) (*goavro.Codec, error) {
+	ad.mutex.RLock()
+	codec, ok := ad.codecs[schemaID]
+	ad.mutex.RUnlock()
+	if ok {
+		return codec, nil
+	}
+
+	schema, err := ad.loadSchema(schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err = goavro.NewCodecForStandardJSONFull(schema)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Avro schema with ID %d: %v", schemaID, err)
+	}
+
+	ad.mutex.Lock()
+	ad.codecs[schemaID] = codec
+	ad.mutex.Unlock()
+
+	return codec, nil
+}
+
+func (ad *avroDecoder) loadSchema(schemaID uint32) (string, error) {
+	response, err := ad.client.Get(fmt.Sprintf("%s/schemas/ids/%d", ad.schemaRegistryURL, schemaID))
+	if err != nil {
+		return "", fmt.Errorf("error loading Avro schema with ID %d from Schema Registry: %v", schemaID, err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading Schema Registry response: %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error loading Avro schema with ID %d from Schema Registry: HTTP code = %d, body: %s", schemaID, response.StatusCode, string(body))
+	}
+
+	payload := &struct {
+		Schema string `json:"schema"`
+	}{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return "", fmt.Errorf("error parsing Schema Registry response: %v", err)
+	}
+
+	return payload.Schema, nil
+}
+
+// toObject converts Avro native value into a JSON object
+func toObject(codec *goavro.Codec, native interface{}) (map[string]interface{}, error) {
+	textual, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return nil, fmt.Errorf("error converting Avro record into JSON: %v", err)
+	}
+
+	return unmarshalObject(textual)
+}
@@ -0,0 +1,125 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Avro and Protobuf media types
+var (
+	avroMediaTypes     = map[string]bool{"avro/binary": true, "application/avro": true, "application/vnd.apache.avro+binary": true}
+	protobufMediaTypes = map[string]bool{"application/x-protobuf": true, "application/protobuf": true, "application/vnd.google.protobuf": true}
+)
+
+const (
+	// protobufMessageParameter is a media type parameter with Protobuf message full name e.g.
+	// application/x-protobuf; proto=com.example.Event
+	protobufMessageParameter = "proto"
+	// protobufEncodingParameter=delimited means that body is a stream of size-delimited messages
+	protobufEncodingParameter = "encoding"
+	protobufDelimitedEncoding = "delimited"
+)
+
+// Config is a configuration of binary protocols of events API
+type Config struct {
+	Avro     *AvroConfig     `mapstructure:"avro" json:"avro,omitempty" yaml:"avro,omitempty"`
+	Protobuf *ProtobufConfig `mapstructure:"protobuf" json:"protobuf,omitempty" yaml:"protobuf,omitempty"`
+}
+
+// AvroConfig is a configuration of Avro decoding
+type AvroConfig struct {
+	// SchemaRegistryURL is used for loading schemas of messages in Schema Registry wire format
+	SchemaRegistryURL string `mapstructure:"schema_registry_url" json:"schema_registry_url,omitempty" yaml:"schema_registry_url,omitempty"`
+}
+
+// ProtobufConfig is a configuration of Protobuf decoding
+type ProtobufConfig struct {
+	// DescriptorSets are paths of FileDescriptorSet files with events message types
+	DescriptorSets []string `mapstructure:"descriptor_sets" json:"descriptor_sets,omitempty" yaml:"descriptor_sets,omitempty"`
+}
+
+// Decoder decodes Avro and Protobuf HTTP bodies into JSON objects according to the Content-Type header
+type Decoder struct {
+	avro     *avroDecoder
+	protobuf *protobufDecoder
+}
+
+// NewDecoder returns configured Decoder or error if Protobuf descriptor sets can't be loaded
+func NewDecoder(config *Config) (*Decoder, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	protobuf, err := newProtobufDecoder(config.Protobuf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Decoder{avro: newAvroDecoder(config.Avro), protobuf: protobuf}, nil
+}
+
+// Decode returns decoded objects and true if the content type is Avro or Protobuf
+// returns false if the content type isn't a binary one (body should be parsed as JSON)
+func (d *Decoder) Decode(contentType string, body []byte) ([]map[string]interface{}, bool, error) {
+	if d == nil || contentType == "" {
+		return nil, false, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	switch {
+	case avroMediaTypes[mediaType]:
+		if len(body) == 0 {
+			return nil, true, errors.New("empty Avro body")
+		}
+		objects, err := d.avro.decode(body)
+		return objects, true, err
+	case protobufMediaTypes[mediaType]:
+		if len(body) == 0 {
+			return nil, true, errors.New("empty Protobuf body")
+		}
+		delimited := strings.EqualFold(params[protobufEncodingParameter], protobufDelimitedEncoding)
+		objects, err := d.protobuf.decode(body, params[protobufMessageParameter], delimited)
+		return objects, true, err
+	default:
+		return nil, false, nil
+	}
+}
+
+func unmarshalValue(payload []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("error parsing decoded JSON: %v", err)
+	}
+
+	return value, nil
+}
+
+func unmarshalObject(payload []byte) (map[string]interface{}, error) {
+	value, err := unmarshalValue(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("decoded value must be an object. Got: %T", value)
+	}
+
+	return object, nil
+}
+
+func timestampFromUnix(seconds, nanos int64) time.Time {
+	return time.Unix(seconds, nanos).UTC()
+}
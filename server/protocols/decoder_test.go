@@ -0,0 +1,224 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const testAvroSchema = `{
+  "type": "record",
+  "name": "Event",
+  "fields": [
+    {"name": "event_type", "type": "string"},
+    {"name": "user_id", "type": ["null", "long"], "default": null},
+    {"name": "revenue", "type": "double"}
+  ]
+}`
+
+func TestDecodeNotBinary(t *testing.T) {
+	decoder, err := NewDecoder(nil)
+	require.NoError(t, err)
+
+	for _, contentType := range []string{"", "application/json", "text/plain;charset=UTF-8", "malformed;;"} {
+		_, ok, err := decoder.Decode(contentType, []byte(`{}`))
+		require.NoError(t, err)
+		require.False(t, ok, contentType)
+	}
+
+	var nilDecoder *Decoder
+	_, ok, _ := nilDecoder.Decode("avro/binary", []byte{1})
+	require.False(t, ok)
+}
+
+func TestDecodeAvroOCF(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer, err := goavro.NewOCFWriter(goavro.OCFConfig{W: buf, Schema: testAvroSchema})
+	require.NoError(t, err)
+	require.NoError(t, writer.Append([]map[string]interface{}{
+		{"event_type": "purchase", "user_id": goavro.Union("long", int64(123)), "revenue": 9.99},
+		{"event_type": "pageview", "user_id": nil, "revenue": 0.0},
+	}))
+
+	decoder, err := NewDecoder(nil)
+	require.NoError(t, err)
+
+	objects, ok, err := decoder.Decode("avro/binary", buf.Bytes())
+	require.True(t, ok)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{
+		{"event_type": "purchase", "user_id": json.Number("123"), "revenue": json.Number("9.99")},
+		{"event_type": "pageview", "user_id": nil, "revenue": json.Number("0")},
+	}, objects)
+
+	_, _, err = decoder.Decode("application/avro", []byte("not avro"))
+	require.Error(t, err)
+}
+
+func TestDecodeAvroSchemaRegistry(t *testing.T) {
+	requests := 0
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/schemas/ids/7" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		payload, _ := json.Marshal(map[string]string{"schema": testAvroSchema})
+		w.Write(payload)
+	}))
+	defer registry.Close()
+
+	codec, err := goavro.NewCodec(testAvroSchema)
+	require.NoError(t, err)
+	message := func(schemaID uint32, event map[string]interface{}) []byte {
+		header := make([]byte, confluentHeaderSize)
+		binary.BigEndian.PutUint32(header[1:], schemaID)
+		datum, err := codec.BinaryFromNative(nil, event)
+		require.NoError(t, err)
+		return append(header, datum...)
+	}
+
+	decoder, err := NewDecoder(&Config{Avro: &AvroConfig{SchemaRegistryURL: registry.URL + "/"}})
+	require.NoError(t, err)
+
+	body := append(message(7, map[string]interface{}{"event_type": "a", "revenue": 1.5}),
+		message(7, map[string]interface{}{"event_type": "b", "user_id": goavro.Union("long", int64(1)), "revenue": 2.0})...)
+	objects, ok, err := decoder.Decode("application/vnd.apache.avro+binary", body)
+	require.True(t, ok)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{
+		{"event_type": "a", "user_id": nil, "revenue": json.Number("1.5")},
+		{"event_type": "b", "user_id": json.Number("1"), "revenue": json.Number("2")},
+	}, objects)
+	require.Equal(t, 1, requests, "schema must be cached")
+
+	_, _, err = decoder.Decode("avro/binary", message(8, map[string]interface{}{"event_type": "a", "revenue": 1.5}))
+	require.Error(t, err)
+
+	withoutRegistry, err := NewDecoder(nil)
+	require.NoError(t, err)
+	_, _, err = withoutRegistry.Decode("avro/binary", body)
+	require.Error(t, err)
+}
+
+func TestDecodeProtobufStruct(t *testing.T) {
+	event, err := structpb.NewStruct(map[string]interface{}{
+		"event_type": "pageview",
+		"user":       map[string]interface{}{"id": "u1"},
+	})
+	require.NoError(t, err)
+	payload, err := proto.Marshal(event)
+	require.NoError(t, err)
+
+	decoder, err := NewDecoder(nil)
+	require.NoError(t, err)
+
+	objects, ok, err := decoder.Decode("application/x-protobuf", payload)
+	require.True(t, ok)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{"event_type": "pageview", "user": map[string]interface{}{"id": "u1"}}}, objects)
+
+	_, _, err = decoder.Decode("application/x-protobuf; proto=unknown.Event", payload)
+	require.Error(t, err)
+}
+
+func TestDecodeProtobufDescriptorSet(t *testing.T) {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("events.proto"),
+		Package:    proto.String("example"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Platform"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("IOS"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Event"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("event_type"), JsonName: proto.String("eventType"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("user_id"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("platform"), Number: proto.Int32(3), Type: descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(), TypeName: proto.String(".example.Platform"), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("tags"), Number: proto.Int32(4), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()},
+				{Name: proto.String("created_at"), Number: proto.Int32(5), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".google.protobuf.Timestamp"), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+			},
+		}},
+	}
+	descriptorSet, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	require.NoError(t, err)
+	descriptorSetPath := filepath.Join(t.TempDir(), "events.pb")
+	require.NoError(t, ioutil.WriteFile(descriptorSetPath, descriptorSet, 0644))
+
+	decoder, err := NewDecoder(&Config{Protobuf: &ProtobufConfig{DescriptorSets: []string{descriptorSetPath}}})
+	require.NoError(t, err)
+
+	// build messages with the same descriptor
+	fileDescriptor, err := protodesc.NewFile(file, resolver{decoder.protobuf.files})
+	require.NoError(t, err)
+	messageDescriptor := fileDescriptor.Messages().ByName("Event")
+	message := func(eventType string, userID int64, platform int32, tags ...string) []byte {
+		m := dynamicpb.NewMessage(messageDescriptor)
+		fields := messageDescriptor.Fields()
+		m.Set(fields.ByName("event_type"), protoreflect.ValueOfString(eventType))
+		m.Set(fields.ByName("user_id"), protoreflect.ValueOfInt64(userID))
+		m.Set(fields.ByName("platform"), protoreflect.ValueOfEnum(protoreflect.EnumNumber(platform)))
+		list := m.Mutable(fields.ByName("tags")).List()
+		for _, tag := range tags {
+			list.Append(protoreflect.ValueOfString(tag))
+		}
+		createdAt, err := proto.Marshal(timestamppb.New(timestampFromUnix(1600000000, 0)))
+		require.NoError(t, err)
+		createdAtMessage := m.Mutable(fields.ByName("created_at")).Message()
+		require.NoError(t, proto.Unmarshal(createdAt, createdAtMessage.Interface()))
+		payload, err := proto.Marshal(m)
+		require.NoError(t, err)
+		return payload
+	}
+
+	first := message("purchase", 9007199254740993, 1, "a", "b")
+	objects, ok, err := decoder.Decode("application/x-protobuf; proto=example.Event", first)
+	require.True(t, ok)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{
+		"event_type": "purchase",
+		"user_id":    int64(9007199254740993),
+		"platform":   "IOS",
+		"tags":       []interface{}{"a", "b"},
+		"created_at": "2020-09-13T12:26:40.000000Z",
+	}}, objects)
+
+	second := message("pageview", 1, 0)
+	var delimited []byte
+	delimited = protowire.AppendBytes(delimited, first)
+	delimited = protowire.AppendBytes(delimited, second)
+	objects, ok, err = decoder.Decode(`application/vnd.google.protobuf; proto="example.Event"; encoding=delimited`, delimited)
+	require.True(t, ok)
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	require.Equal(t, "pageview", objects[1]["event_type"])
+	require.NotContains(t, objects[1], "tags", "unpopulated fields must be skipped")
+
+	_, _, err = decoder.Decode("application/x-protobuf; proto=example.Event; encoding=delimited", delimited[:len(delimited)-1])
+	require.Error(t, err)
+
+	_, err = NewDecoder(&Config{Protobuf: &ProtobufConfig{DescriptorSets: []string{filepath.Join(t.TempDir(), "unknown.pb")}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package protocols
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	//registers well-known types (google.protobuf.Struct is the default message type)
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const defaultProtobufMessage = "google.protobuf.Struct"
+
+// protobufDecoder decodes Protobuf messages described in descriptor sets (or well-known types) into JSON objects
+type protobufDecoder struct {
+	files *protoregistry.Files
+}
+
+func newProtobufDecoder(config *ProtobufConfig) (*protobufDecoder, error) {
+	files := &protoregistry.Files{}
+	if config != nil {
+		for _, path := range config.DescriptorSets {
+			if err := registerDescriptorSet(files, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &protobufDecoder{files: files}, nil
+}
+
+// registerDescriptorSet loads FileDescriptorSet (protoc --include_imports --descriptor_set_out=events.pb events.proto)
+func registerDescriptorSet(files *protoregistry.Files, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading Protobuf descriptor set [%s]: %v", path, err)
+	}
+
+	descriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(content, descriptorSet); err != nil {
+		return fmt.Errorf("error parsing Protobuf descriptor set [%s]: %v", path, err)
+	}
+
+	for _, fileProto := range descriptorSet.File {
+		if _, err := files.FindFileByPath(fileProto.GetName()); err == nil {
+			continue
+		}
+
+		file, err := protodesc.NewFile(fileProto, resolver{files})
+		if err != nil {
+			return fmt.Errorf("error building Protobuf file descriptor [%s] from [%s]: %v", fileProto.GetName(), path, err)
+		}
+		if err := files.RegisterFile(file); err != nil {
+			return fmt.Errorf("error registering Protobuf file descriptor [%s] from [%s]: %v", fileProto.GetName(), path, err)
+		}
+	}
+
+	return nil
+}
+
+// decode returns decoded messages. If delimited is true, body is a stream of messages each prefixed with its varint size
+func (pd *protobufDecoder) decode(body []byte, messageName string, delimited bool) ([]map[string]interface{}, error) {
+	if messageName == "" {
+		messageName = defaultProtobufMessage
+	}
+
+	descriptor, err := pd.findMessage(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, err
+	}
+
+	if !delimited {
+		object, err := pd.decodeMessage(descriptor, body)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]interface{}{object}, nil
+	}
+
+	var objects []map[string]interface{}
+	for len(body) > 0 {
+		size, n := protowire.ConsumeVarint(body)
+		if n < 0 || uint64(len(body)-n) < size {
+			return nil, fmt.Errorf("malformed delimited Protobuf body: %v", protowire.ParseError(n))
+		}
+
+		object, err := pd.decodeMessage(descriptor, body[n:n+int(size)])
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+		body = body[n+int(size):]
+	}
+
+	return objects, nil
+}
+
+func (pd *protobufDecoder) findMessage(name protoreflect.FullName) (protoreflect.MessageDescriptor, error) {
+	descriptor, err := resolver{pd.files}.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown Protobuf message type [%s]. Message types must be described in server.protocols.protobuf.descriptor_sets", name)
+	}
+
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("[%s] isn't a Protobuf message type", name)
+	}
+
+	return messageDescriptor, nil
+}
+
+func (pd *protobufDecoder) decodeMessage(descriptor protoreflect.MessageDescriptor, payload []byte) (map[string]interface{}, error) {
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return nil, fmt.Errorf("error decoding Protobuf message [%s]: %v", descriptor.FullName(), err)
+	}
+
+	value, err := messageValue(message)
+	if err != nil {
+		return nil, err
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Protobuf message [%s] must be decoded into JSON object. Got: %T", descriptor.FullName(), value)
+	}
+
+	return object, nil
+}
+
+// messageValue converts Protobuf message into JSON value. Field names are taken from .proto files,
+// 64-bit integers are kept as numbers (unlike Protobuf JSON mapping), google.protobuf.Timestamp is converted into ISO string
+func messageValue(message protoreflect.Message) (interface{}, error) {
+	descriptor := message.Descriptor()
+	switch descriptor.FullName() {
+	case "google.protobuf.Timestamp":
+		fields := descriptor.Fields()
+		seconds := message.Get(fields.ByName("seconds")).Int()
+		nanos := message.Get(fields.ByName("nanos")).Int()
+		return timestamp.ToISOFormat(timestampFromUnix(seconds, nanos)), nil
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue",
+		"google.protobuf.Duration", "google.protobuf.FieldMask", "google.protobuf.Empty":
+		payload, err := protojson.Marshal(message.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("error converting [%s] into JSON: %v", descriptor.FullName(), err)
+		}
+		return unmarshalValue(payload)
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue", "google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value", "google.protobuf.BoolValue", "google.protobuf.StringValue",
+		"google.protobuf.BytesValue":
+		field := descriptor.Fields().ByName("value")
+		return fieldValue(field, message.Get(field))
+	}
+
+	object := map[string]interface{}{}
+	var rangeErr error
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		var converted interface{}
+		switch {
+		case field.IsList():
+			list := value.List()
+			array := make([]interface{}, 0, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				element, err := fieldValue(field, list.Get(i))
+				if err != nil {
+					rangeErr = err
+					return false
+				}
+				array = append(array, element)
+			}
+			converted = array
+		case field.IsMap():
+			mapObject := map[string]interface{}{}
+			value.Map().Range(func(key protoreflect.MapKey, mapValue protoreflect.Value) bool {
+				element, err := fieldValue(field.MapValue(), mapValue)
+				if err != nil {
+					rangeErr = err
+					return false
+				}
+				mapObject[key.String()] = element
+				return true
+			})
+			converted = mapObject
+		default:
+			element, err := fieldValue(field, value)
+			if err != nil {
+				rangeErr = err
+				return false
+			}
+			converted = element
+		}
+
+		object[string(field.Name())] = converted
+		return rangeErr == nil
+	})
+
+	return object, rangeErr
+}
+
+// fieldValue converts a singular Protobuf value of the field kind into JSON value
+func fieldValue(field protoreflect.FieldDescriptor, value protoreflect.Value) (interface{}, error) {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return value.Bool(), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return value.Int(), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return value.Uint(), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		float := value.Float()
+		if math.IsNaN(float) || math.IsInf(float, 0) {
+			return nil, nil
+		}
+		return float, nil
+	case protoreflect.StringKind:
+		return value.String(), nil
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(value.Bytes()), nil
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name()), nil
+		}
+		return int64(value.Enum()), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageValue(value.Message())
+	default:
+		return nil, fmt.Errorf("unsupported Protobuf field [%s] kind: %s", field.FullName(), field.Kind())
+	}
+}
+
+// resolver looks up descriptors in loaded descriptor sets and then in well-known types
+type resolver struct {
+	files *protoregistry.Files
+}
+
+func (r resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(path); err == nil {
+		return file, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if descriptor, err := r.files.FindDescriptorByName(name); err == nil {
+		return descriptor, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
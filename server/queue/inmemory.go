@@ -2,8 +2,13 @@ package queue
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
+//inMemoryPollTimeout is a wait timeout of InMemory.Poll
+const inMemoryPollTimeout = 100 * time.Millisecond
+
 var (
 	ErrQueueEmpty = errors.New("queue is empty")
 )
@@ -45,6 +50,27 @@ func (im *InMemory) Pop() (interface{}, error) {
 	}
 }
 
+//Poll dequeues an element (if exist) or waits inMemoryPollTimeout for the next element. Returns ErrQueueEmpty if there is no elements
+func (im *InMemory) Poll() (interface{}, error) {
+	select {
+	case <-im.closed:
+		return nil, ErrQueueClosed
+	default:
+	}
+
+	value, err := im.linkedQueue.TryDequeue()
+	if err != ErrQueueEmpty {
+		return value, err
+	}
+
+	select {
+	case <-im.closed:
+		return nil, ErrQueueClosed
+	case <-time.After(inMemoryPollTimeout):
+		return im.linkedQueue.TryDequeue()
+	}
+}
+
 //Size returns the number of enqueued elements
 func (im *InMemory) Size() int64 {
 	return int64(im.linkedQueue.GetSize())
@@ -58,8 +84,11 @@ func (im *InMemory) Type() string {
 	return InMemoryType
 }
 
+//Close closes the queue. Returns err if the queue has unprocessed elements (they are lost)
 func (im *InMemory) Close() error {
 	close(im.closed)
-	im.linkedQueue.Close()
+	if remaining := im.linkedQueue.CloseAndDrain(); len(remaining) > 0 {
+		return fmt.Errorf("in-memory queue has been closed with %d unprocessed elements. Use persistent (Redis) queue for keeping events between restarts", len(remaining))
+	}
 	return nil
 }
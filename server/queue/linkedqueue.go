@@ -143,6 +143,26 @@ func (c *ConcurrentLinkedQueue) Dequeue() (interface{}, error) {
 	return data, err
 }
 
+//TryDequeue returns an element without waiting or ErrQueueEmpty if the queue is empty
+func (c *ConcurrentLinkedQueue) TryDequeue() (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		return nil, ErrQueueClosed
+	}
+	if c.backend.isEmpty() {
+		return nil, ErrQueueEmpty
+	}
+
+	data, err := c.backend.pop()
+
+	//signal notFull
+	c.notFull.Signal()
+
+	return data, err
+}
+
 func (c *ConcurrentLinkedQueue) GetSize() uint32 {
 	c.lock.Lock()
 	size := c.backend.size
@@ -163,6 +183,26 @@ func (c *ConcurrentLinkedQueue) Close() {
 	c.lock.Unlock()
 }
 
+//CloseAndDrain closes the queue and returns all remaining elements in order
+func (c *ConcurrentLinkedQueue) CloseAndDrain() []interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+	var remaining []interface{}
+	for !c.backend.isEmpty() {
+		data, err := c.backend.pop()
+		if err != nil {
+			break
+		}
+		remaining = append(remaining, data)
+	}
+	c.notFull.Broadcast()
+	c.notEmpty.Broadcast()
+
+	return remaining
+}
+
 //NewConcurrentLinkedQueue Creates a new queue
 func NewConcurrentLinkedQueue(maxSize uint32) *ConcurrentLinkedQueue {
 	queue := ConcurrentLinkedQueue{}
@@ -37,6 +37,20 @@ func TestLinkedQueueSingleThread(t *testing.T) {
 	}
 }
 
+func TestLinkedQueueCloseAndDrain(t *testing.T) {
+	queue := NewConcurrentLinkedQueue(10)
+	queue.Enqueue(1)
+	queue.Enqueue(2)
+	queue.Enqueue(3)
+
+	assert.Equal(t, []interface{}{1, 2, 3}, queue.CloseAndDrain())
+	assert.Equal(t, uint32(0), queue.GetSize())
+	assert.Equal(t, ErrQueueClosed, queue.Enqueue(4))
+	_, err := queue.Dequeue()
+	assert.Equal(t, ErrQueueClosed, err)
+	assert.Empty(t, queue.CloseAndDrain())
+}
+
 func TestLinkedQueueMultiThread(t *testing.T) {
 	tests := []struct {
 		producersCount  int
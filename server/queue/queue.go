@@ -6,8 +6,9 @@ import (
 )
 
 const (
-	RedisType    = "redis"
-	InMemoryType = "inmemory"
+	RedisType        = "redis"
+	RedisStreamsType = "redis_streams"
+	InMemoryType     = "inmemory"
 )
 
 var (
@@ -22,3 +23,25 @@ type Queue interface {
 	BufferSize() int64
 	Type() string
 }
+
+//PollingQueue is a Queue which supports a single blocking read attempt
+type PollingQueue interface {
+	Queue
+	//Poll returns an element or ErrQueueEmpty if there are no elements during the queue wait timeout
+	Poll() (interface{}, error)
+}
+
+//AckQueue is a Queue which removes popped elements only after acknowledgement.
+//Elements which haven't been acknowledged (e.g. the consumer has crashed) are delivered again
+type AckQueue interface {
+	Queue
+	//PopWithAck returns an element and a func which acknowledges it. The func must be called after the element is processed
+	PopWithAck() (interface{}, func(), error)
+}
+
+//AckPollingQueue is an AckQueue which supports a single blocking read attempt
+type AckPollingQueue interface {
+	AckQueue
+	//PollWithAck works as PollingQueue.Poll and returns an acknowledgement func as PopWithAck does
+	PollWithAck() (interface{}, func(), error)
+}
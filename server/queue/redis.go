@@ -8,6 +8,7 @@ import (
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/safego"
+	"sync"
 	"time"
 )
 
@@ -17,6 +18,7 @@ const (
 
 	eventsQueueKeyPrefix      = "events_queue:%s#%s"
 	defaultWaitTimeoutSeconds = 1
+	flushBatchSize            = 1000
 )
 
 //redis key [variables] - description
@@ -37,6 +39,8 @@ type Redis struct {
 	errorMetrics *meta.ErrorMetrics
 
 	bufferQueue *ConcurrentLinkedQueue
+	//bufferProcessing is released when processBuffer has pushed the last dequeued element after closing
+	bufferProcessing sync.WaitGroup
 
 	closed chan struct{}
 }
@@ -59,6 +63,7 @@ func NewRedis(namespace, identifier string, redisPool *meta.RedisPool, serializa
 		bufferQueue:               NewConcurrentLinkedQueue(1_000_000),
 		closed:                    make(chan struct{}),
 	}
+	r.bufferProcessing.Add(1)
 	safego.RunWithRestart(r.processBuffer)
 	return r
 }
@@ -81,23 +86,27 @@ func (r *Redis) processBuffer() {
 		v, err := r.bufferQueue.Dequeue()
 		if err != nil {
 			if err == ErrQueueClosed {
+				r.bufferProcessing.Done()
 				return
 			}
 			logging.SystemErrorf("Redis queue %s Error dequeueing from buffer queue: %v", r.identifier, err)
 			time.Sleep(10 * time.Second)
+			continue
 		}
 	Cycle:
 		for {
+			err = r.rpush(v.(string))
+			if err == nil {
+				break Cycle
+			}
+
 			select {
 			case <-r.closed:
+				//the element has been already dequeued: it is lost if the last attempt has failed
+				logging.Errorf("Redis queue %s lost 1 buffered element on closing: %v", r.identifier, err)
+				r.bufferProcessing.Done()
 				return
-			default:
-				err = r.rpush(v.(string))
-				if err != nil {
-					time.Sleep(10 * time.Second)
-				} else {
-					break Cycle
-				}
+			case <-time.After(10 * time.Second):
 			}
 		}
 	}
@@ -105,26 +114,32 @@ func (r *Redis) processBuffer() {
 
 func (r *Redis) Pop() (interface{}, error) {
 	for {
-		select {
-		case <-r.closed:
-			return nil, ErrQueueClosed
-		default:
-			value, err := r.blpop()
-			if err != nil {
-				if err == ErrQueueEmpty {
-					continue
-				}
-
-				return nil, err
-			}
+		model, err := r.Poll()
+		if err == ErrQueueEmpty {
+			continue
+		}
 
-			model := r.serializationModelBuilder()
-			if err := json.Unmarshal([]byte(value), model); err != nil {
-				return nil, fmt.Errorf("error deserializing %v into %T: %v", value, model, err)
-			}
+		return model, err
+	}
+}
+
+//Poll waits for an element during wait timeout (BLPOP). Returns ErrQueueEmpty if there is no elements
+func (r *Redis) Poll() (interface{}, error) {
+	select {
+	case <-r.closed:
+		return nil, ErrQueueClosed
+	default:
+		value, err := r.blpop()
+		if err != nil {
+			return nil, err
+		}
 
-			return model, nil
+		model := r.serializationModelBuilder()
+		if err := json.Unmarshal([]byte(value), model); err != nil {
+			return nil, fmt.Errorf("error deserializing %v into %T: %v", value, model, err)
 		}
+
+		return model, nil
 	}
 }
 
@@ -153,10 +168,30 @@ func (r *Redis) Type() string {
 	return RedisType
 }
 
-//Close doesn't close sharedPool
+//Close stops accepting new elements and flushes buffered elements into Redis in order. Doesn't close sharedPool
 func (r *Redis) Close() error {
 	close(r.closed)
-	r.bufferQueue.Close()
+	remaining := r.bufferQueue.CloseAndDrain()
+	r.bufferProcessing.Wait()
+
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	flushed := 0
+	for start := 0; start < len(remaining); start += flushBatchSize {
+		end := start + flushBatchSize
+		if end > len(remaining) {
+			end = len(remaining)
+		}
+
+		if err := r.rpush(remaining[start:end]...); err != nil {
+			return fmt.Errorf("Redis queue %s flushed %d of %d buffered elements on closing, the rest are lost: %v", r.identifier, flushed, len(remaining), err)
+		}
+		flushed = end
+	}
+
+	logging.Infof("Redis queue %s flushed %d buffered elements on closing", r.identifier, flushed)
 	return nil
 }
 
@@ -181,11 +216,11 @@ func (r *Redis) blpop() (string, error) {
 	return redis.String(v[1], nil)
 }
 
-func (r *Redis) rpush(value string) error {
+func (r *Redis) rpush(values ...interface{}) error {
 	conn := r.sharedPool.Get()
 	defer conn.Close()
 
-	_, err := conn.Do("RPUSH", r.queueKey, value)
+	_, err := conn.Do("RPUSH", append([]interface{}{r.queueKey}, values...)...)
 	if err != nil {
 		if err == redis.ErrNil {
 			return nil
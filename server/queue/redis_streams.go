@@ -0,0 +1,319 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"go.uber.org/atomic"
+)
+
+const (
+	eventsStreamKeyPrefix = "events_stream:%s#%s"
+	streamConsumerGroup   = "jitsu"
+	streamPayloadField    = "payload"
+
+	//pending messages of dead consumers are claimed after this timeout
+	streamClaimIdleTimeout = 5 * time.Minute
+	streamClaimInterval    = time.Minute
+)
+
+//redis key [variables] - description
+//** Events stream**
+//events_stream:destination#$destinationID - stream with destination event JSON's (consumer group: jitsu)
+
+//RedisStreams is a queue implementation based on Redis Streams with one consumer group
+//all server replicas with the same Redis share the stream. Every message is read by only one replica (XREADGROUP)
+//and is acknowledged and deleted after processing (see PopWithAck). Messages which were read by a dead replica
+//(not acknowledged) are claimed by other replicas after streamClaimIdleTimeout, so messages are delivered at least once
+type RedisStreams struct {
+	identifier                string
+	streamKey                 string
+	consumerName              string
+	serializationModelBuilder func() interface{}
+
+	blockTimeoutMs int
+
+	sharedPool   *meta.RedisPool
+	errorMetrics *meta.ErrorMetrics
+
+	//lastClaimTime is unix nano time of the last claiming dead consumers messages
+	lastClaimTime *atomic.Int64
+	closed        chan struct{}
+}
+
+//NewRedisStreams returns configured RedisStreams queue instance and creates consumer group if it doesn't exist
+func NewRedisStreams(namespace, identifier, consumerName string, redisPool *meta.RedisPool, serializationModelBuilder func() interface{},
+	redisReadTimeout time.Duration) (Queue, error) {
+	//block timeout should be less than read timeout
+	blockTimeoutMs := int(redisReadTimeout.Milliseconds() * 3 / 10)
+	if blockTimeoutMs == 0 {
+		blockTimeoutMs = defaultWaitTimeoutSeconds * 1000
+	}
+
+	rs := &RedisStreams{
+		identifier:                identifier,
+		streamKey:                 fmt.Sprintf(eventsStreamKeyPrefix, namespace, identifier),
+		consumerName:              consumerName,
+		serializationModelBuilder: serializationModelBuilder,
+		blockTimeoutMs:            blockTimeoutMs,
+		sharedPool:                redisPool,
+		errorMetrics:              meta.NewErrorMetrics(metrics.EventsRedisErrors),
+		lastClaimTime:             atomic.NewInt64(0),
+		closed:                    make(chan struct{}),
+	}
+
+	if err := rs.createConsumerGroup(); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+func (rs *RedisStreams) Push(v interface{}) error {
+	select {
+	case <-rs.closed:
+		return ErrQueueClosed
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("error serializing %v into json: %v", v, err)
+		}
+
+		conn := rs.sharedPool.Get()
+		defer conn.Close()
+
+		if _, err := conn.Do("XADD", rs.streamKey, "*", streamPayloadField, string(b)); err != nil {
+			rs.errorMetrics.NoticeError(err)
+			return err
+		}
+
+		return nil
+	}
+}
+
+//Pop returns the next message and acknowledges it right away. Use PopWithAck for at least once delivery
+func (rs *RedisStreams) Pop() (interface{}, error) {
+	model, ack, err := rs.PopWithAck()
+	if err != nil {
+		return nil, err
+	}
+
+	ack()
+	return model, nil
+}
+
+//PopWithAck waits for the next message. The message is kept in the consumer group pending entries list until ack func is called
+func (rs *RedisStreams) PopWithAck() (interface{}, func(), error) {
+	for {
+		model, ack, err := rs.PollWithAck()
+		if err == ErrQueueEmpty {
+			continue
+		}
+
+		return model, ack, err
+	}
+}
+
+//Poll waits for a message during block timeout (XREADGROUP) and acknowledges it right away. Returns ErrQueueEmpty if there is no messages
+func (rs *RedisStreams) Poll() (interface{}, error) {
+	model, ack, err := rs.PollWithAck()
+	if err != nil {
+		return nil, err
+	}
+
+	ack()
+	return model, nil
+}
+
+//PollWithAck waits for a message during block timeout (XREADGROUP). Returns ErrQueueEmpty if there is no messages.
+//The message is acknowledged and deleted from the stream only when ack func is called
+func (rs *RedisStreams) PollWithAck() (interface{}, func(), error) {
+	select {
+	case <-rs.closed:
+		return nil, nil, ErrQueueClosed
+	default:
+		id, value, err := rs.read()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		model := rs.serializationModelBuilder()
+		if err := json.Unmarshal([]byte(value), model); err != nil {
+			//malformed message would be delivered again and again
+			rs.ack(id)
+			return nil, nil, fmt.Errorf("error deserializing %v into %T: %v", value, model, err)
+		}
+
+		return model, func() { rs.ack(id) }, nil
+	}
+}
+
+//read returns message of dead consumer (if it is time to claim) or a new message
+func (rs *RedisStreams) read() (string, string, error) {
+	//messages are claimed one by one until there are no more messages of dead consumers
+	if now := time.Now().UnixNano(); now-rs.lastClaimTime.Load() > streamClaimInterval.Nanoseconds() {
+		id, value, err := rs.claim()
+		if err == nil {
+			return id, value, nil
+		}
+		if err != ErrQueueEmpty {
+			logging.Warnf("Redis stream %s error claiming pending messages: %v", rs.identifier, err)
+		}
+		rs.lastClaimTime.Store(now)
+	}
+
+	conn := rs.sharedPool.Get()
+	defer conn.Close()
+
+	reply, err := conn.Do("XREADGROUP", "GROUP", streamConsumerGroup, rs.consumerName, "COUNT", 1,
+		"BLOCK", rs.blockTimeoutMs, "STREAMS", rs.streamKey, ">")
+	if err != nil {
+		rs.errorMetrics.NoticeError(err)
+		return "", "", err
+	}
+	if reply == nil {
+		return "", "", ErrQueueEmpty
+	}
+
+	//[[streamKey, [[id, [field, value]]]]]
+	streams, err := redis.Values(reply, nil)
+	if err != nil || len(streams) == 0 {
+		return "", "", fmt.Errorf("malformed redis response: %v", reply)
+	}
+	stream, err := redis.Values(streams[0], nil)
+	if err != nil || len(stream) != 2 {
+		return "", "", fmt.Errorf("malformed redis response: %v", reply)
+	}
+	messages, err := redis.Values(stream[1], nil)
+	if err != nil {
+		return "", "", fmt.Errorf("malformed redis response: %v", reply)
+	}
+	if len(messages) == 0 {
+		return "", "", ErrQueueEmpty
+	}
+
+	return parseStreamMessage(messages[0])
+}
+
+//claim takes one message from dead consumer (pending longer than streamClaimIdleTimeout)
+func (rs *RedisStreams) claim() (string, string, error) {
+	conn := rs.sharedPool.Get()
+	defer conn.Close()
+
+	reply, err := redis.Values(conn.Do("XAUTOCLAIM", rs.streamKey, streamConsumerGroup, rs.consumerName,
+		streamClaimIdleTimeout.Milliseconds(), "0", "COUNT", 1))
+	if err != nil {
+		if err == redis.ErrNil {
+			return "", "", ErrQueueEmpty
+		}
+		return "", "", err
+	}
+
+	//[nextID, [[id, [field, value]]], ...]
+	if len(reply) < 2 {
+		return "", "", fmt.Errorf("malformed redis response: %v", reply)
+	}
+	messages, err := redis.Values(reply[1], nil)
+	if err != nil {
+		return "", "", fmt.Errorf("malformed redis response: %v", reply)
+	}
+	if len(messages) == 0 {
+		return "", "", ErrQueueEmpty
+	}
+
+	return parseStreamMessage(messages[0])
+}
+
+//ack acknowledges and deletes message from the stream
+func (rs *RedisStreams) ack(id string) {
+	conn := rs.sharedPool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("XACK", rs.streamKey, streamConsumerGroup, id); err != nil {
+		rs.errorMetrics.NoticeError(err)
+		logging.SystemErrorf("Redis stream %s error acknowledging message %s: %v", rs.identifier, id, err)
+		return
+	}
+	if _, err := conn.Do("XDEL", rs.streamKey, id); err != nil {
+		rs.errorMetrics.NoticeError(err)
+		logging.Errorf("Redis stream %s error deleting message %s: %v", rs.identifier, id, err)
+	}
+}
+
+func (rs *RedisStreams) createConsumerGroup() error {
+	conn := rs.sharedPool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("XGROUP", "CREATE", rs.streamKey, streamConsumerGroup, "0", "MKSTREAM"); err != nil {
+		if strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return nil
+		}
+
+		rs.errorMetrics.NoticeError(err)
+		return fmt.Errorf("error creating redis stream %s consumer group: %v", rs.streamKey, err)
+	}
+
+	return nil
+}
+
+func (rs *RedisStreams) Size() int64 {
+	conn := rs.sharedPool.Get()
+	defer conn.Close()
+
+	size, err := redis.Int64(conn.Do("XLEN", rs.streamKey))
+	if err != nil {
+		if err == redis.ErrNil {
+			return 0
+		}
+
+		rs.errorMetrics.NoticeError(err)
+		return -1
+	}
+
+	return size
+}
+
+//BufferSize always returns 0 because messages are written to the stream directly
+func (rs *RedisStreams) BufferSize() int64 {
+	return 0
+}
+
+func (rs *RedisStreams) Type() string {
+	return RedisStreamsType
+}
+
+//Close doesn't close sharedPool
+func (rs *RedisStreams) Close() error {
+	select {
+	case <-rs.closed:
+	default:
+		close(rs.closed)
+	}
+	return nil
+}
+
+//parseStreamMessage returns id and payload from [id, [field, value, ...]] message
+func parseStreamMessage(message interface{}) (string, string, error) {
+	fields, err := redis.Values(message, nil)
+	if err != nil || len(fields) != 2 {
+		return "", "", fmt.Errorf("malformed redis stream message: %v", message)
+	}
+
+	id, err := redis.String(fields[0], nil)
+	if err != nil {
+		return "", "", fmt.Errorf("malformed redis stream message id: %v", fields[0])
+	}
+
+	values, err := redis.StringMap(fields[1], nil)
+	if err != nil {
+		return "", "", fmt.Errorf("malformed redis stream message %s fields: %v", id, err)
+	}
+
+	return id, values[streamPayloadField], nil
+}
@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/stretchr/testify/require"
+)
+
+type streamTestEvent struct {
+	ID string `json:"id"`
+}
+
+func newTestRedisStreams(t *testing.T, server *miniredis.Miniredis, consumerName string) *RedisStreams {
+	port, err := strconv.Atoi(server.Port())
+	require.NoError(t, err)
+	redisPool, err := meta.NewRedisPoolFactory(server.Host(), port, "", 0, false, "").Create()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = redisPool.Close() })
+
+	q, err := NewRedisStreams(DestinationNamespace, "destination1", consumerName, redisPool,
+		func() interface{} { return &streamTestEvent{} }, time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = q.Close() })
+
+	return q.(*RedisStreams)
+}
+
+func pendingCount(t *testing.T, server *miniredis.Miniredis, streamKey string) int {
+	pending, err := server.Stream(streamKey)
+	require.NoError(t, err)
+	return len(pending)
+}
+
+func TestRedisStreamsAck(t *testing.T) {
+	server := miniredis.RunT(t)
+	q := newTestRedisStreams(t, server, "replica1")
+
+	require.NoError(t, q.Push(&streamTestEvent{ID: "1"}))
+	require.NoError(t, q.Push(&streamTestEvent{ID: "2"}))
+	require.Equal(t, int64(2), q.Size())
+
+	v, ack, err := q.PollWithAck()
+	require.NoError(t, err)
+	require.Equal(t, "1", v.(*streamTestEvent).ID)
+	require.Equal(t, 2, pendingCount(t, server, q.streamKey), "message mustn't be deleted before acknowledgement")
+
+	ack()
+	require.Equal(t, 1, pendingCount(t, server, q.streamKey))
+
+	//Poll acknowledges right away
+	v, err = q.Poll()
+	require.NoError(t, err)
+	require.Equal(t, "2", v.(*streamTestEvent).ID)
+	require.Equal(t, 0, pendingCount(t, server, q.streamKey))
+}
+
+func TestRedisStreamsRedeliveryOfDeadConsumerMessages(t *testing.T) {
+	server := miniredis.RunT(t)
+	dead := newTestRedisStreams(t, server, "replica1")
+	alive := newTestRedisStreams(t, server, "replica2")
+	//skip claiming until the message becomes idle
+	alive.lastClaimTime.Store(time.Now().UnixNano())
+
+	require.NoError(t, dead.Push(&streamTestEvent{ID: "1"}))
+	require.NoError(t, dead.Push(&streamTestEvent{ID: "2"}))
+
+	//the first replica reads the message and crashes without acknowledgement
+	v, _, err := dead.PollWithAck()
+	require.NoError(t, err)
+	require.Equal(t, "1", v.(*streamTestEvent).ID)
+	require.NoError(t, dead.Close())
+
+	v, ack, err := alive.PollWithAck()
+	require.NoError(t, err)
+	require.Equal(t, "2", v.(*streamTestEvent).ID)
+	ack()
+
+	//the message isn't claimed until it has been pending for streamClaimIdleTimeout
+	alive.lastClaimTime.Store(0)
+	_, _, err = alive.PollWithAck()
+	require.Equal(t, ErrQueueEmpty, err)
+
+	server.SetTime(time.Now().Add(streamClaimIdleTimeout + time.Minute))
+	alive.lastClaimTime.Store(0)
+	v, ack, err = alive.PollWithAck()
+	require.NoError(t, err)
+	require.Equal(t, "1", v.(*streamTestEvent).ID, "message of the dead consumer must be delivered again")
+	ack()
+
+	require.Equal(t, 0, pendingCount(t, server, alive.streamKey))
+	_, _, err = alive.PollWithAck()
+	require.Equal(t, ErrQueueEmpty, err)
+}
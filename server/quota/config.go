@@ -0,0 +1,81 @@
+package quota
+
+import (
+	"fmt"
+)
+
+const (
+	ProjectSubject = "project"
+	APIKeySubject  = "api_key"
+
+	Daily   = "daily"
+	Monthly = "monthly"
+
+	defaultRefreshIntervalSec = 30
+)
+
+// Config is a configuration of events quotas per project and API key
+type Config struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// RefreshIntervalSec is an interval of reading actual usage from the statistics (meta storage)
+	RefreshIntervalSec int       `mapstructure:"refresh_interval_sec" json:"refresh_interval_sec,omitempty" yaml:"refresh_interval_sec,omitempty"`
+	Projects           []*Limits `mapstructure:"projects" json:"projects,omitempty" yaml:"projects,omitempty"`
+	APIKeys            []*Limits `mapstructure:"api_keys" json:"api_keys,omitempty" yaml:"api_keys,omitempty"`
+}
+
+// Limits are events amounts limits of a project or an API key. 0 means no limit.
+// Soft limit exceeding causes warnings, hard limit exceeding causes events rejection
+type Limits struct {
+	// ID is a project ID or an API key ID
+	ID          string `mapstructure:"id" json:"id,omitempty" yaml:"id,omitempty"`
+	DailySoft   int64  `mapstructure:"daily_soft" json:"daily_soft,omitempty" yaml:"daily_soft,omitempty"`
+	DailyHard   int64  `mapstructure:"daily_hard" json:"daily_hard,omitempty" yaml:"daily_hard,omitempty"`
+	MonthlySoft int64  `mapstructure:"monthly_soft" json:"monthly_soft,omitempty" yaml:"monthly_soft,omitempty"`
+	MonthlyHard int64  `mapstructure:"monthly_hard" json:"monthly_hard,omitempty" yaml:"monthly_hard,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid. Also sets default values
+func (c *Config) Validate() error {
+	if c.RefreshIntervalSec <= 0 {
+		c.RefreshIntervalSec = defaultRefreshIntervalSec
+	}
+
+	for subject, limitsList := range map[string][]*Limits{ProjectSubject: c.Projects, APIKeySubject: c.APIKeys} {
+		ids := map[string]bool{}
+		for i, limits := range limitsList {
+			if err := limits.validate(); err != nil {
+				return fmt.Errorf("quotas %s[%d]: %v", subject, i, err)
+			}
+			if ids[limits.ID] {
+				return fmt.Errorf("quotas %s[%d]: id [%s] isn't unique", subject, i, limits.ID)
+			}
+			ids[limits.ID] = true
+		}
+	}
+
+	return nil
+}
+
+func (l *Limits) validate() error {
+	if l.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+
+	if l.DailySoft < 0 || l.DailyHard < 0 || l.MonthlySoft < 0 || l.MonthlyHard < 0 {
+		return fmt.Errorf("limits must be positive or 0")
+	}
+
+	if l.DailySoft == 0 && l.DailyHard == 0 && l.MonthlySoft == 0 && l.MonthlyHard == 0 {
+		return fmt.Errorf("at least one limit is required")
+	}
+
+	if l.DailyHard > 0 && l.DailySoft > l.DailyHard {
+		return fmt.Errorf("daily_soft [%d] must be less than daily_hard [%d]", l.DailySoft, l.DailyHard)
+	}
+
+	if l.MonthlyHard > 0 && l.MonthlySoft > l.MonthlyHard {
+		return fmt.Errorf("monthly_soft [%d] must be less than monthly_hard [%d]", l.MonthlySoft, l.MonthlyHard)
+	}
+
+	return nil
+}
@@ -0,0 +1,414 @@
+package quota
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+// WarningHeader is an HTTP response header with the soft limit warning
+const WarningHeader = "X-Jitsu-Quota-Warning"
+
+var instance *Service
+
+// ExceededError is returned when accepting events would exceed a hard limit
+type ExceededError struct {
+	Subject string
+	ID      string
+	Period  string
+	Limit   int64
+	// RetryAfter is a duration until the period end
+	RetryAfter time.Duration
+}
+
+func (ee *ExceededError) Error() string {
+	return fmt.Sprintf("%s events quota of %s [%s] is exceeded: limit is %d events", ee.Period, ee.Subject, ee.ID, ee.Limit)
+}
+
+// Usage is a project or API key events amount in the current day and month
+type Usage struct {
+	Subject      string  `json:"subject"`
+	ID           string  `json:"id"`
+	Limits       *Limits `json:"limits"`
+	Daily        int64   `json:"daily"`
+	Monthly      int64   `json:"monthly"`
+	SoftExceeded bool    `json:"soft_exceeded"`
+	HardExceeded bool    `json:"hard_exceeded"`
+}
+
+// Service enforces events quotas. Usage is read from the statistics (push source counters in meta storage)
+// every refresh interval and events accepted by the current server are added until the statistics catch up with them.
+// Statistics are shared between all server replicas so the usage is approximate: it may lag by the refresh interval
+// of other replicas
+type Service struct {
+	storage  meta.Storage
+	projects map[string]*subject
+	apiKeys  map[string]*subject
+
+	closed chan struct{}
+}
+
+// subject is a project or an API key with limits and the current usage
+type subject struct {
+	kind   string
+	id     string
+	limits *Limits
+
+	mutex sync.Mutex
+	//day and month (timestamp.DayLayout, timestamp.MonthLayout) the usage belongs to
+	day           string
+	month         string
+	storedDaily   int64
+	storedMonthly int64
+	//accepted* are events accepted by the current server which aren't counted in the statistics yet
+	acceptedDaily   int64
+	acceptedMonthly int64
+	//recent* are events accepted since the previous refresh
+	recentDaily   int64
+	recentMonthly int64
+	//warned is a set of day/month periods with logged soft limit warnings. Reset every day
+	warned map[string]bool
+}
+
+// NewService returns configured Service and starts usage refreshing goroutine
+func NewService(config *Config, storage meta.Storage) (*Service, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		storage:  storage,
+		projects: newSubjects(ProjectSubject, config.Projects),
+		apiKeys:  newSubjects(APIKeySubject, config.APIKeys),
+		closed:   make(chan struct{}),
+	}
+
+	s.refresh()
+	s.startRefreshing(time.Duration(config.RefreshIntervalSec) * time.Second)
+	return s, nil
+}
+
+// Init creates the global Service
+func Init(config *Config, storage meta.Storage) (*Service, error) {
+	s, err := NewService(config, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	instance = s
+	return s, nil
+}
+
+// Instance returns the global Service or nil if quotas are disabled
+func Instance() *Service {
+	return instance
+}
+
+func newSubjects(kind string, limitsList []*Limits) map[string]*subject {
+	subjects := make(map[string]*subject, len(limitsList))
+	for _, limits := range limitsList {
+		subjects[limits.ID] = &subject{kind: kind, id: limits.ID, limits: limits, warned: map[string]bool{}}
+	}
+
+	return subjects
+}
+
+func (s *Service) startRefreshing(interval time.Duration) {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.closed:
+				return
+			case <-ticker.C:
+				s.refresh()
+			}
+		}
+	})
+}
+
+// refresh reads the current day and month usage of all subjects from the statistics
+func (s *Service) refresh() {
+	now := timestamp.Now().UTC()
+	for _, sub := range s.subjects() {
+		ids := []string{sub.id}
+		if sub.kind == ProjectSubject {
+			var err error
+			ids, err = s.storage.GetProjectSourceIDs(sub.id)
+			if err != nil {
+				logging.Errorf("[quota] Error getting project [%s] API keys: %v", sub.id, err)
+				continue
+			}
+		}
+
+		daily, monthly, err := s.storedUsage(ids, now)
+		if err != nil {
+			logging.Errorf("[quota] Error getting %s [%s] usage: %v", sub.kind, sub.id, err)
+			continue
+		}
+
+		sub.update(now, daily, monthly)
+	}
+}
+
+// storedUsage returns successfully accepted push events amounts of the ids since the day and the month start
+func (s *Service) storedUsage(ids []string, now time.Time) (int64, int64, error) {
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	perHour, err := s.storage.GetEventsWithGranularity(meta.SourceNamespace, meta.SuccessStatus, meta.PushEventType, ids, dayStart, now, meta.HOUR)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	perDay, err := s.storage.GetEventsWithGranularity(meta.SourceNamespace, meta.SuccessStatus, meta.PushEventType, ids, monthStart, now, meta.DAY)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return sumEvents(perHour), sumEvents(perDay), nil
+}
+
+func sumEvents(eventsPerTime []meta.EventsPerTime) int64 {
+	var sum int64
+	for _, ept := range eventsPerTime {
+		sum += int64(ept.Events)
+	}
+
+	return sum
+}
+
+// Check returns *ExceededError if accepting eventsCount events exceeds a hard limit of the API key or its project.
+// Otherwise eventsCount is added to the usage. Should be called with events which are left after sampling and load shedding
+// (events which are dropped later in the pipeline, e.g. by bot filtering, are charged for up to two refresh intervals)
+func (s *Service) Check(tokenID string, eventsCount int) error {
+	subjects := s.tokenSubjects(tokenID)
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	now := timestamp.Now().UTC()
+	//lock all subjects for checking and accepting atomically
+	for _, sub := range subjects {
+		sub.mutex.Lock()
+		defer sub.mutex.Unlock()
+		sub.rollover(now)
+	}
+
+	value := int64(eventsCount)
+	for _, sub := range subjects {
+		if err := sub.checkHard(now, value); err != nil {
+			return err
+		}
+	}
+
+	for _, sub := range subjects {
+		sub.acceptedDaily += value
+		sub.acceptedMonthly += value
+		sub.recentDaily += value
+		sub.recentMonthly += value
+	}
+
+	return nil
+}
+
+// Warning returns a non-empty warning if a soft limit of the API key or its project is exceeded
+func (s *Service) Warning(tokenID string) string {
+	now := timestamp.Now().UTC()
+	var warnings []string
+	for _, sub := range s.tokenSubjects(tokenID) {
+		sub.mutex.Lock()
+		sub.rollover(now)
+		if warning := sub.softWarning(); warning != "" {
+			warnings = append(warnings, warning)
+		}
+		sub.mutex.Unlock()
+	}
+
+	return strings.Join(warnings, "; ")
+}
+
+// Usages returns the current usage of all projects and API keys with limits sorted by subject and id
+func (s *Service) Usages() []*Usage {
+	now := timestamp.Now().UTC()
+	usages := []*Usage{}
+	for _, sub := range s.subjects() {
+		sub.mutex.Lock()
+		sub.rollover(now)
+		daily, monthly := sub.usage()
+		usages = append(usages, &Usage{
+			Subject:      sub.kind,
+			ID:           sub.id,
+			Limits:       sub.limits,
+			Daily:        daily,
+			Monthly:      monthly,
+			SoftExceeded: sub.softWarning() != "",
+			HardExceeded: sub.checkHard(now, 1) != nil,
+		})
+		sub.mutex.Unlock()
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Subject != usages[j].Subject {
+			return usages[i].Subject < usages[j].Subject
+		}
+		return usages[i].ID < usages[j].ID
+	})
+	return usages
+}
+
+func (s *Service) Close() error {
+	close(s.closed)
+	return nil
+}
+
+// tokenSubjects returns the API key and its project (projectID.tokenID) subjects which have limits
+func (s *Service) tokenSubjects(tokenID string) []*subject {
+	var subjects []*subject
+	if sub, ok := s.apiKeys[tokenID]; ok {
+		subjects = append(subjects, sub)
+	}
+
+	if parts := strings.SplitN(tokenID, ".", 2); len(parts) == 2 {
+		if sub, ok := s.projects[parts[0]]; ok {
+			subjects = append(subjects, sub)
+		}
+	}
+
+	return subjects
+}
+
+func (s *Service) subjects() []*subject {
+	subjects := make([]*subject, 0, len(s.projects)+len(s.apiKeys))
+	for _, sub := range s.projects {
+		subjects = append(subjects, sub)
+	}
+	for _, sub := range s.apiKeys {
+		subjects = append(subjects, sub)
+	}
+
+	return subjects
+}
+
+// rollover resets the usage if a new day or month has come. Must be called under the mutex
+func (sub *subject) rollover(now time.Time) {
+	if day := now.Format(timestamp.DayLayout); day != sub.day {
+		sub.day = day
+		sub.storedDaily = 0
+		sub.acceptedDaily = 0
+		sub.recentDaily = 0
+		sub.warned = map[string]bool{}
+	}
+
+	if month := now.Format(timestamp.MonthLayout); month != sub.month {
+		sub.month = month
+		sub.storedMonthly = 0
+		sub.acceptedMonthly = 0
+		sub.recentMonthly = 0
+	}
+}
+
+// update sets usage from the statistics. Accepted events are decreased by the statistics growth (they have caught up
+// with it). Events accepted before the previous refresh are expected to be in the statistics (or to be dropped
+// in the pipeline) so only events accepted since the previous refresh can be left
+func (sub *subject) update(now time.Time, daily, monthly int64) {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	sub.rollover(now)
+	sub.acceptedDaily = notCaughtUp(sub.acceptedDaily, sub.recentDaily, daily-sub.storedDaily)
+	sub.storedDaily = daily
+	sub.recentDaily = 0
+	sub.acceptedMonthly = notCaughtUp(sub.acceptedMonthly, sub.recentMonthly, monthly-sub.storedMonthly)
+	sub.storedMonthly = monthly
+	sub.recentMonthly = 0
+}
+
+// notCaughtUp returns accepted events which aren't counted in the statistics after their growth
+// but not more than recent events
+func notCaughtUp(accepted, recent, growth int64) int64 {
+	if growth > 0 {
+		accepted -= growth
+	}
+	if accepted > recent {
+		accepted = recent
+	}
+	if accepted < 0 {
+		accepted = 0
+	}
+
+	return accepted
+}
+
+func (sub *subject) usage() (int64, int64) {
+	return sub.storedDaily + sub.acceptedDaily, sub.storedMonthly + sub.acceptedMonthly
+}
+
+// checkHard returns *ExceededError if the usage with value exceeds a hard limit. Must be called under the mutex
+func (sub *subject) checkHard(now time.Time, value int64) error {
+	daily, monthly := sub.usage()
+	if sub.limits.DailyHard > 0 && daily+value > sub.limits.DailyHard {
+		nextDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return &ExceededError{Subject: sub.kind, ID: sub.id, Period: Daily, Limit: sub.limits.DailyHard, RetryAfter: nextDay.Sub(now)}
+	}
+
+	if sub.limits.MonthlyHard > 0 && monthly+value > sub.limits.MonthlyHard {
+		nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		return &ExceededError{Subject: sub.kind, ID: sub.id, Period: Monthly, Limit: sub.limits.MonthlyHard, RetryAfter: nextMonth.Sub(now)}
+	}
+
+	return nil
+}
+
+// softWarning returns a warning if the usage exceeds a soft limit and writes it into the log once a period.
+// Must be called under the mutex
+func (sub *subject) softWarning() string {
+	daily, monthly := sub.usage()
+	var period, periodKey string
+	var used, limit int64
+	if sub.limits.DailySoft > 0 && daily > sub.limits.DailySoft {
+		period, periodKey, used, limit = Daily, sub.day, daily, sub.limits.DailySoft
+	} else if sub.limits.MonthlySoft > 0 && monthly > sub.limits.MonthlySoft {
+		period, periodKey, used, limit = Monthly, sub.month, monthly, sub.limits.MonthlySoft
+	} else {
+		return ""
+	}
+
+	warning := fmt.Sprintf("%s events quota soft limit of %s [%s] is exceeded: %d of %d events", period, sub.kind, sub.id, used, limit)
+	if !sub.warned[periodKey] {
+		sub.warned[periodKey] = true
+		logging.Warnf("[quota] %s", warning)
+	}
+
+	return warning
+}
+
+// Check checks the global Service (see Service.Check). Does nothing if quotas are disabled
+func Check(tokenID string, eventsCount int) error {
+	if instance == nil {
+		return nil
+	}
+
+	return instance.Check(tokenID, eventsCount)
+}
+
+// Warning returns the global Service soft limit warning (see Service.Warning). Returns empty string if quotas are disabled
+func Warning(tokenID string) string {
+	if instance == nil {
+		return ""
+	}
+
+	return instance.Warning(tokenID)
+}
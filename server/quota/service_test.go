@@ -0,0 +1,121 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+//testStorage returns the same events amount per hour/day for every id
+type testStorage struct {
+	meta.Dummy
+	projectSourceIDs map[string][]string
+	perHour          int
+	perDay           int
+}
+
+func (ts *testStorage) GetProjectSourceIDs(projectID string) ([]string, error) {
+	return ts.projectSourceIDs[projectID], nil
+}
+
+func (ts *testStorage) GetEventsWithGranularity(namespace, status, eventType string, ids []string, start, end time.Time, granularity meta.Granularity) ([]meta.EventsPerTime, error) {
+	value := ts.perDay
+	if granularity == meta.HOUR {
+		value = ts.perHour
+	}
+
+	var result []meta.EventsPerTime
+	for range ids {
+		result = append(result, meta.EventsPerTime{Key: start.Format(time.RFC3339), Events: value})
+	}
+	return result, nil
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := &Config{APIKeys: []*Limits{{ID: "key1", DailySoft: 10, DailyHard: 20}}}
+	require.NoError(t, config.Validate())
+	require.Equal(t, defaultRefreshIntervalSec, config.RefreshIntervalSec)
+
+	require.Error(t, (&Config{APIKeys: []*Limits{{DailyHard: 20}}}).Validate())
+	require.Error(t, (&Config{APIKeys: []*Limits{{ID: "key1"}}}).Validate())
+	require.Error(t, (&Config{Projects: []*Limits{{ID: "p1", MonthlySoft: 30, MonthlyHard: 20}}}).Validate())
+	require.Error(t, (&Config{Projects: []*Limits{{ID: "p1", DailyHard: 1}, {ID: "p1", DailyHard: 2}}}).Validate())
+}
+
+func TestCheck(t *testing.T) {
+	timestamp.FreezeTime()
+	timestamp.SetFreezeTime(time.Date(2022, 6, 15, 12, 30, 0, 0, time.UTC))
+	defer timestamp.UnfreezeTime()
+
+	storage := &testStorage{projectSourceIDs: map[string][]string{"p1": {"p1.key1", "p1.key2"}}, perHour: 40, perDay: 400}
+	service, err := NewService(&Config{
+		Projects: []*Limits{{ID: "p1", MonthlySoft: 850, MonthlyHard: 900}},
+		APIKeys:  []*Limits{{ID: "p1.key1", DailySoft: 45, DailyHard: 50}},
+	}, storage)
+	require.NoError(t, err)
+	defer service.Close()
+
+	//no limits
+	require.NoError(t, service.Check("other", 1000))
+	require.Empty(t, service.Warning("other"))
+
+	//api key: 40 + 5 = 45 (soft isn't exceeded yet)
+	require.NoError(t, service.Check("p1.key1", 5))
+	require.Empty(t, service.Warning("p1.key1"))
+
+	//api key: 45 + 4 = 49 (soft is exceeded)
+	require.NoError(t, service.Check("p1.key1", 4))
+	require.Contains(t, service.Warning("p1.key1"), "daily events quota soft limit of api_key [p1.key1]")
+
+	//api key hard: 49 + 2 > 50
+	err = service.Check("p1.key1", 2)
+	require.IsType(t, &ExceededError{}, err)
+	quotaErr := err.(*ExceededError)
+	require.Equal(t, Daily, quotaErr.Period)
+	require.Equal(t, APIKeySubject, quotaErr.Subject)
+	require.Equal(t, 11*time.Hour+30*time.Minute, quotaErr.RetryAfter)
+
+	//project: 800 + 9 accepted + 50 = 859 (soft is exceeded)
+	require.NoError(t, service.Check("p1.key2", 50))
+	require.Contains(t, service.Warning("p1.key2"), "monthly events quota soft limit of project [p1]")
+
+	//project hard: 859 + 42 > 900
+	err = service.Check("p1.key2", 42)
+	require.IsType(t, &ExceededError{}, err)
+	require.Equal(t, Monthly, err.(*ExceededError).Period)
+	require.Equal(t, ProjectSubject, err.(*ExceededError).Subject)
+
+	usages := service.Usages()
+	require.Len(t, usages, 2)
+	require.Equal(t, &Usage{Subject: APIKeySubject, ID: "p1.key1", Limits: usages[0].Limits, Daily: 49, Monthly: 409, SoftExceeded: true}, usages[0])
+	require.Equal(t, &Usage{Subject: ProjectSubject, ID: "p1", Limits: usages[1].Limits, Daily: 139, Monthly: 859, SoftExceeded: true}, usages[1])
+
+	//accepted events are kept until the statistics catch up with them: 40 + 9 + 2 > 50
+	service.refresh()
+	require.IsType(t, &ExceededError{}, service.Check("p1.key1", 2))
+
+	//events accepted before the previous refresh are expected to be in the statistics: 44 + 6 = 50
+	storage.perHour = 44
+	service.refresh()
+	require.NoError(t, service.Check("p1.key1", 6))
+	require.IsType(t, &ExceededError{}, service.Check("p1.key1", 1))
+
+	//the statistics have caught up with 3 of 6 accepted events: 47 + 3 + 1 > 50
+	storage.perHour = 47
+	service.refresh()
+	require.IsType(t, &ExceededError{}, service.Check("p1.key1", 1))
+
+	//the rest of accepted events are accepted before the previous refresh: 47 + 3 = 50
+	service.refresh()
+	require.NoError(t, service.Check("p1.key1", 3))
+
+	//the next day
+	timestamp.SetFreezeTime(time.Date(2022, 6, 16, 0, 1, 0, 0, time.UTC))
+	storage.perHour = 0
+	service.refresh()
+	require.NoError(t, service.Check("p1.key1", 10))
+	require.Empty(t, service.Warning("p1.key1"))
+}
@@ -0,0 +1,271 @@
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/locks"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	// Running - retention is being enforced
+	Running = "running"
+	// Succeeded - expired data has been deleted (or counted) in all destinations
+	Succeeded = "succeeded"
+	// Skipped - destination doesn't support retention or retention isn't configured in the destination
+	Skipped = "skipped"
+	// Failed - expired data hasn't been deleted in at least one destination
+	Failed = "failed"
+
+	// maxReports is a maximum amount of kept reports. The oldest reports are removed first
+	maxReports = 100
+
+	lockName    = "data_retention"
+	lockTimeout = time.Minute
+)
+
+// Request is a request for enforcing data retention
+type Request struct {
+	// DestinationIDs is an optional filter of destinations. All destinations are processed by default
+	DestinationIDs []string `json:"destination_ids,omitempty"`
+	// DryRun only counts expired rows and files without deleting them
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// Report is a result of the retention run: deleted (or counted in dry run) rows per table of every destination
+type Report struct {
+	ID           string               `json:"id"`
+	DryRun       bool                 `json:"dry_run"`
+	Scheduled    bool                 `json:"scheduled"`
+	Status       string               `json:"status"`
+	Destinations []*DestinationReport `json:"destinations"`
+	StartedAt    time.Time            `json:"started_at"`
+	FinishedAt   *time.Time           `json:"finished_at,omitempty"`
+}
+
+// DestinationReport is a result of the retention in a destination: deleted rows (files) per table (-1 if it is unknown)
+type DestinationReport struct {
+	ID     string           `json:"id"`
+	Status string           `json:"status"`
+	Tables map[string]int64 `json:"tables,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// Service enforces data retention in destinations periodically (if interval is set) and on demand.
+// Reports are kept in memory
+type Service struct {
+	destinationService *destinations.Service
+	lockFactory        locks.LockFactory
+	defaults           *config.Retention
+	dryRun             bool
+
+	mutex   sync.RWMutex
+	reports []*Report
+	closed  chan struct{}
+}
+
+// NewService returns configured Service. If interval > 0, retention of all destinations is enforced periodically
+// on one instance of the cluster (with the lock). If dryRun is true, scheduled runs only count expired data
+func NewService(destinationService *destinations.Service, lockFactory locks.LockFactory, defaults *config.Retention, interval time.Duration, dryRun bool) *Service {
+	s := &Service{
+		destinationService: destinationService,
+		lockFactory:        lockFactory,
+		defaults:           defaults,
+		dryRun:             dryRun,
+		closed:             make(chan struct{}),
+	}
+
+	if interval > 0 {
+		safego.RunWithRestart(func() {
+			s.startScheduler(interval)
+		})
+	}
+
+	return s
+}
+
+// Run validates the request and starts enforcing retention in background
+// returns created report
+func (s *Service) Run(req *Request) (*Report, error) {
+	destinationIDs := req.DestinationIDs
+	if len(destinationIDs) == 0 {
+		destinationIDs = s.destinationService.GetAllDestinationIDs()
+	}
+	for _, destinationID := range destinationIDs {
+		if _, ok := s.destinationService.GetDestinationByID(destinationID); !ok {
+			return nil, fmt.Errorf("destination [%s] doesn't exist", destinationID)
+		}
+	}
+
+	report := s.newReport(req.DryRun, false)
+	safego.Run(func() {
+		s.run(report, destinationIDs)
+	})
+
+	return s.GetReport(report.ID), nil
+}
+
+// GetReport returns a copy of the report or nil if it doesn't exist
+func (s *Service) GetReport(id string) *Report {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, report := range s.reports {
+		if report.ID == id {
+			return copyReport(report)
+		}
+	}
+
+	return nil
+}
+
+// GetReports returns copies of all reports sorted by start time
+func (s *Service) GetReports() []*Report {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	reports := make([]*Report, 0, len(s.reports))
+	for _, report := range s.reports {
+		reports = append(reports, copyReport(report))
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].StartedAt.Before(reports[j].StartedAt)
+	})
+
+	return reports
+}
+
+// Close stops the scheduler
+func (s *Service) Close() error {
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+
+	return nil
+}
+
+func (s *Service) startScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-ticker.C:
+			s.runScheduled()
+		}
+	}
+}
+
+// runScheduled enforces retention of all destinations if the lock is acquired (another instance isn't running it)
+func (s *Service) runScheduled() {
+	if s.lockFactory != nil {
+		lock := s.lockFactory.CreateLock(lockName)
+		locked, err := lock.TryLock(lockTimeout)
+		if err != nil {
+			logging.Errorf("Error locking data retention: %v", err)
+			return
+		}
+		if !locked {
+			logging.Debug("Data retention is being enforced by another instance")
+			return
+		}
+		defer lock.Unlock()
+	}
+
+	s.run(s.newReport(s.dryRun, true), s.destinationService.GetAllDestinationIDs())
+}
+
+func (s *Service) newReport(dryRun, scheduled bool) *Report {
+	report := &Report{
+		ID:        uuid.New().String(),
+		DryRun:    dryRun,
+		Scheduled: scheduled,
+		Status:    Running,
+		StartedAt: timestamp.Now().UTC(),
+	}
+
+	s.mutex.Lock()
+	s.reports = append(s.reports, report)
+	if len(s.reports) > maxReports {
+		s.reports = s.reports[len(s.reports)-maxReports:]
+	}
+	s.mutex.Unlock()
+
+	return report
+}
+
+func (s *Service) run(report *Report, destinationIDs []string) {
+	logging.Infof("[%s] Data retention (dry run: %t) of %d destinations has been started", report.ID, report.DryRun, len(destinationIDs))
+	sort.Strings(destinationIDs)
+	status := Succeeded
+	for _, destinationID := range destinationIDs {
+		destinationReport := s.expireInDestination(destinationID, report.DryRun)
+		switch destinationReport.Status {
+		case Failed:
+			status = Failed
+			logging.Errorf("[%s] Error enforcing data retention in destination [%s]: %s", report.ID, destinationID, destinationReport.Error)
+		case Succeeded:
+			logging.Infof("[%s] Data retention (dry run: %t) in destination [%s]: %v", report.ID, report.DryRun, destinationID, destinationReport.Tables)
+		}
+
+		s.mutex.Lock()
+		report.Destinations = append(report.Destinations, destinationReport)
+		s.mutex.Unlock()
+	}
+
+	finishedAt := timestamp.Now().UTC()
+	s.mutex.Lock()
+	report.Status = status
+	report.FinishedAt = &finishedAt
+	s.mutex.Unlock()
+	logging.Infof("[%s] Data retention has been finished with status: %s", report.ID, status)
+}
+
+func (s *Service) expireInDestination(destinationID string, dryRun bool) *DestinationReport {
+	destinationReport := &DestinationReport{ID: destinationID}
+	storageProxy, ok := s.destinationService.GetDestinationByID(destinationID)
+	if !ok {
+		destinationReport.Status = Failed
+		destinationReport.Error = "destination doesn't exist"
+		return destinationReport
+	}
+	storage, ok := storageProxy.Get()
+	if !ok {
+		destinationReport.Status = Failed
+		destinationReport.Error = "destination isn't initialized"
+		return destinationReport
+	}
+
+	tables, err := storage.Expire(s.defaults, dryRun)
+	destinationReport.Tables = tables
+	switch {
+	case err == storages.ErrRetentionNotSupported || err == storages.ErrRetentionDisabled:
+		destinationReport.Status = Skipped
+		destinationReport.Error = err.Error()
+	case err != nil:
+		destinationReport.Status = Failed
+		destinationReport.Error = err.Error()
+	default:
+		destinationReport.Status = Succeeded
+	}
+
+	return destinationReport
+}
+
+func copyReport(report *Report) *Report {
+	reportCopy := *report
+	reportCopy.Destinations = append([]*DestinationReport{}, report.Destinations...)
+	return &reportCopy
+}
@@ -0,0 +1,102 @@
+package retention
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+type storageMock struct {
+	storages.Storage
+	tables map[string]int64
+	err    error
+
+	defaults *config.Retention
+	dryRun   bool
+}
+
+func (sm *storageMock) Expire(defaults *config.Retention, dryRun bool) (map[string]int64, error) {
+	sm.defaults = defaults
+	sm.dryRun = dryRun
+	return sm.tables, sm.err
+}
+
+type storageProxyMock struct {
+	storages.StorageProxy
+	storage storages.Storage
+}
+
+func (spm *storageProxyMock) Get() (storages.Storage, bool) { return spm.storage, spm.storage != nil }
+
+func newTestService(defaults *config.Retention, units map[string]storages.Storage) *Service {
+	unitsByID := map[string]*destinations.Unit{}
+	for id, storage := range units {
+		unitsByID[id] = destinations.NewTestUnit(&storageProxyMock{storage: storage})
+	}
+	destinationService := destinations.NewTestService(unitsByID, destinations.TokenizedConsumers{}, destinations.TokenizedStorages{},
+		destinations.TokenizedIDs{}, map[string]events.Consumer{})
+
+	return NewService(destinationService, nil, defaults, 0, false)
+}
+
+func waitForReport(t *testing.T, s *Service, id string) *Report {
+	var report *Report
+	require.Eventually(t, func() bool {
+		report = s.GetReport(id)
+		return report.Status != Running
+	}, 5*time.Second, 10*time.Millisecond)
+	return report
+}
+
+func TestRetentionRun(t *testing.T) {
+	defaults := &config.Retention{Days: 30}
+	postgres := &storageMock{tables: map[string]int64{"events": 10}}
+	s := newTestService(defaults, map[string]storages.Storage{
+		"postgres": postgres,
+		"webhook":  &storageMock{err: storages.ErrRetentionNotSupported},
+		"mysql":    &storageMock{tables: map[string]int64{}, err: errors.New("table events: connection refused")},
+		"broken":   nil,
+	})
+
+	report, err := s.Run(&Request{DryRun: true})
+	require.NoError(t, err)
+	report = waitForReport(t, s, report.ID)
+
+	require.True(t, report.DryRun)
+	require.False(t, report.Scheduled)
+	require.Equal(t, Failed, report.Status)
+	require.NotNil(t, report.FinishedAt)
+	require.Equal(t, []*DestinationReport{
+		{ID: "broken", Status: Failed, Error: "destination isn't initialized"},
+		{ID: "mysql", Status: Failed, Tables: map[string]int64{}, Error: "table events: connection refused"},
+		{ID: "postgres", Status: Succeeded, Tables: map[string]int64{"events": 10}},
+		{ID: "webhook", Status: Skipped, Error: storages.ErrRetentionNotSupported.Error()},
+	}, report.Destinations)
+	require.True(t, postgres.dryRun)
+	require.Equal(t, defaults, postgres.defaults)
+
+	report, err = s.Run(&Request{DestinationIDs: []string{"postgres"}})
+	require.NoError(t, err)
+	report = waitForReport(t, s, report.ID)
+	require.Equal(t, Succeeded, report.Status)
+	require.False(t, postgres.dryRun)
+	require.Len(t, s.GetReports(), 2)
+
+	_, err = s.Run(&Request{DestinationIDs: []string{"unknown"}})
+	require.Error(t, err)
+	require.Nil(t, s.GetReport("unknown"))
+}
+
+func TestRetentionReportsLimit(t *testing.T) {
+	s := newTestService(&config.Retention{}, map[string]storages.Storage{})
+	for i := 0; i < maxReports+5; i++ {
+		s.newReport(false, true)
+	}
+	require.Len(t, s.GetReports(), maxReports)
+}
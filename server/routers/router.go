@@ -11,28 +11,32 @@ import (
 	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/erasure"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/fallback"
 	"github.com/jitsucom/jitsu/server/geo"
 	"github.com/jitsucom/jitsu/server/handlers"
+	"github.com/jitsucom/jitsu/server/health"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/multiplexing"
+	"github.com/jitsucom/jitsu/server/retention"
 	"github.com/jitsucom/jitsu/server/sources"
 	"github.com/jitsucom/jitsu/server/synchronization"
 	"github.com/jitsucom/jitsu/server/system"
+	"github.com/jitsucom/jitsu/server/tracing"
 	"github.com/jitsucom/jitsu/server/wal"
 	"github.com/penglongli/gin-metrics/ginmetrics"
 	"github.com/spf13/viper"
 )
 
 func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *destinations.Service, sourcesService *sources.Service,
-	taskService *synchronization.TaskService, fallbackService *fallback.Service, coordinationService *coordination.Service,
+	taskService *synchronization.TaskService, fallbackService *fallback.Service, erasureService *erasure.Service, retentionService *retention.Service, coordinationService *coordination.Service,
 	eventsCache *caching.EventsCache, systemService *system.Service, segmentEndpointFieldMapper, segmentCompatEndpointFieldMapper events.Mapper,
 	processorHolder *events.ProcessorHolder, multiplexingService *multiplexing.Service, walService *wal.Service, geoService *geo.Service,
-	userRecognition *config.UsersRecognition) *gin.Engine {
+	binaryDecoder events.BinaryDecoder, userRecognition *config.UsersRecognition, healthService *health.Service) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New() //gin.Default()
@@ -46,6 +50,10 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		m.UseWithoutExposingEndpoint(router)
 	}
 
+	if tracing.Enabled() {
+		router.Use(middleware.Tracing)
+	}
+
 	router.Use(gin.RecoveryWithWriter(logging.GlobalLogsWriter, func(c *gin.Context, err interface{}) {
 		logging.SystemErrorf("Panic on request %s: %v\n%s", c.Request.URL.String(), err, string(debug.Stack()))
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -59,12 +67,22 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		c.String(http.StatusOK, "pong")
 	})
 
+	healthHandler := handlers.NewHealthHandler(healthService)
+	router.GET("/health/live", healthHandler.LiveHandler)
+	router.GET("/health/ready", healthHandler.ReadyHandler)
+
 	maxEventSize := viper.GetInt("server.max_event_size")
 	maxCachedEventsErrSize := viper.GetInt("server.cache.events.max_malformed_event_size_bytes")
 	if maxEventSize < maxCachedEventsErrSize {
 		maxCachedEventsErrSize = maxEventSize
 	}
 
+	//ingestion endpoints body size limit and gzip decompression
+	maxBodySize := viper.GetInt64("server.http.max_body_size")
+	ingestion := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return middleware.RequestBody(handler, maxBodySize)
+	}
+
 	publicURL := viper.GetString("server.public_url")
 	configuratorURN := viper.GetString("server.configurator_urn")
 
@@ -77,45 +95,64 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 	router.GET("/s/:filename", staticHandler.Handler)
 	router.GET("/t/:filename", staticHandler.Handler)
 
-	jsEventHandler := handlers.NewEventHandler(walService, multiplexingService, eventsCache, events.NewJitsuParser(maxEventSize, maxCachedEventsErrSize), processorHolder.GetJSPreprocessor(), destinations, geoService)
-	apiEventHandler := handlers.NewEventHandler(walService, multiplexingService, eventsCache, events.NewJitsuParser(maxEventSize, maxCachedEventsErrSize), processorHolder.GetAPIPreprocessor(), destinations, geoService)
+	jsEventHandler := handlers.NewEventHandler(walService, multiplexingService, eventsCache, events.NewJitsuParser(maxEventSize, maxCachedEventsErrSize, binaryDecoder), processorHolder.GetJSPreprocessor(), destinations, geoService)
+	apiEventHandler := handlers.NewEventHandler(walService, multiplexingService, eventsCache, events.NewJitsuParser(maxEventSize, maxCachedEventsErrSize, binaryDecoder), processorHolder.GetAPIPreprocessor(), destinations, geoService)
 	segmentHandler := handlers.NewEventHandler(walService, multiplexingService, eventsCache, events.NewSegmentParser(segmentEndpointFieldMapper, appconfig.Instance.GlobalUniqueIDField, maxEventSize, maxCachedEventsErrSize), processorHolder.GetSegmentPreprocessor(), destinations, geoService)
 	segmentCompatHandler := handlers.NewEventHandler(walService, multiplexingService, eventsCache, events.NewSegmentCompatParser(segmentCompatEndpointFieldMapper, appconfig.Instance.GlobalUniqueIDField, maxEventSize, maxCachedEventsErrSize), processorHolder.GetSegmentPreprocessor(), destinations, geoService)
 
+	segmentSettingsHandler := handlers.NewSegmentSettingsHandler(publicURL)
+
 	taskHandler := handlers.NewTaskHandler(taskService, sourcesService)
 	fallbackHandler := handlers.NewFallbackHandler(fallbackService)
+	erasureHandler := handlers.NewErasureHandler(erasureService)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
 	dryRunHandler := handlers.NewDryRunHandler(destinations, processorHolder.GetJSPreprocessor(), geoService)
 	statisticsHandler := handlers.NewStatisticsHandler(metaStorage)
+	grafanaHandler := handlers.NewGrafanaHandler(metaStorage, appconfig.Instance.AuthorizationService.GetAllTokenIDs,
+		sourcesService.GetSourceIDs, destinations.GetAllDestinationIDs)
 
 	airbyteHandler := handlers.NewAirbyteHandler()
 	sdkSourceHandler := handlers.NewSdkSourceHandler()
 	sourcesHandler := handlers.NewSourcesHandler(sourcesService, metaStorage, destinations)
 	pixelHandler := handlers.NewPixelHandler(multiplexingService, processorHolder.GetPixelPreprocessor(), destinations, geoService)
 
-	bulkHandler := handlers.NewBulkHandler(destinations, processorHolder.GetBulkPreprocessor())
+	bulkHandler := handlers.NewBulkHandler(destinations, processorHolder.GetBulkPreprocessor(), geoService)
+	eventsTailHandler := handlers.NewEventsTailHandler(eventsCache)
 
 	geoDataResolverHandler := handlers.NewGeoDataResolverHandler(geoService)
 
+	//Segment HTTP Tracking API compatible endpoints (Segment libraries can be pointed to Jitsu host)
+	segmentV1 := router.Group("/v1")
+	{
+		segmentAuth := func(handler gin.HandlerFunc) gin.HandlerFunc {
+			return middleware.SegmentWriteKeyAuth(handler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins)
+		}
+		for _, call := range []string{"batch", "b", "track", "t", "identify", "i", "page", "p", "screen", "s", "group", "g", "alias", "a"} {
+			segmentV1.POST("/"+call, ingestion(segmentAuth(segmentHandler.PostHandler)))
+		}
+		segmentV1.GET("/projects/:writeKey/settings", segmentSettingsHandler.Handler)
+	}
+
 	adminTokenMiddleware := middleware.AdminToken{Token: adminToken}
 	apiV1 := router.Group("/api/v1")
 	{
 		//client endpoint
-		apiV1.POST("/event", middleware.TokenFuncAuth(jsEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
-		apiV1.POST("/events", middleware.TokenFuncAuth(jsEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
+		apiV1.POST("/event", ingestion(middleware.TokenFuncAuth(jsEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetClientOrigins, "")))
+		apiV1.POST("/events", ingestion(middleware.TokenFuncAuth(jsEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetClientOrigins, "")))
 		//server endpoint
-		apiV1.POST("/s2s/event", middleware.TokenTwoFuncAuth(apiEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server secret token. Please use an s2s integration token"))
-		apiV1.POST("/s2s/event/", middleware.TokenTwoFuncAuth(apiEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server secret token. Please use an s2s integration token"))
-		apiV1.POST("/s2s/events", middleware.TokenTwoFuncAuth(apiEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server secret token. Please use an s2s integration token"))
+		apiV1.POST("/s2s/event", ingestion(middleware.TokenTwoFuncAuth(apiEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server secret token. Please use an s2s integration token")))
+		apiV1.POST("/s2s/event/", ingestion(middleware.TokenTwoFuncAuth(apiEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server secret token. Please use an s2s integration token")))
+		apiV1.POST("/s2s/events", ingestion(middleware.TokenTwoFuncAuth(apiEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server secret token. Please use an s2s integration token")))
 		//Segment API
-		apiV1.POST("/segment/v1/batch", middleware.TokenFuncAuth(segmentHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, ""))
-		apiV1.POST("/segment", middleware.TokenFuncAuth(segmentHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, ""))
+		apiV1.POST("/segment/v1/batch", ingestion(middleware.TokenFuncAuth(segmentHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, "")))
+		apiV1.POST("/segment", ingestion(middleware.TokenFuncAuth(segmentHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, "")))
 		//Segment compat API
-		apiV1.POST("/segment/compat/v1/batch", middleware.TokenFuncAuth(segmentCompatHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, ""))
-		apiV1.POST("/segment/compat", middleware.TokenFuncAuth(segmentCompatHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, ""))
+		apiV1.POST("/segment/compat/v1/batch", ingestion(middleware.TokenFuncAuth(segmentCompatHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, "")))
+		apiV1.POST("/segment/compat", ingestion(middleware.TokenFuncAuth(segmentCompatHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, "")))
 		//Tracking pixel API
 		apiV1.GET("/p.gif", pixelHandler.Handle)
 		//bulk endpoint
-		apiV1.POST("/events/bulk", middleware.TokenTwoFuncAuth(bulkHandler.BulkLoadingHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server token. Please use an s2s integration token"))
+		apiV1.POST("/events/bulk", ingestion(middleware.TokenTwoFuncAuth(bulkHandler.BulkLoadingHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server token. Please use an s2s integration token")))
 
 		//Dry run
 		apiV1.POST("/events/dry-run", middleware.TokenTwoFuncAuth(dryRunHandler.Handle, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
@@ -123,12 +160,21 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.GET("/geo_data_resolvers/editions", adminTokenMiddleware.AdminAuth(geoDataResolverHandler.EditionsHandler))
 		apiV1.POST("/geo_data_resolvers/test", adminTokenMiddleware.AdminAuth(geoDataResolverHandler.TestHandler))
 		apiV1.POST("/destinations/test", adminTokenMiddleware.AdminAuth(handlers.NewDestinationsHandler(userRecognition).Handler))
+		apiV1.GET("/destinations/circuit_breakers", adminTokenMiddleware.AdminAuth(handlers.CircuitBreakersHandler))
+		apiV1.GET("/destinations/lag", adminTokenMiddleware.AdminAuth(handlers.StreamingLagHandler))
+		apiV1.GET("/destinations/mirrors", adminTokenMiddleware.AdminAuth(handlers.NewMirrorsHandler(destinations, metaStorage).Handler))
+		apiV1.GET("/logging/levels", adminTokenMiddleware.AdminAuth(handlers.LogLevelsHandler))
+		apiV1.POST("/logging/levels", adminTokenMiddleware.AdminAuth(handlers.UpdateLogLevelsHandler))
 		apiV1.POST("/templates/evaluate", adminTokenMiddleware.AdminAuth(handlers.NewEventTemplateHandler(destinations.GetFactory()).Handler))
+		apiV1.POST("/transformations/debug", adminTokenMiddleware.AdminAuth(handlers.NewTransformationDebugHandler().Handler))
 
 		sourcesRoute := apiV1.Group("/sources")
 		{
 			sourcesRoute.POST("/test", adminTokenMiddleware.AdminAuth(sourcesHandler.TestSourcesHandler))
 			sourcesRoute.POST("/clear_cache", adminTokenMiddleware.AdminAuth(sourcesHandler.ClearCacheHandler))
+			sourcesRoute.GET("/state", adminTokenMiddleware.AdminAuth(sourcesHandler.SourceStateHandler))
+			sourcesRoute.POST("/state", adminTokenMiddleware.AdminAuth(sourcesHandler.UpdateSourceStateHandler))
+			sourcesRoute.POST("/state/reset", adminTokenMiddleware.AdminAuth(sourcesHandler.ResetSourceStateHandler))
 			sourcesRoute.GET("/oauth_fields/:sourceType", adminTokenMiddleware.AdminAuth(sourcesHandler.OauthFields))
 		}
 
@@ -136,6 +182,19 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.GET("/statistics", adminTokenMiddleware.AdminAuth(statisticsHandler.DeprecatedGetHandler))
 
 		apiV1.GET("/statistics/detailed", adminTokenMiddleware.AdminAuth(statisticsHandler.GetHandler))
+		apiV1.GET("/statistics/rollups", adminTokenMiddleware.AdminAuth(statisticsHandler.RollupsHandler))
+
+		//Grafana JSON datasource plugin endpoints
+		grafanaRoute := apiV1.Group("/grafana")
+		{
+			grafanaRoute.GET("/", adminTokenMiddleware.AdminAuth(grafanaHandler.TestHandler))
+			grafanaRoute.POST("/search", adminTokenMiddleware.AdminAuth(grafanaHandler.SearchHandler))
+			grafanaRoute.POST("/metrics", adminTokenMiddleware.AdminAuth(grafanaHandler.MetricsHandler))
+			grafanaRoute.POST("/query", adminTokenMiddleware.AdminAuth(grafanaHandler.QueryHandler))
+			grafanaRoute.POST("/annotations", adminTokenMiddleware.AdminAuth(grafanaHandler.AnnotationsHandler))
+		}
+
+		apiV1.GET("/quotas", adminTokenMiddleware.AdminAuth(handlers.QuotasHandler))
 
 		apiV1.GET("/tasks", adminTokenMiddleware.AdminAuth(taskHandler.GetAllHandler))
 		apiV1.GET("/tasks/:taskID", adminTokenMiddleware.AdminAuth(taskHandler.GetByIDHandler))
@@ -145,13 +204,35 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 
 		apiV1.GET("/cluster", adminTokenMiddleware.AdminAuth(handlers.NewClusterHandler(coordinationService).Handler))
 		apiV1.GET("/events/cache", adminTokenMiddleware.AdminAuth(jsEventHandler.GetHandler))
+		apiV1.GET("/events/tail", adminTokenMiddleware.AdminAuth(eventsTailHandler.Handler))
+		apiV1.GET("/events/delivery", adminTokenMiddleware.AdminAuth(handlers.DeliverySearchHandler))
+		apiV1.GET("/events/delivery/:eventID", adminTokenMiddleware.AdminAuth(handlers.DeliveryHandler))
+		apiV1.GET("/schema_registry", adminTokenMiddleware.AdminAuth(handlers.SchemaRegistryHandler))
+		apiV1.GET("/schema_registry/history", adminTokenMiddleware.AdminAuth(handlers.SchemaHistoryHandler))
 
 		apiV1.GET("/fallback", adminTokenMiddleware.AdminAuth(fallbackHandler.GetHandler))
 		apiV1.POST("/replay", adminTokenMiddleware.AdminAuth(fallbackHandler.ReplayHandler))
+		apiV1.POST("/replay/archive", adminTokenMiddleware.AdminAuth(fallbackHandler.ArchiveReplayHandler))
+		apiV1.GET("/replay/archive/tasks", adminTokenMiddleware.AdminAuth(fallbackHandler.ArchiveReplayTasksHandler))
+		apiV1.GET("/replay/archive/tasks/:taskID", adminTokenMiddleware.AdminAuth(fallbackHandler.ArchiveReplayTaskHandler))
+		apiV1.DELETE("/replay/archive/tasks/:taskID", adminTokenMiddleware.AdminAuth(fallbackHandler.CancelArchiveReplayTaskHandler))
+
+		apiV1.POST("/erasure", adminTokenMiddleware.AdminAuth(erasureHandler.EraseHandler))
+		apiV1.GET("/erasure/reports", adminTokenMiddleware.AdminAuth(erasureHandler.ReportsHandler))
+		apiV1.GET("/erasure/reports/:reportID", adminTokenMiddleware.AdminAuth(erasureHandler.ReportHandler))
+
+		apiV1.POST("/retention", adminTokenMiddleware.AdminAuth(retentionHandler.RunHandler))
+		apiV1.GET("/retention/reports", adminTokenMiddleware.AdminAuth(retentionHandler.ReportsHandler))
+		apiV1.GET("/retention/reports/:reportID", adminTokenMiddleware.AdminAuth(retentionHandler.ReportHandler))
 
 		apiV1.GET("/airbyte/:dockerImageName/spec", adminTokenMiddleware.AdminAuth(airbyteHandler.SpecHandler))
 		apiV1.GET("/airbyte/:dockerImageName/versions", adminTokenMiddleware.AdminAuth(airbyteHandler.VersionsHandler))
 		apiV1.POST("/airbyte/:dockerImageName/catalog", adminTokenMiddleware.AdminAuth(airbyteHandler.CatalogHandler))
+		apiV1.GET("/airbyte/:dockerImageName/versions/pinned", adminTokenMiddleware.AdminAuth(airbyteHandler.PinnedVersionHandler))
+		apiV1.POST("/airbyte/:dockerImageName/versions/pinned", adminTokenMiddleware.AdminAuth(airbyteHandler.PinVersionHandler))
+		apiV1.DELETE("/airbyte/:dockerImageName/versions/pinned", adminTokenMiddleware.AdminAuth(airbyteHandler.UnpinVersionHandler))
+		apiV1.POST("/airbyte/:dockerImageName/versions/rollback", adminTokenMiddleware.AdminAuth(airbyteHandler.RollbackVersionHandler))
+		apiV1.POST("/airbyte/:dockerImageName/versions/stage", adminTokenMiddleware.AdminAuth(airbyteHandler.StageVersionHandler))
 
 		apiV1.GET("/sdk_source/spec", adminTokenMiddleware.AdminAuth(sdkSourceHandler.SpecHandler))
 		apiV1.POST("/sdk_source/catalog", adminTokenMiddleware.AdminAuth(sdkSourceHandler.CatalogHandler))
@@ -159,7 +240,7 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.POST("/singer/:tap/catalog", adminTokenMiddleware.AdminAuth(handlers.NewSingerHandler().CatalogHandler))
 	}
 
-	router.POST("/api.:ignored", middleware.TokenFuncAuth(jsEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
+	router.POST("/api.:ignored", ingestion(middleware.TokenFuncAuth(jsEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetClientOrigins, "")))
 
 	if metrics.Exported {
 		router.GET("/prometheus", middleware.TokenAuth(gin.WrapH(metrics.Handler()), adminToken))
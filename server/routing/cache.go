@@ -0,0 +1,56 @@
+package routing
+
+import (
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/resources"
+)
+
+type cachedRouter struct {
+	hash   uint64
+	router *Router
+}
+
+// Cache keeps compiled routers by API key id. A router is recompiled when the API key configuration is changed
+type Cache struct {
+	mutex   sync.RWMutex
+	routers map[string]*cachedRouter
+}
+
+// NewCache returns empty Cache
+func NewCache() *Cache {
+	return &Cache{routers: map[string]*cachedRouter{}}
+}
+
+// Get returns compiled router of the API key or nil if there are no routing rules.
+// Invalid configurations are logged and such API keys events are sent to all destinations
+func (c *Cache) Get(tokenID string, config *Config) *Router {
+	if config.IsEmpty() {
+		return nil
+	}
+
+	hash, err := resources.GetHash(config)
+	if err != nil {
+		logging.SystemErrorf("[%s] Error getting hash of events routing configuration: %v", tokenID, err)
+		return nil
+	}
+
+	c.mutex.RLock()
+	cached, ok := c.routers[tokenID]
+	c.mutex.RUnlock()
+	if ok && cached.hash == hash {
+		return cached.router
+	}
+
+	router, err := NewRouter(config)
+	if err != nil {
+		logging.Errorf("[%s] Error creating events router. Events of the API key will be sent to all destinations: %v", tokenID, err)
+	}
+
+	c.mutex.Lock()
+	c.routers[tokenID] = &cachedRouter{hash: hash, router: router}
+	c.mutex.Unlock()
+
+	return router
+}
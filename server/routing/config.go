@@ -0,0 +1,70 @@
+package routing
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Condition selects events by field values. All non-empty parts must match (AND), any of the listed values
+// of a part must match (OR). Values support * wildcard e.g. "*.example.com"
+type Condition struct {
+	EventTypes []string          `mapstructure:"event_types" json:"event_types,omitempty" yaml:"event_types,omitempty"`
+	Hosts      []string          `mapstructure:"hosts" json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	Fields     []*FieldCondition `mapstructure:"fields" json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// FieldCondition matches an event field value by JSON path e.g. /user/plan
+type FieldCondition struct {
+	Path   string   `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+	Values []string `mapstructure:"values" json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// Rule routes matched events into Destinations. If Table is set, it is used instead of the destination table name template
+type Rule struct {
+	Name         string    `mapstructure:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	When         Condition `mapstructure:"when" json:"when,omitempty" yaml:"when,omitempty"`
+	Destinations []string  `mapstructure:"destinations" json:"destinations,omitempty" yaml:"destinations,omitempty"`
+	Table        string    `mapstructure:"table" json:"table,omitempty" yaml:"table,omitempty"`
+}
+
+// Config is a configuration of events routing per API key. Rules are evaluated in order and the first matched rule
+// is applied. Events which don't match any rule are sent to UnmatchedDestinations (all API key destinations if empty)
+// or are dropped if DropUnmatched is true
+type Config struct {
+	Rules                 []*Rule  `mapstructure:"rules" json:"rules,omitempty" yaml:"rules,omitempty"`
+	UnmatchedDestinations []string `mapstructure:"unmatched_destinations" json:"unmatched_destinations,omitempty" yaml:"unmatched_destinations,omitempty"`
+	DropUnmatched         bool     `mapstructure:"drop_unmatched" json:"drop_unmatched,omitempty" yaml:"drop_unmatched,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	for i, rule := range c.Rules {
+		if rule == nil {
+			return fmt.Errorf("rules[%d]: rule is empty", i)
+		}
+		if len(rule.Destinations) == 0 {
+			return fmt.Errorf("rules[%d]: destinations are required", i)
+		}
+		if rule.Table != "" && !tableNameRegex.MatchString(rule.Table) {
+			return fmt.Errorf("rules[%d]: table must contain only letters, digits and underscores. Got: %q", i, rule.Table)
+		}
+		for j, field := range rule.When.Fields {
+			if field == nil || field.Path == "" {
+				return fmt.Errorf("rules[%d]: fields[%d]: path is required", i, j)
+			}
+		}
+	}
+
+	if c.DropUnmatched && len(c.UnmatchedDestinations) > 0 {
+		return fmt.Errorf("drop_unmatched and unmatched_destinations can't be used together")
+	}
+
+	return nil
+}
+
+// IsEmpty returns true if events aren't routed: all events are sent to all API key destinations
+func (c *Config) IsEmpty() bool {
+	return c == nil || (len(c.Rules) == 0 && len(c.UnmatchedDestinations) == 0 && !c.DropUnmatched)
+}
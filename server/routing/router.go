@@ -0,0 +1,235 @@
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+const (
+	// DestinationsParameter is set into routed events. It contains IDs of destinations which must store the event.
+	// Events without the parameter are stored by all API key destinations
+	DestinationsParameter = "JITSU_ROUTING_DESTINATIONS"
+	// TableParameter is set into events routed by a rule with the table. Destinations use it instead of the table name template
+	TableParameter = "JITSU_ROUTING_TABLE"
+)
+
+var hostPath = jsonutils.NewJSONPath("/eventn_ctx/doc_host||/doc_host")
+
+// Route is a result of events routing: destinations and an optional table name
+type Route struct {
+	// Rule is a matched rule name (or an index if the rule doesn't have a name). Empty for unmatched events
+	Rule         string
+	Destinations []string
+	Table        string
+}
+
+type valueMatcher struct {
+	exact   map[string]bool
+	regexps []*regexp.Regexp
+}
+
+type fieldMatcher struct {
+	path    jsonutils.JSONPath
+	matcher *valueMatcher
+}
+
+type compiledRule struct {
+	name         string
+	eventTypes   *valueMatcher
+	hosts        *valueMatcher
+	fields       []*fieldMatcher
+	destinations []string
+	table        string
+}
+
+// Router evaluates routing rules of an API key against events before transformation
+type Router struct {
+	rules                 []*compiledRule
+	unmatchedDestinations []string
+	dropUnmatched         bool
+}
+
+// NewRouter returns configured Router or nil if events aren't routed
+func NewRouter(config *Config) (*Router, error) {
+	if config.IsEmpty() {
+		return nil, nil
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	router := &Router{unmatchedDestinations: config.UnmatchedDestinations, dropUnmatched: config.DropUnmatched}
+	for i, rule := range config.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+
+		compiled := &compiledRule{
+			name:         name,
+			eventTypes:   newValueMatcher(rule.When.EventTypes),
+			hosts:        newValueMatcher(rule.When.Hosts),
+			destinations: rule.Destinations,
+			table:        rule.Table,
+		}
+		for j, field := range rule.When.Fields {
+			path := jsonutils.NewJSONPath(field.Path)
+			if path.IsEmpty() {
+				return nil, fmt.Errorf("rules[%d]: fields[%d]: path must be a valid path like: /node1/node2. Got: %q", i, j, field.Path)
+			}
+			compiled.fields = append(compiled.fields, &fieldMatcher{path: path, matcher: newValueMatcher(field.Values)})
+		}
+
+		router.rules = append(router.rules, compiled)
+	}
+
+	return router, nil
+}
+
+// Route returns the route of the first matched rule or the unmatched route.
+// Returns nil route if the event should be sent to all API key destinations and false if the event should be dropped
+func (r *Router) Route(event events.Event) (*Route, bool) {
+	if r == nil {
+		return nil, true
+	}
+
+	for _, rule := range r.rules {
+		if rule.match(event) {
+			return &Route{Rule: rule.name, Destinations: rule.destinations, Table: rule.table}, true
+		}
+	}
+
+	if r.dropUnmatched {
+		return nil, false
+	}
+
+	if len(r.unmatchedDestinations) > 0 {
+		return &Route{Destinations: r.unmatchedDestinations}, true
+	}
+
+	return nil, true
+}
+
+func (cr *compiledRule) match(event events.Event) bool {
+	if cr.eventTypes != nil {
+		eventType, _ := event[events.EventType]
+		if !cr.eventTypes.match(eventType) {
+			return false
+		}
+	}
+
+	if cr.hosts != nil {
+		host, _ := hostPath.Get(event)
+		if !cr.hosts.match(host) {
+			return false
+		}
+	}
+
+	for _, field := range cr.fields {
+		value, ok := field.path.Get(event)
+		if !ok {
+			return false
+		}
+		if field.matcher != nil && !field.matcher.match(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newValueMatcher returns nil if values are empty (any value matches)
+func newValueMatcher(values []string) *valueMatcher {
+	if len(values) == 0 {
+		return nil
+	}
+
+	vm := &valueMatcher{exact: map[string]bool{}}
+	for _, value := range values {
+		if !strings.Contains(value, "*") {
+			vm.exact[value] = true
+			continue
+		}
+
+		parts := strings.Split(value, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		vm.regexps = append(vm.regexps, regexp.MustCompile("^"+strings.Join(parts, ".*")+"$"))
+	}
+
+	return vm
+}
+
+func (vm *valueMatcher) match(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		str = fmt.Sprint(value)
+	}
+
+	if vm.exact[str] {
+		return true
+	}
+	for _, re := range vm.regexps {
+		if re.MatchString(str) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply writes the route into the event parameters. Does nothing if the route is nil
+func Apply(event events.Event, route *Route) {
+	if route == nil {
+		return
+	}
+
+	event[DestinationsParameter] = route.Destinations
+	if route.Table != "" {
+		event[TableParameter] = route.Table
+	}
+}
+
+// IsRoutedTo returns true if the event should be stored by the destination.
+// Events are serialized into JSON in batch mode so destinations are either []string or []interface{}
+func IsRoutedTo(event map[string]interface{}, destinationID string) bool {
+	destinations, ok := event[DestinationsParameter]
+	if !ok {
+		return true
+	}
+
+	switch ids := destinations.(type) {
+	case []string:
+		for _, id := range ids {
+			if id == destinationID {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, id := range ids {
+			if id == destinationID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ExtractTable returns the routed table name and removes routing parameters from the object
+func ExtractTable(object map[string]interface{}) string {
+	table, _ := object[TableParameter].(string)
+	delete(object, DestinationsParameter)
+	delete(object, TableParameter)
+	return table
+}
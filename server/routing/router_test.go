@@ -0,0 +1,141 @@
+package routing
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRouter(t *testing.T) {
+	router, err := NewRouter(nil)
+	require.NoError(t, err)
+	require.Nil(t, router)
+	route, ok := router.Route(events.Event{})
+	require.True(t, ok, "nil router must accept all events")
+	require.Nil(t, route)
+
+	_, err = NewRouter(&Config{Rules: []*Rule{{Name: "no destinations"}}})
+	require.Error(t, err)
+	_, err = NewRouter(&Config{Rules: []*Rule{{Destinations: []string{"d1"}, Table: "drop table"}}})
+	require.Error(t, err)
+	_, err = NewRouter(&Config{Rules: []*Rule{{Destinations: []string{"d1"}, When: Condition{Fields: []*FieldCondition{{Values: []string{"a"}}}}}}})
+	require.Error(t, err)
+	_, err = NewRouter(&Config{DropUnmatched: true, UnmatchedDestinations: []string{"d1"}})
+	require.Error(t, err)
+}
+
+func TestRoute(t *testing.T) {
+	router, err := NewRouter(&Config{
+		Rules: []*Rule{
+			{
+				Name:         "purchases",
+				When:         Condition{EventTypes: []string{"purchase", "refund"}, Hosts: []string{"*.shop.com"}},
+				Destinations: []string{"billing", "dwh"},
+				Table:        "orders",
+			},
+			{
+				When:         Condition{Fields: []*FieldCondition{{Path: "/user/plan", Values: []string{"pro*"}}, {Path: "/user/beta"}}},
+				Destinations: []string{"beta"},
+			},
+			{
+				Name:         "numbers",
+				When:         Condition{Fields: []*FieldCondition{{Path: "/level", Values: []string{"1", "true"}}}},
+				Destinations: []string{"levels"},
+			},
+		},
+		UnmatchedDestinations: []string{"dwh"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		event    events.Event
+		expected *Route
+	}{
+		{
+			"event type and host",
+			events.Event{"event_type": "refund", "eventn_ctx": map[string]interface{}{"doc_host": "eu.shop.com"}},
+			&Route{Rule: "purchases", Destinations: []string{"billing", "dwh"}, Table: "orders"},
+		},
+		{
+			"host without context",
+			events.Event{"event_type": "purchase", "doc_host": "us.shop.com"},
+			&Route{Rule: "purchases", Destinations: []string{"billing", "dwh"}, Table: "orders"},
+		},
+		{
+			"host doesn't match",
+			events.Event{"event_type": "purchase", "doc_host": "shop.com"},
+			&Route{Destinations: []string{"dwh"}},
+		},
+		{
+			"fields",
+			events.Event{"user": map[string]interface{}{"plan": "professional", "beta": false}},
+			&Route{Rule: "#1", Destinations: []string{"beta"}},
+		},
+		{
+			"field is absent",
+			events.Event{"user": map[string]interface{}{"plan": "professional"}},
+			&Route{Destinations: []string{"dwh"}},
+		},
+		{
+			"number value",
+			events.Event{"level": 1.0},
+			&Route{Rule: "numbers", Destinations: []string{"levels"}},
+		},
+		{
+			"bool value",
+			events.Event{"level": true},
+			&Route{Rule: "numbers", Destinations: []string{"levels"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, ok := router.Route(tt.event)
+			require.True(t, ok)
+			require.Equal(t, tt.expected, route)
+		})
+	}
+}
+
+func TestRouteUnmatched(t *testing.T) {
+	rules := []*Rule{{When: Condition{EventTypes: []string{"purchase"}}, Destinations: []string{"billing"}}}
+
+	router, err := NewRouter(&Config{Rules: rules})
+	require.NoError(t, err)
+	route, ok := router.Route(events.Event{"event_type": "pageview"})
+	require.True(t, ok)
+	require.Nil(t, route, "unmatched events must be sent to all destinations by default")
+
+	router, err = NewRouter(&Config{Rules: rules, DropUnmatched: true})
+	require.NoError(t, err)
+	_, ok = router.Route(events.Event{"event_type": "pageview"})
+	require.False(t, ok)
+	_, ok = router.Route(events.Event{"event_type": "purchase"})
+	require.True(t, ok)
+}
+
+func TestRoutingParameters(t *testing.T) {
+	event := events.Event{"event_type": "purchase"}
+	require.True(t, IsRoutedTo(event, "d1"), "not routed events must be stored by all destinations")
+
+	Apply(event, nil)
+	require.Equal(t, events.Event{"event_type": "purchase"}, event)
+
+	Apply(event, &Route{Destinations: []string{"d1", "d2"}, Table: "orders"})
+	require.True(t, IsRoutedTo(event, "d2"))
+	require.False(t, IsRoutedTo(event, "d3"))
+
+	//batch mode: events are written into files and parsed back
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	parsed := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(b, &parsed))
+	require.True(t, IsRoutedTo(parsed, "d1"))
+	require.False(t, IsRoutedTo(parsed, "d3"))
+
+	require.Equal(t, "orders", ExtractTable(parsed))
+	require.Equal(t, map[string]interface{}{"event_type": "purchase"}, parsed)
+	require.Equal(t, "", ExtractTable(parsed))
+}
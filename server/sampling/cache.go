@@ -0,0 +1,56 @@
+package sampling
+
+import (
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/resources"
+)
+
+type cachedSampler struct {
+	hash    uint64
+	sampler *Sampler
+}
+
+// Cache keeps compiled samplers by API key id. A sampler is recompiled when the API key configuration is changed
+type Cache struct {
+	mutex    sync.RWMutex
+	samplers map[string]*cachedSampler
+}
+
+// NewCache returns empty Cache
+func NewCache() *Cache {
+	return &Cache{samplers: map[string]*cachedSampler{}}
+}
+
+// Get returns compiled sampler of the API key or nil if events aren't sampled.
+// Invalid configurations are logged and all such API keys events are kept
+func (c *Cache) Get(tokenID string, config *Config) *Sampler {
+	if config.IsEmpty() {
+		return nil
+	}
+
+	hash, err := resources.GetHash(config)
+	if err != nil {
+		logging.SystemErrorf("[%s] Error getting hash of events sampling configuration: %v", tokenID, err)
+		return nil
+	}
+
+	c.mutex.RLock()
+	cached, ok := c.samplers[tokenID]
+	c.mutex.RUnlock()
+	if ok && cached.hash == hash {
+		return cached.sampler
+	}
+
+	sampler, err := NewSampler(config)
+	if err != nil {
+		logging.Errorf("[%s] Error creating events sampler. All events of the API key will be kept: %v", tokenID, err)
+	}
+
+	c.mutex.Lock()
+	c.samplers[tokenID] = &cachedSampler{hash: hash, sampler: sampler}
+	c.mutex.Unlock()
+
+	return sampler
+}
@@ -0,0 +1,92 @@
+package sampling
+
+import (
+	"errors"
+	"fmt"
+)
+
+// API keys priorities which can be shed. API keys without priority have normal priority
+const (
+	HighPriority   = "high"
+	NormalPriority = "normal"
+	LowPriority    = "low"
+)
+
+const (
+	defaultCheckIntervalSec = 5
+	// defaultResumeRatio is a share of the threshold below which load shedding is turned off
+	defaultResumeRatio = 0.8
+)
+
+// Rule keeps Rate share (from 0 to 1) of events with one of EventTypes (values support * wildcard).
+// Rule without event types matches all events
+type Rule struct {
+	EventTypes []string `mapstructure:"event_types" json:"event_types,omitempty" yaml:"event_types,omitempty"`
+	Rate       float64  `mapstructure:"rate" json:"rate" yaml:"rate"`
+}
+
+// Config is a configuration of events sampling per API key. Rules are evaluated in order and the first matched rule
+// is applied. Events which don't match any rule are kept
+type Config struct {
+	Rules []*Rule `mapstructure:"rules" json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	for i, rule := range c.Rules {
+		if rule == nil {
+			return fmt.Errorf("rules[%d]: rule is empty", i)
+		}
+		if rule.Rate < 0 || rule.Rate > 1 {
+			return fmt.Errorf("rules[%d]: rate must be between 0 and 1. Got: %v", i, rule.Rate)
+		}
+	}
+
+	return nil
+}
+
+// IsEmpty returns true if events aren't sampled
+func (c *Config) IsEmpty() bool {
+	return c == nil || len(c.Rules) == 0
+}
+
+// LoadSheddingConfig is a configuration of the global load shedding mode. When the total size of destinations queues
+// exceeds QueueDepthThreshold, events of low-value classes (EventTypes and API keys with Priorities) are dropped
+// until the size goes below ResumeQueueDepth
+type LoadSheddingConfig struct {
+	Enabled             bool  `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	QueueDepthThreshold int64 `mapstructure:"queue_depth_threshold" json:"queue_depth_threshold,omitempty" yaml:"queue_depth_threshold,omitempty"`
+	// ResumeQueueDepth is 80% of the threshold by default
+	ResumeQueueDepth int64 `mapstructure:"resume_queue_depth" json:"resume_queue_depth,omitempty" yaml:"resume_queue_depth,omitempty"`
+	CheckIntervalSec int   `mapstructure:"check_interval_sec" json:"check_interval_sec,omitempty" yaml:"check_interval_sec,omitempty"`
+
+	// EventTypes are dropped event types (values support * wildcard)
+	EventTypes []string `mapstructure:"event_types" json:"event_types,omitempty" yaml:"event_types,omitempty"`
+	// Priorities are API keys priorities (e.g. low) all events of which are dropped
+	Priorities []string `mapstructure:"priorities" json:"priorities,omitempty" yaml:"priorities,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *LoadSheddingConfig) Validate() error {
+	if c.QueueDepthThreshold <= 0 {
+		return errors.New("load_shedding.queue_depth_threshold must be positive")
+	}
+	if c.ResumeQueueDepth < 0 || c.ResumeQueueDepth > c.QueueDepthThreshold {
+		return fmt.Errorf("load_shedding.resume_queue_depth must be between 0 and queue_depth_threshold (%d)", c.QueueDepthThreshold)
+	}
+	if c.CheckIntervalSec < 0 {
+		return errors.New("load_shedding.check_interval_sec can't be negative")
+	}
+	for _, priority := range c.Priorities {
+		switch priority {
+		case HighPriority, NormalPriority, LowPriority:
+		default:
+			return fmt.Errorf("unknown load_shedding.priorities value: %s. Supported: %s, %s, %s", priority, HighPriority, NormalPriority, LowPriority)
+		}
+	}
+	if len(c.EventTypes) == 0 && len(c.Priorities) == 0 {
+		return errors.New("load_shedding.event_types or load_shedding.priorities are required")
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package sampling
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/safego"
+	"go.uber.org/atomic"
+)
+
+// LoadShedder drops low-value events (by event type or API key priority) while the total size of destinations queues
+// is above the threshold. The size is checked in background
+type LoadShedder struct {
+	threshold   int64
+	resumeDepth int64
+	interval    time.Duration
+	eventTypes  *eventTypesMatcher
+	priorities  map[string]bool
+
+	// queueDepth returns the total size of destinations queues
+	queueDepth func() int64
+	active     *atomic.Bool
+	closed     chan struct{}
+}
+
+// NewLoadShedder returns configured and started LoadShedder or nil if it is disabled
+func NewLoadShedder(config *LoadSheddingConfig) (*LoadShedder, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	ls := newLoadShedder(config, destinationQueuesDepth)
+	safego.Run(ls.start)
+	logging.Infof("Load shedding is enabled: events of types %v and API keys with priorities %v will be dropped when destinations queues size exceeds %d",
+		config.EventTypes, config.Priorities, ls.threshold)
+	return ls, nil
+}
+
+func newLoadShedder(config *LoadSheddingConfig, queueDepth func() int64) *LoadShedder {
+	resumeDepth := config.ResumeQueueDepth
+	if resumeDepth == 0 {
+		resumeDepth = int64(float64(config.QueueDepthThreshold) * defaultResumeRatio)
+	}
+	interval := config.CheckIntervalSec
+	if interval == 0 {
+		interval = defaultCheckIntervalSec
+	}
+
+	priorities := map[string]bool{}
+	for _, priority := range config.Priorities {
+		priorities[priority] = true
+	}
+
+	return &LoadShedder{
+		threshold:   config.QueueDepthThreshold,
+		resumeDepth: resumeDepth,
+		interval:    time.Duration(interval) * time.Second,
+		eventTypes:  newEventTypesMatcher(config.EventTypes),
+		priorities:  priorities,
+		queueDepth:  queueDepth,
+		active:      atomic.NewBool(false),
+		closed:      make(chan struct{}),
+	}
+}
+
+func (ls *LoadShedder) start() {
+	ticker := time.NewTicker(ls.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ls.closed:
+			return
+		case <-ticker.C:
+			ls.check()
+		}
+	}
+}
+
+// check turns load shedding on when the queues size exceeds the threshold and off when it goes below the resume depth
+func (ls *LoadShedder) check() {
+	depth := ls.queueDepth()
+	switch {
+	case !ls.active.Load() && depth > ls.threshold:
+		ls.active.Store(true)
+		metrics.LoadShedding(true)
+		logging.Warnf("Load shedding is turned on: destinations queues size %d exceeds %d", depth, ls.threshold)
+	case ls.active.Load() && depth < ls.resumeDepth:
+		ls.active.Store(false)
+		metrics.LoadShedding(false)
+		logging.Infof("Load shedding is turned off: destinations queues size %d is below %d", depth, ls.resumeDepth)
+	}
+}
+
+// Active returns true if low-value events are being dropped
+func (ls *LoadShedder) Active() bool {
+	return ls != nil && ls.active.Load()
+}
+
+// Drop returns true if load shedding is on and the event is a low-value one: its type is configured or
+// the API key has a configured priority
+func (ls *LoadShedder) Drop(tokenPriority string, event events.Event) bool {
+	if !ls.Active() {
+		return false
+	}
+
+	if tokenPriority == "" {
+		tokenPriority = NormalPriority
+	}
+	if ls.priorities[tokenPriority] {
+		return true
+	}
+
+	return ls.eventTypes != nil && ls.eventTypes.match(event)
+}
+
+// Close stops queues size checks
+func (ls *LoadShedder) Close() error {
+	if ls != nil {
+		close(ls.closed)
+	}
+
+	return nil
+}
+
+// destinationQueuesDepth returns the total size of all destinations queues of the server
+func destinationQueuesDepth() int64 {
+	var depth int64
+	for _, size := range events.DestinationQueueSizes() {
+		depth += size
+	}
+
+	return depth
+}
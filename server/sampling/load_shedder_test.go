@@ -0,0 +1,64 @@
+package sampling
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSheddingConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *LoadSheddingConfig
+		valid  bool
+	}{
+		{"valid", &LoadSheddingConfig{QueueDepthThreshold: 100, EventTypes: []string{"scroll"}}, true},
+		{"priorities only", &LoadSheddingConfig{QueueDepthThreshold: 100, Priorities: []string{LowPriority}}, true},
+		{"without threshold", &LoadSheddingConfig{EventTypes: []string{"scroll"}}, false},
+		{"resume depth above threshold", &LoadSheddingConfig{QueueDepthThreshold: 100, ResumeQueueDepth: 200, EventTypes: []string{"scroll"}}, false},
+		{"unknown priority", &LoadSheddingConfig{QueueDepthThreshold: 100, Priorities: []string{"lowest"}}, false},
+		{"without event classes", &LoadSheddingConfig{QueueDepthThreshold: 100}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadShedder(t *testing.T) {
+	depth := int64(0)
+	ls := newLoadShedder(&LoadSheddingConfig{QueueDepthThreshold: 100, EventTypes: []string{"scroll"}, Priorities: []string{NormalPriority}},
+		func() int64 { return depth })
+	scroll := events.Event{"event_type": "scroll"}
+	purchase := events.Event{"event_type": "purchase"}
+
+	require.False(t, ls.Drop(LowPriority, scroll), "events mustn't be dropped while load shedding is off")
+
+	depth = 101
+	ls.check()
+	require.True(t, ls.Active())
+	require.True(t, ls.Drop(LowPriority, scroll))
+	require.False(t, ls.Drop(LowPriority, purchase))
+	require.False(t, ls.Drop(HighPriority, purchase))
+	require.True(t, ls.Drop("", purchase), "API keys without priority have normal priority")
+
+	//load shedding is turned off only below 80% of the threshold
+	depth = 90
+	ls.check()
+	require.True(t, ls.Active())
+	depth = 79
+	ls.check()
+	require.False(t, ls.Active())
+	require.False(t, ls.Drop("", scroll))
+
+	var disabled *LoadShedder
+	require.False(t, disabled.Drop(LowPriority, scroll))
+	require.NoError(t, disabled.Close())
+}
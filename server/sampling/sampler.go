@@ -0,0 +1,110 @@
+package sampling
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/events"
+)
+
+// eventTypesMatcher matches event types exactly or by * wildcard patterns
+type eventTypesMatcher struct {
+	exact   map[string]bool
+	regexps []*regexp.Regexp
+}
+
+type compiledRule struct {
+	eventTypes *eventTypesMatcher
+	rate       float64
+}
+
+// Sampler keeps a configured share of API key events
+type Sampler struct {
+	rules []*compiledRule
+	// random returns a number in [0, 1)
+	random func() float64
+}
+
+// NewSampler returns configured Sampler or nil if events aren't sampled
+func NewSampler(config *Config) (*Sampler, error) {
+	if config.IsEmpty() {
+		return nil, nil
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	sampler := &Sampler{random: rand.Float64}
+	for _, rule := range config.Rules {
+		sampler.rules = append(sampler.rules, &compiledRule{eventTypes: newEventTypesMatcher(rule.EventTypes), rate: rule.Rate})
+	}
+
+	return sampler, nil
+}
+
+// Keep returns false if the event is sampled out by the first matched rule
+func (s *Sampler) Keep(event events.Event) bool {
+	if s == nil {
+		return true
+	}
+
+	for _, rule := range s.rules {
+		if rule.eventTypes.match(event) {
+			return rule.rate >= 1 || s.random() < rule.rate
+		}
+	}
+
+	return true
+}
+
+// newEventTypesMatcher returns nil if event types are empty (any event matches)
+func newEventTypesMatcher(eventTypes []string) *eventTypesMatcher {
+	if len(eventTypes) == 0 {
+		return nil
+	}
+
+	etm := &eventTypesMatcher{exact: map[string]bool{}}
+	for _, eventType := range eventTypes {
+		if !strings.Contains(eventType, "*") {
+			etm.exact[eventType] = true
+			continue
+		}
+
+		parts := strings.Split(eventType, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		etm.regexps = append(etm.regexps, regexp.MustCompile("^"+strings.Join(parts, ".*")+"$"))
+	}
+
+	return etm
+}
+
+func (etm *eventTypesMatcher) match(event events.Event) bool {
+	if etm == nil {
+		return true
+	}
+
+	value, ok := event[events.EventType]
+	if !ok || value == nil {
+		return false
+	}
+	eventType, ok := value.(string)
+	if !ok {
+		eventType = fmt.Sprint(value)
+	}
+
+	if etm.exact[eventType] {
+		return true
+	}
+	for _, re := range etm.regexps {
+		if re.MatchString(eventType) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,52 @@
+package sampling
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSampler(t *testing.T) {
+	sampler, err := NewSampler(nil)
+	require.NoError(t, err)
+	require.Nil(t, sampler)
+	require.True(t, sampler.Keep(events.Event{}), "nil sampler must keep all events")
+
+	_, err = NewSampler(&Config{Rules: []*Rule{nil}})
+	require.Error(t, err)
+	_, err = NewSampler(&Config{Rules: []*Rule{{Rate: -0.1}}})
+	require.Error(t, err)
+}
+
+func TestSamplerKeep(t *testing.T) {
+	sampler, err := NewSampler(&Config{Rules: []*Rule{
+		{EventTypes: []string{"scroll", "heartbeat_*"}, Rate: 0.1},
+		{EventTypes: []string{"debug"}, Rate: 0},
+		{EventTypes: []string{"pageview"}, Rate: 1},
+	}})
+	require.NoError(t, err)
+	sampler.random = func() float64 { return 0.5 }
+
+	require.False(t, sampler.Keep(events.Event{"event_type": "scroll"}))
+	require.False(t, sampler.Keep(events.Event{"event_type": "heartbeat_30s"}))
+	require.False(t, sampler.Keep(events.Event{"event_type": "debug"}))
+	require.True(t, sampler.Keep(events.Event{"event_type": "pageview"}))
+	require.True(t, sampler.Keep(events.Event{"event_type": "purchase"}), "events which don't match any rule must be kept")
+	require.True(t, sampler.Keep(events.Event{}))
+
+	sampler.random = func() float64 { return 0.05 }
+	require.True(t, sampler.Keep(events.Event{"event_type": "scroll"}))
+	require.False(t, sampler.Keep(events.Event{"event_type": "debug"}))
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache()
+	config := &Config{Rules: []*Rule{{Rate: 0.5}}}
+	sampler := cache.Get("key1", config)
+	require.NotNil(t, sampler)
+	require.Same(t, sampler, cache.Get("key1", config))
+	require.NotSame(t, sampler, cache.Get("key1", &Config{Rules: []*Rule{{Rate: 0.2}}}))
+	require.Nil(t, cache.Get("key1", &Config{}))
+	require.Nil(t, cache.Get("key2", &Config{Rules: []*Rule{{Rate: 2}}}), "invalid configuration must keep all events")
+}
@@ -1,6 +1,8 @@
 package schema
 
 import (
+	"fmt"
+
 	"github.com/jitsucom/jitsu/server/logging"
 	"time"
 )
@@ -87,6 +89,24 @@ func (g Granularity) Format(t time.Time) string {
 	}
 }
 
+//Parse returns time from the formatted string value representation (see Format)
+func (g Granularity) Parse(value string) (time.Time, error) {
+	switch g {
+	case HOUR:
+		return time.Parse("2006-01-02_15", value)
+	case DAY, WEEK:
+		return time.Parse("2006-01-02", value)
+	case MONTH, QUARTER:
+		return time.Parse("2006-01", value)
+	case YEAR:
+		return time.Parse("2006", value)
+	case ALL:
+		return time.Time{}, nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown granularity: %s", string(g))
+	}
+}
+
 //String returns string value representation
 func (g Granularity) String() string {
 	switch g {
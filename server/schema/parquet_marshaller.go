@@ -19,9 +19,20 @@ func NewParquetMarshaller(useGZIP bool) StronglyTypedMarshaller {
 	return pm
 }
 
+//NewNullableParquetMarshaller returns ParquetMarshaller which writes missing and nil values as nulls (optional fields)
+//instead of the type default values. It is used for staging files which are loaded into warehouses
+func NewNullableParquetMarshaller(useGZIP bool) StronglyTypedMarshaller {
+	return &ParquetMarshaller{
+		GoroutinesCount: 2,
+		UseGZIP:         useGZIP,
+		Nullable:        true,
+	}
+}
+
 type ParquetMarshaller struct {
 	GoroutinesCount int64
 	UseGZIP         bool
+	Nullable        bool
 }
 
 type parquetMetadataItem struct {
@@ -86,6 +97,9 @@ func (pm *ParquetMarshaller) parquetMetadata(bh *BatchHeader) ([]string, map[str
 		default:
 			return nil, nil, fmt.Errorf("field %s has unmappable data type", field)
 		}
+		if pm.Nullable {
+			parquetSchema[i] += ", repetitiontype=OPTIONAL"
+		}
 		i++
 	}
 	return parquetSchema, meta, nil
@@ -96,6 +110,9 @@ func (pm *ParquetMarshaller) parquetRecord(meta map[string]parquetMetadataItem,
 	for field, metaItem := range meta {
 		fieldValue, ok := obj[field]
 		if !ok || fieldValue == nil {
+			if pm.Nullable {
+				continue
+			}
 			fieldValue = metaItem.defaultValue
 		}
 		switch metaItem.dataType {
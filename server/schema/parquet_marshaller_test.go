@@ -129,6 +129,26 @@ func TestParquetRecord(t *testing.T) {
 	}
 }
 
+func TestNullableParquetMarshal(t *testing.T) {
+	pm := NewNullableParquetMarshaller(true).(*ParquetMarshaller)
+	for _, pte := range []*parquetTestEntity{fieldsOfAllTypesValueOmittedParquetTestEntity(), fieldsOfAllTypesValueAreNilParquetTestEntity()} {
+		actualMd, _, err := pm.parquetMetadata(pte.batchHeader)
+		require.NoError(t, err)
+		for _, md := range actualMd {
+			require.Contains(t, md, "repetitiontype=OPTIONAL")
+		}
+
+		actual, err := pm.parquetRecord(metaStub(pte.batchHeader), pte.inputObj)
+		require.NoError(t, err)
+		for _, v := range actual {
+			require.Nil(t, v, "missing values must be written as nulls")
+		}
+
+		_, err = pm.Marshal(pte.batchHeader, []map[string]interface{}{pte.inputObj, fieldOfAllTypesValuesArePresentParquetTestEntity().inputObj})
+		require.NoError(t, err, "parquet marshalling failed")
+	}
+}
+
 type parquetTestEntity struct {
 	//input
 	batchHeader *BatchHeader
@@ -48,16 +48,29 @@ func (pf *ProcessedFile) GetPayloadUsingStronglyTypedMarshaller(stm StronglyType
 //GetPayloadBytesWithHeader returns marshaling by marshaller func, joined with \n,  bytes
 //assume that payload can't be empty
 func (pf *ProcessedFile) GetPayloadBytesWithHeader(marshaller Marshaller) ([]byte, []string, error) {
-	var buf *bytes.Buffer
-
 	var fields []string
 	//for csv writers using , delimiter
 	if marshaller.NeedHeader() {
 		fields = pf.BatchHeader.Fields.Header()
+	}
+
+	b, err := pf.GetPayloadBytesWithFields(marshaller, fields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return b, fields, nil
+}
+
+//GetPayloadBytesWithFields returns marshaling by marshaller func with the certain fields order (e.g. table columns order)
+//fields header is written if marshaller needs it
+func (pf *ProcessedFile) GetPayloadBytesWithFields(marshaller Marshaller, fields []string) ([]byte, error) {
+	var buf *bytes.Buffer
+	if marshaller.NeedHeader() {
 		buf = bytes.NewBuffer([]byte(strings.Join(fields, ",")))
 		_, err := buf.Write([]byte("\n"))
 		if err != nil {
-			return nil, nil, fmt.Errorf("Error marshaling object in processed file: %v", err)
+			return nil, fmt.Errorf("Error marshaling object in processed file: %v", err)
 		}
 	} else {
 		buf = &bytes.Buffer{}
@@ -66,11 +79,11 @@ func (pf *ProcessedFile) GetPayloadBytesWithHeader(marshaller Marshaller) ([]byt
 	for _, object := range pf.payload {
 		err := marshaller.Marshal(fields, object, buf)
 		if err != nil {
-			return nil, nil, fmt.Errorf("Error marshaling object in processed file: %v", err)
+			return nil, fmt.Errorf("Error marshaling object in processed file: %v", err)
 		}
 	}
 
-	return buf.Bytes(), fields, nil
+	return buf.Bytes(), nil
 }
 
 //GetEventsPerSrc returns events quantity per src
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"errors"
@@ -8,17 +9,25 @@ import (
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/spf13/viper"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/consent"
+	"github.com/jitsucom/jitsu/server/dataprotection"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/filtering"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/maputils"
+	"github.com/jitsucom/jitsu/server/routing"
 	"github.com/jitsucom/jitsu/server/templates"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/tracing"
 	"github.com/jitsucom/jitsu/server/uuid"
+	"github.com/jitsucom/jitsu/server/validation"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var ErrSkipObject = errors.New("Transform or table name filter marked object to be skipped. This object will be skipped.")
@@ -33,6 +42,9 @@ var (
 		templates.TableNameParameter,
 		JitsuEnvelopParameter,
 		JitsuUserRecognizedEvent,
+		validation.QuarantineTableParameter,
+		routing.DestinationsParameter,
+		routing.TableParameter,
 	}
 )
 
@@ -50,7 +62,10 @@ type Processor struct {
 	destinationConfig       *config.DestinationConfig
 	isSQLType               bool
 	tableNameExtractor      *TableNameExtractor
+	consentStep             *consent.Step
+	filter                  *filtering.Filter
 	lookupEnrichmentStep    *enrichment.LookupEnrichmentStep
+	dataProtectionStep      *dataprotection.Step
 	transformer             templates.TemplateExecutor
 	builtinTransformer      templates.TemplateExecutor
 	fieldMapper             events.Mapper
@@ -72,11 +87,31 @@ type Processor struct {
 }
 
 func NewProcessor(destinationID string, destinationConfig *config.DestinationConfig, isSQLType bool, tableNameFuncExpression string, fieldMapper events.Mapper, enrichmentRules []enrichment.Rule, flattener Flattener, typeResolver TypeResolver, uniqueIDField *identifiers.UniqueID, maxColumnNameLen int, mappingStyle string, userRecognitionEnabled bool) (*Processor, error) {
+	dataProtection := destinationConfig.DataProtection
+	if dataProtection == nil {
+		dataProtection = dataprotection.Default()
+	}
+	dataProtectionStep, err := dataprotection.NewStep(dataProtection)
+	if err != nil {
+		return nil, fmt.Errorf("error creating data protection step: %v", err)
+	}
+	consentStep, err := consent.NewStep(destinationConfig.Consent)
+	if err != nil {
+		return nil, fmt.Errorf("error creating consent step: %v", err)
+	}
+	filter, err := filtering.NewFilter(destinationConfig.Filter)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Processor{
 		identifier:              destinationID,
 		destinationConfig:       destinationConfig,
 		isSQLType:               isSQLType,
+		consentStep:             consentStep,
+		filter:                  filter,
 		lookupEnrichmentStep:    enrichment.NewLookupEnrichmentStep(enrichmentRules),
+		dataProtectionStep:      dataProtectionStep,
 		fieldMapper:             fieldMapper,
 		pulledEventsfieldMapper: &DummyMapper{},
 		typeResolver:            typeResolver,
@@ -93,12 +128,18 @@ func NewProcessor(destinationID string, destinationConfig *config.DestinationCon
 }
 
 // ProcessEvent returns table representation, processed flatten object
+// Spans are children of the event trace context (see tracing.EventContextKey)
 func (p *Processor) ProcessEvent(event map[string]interface{}, needCopyEvent bool) ([]Envelope, error) {
+	return p.ProcessEventContext(tracing.ExtractEvent(event), event, needCopyEvent)
+}
+
+// ProcessEventContext returns table representation, processed flatten object. Spans are children of the span from ctx
+func (p *Processor) ProcessEventContext(ctx context.Context, event map[string]interface{}, needCopyEvent bool) ([]Envelope, error) {
 	if !p.transformInitialized {
 		err := fmt.Errorf("Destination: %s Attempt to use processor without running InitJavaScriptTemplates first", p.identifier)
 		return nil, err
 	}
-	return p.processObject(event, map[string]bool{}, needCopyEvent)
+	return p.processObject(ctx, event, map[string]bool{}, needCopyEvent)
 }
 
 // ProcessEvents processes events objects
@@ -120,7 +161,11 @@ func (p *Processor) ProcessEvents(fileName string, objects []map[string]interfac
 			//skip recognized event for storages with disabled/not supported UR
 			continue
 		}
-		envelops, err := p.processObject(event, alreadyUploadedTables, needCopyEvent)
+		if !routing.IsRoutedTo(event, p.identifier) {
+			//batch storages of the API key share events: skip events which are routed to other destinations
+			continue
+		}
+		envelops, err := p.processObject(tracing.ExtractEvent(event), event, alreadyUploadedTables, needCopyEvent)
 		if err != nil {
 			//handle skip object functionality
 			if err == ErrSkipObject {
@@ -130,7 +175,7 @@ func (p *Processor) ProcessEvents(fileName string, objects []map[string]interfac
 				}
 
 				originalEventBytes, _ := json.Marshal(event)
-				skippedEvents.Events = append(skippedEvents.Events, &events.SkippedEvent{Event: originalEventBytes, Error: ErrSkipObject.Error(), RecognizedEvent: recognizedEvent})
+				skippedEvents.Events = append(skippedEvents.Events, &events.SkippedEvent{Event: originalEventBytes, Error: ErrSkipObject.Error(), EventID: eventID, RecognizedEvent: recognizedEvent})
 			} else if p.breakOnError {
 				return nil, nil, nil, nil, err
 			} else {
@@ -199,7 +244,9 @@ func (p *Processor) ProcessPulledEvents(tableName string, objects []map[string]i
 		}
 		var transformed map[string]interface{}
 		if p.transformer != nil && (p.transformSourcesAllowed || viper.GetBool("experimental.source_transform_enabled")) {
+			transformStartTime := timestamp.Now()
 			rawTransformed, err := p.transformer.ProcessEvent(processedObject, nil)
+			metrics.TransformExecutionTime(p.identifier, time.Since(transformStartTime).Seconds())
 			if err != nil {
 				metrics.TransformErrors(p.identifier)
 				return nil, fmt.Errorf("failed to apply javascript transform: %v", err)
@@ -250,9 +297,9 @@ func (p *Processor) ProcessPulledEvents(tableName string, objects []map[string]i
 // skips object if tableNameExtractor returns empty string, 'null' or 'false'
 // returns table representation of object and flatten, mapped object
 // 1. extract table name
-// 2. execute enrichment.LookupEnrichmentStep and Mapping
+// 2. execute enrichment.LookupEnrichmentStep, dataprotection.Step and Mapping
 // or ErrSkipObject/another error
-func (p *Processor) processObject(object map[string]interface{}, alreadyUploadedTables map[string]bool, needCopyEvent bool) ([]Envelope, error) {
+func (p *Processor) processObject(ctx context.Context, object map[string]interface{}, alreadyUploadedTables map[string]bool, needCopyEvent bool) ([]Envelope, error) {
 	var workingObject map[string]interface{}
 	if needCopyEvent {
 		//we need to copy event when more that one storage can process the same event in parallel
@@ -260,8 +307,24 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 	} else {
 		workingObject = object
 	}
+	delete(workingObject, tracing.EventContextKey)
+	delete(workingObject, events.PriorityField)
 
+	//consent is checked before enrichment: personal data (e.g. IP address) of not consented events isn't used
+	if !p.consentStep.Execute(workingObject) {
+		return nil, ErrSkipObject
+	}
+	//invalid events (validation quarantine mode) are stored as is into the quarantine table of SQL destinations only
+	quarantineTable, quarantined := workingObject[validation.QuarantineTableParameter].(string)
+	if quarantined && !p.isSQLType {
+		return nil, ErrSkipObject
+	}
+	//routing table is used instead of the table name template. Table name from the transformation has priority
+	routedTable := routing.ExtractTable(workingObject)
 	p.lookupEnrichmentStep.Execute(workingObject)
+	if err := p.dataProtectionStep.Execute(workingObject); err != nil {
+		return nil, err
+	}
 	mappedObject, err := p.fieldMapper.Map(workingObject)
 	if err != nil {
 		return nil, fmt.Errorf("Error mapping object: %v", err)
@@ -270,9 +333,20 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 	if err != nil {
 		return nil, err
 	}
+	//native filter expression is evaluated before the transformation: filtered out events don't invoke the script runtime
+	if !p.filter.Match(mappedObject) {
+		return nil, ErrSkipObject
+	}
 	var transformed interface{}
-	if p.transformer != nil {
+	if quarantined {
+		delete(mappedObject, validation.QuarantineTableParameter)
+		transformed = mappedObject
+	} else if p.transformer != nil {
+		_, transformSpan := tracing.Start(ctx, "jitsu.transform", attribute.String("jitsu.destination_id", p.identifier))
+		transformStartTime := timestamp.Now()
 		transformed, err = p.transformer.ProcessEvent(mappedObject, nil)
+		metrics.TransformExecutionTime(p.identifier, time.Since(transformStartTime).Seconds())
+		tracing.End(transformSpan, err)
 		if err != nil {
 			metrics.TransformErrors(p.identifier)
 			return nil, fmt.Errorf("failed to apply javascript transform: %v", err)
@@ -284,7 +358,7 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 		//transform that returns null causes skipped event
 		return nil, ErrSkipObject
 	}
-	if p.builtinTransformer != nil {
+	if p.builtinTransformer != nil && !quarantined {
 		transformedObj, ok := transformed.(map[string]interface{})
 		if !ok {
 			return nil, fmt.Errorf("builtin javascript transform requires object. Got: %T", transformed)
@@ -346,7 +420,11 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 			}
 		}
 		tableName, tableNameFromTransform := prObject[templates.TableNameParameter].(string)
-		if !tableNameFromTransform {
+		if quarantined {
+			tableName = quarantineTable
+		} else if !tableNameFromTransform && routedTable != "" {
+			tableName = routedTable
+		} else if !tableNameFromTransform {
 			tableName, err = p.tableNameExtractor.Extract(prObject)
 			if err != nil {
 				return nil, err
@@ -412,6 +490,11 @@ func (p *Processor) foldLongFields(header *BatchHeader, object map[string]interf
 	return header, object, nil
 }
 
+// TableNameExpression returns configured table name template (or a static table name)
+func (p *Processor) TableNameExpression() string {
+	return p.tableNameFuncExpression
+}
+
 // AddJavaScript loads javascript to transformation template's vm
 func (p *Processor) AddJavaScript(js string) {
 	p.javaScripts = append(p.javaScripts, js)
@@ -459,7 +542,8 @@ func (p *Processor) InitJavaScriptTemplates() (err error) {
 	p.AddJavaScriptVariables(templateVariables)
 
 	transformDisabled := false
-	var userTransform string
+	var userTransform, transformLanguage string
+	var transformDependencies map[string]string
 	mappingDisabled := false
 	switch p.fieldMapper.(type) {
 	case DummyMapper, *DummyMapper, nil:
@@ -468,6 +552,8 @@ func (p *Processor) InitJavaScriptTemplates() (err error) {
 	if dataLayout := p.destinationConfig.DataLayout; dataLayout != nil {
 		transformDisabled = dataLayout.TransformEnabled != nil && !*dataLayout.TransformEnabled
 		userTransform = dataLayout.Transform
+		transformLanguage = dataLayout.TransformLanguage
+		transformDependencies = dataLayout.TransformDependencies
 	}
 	if transformDisabled {
 		//transform is explicitly disabled
@@ -489,14 +575,39 @@ Mapping feature is deprecated. It is recommended to migrate to javascript data t
 			return nil
 		} else {
 			userTransform = p.defaultUserTransform
+			transformLanguage = ""
+			transformDependencies = nil
 		}
 	}
-	if userTransform != "" {
+	limits, err := templates.NewScriptLimits(p.destinationConfig.ScriptLimits)
+	if err != nil {
+		return fmt.Errorf("invalid script_limits: %v", err)
+	}
+	if userTransform != "" && transformLanguage == templates.PythonRuntime {
+		transformer, err := templates.NewLimitedScriptExecutor(templates.PythonExpression(userTransform), p.jsVariables, limits)
+		if err != nil {
+			return fmt.Errorf("failed to init transform python: %v", err)
+		}
+		p.transformer = transformer
+	} else if userTransform != "" && transformLanguage == templates.WasmRuntime {
+		transformer, err := templates.NewLimitedScriptExecutor(templates.WasmModule(strings.TrimSpace(userTransform)), p.jsVariables, limits)
+		if err != nil {
+			return fmt.Errorf("failed to init transform wasm module: %v", err)
+		}
+		p.transformer = transformer
+	} else if userTransform != "" {
 		if strings.Contains(userTransform, "toSegment") {
 			//seems like built-in to segment transformation is used. We need to load script
 			p.AddJavaScript(segmentTransform)
 		}
-		transformer, err := templates.NewScriptExecutor(templates.Expression(userTransform), p.jsVariables, p.javaScripts...)
+		if len(transformDependencies) > 0 {
+			dependencies, err := templates.BundleDependencies(transformDependencies)
+			if err != nil {
+				return fmt.Errorf("failed to bundle transform_dependencies: %v", err)
+			}
+			p.AddJavaScript(dependencies)
+		}
+		transformer, err := templates.NewLimitedScriptExecutor(templates.Expression(userTransform), p.jsVariables, limits, p.javaScripts...)
 		if err != nil {
 			return fmt.Errorf("failed to init transform javascript: %v", err)
 		}
@@ -530,6 +641,7 @@ func (p *Processor) DestinationType() string {
 
 func (p *Processor) Close() {
 	p.CloseJavaScriptTemplates()
+	p.lookupEnrichmentStep.Close()
 }
 
 // cutName converts input name that exceeds maxLen to lower length string by cutting parts between '_' to 2 symbols.
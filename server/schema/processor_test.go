@@ -112,7 +112,7 @@ func TestProcessFilePayload(t *testing.T) {
 				},
 			},
 			[]events.FailedEvent{{Event: []byte(`{"_geo_data":{},"event_type":"views","key1000":"super value"}`), Error: "error extracting table name: _timestamp field doesn't exist"}},
-			[]events.SkippedEvent{{Event: []byte(`{"_geo_data":{"city":"New York","country":"US"},"_timestamp":"2020-08-02T18:23:56.291383Z","event_type":"skipped","eventn_ctx_event_id":"qoow1","key1":{"key2":"splu"},"key10":{"sib1":{"1":"k"}}}`), Error: "Transform or table name filter marked object to be skipped. This object will be skipped."}},
+			[]events.SkippedEvent{{Event: []byte(`{"_geo_data":{"city":"New York","country":"US"},"_timestamp":"2020-08-02T18:23:56.291383Z","event_type":"skipped","eventn_ctx_event_id":"qoow1","key1":{"key2":"splu"},"key10":{"sib1":{"1":"k"}}}`), Error: "Transform or table name filter marked object to be skipped. This object will be skipped.", EventID: "qoow1"}},
 		},
 		{
 			"Input fallback file",
@@ -519,3 +519,29 @@ func TestCutName(t *testing.T) {
 	require.Equal(t, "fi_la_mi_co", cutName("fi_lastname_mi_country", 12))
 	require.Equal(t, "_la_mi_co_ci", cutName("fi_la_mi_co_ci", 12))
 }
+
+func TestProcessFilter(t *testing.T) {
+	viper.Set("server.log.path", "")
+	viper.Set("sql_debug_log.ddl.enabled", false)
+	require.NoError(t, appconfig.Init(false, ""))
+
+	transformEnabled := false
+	destination := &config.DestinationConfig{Type: "postgres", Filter: `event_type in ("pageview", "conversion") && host != "staging.example.com"`,
+		DataLayout: &config.DataLayout{TransformEnabled: &transformEnabled}}
+	p, err := NewProcessor("test", destination, true, `events`, DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), identifiers.NewUniqueID("/eventn_ctx/event_id"), 20, "new", false)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+
+	envelopes, err := p.ProcessEvent(map[string]interface{}{"event_type": "pageview", "eventn_ctx": map[string]interface{}{"doc_host": "example.com"}}, false)
+	require.NoError(t, err)
+	require.Len(t, envelopes, 1)
+
+	_, err = p.ProcessEvent(map[string]interface{}{"event_type": "pageview", "eventn_ctx": map[string]interface{}{"doc_host": "staging.example.com"}}, false)
+	require.Equal(t, ErrSkipObject, err)
+	_, err = p.ProcessEvent(map[string]interface{}{"event_type": "identify"}, false)
+	require.Equal(t, ErrSkipObject, err)
+
+	destination.Filter = `event_type ==`
+	_, err = NewProcessor("test", destination, true, `events`, DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), identifiers.NewUniqueID("/eventn_ctx/event_id"), 20, "new", false)
+	require.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package schemaregistry
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	//TableCreated is a change of a table which has been created by Jitsu
+	TableCreated = "table_created"
+	//TableDiscovered is a change of an existing destination table which has been seen for the first time
+	TableDiscovered = "table_discovered"
+	//ColumnAdded is a change of a column which has been added into the table
+	ColumnAdded = "column_added"
+	//ColumnTypeChanged is a change of a column SQL type by columns types migration
+	ColumnTypeChanged = "column_type_changed"
+
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+//Table is a known destination table schema
+type Table struct {
+	Name      string             `json:"name"`
+	Columns   map[string]*Column `json:"columns"`
+	FirstSeen time.Time          `json:"first_seen"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+//Column is a table column SQL type and when it has been seen for the first time
+type Column struct {
+	Type      string    `json:"type"`
+	FirstSeen time.Time `json:"first_seen"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+//Change is a table schema change record
+type Change struct {
+	Table           string    `json:"table"`
+	Type            string    `json:"type"`
+	Column          string    `json:"column,omitempty"`
+	SQLType         string    `json:"sql_type,omitempty"`
+	PreviousSQLType string    `json:"previous_sql_type,omitempty"`
+	Time            time.Time `json:"time"`
+}
+
+//HistoryFilter selects schema changes of the destination. Empty Table matches all tables
+type HistoryFilter struct {
+	DestinationID string
+	Table         string
+	Limit         int
+}
+
+//limit returns the filter limit with default and max values applied
+func (hf *HistoryFilter) limit() int {
+	if hf.Limit <= 0 {
+		return defaultHistoryLimit
+	}
+	if hf.Limit > maxHistoryLimit {
+		return maxHistoryLimit
+	}
+	return hf.Limit
+}
+
+//Match returns true if the change is of the filter table
+func (hf *HistoryFilter) Match(change *Change) bool {
+	return hf.Table == "" || hf.Table == change.Table
+}
+
+//Clone returns a deep copy of the table
+func (t *Table) Clone() *Table {
+	clone := *t
+	clone.Columns = make(map[string]*Column, len(t.Columns))
+	for name, column := range t.Columns {
+		columnCopy := *column
+		clone.Columns[name] = &columnCopy
+	}
+
+	return &clone
+}
+
+//apply returns the table with observed columns (or a new one if known is nil) and changes. Returns nil table if
+//nothing has been changed. Columns which aren't observed are kept: patched schemas might contain only a part of
+//the table columns. Types of known columns are updated without changes records because destinations report types
+//differently from DDL types (e.g. varchar -> character varying). Column types changes are recorded by applyTypeChange
+func apply(known *Table, name string, columns map[string]string, created bool, now time.Time) (*Table, []*Change) {
+	var changes []*Change
+	var table *Table
+	if known == nil {
+		table = &Table{Name: name, Columns: map[string]*Column{}, FirstSeen: now, UpdatedAt: now}
+		changeType := TableDiscovered
+		if created {
+			changeType = TableCreated
+		}
+		changes = append(changes, &Change{Table: name, Type: changeType, Time: now})
+	} else {
+		table = known.Clone()
+	}
+
+	updated := known == nil
+	for _, columnName := range sortedColumns(columns) {
+		sqlType := columns[columnName]
+		column, ok := table.Columns[columnName]
+		switch {
+		case !ok:
+			table.Columns[columnName] = &Column{Type: sqlType, FirstSeen: now, UpdatedAt: now}
+			updated = true
+			//columns of a new table are described by the table change
+			if known != nil {
+				changes = append(changes, &Change{Table: name, Type: ColumnAdded, Column: columnName, SQLType: sqlType, Time: now})
+			}
+		case column.Type != sqlType:
+			column.Type = sqlType
+			column.UpdatedAt = now
+			updated = true
+		}
+	}
+
+	if !updated {
+		return nil, nil
+	}
+
+	table.UpdatedAt = now
+	return table, changes
+}
+
+//applyTypeChange returns the table with the new column type and the change. Returns nil table if the column
+//already has the type
+func applyTypeChange(known *Table, name, columnName, previousSQLType, sqlType string, now time.Time) (*Table, []*Change) {
+	var table *Table
+	if known == nil {
+		table = &Table{Name: name, Columns: map[string]*Column{}, FirstSeen: now}
+	} else {
+		table = known.Clone()
+	}
+
+	column, ok := table.Columns[columnName]
+	if !ok {
+		column = &Column{FirstSeen: now}
+		table.Columns[columnName] = column
+	} else if column.Type == sqlType {
+		return nil, nil
+	}
+
+	column.Type = sqlType
+	column.UpdatedAt = now
+	table.UpdatedAt = now
+	return table, []*Change{{Table: name, Type: ColumnTypeChanged, Column: columnName, SQLType: sqlType, PreviousSQLType: previousSQLType, Time: now}}
+}
+
+func sortedColumns(columns map[string]string) []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//sortTables sorts tables by name
+func sortTables(tables []*Table) {
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+}
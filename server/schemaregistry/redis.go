@@ -0,0 +1,212 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/metrics"
+)
+
+//** Schema registry **
+//schema_registry:destinations [destinationID] - set of destinations which have known tables
+//schema_registry:destination#${destinationID}:tables [tableName] - hashtable with Table JSON
+//schema_registry:destination#${destinationID}:history - list of Change JSON: the newest is the first
+const (
+	destinationsKey      = "schema_registry:destinations"
+	destinationKeyPrefix = "schema_registry:destination#"
+	tablesKeySuffix      = ":tables"
+	historyKeySuffix     = ":history"
+)
+
+//Redis is a Storage based on Redis hashtables and lists bounded by history capacity
+type Redis struct {
+	pool            *meta.RedisPool
+	historyCapacity int
+	errorMetrics    *meta.ErrorMetrics
+}
+
+//NewRedis returns configured Redis storage
+func NewRedis(pool *meta.RedisPool, historyCapacity int) *Redis {
+	return &Redis{
+		pool:            pool,
+		historyCapacity: historyCapacity,
+		errorMetrics:    meta.NewErrorMetrics(metrics.MetaRedisErrors),
+	}
+}
+
+//GetDestinationIDs returns sorted destination IDs
+func (r *Redis) GetDestinationIDs() ([]string, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	destinationIDs, err := redis.Strings(conn.Do("SMEMBERS", destinationsKey))
+	if err != nil && err != redis.ErrNil {
+		r.errorMetrics.NoticeError(err)
+		return nil, err
+	}
+
+	sort.Strings(destinationIDs)
+	return destinationIDs, nil
+}
+
+//GetTable returns the table or nil if it doesn't exist
+func (r *Redis) GetTable(destinationID, tableName string) (*Table, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	serialized, err := redis.Bytes(conn.Do("HGET", tablesKey(destinationID), tableName))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, nil
+		}
+		r.errorMetrics.NoticeError(err)
+		return nil, err
+	}
+
+	return parseTable(tableName, serialized)
+}
+
+//GetTables returns all destination tables sorted by name
+func (r *Redis) GetTables(destinationID string) ([]*Table, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	fields, err := redis.StringMap(conn.Do("HGETALL", tablesKey(destinationID)))
+	if err != nil && err != redis.ErrNil {
+		r.errorMetrics.NoticeError(err)
+		return nil, err
+	}
+
+	tables := make([]*Table, 0, len(fields))
+	for tableName, serialized := range fields {
+		table, err := parseTable(tableName, []byte(serialized))
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	sortTables(tables)
+	return tables, nil
+}
+
+//SaveTable writes the table JSON and adds the destination into the destinations set
+func (r *Redis) SaveTable(destinationID string, table *Table) error {
+	serialized, err := json.Marshal(table)
+	if err != nil {
+		return fmt.Errorf("failed to serialize table [%s] schema: %v", table.Name, err)
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("HSET", tablesKey(destinationID), table.Name, serialized); err != nil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	if _, err := conn.Do("SADD", destinationsKey, destinationID); err != nil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	return nil
+}
+
+//AddChanges pushes changes into the head of the history list and trims it to capacity
+func (r *Redis) AddChanges(destinationID string, changes []*Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	args := []interface{}{historyKey(destinationID)}
+	for _, change := range changes {
+		serialized, err := json.Marshal(change)
+		if err != nil {
+			return fmt.Errorf("failed to serialize schema change [%v]: %v", change, err)
+		}
+		args = append(args, serialized)
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("LPUSH", args...); err != nil {
+		r.errorMetrics.NoticeError(err)
+		return err
+	}
+
+	if r.historyCapacity > 0 {
+		if _, err := conn.Do("LTRIM", historyKey(destinationID), 0, r.historyCapacity-1); err != nil {
+			r.errorMetrics.NoticeError(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+//GetChanges reads the history list from the newest changes and returns changes which match the filter
+func (r *Redis) GetChanges(filter *HistoryFilter) ([]*Change, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	//without table filter only limit changes are read
+	end := -1
+	if filter.Table == "" {
+		end = filter.limit() - 1
+	}
+
+	values, err := redis.ByteSlices(conn.Do("LRANGE", historyKey(filter.DestinationID), 0, end))
+	if err != nil && err != redis.ErrNil {
+		r.errorMetrics.NoticeError(err)
+		return nil, err
+	}
+
+	limit := filter.limit()
+	changes := []*Change{}
+	for _, serialized := range values {
+		change := &Change{}
+		if err := json.Unmarshal(serialized, change); err != nil {
+			return nil, fmt.Errorf("error deserializing schema change [%s]: %v", string(serialized), err)
+		}
+		if filter.Match(change) {
+			changes = append(changes, change)
+			if len(changes) == limit {
+				break
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+func (r *Redis) Type() string {
+	return RedisStorageType
+}
+
+func (r *Redis) Close() error {
+	return r.pool.Close()
+}
+
+func tablesKey(destinationID string) string {
+	return destinationKeyPrefix + destinationID + tablesKeySuffix
+}
+
+func historyKey(destinationID string) string {
+	return destinationKeyPrefix + destinationID + historyKeySuffix
+}
+
+func parseTable(tableName string, serialized []byte) (*Table, error) {
+	table := &Table{}
+	if err := json.Unmarshal(serialized, table); err != nil {
+		return nil, fmt.Errorf("error deserializing table [%s] schema: %v", tableName, err)
+	}
+	if table.Columns == nil {
+		table.Columns = map[string]*Column{}
+	}
+	return table, nil
+}
@@ -0,0 +1,150 @@
+package schemaregistry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+var instance *Service
+
+//DestinationTables is a dto with known tables of the destination
+type DestinationTables struct {
+	DestinationID string   `json:"destination_id"`
+	Tables        []*Table `json:"tables"`
+}
+
+//applyFunc returns the updated table and changes or nil table if nothing has been changed
+type applyFunc func(known *Table, now time.Time) (*Table, []*Change)
+
+//Service keeps known tables in memory and writes tables and changes into the storage only if they have been changed.
+//It is called on tables schema ensuring so unchanged schemas don't touch the storage
+type Service struct {
+	storage Storage
+
+	mutex sync.Mutex
+	//tables are destinationID -> table name -> known table (nil if the table isn't in the storage)
+	tables map[string]map[string]*Table
+}
+
+//NewService returns configured Service
+func NewService(storage Storage) *Service {
+	return &Service{storage: storage, tables: map[string]map[string]*Table{}}
+}
+
+//Init creates the global Service
+func Init(storage Storage) *Service {
+	instance = NewService(storage)
+	return instance
+}
+
+//Instance returns the global Service or nil if the schema registry is disabled
+func Instance() *Service {
+	return instance
+}
+
+//Observe records the table schema (column name -> SQL type) of the destination. Does nothing if the schema registry is disabled
+func Observe(destinationID, tableName string, columns map[string]string, created bool) {
+	if instance != nil {
+		instance.Observe(destinationID, tableName, columns, created)
+	}
+}
+
+//ObserveColumnTypeChange records the column type change of the destination table. Does nothing if the schema registry is disabled
+func ObserveColumnTypeChange(destinationID, tableName, columnName, previousSQLType, sqlType string) {
+	if instance != nil {
+		instance.ObserveColumnTypeChange(destinationID, tableName, columnName, previousSQLType, sqlType)
+	}
+}
+
+//Observe records the table schema. created is true if the table has been just created
+func (s *Service) Observe(destinationID, tableName string, columns map[string]string, created bool) {
+	s.update(destinationID, tableName, func(known *Table, now time.Time) (*Table, []*Change) {
+		return apply(known, tableName, columns, created, now)
+	})
+}
+
+//ObserveColumnTypeChange records the column type change
+func (s *Service) ObserveColumnTypeChange(destinationID, tableName, columnName, previousSQLType, sqlType string) {
+	s.update(destinationID, tableName, func(known *Table, now time.Time) (*Table, []*Change) {
+		return applyTypeChange(known, tableName, columnName, previousSQLType, sqlType, now)
+	})
+}
+
+//update applies the function to the cached table. If there are changes, the function is applied to the stored table
+//(the table might have been changed by another Jitsu node) and the result is written. Errors are only logged
+func (s *Service) update(destinationID, tableName string, fn applyFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := timestamp.Now().UTC()
+	destinationTables, ok := s.tables[destinationID]
+	if !ok {
+		destinationTables = map[string]*Table{}
+		s.tables[destinationID] = destinationTables
+	}
+
+	if known, ok := destinationTables[tableName]; ok {
+		if table, _ := fn(known, now); table == nil {
+			return
+		}
+	}
+
+	stored, err := s.storage.GetTable(destinationID, tableName)
+	if err != nil {
+		logging.Errorf("[%s] [schema registry] Error getting table [%s] schema: %v", destinationID, tableName, err)
+		return
+	}
+
+	table, changes := fn(stored, now)
+	if table == nil {
+		destinationTables[tableName] = stored
+		return
+	}
+
+	if err := s.storage.SaveTable(destinationID, table); err != nil {
+		logging.Errorf("[%s] [schema registry] Error saving table [%s] schema: %v", destinationID, tableName, err)
+		return
+	}
+	destinationTables[tableName] = table
+
+	if len(changes) > 0 {
+		if err := s.storage.AddChanges(destinationID, changes); err != nil {
+			logging.Errorf("[%s] [schema registry] Error saving table [%s] schema changes: %v", destinationID, tableName, err)
+		}
+	}
+}
+
+//GetTables returns known tables of the destination or of all destinations if destinationID is empty
+func (s *Service) GetTables(destinationID string) ([]*DestinationTables, error) {
+	destinationIDs := []string{destinationID}
+	if destinationID == "" {
+		var err error
+		destinationIDs, err = s.storage.GetDestinationIDs()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]*DestinationTables, 0, len(destinationIDs))
+	for _, id := range destinationIDs {
+		tables, err := s.storage.GetTables(id)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &DestinationTables{DestinationID: id, Tables: tables})
+	}
+
+	return result, nil
+}
+
+//GetChanges returns the last destination schema changes which match the filter
+func (s *Service) GetChanges(filter *HistoryFilter) ([]*Change, error) {
+	return s.storage.GetChanges(filter)
+}
+
+func (s *Service) Close() error {
+	return s.storage.Close()
+}
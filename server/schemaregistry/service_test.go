@@ -0,0 +1,60 @@
+package schemaregistry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	storage := NewMemory(100)
+	s := NewService(storage)
+
+	s.Observe("dest1", "events", map[string]string{"id": "text", "value": "bigint"}, true)
+	//the same schema doesn't produce changes
+	s.Observe("dest1", "events", map[string]string{"id": "text", "value": "bigint"}, false)
+	//a patched part of the table
+	s.Observe("dest1", "events", map[string]string{"id": "text", "email": "text"}, false)
+	//destination reports types differently from DDL types
+	s.Observe("dest1", "events", map[string]string{"id": "character varying"}, false)
+	s.ObserveColumnTypeChange("dest1", "events", "value", "bigint", "text")
+	s.ObserveColumnTypeChange("dest1", "events", "value", "bigint", "text")
+	s.Observe("dest2", "users", map[string]string{"id": "String"}, false)
+
+	tables, err := s.GetTables("dest1")
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	require.Equal(t, "dest1", tables[0].DestinationID)
+	events := tables[0].Tables[0]
+	require.Equal(t, "events", events.Name)
+	require.Len(t, events.Columns, 3)
+	require.Equal(t, "character varying", events.Columns["id"].Type)
+	require.Equal(t, "text", events.Columns["value"].Type)
+
+	tables, err = s.GetTables("")
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	require.Equal(t, "dest2", tables[1].DestinationID)
+
+	changes, err := s.GetChanges(&HistoryFilter{DestinationID: "dest1"})
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	require.Equal(t, ColumnTypeChanged, changes[0].Type)
+	require.Equal(t, "bigint", changes[0].PreviousSQLType)
+	require.Equal(t, ColumnAdded, changes[1].Type)
+	require.Equal(t, "email", changes[1].Column)
+	require.Equal(t, TableCreated, changes[2].Type)
+
+	changes, err = s.GetChanges(&HistoryFilter{DestinationID: "dest2"})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, TableDiscovered, changes[0].Type)
+
+	//another node has added a column: it isn't recorded twice
+	other := NewService(storage)
+	other.Observe("dest1", "events", map[string]string{"country": "text"}, false)
+	s.Observe("dest1", "events", map[string]string{"country": "text"}, false)
+	changes, err = s.GetChanges(&HistoryFilter{DestinationID: "dest1", Table: "events"})
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+}
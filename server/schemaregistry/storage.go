@@ -0,0 +1,174 @@
+package schemaregistry
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/spf13/viper"
+)
+
+const (
+	MemoryStorageType = "memory"
+	RedisStorageType  = "redis"
+)
+
+//Storage keeps known table schemas per destination and bounded history of their changes
+type Storage interface {
+	io.Closer
+	//GetDestinationIDs returns IDs of destinations which have known tables
+	GetDestinationIDs() ([]string, error)
+	//GetTable returns the table or nil if it isn't known
+	GetTable(destinationID, tableName string) (*Table, error)
+	//GetTables returns all known tables of the destination sorted by name
+	GetTables(destinationID string) ([]*Table, error)
+	//SaveTable creates or overwrites the table
+	SaveTable(destinationID string, table *Table) error
+	//AddChanges appends changes into the destination history. Only last capacity changes are kept
+	AddChanges(destinationID string, changes []*Change) error
+	//GetChanges returns the last changes which match the filter (the newest first)
+	GetChanges(filter *HistoryFilter) ([]*Change, error)
+	Type() string
+}
+
+//InitializeStorage returns configured Storage: redis if meta.storage.redis is configured or memory otherwise
+func InitializeStorage(metaStorageConfiguration *viper.Viper, historyCapacity int) (Storage, error) {
+	var redisConfigurationSource *viper.Viper
+	if metaStorageConfiguration != nil {
+		redisConfigurationSource = metaStorageConfiguration.Sub("redis")
+	}
+
+	if redisConfigurationSource == nil || redisConfigurationSource.GetString("host") == "" {
+		logging.Infof("📒 Schema registry is stored in memory (last %d changes per destination)", historyCapacity)
+		return NewMemory(historyCapacity), nil
+	}
+
+	factory := meta.NewRedisPoolFactory(redisConfigurationSource.GetString("host"), redisConfigurationSource.GetInt("port"),
+		redisConfigurationSource.GetString("password"), redisConfigurationSource.GetInt("database"),
+		redisConfigurationSource.GetBool("tls_skip_verify"), redisConfigurationSource.GetString("sentinel_master_name")).
+		WithConfiguration(redisConfigurationSource)
+	factory.CheckAndSetDefaultPort()
+
+	logging.Infof("📒 Initializing schema registry redis [%s] (last %d changes per destination)...", factory.Details(), historyCapacity)
+	pool, err := factory.Create()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRedis(pool, historyCapacity), nil
+}
+
+//Memory is an in-memory Storage for single node deployments without Redis
+type Memory struct {
+	historyCapacity int
+
+	mutex  sync.RWMutex
+	tables map[string]map[string]*Table
+	//history keeps changes per destination: the newest is the last
+	history map[string][]*Change
+}
+
+//NewMemory returns configured Memory storage
+func NewMemory(historyCapacity int) *Memory {
+	return &Memory{
+		historyCapacity: historyCapacity,
+		tables:          map[string]map[string]*Table{},
+		history:         map[string][]*Change{},
+	}
+}
+
+//GetDestinationIDs returns sorted destination IDs
+func (m *Memory) GetDestinationIDs() ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	destinationIDs := make([]string, 0, len(m.tables))
+	for destinationID := range m.tables {
+		destinationIDs = append(destinationIDs, destinationID)
+	}
+	sort.Strings(destinationIDs)
+	return destinationIDs, nil
+}
+
+//GetTable returns a copy of the table or nil
+func (m *Memory) GetTable(destinationID, tableName string) (*Table, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	table, ok := m.tables[destinationID][tableName]
+	if !ok {
+		return nil, nil
+	}
+	return table.Clone(), nil
+}
+
+//GetTables returns copies of the destination tables
+func (m *Memory) GetTables(destinationID string) ([]*Table, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	tables := make([]*Table, 0, len(m.tables[destinationID]))
+	for _, table := range m.tables[destinationID] {
+		tables = append(tables, table.Clone())
+	}
+	sortTables(tables)
+	return tables, nil
+}
+
+//SaveTable saves a copy of the table
+func (m *Memory) SaveTable(destinationID string, table *Table) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	tables, ok := m.tables[destinationID]
+	if !ok {
+		tables = map[string]*Table{}
+		m.tables[destinationID] = tables
+	}
+	tables[table.Name] = table.Clone()
+	return nil
+}
+
+//AddChanges appends changes and removes the oldest changes out of capacity
+func (m *Memory) AddChanges(destinationID string, changes []*Change) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	history := m.history[destinationID]
+	for _, change := range changes {
+		changeCopy := *change
+		history = append(history, &changeCopy)
+	}
+	if m.historyCapacity > 0 && len(history) > m.historyCapacity {
+		history = append([]*Change{}, history[len(history)-m.historyCapacity:]...)
+	}
+	m.history[destinationID] = history
+	return nil
+}
+
+//GetChanges returns copies of the last changes which match the filter
+func (m *Memory) GetChanges(filter *HistoryFilter) ([]*Change, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	limit := filter.limit()
+	history := m.history[filter.DestinationID]
+	changes := []*Change{}
+	for i := len(history) - 1; i >= 0 && len(changes) < limit; i-- {
+		if filter.Match(history[i]) {
+			changeCopy := *history[i]
+			changes = append(changes, &changeCopy)
+		}
+	}
+	return changes, nil
+}
+
+func (m *Memory) Type() string {
+	return MemoryStorageType
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package schemaregistry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storage := NewMemory(3)
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	table := &Table{Name: "events", Columns: map[string]*Column{"id": {Type: "text", FirstSeen: now, UpdatedAt: now}}, FirstSeen: now, UpdatedAt: now}
+	require.NoError(t, storage.SaveTable("dest2", table))
+	require.NoError(t, storage.SaveTable("dest1", &Table{Name: "users", Columns: map[string]*Column{}}))
+	require.NoError(t, storage.SaveTable("dest1", &Table{Name: "pages", Columns: map[string]*Column{}}))
+
+	//stored tables are copies
+	table.Columns["id"].Type = "bigint"
+	stored, err := storage.GetTable("dest2", "events")
+	require.NoError(t, err)
+	require.Equal(t, "text", stored.Columns["id"].Type)
+
+	stored, err = storage.GetTable("dest2", "unknown")
+	require.NoError(t, err)
+	require.Nil(t, stored)
+
+	destinationIDs, err := storage.GetDestinationIDs()
+	require.NoError(t, err)
+	require.Equal(t, []string{"dest1", "dest2"}, destinationIDs)
+
+	tables, err := storage.GetTables("dest1")
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	require.Equal(t, "pages", tables[0].Name)
+	require.Equal(t, "users", tables[1].Name)
+
+	require.NoError(t, storage.AddChanges("dest1", []*Change{
+		{Table: "users", Type: TableCreated, Time: now},
+		{Table: "pages", Type: TableCreated, Time: now},
+	}))
+	require.NoError(t, storage.AddChanges("dest1", []*Change{
+		{Table: "users", Type: ColumnAdded, Column: "email", SQLType: "text", Time: now.Add(time.Minute)},
+		{Table: "users", Type: ColumnAdded, Column: "age", SQLType: "bigint", Time: now.Add(time.Minute)},
+	}))
+
+	//only last 3 changes are kept: the newest first
+	changes, err := storage.GetChanges(&HistoryFilter{DestinationID: "dest1"})
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	require.Equal(t, "age", changes[0].Column)
+	require.Equal(t, "pages", changes[2].Table)
+
+	changes, err = storage.GetChanges(&HistoryFilter{DestinationID: "dest1", Table: "users", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, "age", changes[0].Column)
+
+	changes, err = storage.GetChanges(&HistoryFilter{DestinationID: "dest2"})
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
@@ -0,0 +1,145 @@
+package script
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/pkg/errors"
+)
+
+const (
+	FileCacheType  = "file"
+	RedisCacheType = "redis"
+
+	//cacheNamespace is a namespace of cached script artifacts in Storage
+	cacheNamespace = "script_cache"
+)
+
+// Cache is a content-addressed cache of script artifacts (loaded plugins, bundled dependencies, symbol tables).
+// It lets unchanged scripts skip the expensive load path after server restarts and configuration reloads.
+// Cache is best-effort: errors are logged and treated as cache misses.
+type Cache interface {
+
+	// Get returns cached value of the kind by content key. ok is false if value isn't cached.
+	Get(kind, key string) (value []byte, ok bool)
+
+	// Set stores value of the kind by content key.
+	Set(kind, key string, value []byte)
+}
+
+// ContentKey returns a cache key of the content.
+func ContentKey(content ...string) string {
+	hash := sha256.New()
+	for _, part := range content {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// DummyCache is a Cache which doesn't cache anything.
+type DummyCache struct{}
+
+func (DummyCache) Get(kind, key string) ([]byte, bool) { return nil, false }
+func (DummyCache) Set(kind, key string, value []byte)  {}
+
+// FileCache is a Cache which stores values in files: dir/kind/key.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache returns FileCache in the directory (it is created if it doesn't exist)
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "create script cache directory %s", dir)
+	}
+
+	return &FileCache{dir: dir}, nil
+}
+
+// Dir returns directory for runtime specific artifacts of the kind (e.g. compiled WebAssembly modules)
+func (c *FileCache) Dir(kind string) string {
+	return filepath.Join(c.dir, kind)
+}
+
+func (c *FileCache) Get(kind, key string) ([]byte, bool) {
+	value, err := os.ReadFile(filepath.Join(c.dir, kind, key))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warnf("failed to read script cache %s/%s: %v", kind, key, err)
+		}
+
+		return nil, false
+	}
+
+	return value, true
+}
+
+func (c *FileCache) Set(kind, key string, value []byte) {
+	dir := filepath.Join(c.dir, kind)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logging.Warnf("failed to create script cache directory %s: %v", dir, err)
+		return
+	}
+
+	//write into temp file and rename for not exposing partially written values to concurrent readers
+	file, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		logging.Warnf("failed to write script cache %s/%s: %v", kind, key, err)
+		return
+	}
+
+	_, err = file.Write(value)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(dir, key))
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
+		logging.Warnf("failed to write script cache %s/%s: %v", kind, key, err)
+	}
+}
+
+// StorageCache is a Cache which stores values in Storage (e.g. Redis) and is shared between cluster nodes.
+type StorageCache struct {
+	storage Storage
+	ttlMs   int64
+}
+
+// NewStorageCache returns StorageCache which stores values with ttlMs (0 – storage default)
+func NewStorageCache(storage Storage, ttlMs int64) *StorageCache {
+	return &StorageCache{storage: storage, ttlMs: ttlMs}
+}
+
+func (c *StorageCache) Get(kind, key string) ([]byte, bool) {
+	value, err := c.storage.GetTransformValue(cacheNamespace, kind, key)
+	if err != nil {
+		logging.Warnf("failed to get script cache %s/%s: %v", kind, key, err)
+		return nil, false
+	}
+
+	if value == nil {
+		return nil, false
+	}
+
+	return []byte(*value), true
+}
+
+func (c *StorageCache) Set(kind, key string, value []byte) {
+	var ttlMs *int64
+	if c.ttlMs > 0 {
+		ttlMs = &c.ttlMs
+	}
+
+	if err := c.storage.SetTransformValue(cacheNamespace, kind, key, string(value), ttlMs); err != nil {
+		logging.Warnf("failed to set script cache %s/%s: %v", kind, key, err)
+	}
+}
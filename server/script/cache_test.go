@@ -0,0 +1,39 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewFileCache(dir)
+	require.NoError(t, err)
+
+	key := ContentKey("return $")
+	_, ok := cache.Get("symbols", key)
+	assert.False(t, ok)
+
+	cache.Set("symbols", key, []byte(`{"main":{"type":"function"}}`))
+	value, ok := cache.Get("symbols", key)
+	assert.True(t, ok)
+	assert.Equal(t, `{"main":{"type":"function"}}`, string(value))
+
+	//values are kept after restart
+	cache, err = NewFileCache(dir)
+	require.NoError(t, err)
+	value, ok = cache.Get("symbols", key)
+	assert.True(t, ok)
+	assert.Equal(t, `{"main":{"type":"function"}}`, string(value))
+
+	_, ok = cache.Get("plugins", key)
+	assert.False(t, ok)
+}
+
+func TestContentKey(t *testing.T) {
+	assert.Equal(t, ContentKey("a", "b"), ContentKey("a", "b"))
+	assert.NotEqual(t, ContentKey("a", "b"), ContentKey("ab"))
+	assert.NotEqual(t, ContentKey("a"), ContentKey("b"))
+}
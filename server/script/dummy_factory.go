@@ -9,3 +9,19 @@ type dummyFactory struct{}
 func (dummyFactory) CreateScript(executable Executable, variables map[string]interface{}, standalone bool, includes ...string) (Interface, error) {
 	return nil, errors.New("JavaScript functions are disabled")
 }
+
+var DummyPythonFactory = dummyPythonFactory{}
+
+type dummyPythonFactory struct{}
+
+func (dummyPythonFactory) CreateScript(executable Executable, variables map[string]interface{}, standalone bool, includes ...string) (Interface, error) {
+	return nil, errors.New("Python runtime is disabled. Set python.enabled: true in the server configuration")
+}
+
+var DummyWasmFactory = dummyWasmFactory{}
+
+type dummyWasmFactory struct{}
+
+func (dummyWasmFactory) CreateScript(executable Executable, variables map[string]interface{}, standalone bool, includes ...string) (Interface, error) {
+	return nil, errors.New("WebAssembly runtime is disabled. Set wasm.enabled: true in the server configuration")
+}
@@ -142,9 +142,10 @@ func (g *Governor) ExchangeDirect(ctx context.Context, data []byte, listener Dat
 	return g.exchange(ctx, data, listener)
 }
 
+// Close waits for the in-flight exchange (if any) and kills the process.
 func (g *Governor) Close() error {
 	g.closed.Store(true)
-	g.process.Kill()
+	g.kill()
 	logging.Debugf("%s completed successfully", g.process)
 	return nil
 }
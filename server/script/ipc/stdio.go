@@ -138,8 +138,9 @@ func (p *StdIO) Receive(ctx context.Context, listener DataListener) ([]byte, err
 						} else {
 							p.Send(ctx, resp)
 						}
-						continue
 					}
+					//commands without response (e.g. script logs) are processed as well
+					continue
 				}
 			}
 		}
@@ -0,0 +1,67 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/script/ipc"
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+const (
+	JitsuKvGetCommand = "_JITSU_KV_GET"
+	JitsuKvSetCommand = "_JITSU_KV_SET"
+
+	maxTransformValueLength = 10000
+)
+
+// KeyValueCommand is used to pass payload of key-value storage commands and results.
+type KeyValueCommand struct {
+	RequestId     int64   `json:"requestId"`
+	DestinationId string  `json:"destinationId"`
+	Key           string  `json:"key"`
+	Value         *string `json:"value,omitempty"`
+	TTLms         *int64  `json:"ttlMs,omitempty"`
+	Success       bool    `json:"success"`
+	Error         string  `json:"error"`
+}
+
+// ProcessKeyValueCommand executes key-value storage command sent by a script process (Node or Python runtime)
+// and returns the response for the process.
+func ProcessKeyValueCommand(transformStorage Storage, command string, payload []byte) (*ipc.CommandResponse, error) {
+	switch command {
+	case JitsuKvGetCommand, JitsuKvSetCommand:
+		kv := &KeyValueCommand{}
+		err := json.Unmarshal(payload, kv)
+		if err != nil {
+			err = fmt.Errorf("Transform Key-Value error: failed to unmarshal kv command: %s: %w", payload, err)
+			logging.SystemErrorf(err.Error())
+			return nil, err
+		}
+		var value *string
+		if command == JitsuKvGetCommand {
+			value, err = transformStorage.GetTransformValue(DestinationNamespace, kv.DestinationId, kv.Key)
+		} else if kv.Value != nil {
+			if len(*kv.Value) > maxTransformValueLength {
+				err = fmt.Errorf("Transform Key-Value Set error: value length (%d) exceeds allowed limit: %d value(trimmed):\n%s", len(*kv.Value), maxTransformValueLength, utils.ShortenStringWithEllipsis(*kv.Value, 200))
+			} else {
+				err = transformStorage.SetTransformValue(DestinationNamespace, kv.DestinationId, kv.Key, *kv.Value, kv.TTLms)
+			}
+		} else {
+			err = transformStorage.DeleteTransformValue(DestinationNamespace, kv.DestinationId, kv.Key)
+		}
+		if err != nil {
+			kv.Error = err.Error()
+		} else {
+			kv.Success = true
+			kv.Value = value
+		}
+		return &ipc.CommandResponse{
+			Command: command,
+			Payload: kv,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown command: %s", command)
+	}
+}
@@ -0,0 +1,121 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	esbuild        = "esbuild"
+	esbuildVersion = "0.17.19"
+	dependenciesJS = "dependencies.js"
+	bundleJS       = "bundle.js"
+
+	//dependenciesCacheKind is a cache kind of bundled dependencies
+	dependenciesCacheKind = "node_dependencies"
+)
+
+//dependenciesInclude exposes bundled dependencies via require() and falls back to the sandbox require() for builtin modules
+var dependenciesInclude = `var __jitsu_require = require; ` +
+	`var __jitsu_dependencies = (function () { var module = { exports: {} }; var exports = module.exports; %s
+return module.exports; })(); ` +
+	`var require = function (name) { return Object.prototype.hasOwnProperty.call(__jitsu_dependencies, name) ? __jitsu_dependencies[name] : __jitsu_require(name); };`
+
+type dependenciesRef struct {
+	include string
+	err     error
+	once    sync.Once
+}
+
+//BundleDependencies installs npm dependencies (package name -> version) into the cache directory and bundles them
+//with esbuild into a code snippet which should be passed as an include into CreateScript.
+//Bundled dependencies are available in scripts via require(name). Bundles are cached by dependencies set
+func (f *Factory) BundleDependencies(dependencies map[string]string) (string, error) {
+	if len(dependencies) == 0 {
+		return "", nil
+	}
+
+	hash, err := hashstructure.Hash(dependencies, hashstructure.FormatV2, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "hash dependencies")
+	}
+
+	value, _ := f.dependencies.LoadOrStore(hash, &dependenciesRef{})
+	ref := value.(*dependenciesRef)
+	ref.once.Do(func() {
+		key := script.ContentKey(esbuildVersion, fmt.Sprintf("%x", hash))
+		if include, ok := f.cache.Get(dependenciesCacheKind, key); ok {
+			logging.Debugf("loaded bundled npm dependencies %v from cache", dependencies)
+			ref.include = string(include)
+			return
+		}
+
+		dir := filepath.Join(f.dir, "dependencies", fmt.Sprintf("%x", hash))
+		ref.include, ref.err = bundleDependencies(dir, dependencies)
+		if ref.err == nil {
+			logging.Debugf("bundled npm dependencies %v in %s", dependencies, dir)
+			f.cache.Set(dependenciesCacheKind, key, []byte(ref.include))
+		}
+	})
+
+	return ref.include, ref.err
+}
+
+func bundleDependencies(dir string, dependencies map[string]string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrapf(err, "create dependencies directory %s", dir)
+	}
+
+	packageDependencies := map[string]string{esbuild: esbuildVersion}
+	names := make([]string, 0, len(dependencies))
+	for name, version := range dependencies {
+		if name == esbuild {
+			return "", errors.Errorf("%s can't be used as a dependency", esbuild)
+		}
+
+		packageDependencies[name] = version
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	if err := createPackageJSON(dir, packageJSON{Dependencies: packageDependencies}); err != nil {
+		return "", err
+	}
+
+	if err := installNodeModules(dir); err != nil {
+		return "", errors.Wrap(err, "install dependencies")
+	}
+
+	requires := make([]string, len(names))
+	for i, name := range names {
+		quoted, _ := json.Marshal(name)
+		requires[i] = fmt.Sprintf("%s: require(%s)", quoted, quoted)
+	}
+
+	entry := "module.exports = {" + strings.Join(requires, ", ") + "};\n"
+	if err := os.WriteFile(filepath.Join(dir, dependenciesJS), []byte(entry), 0644); err != nil {
+		return "", errors.Wrapf(err, "write %s", dependenciesJS)
+	}
+
+	if err := script.Exec(dir, filepath.Join(dir, "node_modules", ".bin", esbuild), dependenciesJS,
+		"--bundle", "--format=cjs", "--platform=node", "--minify", "--log-level=error", "--outfile="+bundleJS); err != nil {
+		return "", errors.Wrap(err, "bundle dependencies")
+	}
+
+	bundle, err := os.ReadFile(filepath.Join(dir, bundleJS))
+	if err != nil {
+		return "", errors.Wrapf(err, "read %s", bundleJS)
+	}
+
+	return fmt.Sprintf(dependenciesInclude, string(bundle)), nil
+}
@@ -50,21 +50,35 @@ func (e jsError) Error() string {
 
 type exchanger struct {
 	*ipc.Governor
+	sessions *sessions
+}
+
+//unload unloads script sessions from the process. Unloaded scripts are loaded again on the next call
+func (e *exchanger) unload(sessions ...string) {
+	for _, session := range sessions {
+		e.sessions.remove(session)
+		if err := e.exchangeDirect(unload, Session{Session: session}, nil, nil, 0); err != nil {
+			logging.Warnf("%s failed to unload session %s: %v", e, session, err)
+		} else {
+			logging.Debugf("%s unloaded session %s", e, session)
+		}
+	}
 }
 
 var errLoadRequired = errors.New("load required")
 
 type exchangerFunc func(ctx context.Context, data []byte, listener ipc.DataListener) ([]byte, error)
 
-func (e *exchanger) exchangeDirect(command string, payload, result interface{}, listener script.Listener) error {
-	return e.exchange0(command, payload, result, listener, e.ExchangeDirect)
+func (e *exchanger) exchangeDirect(command string, payload, result interface{}, listener script.Listener, timeout time.Duration) error {
+	return e.exchange0(command, payload, result, listener, timeout, e.ExchangeDirect)
 }
 
-func (e *exchanger) exchange(command string, payload, result interface{}, listener script.Listener) error {
-	return e.exchange0(command, payload, result, listener, e.Exchange)
+func (e *exchanger) exchange(command string, payload, result interface{}, listener script.Listener, timeout time.Duration) error {
+	return e.exchange0(command, payload, result, listener, timeout, e.Exchange)
 }
 
-func (e *exchanger) exchange0(command string, payload, result interface{}, listener script.Listener, exchangerFunc exchangerFunc) error {
+//exchange0 sends the command and waits for the result. timeout is used if listener doesn't provide one (DefaultExchangeTimeout if 0)
+func (e *exchanger) exchange0(command string, payload, result interface{}, listener script.Listener, timeout time.Duration, exchangerFunc exchangerFunc) error {
 	data, err := json.Marshal(Request{
 		Command: command,
 		Payload: payload,
@@ -74,7 +88,9 @@ func (e *exchanger) exchange0(command string, payload, result interface{}, liste
 		return fmt.Errorf("error building javascript command: %w", err)
 	}
 
-	timeout := DefaultExchangeTimeout
+	if timeout <= 0 {
+		timeout = DefaultExchangeTimeout
+	}
 	if listener != nil && listener.Timeout() > 0 {
 		timeout = listener.Timeout()
 	}
@@ -3,9 +3,7 @@ package node
 import (
 	"context"
 	_ "embed"
-	"encoding/json"
 	"fmt"
-	"github.com/jitsucom/jitsu/server/utils"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -28,8 +26,10 @@ const (
 	nodePathEnv = "NODE_PATH"
 	mainFile    = "main.cjs"
 
-	JitsuKvGetCommand = "_JITSU_KV_GET"
-	JitsuKvSetCommand = "_JITSU_KV_SET"
+	JitsuKvGetCommand  = script.JitsuKvGetCommand
+	JitsuKvSetCommand  = script.JitsuKvSetCommand
+	JitsuLogCommand    = script.JitsuLogCommand
+	JitsuMetricCommand = script.JitsuMetricCommand
 )
 
 var (
@@ -47,14 +47,33 @@ var errNodeRequired = errors.New(`node and/or npm is not found in $PATH.
 	Or use @jitsucom/* docker images where all necessary packages are pre-installed`)
 
 type Factory struct {
-	maxSpace         int
-	sourcesMaxSpace  int
-	dir              string
-	nodePath         string
-	plugins          *sync.Map
-	exchangers       []*exchanger
-	mu               ipc.Mutex
-	transformStorage script.Storage
+	maxSpace        int
+	sourcesMaxSpace int
+	dir             string
+	nodePath        string
+	plugins         *sync.Map
+	dependencies    *sync.Map
+	exchangers      []*exchanger
+	//limitedExchangers are dedicated processes for scripts with custom memory limit (max space -> exchanger)
+	limitedExchangers map[int]*exchanger
+	mu                ipc.Mutex
+	transformStorage  script.Storage
+	poolConfig        PoolConfig
+	cache             script.Cache
+	reporter          *script.Reporter
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+//limitedFactory creates scripts with the provided limits. Limits are set per script (destination), not per API key
+type limitedFactory struct {
+	*Factory
+	limits script.Limits
+}
+
+func (f *limitedFactory) CreateScript(executable script.Executable, variables map[string]interface{}, standalone bool, includes ...string) (script.Interface, error) {
+	return f.createScript(executable, variables, standalone, f.limits, includes...)
 }
 
 func NewFactory(poolSize, maxSpace, sourcesMaxSpace int, transformStorage script.Storage, tmpDir ...string) (*Factory, error) {
@@ -112,25 +131,35 @@ func NewFactory(poolSize, maxSpace, sourcesMaxSpace int, transformStorage script
 
 	replacer := strings.NewReplacer("[[JITSU_RESULT_COMMAND]]", ipc.JitsuScriptResultCommand,
 		"[[JITSU_KV_GET_COMMAND]]", JitsuKvGetCommand,
-		"[[JITSU_KV_SET_COMMAND]]", JitsuKvSetCommand)
+		"[[JITSU_KV_SET_COMMAND]]", JitsuKvSetCommand,
+		"[[JITSU_LOG_COMMAND]]", JitsuLogCommand,
+		"[[JITSU_METRIC_COMMAND]]", JitsuMetricCommand)
 
 	defer closeQuietly(scriptFile)
 	if _, err = replacer.WriteString(scriptFile, scriptContent); err != nil {
 		return nil, errors.Wrapf(err, "write to %s", scriptPath)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Factory{
-		maxSpace:         maxSpace,
-		sourcesMaxSpace:  sourcesMaxSpace,
-		dir:              dir,
-		nodePath:         nodePath,
-		plugins:          new(sync.Map),
-		exchangers:       make([]*exchanger, poolSize),
-		transformStorage: transformStorage,
+		ctx:               ctx,
+		cancel:            cancel,
+		maxSpace:          maxSpace,
+		sourcesMaxSpace:   sourcesMaxSpace,
+		dir:               dir,
+		nodePath:          nodePath,
+		plugins:           new(sync.Map),
+		dependencies:      new(sync.Map),
+		exchangers:        make([]*exchanger, poolSize),
+		limitedExchangers: make(map[int]*exchanger),
+		transformStorage:  transformStorage,
+		cache:             script.DummyCache{},
+		reporter:          script.NewReporter(script.DefaultLogsRateLimit, script.DefaultMetricsRateLimit),
 	}, nil
 }
 
 func (f *Factory) Close() error {
+	f.cancel()
 	cancel, _ := f.mu.Lock(context.Background())
 	defer cancel()
 
@@ -142,11 +171,35 @@ func (f *Factory) Close() error {
 		_ = exchanger.Close()
 	}
 
+	for _, exchanger := range f.limitedExchangers {
+		_ = exchanger.Close()
+	}
+
 	_ = os.RemoveAll(f.dir)
 	return nil
 }
 
+//SetCache sets cache of loaded plugins, bundled dependencies and scripts symbols
+func (f *Factory) SetCache(cache script.Cache) {
+	f.cache = cache
+}
+
+//SetReporter sets Reporter which forwards $log and $metric output of scripts into the server logs and metrics
+func (f *Factory) SetReporter(reporter *script.Reporter) {
+	f.reporter = reporter
+}
+
+//WithLimits returns factory which creates scripts with the provided limits.
+//Scripts with custom memory limit are run in dedicated processes (shared between scripts with the same limit)
+func (f *Factory) WithLimits(limits script.Limits) script.Factory {
+	return &limitedFactory{Factory: f, limits: limits}
+}
+
 func (f *Factory) CreateScript(executable script.Executable, variables map[string]interface{}, standalone bool, includes ...string) (script.Interface, error) {
+	return f.createScript(executable, variables, standalone, script.Limits{}, includes...)
+}
+
+func (f *Factory) createScript(executable script.Executable, variables map[string]interface{}, standalone bool, limits script.Limits, includes ...string) (script.Interface, error) {
 	var (
 		expression string
 
@@ -175,7 +228,7 @@ module.exports = async (event) => {
 }`
 
 	case script.Package:
-		ref, _ := f.plugins.LoadOrStore(string(e), &pluginRef{plugin: string(e)})
+		ref, _ := f.plugins.LoadOrStore(string(e), &pluginRef{plugin: string(e), cache: f.cache})
 		plugin, err := ref.(*pluginRef).get()
 		if err != nil {
 			return nil, errors.Wrapf(err, "load plugin %s", string(e))
@@ -217,86 +270,68 @@ module.exports = async (event) => {
 	var exer *exchanger
 	exchangerIdx := hash % uint64(len(f.exchangers))
 	maxSpace := f.maxSpace
-	if !standalone {
+	limited := !standalone && limits.MaxMemoryMB > 0 && limits.MaxMemoryMB != f.maxSpace
+	switch {
+	case limited:
+		maxSpace = limits.MaxMemoryMB
+		exer = f.limitedExchangers[maxSpace]
+	case !standalone:
 		exer = f.exchangers[exchangerIdx]
-	} else {
+	case limits.MaxMemoryMB > 0:
+		maxSpace = limits.MaxMemoryMB
+	default:
 		maxSpace = f.sourcesMaxSpace
 	}
 	if exer == nil {
-		process := &ipc.StdIO{
-			Dir:              f.dir,
-			Path:             node,
-			Args:             []string{fmt.Sprintf("--max-old-space-size=%d", maxSpace), filepath.Join(f.dir, mainFile)},
-			Env:              []string{nodePathEnv + "=" + f.nodePath, "TZ=Etc/UTC"},
-			CommandProcessor: f.ProcessCustomCommand,
-		}
-
-		governor, err := ipc.Govern(process, standalone)
+		exer, err = f.newExchanger(maxSpace, standalone)
 		if err != nil {
-			return nil, errors.Wrapf(err, "govern process")
+			return nil, err
 		}
 
-		logging.Debugf("%s running in %s", governor, f.dir)
-		exer = &exchanger{Governor: governor}
-		if !standalone {
+		if limited {
+			f.limitedExchangers[maxSpace] = exer
+		} else if !standalone {
 			f.exchangers[exchangerIdx] = exer
 		}
 	}
 
 	init.Session.Session = fmt.Sprintf("%x", hash)
 	return &Script{
-		Init:       init,
-		exchanger:  exer,
-		rowOffset:  rowOffset,
-		colOffset:  colOffset,
-		standalone: standalone,
+		Init:        init,
+		exchanger:   exer,
+		rowOffset:   rowOffset,
+		colOffset:   colOffset,
+		standalone:  standalone,
+		limits:      limits,
+		maxSessions: f.poolConfig.MaxSessions,
+		cache:       f.cache,
 	}, nil
 }
 
-// KeyValueCommand is used to pass payload of key-value storage commands and results.
-type KeyValueCommand struct {
-	RequestId     int64   `json:"requestId"`
-	DestinationId string  `json:"destinationId"`
-	Key           string  `json:"key"`
-	Value         *string `json:"value,omitempty"`
-	TTLms         *int64  `json:"ttlMs,omitempty"`
-	Success       bool    `json:"success"`
-	Error         string  `json:"error"`
+//newExchanger spawns a new node process. Non-standalone processes are respawned by ipc.Governor on crash
+func (f *Factory) newExchanger(maxSpace int, standalone bool) (*exchanger, error) {
+	process := &ipc.StdIO{
+		Dir:              f.dir,
+		Path:             node,
+		Args:             []string{fmt.Sprintf("--max-old-space-size=%d", maxSpace), filepath.Join(f.dir, mainFile)},
+		Env:              []string{nodePathEnv + "=" + f.nodePath, "TZ=Etc/UTC"},
+		CommandProcessor: f.ProcessCustomCommand,
+	}
+
+	governor, err := ipc.Govern(process, standalone)
+	if err != nil {
+		return nil, errors.Wrapf(err, "govern process")
+	}
+
+	logging.Debugf("%s running in %s", governor, f.dir)
+	return &exchanger{Governor: governor, sessions: newSessions()}, nil
 }
 
 func (f *Factory) ProcessCustomCommand(command string, payload []byte) (*ipc.CommandResponse, error) {
 	switch command {
-	case JitsuKvGetCommand, JitsuKvSetCommand:
-		kv := &KeyValueCommand{}
-		err := json.Unmarshal(payload, kv)
-		if err != nil {
-			err = fmt.Errorf("Transform Key-Value error: failed to unmarshal kv command: %s: %w", payload, err)
-			logging.SystemErrorf(err.Error())
-			return nil, err
-		}
-		var value *string
-		if command == JitsuKvGetCommand {
-			value, err = f.transformStorage.GetTransformValue(script.DestinationNamespace, kv.DestinationId, kv.Key)
-		} else if kv.Value != nil {
-			if len(*kv.Value) > 10000 {
-				err = fmt.Errorf("Transform Key-Value Set error: value length (%d) exceeds allowed limit: %d value(trimmed):\n%s", len(*kv.Value), 10000, utils.ShortenStringWithEllipsis(*kv.Value, 200))
-			} else {
-				err = f.transformStorage.SetTransformValue(script.DestinationNamespace, kv.DestinationId, kv.Key, *kv.Value, kv.TTLms)
-			}
-		} else {
-			err = f.transformStorage.DeleteTransformValue(script.DestinationNamespace, kv.DestinationId, kv.Key)
-		}
-		if err != nil {
-			kv.Error = err.Error()
-		} else {
-			kv.Success = true
-			kv.Value = value
-		}
-		return &ipc.CommandResponse{
-			Command: command,
-			Payload: kv,
-		}, nil
+	case JitsuLogCommand, JitsuMetricCommand:
+		return nil, f.reporter.ProcessCommand(command, payload)
 	default:
-		return nil, fmt.Errorf("unknown command: %s", command)
+		return script.ProcessKeyValueCommand(f.transformStorage, command, payload)
 	}
 }
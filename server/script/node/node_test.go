@@ -17,6 +17,7 @@ type testingT struct {
 	exec script.Executable
 	vars map[string]interface{}
 	incl []string
+	lims *script.Limits
 }
 
 func (t *testingT) load() *testingT {
@@ -25,7 +26,12 @@ func (t *testingT) load() *testingT {
 		t.Fatal(err)
 	}
 
-	inst, err := factory.CreateScript(t.exec, t.vars, false, t.incl...)
+	var scriptFactory script.Factory = factory
+	if t.lims != nil {
+		scriptFactory = factory.WithLimits(*t.lims)
+	}
+
+	inst, err := scriptFactory.CreateScript(t.exec, t.vars, false, t.incl...)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -137,6 +143,36 @@ func TestOutOfMemory(t *testing.T) {
 	}
 }
 
+func TestOutOfMemoryFailPolicy(t *testing.T) {
+	tt := &testingT{
+		T:    t,
+		exec: script.Expression(`(() => { let arr = []; for (;;) { arr.push(arr); } })()`),
+		lims: &script.Limits{MaxMemoryMB: 30, OutOfMemoryPolicy: script.OutOfMemoryFail},
+	}
+
+	defer tt.load().close()
+
+	err := tt.Execute("", nil, nil, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "out of memory")
+	}
+}
+
+func TestTimeoutLimit(t *testing.T) {
+	tt := &testingT{
+		T:    t,
+		exec: script.Expression(`(() => { for (;;) {} })()`),
+		lims: &script.Limits{Timeout: 500 * time.Millisecond},
+	}
+
+	defer tt.load().close()
+
+	err := tt.Execute("", nil, nil, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "script execution timeout exceeded")
+	}
+}
+
 func TestExpressionStackTrace(t *testing.T) {
 	tt := &testingT{
 		T: t,
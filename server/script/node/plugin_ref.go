@@ -3,14 +3,21 @@ package node
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
+	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/script"
 	"github.com/pkg/errors"
 )
 
+//pluginCacheKind is a cache kind of loaded plugins main files
+const pluginCacheKind = "node_plugin"
+
 type pluginRef struct {
 	plugin string
+	cache  script.Cache
 	main   string
 	err    error
 	once   sync.Once
@@ -22,7 +29,7 @@ func (r *pluginRef) get() (string, error) {
 		r.work.Add(1)
 		safego.Run(func() {
 			defer r.work.Done()
-			r.main, r.err = r.load()
+			r.main, r.err = r.cachedLoad()
 		})
 	})
 
@@ -30,6 +37,31 @@ func (r *pluginRef) get() (string, error) {
 	return r.main, r.err
 }
 
+//cachedLoad returns plugin from cache if plugin version is pinned (e.g. @jitsu/plugin@1.0.0), otherwise loads it
+func (r *pluginRef) cachedLoad() (string, error) {
+	if !isPinned(r.plugin) {
+		return r.load()
+	}
+
+	key := script.ContentKey(r.plugin)
+	if main, ok := r.cache.Get(pluginCacheKind, key); ok {
+		logging.Debugf("loaded plugin [%s] from cache", r.plugin)
+		return string(main), nil
+	}
+
+	main, err := r.load()
+	if err == nil {
+		r.cache.Set(pluginCacheKind, key, []byte(main))
+	}
+
+	return main, err
+}
+
+//isPinned returns true if npm package spec has version (name@version or @scope/name@version)
+func isPinned(plugin string) bool {
+	return strings.LastIndex(plugin, "@") > 0
+}
+
 func (r *pluginRef) load() (string, error) {
 	dir, err := os.MkdirTemp(os.TempDir(), "jitsu-plugin-")
 	if err != nil {
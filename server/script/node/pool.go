@@ -0,0 +1,178 @@
+package node
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//evictionInterval is an interval of idle sessions eviction
+var evictionInterval = time.Minute
+
+//PoolConfig is a configuration of Node processes pool
+type PoolConfig struct {
+	//MaxSessions is a maximum number of loaded script sessions per process (0 – unlimited).
+	//Least recently used sessions are unloaded when it is exceeded
+	MaxSessions int
+	//IdleTimeout is a time after which not used script sessions are unloaded (0 – never)
+	IdleTimeout time.Duration
+	//Warm spawns all pool processes on start instead of spawning them on the first script creation
+	Warm bool
+}
+
+type sessionEntry struct {
+	session  string
+	lastUsed time.Time
+}
+
+//sessions keeps script sessions loaded into one process in LRU order
+type sessions struct {
+	mu    sync.Mutex
+	lru   *list.List
+	index map[string]*list.Element
+}
+
+func newSessions() *sessions {
+	return &sessions{
+		lru:   list.New(),
+		index: make(map[string]*list.Element),
+	}
+}
+
+//touch marks session as recently used and returns least recently used sessions exceeding maxSessions
+func (s *sessions) touch(session string, maxSessions int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.index[session]; ok {
+		element.Value.(*sessionEntry).lastUsed = timestamp.Now()
+		s.lru.MoveToFront(element)
+	} else {
+		s.index[session] = s.lru.PushFront(&sessionEntry{session: session, lastUsed: timestamp.Now()})
+	}
+
+	var evicted []string
+	for maxSessions > 0 && s.lru.Len() > maxSessions {
+		evicted = append(evicted, s.removeElement(s.lru.Back()))
+	}
+
+	return evicted
+}
+
+//idle removes and returns sessions which haven't been used for idleTimeout
+func (s *sessions) idle(idleTimeout time.Duration) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var evicted []string
+	threshold := timestamp.Now().Add(-idleTimeout)
+	for element := s.lru.Back(); element != nil && element.Value.(*sessionEntry).lastUsed.Before(threshold); element = s.lru.Back() {
+		evicted = append(evicted, s.removeElement(element))
+	}
+
+	return evicted
+}
+
+func (s *sessions) remove(session string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.index[session]; ok {
+		s.removeElement(element)
+	}
+}
+
+func (s *sessions) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+func (s *sessions) removeElement(element *list.Element) string {
+	session := s.lru.Remove(element).(*sessionEntry).session
+	delete(s.index, session)
+	return session
+}
+
+//ConfigurePool applies pool configuration: spawns processes if config.Warm and starts idle sessions eviction
+func (f *Factory) ConfigurePool(config PoolConfig) error {
+	f.poolConfig = config
+	if config.Warm {
+		if err := f.warmUp(); err != nil {
+			return err
+		}
+	}
+
+	if config.IdleTimeout > 0 {
+		safego.RunWithRestart(f.evictIdleSessions)
+	}
+
+	return nil
+}
+
+//warmUp spawns all not yet spawned pool processes
+func (f *Factory) warmUp() error {
+	cancel, err := f.mu.Lock(f.ctx)
+	if err != nil {
+		return err
+	}
+
+	defer cancel()
+	for i, exer := range f.exchangers {
+		if exer != nil {
+			continue
+		}
+
+		exer, err := f.newExchanger(f.maxSpace, false)
+		if err != nil {
+			return err
+		}
+
+		f.exchangers[i] = exer
+	}
+
+	logging.Infof("Node.js pool: %d processes are warmed up", len(f.exchangers))
+	return nil
+}
+
+//evictIdleSessions periodically unloads script sessions which haven't been used for PoolConfig.IdleTimeout
+func (f *Factory) evictIdleSessions() {
+	ticker := time.NewTicker(evictionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, exer := range f.allExchangers() {
+				exer.unload(exer.sessions.idle(f.poolConfig.IdleTimeout)...)
+			}
+		}
+	}
+}
+
+//allExchangers returns all running pooled exchangers
+func (f *Factory) allExchangers() []*exchanger {
+	cancel, err := f.mu.Lock(f.ctx)
+	if err != nil {
+		return nil
+	}
+
+	defer cancel()
+	exchangers := make([]*exchanger, 0, len(f.exchangers)+len(f.limitedExchangers))
+	for _, exer := range f.exchangers {
+		if exer != nil {
+			exchangers = append(exchangers, exer)
+		}
+	}
+
+	for _, exer := range f.limitedExchangers {
+		exchangers = append(exchangers, exer)
+	}
+
+	return exchangers
+}
@@ -0,0 +1,41 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionsLRU(t *testing.T) {
+	s := newSessions()
+	assert.Empty(t, s.touch("a", 2))
+	assert.Empty(t, s.touch("b", 2))
+	assert.Empty(t, s.touch("a", 2))
+	assert.Equal(t, []string{"b"}, s.touch("c", 2))
+	assert.Equal(t, 2, s.len())
+
+	s.remove("a")
+	assert.Equal(t, 1, s.len())
+	assert.Empty(t, s.touch("d", 0))
+}
+
+func TestSessionsIdle(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	timestamp.FreezeTime()
+	timestamp.SetFreezeTime(start)
+	defer timestamp.UnfreezeTime()
+
+	s := newSessions()
+	s.touch("a", 0)
+	timestamp.SetFreezeTime(start.Add(time.Minute))
+	s.touch("b", 0)
+	timestamp.SetFreezeTime(start.Add(2 * time.Minute))
+	s.touch("c", 0)
+
+	timestamp.SetFreezeTime(start.Add(3 * time.Minute))
+	assert.Equal(t, []string{"a", "b"}, s.idle(90*time.Second))
+	assert.Equal(t, 1, s.len())
+	assert.Empty(t, s.idle(90*time.Second))
+}
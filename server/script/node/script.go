@@ -1,12 +1,15 @@
 package node
 
 import (
+	"context"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/script"
 	"github.com/jitsucom/jitsu/server/script/ipc"
 	"github.com/pkg/errors"
@@ -33,19 +36,39 @@ type Execute struct {
 
 type Script struct {
 	*Init
-	exchanger  *exchanger
-	colOffset  int
-	rowOffset  int
-	errCount   int
-	standalone bool
+	exchanger   *exchanger
+	colOffset   int
+	rowOffset   int
+	errCount    int
+	standalone  bool
+	limits      script.Limits
+	maxSessions int
+	cache       script.Cache
 }
 
+//symbolsCacheKind is a cache kind of scripts symbols
+const symbolsCacheKind = "node_symbols"
+
+//Describe returns script symbols. Symbols are cached by script session (hash of the executable, variables and includes),
+//so cached scripts aren't loaded into a process until the first execution
 func (s *Script) Describe() (script.Symbols, error) {
 	value := make(script.Symbols)
+	if data, ok := s.cache.Get(symbolsCacheKind, s.Session.Session); ok {
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value, nil
+		}
+
+		value = make(script.Symbols)
+	}
+
 	if err := s.exchange(describe, s.Session, &value, nil); err != nil {
 		return nil, err
 	}
 
+	if data, err := json.Marshal(value); err == nil {
+		s.cache.Set(symbolsCacheKind, s.Session.Session, data)
+	}
+
 	return value, nil
 }
 
@@ -61,17 +84,18 @@ func (s *Script) Close() {
 	if s.standalone {
 		s.exchanger.Close()
 	} else {
-		_ = s.exchanger.exchangeDirect(unload, s.Session, nil, nil)
+		s.exchanger.unload(s.Session.Session)
 	}
 }
 
 var vmStackTraceLineRegex = `^\s*at\s(.*?)\s\(.*?%s\.js:(\d+):(\d+)\)$`
 
 func (s *Script) exchange(command string, payload, result interface{}, listener script.Listener) error {
-	err := s.exchanger.exchange(command, payload, result, listener)
+	err := s.exchanger.exchange(command, payload, result, listener, s.limits.Timeout)
 	if errors.Is(err, ipc.ErrOutOfMemory) {
+		metrics.TransformKilled(s.destinationID(), "out_of_memory")
 		s.errCount++
-		if s.errCount >= maxScriptErrors {
+		if s.errCount >= s.maxErrors() {
 			return err
 		}
 
@@ -81,9 +105,17 @@ func (s *Script) exchange(command string, payload, result interface{}, listener
 	switch {
 	case err == nil:
 		s.errCount = 0
+		if !s.standalone {
+			s.exchanger.unload(s.exchanger.sessions.touch(s.Session.Session, s.maxSessions)...)
+		}
 		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		//process has been killed by ipc.StdIO and will be respawned on the next call
+		metrics.TransformKilled(s.destinationID(), "timeout")
+		s.errCount = 0
+		return errors.Wrap(err, "script execution timeout exceeded")
 	case errors.Is(err, errLoadRequired):
-		if err := s.exchanger.exchange(load, s.Init, nil, nil); err != nil {
+		if err := s.exchanger.exchange(load, s.Init, nil, nil, s.limits.Timeout); err != nil {
 			return s.rewriteJavaScriptStack(err)
 		}
 
@@ -94,6 +126,33 @@ func (s *Script) exchange(command string, payload, result interface{}, listener
 	}
 }
 
+//maxErrors returns number of attempts on out of memory errors according to the script limits
+func (s *Script) maxErrors() int {
+	switch {
+	case s.limits.OutOfMemoryPolicy == script.OutOfMemoryFail:
+		return 1
+	case s.limits.MaxRetries > 0:
+		return s.limits.MaxRetries + 1
+	default:
+		return maxScriptErrors
+	}
+}
+
+//includesLines returns number of lines in includes joined by a newline
+func (s *Script) includesLines() int {
+	lines := 0
+	for _, include := range s.Init.Includes {
+		lines += strings.Count(include, "\n") + 1
+	}
+
+	return lines
+}
+
+func (s *Script) destinationID() string {
+	destinationID, _ := s.Init.Variables["destinationId"].(string)
+	return destinationID
+}
+
 func (s *Script) rewriteJavaScriptStack(err error) error {
 	var jsErr jsError
 	if !errors.As(err, &jsErr) {
@@ -123,7 +182,7 @@ func (s *Script) rewriteJavaScriptStack(err error) error {
 		}
 
 		row, _ := strconv.Atoi(match[2])
-		row -= s.rowOffset + 1 + len(s.Init.Includes)
+		row -= s.rowOffset + 1 + s.includesLines()
 		if row < 0 {
 			return err
 		}
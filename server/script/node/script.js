@@ -4,8 +4,12 @@ const __jts_log__ = [];
 const __jts_result = "[[JITSU_RESULT_COMMAND]]";
 const __jts_keyvalue_get = "[[JITSU_KV_GET_COMMAND]]";
 const __jts_keyvalue_set = "[[JITSU_KV_SET_COMMAND]]";
+const __jts_log = "[[JITSU_LOG_COMMAND]]";
+const __jts_metric = "[[JITSU_METRIC_COMMAND]]";
 const __jts_command_callbacks = new Map();
 let __jts_command_id = 0;
+// id of the event being processed. It is used for tagging $log and $metric output
+let __jts_event_id = undefined;
 
 for (let level of ["trace", "info", "warn", "error"]) {
   console[level] = (...args) => {
@@ -188,6 +192,16 @@ const load = async (id, executable, variables, includes) => {
       );
     $kv.del = async (key) => jitsuTransformKeySet(destinationId, key, null);
     vm.sandbox.$kv = $kv;
+    const $log = {};
+    for (let level of ["debug", "info", "warn", "error"]) {
+      $log[level] = (message, fields) =>
+        jitsuTransformLog(destinationId, level, message, fields);
+    }
+    vm.sandbox.$log = $log;
+    vm.sandbox.$metric = {
+      increment: (name, value) =>
+        jitsuTransformMetric(destinationId, name, value ?? 1),
+    };
   }
 
   let file = path.join(process.cwd(), `${id}.js`);
@@ -247,6 +261,34 @@ const jitsuTransformKeySet = async function (destinationId, key, value, ttlMs) {
   });
 };
 
+const jitsuTransformLog = function (destinationId, level, message, fields) {
+  send(
+    __jts_log,
+    JSON.stringify({
+      destinationId,
+      eventId: __jts_event_id,
+      level,
+      message: typeof message === "string" ? message : JSON.stringify(message),
+      fields,
+    })
+  );
+};
+
+const jitsuTransformMetric = function (destinationId, name, value) {
+  send(
+    __jts_metric,
+    JSON.stringify({
+      destinationId,
+      eventId: __jts_event_id,
+      name,
+      value: Number(value),
+    })
+  );
+};
+
+const eventId = (event) =>
+  event?.eventn_ctx?.event_id ?? event?.eventn_ctx_event_id ?? event?.event_id;
+
 //
 // Transport
 //
@@ -326,9 +368,11 @@ readline
           }
 
           try {
+            __jts_event_id = eventId(args[0]);
             result = await (func ? exec[func](...args) : exec(...args));
           } finally {
             entry.sandbox.fetch = undefined;
+            __jts_event_id = undefined;
           }
 
           break;
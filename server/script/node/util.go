@@ -82,6 +82,13 @@ func installNodeModule(dir string, spec string) error {
 	return script.Exec(dir, npm, args...)
 }
 
+//installNodeModules installs all dependencies from package.json in dir
+func installNodeModules(dir string) error {
+	nodeModuleMu.Lock()
+	defer nodeModuleMu.Unlock()
+	return script.Exec(dir, npm, "install", "--no-audit", "--prefer-online")
+}
+
 func checkNodeModule(modulesDir string, name, version string) error {
 	packageJSON, err := readPackageJSON(filepath.Join(modulesDir, name))
 	if err != nil {
@@ -0,0 +1,257 @@
+package python
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/jitsucom/jitsu/server/script/ipc"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	python         = "python3"
+	pythonPathEnv  = "PYTHONPATH"
+	mainFile       = "main.py"
+	sitePackages   = "site-packages"
+	expressionFlag = "__jitsu_expression__ = True"
+)
+
+var (
+	//go:embed script.py
+	scriptContent string
+)
+
+var errPythonRequired = errors.New(`python3 is not found in $PATH.
+	Jitsu will be functional, however Python transformations won't be available.
+	Please make sure that python3 (>=3.7) with pip is installed and available`)
+
+// Factory runs Python scripts in separate python3 processes.
+// It uses the same IPC protocol as the Node runtime (see script/node).
+type Factory struct {
+	dir              string
+	packages         *sync.Map
+	exchangers       []*exchanger
+	mu               ipc.Mutex
+	transformStorage script.Storage
+}
+
+// NewFactory returns Factory with poolSize python processes (for non-standalone scripts).
+// pip packages are installed into dir/site-packages.
+func NewFactory(poolSize int, transformStorage script.Storage, tmpDir ...string) (*Factory, error) {
+	if _, err := exec.LookPath(python); err != nil {
+		return nil, errPythonRequired
+	}
+
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+
+	var dir string
+	if len(tmpDir) > 0 {
+		dir = tmpDir[0]
+	} else {
+		var err error
+		dir, err = os.MkdirTemp(os.TempDir(), "jitsu-python-")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create temp directory")
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, sitePackages), 0755); err != nil {
+		return nil, errors.Wrapf(err, "create %s directory", sitePackages)
+	}
+
+	scriptPath := filepath.Join(dir, mainFile)
+	replacer := strings.NewReplacer("[[JITSU_RESULT_COMMAND]]", ipc.JitsuScriptResultCommand,
+		"[[JITSU_KV_GET_COMMAND]]", script.JitsuKvGetCommand,
+		"[[JITSU_KV_SET_COMMAND]]", script.JitsuKvSetCommand)
+	if err := os.WriteFile(scriptPath, []byte(replacer.Replace(scriptContent)), 0644); err != nil {
+		return nil, errors.Wrapf(err, "write to %s", scriptPath)
+	}
+
+	if transformStorage == nil {
+		transformStorage = &script.Dummy{}
+	}
+
+	return &Factory{
+		dir:              dir,
+		packages:         new(sync.Map),
+		exchangers:       make([]*exchanger, poolSize),
+		transformStorage: transformStorage,
+	}, nil
+}
+
+func (f *Factory) Close() error {
+	cancel, _ := f.mu.Lock(context.Background())
+	defer cancel()
+
+	for _, exchanger := range f.exchangers {
+		if exchanger == nil {
+			continue
+		}
+
+		_ = exchanger.Close()
+	}
+
+	_ = os.RemoveAll(f.dir)
+	return nil
+}
+
+// CreateScript loads Python executable:
+//  script.Expression – body of `main(event)` function (`return` is added for one-line expressions)
+//  script.Package – pip package spec (e.g. `jitsu-destination==1.0.0`), its module is imported with `from module import *`
+//  script.File – path to Python file
+// includes are JavaScript snippets and are ignored by Python runtime.
+func (f *Factory) CreateScript(executable script.Executable, variables map[string]interface{}, standalone bool, includes ...string) (script.Interface, error) {
+	var code string
+	switch e := executable.(type) {
+	case script.Expression:
+		code = wrapExpression(string(e))
+	case script.Package:
+		module, err := f.installPackage(string(e))
+		if err != nil {
+			return nil, errors.Wrapf(err, "load package %s", string(e))
+		}
+
+		code = fmt.Sprintf("from %s import *", module)
+	case script.File:
+		data, err := os.ReadFile(string(e))
+		if err != nil {
+			return nil, errors.Wrapf(err, "read file %s", string(e))
+		}
+
+		code = string(data)
+	default:
+		return nil, errors.Errorf("unsupported executable type %T", executable)
+	}
+
+	if len(includes) > 0 {
+		logging.Debugf("python runtime ignores %d javascript includes", len(includes))
+	}
+
+	init := &Init{
+		Executable: code,
+		Variables:  sanitizeVariables(variables),
+	}
+
+	hash, err := hashstructure.Hash(init, hashstructure.FormatV2, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "hash init")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cancel, err = f.mu.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer cancel()
+
+	var exer *exchanger
+	exchangerIdx := hash % uint64(len(f.exchangers))
+	if !standalone {
+		exer = f.exchangers[exchangerIdx]
+	}
+
+	if exer == nil {
+		process := &ipc.StdIO{
+			Dir:  f.dir,
+			Path: python,
+			Args: []string{"-u", filepath.Join(f.dir, mainFile)},
+			Env:  []string{pythonPathEnv + "=" + filepath.Join(f.dir, sitePackages), "TZ=Etc/UTC", "PYTHONIOENCODING=utf-8"},
+			CommandProcessor: func(command string, payload []byte) (*ipc.CommandResponse, error) {
+				return script.ProcessKeyValueCommand(f.transformStorage, command, payload)
+			},
+		}
+
+		governor, err := ipc.Govern(process, standalone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "govern process")
+		}
+
+		logging.Debugf("%s running in %s", governor, f.dir)
+		exer = &exchanger{Governor: governor}
+		if !standalone {
+			f.exchangers[exchangerIdx] = exer
+		}
+	}
+
+	init.Session.Session = fmt.Sprintf("%x", hash)
+	return &Script{
+		Init:       init,
+		exchanger:  exer,
+		standalone: standalone,
+	}, nil
+}
+
+// installPackage installs pip package once and returns its module name
+func (f *Factory) installPackage(spec string) (string, error) {
+	value, _ := f.packages.LoadOrStore(spec, &packageRef{})
+	ref := value.(*packageRef)
+	ref.once.Do(func() {
+		ref.err = script.Exec(f.dir, python, "-m", "pip", "install", "--quiet", "--disable-pip-version-check",
+			"--target", filepath.Join(f.dir, sitePackages), spec)
+	})
+
+	if ref.err != nil {
+		return "", ref.err
+	}
+
+	return moduleName(spec), nil
+}
+
+type packageRef struct {
+	once sync.Once
+	err  error
+}
+
+// moduleName returns python module name from pip package spec: `jitsu-destination==1.0.0` -> `jitsu_destination`
+func moduleName(spec string) string {
+	if i := strings.IndexAny(spec, "=<>!~[;@ "); i > 0 {
+		spec = spec[:i]
+	}
+
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(spec)), "-", "_")
+}
+
+// wrapExpression wraps expression into `main(event)` function
+func wrapExpression(expression string) string {
+	expression = strings.Trim(expression, "\n")
+	if !strings.Contains(expression, "\n") && !strings.HasPrefix(expression, "return") &&
+		!strings.HasPrefix(expression, "raise ") && expression != "pass" {
+		expression = "return " + expression
+	}
+
+	lines := strings.Split(expression, "\n")
+	for i, line := range lines {
+		lines[i] = "    " + line
+	}
+
+	return expressionFlag + "\n" +
+		"def main(event):\n" +
+		"    _ = event\n" +
+		strings.Join(lines, "\n") + "\n"
+}
+
+func sanitizeVariables(vars map[string]interface{}) map[string]interface{} {
+	variables := make(map[string]interface{})
+	for key, value := range vars {
+		if value == nil || reflect.TypeOf(value).Kind() != reflect.Func {
+			variables[key] = value
+		}
+	}
+
+	return variables
+}
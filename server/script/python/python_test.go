@@ -0,0 +1,86 @@
+package python_test
+
+import (
+	"encoding/json"
+	"os/exec"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/jitsucom/jitsu/server/script/python"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFactory(t *testing.T) *python.Factory {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not installed")
+	}
+
+	factory, err := python.NewFactory(1, nil, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = factory.Close() })
+	return factory
+}
+
+func TestPythonExpression(t *testing.T) {
+	factory := newFactory(t)
+	inst, err := factory.CreateScript(script.Expression(`event["a"] + 1`), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inst.Close()
+
+	var result int
+	err = inst.Execute("", []interface{}{map[string]interface{}{"a": 1}}, &result, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result)
+}
+
+func TestPythonMultilineExpression(t *testing.T) {
+	factory := newFactory(t)
+	inst, err := factory.CreateScript(script.Expression(`
+event["table"] = prefix + event["type"]
+return event`), map[string]interface{}{"prefix": "t_"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inst.Close()
+
+	var result map[string]interface{}
+	err = inst.Execute("", []interface{}{map[string]interface{}{"type": "pageview"}}, &result, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "t_pageview", result["table"])
+
+	symbols, err := inst.Describe()
+	assert.NoError(t, err)
+	assert.Empty(t, symbols)
+}
+
+func TestPythonNamedExports(t *testing.T) {
+	factory := newFactory(t)
+	inst, err := factory.CreateScript(script.Expression(`return None`), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inst.Close()
+
+	err = inst.Execute("destination", nil, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestPythonError(t *testing.T) {
+	factory := newFactory(t)
+	inst, err := factory.CreateScript(script.Expression(`raise ValueError("bad event")`), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inst.Close()
+
+	var result json.RawMessage
+	err = inst.Execute("", []interface{}{map[string]interface{}{}}, &result, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "ValueError: bad event")
+	}
+}
@@ -0,0 +1,192 @@
+package python
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/jitsucom/jitsu/server/script/ipc"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	load     = "load"
+	describe = "describe"
+	execute  = "execute"
+	unload   = "unload"
+)
+
+var DefaultExchangeTimeout = time.Minute
+
+var errLoadRequired = errors.New("load required")
+
+type Session struct {
+	Session string `json:"session"`
+}
+
+type Init struct {
+	Session
+	Executable string                 `json:"executable"`
+	Variables  map[string]interface{} `json:"variables,omitempty"`
+}
+
+type Execute struct {
+	Session
+	Function string        `json:"function,omitempty"`
+	Args     []interface{} `json:"args"`
+}
+
+type Request struct {
+	Command string      `json:"command"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+type Log struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+type Response struct {
+	Ok     bool            `json:"ok"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Stack  string          `json:"stack,omitempty"`
+	Log    []Log           `json:"log,omitempty"`
+}
+
+type pyError struct {
+	message string
+	stack   string
+}
+
+func (e pyError) Error() string {
+	if e.stack != "" {
+		return e.stack
+	}
+
+	return e.message
+}
+
+// Script is a Python executable loaded into python process
+type Script struct {
+	*Init
+	exchanger  *exchanger
+	standalone bool
+}
+
+func (s *Script) Describe() (script.Symbols, error) {
+	value := make(script.Symbols)
+	if err := s.exchange(describe, s.Session, &value, nil); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (s *Script) Execute(name string, args []interface{}, result interface{}, listener script.Listener) error {
+	if args == nil {
+		args = make([]interface{}, 0)
+	}
+
+	return s.exchange(execute, Execute{Session: s.Session, Function: name, Args: args}, result, listener)
+}
+
+func (s *Script) Close() {
+	if s.standalone {
+		s.exchanger.Close()
+	} else {
+		_ = s.exchanger.exchangeDirect(unload, s.Session, nil, nil)
+	}
+}
+
+func (s *Script) exchange(command string, payload, result interface{}, listener script.Listener) error {
+	err := s.exchanger.exchange(command, payload, result, listener)
+	if errors.Is(err, errLoadRequired) {
+		if err := s.exchanger.exchange(load, s.Init, nil, nil); err != nil {
+			return err
+		}
+
+		return s.exchanger.exchange(command, payload, result, listener)
+	}
+
+	return err
+}
+
+type exchanger struct {
+	*ipc.Governor
+}
+
+type exchangerFunc func(ctx context.Context, data []byte, listener ipc.DataListener) ([]byte, error)
+
+func (e *exchanger) exchangeDirect(command string, payload, result interface{}, listener script.Listener) error {
+	return e.exchange0(command, payload, result, listener, e.ExchangeDirect)
+}
+
+func (e *exchanger) exchange(command string, payload, result interface{}, listener script.Listener) error {
+	return e.exchange0(command, payload, result, listener, e.Exchange)
+}
+
+func (e *exchanger) exchange0(command string, payload, result interface{}, listener script.Listener, exchangerFunc exchangerFunc) error {
+	data, err := json.Marshal(Request{
+		Command: command,
+		Payload: payload,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error building python command: %w", err)
+	}
+
+	timeout := DefaultExchangeTimeout
+	if listener != nil && listener.Timeout() > 0 {
+		timeout = listener.Timeout()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := timestamp.Now()
+	newData, err := exchangerFunc(ctx, data, listener)
+
+	logging.Debugf("%s: %s => %s (%v) [%s]", e, string(data), string(newData), err, timestamp.Now().Sub(start))
+	if err != nil {
+		return err
+	}
+
+	var resp Response
+	if err := json.Unmarshal(newData, &resp); err != nil {
+		return err
+	}
+
+	if listener != nil {
+		for _, log := range resp.Log {
+			listener.Log(log.Level, log.Message)
+		}
+	}
+
+	if !resp.Ok {
+		if resp.Error == "__load_required__" {
+			return errLoadRequired
+		}
+
+		return pyError{
+			message: resp.Error,
+			stack:   resp.Stack,
+		}
+	}
+
+	if result != nil && resp.Result != nil {
+		decoder := json.NewDecoder(bytes.NewReader(resp.Result))
+		//parse json exactly the same way as it happens in http request processing.
+		decoder.UseNumber()
+		if err := decoder.Decode(result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
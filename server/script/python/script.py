@@ -0,0 +1,241 @@
+# Jitsu Python transformation runtime.
+# Communicates with Jitsu server via stdin/stdout using the same protocol as the Node runtime:
+# one JSON request per line on stdin, results are written as J$<command>:<json> lines to stdout.
+
+import json
+import sys
+import traceback
+import types
+
+_jts_result = "[[JITSU_RESULT_COMMAND]]"
+_jts_keyvalue_get = "[[JITSU_KV_GET_COMMAND]]"
+_jts_keyvalue_set = "[[JITSU_KV_SET_COMMAND]]"
+
+_stdin = sys.stdin
+_stdout = sys.stdout
+_log = []
+_sessions = {}
+_command_id = 0
+
+
+class LoadRequired(Exception):
+    pass
+
+
+class _LogWriter:
+    """Captures print() output of user code as info logs."""
+
+    def write(self, data):
+        data = data.rstrip("\n")
+        if data:
+            _log.append({"level": "info", "message": data})
+
+    def flush(self):
+        pass
+
+
+class _Logger:
+    def __getattr__(self, level):
+        if level == "warning":
+            level = "warn"
+
+        def log(*args):
+            _log.append({"level": level, "message": " ".join(_stringify(arg) for arg in args)})
+
+        return log
+
+
+def _stringify(value):
+    if isinstance(value, (dict, list)):
+        try:
+            return json.dumps(value, indent=2, default=str)
+        except (TypeError, ValueError):
+            pass
+    return str(value)
+
+
+def _send(command, data):
+    _stdout.write("\nJ$%s:%s\n" % (command, data))
+    _stdout.flush()
+
+
+def _reply(result=None, error=None, stack=None):
+    data = {
+        "ok": error is None,
+        "result": result,
+        "error": error,
+        "stack": stack,
+        "log": list(_log),
+    }
+    del _log[:]
+    try:
+        payload = json.dumps(data, default=str)
+    except (TypeError, ValueError) as e:
+        payload = json.dumps({"ok": False, "error": "Failed to serialize result: %s" % e, "log": data["log"]})
+    _send(_jts_result, payload)
+
+
+def _kv_command(command, request):
+    """Sends key-value command to Jitsu server and synchronously waits for the response line."""
+    global _command_id
+    _command_id += 1
+    request["requestId"] = _command_id
+    _send(command, json.dumps(request))
+    line = _stdin.readline()
+    if not line:
+        raise EOFError("stdin is closed")
+    response = json.loads(line).get("payload") or {}
+    if not response.get("success"):
+        raise RuntimeError("key-value storage error: %s" % response.get("error"))
+    value = response.get("value")
+    return json.loads(value) if value else None
+
+
+class _KeyValue:
+    def __init__(self, destination_id):
+        self._destination_id = destination_id
+
+    def get(self, key):
+        return _kv_command(_jts_keyvalue_get, {"destinationId": self._destination_id, "key": key})
+
+    def set(self, key, value, ttl_ms=None, ttl_sec=None):
+        if ttl_ms is None and ttl_sec is not None:
+            ttl_ms = int(ttl_sec * 1000)
+        request = {"destinationId": self._destination_id, "key": key, "value": json.dumps(value)}
+        if ttl_ms is not None:
+            request["ttlMs"] = ttl_ms
+        return _kv_command(_jts_keyvalue_set, request)
+
+    def delete(self, key):
+        return _kv_command(_jts_keyvalue_set, {"destinationId": self._destination_id, "key": key})
+
+
+def _load(session, executable, variables, includes):
+    module = types.ModuleType("jitsu_%s" % session)
+    namespace = module.__dict__
+    namespace["log"] = _Logger()
+    namespace["print"] = lambda *args, **kwargs: _log.append(
+        {"level": "info", "message": " ".join(_stringify(arg) for arg in args)})
+    for name, value in (variables or {}).items():
+        namespace[name] = value
+    if "destinationId" in namespace:
+        namespace["kv"] = _KeyValue(namespace["destinationId"])
+    reserved = set(namespace.keys())
+
+    code = "\n".join(list(includes or []) + [executable])
+    exec(compile(code, "%s.py" % session, "exec"), namespace)
+    _sessions[session] = {"module": module, "reserved": reserved}
+
+
+def _exports_names(module):
+    exports = getattr(module, "__all__", None)
+    if exports is not None:
+        return set(exports)
+    return set(name for name in module.__dict__ if not name.startswith("_"))
+
+
+def _session(session):
+    if session not in _sessions:
+        raise LoadRequired()
+    return _sessions[session]
+
+
+def _symbol_type(value):
+    if callable(value):
+        return "function"
+    if isinstance(value, bool):
+        return "boolean"
+    if isinstance(value, (int, float)):
+        return "number"
+    if isinstance(value, str):
+        return "string"
+    if value is None:
+        return "undefined"
+    return "object"
+
+
+def _describe(session):
+    entry = _session(session)
+    module = entry["module"]
+    symbols = {}
+    for name in _exports_names(module):
+        if name in entry["reserved"] or (name == "main" and "__jitsu_expression__" in module.__dict__):
+            continue
+        value = getattr(module, name)
+        if isinstance(value, types.ModuleType) or isinstance(value, type):
+            continue
+        symbol = {"type": _symbol_type(value)}
+        if symbol["type"] != "function":
+            symbol["value"] = value
+        symbols[name] = symbol
+    return symbols
+
+
+def _execute(session, function, args):
+    module = _session(session)["module"]
+    anonymous = "__jitsu_expression__" in module.__dict__
+    if not function:
+        if not anonymous:
+            raise Exception("this executable provides named exports, but an anonymous one was given for execution")
+        function = "main"
+    elif anonymous:
+        raise Exception(
+            "this executable provides an anonymous function export, but a named one (%s) was given for execution" % function)
+
+    func = getattr(module, function, None)
+    if func is None or not callable(func):
+        raise Exception("function %s does not exist" % function)
+
+    return func(*(args or []))
+
+
+def _user_stack(e):
+    """Returns traceback of user code only (without runtime frames)."""
+    frames = [frame for frame in traceback.extract_tb(e.__traceback__) if frame.filename != __file__]
+    lines = ["  at %s (%s:%d)" % (frame.name, frame.filename, frame.lineno) for frame in frames]
+    return "\n".join(["%s: %s" % (type(e).__name__, e)] + lines)
+
+
+def _main():
+    sys.stdout = _LogWriter()
+    while True:
+        line = _stdin.readline()
+        if not line:
+            return
+        line = line.strip()
+        if not line:
+            continue
+
+        try:
+            req = json.loads(line)
+        except ValueError as e:
+            _reply(error="Failed to parse incoming IPC request [%s]: %s" % (line, e))
+            continue
+
+        command = req.get("command")
+        if not command:
+            _reply(error="Command is not specified")
+            continue
+
+        payload = req.get("payload") or {}
+        result = None
+        try:
+            if command == "load":
+                _load(payload.get("session"), payload.get("executable"), payload.get("variables"), payload.get("includes"))
+            elif command == "describe":
+                result = _describe(payload.get("session"))
+            elif command == "execute":
+                result = _execute(payload.get("session"), payload.get("function"), payload.get("args"))
+            elif command == "unload":
+                _sessions.pop(payload.get("session"), None)
+            else:
+                raise Exception("Unsupported command: %s" % command)
+            _reply(result)
+        except LoadRequired:
+            _reply(error="__load_required__")
+        except Exception as e:
+            _reply(error="%s: %s" % (type(e).__name__, e), stack=_user_stack(e))
+
+
+if __name__ == "__main__":
+    _main()
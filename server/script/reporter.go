@@ -0,0 +1,176 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"golang.org/x/time/rate"
+)
+
+const (
+	JitsuLogCommand    = "_JITSU_LOG"
+	JitsuMetricCommand = "_JITSU_METRIC"
+
+	DefaultLogsRateLimit    = 10
+	DefaultMetricsRateLimit = 100
+
+	//maxMetricNames is a maximum number of distinct user metrics per destination
+	maxMetricNames = 100
+	maxLogLength   = 10000
+)
+
+var metricNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_:]{0,63}$`)
+
+// LogCommand is a payload of log record written by a script via $log API.
+type LogCommand struct {
+	DestinationId string                 `json:"destinationId"`
+	EventId       string                 `json:"eventId,omitempty"`
+	Level         string                 `json:"level"`
+	Message       string                 `json:"message"`
+	Fields        map[string]interface{} `json:"fields,omitempty"`
+}
+
+// MetricCommand is a payload of metric reported by a script via $metric API.
+type MetricCommand struct {
+	DestinationId string  `json:"destinationId"`
+	EventId       string  `json:"eventId,omitempty"`
+	Name          string  `json:"name"`
+	Value         float64 `json:"value"`
+}
+
+// Reporter forwards log records and metrics from scripts into the server logs and metrics.
+// Records are rate limited per destination: exceeded records are dropped and counted in metrics.
+type Reporter struct {
+	logsRateLimit    rate.Limit
+	metricsRateLimit rate.Limit
+	destinations     sync.Map
+}
+
+type destinationReporter struct {
+	logs    *rate.Limiter
+	metrics *rate.Limiter
+
+	mu          sync.Mutex
+	metricNames map[string]bool
+}
+
+// NewReporter returns Reporter with logs and metrics per second limits per destination
+func NewReporter(logsPerSecond, metricsPerSecond int) *Reporter {
+	return &Reporter{
+		logsRateLimit:    rate.Limit(logsPerSecond),
+		metricsRateLimit: rate.Limit(metricsPerSecond),
+	}
+}
+
+// ProcessCommand handles log and metric commands sent by a script process.
+// These commands don't require response.
+func (r *Reporter) ProcessCommand(command string, payload []byte) error {
+	switch command {
+	case JitsuLogCommand:
+		record := &LogCommand{}
+		if err := json.Unmarshal(payload, record); err != nil {
+			return fmt.Errorf("failed to unmarshal log command: %s: %w", payload, err)
+		}
+
+		r.Log(record)
+		return nil
+	case JitsuMetricCommand:
+		metric := &MetricCommand{}
+		if err := json.Unmarshal(payload, metric); err != nil {
+			return fmt.Errorf("failed to unmarshal metric command: %s: %w", payload, err)
+		}
+
+		r.Metric(metric)
+		return nil
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
+
+// Log writes log record into the server logs if destination logs rate limit isn't exceeded.
+// Returns false if the record has been dropped
+func (r *Reporter) Log(record *LogCommand) bool {
+	if !r.destination(record.DestinationId).logs.Allow() {
+		metrics.TransformUserDropped(record.DestinationId, "log")
+		return false
+	}
+
+	message := record.Message
+	if len(message) > maxLogLength {
+		message = message[:maxLogLength] + "..."
+	}
+
+	if len(record.Fields) > 0 {
+		if fields, err := json.Marshal(record.Fields); err == nil {
+			message += " " + string(fields)
+		}
+	}
+
+	metrics.TransformUserLog(record.DestinationId, record.Level)
+	switch record.Level {
+	case "error":
+		logging.Errorf("[%s] transformation [event: %s]: %s", record.DestinationId, record.EventId, message)
+	case "warn":
+		logging.Warnf("[%s] transformation [event: %s]: %s", record.DestinationId, record.EventId, message)
+	case "debug":
+		logging.Debugf("[%s] transformation [event: %s]: %s", record.DestinationId, record.EventId, message)
+	default:
+		logging.Infof("[%s] transformation [event: %s]: %s", record.DestinationId, record.EventId, message)
+	}
+
+	return true
+}
+
+// Metric adds metric value if destination metrics rate limit isn't exceeded.
+// Metrics with invalid names or exceeding the limit of distinct names per destination are dropped.
+// Returns false if the metric has been dropped
+func (r *Reporter) Metric(metric *MetricCommand) bool {
+	destination := r.destination(metric.DestinationId)
+	if !metricNameRegex.MatchString(metric.Name) || metric.Value < 0 {
+		logging.Debugf("[%s] transformation [event: %s]: invalid metric %s=%v", metric.DestinationId, metric.EventId, metric.Name, metric.Value)
+		metrics.TransformUserDropped(metric.DestinationId, "metric")
+		return false
+	}
+
+	if !destination.metrics.Allow() || !destination.registerMetricName(metric.Name) {
+		metrics.TransformUserDropped(metric.DestinationId, "metric")
+		return false
+	}
+
+	metrics.TransformUserMetric(metric.DestinationId, metric.Name, metric.Value)
+	return true
+}
+
+func (r *Reporter) destination(destinationID string) *destinationReporter {
+	if value, ok := r.destinations.Load(destinationID); ok {
+		return value.(*destinationReporter)
+	}
+
+	value, _ := r.destinations.LoadOrStore(destinationID, &destinationReporter{
+		logs:        rate.NewLimiter(r.logsRateLimit, int(r.logsRateLimit)),
+		metrics:     rate.NewLimiter(r.metricsRateLimit, int(r.metricsRateLimit)),
+		metricNames: make(map[string]bool),
+	})
+
+	return value.(*destinationReporter)
+}
+
+//registerMetricName returns false if the limit of distinct metric names is exceeded
+func (d *destinationReporter) registerMetricName(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.metricNames[name] {
+		return true
+	}
+
+	if len(d.metricNames) >= maxMetricNames {
+		return false
+	}
+
+	d.metricNames[name] = true
+	return true
+}
@@ -0,0 +1,42 @@
+package script
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReporterLogsRateLimit(t *testing.T) {
+	reporter := NewReporter(2, 2)
+	record := &LogCommand{DestinationId: "project.destination", EventId: "event1", Level: "info", Message: "hello"}
+	assert.True(t, reporter.Log(record))
+	assert.True(t, reporter.Log(record))
+	assert.False(t, reporter.Log(record))
+
+	//limits are per destination
+	assert.True(t, reporter.Log(&LogCommand{DestinationId: "project.another", Level: "warn", Message: "hello"}))
+}
+
+func TestReporterMetrics(t *testing.T) {
+	reporter := NewReporter(DefaultLogsRateLimit, 1000)
+	assert.True(t, reporter.Metric(&MetricCommand{DestinationId: "destination", Name: "processed_orders", Value: 1}))
+	assert.False(t, reporter.Metric(&MetricCommand{DestinationId: "destination", Name: "invalid name", Value: 1}))
+	assert.False(t, reporter.Metric(&MetricCommand{DestinationId: "destination", Name: "negative", Value: -1}))
+
+	for i := 1; i < maxMetricNames; i++ {
+		require.True(t, reporter.Metric(&MetricCommand{DestinationId: "destination", Name: fmt.Sprintf("metric_%d", i), Value: 1}))
+	}
+
+	assert.False(t, reporter.Metric(&MetricCommand{DestinationId: "destination", Name: "one_more", Value: 1}))
+	assert.True(t, reporter.Metric(&MetricCommand{DestinationId: "destination", Name: "processed_orders", Value: 2}))
+}
+
+func TestReporterProcessCommand(t *testing.T) {
+	reporter := NewReporter(DefaultLogsRateLimit, DefaultMetricsRateLimit)
+	assert.NoError(t, reporter.ProcessCommand(JitsuLogCommand, []byte(`{"destinationId":"destination","level":"info","message":"hello","fields":{"a":1}}`)))
+	assert.NoError(t, reporter.ProcessCommand(JitsuMetricCommand, []byte(`{"destinationId":"destination","name":"orders","value":1}`)))
+	assert.Error(t, reporter.ProcessCommand(JitsuLogCommand, []byte(`not json`)))
+	assert.Error(t, reporter.ProcessCommand("unknown", nil))
+}
@@ -3,6 +3,7 @@ package script
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -99,3 +100,59 @@ type Factory interface {
 	// `includes` are code snippets to embed into script.
 	CreateScript(executable Executable, variables map[string]interface{}, standalone bool, includes ...string) (Interface, error)
 }
+
+// Limits are resource limits of a single script.
+// Zero values mean that Factory defaults are used.
+// Limits are bound to the script instance (e.g. destination transformation), not to the events source (API key).
+type Limits struct {
+
+	// MaxMemoryMB is a memory ceiling of the process (or module instance) running the script.
+	MaxMemoryMB int
+
+	// Timeout is a maximum execution time of a single call. The process is killed when it is exceeded.
+	Timeout time.Duration
+
+	// OutOfMemoryPolicy defines what happens when the process running the script has been killed because of out of memory:
+	// OutOfMemoryRetry (default) or OutOfMemoryFail.
+	OutOfMemoryPolicy string
+
+	// MaxRetries is a number of retries with OutOfMemoryRetry policy.
+	MaxRetries int
+}
+
+const (
+	// OutOfMemoryRetry respawns the process and retries the call up to Limits.MaxRetries times.
+	OutOfMemoryRetry = "retry"
+
+	// OutOfMemoryFail respawns the process and returns the error immediately.
+	OutOfMemoryFail = "fail"
+)
+
+// Validate returns error if Limits contain unknown OutOfMemoryPolicy or negative values.
+func (l Limits) Validate() error {
+	if l.OutOfMemoryPolicy != "" && l.OutOfMemoryPolicy != OutOfMemoryRetry && l.OutOfMemoryPolicy != OutOfMemoryFail {
+		return fmt.Errorf("unknown out of memory policy: %s. Supported: %s, %s", l.OutOfMemoryPolicy, OutOfMemoryRetry, OutOfMemoryFail)
+	}
+
+	if l.MaxMemoryMB < 0 || l.Timeout < 0 || l.MaxRetries < 0 {
+		return errors.New("script limits must not be negative")
+	}
+
+	return nil
+}
+
+// LimitedFactory is a Factory which supports per-script Limits.
+type LimitedFactory interface {
+	Factory
+
+	// WithLimits returns Factory which creates scripts with the provided Limits.
+	WithLimits(limits Limits) Factory
+}
+
+// DependenciesBundler is a Factory which supports package dependencies of scripts.
+type DependenciesBundler interface {
+
+	// BundleDependencies installs and bundles dependencies (package name -> version)
+	// and returns a code snippet to pass into CreateScript as an include.
+	BundleDependencies(dependencies map[string]string) (string, error)
+}
@@ -73,7 +73,8 @@ func InitializeStorage(enabled bool, metaStorageConfiguration *viper.Viper) (Sto
 		defaultTransformKeyValueTTLms = redisConfigurationSource.GetInt64("ttl_sec") * 1000
 	}
 
-	factory := meta.NewRedisPoolFactory(host, port, password, database, tlsSkipVerify, sentinelMaster)
+	factory := meta.NewRedisPoolFactory(host, port, password, database, tlsSkipVerify, sentinelMaster).
+		WithConfiguration(redisConfigurationSource)
 	options := factory.GetOptions()
 	options.MaxActive = 100
 	factory.WithOptions(options)
@@ -0,0 +1,116 @@
+package wasm
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	//wasmPagesPerMB is a number of 64KiB WebAssembly memory pages in 1MB
+	wasmPagesPerMB = 16
+
+	DefaultMaxMemoryMB = 64
+	DefaultTimeout     = 5 * time.Second
+)
+
+// Factory runs WebAssembly modules in-process (wazero runtime) without spawning any processes.
+// Modules must be WASI commands (e.g. JavaScript compiled with Javy or any language targeting wasm32-wasi):
+// a module reads one JSON request from stdin and writes one JSON response to stdout (see Request and Response).
+// Every call is executed in a fresh module instance with memory and time limits.
+type Factory struct {
+	runtime wazero.Runtime
+	modules *sync.Map
+	timeout time.Duration
+}
+
+// NewFactory returns Factory with maxMemoryMB memory limit per module instance and timeout per call.
+// If compilationCacheDir isn't empty, compiled modules are cached on disk by module content and aren't recompiled after restarts
+func NewFactory(maxMemoryMB int, timeout time.Duration, compilationCacheDir string) (*Factory, error) {
+	if maxMemoryMB <= 0 {
+		maxMemoryMB = DefaultMaxMemoryMB
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(maxMemoryMB * wasmPagesPerMB)).
+		WithCloseOnContextDone(true)
+	if compilationCacheDir != "" {
+		cache, err := wazero.NewCompilationCacheWithDir(compilationCacheDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create compilation cache in %s", compilationCacheDir)
+		}
+
+		runtimeConfig = runtimeConfig.WithCompilationCache(cache)
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		_ = runtime.Close(ctx)
+		return nil, errors.Wrap(err, "instantiate wasi")
+	}
+
+	return &Factory{
+		runtime: runtime,
+		modules: new(sync.Map),
+		timeout: timeout,
+	}, nil
+}
+
+// CreateScript compiles WebAssembly module from script.File (path to .wasm file).
+// Compiled modules are cached by path. includes are JavaScript snippets and are ignored by WebAssembly runtime.
+func (f *Factory) CreateScript(executable script.Executable, variables map[string]interface{}, standalone bool, includes ...string) (script.Interface, error) {
+	file, ok := executable.(script.File)
+	if !ok {
+		return nil, errors.Errorf("unsupported executable type %T: only path to .wasm file is supported", executable)
+	}
+
+	module, err := f.compile(string(file))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Script{
+		runtime:   f.runtime,
+		module:    module,
+		variables: variables,
+		timeout:   f.timeout,
+	}, nil
+}
+
+func (f *Factory) compile(path string) (wazero.CompiledModule, error) {
+	if module, ok := f.modules.Load(path); ok {
+		return module.(wazero.CompiledModule), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read file %s", path)
+	}
+
+	module, err := f.runtime.CompileModule(context.Background(), data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "compile wasm module %s", path)
+	}
+
+	if existing, loaded := f.modules.LoadOrStore(path, module); loaded {
+		_ = module.Close(context.Background())
+		return existing.(wazero.CompiledModule), nil
+	}
+
+	return module, nil
+}
+
+func (f *Factory) Close() error {
+	return f.runtime.Close(context.Background())
+}
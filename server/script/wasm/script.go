@@ -0,0 +1,163 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	describe = "describe"
+	execute  = "execute"
+)
+
+//Request is written into module stdin
+type Request struct {
+	Command   string                 `json:"command"`
+	Function  string                 `json:"function,omitempty"`
+	Args      []interface{}          `json:"args,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type Log struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+//Response is read from module stdout
+type Response struct {
+	Ok     bool            `json:"ok"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Stack  string          `json:"stack,omitempty"`
+	Log    []Log           `json:"log,omitempty"`
+}
+
+type wasmError struct {
+	message string
+	stack   string
+}
+
+func (e wasmError) Error() string {
+	if e.stack != "" {
+		return e.stack
+	}
+
+	return e.message
+}
+
+// Script is a compiled WebAssembly module. Every call instantiates the module from scratch
+// so there is no state shared between calls.
+type Script struct {
+	runtime   wazero.Runtime
+	module    wazero.CompiledModule
+	variables map[string]interface{}
+	timeout   time.Duration
+}
+
+func (s *Script) Describe() (script.Symbols, error) {
+	value := make(script.Symbols)
+	if err := s.call(Request{Command: describe}, &value, nil); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (s *Script) Execute(name string, args []interface{}, result interface{}, listener script.Listener) error {
+	if args == nil {
+		args = make([]interface{}, 0)
+	}
+
+	return s.call(Request{Command: execute, Function: name, Args: args}, result, listener)
+}
+
+//Close does nothing: compiled modules are shared and closed with Factory
+func (s *Script) Close() {}
+
+func (s *Script) call(request Request, result interface{}, listener script.Listener) error {
+	request.Variables = s.variables
+	data, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "error building wasm request")
+	}
+
+	timeout := s.timeout
+	if listener != nil && listener.Timeout() > 0 {
+		timeout = listener.Timeout()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithStdin(bytes.NewReader(data)).
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+
+	start := timestamp.Now()
+	mod, err := s.runtime.InstantiateModule(ctx, s.module, config)
+	if mod != nil {
+		_ = mod.Close(ctx)
+	}
+
+	logging.Debugf("wasm: %s => %s (%v) [%s]", string(data), stdout.String(), err, timestamp.Now().Sub(start))
+	if err != nil {
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			switch exitErr.ExitCode() {
+			case sys.ExitCodeDeadlineExceeded:
+				return fmt.Errorf("wasm module execution exceeded timeout %s", timeout)
+			case sys.ExitCodeContextCanceled:
+				return errors.New("wasm module execution has been canceled")
+			}
+		}
+
+		if stderr.Len() > 0 {
+			return errors.Wrapf(err, "wasm module failed: %s", strings.TrimSpace(stderr.String()))
+		}
+
+		return errors.Wrap(err, "wasm module failed")
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return errors.Wrapf(err, "parse wasm module output [%s]", stdout.String())
+	}
+
+	if listener != nil {
+		for _, log := range resp.Log {
+			listener.Log(log.Level, log.Message)
+		}
+	}
+
+	if !resp.Ok {
+		return wasmError{
+			message: resp.Error,
+			stack:   resp.Stack,
+		}
+	}
+
+	if result != nil && resp.Result != nil {
+		decoder := json.NewDecoder(bytes.NewReader(resp.Result))
+		//parse json exactly the same way as it happens in http request processing.
+		decoder.UseNumber()
+		if err := decoder.Decode(result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
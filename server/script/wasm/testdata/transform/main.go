@@ -0,0 +1,40 @@
+//Test WebAssembly module: build with GOOS=wasip1 GOARCH=wasm
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+type request struct {
+	Command   string                 `json:"command"`
+	Function  string                 `json:"function"`
+	Args      []interface{}          `json:"args"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+func main() {
+	var req request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		reply(map[string]interface{}{"ok": false, "error": err.Error()})
+		return
+	}
+
+	switch {
+	case req.Command == "describe":
+		reply(map[string]interface{}{"ok": true, "result": map[string]interface{}{}})
+	case req.Function == "loop":
+		for {
+		}
+	case req.Function == "fail":
+		reply(map[string]interface{}{"ok": false, "error": "failed"})
+	default:
+		event := req.Args[0].(map[string]interface{})
+		event["prefix"] = req.Variables["prefix"]
+		reply(map[string]interface{}{"ok": true, "result": event, "log": []map[string]string{{"level": "info", "message": "processed"}}})
+	}
+}
+
+func reply(response map[string]interface{}) {
+	_ = json.NewEncoder(os.Stdout).Encode(response)
+}
@@ -0,0 +1,71 @@
+package wasm_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/jitsucom/jitsu/server/script/wasm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//buildModule compiles testdata/transform into WebAssembly (wasip1) module
+func buildModule(t *testing.T) string {
+	output := filepath.Join(t.TempDir(), "transform.wasm")
+	cmd := exec.Command("go", "build", "-o", output, "./testdata/transform")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("unable to build wasip1 module: %v: %s", err, string(out))
+	}
+
+	return output
+}
+
+func TestWasmExecute(t *testing.T) {
+	factory, err := wasm.NewFactory(64, 10*time.Second, "")
+	require.NoError(t, err)
+	defer factory.Close()
+
+	inst, err := factory.CreateScript(script.File(buildModule(t)), map[string]interface{}{"prefix": "p_"}, false)
+	require.NoError(t, err)
+	defer inst.Close()
+
+	symbols, err := inst.Describe()
+	require.NoError(t, err)
+	assert.Empty(t, symbols)
+
+	var result map[string]interface{}
+	err = inst.Execute("", []interface{}{map[string]interface{}{"event_type": "pageview"}}, &result, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"event_type": "pageview", "prefix": "p_"}, result)
+
+	err = inst.Execute("fail", nil, nil, nil)
+	assert.EqualError(t, err, "failed")
+}
+
+func TestWasmTimeout(t *testing.T) {
+	factory, err := wasm.NewFactory(64, 500*time.Millisecond, "")
+	require.NoError(t, err)
+	defer factory.Close()
+
+	inst, err := factory.CreateScript(script.File(buildModule(t)), nil, false)
+	require.NoError(t, err)
+
+	err = inst.Execute("loop", nil, nil, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "exceeded timeout")
+	}
+}
+
+func TestWasmUnsupportedExecutable(t *testing.T) {
+	factory, err := wasm.NewFactory(0, 0, "")
+	require.NoError(t, err)
+	defer factory.Close()
+
+	_, err = factory.CreateScript(script.Expression("return $"), nil, false)
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -182,6 +183,8 @@ func (s *Service) init(sc map[string]driversbase.SourceConfig) {
 			PostHandleDestinationIDs: sourceConfig.PostHandleDestinations,
 			Notifications:            sourceConfig.Notifications,
 			ProjectName:              sourceConfig.ProjectName,
+			SyncConcurrency:          sourceConfig.SyncConcurrency,
+			SyncRateLimit:            sourceConfig.SyncRateLimit,
 			hash:                     hash,
 		}
 		s.Unlock()
@@ -218,6 +221,19 @@ func (s *Service) GetSource(sourceID string) (*Unit, error) {
 	return unit, nil
 }
 
+//GetSourceIDs returns sorted IDs of all configured sources
+func (s *Service) GetSourceIDs() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	ids := make([]string, 0, len(s.sources))
+	for id := range s.sources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 func (s *Service) GetCollections(sourceID string) ([]string, error) {
 	s.RLock()
 	defer s.RUnlock()
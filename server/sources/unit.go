@@ -13,6 +13,8 @@ type Unit struct {
 	PostHandleDestinationIDs []string
 	Notifications            map[string]interface{}
 	ProjectName              string
+	SyncConcurrency          int
+	SyncRateLimit            float64
 
 	hash uint64
 }
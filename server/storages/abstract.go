@@ -2,10 +2,13 @@ package storages
 
 import (
 	"fmt"
+	"sync"
+
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/errorj"
+	"github.com/jitsucom/jitsu/server/iphandling"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/typing"
@@ -17,10 +20,13 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/delivery"
+	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/telemetry"
+	"github.com/jitsucom/jitsu/server/templates"
 )
 
 // Abstract is an Abstract destination storage
@@ -29,6 +35,7 @@ import (
 type Abstract struct {
 	implementation Storage
 	destinationID  string
+	generation     int64
 	fallbackLogger logging.ObjectLogger
 	eventsCache    *caching.EventsCache
 	processor      *schema.Processor
@@ -40,11 +47,18 @@ type Abstract struct {
 	uniqueIDField        *identifiers.UniqueID
 	staged               bool
 	cachingConfiguration *config.CachingConfiguration
+	erasure              *config.Erasure
+	retention            *config.Retention
 
-	streamingWorkers []*StreamingWorker
+	streamingWorkers         []*StreamingWorker
+	catchUp                  *CatchUpController
+	sqlTransformationsWorker *SQLTransformationsWorker
 
 	archiveLogger logging.ObjectLogger
 	roundRobin    atomic.Uint64
+
+	//tableLocks serializes batch loads into the same table when several files are uploaded concurrently
+	tableLocks sync.Map
 }
 
 // ID returns destination ID
@@ -52,6 +66,11 @@ func (a *Abstract) ID() string {
 	return a.destinationID
 }
 
+// Generation returns configuration generation the storage was created with
+func (a *Abstract) Generation() int64 {
+	return a.generation
+}
+
 // Processor returns processor
 func (a *Abstract) Processor() *schema.Processor {
 	return a.processor
@@ -80,10 +99,12 @@ func (a *Abstract) DryRun(payload events.Event) ([][]adapters.TableField, error)
 func (a *Abstract) ErrorEvent(fallback bool, eventCtx *adapters.EventContext, err error) {
 	metrics.ErrorTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID)
 	counters.ErrorPushDestinationEvents(a.destinationID, 1)
+	counters.PushRollup(a.destinationID, eventCtx.TokenID, eventCtx.GetTableName(), meta.ErrorStatus, 1)
 	telemetry.Error(eventCtx.TokenID, a.destinationID, eventCtx.Src, "", 1)
 
 	//cache
 	a.eventsCache.Error(eventCtx.CacheDisabled, a.ID(), eventCtx.GetSerializedOriginalEvent(), err.Error())
+	delivery.Failed(eventCtx.EventID, a.destinationID, a.generation, err.Error())
 
 	if fallback {
 		a.Fallback(&events.FailedEvent{
@@ -97,20 +118,24 @@ func (a *Abstract) ErrorEvent(fallback bool, eventCtx *adapters.EventContext, er
 // SuccessEvent writes success to metrics/counters/telemetry/events cache
 func (a *Abstract) SuccessEvent(eventCtx *adapters.EventContext) {
 	counters.SuccessPushDestinationEvents(a.destinationID, 1)
+	counters.PushRollup(a.destinationID, eventCtx.TokenID, eventCtx.GetTableName(), meta.SuccessStatus, 1)
 	telemetry.Event(eventCtx.TokenID, a.destinationID, eventCtx.Src, "", 1)
 	metrics.SuccessTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID)
 
 	//cache
 	a.eventsCache.Succeed(eventCtx)
+	delivery.Delivered(eventCtx.EventID, a.destinationID, a.generation)
 }
 
 // SkipEvent writes skip to metrics/counters/telemetry and error to events cache
 func (a *Abstract) SkipEvent(eventCtx *adapters.EventContext, err error) {
 	counters.SkipPushDestinationEvents(a.destinationID, 1)
+	counters.PushRollup(a.destinationID, eventCtx.TokenID, eventCtx.GetTableName(), meta.SkipStatus, 1)
 	metrics.SkipTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID)
 
 	//cache
 	a.eventsCache.Skip(eventCtx.CacheDisabled, a.destinationID, eventCtx.GetSerializedOriginalEvent(), err.Error())
+	delivery.Skipped(eventCtx.EventID, a.destinationID, a.generation, err.Error())
 }
 
 // Fallback logs event with error to fallback logger
@@ -155,6 +180,37 @@ func (a *Abstract) Insert(eventContext *adapters.EventContext) (insertErr error)
 	return nil
 }
 
+// InsertBatch writes stream mode micro-batch of events into one table with a multi-row insert in a single transaction.
+// table contains merged columns of all events. Successfully written events are accounted and archived;
+// on error none of events are accounted: the streaming worker retries the batch or inserts events one by one
+func (a *Abstract) InsertBatch(table *adapters.Table, eventContexts []*adapters.EventContext) error {
+	sqlAdapter, tableHelper := a.getAdapters()
+
+	dbTable, err := tableHelper.EnsureTableWithCaching(a.ID(), table)
+	if err != nil {
+		return errorj.Decorate(err, "failed to ensure table")
+	}
+
+	objects := make([]map[string]interface{}, len(eventContexts))
+	for i, eventContext := range eventContexts {
+		objects[i] = eventContext.ProcessedEvent
+	}
+
+	start := timestamp.Now()
+	if err := sqlAdapter.Insert(adapters.NewBatchInsertContext(dbTable, objects, true, nil)); err != nil {
+		return err
+	}
+	logging.Debugf("[%s] Inserted [%d] stream events in [%.2f] seconds", a.ID(), len(objects), timestamp.Now().Sub(start).Seconds())
+
+	for _, eventContext := range eventContexts {
+		eventContext.Table = dbTable
+		a.SuccessEvent(eventContext)
+		a.archiveLogger.Consume(eventContext.RawEvent, eventContext.TokenID)
+	}
+
+	return nil
+}
+
 // Store process events and stores with StoreTable() func
 // returns store result per table, failed events (group of events which are failed to process) and err
 func (a *Abstract) Store(fileName string, objects []map[string]interface{}, alreadyUploadedTables map[string]bool, needCopyEvent bool) (map[string]*StoreResult, *events.FailedEvents, *events.SkippedEvents, error) {
@@ -167,19 +223,21 @@ func (a *Abstract) Store(fileName string, objects []map[string]interface{}, alre
 	for _, failedEvent := range failedEvents.Events {
 		if !failedEvent.RecognizedEvent {
 			a.eventsCache.Error(a.IsCachingDisabled(), a.ID(), string(failedEvent.Event), failedEvent.Error)
+			delivery.Failed(failedEvent.EventID, a.ID(), a.generation, failedEvent.Error)
 		}
 	}
 	//update cache and counter with skipped events
 	for _, skipEvent := range skippedEvents.Events {
 		if !skipEvent.RecognizedEvent {
 			a.eventsCache.Skip(a.IsCachingDisabled(), a.ID(), string(skipEvent.Event), skipEvent.Error)
+			delivery.Skipped(skipEvent.EventID, a.ID(), a.generation, skipEvent.Error)
 		}
 	}
 
 	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
-		table, err := a.implementation.storeTable(fdata)
+		table, err := a.storeTableExclusively(fdata)
 		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen(), EventsSrc: fdata.GetEventsPerSrc()}
 		if err != nil {
 			storeFailedEvents = false
@@ -190,7 +248,7 @@ func (a *Abstract) Store(fileName string, objects []map[string]interface{}, alre
 		}
 	}
 	for _, fdata := range recognizedFlatData {
-		table, err := a.implementation.storeTable(fdata)
+		table, err := a.storeTableExclusively(fdata)
 		if err != nil {
 			logging.Errorf("Failed to store user recognition batch payload for %s table: %s err: %v", a.destinationID, table.Name, err)
 		}
@@ -204,6 +262,18 @@ func (a *Abstract) Store(fileName string, objects []map[string]interface{}, alre
 	return tableResults, nil, skippedEvents, nil
 }
 
+// storeTableExclusively calls implementation storeTable() under the table lock
+// it guarantees that batches of the same table are never loaded concurrently (e.g. by files of different tokens).
+// The order of concurrent loads isn't defined
+func (a *Abstract) storeTableExclusively(fdata *schema.ProcessedFile) (*adapters.Table, error) {
+	mu, _ := a.tableLocks.LoadOrStore(fdata.BatchHeader.TableName, &sync.Mutex{})
+	tableLock := mu.(*sync.Mutex)
+	tableLock.Lock()
+	defer tableLock.Unlock()
+
+	return a.implementation.storeTable(fdata)
+}
+
 // check table schema
 // and store data into one table
 func (a *Abstract) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, error) {
@@ -295,6 +365,9 @@ func (a *Abstract) Clean(tableName string) error {
 }
 
 func (a *Abstract) close() (multiErr error) {
+	if a.catchUp != nil {
+		_ = a.catchUp.Close()
+	}
 	if len(a.streamingWorkers) > 0 {
 		for _, worker := range a.streamingWorkers {
 			if err := worker.Close(); err != nil {
@@ -302,6 +375,11 @@ func (a *Abstract) close() (multiErr error) {
 			}
 		}
 	}
+	if a.sqlTransformationsWorker != nil {
+		if err := a.sqlTransformationsWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing SQL transformations worker: %v", a.ID(), err))
+		}
+	}
 	if a.fallbackLogger != nil {
 		if err := a.fallbackLogger.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", a.ID(), err))
@@ -323,10 +401,13 @@ func (a *Abstract) Init(config *Config, impl Storage, preinstalledJavaScript str
 	a.implementation = impl
 	//Abstract (SQLAdapters and tableHelpers are omitted)
 	a.destinationID = config.destinationID
+	a.generation = config.generation
 	a.eventsCache = config.eventsCache
 	a.uniqueIDField = config.uniqueIDField
 	a.staged = config.destination.Staged
 	a.cachingConfiguration = config.destination.CachingConfiguration
+	a.erasure = config.destination.Erasure
+	a.retention = config.destination.Retention
 	var err error
 	a.processor, a.sqlTypes, err = a.setupProcessor(config)
 	if err != nil {
@@ -349,6 +430,25 @@ func (a *Abstract) Start(config *Config) error {
 		for _, worker := range a.streamingWorkers {
 			worker.start()
 		}
+		if !a.staged {
+			a.catchUp = newCatchUpController(appconfig.Instance.CatchUp, config.eventQueue, a.streamingWorkers)
+		}
+	}
+
+	if config.destination.SQLTransformations != nil && len(a.sqlAdapters) > 0 {
+		materializer, ok := a.sqlAdapters[0].(adapters.Materializer)
+		if !ok {
+			logging.Warnf("[%s] SQL transformations aren't supported by destination type %s", a.ID(), config.destination.Type)
+			return nil
+		}
+
+		worker, err := NewSQLTransformationsWorker(a.ID(), config.destination.SQLTransformations, materializer, config.coordinationService)
+		if err != nil {
+			return err
+		}
+
+		a.sqlTransformationsWorker = worker
+		worker.start()
 	}
 	return nil
 }
@@ -382,18 +482,37 @@ func (a *Abstract) setupProcessor(cfg *Config) (processor *schema.Processor, sql
 		logging.Infof("[%s] uses default table: %s", destinationID, tableName)
 	}
 
+	// ** Enrichment pipeline **
+	pipeline := destination.EnrichmentPipeline
+	if len(pipeline) == 0 {
+		pipeline = enrichment.DefaultPipeline
+	} else {
+		logging.Infof("[%s] configured enrichment pipeline:", destinationID)
+		for _, enricherConfig := range pipeline {
+			logging.Infof("[%s] %s", destinationID, enricherConfig.String())
+		}
+	}
+	limits, err := templates.NewScriptLimits(destination.ScriptLimits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid script_limits: %v", err)
+	}
+	enrichmentRules, err := enrichment.NewPipeline(pipeline, cfg.geoService, destination.GeoDataResolverID, newScriptEnricherFactory(limits))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		//release JavaScript enrichers if the processor wasn't created
+		if err != nil {
+			enrichment.NewLookupEnrichmentStep(enrichmentRules).Close()
+		}
+	}()
+
 	if len(destination.Enrichment) == 0 {
 		logging.Warnf("[%s] doesn't have enrichment rules", destinationID)
 	} else {
 		logging.Infof("[%s] configured enrichment rules:", destinationID)
 	}
 
-	//default enrichment rules
-	enrichmentRules := []enrichment.Rule{
-		enrichment.CreateDefaultJsIPRule(cfg.geoService, destination.GeoDataResolverID),
-		enrichment.DefaultUaRule,
-	}
-
 	// ** Enrichment rules **
 	for _, ruleConfig := range destination.Enrichment {
 		logging.Infof("[%s] %s", destinationID, ruleConfig.String())
@@ -406,6 +525,15 @@ func (a *Abstract) setupProcessor(cfg *Config) (processor *schema.Processor, sql
 		enrichmentRules = append(enrichmentRules, rule)
 	}
 
+	// ** IP handling **
+	//is applied after all enrichers: geo data is resolved from the original IP address
+	//API key IP handling is applied at ingestion, the rule applies destination one only if it is stricter
+	ipHandlingRule, err := enrichment.NewIPHandlingRule(destination.IPHandling, apiKeyIPHandling, cfg.geoService, destination.GeoDataResolverID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ip_handling: %v", err)
+	}
+	enrichmentRules = append(enrichmentRules, ipHandlingRule)
+
 	// ** Mapping rules **
 	if len(oldStyleMappings) > 0 {
 		logging.Warnf("\n\t ** [%s] DEPRECATED mapping configuration. Read more about new configuration schema: https://jitsu.com/docs/configuration/schema-and-mappings **\n", destinationID)
@@ -447,6 +575,15 @@ func (a *Abstract) setupProcessor(cfg *Config) (processor *schema.Processor, sql
 	return processor, sqlTypes, nil
 }
 
+//apiKeyIPHandling returns IP handling configuration of the API key (events contain API key secret or ID)
+func apiKeyIPHandling(apiKey string) *iphandling.Config {
+	if appconfig.Instance.AuthorizationService == nil {
+		return nil
+	}
+
+	return appconfig.Instance.AuthorizationService.GetIPHandling(apiKey)
+}
+
 // assume that adapters quantity == tableHelpers quantity
 func (a *Abstract) getAdapters() (adapters.SQLAdapter, *TableHelper) {
 	if len(a.sqlAdapters) > 1 {
@@ -0,0 +1,70 @@
+package storages
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+)
+
+// tableStoreMock tracks concurrent storeTable calls
+type tableStoreMock struct {
+	Storage
+
+	mutex             sync.Mutex
+	runningByTable    map[string]int
+	maxRunningByTable map[string]int
+	running           int
+	maxRunning        int
+}
+
+func (tsm *tableStoreMock) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, error) {
+	tableName := fdata.BatchHeader.TableName
+	tsm.mutex.Lock()
+	tsm.runningByTable[tableName]++
+	if tsm.runningByTable[tableName] > tsm.maxRunningByTable[tableName] {
+		tsm.maxRunningByTable[tableName] = tsm.runningByTable[tableName]
+	}
+	tsm.running++
+	if tsm.running > tsm.maxRunning {
+		tsm.maxRunning = tsm.running
+	}
+	tsm.mutex.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	tsm.mutex.Lock()
+	tsm.runningByTable[tableName]--
+	tsm.running--
+	tsm.mutex.Unlock()
+	return &adapters.Table{Name: tableName}, nil
+}
+
+func TestStoreTableExclusively(t *testing.T) {
+	implementation := &tableStoreMock{runningByTable: map[string]int{}, maxRunningByTable: map[string]int{}}
+	abstract := &Abstract{implementation: implementation}
+
+	tables := []string{"events", "events", "events", "pages", "clicks"}
+	start := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i, tableName := range tables {
+		wg.Add(1)
+		go func(i int, tableName string) {
+			defer wg.Done()
+			<-start
+			table, err := abstract.storeTableExclusively(&schema.ProcessedFile{BatchHeader: &schema.BatchHeader{TableName: tableName}})
+			if err != nil || table.Name != tableName {
+				t.Errorf("unexpected result of storing %d batch: %v %v", i, table, err)
+			}
+		}(i, tableName)
+	}
+	close(start)
+	wg.Wait()
+
+	require.Equal(t, map[string]int{"events": 1, "pages": 1, "clicks": 1}, implementation.maxRunningByTable,
+		"batches of the same table must be stored one by one")
+	require.Greater(t, implementation.maxRunning, 1, "batches of different tables must be stored in parallel")
+}
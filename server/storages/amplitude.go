@@ -59,7 +59,7 @@ func NewAmplitude(config *Config) (storage Storage, err error) {
 	a.adapter = aAdapter
 
 	//streaming worker (queue reading)
-	a.streamingWorkers = newStreamingWorkers(config.eventQueue, a, config.streamingThreadsCount)
+	a.streamingWorkers = newStreamingWorkers(config.eventQueue, a, config.streamingThreadsCount, nil)
 	return
 }
 
@@ -21,8 +21,9 @@ var disabledRecognitionConfiguration = &UserRecognitionConfiguration{Enabled: fa
 type BigQuery struct {
 	Abstract
 
-	gcsAdapter *adapters.GoogleCloudStorage
-	bqAdapter  *adapters.BigQuery
+	gcsAdapter    *adapters.GoogleCloudStorage
+	bqAdapter     *adapters.BigQuery
+	stagingFormat adapters.FileEncodingFormat
 }
 
 func init() {
@@ -56,16 +57,23 @@ func NewBigQuery(config *Config) (storage Storage, err error) {
 		logging.Warnf("[%s] dataset wasn't provided. Will be used default one: %s", config.destinationID, gConfig.Dataset)
 	}
 
+	format, err := stagingFormat(config, BigQueryType)
+	if err != nil {
+		return
+	}
 	var gcsAdapter *adapters.GoogleCloudStorage
 	if !config.streamMode {
-		gConfig.RequireDefaultStage(BigQueryType)
+		//BigQuery load jobs are able to read gzip compressed JSON and CSV files
+		gConfig.RequireDefaultStage(BigQueryType, adapters.FileCompressionGZIP)
+		gConfig.RequireStagingFormat(BigQueryType, format)
 		gcsAdapter, err = adapters.NewGoogleCloudStorage(config.ctx, gConfig)
 		if err != nil {
 			return
 		}
 	}
 	bq := &BigQuery{
-		gcsAdapter: gcsAdapter,
+		gcsAdapter:    gcsAdapter,
+		stagingFormat: format,
 	}
 	err = bq.Init(config, bq, "", "")
 	if err != nil {
@@ -86,14 +94,14 @@ func NewBigQuery(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper("", bigQueryAdapter, config.coordinationService, config.pkFields, adapters.SchemaToBigQueryString, config.maxColumns, BigQueryType)
+	tableHelper := newTableHelper(config, "", bigQueryAdapter, adapters.SchemaToBigQueryString, BigQueryType)
 
 	//Abstract
 	bq.tableHelpers = []*TableHelper{tableHelper}
 	bq.sqlAdapters = []adapters.SQLAdapter{bigQueryAdapter}
 
 	//streaming worker (queue reading)
-	bq.streamingWorkers = newStreamingWorkers(config.eventQueue, bq, config.streamingThreadsCount, tableHelper)
+	bq.streamingWorkers = newStreamingWorkers(config.eventQueue, bq, config.streamingThreadsCount, config.destination.StreamBatch, tableHelper)
 	return
 }
 
@@ -114,7 +122,7 @@ func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, er
 		if fileName == "" {
 			fileName = dbTable.Name + "_" + uuid.NewLettersNumbers()
 		}
-		b, err := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
+		b, err := bq.marshalStagingFile(fdata, dbTable.Name)
 		if err != nil {
 			return dbTable, err
 		}
@@ -122,7 +130,7 @@ func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, er
 			return dbTable, err
 		}
 
-		if err := bq.bqAdapter.Copy(fileName, dbTable.Name); err != nil {
+		if err := bq.bqAdapter.Copy(fileName, dbTable.Name, bq.stagingFormat); err != nil {
 			return dbTable, fmt.Errorf("Error copying file [%s] from gcp to bigquery: %v", fileName, err)
 		}
 
@@ -142,6 +150,22 @@ func (bq *BigQuery) Update(eventContext *adapters.EventContext) error {
 	return errors.New("BigQuery doesn't support updates")
 }
 
+// marshalStagingFile returns the staging file payload. BigQuery loads csv files by columns positions
+// so csv columns are written in the table columns order
+func (bq *BigQuery) marshalStagingFile(fdata *schema.ProcessedFile, tableName string) ([]byte, error) {
+	if bq.stagingFormat != adapters.FileFormatCSV {
+		b, _, err := marshalStagingFile(fdata, bq.stagingFormat)
+		return b, err
+	}
+
+	columnNames, err := bq.bqAdapter.GetTableColumnNames(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return fdata.GetPayloadBytesWithFields(schema.CSVMarshallerInstance, columnNames)
+}
+
 // SyncStore is used in storing chunk of pulled data to BigQuery with processing
 func (bq *BigQuery) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, deleteConditions *base.DeleteConditions, cacheTable bool, needCopyEvent bool) error {
 	if len(objects) == 0 {
@@ -0,0 +1,207 @@
+package storages
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const catchUpCheckInterval = 5 * time.Second
+
+var catchUpControllers = &catchUpRegistry{controllers: map[string]*CatchUpController{}}
+
+// CatchUpStatus is a catch-up mode state of a streaming destination
+type CatchUpStatus struct {
+	DestinationID string     `json:"destination_id"`
+	Active        bool       `json:"active"`
+	Workers       int        `json:"workers"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+}
+
+// CatchUpController starts extra streaming workers and enlarges stream mode micro-batches while the destination
+// queue lag (size or the oldest event age) exceeds the thresholds (see appconfig.CatchUpConfiguration).
+// Catch-up mode is left when the lag is below half of the thresholds
+type CatchUpController struct {
+	destinationID string
+	config        appconfig.CatchUpConfiguration
+	queue         events.LagQueue
+	workers       []*StreamingWorker
+
+	mutex     sync.RWMutex
+	extra     []*StreamingWorker
+	startedAt *time.Time
+	closed    chan struct{}
+}
+
+// newCatchUpController returns started controller or nil if catch-up mode is disabled or the queue doesn't report lag
+func newCatchUpController(config appconfig.CatchUpConfiguration, eventQueue events.Queue, workers []*StreamingWorker) *CatchUpController {
+	if !config.Enabled || config.ThreadsCount == 0 || len(workers) == 0 || (config.LagThreshold <= 0 && config.AgeThreshold <= 0) {
+		return nil
+	}
+
+	lagQueue, ok := eventQueue.(events.LagQueue)
+	if !ok {
+		return nil
+	}
+
+	cuc := &CatchUpController{
+		destinationID: workers[0].streamingStorage.ID(),
+		config:        config,
+		queue:         lagQueue,
+		workers:       workers,
+		closed:        make(chan struct{}),
+	}
+	catchUpControllers.register(cuc)
+	safego.RunWithRestart(cuc.start)
+	return cuc
+}
+
+func (cuc *CatchUpController) start() {
+	ticker := time.NewTicker(catchUpCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cuc.closed:
+			return
+		case <-ticker.C:
+			cuc.check(cuc.queue.Lag())
+		}
+	}
+}
+
+// check enters or leaves catch-up mode according to the queue lag
+func (cuc *CatchUpController) check(lag events.QueueLag) {
+	if lag.Size < 0 {
+		return
+	}
+
+	cuc.mutex.Lock()
+	defer cuc.mutex.Unlock()
+
+	active := cuc.startedAt != nil
+	if !active && cuc.exceeds(lag, 1) {
+		cuc.enter(lag)
+	} else if active && !cuc.exceeds(lag, 2) {
+		cuc.leave(lag)
+	}
+}
+
+// exceeds returns true if the lag exceeds at least one of the thresholds divided by divider
+func (cuc *CatchUpController) exceeds(lag events.QueueLag, divider int64) bool {
+	if cuc.config.LagThreshold > 0 && lag.Size >= cuc.config.LagThreshold/divider {
+		return true
+	}
+
+	return cuc.config.AgeThreshold > 0 && lag.OldestEventAge >= cuc.config.AgeThreshold/time.Duration(divider)
+}
+
+// enter starts extra workers and enlarges micro-batches. Must be called under the lock
+func (cuc *CatchUpController) enter(lag events.QueueLag) {
+	now := timestamp.Now().UTC()
+	cuc.startedAt = &now
+	cuc.workers[0].catchingUp.Store(true)
+	for i := 0; i < cuc.config.ThreadsCount; i++ {
+		worker := cuc.workers[0].clone()
+		worker.run()
+		cuc.extra = append(cuc.extra, worker)
+	}
+
+	storage := cuc.workers[0].streamingStorage
+	metrics.SetStreamingCatchUp(storage.Type(), cuc.destinationID, true)
+	cuc.workers[0].logger.Infof("[%s] catch-up mode has been started: %d events in the queue, the oldest event age: %s. Extra workers: %d",
+		cuc.destinationID, lag.Size, lag.OldestEventAge.Round(time.Second), cuc.config.ThreadsCount)
+}
+
+// leave stops extra workers and restores micro-batches size. Must be called under the lock
+func (cuc *CatchUpController) leave(lag events.QueueLag) {
+	for _, worker := range cuc.extra {
+		worker.stop()
+	}
+	duration := timestamp.Now().Sub(*cuc.startedAt)
+	cuc.extra = nil
+	cuc.startedAt = nil
+	cuc.workers[0].catchingUp.Store(false)
+
+	storage := cuc.workers[0].streamingStorage
+	metrics.SetStreamingCatchUp(storage.Type(), cuc.destinationID, false)
+	cuc.workers[0].logger.Infof("[%s] catch-up mode has been finished after %s: %d events in the queue", cuc.destinationID,
+		duration.Round(time.Second), lag.Size)
+}
+
+// Status returns the current catch-up state
+func (cuc *CatchUpController) Status() CatchUpStatus {
+	cuc.mutex.RLock()
+	defer cuc.mutex.RUnlock()
+
+	return CatchUpStatus{
+		DestinationID: cuc.destinationID,
+		Active:        cuc.startedAt != nil,
+		Workers:       len(cuc.workers) + len(cuc.extra),
+		StartedAt:     cuc.startedAt,
+	}
+}
+
+// Close stops checking the lag and extra workers. Base workers are closed by the storage
+func (cuc *CatchUpController) Close() error {
+	select {
+	case <-cuc.closed:
+		return nil
+	default:
+		close(cuc.closed)
+	}
+
+	cuc.mutex.Lock()
+	for _, worker := range cuc.extra {
+		worker.stop()
+	}
+	cuc.extra = nil
+	if cuc.startedAt != nil {
+		cuc.startedAt = nil
+		metrics.SetStreamingCatchUp(cuc.workers[0].streamingStorage.Type(), cuc.destinationID, false)
+	}
+	cuc.mutex.Unlock()
+
+	catchUpControllers.unregister(cuc)
+	return nil
+}
+
+type catchUpRegistry struct {
+	sync.RWMutex
+	controllers map[string]*CatchUpController
+}
+
+func (cur *catchUpRegistry) register(cuc *CatchUpController) {
+	cur.Lock()
+	cur.controllers[cuc.destinationID] = cuc
+	cur.Unlock()
+}
+
+func (cur *catchUpRegistry) unregister(cuc *CatchUpController) {
+	cur.Lock()
+	//destination might be already re-created with a new controller
+	if cur.controllers[cuc.destinationID] == cuc {
+		delete(cur.controllers, cuc.destinationID)
+	}
+	cur.Unlock()
+}
+
+// CatchUpStatuses returns catch-up states of all streaming destinations with enabled catch-up mode by destination ID
+func CatchUpStatuses() map[string]CatchUpStatus {
+	catchUpControllers.RLock()
+	controllers := make([]*CatchUpController, 0, len(catchUpControllers.controllers))
+	for _, cuc := range catchUpControllers.controllers {
+		controllers = append(controllers, cuc)
+	}
+	catchUpControllers.RUnlock()
+
+	statuses := make(map[string]CatchUpStatus, len(controllers))
+	for _, cuc := range controllers {
+		statuses[cuc.destinationID] = cuc.Status()
+	}
+	return statuses
+}
@@ -0,0 +1,89 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+)
+
+// lagQueueMock blocks on dequeue until it is closed. Lag is checked by the test directly
+type lagQueueMock struct {
+	events.Queue
+
+	closed chan struct{}
+}
+
+func (lqm *lagQueueMock) DequeueBlock() (events.Event, time.Time, string, error) {
+	<-lqm.closed
+	return nil, time.Time{}, "", events.ErrQueueClosed
+}
+
+func (lqm *lagQueueMock) Lag() events.QueueLag {
+	return events.QueueLag{Size: -1}
+}
+
+func TestCatchUpController(t *testing.T) {
+	storage := &batchStorageMock{}
+	queue := &lagQueueMock{closed: make(chan struct{})}
+	defer close(queue.closed)
+
+	worker := newStreamingWorker(queue, storage, NewCircuitBreaker(storage.ID(), 0, time.Minute),
+		&config.StreamBatch{Size: 10}, &TableHelper{})
+	worker.catchUpBatchSize = 50
+
+	cuc := newCatchUpController(appconfig.CatchUpConfiguration{Enabled: true, LagThreshold: 1000,
+		AgeThreshold: time.Minute, ThreadsCount: 2}, queue, []*StreamingWorker{worker})
+	require.NotNil(t, cuc)
+	defer cuc.Close()
+
+	require.Equal(t, CatchUpStatus{DestinationID: storage.ID(), Workers: 1}, CatchUpStatuses()[storage.ID()])
+
+	//lag is below thresholds
+	cuc.check(events.QueueLag{Size: 999, OldestEventAge: 59 * time.Second})
+	require.False(t, cuc.Status().Active)
+	require.Equal(t, 10, worker.currentBatchSize())
+
+	//the oldest event age exceeds the threshold
+	cuc.check(events.QueueLag{Size: 10, OldestEventAge: time.Minute})
+	status := cuc.Status()
+	require.True(t, status.Active)
+	require.Equal(t, 3, status.Workers)
+	require.NotNil(t, status.StartedAt)
+	require.Equal(t, 50, worker.currentBatchSize())
+	require.Equal(t, 50, cuc.extra[0].currentBatchSize())
+
+	//catch-up mode isn't left until the lag is below half of both thresholds
+	cuc.check(events.QueueLag{Size: 500, OldestEventAge: 0})
+	require.True(t, cuc.Status().Active)
+	cuc.check(events.QueueLag{Size: 10, OldestEventAge: 30 * time.Second})
+	require.True(t, cuc.Status().Active)
+
+	extra := cuc.extra
+	cuc.check(events.QueueLag{Size: 499, OldestEventAge: 29 * time.Second})
+	require.Equal(t, CatchUpStatus{DestinationID: storage.ID(), Workers: 1}, cuc.Status())
+	require.Equal(t, 10, worker.currentBatchSize())
+	for _, w := range extra {
+		require.True(t, w.closed.Load())
+	}
+
+	//unknown lag doesn't change the mode
+	cuc.check(events.QueueLag{Size: -1})
+	require.False(t, cuc.Status().Active)
+}
+
+func TestCatchUpControllerDisabled(t *testing.T) {
+	storage := &batchStorageMock{}
+	worker := newStreamingWorker(&lagQueueMock{}, storage, NewCircuitBreaker(storage.ID(), 0, time.Minute), nil)
+
+	require.Nil(t, newCatchUpController(appconfig.CatchUpConfiguration{LagThreshold: 1000, ThreadsCount: 2},
+		&lagQueueMock{}, []*StreamingWorker{worker}))
+	require.Nil(t, newCatchUpController(appconfig.CatchUpConfiguration{Enabled: true, ThreadsCount: 2},
+		&lagQueueMock{}, []*StreamingWorker{worker}))
+	//queue doesn't report lag
+	require.Nil(t, newCatchUpController(appconfig.CatchUpConfiguration{Enabled: true, LagThreshold: 1000, ThreadsCount: 2},
+		&retryQueueMock{}, []*StreamingWorker{worker}))
+}
@@ -0,0 +1,182 @@
+package storages
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+// CircuitBreakerState is a state of destination circuit breaker
+type CircuitBreakerState string
+
+const (
+	//CircuitBreakerClosed - events are sent to the destination
+	CircuitBreakerClosed CircuitBreakerState = "closed"
+	//CircuitBreakerOpen - events consumption is paused, events are kept in the queue
+	CircuitBreakerOpen CircuitBreakerState = "open"
+	//CircuitBreakerHalfOpen - one probe event is sent to the destination to check if it is healthy
+	CircuitBreakerHalfOpen CircuitBreakerState = "half_open"
+)
+
+var circuitBreakers = &circuitBreakersRegistry{breakers: map[string]*CircuitBreaker{}}
+
+// circuitBreakersRegistry keeps circuit breakers of all streaming destinations
+type circuitBreakersRegistry struct {
+	sync.RWMutex
+	breakers map[string]*CircuitBreaker
+}
+
+// CircuitBreakerStatus is a dto for circuit breaker status response
+type CircuitBreakerStatus struct {
+	DestinationID       string              `json:"destination_id"`
+	State               CircuitBreakerState `json:"state"`
+	ConsecutiveFailures int                 `json:"consecutive_failures"`
+	LastError           string              `json:"last_error,omitempty"`
+	OpenedAt            *time.Time          `json:"opened_at,omitempty"`
+	NextProbeAt         *time.Time          `json:"next_probe_at,omitempty"`
+}
+
+// CircuitBreaker pauses destination events consumption after N consecutive connection errors
+// and periodically lets one probe event through. Consumption is resumed after the first successful probe
+type CircuitBreaker struct {
+	sync.Mutex
+
+	destinationID    string
+	failureThreshold int
+	probeInterval    time.Duration
+
+	state               CircuitBreakerState
+	consecutiveFailures int
+	lastError           string
+	openedAt            time.Time
+	nextProbeAt         time.Time
+}
+
+// NewCircuitBreaker returns configured CircuitBreaker instance
+// failureThreshold <= 0 means that circuit breaker is disabled (always closed)
+func NewCircuitBreaker(destinationID string, failureThreshold int, probeInterval time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		destinationID:    destinationID,
+		failureThreshold: failureThreshold,
+		probeInterval:    probeInterval,
+		state:            CircuitBreakerClosed,
+	}
+}
+
+// Allow returns true if an event can be sent to the destination
+// in open state returns true only once per probe interval (half-open probe)
+func (cb *CircuitBreaker) Allow() bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	if cb.state == CircuitBreakerClosed {
+		return true
+	}
+
+	now := timestamp.Now()
+	if now.Before(cb.nextProbeAt) {
+		return false
+	}
+
+	//probe (or previous probe didn't finish with success or failure, e.g. event was skipped)
+	cb.state = CircuitBreakerHalfOpen
+	cb.nextProbeAt = now.Add(cb.probeInterval)
+	return true
+}
+
+// Success resets consecutive failures and closes circuit breaker
+func (cb *CircuitBreaker) Success() {
+	cb.Lock()
+	defer cb.Unlock()
+
+	if cb.state != CircuitBreakerClosed {
+		logging.Infof("[%s] destination is healthy. Circuit breaker is closed: events consumption is resumed", cb.destinationID)
+	}
+
+	cb.state = CircuitBreakerClosed
+	cb.consecutiveFailures = 0
+	cb.lastError = ""
+}
+
+// Failure increments consecutive failures and opens circuit breaker if threshold is reached
+// or if probe has failed
+func (cb *CircuitBreaker) Failure(err error) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.consecutiveFailures++
+	cb.lastError = err.Error()
+
+	if cb.failureThreshold <= 0 {
+		return
+	}
+
+	now := timestamp.Now()
+	switch cb.state {
+	case CircuitBreakerHalfOpen:
+		cb.state = CircuitBreakerOpen
+		cb.nextProbeAt = now.Add(cb.probeInterval)
+	case CircuitBreakerClosed:
+		if cb.consecutiveFailures >= cb.failureThreshold {
+			logging.Warnf("[%s] Circuit breaker is open after %d consecutive failures: events consumption is paused. Next probe in %s. Last error: %v",
+				cb.destinationID, cb.consecutiveFailures, cb.probeInterval.String(), err)
+			cb.state = CircuitBreakerOpen
+			cb.openedAt = now
+			cb.nextProbeAt = now.Add(cb.probeInterval)
+		}
+	}
+}
+
+// Status returns current circuit breaker status
+func (cb *CircuitBreaker) Status() CircuitBreakerStatus {
+	cb.Lock()
+	defer cb.Unlock()
+
+	status := CircuitBreakerStatus{
+		DestinationID:       cb.destinationID,
+		State:               cb.state,
+		ConsecutiveFailures: cb.consecutiveFailures,
+		LastError:           cb.lastError,
+	}
+	if cb.state != CircuitBreakerClosed {
+		openedAt, nextProbeAt := cb.openedAt, cb.nextProbeAt
+		status.OpenedAt = &openedAt
+		status.NextProbeAt = &nextProbeAt
+	}
+
+	return status
+}
+
+func (cbr *circuitBreakersRegistry) register(cb *CircuitBreaker) {
+	cbr.Lock()
+	cbr.breakers[cb.destinationID] = cb
+	cbr.Unlock()
+}
+
+func (cbr *circuitBreakersRegistry) unregister(cb *CircuitBreaker) {
+	cbr.Lock()
+	//destination might be already re-created with a new circuit breaker
+	if cbr.breakers[cb.destinationID] == cb {
+		delete(cbr.breakers, cb.destinationID)
+	}
+	cbr.Unlock()
+}
+
+// CircuitBreakerStatuses returns statuses of all streaming destinations circuit breakers sorted by destination ID
+func CircuitBreakerStatuses() []CircuitBreakerStatus {
+	circuitBreakers.RLock()
+	defer circuitBreakers.RUnlock()
+
+	statuses := make([]CircuitBreakerStatus, 0, len(circuitBreakers.breakers))
+	for _, cb := range circuitBreakers.breakers {
+		statuses = append(statuses, cb.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].DestinationID < statuses[j].DestinationID
+	})
+
+	return statuses
+}
@@ -0,0 +1,53 @@
+package storages
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2021, 9, 1, 10, 0, 0, 0, time.UTC)
+	timestamp.SetFreezeTime(now)
+	timestamp.FreezeTime()
+	defer timestamp.UnfreezeTime()
+
+	cb := NewCircuitBreaker("dest1", 2, time.Minute)
+	connectionErr := errors.New("connection refused")
+
+	require.True(t, cb.Allow())
+	cb.Failure(connectionErr)
+	require.True(t, cb.Allow())
+	cb.Failure(connectionErr)
+	require.Equal(t, CircuitBreakerOpen, cb.Status().State)
+	require.False(t, cb.Allow())
+
+	//probe after interval, failed probe opens breaker again
+	timestamp.SetFreezeTime(now.Add(time.Minute))
+	require.True(t, cb.Allow())
+	require.Equal(t, CircuitBreakerHalfOpen, cb.Status().State)
+	require.False(t, cb.Allow())
+	cb.Failure(connectionErr)
+	require.Equal(t, CircuitBreakerOpen, cb.Status().State)
+	require.Equal(t, 3, cb.Status().ConsecutiveFailures)
+
+	//successful probe closes breaker
+	timestamp.SetFreezeTime(now.Add(2 * time.Minute))
+	require.True(t, cb.Allow())
+	cb.Success()
+	status := cb.Status()
+	require.Equal(t, CircuitBreakerClosed, status.State)
+	require.Equal(t, 0, status.ConsecutiveFailures)
+	require.Nil(t, status.NextProbeAt)
+	require.True(t, cb.Allow())
+
+	//disabled breaker
+	disabled := NewCircuitBreaker("dest2", 0, time.Minute)
+	for i := 0; i < 100; i++ {
+		disabled.Failure(connectionErr)
+	}
+	require.True(t, disabled.Allow())
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/schema"
 )
@@ -36,6 +37,13 @@ func NewClickHouse(config *Config) (storage Storage, err error) {
 	if err = config.destination.GetDestConfig(config.destination.ClickHouse, chConfig); err != nil {
 		return
 	}
+	if err = chConfig.ProcessSSL(); err != nil {
+		return
+	}
+
+	if err = validateClickHousePartitioning(chConfig, config.destination.DataLayout); err != nil {
+		return
+	}
 
 	tableStatementFactory, err := adapters.NewTableStatementFactory(chConfig)
 	if err != nil {
@@ -72,14 +80,14 @@ func NewClickHouse(config *Config) (storage Storage, err error) {
 	}
 	for i := 0; i < adaptersCount; i++ {
 		var adapter *adapters.ClickHouse
-		adapter, err = adapters.NewClickHouse(config.ctx, chConfig.Dsns[i%len(chConfig.Dsns)], chConfig.Database, chConfig.Cluster, chConfig.TLS,
+		adapter, err = adapters.NewClickHouse(config.ctx, chConfig.Dsns[i%len(chConfig.Dsns)], chConfig.Database, chConfig.Cluster, chConfig.TLS, chConfig.Pool,
 			tableStatementFactory, nullableFields, queryLogger, ch.sqlTypes)
 		if err != nil {
 			return
 		}
 
 		ch.adapters = append(ch.adapters, adapter)
-		ch.chTableHelpers = append(ch.chTableHelpers, NewTableHelper("", adapter, config.coordinationService, config.pkFields, adapters.SchemaToClickhouse, config.maxColumns, ClickHouseType))
+		ch.chTableHelpers = append(ch.chTableHelpers, newTableHelper(config, "", adapter, adapters.SchemaToClickhouse, ClickHouseType))
 		sqlAdapters = append(sqlAdapters, adapter)
 	}
 
@@ -97,7 +105,7 @@ func NewClickHouse(config *Config) (storage Storage, err error) {
 	}
 
 	//streaming worker (queue reading)
-	ch.streamingWorkers = newStreamingWorkers(config.eventQueue, ch, config.streamingThreadsCount, ch.chTableHelpers...)
+	ch.streamingWorkers = newStreamingWorkers(config.eventQueue, ch, config.streamingThreadsCount, config.destination.StreamBatch, ch.chTableHelpers...)
 	return
 }
 
@@ -134,3 +142,17 @@ func (ch *ClickHouse) Close() (multiErr error) {
 
 	return
 }
+
+// validateClickHousePartitioning returns err if data_layout.table_partitioning.partition_by is configured along with
+// engine.raw_statement: raw statement is used as is so partition_by can't be applied
+func validateClickHousePartitioning(chConfig *adapters.ClickHouseConfig, dataLayout *config.DataLayout) error {
+	if dataLayout == nil || chConfig.Engine == nil || chConfig.Engine.RawStatement == "" {
+		return nil
+	}
+	for tableName, partitioning := range dataLayout.TablePartitioning {
+		if partitioning != nil && partitioning.PartitionBy != "" {
+			return fmt.Errorf("data_layout.table_partitioning [%s]: partition_by can't be used with engine.raw_statement. Please add PARTITION BY clause to raw_statement", tableName)
+		}
+	}
+	return nil
+}
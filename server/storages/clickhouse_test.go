@@ -0,0 +1,20 @@
+package storages
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateClickHousePartitioning(t *testing.T) {
+	dataLayout := &config.DataLayout{TablePartitioning: map[string]*config.TablePartitioning{"events": {PartitionBy: "toYYYYMM(_timestamp)"}}}
+
+	require.NoError(t, validateClickHousePartitioning(&adapters.ClickHouseConfig{}, dataLayout))
+	require.NoError(t, validateClickHousePartitioning(&adapters.ClickHouseConfig{Engine: &adapters.EngineConfig{PartitionFields: []adapters.FieldConfig{{Field: "_timestamp"}}}}, dataLayout))
+	require.NoError(t, validateClickHousePartitioning(&adapters.ClickHouseConfig{Engine: &adapters.EngineConfig{RawStatement: "ENGINE = MergeTree() ORDER BY (a)"}}, nil))
+
+	err := validateClickHousePartitioning(&adapters.ClickHouseConfig{Engine: &adapters.EngineConfig{RawStatement: "ENGINE = MergeTree() ORDER BY (a)"}}, dataLayout)
+	require.EqualError(t, err, "data_layout.table_partitioning [events]: partition_by can't be used with engine.raw_statement. Please add PARTITION BY clause to raw_statement")
+}
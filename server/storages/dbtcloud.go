@@ -66,7 +66,7 @@ func NewDbtCloud(config *Config) (storage Storage, err error) {
 	dbt.adapter = dbtAdapter
 
 	//streaming worker (queue reading)
-	dbt.streamingWorkers = newStreamingWorkers(config.eventQueue, dbt, config.streamingThreadsCount)
+	dbt.streamingWorkers = newStreamingWorkers(config.eventQueue, dbt, config.streamingThreadsCount, nil)
 	return
 }
 
@@ -0,0 +1,100 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+)
+
+var (
+	// ErrErasureNotSupported is returned if the destination doesn't support personal data erasure
+	ErrErasureNotSupported = errors.New("personal data erasure isn't supported by the destination")
+	// ErrErasureDisabled is returned if personal data erasure is disabled in the destination configuration
+	ErrErasureDisabled = errors.New("personal data erasure is disabled in the destination configuration")
+
+	staticTableNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+)
+
+// Erase deletes or anonymizes rows with the identifier in the destination tables (right to be forgotten).
+// Tables, columns and mode which aren't set in the destination erasure configuration are taken from defaults.
+// If tables aren't configured, the static destination table is used.
+// Returns amount of affected rows per table (-1 if it is unknown)
+func (a *Abstract) Erase(identifier string, defaults *config.Erasure) (map[string]int64, error) {
+	if len(a.sqlAdapters) == 0 {
+		return nil, ErrErasureNotSupported
+	}
+	eraser, ok := a.sqlAdapters[0].(adapters.Eraser)
+	if !ok {
+		return nil, ErrErasureNotSupported
+	}
+
+	erasure := mergeErasure(a.erasure, defaults)
+	if erasure.Disabled {
+		return nil, ErrErasureDisabled
+	}
+	if len(erasure.Columns) == 0 {
+		return nil, errors.New("erasure.columns must be configured")
+	}
+
+	tables := erasure.Tables
+	if len(tables) == 0 {
+		tableName := a.processor.TableNameExpression()
+		if !staticTableNameRegexp.MatchString(tableName) {
+			return nil, fmt.Errorf("erasure.tables must be configured because the destination table name is dynamic: %s", tableName)
+		}
+		tables = []string{tableName}
+	}
+
+	result := map[string]int64{}
+	var multiErr error
+	for _, table := range tables {
+		affected, err := eraser.Erase(table, erasure.Columns, identifier, erasure.Mode)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("table %s: %v", table, err))
+			continue
+		}
+		result[table] = affected
+	}
+
+	return result, multiErr
+}
+
+// ErasureColumns returns the destination identifier columns (with empty configuration taken from defaults)
+// or nil if erasure is disabled in the destination
+func (a *Abstract) ErasureColumns(defaults *config.Erasure) []string {
+	erasure := mergeErasure(a.erasure, defaults)
+	if erasure.Disabled {
+		return nil
+	}
+
+	return erasure.Columns
+}
+
+// mergeErasure returns the destination erasure configuration with empty fields filled from defaults
+func mergeErasure(destination, defaults *config.Erasure) *config.Erasure {
+	result := &config.Erasure{}
+	if defaults != nil {
+		*result = *defaults
+	}
+	if destination != nil {
+		result.Disabled = destination.Disabled
+		if len(destination.Tables) > 0 {
+			result.Tables = destination.Tables
+		}
+		if len(destination.Columns) > 0 {
+			result.Columns = destination.Columns
+		}
+		if destination.Mode != "" {
+			result.Mode = destination.Mode
+		}
+	}
+	if result.Mode == "" {
+		result.Mode = adapters.EraseDelete
+	}
+
+	return result
+}
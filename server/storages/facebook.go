@@ -59,7 +59,7 @@ func NewFacebook(config *Config) (storage Storage, err error) {
 	fb.adapter = fbAdapter
 
 	//streaming worker (queue reading)
-	fb.streamingWorkers = newStreamingWorkers(config.eventQueue, fb, config.streamingThreadsCount)
+	fb.streamingWorkers = newStreamingWorkers(config.eventQueue, fb, config.streamingThreadsCount, nil)
 	return
 }
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/config"
@@ -17,6 +18,7 @@ import (
 	"github.com/jitsucom/jitsu/server/logevents"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/typing"
 )
 
 const (
@@ -62,9 +64,15 @@ type Config struct {
 	loggerFactory          *logevents.Factory
 	queueFactory           *events.QueueFactory
 	pkFields               map[string]bool
+	tablePKFields          map[string]map[string]bool
+	columnTypes            typing.SQLTypes
+	columnTypesMigration   string
+	tableLayouts           map[string]*adapters.TableLayout
 	uniqueIDField          *identifiers.UniqueID
 	logEventPath           string
 	PostHandleDestinations []string
+	//generation is a destinations configuration version the storage is created from
+	generation int64
 }
 
 // RegisterStorage registers function to create new storage(destination) instance
@@ -74,7 +82,7 @@ func RegisterStorage(storageType StorageType) {
 
 // Factory is a destinations factory for creation
 type Factory interface {
-	Create(name string, destination config.DestinationConfig) (StorageProxy, events.Queue, error)
+	Create(name string, destination config.DestinationConfig, generation int64, eventQueue events.Queue) (StorageProxy, events.Queue, error)
 	Configure(destinationID string, destination config.DestinationConfig) (func(config *Config) (Storage, error), *Config, error)
 }
 
@@ -130,16 +138,23 @@ func NewFactory(ctx context.Context, logEventPath string, geoService *geo.Servic
 
 // Create builds event storage proxy and event consumer (logger or event-queue)
 // Enriches incoming configs with default values if needed
-func (f *FactoryImpl) Create(destinationID string, destination config.DestinationConfig) (StorageProxy, events.Queue, error) {
-	createFunc, config, err := f.Configure(destinationID, destination)
+// generation is a destinations configuration version. eventQueue is an events queue of the previous storage version
+// which is handed off to the new storage (nil if a new queue should be created)
+func (f *FactoryImpl) Create(destinationID string, destination config.DestinationConfig, generation int64, eventQueue events.Queue) (StorageProxy, events.Queue, error) {
+	createFunc, config, err := f.configure(destinationID, destination, eventQueue)
 	if err != nil {
 		return nil, nil, err
 	}
+	config.generation = generation
 	storageProxy := newProxy(createFunc, config)
 	return storageProxy, config.eventQueue, nil
 }
 
 func (f *FactoryImpl) Configure(destinationID string, destination config.DestinationConfig) (func(config *Config) (Storage, error), *Config, error) {
+	return f.configure(destinationID, destination, nil)
+}
+
+func (f *FactoryImpl) configure(destinationID string, destination config.DestinationConfig, eventQueue events.Queue) (func(config *Config) (Storage, error), *Config, error) {
 	if destination.Type == "" {
 		destination.Type = destinationID
 	}
@@ -160,12 +175,26 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 	logging.Infof("[%s] destination mode: %s", destinationID, destination.Mode)
 
 	pkFields := map[string]bool{}
+	tablePKFields := map[string]map[string]bool{}
+	columnTypes := typing.SQLTypes{}
+	columnTypesMigration := ColumnTypesMigrationDisabled
+	tableLayouts := map[string]*adapters.TableLayout{}
 	maxColumns := f.maxColumns
 	uniqueIDField := appconfig.Instance.GlobalUniqueIDField
 	if destination.DataLayout != nil {
 		for _, field := range destination.DataLayout.PrimaryKeyFields {
 			pkFields[field] = true
 		}
+		for tableName, fields := range destination.DataLayout.TablePrimaryKeyFields {
+			if len(fields) == 0 {
+				return nil, nil, fmt.Errorf("data_layout.table_primary_key_fields: primary key fields of [%s] table can't be empty", tableName)
+			}
+			tablePKFields[tableName] = map[string]bool{}
+			for _, field := range fields {
+				tablePKFields[tableName][field] = true
+			}
+			logging.Infof("[%s] table [%s] has primary key fields: [%s]", destinationID, tableName, strings.Join(fields, ", "))
+		}
 		if destination.DataLayout.MaxColumns > 0 {
 			maxColumns = destination.DataLayout.MaxColumns
 			logging.Infof("[%s] uses max_columns setting: %d", destinationID, maxColumns)
@@ -173,6 +202,33 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 		if destination.DataLayout.UniqueIDField != "" {
 			uniqueIDField = identifiers.NewUniqueID(destination.DataLayout.UniqueIDField)
 		}
+		for field, sqlType := range destination.DataLayout.ColumnTypes {
+			if sqlType == "" {
+				return nil, nil, fmt.Errorf("data_layout.column_types: SQL type of [%s] column can't be empty", field)
+			}
+			columnName := jsonutils.NewJSONPath(field).FieldName()
+			columnTypes[columnName] = typing.SQLColumn{Type: sqlType, ColumnType: sqlType, Override: true}
+			logging.Infof("[%s] column [%s] type is overridden with: %s", destinationID, columnName, sqlType)
+		}
+		switch destination.DataLayout.ColumnTypesMigration {
+		case ColumnTypesMigrationDisabled:
+		case ColumnTypesMigrationDryRun, ColumnTypesMigrationEnabled:
+			columnTypesMigration = destination.DataLayout.ColumnTypesMigration
+			logging.Infof("[%s] uses column_types_migration mode: %s", destinationID, columnTypesMigration)
+		default:
+			return nil, nil, fmt.Errorf("data_layout.column_types_migration: unknown mode [%s]. Supported: [%s, %s]", destination.DataLayout.ColumnTypesMigration, ColumnTypesMigrationDryRun, ColumnTypesMigrationEnabled)
+		}
+		for tableName, partitioning := range destination.DataLayout.TablePartitioning {
+			if partitioning == nil {
+				continue
+			}
+			tableLayouts[tableName] = &adapters.TableLayout{
+				PartitionBy: partitioning.PartitionBy,
+				ClusterBy:   partitioning.ClusterBy,
+				SortKeys:    partitioning.SortKeys,
+				DistKey:     partitioning.DistKey,
+			}
+		}
 	}
 	if len(pkFields) > 0 {
 		logging.Infof("[%s] has primary key fields: [%s]", destinationID, strings.Join(destination.DataLayout.PrimaryKeyFields, ", "))
@@ -186,9 +242,15 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 		return nil, nil, err
 	}
 
-	var eventQueue events.Queue
-	if destination.Mode != SynchronousMode {
-		eventQueue, err = f.eventsQueueFactory.CreateEventsQueue(destination.Type, destinationID)
+	if eventQueue == nil && destination.Mode != SynchronousMode {
+		var queueLimits *events.QueueLimits
+		if destination.Queue != nil {
+			queueLimits = &events.QueueLimits{MaxSize: destination.Queue.MaxSize, Policy: destination.Queue.OverflowPolicy}
+			if queueLimits.Policy == "" {
+				queueLimits.Policy = events.QueueOverflowDropNew
+			}
+		}
+		eventQueue, err = f.eventsQueueFactory.CreateEventsQueue(destination.Type, destinationID, queueLimits)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -240,6 +302,10 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 		loggerFactory:          destinationLoggerFactory,
 		queueFactory:           f.eventsQueueFactory,
 		pkFields:               pkFields,
+		tablePKFields:          tablePKFields,
+		columnTypes:            columnTypes,
+		columnTypesMigration:   columnTypesMigration,
+		tableLayouts:           tableLayouts,
 		uniqueIDField:          uniqueIDField,
 		logEventPath:           f.logEventPath,
 		PostHandleDestinations: destination.PostHandleDestinations,
@@ -10,6 +10,7 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/delivery"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/schema"
@@ -47,10 +48,12 @@ func (fs *FileStorage) Store(fileName string, objects []map[string]interface{},
 	//update cache with failed events
 	for _, failedEvent := range failedEvents.Events {
 		fs.eventsCache.Error(fs.IsCachingDisabled(), fs.ID(), string(failedEvent.Event), failedEvent.Error)
+		delivery.Failed(failedEvent.EventID, fs.ID(), fs.Generation(), failedEvent.Error)
 	}
 	//update cache and counter with skipped events
 	for _, skipEvent := range skippedEvents.Events {
 		fs.eventsCache.Skip(fs.IsCachingDisabled(), fs.ID(), string(skipEvent.Event), skipEvent.Error)
+		delivery.Skipped(skipEvent.EventID, fs.ID(), fs.Generation(), skipEvent.Error)
 	}
 
 	storeFailedEvents := true
@@ -175,7 +178,7 @@ func requireBatchMode(config *Config) error {
 			_ = config.eventQueue.Close()
 		}
 
-		return errors.Errorf("%s destination doesn't support %s mode", GCSType, StreamMode)
+		return errors.Errorf("%s destination doesn't support %s mode", config.destination.Type, StreamMode)
 	}
 
 	return nil
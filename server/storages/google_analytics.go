@@ -60,7 +60,7 @@ func NewGoogleAnalytics(config *Config) (storage Storage, err error) {
 	ga.adapter = gaAdapter
 
 	//streaming worker (queue reading)
-	ga.streamingWorkers = newStreamingWorkers(config.eventQueue, ga, config.streamingThreadsCount)
+	ga.streamingWorkers = newStreamingWorkers(config.eventQueue, ga, config.streamingThreadsCount, nil)
 	return
 }
 
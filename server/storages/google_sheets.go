@@ -0,0 +1,143 @@
+package storages
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//GoogleSheets is a destination that writes small tables (e.g. aggregates) into Google Sheets spreadsheet.
+//Every table is a sheet with the same name
+type GoogleSheets struct {
+	Abstract
+
+	adapter *adapters.GoogleSheets
+	mode    string
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: GoogleSheetsType, createFunc: NewGoogleSheets, isSQL: true})
+}
+
+//NewGoogleSheets returns configured GoogleSheets destination
+func NewGoogleSheets(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if err = requireBatchMode(config); err != nil {
+		return
+	}
+
+	sheetsConfig := &adapters.GoogleSheetsConfig{}
+	if err = config.destination.GetDestConfig(map[string]interface{}{}, sheetsConfig); err != nil {
+		return
+	}
+
+	adapter, err := adapters.NewGoogleSheets(config.ctx, sheetsConfig)
+	if err != nil {
+		return
+	}
+
+	gs := &GoogleSheets{adapter: adapter, mode: sheetsConfig.Mode}
+	storage = gs
+	err = gs.Init(config, gs, "", "")
+	return
+}
+
+//DryRun isn't supported
+func (gs *GoogleSheets) DryRun(events.Event) ([][]adapters.TableField, error) {
+	return nil, fmt.Errorf("[%s] does not support dry run functionality", GoogleSheetsType)
+}
+
+//storeTable writes the events batch into the table sheet according to the configured mode
+func (gs *GoogleSheets) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, error) {
+	table := &adapters.Table{Name: fdata.BatchHeader.TableName}
+
+	start := timestamp.Now()
+	var err error
+	if gs.mode == adapters.GoogleSheetsReplaceMode {
+		err = gs.adapter.Replace(table.Name, fdata.GetPayload())
+	} else {
+		err = gs.adapter.Append(table.Name, fdata.GetPayload())
+	}
+	if err != nil {
+		return table, err
+	}
+	logging.Debugf("[%s] Wrote [%d] rows into sheet [%s] in [%.2f] seconds", gs.ID(), fdata.GetPayloadLen(), table.Name, timestamp.Now().Sub(start).Seconds())
+
+	return table, nil
+}
+
+//SyncStore writes source objects into the table sheet. Not empty delete conditions mean the first chunk of
+//a full refresh: the sheet is overwritten. Otherwise objects are appended
+func (gs *GoogleSheets) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, deleteConditions *base.DeleteConditions, cacheTable bool, needCopyEvent bool) error {
+	if len(objects) == 0 {
+		if overriddenDataSchema != nil && !deleteConditions.IsEmpty() {
+			return gs.adapter.Clear(overriddenDataSchema.TableName)
+		}
+		return nil
+	}
+
+	flatDataPerTable, err := processData(gs, overriddenDataSchema, objects, "", needCopyEvent)
+	if err != nil {
+		return err
+	}
+
+	for _, flatData := range flatDataPerTable {
+		if deleteConditions.IsEmpty() {
+			err = gs.adapter.Append(flatData.BatchHeader.TableName, flatData.GetPayload())
+		} else {
+			err = gs.adapter.Replace(flatData.BatchHeader.TableName, flatData.GetPayload())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//ReplaceTable replaces the original sheet with the replacement one
+func (gs *GoogleSheets) ReplaceTable(originalTable, replacementTable string, dropOldTable bool) error {
+	return gs.adapter.ReplaceSheet(originalTable, replacementTable)
+}
+
+//DropTable deletes the table sheet
+func (gs *GoogleSheets) DropTable(tableName string) error {
+	return gs.adapter.DeleteSheet(tableName)
+}
+
+//Clean removes all rows from the table sheet
+func (gs *GoogleSheets) Clean(tableName string) error {
+	return gs.adapter.Clear(tableName)
+}
+
+//GetUsersRecognition returns disabled users recognition configuration
+func (gs *GoogleSheets) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+//Type returns Google Sheets type
+func (gs *GoogleSheets) Type() string {
+	return GoogleSheetsType
+}
+
+//Close closes Google Sheets adapter and fallback logger
+func (gs *GoogleSheets) Close() (multiErr error) {
+	if err := gs.adapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing Google Sheets adapter: %v", gs.ID(), err))
+	}
+	if err := gs.close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+	return
+}
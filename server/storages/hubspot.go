@@ -56,7 +56,7 @@ func NewHubSpot(config *Config) (storage Storage, err error) {
 	h.adapter = hAdapter
 
 	//streaming worker (queue reading)
-	h.streamingWorkers = newStreamingWorkers(config.eventQueue, h, config.streamingThreadsCount)
+	h.streamingWorkers = newStreamingWorkers(config.eventQueue, h, config.streamingThreadsCount, nil)
 	return
 }
 
@@ -17,6 +17,9 @@ type testProxyMock struct {
 //Get is a mock func
 func (tpm *testProxyMock) Get() (Storage, bool) { return nil, false }
 
+//Acquire is a mock func
+func (tpm *testProxyMock) Acquire() (Storage, func(), bool) { return nil, func() {}, false }
+
 //GetUniqueIDField is a mock func
 func (tpm *testProxyMock) GetUniqueIDField() *identifiers.UniqueID {
 	return appconfig.Instance.GlobalUniqueIDField
@@ -50,11 +53,10 @@ type MockFactory struct{}
 func NewMockFactory() Factory { return &MockFactory{} }
 
 //Create returns proxy Mock and events queue
-func (mf *MockFactory) Create(id string, destination config.DestinationConfig) (StorageProxy, events.Queue, error) {
-	var eventQueue events.Queue
-	if destination.Mode == StreamMode {
+func (mf *MockFactory) Create(id string, destination config.DestinationConfig, _ int64, eventQueue events.Queue) (StorageProxy, events.Queue, error) {
+	if eventQueue == nil && destination.Mode == StreamMode {
 		qf := events.NewQueueFactory(nil, 0)
-		eventQueue, _ = qf.CreateEventsQueue(destination.Type, id)
+		eventQueue, _ = qf.CreateEventsQueue(destination.Type, id, nil)
 	}
 	return &testProxyMock{mode: destination.Mode}, eventQueue, nil
 }
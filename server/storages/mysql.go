@@ -63,7 +63,7 @@ func NewMySQL(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper(mConfig.Schema, adapter, config.coordinationService, config.pkFields, adapters.SchemaToMySQL, config.maxColumns, MySQLType)
+	tableHelper := newTableHelper(config, mConfig.Schema, adapter, adapters.SchemaToMySQL, MySQLType)
 
 	m.adapter = adapter
 	m.usersRecognitionConfiguration = config.usersRecognition
@@ -73,7 +73,7 @@ func NewMySQL(config *Config) (storage Storage, err error) {
 	m.sqlAdapters = []adapters.SQLAdapter{adapter}
 
 	//streaming worker (queue reading)
-	m.streamingWorkers = newStreamingWorkers(config.eventQueue, m, config.streamingThreadsCount, tableHelper)
+	m.streamingWorkers = newStreamingWorkers(config.eventQueue, m, config.streamingThreadsCount, config.destination.StreamBatch, tableHelper)
 	return
 }
 
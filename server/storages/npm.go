@@ -34,12 +34,17 @@ func NewNpmDestination(config *Config) (storage Storage, err error) {
 		return nil, fmt.Errorf("NpmDestination destination doesn't support %s mode", BatchMode)
 	}
 
-	jsTemplate, err := templates.NewScriptExecutor(&templates.DestinationPlugin{
+	limits, err := templates.NewScriptLimits(config.destination.ScriptLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script_limits: %v", err)
+	}
+
+	jsTemplate, err := templates.NewLimitedScriptExecutor(&templates.DestinationPlugin{
 		Package: config.destination.Package,
 		ID:      config.destinationID,
 		Type:    NpmType,
 		Config:  utils.MapNestedKeysToString(config.destination.Config),
-	}, nil)
+	}, nil, limits)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to init builtin javascript code: %v", err)
@@ -78,7 +83,7 @@ func NewNpmDestination(config *Config) (storage Storage, err error) {
 	wh.adapter = wbAdapter
 
 	//streaming worker (queue reading)
-	wh.streamingWorkers = newStreamingWorkers(config.eventQueue, wh, config.streamingThreadsCount)
+	wh.streamingWorkers = newStreamingWorkers(config.eventQueue, wh, config.streamingThreadsCount, nil)
 	return
 }
 
@@ -76,7 +76,7 @@ func NewPostgres(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper(pgConfig.Schema, adapter, config.coordinationService, config.pkFields, adapters.SchemaToPostgres, config.maxColumns, PostgresType)
+	tableHelper := newTableHelper(config, pgConfig.Schema, adapter, adapters.SchemaToPostgres, PostgresType)
 
 	p.adapter = adapter
 	p.usersRecognitionConfiguration = config.usersRecognition
@@ -85,7 +85,7 @@ func NewPostgres(config *Config) (storage Storage, err error) {
 	p.sqlAdapters = []adapters.SQLAdapter{adapter}
 
 	//streaming worker (queue reading)
-	p.streamingWorkers = newStreamingWorkers(config.eventQueue, p, config.streamingThreadsCount, tableHelper)
+	p.streamingWorkers = newStreamingWorkers(config.eventQueue, p, config.streamingThreadsCount, config.destination.StreamBatch, tableHelper)
 	return
 }
 
@@ -11,6 +11,9 @@ import (
 	"time"
 )
 
+//inFlightTimeout is a max duration of waiting for in-flight writes on closing
+const inFlightTimeout = 10 * time.Minute
+
 //RetryableProxy creates Storage with retry (if create fails e.g. because of connection issue)
 type RetryableProxy struct {
 	sync.RWMutex
@@ -20,6 +23,8 @@ type RetryableProxy struct {
 	storage Storage
 	ready   *atomic.Bool
 	closed  *atomic.Bool
+	//inFlight is a counter of acquired storage usages (e.g. batch files uploading)
+	inFlight sync.WaitGroup
 }
 
 //newProxy return New RetryableProxy and starts goroutine
@@ -90,6 +95,20 @@ func (rsp *RetryableProxy) Get() (Storage, bool) {
 	return rsp.storage, rsp.ready.Load()
 }
 
+//Acquire returns underlying destination storage, release func and ready flag.
+//Close waits for all acquired usages release so the storage isn't closed in the middle of writing
+func (rsp *RetryableProxy) Acquire() (Storage, func(), bool) {
+	rsp.RLock()
+	defer rsp.RUnlock()
+	if !rsp.ready.Load() || rsp.closed.Load() {
+		return rsp.storage, func() {}, false
+	}
+
+	rsp.inFlight.Add(1)
+	var once sync.Once
+	return rsp.storage, func() { once.Do(rsp.inFlight.Done) }, true
+}
+
 //GetUniqueIDField returns unique ID field configuration
 func (rsp *RetryableProxy) GetUniqueIDField() *identifiers.UniqueID {
 	return rsp.config.uniqueIDField
@@ -124,14 +143,27 @@ func (rsp *RetryableProxy) GetGeoResolverID() string {
 	return rsp.config.destination.GeoDataResolverID
 }
 
-//Close stops underlying goroutine and close the storage
+//Close stops underlying goroutine, waits for in-flight writes (see Acquire) and closes the storage
 func (rsp *RetryableProxy) Close() error {
 	rsp.Lock()
 	rsp.closed.Store(true)
-	if rsp.storage != nil {
-		return rsp.storage.Close()
-	}
+	storage := rsp.storage
 	rsp.Unlock()
 
-	return nil
+	if storage == nil {
+		return nil
+	}
+
+	released := make(chan struct{})
+	safego.Run(func() {
+		rsp.inFlight.Wait()
+		close(released)
+	})
+	select {
+	case <-released:
+	case <-time.After(inFlightTimeout):
+		logging.Warnf("[%s] In-flight writes haven't been completed in %s. The storage will be closed", rsp.config.destinationID, inFlightTimeout)
+	}
+
+	return storage.Close()
 }
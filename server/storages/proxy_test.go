@@ -0,0 +1,51 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+type closeRecorderStorage struct {
+	Storage
+	closed *atomic.Bool
+}
+
+func (crs *closeRecorderStorage) Close() error {
+	crs.closed.Store(true)
+	return nil
+}
+
+func TestRetryableProxyCloseWaitsForAcquired(t *testing.T) {
+	storage := &closeRecorderStorage{closed: atomic.NewBool(false)}
+	rsp := &RetryableProxy{
+		config:  &Config{destinationID: "test"},
+		storage: storage,
+		ready:   atomic.NewBool(true),
+		closed:  atomic.NewBool(false),
+	}
+
+	acquired, release, ok := rsp.Acquire()
+	require.True(t, ok)
+	require.Equal(t, storage, acquired)
+
+	closeErr := make(chan error)
+	go func() {
+		closeErr <- rsp.Close()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	require.False(t, storage.closed.Load(), "storage mustn't be closed while it is acquired")
+
+	//closing proxy isn't acquired anymore
+	_, _, ok = rsp.Acquire()
+	require.False(t, ok)
+
+	release()
+	//release is idempotent
+	release()
+	require.NoError(t, <-closeErr)
+	require.True(t, storage.closed.Load())
+}
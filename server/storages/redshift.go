@@ -20,6 +20,7 @@ type AwsRedshift struct {
 	s3Adapter                     *adapters.S3
 	redshiftAdapter               *adapters.AwsRedshift
 	usersRecognitionConfiguration *UserRecognitionConfiguration
+	stagingFormat                 adapters.FileEncodingFormat
 }
 
 func init() {
@@ -67,6 +68,11 @@ func NewAwsRedshift(config *Config) (storage Storage, err error) {
 	if !ok {
 		s3config = &adapters.S3Config{}
 	}
+	format, err := stagingFormat(config, RedshiftType)
+	if err != nil {
+		return
+	}
+	s3config.RequireStagingFormat(RedshiftType, format)
 	if !config.streamMode {
 		s3Adapter, err = adapters.NewS3(s3config)
 		if err != nil {
@@ -94,10 +100,11 @@ func NewAwsRedshift(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper(redshiftConfig.Schema, redshiftAdapter, config.coordinationService, config.pkFields, adapters.SchemaToRedshift, config.maxColumns, RedshiftType)
+	tableHelper := newTableHelper(config, redshiftConfig.Schema, redshiftAdapter, adapters.SchemaToRedshift, RedshiftType)
 
 	ar.s3Adapter = s3Adapter
 	ar.redshiftAdapter = redshiftAdapter
+	ar.stagingFormat = format
 	ar.usersRecognitionConfiguration = config.usersRecognition
 
 	//Abstract
@@ -105,7 +112,7 @@ func NewAwsRedshift(config *Config) (storage Storage, err error) {
 	ar.sqlAdapters = []adapters.SQLAdapter{redshiftAdapter}
 
 	//streaming worker (queue reading)
-	ar.streamingWorkers = newStreamingWorkers(config.eventQueue, ar, config.streamingThreadsCount, tableHelper)
+	ar.streamingWorkers = newStreamingWorkers(config.eventQueue, ar, config.streamingThreadsCount, config.destination.StreamBatch, tableHelper)
 	return
 }
 
@@ -122,7 +129,7 @@ func (ar *AwsRedshift) storeTable(fdata *schema.ProcessedFile) (*adapters.Table,
 			return table, err
 		}
 
-		b, err := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
+		b, header, err := marshalStagingFile(fdata, ar.stagingFormat)
 		if err != nil {
 			return dbTable, err
 		}
@@ -130,7 +137,7 @@ func (ar *AwsRedshift) storeTable(fdata *schema.ProcessedFile) (*adapters.Table,
 			return dbTable, err
 		}
 
-		if err := ar.redshiftAdapter.Copy(fdata.FileName, dbTable.Name); err != nil {
+		if err := ar.redshiftAdapter.Copy(fdata.FileName, dbTable.Name, ar.stagingFormat, header); err != nil {
 			return dbTable, fmt.Errorf("Error copying file [%s] from s3 to redshift: %v", fdata.FileName, err)
 		}
 
@@ -0,0 +1,115 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+// filesRetentionKey is a key of expired files amount in the result of file storages
+const filesRetentionKey = "files"
+
+var (
+	// ErrRetentionNotSupported is returned if the destination doesn't support data retention
+	ErrRetentionNotSupported = errors.New("data retention isn't supported by the destination")
+	// ErrRetentionDisabled is returned if data retention is disabled or retention days aren't configured
+	ErrRetentionDisabled = errors.New("data retention is disabled in the destination configuration")
+)
+
+// Expire deletes rows older than the retention days from the destination tables (or counts them if dryRun is true).
+// Tables, column and days which aren't set in the destination retention configuration are taken from defaults.
+// If tables aren't configured, the static destination table is used.
+// Returns amount of deleted rows per table (-1 if it is unknown)
+func (a *Abstract) Expire(defaults *config.Retention, dryRun bool) (map[string]int64, error) {
+	if len(a.sqlAdapters) == 0 {
+		return nil, ErrRetentionNotSupported
+	}
+	expirer, ok := a.sqlAdapters[0].(adapters.Expirer)
+	if !ok {
+		return nil, ErrRetentionNotSupported
+	}
+
+	retention := mergeRetention(a.retention, defaults)
+	if retention.Disabled || retention.Days <= 0 {
+		return nil, ErrRetentionDisabled
+	}
+
+	tables := retention.Tables
+	if len(tables) == 0 {
+		tableName := a.processor.TableNameExpression()
+		if !staticTableNameRegexp.MatchString(tableName) {
+			return nil, fmt.Errorf("retention.tables must be configured because the destination table name is dynamic: %s", tableName)
+		}
+		tables = []string{tableName}
+	}
+
+	threshold := retentionThreshold(retention.Days)
+	result := map[string]int64{}
+	var multiErr error
+	for _, table := range tables {
+		affected, err := expirer.Expire(table, retention.Column, threshold, dryRun)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("table %s: %v", table, err))
+			continue
+		}
+		result[table] = affected
+	}
+
+	return result, multiErr
+}
+
+// Expire deletes files older than the retention days (or counts them if dryRun is true)
+// Returns amount of deleted files
+func (fs *FileStorage) Expire(defaults *config.Retention, dryRun bool) (map[string]int64, error) {
+	expirer, ok := fs.adapter.(adapters.FilesExpirer)
+	if !ok {
+		return nil, ErrRetentionNotSupported
+	}
+
+	retention := mergeRetention(fs.retention, defaults)
+	if retention.Disabled || retention.Days <= 0 {
+		return nil, ErrRetentionDisabled
+	}
+
+	expired, err := expirer.ExpireFiles(retentionThreshold(retention.Days), dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]int64{filesRetentionKey: expired}, nil
+}
+
+// retentionThreshold returns the start of the UTC day which is days ago: data before it is expired
+func retentionThreshold(days int) time.Time {
+	return timestamp.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -days)
+}
+
+// mergeRetention returns the destination retention configuration with empty fields filled from defaults
+func mergeRetention(destination, defaults *config.Retention) *config.Retention {
+	result := &config.Retention{}
+	if defaults != nil {
+		*result = *defaults
+	}
+	if destination != nil {
+		result.Disabled = destination.Disabled
+		if destination.Days > 0 {
+			result.Days = destination.Days
+		}
+		if len(destination.Tables) > 0 {
+			result.Tables = destination.Tables
+		}
+		if destination.Column != "" {
+			result.Column = destination.Column
+		}
+	}
+	if result.Column == "" {
+		result.Column = timestamp.Key
+	}
+
+	return result
+}
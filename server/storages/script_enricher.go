@@ -0,0 +1,33 @@
+package storages
+
+import (
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/script"
+	"github.com/jitsucom/jitsu/server/templates"
+)
+
+// scriptEnricher is an enrichment.ScriptExecutor which runs JavaScript enricher code as a transformation expression
+type scriptEnricher struct {
+	executor *templates.NodeExecutor
+}
+
+// newScriptEnricherFactory returns enrichment.ScriptExecutorFactory which creates JavaScript enrichers with
+// destination script limits
+func newScriptEnricherFactory(limits *script.Limits) enrichment.ScriptExecutorFactory {
+	return func(code string) (enrichment.ScriptExecutor, error) {
+		executor, err := templates.NewLimitedScriptExecutor(templates.Expression(code), nil, limits)
+		if err != nil {
+			return nil, err
+		}
+
+		return &scriptEnricher{executor: executor}, nil
+	}
+}
+
+func (se *scriptEnricher) Execute(event map[string]interface{}) (interface{}, error) {
+	return se.executor.ProcessEvent(event, nil)
+}
+
+func (se *scriptEnricher) Close() {
+	se.executor.Close()
+}
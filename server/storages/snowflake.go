@@ -15,7 +15,7 @@ import (
 )
 
 // Snowflake stores files to Snowflake in two modes:
-// batch: via aws s3 (or gcp) in batch mode (1 file = 1 transaction)
+// batch: via aws s3 (or s3-compatible storage, azure, gcp) in batch mode (1 file = 1 transaction)
 // stream: via events queue in stream mode (1 object = 1 transaction)
 type Snowflake struct {
 	Abstract
@@ -23,6 +23,7 @@ type Snowflake struct {
 	stageAdapter                  adapters.Stage
 	snowflakeAdapter              *adapters.Snowflake
 	usersRecognitionConfiguration *UserRecognitionConfiguration
+	stagingFormat                 adapters.FileEncodingFormat
 }
 
 func init() {
@@ -73,28 +74,28 @@ func NewSnowflake(config *Config) (storage Storage, err error) {
 		}
 	}
 
+	if snowflakeConfig.Azure != nil && snowflakeConfig.Stage == "" {
+		return nil, errors.New("Snowflake stage is required parameter in Azure integration")
+	}
+
 	var stageAdapter adapters.Stage
 	var s3config *adapters.S3Config
 	s3c, err := config.destination.GetConfig(snowflakeConfig.S3, config.destination.S3, &adapters.S3Config{})
 	if err != nil {
 		return
 	}
-	s3config, s3ok := s3c.(*adapters.S3Config)
+	s3config, _ = s3c.(*adapters.S3Config)
+	format, err := stagingFormat(config, SnowflakeType)
+	if err != nil {
+		return
+	}
 	if !config.streamMode {
-		if s3ok {
-			stageAdapter, err = adapters.NewS3(s3config)
-			if err != nil {
-				return
-			}
-		} else {
-			googleConfig.RequireDefaultStage(SnowflakeType)
-			stageAdapter, err = adapters.NewGoogleCloudStorage(config.ctx, googleConfig)
-			if err != nil {
-				return
-			}
+		stageAdapter, err = NewSnowflakeStage(config.ctx, snowflakeConfig, s3config, googleConfig, format)
+		if err != nil {
+			return
 		}
 	}
-	snowflake := &Snowflake{stageAdapter: stageAdapter}
+	snowflake := &Snowflake{stageAdapter: stageAdapter, stagingFormat: format}
 	err = snowflake.Init(config, snowflake, "", "")
 	if err != nil {
 		return
@@ -106,7 +107,7 @@ func NewSnowflake(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper(snowflakeConfig.Schema, snowflakeAdapter, config.coordinationService, config.pkFields, adapters.SchemaToSnowflake, config.maxColumns, SnowflakeType)
+	tableHelper := newTableHelper(config, snowflakeConfig.Schema, snowflakeAdapter, adapters.SchemaToSnowflake, SnowflakeType)
 
 	snowflake.snowflakeAdapter = snowflakeAdapter
 	snowflake.usersRecognitionConfiguration = config.usersRecognition
@@ -116,10 +117,33 @@ func NewSnowflake(config *Config) (storage Storage, err error) {
 	snowflake.sqlAdapters = []adapters.SQLAdapter{snowflakeAdapter}
 
 	//streaming worker (queue reading)
-	snowflake.streamingWorkers = newStreamingWorkers(config.eventQueue, snowflake, config.streamingThreadsCount, tableHelper)
+	snowflake.streamingWorkers = newStreamingWorkers(config.eventQueue, snowflake, config.streamingThreadsCount, config.destination.StreamBatch, tableHelper)
 	return
 }
 
+// NewSnowflakeStage returns batch mode stage: S3 (or S3-compatible storage), Azure Blob Storage or Google Cloud Storage.
+// Azure, Google and S3-compatible storages are read by Snowflake via the named stage which doesn't support folder and compression
+func NewSnowflakeStage(ctx context.Context, snowflakeConfig *adapters.SnowflakeConfig, s3config *adapters.S3Config, googleConfig *adapters.GoogleConfig,
+	format adapters.FileEncodingFormat) (adapters.Stage, error) {
+	switch {
+	case s3config != nil:
+		if s3config.Endpoint != "" && snowflakeConfig.Stage != "" {
+			s3config.RequireDefaultStage(SnowflakeType)
+		} else {
+			s3config.RequireStagingFormat(SnowflakeType, format)
+		}
+		return adapters.NewS3(s3config)
+	case snowflakeConfig.Azure != nil:
+		snowflakeConfig.Azure.RequireDefaultStage(SnowflakeType)
+		return adapters.NewAzureBlob(ctx, snowflakeConfig.Azure)
+	case googleConfig != nil:
+		googleConfig.RequireDefaultStage(SnowflakeType)
+		return adapters.NewGoogleCloudStorage(ctx, googleConfig)
+	default:
+		return nil, errors.New("Snowflake batch mode requires s3, azure or google stage configuration")
+	}
+}
+
 // CreateSnowflakeAdapter creates snowflake adapter with schema
 // if schema doesn't exist - snowflake returns error. In this case connect without schema and create it
 func CreateSnowflakeAdapter(ctx context.Context, s3Config *adapters.S3Config, config adapters.SnowflakeConfig,
@@ -158,7 +182,7 @@ func CreateSnowflakeAdapter(ctx context.Context, s3Config *adapters.S3Config, co
 }
 
 // storeTable check table schema
-// and store data into one table via stage (google cloud storage or s3)
+// and store data into one table via stage (s3, azure blob storage or google cloud storage)
 func (s *Snowflake) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, error) {
 	if fdata.RecognitionPayload {
 		return s.Abstract.storeTable(fdata)
@@ -170,7 +194,7 @@ func (s *Snowflake) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, er
 			return table, err
 		}
 
-		b, header, err := fdata.GetPayloadBytesWithHeader(schema.CSVMarshallerInstance)
+		b, header, err := marshalStagingFile(fdata, s.stagingFormat)
 		if err != nil {
 			return dbTable, err
 		}
@@ -178,7 +202,7 @@ func (s *Snowflake) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, er
 			return dbTable, err
 		}
 
-		if err := s.snowflakeAdapter.Copy(fdata.FileName, dbTable.Name, header); err != nil {
+		if err := s.snowflakeAdapter.Copy(fdata.FileName, dbTable.Name, s.stagingFormat, header); err != nil {
 			return dbTable, fmt.Errorf("Error copying file [%s] from stage to snowflake: %v", fdata.FileName, err)
 		}
 
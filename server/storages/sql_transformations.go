@@ -0,0 +1,195 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/coordination"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/robfig/cron/v3"
+)
+
+const sqlTransformationsLockTimeout = 10 * time.Second
+
+var sqlTransformationsParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// SQLTransformationsWorker materializes destination SQL models as tables or views inside the warehouse
+// on schedule. Models are run in dependency order: a model is skipped if any of its dependencies has failed.
+// Only one cluster node runs transformations of a destination at the same time
+type SQLTransformationsWorker struct {
+	destinationID       string
+	materializer        adapters.Materializer
+	coordinationService *coordination.Service
+	models              []config.SQLModel
+
+	cron *cron.Cron
+}
+
+// NewSQLTransformationsWorker validates models and returns worker with models sorted in dependency order
+func NewSQLTransformationsWorker(destinationID string, cfg *config.SQLTransformations, materializer adapters.Materializer,
+	coordinationService *coordination.Service) (*SQLTransformationsWorker, error) {
+	if strings.TrimSpace(cfg.Schedule) == "" {
+		return nil, errors.New("sql_transformations.schedule is required")
+	}
+
+	schedule, err := sqlTransformationsParser.Parse(cfg.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sql_transformations.schedule [%s]: %v", cfg.Schedule, err)
+	}
+
+	models, err := sortSQLModels(cfg.Models)
+	if err != nil {
+		return nil, err
+	}
+
+	worker := &SQLTransformationsWorker{
+		destinationID:       destinationID,
+		materializer:        materializer,
+		coordinationService: coordinationService,
+		models:              models,
+		cron:                cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger))),
+	}
+	worker.cron.Schedule(schedule, cron.FuncJob(worker.run))
+
+	return worker, nil
+}
+
+func (w *SQLTransformationsWorker) start() {
+	w.cron.Start()
+}
+
+func (w *SQLTransformationsWorker) run() {
+	if w.coordinationService != nil {
+		lock := w.coordinationService.CreateLock("sql_transformations_" + w.destinationID)
+		locked, err := lock.TryLock(sqlTransformationsLockTimeout)
+		if err != nil {
+			logging.Errorf("[%s] Error locking SQL transformations: %v", w.destinationID, err)
+			return
+		}
+		if !locked {
+			logging.Debugf("[%s] SQL transformations are being run by another instance", w.destinationID)
+			return
+		}
+		defer lock.Unlock()
+	}
+
+	w.materialize()
+}
+
+//materialize runs all models in dependency order and returns names of failed or skipped models
+func (w *SQLTransformationsWorker) materialize() map[string]bool {
+	failed := map[string]bool{}
+	for _, model := range w.models {
+		var failedDependency string
+		for _, dependency := range model.DependsOn {
+			if failed[dependency] {
+				failedDependency = dependency
+				break
+			}
+		}
+		if failedDependency != "" {
+			failed[model.Name] = true
+			logging.Warnf("[%s] SQL transformation model [%s] is skipped: dependency [%s] has failed", w.destinationID, model.Name, failedDependency)
+			continue
+		}
+
+		start := time.Now()
+		if err := w.materializer.Materialize(model.Name, model.SQL, model.Materialized); err != nil {
+			failed[model.Name] = true
+			logging.Errorf("[%s] Error materializing SQL transformation model [%s]: %v", w.destinationID, model.Name, err)
+			continue
+		}
+
+		logging.Infof("[%s] SQL transformation model [%s] has been materialized as %s in %s", w.destinationID, model.Name, model.Materialized, time.Since(start))
+	}
+
+	return failed
+}
+
+// Close stops scheduling of transformations. Running transformations aren't interrupted
+func (w *SQLTransformationsWorker) Close() error {
+	w.cron.Stop()
+	return nil
+}
+
+//sortSQLModels validates models and returns them in dependency order (Kahn's algorithm).
+//Models without dependencies between each other keep configuration order
+func sortSQLModels(models []config.SQLModel) ([]config.SQLModel, error) {
+	if len(models) == 0 {
+		return nil, errors.New("sql_transformations.models must contain at least one model")
+	}
+
+	models = append([]config.SQLModel(nil), models...)
+	byName := make(map[string]config.SQLModel, len(models))
+	for i, model := range models {
+		if strings.TrimSpace(model.Name) == "" {
+			return nil, fmt.Errorf("sql_transformations.models[%d].name is required", i)
+		}
+		if _, ok := byName[model.Name]; ok {
+			return nil, fmt.Errorf("duplicate SQL transformation model [%s]", model.Name)
+		}
+		if strings.TrimSpace(model.SQL) == "" {
+			return nil, fmt.Errorf("SQL transformation model [%s]: sql is required", model.Name)
+		}
+
+		switch model.Materialized {
+		case "":
+			model.Materialized = adapters.MaterializedTable
+		case adapters.MaterializedTable, adapters.MaterializedView:
+		default:
+			return nil, fmt.Errorf("SQL transformation model [%s]: unknown materialized value [%s]. Supported: %s, %s",
+				model.Name, model.Materialized, adapters.MaterializedTable, adapters.MaterializedView)
+		}
+
+		models[i] = model
+		byName[model.Name] = model
+	}
+
+	inDegree := make(map[string]int, len(models))
+	dependents := make(map[string][]string, len(models))
+	for _, model := range models {
+		for _, dependency := range model.DependsOn {
+			if _, ok := byName[dependency]; !ok {
+				return nil, fmt.Errorf("SQL transformation model [%s] depends on unknown model [%s]", model.Name, dependency)
+			}
+			inDegree[model.Name]++
+			dependents[dependency] = append(dependents[dependency], model.Name)
+		}
+	}
+
+	sorted := make([]config.SQLModel, 0, len(models))
+	done := make(map[string]bool, len(models))
+	for len(sorted) < len(models) {
+		progress := false
+		for _, model := range models {
+			if done[model.Name] || inDegree[model.Name] > 0 {
+				continue
+			}
+
+			done[model.Name] = true
+			progress = true
+			sorted = append(sorted, model)
+			for _, dependent := range dependents[model.Name] {
+				inDegree[dependent]--
+			}
+		}
+
+		if !progress {
+			var cycled []string
+			for _, model := range models {
+				if !done[model.Name] {
+					cycled = append(cycled, model.Name)
+				}
+			}
+			return nil, fmt.Errorf("SQL transformation models have cyclic dependencies: %s", strings.Join(cycled, ", "))
+		}
+	}
+
+	return sorted, nil
+}
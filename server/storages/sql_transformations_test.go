@@ -0,0 +1,94 @@
+package storages
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testMaterializer struct {
+	materialized []string
+	failed       map[string]bool
+}
+
+func (tm *testMaterializer) Materialize(name, query, materialized string) error {
+	if tm.failed[name] {
+		return errors.New("query failed")
+	}
+
+	tm.materialized = append(tm.materialized, name+":"+materialized)
+	return nil
+}
+
+func TestSortSQLModels(t *testing.T) {
+	models := []config.SQLModel{
+		{Name: "revenue", SQL: "select 1", DependsOn: []string{"orders", "users"}},
+		{Name: "orders", SQL: "select 1", Materialized: adapters.MaterializedView},
+		{Name: "users", SQL: "select 1", DependsOn: []string{"orders"}},
+		{Name: "sessions", SQL: "select 1"},
+	}
+
+	sorted, err := sortSQLModels(models)
+	require.NoError(t, err)
+	var names []string
+	for _, model := range sorted {
+		names = append(names, model.Name)
+	}
+	require.Equal(t, []string{"orders", "users", "sessions", "revenue"}, names)
+	require.Equal(t, adapters.MaterializedTable, sorted[1].Materialized)
+	require.Equal(t, adapters.MaterializedView, sorted[0].Materialized)
+	//input isn't modified
+	require.Equal(t, "", models[0].Materialized)
+}
+
+func TestSortSQLModelsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		models []config.SQLModel
+		err    string
+	}{
+		{"empty", nil, "sql_transformations.models must contain at least one model"},
+		{"no name", []config.SQLModel{{SQL: "select 1"}}, "sql_transformations.models[0].name is required"},
+		{"no sql", []config.SQLModel{{Name: "a"}}, "SQL transformation model [a]: sql is required"},
+		{"duplicate", []config.SQLModel{{Name: "a", SQL: "select 1"}, {Name: "a", SQL: "select 2"}}, "duplicate SQL transformation model [a]"},
+		{"materialized", []config.SQLModel{{Name: "a", SQL: "select 1", Materialized: "incremental"}}, "SQL transformation model [a]: unknown materialized value [incremental]. Supported: table, view"},
+		{"unknown dependency", []config.SQLModel{{Name: "a", SQL: "select 1", DependsOn: []string{"b"}}}, "SQL transformation model [a] depends on unknown model [b]"},
+		{"cycle", []config.SQLModel{
+			{Name: "a", SQL: "select 1", DependsOn: []string{"c"}},
+			{Name: "b", SQL: "select 1", DependsOn: []string{"a"}},
+			{Name: "c", SQL: "select 1", DependsOn: []string{"b"}},
+			{Name: "d", SQL: "select 1"},
+		}, "SQL transformation models have cyclic dependencies: a, b, c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sortSQLModels(tt.models)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestSQLTransformationsWorkerSkipsDependents(t *testing.T) {
+	materializer := &testMaterializer{failed: map[string]bool{"orders": true}}
+	worker, err := NewSQLTransformationsWorker("dest1", &config.SQLTransformations{
+		Schedule: "@hourly",
+		Models: []config.SQLModel{
+			{Name: "orders", SQL: "select 1"},
+			{Name: "users", SQL: "select 1", Materialized: adapters.MaterializedView},
+			{Name: "revenue", SQL: "select 1", DependsOn: []string{"orders"}},
+			{Name: "report", SQL: "select 1", DependsOn: []string{"revenue", "users"}},
+		},
+	}, materializer, nil)
+	require.NoError(t, err)
+	defer worker.Close()
+
+	failed := worker.materialize()
+	require.Equal(t, map[string]bool{"orders": true, "revenue": true, "report": true}, failed)
+	require.Equal(t, []string{"users:view"}, materializer.materialized)
+
+	_, err = NewSQLTransformationsWorker("dest1", &config.SQLTransformations{Schedule: "every hour", Models: []config.SQLModel{{Name: "a", SQL: "select 1"}}}, materializer, nil)
+	require.Error(t, err)
+}
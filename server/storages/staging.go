@@ -0,0 +1,38 @@
+package storages
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+// stagingFormat returns the configured staging file format of the batch destination or the default one of the type
+func stagingFormat(config *Config, destinationType string) (adapters.FileEncodingFormat, error) {
+	typeConfig, ok := GetTypeConfig(destinationType)
+	if !ok {
+		return "", fmt.Errorf("unknown destination type [%s]", destinationType)
+	}
+
+	format, err := typeConfig.StagingFormat(config.destination.StagingFormat)
+	if err != nil {
+		return "", fmt.Errorf("%s destination: %v", destinationType, err)
+	}
+
+	return format, nil
+}
+
+// marshalStagingFile returns the staging file payload in the format. The header is the columns order of csv payload.
+// Parquet payload is compressed internally and missing values are written as nulls
+func marshalStagingFile(fdata *schema.ProcessedFile, format adapters.FileEncodingFormat) ([]byte, []string, error) {
+	switch format {
+	case adapters.FileFormatCSV:
+		return fdata.GetPayloadBytesWithHeader(schema.CSVMarshallerInstance)
+	case adapters.FileFormatParquet:
+		b, err := fdata.GetPayloadUsingStronglyTypedMarshaller(schema.NewNullableParquetMarshaller(true))
+		return b, nil, err
+	default:
+		b, err := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
+		return b, nil, err
+	}
+}
@@ -1,20 +1,29 @@
 package storages
 
 import (
+	"context"
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/tracing"
 	"github.com/jitsucom/jitsu/server/utils"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/atomic"
 	"math/rand"
+	"sync"
 	"time"
 )
 
+// StreamingComponent is a logging component of streaming workers
+const StreamingComponent = "streaming"
+
 // StreamingStorage supports Insert operation
 type StreamingStorage interface {
 	Storage
@@ -30,30 +39,126 @@ type StreamingStorage interface {
 	SkipEvent(eventCtx *adapters.EventContext, err error)
 }
 
+// StreamingBatchStorage supports writing stream mode micro-batches of events into one table
+type StreamingBatchStorage interface {
+	StreamingStorage
+	InsertBatch(table *adapters.Table, eventContexts []*adapters.EventContext) error
+}
+
+// pendingBatch is a micro-batch of one table events
+type pendingBatch struct {
+	//table contains merged columns of all events
+	table         *adapters.Table
+	eventContexts []*adapters.EventContext
+	//acks of dequeued events which are released when the batch is written
+	acks []*eventAck
+}
+
+// eventAck acknowledges the dequeued event in the queue when all references are released:
+// the event has been processed and all its micro-batched parts (one per table) have been written.
+// It is used under the worker processing lock
+type eventAck struct {
+	refs int
+	ack  func()
+}
+
+func newEventAck(ack func()) *eventAck {
+	return &eventAck{refs: 1, ack: ack}
+}
+
+func (ea *eventAck) retain() {
+	if ea != nil {
+		ea.refs++
+	}
+}
+
+func (ea *eventAck) release() {
+	if ea == nil {
+		return
+	}
+	ea.refs--
+	if ea.refs == 0 && ea.ack != nil {
+		ea.ack()
+	}
+}
+
 // StreamingWorker reads events from queue and using events.StreamingStorage writes them
 type StreamingWorker struct {
 	eventQueue       events.Queue
 	streamingStorage StreamingStorage
 	tableHelper      []*TableHelper
+	circuitBreaker   *CircuitBreaker
+	logger           *logging.Logger
+
+	//batchStorage is nil if stream mode micro-batching is disabled
+	batchStorage  StreamingBatchStorage
+	batchSize     int
+	flushInterval time.Duration
+	//pending micro-batches per table name
+	pending map[string]*pendingBatch
+	//catchingUp is shared between the destination workers. Micro-batches are catchUpBatchSize while the destination catches up
+	catchingUp       *atomic.Bool
+	catchUpBatchSize int
 
-	closed *atomic.Bool
+	//processing is locked while a dequeued event is being written or pending batches are being flushed. Close waits for it
+	processing sync.Mutex
+	closed     *atomic.Bool
 }
 
 // newStreamingWorker returns configured streaming worker
-func newStreamingWorker(eventQueue events.Queue, streamingStorage StreamingStorage, tableHelper ...*TableHelper) *StreamingWorker {
-	return &StreamingWorker{
+// micro-batching is enabled if streamBatch is enabled and streamingStorage supports batches
+func newStreamingWorker(eventQueue events.Queue, streamingStorage StreamingStorage, circuitBreaker *CircuitBreaker, streamBatch *config.StreamBatch, tableHelper ...*TableHelper) *StreamingWorker {
+	sw := &StreamingWorker{
 		eventQueue:       eventQueue,
 		streamingStorage: streamingStorage,
 		tableHelper:      tableHelper,
+		circuitBreaker:   circuitBreaker,
+		logger:           logging.Component(StreamingComponent).WithDestination(streamingStorage.ID()).With("generation", streamingStorage.Generation()),
+		catchingUp:       atomic.NewBool(false),
+		closed:           atomic.NewBool(false),
+	}
+
+	if batchStorage, ok := streamingStorage.(StreamingBatchStorage); ok && streamBatch.IsEnabled() {
+		sw.batchStorage = batchStorage
+		sw.batchSize = streamBatch.Size
+		sw.catchUpBatchSize = streamBatch.Size
+		sw.flushInterval = streamBatch.FlushInterval()
+		sw.pending = map[string]*pendingBatch{}
+	}
+
+	return sw
+}
+
+// clone returns a new worker with the same configuration, circuit breaker and catch-up state. It is used for catch-up workers
+func (sw *StreamingWorker) clone() *StreamingWorker {
+	return &StreamingWorker{
+		eventQueue:       sw.eventQueue,
+		streamingStorage: sw.streamingStorage,
+		tableHelper:      sw.tableHelper,
+		circuitBreaker:   sw.circuitBreaker,
+		logger:           sw.logger,
+		batchStorage:     sw.batchStorage,
+		batchSize:        sw.batchSize,
+		flushInterval:    sw.flushInterval,
+		pending:          map[string]*pendingBatch{},
+		catchingUp:       sw.catchingUp,
+		catchUpBatchSize: sw.catchUpBatchSize,
 		closed:           atomic.NewBool(false),
 	}
 }
 
-// newStreamingWorkers returns configured streaming workers
-func newStreamingWorkers(eventQueue events.Queue, streamingStorage StreamingStorage, workersCount int, tableHelper ...*TableHelper) []*StreamingWorker {
+// newStreamingWorkers returns configured streaming workers with one shared destination circuit breaker
+func newStreamingWorkers(eventQueue events.Queue, streamingStorage StreamingStorage, workersCount int, streamBatch *config.StreamBatch, tableHelper ...*TableHelper) []*StreamingWorker {
+	circuitBreaker := NewCircuitBreaker(streamingStorage.ID(), appconfig.Instance.CircuitBreakerFailureThreshold,
+		time.Duration(appconfig.Instance.CircuitBreakerProbeIntervalSec)*time.Second)
+	catchingUp := atomic.NewBool(false)
 	workers := make([]*StreamingWorker, workersCount)
 	for i := 0; i < workersCount; i++ {
-		workers[i] = newStreamingWorker(eventQueue, streamingStorage, tableHelper...)
+		workers[i] = newStreamingWorker(eventQueue, streamingStorage, circuitBreaker, streamBatch, tableHelper...)
+		workers[i].catchingUp = catchingUp
+		if multiplier := appconfig.Instance.CatchUp.BatchSizeMultiplier; multiplier > 1 {
+			workers[i].catchUpBatchSize = workers[i].batchSize * multiplier
+		}
 	}
 	return workers
 }
@@ -61,7 +166,14 @@ func newStreamingWorkers(eventQueue events.Queue, streamingStorage StreamingStor
 // Run goroutine to:
 // 1. read from queue
 // 2. Insert in events.StreamingStorage
+// events aren't read from the queue while circuit breaker is open
 func (sw *StreamingWorker) start() {
+	circuitBreakers.register(sw.circuitBreaker)
+	sw.run()
+}
+
+// run starts reading the queue (and flushing micro-batches) without circuit breaker registration
+func (sw *StreamingWorker) run() {
 	safego.RunWithRestart(func() {
 		for {
 			if sw.streamingStorage.IsStaging() {
@@ -70,134 +182,370 @@ func (sw *StreamingWorker) start() {
 			if sw.closed.Load() {
 				break
 			}
+			if !sw.circuitBreaker.Allow() {
+				time.Sleep(time.Second)
+				continue
+			}
 
-			fact, dequeuedTime, tokenID, err := sw.eventQueue.DequeueBlock()
+			fact, dequeuedTime, tokenID, ack, err := sw.dequeue()
 			if err != nil {
 				if err == events.ErrQueueClosed && sw.closed.Load() {
 					continue
 				}
-				logging.SystemErrorf("[%s] Error reading event from queue: %v", sw.streamingStorage.ID(), err)
+				sw.logger.SystemErrorf("[%s] Error reading event from queue: %v", sw.streamingStorage.ID(), err)
 				time.Sleep(time.Second)
 				continue
 			}
 
-			//dequeued event was from retry call and retry timeout hasn't come
-			if timestamp.Now().Before(dequeuedTime) {
-				sw.eventQueue.ConsumeTimed(fact, dequeuedTime, tokenID)
-				continue
-			}
-			_, recognizedEvent := fact[schema.JitsuUserRecognizedEvent]
-			if recognizedEvent && !sw.streamingStorage.GetUsersRecognition().IsEnabled() {
-				//skip recognized event for storages with disabled/not supported UR
-				continue
+			if !sw.handle(fact, dequeuedTime, tokenID, newEventAck(ack)) {
+				break
 			}
+		}
+	})
 
-			//is used in writing counters/metrics/events cache
-			preliminaryEventContext := &adapters.EventContext{
-				CacheDisabled:   sw.streamingStorage.IsCachingDisabled(),
-				DestinationID:   sw.streamingStorage.ID(),
-				EventID:         sw.streamingStorage.GetUniqueIDField().Extract(fact),
-				TokenID:         tokenID,
-				Src:             events.ExtractSrc(fact),
-				RawEvent:        fact,
-				RecognizedEvent: recognizedEvent,
-			}
+	if sw.batchStorage != nil {
+		safego.RunWithRestart(sw.flushPeriodically)
+	}
+}
 
-			envelops, err := sw.streamingStorage.Processor().ProcessEvent(fact, true)
-			if err != nil && !recognizedEvent {
-				if err == schema.ErrSkipObject {
-					if !appconfig.Instance.DisableSkipEventsWarn {
-						logging.Warnf("[%s] Event [%s]: %v", sw.streamingStorage.ID(), sw.streamingStorage.GetUniqueIDField().Extract(fact), err)
-					}
+// dequeue returns the next event with ack func. Events are acknowledged after processing if the queue supports it
+func (sw *StreamingWorker) dequeue() (events.Event, time.Time, string, func(), error) {
+	if ackQueue, ok := sw.eventQueue.(events.AckQueue); ok {
+		return ackQueue.DequeueBlockWithAck()
+	}
 
-					sw.streamingStorage.SkipEvent(preliminaryEventContext, err)
-				} else {
-					logging.Debugf("[%s] Unable to process object %s: %v", sw.streamingStorage.ID(), fact.DebugString(), err)
-					sw.streamingStorage.ErrorEvent(true, preliminaryEventContext, err)
-				}
+	fact, dequeuedTime, tokenID, err := sw.eventQueue.DequeueBlock()
+	return fact, dequeuedTime, tokenID, nil, err
+}
 
-				continue
+// flushPeriodically writes pending micro-batches every flush interval until the worker is closed
+func (sw *StreamingWorker) flushPeriodically() {
+	ticker := time.NewTicker(sw.flushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if sw.closed.Load() {
+			return
+		}
+
+		sw.flushPending()
+	}
+}
+
+// flushPending writes pending micro-batches under the processing lock
+func (sw *StreamingWorker) flushPending() {
+	sw.processing.Lock()
+	defer sw.processing.Unlock()
+
+	sw.flushAll()
+}
+
+//handle processes the dequeued event under the processing lock. If the worker has been closed while waiting for the event
+//(e.g. the destination configuration has been reloaded), puts the event back into the queue for the workers of the new
+//storage version which read the same queue and returns false. The event is acknowledged in the queue after processing
+func (sw *StreamingWorker) handle(fact events.Event, dequeuedTime time.Time, tokenID string, ack *eventAck) bool {
+	sw.processing.Lock()
+	defer sw.processing.Unlock()
+	defer ack.release()
+
+	if sw.closed.Load() {
+		sw.eventQueue.ConsumeTimed(fact, dequeuedTime, tokenID)
+		return false
+	}
+
+	//dequeued event was from retry call and retry timeout hasn't come
+	if timestamp.Now().Before(dequeuedTime) {
+		sw.eventQueue.ConsumeTimed(fact, dequeuedTime, tokenID)
+		return true
+	}
+	_, recognizedEvent := fact[schema.JitsuUserRecognizedEvent]
+	if recognizedEvent && !sw.streamingStorage.GetUsersRecognition().IsEnabled() {
+		//skip recognized event for storages with disabled/not supported UR
+		return true
+	}
+
+	sw.process(fact, tokenID, recognizedEvent, ack)
+	return true
+}
+
+//process writes the event into the destination. Writes span with the event trace context as a parent
+func (sw *StreamingWorker) process(fact events.Event, tokenID string, recognizedEvent bool, ack *eventAck) {
+	eventCtx := tracing.ExtractEvent(fact)
+	sw.observeLag(eventCtx, fact)
+	ctx, span := tracing.Start(eventCtx, "jitsu.destination.stream",
+		attribute.String("jitsu.destination_id", sw.streamingStorage.ID()),
+		attribute.String("jitsu.destination_type", sw.streamingStorage.Type()))
+	defer span.End()
+
+	//is used in writing counters/metrics/events cache
+	preliminaryEventContext := &adapters.EventContext{
+		CacheDisabled:   sw.streamingStorage.IsCachingDisabled(),
+		DestinationID:   sw.streamingStorage.ID(),
+		EventID:         sw.streamingStorage.GetUniqueIDField().Extract(fact),
+		TokenID:         tokenID,
+		Src:             events.ExtractSrc(fact),
+		RawEvent:        fact,
+		RecognizedEvent: recognizedEvent,
+	}
+
+	logger := sw.logger.WithEventID(preliminaryEventContext.EventID)
+	envelops, err := sw.streamingStorage.Processor().ProcessEventContext(ctx, fact, true)
+	if err != nil && !recognizedEvent {
+		if err == schema.ErrSkipObject {
+			if !appconfig.Instance.DisableSkipEventsWarn {
+				logger.Warnf("[%s] Event [%s]: %v", sw.streamingStorage.ID(), sw.streamingStorage.GetUniqueIDField().Extract(fact), err)
 			}
-			for _, envelop := range envelops {
-				batchHeader := envelop.Header
-				flattenObject := envelop.Event
-				//don't process empty object
-				if !batchHeader.Exists() {
-					continue
-				}
-				var table *adapters.Table
-				tableHelper := sw.getTableHelper()
-				if tableHelper != nil {
-					table = tableHelper.MapTableSchema(batchHeader)
+
+			sw.streamingStorage.SkipEvent(preliminaryEventContext, err)
+			span.SetAttributes(attribute.Bool("jitsu.skipped", true))
+		} else {
+			logger.Debugf("[%s] Unable to process object %s: %v", sw.streamingStorage.ID(), fact.DebugString(), err)
+			metrics.DestinationErrors(sw.streamingStorage.Type(), sw.streamingStorage.ID(), metrics.ErrorClassProcessing, 1)
+			sw.streamingStorage.ErrorEvent(true, preliminaryEventContext, err)
+			tracing.SetError(span, err)
+		}
+
+		return
+	}
+	for _, envelop := range envelops {
+		batchHeader := envelop.Header
+		flattenObject := envelop.Event
+		//don't process empty object
+		if !batchHeader.Exists() {
+			continue
+		}
+		var table *adapters.Table
+		tableHelper := sw.getTableHelper()
+		if tableHelper != nil {
+			table = tableHelper.MapTableSchema(batchHeader)
+		}
+		eventContext := &adapters.EventContext{
+			CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
+			DestinationID: sw.streamingStorage.ID(),
+			EventID: utils.NvlString(sw.streamingStorage.GetUniqueIDField().Extract(flattenObject),
+				sw.streamingStorage.GetUniqueIDField().Extract(fact)),
+			TokenID:         tokenID,
+			Src:             events.ExtractSrc(fact),
+			RawEvent:        fact,
+			ProcessedEvent:  flattenObject,
+			Table:           table,
+			RecognizedEvent: recognizedEvent,
+		}
+		if recognizedEvent {
+			_, updateSpan := tracing.Start(ctx, "jitsu.destination.update")
+			updateErr := sw.streamingStorage.Update(eventContext)
+			tracing.End(updateSpan, updateErr)
+			if updateErr != nil {
+				err := errorj.Decorate(updateErr, "failed to update event").
+					WithProperty(errorj.DestinationID, sw.streamingStorage.ID()).
+					WithProperty(errorj.DestinationType, sw.streamingStorage.Type())
+				metrics.DestinationErrors(sw.streamingStorage.Type(), sw.streamingStorage.ID(), ErrorClass(err), 1)
+
+				var retryInfoInLog string
+				retry := IsConnectionError(err)
+				if retry {
+					retryInfoInLog = "connection problem. event will be re-updated after 20 seconds\n"
 				}
-				eventContext := &adapters.EventContext{
-					CacheDisabled: sw.streamingStorage.IsCachingDisabled(),
-					DestinationID: sw.streamingStorage.ID(),
-					EventID: utils.NvlString(sw.streamingStorage.GetUniqueIDField().Extract(flattenObject),
-						sw.streamingStorage.GetUniqueIDField().Extract(fact)),
-					TokenID:         tokenID,
-					Src:             events.ExtractSrc(fact),
-					RawEvent:        fact,
-					ProcessedEvent:  flattenObject,
-					Table:           table,
-					RecognizedEvent: recognizedEvent,
+				if errorj.IsSystemError(err) {
+					logger.SystemErrorf("%+v\n%sorigin event: %s", err, retryInfoInLog, flattenObject.DebugString())
+				} else {
+					logger.Errorf("%+v\n%sorigin event: %s", err, retryInfoInLog, flattenObject.DebugString())
 				}
-				if recognizedEvent {
-					if updateErr := sw.streamingStorage.Update(eventContext); updateErr != nil {
-						err := errorj.Decorate(updateErr, "failed to update event").
-							WithProperty(errorj.DestinationID, sw.streamingStorage.ID()).
-							WithProperty(errorj.DestinationType, sw.streamingStorage.Type())
-
-						var retryInfoInLog string
-						retry := IsConnectionError(err)
-						if retry {
-							retryInfoInLog = "connection problem. event will be re-updated after 20 seconds\n"
-						}
-						if errorj.IsSystemError(err) {
-							logging.SystemErrorf("%+v\n%sorigin event: %s", err, retryInfoInLog, flattenObject.DebugString())
-						} else {
-							logging.Errorf("%+v\n%sorigin event: %s", err, retryInfoInLog, flattenObject.DebugString())
-						}
-
-						if retry {
-							//retry
-							sw.eventQueue.ConsumeTimed(fact, timestamp.Now().Add(20*time.Second), tokenID)
-						}
-					}
+
+				if retry {
+					//retry
+					sw.eventQueue.ConsumeTimed(fact, timestamp.Now().Add(20*time.Second), tokenID)
+					sw.circuitBreaker.Failure(err)
 				} else {
-					if insertErr := sw.streamingStorage.Insert(eventContext); insertErr != nil {
-						err := errorj.Decorate(insertErr, "failed to insert event").
-							WithProperty(errorj.DestinationID, sw.streamingStorage.ID()).
-							WithProperty(errorj.DestinationType, sw.streamingStorage.Type())
-
-						var retryInfoInLog string
-						retry := IsConnectionError(err)
-						if retry {
-							retryInfoInLog = "connection problem. event will be re-inserted after 20 seconds\n"
-						}
-						if errorj.IsSystemError(err) {
-							logging.SystemErrorf("%+v\n%sorigin event: %s", err, retryInfoInLog, flattenObject.DebugString())
-						} else if logging.LogLevel == logging.DEBUG {
-							logging.Debugf("%+v\n%sorigin event: %s", err, retryInfoInLog, flattenObject.DebugString())
-						}
-
-						if retry {
-							//retry
-							sw.eventQueue.ConsumeTimed(fact, timestamp.Now().Add(20*time.Second), tokenID)
-						}
-					}
+					sw.circuitBreaker.Success()
 				}
+			} else {
+				sw.circuitBreaker.Success()
 			}
+		} else if sw.batchStorage != nil && table != nil {
+			sw.addToBatch(eventContext, ack)
+		} else {
+			sw.insert(ctx, logger, eventContext)
 		}
-	})
+	}
+}
+
+//insert writes a single event into the destination. Puts the event back into the queue with the retry timeout on connection errors
+func (sw *StreamingWorker) insert(ctx context.Context, logger *logging.Logger, eventContext *adapters.EventContext) {
+	fact, tokenID, flattenObject := eventContext.RawEvent, eventContext.TokenID, eventContext.ProcessedEvent
+	_, insertSpan := tracing.Start(ctx, "jitsu.destination.insert")
+	insertErr := sw.streamingStorage.Insert(eventContext)
+	tracing.End(insertSpan, insertErr)
+	if insertErr != nil {
+		err := errorj.Decorate(insertErr, "failed to insert event").
+			WithProperty(errorj.DestinationID, sw.streamingStorage.ID()).
+			WithProperty(errorj.DestinationType, sw.streamingStorage.Type())
+		metrics.DestinationErrors(sw.streamingStorage.Type(), sw.streamingStorage.ID(), ErrorClass(err), 1)
+
+		var retryInfoInLog string
+		retry := IsConnectionError(err)
+		if retry {
+			retryInfoInLog = "connection problem. event will be re-inserted after 20 seconds\n"
+		}
+		if errorj.IsSystemError(err) {
+			logger.SystemErrorf("%+v\n%sorigin event: %s", err, retryInfoInLog, flattenObject.DebugString())
+		} else if logger.IsDebugEnabled() {
+			logger.Debugf("%+v\n%sorigin event: %s", err, retryInfoInLog, flattenObject.DebugString())
+		}
+
+		if retry {
+			//retry
+			sw.eventQueue.ConsumeTimed(fact, timestamp.Now().Add(20*time.Second), tokenID)
+			sw.circuitBreaker.Failure(err)
+		} else {
+			sw.circuitBreaker.Success()
+		}
+	} else {
+		sw.circuitBreaker.Success()
+	}
+}
+
+//addToBatch appends the event to the pending micro-batch of its table and writes the batch if it is full.
+//Pending batch is written before adding the event if a column type of the event differs from the batch one.
+//The dequeued event is acknowledged after the batch is written (ack might be nil)
+func (sw *StreamingWorker) addToBatch(eventContext *adapters.EventContext, ack *eventAck) {
+	tableName := eventContext.Table.Name
+	batch, ok := sw.pending[tableName]
+	if ok && !batch.compatible(eventContext.Table) {
+		sw.flush(tableName)
+		ok = false
+	}
+	if !ok {
+		batch = &pendingBatch{table: eventContext.Table.Clone()}
+		batch.table.Partition = eventContext.Table.Partition
+		sw.pending[tableName] = batch
+	}
+
+	for name, column := range eventContext.Table.Columns {
+		batch.table.Columns[name] = column
+	}
+	batch.eventContexts = append(batch.eventContexts, eventContext)
+	ack.retain()
+	batch.acks = append(batch.acks, ack)
+
+	if len(batch.eventContexts) >= sw.currentBatchSize() {
+		sw.flush(tableName)
+	}
+}
+
+//flushAll writes all pending micro-batches
+func (sw *StreamingWorker) flushAll() {
+	for tableName := range sw.pending {
+		sw.flush(tableName)
+	}
 }
 
+//flush writes pending micro-batch of the table with one insert.
+//On connection errors all events are put back into the queue with the retry timeout,
+//on other errors (e.g. a value of a single event can't be written) events are inserted one by one
+func (sw *StreamingWorker) flush(tableName string) {
+	batch, ok := sw.pending[tableName]
+	if !ok {
+		return
+	}
+	delete(sw.pending, tableName)
+	defer batch.release()
+
+	_, span := tracing.Start(context.Background(), "jitsu.destination.insert_batch",
+		attribute.String("jitsu.destination_id", sw.streamingStorage.ID()),
+		attribute.String("jitsu.destination_type", sw.streamingStorage.Type()),
+		attribute.Int("jitsu.batch_size", len(batch.eventContexts)))
+	insertErr := sw.batchStorage.InsertBatch(batch.table, batch.eventContexts)
+	tracing.End(span, insertErr)
+	if insertErr == nil {
+		sw.circuitBreaker.Success()
+		return
+	}
+
+	err := errorj.Decorate(insertErr, "failed to insert batch of %d events", len(batch.eventContexts)).
+		WithProperty(errorj.DestinationID, sw.streamingStorage.ID()).
+		WithProperty(errorj.DestinationType, sw.streamingStorage.Type())
+	if !IsConnectionError(err) {
+		sw.logger.Debugf("%+v\nevents will be inserted one by one", err)
+		for _, eventContext := range batch.eventContexts {
+			sw.insert(context.Background(), sw.logger.WithEventID(eventContext.EventID), eventContext)
+		}
+		return
+	}
+
+	metrics.DestinationErrors(sw.streamingStorage.Type(), sw.streamingStorage.ID(), ErrorClass(err), len(batch.eventContexts))
+	if errorj.IsSystemError(err) {
+		sw.logger.SystemErrorf("%+v\nconnection problem. %d events will be re-inserted after 20 seconds", err, len(batch.eventContexts))
+	} else {
+		sw.logger.Errorf("%+v\nconnection problem. %d events will be re-inserted after 20 seconds", err, len(batch.eventContexts))
+	}
+	retryTime := timestamp.Now().Add(20 * time.Second)
+	for _, eventContext := range batch.eventContexts {
+		sw.streamingStorage.ErrorEvent(false, eventContext, err)
+		sw.eventQueue.ConsumeTimed(eventContext.RawEvent, retryTime, eventContext.TokenID)
+	}
+	sw.circuitBreaker.Failure(err)
+}
+
+//release releases acks of all batch events
+func (pb *pendingBatch) release() {
+	for _, ack := range pb.acks {
+		ack.release()
+	}
+}
+
+//compatible returns false if any of table columns has a different type in the batch
+func (pb *pendingBatch) compatible(table *adapters.Table) bool {
+	for name, column := range table.Columns {
+		if batchColumn, ok := pb.table.Columns[name]; ok && batchColumn != column {
+			return false
+		}
+	}
+	return true
+}
+
+//Close stops the worker, waits for the in-flight event writing and writes pending micro-batches
 func (sw *StreamingWorker) Close() error {
-	sw.closed.Store(true)
+	sw.stop()
+	circuitBreakers.unregister(sw.circuitBreaker)
 
 	return nil
 }
 
+// stop stops reading the queue and writes pending micro-batches. The shared circuit breaker stays registered
+func (sw *StreamingWorker) stop() {
+	sw.closed.Store(true)
+	sw.processing.Lock()
+	if sw.batchStorage != nil {
+		sw.flushAll()
+	}
+	sw.processing.Unlock()
+}
+
+// currentBatchSize returns micro-batch size: it is larger while the destination catches up
+func (sw *StreamingWorker) currentBatchSize() int {
+	if sw.catchingUp.Load() {
+		return sw.catchUpBatchSize
+	}
+
+	return sw.batchSize
+}
+
+// observeLag writes time between event receiving (_timestamp) and dequeuing to metrics and as a queue span
+func (sw *StreamingWorker) observeLag(ctx context.Context, fact events.Event) {
+	ts, ok := fact[timestamp.Key].(string)
+	if !ok {
+		return
+	}
+	receivedAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return
+	}
+
+	metrics.StreamingLag(sw.streamingStorage.Type(), sw.streamingStorage.ID(), timestamp.Now().Sub(receivedAt).Seconds())
+	_, queueSpan := tracing.StartAt(ctx, "jitsu.queue", receivedAt, attribute.String("jitsu.destination_id", sw.streamingStorage.ID()))
+	queueSpan.End()
+}
+
 func (sw *StreamingWorker) getTableHelper() *TableHelper {
 	length := len(sw.tableHelper)
 	if length == 0 {
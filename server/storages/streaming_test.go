@@ -0,0 +1,163 @@
+package storages
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+// batchStorageMock records inserted batches and single events. Returns insertBatchErr from InsertBatch
+type batchStorageMock struct {
+	StreamingStorage
+
+	mutex          sync.Mutex
+	batches        [][]string
+	batchTables    []*adapters.Table
+	inserted       []string
+	failed         []string
+	insertBatchErr error
+}
+
+func (bsm *batchStorageMock) ID() string              { return "batch_destination" }
+func (bsm *batchStorageMock) Type() string            { return PostgresType }
+func (bsm *batchStorageMock) Generation() int64       { return 1 }
+func (bsm *batchStorageMock) IsStaging() bool         { return false }
+func (bsm *batchStorageMock) IsCachingDisabled() bool { return true }
+
+func (bsm *batchStorageMock) InsertBatch(table *adapters.Table, eventContexts []*adapters.EventContext) error {
+	bsm.mutex.Lock()
+	defer bsm.mutex.Unlock()
+	if bsm.insertBatchErr != nil {
+		return bsm.insertBatchErr
+	}
+
+	var ids []string
+	for _, eventContext := range eventContexts {
+		ids = append(ids, eventContext.EventID)
+	}
+	bsm.batches = append(bsm.batches, ids)
+	bsm.batchTables = append(bsm.batchTables, table)
+	return nil
+}
+
+func (bsm *batchStorageMock) Insert(eventContext *adapters.EventContext) error {
+	bsm.mutex.Lock()
+	defer bsm.mutex.Unlock()
+	bsm.inserted = append(bsm.inserted, eventContext.EventID)
+	return nil
+}
+
+func (bsm *batchStorageMock) ErrorEvent(fallback bool, eventCtx *adapters.EventContext, err error) {
+	bsm.mutex.Lock()
+	defer bsm.mutex.Unlock()
+	bsm.failed = append(bsm.failed, eventCtx.EventID)
+}
+
+// retryQueueMock records events which are put back into the queue
+type retryQueueMock struct {
+	events.Queue
+
+	retried []events.Event
+}
+
+func (rqm *retryQueueMock) ConsumeTimed(f map[string]interface{}, t time.Time, tokenID string) {
+	rqm.retried = append(rqm.retried, f)
+}
+
+func newBatchEventContext(eventID, tableName string, columns adapters.Columns) *adapters.EventContext {
+	return &adapters.EventContext{
+		EventID:        eventID,
+		RawEvent:       events.Event{"eventn_ctx_event_id": eventID},
+		ProcessedEvent: events.Event{"eventn_ctx_event_id": eventID},
+		Table:          &adapters.Table{Name: tableName, Columns: columns},
+	}
+}
+
+func TestStreamingWorkerBatches(t *testing.T) {
+	storage := &batchStorageMock{}
+	queue := &retryQueueMock{}
+	sw := newStreamingWorker(queue, storage, NewCircuitBreaker(storage.ID(), 0, time.Minute),
+		&config.StreamBatch{Size: 3, FlushIntervalMs: 100}, &TableHelper{})
+
+	text := typing.SQLColumn{Type: "text"}
+	bigint := typing.SQLColumn{Type: "bigint"}
+
+	sw.addToBatch(newBatchEventContext("1", "events", adapters.Columns{"a": text}), nil)
+	sw.addToBatch(newBatchEventContext("2", "pages", adapters.Columns{"a": text}), nil)
+	sw.addToBatch(newBatchEventContext("3", "events", adapters.Columns{"b": bigint}), nil)
+	require.Empty(t, storage.batches, "batches aren't full")
+
+	//full batch is written with merged columns
+	sw.addToBatch(newBatchEventContext("4", "events", adapters.Columns{"a": text, "c": text}), nil)
+	require.Equal(t, [][]string{{"1", "3", "4"}}, storage.batches)
+	require.Equal(t, adapters.Columns{"a": text, "b": bigint, "c": text}, storage.batchTables[0].Columns)
+
+	//column type conflict writes pending batch before adding the event
+	sw.addToBatch(newBatchEventContext("5", "pages", adapters.Columns{"a": bigint}), nil)
+	require.Equal(t, [][]string{{"1", "3", "4"}, {"2"}}, storage.batches)
+
+	//Close writes all pending batches
+	require.NoError(t, sw.Close())
+	require.Equal(t, [][]string{{"1", "3", "4"}, {"2"}, {"5"}}, storage.batches)
+	require.Empty(t, storage.inserted)
+	require.Empty(t, queue.retried)
+}
+
+func TestStreamingWorkerBatchErrors(t *testing.T) {
+	storage := &batchStorageMock{insertBatchErr: errors.New("pq: invalid input syntax for type bigint")}
+	queue := &retryQueueMock{}
+	sw := newStreamingWorker(queue, storage, NewCircuitBreaker(storage.ID(), 0, time.Minute),
+		&config.StreamBatch{Size: 2}, &TableHelper{})
+
+	//events are inserted one by one if the batch can't be written
+	sw.addToBatch(newBatchEventContext("1", "events", adapters.Columns{}), nil)
+	sw.addToBatch(newBatchEventContext("2", "events", adapters.Columns{}), nil)
+	require.Equal(t, []string{"1", "2"}, storage.inserted)
+	require.Empty(t, queue.retried)
+
+	//events are put back into the queue on connection errors
+	storage.insertBatchErr = errors.New("dial tcp: connection refused")
+	sw.addToBatch(newBatchEventContext("3", "events", adapters.Columns{}), nil)
+	sw.addToBatch(newBatchEventContext("4", "events", adapters.Columns{}), nil)
+	require.Equal(t, []string{"1", "2"}, storage.inserted)
+	require.Equal(t, []string{"3", "4"}, storage.failed)
+	require.Equal(t, []events.Event{{"eventn_ctx_event_id": "3"}, {"eventn_ctx_event_id": "4"}}, queue.retried)
+}
+
+func TestStreamingWorkerBatchesDisabled(t *testing.T) {
+	storage := &batchStorageMock{}
+	circuitBreaker := NewCircuitBreaker(storage.ID(), 0, time.Minute)
+
+	require.Nil(t, newStreamingWorker(&retryQueueMock{}, storage, circuitBreaker, nil).batchStorage)
+	require.Nil(t, newStreamingWorker(&retryQueueMock{}, storage, circuitBreaker, &config.StreamBatch{Size: 1}).batchStorage)
+	require.NotNil(t, newStreamingWorker(&retryQueueMock{}, storage, circuitBreaker, &config.StreamBatch{Size: 100}).batchStorage)
+}
+
+func TestStreamingWorkerBatchAcks(t *testing.T) {
+	storage := &batchStorageMock{}
+	sw := newStreamingWorker(&retryQueueMock{}, storage, NewCircuitBreaker(storage.ID(), 0, time.Minute),
+		&config.StreamBatch{Size: 10}, &TableHelper{})
+
+	acked := 0
+	ack := newEventAck(func() { acked++ })
+
+	//one dequeued event is written into two tables
+	sw.addToBatch(newBatchEventContext("1", "events", adapters.Columns{}), ack)
+	sw.addToBatch(newBatchEventContext("1", "pages", adapters.Columns{}), ack)
+	//processing is finished
+	ack.release()
+	require.Equal(t, 0, acked, "event mustn't be acknowledged before batches are written")
+
+	sw.flush("events")
+	require.Equal(t, 0, acked)
+
+	sw.flush("pages")
+	require.Equal(t, 1, acked)
+}
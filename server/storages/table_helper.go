@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/locks"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,11 +14,24 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/notifications"
 	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/schemaregistry"
 	"github.com/jitsucom/jitsu/server/typing"
 )
 
 const tableLockTimeout = time.Minute
 
+//anyTableLayout is a key of table layout which is applied to all tables without own layout
+const anyTableLayout = "*"
+
+const (
+	//ColumnTypesMigrationDisabled - existing columns types are never changed
+	ColumnTypesMigrationDisabled = ""
+	//ColumnTypesMigrationDryRun - required columns types changes are only logged
+	ColumnTypesMigrationDryRun = "dry_run"
+	//ColumnTypesMigrationEnabled - existing columns types are widened if the destination supports it
+	ColumnTypesMigrationEnabled = "enabled"
+)
+
 // TableHelper keeps tables schema state inmemory and update it according to incoming new data
 // consider that all tables are in one destination schema.
 // note: Assume that after any outer changes in db we need to increment table version in Service
@@ -27,8 +42,21 @@ type TableHelper struct {
 	coordinationService *coordination.Service
 	tables              map[string]*adapters.Table
 
-	pkFields           map[string]bool
+	pkFields map[string]bool
+	//tablePKFields are primary keys of particular tables which are used instead of pkFields
+	tablePKFields      map[string]map[string]bool
 	columnTypesMapping map[typing.DataType]string
+	//columnTypes are explicit column SQL types from the destination configuration
+	columnTypes typing.SQLTypes
+	//columnTypesMigration is a mode of widening existing columns types (see ColumnTypesMigration* constants)
+	columnTypesMigration string
+	//sqlToDataType is a reverse columnTypesMapping: SQL type without length -> data type
+	sqlToDataType map[string]typing.DataType
+	//reportedWidenings are columns types changes which have been only logged (dry run or unsupported by the destination):
+	//table name -> column name -> new SQL type. They aren't reported (and don't cause table patching) again
+	reportedWidenings map[string]map[string]string
+	//tableLayouts are partitioning and clustering configurations per table name (or anyTableLayout)
+	tableLayouts map[string]*adapters.TableLayout
 
 	dbSchema        string
 	destinationType string
@@ -48,6 +76,8 @@ func NewTableHelper(dbSchema string, sqlAdapter adapters.SQLAdapter, coordinatio
 
 		pkFields:           pkFields,
 		columnTypesMapping: columnTypesMapping,
+		sqlToDataType:      sqlToDataTypes(columnTypesMapping),
+		reportedWidenings:  map[string]map[string]string{},
 
 		dbSchema:        dbSchema,
 		destinationType: destinationType,
@@ -55,23 +85,71 @@ func NewTableHelper(dbSchema string, sqlAdapter adapters.SQLAdapter, coordinatio
 	}
 }
 
+// newTableHelper returns TableHelper configured with the destination data layout settings
+func newTableHelper(config *Config, dbSchema string, sqlAdapter adapters.SQLAdapter, columnTypesMapping map[typing.DataType]string, destinationType string) *TableHelper {
+	return NewTableHelper(dbSchema, sqlAdapter, config.coordinationService, config.pkFields, columnTypesMapping, config.maxColumns, destinationType).
+		WithTablePKFields(config.tablePKFields).
+		WithColumnTypes(config.columnTypes).
+		WithColumnTypesMigration(config.columnTypesMigration).
+		WithTableLayouts(config.tableLayouts)
+}
+
+// WithTablePKFields sets primary key fields of particular tables. Data in these tables is upserted (merged)
+// by the keys instead of pkFields
+func (th *TableHelper) WithTablePKFields(tablePKFields map[string]map[string]bool) *TableHelper {
+	th.tablePKFields = tablePKFields
+	return th
+}
+
+// WithColumnTypes sets explicit column SQL types which are used instead of inferred ones
+// during table creation and patching
+func (th *TableHelper) WithColumnTypes(columnTypes typing.SQLTypes) *TableHelper {
+	th.columnTypes = columnTypes
+	return th
+}
+
+// WithColumnTypesMigration sets mode of widening existing columns types when incoming data type changes
+// (e.g. bigint -> text). Changes are applied only if sqlAdapter implements adapters.ColumnTypeMigrator
+func (th *TableHelper) WithColumnTypesMigration(mode string) *TableHelper {
+	th.columnTypesMigration = mode
+	return th
+}
+
+// WithTableLayouts sets partitioning and clustering configurations which are applied on table creation
+func (th *TableHelper) WithTableLayouts(tableLayouts map[string]*adapters.TableLayout) *TableHelper {
+	th.tableLayouts = tableLayouts
+	return th
+}
+
 // MapTableSchema maps schema.BatchHeader (JSON structure with json data types) into adapters.Table (structure with SQL types)
 // applies column types mapping
 func (th *TableHelper) MapTableSchema(batchHeader *schema.BatchHeader) *adapters.Table {
+	pkFields := th.pkFields
+	if tablePKFields, ok := th.tablePKFields[batchHeader.TableName]; ok {
+		pkFields = tablePKFields
+	}
+
 	table := &adapters.Table{
 		Schema:    th.dbSchema,
 		Name:      batchHeader.TableName,
 		Columns:   adapters.Columns{},
 		Partition: batchHeader.Partition,
-		PKFields:  th.pkFields,
+		PKFields:  pkFields,
+		Layout:    th.tableLayout(batchHeader.TableName),
 	}
 
 	//pk fields from the configuration
-	if len(th.pkFields) > 0 {
+	if len(pkFields) > 0 {
 		table.PrimaryKeyName = adapters.BuildConstraintName(table.Schema, table.Name)
 	}
 
 	for fieldName, field := range batchHeader.Fields {
+		//explicit type from the configuration
+		if overriddenSQLType, ok := th.columnTypes[fieldName]; ok {
+			table.Columns[fieldName] = overriddenSQLType
+			continue
+		}
+
 		suggestedSQLType, ok := field.GetSuggestedSQLType(th.destinationType)
 		if ok {
 			table.Columns[fieldName] = suggestedSQLType
@@ -90,6 +168,14 @@ func (th *TableHelper) MapTableSchema(batchHeader *schema.BatchHeader) *adapters
 	return table
 }
 
+// tableLayout returns configured table layout or layout for all tables or nil
+func (th *TableHelper) tableLayout(tableName string) *adapters.TableLayout {
+	if layout, ok := th.tableLayouts[tableName]; ok {
+		return layout
+	}
+	return th.tableLayouts[anyTableLayout]
+}
+
 // EnsureTableWithCaching calls EnsureTable with cacheTable = true
 // it is used in stream destinations (because we don't have time to select table schema, but there is retry on error)
 func (th *TableHelper) EnsureTableWithCaching(destinationID string, dataSchema *adapters.Table) (*adapters.Table, error) {
@@ -123,7 +209,7 @@ func (th *TableHelper) EnsureTable(destinationID string, dataSchema *adapters.Ta
 
 	//if diff doesn't exist - do nothing
 	diff := dbSchema.Diff(dataSchema)
-	if !diff.Exists() {
+	if !diff.Exists() && len(th.columnsToWiden(dbSchema, dataSchema)) == 0 {
 		return dbSchema, nil
 	}
 
@@ -150,18 +236,25 @@ func (th *TableHelper) patchTableWithLock(destinationID string, dataSchema *adap
 	}
 	defer tableLock.Unlock()
 
-	dbSchema, err := th.getOrCreate(dataSchema)
+	dbSchema, err := th.getOrCreate(destinationID, dataSchema)
 	if err != nil {
 		return nil, err
 	}
 
 	//handle table schema local changes (patching was in another goroutine)
 	diff := dbSchema.Diff(dataSchema)
-	if !diff.Exists() {
+	columnsToWiden := th.columnsToWiden(dbSchema, dataSchema)
+	if !diff.Exists() && len(columnsToWiden) == 0 {
 		return dbSchema, nil
 	}
 
-	if err := th.sqlAdapter.PatchTableSchema(diff); err != nil {
+	if diff.Exists() {
+		if err := th.sqlAdapter.PatchTableSchema(diff); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := th.widenColumns(destinationID, dbSchema, columnsToWiden); err != nil {
 		return nil, err
 	}
 
@@ -181,10 +274,131 @@ func (th *TableHelper) patchTableWithLock(destinationID string, dataSchema *adap
 
 	// Save data schema to local cache
 	th.tables[dbSchema.Name] = dbSchema
+	schemaregistry.Observe(destinationID, dbSchema.Name, columnSQLTypes(dbSchema.Columns), false)
 
 	return dbSchema.Clone(), nil
 }
 
+// widenColumns changes columns types in the destination (or only logs changes in dry run mode)
+// and updates dbSchema with the new types
+func (th *TableHelper) widenColumns(destinationID string, dbSchema *adapters.Table, columnsToWiden adapters.Columns) error {
+	if len(columnsToWiden) == 0 {
+		return nil
+	}
+
+	migrator, ok := th.sqlAdapter.(adapters.ColumnTypeMigrator)
+	if th.columnTypesMigration == ColumnTypesMigrationDryRun || !ok {
+		reported, exists := th.reportedWidenings[dbSchema.Name]
+		if !exists {
+			reported = map[string]string{}
+			th.reportedWidenings[dbSchema.Name] = reported
+		}
+		for name, column := range columnsToWiden {
+			logging.Infof("[%s] [dry run] column [%s] type in table [%s] should be changed: %s -> %s", destinationID, name, dbSchema.Name, dbSchema.Columns[name].Type, column.DDLType())
+			reported[name] = normalizeSQLType(column.DDLType())
+		}
+		if !ok {
+			logging.Warnf("[%s] destination type [%s] doesn't support columns types migration", destinationID, th.destinationType)
+		}
+		return nil
+	}
+
+	for name, column := range columnsToWiden {
+		logging.Infof("[%s] changing column [%s] type in table [%s]: %s -> %s", destinationID, name, dbSchema.Name, dbSchema.Columns[name].Type, column.DDLType())
+		if err := migrator.AlterColumnType(dbSchema, name, column); err != nil {
+			return err
+		}
+		schemaregistry.ObserveColumnTypeChange(destinationID, dbSchema.Name, name, dbSchema.Columns[name].Type, column.DDLType())
+		dbSchema.Columns[name] = column
+	}
+
+	return nil
+}
+
+// columnsToWiden returns data schema columns which types are wider than existing db columns types
+// (e.g. bigint -> text, varchar(256) -> varchar(65535)). Explicitly configured types, unknown db types
+// and already reported changes are ignored
+func (th *TableHelper) columnsToWiden(dbSchema, dataSchema *adapters.Table) adapters.Columns {
+	if th.columnTypesMigration == ColumnTypesMigrationDisabled {
+		return nil
+	}
+
+	reported := th.reportedWidenings[dataSchema.Name]
+	result := adapters.Columns{}
+	for name, column := range dataSchema.Columns {
+		dbColumn, ok := dbSchema.Columns[name]
+		if !ok || column.Override || dbColumn.Override {
+			continue
+		}
+
+		dbType, newType := normalizeSQLType(dbColumn.Type), normalizeSQLType(column.DDLType())
+		if dbType == newType || reported[name] == newType {
+			continue
+		}
+
+		//string length growth: varchar(256) -> varchar(65535)
+		dbBase, dbLength := splitSQLTypeLength(dbType)
+		newBase, newLength := splitSQLTypeLength(newType)
+		if dbBase == newBase {
+			if dbLength > 0 && newLength > dbLength {
+				result[name] = column
+			}
+			continue
+		}
+
+		dbDataType, ok := th.sqlToDataType[dbBase]
+		if !ok {
+			continue
+		}
+		newDataType, ok := th.sqlToDataType[newBase]
+		if !ok {
+			continue
+		}
+
+		if newDataType != dbDataType && typing.GetCommonAncestorType(dbDataType, newDataType) == newDataType {
+			result[name] = column
+		}
+	}
+
+	return result
+}
+
+// sqlToDataTypes returns SQL type without length -> data type mapping
+func sqlToDataTypes(columnTypesMapping map[typing.DataType]string) map[string]typing.DataType {
+	sqlToDataType := map[string]typing.DataType{}
+	for dataType, sqlType := range columnTypesMapping {
+		if dataType != typing.UNKNOWN {
+			base, _ := splitSQLTypeLength(normalizeSQLType(sqlType))
+			sqlToDataType[base] = dataType
+		}
+	}
+	return sqlToDataType
+}
+
+// normalizeSQLType returns lower case SQL type without Nullable() wrapper and time zone suffix
+func normalizeSQLType(sqlType string) string {
+	sqlType = strings.ToLower(strings.TrimSpace(sqlType))
+	if strings.HasPrefix(sqlType, "nullable(") && strings.HasSuffix(sqlType, ")") {
+		sqlType = sqlType[len("nullable(") : len(sqlType)-1]
+	}
+	return strings.TrimSuffix(sqlType, " without time zone")
+}
+
+// splitSQLTypeLength returns SQL type without length and the length (0 if it isn't specified): varchar(256) -> varchar, 256
+func splitSQLTypeLength(sqlType string) (string, int) {
+	start := strings.Index(sqlType, "(")
+	if start < 0 || !strings.HasSuffix(sqlType, ")") {
+		return sqlType, 0
+	}
+
+	length, err := strconv.Atoi(sqlType[start+1 : len(sqlType)-1])
+	if err != nil {
+		return sqlType[:start], 0
+	}
+
+	return sqlType[:start], length
+}
+
 func (th *TableHelper) getCachedTableSchema(destinationName string, dataSchema *adapters.Table) (*adapters.Table, error) {
 	dbSchema, ok := th.tables[dataSchema.Name]
 
@@ -228,10 +442,11 @@ func (th *TableHelper) getOrCreateWithLock(destinationID string, dataSchema *ada
 	}
 	defer tableLock.Unlock()
 
-	return th.getOrCreate(dataSchema)
+	return th.getOrCreate(destinationID, dataSchema)
 }
 
-func (th *TableHelper) getOrCreate(dataSchema *adapters.Table) (*adapters.Table, error) {
+// getOrCreate returns db table schema (creates the table if it doesn't exist) and records it in the schema registry
+func (th *TableHelper) getOrCreate(destinationID string, dataSchema *adapters.Table) (*adapters.Table, error) {
 	//Get schema
 	dbTableSchema, err := th.sqlAdapter.GetTableSchema(dataSchema.Name)
 	if err != nil {
@@ -239,7 +454,8 @@ func (th *TableHelper) getOrCreate(dataSchema *adapters.Table) (*adapters.Table,
 	}
 
 	//create new
-	if !dbTableSchema.Exists() {
+	created := !dbTableSchema.Exists()
+	if created {
 		if err := th.sqlAdapter.CreateTable(dataSchema); err != nil {
 			return nil, err
 		}
@@ -251,9 +467,20 @@ func (th *TableHelper) getOrCreate(dataSchema *adapters.Table) (*adapters.Table,
 		dbTableSchema.PrimaryKeyName = dataSchema.PrimaryKeyName
 	}
 
+	schemaregistry.Observe(destinationID, dataSchema.Name, columnSQLTypes(dbTableSchema.Columns), created)
+
 	return dbTableSchema, nil
 }
 
+// columnSQLTypes returns column name -> SQL type
+func columnSQLTypes(columns adapters.Columns) map[string]string {
+	result := make(map[string]string, len(columns))
+	for name, column := range columns {
+		result[name] = column.DDLType()
+	}
+	return result
+}
+
 func (th *TableHelper) lockTable(destinationID, tableName, tableIdentifier string) (locks.Lock, error) {
 	tableLock := th.coordinationService.CreateLock(tableIdentifier)
 	locked, err := tableLock.TryLock(tableLockTimeout)
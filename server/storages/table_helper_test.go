@@ -6,6 +6,7 @@ import (
 
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
@@ -25,24 +26,35 @@ func TestMapTableSchema(t *testing.T) {
 		name               string
 		input              schema.BatchHeader
 		pkFields           map[string]bool
+		tablePKFields      map[string]map[string]bool
 		columnTypesMapping map[typing.DataType]string
+		columnTypes        typing.SQLTypes
 		expected           adapters.Table
 	}{
 		{
-			"Empty configuration => empty result columns",
-			schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{"field1": schema.NewField(typing.STRING)}},
-			map[string]bool{},
-			map[typing.DataType]string{},
-			adapters.Table{Schema: "test", Name: "test_table", Columns: adapters.Columns{}, PKFields: map[string]bool{}},
+			name:               "Empty configuration => empty result columns",
+			input:              schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{"field1": schema.NewField(typing.STRING)}},
+			pkFields:           map[string]bool{},
+			columnTypesMapping: map[typing.DataType]string{},
+			expected:           adapters.Table{Schema: "test", Name: "test_table", Columns: adapters.Columns{}, PKFields: map[string]bool{}},
 		},
 		{
-			"ok data type",
-			schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{"field1": schema.NewField(typing.STRING), "field2": schema.NewField(typing.STRING)}},
-			map[string]bool{"field1": true},
-			map[typing.DataType]string{typing.STRING: "text"},
-			adapters.Table{Schema: "test", Name: "test_table", Columns: adapters.Columns{"field1": typing.SQLColumn{Type: "text"}, "field2": typing.SQLColumn{Type: "text"}},
+			name:               "ok data type",
+			input:              schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{"field1": schema.NewField(typing.STRING), "field2": schema.NewField(typing.STRING)}},
+			pkFields:           map[string]bool{"field1": true},
+			columnTypesMapping: map[typing.DataType]string{typing.STRING: "text"},
+			expected: adapters.Table{Schema: "test", Name: "test_table", Columns: adapters.Columns{"field1": typing.SQLColumn{Type: "text"}, "field2": typing.SQLColumn{Type: "text"}},
 				PKFields: map[string]bool{"field1": true}, PrimaryKeyName: "test_test_table_pk"},
 		},
+		{
+			name:               "table primary key fields override destination ones",
+			input:              schema.BatchHeader{TableName: "users", Fields: schema.Fields{"field1": schema.NewField(typing.STRING), "user_id": schema.NewField(typing.STRING)}},
+			pkFields:           map[string]bool{"field1": true},
+			tablePKFields:      map[string]map[string]bool{"users": {"user_id": true}},
+			columnTypesMapping: map[typing.DataType]string{typing.STRING: "text"},
+			expected: adapters.Table{Schema: "test", Name: "users", Columns: adapters.Columns{"field1": typing.SQLColumn{Type: "text"}, "user_id": typing.SQLColumn{Type: "text"}},
+				PKFields: map[string]bool{"user_id": true}, PrimaryKeyName: "test_users_pk"},
+		},
 		{
 			name: "ok SQL suggestion",
 			input: schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{
@@ -54,10 +66,29 @@ func TestMapTableSchema(t *testing.T) {
 			expected: adapters.Table{Schema: "test", Name: "test_table", Columns: adapters.Columns{"field1": typing.SQLColumn{Type: "varchar", Override: true}, "field2": typing.SQLColumn{Type: "text", Override: true}},
 				PKFields: map[string]bool{}},
 		},
+		{
+			name: "configured column types override inferred types and SQL suggestions",
+			input: schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{
+				"field1": schema.NewField(typing.FLOAT64),
+				"field2": schema.NewFieldWithSQLType(typing.STRING, schema.NewSQLTypeSuggestion(typing.SQLColumn{Type: "text"}, nil)),
+				"field3": schema.NewField(typing.STRING),
+			}},
+			pkFields:           map[string]bool{},
+			columnTypesMapping: map[typing.DataType]string{typing.STRING: "text", typing.FLOAT64: "double precision"},
+			columnTypes: typing.SQLTypes{
+				"field1": {Type: "NUMERIC(38,9)", ColumnType: "NUMERIC(38,9)", Override: true},
+				"field2": {Type: "TIMESTAMPTZ", ColumnType: "TIMESTAMPTZ", Override: true},
+			},
+			expected: adapters.Table{Schema: "test", Name: "test_table", Columns: adapters.Columns{
+				"field1": typing.SQLColumn{Type: "NUMERIC(38,9)", ColumnType: "NUMERIC(38,9)", Override: true},
+				"field2": typing.SQLColumn{Type: "TIMESTAMPTZ", ColumnType: "TIMESTAMPTZ", Override: true},
+				"field3": typing.SQLColumn{Type: "text"}},
+				PKFields: map[string]bool{}},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tableHelper := NewTableHelper("test", nil, nil, tt.pkFields, tt.columnTypesMapping, 0, PostgresType)
+			tableHelper := NewTableHelper("test", nil, nil, tt.pkFields, tt.columnTypesMapping, 0, PostgresType).WithTablePKFields(tt.tablePKFields).WithColumnTypes(tt.columnTypes)
 			actual := tableHelper.MapTableSchema(&tt.input)
 			require.Equal(t, tt.expected, *actual, "Tables aren't equal")
 		})
@@ -147,3 +178,103 @@ return $
 		})
 	}
 }
+
+func TestColumnsToWiden(t *testing.T) {
+	dbSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{
+		"int_to_string":       typing.SQLColumn{Type: "bigint"},
+		"int_to_float":        typing.SQLColumn{Type: "bigint"},
+		"float_to_int":        typing.SQLColumn{Type: "double precision"},
+		"string_to_int":       typing.SQLColumn{Type: "text"},
+		"varchar_growth":      typing.SQLColumn{Type: "character varying(256)"},
+		"timestamp_to_string": typing.SQLColumn{Type: "timestamp without time zone"},
+		"unknown_db_type":     typing.SQLColumn{Type: "jsonb"},
+		"overridden":          typing.SQLColumn{Type: "bigint"},
+	}}
+	dataSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{
+		"int_to_string":       typing.SQLColumn{Type: "text"},
+		"int_to_float":        typing.SQLColumn{Type: "double precision"},
+		"float_to_int":        typing.SQLColumn{Type: "bigint"},
+		"string_to_int":       typing.SQLColumn{Type: "bigint"},
+		"varchar_growth":      typing.SQLColumn{Type: "character varying(65535)"},
+		"timestamp_to_string": typing.SQLColumn{Type: "text"},
+		"unknown_db_type":     typing.SQLColumn{Type: "text"},
+		"overridden":          typing.SQLColumn{Type: "text", ColumnType: "text", Override: true},
+		"new_column":          typing.SQLColumn{Type: "text"},
+	}}
+	mapping := map[typing.DataType]string{
+		typing.STRING:    "text",
+		typing.INT64:     "bigint",
+		typing.FLOAT64:   "double precision",
+		typing.TIMESTAMP: "timestamp",
+		typing.BOOL:      "boolean",
+		typing.UNKNOWN:   "text",
+	}
+
+	disabled := NewTableHelper("test", nil, nil, map[string]bool{}, mapping, 0, PostgresType)
+	require.Empty(t, disabled.columnsToWiden(dbSchema, dataSchema))
+
+	enabled := NewTableHelper("test", nil, nil, map[string]bool{}, mapping, 0, PostgresType).WithColumnTypesMigration(ColumnTypesMigrationEnabled)
+	require.Equal(t, adapters.Columns{
+		"int_to_string":       typing.SQLColumn{Type: "text"},
+		"int_to_float":        typing.SQLColumn{Type: "double precision"},
+		"varchar_growth":      typing.SQLColumn{Type: "character varying(65535)"},
+		"timestamp_to_string": typing.SQLColumn{Type: "text"},
+	}, enabled.columnsToWiden(dbSchema, dataSchema))
+}
+
+//schemaAdapterMock is a SQLAdapter which keeps one table schema and counts schema requests
+type schemaAdapterMock struct {
+	adapters.SQLAdapter
+	table            *adapters.Table
+	getSchemaCalls   int
+	patchSchemaCalls int
+}
+
+func (sam *schemaAdapterMock) GetTableSchema(tableName string) (*adapters.Table, error) {
+	sam.getSchemaCalls++
+	return sam.table.Clone(), nil
+}
+
+func (sam *schemaAdapterMock) PatchTableSchema(schemaToAdd *adapters.Table) error {
+	sam.patchSchemaCalls++
+	return nil
+}
+
+func TestEnsureTableReportsWideningOnce(t *testing.T) {
+	mapping := map[typing.DataType]string{
+		typing.STRING:  "text",
+		typing.INT64:   "bigint",
+		typing.FLOAT64: "double precision",
+	}
+
+	for _, tt := range []struct {
+		name string
+		mode string
+	}{
+		{"Dry run", ColumnTypesMigrationDryRun},
+		{"Unsupported by destination", ColumnTypesMigrationEnabled},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := &schemaAdapterMock{table: &adapters.Table{Name: "test_table", Columns: adapters.Columns{
+				"field": typing.SQLColumn{Type: "bigint"},
+			}, PKFields: map[string]bool{}}}
+			tableHelper := NewTableHelper("test", adapter, coordination.NewInMemoryService(""), map[string]bool{}, mapping, 0, BigQueryType).
+				WithColumnTypesMigration(tt.mode)
+
+			dataSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{"field": typing.SQLColumn{Type: "double precision"}}, PKFields: map[string]bool{}}
+			for i := 0; i < 3; i++ {
+				dbSchema, err := tableHelper.EnsureTableWithCaching("test_destination", dataSchema)
+				require.NoError(t, err)
+				require.Equal(t, "bigint", dbSchema.Columns["field"].Type, "column type mustn't be changed")
+			}
+
+			//the first schema request fills the cache, the second one is on the patching
+			require.Equal(t, 2, adapter.getSchemaCalls)
+			require.Equal(t, 0, adapter.patchSchemaCalls)
+
+			//a wider type is reported again
+			dataSchema.Columns["field"] = typing.SQLColumn{Type: "text"}
+			require.Equal(t, adapters.Columns{"field": typing.SQLColumn{Type: "text"}}, tableHelper.columnsToWiden(adapter.table, dataSchema))
+		})
+	}
+}
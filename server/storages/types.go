@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/jsonutils"
@@ -28,6 +29,11 @@ const (
 	AmplitudeType       = "amplitude"
 	HubSpotType         = "hubspot"
 	DbtCloudType        = "dbtcloud"
+	GoogleSheetsType    = "google_sheets"
+
+	//MirrorType is a logical destination which writes events into several destinations (see config.Mirror).
+	//It isn't a storage: mirrors are expanded by destinations service
+	MirrorType = "mirror"
 )
 
 type URSetup struct {
@@ -65,15 +71,21 @@ type Storage interface {
 	Start(config *Config) error
 	ID() string
 	Type() string
+	Generation() int64
 	IsStaging() bool
 	IsCachingDisabled() bool
 	Clean(tableName string) error
+	Erase(identifier string, defaults *config.Erasure) (map[string]int64, error)
+	ErasureColumns(defaults *config.Erasure) []string
+	Expire(defaults *config.Retention, dryRun bool) (map[string]int64, error)
 }
 
 //StorageProxy is a storage proxy
 type StorageProxy interface {
 	io.Closer
 	Get() (Storage, bool)
+	//Acquire returns storage for writing. The storage isn't closed until release func is called
+	Acquire() (storage Storage, release func(), ready bool)
 	GetUniqueIDField() *identifiers.UniqueID
 	GetPostHandleDestinations() []string
 	GetGeoResolverID() string
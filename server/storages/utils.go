@@ -6,9 +6,12 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/delivery"
 	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"strings"
@@ -45,6 +48,18 @@ func IsConnectionError(err error) bool {
 		strings.Contains(err.Error(), "no such host")
 }
 
+// ErrorClass returns metrics error class of the error returned by a destination
+func ErrorClass(err error) string {
+	if IsConnectionError(err) {
+		return metrics.ErrorClassConnection
+	}
+	if errorj.IsSystemError(err) {
+		return metrics.ErrorClassSystem
+	}
+
+	return metrics.ErrorClassDestination
+}
+
 // syncStoreImpl implements common behaviour used to storing chunk of pulled data to any storages with processing
 func syncStoreImpl(storage Storage, overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, deleteConditions *base.DeleteConditions, cacheTable bool, needCopyEvent bool) error {
 	if len(objects) == 0 {
@@ -124,21 +139,25 @@ func processData(storage Storage, overriddenDataSchema *schema.BatchHeader, obje
 	return flatDataPerTable, nil
 }
 
+//writeEventsToCache writes batch store results into events cache and events delivery records
 func writeEventsToCache(storage Storage, eventsCache *caching.EventsCache, table *adapters.Table, fdata *schema.ProcessedFile, storeErr error) {
 	rawEvents := fdata.GetOriginalRawEvents()
 	for i, object := range fdata.GetPayload() {
 		rawEvent := rawEvents[i]
+		eventID := storage.GetUniqueIDField().Extract(object)
 		if storeErr != nil {
 			eventsCache.Error(storage.IsCachingDisabled(), storage.ID(), rawEvent, storeErr.Error())
+			delivery.Failed(eventID, storage.ID(), storage.Generation(), storeErr.Error())
 		} else {
 			eventsCache.Succeed(&adapters.EventContext{
 				CacheDisabled:           storage.IsCachingDisabled(),
 				DestinationID:           storage.ID(),
 				SerializedOriginalEvent: rawEvent,
-				EventID:                 storage.GetUniqueIDField().Extract(object),
+				EventID:                 eventID,
 				ProcessedEvent:          object,
 				Table:                   table,
 			})
+			delivery.Delivered(eventID, storage.ID(), storage.Generation())
 		}
 	}
 }
@@ -0,0 +1,88 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+)
+
+// TypeConfig describes the type specific configuration of a destination type. It is used for configuration validation
+type TypeConfig struct {
+	// Section is a deprecated destination section with the same configuration as 'config' section (e.g. datasource)
+	Section string
+	// NewConfig returns an empty type specific configuration. It is nil if the configuration isn't fixed (npm plugins)
+	NewConfig func() config.Validatable
+	// Modes are destination modes which the type supports. All modes are supported if empty
+	Modes []string
+	// Exclusive are groups of mutually exclusive options (paths relative to the destination configuration)
+	Exclusive [][]string
+	// StagingFormats are formats of files which are staged for the batch COPY. The first one is the default
+	StagingFormats []adapters.FileEncodingFormat
+}
+
+var typeConfigs = map[string]*TypeConfig{
+	PostgresType:   {Section: "datasource", NewConfig: func() config.Validatable { return &adapters.DataSourceConfig{} }},
+	MySQLType:      {Section: "datasource", NewConfig: func() config.Validatable { return &adapters.DataSourceConfig{} }},
+	ClickHouseType: {Section: "clickhouse", NewConfig: func() config.Validatable { return &adapters.ClickHouseConfig{} }},
+	RedshiftType: {Section: "datasource", NewConfig: func() config.Validatable { return &adapters.DataSourceConfig{} },
+		Exclusive:      [][]string{{"config.s3", "s3"}},
+		StagingFormats: []adapters.FileEncodingFormat{adapters.FileFormatJSON, adapters.FileFormatCSV}},
+	SnowflakeType: {Section: "snowflake", NewConfig: func() config.Validatable { return &adapters.SnowflakeConfig{} },
+		Exclusive:      [][]string{{"config.s3", "config.google", "config.azure"}, {"config.s3", "s3"}, {"config.google", "google"}},
+		StagingFormats: []adapters.FileEncodingFormat{adapters.FileFormatCSV, adapters.FileFormatJSON, adapters.FileFormatParquet}},
+	BigQueryType: {Section: "google", NewConfig: func() config.Validatable { return &adapters.GoogleConfig{} },
+		StagingFormats: []adapters.FileEncodingFormat{adapters.FileFormatJSON, adapters.FileFormatCSV, adapters.FileFormatParquet}},
+	S3Type:              {Section: "s3", NewConfig: func() config.Validatable { return &adapters.S3Config{} }, Modes: []string{BatchMode}},
+	GCSType:             {Section: "google", NewConfig: func() config.Validatable { return &adapters.GoogleConfig{} }, Modes: []string{BatchMode}},
+	GoogleAnalyticsType: {Section: "google_analytics", NewConfig: func() config.Validatable { return &adapters.GoogleAnalyticsConfig{} }, Modes: []string{StreamMode}},
+	FacebookType:        {Section: "facebook", NewConfig: func() config.Validatable { return &adapters.FacebookConversionAPIConfig{} }, Modes: []string{StreamMode}},
+	WebHookType:         {Section: "webhook", NewConfig: func() config.Validatable { return &adapters.WebHookConfig{} }, Modes: []string{StreamMode}},
+	AmplitudeType:       {Section: "amplitude", NewConfig: func() config.Validatable { return &adapters.AmplitudeConfig{} }, Modes: []string{StreamMode}},
+	HubSpotType:         {Section: "hubspot", NewConfig: func() config.Validatable { return &adapters.HubSpotConfig{} }, Modes: []string{StreamMode}},
+	DbtCloudType:        {Section: "dbtcloud", NewConfig: func() config.Validatable { return &adapters.DbtCloudConfig{} }, Modes: []string{StreamMode}},
+	GoogleSheetsType:    {NewConfig: func() config.Validatable { return &adapters.GoogleSheetsConfig{} }},
+	TagType:             {NewConfig: func() config.Validatable { return &adapters.TagConfig{} }},
+	NpmType:             {Modes: []string{StreamMode}},
+}
+
+// StagingFormat returns the staging file format or the default one if format is empty.
+// Returns err if the type doesn't support the format
+func (tc *TypeConfig) StagingFormat(format string) (adapters.FileEncodingFormat, error) {
+	if len(tc.StagingFormats) == 0 {
+		if format != "" {
+			return "", errors.New("staging files aren't used by the destination type")
+		}
+		return "", nil
+	}
+
+	if format == "" {
+		return tc.StagingFormats[0], nil
+	}
+
+	supported := make([]string, 0, len(tc.StagingFormats))
+	for _, stagingFormat := range tc.StagingFormats {
+		if string(stagingFormat) == format {
+			return stagingFormat, nil
+		}
+		supported = append(supported, string(stagingFormat))
+	}
+
+	return "", fmt.Errorf("unsupported staging format [%s]. Supported: [%s]", format, strings.Join(supported, ", "))
+}
+
+// GetTypeConfig returns TypeConfig of the registered destination type. Returns false if the type is unknown
+func GetTypeConfig(destinationType string) (*TypeConfig, bool) {
+	if _, ok := StorageTypes[destinationType]; !ok {
+		return nil, false
+	}
+
+	typeConfig, ok := typeConfigs[destinationType]
+	if !ok {
+		return &TypeConfig{}, true
+	}
+
+	return typeConfig, true
+}
@@ -80,7 +80,7 @@ func NewWebHook(config *Config) (storage Storage, err error) {
 	wh.adapter = wbAdapter
 
 	//streaming worker (queue reading)
-	wh.streamingWorkers = newStreamingWorkers(config.eventQueue, wh, config.streamingThreadsCount)
+	wh.streamingWorkers = newStreamingWorkers(config.eventQueue, wh, config.streamingThreadsCount, nil)
 
 	return
 }
@@ -0,0 +1,128 @@
+package synchronization
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/logging"
+	"golang.org/x/time/rate"
+)
+
+//runChunks synchronizes intervals (chunks) by concurrency workers. Every chunk start waits for the limiter (if it isn't nil).
+//After the first failure or canceling, new chunks aren't started but running ones are completed: every completed chunk
+//saves its own signature so the next run synchronizes only failed and not started chunks.
+//Returns ErrTaskHasBeenCanceled, the only chunk error or combined error of several failed chunks
+func runChunks(intervals []*driversbase.TimeInterval, concurrency int, limiter *rate.Limiter,
+	checkCanceling func() error, syncChunk func(interval *driversbase.TimeInterval) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		errs      []error
+		cancelErr error
+	)
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(errs) > 0
+	}
+
+	workers := make(chan struct{}, concurrency)
+	for _, interval := range intervals {
+		workers <- struct{}{}
+		if err := checkCanceling(); err != nil {
+			<-workers
+			cancelErr = err
+			break
+		}
+		if failed() {
+			<-workers
+			break
+		}
+		if limiter != nil {
+			if err := limiter.Wait(context.Background()); err != nil {
+				<-workers
+				return err
+			}
+		}
+
+		wg.Add(1)
+		chunk := interval
+		go func() {
+			var err error
+			defer func() {
+				if r := recover(); r != nil {
+					logging.SystemErrorf("panic in [%s] chunk synchronization: %v\n%s", chunk.String(), r, string(debug.Stack()))
+					err = fmt.Errorf("[%s] chunk synchronization panic: %v", chunk.String(), r)
+				}
+				if err != nil {
+					mutex.Lock()
+					errs = append(errs, err)
+					mutex.Unlock()
+				}
+				<-workers
+				wg.Done()
+			}()
+
+			err = syncChunk(chunk)
+		}()
+	}
+	wg.Wait()
+
+	if cancelErr != nil {
+		return cancelErr
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = err.Error()
+		}
+		return fmt.Errorf("%d chunks have failed: %s", len(errs), strings.Join(messages, "; "))
+	}
+}
+
+//rateLimiters is a registry of per-source chunks rate limiters. All collections of a source share one limiter
+type rateLimiters struct {
+	mutex    sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newRateLimiters() *rateLimiters {
+	return &rateLimiters{limiters: map[string]*rate.Limiter{}}
+}
+
+//get returns the source limiter with the actual limit (chunks per second) or nil if limit isn't positive
+func (rl *rateLimiters) get(sourceID string, limit float64) *rate.Limiter {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if limit <= 0 {
+		delete(rl.limiters, sourceID)
+		return nil
+	}
+
+	burst := int(math.Ceil(limit))
+	limiter, ok := rl.limiters[sourceID]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(limit), burst)
+		rl.limiters[sourceID] = limiter
+	} else if limiter.Limit() != rate.Limit(limit) {
+		limiter.SetLimit(rate.Limit(limit))
+		limiter.SetBurst(burst)
+	}
+
+	return limiter
+}
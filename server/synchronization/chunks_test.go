@@ -0,0 +1,114 @@
+package synchronization
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	driversbase "github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func testIntervals(count int) []*driversbase.TimeInterval {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	intervals := make([]*driversbase.TimeInterval, count)
+	for i := range intervals {
+		intervals[i] = driversbase.NewTimeInterval(schema.DAY, start.AddDate(0, 0, i))
+	}
+	return intervals
+}
+
+func noCanceling() error {
+	return nil
+}
+
+func TestRunChunksConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	var mutex sync.Mutex
+	synchronized := map[string]bool{}
+
+	err := runChunks(testIntervals(10), 3, nil, noCanceling, func(interval *driversbase.TimeInterval) error {
+		current := running.Inc()
+		for {
+			max := maxRunning.Load()
+			if current <= max || maxRunning.CAS(max, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Dec()
+
+		mutex.Lock()
+		synchronized[interval.String()] = true
+		mutex.Unlock()
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, synchronized, 10)
+	require.Equal(t, int32(3), maxRunning.Load())
+}
+
+func TestRunChunksFailure(t *testing.T) {
+	chunkErr := errors.New("chunk error")
+	var started atomic.Int32
+
+	err := runChunks(testIntervals(10), 1, nil, noCanceling, func(interval *driversbase.TimeInterval) error {
+		if started.Inc() == 2 {
+			return chunkErr
+		}
+		return nil
+	})
+
+	require.Equal(t, chunkErr, err)
+	require.Equal(t, int32(2), started.Load(), "chunks after the failed one mustn't be started")
+
+	err = runChunks(testIntervals(2), 2, nil, noCanceling, func(interval *driversbase.TimeInterval) error {
+		time.Sleep(10 * time.Millisecond)
+		return chunkErr
+	})
+	require.EqualError(t, err, "2 chunks have failed: chunk error; chunk error")
+
+	err = runChunks(testIntervals(1), 1, nil, noCanceling, func(interval *driversbase.TimeInterval) error {
+		panic("unexpected")
+	})
+	require.Error(t, err)
+}
+
+func TestRunChunksCanceling(t *testing.T) {
+	var started atomic.Int32
+	checkCanceling := func() error {
+		if started.Load() == 3 {
+			return ErrTaskHasBeenCanceled
+		}
+		return nil
+	}
+
+	err := runChunks(testIntervals(10), 1, nil, checkCanceling, func(interval *driversbase.TimeInterval) error {
+		started.Inc()
+		return nil
+	})
+
+	require.Equal(t, ErrTaskHasBeenCanceled, err)
+	require.Equal(t, int32(3), started.Load())
+}
+
+func TestRateLimiters(t *testing.T) {
+	limiters := newRateLimiters()
+	require.Nil(t, limiters.get("source", 0))
+
+	limiter := limiters.get("source", 2.5)
+	require.NotNil(t, limiter)
+	require.Equal(t, 3, limiter.Burst())
+	require.Same(t, limiter, limiters.get("source", 2.5))
+	require.NotSame(t, limiter, limiters.get("another", 2.5))
+
+	require.Same(t, limiter, limiters.get("source", 5))
+	require.Equal(t, 5, limiter.Burst())
+
+	require.Nil(t, limiters.get("source", -1))
+	require.NotSame(t, limiter, limiters.get("source", 5))
+}
@@ -130,6 +130,11 @@ var Slack NotificationChannel = func(ctx context.Context, nctx *NotificationCont
 		logs = ""
 	}
 
+	status := task.Status
+	if task.Status == FAILED.String() && task.Attempt > 0 {
+		status = fmt.Sprintf("%s (%d retries have been exhausted)", task.Status, task.Attempt)
+	}
+
 	color := grey
 	switch task.Status {
 	case SUCCESS.String():
@@ -141,7 +146,7 @@ var Slack NotificationChannel = func(ctx context.Context, nctx *NotificationCont
 	return requests.URL(config.URL).
 		Method(http.MethodPost).
 		BodyJSON(Map{
-			"text": fmt.Sprintf("*%s %s* [%s]: Synchronization %s", nctx.ServiceName, nctx.Version, nctx.ServerName, task.Status),
+			"text": fmt.Sprintf("*%s %s* [%s]: Synchronization %s", nctx.ServiceName, nctx.Version, nctx.ServerName, status),
 			"attachments": []Map{{
 				"color": color,
 				"blocks": []Map{
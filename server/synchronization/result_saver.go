@@ -166,6 +166,7 @@ func (rs *ResultSaver) Consume(representation *driversbase.CLIOutputRepresentati
 				metrics.ErrorObjects(rs.task.SourceType, rs.tap, rs.task.Source, rowsCount)
 				telemetry.Error(rs.task.Source, storage.ID(), srcSource, rs.tap, rowsCount)
 				counters.ErrorPullDestinationEvents(storage.ID(), int64(rowsCount))
+				counters.PullRollup(storage.ID(), rs.task.Source, targetTableName, meta.ErrorStatus, int64(rowsCount))
 				counters.ErrorPullSourceEvents(rs.task.Source, int64(rowsCount))
 				return errors.New(errMsg)
 			}
@@ -174,6 +175,7 @@ func (rs *ResultSaver) Consume(representation *driversbase.CLIOutputRepresentati
 			metrics.SuccessObjects(rs.task.SourceType, rs.tap, rs.task.Source, rowsCount)
 			telemetry.Event(rs.task.Source, storage.ID(), srcSource, rs.tap, rowsCount)
 			counters.SuccessPullDestinationEvents(storage.ID(), int64(rowsCount))
+			counters.PullRollup(storage.ID(), rs.task.Source, targetTableName, meta.SuccessStatus, int64(rowsCount))
 		}
 
 		counters.SuccessPullSourceEvents(rs.task.Source, int64(rowsCount))
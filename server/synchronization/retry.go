@@ -0,0 +1,87 @@
+package synchronization
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+//DefaultPermanentErrors are failed tasks error substrings which mean that retry won't help
+var DefaultPermanentErrors = []string{"wasn't found", "doesn't exist", "unauthorized", "authentication",
+	"invalid credentials", "permission denied", "forbidden", "empty destinations"}
+
+//RetryPolicy determines if failed sync tasks should be retried and when: exponential backoff with jitter
+//Errors which contain one of permanent errors substrings (case insensitive) aren't retried
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	//Jitter is a max fraction of delay which is randomly added or subtracted
+	Jitter float64
+
+	permanentErrors []string
+	random          func() float64
+}
+
+//NewRetryPolicy returns configured RetryPolicy or nil if maxAttempts isn't positive (retries are disabled)
+func NewRetryPolicy(maxAttempts int, initialDelay, maxDelay time.Duration, multiplier, jitter float64, permanentErrors []string) *RetryPolicy {
+	if maxAttempts <= 0 {
+		return nil
+	}
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	if jitter < 0 {
+		jitter = 0
+	} else if jitter > 1 {
+		jitter = 1
+	}
+	if maxDelay < initialDelay {
+		maxDelay = initialDelay
+	}
+
+	lowerPermanentErrors := make([]string, 0, len(permanentErrors))
+	for _, permanentError := range permanentErrors {
+		if permanentError = strings.ToLower(strings.TrimSpace(permanentError)); permanentError != "" {
+			lowerPermanentErrors = append(lowerPermanentErrors, permanentError)
+		}
+	}
+
+	return &RetryPolicy{
+		MaxAttempts:     maxAttempts,
+		InitialDelay:    initialDelay,
+		MaxDelay:        maxDelay,
+		Multiplier:      multiplier,
+		Jitter:          jitter,
+		permanentErrors: lowerPermanentErrors,
+		random:          rand.Float64,
+	}
+}
+
+//IsPermanent returns true if the error message contains one of permanent errors substrings
+func (rp *RetryPolicy) IsPermanent(errMsg string) bool {
+	lowerErrMsg := strings.ToLower(errMsg)
+	for _, permanentError := range rp.permanentErrors {
+		if strings.Contains(lowerErrMsg, permanentError) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Delay returns delay before the next attempt. attempt is a number of already made retries (0 for the first failure)
+func (rp *RetryPolicy) Delay(attempt int) time.Duration {
+	delay := float64(rp.InitialDelay) * math.Pow(rp.Multiplier, float64(attempt))
+	if delay > float64(rp.MaxDelay) {
+		delay = float64(rp.MaxDelay)
+	}
+
+	if rp.Jitter > 0 {
+		delay += delay * rp.Jitter * (2*rp.random() - 1)
+	}
+
+	return time.Duration(delay)
+}
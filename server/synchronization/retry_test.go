@@ -0,0 +1,35 @@
+package synchronization
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	require.Nil(t, NewRetryPolicy(0, time.Minute, time.Hour, 2, 0, nil))
+
+	policy := NewRetryPolicy(5, time.Minute, 5*time.Minute, 2, 0, nil)
+	require.Equal(t, time.Minute, policy.Delay(0))
+	require.Equal(t, 2*time.Minute, policy.Delay(1))
+	require.Equal(t, 4*time.Minute, policy.Delay(2))
+	require.Equal(t, 5*time.Minute, policy.Delay(3), "delay must be capped")
+
+	policy = NewRetryPolicy(5, time.Minute, time.Hour, 2, 0.5, nil)
+	policy.random = func() float64 { return 0 }
+	require.Equal(t, 30*time.Second, policy.Delay(0))
+	policy.random = func() float64 { return 1 }
+	require.Equal(t, 90*time.Second, policy.Delay(0))
+}
+
+func TestRetryPolicyIsPermanent(t *testing.T) {
+	policy := NewRetryPolicy(3, time.Minute, time.Hour, 2, 0, DefaultPermanentErrors)
+	require.True(t, policy.IsPermanent("Error [ALL] synchronization: 401 Unauthorized"))
+	require.True(t, policy.IsPermanent("Collection with id [users] wasn't found in source [src]"))
+	require.False(t, policy.IsPermanent("Error storing 100 source objects in [dst] destination: i/o timeout"))
+
+	policy = NewRetryPolicy(3, time.Minute, time.Hour, 2, 0, []string{" Quota ", ""})
+	require.True(t, policy.IsPermanent("quota exceeded"))
+	require.False(t, policy.IsPermanent("401 Unauthorized"))
+}
@@ -3,6 +3,7 @@ package synchronization
 import (
 	"errors"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/alerting"
 	"github.com/jitsucom/jitsu/server/telemetry"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/utils"
@@ -22,6 +23,8 @@ type TaskCloser struct {
 	notificationService *NotificationService
 	notificationConfig  map[string]interface{}
 	projectName         string
+	//retry schedules the next task attempt and returns true if the task will be retried
+	retry func(errMsg string) bool
 }
 
 //TaskID returns task ID
@@ -67,7 +70,15 @@ func (tc *TaskCloser) CloseWithError(msg string, systemErr bool) {
 		tc.taskLogger.ERROR(msg)
 	}
 	telemetry.SourceTaskStatus(tc.ID, tc.Source, tc.SourceType, tc.Collection, FAILED.String(), utils.ShortenString(msg, 1024), tc.CreatedAt, tc.StartedAt, timestamp.NowUTC())
-	tc.notify(FAILED.String())
+
+	if tc.retry != nil && tc.retry(msg) {
+		//failure notification is sent only when all attempts have failed
+		return
+	}
+
+	alerting.SyncTaskFailed(tc.Source, tc.Collection, tc.ID, msg)
+	//previous attempts have failed as well: notify about exhausted retries anyway
+	tc.notify(FAILED.String(), tc.Attempt > 0)
 }
 
 func (tc *TaskCloser) CloseWithSuccess() error {
@@ -77,11 +88,12 @@ func (tc *TaskCloser) CloseWithSuccess() error {
 		return err
 	}
 	telemetry.SourceTaskStatus(tc.ID, tc.Source, tc.SourceType, tc.Collection, SUCCESS.String(), "", tc.CreatedAt, tc.StartedAt, timestamp.NowUTC())
-	tc.notify(SUCCESS.String())
+	alerting.SyncTaskSucceeded(tc.Source, tc.Collection, tc.ID)
+	tc.notify(SUCCESS.String(), false)
 	return nil
 }
 
-func (tc *TaskCloser) notify(status string) {
+func (tc *TaskCloser) notify(status string, force bool) {
 	previousStatus := ""
 	previousTask, err := tc.metaStorage.GetLastTask(tc.Source, tc.Collection, 1)
 	if err != nil {
@@ -90,7 +102,7 @@ func (tc *TaskCloser) notify(status string) {
 		previousStatus = previousTask.Status
 	}
 
-	if previousStatus != status {
+	if force || previousStatus != status {
 		go tc.notificationService.Notify(LoggedTask{
 			Task:          tc.Task,
 			TaskLogger:    tc.taskLogger,
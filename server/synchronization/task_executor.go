@@ -40,6 +40,9 @@ type TaskExecutorContext struct {
 	MetaStorage         meta.Storage
 	CoordinationService *coordination.Service
 	NotificationService *NotificationService
+	TaskService         *TaskService
+	//RetryPolicy is nil if failed tasks aren't retried
+	RetryPolicy *RetryPolicy
 
 	StalledThreshold      time.Duration
 	LastActivityThreshold time.Duration
@@ -51,6 +54,7 @@ type TaskExecutor struct {
 	workersPool      *ants.PoolWithFunc
 	closed           *atomic.Bool
 	sourcesLogWriter io.Writer
+	rateLimiters     *rateLimiters
 }
 
 // NewTaskExecutor returns TaskExecutor and starts 2 goroutines (monitoring and queue observer)
@@ -59,6 +63,7 @@ func NewTaskExecutor(poolSize int, ctx *TaskExecutorContext, sourcesLogWriter io
 		TaskExecutorContext: ctx,
 		sourcesLogWriter:    sourcesLogWriter,
 		closed:              atomic.NewBool(false),
+		rateLimiters:        newRateLimiters(),
 	}
 	pool, err := ants.NewPoolWithFunc(poolSize, executor.execute)
 	if err != nil {
@@ -235,6 +240,9 @@ func (te *TaskExecutor) execute(i interface{}) {
 		notificationConfig:  sourceUnit.Notifications,
 		projectName:         sourceUnit.ProjectName,
 	}
+	taskCloser.retry = func(errMsg string) bool {
+		return te.scheduleRetry(task, taskLogger, errMsg)
+	}
 
 	if taskCloser.HandleCanceling() == ErrTaskHasBeenCanceled {
 		return
@@ -301,11 +309,13 @@ func (te *TaskExecutor) execute(i interface{}) {
 	}
 	//get destinations
 	var destinationStorages []storages.Storage
-	for _, destinationID := range sourceUnit.DestinationIDs {
+	for _, destinationID := range te.DestinationService.ExpandMirrors(sourceUnit.DestinationIDs) {
 		storageProxy, ok := te.DestinationService.GetDestinationByID(destinationID)
 		if ok {
-			storage, ok := storageProxy.Get()
+			//the storage isn't closed by configuration reload until the task is finished
+			storage, release, ok := storageProxy.Acquire()
 			if ok {
+				defer release()
 				destinationStorages = append(destinationStorages, storage)
 			} else {
 				msg := fmt.Sprintf("Unable to get destination [%s] in source [%s]: destination isn't initialized", destinationID, task.Source)
@@ -333,7 +343,7 @@ func (te *TaskExecutor) execute(i interface{}) {
 	if ok {
 		taskErr = te.syncCLI(task, taskLogger, cliDriver, destinationStorages, taskCloser)
 	} else {
-		taskErr = te.sync(task, taskLogger, driver, destinationStorages, taskCloser)
+		taskErr = te.sync(task, taskLogger, sourceUnit, driver, destinationStorages, taskCloser)
 	}
 
 	if taskErr != nil {
@@ -356,6 +366,50 @@ func (te *TaskExecutor) execute(i interface{}) {
 	te.onSuccess(task, sourceUnit, taskLogger)
 }
 
+// scheduleRetry schedules the next attempt of the failed task according to the retry policy
+// returns false if the task won't be retried (retries are disabled, the error is permanent or attempts are exhausted)
+func (te *TaskExecutor) scheduleRetry(task *meta.Task, taskLogger *TaskLogger, errMsg string) bool {
+	if te.RetryPolicy == nil || te.TaskService == nil {
+		return false
+	}
+
+	if te.RetryPolicy.IsPermanent(errMsg) {
+		taskLogger.WARN("The error is permanent. The task won't be retried")
+		return false
+	}
+
+	if task.Attempt >= te.RetryPolicy.MaxAttempts {
+		taskLogger.ERROR("All [%d] retry attempts have been exhausted", te.RetryPolicy.MaxAttempts)
+		return false
+	}
+
+	attempt := task.Attempt + 1
+	delay := te.RetryPolicy.Delay(task.Attempt)
+	taskLogger.WARN("The task will be retried in [%s] (attempt %d of %d)", delay.Round(time.Second).String(), attempt, te.RetryPolicy.MaxAttempts)
+	logging.Infof("[%s] Task will be retried in [%s] (attempt %d of %d)", task.ID, delay.Round(time.Second).String(), attempt, te.RetryPolicy.MaxAttempts)
+
+	time.AfterFunc(delay, func() {
+		if te.closed.Load() {
+			return
+		}
+
+		taskID, err := te.TaskService.Retry(task.Source, task.Collection, attempt)
+		if err != nil {
+			if err == ErrSourceCollectionIsSyncing || err == ErrSourceCollectionIsStartingToSync {
+				logging.Infof("[%s_%s] Retry attempt %d is skipped: %v", task.Source, task.Collection, attempt, err)
+				return
+			}
+
+			logging.Errorf("[%s_%s] Error scheduling retry attempt %d: %v", task.Source, task.Collection, attempt, err)
+			return
+		}
+
+		logging.Infof("[%s_%s] Retry attempt %d has been scheduled! task id: %s", task.Source, task.Collection, attempt, taskID)
+	})
+
+	return true
+}
+
 func (te *TaskExecutor) onSuccess(task *meta.Task, source *sources.Unit, taskLogger *TaskLogger) {
 	event := events.Event{
 		"event_type":      storages.SourceSuccessEventType,
@@ -378,7 +432,7 @@ func (te *TaskExecutor) onSuccess(task *meta.Task, source *sources.Unit, taskLog
 
 // sync runs source synchronization. Return error if occurred
 // doesn't use task closer because there is no async tasks
-func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver driversbase.Driver,
+func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, sourceUnit *sources.Unit, driver driversbase.Driver,
 	destinationStorages []storages.Storage, taskCloser *TaskCloser) error {
 	now := timestamp.Now().UTC()
 
@@ -394,6 +448,25 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 	taskLogger.INFO("Total intervals: [%d] Refresh window: %s", len(intervals), refreshWindow)
 	collectionMetaKey := driver.GetCollectionMetaKey()
 
+	//stateful drivers continue from the last saved position
+	stateMetaKey := collectionMetaKey + driversbase.StateSignatureSuffix
+	statefulDriver, isStateful := driver.(driversbase.StatefulDriver)
+	if isStateful {
+		state, err := te.MetaStorage.GetSignature(task.Source, stateMetaKey, schema.ALL.String())
+		if err != nil {
+			return fmt.Errorf("Error getting driver state: %v", err)
+		}
+		statefulDriver.SetState(state)
+	}
+	saveState := func() error {
+		if state := statefulDriver.GetState(); state != "" {
+			if err := te.MetaStorage.SaveSignature(task.Source, stateMetaKey, schema.ALL.String(), state); err != nil {
+				return fmt.Errorf("Error saving driver state: %v", err)
+			}
+		}
+		return nil
+	}
+
 	var intervalsToSync []*driversbase.TimeInterval
 	for _, interval := range intervals {
 		if err := taskCloser.HandleCanceling(); err != nil {
@@ -426,11 +499,20 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 
 	collectionTableName := driver.GetCollectionTable()
 	reformattedTableName := schema.Reformat(collectionTableName)
-	for _, intervalToSync := range intervalsToSync {
-		if err := taskCloser.HandleCanceling(); err != nil {
-			return err
-		}
+	concurrency := sourceUnit.SyncConcurrency
+	if concurrency <= 0 {
+		concurrency = viper.GetInt("sync-tasks.concurrency")
+	}
+	if isStateful && concurrency > 1 {
+		//stateful drivers keep a single read position
+		taskLogger.WARN("Driver keeps synchronization state so intervals will be synchronized sequentially")
+		concurrency = 1
+	}
+	if concurrency > 1 {
+		taskLogger.INFO("Intervals will be synchronized by [%d] workers", concurrency)
+	}
 
+	syncInterval := func(intervalToSync *driversbase.TimeInterval) error {
 		taskLogger.INFO("Running [%s] synchronization", intervalToSync.String())
 
 		objectsLoader := func(objects []map[string]interface{}, pos, total, percent int) error {
@@ -442,6 +524,10 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 			if percent >= 0 {
 				percentString = fmt.Sprintf("%d%% ", percent)
 			}
+			if len(objects) == 0 && isStateful && isIncremental(driver) {
+				//incremental stateful drivers pass empty chunks only for saving the read position
+				return saveState()
+			}
 			if len(objects) > 0 {
 				taskLogger.INFO("%sLoading objects [%d..%d]%s to destinations ...", percentString, pos+1, pos+len(objects), totalString)
 				//Note: we assume that destinations connected to 1 source can't have different unique ID configuration
@@ -465,7 +551,7 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 			storeAttempts := viper.GetInt("sync-tasks.store_attempts")
 			needCopyEvent := len(destinationStorages) > 1 || storeAttempts > 1
 			deleteConditions := &driversbase.DeleteConditions{}
-			if pos == 0 {
+			if pos == 0 && !isIncremental(driver) {
 				//first chunk deletes full data from previous  load
 				deleteConditions = driversbase.DeleteByTimeChunkCondition(intervalToSync)
 			}
@@ -485,6 +571,7 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 					metrics.ErrorObjects(task.SourceType, metrics.EmptySourceTap, task.Source, rowsCount)
 					telemetry.Error(task.Source, storage.ID(), srcSource, driver.GetDriversInfo().SourceType, rowsCount)
 					counters.ErrorPullDestinationEvents(storage.ID(), int64(rowsCount))
+					counters.PullRollup(storage.ID(), task.Source, reformattedTableName, meta.ErrorStatus, int64(rowsCount))
 					counters.ErrorPullSourceEvents(task.Source, int64(rowsCount))
 					return fmt.Errorf("Error storing %d source objects in [%s] destination: %v. All %d objects haven't been stored", rowsCount, storage.ID(), err, rowsCount)
 				}
@@ -493,9 +580,17 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 				metrics.SuccessObjects(task.SourceType, metrics.EmptySourceTap, task.Source, rowsCount)
 				telemetry.Event(task.Source, storage.ID(), srcSource, driver.GetDriversInfo().SourceType, rowsCount)
 				counters.SuccessPullDestinationEvents(storage.ID(), int64(rowsCount))
+				counters.PullRollup(storage.ID(), task.Source, reformattedTableName, meta.SuccessStatus, int64(rowsCount))
 			}
 
 			counters.SuccessPullSourceEvents(task.Source, int64(rowsCount))
+
+			//save position only after objects have been stored in all destinations
+			if isStateful {
+				if err := saveState(); err != nil {
+					return err
+				}
+			}
 			taskLogger.INFO("Chunk completed.")
 
 			return nil
@@ -511,12 +606,19 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 		}
 
 		taskLogger.INFO("Interval [%s] has been synchronized!", intervalToSync.String())
+		return nil
 	}
 
-	return nil
+	return runChunks(intervalsToSync, concurrency, te.rateLimiters.get(task.Source, sourceUnit.SyncRateLimit), taskCloser.HandleCanceling, syncInterval)
 }
 
 // syncCLI syncs singer/airbyte source
+//isIncremental returns true if driver returns only new objects and previously loaded data mustn't be deleted
+func isIncremental(driver driversbase.Driver) bool {
+	incrementalDriver, ok := driver.(driversbase.IncrementalDriver)
+	return ok && incrementalDriver.IsIncremental()
+}
+
 func (te *TaskExecutor) syncCLI(task *meta.Task, taskLogger *TaskLogger, cliDriver driversbase.CLIDriver,
 	destinationStorages []storages.Storage, taskCloser *TaskCloser) error {
 	state, err := te.MetaStorage.GetSignature(task.Source, cliDriver.GetCollectionMetaKey(), schema.ALL.String())
@@ -34,6 +34,7 @@ type TaskDto struct {
 	StartedAt  string `json:"started_at,omitempty"`
 	FinishedAt string `json:"finished_at,omitempty"`
 	Status     string `json:"status,omitempty"`
+	Attempt    int    `json:"attempt,omitempty"`
 }
 
 //LogRecordDto is used in Task API (handlers.TaskHandler)
@@ -116,6 +117,16 @@ func (ts *TaskService) ScheduleSyncFunc(source, collection string, retryCount in
 //Sync creates task and return its ID
 //returns error if task has been already scheduled or has been already in progress (lock in coordination service)
 func (ts *TaskService) Sync(sourceID, collection string, priority Priority) (string, error) {
+	return ts.createTask(sourceID, collection, priority, 0)
+}
+
+//Retry creates the next attempt task of failed synchronization and return its ID
+func (ts *TaskService) Retry(sourceID, collection string, attempt int) (string, error) {
+	return ts.createTask(sourceID, collection, HIGH, attempt)
+}
+
+//createTask creates task and return its ID
+func (ts *TaskService) createTask(sourceID, collection string, priority Priority, attempt int) (string, error) {
 	if ts.metaStorage == nil {
 		return "", ErrMetaStorageRequired
 	}
@@ -166,7 +177,7 @@ func (ts *TaskService) Sync(sourceID, collection string, priority Priority) (str
 	}
 
 	//make sure all destinations exist and ready
-	for _, destinationID := range sourceUnit.DestinationIDs {
+	for _, destinationID := range ts.destinationService.ExpandMirrors(sourceUnit.DestinationIDs) {
 		storageProxy, ok := ts.destinationService.GetDestinationByID(destinationID)
 		if !ok {
 			return "", fmt.Errorf("Destination [%s] doesn't exist", destinationID)
@@ -191,6 +202,7 @@ func (ts *TaskService) Sync(sourceID, collection string, priority Priority) (str
 		StartedAt:  "",
 		FinishedAt: "",
 		Status:     SCHEDULED.String(),
+		Attempt:    attempt,
 	}
 
 	err = ts.metaStorage.CreateTask(sourceID, collection, &task, now)
@@ -251,6 +263,7 @@ func (ts *TaskService) GetTask(id string) (*TaskDto, error) {
 		StartedAt:  task.StartedAt,
 		FinishedAt: task.FinishedAt,
 		Status:     task.Status,
+		Attempt:    task.Attempt,
 	}, nil
 }
 
@@ -285,6 +298,7 @@ func (ts *TaskService) GetTasks(sourceID, collectionID string, statusFilter *Sta
 			StartedAt:  task.StartedAt,
 			FinishedAt: task.FinishedAt,
 			Status:     task.Status,
+			Attempt:    task.Attempt,
 		})
 	}
 
@@ -0,0 +1,399 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/script"
+)
+
+const (
+	expressionOpen  = "${"
+	expressionClose = "}"
+	expressionNull  = "null"
+)
+
+//dateLayoutTokens are supported date() layout tokens and corresponding Go layout elements. Order matters: YYYY before YY
+var dateLayoutTokens = []struct {
+	token  string
+	layout string
+}{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MM", "01"},
+	{"DD", "02"},
+	{"HH", "15"},
+}
+
+//expressionFunc is a compiled function call of an expression pipeline. Input value is nil if the field doesn't exist
+type expressionFunc func(value interface{}) interface{}
+
+type expressionSegment struct {
+	text string
+	//field is nil for text segments
+	field     jsonutils.JSONPath
+	literal   *string
+	functions []expressionFunc
+}
+
+//expressionTemplateExecutor evaluates templates of a restricted expression language without JavaScript runtime:
+//  events_${event_type | sanitize}_${_timestamp | date("YYYY_MM")}
+//Templates are compiled once when configuration is loaded so invalid templates are rejected before any event is processed
+type expressionTemplateExecutor struct {
+	expression string
+	segments   []*expressionSegment
+}
+
+//returnStatement matches JavaScript return keyword (not a part of identifiers like return_reason or returned)
+var returnStatement = regexp.MustCompile(`(^|[^\w$.])return\b`)
+
+//IsExpressionTemplate returns true if the expression looks like the expression language template: contains ${...}
+//placeholders and isn't JavaScript (doesn't contain template literals or return statements outside of placeholders)
+func IsExpressionTemplate(expression string) bool {
+	return strings.Contains(expression, expressionOpen) && !strings.Contains(expression, "`") &&
+		!returnStatement.MatchString(withoutPlaceholders(expression))
+}
+
+//withoutPlaceholders returns the expression text outside of ${...} placeholders
+func withoutPlaceholders(expression string) string {
+	var result strings.Builder
+	rest := expression
+	for {
+		start := strings.Index(rest, expressionOpen)
+		if start < 0 {
+			result.WriteString(rest)
+			break
+		}
+		result.WriteString(rest[:start])
+
+		rest = rest[start+len(expressionOpen):]
+		end := closingBraceIndex(rest)
+		if end < 0 {
+			break
+		}
+		//placeholders are replaced with a space so the surrounding text isn't glued together
+		result.WriteString(" ")
+		rest = rest[end+len(expressionClose):]
+	}
+
+	return result.String()
+}
+
+//NewExpressionTemplateExecutor returns compiled expression template or error if the template is invalid
+func NewExpressionTemplateExecutor(expression string) (*expressionTemplateExecutor, error) {
+	ete := &expressionTemplateExecutor{expression: expression}
+	rest := expression
+	for len(rest) > 0 {
+		start := strings.Index(rest, expressionOpen)
+		if start < 0 {
+			ete.segments = append(ete.segments, &expressionSegment{text: rest})
+			break
+		}
+		if start > 0 {
+			ete.segments = append(ete.segments, &expressionSegment{text: rest[:start]})
+		}
+
+		rest = rest[start+len(expressionOpen):]
+		end := closingBraceIndex(rest)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed %s at position %d", expressionOpen, len(expression)-len(rest)-len(expressionOpen))
+		}
+
+		segment, err := parsePipeline(rest[:end])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s%s%s: %v", expressionOpen, rest[:end], expressionClose, err)
+		}
+		ete.segments = append(ete.segments, segment)
+		rest = rest[end+len(expressionClose):]
+	}
+
+	return ete, nil
+}
+
+//closingBraceIndex returns index of the closing brace which isn't inside a string literal or -1
+func closingBraceIndex(s string) int {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '}':
+			return i
+		}
+	}
+
+	return -1
+}
+
+//parsePipeline parses: operand | function | function(arg1, arg2)
+//operand is a field path (event_type, user.id, /user/id) or a string literal
+func parsePipeline(pipeline string) (*expressionSegment, error) {
+	parts, err := splitOutsideQuotes(pipeline, '|')
+	if err != nil {
+		return nil, err
+	}
+
+	segment := &expressionSegment{}
+	operand := strings.TrimSpace(parts[0])
+	if operand == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+	if literal, ok, err := parseStringLiteral(operand); err != nil {
+		return nil, err
+	} else if ok {
+		segment.literal = &literal
+	} else {
+		if !isFieldPath(operand) {
+			return nil, fmt.Errorf("invalid field path: %q. Expected: field, node1.node2 or /node1/node2", operand)
+		}
+		segment.field = jsonutils.NewJSONPath(strings.ReplaceAll(operand, ".", "/"))
+	}
+
+	for _, call := range parts[1:] {
+		function, err := parseFunctionCall(strings.TrimSpace(call))
+		if err != nil {
+			return nil, err
+		}
+		segment.functions = append(segment.functions, function)
+	}
+
+	return segment, nil
+}
+
+func isFieldPath(operand string) bool {
+	for _, r := range operand {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' && r != '/' && r != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseFunctionCall(call string) (expressionFunc, error) {
+	name := call
+	var args []string
+	if open := strings.Index(call, "("); open >= 0 {
+		if !strings.HasSuffix(call, ")") {
+			return nil, fmt.Errorf("function %q: missing closing parenthesis", call)
+		}
+		name = strings.TrimSpace(call[:open])
+		argsList := strings.TrimSpace(call[open+1 : len(call)-1])
+		if argsList != "" {
+			rawArgs, err := splitOutsideQuotes(argsList, ',')
+			if err != nil {
+				return nil, err
+			}
+			for _, rawArg := range rawArgs {
+				arg, ok, err := parseStringLiteral(strings.TrimSpace(rawArg))
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return nil, fmt.Errorf("function %s: arguments must be string literals. Got: %s", name, strings.TrimSpace(rawArg))
+				}
+				args = append(args, arg)
+			}
+		}
+	}
+
+	switch name {
+	case "lower":
+		return stringFunc(name, args, strings.ToLower)
+	case "upper":
+		return stringFunc(name, args, strings.ToUpper)
+	case "trim":
+		return stringFunc(name, args, strings.TrimSpace)
+	case "sanitize":
+		return stringFunc(name, args, sanitize)
+	case "replace":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("function replace requires 2 arguments: replace(\"old\", \"new\")")
+		}
+		return stringFunc(name, nil, func(s string) string { return strings.ReplaceAll(s, args[0], args[1]) })
+	case "default":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("function default requires 1 argument: default(\"value\")")
+		}
+		return func(value interface{}) interface{} {
+			if value == nil || value == "" {
+				return args[0]
+			}
+			return value
+		}, nil
+	case "date":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("function date requires 1 argument: date(\"YYYY_MM_DD\")")
+		}
+		layout, err := dateLayout(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(value interface{}) interface{} {
+			t, ok := toTime(value)
+			if !ok {
+				return nil
+			}
+			return t.Format(layout)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown function: %q. Supported: lower, upper, trim, sanitize, replace, default, date", name)
+	}
+}
+
+func stringFunc(name string, args []string, f func(string) string) (expressionFunc, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("function %s doesn't have arguments", name)
+	}
+
+	return func(value interface{}) interface{} {
+		if value == nil {
+			return nil
+		}
+		return f(ToString(value, false, false, true))
+	}, nil
+}
+
+//dateLayout converts layout with YYYY, YY, MM, DD, HH tokens into Go time layout
+func dateLayout(layout string) (string, error) {
+	var result strings.Builder
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, t := range dateLayoutTokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				result.WriteString(t.layout)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		switch c := layout[i]; c {
+		case '_', '-', '.':
+			result.WriteByte(c)
+			i++
+		default:
+			return "", fmt.Errorf("unsupported date layout %q: only YYYY, YY, MM, DD, HH and _ - . are allowed", layout)
+		}
+	}
+
+	return result.String(), nil
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC(), true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t.UTC(), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+//sanitize lowercases the value and replaces all symbols except latin letters and digits with underscore
+func sanitize(s string) string {
+	var result strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			result.WriteRune(r)
+		} else {
+			result.WriteRune('_')
+		}
+	}
+
+	return result.String()
+}
+
+//parseStringLiteral returns unquoted value and true if s is a "double" or 'single' quoted string
+func parseStringLiteral(s string) (string, bool, error) {
+	if len(s) == 0 || (s[0] != '"' && s[0] != '\'') {
+		return "", false, nil
+	}
+
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", false, fmt.Errorf("unclosed string literal: %s", s)
+	}
+
+	return s[1 : len(s)-1], true, nil
+}
+
+//splitOutsideQuotes splits s by the separator which isn't inside a string literal
+func splitOutsideQuotes(s string, separator rune) ([]string, error) {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == separator:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed string literal: %s", s[start:])
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+func (ete *expressionTemplateExecutor) ProcessEvent(event events.Event, _ script.Listener) (interface{}, error) {
+	var result strings.Builder
+	for _, segment := range ete.segments {
+		if segment.field == nil && segment.literal == nil {
+			result.WriteString(segment.text)
+			continue
+		}
+
+		var value interface{}
+		if segment.literal != nil {
+			value = *segment.literal
+		} else {
+			value, _ = segment.field.Get(event)
+		}
+		for _, function := range segment.functions {
+			value = function(value)
+		}
+
+		if value == nil {
+			result.WriteString(expressionNull)
+		} else {
+			result.WriteString(ToString(value, false, false, true))
+		}
+	}
+
+	return strings.TrimSpace(result.String()), nil
+}
+
+func (ete *expressionTemplateExecutor) Format() string {
+	return "expression"
+}
+
+func (ete *expressionTemplateExecutor) Expression() string {
+	return ete.expression
+}
+
+func (ete *expressionTemplateExecutor) Close() {
+}
@@ -0,0 +1,87 @@
+package templates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsExpressionTemplate(t *testing.T) {
+	require.True(t, IsExpressionTemplate(`events_${event_type}`))
+	require.False(t, IsExpressionTemplate(`events`))
+	require.False(t, IsExpressionTemplate("return `events_${$.event_type}`"))
+	require.False(t, IsExpressionTemplate("`events_${$.event_type}`"))
+
+	//return inside field names isn't a JavaScript return statement
+	require.True(t, IsExpressionTemplate(`refunds_${return_reason}`))
+	require.True(t, IsExpressionTemplate(`orders_${event.returned | default('no')}`))
+	require.True(t, IsExpressionTemplate(`${order.return}_events`))
+	require.True(t, IsExpressionTemplate(`returns_${event_type}`))
+	require.False(t, IsExpressionTemplate(`return '${' + $.event_type`))
+	require.False(t, IsExpressionTemplate("if ($.x) {return '${'}"))
+}
+
+func TestExpressionTemplate(t *testing.T) {
+	event := events.Event{
+		"event_type": "Page View",
+		"user":       map[string]interface{}{"plan": "Pro", "id": 42.0},
+		"empty":      "",
+		"_timestamp": time.Date(2022, 3, 7, 15, 4, 5, 0, time.UTC),
+		"utc_time":   "2021-12-31T23:59:59.123Z",
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{"field", "events_${event_type}", "events_Page View"},
+		{"functions", "events_${ event_type | lower | replace(' ', '') }", "events_pageview"},
+		{"sanitize", "${event_type | sanitize}", "page_view"},
+		{"upper and trim", "${' a b ' | trim | upper}", "A B"},
+		{"dotted path", "plan_${user.plan | lower}", "plan_pro"},
+		{"json path", "user_${/user/id}", "user_42"},
+		{"missing field", "events_${user.name}", "events_null"},
+		{"missing field function", "events_${user.name | lower}", "events_null"},
+		{"default", "events_${user.name | default('anonymous')}_${empty | default(\"none\")}", "events_anonymous_none"},
+		{"date from time", "events_${_timestamp | date('YYYY_MM_DD')}", "events_2022_03_07"},
+		{"date from string", "events_${utc_time | date(\"YY.MM-DD_HH\")}", "events_21.12-31_23"},
+		{"date from not a date", "events_${event_type | date('YYYY')}", "events_null"},
+		{"braces inside literals", "${user.name | default('}')}", "}"},
+		{"several placeholders", "${event_type | sanitize}_${user.plan | sanitize}", "page_view_pro"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := SmartParse(tt.name, tt.expression, nil)
+			require.NoError(t, err)
+			require.Equal(t, "expression", tmpl.Format())
+			require.Equal(t, tt.expression, tmpl.Expression())
+
+			result, err := tmpl.ProcessEvent(event, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestExpressionTemplateErrors(t *testing.T) {
+	for _, expression := range []string{
+		"events_${event_type",
+		"events_${}",
+		"events_${event_type | unknown}",
+		"events_${event_type | lower('a')}",
+		"events_${event_type | replace('a')}",
+		"events_${event_type | default(value)}",
+		"events_${event_type | default('value'}",
+		"events_${_timestamp | date('%Y')}",
+		"events_${user['id']}",
+		"events_${'unclosed}",
+	} {
+		t.Run(expression, func(t *testing.T) {
+			_, err := SmartParse("test", expression, nil)
+			require.Error(t, err)
+		})
+	}
+}
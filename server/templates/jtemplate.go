@@ -34,6 +34,10 @@ func SmartParse(name string, expression string, extraFunctions template.FuncMap)
 			//simple table name without templating
 			return newConstTemplateExecutor(expression)
 		}
+		if IsExpressionTemplate(expression) {
+			//safe expression language: evaluated without JavaScript runtime
+			return NewExpressionTemplateExecutor(expression)
+		}
 		//Try parse template as JavaScript
 		jsTmpl, err := NewScriptExecutor(Expression(expression), extraFunctions)
 		if err != nil {
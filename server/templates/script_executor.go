@@ -2,20 +2,48 @@ package templates
 
 import (
 	"encoding/json"
+	"strings"
+	"time"
 
+	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/script"
 	"github.com/pkg/errors"
 )
 
-var scriptFactory script.Factory = script.DummyFactory
+const (
+	//PythonRuntime is a runtime of Python transformations and plugins
+	PythonRuntime = "python"
+	//WasmRuntime is an in-process runtime of transformations compiled into WebAssembly modules
+	WasmRuntime = "wasm"
+	//pythonPackagePrefix is a prefix of destination plugin package which is installed with pip (e.g. pip:jitsu-destination==1.0.0)
+	pythonPackagePrefix = "pip:"
+)
+
+var (
+	scriptFactory       script.Factory = script.DummyFactory
+	pythonScriptFactory script.Factory = script.DummyPythonFactory
+	wasmScriptFactory   script.Factory = script.DummyWasmFactory
+)
 
 func SetScriptFactory(newScriptFactory script.Factory) {
 	scriptFactory = newScriptFactory
 }
 
+//SetPythonScriptFactory sets factory of Python runtime
+func SetPythonScriptFactory(newScriptFactory script.Factory) {
+	pythonScriptFactory = newScriptFactory
+}
+
+//SetWasmScriptFactory sets factory of WebAssembly runtime
+func SetWasmScriptFactory(newScriptFactory script.Factory) {
+	wasmScriptFactory = newScriptFactory
+}
+
 type nodeScript interface {
 	String() string
+	factory() script.Factory
+	format() string
 	executable() script.Executable
 	init(s script.Interface) error
 	validate(s script.Interface) error
@@ -28,6 +56,14 @@ func (e Expression) String() string {
 	return string(e)
 }
 
+func (e Expression) factory() script.Factory {
+	return scriptFactory
+}
+
+func (e Expression) format() string {
+	return "javascript"
+}
+
 func (e Expression) init(s script.Interface) error {
 	return nil
 }
@@ -49,6 +85,69 @@ func (e Expression) transform(s script.Interface, event events.Event, listener s
 	return value, nil
 }
 
+//PythonExpression is a body of Python function main(event)
+type PythonExpression string
+
+func (e PythonExpression) String() string {
+	return string(e)
+}
+
+func (e PythonExpression) factory() script.Factory {
+	return pythonScriptFactory
+}
+
+func (e PythonExpression) format() string {
+	return PythonRuntime
+}
+
+func (e PythonExpression) init(s script.Interface) error {
+	return nil
+}
+
+func (e PythonExpression) executable() script.Executable {
+	return script.Expression(e)
+}
+
+func (e PythonExpression) validate(s script.Interface) error {
+	return nil
+}
+
+func (e PythonExpression) transform(s script.Interface, event events.Event, listener script.Listener) (interface{}, error) {
+	return Expression(e).transform(s, event, listener)
+}
+
+//WasmModule is a path to WebAssembly module with transformation
+type WasmModule string
+
+func (m WasmModule) String() string {
+	return string(m)
+}
+
+func (m WasmModule) factory() script.Factory {
+	return wasmScriptFactory
+}
+
+func (m WasmModule) format() string {
+	return WasmRuntime
+}
+
+func (m WasmModule) init(s script.Interface) error {
+	return nil
+}
+
+func (m WasmModule) executable() script.Executable {
+	return script.File(m)
+}
+
+func (m WasmModule) validate(s script.Interface) error {
+	return nil
+}
+
+func (m WasmModule) transform(s script.Interface, event events.Event, listener script.Listener) (interface{}, error) {
+	return Expression(m).transform(s, event, listener)
+}
+
+//DestinationPlugin is a npm package or a pip package (with pip: prefix) with destination functions
 type DestinationPlugin struct {
 	Package string
 	ID      string
@@ -65,8 +164,28 @@ func (p *DestinationPlugin) String() string {
 	return p.Package
 }
 
+func (p *DestinationPlugin) isPython() bool {
+	return strings.HasPrefix(p.Package, pythonPackagePrefix)
+}
+
+func (p *DestinationPlugin) factory() script.Factory {
+	if p.isPython() {
+		return pythonScriptFactory
+	}
+
+	return scriptFactory
+}
+
+func (p *DestinationPlugin) format() string {
+	if p.isPython() {
+		return PythonRuntime
+	}
+
+	return "javascript"
+}
+
 func (p *DestinationPlugin) executable() script.Executable {
-	return script.Package(p.Package)
+	return script.Package(strings.TrimPrefix(p.Package, pythonPackagePrefix))
 }
 
 func (p *DestinationPlugin) init(s script.Interface) error {
@@ -177,6 +296,14 @@ func (s *SourcePlugin) String() string {
 	return s.Package
 }
 
+func (s *SourcePlugin) factory() script.Factory {
+	return scriptFactory
+}
+
+func (s *SourcePlugin) format() string {
+	return "javascript"
+}
+
 func (s *SourcePlugin) executable() script.Executable {
 	return script.Package(s.Package)
 }
@@ -289,7 +416,7 @@ type SourceExecutor struct {
 }
 
 func NewSourceExecutor(sourcePlugin *SourcePlugin) (*SourceExecutor, error) {
-	instance, err := scriptFactory.CreateScript(sourcePlugin.executable(), nil, true)
+	instance, err := sourcePlugin.factory().CreateScript(sourcePlugin.executable(), nil, true)
 	if err != nil {
 		return nil, errors.Wrap(err, "spawn node process")
 	}
@@ -327,7 +454,27 @@ type NodeExecutor struct {
 }
 
 func NewScriptExecutor(nodeScript nodeScript, variables map[string]interface{}, includes ...string) (*NodeExecutor, error) {
-	instance, err := scriptFactory.CreateScript(nodeScript.executable(), variables, false, includes...)
+	return NewLimitedScriptExecutor(nodeScript, variables, nil, includes...)
+}
+
+//NewLimitedScriptExecutor returns NodeExecutor with the script limits (if runtime supports them)
+func NewLimitedScriptExecutor(nodeScript nodeScript, variables map[string]interface{}, limits *script.Limits, includes ...string) (*NodeExecutor, error) {
+	return newScriptExecutor(nodeScript, variables, limits, false, includes...)
+}
+
+//NewStandaloneScriptExecutor returns NodeExecutor running in a dedicated script session (and process if runtime supports it)
+//which isn't shared with live pipelines. It is used for debugging transformations
+func NewStandaloneScriptExecutor(nodeScript nodeScript, variables map[string]interface{}, limits *script.Limits, includes ...string) (*NodeExecutor, error) {
+	return newScriptExecutor(nodeScript, variables, limits, true, includes...)
+}
+
+func newScriptExecutor(nodeScript nodeScript, variables map[string]interface{}, limits *script.Limits, standalone bool, includes ...string) (*NodeExecutor, error) {
+	factory := nodeScript.factory()
+	if limitedFactory, ok := factory.(script.LimitedFactory); ok && limits != nil {
+		factory = limitedFactory.WithLimits(*limits)
+	}
+
+	instance, err := factory.CreateScript(nodeScript.executable(), variables, standalone, includes...)
 	if err != nil {
 		return nil, errors.Wrap(err, "spawn node process")
 	}
@@ -352,13 +499,42 @@ func (e *NodeExecutor) ProcessEvent(event events.Event, listener script.Listener
 }
 
 func (e *NodeExecutor) Format() string {
-	return "javascript"
+	return e.format()
 }
 
 func (e *NodeExecutor) Expression() string {
 	return e.String()
 }
 
+//BundleDependencies returns JavaScript include with bundled npm dependencies (package name -> version)
+func BundleDependencies(dependencies map[string]string) (string, error) {
+	bundler, ok := scriptFactory.(script.DependenciesBundler)
+	if !ok {
+		return "", errors.New("npm dependencies aren't supported by JavaScript runtime")
+	}
+
+	return bundler.BundleDependencies(dependencies)
+}
+
+//NewScriptLimits returns script.Limits from destination configuration or nil if it isn't configured
+func NewScriptLimits(limitsConfig *config.ScriptLimits) (*script.Limits, error) {
+	if limitsConfig == nil {
+		return nil, nil
+	}
+
+	limits := &script.Limits{
+		MaxMemoryMB:       limitsConfig.MaxMemoryMB,
+		Timeout:           time.Duration(limitsConfig.TimeoutMs) * time.Millisecond,
+		OutOfMemoryPolicy: limitsConfig.OutOfMemoryPolicy,
+		MaxRetries:        limitsConfig.MaxRetries,
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
 type buildInfo struct {
 	SdkVersion     string `json:"sdkVersion"`
 	SdkPackage     string `json:"sdkPackage"`
@@ -16,13 +16,16 @@ import (
 	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/erasure"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/fallback"
 	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/health"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/multiplexing"
+	"github.com/jitsucom/jitsu/server/retention"
 	"github.com/jitsucom/jitsu/server/routers"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/sources"
@@ -87,6 +90,7 @@ func NewSuiteBuilder(t *testing.T) SuiteBuilder {
 		viper.GetString("server.fields_configuration.dst_source_ip"),
 		viper.GetString("server.fields_configuration.src_ua"),
 		viper.GetString("server.fields_configuration.dst_ua"),
+		viper.GetString("server.fields_configuration.client_hints_path"),
 	)
 
 	metaStorage := &meta.Dummy{}
@@ -95,7 +99,7 @@ func NewSuiteBuilder(t *testing.T) SuiteBuilder {
 	consumer := logevents.NewSyncLogger(inmemWriter, false)
 
 	mockStorageFactory := storages.NewMockFactory()
-	mockStorage, _, _ := mockStorageFactory.Create("test", config.DestinationConfig{})
+	mockStorage, _, _ := mockStorageFactory.Create("test", config.DestinationConfig{}, 0, nil)
 	destinationService := destinations.NewTestService(map[string]*destinations.Unit{"dest1": destinations.NewTestUnit(mockStorage)},
 		destinations.TokenizedConsumers{"id1": {"id1": consumer}},
 		destinations.TokenizedStorages{},
@@ -211,18 +215,20 @@ func (sb *suiteBuilder) Build(t *testing.T) Suit {
 	//event processors
 	apiProcessor := events.NewAPIProcessor(sb.recognitionService)
 	bulkProcessor := events.NewBulkProcessor()
-	jsProcessor := events.NewJsProcessor(sb.recognitionService, viper.GetString("server.fields_configuration.user_agent_path"))
+	jsProcessor := events.NewJsProcessor(sb.recognitionService, viper.GetString("server.fields_configuration.user_agent_path"), viper.GetString("server.fields_configuration.client_hints_path"))
 	pixelProcessor := events.NewPixelProcessor()
 	segmentProcessor := events.NewSegmentProcessor(sb.recognitionService)
 	processorHolder := events.NewProcessorHolder(apiProcessor, jsProcessor, pixelProcessor, segmentProcessor, bulkProcessor)
 
-	multiplexingService := multiplexing.NewService(sb.destinationService)
+	multiplexingService := multiplexing.NewService(sb.destinationService, nil, nil)
 	walService := wal.NewService("/tmp", &logevents.SyncLogger{}, multiplexingService, processorHolder)
 	appconfig.Instance.ScheduleWriteAheadLogClosing(walService)
 
 	router := routers.SetupRouter("", sb.metaStorage, sb.destinationService, sources.NewTestService(), synchronization.NewTestTaskService(),
-		fallback.NewTestService(), coordination.NewInMemoryService(""), sb.eventsCache, sb.systemService,
-		sb.segmentRequestFieldsMapper, sb.segmentCompatRequestFieldsMapper, processorHolder, multiplexingService, walService, sb.geoService, sb.globalUsersRecognitionConfig)
+		fallback.NewTestService(), erasure.NewTestService(),
+		retention.NewService(sb.destinationService, nil, &config.Retention{}, 0, false), coordination.NewInMemoryService(""), sb.eventsCache, sb.systemService,
+		sb.segmentRequestFieldsMapper, sb.segmentCompatRequestFieldsMapper, processorHolder, multiplexingService, walService, sb.geoService, nil, sb.globalUsersRecognitionConfig,
+		health.NewService(0))
 
 	server := &http.Server{
 		Addr:              sb.httpAuthority,
@@ -0,0 +1,224 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OTLP status codes (differ from go.opentelemetry.io/otel/codes values)
+const (
+	otlpStatusUnset = 0
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+// OTLPExporter exports spans to OpenTelemetry collector via OTLP/HTTP protocol with JSON encoding
+type OTLPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewOTLPExporter returns configured OTLPExporter. endpoint is a full traces URL e.g. http://localhost:4318/v1/traces
+func NewOTLPExporter(endpoint string, headers map[string]string, timeout time.Duration) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// ExportSpans sends spans to the collector in one request
+func (oe *OTLPExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(toOTLP(spans))
+	if err != nil {
+		return fmt.Errorf("error marshalling spans: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oe.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating OTLP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range oe.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := oe.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending spans to [%s]: %v", oe.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("OTLP collector [%s] responded with HTTP %d: %s", oe.endpoint, resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// Shutdown does nothing: the exporter doesn't keep any state
+func (oe *OTLPExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+type otlpTraces struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string           `json:"traceId"`
+	SpanID            string           `json:"spanId"`
+	ParentSpanID      string           `json:"parentSpanId,omitempty"`
+	Name              string           `json:"name"`
+	Kind              int              `json:"kind"`
+	StartTimeUnixNano string           `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string           `json:"endTimeUnixNano"`
+	Attributes        []*otlpAttribute `json:"attributes,omitempty"`
+	Events            []*otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus       `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string           `json:"timeUnixNano"`
+	Name         string           `json:"name"`
+	Attributes   []*otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// toOTLP groups spans by resource and instrumentation library
+func toOTLP(spans []sdktrace.ReadOnlySpan) *otlpTraces {
+	traces := &otlpTraces{}
+	resourceSpansByKey := map[string]*otlpResourceSpans{}
+	scopeSpansByKey := map[string]*otlpScopeSpans{}
+	for _, span := range spans {
+		var resourceAttributes []attribute.KeyValue
+		resourceKey := ""
+		if span.Resource() != nil {
+			resourceAttributes = span.Resource().Attributes()
+			resourceKey = span.Resource().Encoded(attribute.DefaultEncoder())
+		}
+		resourceSpans, ok := resourceSpansByKey[resourceKey]
+		if !ok {
+			resourceSpans = &otlpResourceSpans{Resource: otlpResource{Attributes: toOTLPAttributes(resourceAttributes)}}
+			resourceSpansByKey[resourceKey] = resourceSpans
+			traces.ResourceSpans = append(traces.ResourceSpans, resourceSpans)
+		}
+
+		library := span.InstrumentationLibrary()
+		scopeKey := resourceKey + "|" + library.Name + "|" + library.Version
+		scopeSpans, ok := scopeSpansByKey[scopeKey]
+		if !ok {
+			scopeSpans = &otlpScopeSpans{Scope: otlpScope{Name: library.Name, Version: library.Version}}
+			scopeSpansByKey[scopeKey] = scopeSpans
+			resourceSpans.ScopeSpans = append(resourceSpans.ScopeSpans, scopeSpans)
+		}
+
+		scopeSpans.Spans = append(scopeSpans.Spans, toOTLPSpan(span))
+	}
+
+	return traces
+}
+
+func toOTLPSpan(span sdktrace.ReadOnlySpan) *otlpSpan {
+	result := &otlpSpan{
+		TraceID:           span.SpanContext().TraceID().String(),
+		SpanID:            span.SpanContext().SpanID().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: unixNano(span.StartTime()),
+		EndTimeUnixNano:   unixNano(span.EndTime()),
+		Attributes:        toOTLPAttributes(span.Attributes()),
+		Status:            otlpStatus{Code: otlpStatusUnset},
+	}
+	if span.Parent().HasSpanID() {
+		result.ParentSpanID = span.Parent().SpanID().String()
+	}
+
+	for _, event := range span.Events() {
+		result.Events = append(result.Events, &otlpEvent{
+			TimeUnixNano: unixNano(event.Time),
+			Name:         event.Name,
+			Attributes:   toOTLPAttributes(event.Attributes),
+		})
+	}
+
+	switch span.Status().Code {
+	case codes.Ok:
+		result.Status.Code = otlpStatusOk
+	case codes.Error:
+		result.Status.Code = otlpStatusError
+		result.Status.Message = span.Status().Description
+	}
+
+	return result
+}
+
+func toOTLPAttributes(attributes []attribute.KeyValue) []*otlpAttribute {
+	result := make([]*otlpAttribute, 0, len(attributes))
+	for _, kv := range attributes {
+		value := map[string]interface{}{}
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			value["boolValue"] = kv.Value.AsBool()
+		case attribute.INT64:
+			//int64 values are encoded as strings in OTLP JSON
+			value["intValue"] = strconv.FormatInt(kv.Value.AsInt64(), 10)
+		case attribute.FLOAT64:
+			value["doubleValue"] = kv.Value.AsFloat64()
+		default:
+			value["stringValue"] = kv.Value.Emit()
+		}
+		result = append(result, &otlpAttribute{Key: string(kv.Key), Value: value})
+	}
+
+	return result
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	//EventContextKey is a reserved event field which carries W3C trace context of the ingestion span
+	//from HTTP handlers through events queues to destinations. It is removed before events processing
+	EventContextKey = "_jitsu_trace"
+
+	instrumentationName = "github.com/jitsucom/jitsu/server"
+)
+
+var (
+	enabled    bool
+	provider   *sdktrace.TracerProvider
+	propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+)
+
+// Config is a tracing configuration
+type Config struct {
+	ServiceName    string
+	ServiceVersion string
+	//SampleRatio is a fraction of traces which are sampled (if the parent span isn't sampled by the client)
+	SampleRatio float64
+	Exporter    sdktrace.SpanExporter
+}
+
+// Init configures global OpenTelemetry tracer provider and propagator
+func Init(config *Config) {
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(config.ServiceName),
+		semconv.ServiceVersionKey.String(config.ServiceVersion),
+	)
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(config.Exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	enabled = true
+}
+
+// Enabled returns true if tracing has been initialized
+func Enabled() bool {
+	return enabled
+}
+
+// Start starts a new span. If tracing isn't enabled a no-op span is returned
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// StartServer starts a new server span (e.g. HTTP request handling)
+func StartServer(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+}
+
+// StartAt starts a new span with the start time in the past (e.g. time spent in a queue)
+func StartAt(ctx context.Context, name string, startTime time.Time, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithTimestamp(startTime), trace.WithAttributes(attributes...))
+}
+
+// End records the error (if not nil) and ends the span
+func End(span trace.Span, err error) {
+	SetError(span, err)
+	span.End()
+}
+
+// SetError records the error (if not nil) and sets the span error status
+func SetError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// InjectEvent writes trace context of the span from ctx into the event
+func InjectEvent(ctx context.Context, event map[string]interface{}) {
+	if !enabled {
+		return
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	if traceParent, ok := carrier["traceparent"]; ok {
+		event[EventContextKey] = traceParent
+	}
+}
+
+// ExtractEvent returns context with remote span context from the event or background context
+func ExtractEvent(event map[string]interface{}) context.Context {
+	ctx := context.Background()
+	if !enabled {
+		return ctx
+	}
+
+	traceParent, ok := event[EventContextKey].(string)
+	if !ok {
+		return ctx
+	}
+
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
+
+// ExtractHTTP returns context with remote span context from HTTP headers (W3C trace context)
+func ExtractHTTP(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return propagator.Extract(ctx, carrier)
+}
+
+// Close flushes all ended spans and shuts down the exporter
+func Close() error {
+	if provider == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return provider.Shutdown(ctx)
+}
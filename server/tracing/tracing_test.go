@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEventContextPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("test")
+	enabled = true
+	defer func() { enabled = false }()
+
+	ctx, span := tracer.Start(context.Background(), "ingest")
+	event := map[string]interface{}{"event_type": "pageview"}
+	InjectEvent(ctx, event)
+	span.End()
+	require.Contains(t, event, EventContextKey)
+
+	extracted := trace.SpanContextFromContext(ExtractEvent(event))
+	require.True(t, extracted.IsRemote())
+	require.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+	require.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+
+	require.False(t, trace.SpanContextFromContext(ExtractEvent(map[string]interface{}{})).IsValid())
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var received map[string]interface{}
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		body, _ := ioutil.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("test")
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child", trace.WithAttributes(attribute.String("destination", "pg"), attribute.Int("rows", 10)))
+	End(child, errors.New("insert failed"))
+	parent.End()
+
+	exporter := NewOTLPExporter(server.URL, map[string]string{"Authorization": "Bearer token"}, time.Second)
+	require.NoError(t, exporter.ExportSpans(context.Background(), recorder.Ended()))
+	require.Equal(t, "Bearer token", authHeader)
+
+	resourceSpans := received["resourceSpans"].([]interface{})
+	require.Len(t, resourceSpans, 1)
+	scopeSpans := resourceSpans[0].(map[string]interface{})["scopeSpans"].([]interface{})
+	require.Len(t, scopeSpans, 1)
+	require.Equal(t, "test", scopeSpans[0].(map[string]interface{})["scope"].(map[string]interface{})["name"])
+	spans := scopeSpans[0].(map[string]interface{})["spans"].([]interface{})
+	require.Len(t, spans, 2)
+
+	exportedChild := spans[0].(map[string]interface{})
+	require.Equal(t, "child", exportedChild["name"])
+	require.Equal(t, parent.SpanContext().SpanID().String(), exportedChild["parentSpanId"])
+	require.Equal(t, parent.SpanContext().TraceID().String(), exportedChild["traceId"])
+	require.Equal(t, map[string]interface{}{"code": float64(otlpStatusError), "message": "insert failed"}, exportedChild["status"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"key": "destination", "value": map[string]interface{}{"stringValue": "pg"}},
+		map[string]interface{}{"key": "rows", "value": map[string]interface{}{"intValue": "10"}},
+	}, exportedChild["attributes"])
+
+	exportedParent := spans[1].(map[string]interface{})
+	require.NotContains(t, exportedParent, "parentSpanId")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	require.Error(t, exporter.ExportSpans(context.Background(), recorder.Ended()))
+}
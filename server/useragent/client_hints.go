@@ -0,0 +1,271 @@
+package useragent
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ClientHintsKey is a json key for User-Agent Client Hints object
+const ClientHintsKey = "client_hints"
+
+// Sec-CH-UA request headers
+const (
+	headerBrands          = "Sec-CH-UA"
+	headerFullVersionList = "Sec-CH-UA-Full-Version-List"
+	headerMobile          = "Sec-CH-UA-Mobile"
+	headerPlatform        = "Sec-CH-UA-Platform"
+	headerPlatformVersion = "Sec-CH-UA-Platform-Version"
+	headerModel           = "Sec-CH-UA-Model"
+	headerArchitecture    = "Sec-CH-UA-Arch"
+	headerBitness         = "Sec-CH-UA-Bitness"
+)
+
+// AcceptClientHints is a value of Accept-CH response header which asks browser to send high entropy client hints
+var AcceptClientHints = strings.Join([]string{headerFullVersionList, headerPlatformVersion, headerModel, headerArchitecture, headerBitness}, ", ")
+
+// browserFamilies maps client hints brands to ua-parser browser families
+var browserFamilies = map[string]string{
+	"Google Chrome":  "Chrome",
+	"Microsoft Edge": "Edge",
+	"Opera":          "Opera",
+	"Brave":          "Brave",
+	"Yandex":         "Yandex Browser",
+	"Vivaldi":        "Vivaldi",
+	"Chromium":       "Chromium",
+}
+
+// osFamilies maps client hints platforms to ua-parser OS families
+var osFamilies = map[string]string{
+	"macOS":       "Mac OS X",
+	"Chrome OS":   "Chrome OS",
+	"Chromium OS": "Chrome OS",
+}
+
+// ClientHintsBrand is a browser brand with version from Sec-CH-UA or Sec-CH-UA-Full-Version-List headers
+type ClientHintsBrand struct {
+	Brand   string `mapstructure:"brand" json:"brand"`
+	Version string `mapstructure:"version" json:"version"`
+}
+
+// ClientHints is a dto for User-Agent Client Hints. It is parsed from Sec-CH-UA-* request headers
+// or can be sent in the event payload (e.g. result of navigator.userAgentData.getHighEntropyValues())
+type ClientHints struct {
+	Brands          []ClientHintsBrand `mapstructure:"brands,omitempty" json:"brands,omitempty"`
+	FullVersionList []ClientHintsBrand `mapstructure:"full_version_list,omitempty" json:"full_version_list,omitempty"`
+	Mobile          *bool              `mapstructure:"mobile,omitempty" json:"mobile,omitempty"`
+	Platform        string             `mapstructure:"platform,omitempty" json:"platform,omitempty"`
+	PlatformVersion string             `mapstructure:"platform_version,omitempty" json:"platform_version,omitempty"`
+	Model           string             `mapstructure:"model,omitempty" json:"model,omitempty"`
+	Architecture    string             `mapstructure:"architecture,omitempty" json:"architecture,omitempty"`
+	Bitness         string             `mapstructure:"bitness,omitempty" json:"bitness,omitempty"`
+}
+
+// ParseClientHints returns client hints from request headers or nil if the request doesn't contain them
+func ParseClientHints(header http.Header) *ClientHints {
+	hints := &ClientHints{
+		Brands:          parseBrandList(header.Get(headerBrands)),
+		FullVersionList: parseBrandList(header.Get(headerFullVersionList)),
+		Platform:        unquote(header.Get(headerPlatform)),
+		PlatformVersion: unquote(header.Get(headerPlatformVersion)),
+		Model:           unquote(header.Get(headerModel)),
+		Architecture:    unquote(header.Get(headerArchitecture)),
+		Bitness:         unquote(header.Get(headerBitness)),
+	}
+
+	switch strings.TrimSpace(header.Get(headerMobile)) {
+	case "?1":
+		mobile := true
+		hints.Mobile = &mobile
+	case "?0":
+		mobile := false
+		hints.Mobile = &mobile
+	}
+
+	if hints.IsEmpty() {
+		return nil
+	}
+
+	return hints
+}
+
+// IsEmpty returns true if all values in ClientHints are empty
+func (ch *ClientHints) IsEmpty() bool {
+	return len(ch.Brands) == 0 && len(ch.FullVersionList) == 0 && ch.Mobile == nil &&
+		ch.Platform == "" && ch.PlatformVersion == "" && ch.Model == "" && ch.Architecture == "" && ch.Bitness == ""
+}
+
+// MergeClientHints overrides user-agent string based values with client hints values which are more accurate
+// (reduced user-agent strings have frozen OS and browser versions and don't contain device model).
+// Returns nil if both resolved and hints are empty
+func MergeClientHints(resolved *ResolvedUa, hints *ClientHints) *ResolvedUa {
+	if hints == nil || hints.IsEmpty() {
+		return resolved
+	}
+
+	merged := &ResolvedUa{}
+	if resolved != nil {
+		*merged = *resolved
+	}
+
+	// browser
+	if brand, ok := significantBrand(hints.FullVersionList); ok {
+		if merged.UaFamily == "" {
+			merged.UaFamily = browserFamily(brand.Brand)
+		}
+		merged.UaVersion = brand.Version
+	} else if brand, ok := significantBrand(hints.Brands); ok {
+		if merged.UaFamily == "" {
+			merged.UaFamily = browserFamily(brand.Brand)
+		}
+		if merged.UaVersion == "" {
+			merged.UaVersion = brand.Version
+		}
+	}
+
+	// OS
+	if hints.Platform != "" && hints.Platform != "Unknown" {
+		if family, ok := osFamilies[hints.Platform]; ok {
+			merged.OsFamily = family
+		} else {
+			merged.OsFamily = hints.Platform
+		}
+
+		if version := osVersion(hints.Platform, hints.PlatformVersion); version != "" {
+			merged.OsVersion = version
+		}
+	}
+
+	if hints.Architecture != "" {
+		merged.OsArchitecture = hints.Architecture
+		if hints.Bitness != "" {
+			merged.OsArchitecture += "_" + hints.Bitness
+		}
+	}
+
+	// device
+	if hints.Model != "" {
+		merged.DeviceFamily = hints.Model
+		merged.DeviceModel = hints.Model
+	}
+
+	if hints.Mobile != nil {
+		if *hints.Mobile {
+			merged.DeviceType = "mobile"
+		} else if merged.OsFamily != "Android" && merged.OsFamily != "iOS" {
+			merged.DeviceType = "desktop"
+		}
+	}
+
+	if merged.IsEmpty() && merged.DeviceType == "" && merged.OsArchitecture == "" {
+		return nil
+	}
+
+	return merged
+}
+
+// significantBrand returns the most specific browser brand: GREASE brands are skipped and Chromium is used
+// only if there is no other brand (e.g. Google Chrome lists Chromium as well)
+func significantBrand(brands []ClientHintsBrand) (ClientHintsBrand, bool) {
+	var chromium *ClientHintsBrand
+	for i, brand := range brands {
+		if isGreaseBrand(brand.Brand) {
+			continue
+		}
+		if brand.Brand == "Chromium" {
+			chromium = &brands[i]
+			continue
+		}
+
+		return brand, true
+	}
+
+	if chromium != nil {
+		return *chromium, true
+	}
+
+	return ClientHintsBrand{}, false
+}
+
+// isGreaseBrand returns true for fake brands (e.g. "Not A(Brand") which browsers add for preventing brands sniffing
+func isGreaseBrand(brand string) bool {
+	lower := strings.ToLower(brand)
+	return strings.Contains(lower, "not") && strings.Contains(lower, "brand")
+}
+
+func browserFamily(brand string) string {
+	if family, ok := browserFamilies[brand]; ok {
+		return family
+	}
+
+	return brand
+}
+
+// osVersion returns OS version from platform version. Windows platform version is mapped to the Windows release:
+// 1-10 -> 10, 13+ -> 11 (user-agent string contains Windows NT 10.0 for both)
+func osVersion(platform, platformVersion string) string {
+	if platformVersion == "" {
+		return ""
+	}
+
+	if platform != "Windows" {
+		return platformVersion
+	}
+
+	major, err := strconv.Atoi(strings.Split(platformVersion, ".")[0])
+	switch {
+	case err != nil || major == 0:
+		// Windows 7, 8, 8.1: keep user-agent value
+		return ""
+	case major >= 13:
+		return "11"
+	default:
+		return "10"
+	}
+}
+
+// parseBrandList parses structured header list: "Chromium";v="110", "Not A(Brand";v="24", "Google Chrome";v="110"
+func parseBrandList(value string) []ClientHintsBrand {
+	var brands []ClientHintsBrand
+	for _, item := range splitOutsideQuotes(value, ',') {
+		params := splitOutsideQuotes(item, ';')
+		brand := ClientHintsBrand{Brand: unquote(params[0])}
+		for _, param := range params[1:] {
+			if kv := strings.SplitN(strings.TrimSpace(param), "=", 2); len(kv) == 2 && kv[0] == "v" {
+				brand.Version = unquote(kv[1])
+			}
+		}
+
+		if brand.Brand != "" {
+			brands = append(brands, brand)
+		}
+	}
+
+	return brands
+}
+
+func splitOutsideQuotes(value string, separator rune) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, r := range value {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == separator && !inQuotes:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, value[start:])
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+
+	return value
+}
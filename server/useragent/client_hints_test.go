@@ -0,0 +1,93 @@
+package useragent
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClientHints(t *testing.T) {
+	require.Nil(t, ParseClientHints(http.Header{}))
+
+	header := http.Header{}
+	header.Set("Sec-CH-UA", `"Chromium";v="110", "Not A(Brand";v="24", "Google Chrome";v="110"`)
+	header.Set("Sec-CH-UA-Full-Version-List", `"Chromium";v="110.0.5481.178", "Not A(Brand";v="24.0.0.0", "Google Chrome";v="110.0.5481.178"`)
+	header.Set("Sec-CH-UA-Mobile", "?0")
+	header.Set("Sec-CH-UA-Platform", `"Windows"`)
+	header.Set("Sec-CH-UA-Platform-Version", `"15.0.0"`)
+	header.Set("Sec-CH-UA-Model", `""`)
+	header.Set("Sec-CH-UA-Arch", `"x86"`)
+	header.Set("Sec-CH-UA-Bitness", `"64"`)
+
+	mobile := false
+	require.Equal(t, &ClientHints{
+		Brands: []ClientHintsBrand{{"Chromium", "110"}, {"Not A(Brand", "24"}, {"Google Chrome", "110"}},
+		FullVersionList: []ClientHintsBrand{{"Chromium", "110.0.5481.178"}, {"Not A(Brand", "24.0.0.0"},
+			{"Google Chrome", "110.0.5481.178"}},
+		Mobile:          &mobile,
+		Platform:        "Windows",
+		PlatformVersion: "15.0.0",
+		Architecture:    "x86",
+		Bitness:         "64",
+	}, ParseClientHints(header))
+}
+
+func TestMergeClientHints(t *testing.T) {
+	mobile := true
+	desktop := false
+	tests := []struct {
+		name     string
+		resolved *ResolvedUa
+		hints    *ClientHints
+		expected *ResolvedUa
+	}{
+		{
+			"Without hints",
+			&ResolvedUa{UaFamily: "Chrome", UaVersion: "110.0.0"},
+			nil,
+			&ResolvedUa{UaFamily: "Chrome", UaVersion: "110.0.0"},
+		},
+		{
+			"Windows 11 desktop",
+			&ResolvedUa{UaFamily: "Chrome", UaVersion: "110.0.0", OsFamily: "Windows", OsVersion: "10"},
+			&ClientHints{
+				Brands:          []ClientHintsBrand{{"Chromium", "110"}, {"Not A(Brand", "24"}, {"Google Chrome", "110"}},
+				FullVersionList: []ClientHintsBrand{{"Not A(Brand", "24.0.0.0"}, {"Chromium", "110.0.5481.178"}, {"Google Chrome", "110.0.5481.178"}},
+				Mobile:          &desktop,
+				Platform:        "Windows",
+				PlatformVersion: "15.0.0",
+				Architecture:    "x86",
+				Bitness:         "64",
+			},
+			&ResolvedUa{UaFamily: "Chrome", UaVersion: "110.0.5481.178", OsFamily: "Windows", OsVersion: "11", OsArchitecture: "x86_64", DeviceType: "desktop"},
+		},
+		{
+			"Reduced Android user-agent",
+			&ResolvedUa{UaFamily: "Chrome Mobile", UaVersion: "110.0.0", OsFamily: "Android", OsVersion: "10", DeviceFamily: "K", DeviceModel: "K"},
+			&ClientHints{
+				Brands:          []ClientHintsBrand{{"Chromium", "110"}, {"Not A(Brand", "24"}, {"Google Chrome", "110"}},
+				Mobile:          &mobile,
+				Platform:        "Android",
+				PlatformVersion: "13.0.0",
+				Model:           "Pixel 7",
+			},
+			&ResolvedUa{UaFamily: "Chrome Mobile", UaVersion: "110.0.0", OsFamily: "Android", OsVersion: "13.0.0", DeviceFamily: "Pixel 7", DeviceModel: "Pixel 7", DeviceType: "mobile"},
+		},
+		{
+			"Only hints",
+			nil,
+			&ClientHints{
+				Brands:   []ClientHintsBrand{{"Not_A Brand", "99"}, {"Microsoft Edge", "109"}, {"Chromium", "109"}},
+				Platform: "macOS",
+			},
+			&ResolvedUa{UaFamily: "Edge", UaVersion: "109", OsFamily: "Mac OS X"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ObjectsEqual(t, tt.expected, MergeClientHints(tt.resolved, tt.hints), "Merged user agents aren't equal")
+		})
+	}
+}
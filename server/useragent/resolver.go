@@ -38,10 +38,14 @@ type ResolvedUa struct {
 
 	OsFamily  string `mapstructure:"os_family,omitempty" json:"os_family,omitempty"`
 	OsVersion string `mapstructure:"os_version,omitempty" json:"os_version,omitempty"`
+	// OsArchitecture is set only from client hints (e.g. x86_64, arm_64)
+	OsArchitecture string `mapstructure:"os_architecture,omitempty" json:"os_architecture,omitempty"`
 
 	DeviceFamily string `mapstructure:"device_family,omitempty" json:"device_family,omitempty"`
 	DeviceBrand  string `mapstructure:"device_brand,omitempty" json:"device_brand,omitempty"`
 	DeviceModel  string `mapstructure:"device_model,omitempty" json:"device_model,omitempty"`
+	// DeviceType is set only from client hints: mobile or desktop
+	DeviceType string `mapstructure:"device_type,omitempty" json:"device_type,omitempty"`
 
 	Bot bool `mapstructure:"bot,omitempty" json:"bot,omitempty"`
 }
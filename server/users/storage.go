@@ -65,7 +65,8 @@ func InitializeStorage(enabled bool, metaStorageConfiguration *viper.Viper) (Sto
 	tlsSkipVerify := redisConfigurationSource.GetBool("tls_skip_verify")
 	anonymousEventsMinutesTTL := redisConfigurationSource.GetInt("ttl_minutes.anonymous_events")
 
-	factory := meta.NewRedisPoolFactory(host, port, password, database, tlsSkipVerify, sentinelMaster)
+	factory := meta.NewRedisPoolFactory(host, port, password, database, tlsSkipVerify, sentinelMaster).
+		WithConfiguration(redisConfigurationSource)
 	options := factory.GetOptions()
 	options.MaxActive = 100
 	factory.WithOptions(options)
@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/resources"
+)
+
+type cachedValidator struct {
+	hash      uint64
+	validator *Validator
+}
+
+// Cache keeps compiled validators by API key id. A validator is recompiled when the API key configuration is changed
+type Cache struct {
+	mutex      sync.RWMutex
+	validators map[string]*cachedValidator
+}
+
+// NewCache returns empty Cache
+func NewCache() *Cache {
+	return &Cache{validators: map[string]*cachedValidator{}}
+}
+
+// Get returns compiled validator of the API key or nil if there are no validation rules.
+// Invalid configurations are logged and such API keys events aren't validated
+func (c *Cache) Get(tokenID string, config *Config) *Validator {
+	if config.IsEmpty() {
+		return nil
+	}
+
+	hash, err := resources.GetHash(config)
+	if err != nil {
+		logging.SystemErrorf("[%s] Error getting hash of events validation configuration: %v", tokenID, err)
+		return nil
+	}
+
+	c.mutex.RLock()
+	cached, ok := c.validators[tokenID]
+	c.mutex.RUnlock()
+	if ok && cached.hash == hash {
+		return cached.validator
+	}
+
+	validator, err := NewValidator(config)
+	if err != nil {
+		logging.Errorf("[%s] Error creating events validator. Events of the API key won't be validated: %v", tokenID, err)
+	}
+
+	c.mutex.Lock()
+	c.validators[tokenID] = &cachedValidator{hash: hash, validator: validator}
+	c.mutex.Unlock()
+
+	return validator
+}
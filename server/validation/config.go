@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Validation modes
+const (
+	// RejectMode rejects requests with invalid events with HTTP 422
+	RejectMode = "reject"
+	// QuarantineMode accepts invalid events and stores them into the quarantine table of SQL destinations
+	QuarantineMode = "quarantine"
+
+	defaultQuarantineTable = "events_quarantine"
+)
+
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Rules are validation rules of events
+type Rules struct {
+	// Schema is a JSON Schema object, JSON Schema string or a JSON Schema URL (http://, https:// or file://)
+	Schema interface{} `mapstructure:"schema" json:"schema,omitempty" yaml:"schema,omitempty"`
+	// RequiredFields are paths of fields which must be present and not empty e.g. /user/id
+	RequiredFields []string `mapstructure:"required_fields" json:"required_fields,omitempty" yaml:"required_fields,omitempty"`
+}
+
+// Config is a configuration of events validation per API key. Rules are applied to all events
+// and EventTypes rules are applied to events with the corresponding event_type value in addition
+type Config struct {
+	Rules           `mapstructure:",squash" yaml:",inline"`
+	EventTypes      map[string]Rules `mapstructure:"event_types" json:"event_types,omitempty" yaml:"event_types,omitempty"`
+	Mode            string           `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+	QuarantineTable string           `mapstructure:"quarantine_table" json:"quarantine_table,omitempty" yaml:"quarantine_table,omitempty"`
+}
+
+// Validate returns err if the configuration is invalid
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "", RejectMode, QuarantineMode:
+	default:
+		return fmt.Errorf("unsupported validation mode: %s. Supported: %s, %s", c.Mode, RejectMode, QuarantineMode)
+	}
+
+	if c.QuarantineTable != "" && !tableNameRegex.MatchString(c.QuarantineTable) {
+		return fmt.Errorf("quarantine_table must contain only letters, digits and underscores. Got: %q", c.QuarantineTable)
+	}
+
+	return nil
+}
+
+// IsEmpty returns true if there are no validation rules
+func (c *Config) IsEmpty() bool {
+	if c == nil {
+		return true
+	}
+
+	if !c.Rules.isEmpty() {
+		return false
+	}
+	for _, rules := range c.EventTypes {
+		if !rules.isEmpty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r Rules) isEmpty() bool {
+	return r.Schema == nil && len(r.RequiredFields) == 0
+}
@@ -0,0 +1,196 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/utils"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// QuarantineTableParameter is set into invalid events in quarantine mode. Such events are stored
+	// into the table from the parameter value without transformation
+	QuarantineTableParameter = "JITSU_QUARANTINE_TABLE"
+	// ErrorsField contains validation errors of quarantined events
+	ErrorsField = "validation_errors"
+)
+
+// EventErrors are validation errors of an event from the request
+type EventErrors struct {
+	Index  int      `json:"index"`
+	Errors []string `json:"errors"`
+}
+
+// Error is returned when a request contains invalid events in reject mode
+type Error struct {
+	Events []EventErrors
+}
+
+func (e *Error) Error() string {
+	var descriptions []string
+	for _, eventErrors := range e.Events {
+		descriptions = append(descriptions, fmt.Sprintf("event #%d: %s", eventErrors.Index, strings.Join(eventErrors.Errors, "; ")))
+	}
+
+	return fmt.Sprintf("Events validation failed: %s", strings.Join(descriptions, ". "))
+}
+
+type compiledRules struct {
+	schema         *gojsonschema.Schema
+	requiredFields []jsonutils.JSONPath
+}
+
+// Validator validates events with JSON Schema and required fields and either rejects requests with
+// invalid events or marks them to be stored into the quarantine table
+type Validator struct {
+	rules           *compiledRules
+	eventTypes      map[string]*compiledRules
+	quarantineTable string
+}
+
+// NewValidator returns configured Validator or nil if there are no validation rules
+func NewValidator(config *Config) (*Validator, error) {
+	if config.IsEmpty() {
+		return nil, nil
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	rules, err := compileRules(config.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	eventTypes := map[string]*compiledRules{}
+	for eventType, eventTypeRules := range config.EventTypes {
+		compiled, err := compileRules(eventTypeRules)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling [%s] event type rules: %v", eventType, err)
+		}
+		eventTypes[eventType] = compiled
+	}
+
+	validator := &Validator{rules: rules, eventTypes: eventTypes}
+	if config.Mode == QuarantineMode {
+		validator.quarantineTable = config.QuarantineTable
+		if validator.quarantineTable == "" {
+			validator.quarantineTable = defaultQuarantineTable
+		}
+	}
+
+	return validator, nil
+}
+
+func compileRules(rules Rules) (*compiledRules, error) {
+	compiled := &compiledRules{}
+	for _, field := range rules.RequiredFields {
+		path := jsonutils.NewJSONPath(field)
+		if path.IsEmpty() {
+			return nil, fmt.Errorf("required_fields must be valid paths like: /node1/node2. Got: %q", field)
+		}
+		compiled.requiredFields = append(compiled.requiredFields, path)
+	}
+
+	if rules.Schema == nil {
+		return compiled, nil
+	}
+
+	var loader gojsonschema.JSONLoader
+	switch schema := rules.Schema.(type) {
+	case string:
+		schema = strings.TrimSpace(schema)
+		if strings.HasPrefix(schema, "{") {
+			loader = gojsonschema.NewStringLoader(schema)
+		} else if strings.HasPrefix(schema, "http://") || strings.HasPrefix(schema, "https://") || strings.HasPrefix(schema, "file://") {
+			loader = gojsonschema.NewReferenceLoader(schema)
+		} else {
+			return nil, fmt.Errorf("schema must be a JSON object, a JSON string or an URL. Got: %q", schema)
+		}
+	default:
+		loader = gojsonschema.NewGoLoader(utils.MapKeysToString(schema))
+	}
+
+	jsonSchema, err := gojsonschema.NewSchema(loader)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling JSON Schema: %v", err)
+	}
+	compiled.schema = jsonSchema
+
+	return compiled, nil
+}
+
+// Apply validates events. In reject mode it returns *Error if at least one event is invalid.
+// In quarantine mode invalid events are marked with the quarantine table and validation errors
+func (v *Validator) Apply(eventsArray []events.Event) error {
+	if v == nil {
+		return nil
+	}
+
+	validationErr := &Error{}
+	for i, event := range eventsArray {
+		errs := v.Validate(event)
+		if len(errs) == 0 {
+			continue
+		}
+
+		if v.quarantineTable != "" {
+			event[QuarantineTableParameter] = v.quarantineTable
+			event[ErrorsField] = strings.Join(errs, "; ")
+			continue
+		}
+
+		validationErr.Events = append(validationErr.Events, EventErrors{Index: i, Errors: errs})
+	}
+
+	if len(validationErr.Events) > 0 {
+		return validationErr
+	}
+
+	return nil
+}
+
+// Validate returns descriptions of all validation errors of the event or empty slice if the event is valid
+func (v *Validator) Validate(event events.Event) []string {
+	//HTTP context isn't a part of the event payload
+	payload := make(map[string]interface{}, len(event))
+	for k, value := range event {
+		if k != events.HTTPContextField {
+			payload[k] = value
+		}
+	}
+
+	errs := v.rules.validate(payload)
+	if eventType, ok := payload[events.EventType].(string); ok {
+		if eventTypeRules, ok := v.eventTypes[eventType]; ok {
+			errs = append(errs, eventTypeRules.validate(payload)...)
+		}
+	}
+
+	return errs
+}
+
+func (cr *compiledRules) validate(payload map[string]interface{}) []string {
+	var errs []string
+	for _, field := range cr.requiredFields {
+		if value, ok := field.Get(payload); !ok || value == nil || value == "" {
+			errs = append(errs, fmt.Sprintf("%s: field is required", field.String()))
+		}
+	}
+
+	if cr.schema != nil {
+		result, err := cr.schema.Validate(gojsonschema.NewGoLoader(payload))
+		if err != nil {
+			return append(errs, fmt.Sprintf("error validating JSON Schema: %v", err))
+		}
+		for _, resultErr := range result.Errors() {
+			errs = append(errs, fmt.Sprintf("%s: %s", resultErr.Field(), resultErr.Description()))
+		}
+	}
+
+	return errs
+}
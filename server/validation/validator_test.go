@@ -0,0 +1,122 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+  "type": "object",
+  "properties": {
+    "event_type": {"type": "string"},
+    "amount": {"type": "number", "minimum": 0}
+  },
+  "required": ["event_type"]
+}`
+
+func TestNewValidator(t *testing.T) {
+	validator, err := NewValidator(nil)
+	require.NoError(t, err)
+	require.Nil(t, validator)
+	require.NoError(t, validator.Apply([]events.Event{{}}), "nil validator must accept all events")
+
+	validator, err = NewValidator(&Config{Mode: QuarantineMode})
+	require.NoError(t, err)
+	require.Nil(t, validator)
+
+	_, err = NewValidator(&Config{Rules: Rules{RequiredFields: []string{"/a"}}, Mode: "unknown"})
+	require.Error(t, err)
+	_, err = NewValidator(&Config{Rules: Rules{Schema: `{"type": 1}`}})
+	require.Error(t, err)
+	_, err = NewValidator(&Config{Rules: Rules{Schema: "not a schema"}})
+	require.Error(t, err)
+	_, err = NewValidator(&Config{Rules: Rules{RequiredFields: []string{"/a"}}, QuarantineTable: "drop table"})
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	validator, err := NewValidator(&Config{
+		Rules: Rules{Schema: testSchema},
+		EventTypes: map[string]Rules{
+			"purchase": {
+				//yaml configuration produces map[interface{}]interface{}
+				Schema: map[interface{}]interface{}{
+					"type":     "object",
+					"required": []interface{}{"amount"},
+				},
+				RequiredFields: []string{"/user/id"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		event    events.Event
+		expected []string
+	}{
+		{
+			"valid",
+			events.Event{"event_type": "pageview", events.HTTPContextField: &events.HTTPContext{}},
+			nil,
+		},
+		{
+			"valid event type",
+			events.Event{"event_type": "purchase", "amount": 10, "user": map[string]interface{}{"id": "u1"}},
+			nil,
+		},
+		{
+			"schema errors",
+			events.Event{"amount": -1},
+			[]string{"(root): event_type is required", "amount: Must be greater than or equal to 0"},
+		},
+		{
+			"event type errors",
+			events.Event{"event_type": "purchase", "user": map[string]interface{}{"id": ""}},
+			[]string{"/user/id: field is required", "(root): amount is required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, validator.Validate(tt.event))
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	rules := Rules{RequiredFields: []string{"/user_id"}}
+
+	rejectValidator, err := NewValidator(&Config{Rules: rules})
+	require.NoError(t, err)
+	err = rejectValidator.Apply([]events.Event{{"user_id": "1"}, {}, {"user_id": "2"}})
+	require.IsType(t, &Error{}, err)
+	require.Equal(t, []EventErrors{{Index: 1, Errors: []string{"/user_id: field is required"}}}, err.(*Error).Events)
+	require.Equal(t, "Events validation failed: event #1: /user_id: field is required", err.Error())
+
+	quarantineValidator, err := NewValidator(&Config{Rules: rules, Mode: QuarantineMode})
+	require.NoError(t, err)
+	valid, invalid := events.Event{"user_id": "1"}, events.Event{}
+	require.NoError(t, quarantineValidator.Apply([]events.Event{valid, invalid}))
+	require.Equal(t, events.Event{"user_id": "1"}, valid)
+	require.Equal(t, events.Event{
+		QuarantineTableParameter: defaultQuarantineTable,
+		ErrorsField:              "/user_id: field is required",
+	}, invalid)
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache()
+	require.Nil(t, cache.Get("token", nil))
+
+	config := &Config{Rules: Rules{RequiredFields: []string{"/user_id"}}}
+	validator := cache.Get("token", config)
+	require.NotNil(t, validator)
+	require.True(t, validator == cache.Get("token", config), "validator must be cached")
+
+	config = &Config{Rules: Rules{RequiredFields: []string{"/user_id"}}, Mode: QuarantineMode}
+	require.False(t, validator == cache.Get("token", config), "validator must be recompiled after configuration change")
+
+	require.Nil(t, cache.Get("token", &Config{Rules: Rules{Schema: "not a schema"}}))
+}
@@ -3,6 +3,7 @@ package wal
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/appstatus"
@@ -139,7 +140,7 @@ func (s *Service) handleFile(filePath string) error {
 
 	for _, record := range records {
 		processor := s.processorHolder.GetByType(record.ProcessorType)
-		_, err := s.multiplexingService.AcceptRequest(processor, record.RequestContext, record.Token, record.Events)
+		_, err := s.multiplexingService.AcceptRequest(context.Background(), processor, record.RequestContext, record.Token, record.Events)
 		if err != nil {
 			//ELOST
 			reqBody, _ := json.Marshal(record)